@@ -19,6 +19,12 @@ const (
 	ExternTypeTable  ExternType = 0x01
 	ExternTypeMemory ExternType = 0x02
 	ExternTypeGlobal ExternType = 0x03
+	// ExternTypeTag is an exception tag, introduced by the exception-handling
+	// proposal. This can only be imported or exported when
+	// experimental.CoreFeaturesExceptionHandling is enabled.
+	//
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md
+	ExternTypeTag ExternType = 0x04
 )
 
 // The below are exported to consolidate parsing behavior for external types.
@@ -31,6 +37,8 @@ const (
 	ExternTypeMemoryName = "memory"
 	// ExternTypeGlobalName is the name of the WebAssembly 1.0 (20191205) Text Format field for ExternTypeGlobal.
 	ExternTypeGlobalName = "global"
+	// ExternTypeTagName is the name of the exception-handling proposal Text Format field for ExternTypeTag.
+	ExternTypeTagName = "tag"
 )
 
 // ExternTypeName returns the name of the WebAssembly 1.0 (20191205) Text Format field of the given type.
@@ -46,6 +54,8 @@ func ExternTypeName(et ExternType) string {
 		return ExternTypeMemoryName
 	case ExternTypeGlobal:
 		return ExternTypeGlobalName
+	case ExternTypeTag:
+		return ExternTypeTagName
 	}
 	return fmt.Sprintf("%#x", et)
 }
@@ -268,7 +278,8 @@ type ExportDefinition interface {
 }
 
 // MemoryDefinition is a WebAssembly memory exported in a module
-// (wazero.CompiledModule). Units are in pages (64KB).
+// (wazero.CompiledModule). Units are in pages, which are 64KB unless a custom
+// page size is declared. See PageSize.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#exports%E2%91%A0
 //
@@ -279,13 +290,18 @@ type ExportDefinition interface {
 type MemoryDefinition interface {
 	ExportDefinition
 
-	// Min returns the possibly zero initial count of 64KB pages.
+	// Min returns the possibly zero initial count of pages.
 	Min() uint32
 
-	// Max returns the possibly zero max count of 64KB pages, or false if
+	// Max returns the possibly zero max count of pages, or false if
 	// unbounded.
 	Max() (uint32, bool)
 
+	// PageSize returns the size of a page in bytes, which is 65536 (64KB)
+	// unless a custom page size of 1 byte is declared with
+	// experimental.CoreFeaturesCustomPageSizes.
+	PageSize() uint32
+
 	internalapi.WazeroOnly
 }
 
@@ -683,6 +699,64 @@ type Memory interface {
 	internalapi.WazeroOnly
 }
 
+// Memory64 is the companion of Memory with 64-bit offsets, which allows access beyond 4GiB. A memory defined with the
+// memory64 proposal can be larger than that, in which case the 32-bit methods on Memory can only access its first 4GiB.
+//
+// For example, to write to a memory which is larger than 4GiB:
+//
+//	mem64 := mod.Memory().(api.Memory64)
+//	ok := mem64.Write64(offset, data)
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations of Memory in wazero implement Memory64.
+//   - Methods are the same as the ones without the "64" suffix on Memory, except for the width of their arguments.
+//
+// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+type Memory64 interface {
+	Memory
+
+	// Size64 returns the memory size in bytes available, which doesn't overflow unlike Memory.Size.
+	Size64() uint64
+
+	// Grow64 is the same as Memory.Grow, but allows the delta over 32-bit.
+	Grow64(deltaPages uint64) (previousPages uint64, ok bool)
+
+	// ReadByte64 is the same as Memory.ReadByte, but with a 64-bit offset.
+	ReadByte64(offset uint64) (byte, bool)
+
+	// ReadUint16Le64 is the same as Memory.ReadUint16Le, but with a 64-bit offset.
+	ReadUint16Le64(offset uint64) (uint16, bool)
+
+	// ReadUint32Le64 is the same as Memory.ReadUint32Le, but with a 64-bit offset.
+	ReadUint32Le64(offset uint64) (uint32, bool)
+
+	// ReadUint64Le64 is the same as Memory.ReadUint64Le, but with a 64-bit offset.
+	ReadUint64Le64(offset uint64) (uint64, bool)
+
+	// Read64 is the same as Memory.Read, but with a 64-bit offset and byteCount.
+	Read64(offset, byteCount uint64) ([]byte, bool)
+
+	// WriteByte64 is the same as Memory.WriteByte, but with a 64-bit offset.
+	WriteByte64(offset uint64, v byte) bool
+
+	// WriteUint16Le64 is the same as Memory.WriteUint16Le, but with a 64-bit offset.
+	WriteUint16Le64(offset uint64, v uint16) bool
+
+	// WriteUint32Le64 is the same as Memory.WriteUint32Le, but with a 64-bit offset.
+	WriteUint32Le64(offset uint64, v uint32) bool
+
+	// WriteUint64Le64 is the same as Memory.WriteUint64Le, but with a 64-bit offset.
+	WriteUint64Le64(offset uint64, v uint64) bool
+
+	// Write64 is the same as Memory.Write, but with a 64-bit offset.
+	Write64(offset uint64, v []byte) bool
+
+	// WriteString64 is the same as Memory.WriteString, but with a 64-bit offset.
+	WriteString64(offset uint64, v string) bool
+}
+
 // CustomSection contains the name and raw data of a custom section.
 //
 // # Notes
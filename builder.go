@@ -2,8 +2,10 @@ package wazero
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/component"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -114,6 +116,39 @@ type HostFunctionBuilder interface {
 	//	--snip--
 	WithFunc(interface{}) HostFunctionBuilder
 
+	// WithComponentFunc uses reflect.Value to map a go `func` to a function
+	// of the component model, whose parameters and results are lifted from,
+	// and lowered to, WebAssembly values as per the canonical ABI.
+	//
+	// Here's an example of a function which returns a string:
+	//
+	//	builder.WithComponentFunc(func(ctx context.Context, name string) string {
+	//		return "hello " + name
+	//	})
+	//
+	// # Defining a function
+	//
+	// Like WithFunc, context.Context and api.Module may be specified as the
+	// first parameters. Other parameters, and the optional result, must be of
+	// the Go types of values of the component model, which are documented in
+	// the experimental/component package, e.g. string for string, []T for
+	// list<T>, or struct for record.
+	//
+	// # Lifting and lowering
+	//
+	// When a component lowers the function, the canonical options of the
+	// lowering apply, and resources are tracked in the handle table of the
+	// component instance: component.Own results are given to it as new
+	// handles, and component.Own parameters take them back.
+	//
+	// When a module imports the function, its signature is the one lowered,
+	// e.g. a string parameter is a pointer and a length. Values are read
+	// from and written to its memory, allocated with its "cabi_realloc"
+	// export, and strings are UTF-8. Resources can't be used.
+	//
+	// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md
+	WithComponentFunc(interface{}) HostFunctionBuilder
+
 	// WithName defines the optional module-local name of this function, e.g.
 	// "random_get"
 	//
@@ -259,6 +294,15 @@ func (h *hostFunctionBuilder) WithFunc(fn interface{}) HostFunctionBuilder {
 	return h
 }
 
+// componentFunc is a func given to WithComponentFunc, which is parsed when the host module is compiled.
+type componentFunc struct{ fn interface{} }
+
+// WithComponentFunc implements HostFunctionBuilder.WithComponentFunc
+func (h *hostFunctionBuilder) WithComponentFunc(fn interface{}) HostFunctionBuilder {
+	h.fn = componentFunc{fn}
+	return h
+}
+
 // WithName implements HostFunctionBuilder.WithName
 func (h *hostFunctionBuilder) WithName(name string) HostFunctionBuilder {
 	h.name = name
@@ -317,6 +361,17 @@ func (b *hostModuleBuilder) NewFunctionBuilder() HostFunctionBuilder {
 
 // Compile implements HostModuleBuilder.Compile
 func (b *hostModuleBuilder) Compile(ctx context.Context) (CompiledModule, error) {
+	for _, name := range b.exportNames {
+		hf := b.nameToHostFunc[name]
+		if fn, ok := hf.Code.GoFunc.(componentFunc); ok {
+			f, err := component.NewHostFunc(fn.fn)
+			if err != nil {
+				return nil, fmt.Errorf("func[%s.%s] %w", b.moduleName, name, err)
+			}
+			hf.ParamTypes, hf.ResultTypes, hf.Code.GoFunc = f.CoreType.Params, f.CoreType.Results, f
+		}
+	}
+
 	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, b.nameToHostFunc, b.r.enabledFeatures)
 	if err != nil {
 		return nil, err
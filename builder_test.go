@@ -350,6 +350,15 @@ func TestNewHostModuleBuilder_Compile_Errors(t *testing.T) {
 			},
 			expectedErr: `func[host.fn] param[0] is unsupported: string`,
 		},
+		{
+			name: "error compiling component func",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").NewFunctionBuilder().
+					WithComponentFunc(func(int) {}).
+					Export("fn")
+			},
+			expectedErr: `func[host.fn] param[0] is unsupported: int is not a type of the component model`,
+		},
 	}
 
 	for _, tt := range tests {
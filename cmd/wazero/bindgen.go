@@ -0,0 +1,778 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero/experimental/wit"
+)
+
+func doBindgen(args []string, stdOut, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("bindgen", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var worldName string
+	flags.StringVar(&worldName, "world", "",
+		"Name of the world to generate the bindings of. Defaults to the only world of the package.")
+
+	var pkgName string
+	flags.StringVar(&pkgName, "package", "bindings", "Name of the Go package of the bindings.")
+
+	var out string
+	flags.StringVar(&out, "o", "", "Writes the bindings at the given path instead of stdout.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printBindgenUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wit directory")
+		printBindgenUsage(stdErr, flags)
+		return 1
+	}
+
+	if !token.IsIdentifier(pkgName) {
+		fmt.Fprintf(stdErr, "invalid package name %q\n", pkgName)
+		return 1
+	}
+
+	witDir, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stdErr, "error resolving wit directory: %v\n", err)
+		return 1
+	}
+
+	pkgs, err := wit.ParseFS(os.DirFS(witDir), ".")
+	if err != nil {
+		fmt.Fprintf(stdErr, "error parsing wit directory: %v\n", err)
+		return 1
+	}
+
+	world, err := selectWorld(pkgs[len(pkgs)-1], worldName)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error selecting world: %v\n", err)
+		return 1
+	}
+
+	src, err := bindgen(world, pkgName)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error generating bindings: %v\n", err)
+		return 1
+	}
+
+	if out == "" {
+		_, _ = stdOut.Write(src)
+	} else if err = os.WriteFile(out, src, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing bindings: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// selectWorld returns the world of the name in the package, or its only world if name is empty.
+func selectWorld(pkg *wit.Package, name string) (*wit.World, error) {
+	if name != "" {
+		if w := pkg.World(name); w != nil {
+			return w, nil
+		}
+		return nil, fmt.Errorf("package %s has no world %q", pkg.Name, name)
+	}
+	switch len(pkg.Worlds) {
+	case 0:
+		return nil, fmt.Errorf("package %s has no world", pkg.Name)
+	case 1:
+		return pkg.Worlds[0], nil
+	}
+	names := make([]string, 0, len(pkg.Worlds))
+	for _, w := range pkg.Worlds {
+		names = append(names, w.Name)
+	}
+	return nil, fmt.Errorf("package %s has worlds %s: use -world to select one", pkg.Name, strings.Join(names, ", "))
+}
+
+const (
+	contextImport   = "context"
+	errorsImport    = "errors"
+	wazeroImport    = "github.com/tetratelabs/wazero"
+	apiImport       = "github.com/tetratelabs/wazero/api"
+	componentImport = "github.com/tetratelabs/wazero/experimental/component"
+)
+
+// generator generates the Go bindings of a world.
+type generator struct {
+	world *wit.World
+	b     bytes.Buffer
+	// imports are the Go packages used by the bindings.
+	imports map[string]bool
+	// names are the top-level Go names declared.
+	names map[string]bool
+	// defs are the types defined, in the order of their declaration.
+	defs     []*wit.TypeDef
+	defNames map[*wit.TypeDef]string
+}
+
+// bindgen returns the Go source of the bindings of the world in the package pkgName:
+//
+//   - Each type defined is a Go type, as documented on the package component of wazero.
+//   - Each interface imported is a Go interface implemented by the host, whose functions are exported to a
+//     wazero.HostModuleBuilder, and the resources of which are Go interfaces implemented by their representations.
+//   - Each interface exported is a Go struct which calls the functions exported by a component with component.Call.
+//
+// The functions imported and exported by the world itself are grouped in the same way.
+func bindgen(world *wit.World, pkgName string) ([]byte, error) {
+	g := &generator{world: world, imports: map[string]bool{}, names: map[string]bool{}, defNames: map[*wit.TypeDef]string{}}
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by wazero bindgen from the world %s. DO NOT EDIT.\n\n", worldName(world))
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", pkgName)
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		if si, sj := strings.Contains(imports[i], "."), strings.Contains(imports[j], "."); si != sj {
+			return sj
+		}
+		return imports[i] < imports[j]
+	})
+	for i, imp := range imports {
+		// The standard packages are grouped before the ones of wazero.
+		if i > 0 && !strings.Contains(imports[i-1], ".") && strings.Contains(imp, ".") {
+			src.WriteString("\n")
+		}
+		if imp == componentImport {
+			fmt.Fprintf(&src, "\tcm %q\n", imp)
+		} else {
+			fmt.Fprintf(&src, "\t%q\n", imp)
+		}
+	}
+	src.WriteString(")\n")
+	src.Write(g.b.Bytes())
+
+	ret, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("BUG: invalid Go source: %w", err)
+	}
+	return ret, nil
+}
+
+func (g *generator) generate() error {
+	// The types are declared first, so that they have precedence over the names derived from the interfaces.
+	for _, def := range g.world.Types {
+		g.typeName(def)
+	}
+	var imports, exports []*wit.WorldItem
+	var importFuncs, exportFuncs []*wit.Func
+	for _, item := range g.world.Imports {
+		if item.Interface == nil {
+			importFuncs = append(importFuncs, item.Func)
+			continue
+		}
+		imports = append(imports, item)
+		for _, def := range item.Interface.Types {
+			g.typeName(def)
+		}
+	}
+	for _, item := range g.world.Exports {
+		if item.Interface == nil {
+			exportFuncs = append(exportFuncs, item.Func)
+			continue
+		}
+		for _, def := range item.Interface.Types {
+			if def.Type.Kind == wit.TypeKindResource {
+				return fmt.Errorf("export %q: resources exported by a component are not supported", item.Name)
+			}
+		}
+		exports = append(exports, item)
+		for _, def := range item.Interface.Types {
+			g.typeName(def)
+		}
+	}
+
+	var names []string
+	for _, item := range imports {
+		names = append(names, g.declare(item.Interface, "", importAffixes))
+	}
+	for _, item := range exports {
+		names = append(names, g.declare(item.Interface, "", exportAffixes))
+	}
+	var importsName, exportsName string
+	if len(importFuncs) > 0 {
+		importsName = g.declare(nil, goName(g.world.Name)+"Imports", importAffixes)
+	}
+	if len(exportFuncs) > 0 {
+		exportsName = g.declare(nil, goName(g.world.Name)+"Exports", exportAffixes)
+	}
+
+	// The types are generated first, which can declare the types of other interfaces they refer to.
+	for i := 0; i < len(g.defs); i++ {
+		if err := g.typeDef(g.defs[i]); err != nil {
+			return err
+		}
+	}
+	for i, item := range imports {
+		desc := fmt.Sprintf("the interface %q imported by the world %s", item.Name, worldName(g.world))
+		if err := g.hostInterface(names[i], desc, item.Interface.Docs, item.Name, item.Interface.Funcs); err != nil {
+			return err
+		}
+	}
+	if importsName != "" {
+		desc := fmt.Sprintf("the functions imported by the world %s", worldName(g.world))
+		if err := g.hostInterface(importsName, desc, "", "$root", importFuncs); err != nil {
+			return err
+		}
+	}
+	for i, item := range exports {
+		desc := fmt.Sprintf("the functions of the interface %q exported by the world %s", item.Name, worldName(g.world))
+		if err := g.guestExports(names[len(imports)+i], desc, item.Interface.Docs, item.Name+"#", item.Interface.Funcs); err != nil {
+			return err
+		}
+	}
+	if exportsName != "" {
+		desc := fmt.Sprintf("the functions exported by the world %s", worldName(g.world))
+		if err := g.guestExports(exportsName, desc, "", "", exportFuncs); err != nil {
+			return err
+		}
+	}
+	if len(exports) > 0 || exportsName != "" {
+		g.use(apiImport)
+		g.use(errorsImport)
+		g.printf(`
+// exportedFunction returns the function of the name exported by the instance of a component.
+func exportedFunction(mod api.Module, name string) (api.Function, error) {
+	if fn := mod.ExportedFunction(name); fn != nil {
+		return fn, nil
+	}
+	return nil, errors.New("function " + strconv.Quote(name) + " is not exported by " + mod.Name())
+}
+`)
+		g.use("strconv")
+	}
+	return nil
+}
+
+// importAffixes and exportAffixes are the prefixes and the suffixes of the names derived from the name of an
+// interface.
+var (
+	importAffixes = [][2]string{{"", ""}, {"", "Name"}, {"Export", ""}, {"Instantiate", ""}}
+	exportAffixes = [][2]string{{"", ""}, {"New", ""}}
+)
+
+// declare declares the name of the interface, or else the name given, with the affixes, and returns it. If the
+// name is already declared, it's prefixed by the name of the package of the interface, or else suffixed by a
+// number.
+func (g *generator) declare(iface *wit.Interface, name string, affixes [][2]string) string {
+	var candidates []string
+	if iface != nil {
+		name = goName(iface.Name)
+		candidates = append(candidates, name)
+		if iface.Package != nil {
+			candidates = append(candidates, goName(iface.Package.Name.Name)+name)
+		}
+	} else {
+		candidates = append(candidates, name)
+	}
+	for i := 2; ; i++ {
+		for _, c := range candidates {
+			if g.free(c, affixes) {
+				for _, a := range affixes {
+					g.names[a[0]+c+a[1]] = true
+				}
+				return c
+			}
+		}
+		candidates = []string{name + strconv.Itoa(i)}
+	}
+}
+
+func (g *generator) free(name string, affixes [][2]string) bool {
+	for _, a := range affixes {
+		if g.names[a[0]+name+a[1]] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *generator) use(imp string) {
+	g.imports[imp] = true
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format, args...)
+}
+
+// docs prints the comment of the description and the documentation, if any.
+func (g *generator) docs(desc, docs string) {
+	if desc != "" {
+		g.printf("// %s\n", desc)
+	}
+	if docs == "" {
+		return
+	}
+	if desc != "" {
+		g.printf("//\n")
+	}
+	for _, line := range strings.Split(docs, "\n") {
+		g.printf("// %s\n", strings.TrimRight(line, " "))
+	}
+}
+
+// typeName returns the Go name of the type defined, which is declared if it's not yet. The names of the aliases are
+// the ones of the types they refer to.
+func (g *generator) typeName(def *wit.TypeDef) string {
+	for def.Type.Kind == wit.TypeKindRef {
+		def = def.Type.Def
+	}
+	if name, ok := g.defNames[def]; ok {
+		return name
+	}
+	name := goName(def.Name)
+	if g.names[name] && def.Interface != nil {
+		name = goName(def.Interface.Name) + name
+	}
+	for i := 2; g.names[name]; i++ {
+		name = goName(def.Name) + strconv.Itoa(i)
+	}
+	g.names[name] = true
+	g.defNames[def] = name
+	g.defs = append(g.defs, def)
+	return name
+}
+
+// qualifiedName returns the name of the type defined in WIT, e.g. "wasi:io/streams@0.2.0#input-stream".
+func qualifiedName(def *wit.TypeDef) string {
+	if def.Interface == nil {
+		return def.Name
+	}
+	return def.Interface.QualifiedName() + "#" + def.Name
+}
+
+func worldName(w *wit.World) string {
+	return w.Package.Name.String() + "/" + w.Name
+}
+
+// typeDef generates the Go type of the type defined.
+func (g *generator) typeDef(def *wit.TypeDef) error {
+	name := g.defNames[def]
+	t := def.Type
+	var kind string
+	switch t.Kind {
+	case wit.TypeKindRecord, wit.TypeKindVariant, wit.TypeKindEnum, wit.TypeKindFlags, wit.TypeKindResource:
+		kind = [...]string{
+			wit.TypeKindRecord:   "record",
+			wit.TypeKindVariant:  "variant",
+			wit.TypeKindEnum:     "enum",
+			wit.TypeKindFlags:    "flags",
+			wit.TypeKindResource: "resource",
+		}[t.Kind]
+	default:
+		kind = "type"
+	}
+	g.printf("\n")
+	g.docs(fmt.Sprintf("%s is the %s %q.", name, kind, qualifiedName(def)), def.Docs)
+
+	switch t.Kind {
+	case wit.TypeKindRecord, wit.TypeKindVariant:
+		g.printf("type %s struct {\n", name)
+		if t.Kind == wit.TypeKindVariant {
+			g.use(componentImport)
+			g.printf("cm.Variant\n")
+		}
+		for _, f := range t.Fields {
+			typ := "struct{}"
+			if f.Type != nil {
+				var err error
+				if typ, err = g.goType(f.Type); err != nil {
+					return fmt.Errorf("%s: %w", qualifiedName(def), err)
+				}
+			}
+			if t.Kind == wit.TypeKindVariant {
+				typ = "*" + typ
+			}
+			g.docs("", f.Docs)
+			g.printf("%s %s `wit:%q`\n", goName(f.Name), typ, f.Name)
+		}
+		g.printf("}\n")
+	case wit.TypeKindEnum, wit.TypeKindFlags:
+		return g.labels(name, def)
+	case wit.TypeKindResource:
+		return g.resource(name, def)
+	default:
+		typ, err := g.goType(t)
+		if err != nil {
+			return fmt.Errorf("%s: %w", qualifiedName(def), err)
+		}
+		g.printf("type %s = %s\n", name, typ)
+	}
+	return nil
+}
+
+// labels generates the unsigned integer type of an enum or flags, and the constants of their labels.
+func (g *generator) labels(name string, def *wit.TypeDef) error {
+	labels := def.Type.Labels
+	bits := 8
+	if def.Type.Kind == wit.TypeKindFlags {
+		if len(labels) > 64 {
+			return fmt.Errorf("%s: more than 64 flags are not supported", qualifiedName(def))
+		}
+		for bits < len(labels) {
+			bits *= 2
+		}
+	} else {
+		for bits < 64 && len(labels) > 1<<bits {
+			bits *= 2
+		}
+	}
+	g.printf("type %s uint%d\n\nconst (\n", name, bits)
+	for i, l := range labels {
+		switch {
+		case i > 0:
+			g.printf("%s%s\n", name, goName(l))
+		case def.Type.Kind == wit.TypeKindEnum:
+			g.printf("%s%s %s = iota\n", name, goName(l), name)
+		default:
+			g.printf("%s%s %s = 1 << iota\n", name, goName(l), name)
+		}
+		g.names[name+goName(l)] = true
+	}
+	g.printf(")\n\n")
+
+	method, iface := "Cases", "Enum"
+	if def.Type.Kind == wit.TypeKindFlags {
+		method, iface = "Flags", "Flags"
+	}
+	quoted := make([]string, len(labels))
+	for i, l := range labels {
+		quoted[i] = strconv.Quote(l)
+	}
+	g.printf("// %s implements component.%s\n", method, iface)
+	g.printf("func (%s) %s() []string {\nreturn []string{%s}\n}\n", name, method, strings.Join(quoted, ", "))
+	return nil
+}
+
+// resource generates the Go interface of the representations of a resource, whose methods are the ones of the
+// resource.
+func (g *generator) resource(name string, def *wit.TypeDef) error {
+	g.printf("//\n// The representations of the handles given to a component which implement api.Closer are closed when\n")
+	g.printf("// they are dropped.\n")
+	g.printf("type %s interface {\n", name)
+	if def.Interface != nil {
+		for _, f := range def.Interface.Funcs {
+			if f.Kind != wit.FuncKindMethod || f.Resource != def {
+				continue
+			}
+			sig, err := g.signature(f.Params[1:], f.Result)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.ExternName(), err)
+			}
+			g.docs("", f.Docs)
+			g.printf("%s%s\n", goName(f.Name), sig)
+		}
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// goType returns the Go type of the type, whose types defined are the ones of their Go name.
+func (g *generator) goType(t *wit.Type) (string, error) {
+	if t == nil {
+		return "struct{}", nil
+	}
+	switch t.Kind {
+	case wit.TypeKindBool:
+		return "bool", nil
+	case wit.TypeKindS8:
+		return "int8", nil
+	case wit.TypeKindU8:
+		return "uint8", nil
+	case wit.TypeKindS16:
+		return "int16", nil
+	case wit.TypeKindU16:
+		return "uint16", nil
+	case wit.TypeKindS32:
+		return "int32", nil
+	case wit.TypeKindU32:
+		return "uint32", nil
+	case wit.TypeKindS64:
+		return "int64", nil
+	case wit.TypeKindU64:
+		return "uint64", nil
+	case wit.TypeKindF32:
+		return "float32", nil
+	case wit.TypeKindF64:
+		return "float64", nil
+	case wit.TypeKindChar:
+		g.use(componentImport)
+		return "cm.Char", nil
+	case wit.TypeKindString:
+		return "string", nil
+	case wit.TypeKindRef:
+		return g.typeName(t.Def), nil
+	case wit.TypeKindOwn, wit.TypeKindBorrow:
+		g.use(componentImport)
+		if t.Kind == wit.TypeKindOwn {
+			return "cm.Own[" + g.typeName(t.Def) + "]", nil
+		}
+		return "cm.Borrow[" + g.typeName(t.Def) + "]", nil
+	case wit.TypeKindList:
+		elem, err := g.goType(t.Elem)
+		if err != nil {
+			return "", err
+		} else if t.Length > 0 {
+			return fmt.Sprintf("[%d]%s", t.Length, elem), nil
+		}
+		return "[]" + elem, nil
+	case wit.TypeKindOption:
+		elem, err := g.goType(t.Elem)
+		g.use(componentImport)
+		return "cm.Option[" + elem + "]", err
+	case wit.TypeKindResult:
+		ok, err := g.goType(t.Elem)
+		if err != nil {
+			return "", err
+		}
+		e, err := g.goType(t.Err)
+		g.use(componentImport)
+		return "cm.Result[" + ok + ", " + e + "]", err
+	case wit.TypeKindTuple:
+		g.use(componentImport)
+		var b strings.Builder
+		b.WriteString("struct {\ncm.Tuple\n")
+		for i, f := range t.Fields {
+			elem, err := g.goType(f.Type)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "F%d %s\n", i, elem)
+		}
+		b.WriteString("}")
+		return b.String(), nil
+	}
+	// The other types are only defined by a TypeDef.
+	return "", fmt.Errorf("BUG: anonymous %s", t)
+}
+
+// signature returns the Go signature of a function, after its name, whose first param is the context.
+func (g *generator) signature(params []wit.Field, result *wit.Type) (string, error) {
+	g.use(contextImport)
+	var b strings.Builder
+	b.WriteString("(ctx context.Context")
+	for _, p := range params {
+		typ, err := g.goType(p.Type)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, ", %s %s", paramName(p.Name), typ)
+	}
+	b.WriteString(")")
+	if result != nil {
+		typ, err := g.goType(result)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(" " + typ)
+	}
+	return b.String(), nil
+}
+
+// methodName returns the name of the method of the Go interface of the host, which implements the function f.
+func (g *generator) methodName(f *wit.Func) string {
+	switch f.Kind {
+	case wit.FuncKindConstructor:
+		return "New" + g.typeName(f.Resource)
+	case wit.FuncKindStatic:
+		return g.typeName(f.Resource) + goName(f.Name)
+	}
+	return goName(f.Name)
+}
+
+// hostInterface generates the Go interface of the functions implemented by the host, and the functions which export
+// them to a host module of the name moduleName.
+func (g *generator) hostInterface(name, desc, docs, moduleName string, funcs []*wit.Func) error {
+	g.use(contextImport)
+	g.use(wazeroImport)
+	g.use(apiImport)
+
+	g.printf("\n")
+	g.docs(fmt.Sprintf("%s implements %s.", name, desc), docs)
+	for _, f := range funcs {
+		if f.Kind == wit.FuncKindMethod {
+			g.printf("//\n// The methods of the resources are implemented by their representations.\n")
+			break
+		}
+	}
+	g.printf("type %s interface {\n", name)
+	for _, f := range funcs {
+		if f.Kind == wit.FuncKindMethod {
+			continue
+		}
+		sig, err := g.signature(f.Params, f.Result)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.ExternName(), err)
+		}
+		switch f.Kind {
+		case wit.FuncKindConstructor:
+			g.docs(fmt.Sprintf("%s is the constructor of %s.", g.methodName(f), g.typeName(f.Resource)), f.Docs)
+		case wit.FuncKindStatic:
+			g.docs(fmt.Sprintf("%s is the static function %q of %s.", g.methodName(f), f.Name, g.typeName(f.Resource)), f.Docs)
+		default:
+			g.docs("", f.Docs)
+		}
+		g.printf("%s%s\n", g.methodName(f), sig)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %sName is the name of the host module which exports the functions of %s.\n", name, name)
+	g.printf("const %sName = %q\n\n", name, moduleName)
+
+	g.printf("// Export%s exports the functions of %s implemented by impl to the builder of the host module named %sName.\n",
+		name, name, name)
+	g.printf("func Export%s(builder wazero.HostModuleBuilder, impl %s) wazero.HostModuleBuilder {\n", name, name)
+	for _, f := range funcs {
+		sig, err := g.signature(f.Params, f.Result)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.ExternName(), err)
+		}
+		args := []string{"ctx"}
+		for _, p := range f.Params {
+			args = append(args, paramName(p.Name))
+		}
+		call := "impl." + g.methodName(f) + "(" + strings.Join(args, ", ") + ")"
+		if f.Kind == wit.FuncKindMethod {
+			call = args[1] + ".Rep." + goName(f.Name) + "(" + strings.Join(append(args[:1:1], args[2:]...), ", ") + ")"
+		}
+		if f.Result != nil {
+			call = "return " + call
+		}
+		g.printf("builder.NewFunctionBuilder().\nWithComponentFunc(func%s {\n%s\n}).\nExport(%q)\n", sig, call, f.ExternName())
+	}
+	g.printf("return builder\n}\n\n")
+
+	g.printf("// Instantiate%s instantiates the host module named %sName, which exports the functions of %s implemented by\n",
+		name, name, name)
+	g.printf("// impl. It must be instantiated before the components which import it.\n")
+	g.printf("func Instantiate%s(ctx context.Context, r wazero.Runtime, impl %s) (api.Module, error) {\n", name, name)
+	g.printf("return Export%s(r.NewHostModuleBuilder(%sName), impl).Instantiate(ctx)\n}\n", name, name)
+	return nil
+}
+
+// guestExports generates the Go struct which calls the functions exported by a component, whose names are prefixed.
+func (g *generator) guestExports(name, desc, docs, prefix string, funcs []*wit.Func) error {
+	g.use(contextImport)
+	g.use(apiImport)
+	g.use(componentImport)
+
+	fields := make([]string, len(funcs))
+	for i, f := range funcs {
+		if fields[i] = lowerName(f.Name); token.IsKeyword(fields[i]) {
+			fields[i] += "_"
+		}
+	}
+
+	g.printf("\n")
+	g.docs(fmt.Sprintf("%s calls %s.", name, desc), docs)
+	g.printf("type %s struct {\n", name)
+	for _, field := range fields {
+		g.printf("%s api.Function\n", field)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// New%s returns the %s of the functions exported by the instance of a component.\n", name, name)
+	g.printf("func New%s(mod api.Module) (ret *%s, err error) {\nret = &%s{}\n", name, name, name)
+	for i, f := range funcs {
+		g.printf("if ret.%s, err = exportedFunction(mod, %q); err != nil {\nreturn nil, err\n}\n", fields[i], prefix+f.Name)
+	}
+	g.printf("return ret, nil\n}\n")
+
+	for i, f := range funcs {
+		args := []string{"ctx", "x." + fields[i], "nil"}
+		params := []string{"ctx context.Context"}
+		for _, p := range f.Params {
+			typ, err := g.goType(p.Type)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			params = append(params, paramName(p.Name)+" "+typ)
+			args = append(args, paramName(p.Name))
+		}
+		results := "error"
+		if f.Result != nil {
+			typ, err := g.goType(f.Result)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			results = "(ret " + typ + ", err error)"
+			args[2] = "&ret"
+		}
+		g.printf("\n")
+		g.docs(fmt.Sprintf("%s calls the function %q.", goName(f.Name), prefix+f.Name), f.Docs)
+		g.printf("func (x *%s) %s(%s) %s {\n", name, goName(f.Name), strings.Join(params, ", "), results)
+		if f.Result != nil {
+			g.printf("err = cm.Call(%s)\nreturn\n}\n", strings.Join(args, ", "))
+		} else {
+			g.printf("return cm.Call(%s)\n}\n", strings.Join(args, ", "))
+		}
+	}
+	return nil
+}
+
+// goName returns the exported Go name of the kebab case identifier, e.g. "InputStream" for "input-stream".
+func goName(id string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(id, "-") {
+		if word == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]))
+		b.WriteString(word[1:])
+	}
+	return b.String()
+}
+
+// lowerName returns the unexported Go name of the kebab case identifier, e.g. "inputStream" for "input-stream".
+func lowerName(id string) string {
+	word, rest, _ := strings.Cut(id, "-")
+	return strings.ToLower(word) + goName(rest)
+}
+
+// paramName returns the Go name of a param, which doesn't conflict with the keywords of Go and the names used by the
+// generated functions.
+func paramName(id string) string {
+	ret := lowerName(id)
+	switch ret {
+	case "ctx", "impl", "builder", "r", "x", "ret", "err", "mod", "cm", "wazero", "api", "context":
+		return ret + "_"
+	}
+	if token.IsKeyword(ret) {
+		return ret + "_"
+	}
+	return ret
+}
+
+func printBindgenUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero bindgen <options> <path to wit directory>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
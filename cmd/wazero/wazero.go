@@ -53,6 +53,8 @@ func doMain(stdOut io.Writer, stdErr logging.Writer) int {
 
 	subCmd := flag.Arg(0)
 	switch subCmd {
+	case "bindgen":
+		return doBindgen(flag.Args()[1:], stdOut, stdErr)
 	case "compile":
 		return doCompile(flag.Args()[1:], stdErr)
 	case "run":
@@ -508,6 +510,7 @@ func printUsage(stdErr io.Writer) {
 	fmt.Fprintln(stdErr, "Usage:\n  wazero <command>")
 	fmt.Fprintln(stdErr)
 	fmt.Fprintln(stdErr, "Commands:")
+	fmt.Fprintln(stdErr, "  bindgen\tGenerates the Go bindings of a WIT world")
 	fmt.Fprintln(stdErr, "  compile\tPre-compiles a WebAssembly binary")
 	fmt.Fprintln(stdErr, "  run\t\tRuns a WebAssembly binary")
 	fmt.Fprintln(stdErr, "  version\tDisplays the version of wazero CLI")
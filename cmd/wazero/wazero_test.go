@@ -199,6 +199,65 @@ func TestCompile_Errors(t *testing.T) {
 	}
 }
 
+func TestBindgen(t *testing.T) {
+	witDir := filepath.Join("testdata", "bindgen")
+	expected, err := os.ReadFile(filepath.Join(witDir, "bindings.go"))
+	require.NoError(t, err)
+
+	t.Run("stdout", func(t *testing.T) {
+		exitCode, stdout, stderr := runMain(t, "", []string{"bindgen", "-package", "bindings", witDir})
+		require.Equal(t, 0, exitCode, stderr)
+		require.Equal(t, string(expected), stdout)
+	})
+
+	t.Run("output file", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "bindings.go")
+		exitCode, stdout, stderr := runMain(t, "", []string{"bindgen", "-world", "host", "-o", out, witDir})
+		require.Equal(t, 0, exitCode, stderr)
+		require.Equal(t, "", stdout)
+
+		actual, err := os.ReadFile(out)
+		require.NoError(t, err)
+		require.Equal(t, string(expected), string(actual))
+	})
+}
+
+func TestBindgen_Errors(t *testing.T) {
+	witDir := filepath.Join("testdata", "bindgen")
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wit directory",
+			args:    []string{},
+		},
+		{
+			message: "invalid package name",
+			args:    []string{"-package", "not-a-name", witDir},
+		},
+		{
+			message: "error parsing wit directory",
+			args:    []string{filepath.Join("testdata", "fs")},
+		},
+		{
+			message: "package test:host@1.0.0 has no world \"guest\"",
+			args:    []string{"-world", "guest", witDir},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"bindgen"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
 func TestRun(t *testing.T) {
 	tmpDir, oldwd := requireChdirToTemp(t)
 	defer os.Chdir(oldwd) //nolint
@@ -686,6 +745,7 @@ Usage:
   wazero <command>
 
 Commands:
+  bindgen	Generates the Go bindings of a WIT world
   compile	Pre-compiles a WebAssembly binary
   run		Runs a WebAssembly binary
   version	Displays the version of wazero CLI
@@ -3,15 +3,14 @@ package wazero
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"io/fs"
 	"math"
-	"net"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/component"
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/platform"
@@ -54,14 +53,19 @@ type RuntimeConfig interface {
 
 	// WithMemoryLimitPages overrides the maximum pages allowed per memory. The
 	// default is 65536, allowing 4GB total memory per instance if the maximum is
-	// not encoded in a Wasm binary. Setting a value larger than default will panic.
+	// not encoded in a Wasm binary.
 	//
 	// This example reduces the largest possible memory size from 4GB to 128KB:
 	//	rConfig = wazero.NewRuntimeConfig().WithMemoryLimitPages(2)
 	//
 	// Note: Wasm has 32-bit memory and each page is 65536 (2^16) bytes. This
-	// implies a max of 65536 (2^16) addressable pages.
+	// implies a max of 65536 (2^16) addressable pages. A value larger than that
+	// only applies to 64-bit memories of experimental.CoreFeaturesMemory64.
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
+	//
+	// The limit is always in 64KB pages, even for a memory declaring a custom
+	// page size with experimental.CoreFeaturesCustomPageSizes. e.g. a limit of
+	// 2 allows 131072 pages of 1 byte.
 	WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig
 
 	// WithMemoryCapacityFromMax eagerly allocates max memory, unless max is
@@ -168,6 +172,25 @@ type RuntimeConfig interface {
 	// When the invocations of api.Function are closed due to this, sys.ExitError is raised to the callers and
 	// the api.Module from which the functions are derived is made closed.
 	WithCloseOnContextDone(bool) RuntimeConfig
+
+	// WithDeterministicRelaxedSIMD forces the relaxed vector instructions of
+	// experimental.CoreFeaturesRelaxedSIMD to produce the same results on all
+	// platforms. Defaults to false.
+	//
+	// By default, the compiler lowers some of them to the native instructions
+	// of the CPU, e.g. f32x4.relaxed_madd to a fused multiply-add, so that the
+	// results can differ between amd64 and arm64, or between CPUs. When
+	// enabled, they behave as their deterministic counterparts:
+	//
+	//   - relaxed_swizzle, relaxed_trunc, relaxed_laneselect, relaxed_min,
+	//     relaxed_max and relaxed_q15mulr behave as swizzle, trunc_sat,
+	//     bitselect, min, max and q15mulr_sat respectively.
+	//   - relaxed_madd and relaxed_nmadd round the product before the addition.
+	//   - relaxed_dot treats both operands as signed, and saturates the sums of
+	//     the adjacent products to 16-bit.
+	//
+	// The interpreter always behaves this way.
+	WithDeterministicRelaxedSIMD(bool) RuntimeConfig
 }
 
 // NewRuntimeConfig returns a RuntimeConfig using the compiler if it is supported in this environment,
@@ -181,15 +204,16 @@ func NewRuntimeConfig() RuntimeConfig {
 type newEngine func(context.Context, api.CoreFeatures, filecache.Cache) wasm.Engine
 
 type runtimeConfig struct {
-	enabledFeatures       api.CoreFeatures
-	memoryLimitPages      uint32
-	memoryCapacityFromMax bool
-	engineKind            engineKind
-	dwarfDisabled         bool // negative as defaults to enabled
-	newEngine             newEngine
-	cache                 CompilationCache
-	storeCustomSections   bool
-	ensureTermination     bool
+	enabledFeatures          api.CoreFeatures
+	memoryLimitPages         uint32
+	memoryCapacityFromMax    bool
+	engineKind               engineKind
+	dwarfDisabled            bool // negative as defaults to enabled
+	newEngine                newEngine
+	cache                    CompilationCache
+	storeCustomSections      bool
+	ensureTermination        bool
+	deterministicRelaxedSIMD bool
 }
 
 // engineLessConfig helps avoid copy/pasting the wrong defaults.
@@ -265,13 +289,16 @@ func (c *runtimeConfig) WithCloseOnContextDone(ensure bool) RuntimeConfig {
 	return ret
 }
 
+// WithDeterministicRelaxedSIMD implements RuntimeConfig.WithDeterministicRelaxedSIMD
+func (c *runtimeConfig) WithDeterministicRelaxedSIMD(deterministic bool) RuntimeConfig {
+	ret := c.clone()
+	ret.deterministicRelaxedSIMD = deterministic
+	return ret
+}
+
 // WithMemoryLimitPages implements RuntimeConfig.WithMemoryLimitPages
 func (c *runtimeConfig) WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig {
 	ret := c.clone()
-	// This panics instead of returning an error as it is unlikely.
-	if memoryLimitPages > wasm.MemoryLimitPages {
-		panic(fmt.Errorf("memoryLimitPages invalid: %d > %d", memoryLimitPages, wasm.MemoryLimitPages))
-	}
 	ret.memoryLimitPages = memoryLimitPages
 	return ret
 }
@@ -367,6 +394,8 @@ type compiledModule struct {
 	// closeWithModule prevents leaking compiled code when a module is compiled implicitly.
 	closeWithModule bool
 	typeIDs         []wasm.FunctionTypeID
+	// component is the compiled component if the binary was one, in which case module is empty.
+	component *component.Compiled
 }
 
 // Name implements CompiledModule.Name
@@ -379,6 +408,10 @@ func (c *compiledModule) Name() (moduleName string) {
 
 // Close implements CompiledModule.Close
 func (c *compiledModule) Close(context.Context) error {
+	if c.component != nil {
+		c.component.Close(c.compiledEngine)
+		return nil
+	}
 	c.compiledEngine.DeleteCompiledModule(c.module)
 	// It is possible the underlying may need to return an error later, but in any case this matches api.Module.Close.
 	return nil
@@ -391,6 +424,9 @@ func (c *compiledModule) ImportedFunctions() []api.FunctionDefinition {
 
 // ExportedFunctions implements CompiledModule.ExportedFunctions
 func (c *compiledModule) ExportedFunctions() map[string]api.FunctionDefinition {
+	if c.component != nil {
+		return c.component.ExportedFunctions()
+	}
 	return c.module.ExportedFunctions()
 }
 
@@ -843,7 +879,7 @@ func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 }
 
 // toSysContext creates a baseline wasm.Context configured by ModuleConfig.
-func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
+func (c *moduleConfig) toSysContext(ctx context.Context) (sysCtx *internalsys.Context, err error) {
 	var environ [][]byte // Intentionally doesn't pre-allocate to reduce logic to default to nil.
 	// Same validation as syscall.Setenv for Linux
 	for i := 0; i < len(c.environ); i += 2 {
@@ -871,13 +907,14 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 
 	var fs []experimentalsys.FS
 	var guestPaths []string
+	var rights []*internalsys.Rights
 	if f, ok := c.fsConfig.(*fsConfig); ok {
-		fs, guestPaths = f.preopens()
+		fs, guestPaths, rights = f.preopens()
 	}
 
-	var listeners []*net.TCPListener
+	var sockets []io.Closer
 	if n := c.sockConfig; n != nil {
-		if listeners, err = n.BuildTCPListeners(); err != nil {
+		if sockets, err = n.BuildSockets(ctx); err != nil {
 			return
 		}
 	}
@@ -893,7 +930,8 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		c.walltime, c.walltimeResolution,
 		c.nanotime, c.nanotimeResolution,
 		c.nanosleep, c.osyield,
-		fs, guestPaths,
-		listeners,
+		fs, guestPaths, rights,
+		sockets,
+		c.sockConfig,
 	)
 }
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/fstest"
 	"github.com/tetratelabs/wazero/internal/platform"
+	internalsock "github.com/tetratelabs/wazero/internal/sock"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -40,6 +43,15 @@ func TestRuntimeConfig(t *testing.T) {
 				memoryLimitPages: 10,
 			},
 		},
+		{
+			name: "memoryLimitPages over 4GB for memory64",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMemoryLimitPages(wasm.MemoryLimitPages + 1)
+			},
+			expected: &runtimeConfig{
+				memoryLimitPages: wasm.MemoryLimitPages + 1,
+			},
+		},
 		{
 			name: "memoryCapacityFromMax",
 			with: func(c RuntimeConfig) RuntimeConfig {
@@ -85,14 +97,6 @@ func TestRuntimeConfig(t *testing.T) {
 			require.Equal(t, &runtimeConfig{}, input)
 		})
 	}
-
-	t.Run("memoryLimitPages invalid panics", func(t *testing.T) {
-		err := require.CapturePanic(func() {
-			input := &runtimeConfig{}
-			input.WithMemoryLimitPages(wasm.MemoryLimitPages + 1)
-		})
-		require.EqualError(t, err, "memoryLimitPages invalid: 65537 > 65536")
-	})
 }
 
 func TestModuleConfig(t *testing.T) {
@@ -344,13 +348,31 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			config, verify := tc.input()
-			actual, err := config.(*moduleConfig).toSysContext()
+			actual, err := config.(*moduleConfig).toSysContext(testCtx)
 			require.NoError(t, err)
 			verify(t, actual)
 		})
 	}
 }
 
+// TestModuleConfig_toSysContext_ConnectionCanceled ensures connections to
+// pre-open are dialed with the context of instantiation.
+func TestModuleConfig_toSysContext_ConnectionCanceled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	config := NewModuleConfig().(*moduleConfig)
+	config.sockConfig = &internalsock.Config{
+		Connections: []internalsock.Connection{{Network: "tcp", Address: ln.Addr().String()}},
+	}
+
+	ctx, cancel := context.WithCancel(testCtx)
+	cancel()
+	_, err = config.toSysContext(ctx)
+	require.True(t, errors.Is(err, context.Canceled), "%v", err)
+}
+
 // TestModuleConfig_toSysContext_WithWalltime has to test differently because we can't
 // compare function pointers when functions are passed by value.
 func TestModuleConfig_toSysContext_WithWalltime(t *testing.T) {
@@ -399,7 +421,7 @@ func TestModuleConfig_toSysContext_WithWalltime(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			sysCtx, err := tc.input.(*moduleConfig).toSysContext()
+			sysCtx, err := tc.input.(*moduleConfig).toSysContext(testCtx)
 			if tc.expectedErr == "" {
 				require.Nil(t, err)
 				sec, nsec := sysCtx.Walltime()
@@ -416,7 +438,7 @@ func TestModuleConfig_toSysContext_WithWalltime(t *testing.T) {
 		sysCtx, err := NewModuleConfig().
 			WithWalltime(func() (sec int64, nsec int32) {
 				return 1, 2
-			}, 3).(*moduleConfig).toSysContext()
+			}, 3).(*moduleConfig).toSysContext(testCtx)
 		require.NoError(t, err)
 		sec, nsec := sysCtx.Walltime()
 		// If below pass, the context was correct!
@@ -470,7 +492,7 @@ func TestModuleConfig_toSysContext_WithNanotime(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			sysCtx, err := tc.input.(*moduleConfig).toSysContext()
+			sysCtx, err := tc.input.(*moduleConfig).toSysContext(testCtx)
 			if tc.expectedErr == "" {
 				require.Nil(t, err)
 				nanos := sysCtx.Nanotime()
@@ -489,7 +511,7 @@ func TestModuleConfig_toSysContext_WithNanosleep(t *testing.T) {
 	sysCtx, err := NewModuleConfig().
 		WithNanosleep(func(ns int64) {
 			require.Equal(t, int64(2), ns)
-		}).(*moduleConfig).toSysContext()
+		}).(*moduleConfig).toSysContext(testCtx)
 	require.NoError(t, err)
 	sysCtx.Nanosleep(2)
 }
@@ -501,7 +523,7 @@ func TestModuleConfig_toSysContext_WithOsyield(t *testing.T) {
 	sysCtx, err := NewModuleConfig().
 		WithOsyield(func() {
 			yielded = true
-		}).(*moduleConfig).toSysContext()
+		}).(*moduleConfig).toSysContext(testCtx)
 	require.NoError(t, err)
 	sysCtx.Osyield()
 	require.True(t, yielded)
@@ -543,7 +565,7 @@ func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := tc.input.(*moduleConfig).toSysContext()
+			_, err := tc.input.(*moduleConfig).toSysContext(testCtx)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -0,0 +1,34 @@
+package component
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// valuesCaller is implemented by the functions exported by a component.
+type valuesCaller interface {
+	CallValues(ctx context.Context, result interface{}, params ...interface{}) error
+}
+
+// Call calls fn, a function exported by a component, with the Go values of its params, and lifts its result into the
+// Go value which result points to, or nil if it has none.
+//
+// The values are lowered into the memory of the component with its realloc function, as per the canonical ABI, so
+// their Go types must represent the types of the function, as documented in this package. The handles of an Own
+// param are given to the component, and those of an Own result are taken from it.
+//
+// For example, this calls a function of type `func(name: string) -> result<u32, string>`:
+//
+//	var res component.Result[uint32, string]
+//	err := component.Call(ctx, mod.ExportedFunction("count"), &res, "wazero")
+//
+// Note: fn must be returned by the api.Module of an instantiated component.
+func Call(ctx context.Context, fn api.Function, result interface{}, params ...interface{}) error {
+	c, ok := fn.(valuesCaller)
+	if !ok {
+		return errors.New("not a function exported by a component")
+	}
+	return c.CallValues(ctx, result, params...)
+}
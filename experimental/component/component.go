@@ -0,0 +1,85 @@
+// Package component defines the Go types of the values of the component model, which aren't represented by a Go type
+// of their own, for the host functions defined with wazero.HostFunctionBuilder WithComponentFunc.
+//
+// The other values are represented as follows:
+//
+//   - bool, s8, u8, s16, u16, s32, u32, s64, u64, f32, f64 and string are their Go counterparts.
+//   - list<T> is a slice of T, and list<T, N> is an array of N T.
+//   - record is a struct, whose fields are named by their `wit` tag, or else by the kebab case of their Go name.
+//   - enum and flags are unsigned integers which implement Enum and Flags.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/WIT.md#wit-types
+package component
+
+// Char is a char, which is a Unicode scalar value.
+type Char rune
+
+// Enum is implemented by the unsigned integer types of enums, whose values are the indexes of their cases.
+type Enum interface {
+	// Cases returns the names of the cases, in order.
+	Cases() []string
+}
+
+// Flags is implemented by the unsigned integer types of flags, whose bit i is set when the flag i is.
+//
+// Note: There can't be more flags than the bits of the type.
+type Flags interface {
+	// Flags returns the names of the flags, in order.
+	Flags() []string
+}
+
+// Tuple is embedded first in the struct of a tuple, whose other fields are its elements in order.
+type Tuple struct{}
+
+// Variant is embedded first in the struct of a variant, whose other fields are pointers to the payloads of its cases,
+// of which exactly one is not nil. The fields of the cases without payload are of type *struct{}.
+//
+// The cases are named as the fields of a record.
+type Variant struct{}
+
+// Option is an option<T>, whose Value is only meaningful when Valid is true.
+type Option[T any] struct {
+	Value T
+	Valid bool
+}
+
+// Some returns an Option of the value.
+func Some[T any](v T) Option[T] {
+	return Option[T]{Value: v, Valid: true}
+}
+
+// None returns an Option without value.
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+// Result is a result<T, E>, which is an error when IsErr is true. A result without ok or error type has the type
+// struct{} in its place.
+type Result[T, E any] struct {
+	Value T
+	Err   E
+	IsErr bool
+}
+
+// Ok returns a Result of the value.
+func Ok[T, E any](v T) Result[T, E] {
+	return Result[T, E]{Value: v}
+}
+
+// Err returns a Result of the error.
+func Err[T, E any](err E) Result[T, E] {
+	return Result[T, E]{Err: err, IsErr: true}
+}
+
+// Own is an own<R> handle, which transfers the ownership of the resource of representation Rep.
+//
+// The handles given to the guest are tracked by the runtime, which closes the representations which implement
+// api.Closer when the guest drops them, or when the component is closed.
+type Own[T any] struct {
+	Rep T
+}
+
+// Borrow is a borrow<R> handle, which lends the resource of representation Rep for the duration of a call.
+type Borrow[T any] struct {
+	Rep T
+}
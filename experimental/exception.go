@@ -0,0 +1,91 @@
+package experimental
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+)
+
+// ExceptionTag identifies the kind of an Exception, and is defined or
+// imported by a module using the tag section.
+//
+// Tags are compared by identity: two tags with the same parameter types, but
+// defined by different modules, do not match each other.
+//
+// See CoreFeaturesExceptionHandling
+type ExceptionTag interface {
+	internalapi.WazeroOnly
+
+	// ParamTypes are the types of the payload values carried by exceptions
+	// with this tag.
+	ParamTypes() []api.ValueType
+}
+
+// ExportedTag returns the tag exported by the module under the given name, or
+// nil if there is none.
+//
+// This is typically used by a host function to get the tag of an exception
+// to raise with NewException, or to compare the tag of an Exception returned
+// from a guest.
+func ExportedTag(m api.Module, name string) ExceptionTag {
+	if te, ok := m.(interface {
+		ExportedTag(name string) ExceptionTag
+	}); ok {
+		return te.ExportedTag(name)
+	}
+	return nil
+}
+
+// Exception is a WebAssembly exception. It is an error, so that host functions
+// can raise it and catch it like any other error:
+//
+//   - A host function raises an exception by panicking with the result of
+//     NewException. A guest can catch it with try_table.
+//   - An exception thrown by a guest and not caught before returning to the
+//     host is returned as an error from api.Function Call. Use errors.As to
+//     retrieve it.
+//
+// See CoreFeaturesExceptionHandling
+type Exception struct {
+	tag     ExceptionTag
+	payload []uint64
+}
+
+// NewException returns an exception with the given tag and payload. The
+// payload is encoded the same way as api.Function parameters, and must match
+// the parameter types of the tag.
+//
+// This panics if tag is nil or the payload doesn't match its parameter types.
+func NewException(tag ExceptionTag, payload ...uint64) *Exception {
+	if tag == nil {
+		panic("nil tag")
+	}
+	var expected int
+	for _, vt := range tag.ParamTypes() {
+		expected++
+		if vt == 0x7b { // v128 is encoded as two uint64 values.
+			expected++
+		}
+	}
+	if len(payload) != expected {
+		panic(fmt.Sprintf("expected %d payload values, but got %d", expected, len(payload)))
+	}
+	return &Exception{tag: tag, payload: payload}
+}
+
+// Tag returns the tag of this exception.
+func (e *Exception) Tag() ExceptionTag {
+	return e.tag
+}
+
+// Payload returns the values carried by this exception, encoded the same way
+// as api.Function results.
+func (e *Exception) Payload() []uint64 {
+	return e.payload
+}
+
+// Error implements error.
+func (e *Exception) Error() string {
+	return "uncaught exception"
+}
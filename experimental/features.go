@@ -16,3 +16,162 @@ const CoreFeaturesThreads = api.CoreFeatureSIMD << 1
 
 // CoreFeaturesTailCall enables tail call instructions ("tail-call").
 const CoreFeaturesTailCall = api.CoreFeatureSIMD << 2
+
+// CoreFeaturesExceptionHandling enables the exception-handling instructions
+// ("exception-handling"), with the "exnref" encoding: the tag section,
+// try_table, throw and throw_ref.
+//
+// # Notes
+//
+//   - Host functions can raise an exception by panicking with an *Exception,
+//     and catch one thrown by a guest via errors.As on the error returned from
+//     api.Function Call. See NewException and ExportedTag.
+//   - exnref values are only valid for the duration of the outermost call in
+//     which they were created. Re-throwing one in a later call traps.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md
+const CoreFeaturesExceptionHandling = api.CoreFeatureSIMD << 3
+
+// CoreFeaturesMultiMemory enables the multi-memory proposal ("multi-memory"),
+// which allows a module to import and define more than one memory.
+//
+// # Notes
+//
+//   - Memory instructions encode a memory index in their immediates, and
+//     active data segments can target any memory in the index space.
+//   - api.Module Memory still returns the memory at index zero. Use
+//     api.Module ExportedMemory to access others by their export name.
+//
+// See https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+const CoreFeaturesMultiMemory = api.CoreFeatureSIMD << 4
+
+// CoreFeaturesMemory64 enables the memory64 proposal ("memory64"), which
+// allows a memory to be addressed with i64 instead of i32.
+//
+// # Notes
+//
+//   - Such a memory can be larger than 4GiB when wazero.RuntimeConfig
+//     WithMemoryLimitPages allows more than 65536 pages.
+//   - Use api.Memory64 to access a memory beyond 4GiB from host functions.
+//   - 64-bit tables (table64) are not supported.
+//
+// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+const CoreFeaturesMemory64 = api.CoreFeatureSIMD << 5
+
+// CoreFeaturesGC enables the garbage collection proposal ("gc"), which adds
+// struct and array types, recursive type groups with subtyping, and the
+// instructions to allocate and access such objects, including i31 references
+// and casts.
+//
+// # Notes
+//
+//   - This is only supported by the interpreter. Compiling a module using
+//     this proposal with the compiler fails.
+//   - Objects are allocated on the Go heap, and referenced by anyref values
+//     which host functions can inspect with the experimental/gc package.
+//   - Unreachable objects are collected when an object is allocated and the
+//     allocating call is the only one in progress in the runtime. A host can
+//     only use a reference while it is held by a Wasm global, table or the
+//     stack of a call in progress.
+//   - Constant expressions of globals, table initializers and element
+//     segments can allocate objects, e.g. with struct.new or array.new_fixed.
+//   - Subtyping is validated precisely, including the field types of structs
+//     and arrays. call_indirect and casts of function references check the
+//     declared subtypes of the function at runtime.
+//
+// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md
+const CoreFeaturesGC = api.CoreFeatureSIMD << 6
+
+// CoreFeaturesFunctionReferences enables the typed function references
+// proposal ("function-references"), which adds references to concrete
+// function types, nullable and non-nullable, and the instructions call_ref,
+// return_call_ref, ref.as_non_null, br_on_null and br_on_non_null.
+//
+// # Notes
+//
+//   - This is also enabled by CoreFeaturesGC, which is built on top of it.
+//   - return_call_ref requires CoreFeaturesTailCall as well.
+//   - Typed references are validated with their heap type and nullability,
+//     and non-nullable locals must be set before they are read.
+//
+// See https://github.com/WebAssembly/function-references/blob/main/proposals/function-references/Overview.md
+const CoreFeaturesFunctionReferences = api.CoreFeatureSIMD << 7
+
+// CoreFeaturesRelaxedSIMD enables the relaxed SIMD proposal ("relaxed-simd"),
+// which adds vector instructions whose results may depend on the platform,
+// such as f32x4.relaxed_madd and i8x16.relaxed_swizzle.
+//
+// # Notes
+//
+//   - This requires api.CoreFeatureSIMD as well.
+//   - The compiler lowers some of the instructions to native ones, e.g.
+//     relaxed_madd to a fused multiply-add on CPUs supporting it, so the
+//     results can differ between amd64 and arm64. Use wazero.RuntimeConfig
+//     WithDeterministicRelaxedSIMD to get identical results on all platforms.
+//   - The interpreter always uses the deterministic lowering.
+//
+// See https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+const CoreFeaturesRelaxedSIMD = api.CoreFeatureSIMD << 8
+
+// CoreFeaturesExtendedConst enables the extended constant expressions
+// proposal ("extended-const"), which allows i32.add, i32.sub, i32.mul and
+// their i64 forms in the constant expressions of globals, and of the offsets
+// of data and element segments.
+//
+// This is used by the position-independent code of toolchains such as
+// Emscripten, which compute the addresses from the imported base offsets,
+// e.g. (i32.add (global.get $__memory_base) (i32.const 16)).
+//
+// See https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+const CoreFeaturesExtendedConst = api.CoreFeatureSIMD << 9
+
+// CoreFeaturesCustomPageSizes enables the custom page sizes proposal
+// ("custom-page-sizes"), which allows a memory to declare a page size of 1
+// byte instead of 64KiB. This lets tiny guests, such as embedded filters,
+// allocate only the bytes they need rather than a whole 64KiB page.
+//
+// # Notes
+//
+//   - The only page sizes allowed are 1 byte and 64KiB.
+//   - memory.size, memory.grow and api.MemoryDefinition Min and Max are in
+//     the unit of the page size of the memory. See api.MemoryDefinition
+//     PageSize.
+//   - wazero.RuntimeConfig WithMemoryLimitPages is always in 64KiB pages,
+//     and converted to the page size of each memory.
+//
+// See https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md
+const CoreFeaturesCustomPageSizes = api.CoreFeatureSIMD << 10
+
+// CoreFeaturesWideArithmetic enables the wide arithmetic proposal
+// ("wide-arithmetic"), which adds the instructions i64.add128, i64.sub128,
+// i64.mul_wide_s and i64.mul_wide_u operating on 128-bit integers represented
+// as pairs of i64 (low, high).
+//
+// These are useful for bignum and cryptography guests, which otherwise have to
+// emulate 128-bit multiplications and carries with several i64 operations.
+//
+// See https://github.com/WebAssembly/wide-arithmetic/blob/main/proposals/wide-arithmetic/Overview.md
+const CoreFeaturesWideArithmetic = api.CoreFeatureSIMD << 11
+
+// CoreFeaturesStackSwitching enables the stack switching proposal
+// ("stack-switching"), which adds continuations of functions that can be
+// suspended and resumed: the continuation types, cont.new, cont.bind, resume
+// and suspend. Guests use these to implement green threads, generators and
+// async/await without rewriting their code with Asyncify.
+//
+// # Notes
+//
+//   - This requires CoreFeaturesExceptionHandling for the tags, and
+//     CoreFeaturesFunctionReferences for the typed references.
+//   - As an interim step, each continuation runs on a goroutine of its own
+//     instead of a stack segment swapped by the engine, so every resume and
+//     suspend hands off between goroutines. Only one of the continuations of
+//     a call runs at a time.
+//   - contref values are only valid for the duration of the outermost call in
+//     which they were created, similar to exnref. Continuations which are still
+//     suspended when the call returns are discarded.
+//   - resume_throw, switch and the (on $tag switch) handlers are missing, and
+//     modules using them fail to compile.
+//
+// See https://github.com/WebAssembly/stack-switching/blob/main/proposals/stack-switching/Explainer.md
+const CoreFeaturesStackSwitching = api.CoreFeatureSIMD << 12
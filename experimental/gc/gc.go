@@ -0,0 +1,223 @@
+// Package gc allows host functions to inspect the references to the objects
+// allocated by the instructions of experimental.CoreFeaturesGC.
+//
+// Such references are passed to host functions as uint64 values of the
+// parameters of ValueTypeAnyref, or of api.ValueTypeExternref if converted with
+// extern.convert_any. The objects live on the Go heap, and are collected once
+// they are unreachable from the stack of the current call, and the globals,
+// tables and element segments of the modules. Therefore, a reference is only
+// valid while the host function is called with it, unless it is stored in one
+// of them.
+package gc
+
+import (
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ValueTypeAnyref is the type of the references to the objects of
+// experimental.CoreFeaturesGC, which can be used in the signatures of host
+// functions.
+//
+// All the reference types of the hierarchy of "any", such as "eqref",
+// "i31ref" or the references to struct and array types, are represented as
+// ValueTypeAnyref in the api package.
+const ValueTypeAnyref api.ValueType = wasm.ValueTypeAnyref
+
+// Kind is the kind of the value referred by a reference.
+type Kind byte
+
+const (
+	// KindNull is the null reference.
+	KindNull Kind = iota
+	// KindI31 is an "i31ref", which is an unboxed 31-bit integer. See I31.
+	KindI31
+	// KindStruct is a reference to a struct. See StructOf.
+	KindStruct
+	// KindArray is a reference to an array. See ArrayOf.
+	KindArray
+	// KindExtern is an externref from the host, which may have been converted
+	// with any.convert_extern. See Extern.
+	KindExtern
+)
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	switch k {
+	case KindNull:
+		return "null"
+	case KindI31:
+		return "i31"
+	case KindStruct:
+		return "struct"
+	case KindArray:
+		return "array"
+	case KindExtern:
+		return "extern"
+	}
+	return "unknown"
+}
+
+// KindOf returns the kind of the value referred by ref, which is passed to a
+// host function called by the module m.
+func KindOf(m api.Module, ref uint64) Kind {
+	if ref == 0 {
+		return KindNull
+	} else if wasm.GCRefIsI31(ref) {
+		return KindI31
+	}
+	obj := object(m, ref)
+	if obj == nil {
+		return KindExtern
+	}
+	switch obj.Kind {
+	case wasm.GCObjectKindStruct:
+		return KindStruct
+	case wasm.GCObjectKindArray:
+		return KindArray
+	default:
+		return KindExtern
+	}
+}
+
+// I31 returns the sign-extended value of ref, or false if ref is not an
+// "i31ref". Use uint32(v)&0x7fffffff for the zero-extended value.
+func I31(ref uint64) (v int32, ok bool) {
+	if ref == 0 || !wasm.GCRefIsI31(ref) {
+		return 0, false
+	}
+	return int32(wasm.GCRefI31Value(ref)<<1) >> 1, true
+}
+
+// NewI31 returns an "i31ref" of the lower 31 bits of v, which can be passed
+// to the guest as ValueTypeAnyref.
+func NewI31(v int32) uint64 {
+	return wasm.GCRefI31(uint32(v))
+}
+
+// Extern returns the externref from the host which ref refers to, or false if
+// ref is not KindExtern.
+func Extern(m api.Module, ref uint64) (uint64, bool) {
+	if KindOf(m, ref) != KindExtern {
+		return 0, false
+	}
+	if obj := object(m, ref); obj != nil {
+		return obj.Values[0], true
+	}
+	return ref, true
+}
+
+// Struct is a struct allocated by struct.new or struct.new_default.
+type Struct interface {
+	internalapi.WazeroOnly
+
+	// TypeIndex is the index of the struct type in the module which
+	// allocated this struct.
+	TypeIndex() uint32
+
+	// NumField returns the number of the fields.
+	NumField() int
+
+	// Field returns the value of the field at the index, encoded the same way
+	// as api.Function results. Packed fields of 8 or 16 bits are zero-extended,
+	// and only the lower 64 bits of a v128 field are returned.
+	//
+	// This panics if the index is out of range.
+	Field(i int) uint64
+}
+
+// StructOf returns the struct referred by ref, or false if ref is not
+// KindStruct.
+func StructOf(m api.Module, ref uint64) (Struct, bool) {
+	if obj := object(m, ref); obj != nil && obj.Kind == wasm.GCObjectKindStruct {
+		return &structObject{obj: obj}, true
+	}
+	return nil, false
+}
+
+// Array is an array allocated by one of the array.new instructions.
+type Array interface {
+	internalapi.WazeroOnly
+
+	// TypeIndex is the index of the array type in the module which allocated
+	// this array.
+	TypeIndex() uint32
+
+	// Len returns the number of the elements.
+	Len() uint32
+
+	// Get returns the element at the index, encoded the same way as
+	// api.Function results. Packed elements of 8 or 16 bits are zero-extended,
+	// and only the lower 64 bits of a v128 element are returned.
+	//
+	// This panics if the index is out of range.
+	Get(i uint32) uint64
+}
+
+// ArrayOf returns the array referred by ref, or false if ref is not
+// KindArray.
+func ArrayOf(m api.Module, ref uint64) (Array, bool) {
+	if obj := object(m, ref); obj != nil && obj.Kind == wasm.GCObjectKindArray {
+		return &arrayObject{obj: obj}, true
+	}
+	return nil, false
+}
+
+// object returns the object referred by ref, or nil if there is none.
+func object(m api.Module, ref uint64) *wasm.GCObject {
+	mi, ok := m.(*wasm.ModuleInstance)
+	if !ok || ref == 0 || wasm.GCRefIsI31(ref) {
+		return nil
+	}
+	h := mi.GCHeap()
+	if h == nil {
+		return nil
+	}
+	return h.Get(ref)
+}
+
+// structObject implements Struct.
+type structObject struct {
+	internalapi.WazeroOnlyType
+	obj *wasm.GCObject
+}
+
+// TypeIndex implements Struct.TypeIndex.
+func (s *structObject) TypeIndex() uint32 {
+	return s.obj.TypeIndex
+}
+
+// NumField implements Struct.NumField.
+func (s *structObject) NumField() int {
+	return len(s.obj.Type().Fields)
+}
+
+// Field implements Struct.Field.
+func (s *structObject) Field(i int) uint64 {
+	return s.obj.Values[s.obj.Type().FieldOffset(wasm.Index(i))]
+}
+
+// arrayObject implements Array.
+type arrayObject struct {
+	internalapi.WazeroOnlyType
+	obj *wasm.GCObject
+}
+
+// TypeIndex implements Array.TypeIndex.
+func (a *arrayObject) TypeIndex() uint32 {
+	return a.obj.TypeIndex
+}
+
+// Len implements Array.Len.
+func (a *arrayObject) Len() uint32 {
+	return a.obj.ArrayLen()
+}
+
+// Get implements Array.Get.
+func (a *arrayObject) Get(i uint32) uint64 {
+	if i >= a.obj.ArrayLen() {
+		panic("array index out of range")
+	}
+	return a.obj.Values[uint64(i)*uint64(a.obj.Type().SizeInUint64())]
+}
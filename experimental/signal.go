@@ -0,0 +1,72 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/expctxkeys"
+)
+
+// SignalAction is the action taken when a module receives a signal.
+type SignalAction uint8
+
+const (
+	// SignalActionDefault takes the default action of the signal, as in
+	// POSIX: SIGCHLD, SIGCONT, SIGURG and SIGWINCH are ignored, as are the
+	// signals which stop a process, as modules can't be stopped. Other
+	// signals terminate the module with a sys.ExitError whose Signal is the
+	// signal.
+	SignalActionDefault SignalAction = iota
+	// SignalActionTerminate terminates the module with a sys.ExitError whose
+	// Signal is the signal.
+	SignalActionTerminate
+	// SignalActionIgnore ignores the signal.
+	SignalActionIgnore
+	// SignalActionCatch keeps the signal pending for the guest, as if it had
+	// a handler for it, until the guest takes it with the function
+	// "take_pending" of the "signal" host module.
+	//
+	// See https://pkg.go.dev/github.com/tetratelabs/wazero/imports/signal
+	SignalActionCatch
+)
+
+// SignalHandler is a hook, invoked when a module receives a signal, which
+// returns the action to take.
+//
+// A module receives a signal when it raises one, with "proc_raise" of
+// "wasi_snapshot_preview1", or when the host delivers one, with
+// wasi_snapshot_preview1.DeliverSignal. A signal which doesn't terminate the
+// module is only seen by the guest when this returns SignalActionCatch.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+type SignalHandler interface {
+	// HandleSignal is called when the module receives the signal, numbered as
+	// in "wasi_snapshot_preview1", e.g. 2 for SIGINT, and returns the action
+	// to take, e.g. SignalActionDefault to only observe the signal.
+	//
+	// Notes:
+	//   - This isn't called for SIGKILL nor SIGSTOP, whose actions can't be
+	//     overridden.
+	//   - This is called from the goroutine of the caller which raised or
+	//     delivered the signal, which may not be the one calling the module.
+	HandleSignal(ctx context.Context, mod api.Module, signal uint8) SignalAction
+}
+
+// SignalHandlerFunc is a convenience for defining inlining a SignalHandler.
+type SignalHandlerFunc func(ctx context.Context, mod api.Module, signal uint8) SignalAction
+
+// HandleSignal implements SignalHandler.HandleSignal.
+func (f SignalHandlerFunc) HandleSignal(ctx context.Context, mod api.Module, signal uint8) SignalAction {
+	return f(ctx, mod, signal)
+}
+
+// WithSignalHandler registers the given SignalHandler into the given
+// context.Context, which is the one of the function call raising the signal,
+// or the one passed to deliver it.
+func WithSignalHandler(ctx context.Context, handler SignalHandler) context.Context {
+	if handler != nil {
+		return context.WithValue(ctx, expctxkeys.SignalHandlerKey{}, handler)
+	}
+	return ctx
+}
@@ -6,14 +6,67 @@ import (
 	"github.com/tetratelabs/wazero/internal/sock"
 )
 
-// Config configures the host to open TCP sockets and allows guest access to
+// Config configures the host to open sockets and allows guest access to
 // them.
 //
-// Instantiating a module with listeners results in pre-opened sockets
-// associated with file-descriptors numerically after pre-opened files.
+// Instantiating a module with listeners or connections results in pre-opened
+// sockets associated with file-descriptors numerically after pre-opened
+// files: the TCP listeners first, then the UDP sockets, the Unix domain
+// socket listeners and the connections, each in the order configured.
+//
+// Guests can also open sockets with the functions of
+// wasi_snapshot_preview1.NewSocketExtensionsExporter, which are only allowed
+// to bind, connect or look up what the allow-lists below allow.
 type Config interface {
 	// WithTCPListener configures the host to set up the given host:port listener.
 	WithTCPListener(host string, port int) Config
+
+	// WithUDPListener configures the host to set up a UDP socket bound to the
+	// given host:port.
+	//
+	// Guests receive datagrams from any address with sock_recv, and reply
+	// with sock_send, which sends to the address of the last datagram
+	// received.
+	WithUDPListener(host string, port int) Config
+
+	// WithUnixListener configures the host to set up a Unix domain socket
+	// listener at the given path, whose connections guests accept like the
+	// ones of WithTCPListener.
+	//
+	// Note: The path must not exist, and is removed when the module closes.
+	WithUnixListener(path string) Config
+
+	// WithConnection configures the host to connect to the given address on
+	// the network, "tcp", "udp" or "unix", as documented on net.Dial. Guests
+	// use the connection with sock_recv and sock_send.
+	//
+	// Note: The connection is dialed with the context passed to
+	// instantiation, so use a deadline to bound how long an unreachable
+	// address blocks it.
+	WithConnection(network, address string) Config
+
+	// WithAllowedBind allows guests to bind sockets to the given host:port.
+	//
+	// The network is "tcp" or "udp", the host an IP address, e.g.
+	// "127.0.0.1", or prefix, e.g. "10.0.0.0/8", and the port a port number.
+	// Any is allowed when a parameter is its zero value, e.g. the host "".
+	//
+	// Note: A UDP socket which sends before it is bound is bound to an
+	// ephemeral port of any address, which isn't checked, like a TCP socket
+	// is when it connects.
+	WithAllowedBind(network, host string, port int) Config
+
+	// WithAllowedConnect allows guests to connect sockets, or send datagrams,
+	// to the given host:port. The parameters are the same as WithAllowedBind.
+	//
+	// Note: Guests look up host names with their own function, so the hosts
+	// allowed are IP addresses, and the names are allowed by
+	// WithAllowedLookup.
+	WithAllowedConnect(network, host string, port int) Config
+
+	// WithAllowedLookup allows guests to look up the IP addresses of the given
+	// host name, or any name if empty.
+	WithAllowedLookup(name string) Config
 }
 
 // NewConfig returns a Config for module instantiation.
@@ -33,9 +86,39 @@ func (c *internalSockConfig) WithTCPListener(host string, port int) Config {
 	return &internalSockConfig{cNew}
 }
 
+// WithUDPListener implements Config.WithUDPListener
+func (c *internalSockConfig) WithUDPListener(host string, port int) Config {
+	return &internalSockConfig{c.c.WithUDPListener(host, port)}
+}
+
+// WithUnixListener implements Config.WithUnixListener
+func (c *internalSockConfig) WithUnixListener(path string) Config {
+	return &internalSockConfig{c.c.WithUnixListener(path)}
+}
+
+// WithConnection implements Config.WithConnection
+func (c *internalSockConfig) WithConnection(network, address string) Config {
+	return &internalSockConfig{c.c.WithConnection(network, address)}
+}
+
+// WithAllowedBind implements Config.WithAllowedBind
+func (c *internalSockConfig) WithAllowedBind(network, host string, port int) Config {
+	return &internalSockConfig{c.c.WithAllowedBind(network, host, port)}
+}
+
+// WithAllowedConnect implements Config.WithAllowedConnect
+func (c *internalSockConfig) WithAllowedConnect(network, host string, port int) Config {
+	return &internalSockConfig{c.c.WithAllowedConnect(network, host, port)}
+}
+
+// WithAllowedLookup implements Config.WithAllowedLookup
+func (c *internalSockConfig) WithAllowedLookup(name string) Config {
+	return &internalSockConfig{c.c.WithAllowedLookup(name)}
+}
+
 // WithConfig registers the given Config into the given context.Context.
 func WithConfig(ctx context.Context, config Config) context.Context {
-	if config, ok := config.(*internalSockConfig); ok && len(config.c.TCPAddresses) > 0 {
+	if config, ok := config.(*internalSockConfig); ok && !config.c.IsZero() {
 		return context.WithValue(ctx, sock.ConfigKey{}, config.c)
 	}
 	return ctx
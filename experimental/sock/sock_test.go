@@ -2,6 +2,7 @@ package sock_test
 
 import (
 	"context"
+	"net/netip"
 	"testing"
 
 	"github.com/tetratelabs/wazero/experimental/sock"
@@ -34,6 +35,26 @@ func TestWithSockConfig(t *testing.T) {
 			sockCfg:  sock.NewConfig().WithTCPListener("", 0),
 			expected: true,
 		},
+		{
+			name:     "decorates with UDP listener",
+			sockCfg:  sock.NewConfig().WithUDPListener("", 0),
+			expected: true,
+		},
+		{
+			name:     "decorates with Unix listener",
+			sockCfg:  sock.NewConfig().WithUnixListener("wazero.sock"),
+			expected: true,
+		},
+		{
+			name:     "decorates with connection",
+			sockCfg:  sock.NewConfig().WithConnection("tcp", "127.0.0.1:8080"),
+			expected: true,
+		},
+		{
+			name:     "decorates with allow-list",
+			sockCfg:  sock.NewConfig().WithAllowedLookup("localhost"),
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -47,3 +68,33 @@ func TestWithSockConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_AllowLists(t *testing.T) {
+	config := sock.NewConfig().
+		WithAllowedBind("udp", "", 0).
+		WithAllowedConnect("tcp", "127.0.0.1", 8080).
+		WithAllowedConnect("", "10.0.0.0/8", 0).
+		WithAllowedLookup("localhost")
+	c := sock.WithConfig(testCtx, config).Value(internalsock.ConfigKey{}).(*internalsock.Config)
+
+	loopback := netip.MustParseAddrPort("127.0.0.1:8080")
+	require.True(t, c.AllowBind("udp4", loopback))
+	require.True(t, c.AllowBind("udp6", netip.MustParseAddrPort("[::1]:53")))
+	require.False(t, c.AllowBind("tcp4", loopback))
+
+	require.True(t, c.AllowConnect("tcp4", loopback))
+	require.True(t, c.AllowConnect("tcp4", netip.MustParseAddrPort("[::ffff:127.0.0.1]:8080")))
+	require.False(t, c.AllowConnect("tcp4", netip.MustParseAddrPort("127.0.0.1:8081")))
+	require.False(t, c.AllowConnect("udp4", loopback))
+	require.True(t, c.AllowConnect("udp4", netip.MustParseAddrPort("10.1.2.3:53")))
+	require.False(t, c.AllowConnect("udp4", netip.MustParseAddrPort("11.1.2.3:53")))
+
+	require.True(t, c.AllowLookup("localhost"))
+	require.False(t, c.AllowLookup("example.com"))
+
+	// A nil config, e.g. when not in the context, allows nothing.
+	var none *internalsock.Config
+	require.False(t, none.AllowBind("udp4", loopback))
+	require.False(t, none.AllowConnect("tcp4", loopback))
+	require.False(t, none.AllowLookup("localhost"))
+}
@@ -0,0 +1,52 @@
+package sys
+
+// LockFlag are flags used for Locker.Lock. Exactly one of LOCK_SH, LOCK_EX or
+// LOCK_UN must be set, optionally with LOCK_NB.
+//
+// # Notes
+//
+//   - Values are the same as `operation` in BSD `flock`, so they can be passed
+//     through from a guest using the definitions of its libc.
+//     See https://man.freebsd.org/cgi/man.cgi?query=flock&sektion=2
+type LockFlag uint32
+
+const (
+	// LOCK_SH places a shared lock, which more than one file can hold.
+	LOCK_SH LockFlag = 1 << iota
+
+	// LOCK_EX places an exclusive lock, which only one file can hold.
+	LOCK_EX
+
+	// LOCK_NB returns EAGAIN instead of blocking when the lock conflicts with
+	// one held by another file.
+	LOCK_NB
+
+	// LOCK_UN removes the lock held by this file.
+	LOCK_UN
+)
+
+// Locker is an optional interface of File, for advisory locks, like
+// syscall.Flock. Callers should type-assert a File to find out whether it is
+// supported, and treat a File which isn't as if Lock returned ENOSYS.
+type Locker interface {
+	// Lock places, converts or removes an advisory lock on this file.
+	//
+	// # Errors
+	//
+	// A zero Errno is success. The below are expected otherwise:
+	//   - ENOSYS: the implementation does not support this function.
+	//   - EBADF: the file or directory was closed.
+	//   - EINVAL: `how` isn't exactly one of LOCK_SH, LOCK_EX or LOCK_UN,
+	//     optionally with LOCK_NB.
+	//   - EAGAIN: LOCK_NB was set and the lock is held by another file.
+	//
+	// # Notes
+	//
+	//   - This is like `flock` in BSD, not `fcntl` locks in POSIX: the lock
+	//     belongs to the open file, not the process. So, locks conflict when
+	//     the same path is opened more than once, even in the same process.
+	//   - The lock is removed when the file is closed.
+	//   - Without LOCK_NB, this blocks until the lock is placed, which isn't
+	//     interrupted by the context of the calling function being done.
+	Lock(how LockFlag) Errno
+}
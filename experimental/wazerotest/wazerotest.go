@@ -666,6 +666,10 @@ func (def memoryDefinition) Max() (uint32, bool) {
 	return def.memory.Max, def.memory.Max != 0
 }
 
+func (def memoryDefinition) PageSize() uint32 {
+	return PageSize
+}
+
 var (
 	_ api.Module   = (*Module)(nil)
 	_ api.Function = (*Function)(nil)
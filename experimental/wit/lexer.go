@@ -0,0 +1,243 @@
+package wit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind is the kind of a token of WIT.
+type tokenKind byte
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenID is an identifier, or a keyword unless it's explicit.
+	tokenID
+	// tokenInt is a decimal integer.
+	tokenInt
+	// tokenPunct is a punctuation, e.g. "->".
+	tokenPunct
+)
+
+// token is a token of WIT, with the documentation comments which precede it.
+type token struct {
+	kind  tokenKind
+	value string
+	// explicit is true if the identifier is prefixed by %, so that it isn't a keyword.
+	explicit bool
+	docs     string
+	pos      position
+}
+
+// position is a position in a file.
+type position struct {
+	file      string
+	line, col int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.file, p.line, p.col)
+}
+
+// is returns true if the token is the keyword or the punctuation s.
+func (t token) is(s string) bool {
+	return (t.kind == tokenPunct || (t.kind == tokenID && !t.explicit)) && t.value == s
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of file"
+	case tokenID:
+		return fmt.Sprintf("identifier %q", t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// lexer splits the source of a WIT file into tokens.
+type lexer struct {
+	src       string
+	i         int
+	pos       position
+	docs      []string
+	peeked    *token
+	peekedErr error
+}
+
+func newLexer(file string, src []byte) *lexer {
+	return &lexer{src: string(src), pos: position{file: file, line: 1, col: 1}}
+}
+
+// peek returns the next token without consuming it.
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		t, err := l.scan()
+		l.peeked, l.peekedErr = &t, err
+	}
+	return *l.peeked, l.peekedErr
+}
+
+// next consumes the next token.
+func (l *lexer) next() (token, error) {
+	t, err := l.peek()
+	l.peeked, l.peekedErr = nil, nil
+	return t, err
+}
+
+func (l *lexer) advance(n int) {
+	for _, r := range l.src[l.i : l.i+n] {
+		if r == '\n' {
+			l.pos.line++
+			l.pos.col = 1
+		} else {
+			l.pos.col++
+		}
+	}
+	l.i += n
+}
+
+func (l *lexer) errorf(pos position, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...))
+}
+
+// skip skips the spaces and the comments, and collects the documentation comments.
+func (l *lexer) skip() error {
+	for l.i < len(l.src) {
+		rest := l.src[l.i:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			l.advance(1)
+		case strings.HasPrefix(rest, "//"):
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			if line := rest[:n]; strings.HasPrefix(line, "///") && !strings.HasPrefix(line, "////") {
+				l.docs = append(l.docs, strings.TrimPrefix(strings.TrimRight(line[3:], "\r"), " "))
+			}
+			l.advance(n)
+		case strings.HasPrefix(rest, "/*"):
+			pos := l.pos
+			depth, n := 0, 0
+			for n < len(rest) {
+				if strings.HasPrefix(rest[n:], "/*") {
+					depth, n = depth+1, n+2
+				} else if strings.HasPrefix(rest[n:], "*/") {
+					depth, n = depth-1, n+2
+					if depth == 0 {
+						break
+					}
+				} else {
+					n++
+				}
+			}
+			if depth > 0 {
+				return l.errorf(pos, "unterminated comment")
+			}
+			if strings.HasPrefix(rest, "/**") && !strings.HasPrefix(rest, "/**/") {
+				for _, line := range strings.Split(rest[3:n-2], "\n") {
+					line = strings.TrimSpace(line)
+					line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+					l.docs = append(l.docs, line)
+				}
+			}
+			l.advance(n)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) scan() (token, error) {
+	if err := l.skip(); err != nil {
+		return token{}, err
+	}
+	t := token{pos: l.pos, docs: strings.TrimSpace(strings.Join(l.docs, "\n"))}
+	l.docs = l.docs[:0]
+	if l.i >= len(l.src) {
+		return t, nil
+	}
+
+	rest := l.src[l.i:]
+	c := rest[0]
+	switch {
+	case c == '%' || isLetter(c):
+		n := 0
+		if c == '%' {
+			t.explicit, n = true, 1
+		}
+		start := n
+		for n < len(rest) && (isLetter(rest[n]) || isDigit(rest[n]) || rest[n] == '-') {
+			n++
+		}
+		t.kind, t.value = tokenID, rest[start:n]
+		if err := checkID(t.value); err != nil {
+			return t, l.errorf(t.pos, "%v", err)
+		}
+		l.advance(n)
+	case isDigit(c):
+		n := 0
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+		t.kind, t.value = tokenInt, rest[:n]
+		l.advance(n)
+	case strings.HasPrefix(rest, "->"):
+		t.kind, t.value = tokenPunct, "->"
+		l.advance(2)
+	case strings.IndexByte("{}()<>,;:=./@*_", c) >= 0:
+		t.kind, t.value = tokenPunct, rest[:1]
+		l.advance(1)
+	default:
+		return t, l.errorf(t.pos, "unexpected character %q", c)
+	}
+	return t, nil
+}
+
+// version consumes a semantic version, which immediately follows an @.
+func (l *lexer) version() (string, position, error) {
+	if l.peeked != nil {
+		return "", l.peeked.pos, l.errorf(l.peeked.pos, "BUG: version after a peek")
+	}
+	if err := l.skip(); err != nil {
+		return "", l.pos, err
+	}
+	l.docs = l.docs[:0]
+	pos, n := l.pos, 0
+	rest := l.src[l.i:]
+	for n < len(rest) && (isLetter(rest[n]) || isDigit(rest[n]) || strings.IndexByte(".+-", rest[n]) >= 0) {
+		n++
+	}
+	// A version doesn't end with a dot, which is the one of a use, e.g. use wasi:io/streams@0.2.0.{error}.
+	for n > 0 && rest[n-1] == '.' {
+		n--
+	}
+	if n == 0 {
+		return "", pos, l.errorf(pos, "expected a version")
+	}
+	l.advance(n)
+	return rest[:n], pos, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// checkID returns an error unless the identifier is kebab case, where each word is either in lower case or in upper
+// case, and starts with a letter.
+func checkID(id string) error {
+	if id == "" {
+		return fmt.Errorf("empty identifier")
+	}
+	for _, word := range strings.Split(id, "-") {
+		if word == "" || !isLetter(word[0]) ||
+			(strings.ToLower(word) != word && strings.ToUpper(word) != word) {
+			return fmt.Errorf("invalid identifier %q", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,830 @@
+package wit
+
+import (
+	"strconv"
+)
+
+// file is a parsed WIT file, whose references are resolved by a resolver.
+type file struct {
+	// pkg is the name of the package declared by the file, or nil if it's declared by another file.
+	pkg        *PackageName
+	pkgDocs    string
+	pos        position
+	interfaces []*Interface
+	worlds     []*World
+	// aliases are the interfaces named by the `use` at the top level of the file.
+	aliases map[string]usePath
+	// scopes are the scopes of the interfaces and the worlds defined by the file.
+	scopes []*scope
+}
+
+// usePath is a reference to an interface, which is either in the same package, or qualified by its package.
+type usePath struct {
+	// pkg is the package of the interface, or nil if it's in the same package.
+	pkg  *PackageName
+	name string
+	pos  position
+}
+
+func (u usePath) String() string {
+	if u.pkg == nil {
+		return u.name
+	}
+	ret := u.pkg.Namespace + ":" + u.pkg.Name + "/" + u.name
+	if u.pkg.Version != "" {
+		ret += "@" + u.pkg.Version
+	}
+	return ret
+}
+
+// useDecl is a `use` of the types of an interface.
+type useDecl struct {
+	path  usePath
+	names []useName
+}
+
+type useName struct {
+	name, as string
+	docs     string
+	pos      position
+}
+
+// typeRef is a reference to a type by name.
+type typeRef struct {
+	t    *Type
+	name string
+	pos  position
+}
+
+// itemRef is an import or an export of a world which references an interface.
+type itemRef struct {
+	item *WorldItem
+	path usePath
+}
+
+// scope is an interface or a world, with the references to resolve in it.
+type scope struct {
+	file  *file
+	iface *Interface
+	world *World
+	// parent is the world of an interface defined inline, or nil.
+	parent   *scope
+	uses     []useDecl
+	refs     []typeRef
+	items    []itemRef
+	includes []usePath
+	// types are the types of a world, or of an inline interface, by name.
+	types map[string]*TypeDef
+}
+
+// parser parses a WIT file.
+type parser struct {
+	lex  *lexer
+	file *file
+}
+
+// parseFile parses the source of a WIT file.
+func parseFile(name string, src []byte) (*file, error) {
+	p := &parser{lex: newLexer(name, src), file: &file{aliases: map[string]usePath{}}}
+	p.file.pos = p.lex.pos
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+func (p *parser) parse() error {
+	for first := true; ; first = false {
+		if err := p.skipGates(); err != nil {
+			return err
+		}
+		t, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case t.kind == tokenEOF:
+			return nil
+		case t.is("package"):
+			if !first {
+				return p.lex.errorf(t.pos, "package must be declared first")
+			}
+			name, err := p.packageName()
+			if err != nil {
+				return err
+			} else if err = p.expect(";"); err != nil {
+				return err
+			}
+			p.file.pkg, p.file.pkgDocs, p.file.pos = &name, t.docs, t.pos
+		case t.is("use"):
+			path, err := p.usePath()
+			if err != nil {
+				return err
+			}
+			as := path.name
+			if next, err := p.lex.peek(); err != nil {
+				return err
+			} else if next.is("as") {
+				_, _ = p.lex.next()
+				if as, err = p.id(); err != nil {
+					return err
+				}
+			}
+			if _, ok := p.file.aliases[as]; ok {
+				return p.lex.errorf(path.pos, "%s is used twice", as)
+			}
+			p.file.aliases[as] = path
+			if err = p.expect(";"); err != nil {
+				return err
+			}
+		case t.is("interface"):
+			if err = p.parseInterface(t.docs, nil); err != nil {
+				return err
+			}
+		case t.is("world"):
+			if err = p.parseWorld(t.docs); err != nil {
+				return err
+			}
+		default:
+			return p.lex.errorf(t.pos, "expected package, use, interface or world, but got %s", t)
+		}
+	}
+}
+
+// skipGates skips the feature gates, e.g. @since(version = 0.2.0), which don't change the definitions.
+func (p *parser) skipGates() error {
+	for {
+		t, err := p.lex.peek()
+		if err != nil {
+			return err
+		} else if !t.is("@") {
+			return nil
+		}
+		_, _ = p.lex.next()
+		gate, err := p.id()
+		if err != nil {
+			return err
+		}
+		switch gate {
+		case "since", "deprecated", "unstable":
+		default:
+			return p.lex.errorf(t.pos, "unknown gate @%s", gate)
+		}
+		if next, err := p.lex.peek(); err != nil {
+			return err
+		} else if !next.is("(") {
+			continue
+		}
+		_, _ = p.lex.next()
+		key, err := p.id()
+		if err != nil {
+			return err
+		} else if err = p.expect("="); err != nil {
+			return err
+		}
+		if key == "version" {
+			if _, _, err = p.lex.version(); err != nil {
+				return err
+			}
+		} else if _, err = p.id(); err != nil {
+			return err
+		}
+		if err = p.expect(")"); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) expect(punct string) error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	} else if !t.is(punct) {
+		return p.lex.errorf(t.pos, "expected %q, but got %s", punct, t)
+	}
+	return nil
+}
+
+// accept consumes the next token if it's the keyword or the punctuation s.
+func (p *parser) accept(s string) (bool, error) {
+	t, err := p.lex.peek()
+	if err != nil || !t.is(s) {
+		return false, err
+	}
+	_, _ = p.lex.next()
+	return true, nil
+}
+
+func (p *parser) id() (string, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return "", err
+	} else if t.kind != tokenID {
+		return "", p.lex.errorf(t.pos, "expected an identifier, but got %s", t)
+	}
+	return t.value, nil
+}
+
+// packageName parses a package name, e.g. wasi:io@0.2.0.
+func (p *parser) packageName() (ret PackageName, err error) {
+	if ret.Namespace, err = p.id(); err != nil {
+		return
+	} else if err = p.expect(":"); err != nil {
+		return
+	} else if ret.Name, err = p.id(); err != nil {
+		return
+	}
+	ret.Version, err = p.version()
+	return
+}
+
+// version parses an optional version, which follows an @.
+func (p *parser) version() (string, error) {
+	if ok, err := p.accept("@"); err != nil || !ok {
+		return "", err
+	}
+	v, _, err := p.lex.version()
+	return v, err
+}
+
+// usePath parses a reference to an interface, e.g. streams or wasi:io/streams@0.2.0.
+func (p *parser) usePath() (usePath, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return usePath{}, err
+	} else if t.kind != tokenID {
+		return usePath{}, p.lex.errorf(t.pos, "expected an interface, but got %s", t)
+	}
+	return p.usePathFrom(t)
+}
+
+// usePathFrom parses the rest of a reference to an interface, whose first identifier is t.
+func (p *parser) usePathFrom(t token) (usePath, error) {
+	ret := usePath{name: t.value, pos: t.pos}
+	if ok, err := p.accept(":"); err != nil || !ok {
+		return ret, err
+	}
+	pkg := PackageName{Namespace: t.value}
+	var err error
+	if pkg.Name, err = p.id(); err != nil {
+		return ret, err
+	} else if err = p.expect("/"); err != nil {
+		return ret, err
+	} else if ret.name, err = p.id(); err != nil {
+		return ret, err
+	} else if pkg.Version, err = p.version(); err != nil {
+		return ret, err
+	}
+	ret.pkg = &pkg
+	return ret, nil
+}
+
+// parseInterface parses an interface after its keyword, which is inline in the world of parent if not nil.
+func (p *parser) parseInterface(docs string, parent *scope) error {
+	name, err := p.id()
+	if err != nil {
+		return err
+	}
+	_, err = p.interfaceBody(name, docs, parent)
+	return err
+}
+
+// interfaceBody parses the items of an interface between braces.
+func (p *parser) interfaceBody(name, docs string, parent *scope) (*Interface, error) {
+	iface := &Interface{Name: name, Docs: docs}
+	s := &scope{file: p.file, iface: iface, parent: parent, types: map[string]*TypeDef{}}
+	if parent == nil {
+		p.file.interfaces = append(p.file.interfaces, iface)
+	}
+	p.file.scopes = append(p.file.scopes, s)
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.skipGates(); err != nil {
+			return nil, err
+		}
+		t, err := p.lex.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.is("}"):
+			return iface, nil
+		case t.is("use"):
+			if err = p.parseUse(s); err != nil {
+				return nil, err
+			}
+		case t.kind == tokenID && !t.explicit && isTypeKeyword(t.value):
+			if err = p.parseTypeDef(s, t); err != nil {
+				return nil, err
+			}
+		case t.kind == tokenID:
+			f, err := p.namedFunc(s, t)
+			if err != nil {
+				return nil, err
+			} else if err = p.addFunc(iface, f, t.pos); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.lex.errorf(t.pos, "expected a type or a function, but got %s", t)
+		}
+	}
+}
+
+// namedFunc parses a function whose name is t.
+func (p *parser) namedFunc(s *scope, t token) (*Func, error) {
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	f, err := p.funcType(s)
+	if err != nil {
+		return nil, err
+	} else if err = p.expect(";"); err != nil {
+		return nil, err
+	}
+	f.Name, f.Docs = t.value, t.docs
+	return f, nil
+}
+
+// addFunc adds the function to the interface.
+func (p *parser) addFunc(iface *Interface, f *Func, pos position) error {
+	if iface.Func(f.ExternName()) != nil {
+		return p.lex.errorf(pos, "function %s is defined twice", f.ExternName())
+	}
+	iface.Funcs = append(iface.Funcs, f)
+	return nil
+}
+
+func isTypeKeyword(s string) bool {
+	switch s {
+	case "type", "record", "variant", "enum", "flags", "resource":
+		return true
+	}
+	return false
+}
+
+// parseUse parses the `use` of the types of an interface after its keyword.
+func (p *parser) parseUse(s *scope) error {
+	path, err := p.usePath()
+	if err != nil {
+		return err
+	} else if err = p.expect("."); err != nil {
+		return err
+	} else if err = p.expect("{"); err != nil {
+		return err
+	}
+	u := useDecl{path: path}
+	for {
+		t, err := p.lex.next()
+		if err != nil {
+			return err
+		} else if t.is("}") {
+			break
+		} else if t.kind != tokenID {
+			return p.lex.errorf(t.pos, "expected a type, but got %s", t)
+		}
+		n := useName{name: t.value, as: t.value, docs: t.docs, pos: t.pos}
+		if ok, err := p.accept("as"); err != nil {
+			return err
+		} else if ok {
+			if n.as, err = p.id(); err != nil {
+				return err
+			}
+		}
+		u.names = append(u.names, n)
+		if ok, err := p.accept(","); err != nil {
+			return err
+		} else if !ok {
+			if err = p.expect("}"); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	s.uses = append(s.uses, u)
+	return p.expect(";")
+}
+
+// addType adds the type definition to the scope.
+func (p *parser) addType(s *scope, def *TypeDef, pos position) error {
+	if _, ok := s.types[def.Name]; ok {
+		return p.lex.errorf(pos, "type %s is defined twice", def.Name)
+	}
+	s.types[def.Name] = def
+	if s.iface != nil {
+		def.Interface = s.iface
+		s.iface.Types = append(s.iface.Types, def)
+	} else {
+		s.world.Types = append(s.world.Types, def)
+	}
+	return nil
+}
+
+// parseTypeDef parses a type definition after its keyword t.
+func (p *parser) parseTypeDef(s *scope, t token) error {
+	name, err := p.id()
+	if err != nil {
+		return err
+	}
+	def := &TypeDef{Name: name, Docs: t.docs}
+	if err = p.addType(s, def, t.pos); err != nil {
+		return err
+	}
+	switch t.value {
+	case "type":
+		if err = p.expect("="); err != nil {
+			return err
+		} else if def.Type, err = p.typ(s); err != nil {
+			return err
+		}
+		return p.expect(";")
+	case "record", "variant":
+		kind := TypeKindRecord
+		if t.value == "variant" {
+			kind = TypeKindVariant
+		}
+		def.Type = &Type{Kind: kind}
+		err = p.list("{", "}", func(t token) error {
+			f := Field{Name: t.value, Docs: t.docs}
+			defer func() {
+				def.Type.Fields = append(def.Type.Fields, f)
+			}()
+			if kind == TypeKindRecord {
+				err := p.expect(":")
+				if err == nil {
+					f.Type, err = p.typ(s)
+				}
+				return err
+			}
+			if ok, err := p.accept("("); err != nil || !ok {
+				return err
+			}
+			var err error
+			if f.Type, err = p.typ(s); err != nil {
+				return err
+			}
+			return p.expect(")")
+		})
+		if err == nil && len(def.Type.Fields) == 0 {
+			err = p.lex.errorf(t.pos, "%s %s is empty", t.value, name)
+		}
+		return err
+	case "enum", "flags":
+		def.Type = &Type{Kind: TypeKindEnum}
+		if t.value == "flags" {
+			def.Type.Kind = TypeKindFlags
+		}
+		err = p.list("{", "}", func(t token) error {
+			def.Type.Labels = append(def.Type.Labels, t.value)
+			return nil
+		})
+		if err == nil && len(def.Type.Labels) == 0 {
+			err = p.lex.errorf(t.pos, "%s %s is empty", t.value, name)
+		}
+		return err
+	default: // resource
+		def.Type = &Type{Kind: TypeKindResource}
+		if ok, err := p.accept(";"); err != nil || ok {
+			return err
+		} else if s.iface == nil {
+			return p.lex.errorf(t.pos, "resource %s of a world can't have functions", name)
+		} else if err = p.expect("{"); err != nil {
+			return err
+		}
+		for {
+			if err = p.skipGates(); err != nil {
+				return err
+			}
+			t, err := p.lex.next()
+			if err != nil {
+				return err
+			}
+			var f *Func
+			switch {
+			case t.is("}"):
+				return nil
+			case t.is("constructor"):
+				f = &Func{Name: "constructor", Docs: t.docs, Kind: FuncKindConstructor, Resource: def}
+				if f.Params, err = p.params(s); err != nil {
+					return err
+				} else if err = p.expect(";"); err != nil {
+					return err
+				}
+				f.Result = &Type{Kind: TypeKindOwn, Def: def}
+			case t.kind == tokenID:
+				if err = p.expect(":"); err != nil {
+					return err
+				}
+				static, err := p.accept("static")
+				if err != nil {
+					return err
+				} else if f, err = p.funcType(s); err != nil {
+					return err
+				} else if err = p.expect(";"); err != nil {
+					return err
+				}
+				f.Name, f.Docs, f.Resource, f.Kind = t.value, t.docs, def, FuncKindStatic
+				if !static {
+					f.Kind = FuncKindMethod
+					self := Field{Name: "self", Type: &Type{Kind: TypeKindBorrow, Def: def}}
+					f.Params = append([]Field{self}, f.Params...)
+				}
+			default:
+				return p.lex.errorf(t.pos, "expected a function of resource %s, but got %s", name, t)
+			}
+			if err = p.addFunc(s.iface, f, t.pos); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// list parses the identifiers between open and close, separated by commas with an optional trailing one, with item
+// parsing the rest of each.
+func (p *parser) list(open, close string, item func(t token) error) error {
+	if err := p.expect(open); err != nil {
+		return err
+	}
+	for {
+		t, err := p.lex.next()
+		if err != nil {
+			return err
+		} else if t.is(close) {
+			return nil
+		} else if t.kind != tokenID {
+			return p.lex.errorf(t.pos, "expected an identifier, but got %s", t)
+		} else if err = item(t); err != nil {
+			return err
+		}
+		if ok, err := p.accept(","); err != nil {
+			return err
+		} else if !ok {
+			return p.expect(close)
+		}
+	}
+}
+
+// funcType parses the type of a function.
+func (p *parser) funcType(s *scope) (*Func, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	} else if t.is("async") {
+		return nil, p.lex.errorf(t.pos, "async functions are not supported")
+	} else if !t.is("func") {
+		return nil, p.lex.errorf(t.pos, "expected func, but got %s", t)
+	}
+	f := &Func{}
+	if f.Params, err = p.params(s); err != nil {
+		return nil, err
+	}
+	if ok, err := p.accept("->"); err != nil {
+		return nil, err
+	} else if ok {
+		if next, err := p.lex.peek(); err != nil {
+			return nil, err
+		} else if next.is("(") {
+			return nil, p.lex.errorf(next.pos, "named results are not supported")
+		}
+		if f.Result, err = p.typ(s); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// params parses the params of a function between parentheses.
+func (p *parser) params(s *scope) (ret []Field, err error) {
+	err = p.list("(", ")", func(t token) error {
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		typ, err := p.typ(s)
+		ret = append(ret, Field{Name: t.value, Type: typ})
+		return err
+	})
+	return
+}
+
+var primitiveKinds = map[string]TypeKind{
+	"bool": TypeKindBool, "s8": TypeKindS8, "u8": TypeKindU8, "s16": TypeKindS16, "u16": TypeKindU16,
+	"s32": TypeKindS32, "u32": TypeKindU32, "s64": TypeKindS64, "u64": TypeKindU64, "f32": TypeKindF32,
+	"f64": TypeKindF64, "float32": TypeKindF32, "float64": TypeKindF64, "char": TypeKindChar, "string": TypeKindString,
+}
+
+// typ parses a type, whose references are resolved later in the scope.
+func (p *parser) typ(s *scope) (*Type, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	} else if t.kind != tokenID {
+		return nil, p.lex.errorf(t.pos, "expected a type, but got %s", t)
+	}
+	if !t.explicit {
+		if k, ok := primitiveKinds[t.value]; ok {
+			return &Type{Kind: k}, nil
+		}
+		switch t.value {
+		case "list":
+			ret := &Type{Kind: TypeKindList}
+			if err = p.expect("<"); err != nil {
+				return nil, err
+			} else if ret.Elem, err = p.typ(s); err != nil {
+				return nil, err
+			}
+			if ok, err := p.accept(","); err != nil {
+				return nil, err
+			} else if ok {
+				n, err := p.lex.next()
+				if err != nil {
+					return nil, err
+				}
+				length, err := strconv.ParseUint(n.value, 10, 32)
+				if n.kind != tokenInt || err != nil || length == 0 {
+					return nil, p.lex.errorf(n.pos, "invalid length %s", n)
+				}
+				ret.Length = uint32(length)
+			}
+			return ret, p.expect(">")
+		case "option":
+			ret := &Type{Kind: TypeKindOption}
+			if err = p.expect("<"); err != nil {
+				return nil, err
+			} else if ret.Elem, err = p.typ(s); err != nil {
+				return nil, err
+			}
+			return ret, p.expect(">")
+		case "result":
+			ret := &Type{Kind: TypeKindResult}
+			if ok, err := p.accept("<"); err != nil || !ok {
+				return ret, err
+			}
+			if ok, err := p.accept("_"); err != nil {
+				return nil, err
+			} else if !ok {
+				if ret.Elem, err = p.typ(s); err != nil {
+					return nil, err
+				}
+			}
+			if ok, err := p.accept(","); err != nil {
+				return nil, err
+			} else if ok {
+				if ret.Err, err = p.typ(s); err != nil {
+					return nil, err
+				}
+			} else if ret.Elem == nil {
+				return nil, p.lex.errorf(t.pos, "result<_> has no type")
+			}
+			return ret, p.expect(">")
+		case "tuple":
+			ret := &Type{Kind: TypeKindTuple}
+			if err = p.expect("<"); err != nil {
+				return nil, err
+			}
+			for {
+				elem, err := p.typ(s)
+				if err != nil {
+					return nil, err
+				}
+				ret.Fields = append(ret.Fields, Field{Type: elem})
+				if ok, err := p.accept(","); err != nil {
+					return nil, err
+				} else if !ok {
+					break
+				} else if ok, err = p.accept(">"); err != nil || ok {
+					return ret, err
+				}
+			}
+			return ret, p.expect(">")
+		case "borrow", "own":
+			ret := &Type{Kind: TypeKindBorrow}
+			if t.value == "own" {
+				ret.Kind = TypeKindOwn
+			}
+			if err = p.expect("<"); err != nil {
+				return nil, err
+			}
+			name, err := p.lex.next()
+			if err != nil {
+				return nil, err
+			} else if name.kind != tokenID {
+				return nil, p.lex.errorf(name.pos, "expected a resource, but got %s", name)
+			}
+			s.refs = append(s.refs, typeRef{t: ret, name: name.value, pos: name.pos})
+			return ret, p.expect(">")
+		case "future", "stream", "error-context":
+			return nil, p.lex.errorf(t.pos, "%s is not supported", t.value)
+		}
+	}
+	ret := &Type{Kind: TypeKindRef}
+	s.refs = append(s.refs, typeRef{t: ret, name: t.value, pos: t.pos})
+	return ret, nil
+}
+
+// parseWorld parses a world after its keyword.
+func (p *parser) parseWorld(docs string) error {
+	name, err := p.id()
+	if err != nil {
+		return err
+	}
+	w := &World{Name: name, Docs: docs}
+	s := &scope{file: p.file, world: w, types: map[string]*TypeDef{}}
+	p.file.worlds = append(p.file.worlds, w)
+	p.file.scopes = append(p.file.scopes, s)
+	if err = p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		if err = p.skipGates(); err != nil {
+			return err
+		}
+		t, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case t.is("}"):
+			return nil
+		case t.is("use"):
+			err = p.parseUse(s)
+		case t.is("import"), t.is("export"):
+			var item *WorldItem
+			if item, err = p.worldItem(s, t.docs); err != nil {
+				return err
+			}
+			if t.is("import") {
+				w.Imports = append(w.Imports, item)
+			} else {
+				w.Exports = append(w.Exports, item)
+			}
+		case t.is("include"):
+			var path usePath
+			if path, err = p.usePath(); err != nil {
+				return err
+			} else if ok, err := p.accept("with"); err != nil {
+				return err
+			} else if ok {
+				return p.lex.errorf(path.pos, "include with is not supported")
+			}
+			s.includes = append(s.includes, path)
+			err = p.expect(";")
+		case t.kind == tokenID && !t.explicit && isTypeKeyword(t.value):
+			err = p.parseTypeDef(s, t)
+		default:
+			return p.lex.errorf(t.pos, "expected import, export, include, use or a type, but got %s", t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// worldItem parses an import or an export of a world after its keyword.
+func (p *parser) worldItem(s *scope, docs string) (*WorldItem, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	} else if t.kind != tokenID {
+		return nil, p.lex.errorf(t.pos, "expected an interface or a name, but got %s", t)
+	}
+	item := &WorldItem{Name: t.value, Docs: docs}
+	if next, err := p.lex.peek(); err != nil {
+		return nil, err
+	} else if next.is(":") {
+		_, _ = p.lex.next()
+		if next, err = p.lex.peek(); err != nil {
+			return nil, err
+		}
+		switch {
+		case next.is("func"), next.is("async"):
+			if item.Func, err = p.funcType(s); err != nil {
+				return nil, err
+			}
+			item.Func.Name, item.Func.Docs = t.value, docs
+			return item, p.expect(";")
+		case next.is("interface"):
+			_, _ = p.lex.next()
+			if item.Interface, err = p.interfaceBody(t.value, docs, s); err != nil {
+				return nil, err
+			}
+			return item, nil
+		}
+		// This is a qualified interface, whose namespace is t.
+		pkg := PackageName{Namespace: t.value}
+		path := usePath{pkg: &pkg, pos: t.pos}
+		if pkg.Name, err = p.id(); err != nil {
+			return nil, err
+		} else if err = p.expect("/"); err != nil {
+			return nil, err
+		} else if path.name, err = p.id(); err != nil {
+			return nil, err
+		} else if pkg.Version, err = p.version(); err != nil {
+			return nil, err
+		}
+		s.items = append(s.items, itemRef{item: item, path: path})
+		return item, p.expect(";")
+	}
+	s.items = append(s.items, itemRef{item: item, path: usePath{name: t.value, pos: t.pos}})
+	return item, p.expect(";")
+}
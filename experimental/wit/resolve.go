@@ -0,0 +1,540 @@
+package wit
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Parse parses the WIT file of the name, which declares a package whose references are to its own definitions.
+func Parse(name string, src []byte) (*Package, error) {
+	f, err := parseFile(name, src)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := resolve([][]*file{{f}})
+	if err != nil {
+		return nil, err
+	}
+	return pkgs[0], nil
+}
+
+// ParseFS parses the package of the WIT files in the directory dir of fsys, and the packages it depends on, which are
+// in its "deps" directory, either as a WIT file or as a directory of WIT files per package.
+//
+// The packages are returned in the order of their dependencies, which ends with the package of dir.
+func ParseFS(fsys fs.FS, dir string) ([]*Package, error) {
+	var groups [][]*file
+	deps := path.Join(dir, "deps")
+	entries, err := fs.ReadDir(fsys, deps)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		p := path.Join(deps, e.Name())
+		var files []*file
+		if e.IsDir() {
+			files, err = parseDir(fsys, p)
+		} else if strings.HasSuffix(e.Name(), ".wit") {
+			files, err = parseFiles(fsys, p)
+		}
+		if err != nil {
+			return nil, err
+		} else if len(files) > 0 {
+			groups = append(groups, files)
+		}
+	}
+
+	files, err := parseDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	} else if len(files) == 0 {
+		return nil, fmt.Errorf("%s has no WIT files", dir)
+	}
+	pkgs, err := resolve(append(groups, files))
+	if err != nil {
+		return nil, err
+	}
+	main := pkgs[len(groups)]
+	sorted := sortPackages(pkgs)
+	for i, p := range sorted {
+		if p == main {
+			// The package of dir is last, even if some of its dependencies aren't used by it.
+			sorted = append(append(sorted[:i:i], sorted[i+1:]...), main)
+			break
+		}
+	}
+	return sorted, nil
+}
+
+// parseDir parses the WIT files of the directory, in the order of their names.
+func parseDir(fsys fs.FS, dir string) ([]*file, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".wit") {
+			names = append(names, path.Join(dir, e.Name()))
+		}
+	}
+	return parseFiles(fsys, names...)
+}
+
+func parseFiles(fsys fs.FS, names ...string) ([]*file, error) {
+	ret := make([]*file, 0, len(names))
+	for _, name := range names {
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseFile(name, src)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+// resolver resolves the references of the files parsed.
+type resolver struct {
+	pkgs []*Package
+	// files are the files of each package.
+	files  map[*Package][]*file
+	scopes map[*Interface]*scope
+	worlds map[*World]*scope
+	// deps are the interfaces which are used by each interface.
+	deps map[*Interface][]*Interface
+	// uses and includes are 1 when the uses of a scope, or the includes of a world, are being resolved, and 2 once
+	// they are.
+	uses, includes map[*scope]byte
+	// aliases are the types which are the definitions of type aliases.
+	aliases map[*Type]bool
+}
+
+// resolve resolves the files, which are grouped by package.
+func resolve(groups [][]*file) ([]*Package, error) {
+	r := &resolver{
+		files:    map[*Package][]*file{},
+		scopes:   map[*Interface]*scope{},
+		worlds:   map[*World]*scope{},
+		deps:     map[*Interface][]*Interface{},
+		uses:     map[*scope]byte{},
+		includes: map[*scope]byte{},
+		aliases:  map[*Type]bool{},
+	}
+	for _, files := range groups {
+		p, err := r.addPackage(files)
+		if err != nil {
+			return nil, err
+		}
+		r.pkgs = append(r.pkgs, p)
+	}
+
+	var scopes []*scope
+	for _, p := range r.pkgs {
+		for _, f := range r.files[p] {
+			scopes = append(scopes, f.scopes...)
+		}
+	}
+	for _, s := range scopes {
+		if err := r.resolveUses(s); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range scopes {
+		for _, ref := range s.refs {
+			def := s.lookup(ref.name)
+			if def == nil {
+				return nil, fmt.Errorf("%s: type %s is not defined", ref.pos, ref.name)
+			}
+			ref.t.Def = def
+		}
+		for _, def := range s.defs() {
+			if def.Type.Kind == TypeKindRef {
+				r.aliases[def.Type] = true
+			}
+		}
+	}
+	for _, s := range scopes {
+		for _, def := range s.defs() {
+			if err := checkCycle(def, map[*TypeDef]bool{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, s := range scopes {
+		for _, ref := range s.refs {
+			res := resourceOf(ref.t.Def)
+			switch {
+			case ref.t.Kind == TypeKindOwn || ref.t.Kind == TypeKindBorrow:
+				if res == nil {
+					return nil, fmt.Errorf("%s: %s is not a resource", ref.pos, ref.name)
+				}
+				ref.t.Def = res
+			case res != nil && !r.aliases[ref.t]:
+				// A resource is an own handle, unless it's aliased.
+				ref.t.Kind, ref.t.Def = TypeKindOwn, res
+			}
+		}
+	}
+
+	for _, s := range scopes {
+		if s.world == nil {
+			continue
+		}
+		for _, ref := range s.items {
+			iface, err := r.lookupInterface(s, ref.path)
+			if err != nil {
+				return nil, err
+			}
+			ref.item.Interface, ref.item.Name = iface, iface.QualifiedName()
+		}
+	}
+	for _, s := range scopes {
+		if s.world != nil {
+			if err := r.resolveIncludes(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, s := range scopes {
+		if s.world != nil {
+			if err := r.addImplicitImports(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r.pkgs, nil
+}
+
+// addPackage adds the package of the files.
+func (r *resolver) addPackage(files []*file) (*Package, error) {
+	var decl *file
+	for _, f := range files {
+		if f.pkg == nil {
+			continue
+		} else if decl == nil {
+			decl = f
+		} else if *f.pkg != *decl.pkg {
+			return nil, fmt.Errorf("%s: package %s != %s", f.pos, f.pkg, decl.pkg)
+		}
+	}
+	if decl == nil {
+		return nil, fmt.Errorf("%s: package is not declared", files[0].pos)
+	}
+	for _, p := range r.pkgs {
+		if p.Name == *decl.pkg {
+			return nil, fmt.Errorf("%s: package %s is defined twice", decl.pos, p.Name)
+		}
+	}
+
+	p := &Package{Name: *decl.pkg, Docs: decl.pkgDocs}
+	r.files[p] = files
+	names := map[string]bool{}
+	for _, f := range files {
+		for _, s := range f.scopes {
+			var name string
+			switch {
+			case s.parent != nil:
+				continue
+			case s.iface != nil:
+				s.iface.Package, name = p, s.iface.Name
+				p.Interfaces = append(p.Interfaces, s.iface)
+				r.scopes[s.iface] = s
+			default:
+				s.world.Package, name = p, s.world.Name
+				p.Worlds = append(p.Worlds, s.world)
+				r.worlds[s.world] = s
+			}
+			if names[name] {
+				return nil, fmt.Errorf("%s: %s is defined twice in package %s", f.pos, name, p.Name)
+			}
+			names[name] = true
+		}
+		for _, s := range f.scopes {
+			if s.parent != nil {
+				r.scopes[s.iface] = s
+			}
+		}
+	}
+	return p, nil
+}
+
+// defs returns the types defined in the scope, in order.
+func (s *scope) defs() []*TypeDef {
+	if s.iface != nil {
+		return s.iface.Types
+	}
+	return s.world.Types
+}
+
+// lookup returns the type of the name in the scope, or in the world of an inline interface, or nil.
+func (s *scope) lookup(name string) *TypeDef {
+	for ; s != nil; s = s.parent {
+		if def, ok := s.types[name]; ok {
+			return def
+		}
+	}
+	return nil
+}
+
+// pkg returns the package of the scope.
+func (s *scope) pkg() *Package {
+	if s.parent != nil {
+		return s.parent.pkg()
+	} else if s.iface != nil {
+		return s.iface.Package
+	}
+	return s.world.Package
+}
+
+// lookupPackage returns the package of the path, which is the one of the scope if it's not qualified.
+func (r *resolver) lookupPackage(s *scope, path usePath) (*Package, error) {
+	if path.pkg == nil {
+		return s.pkg(), nil
+	}
+	for _, p := range r.pkgs {
+		if p.Name.Namespace == path.pkg.Namespace && p.Name.Name == path.pkg.Name &&
+			(path.pkg.Version == "" || p.Name.Version == path.pkg.Version) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: package %s:%s is not defined", path.pos, path.pkg.Namespace, path.pkg.Name)
+}
+
+// lookupInterface returns the interface of the path, which may be an alias of the file of the scope.
+func (r *resolver) lookupInterface(s *scope, path usePath) (*Interface, error) {
+	if alias, ok := s.file.aliases[path.name]; ok && path.pkg == nil {
+		path = alias
+	}
+	p, err := r.lookupPackage(s, path)
+	if err != nil {
+		return nil, err
+	} else if iface := p.Interface(path.name); iface != nil {
+		return iface, nil
+	}
+	return nil, fmt.Errorf("%s: interface %s is not defined", path.pos, path)
+}
+
+// resolveUses adds the types used by the scope, which requires the uses of the interfaces it uses to be resolved.
+func (r *resolver) resolveUses(s *scope) error {
+	switch r.uses[s] {
+	case 1:
+		return fmt.Errorf("%s: interfaces use each other", s.file.pos)
+	case 2:
+		return nil
+	}
+	r.uses[s] = 1
+	for _, u := range s.uses {
+		iface, err := r.lookupInterface(s, u.path)
+		if err != nil {
+			return err
+		}
+		used := r.scopes[iface]
+		if used == s {
+			return fmt.Errorf("%s: interface %s uses itself", u.path.pos, iface.Name)
+		} else if err = r.resolveUses(used); err != nil {
+			return err
+		}
+		if s.iface != nil {
+			r.deps[s.iface] = append(r.deps[s.iface], iface)
+		}
+		for _, n := range u.names {
+			def := used.types[n.name]
+			if def == nil {
+				return fmt.Errorf("%s: interface %s has no type %s", n.pos, iface.Name, n.name)
+			}
+			alias := &TypeDef{Name: n.as, Docs: n.docs, Type: &Type{Kind: TypeKindRef, Def: def}}
+			if _, ok := s.types[n.as]; ok {
+				return fmt.Errorf("%s: type %s is defined twice", n.pos, n.as)
+			}
+			s.types[n.as] = alias
+			if s.iface != nil {
+				alias.Interface = s.iface
+				s.iface.Types = append(s.iface.Types, alias)
+			} else {
+				s.world.Types = append(s.world.Types, alias)
+			}
+		}
+	}
+	r.uses[s] = 2
+	return nil
+}
+
+// resolveIncludes adds the imports and the exports of the worlds included by the world of the scope.
+func (r *resolver) resolveIncludes(s *scope) error {
+	switch r.includes[s] {
+	case 1:
+		return fmt.Errorf("%s: world %s includes itself", s.file.pos, s.world.Name)
+	case 2:
+		return nil
+	}
+	r.includes[s] = 1
+	w := s.world
+	for _, path := range s.includes {
+		p, err := r.lookupPackage(s, path)
+		if err != nil {
+			return err
+		}
+		included := p.World(path.name)
+		if included == nil {
+			return fmt.Errorf("%s: world %s is not defined", path.pos, path)
+		} else if err = r.resolveIncludes(r.worlds[included]); err != nil {
+			return err
+		}
+		for _, item := range included.Imports {
+			if w.Import(item.Name) == nil {
+				w.Imports = append(w.Imports, item)
+			}
+		}
+		for _, item := range included.Exports {
+			if w.Export(item.Name) == nil {
+				w.Exports = append(w.Exports, item)
+			}
+		}
+	}
+	r.includes[s] = 2
+	return nil
+}
+
+// addImplicitImports imports the interfaces used by the interfaces of the world, unless they are exported, and checks
+// that the names of its imports and its exports are unique.
+func (r *resolver) addImplicitImports(s *scope) error {
+	w := s.world
+	var implicit []*WorldItem
+	seen := map[*Interface]bool{}
+	var add func(iface *Interface)
+	add = func(iface *Interface) {
+		if seen[iface] {
+			return
+		}
+		seen[iface] = true
+		for _, dep := range r.deps[iface] {
+			add(dep)
+		}
+		if w.Import(iface.QualifiedName()) == nil && w.Export(iface.QualifiedName()) == nil {
+			implicit = append(implicit, &WorldItem{Name: iface.QualifiedName(), Interface: iface})
+		}
+	}
+	for _, u := range s.uses {
+		iface, err := r.lookupInterface(s, u.path)
+		if err != nil {
+			return err
+		}
+		add(iface)
+	}
+	for _, items := range [][]*WorldItem{w.Imports, w.Exports} {
+		for _, item := range items {
+			if item.Interface != nil {
+				for _, dep := range r.deps[item.Interface] {
+					add(dep)
+				}
+			}
+		}
+	}
+	w.Imports = append(implicit, w.Imports...)
+
+	for _, items := range [][]*WorldItem{w.Imports, w.Exports} {
+		names := map[string]bool{}
+		for _, item := range items {
+			if names[item.Name] {
+				return fmt.Errorf("%s: %s is imported or exported twice by world %s", s.file.pos, item.Name, w.Name)
+			}
+			names[item.Name] = true
+		}
+	}
+	return nil
+}
+
+// resourceOf returns the resource defined by the type, which may be an alias of it, or nil if it isn't one.
+func resourceOf(def *TypeDef) *TypeDef {
+	for def.Type.Kind == TypeKindRef {
+		def = def.Type.Def
+	}
+	if def.Type.Kind == TypeKindResource {
+		return def
+	}
+	return nil
+}
+
+// checkCycle returns an error if the type is defined by itself.
+func checkCycle(def *TypeDef, visiting map[*TypeDef]bool) error {
+	if visiting[def] {
+		return fmt.Errorf("type %s is recursive", def.Name)
+	}
+	visiting[def] = true
+	defer delete(visiting, def)
+	var check func(t *Type) error
+	check = func(t *Type) error {
+		if t == nil {
+			return nil
+		} else if t.Kind == TypeKindRef {
+			return checkCycle(t.Def, visiting)
+		} else if err := check(t.Elem); err != nil {
+			return err
+		} else if err = check(t.Err); err != nil {
+			return err
+		}
+		for _, f := range t.Fields {
+			if err := check(f.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(def.Type)
+}
+
+// sortPackages sorts the packages in the order of their dependencies.
+func sortPackages(pkgs []*Package) []*Package {
+	deps := map[*Package]map[*Package]bool{}
+	for _, p := range pkgs {
+		deps[p] = map[*Package]bool{}
+		for _, i := range p.Interfaces {
+			for _, t := range i.Types {
+				if t.Type.Kind == TypeKindRef && t.Type.Def.Interface != nil && t.Type.Def.Interface.Package != p &&
+					t.Type.Def.Interface.Package != nil {
+					deps[p][t.Type.Def.Interface.Package] = true
+				}
+			}
+		}
+		for _, w := range p.Worlds {
+			for _, items := range [][]*WorldItem{w.Imports, w.Exports} {
+				for _, item := range items {
+					if item.Interface != nil && item.Interface.Package != nil && item.Interface.Package != p {
+						deps[p][item.Interface.Package] = true
+					}
+				}
+			}
+		}
+	}
+
+	var ret []*Package
+	done := map[*Package]bool{}
+	var visit func(p *Package)
+	visit = func(p *Package) {
+		if done[p] {
+			return
+		}
+		done[p] = true
+		var sorted []*Package
+		for dep := range deps[p] {
+			sorted = append(sorted, dep)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name.String() < sorted[j].Name.String() })
+		for _, dep := range sorted {
+			visit(dep)
+		}
+		ret = append(ret, p)
+	}
+	for _, p := range pkgs {
+		visit(p)
+	}
+	return ret
+}
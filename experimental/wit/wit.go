@@ -0,0 +1,388 @@
+// Package wit parses the WebAssembly Interface Type (WIT) definitions of the interfaces and the worlds of components.
+//
+// The definitions can validate the imports of the components compiled with a context given by WithWorld, and the Go
+// bindings of their interfaces are generated by the `wazero bindgen` command.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/WIT.md
+package wit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/expctxkeys"
+)
+
+// WithWorld returns a context which validates the imports of the components compiled with it against the imports of
+// the world, whose functions must have the same types.
+//
+// For example, this fails to compile a component importing a function which isn't imported by the world:
+//
+//	ctx = wit.WithWorld(ctx, world)
+//	compiled, err := r.CompileModule(ctx, componentBinary)
+func WithWorld(ctx context.Context, w *World) context.Context {
+	if w == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, expctxkeys.WITWorldKey{}, w)
+}
+
+// PackageName is the name of a package, e.g. "wasi:io@0.2.0".
+type PackageName struct {
+	Namespace, Name string
+	// Version is the semantic version of the package, or empty if it has none.
+	Version string
+}
+
+// String returns the name as in WIT.
+func (n PackageName) String() string {
+	if n.Version == "" {
+		return n.Namespace + ":" + n.Name
+	}
+	return n.Namespace + ":" + n.Name + "@" + n.Version
+}
+
+// Package is a package of interfaces and worlds.
+type Package struct {
+	Name       PackageName
+	Docs       string
+	Interfaces []*Interface
+	Worlds     []*World
+}
+
+// Interface returns the interface of the name, or nil if there's none.
+func (p *Package) Interface(name string) *Interface {
+	for _, i := range p.Interfaces {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// World returns the world of the name, or nil if there's none.
+func (p *Package) World(name string) *World {
+	for _, w := range p.Worlds {
+		if w.Name == name {
+			return w
+		}
+	}
+	return nil
+}
+
+// Interface is a named set of types and functions, which is imported or exported by a world as an instance.
+type Interface struct {
+	// Name is the name of the interface, which is empty if it's defined inline in a world.
+	Name string
+	Docs string
+	// Package is the package of the interface, which is nil if it's defined inline in a world.
+	Package *Package
+	Types   []*TypeDef
+	// Funcs are the functions of the interface, including the constructors, the methods and the static functions of
+	// its resources.
+	Funcs []*Func
+}
+
+// QualifiedName returns the name of the instance of the interface, e.g. "wasi:io/streams@0.2.0", which is its name
+// if it's defined inline in a world.
+func (i *Interface) QualifiedName() string {
+	if i.Package == nil {
+		return i.Name
+	}
+	n := i.Package.Name
+	ret := n.Namespace + ":" + n.Name + "/" + i.Name
+	if n.Version != "" {
+		ret += "@" + n.Version
+	}
+	return ret
+}
+
+// Type returns the type definition of the name, or nil if there's none.
+func (i *Interface) Type(name string) *TypeDef {
+	for _, t := range i.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Func returns the function of the extern name, e.g. "[method]file.read", or nil if there's none.
+func (i *Interface) Func(externName string) *Func {
+	for _, f := range i.Funcs {
+		if f.ExternName() == externName {
+			return f
+		}
+	}
+	return nil
+}
+
+// World is the set of the imports and the exports of a component.
+type World struct {
+	Name    string
+	Docs    string
+	Package *Package
+	// Types are the types defined by the world, which are imported by the component.
+	Types   []*TypeDef
+	Imports []*WorldItem
+	Exports []*WorldItem
+}
+
+// Import returns the import of the name, or nil if there's none.
+func (w *World) Import(name string) *WorldItem {
+	return findItem(w.Imports, name)
+}
+
+// Export returns the export of the name, or nil if there's none.
+func (w *World) Export(name string) *WorldItem {
+	return findItem(w.Exports, name)
+}
+
+func findItem(items []*WorldItem, name string) *WorldItem {
+	for _, item := range items {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// WorldItem is an import or an export of a world, which is either an interface or a function.
+type WorldItem struct {
+	// Name is the name of the import or the export, which is the qualified name of an interface defined in a package.
+	Name string
+	Docs string
+	// Interface is the interface imported or exported, or nil if it's a function.
+	Interface *Interface
+	// Func is the function imported or exported, or nil if it's an interface.
+	Func *Func
+}
+
+// TypeDef is a named type.
+type TypeDef struct {
+	Name string
+	Docs string
+	// Interface is the interface which defines the type, or nil if it's defined by a world.
+	Interface *Interface
+	// Type is the type defined, which is a TypeKindResource for a resource.
+	Type *Type
+}
+
+// FuncKind is the kind of a function.
+type FuncKind byte
+
+const (
+	// FuncKindFreestanding is a function which isn't a function of a resource.
+	FuncKindFreestanding FuncKind = iota
+	// FuncKindMethod is a method of a resource, whose first param is the borrowed "self".
+	FuncKindMethod
+	// FuncKindStatic is a static function of a resource.
+	FuncKindStatic
+	// FuncKindConstructor is the constructor of a resource, whose result is an own handle.
+	FuncKindConstructor
+)
+
+// Func is a function.
+type Func struct {
+	// Name is the name of the function, which is "constructor" for a constructor.
+	Name string
+	Docs string
+	Kind FuncKind
+	// Resource is the resource of a method, a static function or a constructor.
+	Resource *TypeDef
+	Params   []Field
+	// Result is the type of the result, or nil if there's none.
+	Result *Type
+}
+
+// ExternName returns the name of the function in an instance, e.g. "[method]file.read".
+func (f *Func) ExternName() string {
+	switch f.Kind {
+	case FuncKindMethod:
+		return "[method]" + f.Resource.Name + "." + f.Name
+	case FuncKindStatic:
+		return "[static]" + f.Resource.Name + "." + f.Name
+	case FuncKindConstructor:
+		return "[constructor]" + f.Resource.Name
+	}
+	return f.Name
+}
+
+// TypeKind is the kind of a type.
+type TypeKind byte
+
+const (
+	TypeKindBool TypeKind = iota
+	TypeKindS8
+	TypeKindU8
+	TypeKindS16
+	TypeKindU16
+	TypeKindS32
+	TypeKindU32
+	TypeKindS64
+	TypeKindU64
+	TypeKindF32
+	TypeKindF64
+	TypeKindChar
+	TypeKindString
+
+	TypeKindList
+	TypeKindOption
+	TypeKindResult
+	TypeKindTuple
+	TypeKindRecord
+	TypeKindVariant
+	TypeKindEnum
+	TypeKindFlags
+	TypeKindResource
+	TypeKindOwn
+	TypeKindBorrow
+	// TypeKindRef is a reference to the type defined by Def.
+	TypeKindRef
+)
+
+var primitiveNames = [...]string{
+	TypeKindBool:   "bool",
+	TypeKindS8:     "s8",
+	TypeKindU8:     "u8",
+	TypeKindS16:    "s16",
+	TypeKindU16:    "u16",
+	TypeKindS32:    "s32",
+	TypeKindU32:    "u32",
+	TypeKindS64:    "s64",
+	TypeKindU64:    "u64",
+	TypeKindF32:    "f32",
+	TypeKindF64:    "f64",
+	TypeKindChar:   "char",
+	TypeKindString: "string",
+}
+
+// Type is a type.
+type Type struct {
+	Kind TypeKind
+	// Elem is the element type of a list or an option, or the ok type of a result, or nil if it has none.
+	Elem *Type
+	// Err is the error type of a result, or nil if it has none.
+	Err *Type
+	// Length is the length of a list of a fixed length, or zero for the other lists.
+	Length uint32
+	// Fields are the fields of a record, the cases of a variant whose Type is nil if they have no payload, or the
+	// elements of a tuple, whose Name is empty.
+	Fields []Field
+	// Labels are the cases of an enum, or the flags of flags.
+	Labels []string
+	// Def is the type referenced by a TypeKindRef, or the resource of an own or a borrow handle.
+	Def *TypeDef
+}
+
+// Field is a named type of a Type, or a param of a Func.
+type Field struct {
+	Name string
+	Docs string
+	Type *Type
+}
+
+// IsPrimitive returns true if the type is a primitive, whose kind is at most TypeKindString.
+func (t *Type) IsPrimitive() bool {
+	return t.Kind <= TypeKindString
+}
+
+// Resolve returns the type defined by the references of the type, if any.
+func (t *Type) Resolve() *Type {
+	for t != nil && t.Kind == TypeKindRef {
+		t = t.Def.Type
+	}
+	return t
+}
+
+// String returns the type as in WIT, where the types defined are referenced by their name.
+func (t *Type) String() string {
+	var b strings.Builder
+	t.format(&b)
+	return b.String()
+}
+
+func (t *Type) format(b *strings.Builder) {
+	if t == nil {
+		b.WriteByte('_')
+		return
+	}
+	if t.IsPrimitive() {
+		b.WriteString(primitiveNames[t.Kind])
+		return
+	}
+	switch t.Kind {
+	case TypeKindList:
+		b.WriteString("list<")
+		t.Elem.format(b)
+		if t.Length > 0 {
+			fmt.Fprintf(b, ", %d", t.Length)
+		}
+		b.WriteByte('>')
+	case TypeKindOption:
+		b.WriteString("option<")
+		t.Elem.format(b)
+		b.WriteByte('>')
+	case TypeKindResult:
+		b.WriteString("result")
+		if t.Elem != nil || t.Err != nil {
+			b.WriteByte('<')
+			t.Elem.format(b)
+			if t.Err != nil {
+				b.WriteString(", ")
+				t.Err.format(b)
+			}
+			b.WriteByte('>')
+		}
+	case TypeKindTuple:
+		b.WriteString("tuple<")
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			f.Type.format(b)
+		}
+		b.WriteByte('>')
+	case TypeKindOwn:
+		b.WriteString(t.Def.Name)
+	case TypeKindBorrow:
+		b.WriteString("borrow<" + t.Def.Name + ">")
+	case TypeKindRef:
+		b.WriteString(t.Def.Name)
+	case TypeKindRecord:
+		b.WriteString("record { ")
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(f.Name + ": ")
+			f.Type.format(b)
+		}
+		b.WriteString(" }")
+	case TypeKindVariant:
+		b.WriteString("variant { ")
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(f.Name)
+			if f.Type != nil {
+				b.WriteByte('(')
+				f.Type.format(b)
+				b.WriteByte(')')
+			}
+		}
+		b.WriteString(" }")
+	case TypeKindEnum, TypeKindFlags:
+		if t.Kind == TypeKindEnum {
+			b.WriteString("enum { ")
+		} else {
+			b.WriteString("flags { ")
+		}
+		b.WriteString(strings.Join(t.Labels, ", "))
+		b.WriteString(" }")
+	case TypeKindResource:
+		b.WriteString("resource")
+	}
+}
@@ -0,0 +1,208 @@
+package wit
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// testFS is a package which uses the interfaces of a dependency.
+var testFS = fstest.MapFS{
+	"deps/io/error.wit": {Data: []byte(`package wasi:io@0.2.0;
+
+/// An error.
+interface error {
+  resource error {
+    to-debug-string: func() -> string;
+  }
+}
+`)},
+	"deps/io/streams.wit": {Data: []byte(`package wasi:io@0.2.0;
+
+@since(version = 0.2.0)
+interface streams {
+  use error.{error};
+
+  variant stream-error { last-operation-failed(error), closed }
+
+  resource input-stream {
+    /// Reads up to len bytes.
+    read: func(len: u64) -> result<list<u8>, stream-error>;
+  }
+}
+`)},
+	"host.wit": {Data: []byte(`/// The host.
+package test:host@1.0.0;
+
+interface api {
+  use wasi:io/streams@0.2.0.{input-stream as input};
+
+  /** A point. */
+  record point { x: s32, y: s32, }
+  enum color { red, green, blue }
+  flags perms { read, write }
+  type points = list<point>;
+
+  resource counter {
+    constructor(start: u32);
+    inc: func() -> u32;
+    make: static func() -> counter;
+  }
+
+  greet: func(name: string) -> string;
+  sum: func(ps: points, c: borrow<counter>, s: option<tuple<u8, string>>) -> result<_, color>;
+  open: func() -> input;
+}
+
+world host {
+  import api;
+  import log: func(msg: string);
+  export run: func() -> u32;
+  export calc: interface {
+    add: func(a: u32, b: u32) -> u32;
+  }
+}
+`)},
+}
+
+func TestParseFS(t *testing.T) {
+	pkgs, err := ParseFS(testFS, ".")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(pkgs))
+	require.Equal(t, "wasi:io@0.2.0", pkgs[0].Name.String())
+
+	pkg := pkgs[1]
+	require.Equal(t, PackageName{Namespace: "test", Name: "host", Version: "1.0.0"}, pkg.Name)
+	require.Equal(t, "The host.", pkg.Docs)
+
+	api := pkg.Interface("api")
+	require.Equal(t, "test:host/api@1.0.0", api.QualifiedName())
+	require.Equal(t, "A point.", api.Type("point").Docs)
+	require.Equal(t, "record { x: s32, y: s32 }", api.Type("point").Type.String())
+	require.Equal(t, "enum { red, green, blue }", api.Type("color").Type.String())
+	require.Equal(t, "flags { read, write }", api.Type("perms").Type.String())
+	require.Equal(t, "list<point>", api.Type("points").Type.String())
+
+	// The type used is an alias of the resource of the other package.
+	stream := api.Type("input")
+	require.Equal(t, TypeKindRef, stream.Type.Kind)
+	streams := pkgs[0].Interface("streams")
+	require.Equal(t, streams.Type("input-stream"), stream.Type.Def)
+	require.Equal(t, "Reads up to len bytes.", streams.Func("[method]input-stream.read").Docs)
+	require.Equal(t, "variant { last-operation-failed(error), closed }", streams.Type("stream-error").Type.String())
+
+	var names []string
+	for _, f := range api.Funcs {
+		names = append(names, f.ExternName())
+	}
+	require.Equal(t, []string{
+		"[constructor]counter", "[method]counter.inc", "[static]counter.make", "greet", "sum", "open",
+	}, names)
+
+	inc := api.Func("[method]counter.inc")
+	require.Equal(t, FuncKindMethod, inc.Kind)
+	require.Equal(t, "self", inc.Params[0].Name)
+	require.Equal(t, "borrow<counter>", inc.Params[0].Type.String())
+
+	sum := api.Func("sum")
+	require.Equal(t, "points", sum.Params[0].Type.String())
+	require.Equal(t, "option<tuple<u8, string>>", sum.Params[2].Type.String())
+	require.Equal(t, "result<_, color>", sum.Result.String())
+
+	// A bare resource is an own handle of the resource it's an alias of.
+	open := api.Func("open")
+	require.Equal(t, TypeKindOwn, open.Result.Kind)
+	require.Equal(t, streams.Type("input-stream"), open.Result.Def)
+
+	w := pkg.World("host")
+	names = names[:0]
+	for _, item := range w.Imports {
+		names = append(names, item.Name)
+	}
+	// The interfaces used are imported first, in the order of their dependencies.
+	require.Equal(t, []string{"wasi:io/error@0.2.0", "wasi:io/streams@0.2.0", "test:host/api@1.0.0", "log"}, names)
+	require.Equal(t, api, w.Import("test:host/api@1.0.0").Interface)
+	require.Equal(t, "msg", w.Import("log").Func.Params[0].Name)
+	require.Nil(t, w.Import("api"))
+
+	require.Equal(t, "u32", w.Export("run").Func.Result.String())
+	calc := w.Export("calc").Interface
+	require.Equal(t, "calc", calc.QualifiedName())
+	require.Equal(t, 2, len(calc.Func("add").Params))
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name, src, expectedErr string
+	}{
+		{
+			name:        "no package",
+			src:         "interface a {}",
+			expectedErr: "a.wit:1:1: package is not declared",
+		},
+		{
+			name:        "invalid identifier",
+			src:         "package a:b;\ninterface Bad-name {}",
+			expectedErr: `a.wit:2:11: invalid identifier "Bad-name"`,
+		},
+		{
+			name:        "unterminated comment",
+			src:         "package a:b;\ninterface a { /* x }",
+			expectedErr: "a.wit:2:15: unterminated comment",
+		},
+		{
+			name:        "undefined type",
+			src:         "package a:b;\ninterface a { f: func() -> undefined; }",
+			expectedErr: "a.wit:2:28: type undefined is not defined",
+		},
+		{
+			name:        "recursive type",
+			src:         "package a:b;\ninterface a { type t = u; type u = t; }",
+			expectedErr: "type t is recursive",
+		},
+		{
+			name:        "type defined twice",
+			src:         "package a:b;\ninterface a { type t = u8; type t = u8; }",
+			expectedErr: "a.wit:2:28: type t is defined twice",
+		},
+		{
+			name:        "function defined twice",
+			src:         "package a:b;\ninterface a { f: func(); f: func(); }",
+			expectedErr: "a.wit:2:26: function f is defined twice",
+		},
+		{
+			name:        "empty record",
+			src:         "package a:b;\ninterface a { record r {} }",
+			expectedErr: "a.wit:2:15: record r is empty",
+		},
+		{
+			name:        "empty list",
+			src:         "package a:b;\ninterface a { f: func() -> list<u8, 0>; }",
+			expectedErr: `a.wit:2:37: invalid length "0"`,
+		},
+		{
+			name:        "async",
+			src:         "package a:b;\ninterface a { f: async func(); }",
+			expectedErr: "a.wit:2:18: async functions are not supported",
+		},
+		{
+			name:        "stream",
+			src:         "package a:b;\ninterface a { f: func() -> stream<u8>; }",
+			expectedErr: "a.wit:2:28: stream is not supported",
+		},
+		{
+			name:        "undefined interface",
+			src:         "package a:b;\nworld w { import c; }",
+			expectedErr: "a.wit:2:18: interface c is not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse("a.wit", []byte(tc.src))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package wazero
 
 import (
+	"fmt"
 	"io/fs"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
@@ -129,6 +130,31 @@ type FSConfig interface {
 	//
 	// See sys.NewStat_t for examples.
 	WithFSMount(fs fs.FS, guestPath string) FSConfig
+
+	// WithMountRights limits the WASI rights of the directory mounted at
+	// `guestPath`. This panics if nothing is mounted at `guestPath` yet.
+	//
+	// `base` are the rights of the pre-opened directory itself, and
+	// `inheriting` are the most rights of files and directories opened from
+	// it. Both are bit sets of the rights defined in the wasi_snapshot_preview1
+	// package. For example, this lets the guest create files in "/logs", but
+	// only append to them:
+	//
+	//	rights := wasi_snapshot_preview1.RightsAppendOnly
+	//	fsConfig = fsConfig.WithDirMount("/var/log/app", "/logs").
+	//		WithMountRights("/logs", rights, rights)
+	//
+	// # Notes
+	//
+	//   - Unlike WithReadOnlyDirMount, rights are enforced per file
+	//     descriptor. A guest can narrow them, via `fd_fdstat_set_rights`,
+	//     but never widen them.
+	//   - Rights are enforced by "wasi_snapshot_preview1" functions. Later
+	//     WASI versions don't define them, so "wasi_preview2" maps them onto
+	//     the descriptor-flags "read", "write" and "mutate-directory".
+	//   - Rights don't restrict the host. For example, the guest can still
+	//     escape the directory via relative path lookups like "../../".
+	WithMountRights(guestPath string, base, inheriting uint64) FSConfig
 }
 
 type fsConfig struct {
@@ -140,11 +166,14 @@ type fsConfig struct {
 	// guestPathToFS are the normalized paths to the currently configured
 	// filesystems, used for de-duplicating.
 	guestPathToFS map[string]int
+	// guestPathToRights are the normalized paths to the rights of the
+	// filesystems, if limited by WithMountRights.
+	guestPathToRights map[string]*sys.Rights
 }
 
 // NewFSConfig returns a FSConfig that can be used for configuring module instantiation.
 func NewFSConfig() FSConfig {
-	return &fsConfig{guestPathToFS: map[string]int{}}
+	return &fsConfig{guestPathToFS: map[string]int{}, guestPathToRights: map[string]*sys.Rights{}}
 }
 
 // clone makes a deep copy of this module config.
@@ -158,6 +187,10 @@ func (c *fsConfig) clone() *fsConfig {
 	for key, value := range c.guestPathToFS {
 		ret.guestPathToFS[key] = value
 	}
+	ret.guestPathToRights = make(map[string]*sys.Rights, len(c.guestPathToRights))
+	for key, value := range c.guestPathToRights {
+		ret.guestPathToRights[key] = value
+	}
 	return &ret
 }
 
@@ -198,16 +231,34 @@ func (c *fsConfig) WithSysFSMount(fs experimentalsys.FS, guestPath string) FSCon
 	return ret
 }
 
+// WithMountRights implements FSConfig.WithMountRights
+func (c *fsConfig) WithMountRights(guestPath string, base, inheriting uint64) FSConfig {
+	cleaned := sys.StripPrefixesAndTrailingSlash(guestPath)
+	if _, ok := c.guestPathToFS[cleaned]; !ok {
+		panic(fmt.Errorf("WithMountRights: nothing is mounted at %q", guestPath))
+	}
+	ret := c.clone()
+	ret.guestPathToRights[cleaned] = &sys.Rights{Base: base, Inheriting: inheriting}
+	return ret
+}
+
 // preopens returns the possible nil index-correlated preopened filesystems
-// with guest paths.
-func (c *fsConfig) preopens() ([]experimentalsys.FS, []string) {
+// with guest paths and rights, where nil rights are unrestricted.
+func (c *fsConfig) preopens() ([]experimentalsys.FS, []string, []*sys.Rights) {
 	preopenCount := len(c.fs)
 	if preopenCount == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 	fs := make([]experimentalsys.FS, len(c.fs))
 	copy(fs, c.fs)
 	guestPaths := make([]string, len(c.guestPaths))
 	copy(guestPaths, c.guestPaths)
-	return fs, guestPaths
+	if len(c.guestPathToRights) == 0 {
+		return fs, guestPaths, nil
+	}
+	rights := make([]*sys.Rights, len(c.fs))
+	for guestPath, i := range c.guestPathToFS {
+		rights[i] = c.guestPathToRights[guestPath]
+	}
+	return fs, guestPaths, rights
 }
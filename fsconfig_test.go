@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
 	testfs "github.com/tetratelabs/wazero/internal/testing/fs"
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -21,6 +22,7 @@ func TestFSConfig(t *testing.T) {
 		input              FSConfig
 		expectedFS         []sys.FS
 		expectedGuestPaths []string
+		expectedRights     []*internalsys.Rights
 	}{
 		{
 			name:  "empty",
@@ -54,19 +56,34 @@ func TestFSConfig(t *testing.T) {
 			expectedFS:         []sys.FS{&sysfs.ReadFS{FS: sysfs.DirFS(".")}, sysfs.DirFS("/tmp")},
 			expectedGuestPaths: []string{"/", "/tmp"},
 		},
+		{
+			name:               "WithMountRights",
+			input:              base.WithDirMount(".", "/").WithDirMount("/tmp", "/tmp").WithMountRights("tmp/", 1, 2),
+			expectedFS:         []sys.FS{sysfs.DirFS("."), sysfs.DirFS("/tmp")},
+			expectedGuestPaths: []string{"/", "/tmp"},
+			expectedRights:     []*internalsys.Rights{nil, {Base: 1, Inheriting: 2}},
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			fs, guestPaths := tc.input.(*fsConfig).preopens()
+			fs, guestPaths, rights := tc.input.(*fsConfig).preopens()
 			require.Equal(t, tc.expectedFS, fs)
 			require.Equal(t, tc.expectedGuestPaths, guestPaths)
+			require.Equal(t, tc.expectedRights, rights)
 		})
 	}
 }
 
+func TestFSConfig_WithMountRights_NotMounted(t *testing.T) {
+	base := NewFSConfig().WithDirMount(".", "/tmp")
+
+	err := require.CapturePanic(func() { base.WithMountRights("/", 1, 2) })
+	require.EqualError(t, err, `WithMountRights: nothing is mounted at "/"`)
+}
+
 func TestFSConfig_clone(t *testing.T) {
 	fc := NewFSConfig().(*fsConfig)
 	fc.guestPathToFS["/"] = 0
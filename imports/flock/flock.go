@@ -0,0 +1,132 @@
+// Package flock contains the Go-defined function imported by WebAssembly
+// which needs whole-file advisory locks, as WASI has none.
+//
+// The function "flock" of the module ModuleName is like BSD `flock`, on the
+// file descriptors opened with WASI. It's imported in C like so, and can
+// implement `flock` for wasi-libc:
+//
+//	__attribute__((import_module("flock"), import_name("flock")))
+//	int __wazero_flock(int fd, int operation);
+//
+// The result is zero on success, or else a WASI errno, as in wasi-libc.
+//
+// Note: These aren't the byte-range locks of `fcntl` (F_SETLK), which this
+// doesn't provide. For example, SQLite needs to be built with its "unix-flock"
+// VFS, as its default "unix" VFS uses `fcntl`.
+//
+// A shared lock needs the right RightFdRead of wasi_snapshot_preview1, and an
+// exclusive lock the right RightFdWrite, or else "flock" returns EBADF.
+//
+// # Locks
+//
+// Locks are only supported by files opened by wazero.FSConfig WithDirMount
+// (or WithReadOnlyDirMount) on Unix, except AIX and Solaris, or by a
+// sys.FS whose files implement sys.Locker. Otherwise, "flock" returns ENOSYS.
+//
+// Locks belong to the open file, not the module: they conflict between
+// modules, and between files opened more than once by the same module, as
+// with BSD `flock`. So, several module instances, in this process or others,
+// can safely share a database in the same directory.
+//
+// See https://man.freebsd.org/cgi/man.cgi?query=flock&sektion=2
+package flock
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+const (
+	// ModuleName is the module name the function FlockName is exported into.
+	ModuleName = "flock"
+
+	// FlockName is the name of the function which places, converts or
+	// removes an advisory lock on a file descriptor.
+	FlockName = "flock"
+)
+
+// Operations of FlockName, which are the same as in BSD `flock`. Exactly one
+// of LockSh, LockEx or LockUn must be set, optionally with LockNb.
+const (
+	// LockSh places a shared lock.
+	LockSh = uint32(experimentalsys.LOCK_SH)
+	// LockEx places an exclusive lock.
+	LockEx = uint32(experimentalsys.LOCK_EX)
+	// LockNb returns EAGAIN instead of blocking on a conflicting lock.
+	LockNb = uint32(experimentalsys.LOCK_NB)
+	// LockUn removes a lock.
+	LockUn = uint32(experimentalsys.LOCK_UN)
+)
+
+const i32 = wasm.ValueTypeI32
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+//   - Without LockNb, FlockName blocks until the lock is placed, which isn't
+//     interrupted by wazero.RuntimeConfig WithCloseOnContextDone.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	return NewBuilder(r).Instantiate(ctx)
+}
+
+// NewBuilder returns a new wazero.HostModuleBuilder for ModuleName, which
+// can be compiled instead of instantiated.
+func NewBuilder(r wazero.Runtime) wazero.HostModuleBuilder {
+	builder := r.NewHostModuleBuilder(ModuleName)
+	builder.(wasm.HostFuncExporter).ExportHostFunc(&wasm.HostFunc{
+		ExportName:  FlockName,
+		Name:        FlockName,
+		ParamTypes:  []api.ValueType{i32, i32},
+		ParamNames:  []string{"fd", "operation"},
+		ResultTypes: []api.ValueType{i32},
+		ResultNames: []string{"errno"},
+		Code:        wasm.Code{GoFunc: api.GoModuleFunc(flock)},
+	})
+	return builder
+}
+
+// flock implements FlockName, which returns a WASI errno.
+func flock(_ context.Context, mod api.Module, stack []uint64) {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	fd, how := int32(stack[0]), experimentalsys.LockFlag(uint32(stack[1]))
+
+	var errno experimentalsys.Errno
+	if f, ok := fsc.LookupFile(fd); !ok || !f.HasRights(lockRights(how)) {
+		errno = experimentalsys.EBADF
+	} else if l, ok := f.File.(experimentalsys.Locker); !ok {
+		errno = experimentalsys.ENOSYS
+	} else {
+		errno = l.Lock(how)
+	}
+	stack[0] = uint64(wasip1.ToErrno(errno))
+}
+
+// lockRights returns the WASI rights a file descriptor needs for the lock, like
+// `fcntl` locks need the file to be opened for read or write.
+func lockRights(how experimentalsys.LockFlag) uint64 {
+	switch how &^ experimentalsys.LOCK_NB {
+	case experimentalsys.LOCK_SH:
+		return wasip1.RIGHT_FD_READ
+	case experimentalsys.LOCK_EX:
+		return wasip1.RIGHT_FD_WRITE
+	}
+	return 0 // LOCK_UN, or invalid, which Lock rejects.
+}
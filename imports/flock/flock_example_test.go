@@ -0,0 +1,63 @@
+//go:build unix && !(aix || solaris)
+
+package flock_test
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/flock"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// lockWasm is the binary format of testdata/lock.wat
+//
+//go:embed testdata/lock.wasm
+var lockWasm []byte
+
+// This shows how to instantiate the function which locks files, for several
+// instances of a module which share a file in the same directory.
+func Example_lock() {
+	ctx := context.Background()
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx) // This closes everything this Runtime created.
+
+	// The guest opens files with WASI, and locks them with flock.
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	flock.MustInstantiate(ctx, r)
+
+	dir, err := os.MkdirTemp("", "flock")
+	if err != nil {
+		log.Panicln(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := wazero.NewModuleConfig().WithFSConfig(wazero.NewFSConfig().WithDirMount(dir, "/"))
+	compiled, err := r.CompileModule(ctx, lockWasm)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	// Each instance opens the file itself, so the exclusive lock of one
+	// conflicts with the others.
+	for _, name := range []string{"a", "b"} {
+		mod, err := r.InstantiateModule(ctx, compiled, config.WithName(name))
+		if err != nil {
+			log.Panicln(err)
+		}
+		results, err := mod.ExportedFunction("lock").Call(ctx, uint64(flock.LockEx|flock.LockNb))
+		if err != nil {
+			log.Panicln(err)
+		}
+		fmt.Printf("%s: errno=%d\n", name, results[0])
+	}
+
+	// Output:
+	// a: errno=0
+	// b: errno=6
+}
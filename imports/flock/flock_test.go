@@ -0,0 +1,132 @@
+package flock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+type arbitrary struct{}
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), arbitrary{}, "arbitrary")
+
+func Test_flock(t *testing.T) {
+	tmpDir := t.TempDir()
+	r, proxyCompiled := requireProxyModule(t)
+	defer r.Close(testCtx)
+
+	// Each module opens the same file, like concurrent instances of a guest
+	// sharing a database.
+	mod0, fd0 := requireOpenFile(t, r, proxyCompiled, "mod0", tmpDir)
+	mod1, fd1 := requireOpenFile(t, r, proxyCompiled, "mod1", tmpDir)
+
+	if errno := callFlock(t, mod0, fd0, LockEx|LockNb); errno == wasip1.ErrnoNosys {
+		t.Skip("locks aren't supported on this platform")
+	} else {
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+	}
+	require.Equal(t, wasip1.ErrnoAgain, callFlock(t, mod1, fd1, LockSh|LockNb))
+
+	require.Equal(t, wasip1.ErrnoSuccess, callFlock(t, mod0, fd0, LockUn))
+	require.Equal(t, wasip1.ErrnoSuccess, callFlock(t, mod1, fd1, LockSh|LockNb))
+	require.Equal(t, wasip1.ErrnoSuccess, callFlock(t, mod0, fd0, LockSh|LockNb))
+	require.Equal(t, wasip1.ErrnoAgain, callFlock(t, mod0, fd0, LockEx|LockNb))
+
+	// Closing the module closes its files, which removes their locks.
+	require.NoError(t, mod1.Close(testCtx))
+	require.Equal(t, wasip1.ErrnoSuccess, callFlock(t, mod0, fd0, LockEx|LockNb))
+}
+
+func Test_flock_Rights(t *testing.T) {
+	r, proxyCompiled := requireProxyModule(t)
+	defer r.Close(testCtx)
+
+	mod, fd := requireOpenFile(t, r, proxyCompiled, "mod", t.TempDir())
+	f, ok := mod.(*wasm.ModuleInstance).Sys.FS().LookupFile(fd)
+	require.True(t, ok)
+
+	// Like fd_fdstat_set_rights, drop the right to write.
+	f.Rights = &sys.Rights{Base: wasip1.RIGHT_FD_READ}
+	if errno := callFlock(t, mod, fd, LockSh|LockNb); errno == wasip1.ErrnoNosys {
+		t.Skip("locks aren't supported on this platform")
+	} else {
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+	}
+	require.Equal(t, wasip1.ErrnoBadf, callFlock(t, mod, fd, LockEx|LockNb))
+
+	// Locks can be removed regardless of rights.
+	f.Rights = &sys.Rights{}
+	require.Equal(t, wasip1.ErrnoBadf, callFlock(t, mod, fd, LockSh|LockNb))
+	require.Equal(t, wasip1.ErrnoSuccess, callFlock(t, mod, fd, LockUn))
+}
+
+func Test_flock_Errors(t *testing.T) {
+	r, proxyCompiled := requireProxyModule(t)
+	defer r.Close(testCtx)
+
+	mod, fd := requireOpenFile(t, r, proxyCompiled, "mod", t.TempDir())
+
+	tests := []struct {
+		name          string
+		fd            int32
+		operation     uint32
+		expectedErrno wasip1.Errno
+	}{
+		{name: "invalid fd", fd: 42, operation: LockSh, expectedErrno: wasip1.ErrnoBadf},
+		{name: "stdin", fd: sys.FdStdin, operation: LockSh, expectedErrno: wasip1.ErrnoNosys},
+		{name: "no operation", fd: fd, operation: LockNb, expectedErrno: wasip1.ErrnoInval},
+		{name: "two operations", fd: fd, operation: LockSh | LockEx, expectedErrno: wasip1.ErrnoInval},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedErrno, callFlock(t, mod, tc.fd, tc.operation))
+		})
+	}
+}
+
+// requireProxyModule instantiates ModuleName, and compiles a module which
+// proxies calls to it, so that they are made with the system context of the
+// proxy.
+func requireProxyModule(t *testing.T) (wazero.Runtime, wazero.CompiledModule) {
+	r := wazero.NewRuntime(testCtx)
+
+	compiled, err := NewBuilder(r).Compile(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(ModuleName, compiled))
+	require.NoError(t, err)
+	return r, proxyCompiled
+}
+
+// requireOpenFile instantiates the proxy module with the directory mounted,
+// and opens the file "file" in it, returning its file descriptor.
+func requireOpenFile(t *testing.T, r wazero.Runtime, proxyCompiled wazero.CompiledModule, name, dir string) (api.Module, int32) {
+	mod, err := r.InstantiateModule(testCtx, proxyCompiled, wazero.NewModuleConfig().WithName(name).
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(dir, "/")))
+	require.NoError(t, err)
+
+	fd, errno := mod.(*wasm.ModuleInstance).Sys.FS().OpenFile(sysfs.DirFS(dir), "file",
+		experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	return mod, fd
+}
+
+func callFlock(t *testing.T, mod api.Module, fd int32, operation uint32) wasip1.Errno {
+	results, err := mod.ExportedFunction(FlockName).Call(testCtx, uint64(fd), uint64(operation))
+	require.NoError(t, err)
+	return wasip1.Errno(results[0])
+}
@@ -0,0 +1,33 @@
+;; lock is a guest which opens the file "db" in the pre-opened directory, and
+;; locks it with the function "flock".
+(module $lock
+  (import "wasi_snapshot_preview1" "path_open"
+    (func $wasi.path_open (param $fd i32) (param $dirflags i32) (param $path i32) (param $path_len i32)
+      (param $oflags i32) (param $fs_rights_base i64) (param $fs_rights_inheriting i64)
+      (param $fdflags i32) (param $result.opened_fd i32) (result (;errno;) i32)))
+
+  (import "flock" "flock"
+    (func $flock (param $fd i32) (param $operation i32) (result (;errno;) i32)))
+
+  (memory (export "memory") 1)
+
+  ;; the file name is at offset 16, after the opened file descriptor.
+  (data (i32.const 16) "db")
+
+  ;; lock opens "db", creating it if needed, and places the lock of the
+  ;; operation on it, returning the errno.
+  (func (export "lock") (param $operation i32) (result (;errno;) i32)
+    (local $errno i32)
+    (local.set $errno
+      (call $wasi.path_open
+        (i32.const 3) ;; fd of the pre-opened directory
+        (i32.const 0) ;; dirflags
+        (i32.const 16) (i32.const 2) ;; path "db"
+        (i32.const 1) ;; oflags O_CREAT
+        (i64.const 0x42) ;; rights FD_READ|FD_WRITE
+        (i64.const 0) ;; inheriting rights
+        (i32.const 0) ;; fdflags
+        (i32.const 0))) ;; result.opened_fd
+    (if (local.get $errno) (then (return (local.get $errno))))
+    (call $flock (i32.load (i32.const 0)) (local.get $operation)))
+)
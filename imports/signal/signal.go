@@ -0,0 +1,80 @@
+// Package signal contains the Go-defined function imported by WebAssembly
+// which handles signals without being terminated by them, as WASI has no way
+// to call a signal handler of the guest.
+//
+// The host catches a signal with an experimental.SignalHandler which returns
+// experimental.SignalActionCatch, for signals raised with "proc_raise" of
+// "wasi_snapshot_preview1", or delivered with
+// wasi_snapshot_preview1.DeliverSignal. The signal is then pending until the
+// guest takes it with the function "take_pending" of the module ModuleName,
+// e.g. in the loop of a long computation it cancels on SIGINT. It's imported
+// in C like so:
+//
+//	__attribute__((import_module("signal"), import_name("take_pending")))
+//	uint32_t __wazero_take_pending(void);
+//
+// The result is the set of pending signals, where bit N is set for the signal
+// N of "wasi_snapshot_preview1", e.g. `1 << 2` for SIGINT, which is cleared.
+// As in POSIX, a signal caught more than once before it's taken is only
+// pending once.
+//
+// Note: Catching a signal doesn't interrupt a blocking call of the guest, such
+// as "poll_oneoff", which returns as usual.
+package signal
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+const (
+	// ModuleName is the module name the function TakePendingName is exported
+	// into.
+	ModuleName = "signal"
+
+	// TakePendingName is the name of the function which returns the set of
+	// pending signals, and clears it.
+	TakePendingName = "take_pending"
+)
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	return NewBuilder(r).Instantiate(ctx)
+}
+
+// NewBuilder returns a new wazero.HostModuleBuilder for ModuleName, which
+// can be compiled instead of instantiated.
+func NewBuilder(r wazero.Runtime) wazero.HostModuleBuilder {
+	builder := r.NewHostModuleBuilder(ModuleName)
+	builder.(wasm.HostFuncExporter).ExportHostFunc(&wasm.HostFunc{
+		ExportName:  TakePendingName,
+		Name:        TakePendingName,
+		ResultTypes: []api.ValueType{wasm.ValueTypeI32},
+		ResultNames: []string{"signals"},
+		Code:        wasm.Code{GoFunc: api.GoModuleFunc(takePending)},
+	})
+	return builder
+}
+
+// takePending implements TakePendingName.
+func takePending(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(mod.(*wasm.ModuleInstance).TakePendingSignals())
+}
@@ -0,0 +1,114 @@
+package signal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+type arbitrary struct{}
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), arbitrary{}, "arbitrary")
+
+// catchCtx is a context whose experimental.SignalHandler catches SIGINT and
+// SIGUSR1, and takes the default action of the other signals.
+var catchCtx = experimental.WithSignalHandler(testCtx, experimental.SignalHandlerFunc(
+	func(_ context.Context, _ api.Module, signal uint8) experimental.SignalAction {
+		switch signal {
+		case wasip1.SIGINT, wasip1.SIGUSR1:
+			return experimental.SignalActionCatch
+		}
+		return experimental.SignalActionDefault
+	}))
+
+// guest returns a module which imports the function TakePendingName, and
+// exports "take", which calls it. It also exports "run", which loops until
+// SIGINT is pending, and returns the last pending signals it took.
+func guest() []byte {
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeFunc, Module: ModuleName, Name: TakePendingName, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{LocalTypes: []wasm.ValueType{wasm.ValueTypeI32}, Body: []byte{
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeCall, 0, wasm.OpcodeLocalTee, 0,
+				wasm.OpcodeI32Const, 1 << wasip1.SIGINT, wasm.OpcodeI32And, wasm.OpcodeI32Eqz,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "take", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "run", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	})
+}
+
+// newRuntime returns a runtime with the modules ModuleName and
+// "wasi_snapshot_preview1", and the guest instantiated.
+func newRuntime(t *testing.T) (wazero.Runtime, api.Module) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	MustInstantiate(testCtx, r)
+	wasi_snapshot_preview1.MustInstantiate(testCtx, r)
+
+	mod, err := r.Instantiate(testCtx, guest())
+	require.NoError(t, err)
+	return r, mod
+}
+
+// call calls the function of the module, and returns its result.
+func call(t *testing.T, mod api.Module, name string) uint32 {
+	results, err := mod.ExportedFunction(name).Call(testCtx)
+	require.NoError(t, err)
+	return api.DecodeU32(results[0])
+}
+
+func Test_takePending(t *testing.T) {
+	r, mod := newRuntime(t)
+	defer r.Close(testCtx)
+
+	require.Equal(t, uint32(0), call(t, mod, "take"))
+
+	// Caught signals are pending once until taken, unlike ignored ones.
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(catchCtx, mod, wasip1.SIGUSR1))
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(catchCtx, mod, wasip1.SIGINT))
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(catchCtx, mod, wasip1.SIGUSR1))
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(catchCtx, mod, wasip1.SIGWINCH))
+	require.Equal(t, uint32(1<<wasip1.SIGINT|1<<wasip1.SIGUSR1), call(t, mod, "take"))
+	require.Equal(t, uint32(0), call(t, mod, "take"))
+	require.False(t, mod.IsClosed())
+
+	// Signals which aren't caught still terminate the module.
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(catchCtx, mod, wasip1.SIGTERM))
+	require.True(t, mod.IsClosed())
+}
+
+func Test_takePending_Loop(t *testing.T) {
+	r, mod := newRuntime(t)
+	defer r.Close(testCtx)
+
+	// The guest polls for SIGINT, and handles it by returning, instead of
+	// being terminated.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = wasi_snapshot_preview1.DeliverSignal(catchCtx, mod, wasip1.SIGINT)
+	}()
+	require.Equal(t, uint32(1<<wasip1.SIGINT), call(t, mod, "run"))
+	require.False(t, mod.IsClosed())
+}
@@ -0,0 +1,148 @@
+package wasi_preview2
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// cliEnvironment is the interface wasi:cli/environment, whose arguments and environment variables are the ones of the
+// system context, e.g. configured with wazero.ModuleConfig WithArgs and WithEnv.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/cli/environment.wit
+var cliEnvironment = newInterface("wasi:cli/environment",
+	hostFunc{"get-environment", getEnvironment},
+	hostFunc{"get-arguments", getArguments},
+	hostFunc{"initial-cwd", initialCwd},
+)
+
+// envVar is an environment variable, which is a tuple<string, string> of its key and its value.
+type envVar struct {
+	cm.Tuple
+	Key, Value string
+}
+
+func getEnvironment(_ context.Context, mod api.Module) []envVar {
+	environ := sysCtx(mod).Environ()
+	ret := make([]envVar, 0, len(environ))
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(string(kv), "=")
+		ret = append(ret, envVar{Key: k, Value: v})
+	}
+	return ret
+}
+
+func getArguments(_ context.Context, mod api.Module) []string {
+	args := sysCtx(mod).Args()
+	ret := make([]string, 0, len(args))
+	for _, arg := range args {
+		ret = append(ret, string(arg))
+	}
+	return ret
+}
+
+// initialCwd returns none, as there's no working directory.
+func initialCwd() cm.Option[string] {
+	return cm.None[string]()
+}
+
+// cliExit is the interface wasi:cli/exit.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/cli/exit.wit
+var cliExit = newInterface("wasi:cli/exit",
+	hostFunc{"exit", exit},
+)
+
+// exit terminates the execution of the module with the exit code zero if the status is ok, or one otherwise.
+func exit(ctx context.Context, mod api.Module, status cm.Result[struct{}, struct{}]) {
+	exitCode := uint32(0)
+	if status.IsErr {
+		exitCode = 1
+	}
+
+	// Ensure other callers see the exit code.
+	_ = mod.CloseWithExitCode(ctx, exitCode)
+
+	// Prevent any code from executing after this function.
+	panic(sys.NewExitError(exitCode))
+}
+
+// cliStdin is the interface wasi:cli/stdin.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/cli/stdio.wit
+var cliStdin = newInterface("wasi:cli/stdin",
+	hostFunc{"get-stdin", getStdin},
+)
+
+// cliStdout is the interface wasi:cli/stdout.
+var cliStdout = newInterface("wasi:cli/stdout",
+	hostFunc{"get-stdout", getStdout},
+)
+
+// cliStderr is the interface wasi:cli/stderr.
+var cliStderr = newInterface("wasi:cli/stderr",
+	hostFunc{"get-stderr", getStderr},
+)
+
+// stdioFile returns the file of the standard I/O of the system context, e.g. configured with wazero.ModuleConfig
+// WithStdin.
+func stdioFile(mod api.Module, fd int32) (*internalsys.Context, fsapi.File) {
+	s := sysCtx(mod)
+	f, ok := s.FS().LookupFile(fd)
+	if !ok {
+		panic(experimentalsys.EBADF)
+	}
+	return s, f.File
+}
+
+func getStdin(_ context.Context, mod api.Module) cm.Own[*inputStream] {
+	s, f := stdioFile(mod, internalsys.FdStdin)
+	return cm.Own[*inputStream]{Rep: &inputStream{sysCtx: s, file: f}}
+}
+
+func getStdout(_ context.Context, mod api.Module) cm.Own[*outputStream] {
+	s, f := stdioFile(mod, internalsys.FdStdout)
+	return cm.Own[*outputStream]{Rep: &outputStream{sysCtx: s, file: f}}
+}
+
+func getStderr(_ context.Context, mod api.Module) cm.Own[*outputStream] {
+	s, f := stdioFile(mod, internalsys.FdStderr)
+	return cm.Own[*outputStream]{Rep: &outputStream{sysCtx: s, file: f}}
+}
+
+// cliTerminalInput is the interface wasi:cli/terminal-input, whose resource terminal-input has no functions.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/cli/terminal.wit
+var cliTerminalInput = newInterface("wasi:cli/terminal-input")
+
+// cliTerminalOutput is the interface wasi:cli/terminal-output, whose resource terminal-output has no functions.
+var cliTerminalOutput = newInterface("wasi:cli/terminal-output")
+
+// terminal is the representation of the resources terminal-input and terminal-output, which are never given as the
+// standard I/O isn't considered a terminal.
+type terminal struct{}
+
+// cliTerminalStdin is the interface wasi:cli/terminal-stdin.
+var cliTerminalStdin = newInterface("wasi:cli/terminal-stdin",
+	hostFunc{"get-terminal-stdin", getTerminal},
+)
+
+// cliTerminalStdout is the interface wasi:cli/terminal-stdout.
+var cliTerminalStdout = newInterface("wasi:cli/terminal-stdout",
+	hostFunc{"get-terminal-stdout", getTerminal},
+)
+
+// cliTerminalStderr is the interface wasi:cli/terminal-stderr.
+var cliTerminalStderr = newInterface("wasi:cli/terminal-stderr",
+	hostFunc{"get-terminal-stderr", getTerminal},
+)
+
+func getTerminal() cm.Option[cm.Own[*terminal]] {
+	return cm.None[cm.Own[*terminal]]()
+}
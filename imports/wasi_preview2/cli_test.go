@@ -0,0 +1,96 @@
+package wasi_preview2
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_environment(t *testing.T) {
+	sysCtx, err := internalsys.NewContext(1024, [][]byte{[]byte("a"), []byte("bc")}, [][]byte{[]byte("a=b"), []byte("b=c=d")},
+		nil, nil, nil, nil, nil, 0, nil, 0, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := newModule(t, sysCtx)
+
+	require.Equal(t, []string{"a", "bc"}, getArguments(testCtx, mod))
+	require.Equal(t, []envVar{{Key: "a", Value: "b"}, {Key: "b", Value: "c=d"}}, getEnvironment(testCtx, mod))
+	require.Equal(t, cm.None[string](), initialCwd())
+}
+
+func Test_environment_Empty(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	require.Equal(t, []string{}, getArguments(testCtx, mod))
+	require.Equal(t, []envVar{}, getEnvironment(testCtx, mod))
+}
+
+// exitModule records the exit code of CloseWithExitCode.
+type exitModule struct {
+	api.Module
+	exitCode *uint32
+}
+
+// CloseWithExitCode implements the same method as documented on api.Module.
+func (m exitModule) CloseWithExitCode(_ context.Context, exitCode uint32) error {
+	*m.exitCode = exitCode
+	return nil
+}
+
+func Test_exit(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   cm.Result[struct{}, struct{}]
+		exitCode uint32
+	}{
+		{name: "ok", status: cm.Ok[struct{}, struct{}](struct{}{}), exitCode: 0},
+		{name: "err", status: cm.Err[struct{}](struct{}{}), exitCode: 1},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			closed := uint32(0xff)
+			mod := exitModule{exitCode: &closed}
+			requireExit(t, tc.exitCode, func() { exit(testCtx, mod, tc.status) })
+			require.Equal(t, tc.exitCode, closed)
+		})
+	}
+}
+
+func Test_stdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	sysCtx, err := internalsys.NewContext(0, nil, nil, strings.NewReader("in"), &stdout, &stderr,
+		nil, nil, 0, nil, 0, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := newModule(t, sysCtx)
+
+	stdin := getStdin(testCtx, mod).Rep
+	res := stdin.read(10, true)
+	require.False(t, res.IsErr)
+	require.Equal(t, "in", string(res.Value))
+
+	require.False(t, getStdout(testCtx, mod).Rep.write([]byte("out")).IsErr)
+	require.Equal(t, "out", stdout.String())
+
+	require.False(t, getStderr(testCtx, mod).Rep.write([]byte("err")).IsErr)
+	require.Equal(t, "err", stderr.String())
+}
+
+func Test_stdio_Closed(t *testing.T) {
+	sysCtx := internalsys.DefaultContext(nil)
+	mod := newModule(t, sysCtx)
+	require.EqualErrno(t, 0, sysCtx.FS().CloseFile(internalsys.FdStdout))
+
+	err := require.CapturePanic(func() { getStdout(testCtx, mod) })
+	require.EqualError(t, err, "bad file descriptor")
+}
+
+func Test_getTerminal(t *testing.T) {
+	require.Equal(t, cm.None[cm.Own[*terminal]](), getTerminal())
+}
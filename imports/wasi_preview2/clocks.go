@@ -0,0 +1,68 @@
+package wasi_preview2
+
+import (
+	"context"
+	"math"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+)
+
+// clocksMonotonicClock is the interface wasi:clocks/monotonic-clock, whose instants are the nanotime of the system
+// context, e.g. configured with wazero.ModuleConfig WithNanotime.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/clocks/monotonic-clock.wit
+var clocksMonotonicClock = newInterface("wasi:clocks/monotonic-clock",
+	hostFunc{"now", monotonicClockNow},
+	hostFunc{"resolution", monotonicClockResolution},
+	hostFunc{"subscribe-instant", monotonicClockSubscribeInstant},
+	hostFunc{"subscribe-duration", monotonicClockSubscribeDuration},
+)
+
+func monotonicClockNow(_ context.Context, mod api.Module) uint64 {
+	return uint64(sysCtx(mod).Nanotime())
+}
+
+func monotonicClockResolution(_ context.Context, mod api.Module) uint64 {
+	return uint64(sysCtx(mod).NanotimeResolution())
+}
+
+func monotonicClockSubscribeInstant(_ context.Context, mod api.Module, when uint64) cm.Own[*pollable] {
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: sysCtx(mod), deadline: int64(min(when, math.MaxInt64))}}
+}
+
+func monotonicClockSubscribeDuration(_ context.Context, mod api.Module, when uint64) cm.Own[*pollable] {
+	s := sysCtx(mod)
+	now := s.Nanotime()
+	// Saturate the deadline, so that a duration too long to be reached never is.
+	deadline := int64(math.MaxInt64)
+	if when < uint64(math.MaxInt64-now) {
+		deadline = now + int64(when)
+	}
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: s, deadline: deadline}}
+}
+
+// clocksWallClock is the interface wasi:clocks/wall-clock, whose time is the walltime of the system context, e.g.
+// configured with wazero.ModuleConfig WithWalltime.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/clocks/wall-clock.wit
+var clocksWallClock = newInterface("wasi:clocks/wall-clock",
+	hostFunc{"now", wallClockNow},
+	hostFunc{"resolution", wallClockResolution},
+)
+
+// datetime is the record datetime.
+type datetime struct {
+	Seconds     uint64
+	Nanoseconds uint32
+}
+
+func wallClockNow(_ context.Context, mod api.Module) datetime {
+	sec, nsec := sysCtx(mod).Walltime()
+	return datetime{Seconds: uint64(sec), Nanoseconds: uint32(nsec)}
+}
+
+func wallClockResolution(_ context.Context, mod api.Module) datetime {
+	res := uint64(sysCtx(mod).WalltimeResolution())
+	return datetime{Seconds: res / 1e9, Nanoseconds: uint32(res % 1e9)}
+}
@@ -0,0 +1,46 @@
+package wasi_preview2
+
+import (
+	"math"
+	"testing"
+
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_monotonicClock(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	// The fake nanotime increases by 1ms each reading.
+	require.Equal(t, uint64(0), monotonicClockNow(testCtx, mod))
+	require.Equal(t, uint64(1_000_000), monotonicClockNow(testCtx, mod))
+	require.Equal(t, uint64(1), monotonicClockResolution(testCtx, mod))
+}
+
+func Test_monotonicClockSubscribe(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	// The nanotime is zero when subscribing, so the deadline is the 3rd reading.
+	p := monotonicClockSubscribeDuration(testCtx, mod, 2_000_000).Rep
+	require.Equal(t, int64(2_000_000), p.deadline)
+	require.False(t, p.ready())
+	require.True(t, p.ready())
+
+	p = monotonicClockSubscribeInstant(testCtx, mod, 0).Rep
+	require.True(t, p.ready())
+
+	// The deadlines which can't be represented are never reached.
+	p = monotonicClockSubscribeInstant(testCtx, mod, math.MaxUint64).Rep
+	require.Equal(t, int64(math.MaxInt64), p.deadline)
+	p = monotonicClockSubscribeDuration(testCtx, mod, math.MaxUint64).Rep
+	require.Equal(t, int64(math.MaxInt64), p.deadline)
+}
+
+func Test_wallClock(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	// The fake walltime is midnight UTC 2022-01-01, and increases by 1ms each reading.
+	require.Equal(t, datetime{Seconds: 1640995200}, wallClockNow(testCtx, mod))
+	require.Equal(t, datetime{Seconds: 1640995200, Nanoseconds: 1_000_000}, wallClockNow(testCtx, mod))
+	require.Equal(t, datetime{Nanoseconds: 1000}, wallClockResolution(testCtx, mod))
+}
@@ -0,0 +1,798 @@
+package wasi_preview2
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// filesystemTypes is the interface wasi:filesystem/types, whose descriptors are the files of the pre-opens of the
+// system context, e.g. configured with wazero.FSConfig WithDirMount, as for wasi_snapshot_preview1.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/filesystem/types.wit
+var filesystemTypes = newInterface("wasi:filesystem/types",
+	hostFunc{"[method]descriptor.read-via-stream", descriptorReadViaStream},
+	hostFunc{"[method]descriptor.write-via-stream", descriptorWriteViaStream},
+	hostFunc{"[method]descriptor.append-via-stream", descriptorAppendViaStream},
+	hostFunc{"[method]descriptor.advise", descriptorAdvise},
+	hostFunc{"[method]descriptor.sync-data", descriptorSyncData},
+	hostFunc{"[method]descriptor.get-flags", descriptorGetFlags},
+	hostFunc{"[method]descriptor.get-type", descriptorGetType},
+	hostFunc{"[method]descriptor.set-size", descriptorSetSize},
+	hostFunc{"[method]descriptor.set-times", descriptorSetTimes},
+	hostFunc{"[method]descriptor.read", descriptorRead},
+	hostFunc{"[method]descriptor.write", descriptorWrite},
+	hostFunc{"[method]descriptor.read-directory", descriptorReadDirectory},
+	hostFunc{"[method]descriptor.sync", descriptorSync},
+	hostFunc{"[method]descriptor.create-directory-at", descriptorCreateDirectoryAt},
+	hostFunc{"[method]descriptor.stat", descriptorStat},
+	hostFunc{"[method]descriptor.stat-at", descriptorStatAt},
+	hostFunc{"[method]descriptor.set-times-at", descriptorSetTimesAt},
+	hostFunc{"[method]descriptor.link-at", descriptorLinkAt},
+	hostFunc{"[method]descriptor.open-at", descriptorOpenAt},
+	hostFunc{"[method]descriptor.readlink-at", descriptorReadlinkAt},
+	hostFunc{"[method]descriptor.remove-directory-at", descriptorRemoveDirectoryAt},
+	hostFunc{"[method]descriptor.rename-at", descriptorRenameAt},
+	hostFunc{"[method]descriptor.symlink-at", descriptorSymlinkAt},
+	hostFunc{"[method]descriptor.unlink-file-at", descriptorUnlinkFileAt},
+	hostFunc{"[method]descriptor.is-same-object", descriptorIsSameObject},
+	hostFunc{"[method]descriptor.metadata-hash", descriptorMetadataHash},
+	hostFunc{"[method]descriptor.metadata-hash-at", descriptorMetadataHashAt},
+	hostFunc{"[method]directory-entry-stream.read-directory-entry", directoryEntryStreamReadDirectoryEntry},
+	hostFunc{"filesystem-error-code", filesystemErrorCode},
+)
+
+// descriptorType is the enum descriptor-type.
+type descriptorType uint8
+
+const (
+	descriptorTypeUnknown descriptorType = iota
+	descriptorTypeBlockDevice
+	descriptorTypeCharacterDevice
+	descriptorTypeDirectory
+	descriptorTypeFifo
+	descriptorTypeSymbolicLink
+	descriptorTypeRegularFile
+	descriptorTypeSocket
+)
+
+// Cases implements component.Enum
+func (descriptorType) Cases() []string {
+	return []string{"unknown", "block-device", "character-device", "directory", "fifo", "symbolic-link", "regular-file", "socket"}
+}
+
+// descriptorTypeOf returns the descriptor-type of the file mode.
+func descriptorTypeOf(mode fs.FileMode) descriptorType {
+	switch mode.Type() {
+	case 0:
+		return descriptorTypeRegularFile
+	case fs.ModeDir:
+		return descriptorTypeDirectory
+	case fs.ModeSymlink:
+		return descriptorTypeSymbolicLink
+	case fs.ModeNamedPipe:
+		return descriptorTypeFifo
+	case fs.ModeSocket:
+		return descriptorTypeSocket
+	case fs.ModeDevice:
+		return descriptorTypeBlockDevice
+	case fs.ModeDevice | fs.ModeCharDevice:
+		return descriptorTypeCharacterDevice
+	}
+	return descriptorTypeUnknown
+}
+
+// descriptorFlags is the flags descriptor-flags.
+type descriptorFlags uint8
+
+const (
+	descriptorFlagsRead descriptorFlags = 1 << iota
+	descriptorFlagsWrite
+	descriptorFlagsFileIntegritySync
+	descriptorFlagsDataIntegritySync
+	descriptorFlagsRequestedWriteSync
+	descriptorFlagsMutateDirectory
+)
+
+// Flags implements component.Flags
+func (descriptorFlags) Flags() []string {
+	return []string{"read", "write", "file-integrity-sync", "data-integrity-sync", "requested-write-sync", "mutate-directory"}
+}
+
+// rightsMutateDirectory are the WASI rights which change the entries of a directory.
+const rightsMutateDirectory = wasip1.RIGHT_PATH_CREATE_DIRECTORY | wasip1.RIGHT_PATH_CREATE_FILE |
+	wasip1.RIGHT_PATH_LINK_TARGET | wasip1.RIGHT_PATH_RENAME_SOURCE | wasip1.RIGHT_PATH_RENAME_TARGET |
+	wasip1.RIGHT_PATH_SYMLINK | wasip1.RIGHT_PATH_REMOVE_DIRECTORY | wasip1.RIGHT_PATH_UNLINK_FILE
+
+// flagsOfRights returns the descriptor-flags permitted by the WASI rights, e.g. limited by wazero.FSConfig
+// WithMountRights, as preview2 has no rights.
+func flagsOfRights(rights uint64) descriptorFlags {
+	flags := descriptorFlagsFileIntegritySync | descriptorFlagsDataIntegritySync | descriptorFlagsRequestedWriteSync
+	if rights&(wasip1.RIGHT_FD_READ|wasip1.RIGHT_FD_READDIR) != 0 {
+		flags |= descriptorFlagsRead
+	}
+	if rights&wasip1.RIGHT_FD_WRITE != 0 {
+		flags |= descriptorFlagsWrite
+	}
+	if rights&rightsMutateDirectory != 0 {
+		flags |= descriptorFlagsMutateDirectory
+	}
+	return flags
+}
+
+// pathFlags is the flags path-flags.
+type pathFlags uint8
+
+const pathFlagsSymlinkFollow pathFlags = 1
+
+// Flags implements component.Flags
+func (pathFlags) Flags() []string {
+	return []string{"symlink-follow"}
+}
+
+// openFlags is the flags open-flags.
+type openFlags uint8
+
+const (
+	openFlagsCreate openFlags = 1 << iota
+	openFlagsDirectory
+	openFlagsExclusive
+	openFlagsTruncate
+)
+
+// Flags implements component.Flags
+func (openFlags) Flags() []string {
+	return []string{"create", "directory", "exclusive", "truncate"}
+}
+
+// errorCode is the enum error-code.
+type errorCode uint8
+
+const (
+	errorCodeAccess errorCode = iota
+	errorCodeWouldBlock
+	errorCodeAlready
+	errorCodeBadDescriptor
+	errorCodeBusy
+	errorCodeDeadlock
+	errorCodeQuota
+	errorCodeExist
+	errorCodeFileTooLarge
+	errorCodeIllegalByteSequence
+	errorCodeInProgress
+	errorCodeInterrupted
+	errorCodeInvalid
+	errorCodeIo
+	errorCodeIsDirectory
+	errorCodeLoop
+	errorCodeTooManyLinks
+	errorCodeMessageSize
+	errorCodeNameTooLong
+	errorCodeNoDevice
+	errorCodeNoEntry
+	errorCodeNoLock
+	errorCodeInsufficientMemory
+	errorCodeInsufficientSpace
+	errorCodeNotDirectory
+	errorCodeNotEmpty
+	errorCodeNotRecoverable
+	errorCodeUnsupported
+	errorCodeNoTty
+	errorCodeNoSuchDevice
+	errorCodeOverflow
+	errorCodeNotPermitted
+	errorCodePipe
+	errorCodeReadOnly
+	errorCodeInvalidSeek
+	errorCodeTextFileBusy
+	errorCodeCrossDevice
+)
+
+// Cases implements component.Enum
+func (errorCode) Cases() []string {
+	return []string{
+		"access", "would-block", "already", "bad-descriptor", "busy", "deadlock", "quota", "exist", "file-too-large",
+		"illegal-byte-sequence", "in-progress", "interrupted", "invalid", "io", "is-directory", "loop", "too-many-links",
+		"message-size", "name-too-long", "no-device", "no-entry", "no-lock", "insufficient-memory", "insufficient-space",
+		"not-directory", "not-empty", "not-recoverable", "unsupported", "no-tty", "no-such-device", "overflow",
+		"not-permitted", "pipe", "read-only", "invalid-seek", "text-file-busy", "cross-device",
+	}
+}
+
+// errorCodeOf returns the error-code of the errno, which must not be zero.
+func errorCodeOf(errno experimentalsys.Errno) errorCode {
+	switch errno {
+	case experimentalsys.EACCES:
+		return errorCodeAccess
+	case experimentalsys.EAGAIN:
+		return errorCodeWouldBlock
+	case experimentalsys.EBADF:
+		return errorCodeBadDescriptor
+	case experimentalsys.EEXIST:
+		return errorCodeExist
+	case experimentalsys.EINTR:
+		return errorCodeInterrupted
+	case experimentalsys.EFAULT, experimentalsys.EINVAL, experimentalsys.ENOTSOCK:
+		return errorCodeInvalid
+	case experimentalsys.EISDIR:
+		return errorCodeIsDirectory
+	case experimentalsys.ELOOP:
+		return errorCodeLoop
+	case experimentalsys.ENAMETOOLONG:
+		return errorCodeNameTooLong
+	case experimentalsys.ENOENT:
+		return errorCodeNoEntry
+	case experimentalsys.ENOSYS, experimentalsys.ENOTSUP:
+		return errorCodeUnsupported
+	case experimentalsys.ENOTDIR:
+		return errorCodeNotDirectory
+	case experimentalsys.ERANGE:
+		return errorCodeOverflow
+	case experimentalsys.ENOTEMPTY:
+		return errorCodeNotEmpty
+	case experimentalsys.EPERM:
+		return errorCodeNotPermitted
+	case experimentalsys.EROFS:
+		return errorCodeReadOnly
+	}
+	return errorCodeIo
+}
+
+// advice is the enum advice.
+type advice uint8
+
+// Cases implements component.Enum
+func (advice) Cases() []string {
+	return []string{"normal", "sequential", "random", "will-need", "dont-need", "no-reuse"}
+}
+
+// descriptorStatRecord is the record descriptor-stat.
+type descriptorStatRecord struct {
+	Type                      descriptorType
+	LinkCount                 uint64
+	Size                      uint64
+	DataAccessTimestamp       cm.Option[datetime]
+	DataModificationTimestamp cm.Option[datetime]
+	StatusChangeTimestamp     cm.Option[datetime]
+}
+
+func descriptorStatOf(st sys.Stat_t) descriptorStatRecord {
+	return descriptorStatRecord{
+		Type:                      descriptorTypeOf(st.Mode),
+		LinkCount:                 st.Nlink,
+		Size:                      uint64(st.Size),
+		DataAccessTimestamp:       cm.Some(datetimeOf(st.Atim)),
+		DataModificationTimestamp: cm.Some(datetimeOf(st.Mtim)),
+		StatusChangeTimestamp:     cm.Some(datetimeOf(st.Ctim)),
+	}
+}
+
+// datetimeOf returns the datetime of the nanoseconds since the epoch.
+func datetimeOf(nanos int64) datetime {
+	return datetime{Seconds: uint64(nanos / 1e9), Nanoseconds: uint32(nanos % 1e9)}
+}
+
+// newTimestamp is the variant new-timestamp.
+type newTimestamp struct {
+	cm.Variant
+	NoChange  *struct{}
+	Now       *struct{}
+	Timestamp *datetime
+}
+
+// nanos returns the nanoseconds since the epoch of the timestamp, or UTIME_OMIT if it's unchanged.
+func (t newTimestamp) nanos(sysCtx *internalsys.Context) int64 {
+	switch {
+	case t.Now != nil:
+		return sysCtx.WalltimeNanos()
+	case t.Timestamp != nil:
+		return int64(t.Timestamp.Seconds)*1e9 + int64(t.Timestamp.Nanoseconds)
+	}
+	return experimentalsys.UTIME_OMIT
+}
+
+// directoryEntry is the record directory-entry.
+type directoryEntry struct {
+	Type descriptorType
+	Name string
+}
+
+// metadataHashValue is the record metadata-hash-value.
+type metadataHashValue struct {
+	Lower, Upper uint64
+}
+
+// readResult is the tuple<list<u8>, bool> of the bytes read and whether the end of the file was reached.
+type readResult struct {
+	cm.Tuple
+	Data []byte
+	EOF  bool
+}
+
+// descriptor is the representation of the resource descriptor, which is a file of the file table of the system
+// context.
+type descriptor struct {
+	sysCtx *internalsys.Context
+	// fd is the file descriptor of the file in the file table.
+	fd    int32
+	entry *internalsys.FileEntry
+	// preopen is the file descriptor of the pre-open the file was opened from, which is its own for a pre-open.
+	preopen int32
+	flags   descriptorFlags
+}
+
+// check returns EBADF unless the descriptor has the flags, or EROFS for the flag mutate-directory, as in the
+// specification of open-at.
+func (d *descriptor) check(flags descriptorFlags) experimentalsys.Errno {
+	if d.flags&flags == flags {
+		return 0
+	} else if flags&descriptorFlagsMutateDirectory != 0 {
+		return experimentalsys.EROFS
+	}
+	return experimentalsys.EBADF
+}
+
+// Close implements api.Closer, which closes the file unless it's a pre-open, which is shared.
+func (d *descriptor) Close(context.Context) error {
+	if d.entry.IsPreopen {
+		return nil
+	}
+	if errno := d.sysCtx.FS().CloseFile(d.fd); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// at returns the file system of the directory of the descriptor, and the path in it of p relative to the directory.
+//
+// As for wasi_snapshot_preview1, the paths which escape the directory are not permitted.
+func (d *descriptor) at(p string) (experimentalsys.FS, string, experimentalsys.Errno) {
+	if isDir, errno := d.entry.File.IsDir(); errno != 0 {
+		return nil, "", errno
+	} else if !isDir {
+		return nil, "", experimentalsys.ENOTDIR
+	} else if p == "" {
+		return nil, "", experimentalsys.ENOENT
+	}
+
+	hasTrailingSlash := strings.HasSuffix(p, "/")
+	if p = path.Clean(p); !fs.ValidPath(p) {
+		return nil, "", experimentalsys.EPERM
+	}
+	if !d.entry.IsPreopen && d.entry.Name != "" {
+		p = path.Join(d.entry.Name, p)
+	}
+	if hasTrailingSlash {
+		p += "/"
+	}
+	return d.entry.FS, p, 0
+}
+
+// mutateAt is like at, for operations which change the entries of the directory.
+func (d *descriptor) mutateAt(p string) (experimentalsys.FS, string, experimentalsys.Errno) {
+	fsys, p, errno := d.at(p)
+	if errno == 0 {
+		errno = d.check(descriptorFlagsMutateDirectory)
+	}
+	return fsys, p, errno
+}
+
+// resultOf returns the result of the errno of an operation without value.
+func resultOf(errno experimentalsys.Errno) cm.Result[struct{}, errorCode] {
+	if errno != 0 {
+		return cm.Err[struct{}](errorCodeOf(errno))
+	}
+	return cm.Ok[struct{}, errorCode](struct{}{})
+}
+
+func descriptorReadViaStream(self cm.Borrow[*descriptor], offset uint64) cm.Result[cm.Own[*inputStream], errorCode] {
+	d := self.Rep
+	if errno := d.check(descriptorFlagsRead); errno != 0 {
+		return cm.Err[cm.Own[*inputStream]](errorCodeOf(errno))
+	}
+	s := &inputStream{sysCtx: d.sysCtx, file: d.entry.File, positional: true, offset: int64(offset)}
+	return cm.Ok[cm.Own[*inputStream], errorCode](cm.Own[*inputStream]{Rep: s})
+}
+
+func descriptorWriteViaStream(self cm.Borrow[*descriptor], offset uint64) cm.Result[cm.Own[*outputStream], errorCode] {
+	d := self.Rep
+	if errno := d.checkPositionalWrite(); errno != 0 {
+		return cm.Err[cm.Own[*outputStream]](errorCodeOf(errno))
+	}
+	s := &outputStream{sysCtx: d.sysCtx, file: d.entry.File, positional: true, offset: int64(offset)}
+	return cm.Ok[cm.Own[*outputStream], errorCode](cm.Own[*outputStream]{Rep: s})
+}
+
+func descriptorAppendViaStream(self cm.Borrow[*descriptor]) cm.Result[cm.Own[*outputStream], errorCode] {
+	d := self.Rep
+	if errno := d.check(descriptorFlagsWrite); errno != 0 {
+		return cm.Err[cm.Own[*outputStream]](errorCodeOf(errno))
+	}
+	s := &outputStream{sysCtx: d.sysCtx, file: d.entry.File, appending: true}
+	return cm.Ok[cm.Own[*outputStream], errorCode](cm.Own[*outputStream]{Rep: s})
+}
+
+// descriptorAdvise does nothing, as the advice is only a hint.
+func descriptorAdvise(cm.Borrow[*descriptor], uint64, uint64, advice) cm.Result[struct{}, errorCode] {
+	return resultOf(0)
+}
+
+func descriptorSyncData(self cm.Borrow[*descriptor]) cm.Result[struct{}, errorCode] {
+	return resultOf(self.Rep.entry.File.Datasync())
+}
+
+func descriptorGetFlags(self cm.Borrow[*descriptor]) cm.Result[descriptorFlags, errorCode] {
+	return cm.Ok[descriptorFlags, errorCode](self.Rep.flags)
+}
+
+func descriptorGetType(self cm.Borrow[*descriptor]) cm.Result[descriptorType, errorCode] {
+	st, errno := self.Rep.entry.File.Stat()
+	if errno != 0 {
+		return cm.Err[descriptorType](errorCodeOf(errno))
+	}
+	return cm.Ok[descriptorType, errorCode](descriptorTypeOf(st.Mode))
+}
+
+func descriptorSetSize(self cm.Borrow[*descriptor], size uint64) cm.Result[struct{}, errorCode] {
+	d := self.Rep
+	if errno := d.check(descriptorFlagsWrite); errno != 0 {
+		return resultOf(errno)
+	}
+	return resultOf(d.entry.File.Truncate(int64(size)))
+}
+
+func descriptorSetTimes(self cm.Borrow[*descriptor], atim, mtim newTimestamp) cm.Result[struct{}, errorCode] {
+	d := self.Rep
+	return resultOf(d.entry.File.Utimens(atim.nanos(d.sysCtx), mtim.nanos(d.sysCtx)))
+}
+
+func descriptorRead(self cm.Borrow[*descriptor], length, offset uint64) cm.Result[readResult, errorCode] {
+	d := self.Rep
+	if errno := d.check(descriptorFlagsRead); errno != 0 {
+		return cm.Err[readResult](errorCodeOf(errno))
+	}
+	buf := make([]byte, min(length, maxBufferSize))
+	n, errno := d.entry.File.Pread(buf, int64(offset))
+	if errno != 0 {
+		return cm.Err[readResult](errorCodeOf(errno))
+	}
+	// A short read of a file is at its end.
+	return cm.Ok[readResult, errorCode](readResult{Data: buf[:n], EOF: n < len(buf)})
+}
+
+// checkPositionalWrite returns the errno of check for the flag write, or EACCES if the rights of the file only permit
+// appending to it, e.g. wasi_snapshot_preview1.RightsAppendOnly.
+func (d *descriptor) checkPositionalWrite() experimentalsys.Errno {
+	if errno := d.check(descriptorFlagsWrite); errno != 0 {
+		return errno
+	} else if !d.entry.HasRights(wasip1.RIGHT_FD_SEEK) {
+		return experimentalsys.EACCES
+	}
+	return 0
+}
+
+func descriptorWrite(self cm.Borrow[*descriptor], buffer []byte, offset uint64) cm.Result[uint64, errorCode] {
+	d := self.Rep
+	if errno := d.checkPositionalWrite(); errno != 0 {
+		return cm.Err[uint64](errorCodeOf(errno))
+	}
+	n, errno := d.entry.File.Pwrite(buffer, int64(offset))
+	if errno != 0 {
+		return cm.Err[uint64](errorCodeOf(errno))
+	}
+	return cm.Ok[uint64, errorCode](uint64(n))
+}
+
+// descriptorReadDirectory opens the directory again, so that the entries are read from the start.
+func descriptorReadDirectory(self cm.Borrow[*descriptor]) cm.Result[cm.Own[*directoryEntryStream], errorCode] {
+	fsys, p, errno := self.Rep.at(".")
+	if errno == 0 {
+		errno = self.Rep.check(descriptorFlagsRead)
+	}
+	if errno != 0 {
+		return cm.Err[cm.Own[*directoryEntryStream]](errorCodeOf(errno))
+	}
+	dir, errno := fsys.OpenFile(p, experimentalsys.O_RDONLY|experimentalsys.O_DIRECTORY, 0)
+	if errno != 0 {
+		return cm.Err[cm.Own[*directoryEntryStream]](errorCodeOf(errno))
+	}
+	return cm.Ok[cm.Own[*directoryEntryStream], errorCode](cm.Own[*directoryEntryStream]{Rep: &directoryEntryStream{dir: dir}})
+}
+
+func descriptorSync(self cm.Borrow[*descriptor]) cm.Result[struct{}, errorCode] {
+	return resultOf(self.Rep.entry.File.Sync())
+}
+
+func descriptorCreateDirectoryAt(self cm.Borrow[*descriptor], p string) cm.Result[struct{}, errorCode] {
+	fsys, p, errno := self.Rep.mutateAt(p)
+	if errno != 0 {
+		return resultOf(errno)
+	}
+	return resultOf(fsys.Mkdir(p, 0o700))
+}
+
+func descriptorStat(self cm.Borrow[*descriptor]) cm.Result[descriptorStatRecord, errorCode] {
+	st, errno := self.Rep.entry.File.Stat()
+	if errno != 0 {
+		return cm.Err[descriptorStatRecord](errorCodeOf(errno))
+	}
+	return cm.Ok[descriptorStatRecord, errorCode](descriptorStatOf(st))
+}
+
+// statAt returns the stat of the file at the path, which is the one of the symbolic link itself unless followed.
+func (d *descriptor) statAt(flags pathFlags, p string) (sys.Stat_t, experimentalsys.Errno) {
+	fsys, p, errno := d.at(p)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	} else if flags&pathFlagsSymlinkFollow != 0 {
+		return fsys.Stat(p)
+	}
+	return fsys.Lstat(p)
+}
+
+func descriptorStatAt(self cm.Borrow[*descriptor], flags pathFlags, p string) cm.Result[descriptorStatRecord, errorCode] {
+	st, errno := self.Rep.statAt(flags, p)
+	if errno != 0 {
+		return cm.Err[descriptorStatRecord](errorCodeOf(errno))
+	}
+	return cm.Ok[descriptorStatRecord, errorCode](descriptorStatOf(st))
+}
+
+func descriptorSetTimesAt(self cm.Borrow[*descriptor], _ pathFlags, p string, atim, mtim newTimestamp) cm.Result[struct{}, errorCode] {
+	d := self.Rep
+	fsys, p, errno := d.at(p)
+	if errno != 0 {
+		return resultOf(errno)
+	}
+	return resultOf(fsys.Utimens(p, atim.nanos(d.sysCtx), mtim.nanos(d.sysCtx)))
+}
+
+// atPaths returns the file system and the paths of the old path relative to the descriptor d, and the new path relative
+// to the descriptor newD, which must be of the same pre-open.
+func atPaths(d *descriptor, oldPath string, newD *descriptor, newPath string) (experimentalsys.FS, string, string, cm.Result[struct{}, errorCode]) {
+	if d.preopen != newD.preopen {
+		return nil, "", "", cm.Err[struct{}](errorCodeCrossDevice)
+	}
+	fsys, oldPath, errno := d.mutateAt(oldPath)
+	if errno == 0 {
+		_, newPath, errno = newD.mutateAt(newPath)
+	}
+	return fsys, oldPath, newPath, resultOf(errno)
+}
+
+func descriptorLinkAt(self cm.Borrow[*descriptor], _ pathFlags, oldPath string, newDescriptor cm.Borrow[*descriptor], newPath string) cm.Result[struct{}, errorCode] {
+	fsys, oldPath, newPath, res := atPaths(self.Rep, oldPath, newDescriptor.Rep, newPath)
+	if res.IsErr {
+		return res
+	}
+	return resultOf(fsys.Link(oldPath, newPath))
+}
+
+func descriptorOpenAt(self cm.Borrow[*descriptor], pflags pathFlags, p string, oflags openFlags, flags descriptorFlags) cm.Result[cm.Own[*descriptor], errorCode] {
+	d := self.Rep
+	fsys, p, errno := d.at(p)
+	if errno == 0 && (flags&(descriptorFlagsWrite|descriptorFlagsMutateDirectory) != 0 ||
+		oflags&(openFlagsCreate|openFlagsTruncate) != 0) {
+		errno = d.check(descriptorFlagsMutateDirectory)
+	}
+	if errno != 0 {
+		return cm.Err[cm.Own[*descriptor]](errorCodeOf(errno))
+	}
+
+	// Files opened relative to a restricted directory inherit its rights.
+	var newRights *internalsys.Rights
+	if r := d.entry.Rights; r != nil {
+		newRights = &internalsys.Rights{Base: r.Inheriting, Inheriting: r.Inheriting}
+		if denied := flags &^ flagsOfRights(r.Inheriting); denied&(descriptorFlagsWrite|descriptorFlagsMutateDirectory) != 0 {
+			return cm.Err[cm.Own[*descriptor]](errorCodeReadOnly)
+		} else if denied != 0 {
+			return cm.Err[cm.Own[*descriptor]](errorCodeNotPermitted)
+		}
+	}
+
+	var oflag experimentalsys.Oflag
+	switch {
+	case flags&descriptorFlagsRead != 0 && flags&descriptorFlagsWrite != 0:
+		oflag = experimentalsys.O_RDWR
+	case flags&descriptorFlagsWrite != 0:
+		oflag = experimentalsys.O_WRONLY
+	}
+	if pflags&pathFlagsSymlinkFollow == 0 {
+		oflag |= experimentalsys.O_NOFOLLOW
+	}
+	isDir := oflags&openFlagsDirectory != 0
+	if isDir {
+		if oflags&openFlagsCreate != 0 {
+			return cm.Err[cm.Own[*descriptor]](errorCodeInvalid) // use create-directory-at!
+		}
+		oflag |= experimentalsys.O_DIRECTORY
+	}
+	if oflags&openFlagsCreate != 0 {
+		oflag |= experimentalsys.O_CREAT
+	}
+	if oflags&openFlagsExclusive != 0 {
+		oflag |= experimentalsys.O_EXCL
+	}
+	if oflags&openFlagsTruncate != 0 {
+		oflag |= experimentalsys.O_TRUNC
+	}
+	if flags&descriptorFlagsWrite != 0 && newRights != nil && newRights.Base&wasip1.RIGHT_FD_SEEK == 0 {
+		oflag |= experimentalsys.O_APPEND // The rights only permit appending.
+	}
+	if flags&descriptorFlagsFileIntegritySync != 0 {
+		oflag |= experimentalsys.O_SYNC
+	}
+	if flags&descriptorFlagsDataIntegritySync != 0 {
+		oflag |= experimentalsys.O_DSYNC
+	}
+	if flags&descriptorFlagsRequestedWriteSync != 0 {
+		oflag |= experimentalsys.O_RSYNC
+	}
+
+	fsc := d.sysCtx.FS()
+	fd, errno := fsc.OpenFile(fsys, p, oflag, 0o600)
+	if errno != 0 {
+		return cm.Err[cm.Own[*descriptor]](errorCodeOf(errno))
+	}
+	entry, _ := fsc.LookupFile(fd)
+	entry.Rights = newRights
+	if isDir {
+		if isDir, errno = entry.File.IsDir(); errno == 0 && !isDir {
+			errno = experimentalsys.ENOTDIR
+		}
+		if errno != 0 {
+			_ = fsc.CloseFile(fd)
+			return cm.Err[cm.Own[*descriptor]](errorCodeOf(errno))
+		}
+	}
+	return cm.Ok[cm.Own[*descriptor], errorCode](cm.Own[*descriptor]{Rep: &descriptor{
+		sysCtx: d.sysCtx, fd: fd, entry: entry, preopen: d.preopen, flags: flags,
+	}})
+}
+
+func descriptorReadlinkAt(self cm.Borrow[*descriptor], p string) cm.Result[string, errorCode] {
+	fsys, p, errno := self.Rep.at(p)
+	if errno != 0 {
+		return cm.Err[string](errorCodeOf(errno))
+	}
+	dst, errno := fsys.Readlink(p)
+	if errno != 0 {
+		return cm.Err[string](errorCodeOf(errno))
+	}
+	return cm.Ok[string, errorCode](dst)
+}
+
+func descriptorRemoveDirectoryAt(self cm.Borrow[*descriptor], p string) cm.Result[struct{}, errorCode] {
+	fsys, p, errno := self.Rep.mutateAt(p)
+	if errno != 0 {
+		return resultOf(errno)
+	}
+	return resultOf(fsys.Rmdir(p))
+}
+
+func descriptorRenameAt(self cm.Borrow[*descriptor], oldPath string, newDescriptor cm.Borrow[*descriptor], newPath string) cm.Result[struct{}, errorCode] {
+	fsys, oldPath, newPath, res := atPaths(self.Rep, oldPath, newDescriptor.Rep, newPath)
+	if res.IsErr {
+		return res
+	}
+	return resultOf(fsys.Rename(oldPath, newPath))
+}
+
+// descriptorSymlinkAt creates the symbolic link at the new path to the old path, which is stored as given.
+func descriptorSymlinkAt(self cm.Borrow[*descriptor], oldPath, newPath string) cm.Result[struct{}, errorCode] {
+	fsys, newPath, errno := self.Rep.mutateAt(newPath)
+	if errno != 0 {
+		return resultOf(errno)
+	}
+	return resultOf(fsys.Symlink(oldPath, newPath))
+}
+
+func descriptorUnlinkFileAt(self cm.Borrow[*descriptor], p string) cm.Result[struct{}, errorCode] {
+	fsys, p, errno := self.Rep.mutateAt(p)
+	if errno != 0 {
+		return resultOf(errno)
+	}
+	return resultOf(fsys.Unlink(p))
+}
+
+func descriptorIsSameObject(self, other cm.Borrow[*descriptor]) bool {
+	st, errno := self.Rep.entry.File.Stat()
+	if errno != 0 {
+		return false
+	}
+	otherSt, errno := other.Rep.entry.File.Stat()
+	return errno == 0 && st.Dev == otherSt.Dev && st.Ino == otherSt.Ino
+}
+
+// metadataHashOf returns the hash of the stat, which is its device and inode.
+func metadataHashOf(st sys.Stat_t, errno experimentalsys.Errno) cm.Result[metadataHashValue, errorCode] {
+	if errno != 0 {
+		return cm.Err[metadataHashValue](errorCodeOf(errno))
+	}
+	return cm.Ok[metadataHashValue, errorCode](metadataHashValue{Lower: st.Ino, Upper: st.Dev})
+}
+
+func descriptorMetadataHash(self cm.Borrow[*descriptor]) cm.Result[metadataHashValue, errorCode] {
+	return metadataHashOf(self.Rep.entry.File.Stat())
+}
+
+func descriptorMetadataHashAt(self cm.Borrow[*descriptor], flags pathFlags, p string) cm.Result[metadataHashValue, errorCode] {
+	return metadataHashOf(self.Rep.statAt(flags, p))
+}
+
+// directoryEntryStream is the representation of the resource directory-entry-stream, which reads a directory.
+type directoryEntryStream struct {
+	dir experimentalsys.File
+}
+
+// Close implements api.Closer
+func (s *directoryEntryStream) Close(context.Context) error {
+	if errno := s.dir.Close(); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func directoryEntryStreamReadDirectoryEntry(self cm.Borrow[*directoryEntryStream]) cm.Result[cm.Option[directoryEntry], errorCode] {
+	dirents, errno := self.Rep.dir.Readdir(1)
+	if errno != 0 {
+		return cm.Err[cm.Option[directoryEntry]](errorCodeOf(errno))
+	} else if len(dirents) == 0 {
+		return cm.Ok[cm.Option[directoryEntry], errorCode](cm.None[directoryEntry]())
+	}
+	e := directoryEntry{Type: descriptorTypeOf(dirents[0].Type), Name: dirents[0].Name}
+	return cm.Ok[cm.Option[directoryEntry], errorCode](cm.Some(e))
+}
+
+// filesystemErrorCode returns the error-code of the error of a stream of a descriptor.
+func filesystemErrorCode(err cm.Borrow[*ioErr]) cm.Option[errorCode] {
+	if errno, ok := err.Rep.err.(experimentalsys.Errno); ok && errno != 0 {
+		return cm.Some(errorCodeOf(errno))
+	}
+	return cm.None[errorCode]()
+}
+
+// filesystemPreopens is the interface wasi:filesystem/preopens, whose directories are the pre-opens of the system
+// context.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/filesystem/preopens.wit
+var filesystemPreopens = newInterface("wasi:filesystem/preopens",
+	hostFunc{"get-directories", getDirectories},
+)
+
+// preopen is the tuple<descriptor, string> of a pre-opened directory and its path.
+type preopen struct {
+	cm.Tuple
+	Descriptor cm.Own[*descriptor]
+	Path       string
+}
+
+func getDirectories(_ context.Context, mod api.Module) []preopen {
+	s := sysCtx(mod)
+	ret := []preopen{}
+	for fd := int32(internalsys.FdPreopen); ; fd++ {
+		entry, ok := s.FS().LookupFile(fd)
+		if !ok || !entry.IsPreopen {
+			break
+		}
+		// The pre-opens which aren't directories, e.g. the TCP listeners, are not files of the file system.
+		if isDir, errno := entry.File.IsDir(); errno != 0 || !isDir {
+			continue
+		}
+		flags := descriptorFlagsRead | descriptorFlagsMutateDirectory
+		if entry.Rights != nil {
+			flags &= flagsOfRights(entry.Rights.Base)
+		}
+		d := &descriptor{sysCtx: s, fd: fd, entry: entry, preopen: fd, flags: flags}
+		ret = append(ret, preopen{Descriptor: cm.Own[*descriptor]{Rep: d}, Path: entry.Name})
+	}
+	return ret
+}
@@ -0,0 +1,337 @@
+package wasi_preview2
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+// newFSContext returns a system context whose pre-opens are the file systems at the guest paths.
+func newFSContext(t *testing.T, fs []experimentalsys.FS, guestPaths []string) *internalsys.Context {
+	return newFSContextWithRights(t, fs, guestPaths, nil)
+}
+
+// newFSContextWithRights is like newFSContext, except the pre-opens have the WASI rights.
+func newFSContextWithRights(t *testing.T, fs []experimentalsys.FS, guestPaths []string, rights []*internalsys.Rights) *internalsys.Context {
+	sysCtx, err := internalsys.NewContext(0, nil, nil, nil, nil, nil, nil, nil, 0, nil, 0, nil, nil, fs, guestPaths, rights, nil, nil)
+	require.NoError(t, err)
+	return sysCtx
+}
+
+// enumErrorCode is an error-code enum, e.g. the one of wasi:filesystem or wasi:sockets.
+type enumErrorCode interface {
+	~uint8
+	Cases() []string
+}
+
+// requireOk returns the value of the result, which must not be an error.
+func requireOk[T any, E enumErrorCode](t *testing.T, res cm.Result[T, E]) T {
+	t.Helper()
+	require.False(t, res.IsErr, "error-code %s", res.Err.Cases()[res.Err])
+	return res.Value
+}
+
+// requireErrorCode requires the result to be the error.
+func requireErrorCode[T any, E enumErrorCode](t *testing.T, expected E, res cm.Result[T, E]) {
+	t.Helper()
+	require.True(t, res.IsErr)
+	require.Equal(t, expected.Cases()[expected], res.Err.Cases()[res.Err])
+}
+
+func Test_getDirectories(t *testing.T) {
+	tmpDir, otherDir := t.TempDir(), t.TempDir()
+	sysCtx := newFSContext(t, []experimentalsys.FS{sysfs.DirFS(tmpDir), sysfs.DirFS(otherDir)}, []string{"/", "/tmp"})
+
+	preopens := getDirectories(testCtx, newModule(t, sysCtx))
+	require.Equal(t, 2, len(preopens))
+	require.Equal(t, "/", preopens[0].Path)
+	require.Equal(t, internalsys.FdPreopen, preopens[0].Descriptor.Rep.fd)
+	require.Equal(t, "/tmp", preopens[1].Path)
+	require.Equal(t, internalsys.FdPreopen+1, preopens[1].Descriptor.Rep.fd)
+
+	// Dropping a pre-open doesn't close it, as it's shared.
+	require.NoError(t, preopens[0].Descriptor.Rep.Close(testCtx))
+	_, ok := sysCtx.FS().LookupFile(internalsys.FdPreopen)
+	require.True(t, ok)
+}
+
+func Test_getDirectories_None(t *testing.T) {
+	require.Equal(t, []preopen{}, getDirectories(testCtx, newModule(t, newFSContext(t, nil, nil))))
+}
+
+func Test_descriptor(t *testing.T) {
+	tmpDir := t.TempDir()
+	sysCtx := newFSContext(t, []experimentalsys.FS{sysfs.DirFS(tmpDir)}, []string{"/"})
+	root := cm.Borrow[*descriptor]{Rep: getDirectories(testCtx, newModule(t, sysCtx))[0].Descriptor.Rep}
+
+	requireOk(t, descriptorCreateDirectoryAt(root, "dir"))
+	dirD := requireOk(t, descriptorOpenAt(root, 0, "dir", openFlagsDirectory,
+		descriptorFlagsRead|descriptorFlagsMutateDirectory)).Rep
+	require.Equal(t, descriptorTypeDirectory, requireOk(t, descriptorGetType(cm.Borrow[*descriptor]{Rep: dirD})))
+	dir := cm.Borrow[*descriptor]{Rep: dirD}
+
+	// Paths are relative to the directory of the descriptor.
+	f := cm.Borrow[*descriptor]{Rep: requireOk(t, descriptorOpenAt(dir, 0, "file", openFlagsCreate|openFlagsExclusive,
+		descriptorFlagsRead|descriptorFlagsWrite)).Rep}
+	require.Equal(t, descriptorFlagsRead|descriptorFlagsWrite, requireOk(t, descriptorGetFlags(f)))
+	require.Equal(t, uint64(5), requireOk(t, descriptorWrite(f, []byte("hello"), 0)))
+	require.Equal(t, readResult{Data: []byte("ell"), EOF: false}, requireOk(t, descriptorRead(f, 3, 1)))
+	require.Equal(t, readResult{Data: []byte("lo"), EOF: true}, requireOk(t, descriptorRead(f, 10, 3)))
+	b, err := os.ReadFile(path.Join(tmpDir, "dir", "file"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+
+	st := requireOk(t, descriptorStat(f))
+	require.Equal(t, descriptorTypeRegularFile, st.Type)
+	require.Equal(t, uint64(5), st.Size)
+	require.Equal(t, st, requireOk(t, descriptorStatAt(root, 0, "dir/file")))
+	require.True(t, descriptorIsSameObject(f, f))
+	require.False(t, descriptorIsSameObject(f, dir))
+	require.Equal(t, requireOk(t, descriptorMetadataHash(f)), requireOk(t, descriptorMetadataHashAt(dir, 0, "file")))
+
+	requireOk(t, descriptorSetSize(f, 4))
+	requireOk(t, descriptorSync(f))
+	requireOk(t, descriptorSyncData(f))
+	requireOk(t, descriptorAdvise(f, 0, 4, 0))
+
+	// The times are set, unless unchanged.
+	mtim := datetime{Seconds: 1234, Nanoseconds: 5000}
+	requireOk(t, descriptorSetTimes(f, newTimestamp{NoChange: &struct{}{}}, newTimestamp{Timestamp: &mtim}))
+	require.Equal(t, cm.Some(mtim), requireOk(t, descriptorStat(f)).DataModificationTimestamp)
+	mtim.Seconds++
+	requireOk(t, descriptorSetTimesAt(dir, 0, "file", newTimestamp{Now: &struct{}{}}, newTimestamp{Timestamp: &mtim}))
+	require.Equal(t, cm.Some(mtim), requireOk(t, descriptorStat(f)).DataModificationTimestamp)
+
+	requireOk(t, descriptorLinkAt(dir, 0, "file", root, "link"))
+	requireOk(t, descriptorSymlinkAt(root, "dir/file", "symlink"))
+	require.Equal(t, "dir/file", requireOk(t, descriptorReadlinkAt(root, "symlink")))
+	require.Equal(t, descriptorTypeSymbolicLink, requireOk(t, descriptorStatAt(root, 0, "symlink")).Type)
+	require.Equal(t, descriptorTypeRegularFile, requireOk(t, descriptorStatAt(root, pathFlagsSymlinkFollow, "symlink")).Type)
+
+	requireOk(t, descriptorRenameAt(root, "link", dir, "renamed"))
+	entries := requireOk(t, descriptorReadDirectory(dir)).Rep
+	var names []string
+	for {
+		e := requireOk(t, directoryEntryStreamReadDirectoryEntry(cm.Borrow[*directoryEntryStream]{Rep: entries}))
+		if !e.Valid {
+			break
+		}
+		require.Equal(t, descriptorTypeRegularFile, e.Value.Type)
+		names = append(names, e.Value.Name)
+	}
+	require.NoError(t, entries.Close(testCtx))
+	require.Equal(t, 2, len(names))
+
+	requireOk(t, descriptorUnlinkFileAt(dir, "renamed"))
+	requireOk(t, descriptorUnlinkFileAt(dir, "file"))
+	requireOk(t, descriptorUnlinkFileAt(root, "symlink"))
+	requireErrorCode(t, errorCodeNotEmpty, descriptorRemoveDirectoryAt(root, "."))
+
+	// Dropping a descriptor closes its file.
+	require.NoError(t, f.Rep.Close(testCtx))
+	require.NoError(t, dirD.Close(testCtx))
+	_, ok := sysCtx.FS().LookupFile(dirD.fd)
+	require.False(t, ok)
+	requireOk(t, descriptorRemoveDirectoryAt(root, "dir"))
+}
+
+func Test_descriptor_Streams(t *testing.T) {
+	tmpDir := t.TempDir()
+	sysCtx := newFSContext(t, []experimentalsys.FS{sysfs.DirFS(tmpDir)}, []string{"/"})
+	root := cm.Borrow[*descriptor]{Rep: getDirectories(testCtx, newModule(t, sysCtx))[0].Descriptor.Rep}
+	f := cm.Borrow[*descriptor]{Rep: requireOk(t, descriptorOpenAt(root, 0, "file", openFlagsCreate,
+		descriptorFlagsRead|descriptorFlagsWrite)).Rep}
+
+	w := requireOk(t, descriptorWriteViaStream(f, 2)).Rep
+	require.False(t, w.write([]byte("cd")).IsErr)
+	require.False(t, w.write([]byte("ef")).IsErr)
+	a := requireOk(t, descriptorAppendViaStream(f)).Rep
+	require.False(t, a.write([]byte("gh")).IsErr)
+	require.Equal(t, uint64(2), requireOk(t, descriptorWrite(f, []byte("ab"), 0)))
+
+	r := requireOk(t, descriptorReadViaStream(f, 1)).Rep
+	res := r.read(4, true)
+	require.False(t, res.IsErr)
+	require.Equal(t, "bcde", string(res.Value))
+	res = r.read(10, false)
+	require.False(t, res.IsErr)
+	require.Equal(t, "fgh", string(res.Value))
+	res = r.read(10, true)
+	require.True(t, res.IsErr)
+	require.NotNil(t, res.Err.Closed)
+
+	// The error of a stream has the error-code of the operation which failed.
+	require.NoError(t, f.Rep.Close(testCtx))
+	res = requireOk(t, descriptorReadViaStream(f, 0)).Rep.read(1, true)
+	require.True(t, res.IsErr)
+	e := cm.Borrow[*ioErr]{Rep: res.Err.LastOperationFailed.Rep}
+	require.Equal(t, cm.Some(errorCodeBadDescriptor), filesystemErrorCode(e))
+}
+
+func Test_descriptor_Errors(t *testing.T) {
+	tmpDir, otherDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("a"), 0o600))
+	sysCtx := newFSContext(t, []experimentalsys.FS{sysfs.DirFS(tmpDir), &sysfs.ReadFS{FS: sysfs.DirFS(otherDir)}},
+		[]string{"/", "/ro"})
+	preopens := getDirectories(testCtx, newModule(t, sysCtx))
+	root := cm.Borrow[*descriptor]{Rep: preopens[0].Descriptor.Rep}
+	ro := cm.Borrow[*descriptor]{Rep: preopens[1].Descriptor.Rep}
+	f := cm.Borrow[*descriptor]{Rep: requireOk(t, descriptorOpenAt(root, 0, "file", 0, descriptorFlagsRead)).Rep}
+
+	tests := []struct {
+		name     string
+		res      cm.Result[struct{}, errorCode]
+		expected errorCode
+	}{
+		{name: "escapes", res: descriptorCreateDirectoryAt(root, "../dir"), expected: errorCodeNotPermitted},
+		{name: "absolute", res: descriptorCreateDirectoryAt(root, "/dir"), expected: errorCodeNotPermitted},
+		{name: "empty", res: descriptorCreateDirectoryAt(root, ""), expected: errorCodeNoEntry},
+		{name: "exists", res: descriptorCreateDirectoryAt(root, "file"), expected: errorCodeExist},
+		{name: "not a directory", res: descriptorCreateDirectoryAt(f, "dir"), expected: errorCodeNotDirectory},
+		{name: "read-only", res: descriptorCreateDirectoryAt(ro, "dir"), expected: errorCodeReadOnly},
+		{name: "missing", res: descriptorUnlinkFileAt(root, "missing"), expected: errorCodeNoEntry},
+		{name: "cross-device", res: descriptorRenameAt(root, "file", ro, "file"), expected: errorCodeCrossDevice},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			requireErrorCode(t, tc.expected, tc.res)
+		})
+	}
+
+	t.Run("open-at", func(t *testing.T) {
+		requireErrorCode(t, errorCodeNoEntry, descriptorOpenAt(root, 0, "missing", 0, descriptorFlagsRead))
+		requireErrorCode(t, errorCodeNotDirectory, descriptorOpenAt(root, 0, "file", openFlagsDirectory, descriptorFlagsRead))
+		requireErrorCode(t, errorCodeInvalid, descriptorOpenAt(root, 0, "dir", openFlagsDirectory|openFlagsCreate, descriptorFlagsRead))
+		requireErrorCode(t, errorCodeExist, descriptorOpenAt(root, 0, "file", openFlagsCreate|openFlagsExclusive, descriptorFlagsRead))
+	})
+
+	t.Run("read a directory", func(t *testing.T) {
+		requireErrorCode(t, errorCodeIsDirectory, descriptorRead(root, 1, 0))
+	})
+}
+
+func Test_descriptor_Flags(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("a"), 0o600))
+	sysCtx := newFSContext(t, []experimentalsys.FS{sysfs.DirFS(tmpDir)}, []string{"/"})
+	root := cm.Borrow[*descriptor]{Rep: getDirectories(testCtx, newModule(t, sysCtx))[0].Descriptor.Rep}
+
+	// Descriptors can only read or write per their flags.
+	f := cm.Borrow[*descriptor]{Rep: requireOk(t, descriptorOpenAt(root, 0, "file", 0, descriptorFlagsWrite)).Rep}
+	requireErrorCode(t, errorCodeBadDescriptor, descriptorRead(f, 1, 0))
+	requireErrorCode(t, errorCodeBadDescriptor, descriptorReadViaStream(f, 0))
+	requireErrorCode(t, errorCodeBadDescriptor, descriptorWrite(root, []byte("a"), 0))
+	requireErrorCode(t, errorCodeBadDescriptor, descriptorSetSize(root, 0))
+
+	// A directory opened without mutate-directory can't change its entries, or open files to write.
+	dir := cm.Borrow[*descriptor]{Rep: requireOk(t, descriptorOpenAt(root, 0, ".", openFlagsDirectory, descriptorFlagsRead)).Rep}
+	requireErrorCode(t, errorCodeReadOnly, descriptorCreateDirectoryAt(dir, "dir"))
+	requireErrorCode(t, errorCodeReadOnly, descriptorUnlinkFileAt(dir, "file"))
+	requireErrorCode(t, errorCodeReadOnly, descriptorRenameAt(root, "file", dir, "renamed"))
+	requireErrorCode(t, errorCodeReadOnly, descriptorOpenAt(dir, 0, "file", 0, descriptorFlagsWrite))
+	requireErrorCode(t, errorCodeReadOnly, descriptorOpenAt(dir, 0, "new", openFlagsCreate, descriptorFlagsRead))
+	requireOk(t, descriptorOpenAt(dir, 0, "file", 0, descriptorFlagsRead))
+}
+
+func Test_descriptor_Rights(t *testing.T) {
+	tmpDir, otherDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("a"), 0o600))
+	readOnly := wasip1.RIGHT_PATH_OPEN | wasip1.RIGHT_FD_READDIR | wasip1.RIGHT_FD_READ | wasip1.RIGHT_FD_SEEK
+	appendOnly := wasip1.RIGHT_PATH_OPEN | wasip1.RIGHT_PATH_CREATE_FILE | wasip1.RIGHT_FD_READ | wasip1.RIGHT_FD_WRITE
+	sysCtx := newFSContextWithRights(t,
+		[]experimentalsys.FS{sysfs.DirFS(tmpDir), sysfs.DirFS(otherDir)}, []string{"/", "/logs"},
+		[]*internalsys.Rights{{Base: readOnly, Inheriting: readOnly}, {Base: appendOnly, Inheriting: appendOnly}})
+	preopens := getDirectories(testCtx, newModule(t, sysCtx))
+	root := cm.Borrow[*descriptor]{Rep: preopens[0].Descriptor.Rep}
+	logs := cm.Borrow[*descriptor]{Rep: preopens[1].Descriptor.Rep}
+
+	// The rights of the pre-opens limit their flags.
+	require.Equal(t, descriptorFlagsRead, requireOk(t, descriptorGetFlags(root)))
+	require.Equal(t, descriptorFlagsRead|descriptorFlagsMutateDirectory, requireOk(t, descriptorGetFlags(logs)))
+	requireErrorCode(t, errorCodeReadOnly, descriptorCreateDirectoryAt(root, "dir"))
+	requireErrorCode(t, errorCodeReadOnly, descriptorOpenAt(root, 0, "file", 0, descriptorFlagsRead|descriptorFlagsWrite))
+
+	// Files inherit the rights of their directory.
+	f := requireOk(t, descriptorOpenAt(root, 0, "file", 0, descriptorFlagsRead)).Rep
+	require.Equal(t, &internalsys.Rights{Base: readOnly, Inheriting: readOnly}, f.entry.Rights)
+	require.Equal(t, readResult{Data: []byte("a"), EOF: true}, requireOk(t, descriptorRead(cm.Borrow[*descriptor]{Rep: f}, 2, 0)))
+
+	// Files without the right to seek can only be appended to.
+	log := cm.Borrow[*descriptor]{Rep: requireOk(t, descriptorOpenAt(logs, 0, "log", openFlagsCreate, descriptorFlagsWrite)).Rep}
+	requireErrorCode(t, errorCodeAccess, descriptorWrite(log, []byte("a"), 0))
+	requireErrorCode(t, errorCodeAccess, descriptorWriteViaStream(log, 0))
+	a := requireOk(t, descriptorAppendViaStream(log)).Rep
+	require.False(t, a.write([]byte("ab")).IsErr)
+	require.False(t, a.write([]byte("cd")).IsErr)
+	b, err := os.ReadFile(path.Join(otherDir, "log"))
+	require.NoError(t, err)
+	require.Equal(t, "abcd", string(b))
+}
+
+func Test_errorCodeOf(t *testing.T) {
+	tests := []struct {
+		errno    experimentalsys.Errno
+		expected errorCode
+	}{
+		{errno: experimentalsys.EACCES, expected: errorCodeAccess},
+		{errno: experimentalsys.EAGAIN, expected: errorCodeWouldBlock},
+		{errno: experimentalsys.EBADF, expected: errorCodeBadDescriptor},
+		{errno: experimentalsys.EEXIST, expected: errorCodeExist},
+		{errno: experimentalsys.EFAULT, expected: errorCodeInvalid},
+		{errno: experimentalsys.EINTR, expected: errorCodeInterrupted},
+		{errno: experimentalsys.EINVAL, expected: errorCodeInvalid},
+		{errno: experimentalsys.EIO, expected: errorCodeIo},
+		{errno: experimentalsys.EISDIR, expected: errorCodeIsDirectory},
+		{errno: experimentalsys.ELOOP, expected: errorCodeLoop},
+		{errno: experimentalsys.ENAMETOOLONG, expected: errorCodeNameTooLong},
+		{errno: experimentalsys.ENOENT, expected: errorCodeNoEntry},
+		{errno: experimentalsys.ENOSYS, expected: errorCodeUnsupported},
+		{errno: experimentalsys.ENOTDIR, expected: errorCodeNotDirectory},
+		{errno: experimentalsys.ERANGE, expected: errorCodeOverflow},
+		{errno: experimentalsys.ENOTEMPTY, expected: errorCodeNotEmpty},
+		{errno: experimentalsys.ENOTSOCK, expected: errorCodeInvalid},
+		{errno: experimentalsys.ENOTSUP, expected: errorCodeUnsupported},
+		{errno: experimentalsys.EPERM, expected: errorCodeNotPermitted},
+		{errno: experimentalsys.EROFS, expected: errorCodeReadOnly},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.errno.Error(), func(t *testing.T) {
+			require.Equal(t, tc.expected, errorCodeOf(tc.errno))
+		})
+	}
+}
+
+func Test_descriptorTypeOf(t *testing.T) {
+	tests := []struct {
+		mode     fs.FileMode
+		expected descriptorType
+	}{
+		{mode: 0o600, expected: descriptorTypeRegularFile},
+		{mode: fs.ModeDir | 0o700, expected: descriptorTypeDirectory},
+		{mode: fs.ModeSymlink, expected: descriptorTypeSymbolicLink},
+		{mode: fs.ModeNamedPipe, expected: descriptorTypeFifo},
+		{mode: fs.ModeSocket, expected: descriptorTypeSocket},
+		{mode: fs.ModeDevice, expected: descriptorTypeBlockDevice},
+		{mode: fs.ModeDevice | fs.ModeCharDevice, expected: descriptorTypeCharacterDevice},
+		{mode: fs.ModeIrregular, expected: descriptorTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			require.Equal(t, tc.expected, descriptorTypeOf(tc.mode))
+		})
+	}
+}
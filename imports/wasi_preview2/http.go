@@ -0,0 +1,728 @@
+package wasi_preview2
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// httpTypes is the interface wasi:http/types, whose requests and responses are the ones of the net/http package.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/http/types.wit
+var httpTypes = newInterface("wasi:http/types",
+	hostFunc{"[constructor]fields", newFields},
+	hostFunc{"[static]fields.from-list", fieldsFromList},
+	hostFunc{"[method]fields.get", fieldsGet},
+	hostFunc{"[method]fields.has", fieldsHas},
+	hostFunc{"[method]fields.set", fieldsSet},
+	hostFunc{"[method]fields.delete", fieldsDelete},
+	hostFunc{"[method]fields.append", fieldsAppend},
+	hostFunc{"[method]fields.entries", fieldsEntries},
+	hostFunc{"[method]fields.clone", fieldsClone},
+	hostFunc{"[method]incoming-request.method", incomingRequestMethod},
+	hostFunc{"[method]incoming-request.path-with-query", incomingRequestPathWithQuery},
+	hostFunc{"[method]incoming-request.scheme", incomingRequestScheme},
+	hostFunc{"[method]incoming-request.authority", incomingRequestAuthority},
+	hostFunc{"[method]incoming-request.headers", incomingRequestHeaders},
+	hostFunc{"[method]incoming-request.consume", incomingRequestConsume},
+	hostFunc{"[constructor]outgoing-request", newOutgoingRequest},
+	hostFunc{"[method]outgoing-request.body", outgoingRequestBody},
+	hostFunc{"[method]outgoing-request.method", outgoingRequestMethod},
+	hostFunc{"[method]outgoing-request.set-method", outgoingRequestSetMethod},
+	hostFunc{"[method]outgoing-request.path-with-query", outgoingRequestPathWithQuery},
+	hostFunc{"[method]outgoing-request.set-path-with-query", outgoingRequestSetPathWithQuery},
+	hostFunc{"[method]outgoing-request.scheme", outgoingRequestScheme},
+	hostFunc{"[method]outgoing-request.set-scheme", outgoingRequestSetScheme},
+	hostFunc{"[method]outgoing-request.authority", outgoingRequestAuthority},
+	hostFunc{"[method]outgoing-request.set-authority", outgoingRequestSetAuthority},
+	hostFunc{"[method]outgoing-request.headers", outgoingRequestHeaders},
+	hostFunc{"[constructor]request-options", newRequestOptions},
+	hostFunc{"[method]request-options.connect-timeout", requestOptionsConnectTimeout},
+	hostFunc{"[method]request-options.set-connect-timeout", requestOptionsSetConnectTimeout},
+	hostFunc{"[method]request-options.first-byte-timeout", requestOptionsFirstByteTimeout},
+	hostFunc{"[method]request-options.set-first-byte-timeout", requestOptionsSetFirstByteTimeout},
+	hostFunc{"[method]request-options.between-bytes-timeout", requestOptionsBetweenBytesTimeout},
+	hostFunc{"[method]request-options.set-between-bytes-timeout", requestOptionsSetBetweenBytesTimeout},
+	hostFunc{"[static]response-outparam.set", responseOutparamSet},
+	hostFunc{"[method]incoming-response.status", incomingResponseStatus},
+	hostFunc{"[method]incoming-response.headers", incomingResponseHeaders},
+	hostFunc{"[method]incoming-response.consume", incomingResponseConsume},
+	hostFunc{"[method]incoming-body.stream", incomingBodyStream},
+	hostFunc{"[static]incoming-body.finish", incomingBodyFinish},
+	hostFunc{"[method]future-trailers.subscribe", futureTrailersSubscribe},
+	hostFunc{"[method]future-trailers.get", futureTrailersGet},
+	hostFunc{"[constructor]outgoing-response", newOutgoingResponse},
+	hostFunc{"[method]outgoing-response.status-code", outgoingResponseStatusCode},
+	hostFunc{"[method]outgoing-response.set-status-code", outgoingResponseSetStatusCode},
+	hostFunc{"[method]outgoing-response.headers", outgoingResponseHeaders},
+	hostFunc{"[method]outgoing-response.body", outgoingResponseBody},
+	hostFunc{"[method]outgoing-body.write", outgoingBodyWrite},
+	hostFunc{"[static]outgoing-body.finish", outgoingBodyFinish},
+	hostFunc{"[method]future-incoming-response.subscribe", futureIncomingResponseSubscribe},
+	hostFunc{"[method]future-incoming-response.get", futureIncomingResponseGet},
+	hostFunc{"http-error-code", httpErrorCodeFunc},
+)
+
+// method is the variant method.
+type method struct {
+	cm.Variant
+	Get, Head, Post, Put, Delete, Connect, Options, Trace, Patch *struct{}
+	Other                                                        *string
+}
+
+// methodOf returns the method of the name of an HTTP method, e.g. "GET".
+func methodOf(name string) method {
+	some := &struct{}{}
+	switch name {
+	case http.MethodGet:
+		return method{Get: some}
+	case http.MethodHead:
+		return method{Head: some}
+	case http.MethodPost:
+		return method{Post: some}
+	case http.MethodPut:
+		return method{Put: some}
+	case http.MethodDelete:
+		return method{Delete: some}
+	case http.MethodConnect:
+		return method{Connect: some}
+	case http.MethodOptions:
+		return method{Options: some}
+	case http.MethodTrace:
+		return method{Trace: some}
+	case http.MethodPatch:
+		return method{Patch: some}
+	}
+	return method{Other: &name}
+}
+
+// String returns the name of the method, e.g. "GET".
+func (m method) String() string {
+	switch {
+	case m.Head != nil:
+		return http.MethodHead
+	case m.Post != nil:
+		return http.MethodPost
+	case m.Put != nil:
+		return http.MethodPut
+	case m.Delete != nil:
+		return http.MethodDelete
+	case m.Connect != nil:
+		return http.MethodConnect
+	case m.Options != nil:
+		return http.MethodOptions
+	case m.Trace != nil:
+		return http.MethodTrace
+	case m.Patch != nil:
+		return http.MethodPatch
+	case m.Other != nil:
+		return *m.Other
+	}
+	return http.MethodGet
+}
+
+// scheme is the variant scheme.
+type scheme struct {
+	cm.Variant
+	HTTP, HTTPS *struct{}
+	Other       *string
+}
+
+// schemeOf returns the scheme of the name of a URL scheme, e.g. "https".
+func schemeOf(name string) scheme {
+	switch name {
+	case "http":
+		return scheme{HTTP: &struct{}{}}
+	case "https":
+		return scheme{HTTPS: &struct{}{}}
+	}
+	return scheme{Other: &name}
+}
+
+// String returns the name of the scheme, e.g. "https".
+func (s scheme) String() string {
+	switch {
+	case s.HTTP != nil:
+		return "http"
+	case s.Other != nil:
+		return *s.Other
+	}
+	return "https"
+}
+
+// dnsErrorPayload is the record DNS-error-payload.
+type dnsErrorPayload struct {
+	Rcode    cm.Option[string]
+	InfoCode cm.Option[uint16]
+}
+
+// tlsAlertReceivedPayload is the record TLS-alert-received-payload.
+type tlsAlertReceivedPayload struct {
+	AlertID      cm.Option[uint8]
+	AlertMessage cm.Option[string]
+}
+
+// fieldSizePayload is the record field-size-payload.
+type fieldSizePayload struct {
+	FieldName cm.Option[string]
+	FieldSize cm.Option[uint32]
+}
+
+// httpErrorCode is the variant error-code of wasi:http/types.
+type httpErrorCode struct {
+	cm.Variant
+	DNSTimeout                     *struct{}
+	DNSError                       *dnsErrorPayload
+	DestinationNotFound            *struct{}
+	DestinationUnavailable         *struct{}
+	DestinationIPProhibited        *struct{}
+	DestinationIPUnroutable        *struct{}
+	ConnectionRefused              *struct{}
+	ConnectionTerminated           *struct{}
+	ConnectionTimeout              *struct{}
+	ConnectionReadTimeout          *struct{}
+	ConnectionWriteTimeout         *struct{}
+	ConnectionLimitReached         *struct{}
+	TLSProtocolError               *struct{}
+	TLSCertificateError            *struct{}
+	TLSAlertReceived               *tlsAlertReceivedPayload
+	HTTPRequestDenied              *struct{}
+	HTTPRequestLengthRequired      *struct{}
+	HTTPRequestBodySize            *cm.Option[uint64]
+	HTTPRequestMethodInvalid       *struct{}
+	HTTPRequestURIInvalid          *struct{}
+	HTTPRequestURITooLong          *struct{}
+	HTTPRequestHeaderSectionSize   *cm.Option[uint32]
+	HTTPRequestHeaderSize          *cm.Option[fieldSizePayload]
+	HTTPRequestTrailerSectionSize  *cm.Option[uint32]
+	HTTPRequestTrailerSize         *fieldSizePayload
+	HTTPResponseIncomplete         *struct{}
+	HTTPResponseHeaderSectionSize  *cm.Option[uint32]
+	HTTPResponseHeaderSize         *fieldSizePayload
+	HTTPResponseBodySize           *cm.Option[uint64]
+	HTTPResponseTrailerSectionSize *cm.Option[uint32]
+	HTTPResponseTrailerSize        *fieldSizePayload
+	HTTPResponseTransferCoding     *cm.Option[string]
+	HTTPResponseContentCoding      *cm.Option[string]
+	HTTPResponseTimeout            *struct{}
+	HTTPUpgradeFailed              *struct{}
+	HTTPProtocolError              *struct{}
+	LoopDetected                   *struct{}
+	ConfigurationError             *struct{}
+	InternalError                  *cm.Option[string]
+}
+
+var (
+	// errFirstByteTimeout is the error of a response whose headers are not received before the first byte timeout.
+	errFirstByteTimeout = errors.New("first byte timeout")
+	// errBetweenBytesTimeout is the error of a body whose bytes are not received before the between bytes timeout.
+	errBetweenBytesTimeout = errors.New("between bytes timeout")
+)
+
+// httpErrorCodeOf returns the error-code of the error of a round trip, or of the read of a body.
+func httpErrorCodeOf(err error) httpErrorCode {
+	some := &struct{}{}
+	var dnsErr *net.DNSError
+	var alertErr tls.AlertError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errFirstByteTimeout):
+		return httpErrorCode{HTTPResponseTimeout: some}
+	case errors.Is(err, errBetweenBytesTimeout):
+		return httpErrorCode{ConnectionReadTimeout: some}
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return httpErrorCode{DNSTimeout: some}
+		}
+		return httpErrorCode{DNSError: &dnsErrorPayload{Rcode: cm.Some(dnsErr.Err)}}
+	case errors.As(err, &alertErr):
+		return httpErrorCode{TLSAlertReceived: &tlsAlertReceivedPayload{
+			AlertID:      cm.Some(uint8(alertErr)),
+			AlertMessage: cm.Some(alertErr.Error()),
+		}}
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return httpErrorCode{TLSCertificateError: some}
+	case errors.As(err, &recordErr):
+		return httpErrorCode{TLSProtocolError: some}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return httpErrorCode{ConnectionRefused: some}
+	case errors.Is(err, syscall.ECONNRESET):
+		return httpErrorCode{ConnectionTerminated: some}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return httpErrorCode{HTTPResponseIncomplete: some}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return httpErrorCode{ConnectionTimeout: some}
+	}
+	msg := err.Error()
+	return httpErrorCode{InternalError: &cm.Option[string]{Value: msg, Valid: true}}
+}
+
+// httpErrorCodeFunc is the function http-error-code, which returns the error-code of the error of a stream of a
+// body.
+func httpErrorCodeFunc(err cm.Borrow[*ioErr]) cm.Option[httpErrorCode] {
+	switch err.Rep.err.(type) {
+	case nil, experimentalsys.Errno:
+		return cm.None[httpErrorCode]()
+	}
+	return cm.Some(httpErrorCodeOf(err.Rep.err))
+}
+
+// headerError is the variant header-error.
+type headerError struct {
+	cm.Variant
+	InvalidSyntax, Forbidden, Immutable *struct{}
+}
+
+// fieldEntry is the tuple<field-key, field-value> of a field.
+type fieldEntry struct {
+	cm.Tuple
+	Key   string
+	Value []byte
+}
+
+// forbiddenFields are the lower-case names of the fields set by the round tripper, e.g. the hop-by-hop headers.
+var forbiddenFields = map[string]struct{}{
+	"connection":        {},
+	"host":              {},
+	"http2-settings":    {},
+	"keep-alive":        {},
+	"proxy-connection":  {},
+	"transfer-encoding": {},
+	"upgrade":           {},
+}
+
+// fieldError returns the header-error of the field, if it's invalid or forbidden.
+func fieldError(name string, values ...[]byte) (headerError, bool) {
+	if !validFieldName(name) {
+		return headerError{InvalidSyntax: &struct{}{}}, true
+	} else if _, ok := forbiddenFields[strings.ToLower(name)]; ok {
+		return headerError{Forbidden: &struct{}{}}, true
+	}
+	for _, v := range values {
+		if !validFieldValue(v) {
+			return headerError{InvalidSyntax: &struct{}{}}, true
+		}
+	}
+	return headerError{}, false
+}
+
+// validFieldName returns true if the name is a token of RFC 9110.
+func validFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// validFieldValue returns true if the value has no line break nor NUL, which would split or end the field.
+func validFieldValue(value []byte) bool {
+	for _, c := range value {
+		if c == '\r' || c == '\n' || c == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fields is the representation of the resource fields, which are the headers or the trailers of a request or a
+// response.
+type fields struct {
+	entries []fieldEntry
+	// immutable is true for the fields of a request or a response.
+	immutable bool
+}
+
+// fieldsOf returns the immutable fields of the header, whose names are in lower case and sorted.
+func fieldsOf(h http.Header) *fields {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f := &fields{immutable: true}
+	for _, k := range keys {
+		for _, v := range h[k] {
+			f.entries = append(f.entries, fieldEntry{Key: strings.ToLower(k), Value: []byte(v)})
+		}
+	}
+	return f
+}
+
+// view returns the immutable fields of the same entries, e.g. the headers of a request.
+func (f *fields) view() *fields {
+	return &fields{entries: f.entries, immutable: true}
+}
+
+// header returns the fields as a header.
+func (f *fields) header() http.Header {
+	h := make(http.Header, len(f.entries))
+	for _, e := range f.entries {
+		h.Add(e.Key, string(e.Value))
+	}
+	return h
+}
+
+func (f *fields) get(name string) [][]byte {
+	ret := [][]byte{}
+	for _, e := range f.entries {
+		if strings.EqualFold(e.Key, name) {
+			ret = append(ret, e.Value)
+		}
+	}
+	return ret
+}
+
+func (f *fields) delete(name string) {
+	entries := f.entries[:0]
+	for _, e := range f.entries {
+		if !strings.EqualFold(e.Key, name) {
+			entries = append(entries, e)
+		}
+	}
+	f.entries = entries
+}
+
+// mutate calls fn with the fields if they're mutable and the field is valid.
+func (f *fields) mutate(name string, values [][]byte, fn func()) cm.Result[struct{}, headerError] {
+	if f.immutable {
+		return cm.Err[struct{}](headerError{Immutable: &struct{}{}})
+	} else if herr, ok := fieldError(name, values...); ok {
+		return cm.Err[struct{}](herr)
+	}
+	fn()
+	return cm.Ok[struct{}, headerError](struct{}{})
+}
+
+func newFields() cm.Own[*fields] {
+	return cm.Own[*fields]{Rep: &fields{}}
+}
+
+func fieldsFromList(entries []fieldEntry) cm.Result[cm.Own[*fields], headerError] {
+	for _, e := range entries {
+		if herr, ok := fieldError(e.Key, e.Value); ok {
+			return cm.Err[cm.Own[*fields]](herr)
+		}
+	}
+	return cm.Ok[cm.Own[*fields], headerError](cm.Own[*fields]{Rep: &fields{entries: entries}})
+}
+
+func fieldsGet(self cm.Borrow[*fields], name string) [][]byte {
+	return self.Rep.get(name)
+}
+
+func fieldsHas(self cm.Borrow[*fields], name string) bool {
+	return len(self.Rep.get(name)) > 0
+}
+
+func fieldsSet(self cm.Borrow[*fields], name string, values [][]byte) cm.Result[struct{}, headerError] {
+	f := self.Rep
+	return f.mutate(name, values, func() {
+		f.delete(name)
+		for _, v := range values {
+			f.entries = append(f.entries, fieldEntry{Key: name, Value: v})
+		}
+	})
+}
+
+func fieldsDelete(self cm.Borrow[*fields], name string) cm.Result[struct{}, headerError] {
+	f := self.Rep
+	return f.mutate(name, nil, func() { f.delete(name) })
+}
+
+func fieldsAppend(self cm.Borrow[*fields], name string, value []byte) cm.Result[struct{}, headerError] {
+	f := self.Rep
+	return f.mutate(name, [][]byte{value}, func() {
+		f.entries = append(f.entries, fieldEntry{Key: name, Value: value})
+	})
+}
+
+func fieldsEntries(self cm.Borrow[*fields]) []fieldEntry {
+	return append([]fieldEntry{}, self.Rep.entries...)
+}
+
+// fieldsClone returns a mutable copy of the fields.
+func fieldsClone(self cm.Borrow[*fields]) cm.Own[*fields] {
+	return cm.Own[*fields]{Rep: &fields{entries: append([]fieldEntry{}, self.Rep.entries...)}}
+}
+
+// outgoingRequest is the representation of the resource outgoing-request.
+type outgoingRequest struct {
+	headers       *fields
+	method        method
+	pathWithQuery cm.Option[string]
+	scheme        cm.Option[scheme]
+	authority     cm.Option[string]
+	// body is the body of the request, or nil if it has none.
+	body *outgoingBody
+}
+
+func newOutgoingRequest(headers cm.Own[*fields]) cm.Own[*outgoingRequest] {
+	headers.Rep.immutable = true
+	return cm.Own[*outgoingRequest]{Rep: &outgoingRequest{headers: headers.Rep, method: method{Get: &struct{}{}}}}
+}
+
+func outgoingRequestBody(self cm.Borrow[*outgoingRequest]) cm.Result[cm.Own[*outgoingBody], struct{}] {
+	r := self.Rep
+	if r.body != nil {
+		return cm.Err[cm.Own[*outgoingBody]](struct{}{})
+	}
+	r.body = newOutgoingBody(r.headers)
+	return cm.Ok[cm.Own[*outgoingBody], struct{}](cm.Own[*outgoingBody]{Rep: r.body})
+}
+
+func outgoingRequestMethod(self cm.Borrow[*outgoingRequest]) method {
+	return self.Rep.method
+}
+
+func outgoingRequestSetMethod(self cm.Borrow[*outgoingRequest], m method) cm.Result[struct{}, struct{}] {
+	if m.Other != nil && !validFieldName(*m.Other) {
+		return cm.Err[struct{}](struct{}{})
+	}
+	self.Rep.method = m
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+func outgoingRequestPathWithQuery(self cm.Borrow[*outgoingRequest]) cm.Option[string] {
+	return self.Rep.pathWithQuery
+}
+
+func outgoingRequestSetPathWithQuery(self cm.Borrow[*outgoingRequest], pathWithQuery cm.Option[string]) cm.Result[struct{}, struct{}] {
+	if pathWithQuery.Valid && !validURIPart(pathWithQuery.Value, "") {
+		return cm.Err[struct{}](struct{}{})
+	}
+	self.Rep.pathWithQuery = pathWithQuery
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+func outgoingRequestScheme(self cm.Borrow[*outgoingRequest]) cm.Option[scheme] {
+	return self.Rep.scheme
+}
+
+func outgoingRequestSetScheme(self cm.Borrow[*outgoingRequest], s cm.Option[scheme]) cm.Result[struct{}, struct{}] {
+	if s.Valid && s.Value.Other != nil && !validScheme(*s.Value.Other) {
+		return cm.Err[struct{}](struct{}{})
+	}
+	self.Rep.scheme = s
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+func outgoingRequestAuthority(self cm.Borrow[*outgoingRequest]) cm.Option[string] {
+	return self.Rep.authority
+}
+
+func outgoingRequestSetAuthority(self cm.Borrow[*outgoingRequest], authority cm.Option[string]) cm.Result[struct{}, struct{}] {
+	if authority.Valid && (authority.Value == "" || !validURIPart(authority.Value, "/?#@")) {
+		return cm.Err[struct{}](struct{}{})
+	}
+	self.Rep.authority = authority
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+// outgoingRequestHeaders returns the immutable headers of the request.
+func outgoingRequestHeaders(self cm.Borrow[*outgoingRequest]) cm.Own[*fields] {
+	return cm.Own[*fields]{Rep: self.Rep.headers.view()}
+}
+
+// validURIPart returns true if the part of a URI has no whitespace, control character, nor any of the excluded.
+func validURIPart(part, excluded string) bool {
+	for i := 0; i < len(part); i++ {
+		if c := part[i]; c <= ' ' || c == 0x7f || strings.IndexByte(excluded, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// validScheme returns true if the scheme is valid per RFC 3986.
+func validScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.')) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// requestOptions is the representation of the resource request-options, whose durations are in nanoseconds.
+type requestOptions struct {
+	connectTimeout, firstByteTimeout, betweenBytesTimeout cm.Option[uint64]
+}
+
+func newRequestOptions() cm.Own[*requestOptions] {
+	return cm.Own[*requestOptions]{Rep: &requestOptions{}}
+}
+
+func requestOptionsConnectTimeout(self cm.Borrow[*requestOptions]) cm.Option[uint64] {
+	return self.Rep.connectTimeout
+}
+
+func requestOptionsSetConnectTimeout(self cm.Borrow[*requestOptions], d cm.Option[uint64]) cm.Result[struct{}, struct{}] {
+	self.Rep.connectTimeout = d
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+func requestOptionsFirstByteTimeout(self cm.Borrow[*requestOptions]) cm.Option[uint64] {
+	return self.Rep.firstByteTimeout
+}
+
+func requestOptionsSetFirstByteTimeout(self cm.Borrow[*requestOptions], d cm.Option[uint64]) cm.Result[struct{}, struct{}] {
+	self.Rep.firstByteTimeout = d
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+func requestOptionsBetweenBytesTimeout(self cm.Borrow[*requestOptions]) cm.Option[uint64] {
+	return self.Rep.betweenBytesTimeout
+}
+
+func requestOptionsSetBetweenBytesTimeout(self cm.Borrow[*requestOptions], d cm.Option[uint64]) cm.Result[struct{}, struct{}] {
+	self.Rep.betweenBytesTimeout = d
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+// incomingResponse is the representation of the resource incoming-response, which is the response of a round trip.
+type incomingResponse struct {
+	resp *http.Response
+	// body is the body of the response, once consumed.
+	body *incomingBody
+}
+
+// Close implements api.Closer, which closes the body unless consumed.
+func (r *incomingResponse) Close(ctx context.Context) error {
+	if r.body == nil {
+		return r.resp.Body.Close()
+	}
+	return nil
+}
+
+func incomingResponseStatus(self cm.Borrow[*incomingResponse]) uint16 {
+	return uint16(self.Rep.resp.StatusCode)
+}
+
+func incomingResponseHeaders(self cm.Borrow[*incomingResponse]) cm.Own[*fields] {
+	return cm.Own[*fields]{Rep: fieldsOf(self.Rep.resp.Header)}
+}
+
+func incomingResponseConsume(self cm.Borrow[*incomingResponse]) cm.Result[cm.Own[*incomingBody], struct{}] {
+	r := self.Rep
+	if r.body != nil {
+		return cm.Err[cm.Own[*incomingBody]](struct{}{})
+	}
+	r.body = &incomingBody{body: r.resp.Body, trailer: func() http.Header { return r.resp.Trailer }}
+	return cm.Ok[cm.Own[*incomingBody], struct{}](cm.Own[*incomingBody]{Rep: r.body})
+}
+
+// incomingBody is the representation of the resource incoming-body, which is the body of a request or a response.
+type incomingBody struct {
+	body io.ReadCloser
+	// trailer returns the trailer, which is complete once the body is read.
+	trailer func() http.Header
+	// file is the file of the stream of the body, once returned.
+	file *readAheadFile
+}
+
+// Close implements api.Closer
+func (b *incomingBody) Close(context.Context) error {
+	if b.file != nil {
+		_ = b.file.Close()
+		return nil
+	}
+	return b.body.Close()
+}
+
+func incomingBodyStream(_ context.Context, mod api.Module, self cm.Borrow[*incomingBody]) cm.Result[cm.Own[*inputStream], struct{}] {
+	b := self.Rep
+	if b.file != nil {
+		return cm.Err[cm.Own[*inputStream]](struct{}{})
+	}
+	b.file = newReadAheadFile(b.body, nil)
+	return cm.Ok[cm.Own[*inputStream], struct{}](cm.Own[*inputStream]{Rep: &inputStream{sysCtx: sysCtx(mod), file: b.file}})
+}
+
+// incomingBodyFinish returns the trailers of the body, once the rest of the body is read and discarded.
+func incomingBodyFinish(this cm.Own[*incomingBody]) cm.Own[*futureTrailers] {
+	b := this.Rep
+	trailers := goFuture(func() (http.Header, error) {
+		defer b.Close(context.Background())
+		var err error
+		if b.file == nil {
+			_, err = io.Copy(io.Discard, b.body)
+		} else {
+			buf := make([]byte, maxBufferSize)
+			for {
+				n, errno := b.file.Read(buf)
+				if errno != 0 {
+					if err = b.file.cause(); err == nil {
+						err = errno
+					}
+				}
+				if n == 0 {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return b.trailer(), nil
+	})
+	return cm.Own[*futureTrailers]{Rep: &futureTrailers{trailers: trailers}}
+}
+
+// futureTrailers is the representation of the resource future-trailers.
+type futureTrailers struct {
+	trailers *future[http.Header]
+	// got is true once the trailers are returned.
+	got bool
+}
+
+func futureTrailersSubscribe(_ context.Context, mod api.Module, self cm.Borrow[*futureTrailers]) cm.Own[*pollable] {
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: sysCtx(mod), waiter: self.Rep.trailers}}
+}
+
+// trailersResult is the result<result<option<trailers>, error-code>> of future-trailers.get.
+type trailersResult = cm.Result[cm.Result[cm.Option[cm.Own[*fields]], httpErrorCode], struct{}]
+
+func futureTrailersGet(self cm.Borrow[*futureTrailers]) cm.Option[trailersResult] {
+	f := self.Rep
+	switch {
+	case !f.trailers.ready():
+		return cm.None[trailersResult]()
+	case f.got:
+		return cm.Some(cm.Err[cm.Result[cm.Option[cm.Own[*fields]], httpErrorCode]](struct{}{}))
+	}
+	f.got = true
+	var res cm.Result[cm.Option[cm.Own[*fields]], httpErrorCode]
+	switch {
+	case f.trailers.err != nil:
+		res = cm.Err[cm.Option[cm.Own[*fields]]](httpErrorCodeOf(f.trailers.err))
+	case len(f.trailers.value) == 0:
+		res = cm.Ok[cm.Option[cm.Own[*fields]], httpErrorCode](cm.None[cm.Own[*fields]]())
+	default:
+		res = cm.Ok[cm.Option[cm.Own[*fields]], httpErrorCode](cm.Some(cm.Own[*fields]{Rep: fieldsOf(f.trailers.value)}))
+	}
+	return cm.Some(cm.Ok[cm.Result[cm.Option[cm.Own[*fields]], httpErrorCode], struct{}](res))
+}
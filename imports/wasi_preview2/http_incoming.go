@@ -0,0 +1,342 @@
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+)
+
+// IncomingHandlerName is the name of the function exported by a proxy
+// component, which is the handle function of its wasi:http/incoming-handler
+// interface.
+const IncomingHandlerName = "wasi:http/incoming-handler@" + Version + "#handle"
+
+// MaxIdleInstances is the maximum count of instances idle in the pool of an
+// IncomingHandler. Instances beyond it are closed once they handled their
+// request.
+const MaxIdleInstances = 16
+
+// IncomingHandler is a http.Handler of the requests handled by the instances
+// of a proxy component, which is returned by NewIncomingHandler.
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+//   - Close closes the instances idle in the pool, which are otherwise
+//     closed with the wazero.Runtime.
+type IncomingHandler interface {
+	http.Handler
+	api.Closer
+}
+
+// NewIncomingHandler returns a http.Handler which calls the function
+// IncomingHandlerName of the compiled component for each request.
+//
+// Each request is handled by an instance of the component, which is
+// instantiated with wazero.Runtime InstantiateModule and the config, unless
+// one is idle in the pool of the handler. The instance returns to the pool
+// once the request is handled, unless its call failed, or the pool already
+// has MaxIdleInstances, as after a burst of concurrent requests.
+//
+// The bodies of the request and of the response are streamed through the
+// streams of wasi:io, and the context of the call is the one of the request,
+// so that it's canceled when the client disconnects. Configure the runtime
+// with wazero.RuntimeConfig WithCloseOnContextDone to interrupt a guest
+// which doesn't return then.
+//
+// e.g. Serve the requests of a component on a port.
+//
+//	compiled, _ := r.CompileModule(ctx, component)
+//	h, _ := wasi_preview2.NewIncomingHandler(r, compiled, wazero.NewModuleConfig())
+//	defer h.Close(ctx)
+//	_ = http.ListenAndServe(":8080", h)
+//
+// Note: The host modules of the interfaces must be instantiated into the
+// runtime before the requests, e.g. with Instantiate.
+func NewIncomingHandler(r wazero.Runtime, compiled wazero.CompiledModule, config wazero.ModuleConfig) (IncomingHandler, error) {
+	if _, ok := compiled.ExportedFunctions()[IncomingHandlerName]; !ok {
+		return nil, fmt.Errorf("component doesn't export %s", IncomingHandlerName)
+	}
+	// The instances are anonymous, so that there can be several of them.
+	config = config.WithName("")
+	return &incomingHandler{
+		maxIdle: MaxIdleInstances,
+		instantiate: func(ctx context.Context) (api.Module, error) {
+			return r.InstantiateModule(ctx, compiled, config)
+		},
+		handle: func(ctx context.Context, mod api.Module, request *incomingRequest, out *responseOutparam) error {
+			return cm.Call(ctx, mod.ExportedFunction(IncomingHandlerName), nil,
+				cm.Own[*incomingRequest]{Rep: request}, cm.Own[*responseOutparam]{Rep: out})
+		},
+	}, nil
+}
+
+// incomingHandler implements IncomingHandler
+type incomingHandler struct {
+	instantiate func(context.Context) (api.Module, error)
+	// handle calls the function IncomingHandlerName of the instance.
+	handle func(context.Context, api.Module, *incomingRequest, *responseOutparam) error
+
+	// maxIdle is the maximum length of idle.
+	maxIdle int
+
+	mu sync.Mutex
+	// idle are the instances idle in the pool.
+	idle   []api.Module
+	closed bool
+}
+
+// get returns an instance idle in the pool, or else a new instance.
+func (h *incomingHandler) get(ctx context.Context) (api.Module, error) {
+	h.mu.Lock()
+	if n := len(h.idle); n > 0 {
+		mod := h.idle[n-1]
+		h.idle = h.idle[:n-1]
+		h.mu.Unlock()
+		return mod, nil
+	}
+	h.mu.Unlock()
+	return h.instantiate(ctx)
+}
+
+// put returns the instance to the pool, unless the handler is closed or the
+// pool is full.
+func (h *incomingHandler) put(ctx context.Context, mod api.Module) {
+	h.mu.Lock()
+	if !h.closed && len(h.idle) < h.maxIdle {
+		h.idle = append(h.idle, mod)
+		mod = nil
+	}
+	h.mu.Unlock()
+	if mod != nil {
+		_ = mod.Close(ctx)
+	}
+}
+
+// Close implements api.Closer
+func (h *incomingHandler) Close(ctx context.Context) (err error) {
+	h.mu.Lock()
+	idle := h.idle
+	h.idle, h.closed = nil, true
+	h.mu.Unlock()
+	for _, mod := range idle {
+		if e := mod.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// ServeHTTP implements http.Handler
+func (h *incomingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	mod, err := h.get(ctx)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	request := &incomingRequest{req: req}
+	out := &responseOutparam{w: w}
+	err = h.handle(ctx, mod, request, out)
+	// The body of the request can't be read once the request is handled.
+	if request.body != nil {
+		_ = request.body.Close(ctx)
+	}
+	if err != nil {
+		_ = mod.Close(ctx) // The state of an instance whose call failed is unknown.
+	} else {
+		h.put(ctx, mod)
+	}
+
+	if err = out.finish(err); err != nil {
+		if !out.sent {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		// Abort the response whose headers are sent, so that the client doesn't take it as complete.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// incomingRequest is the representation of the resource incoming-request, which is the request of a handler.
+type incomingRequest struct {
+	req *http.Request
+	// body is the body of the request, once consumed.
+	body *incomingBody
+}
+
+func incomingRequestMethod(self cm.Borrow[*incomingRequest]) method {
+	return methodOf(self.Rep.req.Method)
+}
+
+func incomingRequestPathWithQuery(self cm.Borrow[*incomingRequest]) cm.Option[string] {
+	return cm.Some(self.Rep.req.URL.RequestURI())
+}
+
+func incomingRequestScheme(self cm.Borrow[*incomingRequest]) cm.Option[scheme] {
+	if self.Rep.req.TLS != nil {
+		return cm.Some(schemeOf("https"))
+	}
+	return cm.Some(schemeOf("http"))
+}
+
+func incomingRequestAuthority(self cm.Borrow[*incomingRequest]) cm.Option[string] {
+	if host := self.Rep.req.Host; host != "" {
+		return cm.Some(host)
+	}
+	return cm.None[string]()
+}
+
+func incomingRequestHeaders(self cm.Borrow[*incomingRequest]) cm.Own[*fields] {
+	return cm.Own[*fields]{Rep: fieldsOf(self.Rep.req.Header)}
+}
+
+func incomingRequestConsume(self cm.Borrow[*incomingRequest]) cm.Result[cm.Own[*incomingBody], struct{}] {
+	r := self.Rep
+	if r.body != nil {
+		return cm.Err[cm.Own[*incomingBody]](struct{}{})
+	}
+	r.body = &incomingBody{body: r.req.Body, trailer: func() http.Header { return r.req.Trailer }}
+	return cm.Ok[cm.Own[*incomingBody], struct{}](cm.Own[*incomingBody]{Rep: r.body})
+}
+
+// outgoingResponse is the representation of the resource outgoing-response, which is the response of a handler.
+type outgoingResponse struct {
+	status  uint16
+	headers *fields
+	// body is the body of the response, or nil if it has none.
+	body *outgoingBody
+}
+
+func newOutgoingResponse(headers cm.Own[*fields]) cm.Own[*outgoingResponse] {
+	headers.Rep.immutable = true
+	return cm.Own[*outgoingResponse]{Rep: &outgoingResponse{status: http.StatusOK, headers: headers.Rep}}
+}
+
+func outgoingResponseStatusCode(self cm.Borrow[*outgoingResponse]) uint16 {
+	return self.Rep.status
+}
+
+func outgoingResponseSetStatusCode(self cm.Borrow[*outgoingResponse], status uint16) cm.Result[struct{}, struct{}] {
+	// The status codes are the ones of 3 digits, as for http.ResponseWriter WriteHeader.
+	if status < 100 || status > 999 {
+		return cm.Err[struct{}](struct{}{})
+	}
+	self.Rep.status = status
+	return cm.Ok[struct{}, struct{}](struct{}{})
+}
+
+// outgoingResponseHeaders returns the immutable headers of the response.
+func outgoingResponseHeaders(self cm.Borrow[*outgoingResponse]) cm.Own[*fields] {
+	return cm.Own[*fields]{Rep: self.Rep.headers.view()}
+}
+
+func outgoingResponseBody(self cm.Borrow[*outgoingResponse]) cm.Result[cm.Own[*outgoingBody], struct{}] {
+	r := self.Rep
+	if r.body != nil {
+		return cm.Err[cm.Own[*outgoingBody]](struct{}{})
+	}
+	r.body = newOutgoingBody(r.headers)
+	return cm.Ok[cm.Own[*outgoingBody], struct{}](cm.Own[*outgoingBody]{Rep: r.body})
+}
+
+var (
+	// errNoResponse is the error of a request handled without setting its response.
+	errNoResponse = errors.New("response not set")
+	// errResponseErrorCode is the error of a request whose response is set to an error-code.
+	errResponseErrorCode = errors.New("response set to an error-code")
+)
+
+// responseOutparam is the representation of the resource response-outparam, whose response is written to the
+// http.ResponseWriter of the request when set.
+type responseOutparam struct {
+	w http.ResponseWriter
+	// sent is true once the headers of the response are sent.
+	sent bool
+	// errorCode is the error-code set, or nil if none.
+	errorCode *httpErrorCode
+	// body is the body of the response, which is copied by a goroutine until copied is closed.
+	body   *outgoingBody
+	copied chan struct{}
+	// copyErr is the error of the copy, once copied.
+	copyErr error
+}
+
+// responseOutparamSet sends the headers of the response, and then its body while it's written.
+func responseOutparamSet(param cm.Own[*responseOutparam], response cm.Result[cm.Own[*outgoingResponse], httpErrorCode]) {
+	o := param.Rep
+	if response.IsErr {
+		o.errorCode = &response.Err
+		return
+	}
+	r := response.Value.Rep
+	h := o.w.Header()
+	for _, e := range r.headers.entries {
+		h.Add(e.Key, string(e.Value))
+	}
+	o.w.WriteHeader(int(r.status))
+	o.sent = true
+	if r.body != nil {
+		o.body, o.copied = r.body, make(chan struct{})
+		go o.copy()
+	}
+}
+
+// copy writes the body to the http.ResponseWriter as it's written, and then its trailers.
+func (o *responseOutparam) copy() {
+	defer close(o.copied)
+	rc := http.NewResponseController(o.w)
+	r := outgoingBodyReader{o.body}
+	buf := make([]byte, maxBufferSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := o.w.Write(buf[:n]); werr != nil {
+				_ = r.Close() // The writes of the guest fail once the client is gone.
+				o.copyErr = werr
+				return
+			}
+			_ = rc.Flush()
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			o.copyErr = err
+			return
+		}
+	}
+	h := o.w.Header()
+	for k, v := range o.body.trailer {
+		h[http.TrailerPrefix+k] = v
+	}
+}
+
+// finish waits until the body of the response is copied, and returns the error of the request, if any, given the
+// error of the call of the handler.
+func (o *responseOutparam) finish(callErr error) error {
+	if o.body != nil {
+		// The body can't be written once the call of the handler returns.
+		o.body.end(errBodyDropped)
+		<-o.copied
+	}
+	switch {
+	case callErr != nil:
+		return callErr
+	case o.copyErr != nil:
+		return o.copyErr
+	case o.errorCode != nil:
+		return errResponseErrorCode
+	case !o.sent:
+		return errNoResponse
+	}
+	return nil
+}
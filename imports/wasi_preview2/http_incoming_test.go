@@ -0,0 +1,277 @@
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// pooledModule is an instance of a handler, which records whether it's closed.
+type pooledModule struct {
+	api.Module
+	mu     sync.Mutex
+	closed bool
+}
+
+// Close implements api.Closer
+func (m *pooledModule) Close(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+// newIncomingHandler returns a handler whose function of each instance is handle, and which records the instances.
+func newIncomingHandler(instances *[]*pooledModule, handle func(context.Context, *incomingRequest, *responseOutparam) error) *incomingHandler {
+	var mu sync.Mutex
+	return &incomingHandler{
+		maxIdle: MaxIdleInstances,
+		instantiate: func(context.Context) (api.Module, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			m := &pooledModule{}
+			*instances = append(*instances, m)
+			return m, nil
+		},
+		handle: func(ctx context.Context, _ api.Module, request *incomingRequest, out *responseOutparam) error {
+			return handle(ctx, request, out)
+		},
+	}
+}
+
+// setResponse sets the response of the status and the headers, and returns the output stream of its body.
+func setResponse(t *testing.T, mod api.Module, out *responseOutparam, status uint16, headers ...string) (*outgoingBody, cm.Borrow[*outputStream]) {
+	f := cm.Borrow[*fields]{Rep: newFields().Rep}
+	for i := 0; i < len(headers); i += 2 {
+		require.False(t, fieldsAppend(f, headers[i], []byte(headers[i+1])).IsErr)
+	}
+	resp := cm.Borrow[*outgoingResponse]{Rep: newOutgoingResponse(cm.Own[*fields]{Rep: f.Rep}).Rep}
+	require.False(t, outgoingResponseSetStatusCode(resp, status).IsErr)
+	body := requireUnitOk(t, outgoingResponseBody(resp)).Rep
+	responseOutparamSet(cm.Own[*responseOutparam]{Rep: out},
+		cm.Ok[cm.Own[*outgoingResponse], httpErrorCode](cm.Own[*outgoingResponse]{Rep: resp.Rep}))
+	return body, cm.Borrow[*outputStream]{Rep: requireUnitOk(t, outgoingBodyWrite(testCtx, mod, cm.Borrow[*outgoingBody]{Rep: body})).Rep}
+}
+
+func TestNewIncomingHandler(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{}))
+	require.NoError(t, err)
+	_, err = NewIncomingHandler(r, compiled, wazero.NewModuleConfig())
+	require.EqualError(t, err, "component doesn't export wasi:http/incoming-handler@0.2.0#handle")
+}
+
+func Test_incomingHandler(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var instances []*pooledModule
+	// The handler responds with the body of the request in upper case, and the trailers of the request.
+	h := newIncomingHandler(&instances, func(_ context.Context, request *incomingRequest, out *responseOutparam) error {
+		req := cm.Borrow[*incomingRequest]{Rep: request}
+		require.Equal(t, "PUT", incomingRequestMethod(req).String())
+		require.Equal(t, "/a?b=c", incomingRequestPathWithQuery(req).Value)
+		require.Equal(t, "http", incomingRequestScheme(req).Value.String())
+		headers := cm.Borrow[*fields]{Rep: incomingRequestHeaders(req).Rep}
+
+		reqBody := cm.Borrow[*incomingBody]{Rep: requireUnitOk(t, incomingRequestConsume(req)).Rep}
+		require.True(t, incomingRequestConsume(req).IsErr)
+		in := borrow(requireUnitOk(t, incomingBodyStream(testCtx, mod, reqBody)))
+
+		body, outStream := setResponse(t, mod, out, 201, "x-echo", string(fieldsGet(headers, "x-echo")[0]))
+		for {
+			res := inputStreamBlockingRead(in, maxBufferSize)
+			if res.IsErr {
+				require.NotNil(t, res.Err.Closed)
+				break
+			}
+			require.False(t, outputStreamWrite(outStream, []byte(strings.ToUpper(string(res.Value)))).IsErr)
+		}
+
+		ft := borrow(incomingBodyFinish(cm.Own[*incomingBody]{Rep: reqBody.Rep}))
+		pollableBlock(testCtx, borrow(futureTrailersSubscribe(testCtx, mod, ft)))
+		tr := requireHTTPOk(t, futureTrailersGet(ft).Value.Value)
+		res := outgoingBodyFinish(cm.Own[*outgoingBody]{Rep: body}, tr)
+		require.False(t, res.IsErr)
+		return nil
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		pr, pw := io.Pipe()
+		req, err := http.NewRequest("PUT", srv.URL+"/a?b=c", pr)
+		require.NoError(t, err)
+		req.Header.Set("X-Echo", "hello")
+		req.Trailer = http.Header{"X-Checksum": nil}
+		go func() {
+			_, _ = pw.Write([]byte("hello "))
+			_, _ = pw.Write([]byte("world"))
+			req.Trailer.Set("X-Checksum", "abc")
+			_ = pw.Close()
+		}()
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, 201, resp.StatusCode)
+		require.Equal(t, "hello", resp.Header.Get("X-Echo"))
+		require.Equal(t, "HELLO WORLD", string(b))
+		require.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+	}
+
+	// The instance is reused by the second request.
+	require.Equal(t, 1, len(instances))
+	require.NoError(t, h.Close(testCtx))
+	require.True(t, instances[0].closed)
+}
+
+func Test_incomingHandler_MaxIdle(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var instances []*pooledModule
+	var started sync.WaitGroup
+	started.Add(4)
+	release := make(chan struct{})
+	h := newIncomingHandler(&instances, func(_ context.Context, _ *incomingRequest, out *responseOutparam) error {
+		started.Done()
+		<-release
+		body, _ := setResponse(t, mod, out, 204)
+		require.False(t, outgoingBodyFinish(cm.Own[*outgoingBody]{Rep: body}, cm.None[cm.Own[*fields]]()).IsErr)
+		return nil
+	})
+	h.maxIdle = 2
+
+	// A burst of concurrent requests needs an instance each.
+	var done sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}()
+	}
+	started.Wait()
+	close(release)
+	done.Wait()
+
+	// Only maxIdle instances are kept in the pool, and the others closed.
+	require.Equal(t, 4, len(instances))
+	require.Equal(t, 2, len(h.idle))
+	closed := 0
+	for _, m := range instances {
+		if m.closed {
+			closed++
+		}
+	}
+	require.Equal(t, 2, closed)
+	require.NoError(t, h.Close(testCtx))
+}
+
+func Test_incomingHandler_Errors(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	tests := []struct {
+		name   string
+		handle func(context.Context, *incomingRequest, *responseOutparam) error
+	}{
+		{
+			name: "trap",
+			handle: func(context.Context, *incomingRequest, *responseOutparam) error {
+				return errors.New("unreachable")
+			},
+		},
+		{
+			name: "no response",
+			handle: func(context.Context, *incomingRequest, *responseOutparam) error {
+				return nil
+			},
+		},
+		{
+			name: "error-code",
+			handle: func(_ context.Context, _ *incomingRequest, out *responseOutparam) error {
+				responseOutparamSet(cm.Own[*responseOutparam]{Rep: out},
+					cm.Err[cm.Own[*outgoingResponse]](httpErrorCode{InternalError: &cm.Option[string]{}}))
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var instances []*pooledModule
+			srv := httptest.NewServer(newIncomingHandler(&instances, tc.handle))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, 500, resp.StatusCode)
+		})
+	}
+
+	t.Run("trap closes the instance", func(t *testing.T) {
+		var instances []*pooledModule
+		h := newIncomingHandler(&instances, tests[0].handle)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, 1, len(instances))
+		require.True(t, instances[0].closed)
+		require.Equal(t, 0, len(h.idle))
+	})
+
+	t.Run("body not finished", func(t *testing.T) {
+		var instances []*pooledModule
+		srv := httptest.NewServer(newIncomingHandler(&instances, func(_ context.Context, _ *incomingRequest, out *responseOutparam) error {
+			_, outStream := setResponse(t, mod, out, 200)
+			require.False(t, outputStreamWrite(outStream, []byte("partial")).IsErr)
+			return nil
+		}))
+		defer srv.Close()
+
+		// The response is aborted, so that it isn't taken as complete.
+		resp, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.Error(t, err)
+		require.NoError(t, resp.Body.Close())
+	})
+}
+
+func Test_incomingHandler_Cancel(t *testing.T) {
+	var instances []*pooledModule
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+	srv := httptest.NewServer(newIncomingHandler(&instances, func(ctx context.Context, _ *incomingRequest, _ *responseOutparam) error {
+		close(started)
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return ctx.Err()
+	}))
+	defer srv.Close()
+
+	// The context of the call is canceled when the client disconnects.
+	ctx, cancel := context.WithCancel(testCtx)
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	require.NoError(t, err)
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err = http.DefaultClient.Do(req)
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, <-canceled, context.Canceled)
+}
@@ -0,0 +1,426 @@
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+)
+
+// roundTripperKey is a context.Context Value key. Its associated value should be a http.RoundTripper.
+type roundTripperKey struct{}
+
+// WithRoundTripper returns a new context which makes the requests of
+// wasi:http/outgoing-handler of the calls with it go through rt, instead of
+// the round tripper of the Builder.
+//
+// e.g. A host can intercept the requests of one module only, by calling its
+// functions with this context.
+func WithRoundTripper(ctx context.Context, rt http.RoundTripper) context.Context {
+	return context.WithValue(ctx, roundTripperKey{}, rt)
+}
+
+// httpOutgoingHandler returns the interface wasi:http/outgoing-handler, whose requests go through rt, unless the
+// context of the call has another, or are denied if neither is set.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/http/handler.wit
+func httpOutgoingHandler(rt http.RoundTripper) *hostInterface {
+	return newInterface("wasi:http/outgoing-handler",
+		hostFunc{"handle", func(ctx context.Context, request cm.Own[*outgoingRequest], options cm.Option[cm.Own[*requestOptions]]) cm.Result[cm.Own[*futureIncomingResponse], httpErrorCode] {
+			if v, ok := ctx.Value(roundTripperKey{}).(http.RoundTripper); ok && v != nil {
+				rt = v
+			}
+			o := &requestOptions{}
+			if options.Valid {
+				o = options.Value.Rep
+			}
+			return handle(rt, request.Rep, o)
+		}},
+	)
+}
+
+// handle starts the round trip of the request, whose response is returned by the future.
+func handle(rt http.RoundTripper, request *outgoingRequest, options *requestOptions) cm.Result[cm.Own[*futureIncomingResponse], httpErrorCode] {
+	if rt == nil {
+		return cm.Err[cm.Own[*futureIncomingResponse]](httpErrorCode{HTTPRequestDenied: &struct{}{}})
+	} else if !request.authority.Valid {
+		return cm.Err[cm.Own[*futureIncomingResponse]](httpErrorCode{HTTPRequestURIInvalid: &struct{}{}})
+	}
+	s := "https"
+	if request.scheme.Valid {
+		s = request.scheme.Value.String()
+	}
+	path := "/"
+	if request.pathWithQuery.Valid && request.pathWithQuery.Value != "" {
+		path = request.pathWithQuery.Value
+	}
+	u, err := url.Parse(s + "://" + request.authority.Value + path)
+	if err != nil {
+		return cm.Err[cm.Own[*futureIncomingResponse]](httpErrorCode{HTTPRequestURIInvalid: &struct{}{}})
+	}
+
+	// The round trip outlives the call of handle, so it's only canceled by the timeouts and the drop of its future.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	req, err := http.NewRequestWithContext(ctx, request.method.String(), u.String(), nil)
+	if err != nil {
+		cancel(nil)
+		return cm.Err[cm.Own[*futureIncomingResponse]](httpErrorCode{HTTPRequestMethodInvalid: &struct{}{}})
+	}
+	req.Header = request.headers.header()
+	if b := request.body; b != nil {
+		req.Body = outgoingBodyReader{b}
+		req.ContentLength = b.contentLength
+		// The trailers are set by the read of the end of the body, before the round tripper writes them.
+		req.Trailer = b.trailer
+	}
+
+	// The first byte timeout includes the connect timeout, as a round tripper doesn't report the connection.
+	var timer *time.Timer
+	if d, ok := options.firstByte(); ok {
+		timer = time.AfterFunc(d, func() { cancel(errFirstByteTimeout) })
+	}
+	resp := goFuture(func() (*http.Response, error) {
+		resp, err := rt.RoundTrip(req)
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+				err = cause
+			}
+			cancel(nil)
+			return nil, err
+		}
+		resp.Body = &responseBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel, timeout: options.betweenBytes()}
+		return resp, nil
+	})
+	return cm.Ok[cm.Own[*futureIncomingResponse], httpErrorCode](cm.Own[*futureIncomingResponse]{
+		Rep: &futureIncomingResponse{resp: resp, cancel: cancel},
+	})
+}
+
+// firstByte returns the minimum of the connect and the first byte timeouts, and false if neither is set.
+func (o *requestOptions) firstByte() (time.Duration, bool) {
+	switch {
+	case o.connectTimeout.Valid && o.firstByteTimeout.Valid:
+		return time.Duration(min(o.connectTimeout.Value, o.firstByteTimeout.Value)), true
+	case o.connectTimeout.Valid:
+		return time.Duration(o.connectTimeout.Value), true
+	case o.firstByteTimeout.Valid:
+		return time.Duration(o.firstByteTimeout.Value), true
+	}
+	return 0, false
+}
+
+// betweenBytes returns the between bytes timeout, or zero if not set.
+func (o *requestOptions) betweenBytes() time.Duration {
+	if o.betweenBytesTimeout.Valid {
+		return time.Duration(o.betweenBytesTimeout.Value)
+	}
+	return 0
+}
+
+// responseBody is the body of a response, whose reads are canceled by the between bytes timeout, and whose close
+// ends its round trip.
+type responseBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	// timeout is the between bytes timeout, or zero if none.
+	timeout time.Duration
+}
+
+// Read implements io.Reader
+func (b *responseBody) Read(p []byte) (int, error) {
+	if b.timeout > 0 {
+		t := time.AfterFunc(b.timeout, func() { b.cancel(errBetweenBytesTimeout) })
+		defer t.Stop()
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		if cause := context.Cause(b.ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+			err = cause
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (b *responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
+
+// futureIncomingResponse is the representation of the resource future-incoming-response.
+type futureIncomingResponse struct {
+	resp   *future[*http.Response]
+	cancel context.CancelCauseFunc
+	// got is true once the response is returned.
+	got bool
+}
+
+// Close implements api.Closer, which cancels the round trip, unless its response was returned.
+func (f *futureIncomingResponse) Close(context.Context) error {
+	if f.got {
+		return nil
+	}
+	f.cancel(nil)
+	go func() {
+		<-f.resp.done
+		if f.resp.value != nil {
+			_ = f.resp.value.Body.Close()
+		}
+	}()
+	return nil
+}
+
+func futureIncomingResponseSubscribe(_ context.Context, mod api.Module, self cm.Borrow[*futureIncomingResponse]) cm.Own[*pollable] {
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: sysCtx(mod), waiter: self.Rep.resp}}
+}
+
+// responseResult is the result<result<incoming-response, error-code>> of future-incoming-response.get.
+type responseResult = cm.Result[cm.Result[cm.Own[*incomingResponse], httpErrorCode], struct{}]
+
+func futureIncomingResponseGet(self cm.Borrow[*futureIncomingResponse]) cm.Option[responseResult] {
+	f := self.Rep
+	switch {
+	case !f.resp.ready():
+		return cm.None[responseResult]()
+	case f.got:
+		return cm.Some(cm.Err[cm.Result[cm.Own[*incomingResponse], httpErrorCode]](struct{}{}))
+	}
+	f.got = true
+	var res cm.Result[cm.Own[*incomingResponse], httpErrorCode]
+	if f.resp.err != nil {
+		res = cm.Err[cm.Own[*incomingResponse]](httpErrorCodeOf(f.resp.err))
+	} else {
+		res = cm.Ok[cm.Own[*incomingResponse], httpErrorCode](cm.Own[*incomingResponse]{Rep: &incomingResponse{resp: f.resp.value}})
+	}
+	return cm.Some(cm.Ok[cm.Result[cm.Own[*incomingResponse], httpErrorCode], struct{}](res))
+}
+
+var (
+	// errBodyClosed is the error of the writes of a body closed by its round tripper.
+	errBodyClosed = errors.New("outgoing body closed")
+	// errBodyDropped is the error of a body dropped without being finished.
+	errBodyDropped = errors.New("outgoing body dropped without being finished")
+	// errBodySize is the error of a body whose size isn't its declared Content-Length.
+	errBodySize = errors.New("outgoing body size doesn't match its Content-Length")
+)
+
+// outgoingBody is the representation of the resource outgoing-body, which is the body of a request read by its round
+// tripper as its bytes are written to its output stream.
+type outgoingBody struct {
+	// contentLength is the Content-Length of the headers, or -1 if unknown.
+	contentLength int64
+	// trailer is the trailer of the request, which is set by the read of the end of the body, as the round tripper
+	// reads it concurrently until then.
+	trailer http.Header
+	// finished is the trailer of the finished body.
+	finished http.Header
+
+	mu  sync.Mutex
+	buf []byte
+	// reading is true once the round tripper reads the body, from when the buffer is bounded.
+	reading bool
+	written uint64
+	// err ends the reads, which is io.EOF once finished, and the writes.
+	err error
+	// streamed is true once the output stream is returned.
+	streamed bool
+
+	// readable is signaled when bytes are written, or the error is set.
+	readable chan struct{}
+	// writable is signaled when bytes are read, or the error is set.
+	writable chan struct{}
+}
+
+func newOutgoingBody(headers *fields) *outgoingBody {
+	b := &outgoingBody{
+		contentLength: -1,
+		trailer:       http.Header{},
+		readable:      make(chan struct{}, 1),
+		writable:      make(chan struct{}, 1),
+	}
+	if v := headers.get("content-length"); len(v) == 1 {
+		if n, err := strconv.ParseInt(string(v[0]), 10, 64); err == nil && n >= 0 {
+			b.contentLength = n
+		}
+	}
+	return b
+}
+
+// end ends the reads and the writes with the error, unless already ended.
+func (b *outgoingBody) end(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err == nil {
+		b.err = err
+	}
+	signal(b.readable)
+	signal(b.writable)
+}
+
+// read blocks until bytes are written, or the body ends.
+func (b *outgoingBody) read(p []byte) (int, error) {
+	b.mu.Lock()
+	b.reading = true
+	for len(b.buf) == 0 && b.err == nil {
+		b.mu.Unlock()
+		<-b.readable
+		b.mu.Lock()
+	}
+	defer b.mu.Unlock()
+	if n := copy(p, b.buf); n > 0 {
+		b.buf = b.buf[n:]
+		signal(b.writable)
+		return n, nil
+	}
+	if b.err == io.EOF {
+		for k, v := range b.finished {
+			b.trailer[k] = v
+		}
+	}
+	return 0, b.err
+}
+
+// Close implements api.Closer, which fails the request of a body dropped without being finished.
+func (b *outgoingBody) Close(context.Context) error {
+	b.end(errBodyDropped)
+	return nil
+}
+
+// write writes the bytes, which blocks while the round tripper reads and the buffer is full.
+func (b *outgoingBody) write(p []byte) (int, experimentalsys.Errno) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.err == nil && b.reading && len(b.buf) >= maxBufferSize {
+		b.mu.Unlock()
+		<-b.writable
+		b.mu.Lock()
+	}
+	switch {
+	case b.err != nil:
+		return 0, experimentalsys.EIO
+	case b.contentLength >= 0 && b.written+uint64(len(p)) > uint64(b.contentLength):
+		b.err = errBodySize
+		signal(b.readable)
+		return 0, experimentalsys.EIO
+	}
+	b.buf = append(b.buf, p...)
+	b.written += uint64(len(p))
+	signal(b.readable)
+	return len(p), 0
+}
+
+// writableNow returns true if a write doesn't block.
+func (b *outgoingBody) writableNow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err != nil || !b.reading || len(b.buf) < maxBufferSize
+}
+
+func outgoingBodyWrite(_ context.Context, mod api.Module, self cm.Borrow[*outgoingBody]) cm.Result[cm.Own[*outputStream], struct{}] {
+	b := self.Rep
+	if b.streamed {
+		return cm.Err[cm.Own[*outputStream]](struct{}{})
+	}
+	b.streamed = true
+	return cm.Ok[cm.Own[*outputStream], struct{}](cm.Own[*outputStream]{Rep: &outputStream{sysCtx: sysCtx(mod), file: &outgoingBodyFile{b: b}}})
+}
+
+// outgoingBodyFinish ends the body with the trailers, unless its size isn't its declared Content-Length.
+func outgoingBodyFinish(this cm.Own[*outgoingBody], trailers cm.Option[cm.Own[*fields]]) cm.Result[struct{}, httpErrorCode] {
+	b := this.Rep
+	b.mu.Lock()
+	written, contentLength := b.written, b.contentLength
+	b.mu.Unlock()
+	if contentLength >= 0 && written != uint64(contentLength) {
+		b.end(errBodySize)
+		return cm.Err[struct{}](httpErrorCode{HTTPRequestBodySize: &cm.Option[uint64]{Value: written, Valid: true}})
+	}
+	if trailers.Valid {
+		b.mu.Lock()
+		b.finished = trailers.Value.Rep.header()
+		b.mu.Unlock()
+	}
+	b.end(io.EOF)
+	return cm.Ok[struct{}, httpErrorCode](struct{}{})
+}
+
+// outgoingBodyReader is the body of the request read by the round tripper.
+type outgoingBodyReader struct {
+	b *outgoingBody
+}
+
+// Read implements io.Reader
+func (r outgoingBodyReader) Read(p []byte) (int, error) {
+	return r.b.read(p)
+}
+
+// Close implements io.Closer, so that the writes fail.
+func (r outgoingBodyReader) Close() error {
+	r.b.end(errBodyClosed)
+	return nil
+}
+
+// outgoingBodyFile is the file of the output stream of an outgoing body.
+type outgoingBodyFile struct {
+	experimentalsys.UnimplementedFile
+	b *outgoingBody
+}
+
+// cause implements causer
+func (f *outgoingBodyFile) cause() error {
+	f.b.mu.Lock()
+	defer f.b.mu.Unlock()
+	return f.b.err
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (f *outgoingBodyFile) IsNonblock() bool {
+	return false
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (f *outgoingBodyFile) SetNonblock(bool) experimentalsys.Errno {
+	return experimentalsys.ENOSYS
+}
+
+// Poll implements the same method as documented on fsapi.File, which is never ready for reads.
+func (f *outgoingBodyFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != fsapi.POLLOUT {
+		return false, experimentalsys.ENOTSUP
+	}
+	if f.b.writableNow() || timeoutMillis == 0 {
+		return f.b.writableNow(), 0
+	}
+	var timeout <-chan time.Time
+	if timeoutMillis > 0 {
+		t := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-f.b.writable:
+		// Leave the signal to the blocked write, if any.
+		signal(f.b.writable)
+	case <-timeout:
+	}
+	return f.b.writableNow(), 0
+}
+
+// Write implements the same method as documented on experimentalsys.File
+func (f *outgoingBodyFile) Write(buf []byte) (n int, errno experimentalsys.Errno) {
+	return f.b.write(buf)
+}
@@ -0,0 +1,344 @@
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// roundTripperFunc is a http.RoundTripper of a function, e.g. a mock.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newRequest returns a request of the method to the URL of the server, whose scheme is http.
+func newRequest(t *testing.T, m method, u string, headers *fields) *outgoingRequest {
+	t.Helper()
+	r := newOutgoingRequest(cm.Own[*fields]{Rep: headers}).Rep
+	b := cm.Borrow[*outgoingRequest]{Rep: r}
+	require.False(t, outgoingRequestSetMethod(b, m).IsErr)
+	require.False(t, outgoingRequestSetScheme(b, cm.Some(schemeOf("http"))).IsErr)
+	require.False(t, outgoingRequestSetAuthority(b, cm.Some(strings.TrimPrefix(u, "http://"))).IsErr)
+	return r
+}
+
+// requireResponse requires the round trip to succeed, and returns its response.
+func requireResponse(t *testing.T, mod api.Module, res cm.Result[cm.Own[*futureIncomingResponse], httpErrorCode]) *incomingResponse {
+	t.Helper()
+	resp := requireHTTPOk(t, requireRoundTrip(t, mod, res))
+	return resp.Rep
+}
+
+// requireRoundTrip waits for the end of the round trip, and returns its result.
+func requireRoundTrip(t *testing.T, mod api.Module, res cm.Result[cm.Own[*futureIncomingResponse], httpErrorCode]) cm.Result[cm.Own[*incomingResponse], httpErrorCode] {
+	t.Helper()
+	f := borrow(requireHTTPOk(t, res))
+	pollableBlock(testCtx, borrow(futureIncomingResponseSubscribe(testCtx, mod, f)))
+	got := futureIncomingResponseGet(f)
+	require.True(t, got.Valid)
+	require.False(t, got.Value.IsErr)
+	// The response is only returned once.
+	require.True(t, futureIncomingResponseGet(f).Value.IsErr)
+	return got.Value.Value
+}
+
+// requireHTTPOk requires the result not to be an error.
+func requireHTTPOk[T any](t *testing.T, res cm.Result[T, httpErrorCode]) T {
+	t.Helper()
+	require.False(t, res.IsErr, "error-code %#v", res.Err)
+	return res.Value
+}
+
+// requireUnitOk requires the result, whose error has no payload, not to be an error.
+func requireUnitOk[T any](t *testing.T, res cm.Result[T, struct{}]) T {
+	t.Helper()
+	require.False(t, res.IsErr)
+	return res.Value
+}
+
+// requireHTTPErrorCode requires the result to be the error.
+func requireHTTPErrorCode[T any](t *testing.T, expected httpErrorCode, res cm.Result[T, httpErrorCode]) {
+	t.Helper()
+	require.True(t, res.IsErr)
+	require.Equal(t, expected, res.Err)
+}
+
+// readAll reads the stream until its end.
+func readAll(t *testing.T, in cm.Borrow[*inputStream]) (string, *streamError) {
+	t.Helper()
+	var sb strings.Builder
+	for {
+		res := inputStreamBlockingRead(in, maxBufferSize)
+		if res.IsErr {
+			if res.Err.Closed != nil {
+				return sb.String(), nil
+			}
+			return sb.String(), &res.Err
+		}
+		sb.Write(res.Value)
+	}
+}
+
+func Test_fields(t *testing.T) {
+	f := cm.Borrow[*fields]{Rep: newFields().Rep}
+	require.False(t, fieldsSet(f, "Content-Type", [][]byte{[]byte("text/plain")}).IsErr)
+	require.False(t, fieldsAppend(f, "x-a", []byte("1")).IsErr)
+	require.False(t, fieldsAppend(f, "X-A", []byte("2")).IsErr)
+
+	// Names are case-insensitive.
+	require.True(t, fieldsHas(f, "content-type"))
+	require.Equal(t, [][]byte{[]byte("1"), []byte("2")}, fieldsGet(f, "x-A"))
+	require.Equal(t, [][]byte{}, fieldsGet(f, "x-b"))
+
+	require.Equal(t, headerError{Forbidden: &struct{}{}}, fieldsSet(f, "Connection", [][]byte{[]byte("close")}).Err)
+	require.Equal(t, headerError{Forbidden: &struct{}{}}, fieldsAppend(f, "host", []byte("example.com")).Err)
+	require.Equal(t, headerError{InvalidSyntax: &struct{}{}}, fieldsAppend(f, "x a", []byte("1")).Err)
+	require.Equal(t, headerError{InvalidSyntax: &struct{}{}}, fieldsAppend(f, "x-a", []byte("1\r\nx-b: 2")).Err)
+
+	require.False(t, fieldsDelete(f, "X-a").IsErr)
+	require.Equal(t, []fieldEntry{{Key: "Content-Type", Value: []byte("text/plain")}}, fieldsEntries(f))
+
+	t.Run("from-list", func(t *testing.T) {
+		res := fieldsFromList([]fieldEntry{{Key: "x-a", Value: []byte("1")}})
+		require.False(t, res.IsErr)
+		require.Equal(t, headerError{Forbidden: &struct{}{}}, fieldsFromList([]fieldEntry{{Key: "upgrade"}}).Err)
+	})
+
+	t.Run("immutable", func(t *testing.T) {
+		r := cm.Borrow[*outgoingRequest]{Rep: newOutgoingRequest(fieldsClone(f)).Rep}
+		headers := cm.Borrow[*fields]{Rep: outgoingRequestHeaders(r).Rep}
+		require.Equal(t, headerError{Immutable: &struct{}{}}, fieldsAppend(headers, "x-a", []byte("1")).Err)
+		require.Equal(t, headerError{Immutable: &struct{}{}}, fieldsDelete(headers, "content-type").Err)
+
+		// A clone is mutable.
+		require.False(t, fieldsAppend(cm.Borrow[*fields]{Rep: fieldsClone(headers).Rep}, "x-a", []byte("1")).IsErr)
+	})
+
+	t.Run("fieldsOf", func(t *testing.T) {
+		h := fieldsOf(http.Header{"X-B": {"2"}, "X-A": {"1", "3"}})
+		require.True(t, h.immutable)
+		require.Equal(t, []fieldEntry{
+			{Key: "x-a", Value: []byte("1")},
+			{Key: "x-a", Value: []byte("3")},
+			{Key: "x-b", Value: []byte("2")},
+		}, h.entries)
+	})
+}
+
+func Test_outgoingRequest(t *testing.T) {
+	r := cm.Borrow[*outgoingRequest]{Rep: newOutgoingRequest(newFields()).Rep}
+	require.Equal(t, "GET", outgoingRequestMethod(r).String())
+	require.False(t, outgoingRequestScheme(r).Valid)
+
+	require.False(t, outgoingRequestSetMethod(r, methodOf("PURGE")).IsErr)
+	require.Equal(t, "PURGE", outgoingRequestMethod(r).String())
+	require.True(t, outgoingRequestSetMethod(r, methodOf("PUR GE")).IsErr)
+
+	require.False(t, outgoingRequestSetPathWithQuery(r, cm.Some("/a?b=c")).IsErr)
+	require.Equal(t, "/a?b=c", outgoingRequestPathWithQuery(r).Value)
+	require.True(t, outgoingRequestSetPathWithQuery(r, cm.Some("/a b")).IsErr)
+
+	require.True(t, outgoingRequestSetAuthority(r, cm.Some("")).IsErr)
+	require.True(t, outgoingRequestSetAuthority(r, cm.Some("example.com/a")).IsErr)
+	require.True(t, outgoingRequestSetScheme(r, cm.Some(schemeOf("1http"))).IsErr)
+
+	// The body is only returned once.
+	require.False(t, outgoingRequestBody(r).IsErr)
+	require.True(t, outgoingRequestBody(r).IsErr)
+}
+
+func Test_handle(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Path", r.URL.RequestURI())
+		w.Header().Set("X-Echo", r.Header.Get("X-Echo"))
+		w.Header().Set("X-Trailer", r.Trailer.Get("X-Request-Checksum"))
+		_, _ = w.Write(body)
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer srv.Close()
+
+	headers := newFields().Rep
+	require.False(t, fieldsSet(cm.Borrow[*fields]{Rep: headers}, "x-echo", [][]byte{[]byte("hello")}).IsErr)
+	r := newRequest(t, methodOf("POST"), srv.URL, headers)
+	require.False(t, outgoingRequestSetPathWithQuery(cm.Borrow[*outgoingRequest]{Rep: r}, cm.Some("/a?b=c")).IsErr)
+	body := cm.Borrow[*outgoingBody]{Rep: requireUnitOk(t, outgoingRequestBody(cm.Borrow[*outgoingRequest]{Rep: r})).Rep}
+	out := cm.Borrow[*outputStream]{Rep: requireUnitOk(t, outgoingBodyWrite(testCtx, mod, body)).Rep}
+
+	// The body is streamed while the request is sent.
+	future := handle(http.DefaultTransport, r, &requestOptions{})
+	require.False(t, outputStreamWrite(out, []byte("hello ")).IsErr)
+	require.False(t, outputStreamWrite(out, []byte("world")).IsErr)
+	require.True(t, pollableReady(borrow(outputStreamSubscribe(out))))
+	trailers := cm.Borrow[*fields]{Rep: newFields().Rep}
+	require.False(t, fieldsSet(trailers, "x-request-checksum", [][]byte{[]byte("def")}).IsErr)
+	requireHTTPOk(t, outgoingBodyFinish(cm.Own[*outgoingBody]{Rep: body.Rep}, cm.Some(cm.Own[*fields]{Rep: trailers.Rep})))
+
+	resp := cm.Borrow[*incomingResponse]{Rep: requireResponse(t, mod, future)}
+	require.Equal(t, uint16(200), incomingResponseStatus(resp))
+	h := cm.Borrow[*fields]{Rep: incomingResponseHeaders(resp).Rep}
+	require.Equal(t, [][]byte{[]byte("POST")}, fieldsGet(h, "x-method"))
+	require.Equal(t, [][]byte{[]byte("/a?b=c")}, fieldsGet(h, "x-path"))
+	require.Equal(t, [][]byte{[]byte("hello")}, fieldsGet(h, "x-echo"))
+	require.Equal(t, [][]byte{[]byte("def")}, fieldsGet(h, "x-trailer"))
+
+	b := cm.Borrow[*incomingBody]{Rep: requireUnitOk(t, incomingResponseConsume(resp)).Rep}
+	require.True(t, incomingResponseConsume(resp).IsErr)
+	in := borrow(requireUnitOk(t, incomingBodyStream(testCtx, mod, b)))
+	read, serr := readAll(t, in)
+	require.Nil(t, serr)
+	require.Equal(t, "hello world", read)
+
+	ft := borrow(incomingBodyFinish(cm.Own[*incomingBody]{Rep: b.Rep}))
+	pollableBlock(testCtx, borrow(futureTrailersSubscribe(testCtx, mod, ft)))
+	got := futureTrailersGet(ft)
+	require.True(t, got.Valid)
+	tr := requireHTTPOk(t, got.Value.Value)
+	require.True(t, tr.Valid)
+	require.Equal(t, [][]byte{[]byte("abc")}, fieldsGet(cm.Borrow[*fields]{Rep: tr.Value.Rep}, "x-checksum"))
+	require.True(t, futureTrailersGet(ft).Value.IsErr)
+}
+
+func Test_handle_RoundTripper(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var requests []string
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.String())
+		if req.URL.Host == "unknown.invalid" {
+			return nil, &net.DNSError{Err: "no such host", Name: req.URL.Host, IsNotFound: true}
+		}
+		return &http.Response{StatusCode: 204, Header: http.Header{}, Body: http.NoBody}, nil
+	})
+
+	resp := requireResponse(t, mod, handle(rt, newRequest(t, methodOf("GET"), "example.com", newFields().Rep), &requestOptions{}))
+	require.Equal(t, uint16(204), incomingResponseStatus(cm.Borrow[*incomingResponse]{Rep: resp}))
+	require.NoError(t, resp.Close(testCtx))
+
+	res := requireRoundTrip(t, mod, handle(rt, newRequest(t, methodOf("DELETE"), "unknown.invalid", newFields().Rep), &requestOptions{}))
+	requireHTTPErrorCode(t, httpErrorCode{DNSError: &dnsErrorPayload{Rcode: cm.Some("no such host")}}, res)
+
+	require.Equal(t, []string{"GET http://example.com/", "DELETE http://unknown.invalid/"}, requests)
+
+	t.Run("context", func(t *testing.T) {
+		fn := httpOutgoingHandler(nil).funcs[0].fn.(func(context.Context, cm.Own[*outgoingRequest], cm.Option[cm.Own[*requestOptions]]) cm.Result[cm.Own[*futureIncomingResponse], httpErrorCode])
+		request := cm.Own[*outgoingRequest]{Rep: newRequest(t, methodOf("GET"), "example.com", newFields().Rep)}
+		requireHTTPErrorCode(t, httpErrorCode{HTTPRequestDenied: &struct{}{}}, fn(testCtx, request, cm.None[cm.Own[*requestOptions]]()))
+
+		requireResponse(t, mod, fn(WithRoundTripper(testCtx, rt), request, cm.None[cm.Own[*requestOptions]]()))
+	})
+}
+
+func Test_handle_Errors(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Length", "10")
+		_, _ = w.Write([]byte("short"))
+	}))
+	defer srv.Close()
+
+	t.Run("denied", func(t *testing.T) {
+		r := newRequest(t, methodOf("GET"), srv.URL, newFields().Rep)
+		requireHTTPErrorCode(t, httpErrorCode{HTTPRequestDenied: &struct{}{}}, handle(nil, r, &requestOptions{}))
+	})
+
+	t.Run("uri invalid", func(t *testing.T) {
+		r := newOutgoingRequest(newFields()).Rep
+		requireHTTPErrorCode(t, httpErrorCode{HTTPRequestURIInvalid: &struct{}{}}, handle(http.DefaultTransport, r, &requestOptions{}))
+	})
+
+	t.Run("first byte timeout", func(t *testing.T) {
+		r := newRequest(t, methodOf("GET"), srv.URL, newFields().Rep)
+		require.False(t, outgoingRequestSetPathWithQuery(cm.Borrow[*outgoingRequest]{Rep: r}, cm.Some("/slow")).IsErr)
+		o := cm.Borrow[*requestOptions]{Rep: newRequestOptions().Rep}
+		require.False(t, requestOptionsSetFirstByteTimeout(o, cm.Some(uint64(10*time.Millisecond))).IsErr)
+		require.Equal(t, uint64(10*time.Millisecond), requestOptionsFirstByteTimeout(o).Value)
+
+		res := requireRoundTrip(t, mod, handle(http.DefaultTransport, r, o.Rep))
+		requireHTTPErrorCode(t, httpErrorCode{HTTPResponseTimeout: &struct{}{}}, res)
+	})
+
+	t.Run("response incomplete", func(t *testing.T) {
+		r := newRequest(t, methodOf("GET"), srv.URL, newFields().Rep)
+		resp := cm.Borrow[*incomingResponse]{Rep: requireResponse(t, mod, handle(http.DefaultTransport, r, &requestOptions{}))}
+		b := cm.Borrow[*incomingBody]{Rep: requireUnitOk(t, incomingResponseConsume(resp)).Rep}
+		read, serr := readAll(t, borrow(requireUnitOk(t, incomingBodyStream(testCtx, mod, b))))
+		require.Equal(t, "short", read)
+		require.NotNil(t, serr)
+		code := httpErrorCodeFunc(cm.Borrow[*ioErr]{Rep: serr.LastOperationFailed.Rep})
+		require.Equal(t, cm.Some(httpErrorCode{HTTPResponseIncomplete: &struct{}{}}), code)
+		require.NoError(t, b.Rep.Close(testCtx))
+	})
+
+	t.Run("body size", func(t *testing.T) {
+		headers := newFields().Rep
+		require.False(t, fieldsSet(cm.Borrow[*fields]{Rep: headers}, "content-length", [][]byte{[]byte("3")}).IsErr)
+		r := newRequest(t, methodOf("PUT"), srv.URL, headers)
+		body := cm.Borrow[*outgoingBody]{Rep: requireUnitOk(t, outgoingRequestBody(cm.Borrow[*outgoingRequest]{Rep: r})).Rep}
+		out := cm.Borrow[*outputStream]{Rep: requireUnitOk(t, outgoingBodyWrite(testCtx, mod, body)).Rep}
+		require.True(t, outgoingBodyWrite(testCtx, mod, body).IsErr)
+
+		require.False(t, outputStreamWrite(out, []byte("ab")).IsErr)
+		res := outgoingBodyFinish(cm.Own[*outgoingBody]{Rep: body.Rep}, cm.None[cm.Own[*fields]]())
+		requireHTTPErrorCode(t, httpErrorCode{HTTPRequestBodySize: &cm.Option[uint64]{Value: 2, Valid: true}}, res)
+
+		// Writes past the Content-Length fail.
+		body = cm.Borrow[*outgoingBody]{Rep: newOutgoingBody(headers)}
+		out = cm.Borrow[*outputStream]{Rep: requireUnitOk(t, outgoingBodyWrite(testCtx, mod, body)).Rep}
+		werr := outputStreamWrite(out, []byte("abcd"))
+		require.True(t, werr.IsErr)
+		require.Equal(t, errBodySize, werr.Err.LastOperationFailed.Rep.err)
+	})
+}
+
+func Test_httpErrorCodeOf(t *testing.T) {
+	some := &struct{}{}
+	tests := []struct {
+		name     string
+		err      error
+		expected httpErrorCode
+	}{
+		{name: "first byte timeout", err: errFirstByteTimeout, expected: httpErrorCode{HTTPResponseTimeout: some}},
+		{name: "between bytes timeout", err: errBetweenBytesTimeout, expected: httpErrorCode{ConnectionReadTimeout: some}},
+		{name: "DNS timeout", err: &net.DNSError{IsTimeout: true}, expected: httpErrorCode{DNSTimeout: some}},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: httpErrorCode{ConnectionRefused: some}},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, expected: httpErrorCode{HTTPResponseIncomplete: some}},
+		{name: "other", err: errors.New("other"), expected: httpErrorCode{InternalError: &cm.Option[string]{Value: "other", Valid: true}}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, httpErrorCodeOf(tc.err))
+		})
+	}
+
+	// The errors of the streams which aren't of HTTP have no error-code.
+	require.False(t, httpErrorCodeFunc(cm.Borrow[*ioErr]{Rep: &ioErr{err: experimentalsys.EIO}}).Valid)
+}
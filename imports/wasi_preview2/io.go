@@ -0,0 +1,622 @@
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"syscall"
+	"time"
+
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+)
+
+// maxBufferSize is the maximum size of a read, and the size of a write permitted by check-write.
+const maxBufferSize = 64 * 1024
+
+// pollInterval is the time a poll of several pollables blocks on the first file at most, before polling the others
+// again.
+const pollInterval = 10 * time.Millisecond
+
+// ioError is the interface wasi:io/error.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/io/error.wit
+var ioError = newInterface("wasi:io/error",
+	hostFunc{"[method]error.to-debug-string", errorToDebugString},
+)
+
+// ioErr is the representation of the resource error, which is the error of a failed operation.
+type ioErr struct {
+	err error
+}
+
+func errorToDebugString(self cm.Borrow[*ioErr]) string {
+	return self.Rep.err.Error()
+}
+
+// ioPoll is the interface wasi:io/poll.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/io/poll.wit
+var ioPoll = newInterface("wasi:io/poll",
+	hostFunc{"[method]pollable.ready", pollableReady},
+	hostFunc{"[method]pollable.block", pollableBlock},
+	hostFunc{"poll", poll},
+)
+
+// pollable is the representation of the resource pollable, which is ready when its file is ready for its flag, when
+// its waiter is, or else at its deadline of the monotonic clock.
+type pollable struct {
+	sysCtx *internalsys.Context
+	// file is the file polled, or nil if the pollable is the one of a waiter or a clock.
+	file fsapi.File
+	flag fsapi.Pflag
+	// waiter is the operation waited, or nil if the pollable is the one of a file or a clock.
+	waiter waiter
+	// deadline is the nanotime at which the pollable of a clock is ready.
+	deadline int64
+}
+
+// ready returns true if the pollable is ready, without blocking.
+func (p *pollable) ready() bool {
+	switch {
+	case p.file != nil:
+		ready, errno := p.file.Poll(p.flag, 0)
+		// The files which can't be polled are ready, as their operations report their errors.
+		return ready || errno != 0
+	case p.waiter != nil:
+		return p.waiter.ready()
+	}
+	return p.sysCtx.Nanotime() >= p.deadline
+}
+
+// waiter is an operation in progress in a goroutine, e.g. the connection of a socket, which a pollable waits.
+type waiter interface {
+	// ready returns true if the operation is ready, without blocking.
+	ready() bool
+	// wait blocks until the operation is ready, or the timeout elapses.
+	wait(timeout time.Duration)
+}
+
+// future is a waiter of a function called in a goroutine, which is ready when it returns.
+type future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// goFuture calls fn in a goroutine, and returns the future of its result.
+func goFuture[T any](fn func() (T, error)) *future[T] {
+	f := &future[T]{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.value, f.err = fn()
+	}()
+	return f
+}
+
+func (f *future[T]) ready() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *future[T]) wait(timeout time.Duration) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-f.done:
+	case <-t.C:
+	}
+}
+
+// queue is a waiter of the values produced by a goroutine, e.g. the connections accepted by a listener, which is
+// ready when a value is queued, or the goroutine ended with an error.
+type queue[T any] struct {
+	mu     sync.Mutex
+	values []T
+	// limit is the maximum number of values queued.
+	limit int
+	err   error
+	// notify is signaled when a value is queued, or the error is set.
+	notify chan struct{}
+}
+
+func newQueue[T any](limit int) *queue[T] {
+	return &queue[T]{limit: limit, notify: make(chan struct{}, 1)}
+}
+
+// push queues the value, and returns false if the queue is full.
+func (q *queue[T]) push(v T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.values) >= q.limit {
+		return false
+	}
+	q.values = append(q.values, v)
+	q.signal()
+	return true
+}
+
+// end sets the error which ended the goroutine.
+func (q *queue[T]) end(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.err = err
+	q.signal()
+}
+
+func (q *queue[T]) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns up to n values without blocking, or the error which ended the goroutine once they are all popped.
+func (q *queue[T]) pop(n int) ([]T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.values) == 0 {
+		return nil, q.err
+	}
+	n = min(n, len(q.values))
+	ret := q.values[:n:n]
+	q.values = q.values[n:]
+	return ret, nil
+}
+
+func (q *queue[T]) ready() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.values) > 0 || q.err != nil
+}
+
+func (q *queue[T]) wait(timeout time.Duration) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-q.notify:
+	case <-t.C:
+	}
+}
+
+func pollableReady(self cm.Borrow[*pollable]) bool {
+	return self.Rep.ready()
+}
+
+func pollableBlock(ctx context.Context, self cm.Borrow[*pollable]) {
+	pollPollables(ctx, []*pollable{self.Rep})
+}
+
+func poll(ctx context.Context, in []cm.Borrow[*pollable]) []uint32 {
+	if len(in) == 0 {
+		panic(errors.New("poll: no pollables"))
+	}
+	ps := make([]*pollable, len(in))
+	for i, p := range in {
+		ps[i] = p.Rep
+	}
+	return pollPollables(ctx, ps)
+}
+
+// pollPollables blocks until at least one of the pollables is ready, and returns the indexes of those which are.
+func pollPollables(ctx context.Context, ps []*pollable) []uint32 {
+	sysCtx := ps[0].sysCtx
+	for {
+		var ready []uint32
+		for i, p := range ps {
+			if p.ready() {
+				ready = append(ready, uint32(i))
+			}
+		}
+		if len(ready) > 0 {
+			return ready
+		} else if err := ctx.Err(); err != nil {
+			panic(err)
+		}
+
+		// Block until the earliest deadline, or on the first file or waiter for at most pollInterval.
+		var first *pollable
+		wait := int64(math.MaxInt64)
+		now := sysCtx.Nanotime()
+		for _, p := range ps {
+			if p.file == nil && p.waiter == nil {
+				wait = min(wait, p.deadline-now)
+			} else if wait = min(wait, int64(pollInterval)); first == nil {
+				first = p
+			}
+		}
+		switch {
+		case first == nil:
+			sysCtx.Nanosleep(wait)
+		case first.file != nil:
+			_, _ = first.file.Poll(first.flag, int32((wait+int64(time.Millisecond)-1)/int64(time.Millisecond)))
+		default:
+			first.waiter.wait(time.Duration(wait))
+		}
+	}
+}
+
+// ioStreams is the interface wasi:io/streams.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/io/streams.wit
+var ioStreams = newInterface("wasi:io/streams",
+	hostFunc{"[method]input-stream.read", inputStreamRead},
+	hostFunc{"[method]input-stream.blocking-read", inputStreamBlockingRead},
+	hostFunc{"[method]input-stream.skip", inputStreamSkip},
+	hostFunc{"[method]input-stream.blocking-skip", inputStreamBlockingSkip},
+	hostFunc{"[method]input-stream.subscribe", inputStreamSubscribe},
+	hostFunc{"[method]output-stream.check-write", outputStreamCheckWrite},
+	hostFunc{"[method]output-stream.write", outputStreamWrite},
+	hostFunc{"[method]output-stream.blocking-write-and-flush", outputStreamWrite},
+	hostFunc{"[method]output-stream.flush", outputStreamFlush},
+	hostFunc{"[method]output-stream.blocking-flush", outputStreamFlush},
+	hostFunc{"[method]output-stream.subscribe", outputStreamSubscribe},
+	hostFunc{"[method]output-stream.write-zeroes", outputStreamWriteZeroes},
+	hostFunc{"[method]output-stream.blocking-write-zeroes-and-flush", outputStreamWriteZeroes},
+	hostFunc{"[method]output-stream.splice", outputStreamSplice},
+	hostFunc{"[method]output-stream.blocking-splice", outputStreamBlockingSplice},
+)
+
+// streamError is the variant stream-error.
+type streamError struct {
+	cm.Variant
+	LastOperationFailed *cm.Own[*ioErr]
+	Closed              *struct{}
+}
+
+// streamErrorOf returns the stream-error of the failure of an operation with the error, which is usually an errno.
+func streamErrorOf(err error) streamError {
+	return streamError{LastOperationFailed: &cm.Own[*ioErr]{Rep: &ioErr{err: err}}}
+}
+
+// causer is a file whose failed operations have a cause more precise than their errno, e.g. a timeout of an HTTP
+// body.
+type causer interface {
+	// cause returns the cause of the failed operations, or nil if unknown.
+	cause() error
+}
+
+// fileStreamErrorOf returns the stream-error of the failure of an operation of the file with the errno, whose error
+// is the cause of the failure if known.
+func fileStreamErrorOf(file fsapi.File, errno experimentalsys.Errno) streamError {
+	if c, ok := file.(causer); ok {
+		if err := c.cause(); err != nil {
+			return streamErrorOf(err)
+		}
+	}
+	return streamErrorOf(errno)
+}
+
+// inputStream is the representation of the resource input-stream, which reads a file.
+type inputStream struct {
+	sysCtx *internalsys.Context
+	file   fsapi.File
+	// positional is true when the file is read with Pread at offset, e.g. for a descriptor, instead of Read.
+	positional bool
+	offset     int64
+	// closed is true when the end of the file was read.
+	closed bool
+}
+
+// read reads up to n bytes, or returns an empty list if none is available, unless blocking.
+func (s *inputStream) read(n uint64, blocking bool) cm.Result[[]byte, streamError] {
+	if s.closed {
+		return cm.Err[[]byte](streamError{Closed: &struct{}{}})
+	} else if n == 0 {
+		return cm.Ok[[]byte, streamError]([]byte{})
+	}
+	if !blocking {
+		if ready, errno := s.file.Poll(fsapi.POLLIN, 0); errno == 0 && !ready {
+			return cm.Ok[[]byte, streamError]([]byte{})
+		}
+	}
+	buf := make([]byte, min(n, maxBufferSize))
+	var m int
+	var errno experimentalsys.Errno
+	if s.positional {
+		m, errno = s.file.Pread(buf, s.offset)
+		s.offset += int64(m)
+	} else {
+		m, errno = s.file.Read(buf)
+	}
+	switch {
+	case errno == experimentalsys.EAGAIN:
+		return cm.Ok[[]byte, streamError]([]byte{})
+	case errno != 0:
+		return cm.Err[[]byte](fileStreamErrorOf(s.file, errno))
+	case m == 0:
+		s.closed = true
+		return cm.Err[[]byte](streamError{Closed: &struct{}{}})
+	}
+	return cm.Ok[[]byte, streamError](buf[:m])
+}
+
+func inputStreamRead(self cm.Borrow[*inputStream], n uint64) cm.Result[[]byte, streamError] {
+	return self.Rep.read(n, false)
+}
+
+func inputStreamBlockingRead(self cm.Borrow[*inputStream], n uint64) cm.Result[[]byte, streamError] {
+	return self.Rep.read(n, true)
+}
+
+func inputStreamSkip(self cm.Borrow[*inputStream], n uint64) cm.Result[uint64, streamError] {
+	return skipped(self.Rep.read(n, false))
+}
+
+func inputStreamBlockingSkip(self cm.Borrow[*inputStream], n uint64) cm.Result[uint64, streamError] {
+	return skipped(self.Rep.read(n, true))
+}
+
+// skipped returns the number of bytes read.
+func skipped(res cm.Result[[]byte, streamError]) cm.Result[uint64, streamError] {
+	if res.IsErr {
+		return cm.Err[uint64](res.Err)
+	}
+	return cm.Ok[uint64, streamError](uint64(len(res.Value)))
+}
+
+func inputStreamSubscribe(self cm.Borrow[*inputStream]) cm.Own[*pollable] {
+	s := self.Rep
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: s.sysCtx, file: s.file, flag: fsapi.POLLIN}}
+}
+
+// outputStream is the representation of the resource output-stream, which writes a file without buffering, so that
+// its flushes do nothing.
+type outputStream struct {
+	sysCtx *internalsys.Context
+	file   fsapi.File
+	// positional is true when the file is written with Pwrite at offset, e.g. for a descriptor, instead of Write.
+	positional bool
+	offset     int64
+	// appending is true when the file is written with Pwrite at its end.
+	appending bool
+}
+
+// write writes all the bytes.
+func (s *outputStream) write(buf []byte) cm.Result[struct{}, streamError] {
+	for len(buf) > 0 {
+		n, errno := s.writeOnce(buf)
+		if errno != 0 {
+			return cm.Err[struct{}](fileStreamErrorOf(s.file, errno))
+		}
+		buf = buf[n:]
+	}
+	return cm.Ok[struct{}, streamError](struct{}{})
+}
+
+func (s *outputStream) writeOnce(buf []byte) (int, experimentalsys.Errno) {
+	switch {
+	case s.appending && s.file.IsAppend():
+		return s.file.Write(buf) // The file can't be written at an offset, e.g. if it only permits appending.
+	case s.appending:
+		st, errno := s.file.Stat()
+		if errno != 0 {
+			return 0, errno
+		}
+		return s.file.Pwrite(buf, st.Size)
+	case s.positional:
+		n, errno := s.file.Pwrite(buf, s.offset)
+		s.offset += int64(n)
+		return n, errno
+	}
+	return s.file.Write(buf)
+}
+
+func outputStreamCheckWrite(cm.Borrow[*outputStream]) cm.Result[uint64, streamError] {
+	return cm.Ok[uint64, streamError](maxBufferSize)
+}
+
+func outputStreamWrite(self cm.Borrow[*outputStream], contents []byte) cm.Result[struct{}, streamError] {
+	return self.Rep.write(contents)
+}
+
+func outputStreamFlush(cm.Borrow[*outputStream]) cm.Result[struct{}, streamError] {
+	return cm.Ok[struct{}, streamError](struct{}{})
+}
+
+func outputStreamSubscribe(self cm.Borrow[*outputStream]) cm.Own[*pollable] {
+	s := self.Rep
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: s.sysCtx, file: s.file, flag: fsapi.POLLOUT}}
+}
+
+func outputStreamWriteZeroes(self cm.Borrow[*outputStream], n uint64) cm.Result[struct{}, streamError] {
+	zeroes := make([]byte, min(n, maxBufferSize))
+	for n > 0 {
+		m := min(n, uint64(len(zeroes)))
+		if res := self.Rep.write(zeroes[:m]); res.IsErr {
+			return res
+		}
+		n -= m
+	}
+	return cm.Ok[struct{}, streamError](struct{}{})
+}
+
+func outputStreamSplice(self cm.Borrow[*outputStream], src cm.Borrow[*inputStream], n uint64) cm.Result[uint64, streamError] {
+	return splice(self.Rep, src.Rep, n, false)
+}
+
+func outputStreamBlockingSplice(self cm.Borrow[*outputStream], src cm.Borrow[*inputStream], n uint64) cm.Result[uint64, streamError] {
+	return splice(self.Rep, src.Rep, n, true)
+}
+
+// splice reads up to n bytes of src, and writes them to dst.
+func splice(dst *outputStream, src *inputStream, n uint64, blocking bool) cm.Result[uint64, streamError] {
+	read := src.read(n, blocking)
+	if read.IsErr {
+		return cm.Err[uint64](read.Err)
+	} else if res := dst.write(read.Value); res.IsErr {
+		return cm.Err[uint64](res.Err)
+	}
+	return cm.Ok[uint64, streamError](uint64(len(read.Value)))
+}
+
+// errnoOf returns the errno of the error of a reader or writer, e.g. ECONNRESET for a connection.
+func errnoOf(err error) experimentalsys.Errno {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return experimentalsys.UnwrapOSError(errno)
+	}
+	return experimentalsys.UnwrapOSError(err)
+}
+
+// readAheadFile is the file of the streams of a reader, e.g. a connection or the body of an HTTP response, which is
+// read by a goroutine up to maxBufferSize ahead, so that the readiness of its input stream can be polled.
+type readAheadFile struct {
+	experimentalsys.UnimplementedFile
+	r io.ReadCloser
+	// w is the writer of the writes, or nil if the file is read-only.
+	w io.Writer
+
+	mu  sync.Mutex
+	buf []byte
+	// err is the error which ended the reads, which is io.EOF at the end of the connection.
+	err error
+
+	// readable is signaled when bytes are read, or the error is set.
+	readable chan struct{}
+	// drained is signaled when bytes are consumed.
+	drained   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newReadAheadFile(r io.ReadCloser, w io.Writer) *readAheadFile {
+	f := &readAheadFile{
+		r:        r,
+		w:        w,
+		readable: make(chan struct{}, 1),
+		drained:  make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	go f.readAhead()
+	return f
+}
+
+// signal signals the channel of a capacity of one, unless already signaled.
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+func (f *readAheadFile) readAhead() {
+	buf := make([]byte, maxBufferSize)
+	for {
+		f.mu.Lock()
+		full := len(f.buf) >= maxBufferSize
+		f.mu.Unlock()
+		if full {
+			select {
+			case <-f.drained:
+				continue
+			case <-f.closed:
+				return
+			}
+		}
+		n, err := f.r.Read(buf)
+		f.mu.Lock()
+		f.buf = append(f.buf, buf[:n]...)
+		f.err = err
+		f.mu.Unlock()
+		signal(f.readable)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// cause implements causer, which returns the error which ended the reads, unless their end.
+func (f *readAheadFile) cause() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if errors.Is(f.err, io.EOF) {
+		return nil
+	}
+	return f.err
+}
+
+// readReady returns true if a read doesn't block.
+func (f *readAheadFile) readReady() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.buf) > 0 || f.err != nil
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (f *readAheadFile) IsNonblock() bool {
+	return false
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (f *readAheadFile) SetNonblock(bool) experimentalsys.Errno {
+	return experimentalsys.ENOSYS
+}
+
+// Poll implements the same method as documented on fsapi.File, which is always ready for writes, as they block.
+func (f *readAheadFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != fsapi.POLLIN || f.readReady() || timeoutMillis == 0 {
+		return flag != fsapi.POLLIN || f.readReady(), 0
+	}
+	var timeout <-chan time.Time
+	if timeoutMillis > 0 {
+		t := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-f.readable:
+	case <-timeout:
+	case <-f.closed:
+	}
+	return f.readReady(), 0
+}
+
+// Read implements the same method as documented on experimentalsys.File
+func (f *readAheadFile) Read(buf []byte) (n int, errno experimentalsys.Errno) {
+	f.mu.Lock()
+	for len(f.buf) == 0 && f.err == nil {
+		f.mu.Unlock()
+		select {
+		case <-f.readable:
+		case <-f.closed:
+			return 0, experimentalsys.EBADF
+		}
+		f.mu.Lock()
+	}
+	defer f.mu.Unlock()
+	if n = copy(buf, f.buf); n > 0 {
+		f.buf = f.buf[n:]
+		signal(f.drained)
+		return n, 0
+	} else if errors.Is(f.err, io.EOF) {
+		return 0, 0
+	}
+	return 0, errnoOf(f.err)
+}
+
+// Write implements the same method as documented on experimentalsys.File
+func (f *readAheadFile) Write(buf []byte) (n int, errno experimentalsys.Errno) {
+	if f.w == nil {
+		return 0, experimentalsys.EBADF
+	}
+	n, err := f.w.Write(buf)
+	return n, errnoOf(err)
+}
+
+// Close implements the same method as documented on experimentalsys.File
+func (f *readAheadFile) Close() (errno experimentalsys.Errno) {
+	f.closeOnce.Do(func() {
+		close(f.closed)
+		errno = errnoOf(f.r.Close())
+	})
+	return
+}
@@ -0,0 +1,156 @@
+package wasi_preview2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_errorToDebugString(t *testing.T) {
+	e := streamErrorOf(experimentalsys.EBADF).LastOperationFailed
+	require.Equal(t, "bad file descriptor", errorToDebugString(cm.Borrow[*ioErr]{Rep: e.Rep}))
+}
+
+func Test_inputStream(t *testing.T) {
+	sysCtx, err := internalsys.NewContext(0, nil, nil, strings.NewReader("hello"), nil, nil,
+		nil, nil, 0, nil, 0, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	s := cm.Borrow[*inputStream]{Rep: getStdin(testCtx, newModule(t, sysCtx)).Rep}
+
+	res := inputStreamRead(s, 2)
+	require.False(t, res.IsErr)
+	require.Equal(t, "he", string(res.Value))
+
+	res = inputStreamRead(s, 0)
+	require.False(t, res.IsErr)
+	require.Equal(t, []byte{}, res.Value)
+
+	skip := inputStreamSkip(s, 1)
+	require.False(t, skip.IsErr)
+	require.Equal(t, uint64(1), skip.Value)
+
+	res = inputStreamBlockingRead(s, 10)
+	require.False(t, res.IsErr)
+	require.Equal(t, "lo", string(res.Value))
+
+	// The end of the file closes the stream.
+	res = inputStreamBlockingRead(s, 10)
+	require.True(t, res.IsErr)
+	require.NotNil(t, res.Err.Closed)
+	skip = inputStreamBlockingSkip(s, 10)
+	require.True(t, skip.IsErr)
+	require.NotNil(t, skip.Err.Closed)
+}
+
+func Test_outputStream(t *testing.T) {
+	var stdout bytes.Buffer
+	sysCtx, err := internalsys.NewContext(0, nil, nil, strings.NewReader("spliced"), &stdout, nil,
+		nil, nil, 0, nil, 0, nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	mod := newModule(t, sysCtx)
+	s := cm.Borrow[*outputStream]{Rep: getStdout(testCtx, mod).Rep}
+
+	check := outputStreamCheckWrite(s)
+	require.False(t, check.IsErr)
+	require.Equal(t, uint64(maxBufferSize), check.Value)
+
+	require.False(t, outputStreamWrite(s, []byte("a")).IsErr)
+	require.False(t, outputStreamFlush(s).IsErr)
+	require.False(t, outputStreamWriteZeroes(s, 2).IsErr)
+
+	spliced := outputStreamBlockingSplice(s, cm.Borrow[*inputStream]{Rep: getStdin(testCtx, mod).Rep}, 3)
+	require.False(t, spliced.IsErr)
+	require.Equal(t, uint64(3), spliced.Value)
+
+	require.Equal(t, "a\x00\x00spl", stdout.String())
+}
+
+func Test_outputStream_Errors(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	// The standard input can't be written.
+	s := &outputStream{file: getStdin(testCtx, mod).Rep.file}
+
+	res := s.write([]byte("a"))
+	require.True(t, res.IsErr)
+	require.EqualErrno(t, experimentalsys.ENOSYS, res.Err.LastOperationFailed.Rep.err.(experimentalsys.Errno))
+}
+
+func Test_poll(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	// The standard input which is empty is always ready, unlike the instant which is never reached.
+	stdin := inputStreamSubscribe(cm.Borrow[*inputStream]{Rep: getStdin(testCtx, mod).Rep}).Rep
+	never := monotonicClockSubscribeDuration(testCtx, mod, 1<<62).Rep
+	require.Equal(t, []uint32{1}, poll(testCtx, []cm.Borrow[*pollable]{{Rep: never}, {Rep: stdin}}))
+
+	// Blocking waits for the instant, as the fake nanotime increases by 1ms each reading.
+	later := monotonicClockSubscribeDuration(testCtx, mod, 5_000_000).Rep
+	require.False(t, pollableReady(cm.Borrow[*pollable]{Rep: later}))
+	pollableBlock(testCtx, cm.Borrow[*pollable]{Rep: later})
+	require.True(t, pollableReady(cm.Borrow[*pollable]{Rep: later}))
+}
+
+func Test_poll_Errors(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	t.Run("no pollables", func(t *testing.T) {
+		err := require.CapturePanic(func() { poll(testCtx, nil) })
+		require.EqualError(t, err, "poll: no pollables")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(testCtx)
+		cancel()
+		never := monotonicClockSubscribeDuration(testCtx, mod, 1<<62).Rep
+		err := require.CapturePanic(func() { pollableBlock(ctx, cm.Borrow[*pollable]{Rep: never}) })
+		require.EqualError(t, err, "context canceled")
+	})
+}
+
+func Test_queue(t *testing.T) {
+	q := newQueue[int](2)
+	require.False(t, q.ready())
+
+	// The values beyond the limit are not queued.
+	require.True(t, q.push(1))
+	require.True(t, q.push(2))
+	require.False(t, q.push(3))
+	require.True(t, q.ready())
+	q.wait(time.Hour)
+
+	// The error is returned once all the values are popped.
+	q.end(errors.New("ended"))
+	values, err := q.pop(10)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, values)
+	_, err = q.pop(10)
+	require.EqualError(t, err, "ended")
+}
+
+func Test_future(t *testing.T) {
+	release := make(chan struct{})
+	f := goFuture(func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	require.False(t, f.ready())
+	f.wait(time.Millisecond)
+	require.False(t, f.ready())
+
+	close(release)
+	f.wait(time.Hour)
+	require.True(t, f.ready())
+	require.Equal(t, 1, f.value)
+
+	// A pollable of the future is ready when it is.
+	p := &pollable{sysCtx: internalsys.DefaultContext(nil), waiter: f}
+	require.Equal(t, []uint32{0}, pollPollables(testCtx, []*pollable{p}))
+}
@@ -0,0 +1,69 @@
+package wasi_preview2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+)
+
+// maxRandomBytes is the maximum number of random bytes which can be requested at once.
+const maxRandomBytes = 1 << 20
+
+// randomRandom is the interface wasi:random/random, whose bytes are read from the random source of the system
+// context, e.g. configured with wazero.ModuleConfig WithRandSource.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/random/random.wit
+var randomRandom = newInterface("wasi:random/random",
+	hostFunc{"get-random-bytes", getRandomBytes},
+	hostFunc{"get-random-u64", getRandomU64},
+)
+
+// randomInsecure is the interface wasi:random/insecure, which reads the same random source as randomRandom.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/random/insecure.wit
+var randomInsecure = newInterface("wasi:random/insecure",
+	hostFunc{"get-insecure-random-bytes", getRandomBytes},
+	hostFunc{"get-insecure-random-u64", getRandomU64},
+)
+
+// randomInsecureSeed is the interface wasi:random/insecure-seed, which reads the same random source as randomRandom.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/random/insecure-seed.wit
+var randomInsecureSeed = newInterface("wasi:random/insecure-seed",
+	hostFunc{"insecure-seed", insecureSeed},
+)
+
+// readRandom reads n random bytes, or panics if the random source fails, as the functions have no error.
+func readRandom(mod api.Module, n uint64) []byte {
+	if n > maxRandomBytes {
+		panic(fmt.Errorf("random bytes %d > %d", n, maxRandomBytes))
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(sysCtx(mod).RandSource(), buf); err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+func getRandomBytes(_ context.Context, mod api.Module, n uint64) []byte {
+	return readRandom(mod, n)
+}
+
+func getRandomU64(_ context.Context, mod api.Module) uint64 {
+	return binary.LittleEndian.Uint64(readRandom(mod, 8))
+}
+
+// seed is the tuple<u64, u64> of insecure-seed.
+type seed struct {
+	cm.Tuple
+	Lo, Hi uint64
+}
+
+func insecureSeed(_ context.Context, mod api.Module) seed {
+	buf := readRandom(mod, 16)
+	return seed{Lo: binary.LittleEndian.Uint64(buf), Hi: binary.LittleEndian.Uint64(buf[8:])}
+}
@@ -0,0 +1,45 @@
+package wasi_preview2
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_getRandomBytes(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+
+	// The bytes are the ones of the fake random source.
+	expected := make([]byte, 29)
+	_, err := io.ReadFull(platform.NewFakeRandSource(), expected)
+	require.NoError(t, err)
+
+	require.Equal(t, expected[:5], getRandomBytes(testCtx, mod, 5))
+	require.Equal(t, binary.LittleEndian.Uint64(expected[5:]), getRandomU64(testCtx, mod))
+	require.Equal(t, []byte{}, getRandomBytes(testCtx, mod, 0))
+	require.Equal(t, seed{Lo: binary.LittleEndian.Uint64(expected[13:]), Hi: binary.LittleEndian.Uint64(expected[21:])},
+		insecureSeed(testCtx, mod))
+}
+
+func Test_getRandomBytes_Errors(t *testing.T) {
+	t.Run("too many", func(t *testing.T) {
+		mod := newModule(t, internalsys.DefaultContext(nil))
+		err := require.CapturePanic(func() { getRandomBytes(testCtx, mod, maxRandomBytes+1) })
+		require.EqualError(t, err, "random bytes 1048577 > 1048576")
+	})
+
+	t.Run("random source fails", func(t *testing.T) {
+		sysCtx, err := internalsys.NewContext(0, nil, nil, nil, nil, nil, io.LimitReader(platform.NewFakeRandSource(), 4),
+			nil, 0, nil, 0, nil, nil, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		mod := newModule(t, sysCtx)
+
+		err = require.CapturePanic(func() { getRandomU64(testCtx, mod) })
+		require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	})
+}
@@ -0,0 +1,357 @@
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+)
+
+// SocketOperation is an operation of wasi:sockets checked by a SocketPolicy.
+type SocketOperation uint8
+
+const (
+	// SocketBind is the bind of a socket to a local address, e.g. before
+	// listening.
+	SocketBind SocketOperation = iota
+	// SocketConnect is the connection of a socket to a remote address, which
+	// is also the one of the remote address of a datagram sent.
+	SocketConnect
+	// SocketLookup is the lookup of the IP addresses of a name.
+	SocketLookup
+)
+
+// String returns the name of the operation, e.g. "bind".
+func (op SocketOperation) String() string {
+	switch op {
+	case SocketBind:
+		return "bind"
+	case SocketConnect:
+		return "connect"
+	case SocketLookup:
+		return "lookup"
+	}
+	return "unknown"
+}
+
+// SocketPolicy returns true if the operation of a guest is allowed, or false
+// if it fails with the error-code access-denied.
+//
+// The network is "tcp" or "udp" and the address is of the form "host:port",
+// e.g. "127.0.0.1:8080" or "[::1]:8080", for SocketBind and SocketConnect.
+// The network is "ip" and the address is the name looked up for SocketLookup.
+//
+// e.g. This allows the connections to the loopback addresses only:
+//
+//	policy := func(_ context.Context, op wasi_preview2.SocketOperation, _, address string) bool {
+//		addr, err := netip.ParseAddrPort(address)
+//		return op == wasi_preview2.SocketConnect && err == nil && addr.Addr().IsLoopback()
+//	}
+type SocketPolicy func(ctx context.Context, op SocketOperation, network, address string) bool
+
+// socketsNetwork is the interface wasi:sockets/network, which only defines the resource network.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/network.wit
+var socketsNetwork = newInterface("wasi:sockets/network")
+
+// socketsInstanceNetwork returns the interface wasi:sockets/instance-network, whose network allows the operations of
+// the policy.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/instance-network.wit
+func socketsInstanceNetwork(policy SocketPolicy) *hostInterface {
+	return newInterface("wasi:sockets/instance-network",
+		hostFunc{"instance-network", func() cm.Own[*network] {
+			return cm.Own[*network]{Rep: &network{policy: policy}}
+		}},
+	)
+}
+
+// network is the representation of the resource network, which is the network of the host, whose operations are
+// allowed by its policy.
+type network struct {
+	policy SocketPolicy
+}
+
+// allow returns true if the policy allows the operation, which is denied without a policy.
+func (n *network) allow(ctx context.Context, op SocketOperation, network, address string) bool {
+	return n.policy != nil && n.policy(ctx, op, network, address)
+}
+
+// socketErrorCode is the enum error-code of wasi:sockets/network.
+type socketErrorCode uint8
+
+const (
+	socketErrorCodeUnknown socketErrorCode = iota
+	socketErrorCodeAccessDenied
+	socketErrorCodeNotSupported
+	socketErrorCodeInvalidArgument
+	socketErrorCodeOutOfMemory
+	socketErrorCodeTimeout
+	socketErrorCodeConcurrencyConflict
+	socketErrorCodeNotInProgress
+	socketErrorCodeWouldBlock
+	socketErrorCodeInvalidState
+	socketErrorCodeNewSocketLimit
+	socketErrorCodeAddressNotBindable
+	socketErrorCodeAddressInUse
+	socketErrorCodeRemoteUnreachable
+	socketErrorCodeConnectionRefused
+	socketErrorCodeConnectionReset
+	socketErrorCodeConnectionAborted
+	socketErrorCodeDatagramTooLarge
+	socketErrorCodeNameUnresolvable
+	socketErrorCodeTemporaryResolverFailure
+	socketErrorCodePermanentResolverFailure
+)
+
+// Cases implements component.Enum
+func (socketErrorCode) Cases() []string {
+	return []string{
+		"unknown", "access-denied", "not-supported", "invalid-argument", "out-of-memory", "timeout",
+		"concurrency-conflict", "not-in-progress", "would-block", "invalid-state", "new-socket-limit",
+		"address-not-bindable", "address-in-use", "remote-unreachable", "connection-refused", "connection-reset",
+		"connection-aborted", "datagram-too-large", "name-unresolvable", "temporary-resolver-failure",
+		"permanent-resolver-failure",
+	}
+}
+
+// socketErrorCodeOf returns the error-code of the error of an operation of the net package.
+func socketErrorCodeOf(err error) socketErrorCode {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		switch {
+		case dnsErr.IsNotFound:
+			return socketErrorCodeNameUnresolvable
+		case dnsErr.IsTemporary, dnsErr.IsTimeout:
+			return socketErrorCodeTemporaryResolverFailure
+		}
+		return socketErrorCodePermanentResolverFailure
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return socketErrorCodeTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return socketErrorCodeConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return socketErrorCodeConnectionReset
+	case errors.Is(err, syscall.ECONNABORTED):
+		return socketErrorCodeConnectionAborted
+	case errors.Is(err, syscall.EADDRINUSE):
+		return socketErrorCodeAddressInUse
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return socketErrorCodeAddressNotBindable
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return socketErrorCodeRemoteUnreachable
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return socketErrorCodeAccessDenied
+	case errors.Is(err, syscall.EMSGSIZE):
+		return socketErrorCodeDatagramTooLarge
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE):
+		return socketErrorCodeNewSocketLimit
+	}
+	return socketErrorCodeUnknown
+}
+
+// socketOk is the successful result of an operation of a socket.
+func socketOk() cm.Result[struct{}, socketErrorCode] {
+	return cm.Ok[struct{}, socketErrorCode](struct{}{})
+}
+
+// ipAddressFamily is the enum ip-address-family.
+type ipAddressFamily uint8
+
+const (
+	ipAddressFamilyIPv4 ipAddressFamily = iota
+	ipAddressFamilyIPv6
+)
+
+// Cases implements component.Enum
+func (ipAddressFamily) Cases() []string {
+	return []string{"ipv4", "ipv6"}
+}
+
+// network returns the network of the family, e.g. "tcp4" for the network "tcp".
+func (f ipAddressFamily) network(n string) string {
+	if f == ipAddressFamilyIPv4 {
+		return n + "4"
+	}
+	return n + "6"
+}
+
+// ipAddressFamilyOf returns the family of the address, which is ipv4 for the IPv4-mapped IPv6 addresses.
+func ipAddressFamilyOf(addr netip.Addr) ipAddressFamily {
+	if addr.Unmap().Is4() {
+		return ipAddressFamilyIPv4
+	}
+	return ipAddressFamilyIPv6
+}
+
+// ipv4Address is the tuple<u8, u8, u8, u8> ipv4-address.
+type ipv4Address struct {
+	cm.Tuple
+	A, B, C, D uint8
+}
+
+// ipv6Address is the tuple<u16, u16, u16, u16, u16, u16, u16, u16> ipv6-address.
+type ipv6Address struct {
+	cm.Tuple
+	A, B, C, D, E, F, G, H uint16
+}
+
+func ipv4AddressOf(addr netip.Addr) ipv4Address {
+	b := addr.Unmap().As4()
+	return ipv4Address{A: b[0], B: b[1], C: b[2], D: b[3]}
+}
+
+func (a ipv4Address) addr() netip.Addr {
+	return netip.AddrFrom4([4]byte{a.A, a.B, a.C, a.D})
+}
+
+func ipv6AddressOf(addr netip.Addr) ipv6Address {
+	b := addr.As16()
+	s := func(i int) uint16 { return uint16(b[i])<<8 | uint16(b[i+1]) }
+	return ipv6Address{A: s(0), B: s(2), C: s(4), D: s(6), E: s(8), F: s(10), G: s(12), H: s(14)}
+}
+
+func (a ipv6Address) addr() netip.Addr {
+	var b [16]byte
+	for i, s := range []uint16{a.A, a.B, a.C, a.D, a.E, a.F, a.G, a.H} {
+		b[2*i], b[2*i+1] = byte(s>>8), byte(s)
+	}
+	return netip.AddrFrom16(b)
+}
+
+// ipAddress is the variant ip-address.
+type ipAddress struct {
+	cm.Variant
+	IPv4 *ipv4Address
+	IPv6 *ipv6Address
+}
+
+func ipAddressOf(addr netip.Addr) ipAddress {
+	if ipAddressFamilyOf(addr) == ipAddressFamilyIPv4 {
+		a := ipv4AddressOf(addr)
+		return ipAddress{IPv4: &a}
+	}
+	a := ipv6AddressOf(addr)
+	return ipAddress{IPv6: &a}
+}
+
+// ipv4SocketAddress is the record ipv4-socket-address.
+type ipv4SocketAddress struct {
+	Port    uint16
+	Address ipv4Address
+}
+
+// ipv6SocketAddress is the record ipv6-socket-address.
+type ipv6SocketAddress struct {
+	Port     uint16
+	FlowInfo uint32
+	Address  ipv6Address
+	ScopeID  uint32
+}
+
+// ipSocketAddress is the variant ip-socket-address.
+type ipSocketAddress struct {
+	cm.Variant
+	IPv4 *ipv4SocketAddress
+	IPv6 *ipv6SocketAddress
+}
+
+// ipSocketAddressOf returns the ip-socket-address of the address of the net package, which is a *net.TCPAddr or a
+// *net.UDPAddr.
+func ipSocketAddressOf(addr net.Addr) ipSocketAddress {
+	var ap netip.AddrPort
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ap = a.AddrPort()
+	case *net.UDPAddr:
+		ap = a.AddrPort()
+	}
+	return ipSocketAddressOfAddrPort(ap)
+}
+
+func ipSocketAddressOfAddrPort(ap netip.AddrPort) ipSocketAddress {
+	if ipAddressFamilyOf(ap.Addr()) == ipAddressFamilyIPv4 {
+		return ipSocketAddress{IPv4: &ipv4SocketAddress{Port: ap.Port(), Address: ipv4AddressOf(ap.Addr())}}
+	}
+	return ipSocketAddress{IPv6: &ipv6SocketAddress{Port: ap.Port(), Address: ipv6AddressOf(ap.Addr())}}
+}
+
+// family returns the family of the address.
+func (a ipSocketAddress) family() ipAddressFamily {
+	if a.IPv4 != nil {
+		return ipAddressFamilyIPv4
+	}
+	return ipAddressFamilyIPv6
+}
+
+// addrPort returns the address and port, ignoring the flow info and scope ID of an IPv6 address.
+func (a ipSocketAddress) addrPort() netip.AddrPort {
+	if a.IPv4 != nil {
+		return netip.AddrPortFrom(a.IPv4.Address.addr(), a.IPv4.Port)
+	}
+	return netip.AddrPortFrom(a.IPv6.Address.addr(), a.IPv6.Port)
+}
+
+// validRemote returns true if the address is a valid remote address of a socket of the family, so not unspecified
+// nor of the port zero.
+func (a ipSocketAddress) validRemote(family ipAddressFamily) bool {
+	ap := a.addrPort()
+	return a.family() == family && !ap.Addr().IsUnspecified() && ap.Port() != 0
+}
+
+// socketsIPNameLookup is the interface wasi:sockets/ip-name-lookup, whose lookups are the ones of the default resolver
+// of the net package.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/ip-name-lookup.wit
+var socketsIPNameLookup = newInterface("wasi:sockets/ip-name-lookup",
+	hostFunc{"[method]resolve-address-stream.resolve-next-address", resolveAddressStreamResolveNextAddress},
+	hostFunc{"[method]resolve-address-stream.subscribe", resolveAddressStreamSubscribe},
+	hostFunc{"resolve-addresses", resolveAddresses},
+)
+
+// resolveAddressStream is the representation of the resource resolve-address-stream, which is the result of a lookup
+// in progress.
+type resolveAddressStream struct {
+	lookup *future[[]netip.Addr]
+	// next is the index of the next address of the result returned.
+	next int
+}
+
+func resolveAddresses(ctx context.Context, self cm.Borrow[*network], name string) cm.Result[cm.Own[*resolveAddressStream], socketErrorCode] {
+	if name == "" {
+		return cm.Err[cm.Own[*resolveAddressStream]](socketErrorCodeInvalidArgument)
+	} else if !self.Rep.allow(ctx, SocketLookup, "ip", name) {
+		return cm.Err[cm.Own[*resolveAddressStream]](socketErrorCodeAccessDenied)
+	}
+	lookup := goFuture(func() ([]netip.Addr, error) {
+		if addr, err := netip.ParseAddr(name); err == nil {
+			return []netip.Addr{addr}, nil
+		}
+		return net.DefaultResolver.LookupNetIP(context.Background(), "ip", name)
+	})
+	return cm.Ok[cm.Own[*resolveAddressStream], socketErrorCode](cm.Own[*resolveAddressStream]{Rep: &resolveAddressStream{lookup: lookup}})
+}
+
+func resolveAddressStreamResolveNextAddress(self cm.Borrow[*resolveAddressStream]) cm.Result[cm.Option[ipAddress], socketErrorCode] {
+	s := self.Rep
+	switch {
+	case !s.lookup.ready():
+		return cm.Err[cm.Option[ipAddress]](socketErrorCodeWouldBlock)
+	case s.lookup.err != nil:
+		return cm.Err[cm.Option[ipAddress]](socketErrorCodeOf(s.lookup.err))
+	case s.next == len(s.lookup.value):
+		return cm.Ok[cm.Option[ipAddress], socketErrorCode](cm.None[ipAddress]())
+	}
+	s.next++
+	return cm.Ok[cm.Option[ipAddress], socketErrorCode](cm.Some(ipAddressOf(s.lookup.value[s.next-1])))
+}
+
+func resolveAddressStreamSubscribe(_ context.Context, mod api.Module, self cm.Borrow[*resolveAddressStream]) cm.Own[*pollable] {
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: sysCtx(mod), waiter: self.Rep.lookup}}
+}
@@ -0,0 +1,164 @@
+package wasi_preview2
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"testing"
+
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// operation is an operation checked by a policy.
+type operation struct {
+	op               SocketOperation
+	network, address string
+}
+
+// recordingPolicy returns a policy which records the operations checked, and allows the ones of the loopback
+// addresses and of the name localhost only.
+func recordingPolicy(checked *[]operation) SocketPolicy {
+	return func(_ context.Context, op SocketOperation, network, address string) bool {
+		*checked = append(*checked, operation{op, network, address})
+		if op == SocketLookup {
+			return address == "localhost"
+		}
+		addr, err := netip.ParseAddrPort(address)
+		return err == nil && addr.Addr().IsLoopback()
+	}
+}
+
+// loopback returns the ip-socket-address of the port of the IPv4 loopback address.
+func loopback(port uint16) ipSocketAddress {
+	return ipSocketAddressOfAddrPort(netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), port))
+}
+
+// borrow returns a borrow of the owned handle, e.g. a pollable returned by a subscribe.
+func borrow[T any](own cm.Own[T]) cm.Borrow[T] {
+	return cm.Borrow[T]{Rep: own.Rep}
+}
+
+func Test_network_allow(t *testing.T) {
+	var checked []operation
+	n := &network{policy: recordingPolicy(&checked)}
+
+	require.True(t, n.allow(testCtx, SocketConnect, "tcp", "127.0.0.1:80"))
+	require.False(t, n.allow(testCtx, SocketConnect, "tcp", "192.0.2.1:80"))
+	require.True(t, n.allow(testCtx, SocketLookup, "ip", "localhost"))
+	require.Equal(t, []operation{
+		{SocketConnect, "tcp", "127.0.0.1:80"},
+		{SocketConnect, "tcp", "192.0.2.1:80"},
+		{SocketLookup, "ip", "localhost"},
+	}, checked)
+
+	// Everything is denied without a policy.
+	require.False(t, (&network{}).allow(testCtx, SocketConnect, "tcp", "127.0.0.1:80"))
+}
+
+func TestSocketOperation_String(t *testing.T) {
+	require.Equal(t, "bind", SocketBind.String())
+	require.Equal(t, "connect", SocketConnect.String())
+	require.Equal(t, "lookup", SocketLookup.String())
+	require.Equal(t, "unknown", SocketOperation(3).String())
+}
+
+func Test_ipSocketAddress(t *testing.T) {
+	tests := []string{"127.0.0.1:80", "[::1]:443", "[2001:db8::1:2]:8080"}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc, func(t *testing.T) {
+			ap := netip.MustParseAddrPort(tc)
+			a := ipSocketAddressOfAddrPort(ap)
+			require.Equal(t, ap, a.addrPort())
+			require.Equal(t, ap, ipSocketAddressOf(net.TCPAddrFromAddrPort(ap)).addrPort())
+			require.Equal(t, ap, ipSocketAddressOf(net.UDPAddrFromAddrPort(ap)).addrPort())
+			require.Equal(t, ipAddressFamilyOf(ap.Addr()), a.family())
+		})
+	}
+
+	// The IPv4-mapped IPv6 addresses are IPv4 addresses.
+	a := ipSocketAddressOfAddrPort(netip.MustParseAddrPort("[::ffff:127.0.0.1]:80"))
+	require.Equal(t, &ipv4SocketAddress{Port: 80, Address: ipv4Address{A: 127, D: 1}}, a.IPv4)
+
+	require.True(t, loopback(80).validRemote(ipAddressFamilyIPv4))
+	require.False(t, loopback(80).validRemote(ipAddressFamilyIPv6))
+	require.False(t, loopback(0).validRemote(ipAddressFamilyIPv4))
+	require.False(t, ipSocketAddressOfAddrPort(netip.MustParseAddrPort("0.0.0.0:80")).validRemote(ipAddressFamilyIPv4))
+}
+
+func Test_socketErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected socketErrorCode
+	}{
+		{err: &net.DNSError{IsNotFound: true}, expected: socketErrorCodeNameUnresolvable},
+		{err: &net.DNSError{IsTemporary: true}, expected: socketErrorCodeTemporaryResolverFailure},
+		{err: &net.DNSError{}, expected: socketErrorCodePermanentResolverFailure},
+		{err: os.ErrDeadlineExceeded, expected: socketErrorCodeTimeout},
+		{err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: socketErrorCodeConnectionRefused},
+		{err: syscall.ECONNRESET, expected: socketErrorCodeConnectionReset},
+		{err: syscall.EADDRINUSE, expected: socketErrorCodeAddressInUse},
+		{err: syscall.EHOSTUNREACH, expected: socketErrorCodeRemoteUnreachable},
+		{err: net.ErrClosed, expected: socketErrorCodeUnknown},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		name := tc.expected.Cases()[tc.expected]
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, name, tc.expected.Cases()[socketErrorCodeOf(tc.err)])
+		})
+	}
+}
+
+func Test_resolveAddresses(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var checked []operation
+	n := cm.Borrow[*network]{Rep: &network{policy: recordingPolicy(&checked)}}
+
+	t.Run("localhost", func(t *testing.T) {
+		s := cm.Borrow[*resolveAddressStream]{Rep: requireOk(t, resolveAddresses(testCtx, n, "localhost")).Rep}
+		pollableBlock(testCtx, borrow(resolveAddressStreamSubscribe(testCtx, mod, s)))
+
+		var addrs []netip.Addr
+		for {
+			next := requireOk(t, resolveAddressStreamResolveNextAddress(s))
+			if !next.Valid {
+				break
+			}
+			if next.Value.IPv4 != nil {
+				addrs = append(addrs, next.Value.IPv4.addr())
+			} else {
+				addrs = append(addrs, next.Value.IPv6.addr())
+			}
+		}
+		require.NotEqual(t, 0, len(addrs))
+		for _, addr := range addrs {
+			require.True(t, addr.IsLoopback(), addr.String())
+		}
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		requireErrorCode(t, socketErrorCodeAccessDenied, resolveAddresses(testCtx, n, "example.com"))
+		requireErrorCode(t, socketErrorCodeInvalidArgument, resolveAddresses(testCtx, n, ""))
+	})
+
+	require.Equal(t, []operation{{SocketLookup, "ip", "localhost"}, {SocketLookup, "ip", "example.com"}}, checked)
+}
+
+func Test_resolveAddressStream_WouldBlock(t *testing.T) {
+	lookup := &future[[]netip.Addr]{done: make(chan struct{})}
+	s := cm.Borrow[*resolveAddressStream]{Rep: &resolveAddressStream{lookup: lookup}}
+	requireErrorCode(t, socketErrorCodeWouldBlock, resolveAddressStreamResolveNextAddress(s))
+
+	lookup.value = []netip.Addr{netip.MustParseAddr("::1")}
+	close(lookup.done)
+	next := requireOk(t, resolveAddressStreamResolveNextAddress(s))
+	require.Equal(t, &ipv6Address{H: 1}, next.Value.IPv6)
+	require.False(t, requireOk(t, resolveAddressStreamResolveNextAddress(s)).Valid)
+}
@@ -0,0 +1,465 @@
+package wasi_preview2
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+)
+
+// socketsTCP is the interface wasi:sockets/tcp, whose sockets are the TCP connections and listeners of the net
+// package.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/tcp.wit
+var socketsTCP = newInterface("wasi:sockets/tcp",
+	hostFunc{"[method]tcp-socket.start-bind", tcpSocketStartBind},
+	hostFunc{"[method]tcp-socket.finish-bind", tcpSocketFinishBind},
+	hostFunc{"[method]tcp-socket.start-connect", tcpSocketStartConnect},
+	hostFunc{"[method]tcp-socket.finish-connect", tcpSocketFinishConnect},
+	hostFunc{"[method]tcp-socket.start-listen", tcpSocketStartListen},
+	hostFunc{"[method]tcp-socket.finish-listen", tcpSocketFinishListen},
+	hostFunc{"[method]tcp-socket.accept", tcpSocketAccept},
+	hostFunc{"[method]tcp-socket.local-address", tcpSocketLocalAddress},
+	hostFunc{"[method]tcp-socket.remote-address", tcpSocketRemoteAddress},
+	hostFunc{"[method]tcp-socket.is-listening", tcpSocketIsListening},
+	hostFunc{"[method]tcp-socket.address-family", tcpSocketAddressFamily},
+	hostFunc{"[method]tcp-socket.set-listen-backlog-size", tcpSocketSetListenBacklogSize},
+	hostFunc{"[method]tcp-socket.keep-alive-enabled", tcpSocketKeepAliveEnabled},
+	hostFunc{"[method]tcp-socket.set-keep-alive-enabled", tcpSocketSetKeepAliveEnabled},
+	hostFunc{"[method]tcp-socket.keep-alive-idle-time", tcpSocketKeepAliveIdleTime},
+	hostFunc{"[method]tcp-socket.set-keep-alive-idle-time", tcpSocketSetKeepAliveIdleTime},
+	hostFunc{"[method]tcp-socket.keep-alive-interval", tcpSocketKeepAliveInterval},
+	hostFunc{"[method]tcp-socket.set-keep-alive-interval", tcpSocketSetKeepAliveInterval},
+	hostFunc{"[method]tcp-socket.keep-alive-count", tcpSocketKeepAliveCount},
+	hostFunc{"[method]tcp-socket.set-keep-alive-count", tcpSocketSetKeepAliveCount},
+	hostFunc{"[method]tcp-socket.hop-limit", tcpSocketHopLimit},
+	hostFunc{"[method]tcp-socket.set-hop-limit", tcpSocketSetHopLimit},
+	hostFunc{"[method]tcp-socket.receive-buffer-size", tcpSocketReceiveBufferSize},
+	hostFunc{"[method]tcp-socket.set-receive-buffer-size", tcpSocketSetReceiveBufferSize},
+	hostFunc{"[method]tcp-socket.send-buffer-size", tcpSocketSendBufferSize},
+	hostFunc{"[method]tcp-socket.set-send-buffer-size", tcpSocketSetSendBufferSize},
+	hostFunc{"[method]tcp-socket.subscribe", tcpSocketSubscribe},
+	hostFunc{"[method]tcp-socket.shutdown", tcpSocketShutdown},
+)
+
+// socketsTCPCreateSocket is the interface wasi:sockets/tcp-create-socket.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/tcp-create-socket.wit
+var socketsTCPCreateSocket = newInterface("wasi:sockets/tcp-create-socket",
+	hostFunc{"create-tcp-socket", createTCPSocket},
+)
+
+// tcpState is the state of a TCP socket.
+type tcpState uint8
+
+const (
+	tcpStateUnbound tcpState = iota
+	tcpStateBindStarted
+	tcpStateBound
+	tcpStateConnectStarted
+	tcpStateConnected
+	tcpStateListenStarted
+	tcpStateListening
+	// tcpStateClosed is the state of a socket whose connection failed, which can't be used anymore.
+	tcpStateClosed
+)
+
+// tcpOptions are the options of a TCP socket, which are inherited by the sockets it accepts.
+type tcpOptions struct {
+	backlog           uint64
+	keepAlive         bool
+	keepAliveIdle     time.Duration
+	keepAliveInterval time.Duration
+	keepAliveCount    uint32
+	// hopLimit is only reported, as the net package can't set it.
+	hopLimit          uint8
+	receiveBufferSize uint64
+	sendBufferSize    uint64
+}
+
+// defaultTCPOptions are the options of a new TCP socket, which are the usual defaults of Linux.
+var defaultTCPOptions = tcpOptions{
+	backlog:           128,
+	keepAliveIdle:     2 * time.Hour,
+	keepAliveInterval: 75 * time.Second,
+	keepAliveCount:    9,
+	hopLimit:          64,
+	receiveBufferSize: maxBufferSize,
+	sendBufferSize:    maxBufferSize,
+}
+
+// apply sets the options of the connection, ignoring the errors as the options are hints.
+func (o *tcpOptions) apply(conn *net.TCPConn) {
+	_ = conn.SetKeepAliveConfig(net.KeepAliveConfig{
+		Enable:   o.keepAlive,
+		Idle:     o.keepAliveIdle,
+		Interval: o.keepAliveInterval,
+		Count:    int(o.keepAliveCount),
+	})
+	_ = conn.SetReadBuffer(int(min(o.receiveBufferSize, math.MaxInt32)))
+	_ = conn.SetWriteBuffer(int(min(o.sendBufferSize, math.MaxInt32)))
+}
+
+// tcpSocket is the representation of the resource tcp-socket.
+//
+// The net package can't bind a socket without connecting or listening, so the local address of a bind is only bound
+// by the following connect or listen, which report its errors, e.g. address-in-use.
+type tcpSocket struct {
+	sysCtx  *internalsys.Context
+	family  ipAddressFamily
+	state   tcpState
+	options tcpOptions
+	// local is the address of the bind.
+	local netip.AddrPort
+	// connect is the connection in progress, until finish-connect.
+	connect       *future[*net.TCPConn]
+	cancelConnect context.CancelFunc
+	conn          *net.TCPConn
+	file          *readAheadFile
+	listener      *net.TCPListener
+	// accepted are the connections accepted by the listener, up to the backlog.
+	accepted *queue[*net.TCPConn]
+}
+
+// Close implements api.Closer
+func (s *tcpSocket) Close(context.Context) error {
+	if s.cancelConnect != nil {
+		s.cancelConnect()
+		// The connection may be established before the dial is canceled.
+		go func(connect *future[*net.TCPConn]) {
+			<-connect.done
+			if connect.value != nil {
+				_ = connect.value.Close()
+			}
+		}(s.connect)
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+	if s.listener != nil {
+		_ = s.listener.Close()
+		for {
+			conns, _ := s.accepted.pop(1)
+			if len(conns) == 0 {
+				break
+			}
+			_ = conns[0].Close()
+		}
+	}
+	return nil
+}
+
+// connected sets the connection of the socket.
+func (s *tcpSocket) connected(conn *net.TCPConn) {
+	s.options.apply(conn)
+	s.conn, s.file = conn, newReadAheadFile(conn, conn)
+	s.state = tcpStateConnected
+}
+
+// streams returns new streams of the connection.
+func (s *tcpSocket) streams() (cm.Own[*inputStream], cm.Own[*outputStream]) {
+	return cm.Own[*inputStream]{Rep: &inputStream{sysCtx: s.sysCtx, file: s.file}},
+		cm.Own[*outputStream]{Rep: &outputStream{sysCtx: s.sysCtx, file: s.file}}
+}
+
+func createTCPSocket(_ context.Context, mod api.Module, family ipAddressFamily) cm.Result[cm.Own[*tcpSocket], socketErrorCode] {
+	s := &tcpSocket{sysCtx: sysCtx(mod), family: family, options: defaultTCPOptions}
+	return cm.Ok[cm.Own[*tcpSocket], socketErrorCode](cm.Own[*tcpSocket]{Rep: s})
+}
+
+func tcpSocketStartBind(ctx context.Context, self cm.Borrow[*tcpSocket], n cm.Borrow[*network], local ipSocketAddress) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	switch {
+	case s.state != tcpStateUnbound:
+		return cm.Err[struct{}](socketErrorCodeInvalidState)
+	case local.family() != s.family:
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	case !n.Rep.allow(ctx, SocketBind, "tcp", local.addrPort().String()):
+		return cm.Err[struct{}](socketErrorCodeAccessDenied)
+	}
+	s.local = local.addrPort()
+	s.state = tcpStateBindStarted
+	return socketOk()
+}
+
+func tcpSocketFinishBind(self cm.Borrow[*tcpSocket]) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	if s.state != tcpStateBindStarted {
+		return cm.Err[struct{}](socketErrorCodeNotInProgress)
+	}
+	s.state = tcpStateBound
+	return socketOk()
+}
+
+func tcpSocketStartConnect(ctx context.Context, self cm.Borrow[*tcpSocket], n cm.Borrow[*network], remote ipSocketAddress) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	switch {
+	case s.state != tcpStateUnbound && s.state != tcpStateBound:
+		return cm.Err[struct{}](socketErrorCodeInvalidState)
+	case !remote.validRemote(s.family):
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	case !n.Rep.allow(ctx, SocketConnect, "tcp", remote.addrPort().String()):
+		return cm.Err[struct{}](socketErrorCodeAccessDenied)
+	}
+	var d net.Dialer
+	if s.state == tcpStateBound {
+		d.LocalAddr = net.TCPAddrFromAddrPort(s.local)
+	}
+	// The connection outlives the call, so it's only canceled by closing the socket.
+	dialCtx, cancel := context.WithCancel(context.Background())
+	network, address := s.family.network("tcp"), remote.addrPort().String()
+	s.connect = goFuture(func() (*net.TCPConn, error) {
+		conn, err := d.DialContext(dialCtx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return conn.(*net.TCPConn), nil
+	})
+	s.cancelConnect = cancel
+	s.state = tcpStateConnectStarted
+	return socketOk()
+}
+
+// tcpStreams is the tuple<input-stream, output-stream> of a connection.
+type tcpStreams struct {
+	cm.Tuple
+	Input  cm.Own[*inputStream]
+	Output cm.Own[*outputStream]
+}
+
+func tcpSocketFinishConnect(self cm.Borrow[*tcpSocket]) cm.Result[tcpStreams, socketErrorCode] {
+	s := self.Rep
+	if s.state != tcpStateConnectStarted {
+		return cm.Err[tcpStreams](socketErrorCodeNotInProgress)
+	} else if !s.connect.ready() {
+		return cm.Err[tcpStreams](socketErrorCodeWouldBlock)
+	}
+	connect := s.connect
+	s.cancelConnect()
+	s.connect, s.cancelConnect = nil, nil
+	if connect.err != nil {
+		s.state = tcpStateClosed
+		return cm.Err[tcpStreams](socketErrorCodeOf(connect.err))
+	}
+	s.connected(connect.value)
+	in, out := s.streams()
+	return cm.Ok[tcpStreams, socketErrorCode](tcpStreams{Input: in, Output: out})
+}
+
+func tcpSocketStartListen(self cm.Borrow[*tcpSocket]) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	if s.state != tcpStateBound {
+		return cm.Err[struct{}](socketErrorCodeInvalidState)
+	}
+	l, err := net.ListenTCP(s.family.network("tcp"), net.TCPAddrFromAddrPort(s.local))
+	if err != nil {
+		return cm.Err[struct{}](socketErrorCodeOf(err))
+	}
+	s.listener = l
+	s.accepted = newQueue[*net.TCPConn](int(min(s.options.backlog, math.MaxInt32)))
+	go func(accepted *queue[*net.TCPConn]) {
+		for {
+			conn, err := l.AcceptTCP()
+			if err != nil {
+				accepted.end(err)
+				return
+			}
+			// The connections beyond the backlog are refused.
+			if !accepted.push(conn) {
+				_ = conn.Close()
+			}
+		}
+	}(s.accepted)
+	s.state = tcpStateListenStarted
+	return socketOk()
+}
+
+func tcpSocketFinishListen(self cm.Borrow[*tcpSocket]) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	if s.state != tcpStateListenStarted {
+		return cm.Err[struct{}](socketErrorCodeNotInProgress)
+	}
+	s.state = tcpStateListening
+	return socketOk()
+}
+
+// acceptedConnection is the tuple<tcp-socket, input-stream, output-stream> of an accepted connection.
+type acceptedConnection struct {
+	cm.Tuple
+	Socket cm.Own[*tcpSocket]
+	Input  cm.Own[*inputStream]
+	Output cm.Own[*outputStream]
+}
+
+func tcpSocketAccept(self cm.Borrow[*tcpSocket]) cm.Result[acceptedConnection, socketErrorCode] {
+	s := self.Rep
+	if s.state != tcpStateListening {
+		return cm.Err[acceptedConnection](socketErrorCodeInvalidState)
+	}
+	conns, err := s.accepted.pop(1)
+	if err != nil {
+		return cm.Err[acceptedConnection](socketErrorCodeOf(err))
+	} else if len(conns) == 0 {
+		return cm.Err[acceptedConnection](socketErrorCodeWouldBlock)
+	}
+	c := &tcpSocket{sysCtx: s.sysCtx, family: s.family, options: s.options}
+	c.connected(conns[0])
+	in, out := c.streams()
+	return cm.Ok[acceptedConnection, socketErrorCode](acceptedConnection{Socket: cm.Own[*tcpSocket]{Rep: c}, Input: in, Output: out})
+}
+
+func tcpSocketLocalAddress(self cm.Borrow[*tcpSocket]) cm.Result[ipSocketAddress, socketErrorCode] {
+	s := self.Rep
+	switch {
+	case s.conn != nil:
+		return cm.Ok[ipSocketAddress, socketErrorCode](ipSocketAddressOf(s.conn.LocalAddr()))
+	case s.listener != nil:
+		return cm.Ok[ipSocketAddress, socketErrorCode](ipSocketAddressOf(s.listener.Addr()))
+	case s.state == tcpStateBound:
+		return cm.Ok[ipSocketAddress, socketErrorCode](ipSocketAddressOfAddrPort(s.local))
+	}
+	return cm.Err[ipSocketAddress](socketErrorCodeInvalidState)
+}
+
+func tcpSocketRemoteAddress(self cm.Borrow[*tcpSocket]) cm.Result[ipSocketAddress, socketErrorCode] {
+	if conn := self.Rep.conn; conn != nil {
+		return cm.Ok[ipSocketAddress, socketErrorCode](ipSocketAddressOf(conn.RemoteAddr()))
+	}
+	return cm.Err[ipSocketAddress](socketErrorCodeInvalidState)
+}
+
+func tcpSocketIsListening(self cm.Borrow[*tcpSocket]) bool {
+	return self.Rep.state == tcpStateListening
+}
+
+func tcpSocketAddressFamily(self cm.Borrow[*tcpSocket]) ipAddressFamily {
+	return self.Rep.family
+}
+
+// setOption sets an option of the socket with set, unless the value is zero, and applies it to the connection.
+func (s *tcpSocket) setOption(zero bool, set func(*tcpOptions)) cm.Result[struct{}, socketErrorCode] {
+	if zero {
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	}
+	set(&s.options)
+	if s.conn != nil {
+		s.options.apply(s.conn)
+	}
+	return socketOk()
+}
+
+func tcpSocketSetListenBacklogSize(self cm.Borrow[*tcpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	// The backlog of a listener can't be changed.
+	if s.state == tcpStateConnectStarted || s.state == tcpStateConnected || s.listener != nil {
+		return cm.Err[struct{}](socketErrorCodeInvalidState)
+	}
+	return s.setOption(value == 0, func(o *tcpOptions) { o.backlog = value })
+}
+
+func tcpSocketKeepAliveEnabled(self cm.Borrow[*tcpSocket]) cm.Result[bool, socketErrorCode] {
+	return cm.Ok[bool, socketErrorCode](self.Rep.options.keepAlive)
+}
+
+func tcpSocketSetKeepAliveEnabled(self cm.Borrow[*tcpSocket], value bool) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(false, func(o *tcpOptions) { o.keepAlive = value })
+}
+
+func tcpSocketKeepAliveIdleTime(self cm.Borrow[*tcpSocket]) cm.Result[uint64, socketErrorCode] {
+	return cm.Ok[uint64, socketErrorCode](uint64(self.Rep.options.keepAliveIdle))
+}
+
+func tcpSocketSetKeepAliveIdleTime(self cm.Borrow[*tcpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(value == 0, func(o *tcpOptions) { o.keepAliveIdle = time.Duration(min(value, math.MaxInt64)) })
+}
+
+func tcpSocketKeepAliveInterval(self cm.Borrow[*tcpSocket]) cm.Result[uint64, socketErrorCode] {
+	return cm.Ok[uint64, socketErrorCode](uint64(self.Rep.options.keepAliveInterval))
+}
+
+func tcpSocketSetKeepAliveInterval(self cm.Borrow[*tcpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(value == 0, func(o *tcpOptions) { o.keepAliveInterval = time.Duration(min(value, math.MaxInt64)) })
+}
+
+func tcpSocketKeepAliveCount(self cm.Borrow[*tcpSocket]) cm.Result[uint32, socketErrorCode] {
+	return cm.Ok[uint32, socketErrorCode](self.Rep.options.keepAliveCount)
+}
+
+func tcpSocketSetKeepAliveCount(self cm.Borrow[*tcpSocket], value uint32) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(value == 0, func(o *tcpOptions) { o.keepAliveCount = value })
+}
+
+func tcpSocketHopLimit(self cm.Borrow[*tcpSocket]) cm.Result[uint8, socketErrorCode] {
+	return cm.Ok[uint8, socketErrorCode](self.Rep.options.hopLimit)
+}
+
+func tcpSocketSetHopLimit(self cm.Borrow[*tcpSocket], value uint8) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(value == 0, func(o *tcpOptions) { o.hopLimit = value })
+}
+
+func tcpSocketReceiveBufferSize(self cm.Borrow[*tcpSocket]) cm.Result[uint64, socketErrorCode] {
+	return cm.Ok[uint64, socketErrorCode](self.Rep.options.receiveBufferSize)
+}
+
+func tcpSocketSetReceiveBufferSize(self cm.Borrow[*tcpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(value == 0, func(o *tcpOptions) { o.receiveBufferSize = value })
+}
+
+func tcpSocketSendBufferSize(self cm.Borrow[*tcpSocket]) cm.Result[uint64, socketErrorCode] {
+	return cm.Ok[uint64, socketErrorCode](self.Rep.options.sendBufferSize)
+}
+
+func tcpSocketSetSendBufferSize(self cm.Borrow[*tcpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	return self.Rep.setOption(value == 0, func(o *tcpOptions) { o.sendBufferSize = value })
+}
+
+// tcpSocketSubscribe returns a pollable ready when the connection in progress is established or failed, or when the
+// listener accepted a connection. Otherwise, it's ready immediately.
+func tcpSocketSubscribe(self cm.Borrow[*tcpSocket]) cm.Own[*pollable] {
+	s := self.Rep
+	p := &pollable{sysCtx: s.sysCtx}
+	switch {
+	case s.connect != nil:
+		p.waiter = s.connect
+	case s.accepted != nil:
+		p.waiter = s.accepted
+	}
+	return cm.Own[*pollable]{Rep: p}
+}
+
+// shutdownType is the enum shutdown-type.
+type shutdownType uint8
+
+const (
+	shutdownTypeReceive shutdownType = iota
+	shutdownTypeSend
+	shutdownTypeBoth
+)
+
+// Cases implements component.Enum
+func (shutdownType) Cases() []string {
+	return []string{"receive", "send", "both"}
+}
+
+func tcpSocketShutdown(self cm.Borrow[*tcpSocket], how shutdownType) cm.Result[struct{}, socketErrorCode] {
+	conn := self.Rep.conn
+	if conn == nil {
+		return cm.Err[struct{}](socketErrorCodeInvalidState)
+	}
+	var err error
+	if how != shutdownTypeSend {
+		err = conn.CloseRead()
+	}
+	if how != shutdownTypeReceive && err == nil {
+		err = conn.CloseWrite()
+	}
+	if err != nil {
+		return cm.Err[struct{}](socketErrorCodeOf(err))
+	}
+	return socketOk()
+}
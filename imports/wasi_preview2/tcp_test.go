@@ -0,0 +1,146 @@
+package wasi_preview2
+
+import (
+	"net/netip"
+	"testing"
+
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// listen makes the TCP socket listen on an ephemeral port of the loopback address.
+func listen(t *testing.T, s cm.Borrow[*tcpSocket], n cm.Borrow[*network]) {
+	t.Helper()
+	requireOk(t, tcpSocketStartBind(testCtx, s, n, loopback(0)))
+	requireOk(t, tcpSocketFinishBind(s))
+	requireOk(t, tcpSocketStartListen(s))
+	requireOk(t, tcpSocketFinishListen(s))
+}
+
+func Test_tcpSocket(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var checked []operation
+	n := cm.Borrow[*network]{Rep: &network{policy: recordingPolicy(&checked)}}
+
+	l := cm.Borrow[*tcpSocket]{Rep: requireOk(t, createTCPSocket(testCtx, mod, ipAddressFamilyIPv4)).Rep}
+	defer l.Rep.Close(testCtx)
+	listen(t, l, n)
+	require.True(t, tcpSocketIsListening(l))
+	addr := requireOk(t, tcpSocketLocalAddress(l))
+	requireErrorCode(t, socketErrorCodeWouldBlock, tcpSocketAccept(l))
+
+	c := cm.Borrow[*tcpSocket]{Rep: requireOk(t, createTCPSocket(testCtx, mod, ipAddressFamilyIPv4)).Rep}
+	defer c.Rep.Close(testCtx)
+	requireOk(t, tcpSocketStartConnect(testCtx, c, n, addr))
+	pollableBlock(testCtx, borrow(tcpSocketSubscribe(c)))
+	streams := requireOk(t, tcpSocketFinishConnect(c))
+	require.Equal(t, addr, requireOk(t, tcpSocketRemoteAddress(c)))
+
+	pollableBlock(testCtx, borrow(tcpSocketSubscribe(l)))
+	accepted := requireOk(t, tcpSocketAccept(l))
+	a := cm.Borrow[*tcpSocket]{Rep: accepted.Socket.Rep}
+	defer a.Rep.Close(testCtx)
+	require.Equal(t, requireOk(t, tcpSocketLocalAddress(c)), requireOk(t, tcpSocketRemoteAddress(a)))
+
+	require.Equal(t, []operation{
+		{SocketBind, "tcp", "127.0.0.1:0"},
+		{SocketConnect, "tcp", addr.addrPort().String()},
+	}, checked)
+
+	t.Run("streams", func(t *testing.T) {
+		out := cm.Borrow[*outputStream]{Rep: streams.Output.Rep}
+		in := cm.Borrow[*inputStream]{Rep: accepted.Input.Rep}
+
+		// Nothing is read until written.
+		res := inputStreamRead(in, 10)
+		require.False(t, res.IsErr)
+		require.Equal(t, 0, len(res.Value))
+		require.False(t, pollableReady(borrow(inputStreamSubscribe(in))))
+
+		require.False(t, outputStreamWrite(out, []byte("hello")).IsErr)
+		pollableBlock(testCtx, borrow(inputStreamSubscribe(in)))
+		res = inputStreamBlockingRead(in, 10)
+		require.False(t, res.IsErr)
+		require.Equal(t, "hello", string(res.Value))
+
+		// Shutting down the sends of a socket ends the input stream of its peer.
+		requireOk(t, tcpSocketShutdown(c, shutdownTypeSend))
+		res = inputStreamBlockingRead(in, 10)
+		require.True(t, res.IsErr)
+		require.NotNil(t, res.Err.Closed)
+	})
+}
+
+func Test_tcpSocket_Errors(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var checked []operation
+	n := cm.Borrow[*network]{Rep: &network{policy: recordingPolicy(&checked)}}
+	newSocket := func() cm.Borrow[*tcpSocket] {
+		return cm.Borrow[*tcpSocket]{Rep: requireOk(t, createTCPSocket(testCtx, mod, ipAddressFamilyIPv4)).Rep}
+	}
+
+	t.Run("denied", func(t *testing.T) {
+		s := newSocket()
+		remote := ipSocketAddressOfAddrPort(netip.MustParseAddrPort("192.0.2.1:80"))
+		requireErrorCode(t, socketErrorCodeAccessDenied, tcpSocketStartBind(testCtx, s, n, remote))
+		requireErrorCode(t, socketErrorCodeAccessDenied, tcpSocketStartConnect(testCtx, s, n, remote))
+	})
+
+	t.Run("invalid argument", func(t *testing.T) {
+		s := newSocket()
+		ipv6 := ipSocketAddressOfAddrPort(netip.MustParseAddrPort("[::1]:80"))
+		requireErrorCode(t, socketErrorCodeInvalidArgument, tcpSocketStartBind(testCtx, s, n, ipv6))
+		requireErrorCode(t, socketErrorCodeInvalidArgument, tcpSocketStartConnect(testCtx, s, n, loopback(0)))
+		requireErrorCode(t, socketErrorCodeInvalidArgument, tcpSocketSetKeepAliveCount(s, 0))
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		s := newSocket()
+		requireErrorCode(t, socketErrorCodeNotInProgress, tcpSocketFinishBind(s))
+		requireErrorCode(t, socketErrorCodeNotInProgress, tcpSocketFinishConnect(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, tcpSocketStartListen(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, tcpSocketAccept(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, tcpSocketLocalAddress(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, tcpSocketRemoteAddress(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, tcpSocketShutdown(s, shutdownTypeBoth))
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		// The port of a closed listener refuses the connections.
+		l := newSocket()
+		listen(t, l, n)
+		addr := requireOk(t, tcpSocketLocalAddress(l))
+		require.NoError(t, l.Rep.Close(testCtx))
+
+		s := newSocket()
+		requireOk(t, tcpSocketStartConnect(testCtx, s, n, addr))
+		pollableBlock(testCtx, borrow(tcpSocketSubscribe(s)))
+		requireErrorCode(t, socketErrorCodeConnectionRefused, tcpSocketFinishConnect(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, tcpSocketStartConnect(testCtx, s, n, addr))
+	})
+}
+
+func Test_tcpSocket_Options(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	s := cm.Borrow[*tcpSocket]{Rep: requireOk(t, createTCPSocket(testCtx, mod, ipAddressFamilyIPv6)).Rep}
+
+	require.Equal(t, ipAddressFamilyIPv6, tcpSocketAddressFamily(s))
+	require.False(t, requireOk(t, tcpSocketKeepAliveEnabled(s)))
+	requireOk(t, tcpSocketSetKeepAliveEnabled(s, true))
+	require.True(t, requireOk(t, tcpSocketKeepAliveEnabled(s)))
+	requireOk(t, tcpSocketSetKeepAliveIdleTime(s, 1_000_000_000))
+	require.Equal(t, uint64(1_000_000_000), requireOk(t, tcpSocketKeepAliveIdleTime(s)))
+	requireOk(t, tcpSocketSetKeepAliveInterval(s, 2_000_000_000))
+	require.Equal(t, uint64(2_000_000_000), requireOk(t, tcpSocketKeepAliveInterval(s)))
+	requireOk(t, tcpSocketSetKeepAliveCount(s, 3))
+	require.Equal(t, uint32(3), requireOk(t, tcpSocketKeepAliveCount(s)))
+	requireOk(t, tcpSocketSetHopLimit(s, 32))
+	require.Equal(t, uint8(32), requireOk(t, tcpSocketHopLimit(s)))
+	requireOk(t, tcpSocketSetReceiveBufferSize(s, 1024))
+	require.Equal(t, uint64(1024), requireOk(t, tcpSocketReceiveBufferSize(s)))
+	requireOk(t, tcpSocketSetSendBufferSize(s, 2048))
+	require.Equal(t, uint64(2048), requireOk(t, tcpSocketSendBufferSize(s)))
+	requireOk(t, tcpSocketSetListenBacklogSize(s, 1))
+	require.Equal(t, uint64(1), s.Rep.options.backlog)
+}
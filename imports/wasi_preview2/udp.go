@@ -0,0 +1,342 @@
+package wasi_preview2
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"net/netip"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+)
+
+// maxDatagramSize is the maximum size of a datagram received.
+const maxDatagramSize = math.MaxUint16
+
+// maxDatagrams is the maximum number of datagrams received and not yet returned, beyond which they are dropped, and
+// the number of datagrams permitted by check-send.
+const maxDatagrams = 64
+
+// socketsUDP is the interface wasi:sockets/udp, whose sockets are the UDP sockets of the net package.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/udp.wit
+var socketsUDP = newInterface("wasi:sockets/udp",
+	hostFunc{"[method]udp-socket.start-bind", udpSocketStartBind},
+	hostFunc{"[method]udp-socket.finish-bind", udpSocketFinishBind},
+	hostFunc{"[method]udp-socket.stream", udpSocketStream},
+	hostFunc{"[method]udp-socket.local-address", udpSocketLocalAddress},
+	hostFunc{"[method]udp-socket.remote-address", udpSocketRemoteAddress},
+	hostFunc{"[method]udp-socket.address-family", udpSocketAddressFamily},
+	hostFunc{"[method]udp-socket.unicast-hop-limit", udpSocketUnicastHopLimit},
+	hostFunc{"[method]udp-socket.set-unicast-hop-limit", udpSocketSetUnicastHopLimit},
+	hostFunc{"[method]udp-socket.receive-buffer-size", udpSocketReceiveBufferSize},
+	hostFunc{"[method]udp-socket.set-receive-buffer-size", udpSocketSetReceiveBufferSize},
+	hostFunc{"[method]udp-socket.send-buffer-size", udpSocketSendBufferSize},
+	hostFunc{"[method]udp-socket.set-send-buffer-size", udpSocketSetSendBufferSize},
+	hostFunc{"[method]udp-socket.subscribe", udpSocketSubscribe},
+	hostFunc{"[method]incoming-datagram-stream.receive", incomingDatagramStreamReceive},
+	hostFunc{"[method]incoming-datagram-stream.subscribe", incomingDatagramStreamSubscribe},
+	hostFunc{"[method]outgoing-datagram-stream.check-send", outgoingDatagramStreamCheckSend},
+	hostFunc{"[method]outgoing-datagram-stream.send", outgoingDatagramStreamSend},
+	hostFunc{"[method]outgoing-datagram-stream.subscribe", outgoingDatagramStreamSubscribe},
+)
+
+// socketsUDPCreateSocket is the interface wasi:sockets/udp-create-socket.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/wasip2/sockets/udp-create-socket.wit
+var socketsUDPCreateSocket = newInterface("wasi:sockets/udp-create-socket",
+	hostFunc{"create-udp-socket", createUDPSocket},
+)
+
+// datagram is a datagram received from the remote address.
+type datagram struct {
+	data   []byte
+	remote netip.AddrPort
+}
+
+// udpSocket is the representation of the resource udp-socket.
+type udpSocket struct {
+	sysCtx *internalsys.Context
+	family ipAddressFamily
+	// network is the network of the bind, which allows the remote addresses.
+	network *network
+	// local is the address of the bind, which is bound by finish-bind.
+	local       netip.AddrPort
+	bindStarted bool
+	conn        *net.UDPConn
+	received    *queue[datagram]
+	// remote is the remote address of the streams, or the zero value if they have none.
+	remote netip.AddrPort
+	// streams counts the calls of stream, which invalidate the streams of the previous one.
+	streams int
+	// hopLimit is only reported, as the net package can't set it.
+	hopLimit          uint8
+	receiveBufferSize uint64
+	sendBufferSize    uint64
+}
+
+// Close implements api.Closer
+func (s *udpSocket) Close(context.Context) error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// apply sets the buffer sizes of the socket, ignoring the errors as they are hints.
+func (s *udpSocket) apply() {
+	if s.conn != nil {
+		_ = s.conn.SetReadBuffer(int(min(s.receiveBufferSize, math.MaxInt32)))
+		_ = s.conn.SetWriteBuffer(int(min(s.sendBufferSize, math.MaxInt32)))
+	}
+}
+
+func createUDPSocket(_ context.Context, mod api.Module, family ipAddressFamily) cm.Result[cm.Own[*udpSocket], socketErrorCode] {
+	s := &udpSocket{sysCtx: sysCtx(mod), family: family, hopLimit: 64, receiveBufferSize: maxBufferSize, sendBufferSize: maxBufferSize}
+	return cm.Ok[cm.Own[*udpSocket], socketErrorCode](cm.Own[*udpSocket]{Rep: s})
+}
+
+func udpSocketStartBind(ctx context.Context, self cm.Borrow[*udpSocket], n cm.Borrow[*network], local ipSocketAddress) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	switch {
+	case s.bindStarted || s.conn != nil:
+		return cm.Err[struct{}](socketErrorCodeInvalidState)
+	case local.family() != s.family:
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	case !n.Rep.allow(ctx, SocketBind, "udp", local.addrPort().String()):
+		return cm.Err[struct{}](socketErrorCodeAccessDenied)
+	}
+	s.network, s.local, s.bindStarted = n.Rep, local.addrPort(), true
+	return socketOk()
+}
+
+func udpSocketFinishBind(self cm.Borrow[*udpSocket]) cm.Result[struct{}, socketErrorCode] {
+	s := self.Rep
+	if !s.bindStarted {
+		return cm.Err[struct{}](socketErrorCodeNotInProgress)
+	}
+	s.bindStarted = false
+	conn, err := net.ListenUDP(s.family.network("udp"), net.UDPAddrFromAddrPort(s.local))
+	if err != nil {
+		return cm.Err[struct{}](socketErrorCodeOf(err))
+	}
+	s.conn, s.received = conn, newQueue[datagram](maxDatagrams)
+	s.apply()
+	go func(received *queue[datagram]) {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, remote, err := conn.ReadFromUDPAddrPort(buf)
+			if err != nil {
+				received.end(err)
+				return
+			}
+			// The datagrams are dropped when too many are not yet returned, as by a full receive buffer.
+			_ = received.push(datagram{data: bytes.Clone(buf[:n]), remote: unmap(remote)})
+		}
+	}(s.received)
+	return socketOk()
+}
+
+// unmap returns the address with the IPv4-mapped IPv6 address unmapped.
+func unmap(ap netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+}
+
+// datagramStreams is the tuple<incoming-datagram-stream, outgoing-datagram-stream> of a UDP socket.
+type datagramStreams struct {
+	cm.Tuple
+	Incoming cm.Own[*incomingDatagramStream]
+	Outgoing cm.Own[*outgoingDatagramStream]
+}
+
+func udpSocketStream(ctx context.Context, self cm.Borrow[*udpSocket], remote cm.Option[ipSocketAddress]) cm.Result[datagramStreams, socketErrorCode] {
+	s := self.Rep
+	var ap netip.AddrPort
+	if s.conn == nil {
+		return cm.Err[datagramStreams](socketErrorCodeInvalidState)
+	} else if remote.Valid {
+		if !remote.Value.validRemote(s.family) {
+			return cm.Err[datagramStreams](socketErrorCodeInvalidArgument)
+		} else if ap = unmap(remote.Value.addrPort()); !s.network.allow(ctx, SocketConnect, "udp", ap.String()) {
+			return cm.Err[datagramStreams](socketErrorCodeAccessDenied)
+		}
+	}
+	s.remote = ap
+	s.streams++
+	return cm.Ok[datagramStreams, socketErrorCode](datagramStreams{
+		Incoming: cm.Own[*incomingDatagramStream]{Rep: &incomingDatagramStream{socket: s, stream: s.streams}},
+		Outgoing: cm.Own[*outgoingDatagramStream]{Rep: &outgoingDatagramStream{socket: s, stream: s.streams}},
+	})
+}
+
+func udpSocketLocalAddress(self cm.Borrow[*udpSocket]) cm.Result[ipSocketAddress, socketErrorCode] {
+	if conn := self.Rep.conn; conn != nil {
+		return cm.Ok[ipSocketAddress, socketErrorCode](ipSocketAddressOf(conn.LocalAddr()))
+	}
+	return cm.Err[ipSocketAddress](socketErrorCodeInvalidState)
+}
+
+func udpSocketRemoteAddress(self cm.Borrow[*udpSocket]) cm.Result[ipSocketAddress, socketErrorCode] {
+	if remote := self.Rep.remote; remote.IsValid() {
+		return cm.Ok[ipSocketAddress, socketErrorCode](ipSocketAddressOfAddrPort(remote))
+	}
+	return cm.Err[ipSocketAddress](socketErrorCodeInvalidState)
+}
+
+func udpSocketAddressFamily(self cm.Borrow[*udpSocket]) ipAddressFamily {
+	return self.Rep.family
+}
+
+func udpSocketUnicastHopLimit(self cm.Borrow[*udpSocket]) cm.Result[uint8, socketErrorCode] {
+	return cm.Ok[uint8, socketErrorCode](self.Rep.hopLimit)
+}
+
+func udpSocketSetUnicastHopLimit(self cm.Borrow[*udpSocket], value uint8) cm.Result[struct{}, socketErrorCode] {
+	if value == 0 {
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	}
+	self.Rep.hopLimit = value
+	return socketOk()
+}
+
+func udpSocketReceiveBufferSize(self cm.Borrow[*udpSocket]) cm.Result[uint64, socketErrorCode] {
+	return cm.Ok[uint64, socketErrorCode](self.Rep.receiveBufferSize)
+}
+
+func udpSocketSetReceiveBufferSize(self cm.Borrow[*udpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	if value == 0 {
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	}
+	self.Rep.receiveBufferSize = value
+	self.Rep.apply()
+	return socketOk()
+}
+
+func udpSocketSendBufferSize(self cm.Borrow[*udpSocket]) cm.Result[uint64, socketErrorCode] {
+	return cm.Ok[uint64, socketErrorCode](self.Rep.sendBufferSize)
+}
+
+func udpSocketSetSendBufferSize(self cm.Borrow[*udpSocket], value uint64) cm.Result[struct{}, socketErrorCode] {
+	if value == 0 {
+		return cm.Err[struct{}](socketErrorCodeInvalidArgument)
+	}
+	self.Rep.sendBufferSize = value
+	self.Rep.apply()
+	return socketOk()
+}
+
+// udpSocketSubscribe returns a pollable which is ready immediately, as the binds finish immediately.
+func udpSocketSubscribe(self cm.Borrow[*udpSocket]) cm.Own[*pollable] {
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: self.Rep.sysCtx}}
+}
+
+// incomingDatagram is the record incoming-datagram.
+type incomingDatagram struct {
+	Data          []byte
+	RemoteAddress ipSocketAddress
+}
+
+// incomingDatagramStream is the representation of the resource incoming-datagram-stream.
+type incomingDatagramStream struct {
+	socket *udpSocket
+	// stream is the count of calls of stream of the socket which returned the stream.
+	stream int
+}
+
+func incomingDatagramStreamReceive(self cm.Borrow[*incomingDatagramStream], maxResults uint64) cm.Result[[]incomingDatagram, socketErrorCode] {
+	s := self.Rep.socket
+	if self.Rep.stream != s.streams {
+		return cm.Err[[]incomingDatagram](socketErrorCodeInvalidState)
+	}
+	ret := []incomingDatagram{}
+	for uint64(len(ret)) < maxResults {
+		ds, err := s.received.pop(1)
+		if err != nil {
+			if len(ret) > 0 {
+				break
+			}
+			return cm.Err[[]incomingDatagram](socketErrorCodeOf(err))
+		} else if len(ds) == 0 {
+			break
+		}
+		// The datagrams of other remote addresses than the one of the streams are discarded.
+		if d := ds[0]; !s.remote.IsValid() || d.remote == s.remote {
+			ret = append(ret, incomingDatagram{Data: d.data, RemoteAddress: ipSocketAddressOfAddrPort(d.remote)})
+		}
+	}
+	return cm.Ok[[]incomingDatagram, socketErrorCode](ret)
+}
+
+func incomingDatagramStreamSubscribe(self cm.Borrow[*incomingDatagramStream]) cm.Own[*pollable] {
+	s := self.Rep.socket
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: s.sysCtx, waiter: s.received}}
+}
+
+// outgoingDatagram is the record outgoing-datagram.
+type outgoingDatagram struct {
+	Data          []byte
+	RemoteAddress cm.Option[ipSocketAddress]
+}
+
+// outgoingDatagramStream is the representation of the resource outgoing-datagram-stream, whose sends block.
+type outgoingDatagramStream struct {
+	socket *udpSocket
+	// stream is the count of calls of stream of the socket which returned the stream.
+	stream int
+}
+
+func outgoingDatagramStreamCheckSend(self cm.Borrow[*outgoingDatagramStream]) cm.Result[uint64, socketErrorCode] {
+	if self.Rep.stream != self.Rep.socket.streams {
+		return cm.Err[uint64](socketErrorCodeInvalidState)
+	}
+	return cm.Ok[uint64, socketErrorCode](maxDatagrams)
+}
+
+func outgoingDatagramStreamSend(ctx context.Context, self cm.Borrow[*outgoingDatagramStream], datagrams []outgoingDatagram) cm.Result[uint64, socketErrorCode] {
+	s := self.Rep.socket
+	if self.Rep.stream != s.streams {
+		return cm.Err[uint64](socketErrorCodeInvalidState)
+	}
+	var sent uint64
+	for _, d := range datagrams {
+		if code, ok := s.send(ctx, d); !ok {
+			// The datagrams sent before the one which failed are reported instead of its error.
+			if sent > 0 {
+				break
+			}
+			return cm.Err[uint64](code)
+		}
+		sent++
+	}
+	return cm.Ok[uint64, socketErrorCode](sent)
+}
+
+// send sends the datagram to its remote address, which is the one of the streams if it has none.
+func (s *udpSocket) send(ctx context.Context, d outgoingDatagram) (socketErrorCode, bool) {
+	remote := s.remote
+	if d.RemoteAddress.Valid {
+		ap := unmap(d.RemoteAddress.Value.addrPort())
+		switch {
+		case !d.RemoteAddress.Value.validRemote(s.family):
+			return socketErrorCodeInvalidArgument, false
+		case s.remote.IsValid() && ap != s.remote:
+			return socketErrorCodeInvalidArgument, false
+		case !s.remote.IsValid() && !s.network.allow(ctx, SocketConnect, "udp", ap.String()):
+			return socketErrorCodeAccessDenied, false
+		}
+		remote = ap
+	} else if !remote.IsValid() {
+		return socketErrorCodeInvalidArgument, false
+	}
+	if _, err := s.conn.WriteToUDPAddrPort(d.Data, remote); err != nil {
+		return socketErrorCodeOf(err), false
+	}
+	return 0, true
+}
+
+// outgoingDatagramStreamSubscribe returns a pollable which is ready immediately, as the sends block.
+func outgoingDatagramStreamSubscribe(self cm.Borrow[*outgoingDatagramStream]) cm.Own[*pollable] {
+	return cm.Own[*pollable]{Rep: &pollable{sysCtx: self.Rep.socket.sysCtx}}
+}
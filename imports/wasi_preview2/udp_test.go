@@ -0,0 +1,140 @@
+package wasi_preview2
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// bindUDP returns a UDP socket bound to an ephemeral port of the loopback address.
+func bindUDP(t *testing.T, mod api.Module, n cm.Borrow[*network]) cm.Borrow[*udpSocket] {
+	t.Helper()
+	s := cm.Borrow[*udpSocket]{Rep: requireOk(t, createUDPSocket(testCtx, mod, ipAddressFamilyIPv4)).Rep}
+	t.Cleanup(func() { _ = s.Rep.Close(testCtx) })
+	requireOk(t, udpSocketStartBind(testCtx, s, n, loopback(0)))
+	requireOk(t, udpSocketFinishBind(s))
+	return s
+}
+
+// receive blocks until the stream receives a datagram, and returns the datagrams received.
+func receive(t *testing.T, s cm.Borrow[*incomingDatagramStream]) []incomingDatagram {
+	t.Helper()
+	pollableBlock(testCtx, borrow(incomingDatagramStreamSubscribe(s)))
+	return requireOk(t, incomingDatagramStreamReceive(s, 10))
+}
+
+func Test_udpSocket(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var checked []operation
+	n := cm.Borrow[*network]{Rep: &network{policy: recordingPolicy(&checked)}}
+
+	a, b := bindUDP(t, mod, n), bindUDP(t, mod, n)
+	aAddr, bAddr := requireOk(t, udpSocketLocalAddress(a)), requireOk(t, udpSocketLocalAddress(b))
+
+	// The streams of a have no remote address, so the datagrams sent have theirs.
+	aStreams := requireOk(t, udpSocketStream(testCtx, a, cm.None[ipSocketAddress]()))
+	requireErrorCode(t, socketErrorCodeInvalidState, udpSocketRemoteAddress(a))
+	// The streams of b have the remote address of a.
+	bStreams := requireOk(t, udpSocketStream(testCtx, b, cm.Some(aAddr)))
+	require.Equal(t, aAddr, requireOk(t, udpSocketRemoteAddress(b)))
+
+	aOut := borrow(aStreams.Outgoing)
+	require.Equal(t, uint64(maxDatagrams), requireOk(t, outgoingDatagramStreamCheckSend(aOut)))
+	sent := requireOk(t, outgoingDatagramStreamSend(testCtx, aOut, []outgoingDatagram{
+		{Data: []byte("hello"), RemoteAddress: cm.Some(bAddr)},
+		{Data: []byte("world"), RemoteAddress: cm.Some(bAddr)},
+	}))
+	require.Equal(t, uint64(2), sent)
+
+	var received []incomingDatagram
+	for len(received) < 2 {
+		received = append(received, receive(t, borrow(bStreams.Incoming))...)
+	}
+	require.Equal(t, []incomingDatagram{
+		{Data: []byte("hello"), RemoteAddress: aAddr},
+		{Data: []byte("world"), RemoteAddress: aAddr},
+	}, received)
+
+	// The datagrams sent by b go to a without a remote address.
+	bOut := borrow(bStreams.Outgoing)
+	require.Equal(t, uint64(1), requireOk(t, outgoingDatagramStreamSend(testCtx, bOut, []outgoingDatagram{{Data: []byte("back")}})))
+	require.Equal(t, []incomingDatagram{{Data: []byte("back"), RemoteAddress: bAddr}}, receive(t, borrow(aStreams.Incoming)))
+
+	require.Equal(t, []operation{
+		{SocketBind, "udp", "127.0.0.1:0"},
+		{SocketBind, "udp", "127.0.0.1:0"},
+		{SocketConnect, "udp", aAddr.addrPort().String()},
+		{SocketConnect, "udp", bAddr.addrPort().String()},
+		{SocketConnect, "udp", bAddr.addrPort().String()},
+	}, checked)
+}
+
+func Test_udpSocket_RemoteAddress(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var checked []operation
+	n := cm.Borrow[*network]{Rep: &network{policy: recordingPolicy(&checked)}}
+
+	a, b, c := bindUDP(t, mod, n), bindUDP(t, mod, n), bindUDP(t, mod, n)
+	aAddr, cAddr := requireOk(t, udpSocketLocalAddress(a)), requireOk(t, udpSocketLocalAddress(c))
+	aStreams := requireOk(t, udpSocketStream(testCtx, a, cm.Some(cAddr)))
+
+	// The datagrams of other remote addresses than the one of the streams are discarded.
+	for _, s := range []cm.Borrow[*udpSocket]{b, c} {
+		out := borrow(requireOk(t, udpSocketStream(testCtx, s, cm.Some(aAddr))).Outgoing)
+		requireOk(t, outgoingDatagramStreamSend(testCtx, out, []outgoingDatagram{{Data: []byte("hello")}}))
+	}
+	var received []incomingDatagram
+	for deadline := time.Now().Add(time.Second); len(received) == 0 && time.Now().Before(deadline); {
+		received = receive(t, borrow(aStreams.Incoming))
+	}
+	require.Equal(t, []incomingDatagram{{Data: []byte("hello"), RemoteAddress: cAddr}}, received)
+
+	// The datagrams can't be sent to other remote addresses either.
+	out := borrow(aStreams.Outgoing)
+	requireErrorCode(t, socketErrorCodeInvalidArgument, outgoingDatagramStreamSend(testCtx, out,
+		[]outgoingDatagram{{Data: []byte("hello"), RemoteAddress: cm.Some(aAddr)}}))
+
+	// A new stream invalidates the previous ones.
+	requireOk(t, udpSocketStream(testCtx, a, cm.None[ipSocketAddress]()))
+	requireErrorCode(t, socketErrorCodeInvalidState, outgoingDatagramStreamCheckSend(out))
+	requireErrorCode(t, socketErrorCodeInvalidState, incomingDatagramStreamReceive(borrow(aStreams.Incoming), 1))
+}
+
+func Test_udpSocket_Errors(t *testing.T) {
+	mod := newModule(t, internalsys.DefaultContext(nil))
+	var checked []operation
+	n := cm.Borrow[*network]{Rep: &network{policy: recordingPolicy(&checked)}}
+	remote := ipSocketAddressOfAddrPort(netip.MustParseAddrPort("192.0.2.1:53"))
+
+	t.Run("denied", func(t *testing.T) {
+		s := cm.Borrow[*udpSocket]{Rep: requireOk(t, createUDPSocket(testCtx, mod, ipAddressFamilyIPv4)).Rep}
+		requireErrorCode(t, socketErrorCodeAccessDenied, udpSocketStartBind(testCtx, s, n, remote))
+
+		s = bindUDP(t, mod, n)
+		requireErrorCode(t, socketErrorCodeAccessDenied, udpSocketStream(testCtx, s, cm.Some(remote)))
+		out := borrow(requireOk(t, udpSocketStream(testCtx, s, cm.None[ipSocketAddress]())).Outgoing)
+		requireErrorCode(t, socketErrorCodeAccessDenied, outgoingDatagramStreamSend(testCtx, out,
+			[]outgoingDatagram{{Data: []byte("hello"), RemoteAddress: cm.Some(remote)}}))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		s := cm.Borrow[*udpSocket]{Rep: requireOk(t, createUDPSocket(testCtx, mod, ipAddressFamilyIPv4)).Rep}
+		requireErrorCode(t, socketErrorCodeNotInProgress, udpSocketFinishBind(s))
+		requireErrorCode(t, socketErrorCodeInvalidState, udpSocketStream(testCtx, s, cm.None[ipSocketAddress]()))
+		requireErrorCode(t, socketErrorCodeInvalidState, udpSocketLocalAddress(s))
+		requireErrorCode(t, socketErrorCodeInvalidArgument, udpSocketSetUnicastHopLimit(s, 0))
+
+		s = bindUDP(t, mod, n)
+		requireErrorCode(t, socketErrorCodeInvalidState, udpSocketStartBind(testCtx, s, n, loopback(0)))
+		requireErrorCode(t, socketErrorCodeInvalidArgument, udpSocketStream(testCtx, s, cm.Some(loopback(0))))
+		// A datagram needs a remote address without one of the streams.
+		out := borrow(requireOk(t, udpSocketStream(testCtx, s, cm.None[ipSocketAddress]())).Outgoing)
+		requireErrorCode(t, socketErrorCodeInvalidArgument, outgoingDatagramStreamSend(testCtx, out,
+			[]outgoingDatagram{{Data: []byte("hello")}}))
+	})
+}
@@ -0,0 +1,251 @@
+// Package wasi_preview2 contains Go-defined functions of the interfaces of
+// WASI preview 2, which are imported by components of the component model,
+// e.g. the ones built for the Rust target wasm32-wasip2.
+//
+// The interfaces implemented are wasi:cli, wasi:clocks, wasi:filesystem,
+// wasi:random, wasi:sockets, wasi:http, and the streams and polling of
+// wasi:io, whose functions are exported by a host module of the name of each
+// interface, e.g. "wasi:io/streams@0.2.0".
+//
+// e.g. Call Instantiate before instantiating any component that imports them,
+// otherwise, it will error due to missing imports.
+//
+//	ctx := context.Background()
+//	r := wazero.NewRuntime(ctx)
+//	defer r.Close(ctx) // This closes everything this Runtime created.
+//
+//	wasi_preview2.MustInstantiate(ctx, r)
+//	mod, _ := r.InstantiateWithConfig(ctx, component, wazero.NewModuleConfig().WithStdout(os.Stdout))
+//	err := wasi_preview2.Run(ctx, mod)
+//
+// The arguments, the environment variables, the clocks, the random source,
+// the standard I/O and the pre-opened directories are the ones of the
+// wazero.ModuleConfig of the component, as for wasi_snapshot_preview1. The
+// sockets are the ones of the net package, whose operations are allowed by
+// the SocketPolicy of the Builder. The outgoing requests go through the
+// http.RoundTripper of the Builder, e.g. http.DefaultTransport, and the
+// incoming requests of a proxy component are served by the http.Handler
+// returned by NewIncomingHandler.
+//
+// See https://github.com/WebAssembly/WASI/tree/main/wasip2
+package wasi_preview2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Version is the version of the interfaces, which is the one of the names of
+// their host modules.
+const Version = "0.2.0"
+
+// RunName is the name of the function exported by a command component, which
+// is the run function of its wasi:cli/run interface.
+const RunName = "wasi:cli/run@" + Version + "#run"
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the host modules are not
+// already instantiated, and don't need to unload them.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the host modules of the interfaces into the
+// runtime, and returns a closer of all of them.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+//   - The operations of wasi:sockets and the requests of wasi:http are all
+//     denied. Use NewBuilder to allow some with WithSocketPolicy, or to send
+//     them with WithRoundTripper.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	return NewBuilder(r).Instantiate(ctx)
+}
+
+// Builder configures the host modules of the interfaces for later use via
+// Instantiate.
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+type Builder interface {
+	// WithSocketPolicy sets the policy which allows or denies the binds, the
+	// connections and the lookups of wasi:sockets. The default is to deny all
+	// of them.
+	//
+	// e.g. A test can allow the connections to a loopback stand-in of a
+	// remote server only.
+	WithSocketPolicy(SocketPolicy) Builder
+
+	// WithRoundTripper sets the round tripper of the requests of
+	// wasi:http/outgoing-handler. The default is nil, which denies all of
+	// them. WithRoundTripper of a context overrides it for the calls with it.
+	//
+	// e.g. A test can send the requests to a httptest.Server, or mock their
+	// responses, without any network.
+	WithRoundTripper(http.RoundTripper) Builder
+
+	// Instantiate instantiates the host modules of the interfaces and returns
+	// a closer of all of them.
+	//
+	// Note: This has the same effect as the same function on wazero.HostModuleBuilder.
+	Instantiate(context.Context) (api.Closer, error)
+}
+
+// NewBuilder returns a new Builder.
+func NewBuilder(r wazero.Runtime) Builder {
+	return &builder{r: r}
+}
+
+type builder struct {
+	r            wazero.Runtime
+	socketPolicy SocketPolicy
+	roundTripper http.RoundTripper
+}
+
+// WithSocketPolicy implements Builder.WithSocketPolicy
+func (b *builder) WithSocketPolicy(policy SocketPolicy) Builder {
+	ret := *b // copy
+	ret.socketPolicy = policy
+	return &ret
+}
+
+// WithRoundTripper implements Builder.WithRoundTripper
+func (b *builder) WithRoundTripper(rt http.RoundTripper) Builder {
+	ret := *b // copy
+	ret.roundTripper = rt
+	return &ret
+}
+
+// Instantiate implements Builder.Instantiate
+func (b *builder) Instantiate(ctx context.Context) (api.Closer, error) {
+	var ret modules
+	for _, i := range b.interfaces() {
+		hb := b.r.NewHostModuleBuilder(i.name)
+		i.export(hb)
+		m, err := hb.Instantiate(ctx)
+		if err != nil {
+			_ = ret.Close(ctx)
+			return nil, err
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+// interfaces returns the interfaces instantiated, which are ordered by their
+// dependencies.
+func (b *builder) interfaces() []*hostInterface {
+	return []*hostInterface{
+		ioError,
+		ioPoll,
+		ioStreams,
+		clocksMonotonicClock,
+		clocksWallClock,
+		filesystemTypes,
+		filesystemPreopens,
+		socketsNetwork,
+		socketsInstanceNetwork(b.socketPolicy),
+		socketsTCP,
+		socketsTCPCreateSocket,
+		socketsUDP,
+		socketsUDPCreateSocket,
+		socketsIPNameLookup,
+		httpTypes,
+		httpOutgoingHandler(b.roundTripper),
+		randomRandom,
+		randomInsecure,
+		randomInsecureSeed,
+		cliEnvironment,
+		cliExit,
+		cliStdin,
+		cliStdout,
+		cliStderr,
+		cliTerminalInput,
+		cliTerminalOutput,
+		cliTerminalStdin,
+		cliTerminalStdout,
+		cliTerminalStderr,
+	}
+}
+
+// Run calls the function RunName of the command component mod.
+//
+// The result is nil if it succeeds or exits with the code zero. Otherwise, it
+// is a sys.ExitError with the exit code, which is one if the function returns
+// an error.
+func Run(ctx context.Context, mod api.Module) error {
+	fn := mod.ExportedFunction(RunName)
+	if fn == nil {
+		return fmt.Errorf("%s doesn't export %s", mod.Name(), RunName)
+	}
+	var res cm.Result[struct{}, struct{}]
+	err := cm.Call(ctx, fn, &res)
+	var exitErr *sys.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 0:
+		return nil
+	case err != nil:
+		return err
+	case res.IsErr:
+		return sys.NewExitError(1)
+	}
+	return nil
+}
+
+// modules are the host modules of the interfaces, which are closed together.
+type modules []api.Module
+
+// Close implements api.Closer
+func (ms modules) Close(ctx context.Context) (err error) {
+	for i := len(ms) - 1; i >= 0; i-- {
+		if e := ms[i].Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// hostInterface is an interface of WASI, whose functions are exported by the
+// host module of its name.
+type hostInterface struct {
+	name  string
+	funcs []hostFunc
+}
+
+// hostFunc is a function of an interface, whose Go func is defined with
+// wazero.HostFunctionBuilder WithComponentFunc.
+type hostFunc struct {
+	name string
+	fn   interface{}
+}
+
+func newInterface(name string, funcs ...hostFunc) *hostInterface {
+	return &hostInterface{name: name + "@" + Version, funcs: funcs}
+}
+
+func (i *hostInterface) export(b wazero.HostModuleBuilder) {
+	for _, f := range i.funcs {
+		b.NewFunctionBuilder().WithComponentFunc(f.fn).Export(f.name)
+	}
+}
+
+// sysCtx returns the system context of the module which calls a function.
+func sysCtx(mod api.Module) *internalsys.Context {
+	return mod.(*wasm.ModuleInstance).Sys
+}
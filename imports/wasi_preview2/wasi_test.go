@@ -0,0 +1,307 @@
+package wasi_preview2
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+const i32 = wasm.ValueTypeI32
+
+// echoAllocModule is the core module of echoBinary which defines the memory and cabi_realloc.
+var echoAllocModule = &wasm.Module{
+	TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}}},
+	FunctionSection: []wasm.Index{0},
+	MemorySection:   &wasm.Memory{Min: 1},
+	GlobalSection: []wasm.Global{{
+		Type: wasm.GlobalType{ValType: i32, Mutable: true},
+		Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x80, 0x08}}, // 1024
+	}},
+	CodeSection: []wasm.Code{{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+		// ptr = (heap + align - 1) & -align
+		wasm.OpcodeGlobalGet, 0,
+		wasm.OpcodeLocalGet, 2,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeI32Const, 1,
+		wasm.OpcodeI32Sub,
+		wasm.OpcodeI32Const, 0,
+		wasm.OpcodeLocalGet, 2,
+		wasm.OpcodeI32Sub,
+		wasm.OpcodeI32And,
+		wasm.OpcodeLocalSet, 4,
+		// heap = ptr + size
+		wasm.OpcodeLocalGet, 4,
+		wasm.OpcodeLocalGet, 3,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeGlobalSet, 0,
+		wasm.OpcodeLocalGet, 4,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "cabi_realloc", Type: wasm.ExternTypeFunc, Index: 0},
+	},
+}
+
+// echoGuestModule is the core module of echoBinary, whose run writes the second argument to the standard output, and
+// returns the discriminant of the result of the write.
+var echoGuestModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32, i32, i32, i32}},
+		{Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32}},
+	},
+	ImportSection: []wasm.Import{
+		{Module: "host", Name: "blocking-write-and-flush", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		{Module: "host", Name: "get-stdout", Type: wasm.ExternTypeFunc, DescFunc: 1},
+		{Module: "host", Name: "get-arguments", Type: wasm.ExternTypeFunc, DescFunc: 2},
+		{Module: "host", Name: "drop", Type: wasm.ExternTypeFunc, DescFunc: 2},
+		{Module: "alloc", Name: "memory", Type: wasm.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1}},
+	},
+	FunctionSection: []wasm.Index{1},
+	CodeSection: []wasm.Code{{LocalTypes: []wasm.ValueType{i32, i32}, Body: []byte{
+		// The list of the arguments is at 256.
+		wasm.OpcodeI32Const, 0x80, 0x02,
+		wasm.OpcodeCall, 2,
+		wasm.OpcodeCall, 1,
+		wasm.OpcodeLocalSet, 0,
+		wasm.OpcodeI32Const, 0x80, 0x02,
+		wasm.OpcodeI32Load, 2, 0,
+		wasm.OpcodeLocalSet, 1,
+		// The result of the write of the pointer and the length of the second argument is at 272.
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeI32Load, 2, 8,
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeI32Load, 2, 12,
+		wasm.OpcodeI32Const, 0x90, 0x02,
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeCall, 3,
+		wasm.OpcodeI32Const, 0x90, 0x02,
+		wasm.OpcodeI32Load8U, 0, 0,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 4}},
+}
+
+// echoBinary is a command component, which imports the interfaces wasi:io/streams, wasi:cli/stdout and
+// wasi:cli/environment, and exports the interface wasi:cli/run whose run writes its second argument to the standard
+// output.
+var echoBinary = func() []byte {
+	name := binaryencoding.ComponentName
+	vec := binaryencoding.ComponentVec
+	cat := func(bs ...[]byte) (ret []byte) {
+		for _, b := range bs {
+			ret = append(ret, b...)
+		}
+		return
+	}
+	const u8, str = 0x7d, 0x73
+
+	return binaryencoding.EncodeComponent(
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			// type 0: (instance
+			//   (export "error" (type (sub resource))) (export "output-stream" (type (sub resource)))
+			//   (type (own 0)) (type (variant (case "last-operation-failed" 2) (case "closed")))
+			//   (type (borrow 1)) (type (list u8)) (type (result (error 3)))
+			//   (type (func (param "self" 4) (param "contents" 5) (result 6)))
+			//   (export "[method]output-stream.blocking-write-and-flush" (func (type 7))))
+			cat([]byte{0x42}, vec(
+				cat([]byte{0x04, 0x00}, name("error"), []byte{0x03, 0x01}),
+				cat([]byte{0x04, 0x00}, name("output-stream"), []byte{0x03, 0x01}),
+				[]byte{0x01, 0x69, 0},
+				cat([]byte{0x01, 0x71}, vec(
+					cat(name("last-operation-failed"), []byte{0x01, 2, 0x00}),
+					cat(name("closed"), []byte{0x00, 0x00}),
+				)),
+				[]byte{0x01, 0x68, 1},
+				[]byte{0x01, 0x70, u8},
+				[]byte{0x01, 0x6a, 0x00, 0x01, 3},
+				cat([]byte{0x01, 0x40}, vec(cat(name("self"), []byte{4}), cat(name("contents"), []byte{5})), []byte{0x00, 6}),
+				cat([]byte{0x04, 0x00}, name("[method]output-stream.blocking-write-and-flush"), []byte{0x01, 7}),
+			))),
+		// instance 0: (import "wasi:io/streams@0.2.0" (instance (type 0)))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDImport,
+			cat([]byte{0x00}, name("wasi:io/streams@0.2.0"), []byte{0x05, 0})),
+		// type 1: (alias export 0 "output-stream")
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			cat([]byte{0x03, 0x00, 0}, name("output-stream"))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			// type 2: (instance
+			//   (alias outer 1 1 (type)) (export "output-stream" (type (eq 0)))
+			//   (type (own 1)) (type (func (result 2))) (export "get-stdout" (func (type 3))))
+			cat([]byte{0x42}, vec(
+				[]byte{0x02, 0x03, 0x02, 1, 1},
+				cat([]byte{0x04, 0x00}, name("output-stream"), []byte{0x03, 0x00, 0}),
+				[]byte{0x01, 0x69, 1},
+				cat([]byte{0x01, 0x40}, vec(), []byte{0x00, 2}),
+				cat([]byte{0x04, 0x00}, name("get-stdout"), []byte{0x01, 3}),
+			)),
+			// type 3: (instance
+			//   (type (list string)) (type (func (result 0))) (export "get-arguments" (func (type 1))))
+			cat([]byte{0x42}, vec(
+				[]byte{0x01, 0x70, str},
+				cat([]byte{0x01, 0x40}, vec(), []byte{0x00, 0}),
+				cat([]byte{0x04, 0x00}, name("get-arguments"), []byte{0x01, 1}),
+			))),
+		// instance 1 and 2: (import "wasi:cli/stdout@0.2.0" (instance (type 2))) and
+		// (import "wasi:cli/environment@0.2.0" (instance (type 3)))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDImport,
+			cat([]byte{0x00}, name("wasi:cli/stdout@0.2.0"), []byte{0x05, 2}),
+			cat([]byte{0x00}, name("wasi:cli/environment@0.2.0"), []byte{0x05, 3})),
+		// func 0, 1 and 2: (alias export 0 "[method]output-stream.blocking-write-and-flush"),
+		// (alias export 1 "get-stdout") and (alias export 2 "get-arguments")
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			cat([]byte{0x01, 0x00, 0}, name("[method]output-stream.blocking-write-and-flush")),
+			cat([]byte{0x01, 0x00, 1}, name("get-stdout")),
+			cat([]byte{0x01, 0x00, 2}, name("get-arguments"))),
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDCoreModule,
+			binaryencoding.EncodeModule(echoAllocModule)),
+		// core instance 0: (instantiate 0)
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance, []byte{0x00, 0, 0}),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			// core func 0: (alias core export 0 "cabi_realloc")
+			cat([]byte{0x00, 0x00, 0x01, 0}, name("cabi_realloc")),
+			// core memory 0: (alias core export 0 "memory")
+			cat([]byte{0x00, 0x02, 0x01, 0}, name("memory"))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon,
+			// core func 1: (canon lower (func 0) (memory 0))
+			cat([]byte{0x01, 0x00, 0}, vec([]byte{0x03, 0})),
+			// core func 2: (canon lower (func 1))
+			[]byte{0x01, 0x00, 1, 0},
+			// core func 3: (canon lower (func 2) (memory 0) (realloc 0))
+			cat([]byte{0x01, 0x00, 2}, vec([]byte{0x03, 0}, []byte{0x04, 0})),
+			// core func 4: (canon resource.drop 1)
+			[]byte{0x03, 1}),
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDCoreModule,
+			binaryencoding.EncodeModule(echoGuestModule)),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance,
+			// core instance 1: (instance (export "blocking-write-and-flush" (func 1)) ...)
+			cat([]byte{0x01}, vec(
+				cat(name("blocking-write-and-flush"), []byte{0x00, 1}),
+				cat(name("get-stdout"), []byte{0x00, 2}),
+				cat(name("get-arguments"), []byte{0x00, 3}),
+				cat(name("drop"), []byte{0x00, 4}),
+			)),
+			// core instance 2: (instance (export "memory" (memory 0)))
+			cat([]byte{0x01}, vec(cat(name("memory"), []byte{0x02, 0}))),
+			// core instance 3: (instantiate 1 (with "host" (instance 1)) (with "alloc" (instance 2)))
+			cat([]byte{0x00, 1}, vec(cat(name("host"), []byte{0x12, 1}), cat(name("alloc"), []byte{0x12, 2})))),
+		// core func 5: (alias core export 3 "run")
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			cat([]byte{0x00, 0x00, 0x01, 3}, name("run"))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			// type 4: (result)
+			[]byte{0x6a, 0x00, 0x00},
+			// type 5: (func (result 4))
+			cat([]byte{0x40}, vec(), []byte{0x00, 4})),
+		// func 3: (canon lift (core func 5) (type 5))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon,
+			[]byte{0x00, 0x00, 5, 0, 5}),
+		// instance 3: (instance (export "run" (func 3)))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDInstance,
+			cat([]byte{0x01}, vec(cat([]byte{0x00}, name("run"), []byte{0x01, 3})))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDExport,
+			cat([]byte{0x00}, name("wasi:cli/run@0.2.0"), []byte{0x05, 3, 0x00})),
+	)
+}()
+
+func TestRun(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	MustInstantiate(testCtx, r)
+
+	var stdout bytes.Buffer
+	mod, err := r.InstantiateWithConfig(testCtx, echoBinary,
+		wazero.NewModuleConfig().WithArgs("echo", "hello").WithStdout(&stdout))
+	require.NoError(t, err)
+
+	require.NoError(t, Run(testCtx, mod))
+	require.Equal(t, "hello", stdout.String())
+}
+
+func TestRun_NotCommand(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	mod, err := r.InstantiateWithConfig(testCtx, binaryencoding.EncodeModule(&wasm.Module{}),
+		wazero.NewModuleConfig().WithName("guest"))
+	require.NoError(t, err)
+
+	err = Run(testCtx, mod)
+	require.EqualError(t, err, "guest doesn't export wasi:cli/run@0.2.0#run")
+}
+
+func TestInstantiate(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	closer, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+	interfaces := (&builder{}).interfaces()
+	for _, i := range interfaces {
+		require.NotNil(t, r.Module(i.name), i.name)
+	}
+
+	// Instantiating again fails, as the host modules already exist.
+	_, err = Instantiate(testCtx, r)
+	require.EqualError(t, err, "module[wasi:io/error@0.2.0] has already been instantiated")
+
+	// Closing the host modules allows instantiating them again.
+	require.NoError(t, closer.Close(testCtx))
+	for _, i := range interfaces {
+		require.Nil(t, r.Module(i.name), i.name)
+	}
+	_, err = Instantiate(testCtx, r)
+	require.NoError(t, err)
+}
+
+func TestBuilder_WithSocketPolicy(t *testing.T) {
+	b := NewBuilder(nil)
+	allowed := b.WithSocketPolicy(func(context.Context, SocketOperation, string, string) bool { return true })
+
+	// The builder is copied, so the policy of the original is unchanged.
+	require.Nil(t, b.(*builder).socketPolicy)
+	require.NotNil(t, allowed.(*builder).socketPolicy)
+}
+
+func TestBuilder_WithRoundTripper(t *testing.T) {
+	b := NewBuilder(nil)
+	withRT := b.WithRoundTripper(http.DefaultTransport)
+
+	// The builder is copied, so the round tripper of the original is unchanged.
+	require.Nil(t, b.(*builder).roundTripper)
+	require.Equal(t, http.DefaultTransport, withRT.(*builder).roundTripper)
+}
+
+// newModule returns a module calling the functions, whose system context is the one of the args.
+func newModule(t *testing.T, sysCtx *internalsys.Context) api.Module {
+	t.Helper()
+	return &wasm.ModuleInstance{ModuleName: t.Name(), Sys: sysCtx}
+}
+
+// requireExit requires fn to panic with the sys.ExitError of the exit code.
+func requireExit(t *testing.T, exitCode uint32, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		err, ok := recover().(error)
+		require.True(t, ok)
+		require.Equal(t, sys.NewExitError(exitCode), err)
+	}()
+	fn()
+}
@@ -35,7 +35,7 @@ func fdAdviseFn(_ context.Context, mod api.Module, params []uint64) experimental
 	advice := byte(params[3])
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
-	_, ok := fsc.LookupFile(fd)
+	_, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_ADVISE)
 	if !ok {
 		return experimentalsys.EBADF
 	}
@@ -77,7 +77,7 @@ func fdAllocateFn(_ context.Context, mod api.Module, params []uint64) experiment
 	length := params[2]
 
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-	f, ok := fsc.LookupFile(fd)
+	f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_ALLOCATE)
 	if !ok {
 		return experimentalsys.EBADF
 	}
@@ -135,7 +135,7 @@ func fdDatasyncFn(_ context.Context, mod api.Module, params []uint64) experiment
 	fd := int32(params[0])
 
 	// Check to see if the file descriptor is available
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_DATASYNC); !ok {
 		return experimentalsys.EBADF
 	} else {
 		return f.File.Datasync()
@@ -160,8 +160,9 @@ func fdDatasyncFn(_ context.Context, mod api.Module, params []uint64) experiment
 //   - fs_filetype 1 byte: the file type
 //   - fs_flags 2 bytes: the file descriptor flag
 //   - 5 pad bytes
-//   - fs_right_base 8 bytes: ignored as rights were removed from WASI.
-//   - fs_right_inheriting 8 bytes: ignored as rights were removed from WASI.
+//   - fs_right_base 8 bytes: the rights of the file descriptor
+//   - fs_right_inheriting 8 bytes: the most rights of file descriptors opened
+//     relative to this one, via path_open
 //
 // For example, with a file corresponding with `fd` was a directory (=3) opened
 // with `fd_read` right (=1) and no fs_flags (=0), parameter resultFdstat=1,
@@ -210,8 +211,8 @@ func fdFdstatGetFn(_ context.Context, mod api.Module, params []uint64) experimen
 		fdflags |= wasip1.FD_NONBLOCK
 	}
 
-	var fsRightsBase uint32
-	var fsRightsInheriting uint32
+	var fsRightsBase uint64
+	var fsRightsInheriting uint64
 	fileType := getExtendedWasiFiletype(f.File, st.Mode)
 
 	switch fileType {
@@ -229,6 +230,12 @@ func fdFdstatGetFn(_ context.Context, mod api.Module, params []uint64) experimen
 		fsRightsBase = fileRightsBase
 	}
 
+	// Report only the rights the file descriptor has, if restricted.
+	if r := f.Rights; r != nil {
+		fsRightsBase &= r.Base
+		fsRightsInheriting &= r.Inheriting
+	}
+
 	writeFdstat(buf, fileType, fdflags, fsRightsBase, fsRightsInheriting)
 	return 0
 }
@@ -285,13 +292,13 @@ const dirRightsBase = wasip1.RIGHT_FD_DATASYNC |
 	wasip1.RIGHT_PATH_REMOVE_DIRECTORY |
 	wasip1.RIGHT_PATH_UNLINK_FILE
 
-func writeFdstat(buf []byte, fileType uint8, fdflags uint16, fsRightsBase, fsRightsInheriting uint32) {
+func writeFdstat(buf []byte, fileType uint8, fdflags uint16, fsRightsBase, fsRightsInheriting uint64) {
 	b := (*[24]byte)(buf)
 	le.PutUint16(b[0:], uint16(fileType))
 	le.PutUint16(b[2:], fdflags)
 	le.PutUint32(b[4:], 0)
-	le.PutUint64(b[8:], uint64(fsRightsBase))
-	le.PutUint64(b[16:], uint64(fsRightsInheriting))
+	le.PutUint64(b[8:], fsRightsBase)
+	le.PutUint64(b[16:], fsRightsInheriting)
 }
 
 // fdFdstatSetFlags is the WASI function named FdFdstatSetFlagsName which
@@ -307,7 +314,7 @@ func fdFdstatSetFlagsFn(_ context.Context, mod api.Module, params []uint64) expe
 		return experimentalsys.EINVAL
 	}
 
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FDSTAT_SET_FLAGS); !ok {
 		return experimentalsys.EBADF
 	} else {
 		nonblock := wasip1.FD_NONBLOCK&wasiFlag != 0
@@ -318,6 +325,9 @@ func fdFdstatSetFlagsFn(_ context.Context, mod api.Module, params []uint64) expe
 		if stat, err := f.File.Stat(); err == 0 && stat.Mode.IsRegular() {
 			// For normal files, proceed to apply an append flag.
 			append := wasip1.FD_APPEND&wasiFlag != 0
+			if !append && f.File.IsAppend() && isAppendOnly(f.Rights) {
+				return experimentalsys.EPERM
+			}
 			return f.File.SetAppend(append)
 		}
 	}
@@ -325,15 +335,67 @@ func fdFdstatSetFlagsFn(_ context.Context, mod api.Module, params []uint64) expe
 	return 0
 }
 
-// fdFdstatSetRights will not be implemented as rights were removed from WASI.
+// fdFdstatSetRights is the WASI function named FdFdstatSetRightsName which
+// removes rights from a file descriptor.
+//
+// # Parameters
+//
+//   - fd: file descriptor to adjust the rights of
+//   - fsRightsBase: the rights of the file descriptor
+//   - fsRightsInheriting: the most rights of file descriptors opened relative
+//     to this one, via path_open
+//
+// Result (Errno)
+//
+// The return value is 0 except the following error conditions:
+//   - sys.EBADF: `fd` is invalid
+//   - sys.EPERM: `fsRightsBase` or `fsRightsInheriting` include rights the
+//     file descriptor doesn't have, as rights can only be removed.
 //
-// See https://github.com/bytecodealliance/wasmtime/pull/4666
-var fdFdstatSetRights = stubFunction(
-	wasip1.FdFdstatSetRightsName,
+// Note: Rights were removed from WASI after wasi_snapshot_preview1, but are
+// still used by hosts to hand restricted file descriptors to guests. See
+// /RATIONALE.md for details.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_fdstat_set_rightsfd-fd-fs_rights_base-rights-fs_rights_inheriting-rights---errno
+var fdFdstatSetRights = newHostFunc(
+	wasip1.FdFdstatSetRightsName, fdFdstatSetRightsFn,
 	[]wasm.ValueType{i32, i64, i64},
 	"fd", "fs_rights_base", "fs_rights_inheriting",
 )
 
+func fdFdstatSetRightsFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+
+	fd, base, inheriting := int32(params[0]), params[1], params[2]
+
+	f, ok := fsc.LookupFile(fd)
+	if !ok {
+		return experimentalsys.EBADF
+	} else if r := f.Rights; r != nil && (base&^r.Base != 0 || inheriting&^r.Inheriting != 0) {
+		return experimentalsys.EPERM
+	}
+	f.Rights = &sys.Rights{Base: base, Inheriting: inheriting}
+	return 0
+}
+
+// lookupFile returns the file at the descriptor, if it is open and has all
+// the given rights.
+//
+// Note: A missing right results in sys.EBADF, like using a file opened
+// read-only to write in POSIX. See /RATIONALE.md for why not ENOTCAPABLE.
+func lookupFile(fsc *sys.FSContext, fd int32, rights uint64) (*sys.FileEntry, bool) {
+	if f, ok := fsc.LookupFile(fd); ok && f.HasRights(rights) {
+		return f, true
+	}
+	return nil, false
+}
+
+// isAppendOnly returns true if the file descriptor can write, but not seek,
+// so must only append to files.
+func isAppendOnly(r *sys.Rights) bool {
+	return r != nil && r.Base&wasip1.RIGHT_FD_WRITE != 0 && r.Base&wasip1.RIGHT_FD_SEEK == 0
+}
+
 // fdFilestatGet is the WASI function named FdFilestatGetName which returns
 // the stat attributes of an open file.
 //
@@ -398,7 +460,7 @@ func fdFilestatGetFunc(mod api.Module, fd int32, resultBuf uint32) experimentals
 		return experimentalsys.EFAULT
 	}
 
-	f, ok := fsc.LookupFile(fd)
+	f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_FILESTAT_GET)
 	if !ok {
 		return experimentalsys.EBADF
 	}
@@ -419,6 +481,14 @@ func getExtendedWasiFiletype(file experimentalsys.File, fm fs.FileMode) (ftype u
 			ftype = wasip1.FILETYPE_SOCKET_STREAM
 		} else if _, ok = file.(socketapi.TCPConn); ok {
 			ftype = wasip1.FILETYPE_SOCKET_STREAM
+		} else if _, ok = file.(socketapi.UDPConn); ok {
+			ftype = wasip1.FILETYPE_SOCKET_DGRAM
+		} else if sock, ok := file.(socketapi.Socket); ok {
+			if network := sock.Network(); network == "udp4" || network == "udp6" {
+				ftype = wasip1.FILETYPE_SOCKET_DGRAM
+			} else {
+				ftype = wasip1.FILETYPE_SOCKET_STREAM
+			}
 		}
 	}
 	return
@@ -469,7 +539,7 @@ func fdFilestatSetSizeFn(_ context.Context, mod api.Module, params []uint64) exp
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
 	// Check to see if the file descriptor is available
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_FILESTAT_SET_SIZE); !ok {
 		return experimentalsys.EBADF
 	} else {
 		return f.File.Truncate(size)
@@ -495,7 +565,7 @@ func fdFilestatSetTimesFn(_ context.Context, mod api.Module, params []uint64) ex
 	sys := mod.(*wasm.ModuleInstance).Sys
 	fsc := sys.FS()
 
-	f, ok := fsc.LookupFile(fd)
+	f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_FILESTAT_SET_TIMES)
 	if !ok {
 		return experimentalsys.EBADF
 	}
@@ -774,9 +844,15 @@ func fdReadOrPread(mod api.Module, params []uint64, isPread bool) experimentalsy
 	iovs := uint32(params[1])
 	iovsCount := uint32(params[2])
 
+	// fd_pread also requires the right to seek, as it reads at an offset.
+	rights := wasip1.RIGHT_FD_READ
+	if isPread {
+		rights |= wasip1.RIGHT_FD_SEEK
+	}
+
 	var resultNread uint32
 	var reader func(buf []byte) (n int, errno experimentalsys.Errno)
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, rights); !ok {
 		return experimentalsys.EBADF
 	} else if isPread {
 		offset := int64(params[3])
@@ -1033,7 +1109,7 @@ func writeDirent(buf []byte, dNext uint64, ino sysapi.Inode, dNamlen uint32, dTy
 // direntCache lazy opens a sys.DirentCache for this directory or returns an
 // error.
 func direntCache(fsc *sys.FSContext, fd int32) (*sys.DirentCache, experimentalsys.Errno) {
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_READDIR); !ok {
 		return nil, experimentalsys.EBADF
 	} else if dir, errno := f.DirentCache(); errno == 0 {
 		return dir, 0
@@ -1121,6 +1197,8 @@ func fdSeekFn(_ context.Context, mod api.Module, params []uint64) experimentalsy
 
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if !f.HasRights(wasip1.RIGHT_FD_SEEK) && !(isTell(offset, whence) && f.HasRights(wasip1.RIGHT_FD_TELL)) {
+		return experimentalsys.EBADF // RIGHT_FD_SEEK implies RIGHT_FD_TELL.
 	} else if isDir, _ := f.File.IsDir(); isDir {
 		return experimentalsys.EISDIR // POSIX doesn't forbid seeking a directory, but wasi-testsuite does.
 	} else if newOffset, errno := f.File.Seek(int64(offset), int(whence)); errno != 0 {
@@ -1142,13 +1220,18 @@ func fdSyncFn(_ context.Context, mod api.Module, params []uint64) experimentalsy
 	fd := int32(params[0])
 
 	// Check to see if the file descriptor is available
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, wasip1.RIGHT_FD_SYNC); !ok {
 		return experimentalsys.EBADF
 	} else {
 		return f.File.Sync()
 	}
 }
 
+// isTell returns true if fd_seek only reads the offset, like fd_tell.
+func isTell(offset uint64, whence uint32) bool {
+	return offset == 0 && whence == io.SeekCurrent
+}
+
 // fdTell is the WASI function named FdTellName which returns the current
 // offset of a file descriptor.
 //
@@ -1258,9 +1341,16 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) experimenta
 	iovs := uint32(params[1])
 	iovsCount := uint32(params[2])
 
+	// fd_pwrite also requires the right to seek, so that a file descriptor
+	// without it is append-only.
+	rights := wasip1.RIGHT_FD_WRITE
+	if isPwrite {
+		rights |= wasip1.RIGHT_FD_SEEK
+	}
+
 	var resultNwritten uint32
 	var writer func(buf []byte) (n int, errno experimentalsys.Errno)
-	if f, ok := fsc.LookupFile(fd); !ok {
+	if f, ok := lookupFile(fsc, fd, rights); !ok {
 		return experimentalsys.EBADF
 	} else if isPwrite {
 		offset := int64(params[3])
@@ -1343,7 +1433,7 @@ func pathCreateDirectoryFn(_ context.Context, mod api.Module, params []uint64) e
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_CREATE_DIRECTORY)
 	if errno != 0 {
 		return errno
 	}
@@ -1397,7 +1487,7 @@ func pathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) exper
 	path := uint32(params[2])
 	pathLen := uint32(params[3])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_FILESTAT_GET)
 	if errno != 0 {
 		return errno
 	}
@@ -1452,7 +1542,7 @@ func pathFilestatSetTimesFn(_ context.Context, mod api.Module, params []uint64)
 		return errno
 	}
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_FILESTAT_SET_TIMES)
 	if errno != 0 {
 		return errno
 	}
@@ -1490,7 +1580,7 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 	oldPath := uint32(params[2])
 	oldPathLen := uint32(params[3])
 
-	oldFS, oldName, errno := atPath(fsc, mem, oldFD, oldPath, oldPathLen)
+	oldFS, oldName, errno := atPath(fsc, mem, oldFD, oldPath, oldPathLen, wasip1.RIGHT_PATH_LINK_SOURCE)
 	if errno != 0 {
 		return errno
 	}
@@ -1499,7 +1589,7 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 	newPath := uint32(params[5])
 	newPathLen := uint32(params[6])
 
-	newFS, newName, errno := atPath(fsc, mem, newFD, newPath, newPathLen)
+	newFS, newName, errno := atPath(fsc, mem, newFD, newPath, newPathLen, wasip1.RIGHT_PATH_LINK_TARGET)
 	if errno != 0 {
 		return errno
 	}
@@ -1522,8 +1612,8 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 //   - pathLen: length of `path`
 //   - oFlags: open flags to indicate the method by which to open the file
 //   - fsRightsBase: interpret RIGHT_FD_WRITE to set O_RDWR
-//   - fsRightsInheriting: ignored as rights were removed from WASI.
-//     created file descriptor for `path`
+//   - fsRightsInheriting: ignored, as the new file descriptor inherits the
+//     rights of `fd`, which the guest can narrow with fd_fdstat_set_rights.
 //   - fdFlags: file descriptor flags
 //   - resultOpenedFD: offset in api.Memory to write the newly created file
 //     descriptor to.
@@ -1533,6 +1623,8 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 //
 // The return value is 0 except the following error conditions:
 //   - sys.EBADF: `fd` is invalid
+//   - sys.EACCES: `fd` doesn't have the rights to open `path` with `oFlags`,
+//     or its inheriting rights don't allow writing to it.
 //   - sys.EFAULT: `resultOpenedFD` points to an offset out of memory
 //   - sys.ENOENT: `path` does not exist.
 //   - sys.EEXIST: `path` exists, while `oFlags` requires that it must not.
@@ -1584,14 +1676,22 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 
 	oflags := uint16(params[4])
 
-	rights := uint32(params[5])
-	// inherited rights aren't used
+	rights := params[5]
+	// inherited rights aren't used, as the directory's are inherited.
 	_ = params[6]
 
 	fdflags := uint16(params[7])
 	resultOpenedFD := uint32(params[8])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), preopenFD, path, pathLen)
+	dirRights := wasip1.RIGHT_PATH_OPEN
+	if oflags&wasip1.O_CREAT != 0 {
+		dirRights |= wasip1.RIGHT_PATH_CREATE_FILE
+	}
+	if oflags&wasip1.O_TRUNC != 0 {
+		dirRights |= wasip1.RIGHT_PATH_FILESTAT_SET_SIZE
+	}
+
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), preopenFD, path, pathLen, dirRights)
 	if errno != 0 {
 		return errno
 	}
@@ -1607,10 +1707,27 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 		return experimentalsys.EINVAL // use pathCreateDirectory!
 	}
 
+	// Files opened relative to a restricted directory inherit its rights.
+	var newRights *sys.Rights
+	if dir, _ := fsc.LookupFile(preopenFD); dir.Rights != nil {
+		newRights = &sys.Rights{Base: dir.Rights.Inheriting, Inheriting: dir.Rights.Inheriting}
+		if fileOpenFlags&(experimentalsys.O_WRONLY|experimentalsys.O_RDWR) != 0 {
+			if newRights.Base&wasip1.RIGHT_FD_WRITE == 0 {
+				return experimentalsys.EACCES
+			} else if isAppendOnly(newRights) {
+				fileOpenFlags |= experimentalsys.O_APPEND
+			}
+		}
+	}
+
 	newFD, errno := fsc.OpenFile(preopen, pathName, fileOpenFlags, 0o600)
 	if errno != 0 {
 		return errno
 	}
+	if newRights != nil {
+		f, _ := fsc.LookupFile(newFD)
+		f.Rights = newRights
+	}
 
 	// Check any flags that require the file to evaluate.
 	if isDir {
@@ -1632,7 +1749,8 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 	return 0
 }
 
-// atPath returns the pre-open specific path after verifying it is a directory.
+// atPath returns the pre-open specific path after verifying it is a directory
+// with the given rights.
 //
 // # Notes
 //
@@ -1648,7 +1766,7 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 //
 // See https://github.com/WebAssembly/wasi-libc/blob/659ff414560721b1660a19685110e484a081c3d4/libc-bottom-half/sources/at_fdcwd.c
 // See https://linux.die.net/man/2/openat
-func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (experimentalsys.FS, string, experimentalsys.Errno) {
+func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32, rights uint64) (experimentalsys.FS, string, experimentalsys.Errno) {
 	b, ok := mem.Read(p, pathLen)
 	if !ok {
 		return nil, "", experimentalsys.EFAULT
@@ -1676,6 +1794,8 @@ func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (ex
 
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return nil, "", experimentalsys.EBADF // closed or invalid
+	} else if !f.HasRights(rights) {
+		return nil, "", experimentalsys.EACCES
 	} else if isDir, errno := f.File.IsDir(); errno != 0 {
 		return nil, "", errno
 	} else if !isDir {
@@ -1702,7 +1822,7 @@ func preopenPath(fsc *sys.FSContext, fd int32) (string, experimentalsys.Errno) {
 	}
 }
 
-func openFlags(dirflags, oflags, fdflags uint16, rights uint32) (openFlags experimentalsys.Oflag) {
+func openFlags(dirflags, oflags, fdflags uint16, rights uint64) (openFlags experimentalsys.Oflag) {
 	if dirflags&wasip1.LOOKUP_SYMLINK_FOLLOW == 0 {
 		openFlags |= experimentalsys.O_NOFOLLOW
 	}
@@ -1711,13 +1831,13 @@ func openFlags(dirflags, oflags, fdflags uint16, rights uint32) (openFlags exper
 	} else if oflags&wasip1.O_EXCL != 0 {
 		openFlags |= experimentalsys.O_EXCL
 	}
-	// Because we don't implement rights, we partially rely on the open flags
-	// to determine the mode in which the file will be opened. This will create
-	// divergent behavior compared to WASI runtimes which have a more strict
-	// interpretation of the WASI capabilities model; for example, a program
-	// which sets O_CREAT but does not give read or write permissions will
-	// successfully create a file when running with wazero, but might get a
-	// permission denied error on other runtimes.
+	// Because we don't enforce the requested rights, we partially rely on the
+	// open flags to determine the mode in which the file will be opened. This
+	// will create divergent behavior compared to WASI runtimes which have a
+	// more strict interpretation of the WASI capabilities model; for example,
+	// a program which sets O_CREAT but does not give read or write permissions
+	// will successfully create a file when running with wazero, but might get
+	// a permission denied error on other runtimes.
 	defaultMode := experimentalsys.O_RDONLY
 	if oflags&wasip1.O_TRUNC != 0 {
 		openFlags |= experimentalsys.O_TRUNC
@@ -1789,7 +1909,7 @@ func pathReadlinkFn(_ context.Context, mod api.Module, params []uint64) experime
 	}
 
 	mem := mod.Memory()
-	preopen, p, errno := atPath(fsc, mem, fd, path, pathLen)
+	preopen, p, errno := atPath(fsc, mem, fd, path, pathLen, wasip1.RIGHT_PATH_READLINK)
 	if errno != 0 {
 		return errno
 	}
@@ -1848,7 +1968,7 @@ func pathRemoveDirectoryFn(_ context.Context, mod api.Module, params []uint64) e
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_REMOVE_DIRECTORY)
 	if errno != 0 {
 		return errno
 	}
@@ -1898,12 +2018,12 @@ func pathRenameFn(_ context.Context, mod api.Module, params []uint64) experiment
 	newPath := uint32(params[4])
 	newPathLen := uint32(params[5])
 
-	oldFS, oldPathName, errno := atPath(fsc, mod.Memory(), fd, oldPath, oldPathLen)
+	oldFS, oldPathName, errno := atPath(fsc, mod.Memory(), fd, oldPath, oldPathLen, wasip1.RIGHT_PATH_RENAME_SOURCE)
 	if errno != 0 {
 		return errno
 	}
 
-	newFS, newPathName, errno := atPath(fsc, mod.Memory(), newFD, newPath, newPathLen)
+	newFS, newPathName, errno := atPath(fsc, mod.Memory(), newFD, newPath, newPathLen, wasip1.RIGHT_PATH_RENAME_TARGET)
 	if errno != 0 {
 		return errno
 	}
@@ -1954,7 +2074,7 @@ func pathSymlinkFn(_ context.Context, mod api.Module, params []uint64) experimen
 		return experimentalsys.EFAULT
 	}
 
-	_, newPathName, errno := atPath(fsc, mod.Memory(), fd, newPath, newPathLen)
+	_, newPathName, errno := atPath(fsc, mod.Memory(), fd, newPath, newPathLen, wasip1.RIGHT_PATH_SYMLINK)
 	if errno != 0 {
 		return errno
 	}
@@ -2001,7 +2121,7 @@ func pathUnlinkFileFn(_ context.Context, mod api.Module, params []uint64) experi
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_UNLINK_FILE)
 	if errno != 0 {
 		return errno
 	}
@@ -652,13 +652,40 @@ func Test_fdFdstatSetFlags(t *testing.T) {
 	})
 }
 
-// Test_fdFdstatSetRights only tests it is stubbed for GrainLang per #271
 func Test_fdFdstatSetRights(t *testing.T) {
-	log := requireErrnoNosys(t, wasip1.FdFdstatSetRightsName, 0, 0, 0)
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(t.TempDir(), "/")))
+	defer r.Close(testCtx)
+
+	fd := uint64(sys.FdPreopen)
+	base := uint64(wasip1.RIGHT_PATH_OPEN | wasip1.RIGHT_FD_READDIR)
+	inheriting := uint64(wasip1.RIGHT_FD_READ)
+
+	// Remove all the rights of the pre-open, except reading.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatSetRightsName, fd, base, inheriting)
 	require.Equal(t, `
-==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=0,fs_rights_base=,fs_rights_inheriting=)
-<== errno=ENOSYS
-`, log)
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=3,fs_rights_base=PATH_OPEN|FD_READDIR,fs_rights_inheriting=FD_READ)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+	log.Reset()
+
+	// Only the remaining rights are reported.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, fd, 0)
+	buf, ok := mod.Memory().Read(8, 16)
+	require.True(t, ok)
+	require.Equal(t, u64.LeBytes(base), buf[:8])
+	require.Equal(t, u64.LeBytes(inheriting), buf[8:])
+	log.Reset()
+
+	// The removed rights are enforced.
+	requireErrnoResult(t, wasip1.ErrnoAcces, mod, wasip1.PathCreateDirectoryName, fd, 0, 1)
+
+	// Rights can be removed again, but not added back.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatSetRightsName, fd, base, 0)
+	requireErrnoResult(t, wasip1.ErrnoPerm, mod, wasip1.FdFdstatSetRightsName, fd, base, inheriting)
+	requireErrnoResult(t, wasip1.ErrnoPerm, mod, wasip1.FdFdstatSetRightsName, fd, base|uint64(wasip1.RIGHT_PATH_CREATE_DIRECTORY), 0)
+
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdFdstatSetRightsName, uint64(12345), 0, 0)
 }
 
 func Test_fdFilestatGet(t *testing.T) {
@@ -3748,7 +3775,7 @@ func Test_pathOpen(t *testing.T) {
 		path          func(t *testing.T) string
 		oflags        uint16
 		fdflags       uint16
-		rights        uint32
+		rights        uint64
 		expected      func(t *testing.T, fsc *sys.FSContext)
 		expectedErrno wasip1.Errno
 		expectedLog   string
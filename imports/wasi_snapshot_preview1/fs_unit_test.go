@@ -206,7 +206,7 @@ func Test_openFlags(t *testing.T) {
 	tests := []struct {
 		name                      string
 		dirflags, oflags, fdflags uint16
-		rights                    uint32
+		rights                    uint64
 		expectedOpenFlags         experimentalsys.Oflag
 	}{
 		{
@@ -2,12 +2,15 @@ package wasi_snapshot_preview1
 
 import (
 	"context"
+	"io"
+	"math"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -25,14 +28,23 @@ import (
 // Result (Errno)
 //
 // The return value is 0 except the following error conditions:
-//   - sys.EINVAL: the parameters are invalid
-//   - sys.ENOTSUP: a parameters is valid, but not yet supported.
+//   - sys.EINVAL: the parameters are invalid, such as an absolute timeout
+//     of a clock other than realtime or monotonic.
+//   - sys.EBADF: a file descriptor is negative.
 //   - sys.EFAULT: there is not enough memory to read the subscriptions or
 //     write results.
+//   - sys.EINTR: the context of the call was done while waiting for files.
 //
 // # Notes
 //
 //   - Since the `out` pointer nests Errno, the result is always 0.
+//   - This waits until a file of a fd_read or fd_write subscription is ready,
+//     or the first clock subscription expires. Only the events which occurred
+//     are written, in the order of their subscriptions.
+//   - Files which can't be polled, such as regular files, are always ready.
+//   - The nbytes of a fd_read event on a regular file is the count of bytes
+//     left to read, and one otherwise. Its flags are
+//     FD_READWRITE_HANGUP when the peer of a socket or pipe hung up.
 //   - This is similar to `poll` in POSIX.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#poll_oneoff
@@ -47,9 +59,25 @@ type event struct {
 	eventType byte
 	userData  []byte
 	errno     wasip1.Errno
+	// nbytes and flags are the fields of an fd_read or fd_write event.
+	nbytes uint64
+	flags  uint16
 }
 
-func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+// subscription is a subscription read from the `in` buffer, and whether its
+// event occurred.
+type subscription struct {
+	event
+	// timeout is the relative timeout of a clock subscription.
+	timeout time.Duration
+	// file is the index of the sysfs.PollFile of a fd_read or fd_write
+	// subscription, or -1.
+	file  int
+	fd    int32
+	ready bool
+}
+
+func pollOneoffFn(ctx context.Context, mod api.Module, params []uint64) sys.Errno {
 	in := uint32(params[0])
 	out := uint32(params[1])
 	nsubscriptions := uint32(params[2])
@@ -74,156 +102,194 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		return sys.EFAULT
 	}
 
-	// Eagerly write the number of events which will equal subscriptions unless
-	// there's a fault in parsing (not processing).
+	// Eagerly write the number of events, so that a fault is detected before
+	// waiting. It is overwritten once the events are known.
 	if !mod.Memory().WriteUint32Le(resultNevents, nsubscriptions) {
 		return sys.EFAULT
 	}
 
-	// Loop through all subscriptions and write their output.
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 
-	// Extract FS context, used in the body of the for loop for FS access.
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-	// Slice of events that are processed out of the loop (blocking stdin subscribers).
-	var blockingStdinSubs []*event
-	// The timeout is initialized at max Duration, the loop will find the minimum.
-	var timeout time.Duration = 1<<63 - 1
-	// Count of all the subscriptions that have been already written back to outBuf.
-	// nevents*32 returns at all times the offset where the next event should be written:
-	// this way we ensure that there are no gaps between records.
-	nevents := uint32(0)
+	subs := make([]subscription, nsubscriptions)
+	// files are polled for the fd_read and fd_write subscriptions.
+	var files []sysfs.PollFile
+	// timeout is the minimum timeout of the clock subscriptions, or -1 if
+	// there are none.
+	var timeout time.Duration = -1
+	// occurred is true when an event occurred without polling, so that
+	// polling mustn't wait.
+	occurred := false
 
 	// Layout is subscription_u: Union
 	// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#subscription_u
-	for i := uint32(0); i < nsubscriptions; i++ {
-		inOffset := i * 48
-		outOffset := nevents * 32
+	for i := range subs {
+		inOffset := uint32(i) * 48
 
 		eventType := inBuf[inOffset+8] // +8 past userdata
 		// +8 past userdata +8 contents_offset
 		argBuf := inBuf[inOffset+8+8:]
 		userData := inBuf[inOffset : inOffset+8]
 
-		evt := &event{
-			eventType: eventType,
-			userData:  userData,
-			errno:     wasip1.ErrnoSuccess,
-		}
+		sub := &subs[i]
+		sub.event = event{eventType: eventType, userData: userData, errno: wasip1.ErrnoSuccess}
+		sub.file = -1
 
 		switch eventType {
-		case wasip1.EventTypeClock: // handle later
-			newTimeout, err := processClockEvent(argBuf)
-			if err != 0 {
-				return err
+		case wasip1.EventTypeClock:
+			newTimeout, errno := processClockEvent(sysCtx, argBuf)
+			if errno != 0 {
+				return errno
 			}
-			// Min timeout.
-			if newTimeout < timeout {
+			sub.timeout = newTimeout
+			if timeout < 0 || newTimeout < timeout {
 				timeout = newTimeout
 			}
-			// Ack the clock event to the outBuf.
-			writeEvent(outBuf[outOffset:], evt)
-			nevents++
-		case wasip1.EventTypeFdRead:
+		case wasip1.EventTypeFdRead, wasip1.EventTypeFdWrite:
 			fd := int32(le.Uint32(argBuf))
 			if fd < 0 {
 				return sys.EBADF
 			}
-			if file, ok := fsc.LookupFile(fd); !ok {
-				evt.errno = wasip1.ErrnoBadf
-				writeEvent(outBuf[outOffset:], evt)
-				nevents++
-			} else if fd != internalsys.FdStdin && file.File.IsNonblock() {
-				writeEvent(outBuf[outOffset:], evt)
-				nevents++
-			} else {
-				// if the fd is Stdin, and it is in blocking mode,
-				// do not ack yet, append to a slice for delayed evaluation.
-				blockingStdinSubs = append(blockingStdinSubs, evt)
-			}
-		case wasip1.EventTypeFdWrite:
-			fd := int32(le.Uint32(argBuf))
-			if fd < 0 {
-				return sys.EBADF
+			flag, rights := fsapi.POLLIN, wasip1.RIGHT_POLL_FD_READWRITE|wasip1.RIGHT_FD_READ
+			if eventType == wasip1.EventTypeFdWrite {
+				flag, rights = fsapi.POLLOUT, wasip1.RIGHT_POLL_FD_READWRITE|wasip1.RIGHT_FD_WRITE
 			}
-			if _, ok := fsc.LookupFile(fd); ok {
-				evt.errno = wasip1.ErrnoNotsup
-			} else {
-				evt.errno = wasip1.ErrnoBadf
+			file, ok := lookupFile(fsc, fd, rights)
+			if !ok {
+				sub.errno = wasip1.ErrnoBadf
+				sub.ready, occurred = true, true
+				continue
 			}
-			nevents++
-			writeEvent(outBuf[outOffset:], evt)
+			sub.file, sub.fd = len(files), fd
+			files = append(files, sysfs.PollFile{File: file.File, Flag: flag})
 		default:
 			return sys.EINVAL
 		}
 	}
 
-	sysCtx := mod.(*wasm.ModuleInstance).Sys
-	if nevents == nsubscriptions {
-		// We already wrote back all the results. We already wrote this number
-		// earlier to offset `resultNevents`.
-		// We only need to observe the timeout (nonzero if there are clock subscriptions)
-		// and return.
-		if timeout > 0 {
-			sysCtx.Nanosleep(int64(timeout))
+	// Wait until a file is ready, or the first clock subscription expires,
+	// unless an event already occurred.
+	wait := timeout
+	if occurred {
+		wait = 0
+	}
+	if len(files) > 0 {
+		n, errno := sysfs.PollFiles(ctx, files, pollTimeoutMillis(wait))
+		if errno != 0 {
+			return errno
 		}
-		return 0
+		occurred = occurred || n > 0
+	} else if wait > 0 {
+		sysCtx.Nanosleep(int64(wait))
 	}
 
-	// If there are blocking stdin subscribers, check for data with given timeout.
-	stdin, ok := fsc.LookupFile(internalsys.FdStdin)
-	if !ok {
-		return sys.EBADF
-	}
-	// Wait for the timeout to expire, or for some data to become available on Stdin.
-
-	if stdinReady, errno := stdin.File.Poll(fsapi.POLLIN, int32(timeout.Milliseconds())); errno != 0 {
-		return errno
-	} else if stdinReady {
-		// stdin has data ready to for reading, write back all the events
-		for i := range blockingStdinSubs {
-			evt := blockingStdinSubs[i]
-			evt.errno = 0
-			writeEvent(outBuf[nevents*32:], evt)
+	// Write the events which occurred, in the order of their subscriptions.
+	nevents := uint32(0)
+	for i := range subs {
+		sub := &subs[i]
+		if sub.file >= 0 {
+			f := &files[sub.file]
+			sub.ready = f.Ready
+			if f.Errno != 0 {
+				sub.errno = wasip1.ToErrno(f.Errno)
+			} else if f.Ready {
+				sub.nbytes = readwriteNbytes(sub.fd, f)
+				if f.Hangup {
+					sub.flags = wasip1.EVENTRWFLAGS_FD_READWRITE_HANGUP
+				}
+			}
+		} else if sub.eventType == wasip1.EventTypeClock {
+			// The clocks with the minimum timeout expired unless another
+			// event occurred first.
+			sub.ready = sub.timeout == 0 || (!occurred && sub.timeout == timeout)
+		}
+		if sub.ready {
+			writeEvent(outBuf[nevents*32:], &sub.event)
 			nevents++
 		}
 	}
 
-	if nevents != nsubscriptions {
-		if !mod.Memory().WriteUint32Le(resultNevents, nevents) {
-			return sys.EFAULT
-		}
+	if !mod.Memory().WriteUint32Le(resultNevents, nevents) {
+		return sys.EFAULT
 	}
-
 	return 0
 }
 
-// processClockEvent supports only relative name events, as that's what's used
-// to implement sleep in various compilers including Rust, Zig and TinyGo.
-func processClockEvent(inBuf []byte) (time.Duration, sys.Errno) {
-	_ /* ID */ = le.Uint32(inBuf[0:8])          // See below
-	timeout := le.Uint64(inBuf[8:16])           // nanos if relative
+// processClockEvent returns the timeout of a clock subscription relative to
+// now. Relative timeouts are used to implement sleep in various compilers
+// including Rust, Zig and TinyGo, and absolute ones by wasi-libc.
+func processClockEvent(sysCtx *internalsys.Context, inBuf []byte) (time.Duration, sys.Errno) {
+	id := le.Uint32(inBuf[0:8])
+	timeout := le.Uint64(inBuf[8:16])           // nanos, absolute if subscription_clock_abstime
 	_ /* precision */ = le.Uint64(inBuf[16:24]) // Unused
 	flags := le.Uint16(inBuf[24:32])
 
-	var err sys.Errno
+	if timeout > math.MaxInt64 {
+		timeout = math.MaxInt64
+	}
+
 	// subclockflags has only one flag defined:  subscription_clock_abstime
 	switch flags {
 	case 0: // relative time
+		// https://linux.die.net/man/3/clock_settime says relative timers are
+		// unaffected. So, we can skip name ID validation.
+		return time.Duration(timeout), 0
 	case 1: // subscription_clock_abstime
-		err = sys.ENOTSUP
+		var now int64
+		switch id {
+		case wasip1.ClockIDRealtime:
+			now = sysCtx.WalltimeNanos()
+		case wasip1.ClockIDMonotonic:
+			now = sysCtx.Nanotime()
+		default:
+			return 0, sys.EINVAL
+		}
+		if remaining := int64(timeout) - now; remaining > 0 {
+			return time.Duration(remaining), 0
+		}
+		return 0, 0 // already expired
 	default: // subclockflags has only one flag defined.
-		err = sys.EINVAL
+		return 0, sys.EINVAL
 	}
+}
 
-	if err != 0 {
-		return 0, err
-	} else {
-		// https://linux.die.net/man/3/clock_settime says relative timers are
-		// unaffected. Since this function only supports relative timeout, we can
-		// skip name ID validation and use a single sleep function.
+// pollTimeoutMillis returns the timeout in milliseconds for sysfs.PollFiles,
+// rounded up so that it doesn't return before the timeout.
+func pollTimeoutMillis(timeout time.Duration) int32 {
+	if timeout < 0 {
+		return -1 // no clock subscription: wait until a file is ready.
+	}
+	millis := timeout / time.Millisecond
+	if timeout%time.Millisecond != 0 {
+		millis++
+	}
+	if millis > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(millis)
+}
 
-		return time.Duration(timeout), 0
+// readwriteNbytes returns the nbytes of the event of a ready file: the bytes
+// left to read of a regular file, which may be zero at its end, or else one,
+// as the count of bytes which can be read or written without blocking isn't
+// known.
+func readwriteNbytes(fd int32, f *sysfs.PollFile) uint64 {
+	// The size of stdio files isn't known, as their stat is constant.
+	if f.Flag != fsapi.POLLIN || fd <= internalsys.FdStderr {
+		return 1
 	}
+	st, errno := f.File.Stat()
+	if errno != 0 || !st.Mode.IsRegular() {
+		return 1
+	}
+	offset, errno := f.File.Seek(0, io.SeekCurrent)
+	if errno != 0 {
+		return 1
+	}
+	if offset >= st.Size {
+		return 0
+	}
+	return uint64(st.Size - offset)
 }
 
 // writeEvent writes the event corresponding to the processed subscription.
@@ -233,5 +299,9 @@ func writeEvent(outBuf []byte, evt *event) {
 	outBuf[8] = byte(evt.errno) // uint16, but safe as < 255
 	outBuf[9] = 0
 	le.PutUint32(outBuf[10:], uint32(evt.eventType))
-	// TODO: When FD events are supported, write outOffset+16
+	if evt.eventType != wasip1.EventTypeClock {
+		// fd_readwrite: nbytes, then flags
+		le.PutUint64(outBuf[16:], evt.nbytes)
+		le.PutUint16(outBuf[24:], evt.flags)
+	}
 }
@@ -1,7 +1,9 @@
 package wasi_snapshot_preview1_test
 
 import (
+	"context"
 	"io/fs"
+	"net"
 	"os"
 	"strings"
 	"testing"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	experimentalsock "github.com/tetratelabs/wazero/experimental/sock"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
 	"github.com/tetratelabs/wazero/internal/sys"
@@ -181,7 +184,8 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
 				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, // pad to 16
+				0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
@@ -193,9 +197,9 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 `,
 		},
 		{
-			name:            "20ms timeout, fdread on tty (buffer ready): both events are written",
+			name:            "20ms timeout, fdread on tty (buffer ready): only fdread event is written",
 			nsubscriptions:  2,
-			expectedNevents: 2,
+			expectedNevents: 1,
 			stdin:           &ttyStdinFile{StdinFile: sys.StdinFile{Reader: strings.NewReader("test")}},
 			mem: concat(
 				clockNsSub(20*1000*1000),
@@ -205,31 +209,32 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			out:           128, // past in
 			resultNevents: 512, // past out
 			expectedMem: []byte{
+				// The clock didn't expire as the file is ready first.
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				0x0, 0x0, // pad to 16
+				0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
-<== (nevents=2,errno=ESUCCESS)
+<== (nevents=1,errno=ESUCCESS)
 `,
 		},
 		{
-			name:            "0ns timeout, fdread on tty (buffer ready): both are written",
+			name:            "0ns timeout, fdread on tty (buffer ready): only fdread event is written",
 			nsubscriptions:  2,
-			expectedNevents: 2,
+			expectedNevents: 1,
 			stdin:           &ttyStdinFile{StdinFile: sys.StdinFile{Reader: strings.NewReader("test")}},
 			mem: concat(
 				clockNsSub(20*1000*1000),
@@ -239,31 +244,32 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			out:           128, // past in
 			resultNevents: 512, // past out
 			expectedMem: []byte{
+				// The clock didn't expire as the file is ready first.
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				0x0, 0x0, // pad to 16
+				0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
-<== (nevents=2,errno=ESUCCESS)
+<== (nevents=1,errno=ESUCCESS)
 `,
 		},
 		{
-			name:            "0ns timeout, fdread on regular file: both events are written",
+			name:            "0ns timeout, fdread on regular file: only fdread event is written",
 			nsubscriptions:  2,
-			expectedNevents: 2,
+			expectedNevents: 1,
 			stdin:           &sys.StdinFile{Reader: strings.NewReader("test")},
 			mem: concat(
 				clockNsSub(20*1000*1000),
@@ -273,31 +279,32 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			out:           128, // past in
 			resultNevents: 512, // past out
 			expectedMem: []byte{
+				// The clock didn't expire as the file is ready first.
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				0x0, 0x0, // pad to 16
+				0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
-<== (nevents=2,errno=ESUCCESS)
+<== (nevents=1,errno=ESUCCESS)
 `,
 		},
 		{
-			name:            "1ns timeout, fdread on regular file: both events are written",
+			name:            "1ns timeout, fdread on regular file: only fdread event is written",
 			nsubscriptions:  2,
-			expectedNevents: 2,
+			expectedNevents: 1,
 			stdin:           &sys.StdinFile{Reader: strings.NewReader("test")},
 			mem: concat(
 				clockNsSub(20*1000*1000),
@@ -307,25 +314,26 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			out:           128, // past in
 			resultNevents: 512, // past out
 			expectedMem: []byte{
+				// The clock didn't expire as the file is ready first.
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				0x0, 0x0, // pad to 16
+				0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
-<== (nevents=2,errno=ESUCCESS)
+<== (nevents=1,errno=ESUCCESS)
 `,
 		},
 		{
@@ -363,9 +371,9 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 `,
 		},
 		{
-			name:            "pollable pipe, multiple subs, events returned out of order",
+			name:            "pollable pipe, multiple subs, events returned in order",
 			nsubscriptions:  3,
-			expectedNevents: 3,
+			expectedNevents: 2,
 			mem: concat(
 				fdReadSub,
 				clockNsSub(20*1000*1000),
@@ -376,15 +384,17 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			out:           128, // past in
 			resultNevents: 512, // past out
 			expectedMem: []byte{
-				// Clock is acknowledged first.
+				// Stdin is ready first.
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				0x0, 0x0, // pad to 16
+				0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				// Then an illegal file with custom user data.
+				// Then an illegal file with custom user data, while the clock
+				// didn't expire.
 				0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, // userdata
 				byte(wasip1.ErrnoBadf), 0x0, // errno is 16 bit
 				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
@@ -392,20 +402,17 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				// Stdin pipes are delayed to invoke sysfs.poll
-				// thus, they are written back last.
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=3)
-<== (nevents=3,errno=ESUCCESS)
+<== (nevents=2,errno=ESUCCESS)
 `,
 		},
 	}
@@ -472,20 +479,21 @@ func Test_pollOneoff_Zero(t *testing.T) {
 		),
 	)
 
+	// The clock doesn't expire as the fd is ready first.
 	expectedMem := []byte{
-		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-		byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-		wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-
 		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 		byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
 		wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+		0x0, 0x0, // pad to 16
+		0x1, 0x0, 0x0, 0x0, 0x0, 0x0, // nbytes is one, as the count readable is unknown
 		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 		0x0, 0x0,
 
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+
 		'?', // stopped after encoding
 	}
 
@@ -499,7 +507,7 @@ func Test_pollOneoff_Zero(t *testing.T) {
 	// Events should be written on success regardless of nested failure.
 	nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
 	require.True(t, ok)
-	require.Equal(t, uint32(2), nevents)
+	require.Equal(t, uint32(1), nevents)
 
 	// second run: simulate no more data on the fd
 	poller.ready = false
@@ -538,6 +546,150 @@ func Test_pollOneoff_Zero(t *testing.T) {
 	require.Equal(t, uint32(1), nevents)
 }
 
+func Test_pollOneoff_Abstime(t *testing.T) {
+	tests := []struct {
+		name            string
+		mem             []byte
+		expectedErrno   wasip1.Errno
+		expectedNevents uint32
+	}{
+		{
+			name:            "monotonic expired",
+			mem:             clockAbsSub(wasip1.ClockIDMonotonic, 0),
+			expectedErrno:   wasip1.ErrnoSuccess,
+			expectedNevents: 1,
+		},
+		{
+			name:            "realtime expired",
+			mem:             clockAbsSub(wasip1.ClockIDRealtime, 0),
+			expectedErrno:   wasip1.ErrnoSuccess,
+			expectedNevents: 1,
+		},
+		{
+			name: "fdread before monotonic",
+			mem: concat(
+				clockAbsSub(wasip1.ClockIDMonotonic, uint64(time.Hour)),
+				fdReadSub,
+			),
+			expectedErrno:   wasip1.ErrnoSuccess,
+			expectedNevents: 1,
+		},
+		{
+			name:          "invalid clock",
+			mem:           clockAbsSub(2, 0),
+			expectedErrno: wasip1.ErrnoInval,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig())
+			defer r.Close(testCtx)
+
+			maskMemory(t, mod, 1024)
+			mod.Memory().Write(0, tc.mem)
+
+			out := uint32(128)
+			resultNevents := uint32(512)
+			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.PollOneoffName, uint64(0), uint64(out),
+				uint64(len(tc.mem)/48), uint64(resultNevents))
+			if tc.expectedErrno != wasip1.ErrnoSuccess {
+				return
+			}
+
+			nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedNevents, nevents)
+
+			// The expired clock, or else the ready file, is the only event.
+			eventType, ok := mod.Memory().ReadByte(out + 10)
+			require.True(t, ok)
+			require.Equal(t, tc.mem[len(tc.mem)-40], eventType)
+		})
+	}
+}
+
+func Test_pollOneoff_Socket(t *testing.T) {
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithTCPListener("127.0.0.1", 0))
+
+	mod, r, _ := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	out := uint32(128)
+	resultNevents := uint32(512)
+	mem := concat(
+		clockNsSub(20*1000*1000),
+		fdReadSubFd(byte(sys.FdPreopen)),
+	)
+
+	// pollEvent polls the subscriptions, and returns the type of the only event.
+	pollEvent := func() byte {
+		maskMemory(t, mod, 1024)
+		mod.Memory().Write(0, mem)
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName, uint64(0), uint64(out),
+			uint64(2), uint64(resultNevents))
+
+		nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+		require.True(t, ok)
+		require.Equal(t, uint32(1), nevents)
+		eventType, ok := mod.Memory().ReadByte(out + 10)
+		require.True(t, ok)
+		return eventType
+	}
+
+	// The clock expires as no connection is pending.
+	require.Equal(t, byte(wasip1.EventTypeClock), pollEvent())
+
+	tcp, err := net.DialTCP("tcp", nil, requireTCPListenerAddr(t, mod))
+	require.NoError(t, err)
+	defer tcp.Close() //nolint
+
+	// The listener is ready to accept the connection before the clock expires.
+	require.Equal(t, byte(wasip1.EventTypeFdRead), pollEvent())
+}
+
+func Test_pollOneoff_RegularFile(t *testing.T) {
+	mod, fd, log, r := requireOpenFile(t, t.TempDir(), "test_path", []byte("wazero"), true)
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	f, ok := mod.(*wasm.ModuleInstance).Sys.FS().LookupFile(fd)
+	require.True(t, ok)
+	_, errno := f.File.Read(make([]byte, 2))
+	require.EqualErrno(t, 0, errno)
+
+	out, resultNevents := uint32(128), uint32(512)
+	mod.Memory().Write(0, fdReadSubFd(byte(fd)))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName, uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+
+	// nbytes is the count of bytes left to read, and no flag is set.
+	nbytes, ok := mod.Memory().ReadUint64Le(out + 16)
+	require.True(t, ok)
+	require.Equal(t, uint64(4), nbytes)
+	flags, ok := mod.Memory().ReadUint16Le(out + 24)
+	require.True(t, ok)
+	require.Equal(t, uint16(0), flags)
+}
+
+func Test_pollOneoff_Context(t *testing.T) {
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithTCPListener("127.0.0.1", 0))
+
+	mod, r, _ := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	// Without a clock subscription, the listener is polled until a connection
+	// is pending, unless the context is done first.
+	out, resultNevents := uint32(128), uint32(512)
+	mod.Memory().Write(0, fdReadSubFd(byte(sys.FdPreopen)))
+
+	ctx, cancel := context.WithTimeout(testCtx, 50*time.Millisecond)
+	defer cancel()
+	results, err := mod.ExportedFunction(wasip1.PollOneoffName).Call(ctx, uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+	require.NoError(t, err)
+	require.Equal(t, wasip1.ErrnoIntr, wasip1.Errno(results[0]))
+}
+
 func concat(bytes ...[]byte) []byte {
 	var res []byte
 	for i := range bytes {
@@ -559,6 +711,14 @@ func clockNsSub(ns uint64) []byte {
 	}
 }
 
+// subscription for a given absolute timeout in ns of a clock
+func clockAbsSub(id byte, ns uint64) []byte {
+	sub := clockNsSub(ns)
+	sub[16] = id
+	sub[40] = 0x1 // flags (subscription_clock_abstime)
+	return sub
+}
+
 // subscription for an EventTypeFdRead on a given fd
 func fdReadSubFd(fd byte) []byte {
 	return []byte{
@@ -4,6 +4,9 @@ import (
 	"context"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/expctxkeys"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/sys"
@@ -38,7 +41,104 @@ func procExitFn(ctx context.Context, mod api.Module, params []uint64) {
 	panic(sys.NewExitError(exitCode))
 }
 
-// procRaise is stubbed and will never be supported, as it was removed.
+// procRaise is the WASI function named ProcRaiseName which sends a signal to
+// the module itself. Its action is the default one of the signal, unless
+// overridden by the experimental.SignalHandler of the context.
 //
-// See https://github.com/WebAssembly/WASI/pull/136
-var procRaise = stubFunction(wasip1.ProcRaiseName, []api.ValueType{i32}, "sig")
+// # Parameters
+//
+//   - sig: signal, e.g. SIGABRT.
+//
+// Result (Errno)
+//
+// The return value is 0 except the following error conditions:
+//   - sys.EINVAL: `sig` isn't a signal.
+//
+// When the action terminates the module, this doesn't return. The module is
+// closed, and the sys.ExitError is the one of sys.NewSignalExitError.
+//
+// Note: This was removed from WASI after wasi_snapshot_preview1, but is still
+// imported, e.g. by wasi-libc for raise and abort.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#proc_raise
+// and https://github.com/WebAssembly/WASI/pull/136
+var procRaise = newHostFunc(wasip1.ProcRaiseName, procRaiseFn, []api.ValueType{i32}, "sig")
+
+func procRaiseFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	signal := uint32(params[0])
+
+	terminate, errno := receiveSignal(ctx, mod, signal)
+	if errno != 0 {
+		return errno
+	} else if !terminate {
+		return 0
+	}
+
+	// Ensure other callers see the signal, and prevent any code from
+	// executing after this function, like procExit.
+	_ = mod.(*wasm.ModuleInstance).CloseWithSignal(ctx, uint8(signal))
+	panic(sys.NewSignalExitError(uint8(signal)))
+}
+
+// DeliverSignal delivers the signal from the host to the module, e.g. SIGINT
+// to cancel it. Its action is the default one of the signal, unless
+// overridden by the experimental.SignalHandler of the context.
+//
+// When the action terminates the module, it's closed like with
+// api.Module CloseWithExitCode, and the sys.ExitError is the one of
+// sys.NewSignalExitError. A function of the module which is running returns
+// this error once it checks if the module is closed, which requires
+// wazero.RuntimeConfig WithCloseOnContextDone.
+//
+// Otherwise, the signal is dropped, unless the action is
+// experimental.SignalActionCatch, which keeps it pending until the guest takes
+// it, e.g. to cancel a loop without being terminated.
+//
+// The signal is numbered as in "wasi_snapshot_preview1", e.g. 2 for SIGINT,
+// or else this fails with sys.EINVAL.
+func DeliverSignal(ctx context.Context, mod api.Module, signal uint8) error {
+	terminate, errno := receiveSignal(ctx, mod, uint32(signal))
+	if errno != 0 {
+		return errno
+	} else if !terminate {
+		return nil
+	}
+	return mod.(*wasm.ModuleInstance).CloseWithSignal(ctx, signal)
+}
+
+// receiveSignal returns true if the signal terminates the module, per its
+// default action or the experimental.SignalHandler of the context. Otherwise,
+// it keeps the signal pending if the handler catches it.
+func receiveSignal(ctx context.Context, mod api.Module, signal uint32) (bool, experimentalsys.Errno) {
+	if signal > uint32(wasip1.SIGSYS) {
+		return false, experimentalsys.EINVAL
+	}
+	switch sig := uint8(signal); sig {
+	case wasip1.SIGNONE:
+		return false, 0 // Like kill, this only checks the signal can be sent.
+	case wasip1.SIGKILL:
+		return true, 0 // This can't be handled.
+	case wasip1.SIGSTOP:
+		return false, 0 // This can't be handled, and modules can't be stopped.
+	}
+
+	action := experimental.SignalActionDefault
+	if handler, ok := ctx.Value(expctxkeys.SignalHandlerKey{}).(experimental.SignalHandler); ok {
+		action = handler.HandleSignal(ctx, mod, uint8(signal))
+	}
+	switch action {
+	case experimental.SignalActionTerminate:
+		return true, 0
+	case experimental.SignalActionIgnore:
+		return false, 0
+	case experimental.SignalActionCatch:
+		mod.(*wasm.ModuleInstance).AddPendingSignal(uint8(signal))
+		return false, 0
+	}
+	switch uint8(signal) {
+	case wasip1.SIGCHLD, wasip1.SIGCONT, wasip1.SIGURG, wasip1.SIGWINCH,
+		wasip1.SIGTSTP, wasip1.SIGTTIN, wasip1.SIGTTOU:
+		return false, 0 // Ignored, including the ones which stop a process.
+	}
+	return true, 0
+}
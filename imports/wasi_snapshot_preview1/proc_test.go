@@ -1,11 +1,19 @@
 package wasi_snapshot_preview1_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -51,11 +59,166 @@ func Test_procExit(t *testing.T) {
 	}
 }
 
-// Test_procRaise only tests it is stubbed for GrainLang per #271
 func Test_procRaise(t *testing.T) {
-	log := requireErrnoNosys(t, wasip1.ProcRaiseName, 0)
-	require.Equal(t, `
+	tests := []struct {
+		name            string
+		signal          uint8
+		handler         experimental.SignalHandlerFunc
+		expectedErrno   wasip1.Errno
+		expectedSignal  uint8
+		expectedPending uint32
+		expectedLog     string
+	}{
+		{
+			name:           "default terminates",
+			signal:         wasip1.SIGABRT,
+			expectedSignal: wasip1.SIGABRT,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=6)
+`,
+		},
+		{
+			name:   "default ignores",
+			signal: wasip1.SIGCHLD,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=16)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:   "handler ignores",
+			signal: wasip1.SIGTERM,
+			handler: func(context.Context, api.Module, uint8) experimental.SignalAction {
+				return experimental.SignalActionIgnore
+			},
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=15)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:   "handler catches",
+			signal: wasip1.SIGTERM,
+			handler: func(context.Context, api.Module, uint8) experimental.SignalAction {
+				return experimental.SignalActionCatch
+			},
+			expectedPending: 1 << wasip1.SIGTERM,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=15)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:   "handler terminates",
+			signal: wasip1.SIGURG,
+			handler: func(context.Context, api.Module, uint8) experimental.SignalAction {
+				return experimental.SignalActionTerminate
+			},
+			expectedSignal: wasip1.SIGURG,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=22)
+`,
+		},
+		{
+			name:   "SIGKILL can't be handled",
+			signal: wasip1.SIGKILL,
+			handler: func(context.Context, api.Module, uint8) experimental.SignalAction {
+				return experimental.SignalActionIgnore
+			},
+			expectedSignal: wasip1.SIGKILL,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=9)
+`,
+		},
+		{
+			name:   "SIGNONE",
+			signal: wasip1.SIGNONE,
+			expectedLog: `
 ==> wasi_snapshot_preview1.proc_raise(sig=0)
-<== errno=ENOSYS
-`, log)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:          "invalid",
+			signal:        wasip1.SIGSYS + 1,
+			expectedErrno: wasip1.ErrnoInval,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=31)
+<== errno=EINVAL
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			var handled []uint8
+			ctx := testCtx
+			if tc.handler != nil {
+				ctx = experimental.WithSignalHandler(ctx, experimental.SignalHandlerFunc(func(ctx context.Context, mod api.Module, signal uint8) experimental.SignalAction {
+					handled = append(handled, signal)
+					return tc.handler(ctx, mod, signal)
+				}))
+			}
+
+			mod, r, log := requireProxyModule(t, wazero.NewModuleConfig())
+			defer r.Close(testCtx)
+
+			results, err := mod.ExportedFunction(wasip1.ProcRaiseName).Call(ctx, uint64(tc.signal))
+			if tc.expectedSignal != 0 {
+				require.Equal(t, sys.NewSignalExitError(tc.expectedSignal), err)
+				require.True(t, mod.IsClosed())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expectedErrno, wasip1.Errno(results[0]))
+				require.False(t, mod.IsClosed())
+			}
+			require.Equal(t, tc.expectedPending, mod.(*wasm.ModuleInstance).TakePendingSignals())
+			if tc.handler != nil && tc.signal != wasip1.SIGKILL {
+				require.Equal(t, []uint8{tc.signal}, handled)
+			} else {
+				require.Nil(t, handled)
+			}
+			require.Equal(t, tc.expectedLog, "\n"+log.String())
+		})
+	}
+}
+
+func TestDeliverSignal(t *testing.T) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer r.Close(testCtx)
+	wasi_snapshot_preview1.MustInstantiate(testCtx, r)
+
+	// The guest loops until it's closed.
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLoop, 0x40, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "loop", Type: wasm.ExternTypeFunc, Index: 0}},
+	}))
+	require.NoError(t, err)
+
+	// Ignored signals don't close the module.
+	ctx := experimental.WithSignalHandler(testCtx, experimental.SignalHandlerFunc(func(_ context.Context, _ api.Module, signal uint8) experimental.SignalAction {
+		if signal == wasip1.SIGHUP {
+			return experimental.SignalActionIgnore
+		}
+		return experimental.SignalActionDefault
+	}))
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(ctx, mod, wasip1.SIGHUP))
+	require.NoError(t, wasi_snapshot_preview1.DeliverSignal(ctx, mod, wasip1.SIGWINCH))
+	require.False(t, mod.IsClosed())
+	require.EqualErrno(t, experimentalsys.EINVAL, wasi_snapshot_preview1.DeliverSignal(ctx, mod, wasip1.SIGSYS+1))
+
+	// SIGINT terminates the running guest.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = wasi_snapshot_preview1.DeliverSignal(ctx, mod, wasip1.SIGINT)
+	}()
+	_, err = mod.ExportedFunction("loop").Call(testCtx)
+	require.Equal(t, sys.NewSignalExitError(wasip1.SIGINT), err)
+	require.Equal(t, uint32(130), err.(*sys.ExitError).ExitCode())
 }
@@ -0,0 +1,57 @@
+package wasi_snapshot_preview1
+
+import "github.com/tetratelabs/wazero/internal/wasip1"
+
+// Rights of a file descriptor, which are combined into the bit sets passed to
+// wazero.FSConfig WithMountRights. Each is the right to invoke the function of
+// the same name, except where noted.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-rights-flagsu64
+const (
+	RightFdDatasync           = wasip1.RIGHT_FD_DATASYNC
+	RightFdRead               = wasip1.RIGHT_FD_READ
+	RightFdSeek               = wasip1.RIGHT_FD_SEEK // also fd_tell, fd_pread and fd_pwrite
+	RightFdFdstatSetFlags     = wasip1.RIGHT_FDSTAT_SET_FLAGS
+	RightFdSync               = wasip1.RIGHT_FD_SYNC
+	RightFdTell               = wasip1.RIGHT_FD_TELL
+	RightFdWrite              = wasip1.RIGHT_FD_WRITE
+	RightFdAdvise             = wasip1.RIGHT_FD_ADVISE
+	RightFdAllocate           = wasip1.RIGHT_FD_ALLOCATE
+	RightPathCreateDirectory  = wasip1.RIGHT_PATH_CREATE_DIRECTORY
+	RightPathCreateFile       = wasip1.RIGHT_PATH_CREATE_FILE // path_open with O_CREAT
+	RightPathLinkSource       = wasip1.RIGHT_PATH_LINK_SOURCE
+	RightPathLinkTarget       = wasip1.RIGHT_PATH_LINK_TARGET
+	RightPathOpen             = wasip1.RIGHT_PATH_OPEN
+	RightFdReaddir            = wasip1.RIGHT_FD_READDIR
+	RightPathReadlink         = wasip1.RIGHT_PATH_READLINK
+	RightPathRenameSource     = wasip1.RIGHT_PATH_RENAME_SOURCE
+	RightPathRenameTarget     = wasip1.RIGHT_PATH_RENAME_TARGET
+	RightPathFilestatGet      = wasip1.RIGHT_PATH_FILESTAT_GET
+	RightPathFilestatSetSize  = wasip1.RIGHT_PATH_FILESTAT_SET_SIZE // path_open with O_TRUNC
+	RightPathFilestatSetTimes = wasip1.RIGHT_PATH_FILESTAT_SET_TIMES
+	RightFdFilestatGet        = wasip1.RIGHT_FD_FILESTAT_GET
+	RightFdFilestatSetSize    = wasip1.RIGHT_FD_FILESTAT_SET_SIZE
+	RightFdFilestatSetTimes   = wasip1.RIGHT_FD_FILESTAT_SET_TIMES
+	RightPathSymlink          = wasip1.RIGHT_PATH_SYMLINK
+	RightPathRemoveDirectory  = wasip1.RIGHT_PATH_REMOVE_DIRECTORY
+	RightPathUnlinkFile       = wasip1.RIGHT_PATH_UNLINK_FILE
+	RightPollFdReadwrite      = wasip1.RIGHT_POLL_FD_READWRITE // poll_oneoff, with RightFdRead or RightFdWrite
+	RightSockShutdown         = wasip1.RIGHT_SOCK_SHUTDOWN
+)
+
+const (
+	// RightsAll are all rights, which is the same as not restricting them.
+	RightsAll = RightSockShutdown<<1 - 1
+
+	// RightsReadOnly are the rights to read files and directories, without
+	// changing them.
+	RightsReadOnly = RightFdRead | RightFdSeek | RightFdFdstatSetFlags |
+		RightFdTell | RightFdAdvise | RightPathOpen | RightFdReaddir |
+		RightPathReadlink | RightPathFilestatGet | RightFdFilestatGet |
+		RightPollFdReadwrite
+
+	// RightsAppendOnly are RightsReadOnly, except files can be created and
+	// only appended to, as they can't be seeked, truncated or removed.
+	RightsAppendOnly = RightsReadOnly&^(RightFdSeek|RightFdTell) |
+		RightFdDatasync | RightFdSync | RightFdWrite | RightPathCreateFile
+)
@@ -0,0 +1,97 @@
+package wasi_snapshot_preview1_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+func Test_mountRights_ReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, tmpDir, "file.txt", []byte("wazero"))
+
+	rights := wasi_snapshot_preview1.RightsReadOnly
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(tmpDir, "/").WithMountRights("/", rights, rights)))
+	defer r.Close(testCtx)
+
+	// Files can be opened to read, but not changed.
+	fd := requirePathOpen(t, mod, "file.txt", 0, wasip1.RIGHT_FD_READ)
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdFilestatSetSizeName, uint64(fd), 0)
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdFilestatSetTimesName, uint64(fd), 0, 0, 0)
+
+	// Files can't be opened to write, created or removed.
+	requirePathOpenErrno(t, wasip1.ErrnoAcces, mod, "file.txt", 0, wasip1.RIGHT_FD_WRITE)
+	requirePathOpenErrno(t, wasip1.ErrnoAcces, mod, "file.txt", wasip1.O_TRUNC, wasip1.RIGHT_FD_READ)
+	requirePathOpenErrno(t, wasip1.ErrnoAcces, mod, "new.txt", wasip1.O_CREAT, wasip1.RIGHT_FD_READ)
+	mod.Memory().Write(0, []byte("file.txt"))
+	requireErrnoResult(t, wasip1.ErrnoAcces, mod, wasip1.PathUnlinkFileName, uint64(sys.FdPreopen), 0, 8)
+
+	buf, err := os.ReadFile(joinPath(tmpDir, "file.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(buf))
+	_, err = os.Stat(joinPath(tmpDir, "new.txt"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_mountRights_AppendOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, tmpDir, "log.txt", []byte("0123"))
+
+	rights := wasi_snapshot_preview1.RightsAppendOnly
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(tmpDir, "/").WithMountRights("/", rights, rights)))
+	defer r.Close(testCtx)
+
+	// Files opened to write only append, even without FD_APPEND.
+	fd := requirePathOpen(t, mod, "log.txt", 0, wasip1.RIGHT_FD_WRITE)
+	requireWrite(t, mod, fd, "wazero")
+
+	// The offset can't be changed or cleared of FD_APPEND.
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdSeekName, uint64(fd), 0, 0, 0)
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdPwriteName, uint64(fd), 0, 0, 0, 0)
+	requireErrnoResult(t, wasip1.ErrnoPerm, mod, wasip1.FdFdstatSetFlagsName, uint64(fd), 0)
+	requireWrite(t, mod, fd, "!")
+
+	// Files can be created, but not truncated.
+	requirePathOpenErrno(t, wasip1.ErrnoAcces, mod, "log.txt", wasip1.O_TRUNC, wasip1.RIGHT_FD_WRITE)
+	newFD := requirePathOpen(t, mod, "new.txt", wasip1.O_CREAT, wasip1.RIGHT_FD_WRITE)
+	requireWrite(t, mod, newFD, "wazero")
+
+	buf, err := os.ReadFile(joinPath(tmpDir, "log.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "0123wazero!", string(buf))
+	buf, err = os.ReadFile(joinPath(tmpDir, "new.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(buf))
+}
+
+// requirePathOpen opens the path relative to the pre-open, with the given
+// oflags and rights, returning the new file descriptor.
+func requirePathOpen(t *testing.T, mod api.Module, path string, oflags uint16, rights uint64) uint32 {
+	requirePathOpenErrno(t, wasip1.ErrnoSuccess, mod, path, oflags, rights)
+	fd, ok := mod.Memory().ReadUint32Le(128)
+	require.True(t, ok)
+	return fd
+}
+
+func requirePathOpenErrno(t *testing.T, expectedErrno wasip1.Errno, mod api.Module, path string, oflags uint16, rights uint64) {
+	mod.Memory().Write(0, []byte(path))
+	requireErrnoResult(t, expectedErrno, mod, wasip1.PathOpenName, uint64(sys.FdPreopen), 0, 0,
+		uint64(len(path)), uint64(oflags), rights, 0, 0, 128)
+}
+
+// requireWrite writes the data to the file descriptor, with fd_write.
+func requireWrite(t *testing.T, mod api.Module, fd uint32, data string) {
+	mem := mod.Memory()
+	mem.Write(200, []byte(data))
+	mem.WriteUint32Le(100, 200)
+	mem.WriteUint32Le(104, uint32(len(data)))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), 100, 1, 108)
+}
@@ -64,6 +64,8 @@ func sockRecvFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 	var conn socketapi.TCPConn
 	if e, ok := fsc.LookupFile(fd); !ok {
 		return sys.EBADF // Not open
+	} else if udp, ok := e.File.(socketapi.UDPConn); ok {
+		return sockRecvDatagram(mem, udp, riData, riDataCount, riFlags, resultRoDatalen, resultRoFlags)
 	} else if conn, ok = e.File.(socketapi.TCPConn); !ok {
 		return sys.EBADF // Not a conn
 	}
@@ -111,6 +113,22 @@ func sockRecvFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 	return 0
 }
 
+// sockRecvDatagram implements sockRecv for a UDP socket, which receives a
+// single datagram.
+func sockRecvDatagram(mem api.Memory, conn socketapi.UDPConn, riData, riDataCount uint32, riFlags uint8, resultRoDatalen, resultRoFlags uint32) sys.Errno {
+	if riFlags != 0 {
+		return sys.ENOTSUP
+	}
+	n, _, roFlags, errno := recvDatagram(mem, riData, riDataCount, conn)
+	if errno != 0 {
+		return errno
+	}
+	if !mem.WriteUint32Le(resultRoDatalen, n) || !mem.WriteUint16Le(resultRoFlags, roFlags) {
+		return sys.EFAULT
+	}
+	return 0
+}
+
 // sockSend is the WASI function named SockSendName which sends a message
 // on a socket.
 //
@@ -139,6 +157,15 @@ func sockSendFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 	var conn socketapi.TCPConn
 	if e, ok := fsc.LookupFile(fd); !ok {
 		return sys.EBADF // Not open
+	} else if udp, ok := e.File.(socketapi.UDPConn); ok {
+		// A UDP socket sends a single datagram, to the address it's
+		// connected to.
+		bufSize, errno := sendDatagram(mem, siData, siDataCount, udp.Write)
+		if errno != 0 {
+			return errno
+		}
+		mem.WriteUint32Le(resultSoDatalen, bufSize)
+		return 0
 	} else if conn, ok = e.File.(socketapi.TCPConn); !ok {
 		return sys.EBADF // Not a conn
 	}
@@ -0,0 +1,671 @@
+package wasi_snapshot_preview1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	socketapi "github.com/tetratelabs/wazero/internal/sock"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// NewSocketExtensionsExporter returns a FunctionExporter of the functions
+// guests open sockets with, which aren't in WASI, but are the socket
+// extensions of WasmEdge, also implemented by WASIX: sock_open, sock_bind,
+// sock_connect, sock_getaddrinfo, sock_send_to and sock_recv_from.
+//
+// These are only allowed to bind, connect or look up what the allow-lists of
+// experimental/sock.Config allow, and fail with sys.EACCES otherwise. A UDP
+// socket which sends or connects before it is bound is bound to an ephemeral
+// port of any address, regardless of the allow-list, like a TCP socket is
+// when it connects.
+//
+// # Example
+//
+//	wasiBuilder := r.NewHostModuleBuilder(ModuleName)
+//	wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(wasiBuilder)
+//	wasi_snapshot_preview1.NewSocketExtensionsExporter().ExportFunctions(wasiBuilder)
+//	_, err := wasiBuilder.Instantiate(ctx)
+//
+//	config := sock.NewConfig().WithAllowedConnect("tcp", "127.0.0.1", 8080)
+//	mod, err := r.InstantiateWithConfig(sock.WithConfig(ctx, config), guest, moduleConfig)
+//
+// # Notes
+//
+//   - WasmEdge also defines sock_accept with other parameters than WASI, so
+//     the one of WASI is kept.
+//   - Connecting a TCP socket blocks, even if non-blocking, until connected
+//     or the context of the call is done, e.g. with wazero.RuntimeConfig
+//     WithCloseOnContextDone.
+//
+// See https://github.com/second-state/wasmedge_wasi_socket
+func NewSocketExtensionsExporter() FunctionExporter {
+	return socketExtensionsExporter{}
+}
+
+type socketExtensionsExporter struct{}
+
+// ExportFunctions implements FunctionExporter.ExportFunctions
+func (socketExtensionsExporter) ExportFunctions(builder wazero.HostModuleBuilder) {
+	exporter := builder.(wasm.HostFuncExporter)
+	exporter.ExportHostFunc(sockOpen)
+	exporter.ExportHostFunc(sockBind)
+	exporter.ExportHostFunc(sockConnect)
+	exporter.ExportHostFunc(sockGetaddrinfo)
+	exporter.ExportHostFunc(sockSendTo)
+	exporter.ExportHostFunc(sockRecvFrom)
+}
+
+// sockOpen is the function named SockOpenName which opens a socket, which
+// isn't bound nor connected.
+//
+// # Parameters
+//
+//   - af: address family, AF_INET4 or AF_INET6.
+//   - socktype: socket type, SOCK_STREAM for TCP or SOCK_DGRAM for UDP.
+//   - resultFd: offset to write the file descriptor of the socket.
+var sockOpen = newHostFunc(
+	wasip1.SockOpenName,
+	sockOpenFn,
+	[]wasm.ValueType{i32, i32, i32},
+	"af", "socktype", "result.fd",
+)
+
+func sockOpenFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+
+	af := uint8(params[0])
+	socktype := uint8(params[1])
+	resultFd := uint32(params[2])
+
+	var network string
+	switch socktype {
+	case wasip1.SOCK_STREAM:
+		network = "tcp"
+	case wasip1.SOCK_DGRAM:
+		network = "udp"
+	default:
+		return sys.EINVAL
+	}
+	switch af {
+	case wasip1.AF_INET4:
+		network += "4"
+	case wasip1.AF_INET6:
+		network += "6"
+	default:
+		return sys.EINVAL
+	}
+
+	fd, errno := fsc.SockOpen(network)
+	if errno != 0 {
+		return errno
+	}
+	if !mod.Memory().WriteUint32Le(resultFd, uint32(fd)) {
+		_ = fsc.CloseFile(fd)
+		return sys.EFAULT
+	}
+	return 0
+}
+
+// sockBind is the function named SockBindName which binds a socket opened by
+// sockOpen to a local address, when allowed.
+//
+// # Parameters
+//
+//   - fd: file descriptor of the socket.
+//   - addr: offset of the address, see readSockAddress.
+//   - port: port number.
+var sockBind = newHostFunc(
+	wasip1.SockBindName,
+	sockBindFn,
+	[]wasm.ValueType{i32, i32, i32},
+	"fd", "addr", "port",
+)
+
+func sockBindFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+
+	fd := int32(params[0])
+	addr, errno := readSockAddress(mod.Memory(), uint32(params[1]), uint32(params[2]))
+	if errno != 0 {
+		return errno
+	}
+
+	e, sock, errno := lookupSocket(sysCtx.FS(), fd)
+	if errno != 0 {
+		return errno
+	} else if !sysCtx.SockConfig().AllowBind(sock.Network(), addr) {
+		return sys.EACCES
+	}
+
+	conn, errno := sock.Bind(addr)
+	if errno == 0 && conn != nil {
+		e.File = fsapi.Adapt(conn)
+	}
+	return errno
+}
+
+// sockConnect is the function named SockConnectName which connects a socket
+// opened by sockOpen to a remote address, when allowed. A UDP socket is only
+// connected to send datagrams to the address, e.g. with sock_send.
+//
+// # Parameters
+//
+//   - fd: file descriptor of the socket.
+//   - addr: offset of the address, see readSockAddress.
+//   - port: port number.
+var sockConnect = newHostFunc(
+	wasip1.SockConnectName,
+	sockConnectFn,
+	[]wasm.ValueType{i32, i32, i32},
+	"fd", "addr", "port",
+)
+
+func sockConnectFn(ctx context.Context, mod api.Module, params []uint64) sys.Errno {
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
+
+	fd := int32(params[0])
+	addr, errno := readSockAddress(mod.Memory(), uint32(params[1]), uint32(params[2]))
+	if errno != 0 {
+		return errno
+	}
+
+	// A bound UDP socket is connected by setting the address it sends to.
+	if e, ok := fsc.LookupFile(fd); ok {
+		if conn, ok := e.File.(socketapi.UDPConn); ok {
+			if !sysCtx.SockConfig().AllowConnect(udpNetwork(addr), addr) {
+				return sys.EACCES
+			}
+			return conn.Connect(addr)
+		}
+	}
+
+	e, sock, errno := lookupSocket(fsc, fd)
+	if errno != 0 {
+		return errno
+	} else if !sysCtx.SockConfig().AllowConnect(sock.Network(), addr) {
+		return sys.EACCES
+	}
+
+	conn, errno := sock.Connect(ctx, addr)
+	if errno == 0 {
+		e.File = fsapi.Adapt(conn)
+	}
+	return errno
+}
+
+// sockGetaddrinfo is the function named SockGetaddrinfoName which looks up
+// the addresses of a name and service, when allowed.
+//
+// # Parameters
+//
+//   - node: offset of the name, e.g. "localhost" or an IP address. If empty,
+//     the addresses are the unspecified ones with the flag AI_PASSIVE of the
+//     hints, or else the loopback ones.
+//   - nodeLen: length of the name.
+//   - service: offset of the service, e.g. "80" or "http".
+//   - serviceLen: length of the service.
+//   - hints: offset of the struct addrinfo of the flags, the family and the
+//     socket type of the addresses, where SOCK_ANY is SOCK_STREAM.
+//   - res: offset of the offset of the first struct addrinfo to write the
+//     addresses to, which are linked by ai_next, and written until maxLen.
+//   - maxLen: maximum count of addresses.
+//   - resultResLen: offset to write the count of addresses written.
+//
+// The layout of struct addrinfo, whose ai_canonname isn't written, is:
+//
+//	struct addrinfo {
+//		u16 ai_flags;               // offset 0
+//		u8  ai_family;              // offset 2
+//		u8  ai_socktype;            // offset 3
+//		u8  ai_protocol;            // offset 4
+//		u32 ai_addrlen;             // offset 8
+//		struct sockaddr *ai_addr;   // offset 12
+//		u8 *ai_canonname;           // offset 16
+//		u32 ai_canonnamelen;        // offset 20
+//		struct addrinfo *ai_next;   // offset 24
+//	}
+//
+//	struct sockaddr {
+//		u8  sa_family;              // offset 0
+//		u32 sa_data_len;            // offset 4
+//		u8 *sa_data;                // offset 8
+//	}
+//
+// The sa_data is the port, big-endian, followed by the IP address. Its length
+// and ai_addrlen are the length written, and an address which doesn't fit in
+// the sa_data_len is skipped.
+var sockGetaddrinfo = newHostFunc(
+	wasip1.SockGetaddrinfoName,
+	sockGetaddrinfoFn,
+	[]wasm.ValueType{i32, i32, i32, i32, i32, i32, i32, i32},
+	"node", "node_len", "service", "service_len", "hints", "res", "max_len", "result.res_len",
+)
+
+func sockGetaddrinfoFn(ctx context.Context, mod api.Module, params []uint64) sys.Errno {
+	mem := mod.Memory()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+
+	node, ok := mem.Read(uint32(params[0]), uint32(params[1]))
+	if !ok {
+		return sys.EFAULT
+	}
+	service, ok := mem.Read(uint32(params[2]), uint32(params[3]))
+	if !ok {
+		return sys.EFAULT
+	}
+	hints, ok := mem.Read(uint32(params[4]), 5)
+	if !ok {
+		return sys.EFAULT
+	}
+	res := uint32(params[5])
+	maxLen := uint32(params[6])
+	resultResLen := uint32(params[7])
+
+	flags, family, socktype := le.Uint16(hints), hints[2], hints[3]
+	network, protocol := "tcp", wasip1.IPPROTO_TCP
+	switch socktype {
+	case wasip1.SOCK_ANY:
+		socktype = wasip1.SOCK_STREAM
+	case wasip1.SOCK_STREAM:
+	case wasip1.SOCK_DGRAM:
+		network, protocol = "udp", wasip1.IPPROTO_UDP
+	default:
+		return sys.EINVAL
+	}
+
+	port, errno := lookupPort(ctx, network, string(service))
+	if errno != 0 {
+		return errno
+	}
+	ips, errno := lookupIPs(ctx, sysCtx, string(node), flags&wasip1.AI_PASSIVE != 0)
+	if errno != 0 {
+		return errno
+	}
+
+	next, ok := mem.ReadUint32Le(res)
+	if !ok {
+		return sys.EFAULT
+	}
+	var n uint32
+	for _, ip := range ips {
+		if n == maxLen || next == 0 {
+			break
+		}
+		ipFamily := wasip1.AF_INET6
+		if ip.Is4() {
+			ipFamily = wasip1.AF_INET4
+		}
+		if family != wasip1.AF_UNSPEC && family != ipFamily {
+			continue
+		}
+
+		ai, ok := mem.Read(next, 28)
+		if !ok {
+			return sys.EFAULT
+		}
+		sockaddr, ok := mem.Read(le.Uint32(ai[12:]), 12)
+		if !ok {
+			return sys.EFAULT
+		}
+		data := append([]byte{byte(port >> 8), byte(port)}, ip.AsSlice()...)
+		if le.Uint32(sockaddr[4:]) < uint32(len(data)) {
+			continue // The address doesn't fit.
+		} else if !mem.Write(le.Uint32(sockaddr[8:]), data) {
+			return sys.EFAULT
+		}
+		sockaddr[0] = ipFamily
+		le.PutUint32(sockaddr[4:], uint32(len(data)))
+
+		le.PutUint16(ai[0:], flags)
+		ai[2], ai[3], ai[4] = ipFamily, socktype, protocol
+		le.PutUint32(ai[8:], uint32(len(data)))
+		le.PutUint32(ai[20:], 0) // ai_canonnamelen
+		n++
+		next = le.Uint32(ai[24:])
+	}
+
+	if !mem.WriteUint32Le(resultResLen, n) {
+		return sys.EFAULT
+	}
+	return 0
+}
+
+// lookupPort returns the port of the service, which is a number or the name
+// of a service.
+func lookupPort(ctx context.Context, network, service string) (uint16, sys.Errno) {
+	if service == "" {
+		return 0, 0
+	} else if port, err := strconv.ParseUint(service, 10, 16); err == nil {
+		return uint16(port), 0
+	} else if port, err := net.DefaultResolver.LookupPort(ctx, network, service); err == nil {
+		return uint16(port), 0
+	}
+	return 0, sys.ENOENT
+}
+
+// lookupIPs returns the IP addresses of the node, which is only looked up if
+// allowed, unless it is an IP address.
+func lookupIPs(ctx context.Context, sysCtx *internalsys.Context, node string, passive bool) ([]netip.Addr, sys.Errno) {
+	if node == "" {
+		if passive {
+			return []netip.Addr{netip.IPv4Unspecified(), netip.IPv6Unspecified()}, 0
+		}
+		return []netip.Addr{netip.AddrFrom4([4]byte{127, 0, 0, 1}), netip.IPv6Loopback()}, 0
+	} else if ip, err := netip.ParseAddr(node); err == nil {
+		return []netip.Addr{ip.Unmap()}, 0
+	} else if !sysCtx.SockConfig().AllowLookup(node) {
+		return nil, sys.EACCES
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", node)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, sys.ENOENT
+	} else if err != nil {
+		return nil, sys.EIO
+	}
+	for i := range ips {
+		ips[i] = ips[i].Unmap()
+	}
+	return ips, 0
+}
+
+// sockSendTo is the function named SockSendToName which sends a datagram to
+// an address, when allowed. A UDP socket opened by sockOpen is bound to an
+// ephemeral port first.
+//
+// # Parameters
+//
+//   - fd: file descriptor of the socket.
+//   - siData: offset of the iovec array of the datagram.
+//   - siDataLen: count of the iovec array.
+//   - addr: offset of the address, see readSockAddress.
+//   - port: port number.
+//   - siFlags: flags, which must be zero.
+//   - resultSoDatalen: offset to write the count of bytes sent.
+var sockSendTo = newHostFunc(
+	wasip1.SockSendToName,
+	sockSendToFn,
+	[]wasm.ValueType{i32, i32, i32, i32, i32, i32, i32},
+	"fd", "si_data", "si_data_len", "addr", "port", "si_flags", "result.so_datalen",
+)
+
+func sockSendToFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+	mem := mod.Memory()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+
+	fd := int32(params[0])
+	siData := uint32(params[1])
+	siDataCount := uint32(params[2])
+	siFlags := uint32(params[5])
+	resultSoDatalen := uint32(params[6])
+
+	if siFlags != 0 {
+		return sys.ENOTSUP
+	}
+	addr, errno := readSockAddress(mem, uint32(params[3]), uint32(params[4]))
+	if errno != 0 {
+		return errno
+	}
+
+	conn, errno := lookupUDPConn(sysCtx.FS(), fd)
+	if errno != 0 {
+		return errno
+	} else if !sysCtx.SockConfig().AllowConnect(udpNetwork(addr), addr) {
+		return sys.EACCES
+	}
+
+	n, errno := sendDatagram(mem, siData, siDataCount, func(buf []byte) (int, sys.Errno) {
+		return conn.SendTo(buf, addr)
+	})
+	if errno != 0 {
+		return errno
+	}
+	if !mem.WriteUint32Le(resultSoDatalen, n) {
+		return sys.EFAULT
+	}
+	return 0
+}
+
+// sockRecvFrom is the function named SockRecvFromName which receives a
+// datagram, and the address it was sent from.
+//
+// # Parameters
+//
+//   - fd: file descriptor of the socket, which is bound.
+//   - riData: offset of the iovec array to read the datagram into.
+//   - riDataLen: count of the iovec array.
+//   - addr: offset of the address to write, see readSockAddress.
+//   - riFlags: flags, which must be zero.
+//   - resultPort: offset to write the port number of the address.
+//   - resultRoDatalen: offset to write the count of bytes received.
+//   - resultRoFlags: offset to write RO_RECV_DATA_TRUNCATED if the datagram
+//     was truncated.
+var sockRecvFrom = newHostFunc(
+	wasip1.SockRecvFromName,
+	sockRecvFromFn,
+	[]wasm.ValueType{i32, i32, i32, i32, i32, i32, i32, i32},
+	"fd", "ri_data", "ri_data_len", "addr", "ri_flags", "result.port", "result.ro_datalen", "result.ro_flags",
+)
+
+func sockRecvFromFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+	mem := mod.Memory()
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+
+	fd := int32(params[0])
+	riData := uint32(params[1])
+	riDataCount := uint32(params[2])
+	addrOffset := uint32(params[3])
+	riFlags := uint16(params[4])
+	resultPort := uint32(params[5])
+	resultRoDatalen := uint32(params[6])
+	resultRoFlags := uint32(params[7])
+
+	if riFlags != 0 {
+		return sys.ENOTSUP
+	}
+
+	var conn socketapi.UDPConn
+	if e, ok := fsc.LookupFile(fd); !ok {
+		return sys.EBADF // Not open
+	} else if conn, ok = e.File.(socketapi.UDPConn); !ok {
+		return sys.EINVAL // Not a bound UDP socket.
+	}
+
+	n, addr, roFlags, errno := recvDatagram(mem, riData, riDataCount, conn)
+	if errno != 0 {
+		return errno
+	}
+	if errno = writeSockAddress(mem, addrOffset, addr); errno != 0 {
+		return errno
+	}
+	if !mem.WriteUint32Le(resultPort, uint32(addr.Port())) ||
+		!mem.WriteUint32Le(resultRoDatalen, n) ||
+		!mem.WriteUint16Le(resultRoFlags, roFlags) {
+		return sys.EFAULT
+	}
+	return 0
+}
+
+// sendDatagram sends the iovec array as a single datagram with send.
+func sendDatagram(mem api.Memory, iovs, iovsCount uint32, send func([]byte) (int, sys.Errno)) (uint32, sys.Errno) {
+	var buf []byte
+	if _, errno := writev(mem, iovs, iovsCount, func(b []byte) (int, sys.Errno) {
+		buf = append(buf, b...)
+		return len(b), 0
+	}); errno != 0 {
+		return 0, errno
+	}
+	n, errno := send(buf)
+	return uint32(n), errno
+}
+
+// recvDatagram receives a single datagram into the iovec array, and returns
+// its size, the address it was sent from, and RO_RECV_DATA_TRUNCATED if it
+// didn't fit.
+func recvDatagram(mem api.Memory, iovs, iovsCount uint32, conn socketapi.UDPConn) (uint32, netip.AddrPort, uint16, sys.Errno) {
+	var size uint32
+	if _, errno := writev(mem, iovs, iovsCount, func(b []byte) (int, sys.Errno) {
+		size += uint32(len(b))
+		return len(b), 0
+	}); errno != 0 {
+		return 0, netip.AddrPort{}, 0, errno
+	}
+
+	// Receive a byte more than the iovec array to know if it's truncated.
+	buf := make([]byte, size+1)
+	n, addr, errno := conn.RecvFrom(buf)
+	if errno != 0 {
+		return 0, addr, 0, errno
+	}
+	var roFlags uint16
+	if uint32(n) > size {
+		n, roFlags = int(size), uint16(wasip1.RO_RECV_DATA_TRUNCATED)
+	}
+	data := buf[:n]
+	if _, errno = readv(mem, iovs, iovsCount, func(b []byte) (int, sys.Errno) {
+		copied := copy(b, data)
+		data = data[copied:]
+		return copied, 0
+	}); errno != 0 {
+		return 0, addr, 0, errno
+	}
+	return uint32(n), addr, roFlags, 0
+}
+
+// readSockAddress reads the address at the offset of the struct
+// {u8 *buf; u32 buf_len}, and returns it with the port.
+//
+// The buffer is the family, a u16 of AF_INET4 or AF_INET6, followed by the IP
+// address, as written by writeSockAddress. A buffer of four bytes only is an
+// IPv4 address.
+func readSockAddress(mem api.Memory, offset, port uint32) (netip.AddrPort, sys.Errno) {
+	if port > 0xffff {
+		return netip.AddrPort{}, sys.EINVAL
+	}
+	buf, errno := sockAddressBuf(mem, offset)
+	if errno != 0 {
+		return netip.AddrPort{}, errno
+	}
+
+	var ip netip.Addr
+	switch {
+	case len(buf) == 4:
+		ip = netip.AddrFrom4([4]byte(buf))
+	case len(buf) >= 6 && le.Uint16(buf) == uint16(wasip1.AF_INET4):
+		ip = netip.AddrFrom4([4]byte(buf[2:6]))
+	case len(buf) >= 18 && le.Uint16(buf) == uint16(wasip1.AF_INET6):
+		ip = netip.AddrFrom16([16]byte(buf[2:18]))
+	default:
+		return netip.AddrPort{}, sys.EINVAL
+	}
+	return netip.AddrPortFrom(ip, uint16(port)), 0
+}
+
+// writeSockAddress writes the address into the buffer of the struct at the
+// offset, like readSockAddress reads it.
+func writeSockAddress(mem api.Memory, offset uint32, addr netip.AddrPort) sys.Errno {
+	buf, errno := sockAddressBuf(mem, offset)
+	if errno != 0 {
+		return errno
+	}
+	ip := addr.Addr().Unmap()
+	family := wasip1.AF_INET6
+	if ip.Is4() {
+		family = wasip1.AF_INET4
+	}
+	if len(buf) < 2+ip.BitLen()/8 {
+		return sys.EINVAL
+	}
+	le.PutUint16(buf, uint16(family))
+	copy(buf[2:], ip.AsSlice())
+	return 0
+}
+
+// sockAddressBuf returns the buffer of the struct {u8 *buf; u32 buf_len} at
+// the offset.
+func sockAddressBuf(mem api.Memory, offset uint32) ([]byte, sys.Errno) {
+	bufOffset, ok := mem.ReadUint32Le(offset)
+	if !ok {
+		return nil, sys.EFAULT
+	}
+	bufLen, ok := mem.ReadUint32Le(offset + 4)
+	if !ok {
+		return nil, sys.EFAULT
+	}
+	buf, ok := mem.Read(bufOffset, bufLen)
+	if !ok {
+		return nil, sys.EFAULT
+	}
+	return buf, 0
+}
+
+// lookupSocket returns the file entry of the file descriptor, and its socket
+// which isn't bound nor connected yet.
+func lookupSocket(fsc *internalsys.FSContext, fd int32) (*internalsys.FileEntry, socketapi.Socket, sys.Errno) {
+	if e, ok := fsc.LookupFile(fd); !ok {
+		return nil, nil, sys.EBADF // Not open
+	} else if sock, ok := e.File.(socketapi.Socket); ok {
+		return e, sock, 0
+	} else if isSocket(e.File) {
+		return nil, nil, sys.EINVAL // Already bound or connected.
+	}
+	return nil, nil, sys.ENOTSOCK
+}
+
+// lookupUDPConn returns the UDP socket of the file descriptor, which is bound
+// to an ephemeral port if it isn't yet. This isn't checked by the allow-list
+// of binds, as documented on NewSocketExtensionsExporter.
+func lookupUDPConn(fsc *internalsys.FSContext, fd int32) (socketapi.UDPConn, sys.Errno) {
+	e, ok := fsc.LookupFile(fd)
+	if !ok {
+		return nil, sys.EBADF // Not open
+	} else if conn, ok := e.File.(socketapi.UDPConn); ok {
+		return conn, 0
+	} else if sock, ok := e.File.(socketapi.Socket); !ok {
+		if isSocket(e.File) {
+			return nil, sys.ENOTSUP // Not a UDP socket.
+		}
+		return nil, sys.ENOTSOCK
+	} else if network := sock.Network(); network != "udp4" && network != "udp6" {
+		return nil, sys.ENOTSUP // Not a UDP socket.
+	} else {
+		unspecified := netip.IPv6Unspecified()
+		if network == "udp4" {
+			unspecified = netip.IPv4Unspecified()
+		}
+		conn, errno := sock.Bind(netip.AddrPortFrom(unspecified, 0))
+		if errno != 0 {
+			return nil, errno
+		}
+		e.File = fsapi.Adapt(conn)
+		return conn, 0
+	}
+}
+
+// isSocket returns true if the file is a socket.
+func isSocket(f sys.File) bool {
+	switch f.(type) {
+	case socketapi.TCPSock, socketapi.TCPConn, socketapi.UDPConn, socketapi.Socket:
+		return true
+	}
+	return false
+}
+
+// udpNetwork returns the UDP network of the address, "udp4" or "udp6".
+func udpNetwork(addr netip.AddrPort) string {
+	if addr.Addr().Unmap().Is4() {
+		return "udp4"
+	}
+	return "udp6"
+}
@@ -0,0 +1,302 @@
+package wasi_snapshot_preview1_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	experimentalsock "github.com/tetratelabs/wazero/experimental/sock"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+func Test_sockOpen(t *testing.T) {
+	mod, r, log := requireSocketExtensionsModule(testCtx, t)
+	defer r.Close(testCtx)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockOpenName, uint64(wasip1.AF_INET4), uint64(wasip1.SOCK_STREAM), 128)
+	fd, _ := mod.Memory().ReadUint32Le(128)
+	require.Equal(t, uint32(3), fd)
+
+	// The socket is a stream until it's connected.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(fd), 128)
+	ftype, _ := mod.Memory().ReadByte(128)
+	require.Equal(t, wasip1.FILETYPE_SOCKET_STREAM, ftype)
+
+	requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.SockOpenName, uint64(wasip1.AF_UNSPEC), uint64(wasip1.SOCK_STREAM), 128)
+	require.Equal(t, `
+==> wasi_snapshot_preview1.sock_open(af=1,socktype=2)
+<== (fd=3,errno=ESUCCESS)
+==> wasi_snapshot_preview1.sock_open(af=0,socktype=2)
+<== (fd=,errno=EINVAL)
+`, "\n"+log.String())
+}
+
+func Test_sockConnect(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	t.Run("allowed", func(t *testing.T) {
+		config := experimentalsock.NewConfig().WithAllowedConnect("tcp", "127.0.0.1", port)
+		mod, r, _ := requireSocketExtensionsModule(experimentalsock.WithConfig(testCtx, config), t)
+		defer r.Close(testCtx)
+
+		fd := requireSockOpen(t, mod, wasip1.SOCK_STREAM)
+		writeSockAddress(t, mod, 0, netip.MustParseAddr("127.0.0.1"))
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockConnectName, uint64(fd), 0, uint64(port))
+
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// The socket is now a connection, which sends with sock_send.
+		require.True(t, mod.Memory().Write(64, []byte("wazero")))
+		writeIovec(t, mod, 32, 64, 6)
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockSendName, uint64(fd), 32, 1, 0, 128)
+		buf := make([]byte, 6)
+		_, err = conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(buf))
+
+		// It can't be connected twice.
+		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.SockConnectName, uint64(fd), 0, uint64(port))
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		config := experimentalsock.NewConfig().WithAllowedConnect("tcp", "127.0.0.1", port+1)
+		mod, r, _ := requireSocketExtensionsModule(experimentalsock.WithConfig(testCtx, config), t)
+		defer r.Close(testCtx)
+
+		fd := requireSockOpen(t, mod, wasip1.SOCK_STREAM)
+		writeSockAddress(t, mod, 0, netip.MustParseAddr("127.0.0.1"))
+		requireErrnoResult(t, wasip1.ErrnoAcces, mod, wasip1.SockConnectName, uint64(fd), 0, uint64(port))
+	})
+
+	t.Run("no config", func(t *testing.T) {
+		mod, r, _ := requireSocketExtensionsModule(testCtx, t)
+		defer r.Close(testCtx)
+
+		fd := requireSockOpen(t, mod, wasip1.SOCK_STREAM)
+		writeSockAddress(t, mod, 0, netip.MustParseAddr("127.0.0.1"))
+		requireErrnoResult(t, wasip1.ErrnoAcces, mod, wasip1.SockConnectName, uint64(fd), 0, uint64(port))
+	})
+}
+
+func Test_sockSendTo_sockRecvFrom(t *testing.T) {
+	host, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer host.Close()
+	hostPort := host.LocalAddr().(*net.UDPAddr).Port
+
+	config := experimentalsock.NewConfig().
+		WithAllowedBind("udp", "127.0.0.1", 0).
+		WithAllowedConnect("udp", "127.0.0.1", hostPort)
+	mod, r, _ := requireSocketExtensionsModule(experimentalsock.WithConfig(testCtx, config), t)
+	defer r.Close(testCtx)
+
+	fd := requireSockOpen(t, mod, wasip1.SOCK_DGRAM)
+	writeSockAddress(t, mod, 0, netip.MustParseAddr("127.0.0.1"))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockBindName, uint64(fd), 0, 0)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(fd), 128)
+	ftype, _ := mod.Memory().ReadByte(128)
+	require.Equal(t, wasip1.FILETYPE_SOCKET_DGRAM, ftype)
+
+	// Send a datagram of two iovecs to the host.
+	require.True(t, mod.Memory().Write(64, []byte("wazero")))
+	writeIovec(t, mod, 32, 64, 4)
+	writeIovec(t, mod, 40, 68, 2)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockSendToName, uint64(fd), 32, 2, 0, uint64(hostPort), 0, 128)
+	n, _ := mod.Memory().ReadUint32Le(128)
+	require.Equal(t, uint32(6), n)
+
+	buf := make([]byte, 16)
+	n2, guest, err := host.ReadFromUDP(buf)
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(buf[:n2]))
+
+	// Sending to another port isn't allowed.
+	requireErrnoResult(t, wasip1.ErrnoAcces, mod, wasip1.SockSendToName, uint64(fd), 32, 2, 0, uint64(hostPort+1), 0, 128)
+
+	// Receive a datagram larger than the iovec, which is truncated.
+	_, err = host.WriteToUDP([]byte("hello world"), guest)
+	require.NoError(t, err)
+	writeIovec(t, mod, 32, 64, 5)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockRecvFromName, uint64(fd), 32, 1, 0, 0, 128, 132, 136)
+	data, _ := mod.Memory().Read(64, 5)
+	require.Equal(t, "hello", string(data))
+	port, _ := mod.Memory().ReadUint32Le(128)
+	require.Equal(t, uint32(hostPort), port)
+	n, _ = mod.Memory().ReadUint32Le(132)
+	require.Equal(t, uint32(5), n)
+	roFlags, _ := mod.Memory().ReadUint16Le(136)
+	require.Equal(t, uint16(wasip1.RO_RECV_DATA_TRUNCATED), roFlags)
+	address, _ := mod.Memory().Read(8, 6)
+	require.Equal(t, []byte{wasip1.AF_INET4, 0, 127, 0, 0, 1}, address)
+
+	// Results past the end of memory fault.
+	memSize := uint64(mod.Memory().Size())
+	requireErrnoResult(t, wasip1.ErrnoFault, mod, wasip1.SockSendToName, uint64(fd), 32, 2, 0, uint64(hostPort), 0, memSize)
+	_, _, err = host.ReadFromUDP(buf)
+	require.NoError(t, err)
+	_, err = host.WriteToUDP([]byte("hello"), guest)
+	require.NoError(t, err)
+	requireErrnoResult(t, wasip1.ErrnoFault, mod, wasip1.SockRecvFromName, uint64(fd), 32, 1, 0, 0, 128, 132, memSize)
+
+	// Once connected, the socket sends and receives with sock_send and sock_recv.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockConnectName, uint64(fd), 0, uint64(hostPort))
+	writeIovec(t, mod, 32, 64, 6)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockSendName, uint64(fd), 32, 1, 0, 128)
+	n2, _, err = host.ReadFromUDP(buf)
+	require.NoError(t, err)
+	require.Equal(t, "helloo", string(buf[:n2]))
+
+	_, err = host.WriteToUDP([]byte("wazero"), guest)
+	require.NoError(t, err)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockRecvName, uint64(fd), 32, 1, 0, 128, 132)
+	data, _ = mod.Memory().Read(64, 6)
+	require.Equal(t, "wazero", string(data))
+
+	_, err = host.WriteToUDP([]byte("wazero"), guest)
+	require.NoError(t, err)
+	requireErrnoResult(t, wasip1.ErrnoFault, mod, wasip1.SockRecvName, uint64(fd), 32, 1, 0, 128, memSize)
+}
+
+func Test_sockBind_Denied(t *testing.T) {
+	config := experimentalsock.NewConfig().WithAllowedBind("tcp", "127.0.0.1", 0)
+	mod, r, _ := requireSocketExtensionsModule(experimentalsock.WithConfig(testCtx, config), t)
+	defer r.Close(testCtx)
+
+	fd := requireSockOpen(t, mod, wasip1.SOCK_DGRAM)
+	writeSockAddress(t, mod, 0, netip.MustParseAddr("127.0.0.1"))
+	requireErrnoResult(t, wasip1.ErrnoAcces, mod, wasip1.SockBindName, uint64(fd), 0, 0)
+
+	// A file which isn't a socket can't be bound.
+	requireErrnoResult(t, wasip1.ErrnoNotsock, mod, wasip1.SockBindName, 1, 0, 0)
+}
+
+func Test_sockGetaddrinfo(t *testing.T) {
+	const (
+		node, service, hints = 64, 96, 104
+		res, resultResLen    = 112, 116
+		ai, sockaddr, data   = 128, 160, 176
+	)
+
+	// getaddrinfo looks up the node, and writes the addresses to a single
+	// struct addrinfo.
+	getaddrinfo := func(t *testing.T, mod api.Module, expectedErrno wasip1.Errno, name string) {
+		mem := mod.Memory()
+		require.True(t, mem.Write(node, []byte(name)))
+		require.True(t, mem.Write(service, []byte("80")))
+		require.True(t, mem.Write(hints, []byte{0, 0, wasip1.AF_INET4, wasip1.SOCK_STREAM, 0}))
+		require.True(t, mem.WriteUint32Le(res, ai))
+		require.True(t, mem.WriteUint32Le(ai+12, sockaddr))
+		require.True(t, mem.WriteUint32Le(ai+24, 0))
+		require.True(t, mem.WriteUint32Le(sockaddr+4, 18))
+		require.True(t, mem.WriteUint32Le(sockaddr+8, data))
+		requireErrnoResult(t, expectedErrno, mod, wasip1.SockGetaddrinfoName,
+			node, uint64(len(name)), service, 2, hints, res, 1, resultResLen)
+	}
+
+	requireLoopback := func(t *testing.T, mod api.Module) {
+		n, _ := mod.Memory().ReadUint32Le(resultResLen)
+		require.Equal(t, uint32(1), n)
+		family, _ := mod.Memory().ReadByte(ai + 2)
+		require.Equal(t, wasip1.AF_INET4, family)
+		socktype, _ := mod.Memory().ReadByte(ai + 3)
+		require.Equal(t, wasip1.SOCK_STREAM, socktype)
+		dataLen, _ := mod.Memory().ReadUint32Le(sockaddr + 4)
+		require.Equal(t, uint32(6), dataLen)
+		address, _ := mod.Memory().Read(data, 6)
+		require.Equal(t, []byte{0, 80, 127, 0, 0, 1}, address)
+	}
+
+	t.Run("IP address", func(t *testing.T) {
+		mod, r, _ := requireSocketExtensionsModule(testCtx, t)
+		defer r.Close(testCtx)
+
+		getaddrinfo(t, mod, wasip1.ErrnoSuccess, "127.0.0.1")
+		requireLoopback(t, mod)
+	})
+
+	t.Run("name denied", func(t *testing.T) {
+		config := experimentalsock.NewConfig().WithAllowedLookup("example.com")
+		mod, r, _ := requireSocketExtensionsModule(experimentalsock.WithConfig(testCtx, config), t)
+		defer r.Close(testCtx)
+
+		getaddrinfo(t, mod, wasip1.ErrnoAcces, "localhost")
+	})
+
+	t.Run("name allowed", func(t *testing.T) {
+		config := experimentalsock.NewConfig().WithAllowedLookup("localhost")
+		mod, r, _ := requireSocketExtensionsModule(experimentalsock.WithConfig(testCtx, config), t)
+		defer r.Close(testCtx)
+
+		getaddrinfo(t, mod, wasip1.ErrnoSuccess, "localhost")
+		requireLoopback(t, mod)
+	})
+}
+
+// requireSocketExtensionsModule is like requireProxyModuleWithContext, but
+// the host module also exports the functions of NewSocketExtensionsExporter.
+func requireSocketExtensionsModule(ctx context.Context, t *testing.T) (api.Module, api.Closer, *bytes.Buffer) {
+	var log bytes.Buffer
+
+	// Set context to one that has an experimental listener
+	ctx = experimental.WithFunctionListenerFactory(ctx,
+		proxy.NewLoggingListenerFactory(&log, logging.LogScopeSock))
+
+	r := wazero.NewRuntime(ctx)
+
+	builder := r.NewHostModuleBuilder(wasi_snapshot_preview1.ModuleName)
+	wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(builder)
+	wasi_snapshot_preview1.NewSocketExtensionsExporter().ExportFunctions(builder)
+	wasiModuleCompiled, err := builder.Compile(ctx)
+	require.NoError(t, err)
+
+	_, err = r.InstantiateModule(ctx, wasiModuleCompiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	proxyBin := proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiModuleCompiled)
+
+	proxyCompiled, err := r.CompileModule(ctx, proxyBin)
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(ctx, proxyCompiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	return mod, r, &log
+}
+
+// requireSockOpen opens an IPv4 socket of the type, and returns its file
+// descriptor.
+func requireSockOpen(t *testing.T, mod api.Module, socktype uint8) uint32 {
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockOpenName, uint64(wasip1.AF_INET4), uint64(socktype), 256)
+	fd, _ := mod.Memory().ReadUint32Le(256)
+	return fd
+}
+
+// writeSockAddress writes the struct of the address at the offset, followed
+// by its buffer.
+func writeSockAddress(t *testing.T, mod api.Module, offset uint32, ip netip.Addr) {
+	buf := append([]byte{wasip1.AF_INET4, 0}, ip.AsSlice()...)
+	require.True(t, mod.Memory().WriteUint32Le(offset, offset+8))
+	require.True(t, mod.Memory().WriteUint32Le(offset+4, uint32(len(buf))))
+	require.True(t, mod.Memory().Write(offset+8, buf))
+}
+
+// writeIovec writes the iovec of the buffer at the offset.
+func writeIovec(t *testing.T, mod api.Module, offset, buf, bufLen uint32) {
+	require.True(t, mod.Memory().WriteUint32Le(offset, buf))
+	require.True(t, mod.Memory().WriteUint32Le(offset+4, bufLen))
+}
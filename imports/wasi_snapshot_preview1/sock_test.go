@@ -2,7 +2,10 @@ package wasi_snapshot_preview1_test
 
 import (
 	"bytes"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -376,3 +379,98 @@ func requireTCPListenerAddr(t *testing.T, mod api.Module) *net.TCPAddr {
 	require.True(t, ok)
 	return sock.File.(addr).Addr()
 }
+
+func Test_sockRecv_UDPListener(t *testing.T) {
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithUDPListener("127.0.0.1", 0))
+
+	mod, r, _ := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	sock, ok := mod.(*wasm.ModuleInstance).Sys.FS().LookupFile(sys.FdPreopen)
+	require.True(t, ok)
+	client, err := net.DialUDP("udp", nil, sock.File.(interface{ Addr() *net.UDPAddr }).Addr())
+	require.NoError(t, err)
+	defer client.Close()
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(sys.FdPreopen), 128)
+	ftype, _ := mod.Memory().ReadByte(128)
+	require.Equal(t, wasip1.FILETYPE_SOCKET_DGRAM, ftype)
+
+	// The socket is ready to read once a datagram is received.
+	_, err = client.Write([]byte("ping"))
+	require.NoError(t, err)
+	mod.Memory().Write(0, fdReadSubFd(byte(sys.FdPreopen)))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName, 0, 128, 1, 256)
+	nevents, _ := mod.Memory().ReadUint32Le(256)
+	require.Equal(t, uint32(1), nevents)
+
+	// Receive the datagram, and reply to it.
+	writeIovec(t, mod, 32, 64, 8)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockRecvName, uint64(sys.FdPreopen), 32, 1, 0, 128, 132)
+	n, _ := mod.Memory().ReadUint32Le(128)
+	data, _ := mod.Memory().Read(64, n)
+	require.Equal(t, "ping", string(data))
+
+	mod.Memory().Write(64, []byte("pong"))
+	writeIovec(t, mod, 32, 64, 4)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockSendName, uint64(sys.FdPreopen), 32, 1, 0, 128)
+	buf := make([]byte, 8)
+	read, err := client.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(buf[:read]))
+}
+
+func Test_sockAccept_UnixListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sock")
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithUnixListener(path))
+
+	mod, r, _ := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockAcceptName, uint64(sys.FdPreopen), 0, 128)
+	connFd, _ := mod.Memory().ReadUint32Le(128)
+	require.Equal(t, uint32(4), connFd)
+
+	_, err = conn.Write([]byte("wazero"))
+	require.NoError(t, err)
+	writeIovec(t, mod, 32, 64, 6)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockRecvName, uint64(connFd), 32, 1, 0, 128, 132)
+	data, _ := mod.Memory().Read(64, 6)
+	require.Equal(t, "wazero", string(data))
+
+	// Closing the module removes the socket.
+	require.NoError(t, r.Close(testCtx))
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_sockSend_Connection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithConnection("tcp", listener.Addr().String()))
+
+	mod, r, _ := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(sys.FdPreopen), 128)
+	ftype, _ := mod.Memory().ReadByte(128)
+	require.Equal(t, wasip1.FILETYPE_SOCKET_STREAM, ftype)
+
+	mod.Memory().Write(64, []byte("wazero"))
+	writeIovec(t, mod, 32, 64, 6)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockSendName, uint64(sys.FdPreopen), 32, 1, 0, 128)
+	buf := make([]byte, 6)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(buf))
+}
@@ -116,38 +116,6 @@ func requireProxyModuleWithContext(ctx context.Context, t *testing.T, config waz
 	return mod, r, &log
 }
 
-// requireErrnoNosys ensures a call of the given function returns errno. The log
-// message returned can verify the output is wasm `-->` or a host `==>`
-// function.
-func requireErrnoNosys(t *testing.T, funcName string, params ...uint64) string {
-	var log bytes.Buffer
-
-	// Set context to one that has an experimental listener
-	ctx := experimental.WithFunctionListenerFactory(testCtx,
-		proxy.NewLoggingListenerFactory(&log, logging.LogScopeAll))
-
-	r := wazero.NewRuntime(ctx)
-	defer r.Close(ctx)
-
-	// Instantiate the wasi module.
-	wasiModuleCompiled, err := wasi_snapshot_preview1.NewBuilder(r).Compile(ctx)
-	require.NoError(t, err)
-
-	_, err = r.InstantiateModule(ctx, wasiModuleCompiled, wazero.NewModuleConfig())
-	require.NoError(t, err)
-
-	proxyBin := proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiModuleCompiled)
-
-	proxyCompiled, err := r.CompileModule(ctx, proxyBin)
-	require.NoError(t, err)
-
-	mod, err := r.InstantiateModule(ctx, proxyCompiled, wazero.NewModuleConfig())
-	require.NoError(t, err)
-
-	requireErrnoResult(t, wasip1.ErrnoNosys, mod, funcName, params...)
-	return "\n" + log.String()
-}
-
 func requireErrnoResult(t *testing.T, expectedErrno wasip1.Errno, mod api.Closer, funcName string, params ...uint64) {
 	results, err := mod.(api.Module).ExportedFunction(funcName).Call(testCtx, params...)
 	require.NoError(t, err)
@@ -0,0 +1,4 @@
+;; memory exports a shared memory of one page.
+(module
+  (memory (export "memory") 1 1 shared)
+)
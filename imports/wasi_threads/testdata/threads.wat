@@ -0,0 +1,30 @@
+;; threads spawns threads, which each add their start argument to a sum.
+(module
+  (import "env" "memory" (memory 1 1 shared))
+  (import "wasi" "thread-spawn" (func $thread_spawn (param i32) (result i32)))
+
+  ;; run spawns $n threads, whose start arguments are 1 to $n, waits until
+  ;; they're done, and returns the sum.
+  (func (export "run") (param $n i32) (result i32)
+    (local $i i32)
+    (local $done i32)
+    (loop $spawn
+      (local.set $i (i32.add (local.get $i) (i32.const 1)))
+      (if (i32.lt_s (call $thread_spawn (local.get $i)) (i32.const 0))
+        (then (unreachable)))
+      (br_if $spawn (i32.lt_u (local.get $i) (local.get $n))))
+    (loop $wait
+      (local.set $done (i32.atomic.load (i32.const 4)))
+      (if (i32.lt_u (local.get $done) (local.get $n))
+        (then
+          (drop (memory.atomic.wait32 (i32.const 4) (local.get $done) (i64.const -1)))
+          (br $wait))))
+    (i32.atomic.load (i32.const 0)))
+
+  ;; wasi_thread_start adds the start argument to the sum at address zero,
+  ;; and counts the threads done at address four.
+  (func (export "wasi_thread_start") (param $tid i32) (param $arg i32)
+    (drop (i32.atomic.rmw.add (i32.const 0) (local.get $arg)))
+    (drop (i32.atomic.rmw.add (i32.const 4) (i32.const 1)))
+    (drop (memory.atomic.notify (i32.const 4) (i32.const -1))))
+)
@@ -0,0 +1,267 @@
+// Package wasi_threads contains the Go-defined function imported by
+// WebAssembly compiled for wasi-threads, such as with the target
+// wasm32-wasip1-threads, to spawn threads.
+//
+// The function "thread-spawn" of the module ModuleName instantiates the
+// calling module again, and calls the function ThreadStartName exported by the
+// new instance on a goroutine. The new instance imports the same shared
+// memory, and shares the system context of the calling module, such as its
+// open files.
+//
+// # Termination
+//
+// A trap, or an exit such as with proc_exit, in any thread terminates all the
+// threads of the module, including the module itself: they're closed with the
+// exit code, or 1 on a trap. Closing the module, such as when its function
+// "_start" returns or traps, terminates its threads likewise.
+//
+// A thread which is running when terminated is only interrupted when the
+// wazero.Runtime is configured with wazero.RuntimeConfig
+// WithCloseOnContextDone. Otherwise, it runs until its function
+// ThreadStartName returns.
+//
+// See https://github.com/WebAssembly/wasi-threads
+package wasi_threads
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	// ModuleName is the module name the function ThreadSpawnName is exported
+	// into.
+	ModuleName = "wasi"
+
+	// ThreadSpawnName is the name of the function which spawns a thread.
+	ThreadSpawnName = "thread-spawn"
+
+	// ThreadStartName is the name of the function a module exports to run a
+	// thread, which is called with the thread ID and the start argument
+	// passed to ThreadSpawnName.
+	ThreadStartName = "wasi_thread_start"
+)
+
+const i32 = wasm.ValueTypeI32
+
+// maxThreadID is the maximum thread ID, as the upper bits of a thread ID
+// are reserved by wasi-libc.
+//
+// See https://github.com/WebAssembly/wasi-threads#design-choice-thread-ids
+const maxThreadID = 0x1fffffff
+
+// trapExitCode is the exit code of the threads terminated by a trap.
+const trapExitCode = 1
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+//   - The runtime must be configured with experimental.CoreFeaturesThreads,
+//     and the modules spawning threads must import their memory, which must
+//     be shared, e.g. from a module named "env" which exports it.
+//   - The host functions called by the threads must be safe for concurrent
+//     use. Notably, opening or closing files concurrently with WASI isn't.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	return newThreads().hostModuleBuilder(r).Instantiate(ctx)
+}
+
+var (
+	errTerminated = errors.New("terminated")
+	errMemory     = errors.New("memory isn't imported and shared")
+	errThreadIDs  = errors.New("thread IDs exhausted")
+)
+
+// threads is the state of the module ModuleName.
+type threads struct {
+	mu sync.Mutex
+	// groups are the groups of the modules which spawned threads, and of
+	// their threads.
+	groups map[*wasm.ModuleInstance]*group
+}
+
+func newThreads() *threads {
+	return &threads{groups: map[*wasm.ModuleInstance]*group{}}
+}
+
+// hostModuleBuilder returns a new wazero.HostModuleBuilder for ModuleName,
+// whose state is t.
+func (t *threads) hostModuleBuilder(r wazero.Runtime) wazero.HostModuleBuilder {
+	builder := r.NewHostModuleBuilder(ModuleName)
+	builder.(wasm.HostFuncExporter).ExportHostFunc(&wasm.HostFunc{
+		ExportName:  ThreadSpawnName,
+		Name:        "thread_spawn",
+		ParamTypes:  []api.ValueType{i32},
+		ParamNames:  []string{"start_arg"},
+		ResultTypes: []api.ValueType{i32},
+		ResultNames: []string{"tid"},
+		Code:        wasm.Code{GoFunc: api.GoModuleFunc(t.threadSpawn)},
+	})
+	return builder
+}
+
+// group is a module which spawned threads, and its threads, which terminate
+// together.
+type group struct {
+	t    *threads
+	main *wasm.ModuleInstance
+
+	// The fields below are guarded by threads.mu.
+
+	// lastID is the ID of the last thread spawned.
+	lastID uint32
+	// live are the threads which are running.
+	live       map[*wasm.ModuleInstance]struct{}
+	terminated bool
+}
+
+// threadSpawn implements ThreadSpawnName, which returns the ID of the thread,
+// or -1 if it failed to spawn.
+func (t *threads) threadSpawn(ctx context.Context, mod api.Module, stack []uint64) {
+	startArg := uint32(stack[0])
+	tid, err := t.spawn(ctx, mod.(*wasm.ModuleInstance), startArg)
+	if err != nil {
+		stack[0] = api.EncodeI32(-1)
+		return
+	}
+	stack[0] = uint64(tid)
+}
+
+// spawn instantiates a thread of the module, and calls its function
+// ThreadStartName on a goroutine.
+func (t *threads) spawn(ctx context.Context, mod *wasm.ModuleInstance, startArg uint32) (uint32, error) {
+	g, err := t.groupOf(mod)
+	if err != nil {
+		return 0, err
+	}
+
+	thread, err := mod.InstantiateThread(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if mem := thread.MemoryInstance; mem == nil || !mem.Shared || mem != mod.MemoryInstance {
+		_ = thread.Close(ctx)
+		return 0, errMemory
+	}
+	start := thread.ExportedFunction(ThreadStartName)
+	if start == nil || !slices.Equal(start.Definition().ParamTypes(), []api.ValueType{i32, i32}) ||
+		len(start.Definition().ResultTypes()) != 0 {
+		_ = thread.Close(ctx)
+		return 0, errors.New("function " + ThreadStartName + "(i32, i32) not exported")
+	}
+
+	tid, err := g.add(thread)
+	if err != nil {
+		_ = thread.Close(ctx)
+		return 0, err
+	}
+	go g.run(ctx, thread, start, tid, startArg)
+	return tid, nil
+}
+
+// groupOf returns the group of the module, which is a new group unless the
+// module already spawned threads or is a thread.
+func (t *threads) groupOf(mod *wasm.ModuleInstance) (*group, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if g, ok := t.groups[mod]; ok {
+		return g, nil
+	} else if mod.Thread || mod.IsClosed() {
+		return nil, errTerminated // The group of the thread was terminated.
+	}
+
+	g := &group{t: t, main: mod, live: map[*wasm.ModuleInstance]struct{}{}}
+	// Closing the module terminates its threads. This is called with the
+	// store locked when the runtime is closed, so terminate asynchronously
+	// as closing the threads locks it too.
+	if !mod.AddCloseNotifier(experimental.CloseNotifyFunc(func(ctx context.Context, exitCode uint32) {
+		go g.terminate(ctx, exitCode)
+	})) {
+		return nil, errTerminated // The module was closed concurrently.
+	}
+	t.groups[mod] = g
+	return g, nil
+}
+
+// add adds the thread to the group, and returns its ID.
+func (g *group) add(thread *wasm.ModuleInstance) (uint32, error) {
+	t := g.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if g.terminated {
+		return 0, errTerminated
+	} else if g.lastID == maxThreadID {
+		return 0, errThreadIDs
+	}
+	g.lastID++
+	g.live[thread] = struct{}{}
+	t.groups[thread] = g
+	return g.lastID, nil
+}
+
+// run calls the function ThreadStartName of the thread, and terminates the
+// group if it fails.
+func (g *group) run(ctx context.Context, thread *wasm.ModuleInstance, start api.Function, tid, startArg uint32) {
+	_, err := start.Call(ctx, uint64(tid), uint64(startArg))
+
+	t := g.t
+	t.mu.Lock()
+	delete(g.live, thread)
+	delete(t.groups, thread)
+	t.mu.Unlock()
+
+	if err != nil {
+		exitCode := uint32(trapExitCode)
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		g.terminate(ctx, exitCode)
+	}
+	_ = thread.Close(ctx)
+}
+
+// terminate closes the module which spawned the threads and the threads
+// running with the exit code, unless they're already terminated.
+func (g *group) terminate(ctx context.Context, exitCode uint32) {
+	t := g.t
+	t.mu.Lock()
+	if g.terminated {
+		t.mu.Unlock()
+		return
+	}
+	g.terminated = true
+	modules := []*wasm.ModuleInstance{g.main}
+	for thread := range g.live {
+		modules = append(modules, thread)
+	}
+	for _, m := range modules {
+		delete(t.groups, m)
+	}
+	g.live = nil
+	t.mu.Unlock()
+
+	for _, m := range modules {
+		_ = m.CloseWithExitCode(ctx, exitCode)
+	}
+}
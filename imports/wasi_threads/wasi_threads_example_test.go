@@ -0,0 +1,59 @@
+package wasi_threads_test
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_threads"
+)
+
+// memoryWasm is the binary format of testdata/memory.wat
+//
+//go:embed testdata/memory.wasm
+var memoryWasm []byte
+
+// threadsWasm is the binary format of testdata/threads.wat
+//
+//go:embed testdata/threads.wasm
+var threadsWasm []byte
+
+// This shows how to instantiate the function which spawns threads, for a
+// module which imports its shared memory.
+func Example_threadSpawn() {
+	ctx := context.Background()
+
+	// Threads support must be enabled explicitly in addition to standard V2 features.
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads).
+		WithCloseOnContextDone(true))
+	defer r.Close(ctx) // This closes everything this Runtime created.
+
+	// The module imports its shared memory from the module "env".
+	if _, err := r.InstantiateWithConfig(ctx, memoryWasm, wazero.NewModuleConfig().WithName("env")); err != nil {
+		log.Panicln(err)
+	}
+
+	// This adds the module "wasi", whose function "thread-spawn" runs a new
+	// instance of the calling module on a goroutine.
+	wasi_threads.MustInstantiate(ctx, r)
+
+	mod, err := r.Instantiate(ctx, threadsWasm)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	// run spawns 4 threads, which add their start arguments 1 to 4 to a sum.
+	res, err := mod.ExportedFunction("run").Call(ctx, 4)
+	if err != nil {
+		log.Panicln(err)
+	}
+	fmt.Println(res[0])
+
+	// Output:
+	// 10
+}
@@ -0,0 +1,258 @@
+package wasi_threads
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+type arbitrary struct{}
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), arbitrary{}, "arbitrary")
+
+var (
+	i32_i32     = wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	i32i32_v    = wasm.FunctionType{Params: []wasm.ValueType{i32, i32}}
+	sharedPage  = &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true, IsShared: true}
+	spawnImport = wasm.Import{Type: wasm.ExternTypeFunc, Module: ModuleName, Name: ThreadSpawnName, DescFunc: 0}
+)
+
+// threadStart is the body of the function ThreadStartName of the guest, which
+// behaves per its start argument:
+//
+//   - 0: traps.
+//   - 1: loops forever.
+//   - else: stores the thread ID at the address of the argument, and notifies
+//     the waiters on it.
+var threadStart = []byte{
+	wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Eqz,
+	wasm.OpcodeIf, 0x40, wasm.OpcodeUnreachable, wasm.OpcodeEnd,
+	wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Eq,
+	wasm.OpcodeIf, 0x40, wasm.OpcodeLoop, 0x40, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd,
+	wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0,
+	wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Store, 2, 0,
+	wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 1,
+	wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicMemoryNotify, 2, 0, wasm.OpcodeDrop,
+	wasm.OpcodeEnd,
+}
+
+// guest returns a module which imports the function ThreadSpawnName, and
+// exports it as "spawn". It also exports "wait", which waits until the value
+// at an address isn't zero, and returns it.
+func guest(memory wasm.Import, memorySection *wasm.Memory, exportStart bool) []byte {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32_i32, i32i32_v},
+		ImportSection:   []wasm.Import{spawnImport},
+		MemorySection:   memorySection,
+		FunctionSection: []wasm.Index{0, 0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI64Const, 0x7f,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicMemoryWait32, 2, 0, wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Load, 2, 0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: threadStart},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "spawn", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "wait", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	}
+	if memory.Type == wasm.ExternTypeMemory {
+		m.ImportSection = append(m.ImportSection, memory)
+	}
+	if exportStart {
+		m.ExportSection = append(m.ExportSection, wasm.Export{Name: ThreadStartName, Type: wasm.ExternTypeFunc, Index: 3})
+	}
+	return binaryencoding.EncodeModule(m)
+}
+
+var sharedMemory = wasm.Import{Type: wasm.ExternTypeMemory, Module: "env", Name: "memory", DescMem: sharedPage}
+
+// newRuntime returns a runtime with the module ModuleName, whose state is
+// returned, and a module "env", which exports a shared memory.
+func newRuntime(t *testing.T) (wazero.Runtime, *threads) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().
+		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads).
+		WithCloseOnContextDone(true))
+
+	_, err := r.InstantiateWithConfig(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		MemorySection: sharedPage,
+		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory}},
+	}), wazero.NewModuleConfig().WithName("env"))
+	require.NoError(t, err)
+
+	state := newThreads()
+	_, err = state.hostModuleBuilder(r).Instantiate(testCtx)
+	require.NoError(t, err)
+	return r, state
+}
+
+// call calls the function of the module, and returns its result.
+func call(t *testing.T, mod api.Module, name string, params ...uint64) int32 {
+	results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+	require.NoError(t, err)
+	return api.DecodeI32(results[0])
+}
+
+// eventually waits until the condition is true, or fails the test.
+func eventually(t *testing.T, condition func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func Test_threadSpawn(t *testing.T) {
+	r, state := newRuntime(t)
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, guest(sharedMemory, nil, true))
+	require.NoError(t, err)
+
+	// Each thread stores its ID at the address of its argument.
+	require.Equal(t, int32(1), call(t, mod, "spawn", 8))
+	require.Equal(t, int32(1), call(t, mod, "wait", 8))
+	require.Equal(t, int32(2), call(t, mod, "spawn", 16))
+	require.Equal(t, int32(2), call(t, mod, "wait", 16))
+
+	// The threads are closed once they return, unlike the module which spawned them.
+	eventually(t, func() bool {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return len(state.groups) == 1
+	})
+	require.False(t, mod.IsClosed())
+
+	// Closing the module removes its group.
+	require.NoError(t, mod.Close(testCtx))
+	eventually(t, func() bool {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return len(state.groups) == 0
+	})
+}
+
+func Test_threadSpawn_Trap(t *testing.T) {
+	r, state := newRuntime(t)
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, guest(sharedMemory, nil, true))
+	require.NoError(t, err)
+
+	// The first thread loops until it's terminated by the trap of the second.
+	require.Equal(t, int32(1), call(t, mod, "spawn", 1))
+	thread := liveThread(state)
+	require.Equal(t, int32(2), call(t, mod, "spawn", 0))
+
+	eventually(t, mod.IsClosed)
+	_, err = mod.ExportedFunction("spawn").Call(testCtx, 8)
+	require.Equal(t, uint32(trapExitCode), err.(*sys.ExitError).ExitCode())
+	eventually(t, thread.IsClosed)
+}
+
+func Test_threadSpawn_Close(t *testing.T) {
+	r, state := newRuntime(t)
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, guest(sharedMemory, nil, true))
+	require.NoError(t, err)
+
+	require.Equal(t, int32(1), call(t, mod, "spawn", 1))
+	thread := liveThread(state)
+
+	// Closing the module terminates its threads with its exit code.
+	require.NoError(t, mod.CloseWithExitCode(testCtx, 3))
+	eventually(t, thread.IsClosed)
+	require.Equal(t, uint64(3), thread.Closed.Load()>>32)
+}
+
+// Test_threadSpawn_CloseConcurrently ensures threads which exit race neither
+// with a concurrent close of the module which spawned them, nor leak its
+// group. Run this with -race.
+func Test_threadSpawn_CloseConcurrently(t *testing.T) {
+	r, state := newRuntime(t)
+	defer r.Close(testCtx)
+
+	for i := 0; i < 20; i++ {
+		mod, err := r.Instantiate(testCtx, guest(sharedMemory, nil, true))
+		require.NoError(t, err)
+
+		// The thread traps, which exits the module, while the module is
+		// also closed by another goroutine.
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = mod.ExportedFunction("spawn").Call(testCtx, 0)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = mod.CloseWithExitCode(testCtx, 3)
+		}()
+		wg.Wait()
+
+		eventually(t, func() bool {
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			return len(state.groups) == 0
+		})
+	}
+}
+
+func Test_threadSpawn_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		guest []byte
+	}{
+		{
+			name:  "memory not imported",
+			guest: guest(wasm.Import{}, sharedPage, true),
+		},
+		{
+			name:  "start not exported",
+			guest: guest(sharedMemory, nil, false),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r, state := newRuntime(t)
+			defer r.Close(testCtx)
+
+			mod, err := r.Instantiate(testCtx, tc.guest)
+			require.NoError(t, err)
+			require.Equal(t, int32(-1), call(t, mod, "spawn", 8))
+
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			require.Equal(t, 0, len(state.groups[mod.(*wasm.ModuleInstance)].live))
+		})
+	}
+}
+
+// liveThread returns a thread which is running.
+func liveThread(state *threads) *wasm.ModuleInstance {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for m := range state.groups {
+		if m.Thread {
+			return m
+		}
+	}
+	return nil
+}
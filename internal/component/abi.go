@@ -0,0 +1,773 @@
+package component
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// maxStringByteLength is the maximum length of a string in memory, whose length is tagged with the high bit when it's
+// UTF-16 in a Latin-1+UTF-16 encoding.
+const maxStringByteLength = 1<<31 - 1
+
+// utf16Tag is the tag of the length of a string in UTF-16 in a Latin-1+UTF-16 encoding.
+const utf16Tag = 1 << 31
+
+var (
+	errNoMemory   = errors.New("canonical ABI: memory is required")
+	errNoRealloc  = errors.New("canonical ABI: realloc is required")
+	errNoHandles  = errors.New("canonical ABI: resources are only supported in components")
+	errUnaligned  = errors.New("canonical ABI: unaligned pointer")
+	errBadString  = errors.New("canonical ABI: invalid string")
+	errBadChar    = errors.New("canonical ABI: invalid char")
+	errBadCase    = errors.New("canonical ABI: invalid case")
+	errTooLong    = errors.New("canonical ABI: string too long")
+	errNoCase     = errors.New("canonical ABI: variant has no case set")
+	errManyCases  = errors.New("canonical ABI: variant has several cases set")
+	errBadHandle  = errors.New("canonical ABI: invalid handle")
+	errHandleType = errors.New("canonical ABI: handle of another resource type")
+)
+
+// callContext holds the canonical options of a call which lifts and lowers values as per the canonical ABI.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md
+type callContext struct {
+	ctx      context.Context
+	memory   api.Memory
+	realloc  api.Function
+	encoding StringEncoding
+	// handles is the table of the handles of the component instance, or nil if there's none.
+	handles *handleTable
+}
+
+func alignTo(ptr, alignment uint32) uint32 {
+	return (ptr + alignment - 1) / alignment * alignment
+}
+
+// discriminantSize returns the size of the discriminant of a variant of n cases.
+func discriminantSize(n int) uint32 {
+	switch {
+	case n <= 1<<8:
+		return 1
+	case n <= 1<<16:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// alignment returns the alignment of a value of the type in memory.
+func (t *Type) alignment() uint32 {
+	switch t.Kind {
+	case TypeKindBool, TypeKindS8, TypeKindU8:
+		return 1
+	case TypeKindS16, TypeKindU16:
+		return 2
+	case TypeKindS64, TypeKindU64, TypeKindF64:
+		return 8
+	case TypeKindList:
+		if t.Length > 0 {
+			return t.Elem.alignment()
+		}
+		return 4
+	case TypeKindRecord, TypeKindTuple:
+		a := uint32(1)
+		for _, f := range t.Fields {
+			a = max(a, f.Type.alignment())
+		}
+		return a
+	case TypeKindVariant, TypeKindOption, TypeKindResult, TypeKindEnum:
+		cases := t.caseCount()
+		return max(discriminantSize(cases), t.maxCaseAlignment())
+	case TypeKindFlags:
+		return min(flagsSize(len(t.Labels)), 4)
+	default:
+		return 4
+	}
+}
+
+// flagsSize returns the size of flags of n labels, which are stored in 32-bit words above 16 labels.
+func flagsSize(n int) uint32 {
+	switch {
+	case n <= 8:
+		return 1
+	case n <= 16:
+		return 2
+	default:
+		return 4 * uint32((n+31)/32)
+	}
+}
+
+// size returns the size of a value of the type in memory.
+func (t *Type) size() uint32 {
+	switch t.Kind {
+	case TypeKindBool, TypeKindS8, TypeKindU8:
+		return 1
+	case TypeKindS16, TypeKindU16:
+		return 2
+	case TypeKindS64, TypeKindU64, TypeKindF64, TypeKindString:
+		return 8
+	case TypeKindList:
+		if t.Length > 0 {
+			return t.Length * t.Elem.size()
+		}
+		return 8
+	case TypeKindRecord, TypeKindTuple:
+		var s uint32
+		for _, f := range t.Fields {
+			s = alignTo(s, f.Type.alignment()) + f.Type.size()
+		}
+		return alignTo(s, t.alignment())
+	case TypeKindVariant, TypeKindOption, TypeKindResult, TypeKindEnum:
+		s := alignTo(discriminantSize(t.caseCount()), t.maxCaseAlignment())
+		var payload uint32
+		for _, c := range t.Cases() {
+			if c != nil {
+				payload = max(payload, c.size())
+			}
+		}
+		return alignTo(s+payload, t.alignment())
+	case TypeKindFlags:
+		return flagsSize(len(t.Labels))
+	default:
+		return 4
+	}
+}
+
+func (t *Type) caseCount() int {
+	switch t.Kind {
+	case TypeKindOption, TypeKindResult:
+		return 2
+	case TypeKindEnum:
+		return len(t.Labels)
+	default:
+		return len(t.Fields)
+	}
+}
+
+func (t *Type) maxCaseAlignment() uint32 {
+	a := uint32(1)
+	if t.Kind == TypeKindEnum {
+		return a
+	}
+	for _, c := range t.Cases() {
+		if c != nil {
+			a = max(a, c.alignment())
+		}
+	}
+	return a
+}
+
+// payloadOffset returns the offset of the payload of a variant, an option or a result.
+func (t *Type) payloadOffset() uint32 {
+	return alignTo(discriminantSize(t.caseCount()), t.maxCaseAlignment())
+}
+
+func (cx *callContext) mem() api.Memory {
+	if cx.memory == nil {
+		panic(errNoMemory)
+	}
+	return cx.memory
+}
+
+// read returns the bytes of memory in the range, or traps if it's out of bounds.
+func (cx *callContext) read(ptr, n uint32) []byte {
+	b, ok := cx.mem().Read(ptr, n)
+	if !ok {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	return b
+}
+
+// alloc allocates memory with the realloc function of the callee.
+func (cx *callContext) alloc(alignment, size uint32) uint32 {
+	if cx.realloc == nil {
+		panic(errNoRealloc)
+	}
+	res, err := cx.realloc.Call(cx.ctx, 0, 0, uint64(alignment), uint64(size))
+	if err != nil {
+		panic(err)
+	}
+	ptr := uint32(res[0])
+	if ptr%alignment != 0 {
+		panic(errUnaligned)
+	} else if uint64(ptr)+uint64(size) > uint64(cx.mem().Size()) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	return ptr
+}
+
+// load lifts the value of the type at ptr in memory into v.
+func (cx *callContext) load(t *Type, v reflect.Value, ptr uint32) {
+	if ptr%t.alignment() != 0 {
+		panic(errUnaligned)
+	}
+	b := cx.read(ptr, t.size())
+	switch t.Kind {
+	case TypeKindBool:
+		v.SetBool(b[0] != 0)
+	case TypeKindS8:
+		v.SetInt(int64(int8(b[0])))
+	case TypeKindU8:
+		v.SetUint(uint64(b[0]))
+	case TypeKindS16:
+		v.SetInt(int64(int16(binary.LittleEndian.Uint16(b))))
+	case TypeKindU16:
+		v.SetUint(uint64(binary.LittleEndian.Uint16(b)))
+	case TypeKindS32:
+		v.SetInt(int64(int32(binary.LittleEndian.Uint32(b))))
+	case TypeKindU32:
+		v.SetUint(uint64(binary.LittleEndian.Uint32(b)))
+	case TypeKindS64:
+		v.SetInt(int64(binary.LittleEndian.Uint64(b)))
+	case TypeKindU64:
+		v.SetUint(binary.LittleEndian.Uint64(b))
+	case TypeKindF32:
+		v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case TypeKindF64:
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case TypeKindChar:
+		v.SetInt(int64(liftChar(binary.LittleEndian.Uint32(b))))
+	case TypeKindString:
+		v.SetString(cx.liftString(binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])))
+	case TypeKindList:
+		if t.Length > 0 {
+			size := t.Elem.size()
+			for i := 0; i < int(t.Length); i++ {
+				cx.load(t.Elem, v.Index(i), ptr+uint32(i)*size)
+			}
+		} else {
+			cx.liftList(t.Elem, v, binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:]))
+		}
+	case TypeKindRecord, TypeKindTuple:
+		var offset uint32
+		for i, fi := range goFields(v.Type()) {
+			f := t.Fields[i].Type
+			offset = alignTo(offset, f.alignment())
+			cx.load(f, v.Field(fi), ptr+offset)
+			offset += f.size()
+		}
+	case TypeKindVariant, TypeKindOption, TypeKindResult:
+		cx.liftCase(t, v, uint32(loadUint(b, discriminantSize(t.caseCount()))), func(c *Type, pv reflect.Value) {
+			cx.load(c, pv, ptr+t.payloadOffset())
+		})
+	case TypeKindEnum:
+		c := loadUint(b, discriminantSize(len(t.Labels)))
+		if c >= uint64(len(t.Labels)) {
+			panic(errBadCase)
+		}
+		v.SetUint(c)
+	case TypeKindFlags:
+		var bits uint64
+		if len(b) > 4 {
+			bits = uint64(binary.LittleEndian.Uint32(b)) | uint64(binary.LittleEndian.Uint32(b[4:]))<<32
+		} else {
+			bits = loadUint(b, uint32(len(b)))
+		}
+		v.SetUint(bits & flagsMask(len(t.Labels)))
+	case TypeKindOwn, TypeKindBorrow:
+		cx.liftHandle(t, v, binary.LittleEndian.Uint32(b))
+	default:
+		panic(fmt.Sprintf("BUG: %s can't be loaded", t.Kind))
+	}
+}
+
+// store lowers v as a value of the type at ptr in memory.
+func (cx *callContext) store(t *Type, v reflect.Value, ptr uint32) {
+	if ptr%t.alignment() != 0 {
+		panic(errUnaligned)
+	}
+	b := cx.read(ptr, t.size())
+	switch t.Kind {
+	case TypeKindBool:
+		if v.Bool() {
+			b[0] = 1
+		} else {
+			b[0] = 0
+		}
+	case TypeKindS8, TypeKindS16, TypeKindS32, TypeKindS64:
+		storeUint(b, uint64(v.Int()), uint32(len(b)))
+	case TypeKindU8, TypeKindU16, TypeKindU32, TypeKindU64:
+		storeUint(b, v.Uint(), uint32(len(b)))
+	case TypeKindF32:
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v.Float())))
+	case TypeKindF64:
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v.Float()))
+	case TypeKindChar:
+		binary.LittleEndian.PutUint32(b, lowerChar(v.Int()))
+	case TypeKindString:
+		p, n := cx.lowerString(v.String())
+		// The memory may have grown while allocating the string.
+		b = cx.read(ptr, 8)
+		binary.LittleEndian.PutUint32(b, p)
+		binary.LittleEndian.PutUint32(b[4:], n)
+	case TypeKindList:
+		if t.Length > 0 {
+			size := t.Elem.size()
+			for i := 0; i < int(t.Length); i++ {
+				cx.store(t.Elem, v.Index(i), ptr+uint32(i)*size)
+			}
+		} else {
+			p, n := cx.lowerList(t.Elem, v)
+			b = cx.read(ptr, 8)
+			binary.LittleEndian.PutUint32(b, p)
+			binary.LittleEndian.PutUint32(b[4:], n)
+		}
+	case TypeKindRecord, TypeKindTuple:
+		var offset uint32
+		for i, fi := range goFields(v.Type()) {
+			f := t.Fields[i].Type
+			offset = alignTo(offset, f.alignment())
+			cx.store(f, v.Field(fi), ptr+offset)
+			offset += f.size()
+		}
+	case TypeKindVariant, TypeKindOption, TypeKindResult:
+		c, payload, pv := cx.lowerCase(t, v)
+		storeUint(b, uint64(c), discriminantSize(t.caseCount()))
+		if payload != nil {
+			cx.store(payload, pv, ptr+t.payloadOffset())
+		}
+	case TypeKindEnum:
+		if v.Uint() >= uint64(len(t.Labels)) {
+			panic(errBadCase)
+		}
+		storeUint(b, v.Uint(), uint32(len(b)))
+	case TypeKindFlags:
+		bits := v.Uint() & flagsMask(len(t.Labels))
+		if len(b) > 4 {
+			binary.LittleEndian.PutUint32(b, uint32(bits))
+			binary.LittleEndian.PutUint32(b[4:], uint32(bits>>32))
+		} else {
+			storeUint(b, bits, uint32(len(b)))
+		}
+	case TypeKindOwn, TypeKindBorrow:
+		binary.LittleEndian.PutUint32(b, cx.lowerHandle(t, v))
+	default:
+		panic(fmt.Sprintf("BUG: %s can't be stored", t.Kind))
+	}
+}
+
+func loadUint(b []byte, size uint32) uint64 {
+	switch size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	default:
+		return binary.LittleEndian.Uint64(b)
+	}
+}
+
+func storeUint(b []byte, v uint64, size uint32) {
+	switch size {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(b, v)
+	}
+}
+
+func flagsMask(n int) uint64 {
+	if n >= 64 {
+		return math.MaxUint64
+	}
+	return 1<<n - 1
+}
+
+func liftChar(c uint32) rune {
+	if c >= 0x110000 || (c >= 0xd800 && c <= 0xdfff) {
+		panic(errBadChar)
+	}
+	return rune(c)
+}
+
+func lowerChar(c int64) uint32 {
+	if c < 0 {
+		panic(errBadChar)
+	}
+	return uint32(liftChar(uint32(c)))
+}
+
+// liftCase lifts the case c of a variant, an option or a result into v, whose payload is lifted by payload.
+func (cx *callContext) liftCase(t *Type, v reflect.Value, c uint32, payload func(*Type, reflect.Value)) {
+	cases := t.Cases()
+	if int(c) >= len(cases) {
+		panic(errBadCase)
+	}
+	switch t.Kind {
+	case TypeKindOption:
+		v.Field(1).SetBool(c == 1)
+		if c == 1 {
+			payload(t.Elem, v.Field(0))
+		}
+	case TypeKindResult:
+		v.Field(2).SetBool(c == 1)
+		if cases[c] != nil {
+			payload(cases[c], v.Field(int(c)))
+		}
+	default:
+		f := v.Field(goFields(v.Type())[c])
+		pv := reflect.New(f.Type().Elem())
+		if cases[c] != nil {
+			payload(cases[c], pv.Elem())
+		}
+		f.Set(pv)
+	}
+}
+
+// lowerCase returns the case of the variant, the option or the result v, and its payload if any.
+func (cx *callContext) lowerCase(t *Type, v reflect.Value) (uint32, *Type, reflect.Value) {
+	switch t.Kind {
+	case TypeKindOption:
+		if v.Field(1).Bool() {
+			return 1, t.Elem, v.Field(0)
+		}
+		return 0, nil, reflect.Value{}
+	case TypeKindResult:
+		if v.Field(2).Bool() {
+			return 1, t.Err, v.Field(1)
+		}
+		return 0, t.Elem, v.Field(0)
+	default:
+		c := -1
+		for i, fi := range goFields(v.Type()) {
+			if !v.Field(fi).IsNil() {
+				if c >= 0 {
+					panic(errManyCases)
+				}
+				c = i
+			}
+		}
+		if c < 0 {
+			panic(errNoCase)
+		}
+		payload := t.Fields[c].Type
+		if payload == nil {
+			return uint32(c), nil, reflect.Value{}
+		}
+		return uint32(c), payload, v.Field(goFields(v.Type())[c]).Elem()
+	}
+}
+
+// liftString returns the string of n code units at ptr in memory.
+func (cx *callContext) liftString(ptr, n uint32) string {
+	switch cx.encoding {
+	case StringEncodingUTF16:
+		return cx.liftUTF16(ptr, n)
+	case StringEncodingLatin1UTF16:
+		if n&utf16Tag != 0 {
+			return cx.liftUTF16(ptr, n&^utf16Tag)
+		}
+		if ptr%2 != 0 {
+			panic(errUnaligned)
+		}
+		b := cx.read(ptr, n)
+		rs := make([]rune, len(b))
+		for i, c := range b {
+			rs[i] = rune(c)
+		}
+		return string(rs)
+	default:
+		b := cx.read(ptr, n)
+		if !utf8.Valid(b) {
+			panic(errBadString)
+		}
+		return string(b)
+	}
+}
+
+func (cx *callContext) liftUTF16(ptr, n uint32) string {
+	if ptr%2 != 0 {
+		panic(errUnaligned)
+	}
+	b := cx.read(ptr, 2*n)
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	for i := 0; i < len(units); i++ {
+		switch u := units[i]; {
+		case utf16.IsSurrogate(rune(u)) && u < 0xdc00 && i+1 < len(units) && units[i+1] >= 0xdc00 && units[i+1] <= 0xdfff:
+			i++
+		case utf16.IsSurrogate(rune(u)):
+			panic(errBadString)
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// lowerString allocates the string in memory, and returns its pointer and its length in code units.
+func (cx *callContext) lowerString(s string) (uint32, uint32) {
+	if !utf8.ValidString(s) {
+		panic(errBadString)
+	}
+	switch cx.encoding {
+	case StringEncodingUTF16:
+		return cx.lowerUTF16(s, 0)
+	case StringEncodingLatin1UTF16:
+		latin1 := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xff {
+				return cx.lowerUTF16(s, utf16Tag)
+			}
+			latin1 = append(latin1, byte(r))
+		}
+		if len(latin1) > maxStringByteLength {
+			panic(errTooLong)
+		}
+		ptr := cx.alloc(2, uint32(len(latin1)))
+		copy(cx.read(ptr, uint32(len(latin1))), latin1)
+		return ptr, uint32(len(latin1))
+	default:
+		if len(s) > maxStringByteLength {
+			panic(errTooLong)
+		}
+		ptr := cx.alloc(1, uint32(len(s)))
+		copy(cx.read(ptr, uint32(len(s))), s)
+		return ptr, uint32(len(s))
+	}
+}
+
+func (cx *callContext) lowerUTF16(s string, tag uint32) (uint32, uint32) {
+	units := utf16.Encode([]rune(s))
+	if 2*len(units) > maxStringByteLength {
+		panic(errTooLong)
+	}
+	n := uint32(len(units))
+	ptr := cx.alloc(2, 2*n)
+	b := cx.read(ptr, 2*n)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], u)
+	}
+	return ptr, n | tag
+}
+
+// liftList lifts the list of n elements at ptr in memory into the slice v.
+func (cx *callContext) liftList(elem *Type, v reflect.Value, ptr, n uint32) {
+	size, alignment := elem.size(), elem.alignment()
+	if ptr%alignment != 0 {
+		panic(errUnaligned)
+	} else if uint64(ptr)+uint64(n)*uint64(size) > uint64(cx.mem().Size()) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	s := reflect.MakeSlice(v.Type(), int(n), int(n))
+	if elem.Kind == TypeKindU8 && s.Type().Elem().Kind() == reflect.Uint8 {
+		reflect.Copy(s, reflect.ValueOf(cx.read(ptr, n)))
+	} else {
+		for i := 0; i < int(n); i++ {
+			cx.load(elem, s.Index(i), ptr+uint32(i)*size)
+		}
+	}
+	v.Set(s)
+}
+
+// lowerList allocates the elements of the slice v in memory, and returns their pointer and their count.
+func (cx *callContext) lowerList(elem *Type, v reflect.Value) (uint32, uint32) {
+	size, n := elem.size(), v.Len()
+	if uint64(n)*uint64(size) > math.MaxUint32 {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	ptr := cx.alloc(elem.alignment(), uint32(n)*size)
+	if elem.Kind == TypeKindU8 && v.Type().Elem().Kind() == reflect.Uint8 {
+		reflect.Copy(reflect.ValueOf(cx.read(ptr, uint32(n))), v)
+	} else {
+		for i := 0; i < n; i++ {
+			cx.store(elem, v.Index(i), ptr+uint32(i)*size)
+		}
+	}
+	return ptr, uint32(n)
+}
+
+// flatValues iterates the core values of flattened values.
+type flatValues struct {
+	values []uint64
+	i      int
+}
+
+func (f *flatValues) next() uint64 {
+	v := f.values[f.i]
+	f.i++
+	return v
+}
+
+// liftFlat lifts the value of the type from its flattened core values into v.
+func (cx *callContext) liftFlat(t *Type, v reflect.Value, f *flatValues) {
+	switch t.Kind {
+	case TypeKindBool:
+		v.SetBool(uint32(f.next()) != 0)
+	case TypeKindS8:
+		v.SetInt(int64(int8(f.next())))
+	case TypeKindU8:
+		v.SetUint(uint64(uint8(f.next())))
+	case TypeKindS16:
+		v.SetInt(int64(int16(f.next())))
+	case TypeKindU16:
+		v.SetUint(uint64(uint16(f.next())))
+	case TypeKindS32:
+		v.SetInt(int64(int32(f.next())))
+	case TypeKindU32:
+		v.SetUint(uint64(uint32(f.next())))
+	case TypeKindS64:
+		v.SetInt(int64(f.next()))
+	case TypeKindU64:
+		v.SetUint(f.next())
+	case TypeKindF32:
+		v.SetFloat(float64(math.Float32frombits(uint32(f.next()))))
+	case TypeKindF64:
+		v.SetFloat(math.Float64frombits(f.next()))
+	case TypeKindChar:
+		v.SetInt(int64(liftChar(uint32(f.next()))))
+	case TypeKindString:
+		ptr := uint32(f.next())
+		v.SetString(cx.liftString(ptr, uint32(f.next())))
+	case TypeKindList:
+		if t.Length > 0 {
+			for i := 0; i < int(t.Length); i++ {
+				cx.liftFlat(t.Elem, v.Index(i), f)
+			}
+		} else {
+			ptr := uint32(f.next())
+			cx.liftList(t.Elem, v, ptr, uint32(f.next()))
+		}
+	case TypeKindRecord, TypeKindTuple:
+		for i, fi := range goFields(v.Type()) {
+			cx.liftFlat(t.Fields[i].Type, v.Field(fi), f)
+		}
+	case TypeKindVariant, TypeKindOption, TypeKindResult:
+		c := uint32(f.next())
+		// The payloads of the cases share the same core values, which are coerced when they're joined. As core values
+		// are kept in their bit representation zero-extended to 64 bits, no conversion is needed.
+		n := len(t.Flatten(nil)) - 1
+		payload := &flatValues{values: f.values[f.i : f.i+n]}
+		f.i += n
+		cx.liftCase(t, v, c, func(c *Type, pv reflect.Value) {
+			cx.liftFlat(c, pv, payload)
+		})
+	case TypeKindEnum:
+		c := uint32(f.next())
+		if c >= uint32(len(t.Labels)) {
+			panic(errBadCase)
+		}
+		v.SetUint(uint64(c))
+	case TypeKindFlags:
+		var bits uint64
+		for i := 0; i < len(t.Labels); i += 32 {
+			bits |= uint64(uint32(f.next())) << i
+		}
+		v.SetUint(bits & flagsMask(len(t.Labels)))
+	case TypeKindOwn, TypeKindBorrow:
+		cx.liftHandle(t, v, uint32(f.next()))
+	default:
+		panic(fmt.Sprintf("BUG: %s can't be lifted", t.Kind))
+	}
+}
+
+// lowerFlat appends the flattened core values of v as a value of the type.
+func (cx *callContext) lowerFlat(t *Type, v reflect.Value, dst []uint64) []uint64 {
+	switch t.Kind {
+	case TypeKindBool:
+		if v.Bool() {
+			return append(dst, 1)
+		}
+		return append(dst, 0)
+	case TypeKindS8, TypeKindS16, TypeKindS32:
+		return append(dst, uint64(uint32(v.Int())))
+	case TypeKindS64:
+		return append(dst, uint64(v.Int()))
+	case TypeKindU8, TypeKindU16, TypeKindU32, TypeKindU64:
+		return append(dst, v.Uint())
+	case TypeKindF32:
+		return append(dst, uint64(math.Float32bits(float32(v.Float()))))
+	case TypeKindF64:
+		return append(dst, math.Float64bits(v.Float()))
+	case TypeKindChar:
+		return append(dst, uint64(lowerChar(v.Int())))
+	case TypeKindString:
+		ptr, n := cx.lowerString(v.String())
+		return append(dst, uint64(ptr), uint64(n))
+	case TypeKindList:
+		if t.Length > 0 {
+			for i := 0; i < int(t.Length); i++ {
+				dst = cx.lowerFlat(t.Elem, v.Index(i), dst)
+			}
+			return dst
+		}
+		ptr, n := cx.lowerList(t.Elem, v)
+		return append(dst, uint64(ptr), uint64(n))
+	case TypeKindRecord, TypeKindTuple:
+		for i, fi := range goFields(v.Type()) {
+			dst = cx.lowerFlat(t.Fields[i].Type, v.Field(fi), dst)
+		}
+		return dst
+	case TypeKindVariant, TypeKindOption, TypeKindResult:
+		c, payload, pv := cx.lowerCase(t, v)
+		dst = append(dst, uint64(c))
+		start := len(dst)
+		if payload != nil {
+			dst = cx.lowerFlat(payload, pv, dst)
+		}
+		// The core values not used by the case are zero.
+		for n := len(t.Flatten(nil)) - 1; len(dst)-start < n; {
+			dst = append(dst, 0)
+		}
+		return dst
+	case TypeKindEnum:
+		if v.Uint() >= uint64(len(t.Labels)) {
+			panic(errBadCase)
+		}
+		return append(dst, v.Uint())
+	case TypeKindFlags:
+		bits := v.Uint() & flagsMask(len(t.Labels))
+		for i := 0; i < len(t.Labels); i += 32 {
+			dst = append(dst, uint64(uint32(bits>>i)))
+		}
+		return dst
+	case TypeKindOwn, TypeKindBorrow:
+		return append(dst, uint64(cx.lowerHandle(t, v)))
+	default:
+		panic(fmt.Sprintf("BUG: %s can't be lowered", t.Kind))
+	}
+}
+
+// liftHandle lifts the handle h into the Own or the Borrow v, which takes it from the table if it's owned.
+func (cx *callContext) liftHandle(t *Type, v reflect.Value, h uint32) {
+	if cx.handles == nil {
+		panic(errNoHandles)
+	}
+	var e *handle
+	if t.Kind == TypeKindOwn {
+		e = cx.handles.remove(h, t.Elem, true)
+	} else {
+		e = cx.handles.get(h, t.Elem)
+	}
+	rep := reflect.ValueOf(e.rep)
+	if f := v.Field(0); !rep.IsValid() || !rep.Type().AssignableTo(f.Type()) {
+		panic(fmt.Errorf("%w: %T is not a %v", errHandleType, e.rep, f.Type()))
+	} else {
+		f.Set(rep)
+	}
+}
+
+// lowerHandle adds the representation of the Own v to the table, and returns its handle.
+func (cx *callContext) lowerHandle(t *Type, v reflect.Value) uint32 {
+	if cx.handles == nil {
+		panic(errNoHandles)
+	} else if t.Kind != TypeKindOwn {
+		panic("BUG: borrowed handles can't be lowered")
+	}
+	return cx.handles.add(&handle{typ: t.Elem, rep: v.Field(0).Interface(), own: true})
+}
@@ -0,0 +1,299 @@
+package component
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// bumpRealloc is a realloc function which allocates memory from a bump pointer.
+type bumpRealloc struct {
+	internalapi.WazeroOnlyType
+	next uint32
+}
+
+func (*bumpRealloc) Definition() api.FunctionDefinition { return nil }
+
+func (r *bumpRealloc) Call(_ context.Context, params ...uint64) ([]uint64, error) {
+	ptr := alignTo(r.next, uint32(params[2]))
+	r.next = ptr + uint32(params[3])
+	return []uint64{uint64(ptr)}, nil
+}
+
+func (r *bumpRealloc) CallWithStack(ctx context.Context, stack []uint64) error {
+	res, err := r.Call(ctx, stack...)
+	stack[0] = res[0]
+	return err
+}
+
+func newTestCallContext(encoding StringEncoding) *callContext {
+	return &callContext{
+		ctx:      context.Background(),
+		memory:   wasm.NewMemoryInstance(&wasm.Memory{Min: 1, Cap: 1, Max: 1}, nil, nil),
+		realloc:  &bumpRealloc{next: 8},
+		encoding: encoding,
+		handles:  &handleTable{},
+	}
+}
+
+type testColor uint8
+
+func (testColor) Cases() []string { return []string{"red", "green", "blue"} }
+
+type testPermissions uint16
+
+func (testPermissions) Flags() []string { return []string{"read", "write", "exec"} }
+
+type testShape struct {
+	cm.Variant
+	Circle *float32
+	Square *[2]int8
+	Empty  *struct{}
+}
+
+type testPair struct {
+	cm.Tuple
+	A uint64
+	B cm.Char
+}
+
+type testRecord struct {
+	Name        string
+	Tags        []string
+	Shapes      []testShape
+	Color       testColor
+	Permissions testPermissions
+	Pair        testPair
+	Size        cm.Option[uint16]
+	Status      cm.Result[struct{}, string]
+	Bytes       []byte
+	Ignored     int `wit:"-"`
+}
+
+func TestType_layout(t *testing.T) {
+	u8, u16, u32, u64, str := PrimitiveType(TypeKindU8), PrimitiveType(TypeKindU16), PrimitiveType(TypeKindU32),
+		PrimitiveType(TypeKindU64), PrimitiveType(TypeKindString)
+
+	tests := []struct {
+		name            string
+		input           *Type
+		size, alignment uint32
+	}{
+		{name: "u8", input: u8, size: 1, alignment: 1},
+		{name: "u64", input: u64, size: 8, alignment: 8},
+		{name: "string", input: str, size: 8, alignment: 4},
+		{name: "list", input: &Type{Kind: TypeKindList, Elem: u64}, size: 8, alignment: 4},
+		{name: "fixed list", input: &Type{Kind: TypeKindList, Elem: u16, Length: 3}, size: 6, alignment: 2},
+		{
+			name:  "record",
+			input: &Type{Kind: TypeKindRecord, Fields: []Field{{Name: "a", Type: u8}, {Name: "b", Type: u32}, {Name: "c", Type: u8}}},
+			size:  12, alignment: 4,
+		},
+		{
+			name:  "variant",
+			input: &Type{Kind: TypeKindVariant, Fields: []Field{{Name: "a", Type: u8}, {Name: "b", Type: u64}, {Name: "c"}}},
+			size:  16, alignment: 8,
+		},
+		{name: "option", input: &Type{Kind: TypeKindOption, Elem: u16}, size: 4, alignment: 2},
+		{name: "result", input: &Type{Kind: TypeKindResult, Err: str}, size: 12, alignment: 4},
+		{name: "enum", input: &Type{Kind: TypeKindEnum, Labels: make([]string, 300)}, size: 2, alignment: 2},
+		{name: "flags of 9", input: &Type{Kind: TypeKindFlags, Labels: make([]string, 9)}, size: 2, alignment: 2},
+		{name: "flags of 40", input: &Type{Kind: TypeKindFlags, Labels: make([]string, 40)}, size: 8, alignment: 4},
+		{name: "own", input: &Type{Kind: TypeKindOwn, Elem: &Type{Kind: TypeKindResource}}, size: 4, alignment: 4},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.size, tc.input.size())
+			require.Equal(t, tc.alignment, tc.input.alignment())
+		})
+	}
+}
+
+func TestCallContext_roundTrip(t *testing.T) {
+	radius, square := float32(1.5), [2]int8{-1, 2}
+	input := testRecord{
+		Name:        "héllo, 世界 🌍",
+		Tags:        []string{"a", "", "ÿ"},
+		Shapes:      []testShape{{Circle: &radius}, {Square: &square}, {Empty: &struct{}{}}},
+		Color:       2,
+		Permissions: 5,
+		Pair:        testPair{A: 1 << 40, B: '🌍'},
+		Size:        cm.Some[uint16](42),
+		Status:      cm.Err[struct{}]("failed"),
+		Bytes:       []byte{1, 2, 3},
+	}
+	typ, err := (&goTypes{resources: map[reflect.Type]*Type{}, visiting: map[reflect.Type]bool{}}).
+		typeOf(reflect.TypeOf(input))
+	require.NoError(t, err)
+	require.Equal(t, "record {name: string, tags: list<string>, shapes: list<variant {circle: f32, square: list<s8, 2>, empty}>, "+
+		"color: enum {red, green, blue}, permissions: flags {read, write, exec}, pair: tuple<u64, char>, size: option<u16>, "+
+		"status: result<_, string>, bytes: list<u8>}", typ.String())
+
+	for _, encoding := range []StringEncoding{StringEncodingUTF8, StringEncodingUTF16, StringEncodingLatin1UTF16} {
+		cx := newTestCallContext(encoding)
+
+		t.Run("memory", func(t *testing.T) {
+			ptr := cx.alloc(typ.alignment(), typ.size())
+			cx.store(typ, reflect.ValueOf(input), ptr)
+
+			var actual testRecord
+			cx.load(typ, reflect.ValueOf(&actual).Elem(), ptr)
+			require.Equal(t, input, actual)
+		})
+
+		t.Run("flat", func(t *testing.T) {
+			flat := cx.lowerFlat(typ, reflect.ValueOf(input), nil)
+			require.Equal(t, len(typ.Flatten(nil)), len(flat))
+
+			var actual testRecord
+			cx.liftFlat(typ, reflect.ValueOf(&actual).Elem(), &flatValues{values: flat})
+			require.Equal(t, input, actual)
+		})
+	}
+}
+
+func TestCallContext_errors(t *testing.T) {
+	str, char := PrimitiveType(TypeKindString), PrimitiveType(TypeKindChar)
+	variant := &Type{Kind: TypeKindVariant, Fields: []Field{{Name: "circle", Type: PrimitiveType(TypeKindF32)}, {Name: "square", Type: &Type{Kind: TypeKindList, Elem: PrimitiveType(TypeKindS8), Length: 2}}, {Name: "empty"}}}
+
+	tests := []struct {
+		name        string
+		fn          func(cx *callContext)
+		expectedErr error
+	}{
+		{
+			name: "invalid UTF-8",
+			fn: func(cx *callContext) {
+				cx.memory.Write(16, []byte{0xff, 0xfe})
+				var s string
+				cx.liftFlat(str, reflect.ValueOf(&s).Elem(), &flatValues{values: []uint64{16, 2}})
+			},
+			expectedErr: errBadString,
+		},
+		{
+			name: "string out of bounds",
+			fn: func(cx *callContext) {
+				var s string
+				cx.liftFlat(str, reflect.ValueOf(&s).Elem(), &flatValues{values: []uint64{uint64(wasm.MemoryPageSize - 1), 4}})
+			},
+			expectedErr: wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess,
+		},
+		{
+			name: "surrogate char",
+			fn: func(cx *callContext) {
+				var c cm.Char
+				cx.liftFlat(char, reflect.ValueOf(&c).Elem(), &flatValues{values: []uint64{0xd800}})
+			},
+			expectedErr: errBadChar,
+		},
+		{
+			name: "invalid case",
+			fn: func(cx *callContext) {
+				var v testShape
+				cx.liftFlat(variant, reflect.ValueOf(&v).Elem(), &flatValues{values: []uint64{3, 0, 0}})
+			},
+			expectedErr: errBadCase,
+		},
+		{
+			name: "no case",
+			fn: func(cx *callContext) {
+				cx.lowerFlat(variant, reflect.ValueOf(testShape{}), nil)
+			},
+			expectedErr: errNoCase,
+		},
+		{
+			name: "several cases",
+			fn: func(cx *callContext) {
+				cx.lowerFlat(variant, reflect.ValueOf(testShape{Empty: &struct{}{}, Circle: new(float32)}), nil)
+			},
+			expectedErr: errManyCases,
+		},
+		{
+			name: "no memory",
+			fn: func(cx *callContext) {
+				cx.memory = nil
+				cx.lowerFlat(str, reflect.ValueOf("a"), nil)
+			},
+			expectedErr: errNoMemory,
+		},
+		{
+			name: "no realloc",
+			fn: func(cx *callContext) {
+				cx.realloc = nil
+				cx.lowerFlat(str, reflect.ValueOf("a"), nil)
+			},
+			expectedErr: errNoRealloc,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := require.CapturePanic(func() { tc.fn(newTestCallContext(StringEncodingUTF8)) })
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestCallContext_handles(t *testing.T) {
+	resource := &Type{Kind: TypeKindResource}
+	own, borrow := &Type{Kind: TypeKindOwn, Elem: resource}, &Type{Kind: TypeKindBorrow, Elem: resource}
+	cx := newTestCallContext(StringEncodingUTF8)
+
+	closed := false
+	rep := &testCloser{closed: &closed}
+	h := cx.lowerFlat(own, reflect.ValueOf(cm.Own[*testCloser]{Rep: rep}), nil)
+	require.Equal(t, []uint64{1}, h)
+
+	var b cm.Borrow[*testCloser]
+	cx.liftFlat(borrow, reflect.ValueOf(&b).Elem(), &flatValues{values: h})
+	require.Equal(t, rep, b.Rep)
+
+	// A handle of another resource type can't be lifted.
+	err := require.CapturePanic(func() {
+		var o cm.Own[*testCloser]
+		cx.liftFlat(&Type{Kind: TypeKindOwn, Elem: &Type{Kind: TypeKindResource}}, reflect.ValueOf(&o).Elem(), &flatValues{values: h})
+	})
+	require.ErrorIs(t, err, errHandleType)
+
+	var o cm.Own[*testCloser]
+	cx.liftFlat(own, reflect.ValueOf(&o).Elem(), &flatValues{values: h})
+	require.Equal(t, rep, o.Rep)
+
+	// The owned handle was removed from the table.
+	err = require.CapturePanic(func() {
+		cx.liftFlat(borrow, reflect.ValueOf(&b).Elem(), &flatValues{values: h})
+	})
+	require.ErrorIs(t, err, errBadHandle)
+
+	// The table closes the owned handles which are left.
+	require.Equal(t, []uint64{1}, cx.lowerFlat(own, reflect.ValueOf(o), nil))
+	cx.handles.close(context.Background())
+	require.True(t, closed)
+
+	// Handles are only supported with a table.
+	err = require.CapturePanic(func() {
+		cx := newTestCallContext(StringEncodingUTF8)
+		cx.handles = nil
+		cx.lowerFlat(own, reflect.ValueOf(o), nil)
+	})
+	require.ErrorIs(t, err, errNoHandles)
+}
+
+type testCloser struct {
+	closed *bool
+}
+
+func (c *testCloser) Close(context.Context) error {
+	*c.closed = true
+	return nil
+}
@@ -0,0 +1,129 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// exportedFunction is the core function lifted by a function exported by a component, which can also be called with
+// the Go values of its params and result.
+type exportedFunction struct {
+	api.Function
+	f *function
+}
+
+// CallValues lowers the Go values of the params, calls the function, and lifts its result into the Go value result
+// points to, as per the canonical ABI.
+//
+// See the documentation of experimental/component.Call for the Go types of the values.
+func (e *exportedFunction) CallValues(ctx context.Context, result interface{}, params ...interface{}) error {
+	return e.f.callValues(ctx, e.Function, result, params)
+}
+
+// callValues calls the lifted function fn with the Go values of params, and lifts its result into result.
+func (f *function) callValues(ctx context.Context, fn api.Function, result interface{}, params []interface{}) (err error) {
+	t := f.typ
+	if t == nil {
+		return errors.New("function has no type of the component model")
+	} else if len(params) != len(t.Fields) {
+		return fmt.Errorf("expected %d params, but got %d", len(t.Fields), len(params))
+	}
+
+	g := &goTypes{resources: map[reflect.Type]*Type{}, visiting: map[reflect.Type]bool{}}
+	args := make([]reflect.Value, len(params))
+	for i, p := range params {
+		if p == nil {
+			return fmt.Errorf("param[%d] is nil", i)
+		}
+		args[i] = reflect.ValueOf(p)
+		if err = checkGoType(g, args[i].Type(), t.Fields[i].Type); err != nil {
+			return fmt.Errorf("param[%d]: %w", i, err)
+		}
+	}
+	var resultV reflect.Value
+	if len(t.Results) > 0 {
+		v := reflect.ValueOf(result)
+		if v.Kind() != reflect.Pointer || v.IsNil() {
+			return fmt.Errorf("result must be a non-nil pointer, but was %T", result)
+		}
+		resultV = v.Elem()
+		if err = checkGoType(g, resultV.Type(), t.Results[0].Type); err != nil {
+			return fmt.Errorf("result: %w", err)
+		}
+	} else if result != nil {
+		return errors.New("function has no result")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	cx := &callContext{ctx: ctx, realloc: f.realloc, encoding: f.options.StringEncoding, handles: f.handles}
+	if f.memory != nil {
+		cx.memory = f.memory.Memory()
+	}
+	def := fn.Definition()
+	stack := make([]uint64, max(len(def.ParamTypes()), len(def.ResultTypes())))
+
+	var paramTypes []wasm.ValueType
+	for _, field := range t.Fields {
+		paramTypes = field.Type.Flatten(paramTypes)
+	}
+	if len(paramTypes) > MaxFlatParams {
+		// The params are stored in memory as a tuple, whose pointer is given instead.
+		tuple := &Type{Kind: TypeKindTuple, Fields: t.Fields}
+		base := cx.alloc(tuple.alignment(), tuple.size())
+		ptr := base
+		for i, field := range t.Fields {
+			ptr = alignTo(ptr, field.Type.alignment())
+			cx.store(field.Type, args[i], ptr)
+			ptr += field.Type.size()
+		}
+		stack[0] = uint64(base)
+	} else {
+		flat := make([]uint64, 0, len(paramTypes))
+		for i, field := range t.Fields {
+			flat = cx.lowerFlat(field.Type, args[i], flat)
+		}
+		copy(stack, flat)
+	}
+
+	if err = fn.CallWithStack(ctx, stack); err != nil {
+		return err
+	}
+	results := append([]uint64(nil), stack[:len(def.ResultTypes())]...)
+	if resultV.IsValid() {
+		rt := t.Results[0].Type
+		if len(rt.Flatten(nil)) > MaxFlatResults {
+			cx.load(rt, resultV, uint32(results[0]))
+		} else {
+			cx.liftFlat(rt, resultV, &flatValues{values: results})
+		}
+	}
+	if f.postReturn != nil {
+		_, err = f.postReturn.Call(ctx, results...)
+	}
+	return err
+}
+
+// checkGoType returns an error unless the Go type represents a value of the type t.
+func checkGoType(g *goTypes, goType reflect.Type, t *Type) error {
+	gt, err := g.typeOf(goType)
+	if err != nil {
+		return err
+	} else if err = checkShape(gt, t); err != nil {
+		return fmt.Errorf("%w: %s != %s", err, t, gt)
+	}
+	return nil
+}
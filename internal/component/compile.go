@@ -0,0 +1,342 @@
+package component
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/wit"
+	"github.com/tetratelabs/wazero/internal/expctxkeys"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// builtinsInstance is the index of the core instance which implements the built-in core functions in coreRef.
+const builtinsInstance = -1
+
+// Compiled is a component whose core modules are compiled, which is instantiated by Instantiate.
+type Compiled struct {
+	Component *Component
+
+	// shims are the modules of the inline core instances per index, which re-export the definitions of other core
+	// instances.
+	shims map[Index]*shim
+	// nested are the compiled components per index in Component.Components.
+	nested []*Compiled
+	// builtins are the indexes of the core functions implemented by the runtime, which are the lowered functions and
+	// the resource built-ins, and builtinTypes are their types.
+	builtins     []Index
+	builtinTypes []*wasm.FunctionType
+}
+
+// shim is a module which imports each definition from a core instance of its own module name, and exports it.
+type shim struct {
+	module  *wasm.Module
+	typeIDs []wasm.FunctionTypeID
+	// refs are the definitions imported, whose index is the module name of the import.
+	refs []coreRef
+}
+
+// coreRef is a definition exported by a core instance, whose index is builtinsInstance for the built-in functions.
+type coreRef struct {
+	instance int
+	name     string
+}
+
+// coreExtern is the type of a core definition.
+type coreExtern struct {
+	typ    wasm.ExternType
+	fn     *wasm.FunctionType
+	table  wasm.Table
+	mem    *wasm.Memory
+	global wasm.GlobalType
+}
+
+// CompileModuleFunc compiles a core module of a component the same way as the other modules of the runtime.
+type CompileModuleFunc func(ctx context.Context, binary []byte) (*wasm.Module, []wasm.FunctionTypeID, error)
+
+// Compile compiles the core modules of the component with compileModule, and the modules which implement its inline
+// core instances. This validates the types of the core definitions, which are statically known, and the imports of
+// the component against the wit.World of ctx, if any.
+func Compile(ctx context.Context, c *Component, s *wasm.Store, compileModule CompileModuleFunc) (*Compiled, error) {
+	if w, ok := ctx.Value(expctxkeys.WITWorldKey{}).(*wit.World); ok {
+		if err := checkWorld(c, w); err != nil {
+			return nil, err
+		}
+	}
+	compiled := map[*Component]*Compiled{}
+	ret, err := compile(ctx, c, s, compileModule, compiled)
+	if err != nil {
+		for _, cc := range compiled {
+			cc.close(s.Engine)
+		}
+		return nil, err
+	}
+	return ret, nil
+}
+
+func compile(ctx context.Context, c *Component, s *wasm.Store, compileModule CompileModuleFunc, compiled map[*Component]*Compiled) (*Compiled, error) {
+	if cc, ok := compiled[c]; ok {
+		return cc, nil
+	}
+	cc := &Compiled{Component: c, shims: map[Index]*shim{}}
+	compiled[c] = cc
+
+	for i, m := range c.CoreModules {
+		if m.Module != nil {
+			continue // aliased.
+		}
+		var err error
+		if m.Module, m.TypeIDs, err = compileModule(ctx, m.Binary); err != nil {
+			return nil, fmt.Errorf("core module[%d]: %w", i, err)
+		}
+	}
+
+	for i, f := range c.CoreFuncs {
+		switch f.Kind {
+		case CoreFuncKindAlias:
+			continue
+		case CoreFuncKindLower:
+			cc.builtinTypes = append(cc.builtinTypes, c.Funcs[f.Func].Type.CoreType(true))
+		case CoreFuncKindResourceDrop:
+			cc.builtinTypes = append(cc.builtinTypes, &wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI32}})
+		default:
+			cc.builtinTypes = append(cc.builtinTypes, &wasm.FunctionType{
+				Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32},
+			})
+		}
+		cc.builtins = append(cc.builtins, Index(i))
+	}
+
+	for i, inst := range c.CoreInstances {
+		if !inst.Inline {
+			continue
+		}
+		sh, err := cc.compileShim(ctx, s, inst.Exports)
+		if err != nil {
+			return nil, fmt.Errorf("core instance[%d]: %w", i, err)
+		}
+		cc.shims[Index(i)] = sh
+	}
+
+	for i, f := range c.Funcs {
+		if f.Kind != FuncKindLift {
+			continue
+		}
+		ext, err := cc.coreTypeOf(SortCoreFunc, f.CoreFunc)
+		if err != nil {
+			return nil, fmt.Errorf("func[%d]: %w", i, err)
+		}
+		if expected := f.Type.CoreType(false); !ext.fn.EqualsSignature(expected.Params, expected.Results) {
+			return nil, fmt.Errorf("func[%d]: canon lift: core function type mismatch: %s != %s", i, ext.fn, expected)
+		}
+	}
+
+	for _, nested := range c.Components {
+		n, err := compile(ctx, nested, s, compileModule, compiled)
+		if err != nil {
+			return nil, err
+		}
+		cc.nested = append(cc.nested, n)
+	}
+	return cc, nil
+}
+
+// Close releases the compiled core modules of the component.
+func (c *Compiled) Close(e wasm.Engine) {
+	seen := map[*Compiled]struct{}{}
+	var closeAll func(cc *Compiled)
+	closeAll = func(cc *Compiled) {
+		if _, ok := seen[cc]; ok {
+			return
+		}
+		seen[cc] = struct{}{}
+		cc.close(e)
+		for _, n := range cc.nested {
+			closeAll(n)
+		}
+	}
+	closeAll(c)
+}
+
+func (c *Compiled) close(e wasm.Engine) {
+	for _, m := range c.Component.CoreModules {
+		if m.Module != nil {
+			e.DeleteCompiledModule(m.Module)
+			m.Module = nil
+		}
+	}
+	for _, sh := range c.shims {
+		e.DeleteCompiledModule(sh.module)
+	}
+}
+
+// refOf returns the core instance and the name of the export which is the core definition.
+func (c *Compiled) refOf(sort Sort, idx Index) coreRef {
+	var a *CoreAlias
+	switch sort {
+	case SortCoreFunc:
+		if f := c.Component.CoreFuncs[idx]; f.Kind != CoreFuncKindAlias {
+			return coreRef{instance: builtinsInstance, name: strconv.Itoa(int(idx))}
+		} else {
+			a = &f.Alias
+		}
+	case SortCoreTable:
+		a = c.Component.CoreTables[idx]
+	case SortCoreMemory:
+		a = c.Component.CoreMemories[idx]
+	default:
+		a = c.Component.CoreGlobals[idx]
+	}
+	return coreRef{instance: int(a.Instance), name: a.Name}
+}
+
+// coreTypeOf returns the type of the core definition.
+func (c *Compiled) coreTypeOf(sort Sort, idx Index) (coreExtern, error) {
+	ref := c.refOf(sort, idx)
+	if ref.instance == builtinsInstance {
+		for i, b := range c.builtins {
+			if b == idx {
+				return coreExtern{typ: wasm.ExternTypeFunc, fn: c.builtinTypes[i]}, nil
+			}
+		}
+	}
+	return c.exportTypeOf(Index(ref.instance), ref.name, sort)
+}
+
+// exportTypeOf returns the type of the export of the core instance.
+func (c *Compiled) exportTypeOf(instance Index, name string, sort Sort) (coreExtern, error) {
+	inst := c.Component.CoreInstances[instance]
+	if inst.Inline {
+		for _, e := range inst.Exports {
+			if e.Name == name && e.Sort == sort {
+				return c.coreTypeOf(e.Sort, e.Index)
+			}
+		}
+		return coreExtern{}, fmt.Errorf("core instance[%d] has no %s export %q", instance, sort, name)
+	}
+
+	m := c.Component.CoreModules[inst.Module].Module
+	exp, ok := m.Exports[name]
+	if !ok || exp.Type != sort.ExternType() {
+		return coreExtern{}, fmt.Errorf("core instance[%d] has no %s export %q", instance, sort, name)
+	}
+	functions, globals, _, tables, err := m.AllDeclarations()
+	if err != nil {
+		return coreExtern{}, err
+	}
+	ret := coreExtern{typ: exp.Type}
+	switch exp.Type {
+	case wasm.ExternTypeFunc:
+		ret.fn = &m.TypeSection[functions[exp.Index]]
+	case wasm.ExternTypeTable:
+		ret.table = tables[exp.Index]
+	case wasm.ExternTypeMemory:
+		ret.mem = m.AllMemories()[exp.Index]
+	case wasm.ExternTypeGlobal:
+		ret.global = globals[exp.Index]
+	}
+	return ret, nil
+}
+
+// compileShim compiles the module of an inline core instance.
+func (c *Compiled) compileShim(ctx context.Context, s *wasm.Store, exports []CoreInlineExport) (*shim, error) {
+	m := &wasm.Module{ImportPerModule: map[string][]*wasm.Import{}, Exports: map[string]*wasm.Export{}}
+	sh := &shim{module: m}
+	m.ImportSection = make([]wasm.Import, len(exports))
+	m.ExportSection = make([]wasm.Export, len(exports))
+	// The module ID is the hash of a description of the imports and the exports, as they are all it has, and of the
+	// address of the module so that the compiled module is deleted by the Compiled it belongs to only.
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%p;", m)
+	for i, e := range exports {
+		ext, err := c.coreTypeOf(e.Sort, e.Index)
+		if err != nil {
+			return nil, err
+		}
+		ref := c.refOf(e.Sort, e.Index)
+		sh.refs = append(sh.refs, ref)
+
+		imp := &m.ImportSection[i]
+		imp.Type, imp.Module, imp.Name = ext.typ, strconv.Itoa(i), ref.name
+		switch ext.typ {
+		case wasm.ExternTypeFunc:
+			imp.DescFunc = Index(len(m.TypeSection))
+			m.TypeSection = append(m.TypeSection, *ext.fn)
+			imp.IndexPerType = m.ImportFunctionCount
+			m.ImportFunctionCount++
+		case wasm.ExternTypeTable:
+			imp.DescTable = wasm.Table{Type: ext.table.Type}
+			imp.IndexPerType = m.ImportTableCount
+			m.ImportTableCount++
+		case wasm.ExternTypeMemory:
+			imp.DescMem = &wasm.Memory{
+				Max: math.MaxUint32, IsShared: ext.mem.IsShared, IsMemory64: ext.mem.IsMemory64,
+				IsPageSizeEncoded: ext.mem.IsPageSizeEncoded, PageSizeLog2: ext.mem.PageSizeLog2,
+			}
+			imp.IndexPerType = m.ImportMemoryCount
+			m.ImportMemoryCount++
+		case wasm.ExternTypeGlobal:
+			imp.DescGlobal = ext.global
+			imp.IndexPerType = m.ImportGlobalCount
+			m.ImportGlobalCount++
+		}
+		m.ImportPerModule[imp.Module] = []*wasm.Import{imp}
+
+		exp := &m.ExportSection[i]
+		exp.Type, exp.Name, exp.Index = ext.typ, e.Name, imp.IndexPerType
+		if _, ok := m.Exports[e.Name]; ok {
+			return nil, fmt.Errorf("duplicate export %q", e.Name)
+		}
+		m.Exports[e.Name] = exp
+		_, _ = fmt.Fprintf(h, "%d:%s:%s:%s:%v:%v:%v:%v;", ext.typ, ref.name, e.Name, ext.fn, ext.table.Type,
+			ext.mem, ext.global.ValType, ext.global.Mutable)
+	}
+	for i := range m.TypeSection {
+		m.TypeSection[i].CacheNumInUint64()
+	}
+	m.BuildMemoryDefinitions()
+	m.AssignModuleID(h.Sum(nil), nil, false)
+	if err := s.Engine.CompileModule(ctx, m, nil, false); err != nil {
+		return nil, err
+	}
+	typeIDs, err := s.GetFunctionTypeIDs(m.TypeSection)
+	if err != nil {
+		return nil, err
+	}
+	sh.typeIDs = typeIDs
+	return sh, nil
+}
+
+// ExportedFunctions returns the definitions of the core functions exported by instances of the component, as
+// documented on ModuleInstance.
+func (c *Compiled) ExportedFunctions() map[string]api.FunctionDefinition {
+	// The definitions are the ones of a module which imports and exports the functions.
+	m := &wasm.Module{}
+	add := func(name string, t *Type) {
+		if t == nil || t.Kind != TypeKindFunc {
+			return
+		}
+		m.ImportSection = append(m.ImportSection, wasm.Import{
+			Type: wasm.ExternTypeFunc, Name: name, DescFunc: Index(len(m.TypeSection)), IndexPerType: m.ImportFunctionCount,
+		})
+		m.ExportSection = append(m.ExportSection, wasm.Export{Type: wasm.ExternTypeFunc, Name: name, Index: m.ImportFunctionCount})
+		m.TypeSection = append(m.TypeSection, *t.CoreType(false))
+		m.ImportFunctionCount++
+	}
+	for _, e := range c.Component.Exports {
+		switch e.Sort {
+		case SortFunc:
+			add(e.Name, e.Type)
+		case SortInstance:
+			for _, decl := range e.Type.Exports {
+				if decl.Sort == SortFunc {
+					add(e.Name+"#"+decl.Name, decl.Type)
+				}
+			}
+		}
+	}
+	return m.ExportedFunctions()
+}
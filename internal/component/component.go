@@ -0,0 +1,284 @@
+// Package component implements the binary format of WebAssembly components, and their instantiation on top of
+// wasm.Store, as per the component model.
+//
+// A component is made of core modules, which are compiled and instantiated the same way as the other modules, and of
+// the definitions which link their instances together, and to the imports and the exports of the component.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Explainer.md
+package component
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Index is the index of a definition in one of the index spaces of a component.
+type Index = uint32
+
+// Sort is the kind of a definition, which has an index space of its own.
+type Sort byte
+
+const (
+	SortCoreFunc Sort = iota
+	SortCoreTable
+	SortCoreMemory
+	SortCoreGlobal
+	SortCoreType
+	SortCoreModule
+	SortCoreInstance
+	SortFunc
+	SortValue
+	SortType
+	SortComponent
+	SortInstance
+)
+
+var sortNames = [...]string{
+	SortCoreFunc:     "core func",
+	SortCoreTable:    "core table",
+	SortCoreMemory:   "core memory",
+	SortCoreGlobal:   "core global",
+	SortCoreType:     "core type",
+	SortCoreModule:   "core module",
+	SortCoreInstance: "core instance",
+	SortFunc:         "func",
+	SortValue:        "value",
+	SortType:         "type",
+	SortComponent:    "component",
+	SortInstance:     "instance",
+}
+
+// String returns the name of the sort as in the text format.
+func (s Sort) String() string {
+	if int(s) < len(sortNames) {
+		return sortNames[s]
+	}
+	return fmt.Sprintf("unknown(%d)", s)
+}
+
+// ExternType returns the type of the core import or export of a core sort.
+func (s Sort) ExternType() wasm.ExternType {
+	switch s {
+	case SortCoreFunc:
+		return wasm.ExternTypeFunc
+	case SortCoreTable:
+		return wasm.ExternTypeTable
+	case SortCoreMemory:
+		return wasm.ExternTypeMemory
+	default:
+		return wasm.ExternTypeGlobal
+	}
+}
+
+// Component is a decoded component, whose fields are its index spaces.
+type Component struct {
+	CoreModules   []*CoreModule
+	CoreInstances []*CoreInstance
+	CoreFuncs     []*CoreFunc
+	// CoreTables, CoreMemories and CoreGlobals are the exports of core instances aliased by the component.
+	CoreTables   []*CoreAlias
+	CoreMemories []*CoreAlias
+	CoreGlobals  []*CoreAlias
+	// CoreTypes are the core types, which are nil except for function types.
+	CoreTypes []*wasm.FunctionType
+
+	Components []*Component
+	Instances  []*Instance
+	Funcs      []*Func
+	Types      []*Type
+
+	Imports []*Import
+	Exports []*Export
+
+	// Definitions are the definitions with effects, which are evaluated in this order on instantiation.
+	Definitions []Definition
+}
+
+// Definition is a definition of a component which is evaluated on instantiation.
+type Definition struct {
+	// Sort is either SortCoreInstance, SortInstance, or SortCoreFunc for a lowered function.
+	Sort  Sort
+	Index Index
+}
+
+// CoreModule is a core module of a component.
+type CoreModule struct {
+	// Binary is the binary of the module.
+	Binary []byte
+	// Module is the module compiled by Compile.
+	Module  *wasm.Module
+	TypeIDs []wasm.FunctionTypeID
+}
+
+// CoreInstance is either an instance of a core module, or a core instance made of other definitions.
+type CoreInstance struct {
+	// Inline is true if the instance is made of Exports, instead of instantiated from a module.
+	Inline bool
+	// Module is the index of the core module instantiated.
+	Module Index
+	// Args are the instances which satisfy the imports of the module per module name.
+	Args []CoreInstantiateArg
+	// Exports are the exports of an inline core instance.
+	Exports []CoreInlineExport
+}
+
+// CoreInstantiateArg is a core instance which satisfies the imports of a module for the given module name.
+type CoreInstantiateArg struct {
+	Name     string
+	Instance Index
+}
+
+// CoreInlineExport is an export of an inline core instance.
+type CoreInlineExport struct {
+	Name  string
+	Sort  Sort
+	Index Index
+}
+
+// CoreAlias is an export of a core instance.
+type CoreAlias struct {
+	Instance Index
+	Name     string
+}
+
+// CoreFuncKind is the kind of the definition of a CoreFunc.
+type CoreFuncKind byte
+
+const (
+	// CoreFuncKindAlias is a function exported by a core instance.
+	CoreFuncKindAlias CoreFuncKind = iota
+	// CoreFuncKindLower is a function lowered from a function of the component.
+	CoreFuncKindLower
+	// CoreFuncKindResourceNew is the canon resource.new built-in of a resource type.
+	CoreFuncKindResourceNew
+	// CoreFuncKindResourceDrop is the canon resource.drop built-in of a resource type.
+	CoreFuncKindResourceDrop
+	// CoreFuncKindResourceRep is the canon resource.rep built-in of a resource type.
+	CoreFuncKindResourceRep
+)
+
+// CoreFunc is a core function of a component.
+type CoreFunc struct {
+	Kind CoreFuncKind
+	// Alias is the export of the core instance when Kind is CoreFuncKindAlias.
+	Alias CoreAlias
+	// Func is the index of the function lowered when Kind is CoreFuncKindLower.
+	Func Index
+	// Options are the canonical options of the lowering.
+	Options CanonOptions
+	// Type is the resource type of the built-ins.
+	Type *Type
+}
+
+// CanonOptions are the options of canon lift and canon lower.
+type CanonOptions struct {
+	StringEncoding StringEncoding
+	// Memory, Realloc and PostReturn are the indexes of the core memory and functions, or nil if not given.
+	Memory     *Index
+	Realloc    *Index
+	PostReturn *Index
+}
+
+// StringEncoding is the encoding of strings in the memory of a component.
+type StringEncoding byte
+
+const (
+	StringEncodingUTF8 StringEncoding = iota
+	StringEncodingUTF16
+	StringEncodingLatin1UTF16
+)
+
+// FuncKind is the kind of the definition of a Func.
+type FuncKind byte
+
+const (
+	// FuncKindImport is a function imported by the component.
+	FuncKindImport FuncKind = iota
+	// FuncKindAlias is a function exported by an instance.
+	FuncKindAlias
+	// FuncKindLift is a core function lifted to a function of the component.
+	FuncKindLift
+)
+
+// Func is a function of a component.
+type Func struct {
+	Kind FuncKind
+	// Type is the function type.
+	Type *Type
+	// Import is the index of the import when Kind is FuncKindImport.
+	Import Index
+	// Instance and Name are the export of the instance when Kind is FuncKindAlias.
+	Instance Index
+	Name     string
+	// CoreFunc is the index of the core function when Kind is FuncKindLift.
+	CoreFunc Index
+	// Options are the canonical options of the lifting.
+	Options CanonOptions
+}
+
+// InstanceKind is the kind of the definition of an Instance.
+type InstanceKind byte
+
+const (
+	// InstanceKindImport is an instance imported by the component.
+	InstanceKindImport InstanceKind = iota
+	// InstanceKindAlias is an instance exported by another one.
+	InstanceKindAlias
+	// InstanceKindInstantiate is an instance of a component.
+	InstanceKindInstantiate
+	// InstanceKindInline is an instance made of other definitions.
+	InstanceKindInline
+)
+
+// Instance is an instance of a component, which is a set of named definitions.
+type Instance struct {
+	Kind InstanceKind
+	// Type is the instance type.
+	Type *Type
+	// Import is the index of the import when Kind is InstanceKindImport.
+	Import Index
+	// Instance and Name are the export of the instance when Kind is InstanceKindAlias.
+	Instance Index
+	Name     string
+	// Component is the index of the component instantiated when Kind is InstanceKindInstantiate.
+	Component Index
+	// Args are the arguments of the instantiation, or the exports of an inline instance.
+	Args []SortIndex
+}
+
+// SortIndex is a named definition, which is an argument of an instantiation or an export of an inline instance.
+type SortIndex struct {
+	Name  string
+	Sort  Sort
+	Index Index
+}
+
+// Import is an import of a component.
+type Import struct {
+	Name string
+	Sort Sort
+	// Type is the type of the function, the instance or the type imported.
+	Type *Type
+}
+
+// Export is an export of a component.
+type Export struct {
+	Name  string
+	Sort  Sort
+	Index Index
+	// Type is the type of the function or the instance exported, or nil.
+	Type *Type
+}
+
+// Type returns the type of the component, which declares its imports and exports.
+func (c *Component) Type() *Type {
+	t := &Type{Kind: TypeKindComponent}
+	for _, i := range c.Imports {
+		t.Imports = append(t.Imports, ExternDecl{Name: i.Name, Sort: i.Sort, Type: i.Type})
+	}
+	for _, e := range c.Exports {
+		t.Exports = append(t.Exports, ExternDecl{Name: e.Name, Sort: e.Sort, Type: e.Type})
+	}
+	return t
+}
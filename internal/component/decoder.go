@@ -0,0 +1,1242 @@
+package component
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Section IDs of the binary format of components.
+const (
+	sectionIDCustom byte = iota
+	sectionIDCoreModule
+	sectionIDCoreInstance
+	sectionIDCoreType
+	sectionIDComponent
+	sectionIDInstance
+	sectionIDAlias
+	sectionIDType
+	sectionIDCanon
+	sectionIDStart
+	sectionIDImport
+	sectionIDExport
+	sectionIDValue
+)
+
+var (
+	magic    = []byte{0x00, 0x61, 0x73, 0x6D}
+	preamble = []byte{0x0d, 0x00, 0x01, 0x00}
+)
+
+// IsComponent returns true if the binary begins with the preamble of a component, as opposed to the one of a module.
+func IsComponent(binary []byte) bool {
+	return len(binary) >= 8 && bytes.Equal(binary[:4], magic) && bytes.Equal(binary[4:8], preamble)
+}
+
+// DecodeComponent decodes the binary of a component.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Binary.md
+func DecodeComponent(binary []byte) (*Component, error) {
+	if !IsComponent(binary) {
+		return nil, errors.New("invalid component header")
+	}
+	return decodeComponent(binary[8:], nil)
+}
+
+// decoder decodes the sections of a component, whose parent is the decoder of the enclosing component, if any.
+type decoder struct {
+	parent *decoder
+	c      *Component
+	scope  *scope
+}
+
+// scope holds the index spaces of the types of a component, or of a component or an instance type, which can be
+// referred by the types nested in them with outer aliases.
+type scope struct {
+	parent    *scope
+	types     []*Type
+	coreTypes []*wasm.FunctionType
+	// instances are the types of the instances.
+	instances []*Type
+}
+
+func decodeComponent(binary []byte, parent *decoder) (*Component, error) {
+	d := &decoder{parent: parent, c: &Component{}, scope: &scope{}}
+	if parent != nil {
+		d.scope.parent = parent.scope
+	}
+	r := bytes.NewReader(binary)
+	for {
+		sectionID, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read section id: %w", err)
+		}
+
+		sectionSize, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return nil, fmt.Errorf("get size of section %d: %v", sectionID, err)
+		}
+		offset := len(binary) - r.Len()
+		if int(sectionSize) > r.Len() {
+			return nil, fmt.Errorf("section %d of size %d exceeds the binary", sectionID, sectionSize)
+		}
+		content := binary[offset : offset+int(sectionSize)]
+		if _, err = r.Seek(int64(sectionSize), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+
+		if err = d.decodeSection(sectionID, content); err != nil {
+			return nil, fmt.Errorf("section %d: %w", sectionID, err)
+		}
+	}
+	d.c.Types = d.scope.types
+	d.c.CoreTypes = d.scope.coreTypes
+	return d.c, nil
+}
+
+func (d *decoder) decodeSection(sectionID byte, content []byte) (err error) {
+	switch sectionID {
+	case sectionIDCustom:
+		return nil
+	case sectionIDCoreModule:
+		d.c.CoreModules = append(d.c.CoreModules, &CoreModule{Binary: content})
+		return nil
+	case sectionIDComponent:
+		var nested *Component
+		if nested, err = decodeComponent(content, d); err == nil {
+			d.c.Components = append(d.c.Components, nested)
+		}
+		return
+	case sectionIDStart:
+		return errors.New("start functions are not supported")
+	case sectionIDValue:
+		return errors.New("values are not supported")
+	}
+
+	var decode func(r *bytes.Reader) error
+	switch sectionID {
+	case sectionIDCoreInstance:
+		decode = d.decodeCoreInstance
+	case sectionIDCoreType:
+		decode = d.decodeCoreType
+	case sectionIDInstance:
+		decode = d.decodeInstance
+	case sectionIDAlias:
+		decode = d.decodeAlias
+	case sectionIDType:
+		decode = func(r *bytes.Reader) error {
+			t, err := d.scope.decodeDefType(r)
+			if err == nil {
+				d.scope.types = append(d.scope.types, t)
+			}
+			return err
+		}
+	case sectionIDCanon:
+		decode = d.decodeCanon
+	case sectionIDImport:
+		decode = d.decodeImport
+	case sectionIDExport:
+		decode = d.decodeExport
+	default:
+		return fmt.Errorf("invalid section id: %d", sectionID)
+	}
+
+	r := bytes.NewReader(content)
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		if err = decode(r); err != nil {
+			return fmt.Errorf("entry[%d]: %w", i, err)
+		}
+	}
+	if r.Len() != 0 {
+		return errors.New("section size mismatch")
+	}
+	return nil
+}
+
+func (d *decoder) decodeCoreInstance(r *bytes.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	inst := &CoreInstance{}
+	switch b {
+	case 0x00:
+		if inst.Module, err = d.decodeIndex(r, SortCoreModule); err != nil {
+			return err
+		}
+		n, err := decodeVecLen(r)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < n; i++ {
+			var arg CoreInstantiateArg
+			if arg.Name, err = decodeName(r); err != nil {
+				return err
+			}
+			if b, err = r.ReadByte(); err != nil {
+				return err
+			} else if b != 0x12 {
+				return fmt.Errorf("invalid sort of instantiate arg: 0x%x", b)
+			}
+			if arg.Instance, err = d.decodeIndex(r, SortCoreInstance); err != nil {
+				return err
+			}
+			inst.Args = append(inst.Args, arg)
+		}
+	case 0x01:
+		inst.Inline = true
+		n, err := decodeVecLen(r)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < n; i++ {
+			var e CoreInlineExport
+			if e.Name, err = decodeName(r); err != nil {
+				return err
+			}
+			if e.Sort, err = decodeCoreSort(r); err != nil {
+				return err
+			}
+			switch e.Sort {
+			case SortCoreFunc, SortCoreTable, SortCoreMemory, SortCoreGlobal:
+			default:
+				return fmt.Errorf("%s can't be exported by a core instance", e.Sort)
+			}
+			if e.Index, err = d.decodeIndex(r, e.Sort); err != nil {
+				return err
+			}
+			inst.Exports = append(inst.Exports, e)
+		}
+	default:
+		return fmt.Errorf("invalid core instance: 0x%x", b)
+	}
+	d.c.Definitions = append(d.c.Definitions, Definition{Sort: SortCoreInstance, Index: Index(len(d.c.CoreInstances))})
+	d.c.CoreInstances = append(d.c.CoreInstances, inst)
+	return nil
+}
+
+func (d *decoder) decodeCoreType(r *bytes.Reader) error {
+	ft, err := d.scope.decodeCoreType(r)
+	if err == nil {
+		d.scope.coreTypes = append(d.scope.coreTypes, ft)
+	}
+	return err
+}
+
+func (d *decoder) decodeInstance(r *bytes.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	var inst *Instance
+	switch b {
+	case 0x00:
+		inst = &Instance{Kind: InstanceKindInstantiate}
+		if inst.Component, err = d.decodeIndex(r, SortComponent); err != nil {
+			return err
+		}
+		if inst.Args, err = d.decodeSortIndexes(r, false); err != nil {
+			return err
+		}
+		inst.Type = d.c.Components[inst.Component].Type()
+		inst.Type.Kind, inst.Type.Imports = TypeKindInstance, nil
+		d.c.Definitions = append(d.c.Definitions, Definition{Sort: SortInstance, Index: Index(len(d.c.Instances))})
+	case 0x01:
+		inst = &Instance{Kind: InstanceKindInline, Type: &Type{Kind: TypeKindInstance}}
+		if inst.Args, err = d.decodeSortIndexes(r, true); err != nil {
+			return err
+		}
+		for _, e := range inst.Args {
+			inst.Type.Exports = append(inst.Type.Exports, ExternDecl{Name: e.Name, Sort: e.Sort, Type: d.typeOf(e.Sort, e.Index)})
+		}
+	default:
+		return fmt.Errorf("invalid instance: 0x%x", b)
+	}
+	d.addInstance(inst)
+	return nil
+}
+
+// decodeSortIndexes decodes the arguments of an instantiation, or the exports of an inline instance whose names are
+// prefixed by their kind.
+func (d *decoder) decodeSortIndexes(r *bytes.Reader, exports bool) ([]SortIndex, error) {
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]SortIndex, n)
+	for i := range ret {
+		if exports {
+			ret[i].Name, err = decodeExternName(r)
+		} else {
+			ret[i].Name, err = decodeName(r)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ret[i].Sort, err = decodeSort(r); err != nil {
+			return nil, err
+		}
+		if ret[i].Index, err = d.decodeIndex(r, ret[i].Sort); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (d *decoder) decodeAlias(r *bytes.Reader) error {
+	sort, err := decodeSort(r)
+	if err != nil {
+		return err
+	}
+	target, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch target {
+	case 0x00: // export of an instance.
+		i, err := d.decodeIndex(r, SortInstance)
+		if err != nil {
+			return err
+		}
+		name, err := decodeName(r)
+		if err != nil {
+			return err
+		}
+		decl := d.c.Instances[i].Type.Export(name)
+		if decl == nil {
+			return fmt.Errorf("instance[%d] has no export %q", i, name)
+		} else if decl.Sort != sort {
+			return fmt.Errorf("export %q of instance[%d] is a %s, not a %s", name, i, decl.Sort, sort)
+		}
+		switch sort {
+		case SortFunc:
+			d.c.Funcs = append(d.c.Funcs, &Func{Kind: FuncKindAlias, Type: decl.Type, Instance: i, Name: name})
+		case SortInstance:
+			d.addInstance(&Instance{Kind: InstanceKindAlias, Type: decl.Type, Instance: i, Name: name})
+		case SortType:
+			d.scope.types = append(d.scope.types, decl.Type)
+		default:
+			return fmt.Errorf("aliasing a %s export is not supported", sort)
+		}
+	case 0x01: // export of a core instance.
+		var a CoreAlias
+		if a.Instance, err = d.decodeIndex(r, SortCoreInstance); err != nil {
+			return err
+		}
+		if a.Name, err = decodeName(r); err != nil {
+			return err
+		}
+		switch sort {
+		case SortCoreFunc:
+			d.c.CoreFuncs = append(d.c.CoreFuncs, &CoreFunc{Kind: CoreFuncKindAlias, Alias: a})
+		case SortCoreTable:
+			d.c.CoreTables = append(d.c.CoreTables, &a)
+		case SortCoreMemory:
+			d.c.CoreMemories = append(d.c.CoreMemories, &a)
+		case SortCoreGlobal:
+			d.c.CoreGlobals = append(d.c.CoreGlobals, &a)
+		default:
+			return fmt.Errorf("%s can't be exported by a core instance", sort)
+		}
+	case 0x02: // outer
+		count, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return err
+		}
+		idx, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return err
+		}
+		outer := d
+		for ; count > 0 && outer != nil; count-- {
+			outer = outer.parent
+		}
+		if outer == nil {
+			return errors.New("invalid outer alias count")
+		}
+		if _, err = outer.checkIndex(sort, idx); err != nil {
+			return err
+		}
+		switch sort {
+		case SortType:
+			d.scope.types = append(d.scope.types, outer.scope.types[idx])
+		case SortCoreType:
+			d.scope.coreTypes = append(d.scope.coreTypes, outer.scope.coreTypes[idx])
+		case SortCoreModule:
+			d.c.CoreModules = append(d.c.CoreModules, outer.c.CoreModules[idx])
+		case SortComponent:
+			d.c.Components = append(d.c.Components, outer.c.Components[idx])
+		default:
+			return fmt.Errorf("outer alias of a %s is not allowed", sort)
+		}
+	default:
+		return fmt.Errorf("invalid alias target: 0x%x", target)
+	}
+	return nil
+}
+
+func (d *decoder) decodeCanon(r *bytes.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case 0x00, 0x01: // lift, lower
+		if b2, err := r.ReadByte(); err != nil {
+			return err
+		} else if b2 != 0x00 {
+			return fmt.Errorf("invalid canon: 0x%x 0x%x", b, b2)
+		}
+		sort := SortCoreFunc
+		if b == 0x01 {
+			sort = SortFunc
+		}
+		f, err := d.decodeIndex(r, sort)
+		if err != nil {
+			return err
+		}
+		opts, err := d.decodeCanonOptions(r)
+		if err != nil {
+			return err
+		}
+		if b == 0x01 {
+			d.c.Definitions = append(d.c.Definitions, Definition{Sort: SortCoreFunc, Index: Index(len(d.c.CoreFuncs))})
+			d.c.CoreFuncs = append(d.c.CoreFuncs, &CoreFunc{Kind: CoreFuncKindLower, Func: f, Options: opts})
+			return nil
+		}
+		t, err := d.decodeIndex(r, SortType)
+		if err != nil {
+			return err
+		}
+		ft := d.scope.types[t]
+		if ft.Kind != TypeKindFunc {
+			return fmt.Errorf("type[%d] is a %s, not a func", t, ft.Kind)
+		}
+		d.c.Funcs = append(d.c.Funcs, &Func{Kind: FuncKindLift, Type: ft, CoreFunc: f, Options: opts})
+	case 0x02, 0x03, 0x04: // resource.new, resource.drop, resource.rep
+		t, err := d.decodeIndex(r, SortType)
+		if err != nil {
+			return err
+		}
+		rt := d.scope.types[t]
+		if rt.Kind != TypeKindResource {
+			return fmt.Errorf("type[%d] is a %s, not a resource", t, rt.Kind)
+		}
+		kind := map[byte]CoreFuncKind{0x02: CoreFuncKindResourceNew, 0x03: CoreFuncKindResourceDrop, 0x04: CoreFuncKindResourceRep}[b]
+		d.c.CoreFuncs = append(d.c.CoreFuncs, &CoreFunc{Kind: kind, Type: rt})
+	default:
+		return fmt.Errorf("canon 0x%x is not supported", b)
+	}
+	return nil
+}
+
+func (d *decoder) decodeCanonOptions(r *bytes.Reader) (opts CanonOptions, err error) {
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return
+	}
+	for i := uint32(0); i < n; i++ {
+		var b byte
+		if b, err = r.ReadByte(); err != nil {
+			return
+		}
+		var idx Index
+		switch b {
+		case 0x00:
+			opts.StringEncoding = StringEncodingUTF8
+		case 0x01:
+			opts.StringEncoding = StringEncodingUTF16
+		case 0x02:
+			opts.StringEncoding = StringEncodingLatin1UTF16
+		case 0x03:
+			if idx, err = d.decodeIndex(r, SortCoreMemory); err != nil {
+				return
+			}
+			opts.Memory = &idx
+		case 0x04, 0x05:
+			if idx, err = d.decodeIndex(r, SortCoreFunc); err != nil {
+				return
+			}
+			if b == 0x04 {
+				opts.Realloc = &idx
+			} else {
+				opts.PostReturn = &idx
+			}
+		default:
+			err = fmt.Errorf("canon option 0x%x is not supported", b)
+			return
+		}
+	}
+	return
+}
+
+func (d *decoder) decodeImport(r *bytes.Reader) error {
+	name, err := decodeExternName(r)
+	if err != nil {
+		return err
+	}
+	sort, t, err := d.scope.decodeExternDesc(r)
+	if err != nil {
+		return err
+	}
+	idx := Index(len(d.c.Imports))
+	switch sort {
+	case SortFunc:
+		d.c.Funcs = append(d.c.Funcs, &Func{Kind: FuncKindImport, Type: t, Import: idx})
+	case SortInstance:
+		d.addInstance(&Instance{Kind: InstanceKindImport, Type: t, Import: idx})
+	case SortType:
+		d.scope.types = append(d.scope.types, t)
+	default:
+		return fmt.Errorf("importing a %s is not supported", sort)
+	}
+	d.c.Imports = append(d.c.Imports, &Import{Name: name, Sort: sort, Type: t})
+	return nil
+}
+
+func (d *decoder) decodeExport(r *bytes.Reader) error {
+	name, err := decodeExternName(r)
+	if err != nil {
+		return err
+	}
+	sort, err := decodeSort(r)
+	if err != nil {
+		return err
+	}
+	idx, err := d.decodeIndex(r, sort)
+	if err != nil {
+		return err
+	}
+	if b, err := r.ReadByte(); err != nil {
+		return err
+	} else if b == 0x01 { // The type ascribed to the export.
+		if _, _, err = d.scope.decodeExternDesc(r); err != nil {
+			return err
+		}
+	} else if b != 0x00 {
+		return fmt.Errorf("invalid export type: 0x%x", b)
+	}
+
+	// The export is a new definition of the same sort.
+	switch sort {
+	case SortFunc:
+		d.c.Funcs = append(d.c.Funcs, d.c.Funcs[idx])
+	case SortInstance:
+		d.addInstance(d.c.Instances[idx])
+	case SortType:
+		d.scope.types = append(d.scope.types, d.scope.types[idx])
+	case SortComponent:
+		d.c.Components = append(d.c.Components, d.c.Components[idx])
+	case SortCoreModule:
+		d.c.CoreModules = append(d.c.CoreModules, d.c.CoreModules[idx])
+	default:
+		return fmt.Errorf("exporting a %s is not supported", sort)
+	}
+	d.c.Exports = append(d.c.Exports, &Export{Name: name, Sort: sort, Index: idx, Type: d.typeOf(sort, idx)})
+	return nil
+}
+
+func (d *decoder) addInstance(inst *Instance) {
+	d.c.Instances = append(d.c.Instances, inst)
+	d.scope.instances = append(d.scope.instances, inst.Type)
+}
+
+// typeOf returns the type of the definition, or nil if it has none.
+func (d *decoder) typeOf(sort Sort, idx Index) *Type {
+	switch sort {
+	case SortFunc:
+		return d.c.Funcs[idx].Type
+	case SortInstance:
+		return d.c.Instances[idx].Type
+	case SortType:
+		return d.scope.types[idx]
+	case SortComponent:
+		return d.c.Components[idx].Type()
+	}
+	return nil
+}
+
+// decodeIndex decodes an index of the sort, which must be defined.
+func (d *decoder) decodeIndex(r *bytes.Reader, sort Sort) (Index, error) {
+	idx, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return 0, fmt.Errorf("read %s index: %w", sort, err)
+	}
+	return d.checkIndex(sort, idx)
+}
+
+func (d *decoder) checkIndex(sort Sort, idx Index) (Index, error) {
+	var n int
+	switch sort {
+	case SortCoreFunc:
+		n = len(d.c.CoreFuncs)
+	case SortCoreTable:
+		n = len(d.c.CoreTables)
+	case SortCoreMemory:
+		n = len(d.c.CoreMemories)
+	case SortCoreGlobal:
+		n = len(d.c.CoreGlobals)
+	case SortCoreType:
+		n = len(d.scope.coreTypes)
+	case SortCoreModule:
+		n = len(d.c.CoreModules)
+	case SortCoreInstance:
+		n = len(d.c.CoreInstances)
+	case SortFunc:
+		n = len(d.c.Funcs)
+	case SortType:
+		n = len(d.scope.types)
+	case SortComponent:
+		n = len(d.c.Components)
+	case SortInstance:
+		n = len(d.c.Instances)
+	}
+	if int(idx) >= n {
+		return 0, fmt.Errorf("%s index %d out of range", sort, idx)
+	}
+	return idx, nil
+}
+
+// decodeDefType decodes a type definition in the scope.
+func (s *scope) decodeDefType(r *bytes.Reader) (*Type, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if t := primitiveTypeOf(b); t != nil {
+		return t, nil
+	}
+	switch b {
+	case 0x72: // record
+		fields, err := s.decodeFields(r)
+		return &Type{Kind: TypeKindRecord, Fields: fields}, err
+	case 0x71: // variant
+		n, err := decodeVecLen(r)
+		if err != nil {
+			return nil, err
+		}
+		t := &Type{Kind: TypeKindVariant, Fields: make([]Field, n)}
+		for i := range t.Fields {
+			if t.Fields[i].Name, err = decodeName(r); err != nil {
+				return nil, err
+			}
+			if t.Fields[i].Type, err = s.decodeOptionalValType(r); err != nil {
+				return nil, err
+			}
+			// The index of the case refined, which is no longer used.
+			if b, err = r.ReadByte(); err != nil {
+				return nil, err
+			} else if b == 0x01 {
+				if _, _, err = leb128.DecodeUint32(r); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return t, nil
+	case 0x70: // list
+		elem, err := s.decodeValType(r)
+		return &Type{Kind: TypeKindList, Elem: elem}, err
+	case 0x67: // list of a fixed length
+		elem, err := s.decodeValType(r)
+		if err != nil {
+			return nil, err
+		}
+		n, _, err := leb128.DecodeUint32(r)
+		if err == nil && n == 0 {
+			err = errors.New("fixed length list must not be empty")
+		}
+		return &Type{Kind: TypeKindList, Elem: elem, Length: n}, err
+	case 0x6f: // tuple
+		n, err := decodeVecLen(r)
+		if err != nil {
+			return nil, err
+		}
+		t := &Type{Kind: TypeKindTuple, Fields: make([]Field, n)}
+		for i := range t.Fields {
+			if t.Fields[i].Type, err = s.decodeValType(r); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case 0x6e, 0x6d: // flags, enum
+		n, err := decodeVecLen(r)
+		if err != nil {
+			return nil, err
+		}
+		t := &Type{Kind: TypeKindFlags, Labels: make([]string, n)}
+		if b == 0x6d {
+			t.Kind = TypeKindEnum
+		}
+		for i := range t.Labels {
+			if t.Labels[i], err = decodeName(r); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case 0x6b: // option
+		elem, err := s.decodeValType(r)
+		return &Type{Kind: TypeKindOption, Elem: elem}, err
+	case 0x6a: // result
+		t := &Type{Kind: TypeKindResult}
+		if t.Elem, err = s.decodeOptionalValType(r); err != nil {
+			return nil, err
+		}
+		t.Err, err = s.decodeOptionalValType(r)
+		return t, err
+	case 0x69, 0x68: // own, borrow
+		t := &Type{Kind: TypeKindOwn}
+		if b == 0x68 {
+			t.Kind = TypeKindBorrow
+		}
+		idx, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return nil, err
+		} else if int(idx) >= len(s.types) {
+			return nil, fmt.Errorf("type index %d out of range", idx)
+		} else if t.Elem = s.types[idx]; t.Elem.Kind != TypeKindResource {
+			return nil, fmt.Errorf("type[%d] is a %s, not a resource", idx, t.Elem.Kind)
+		}
+		return t, nil
+	case 0x66, 0x65: // stream, future
+		t := &Type{Kind: TypeKindStream}
+		if b == 0x65 {
+			t.Kind = TypeKindFuture
+		}
+		t.Elem, err = s.decodeOptionalValType(r)
+		return t, err
+	case 0x40: // func
+		t := &Type{Kind: TypeKindFunc}
+		if t.Fields, err = s.decodeFields(r); err != nil {
+			return nil, err
+		}
+		if b, err = r.ReadByte(); err != nil {
+			return nil, err
+		}
+		switch b {
+		case 0x00:
+			var result *Type
+			if result, err = s.decodeValType(r); err != nil {
+				return nil, err
+			}
+			t.Results = []Field{{Type: result}}
+		case 0x01:
+			// Either no result, or named results as in the early binaries.
+			if t.Results, err = s.decodeFields(r); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("invalid result list: 0x%x", b)
+		}
+		return t, nil
+	case 0x41, 0x42: // component, instance
+		return s.decodeDeclarations(r, b == 0x41)
+	case 0x3f: // resource
+		if b, err = r.ReadByte(); err != nil {
+			return nil, err
+		} else if b != 0x7f {
+			return nil, fmt.Errorf("invalid representation of resource: 0x%x", b)
+		}
+		t := &Type{Kind: TypeKindResource, Defined: true}
+		if b, err = r.ReadByte(); err != nil {
+			return nil, err
+		} else if b == 0x01 {
+			idx, _, err := leb128.DecodeUint32(r)
+			if err != nil {
+				return nil, err
+			}
+			t.Destructor = &idx
+		} else if b != 0x00 {
+			return nil, fmt.Errorf("invalid destructor of resource: 0x%x", b)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("type 0x%x is not supported", b)
+}
+
+// decodeDeclarations decodes the declarations of a component or an instance type in a new scope.
+func (s *scope) decodeDeclarations(r *bytes.Reader, isComponent bool) (*Type, error) {
+	t := &Type{Kind: TypeKindInstance}
+	if isComponent {
+		t.Kind = TypeKindComponent
+	}
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return nil, err
+	}
+	inner := &scope{parent: s}
+	for i := uint32(0); i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case 0x00: // core type
+			ft, err := inner.decodeCoreType(r)
+			if err != nil {
+				return nil, err
+			}
+			inner.coreTypes = append(inner.coreTypes, ft)
+		case 0x01: // type
+			dt, err := inner.decodeDefType(r)
+			if err != nil {
+				return nil, err
+			}
+			inner.types = append(inner.types, dt)
+		case 0x02: // alias
+			if err = inner.decodeAliasDeclaration(r); err != nil {
+				return nil, err
+			}
+		case 0x03, 0x04: // import, export
+			if b == 0x03 && !isComponent {
+				return nil, errors.New("instance types can't declare imports")
+			}
+			name, err := decodeExternName(r)
+			if err != nil {
+				return nil, err
+			}
+			sort, dt, err := inner.decodeExternDesc(r)
+			if err != nil {
+				return nil, err
+			}
+			switch sort {
+			case SortType:
+				inner.types = append(inner.types, dt)
+			case SortInstance:
+				inner.instances = append(inner.instances, dt)
+			}
+			decl := ExternDecl{Name: name, Sort: sort, Type: dt}
+			if b == 0x03 {
+				t.Imports = append(t.Imports, decl)
+			} else {
+				t.Exports = append(t.Exports, decl)
+			}
+		default:
+			return nil, fmt.Errorf("invalid declaration: 0x%x", b)
+		}
+	}
+	return t, nil
+}
+
+// decodeAliasDeclaration decodes an alias in a component or an instance type.
+func (s *scope) decodeAliasDeclaration(r *bytes.Reader) error {
+	sort, err := decodeSort(r)
+	if err != nil {
+		return err
+	}
+	target, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch target {
+	case 0x00: // export of an instance.
+		i, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return err
+		} else if int(i) >= len(s.instances) {
+			return fmt.Errorf("instance index %d out of range", i)
+		}
+		name, err := decodeName(r)
+		if err != nil {
+			return err
+		}
+		decl := s.instances[i].Export(name)
+		if decl == nil || decl.Sort != sort {
+			return fmt.Errorf("instance[%d] has no %s export %q", i, sort, name)
+		}
+		switch sort {
+		case SortType:
+			s.types = append(s.types, decl.Type)
+		case SortInstance:
+			s.instances = append(s.instances, decl.Type)
+		}
+	case 0x02: // outer
+		count, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return err
+		}
+		idx, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return err
+		}
+		outer := s
+		for ; count > 0 && outer != nil; count-- {
+			outer = outer.parent
+		}
+		if outer == nil {
+			return errors.New("invalid outer alias count")
+		}
+		switch sort {
+		case SortType:
+			if int(idx) >= len(outer.types) {
+				return fmt.Errorf("type index %d out of range", idx)
+			}
+			s.types = append(s.types, outer.types[idx])
+		case SortCoreType:
+			if int(idx) >= len(outer.coreTypes) {
+				return fmt.Errorf("core type index %d out of range", idx)
+			}
+			s.coreTypes = append(s.coreTypes, outer.coreTypes[idx])
+		default:
+			return fmt.Errorf("outer alias of a %s is not allowed in types", sort)
+		}
+	default:
+		return fmt.Errorf("invalid alias target in types: 0x%x", target)
+	}
+	return nil
+}
+
+// decodeExternDesc decodes the description of an import or an export, and returns its sort and type.
+func (s *scope) decodeExternDesc(r *bytes.Reader) (Sort, *Type, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	switch b {
+	case 0x00: // core module
+		if b, err = r.ReadByte(); err != nil {
+			return 0, nil, err
+		} else if b != 0x11 {
+			return 0, nil, fmt.Errorf("invalid core sort of extern: 0x%x", b)
+		}
+		_, _, err = leb128.DecodeUint32(r)
+		return SortCoreModule, nil, err
+	case 0x01, 0x04, 0x05: // func, component, instance
+		sort, kind := SortFunc, TypeKindFunc
+		if b == 0x04 {
+			sort, kind = SortComponent, TypeKindComponent
+		} else if b == 0x05 {
+			sort, kind = SortInstance, TypeKindInstance
+		}
+		idx, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return 0, nil, err
+		} else if int(idx) >= len(s.types) {
+			return 0, nil, fmt.Errorf("type index %d out of range", idx)
+		} else if t := s.types[idx]; t.Kind != kind {
+			return 0, nil, fmt.Errorf("type[%d] is a %s, not a %s", idx, t.Kind, kind)
+		} else {
+			return sort, t, nil
+		}
+	case 0x02: // value
+		return 0, nil, errors.New("values are not supported")
+	case 0x03: // type
+		if b, err = r.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+		switch b {
+		case 0x00: // eq
+			idx, _, err := leb128.DecodeUint32(r)
+			if err != nil {
+				return 0, nil, err
+			} else if int(idx) >= len(s.types) {
+				return 0, nil, fmt.Errorf("type index %d out of range", idx)
+			}
+			return SortType, s.types[idx], nil
+		case 0x01: // sub resource
+			return SortType, &Type{Kind: TypeKindResource}, nil
+		}
+		return 0, nil, fmt.Errorf("invalid type bound: 0x%x", b)
+	}
+	return 0, nil, fmt.Errorf("invalid extern desc: 0x%x", b)
+}
+
+// decodeCoreType decodes a core type, which is a function type, or a module type which is skipped and returns nil.
+func (s *scope) decodeCoreType(r *bytes.Reader) (*wasm.FunctionType, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch b {
+	case 0x60:
+		ft := &wasm.FunctionType{}
+		if ft.Params, err = decodeCoreValueTypes(r); err != nil {
+			return nil, err
+		}
+		if ft.Results, err = decodeCoreValueTypes(r); err != nil {
+			return nil, err
+		}
+		ft.CacheNumInUint64()
+		return ft, nil
+	case 0x50:
+		return nil, skipModuleType(r)
+	}
+	return nil, fmt.Errorf("core type 0x%x is not supported", b)
+}
+
+func (s *scope) decodeFields(r *bytes.Reader) ([]Field, error) {
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]Field, n)
+	for i := range fields {
+		if fields[i].Name, err = decodeName(r); err != nil {
+			return nil, err
+		}
+		if fields[i].Type, err = s.decodeValType(r); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func (s *scope) decodeValType(r *bytes.Reader) (*Type, error) {
+	v, _, err := leb128.DecodeInt33AsInt64(r)
+	if err != nil {
+		return nil, err
+	}
+	if v < 0 {
+		if t := primitiveTypeOf(byte(v & 0x7f)); t != nil {
+			return t, nil
+		}
+		return nil, fmt.Errorf("invalid value type: 0x%x", byte(v&0x7f))
+	} else if v >= int64(len(s.types)) {
+		return nil, fmt.Errorf("type index %d out of range", v)
+	}
+	return s.types[v], nil
+}
+
+func (s *scope) decodeOptionalValType(r *bytes.Reader) (*Type, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch b {
+	case 0x00:
+		return nil, nil
+	case 0x01:
+		return s.decodeValType(r)
+	}
+	return nil, fmt.Errorf("invalid optional value type: 0x%x", b)
+}
+
+func primitiveTypeOf(b byte) *Type {
+	switch {
+	case b >= 0x73 && b <= 0x7f:
+		// 0x7f is bool, and so on until 0x73 which is string.
+		return primitiveTypes[0x7f-b]
+	case b == 0x64:
+		return primitiveTypes[TypeKindErrorContext]
+	}
+	return nil
+}
+
+func decodeCoreValueTypes(r *bytes.Reader) ([]wasm.ValueType, error) {
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]wasm.ValueType, n)
+	for i := range ret {
+		if ret[i], err = r.ReadByte(); err != nil {
+			return nil, err
+		}
+		switch ret[i] {
+		case wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64, wasm.ValueTypeV128,
+			wasm.ValueTypeFuncref, wasm.ValueTypeExternref:
+		default:
+			return nil, fmt.Errorf("invalid core value type: 0x%x", ret[i])
+		}
+	}
+	return ret, nil
+}
+
+// skipModuleType skips the declarations of a core module type, as importing core modules isn't supported.
+func skipModuleType(r *bytes.Reader) error {
+	n, err := decodeVecLen(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case 0x00: // import
+			if _, err = decodeName(r); err == nil {
+				if _, err = decodeName(r); err == nil {
+					err = skipCoreImportDesc(r)
+				}
+			}
+		case 0x01: // type
+			if b, err = r.ReadByte(); err == nil {
+				if b != 0x60 {
+					err = fmt.Errorf("core type 0x%x is not supported in module types", b)
+				} else if _, err = decodeCoreValueTypes(r); err == nil {
+					_, err = decodeCoreValueTypes(r)
+				}
+			}
+		case 0x02: // outer alias
+			if _, err = decodeCoreSort(r); err == nil {
+				if b, err = r.ReadByte(); err == nil && b != 0x01 {
+					err = fmt.Errorf("invalid alias target in module types: 0x%x", b)
+				} else if err == nil {
+					if _, _, err = leb128.DecodeUint32(r); err == nil {
+						_, _, err = leb128.DecodeUint32(r)
+					}
+				}
+			}
+		case 0x03: // export
+			if _, err = decodeName(r); err == nil {
+				err = skipCoreImportDesc(r)
+			}
+		default:
+			err = fmt.Errorf("invalid module type declaration: 0x%x", b)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func skipCoreImportDesc(r *bytes.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case 0x00: // func
+		_, _, err = leb128.DecodeUint32(r)
+	case 0x01: // table
+		if _, _, err = leb128.DecodeInt33AsInt64(r); err == nil {
+			err = skipLimits(r)
+		}
+	case 0x02: // memory
+		err = skipLimits(r)
+	case 0x03: // global
+		if _, _, err = leb128.DecodeInt33AsInt64(r); err == nil {
+			_, err = r.ReadByte()
+		}
+	case 0x04: // tag
+		if _, err = r.ReadByte(); err == nil {
+			_, _, err = leb128.DecodeUint32(r)
+		}
+	default:
+		err = fmt.Errorf("invalid core import desc: 0x%x", b)
+	}
+	return err
+}
+
+func skipLimits(r *bytes.Reader) error {
+	flag, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	n := 1
+	if flag&0x01 != 0 {
+		n++
+	}
+	for ; n > 0; n-- {
+		if _, _, err = leb128.DecodeUint64(r); err != nil {
+			return err
+		}
+	}
+	if flag&0x08 != 0 { // custom page size
+		_, _, err = leb128.DecodeUint32(r)
+	}
+	return err
+}
+
+func decodeSort(r *bytes.Reader) (Sort, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case 0x00:
+		return decodeCoreSort(r)
+	case 0x01:
+		return SortFunc, nil
+	case 0x02:
+		return SortValue, nil
+	case 0x03:
+		return SortType, nil
+	case 0x04:
+		return SortComponent, nil
+	case 0x05:
+		return SortInstance, nil
+	}
+	return 0, fmt.Errorf("invalid sort: 0x%x", b)
+}
+
+func decodeCoreSort(r *bytes.Reader) (Sort, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case 0x00:
+		return SortCoreFunc, nil
+	case 0x01:
+		return SortCoreTable, nil
+	case 0x02:
+		return SortCoreMemory, nil
+	case 0x03:
+		return SortCoreGlobal, nil
+	case 0x10:
+		return SortCoreType, nil
+	case 0x11:
+		return SortCoreModule, nil
+	case 0x12:
+		return SortCoreInstance, nil
+	}
+	return 0, fmt.Errorf("invalid core sort: 0x%x", b)
+}
+
+// decodeExternName decodes the name of an import or an export, which is prefixed by its kind.
+func decodeExternName(r *bytes.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 0x00, 0x01:
+		return decodeName(r)
+	case 0x02: // The name is followed by a version suffix, which isn't used.
+		name, err := decodeName(r)
+		if err == nil {
+			_, err = decodeName(r)
+		}
+		return name, err
+	}
+	return "", fmt.Errorf("invalid extern name: 0x%x", b)
+}
+
+func decodeName(r *bytes.Reader) (string, error) {
+	size, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", fmt.Errorf("read name size: %w", err)
+	} else if int(size) > r.Len() {
+		return "", fmt.Errorf("name size %d exceeds the section", size)
+	}
+	buf := make([]byte, size)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return "", fmt.Errorf("read name: %w", err)
+	} else if !utf8.Valid(buf) {
+		return "", errors.New("name is not valid UTF-8")
+	}
+	return string(buf), nil
+}
+
+func decodeVecLen(r *bytes.Reader) (uint32, error) {
+	n, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return 0, fmt.Errorf("read vector size: %w", err)
+	} else if int(n) > r.Len() {
+		return 0, fmt.Errorf("vector size %d exceeds the section", n)
+	}
+	return n, nil
+}
@@ -0,0 +1,181 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func cat(bs ...[]byte) (ret []byte) {
+	for _, b := range bs {
+		ret = append(ret, b...)
+	}
+	return
+}
+
+var (
+	name = binaryencoding.ComponentName
+	vec  = binaryencoding.ComponentVec
+)
+
+func TestIsComponent(t *testing.T) {
+	require.True(t, IsComponent([]byte("\x00asm\x0d\x00\x01\x00")))
+	require.False(t, IsComponent([]byte("\x00asm\x01\x00\x00\x00")))
+	require.False(t, IsComponent([]byte("\x00asm")))
+}
+
+func TestDecodeComponent(t *testing.T) {
+	t.Run("types", func(t *testing.T) {
+		c, err := DecodeComponent(binaryencoding.EncodeComponent(
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+				// type 0: (record (field "a" u8) (field "b" string))
+				cat([]byte{0x72}, vec(cat(name("a"), []byte{0x7d}), cat(name("b"), []byte{0x73}))),
+				// type 1: (list 0)
+				[]byte{0x70, 0},
+				// type 2: (result string (error u32))
+				[]byte{0x6a, 0x01, 0x73, 0x01, 0x79},
+				// type 3: (enum "x" "y")
+				cat([]byte{0x6d}, vec(name("x"), name("y"))),
+				// type 4: (resource (rep i32))
+				[]byte{0x3f, 0x7f, 0x00},
+				// type 5: (own 4)
+				[]byte{0x69, 4},
+				// type 6: (func (param "l" 1) (result 2))
+				cat([]byte{0x40}, vec(cat(name("l"), []byte{1})), []byte{0x00, 2}),
+			),
+		))
+		require.NoError(t, err)
+		require.Equal(t, 7, len(c.Types))
+
+		record := c.Types[0]
+		require.Equal(t, "record {a: u8, b: string}", record.String())
+		require.Equal(t, record, c.Types[1].Elem)
+		require.Equal(t, "result<string, u32>", c.Types[2].String())
+		require.Equal(t, []string{"x", "y"}, c.Types[3].Labels)
+		require.Equal(t, TypeKindResource, c.Types[4].Kind)
+		require.True(t, c.Types[4].Defined)
+		require.Equal(t, c.Types[4], c.Types[5].Elem)
+		require.Equal(t, "func(l: list<record {a: u8, b: string}>) -> result<string, u32>", c.Types[6].String())
+	})
+
+	t.Run("definitions", func(t *testing.T) {
+		c, err := DecodeComponent(binaryencoding.EncodeComponent(
+			// type 0: (instance (type (func (param "x" u32))) (export "f" (func (type 0))))
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+				cat([]byte{0x42}, vec(
+					cat([]byte{0x01, 0x40}, vec(cat(name("x"), []byte{0x79})), []byte{0x01, 0x00}),
+					cat([]byte{0x04, 0x00}, name("f"), []byte{0x01, 0}),
+				))),
+			// instance 0: (import "a:b/c@1.0.0" (instance (type 0)))
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDImport,
+				cat([]byte{0x00}, name("a:b/c@1.0.0"), []byte{0x05, 0})),
+			// func 0: (alias export 0 "f")
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+				cat([]byte{0x01, 0x00, 0}, name("f"))),
+			// core func 0: (canon lower (func 0) string-encoding=utf16)
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon, []byte{0x01, 0x00, 0, 1, 0x01}),
+			// core instance 0: (instance (export "f" (func 0)))
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance,
+				cat([]byte{0x01}, vec(cat(name("f"), []byte{0x00, 0})))),
+			// (export "g" (func 0))
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDExport,
+				cat([]byte{0x00}, name("g"), []byte{0x01, 0, 0x00})),
+		))
+		require.NoError(t, err)
+
+		require.Equal(t, []*Import{{Name: "a:b/c@1.0.0", Sort: SortInstance, Type: c.Types[0]}}, c.Imports)
+		fn := c.Types[0].Export("f").Type
+		require.Equal(t, "func(x: u32)", fn.String())
+
+		require.Equal(t, 2, len(c.Funcs))
+		require.Equal(t, &Func{Kind: FuncKindAlias, Type: fn, Instance: 0, Name: "f"}, c.Funcs[0])
+		require.Equal(t, FuncKindAlias, c.Funcs[1].Kind)
+
+		require.Equal(t, 1, len(c.CoreFuncs))
+		lowered := c.CoreFuncs[0]
+		require.Equal(t, CoreFuncKindLower, lowered.Kind)
+		require.Equal(t, StringEncodingUTF16, lowered.Options.StringEncoding)
+		require.Equal(t, Index(0), lowered.Func)
+
+		require.Equal(t, []*CoreInstance{{Inline: true, Exports: []CoreInlineExport{{Name: "f", Sort: SortCoreFunc}}}}, c.CoreInstances)
+		require.Equal(t, []Definition{{Sort: SortCoreFunc}, {Sort: SortCoreInstance}}, c.Definitions)
+		require.Equal(t, []*Export{{Name: "g", Sort: SortFunc, Index: 0, Type: fn}}, c.Exports)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		nested := binaryencoding.EncodeComponent(
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType, []byte{0x40, 0, 0x01, 0}),
+			binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDImport,
+				cat([]byte{0x00}, name("f"), []byte{0x01, 0})),
+		)[8:]
+		c, err := DecodeComponent(binaryencoding.EncodeComponent(
+			binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDComponent, nested),
+		))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(c.Components))
+		require.Equal(t, `component {import "f"}`, c.Components[0].Type().String())
+	})
+}
+
+func TestDecodeComponent_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "module",
+			input:       []byte("\x00asm\x01\x00\x00\x00"),
+			expectedErr: "invalid component header",
+		},
+		{
+			name:        "section exceeding the binary",
+			input:       binaryencoding.EncodeComponent([]byte{binaryencoding.ComponentSectionIDType, 10, 0}),
+			expectedErr: "section 7 of size 10 exceeds the binary",
+		},
+		{
+			name:        "start section",
+			input:       binaryencoding.EncodeComponent(binaryencoding.ComponentSection(9, []byte{0})),
+			expectedErr: "section 9: start functions are not supported",
+		},
+		{
+			name: "invalid type",
+			input: binaryencoding.EncodeComponent(
+				binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType, []byte{0x10})),
+			expectedErr: "section 7: entry[0]: type 0x10 is not supported",
+		},
+		{
+			name: "type index out of range",
+			input: binaryencoding.EncodeComponent(
+				binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType, []byte{0x70, 0})),
+			expectedErr: "section 7: entry[0]: type index 0 out of range",
+		},
+		{
+			name: "own of a non resource",
+			input: binaryencoding.EncodeComponent(
+				binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType, []byte{0x70, 0x79}, []byte{0x69, 0})),
+			expectedErr: "section 7: entry[1]: type[0] is a list, not a resource",
+		},
+		{
+			name: "func index out of range",
+			input: binaryencoding.EncodeComponent(
+				binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon, []byte{0x01, 0x00, 0, 0})),
+			expectedErr: "section 8: entry[0]: func index 0 out of range",
+		},
+		{
+			name: "section size mismatch",
+			input: binaryencoding.EncodeComponent(
+				binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType, []byte{0x70, 0x79, 0})),
+			expectedErr: "section 7: section size mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeComponent(tc.input)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	cm "github.com/tetratelabs/wazero/experimental/component"
+)
+
+// goTypesPkgPath is the package of the Go types of the values which have no Go counterpart.
+const goTypesPkgPath = "github.com/tetratelabs/wazero/experimental/component"
+
+var (
+	charType    = reflect.TypeOf(cm.Char(0))
+	tupleType   = reflect.TypeOf(cm.Tuple{})
+	variantType = reflect.TypeOf(cm.Variant{})
+	unitType    = reflect.TypeOf(struct{}{})
+	enumType    = reflect.TypeOf((*cm.Enum)(nil)).Elem()
+	flagsType   = reflect.TypeOf((*cm.Flags)(nil)).Elem()
+)
+
+// goTypes maps the Go types to the types of the component model, which keeps the resource types of the Go types of
+// the representations of the handles.
+type goTypes struct {
+	resources map[reflect.Type]*Type
+	visiting  map[reflect.Type]bool
+}
+
+// typeOf returns the type of the component model which is represented by the Go type.
+func (g *goTypes) typeOf(t reflect.Type) (*Type, error) {
+	if g.visiting[t] {
+		return nil, fmt.Errorf("%v is recursive", t)
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	if t == charType {
+		return PrimitiveType(TypeKindChar), nil
+	}
+	if isUnsigned(t.Kind()) {
+		var labels []string
+		var kind TypeKind
+		if t.Implements(enumType) {
+			labels, kind = reflect.Zero(t).Interface().(cm.Enum).Cases(), TypeKindEnum
+		} else if t.Implements(flagsType) {
+			labels, kind = reflect.Zero(t).Interface().(cm.Flags).Flags(), TypeKindFlags
+		}
+		if kind != 0 {
+			if len(labels) == 0 {
+				return nil, fmt.Errorf("%v has no %s", t, kind)
+			} else if kind == TypeKindFlags && len(labels) > t.Bits() {
+				return nil, fmt.Errorf("%v has %d flags, but only %d bits", t, len(labels), t.Bits())
+			} else if kind == TypeKindEnum && t.Bits() < 64 && uint64(len(labels)) > uint64(1)<<t.Bits() {
+				return nil, fmt.Errorf("%v has %d cases, but only %d bits", t, len(labels), t.Bits())
+			}
+			return &Type{Kind: kind, Labels: labels}, nil
+		}
+	}
+
+	if t.PkgPath() == goTypesPkgPath {
+		switch name, _, _ := strings.Cut(t.Name(), "["); name {
+		case "Option":
+			elem, err := g.typeOf(t.Field(0).Type)
+			return &Type{Kind: TypeKindOption, Elem: elem}, err
+		case "Result":
+			ret := &Type{Kind: TypeKindResult}
+			var err error
+			if ret.Elem, err = g.payloadTypeOf(t.Field(0).Type); err != nil {
+				return nil, err
+			}
+			ret.Err, err = g.payloadTypeOf(t.Field(1).Type)
+			return ret, err
+		case "Own", "Borrow":
+			ret := &Type{Kind: TypeKindOwn}
+			if name == "Borrow" {
+				ret.Kind = TypeKindBorrow
+			}
+			rep := t.Field(0).Type
+			if ret.Elem = g.resources[rep]; ret.Elem == nil {
+				ret.Elem = &Type{Kind: TypeKindResource}
+				g.resources[rep] = ret.Elem
+			}
+			return ret, nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return PrimitiveType(TypeKindBool), nil
+	case reflect.Int8:
+		return PrimitiveType(TypeKindS8), nil
+	case reflect.Uint8:
+		return PrimitiveType(TypeKindU8), nil
+	case reflect.Int16:
+		return PrimitiveType(TypeKindS16), nil
+	case reflect.Uint16:
+		return PrimitiveType(TypeKindU16), nil
+	case reflect.Int32:
+		return PrimitiveType(TypeKindS32), nil
+	case reflect.Uint32:
+		return PrimitiveType(TypeKindU32), nil
+	case reflect.Int64:
+		return PrimitiveType(TypeKindS64), nil
+	case reflect.Uint64:
+		return PrimitiveType(TypeKindU64), nil
+	case reflect.Float32:
+		return PrimitiveType(TypeKindF32), nil
+	case reflect.Float64:
+		return PrimitiveType(TypeKindF64), nil
+	case reflect.String:
+		return PrimitiveType(TypeKindString), nil
+	case reflect.Slice, reflect.Array:
+		elem, err := g.typeOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		ret := &Type{Kind: TypeKindList, Elem: elem}
+		if t.Kind() == reflect.Array {
+			if t.Len() == 0 {
+				return nil, fmt.Errorf("%v is empty", t)
+			}
+			ret.Length = uint32(t.Len())
+		}
+		return ret, nil
+	case reflect.Struct:
+		return g.structTypeOf(t)
+	}
+	return nil, fmt.Errorf("%v is not a type of the component model", t)
+}
+
+// payloadTypeOf returns the type of the payload of a case, which is nil for struct{}.
+func (g *goTypes) payloadTypeOf(t reflect.Type) (*Type, error) {
+	if t == unitType {
+		return nil, nil
+	}
+	return g.typeOf(t)
+}
+
+// structTypeOf returns the type of a record, a tuple or a variant.
+func (g *goTypes) structTypeOf(t reflect.Type) (*Type, error) {
+	ret := &Type{Kind: TypeKindRecord}
+	if t.NumField() > 0 && t.Field(0).Anonymous {
+		switch t.Field(0).Type {
+		case tupleType:
+			ret.Kind = TypeKindTuple
+		case variantType:
+			ret.Kind = TypeKindVariant
+		}
+	}
+	for _, i := range goFields(t) {
+		f := t.Field(i)
+		if !f.IsExported() {
+			return nil, fmt.Errorf("%v has unexported field %s", t, f.Name)
+		}
+		field := Field{Name: fieldName(f)}
+		var err error
+		if ret.Kind == TypeKindVariant {
+			if f.Type.Kind() != reflect.Pointer {
+				return nil, fmt.Errorf("case %s of %v is not a pointer", f.Name, t)
+			}
+			field.Type, err = g.payloadTypeOf(f.Type.Elem())
+		} else {
+			field.Type, err = g.typeOf(f.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ret.Kind == TypeKindTuple {
+			field.Name = ""
+		}
+		ret.Fields = append(ret.Fields, field)
+	}
+	if len(ret.Fields) == 0 {
+		return nil, fmt.Errorf("%v has no fields", t)
+	}
+	return ret, nil
+}
+
+var goFieldsCache sync.Map // map[reflect.Type][]int
+
+// goFields returns the indexes of the fields of the struct which are the fields of a record or a tuple, or the cases
+// of a variant, which exclude the fields tagged `wit:"-"`, and the embedded Tuple or Variant.
+func goFields(t reflect.Type) []int {
+	if v, ok := goFieldsCache.Load(t); ok {
+		return v.([]int)
+	}
+	var ret []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("wit") == "-" || (i == 0 && f.Anonymous && (f.Type == tupleType || f.Type == variantType)) {
+			continue
+		}
+		ret = append(ret, i)
+	}
+	goFieldsCache.Store(t, ret)
+	return ret
+}
+
+// fieldName returns the `wit` tag of the field, or else the kebab case of its name, e.g. "file-size" for FileSize.
+func fieldName(f reflect.StructField) string {
+	if name := f.Tag.Get("wit"); name != "" {
+		return name
+	}
+	rs := []rune(f.Name)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 &&
+			(!unicode.IsUpper(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+			b.WriteByte('-')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// errTypeMismatch is returned by checkShape when the types don't have the same representation.
+var errTypeMismatch = errors.New("type mismatch")
+
+// checkShape returns errTypeMismatch unless the types have the same representation, regardless of the names of their
+// fields, and of the identity of their resource types.
+func checkShape(a, b *Type) error {
+	if a == b {
+		return nil
+	} else if a == nil || b == nil || a.Kind != b.Kind || len(a.Fields) != len(b.Fields) ||
+		len(a.Results) != len(b.Results) || len(a.Labels) != len(b.Labels) || a.Length != b.Length {
+		return errTypeMismatch
+	}
+	switch a.Kind {
+	case TypeKindOwn, TypeKindBorrow:
+		return nil
+	case TypeKindList, TypeKindOption, TypeKindResult, TypeKindStream, TypeKindFuture:
+		if (a.Elem == nil) != (b.Elem == nil) || (a.Err == nil) != (b.Err == nil) {
+			return errTypeMismatch
+		}
+		if a.Elem != nil {
+			if err := checkShape(a.Elem, b.Elem); err != nil {
+				return err
+			}
+		}
+		if a.Err != nil {
+			return checkShape(a.Err, b.Err)
+		}
+	}
+	for _, fields := range [][2][]Field{{a.Fields, b.Fields}, {a.Results, b.Results}} {
+		for i := range fields[0] {
+			x, y := fields[0][i].Type, fields[1][i].Type
+			if (x == nil) != (y == nil) {
+				return errTypeMismatch
+			} else if x != nil {
+				if err := checkShape(x, y); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
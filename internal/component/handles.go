@@ -0,0 +1,93 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// handle is an entry of the handle table of a component instance.
+type handle struct {
+	// typ is the resource type of the handle.
+	typ *Type
+	// rep is the representation of the resource, which is an uint32 for the resource types defined by a component, or
+	// the Go value given by the host.
+	rep any
+	own bool
+}
+
+// handleTable is the table of the handles of the resources given to a component instance, which are indexes in the
+// table from 1, as 0 is never a valid handle.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md#handle-state
+type handleTable struct {
+	mu      sync.Mutex
+	entries []*handle
+	free    []uint32
+}
+
+// add adds the handle to the table, and returns its index.
+func (t *handleTable) add(h *handle) uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.free); n > 0 {
+		i := t.free[n-1]
+		t.free = t.free[:n-1]
+		t.entries[i] = h
+		return i
+	}
+	if len(t.entries) == 0 {
+		t.entries = append(t.entries, nil)
+	}
+	t.entries = append(t.entries, h)
+	return uint32(len(t.entries) - 1)
+}
+
+// get returns the handle of the index, which must be of the resource type typ, or traps otherwise.
+func (t *handleTable) get(i uint32, typ *Type) *handle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lookup(i, typ)
+}
+
+func (t *handleTable) lookup(i uint32, typ *Type) *handle {
+	if i == 0 || int(i) >= len(t.entries) || t.entries[i] == nil {
+		panic(fmt.Errorf("%w: %d", errBadHandle, i))
+	}
+	h := t.entries[i]
+	if h.typ != typ {
+		panic(fmt.Errorf("%w: %d", errHandleType, i))
+	}
+	return h
+}
+
+// remove removes the handle of the index, which must be of the resource type typ, and must be owned if own is true.
+func (t *handleTable) remove(i uint32, typ *Type, own bool) *handle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.lookup(i, typ)
+	if own && !h.own {
+		panic(fmt.Errorf("%w: %d is borrowed", errBadHandle, i))
+	}
+	t.entries[i] = nil
+	t.free = append(t.free, i)
+	return h
+}
+
+// close closes the representations of the resources owned by the table, which implement api.Closer.
+func (t *handleTable) close(ctx context.Context) {
+	t.mu.Lock()
+	entries := t.entries
+	t.entries, t.free = nil, nil
+	t.mu.Unlock()
+	for _, h := range entries {
+		if h == nil || !h.own {
+			continue
+		}
+		if c, ok := h.rep.(api.Closer); ok {
+			_ = c.Close(ctx)
+		}
+	}
+}
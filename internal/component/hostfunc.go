@@ -0,0 +1,159 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ReallocName is the name of the function exported by core modules to allocate the values lowered into their memory,
+// when their options aren't given by a component.
+const ReallocName = "cabi_realloc"
+
+var (
+	moduleType    = reflect.TypeOf((*api.Module)(nil)).Elem()
+	goContextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// compile-time check to ensure HostFunc implements api.GoModuleFunction.
+var _ api.GoModuleFunction = (*HostFunc)(nil)
+
+// HostFunc is a Go function of the host whose params and results are values of the component model, which are
+// lifted from and lowered to core values as per the canonical ABI.
+//
+// When it's lowered by a component, the canonical options of the lowering apply, and its handles are the ones of the
+// component instance. Otherwise, it uses the memory and the ReallocName function of the calling module, with UTF-8
+// strings, and can't use handles.
+type HostFunc struct {
+	// Type is the function type of the component model of the Go function.
+	Type *Type
+	// CoreType is the type of the core function which it's lowered to.
+	CoreType *wasm.FunctionType
+
+	fn                  reflect.Value
+	withCtx, withModule bool
+	// flatParams and flatResults are the number of the flattened core values of the params and the results.
+	flatParams, flatResults int
+}
+
+// NewHostFunc returns the HostFunc of the Go func, which may have a context.Context param, followed by an api.Module
+// one, and then params of the Go types of the values of the component model. It may have one such result.
+func NewHostFunc(fn interface{}) (*HostFunc, error) {
+	fnV := reflect.ValueOf(fn)
+	if fnV.Kind() != reflect.Func {
+		return nil, fmt.Errorf("kind != func: %s", fnV.Kind().String())
+	}
+	p := fnV.Type()
+	ret := &HostFunc{fn: fnV, Type: &Type{Kind: TypeKindFunc}}
+
+	offset := 0
+	if p.NumIn() > 0 && p.In(0) == goContextType {
+		ret.withCtx, offset = true, 1
+		if p.NumIn() > 1 && p.In(1) == moduleType {
+			ret.withModule, offset = true, 2
+		}
+	}
+
+	g := &goTypes{resources: map[reflect.Type]*Type{}, visiting: map[reflect.Type]bool{}}
+	for i := offset; i < p.NumIn(); i++ {
+		pI := p.In(i)
+		if pI == goContextType || pI == moduleType {
+			return nil, fmt.Errorf("param[%d] is a %s, which may be defined only once as param[%d]", i, pI, i-offset)
+		}
+		t, err := g.typeOf(pI)
+		if err != nil {
+			return nil, fmt.Errorf("param[%d] is unsupported: %w", i, err)
+		}
+		ret.Type.Fields = append(ret.Type.Fields, Field{Name: fmt.Sprintf("p%d", i-offset), Type: t})
+	}
+	switch p.NumOut() {
+	case 0:
+	case 1:
+		t, err := g.typeOf(p.Out(0))
+		if err != nil {
+			return nil, fmt.Errorf("result[0] is unsupported: %w", err)
+		} else if t.Kind == TypeKindBorrow {
+			return nil, errors.New("result[0] is unsupported: borrowed handles can't be returned")
+		}
+		ret.Type.Results = []Field{{Type: t}}
+	default:
+		return nil, fmt.Errorf("multiple results are unsupported: %d", p.NumOut())
+	}
+
+	ret.CoreType = ret.Type.CoreType(true)
+	var params, results []wasm.ValueType
+	for _, f := range ret.Type.Fields {
+		params = f.Type.Flatten(params)
+	}
+	for _, f := range ret.Type.Results {
+		results = f.Type.Flatten(results)
+	}
+	ret.flatParams, ret.flatResults = len(params), len(results)
+	return ret, nil
+}
+
+// Call implements api.GoModuleFunction, with the memory and the ReallocName function of the calling module.
+func (f *HostFunc) Call(ctx context.Context, mod api.Module, stack []uint64) {
+	cx := &callContext{ctx: ctx, memory: mod.Memory(), encoding: StringEncodingUTF8}
+	if realloc := mod.ExportedFunction(ReallocName); realloc != nil {
+		cx.realloc = realloc
+	}
+	f.call(cx, f.Type, mod, stack)
+}
+
+// check returns an error unless the function can be lowered as a function of the type.
+func (f *HostFunc) check(t *Type) error {
+	if t == nil {
+		return nil
+	} else if err := checkShape(f.Type, t); err != nil {
+		return fmt.Errorf("%w: %s != %s", err, t, f.Type)
+	}
+	return nil
+}
+
+// call lifts the params of the function of the type t from the stack, calls the Go function, and lowers its result.
+func (f *HostFunc) call(cx *callContext, t *Type, mod api.Module, stack []uint64) {
+	p := f.fn.Type()
+	args := make([]reflect.Value, 0, p.NumIn())
+	if f.withCtx {
+		args = append(args, reflect.ValueOf(cx.ctx))
+	}
+	if f.withModule {
+		args = append(args, reflect.ValueOf(mod))
+	}
+
+	var flat *flatValues
+	var ptr uint32
+	if f.flatParams > MaxFlatParams {
+		ptr = uint32(stack[0])
+	} else {
+		flat = &flatValues{values: stack}
+	}
+	for _, field := range t.Fields {
+		v := reflect.New(p.In(len(args))).Elem()
+		if flat != nil {
+			cx.liftFlat(field.Type, v, flat)
+		} else {
+			ptr = alignTo(ptr, field.Type.alignment())
+			cx.load(field.Type, v, ptr)
+			ptr += field.Type.size()
+		}
+		args = append(args, v)
+	}
+
+	results := f.fn.Call(args)
+	if len(t.Results) == 0 {
+		return
+	}
+	result := t.Results[0].Type
+	if f.flatResults > MaxFlatResults {
+		// The pointer to store the results at is given as the last param.
+		cx.store(result, results[0], uint32(stack[len(f.CoreType.Params)-1]))
+	} else {
+		copy(stack, cx.lowerFlat(result, results[0], make([]uint64, 0, f.flatResults)))
+	}
+}
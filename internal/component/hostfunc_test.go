@@ -0,0 +1,111 @@
+package component
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestNewHostFunc(t *testing.T) {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+
+	tests := []struct {
+		name                 string
+		input                interface{}
+		expectedType         string
+		expectedParams       []wasm.ValueType
+		expectedResults      []wasm.ValueType
+		expectedCtx, withMod bool
+	}{
+		{
+			name:         "no params",
+			input:        func() {},
+			expectedType: "func()",
+		},
+		{
+			name:            "context and module",
+			input:           func(context.Context, api.Module, uint64) bool { return false },
+			expectedType:    "func(p0: u64) -> bool",
+			expectedParams:  []wasm.ValueType{i64},
+			expectedResults: []wasm.ValueType{i32},
+			expectedCtx:     true,
+			withMod:         true,
+		},
+		{
+			name:           "string to string",
+			input:          func(context.Context, string) string { return "" },
+			expectedType:   "func(p0: string) -> string",
+			expectedParams: []wasm.ValueType{i32, i32, i32},
+			expectedCtx:    true,
+		},
+		{
+			name: "resources",
+			input: func(cm.Own[*testCloser], cm.Borrow[*testCloser]) cm.Option[cm.Own[*testCloser]] {
+				return cm.Option[cm.Own[*testCloser]]{}
+			},
+			expectedType:   "func(p0: own<resource>, p1: borrow<resource>) -> option<own<resource>>",
+			expectedParams: []wasm.ValueType{i32, i32, i32},
+		},
+		{
+			name:           "many params",
+			input:          func([9]string) {},
+			expectedType:   "func(p0: list<string, 9>)",
+			expectedParams: []wasm.ValueType{i32},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewHostFunc(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedType, f.Type.String())
+			require.Equal(t, tc.expectedParams, f.CoreType.Params)
+			require.Equal(t, tc.expectedResults, f.CoreType.Results)
+			require.Equal(t, tc.expectedCtx, f.withCtx)
+			require.Equal(t, tc.withMod, f.withModule)
+		})
+	}
+}
+
+func TestNewHostFunc_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       interface{}
+		expectedErr string
+	}{
+		{name: "not a func", input: 1, expectedErr: "kind != func: int"},
+		{
+			name:        "context twice",
+			input:       func(context.Context, context.Context) {},
+			expectedErr: "param[1] is a context.Context, which may be defined only once as param[0]",
+		},
+		{
+			name:        "unsupported param",
+			input:       func(int) {},
+			expectedErr: "param[0] is unsupported: int is not a type of the component model",
+		},
+		{
+			name:        "borrowed result",
+			input:       func() cm.Borrow[*testCloser] { return cm.Borrow[*testCloser]{} },
+			expectedErr: "result[0] is unsupported: borrowed handles can't be returned",
+		},
+		{
+			name:        "multiple results",
+			input:       func() (uint32, uint32) { return 0, 0 },
+			expectedErr: "multiple results are unsupported: 2",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHostFunc(tc.input)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,588 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/expctxkeys"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// RootModuleName is the name of the module which exports the functions imported by a component at its top level, as
+// opposed to those of its imported instances, which are exported by the module of the name of the instance.
+const RootModuleName = "$root"
+
+// ModuleInstance is an instantiated component, which implements api.Module with the core functions of its exports.
+// The functions of its exported instances are exported as "<instance name>#<function name>".
+type ModuleInstance struct {
+	internalapi.WazeroOnlyType
+
+	name    string
+	exports map[string]*function
+	memory  api.Memory
+	engine  wasm.Engine
+	// modules are the core instances in the order of instantiation.
+	modules []*wasm.ModuleInstance
+	// hostModules are the modules of the built-in functions, which are compiled per instantiation.
+	hostModules []*wasm.Module
+	// handles are the handle tables of the component instances, which are closed with the instance.
+	handles []*handleTable
+	closed  atomic.Bool
+
+	// CodeCloser is closed with the instance, which is the compiled component if it was compiled implicitly.
+	CodeCloser api.Closer
+}
+
+// function is a function of an instance of a component.
+type function struct {
+	typ *Type
+	// module and name are the export of the core function lifted, or of the function of the host.
+	module *wasm.ModuleInstance
+	name   string
+	lifted bool
+	// options are the canonical options of the lifting, or of the lowering of a lowered function.
+	options *CanonOptions
+	// memory is the core instance which exports the memory of the options, or nil if they have none.
+	memory *wasm.ModuleInstance
+	// impl is the Go function of a lowered function of the host, or nil if it's called as an api.Function.
+	impl api.GoModuleFunction
+	// host is impl when it's a HostFunc, whose values are lifted and lowered with the options, the realloc function
+	// and the handles of the component instance which lowers it.
+	host *HostFunc
+	// realloc and postReturn are the functions of the options, or nil if they have none.
+	realloc, postReturn api.Function
+	handles             *handleTable
+}
+
+// call calls the lowered function with the stack of its core function, which is called by mod.
+func (f *function) call(ctx context.Context, mod api.Module, stack []uint64) {
+	if f.memory != nil {
+		// The functions of the host use the memory of the options, which isn't necessarily the one of the caller.
+		mod = f.memory
+	}
+	if f.host != nil {
+		cx := &callContext{ctx: ctx, memory: mod.Memory(), realloc: f.realloc, encoding: f.options.StringEncoding, handles: f.handles}
+		f.host.call(cx, f.typ, mod, stack)
+	} else if f.impl != nil {
+		f.impl.Call(ctx, mod, stack)
+	} else if err := f.module.ExportedFunction(f.name).CallWithStack(ctx, stack); err != nil {
+		panic(err)
+	}
+}
+
+// goFuncOf returns the Go function of the function exported by a host module, or nil if it isn't one.
+func goFuncOf(m *wasm.ModuleInstance, name string) api.GoModuleFunction {
+	src := m.Source
+	exp, ok := m.Exports[name]
+	if !ok || !src.IsHostModule || exp.Index < src.ImportFunctionCount {
+		return nil
+	}
+	switch fn := src.CodeSection[exp.Index-src.ImportFunctionCount].GoFunc.(type) {
+	case api.GoModuleFunction:
+		return fn
+	case api.GoFunction:
+		return api.GoModuleFunc(func(ctx context.Context, _ api.Module, stack []uint64) {
+			fn.Call(ctx, stack)
+		})
+	}
+	return nil
+}
+
+// instance is an instance of a component, which is either imported from the host or instantiated.
+type instance struct {
+	typ *Type
+	// host is the module which exports the functions of an instance imported from the host.
+	host    *wasm.ModuleInstance
+	exports map[string]any
+}
+
+// export returns the export of the instance, which is either a *function or an *instance, or an error if it
+// doesn't exist.
+func (i *instance) export(name string, sort Sort) (any, error) {
+	if i.host != nil {
+		if sort != SortFunc {
+			return nil, fmt.Errorf("instance %s: %s export %q can't be imported from the host", i.host.ModuleName, sort, name)
+		}
+		var typ *Type
+		if decl := i.typ.Export(name); decl != nil {
+			typ = decl.Type
+		}
+		return &function{typ: typ, module: i.host, name: name}, nil
+	}
+	v, ok := i.exports[name]
+	if !ok {
+		return nil, fmt.Errorf("instance has no export %q", name)
+	}
+	return v, nil
+}
+
+// instantiation holds the state of the instantiation of a component.
+type instantiation struct {
+	c      *Compiled
+	s      *wasm.Store
+	sysCtx *internalsys.Context
+	root   *ModuleInstance
+	// args are the definitions given for the imports of a nested component, or nil at the top level.
+	args map[string]any
+	// resolveImport is the experimental.ImportResolver given by the caller, if any.
+	resolveImport experimental.ImportResolver
+
+	coreInstances []*wasm.ModuleInstance
+	builtins      *wasm.ModuleInstance
+	// lowered are the functions lowered per index in Compiled.builtins.
+	lowered   []*function
+	funcs     []*function
+	instances []*instance
+	handles   *handleTable
+}
+
+// Instantiate instantiates the component in the store, whose core instances are anonymous, and share sysCtx.
+//
+// The instances imported by the component are resolved with the experimental.ImportResolver of ctx if any, and then
+// by name in the store. The functions imported at the top level are resolved in the module named RootModuleName.
+func Instantiate(ctx context.Context, s *wasm.Store, c *Compiled, name string, sysCtx *internalsys.Context) (*ModuleInstance, error) {
+	root := &ModuleInstance{name: name, exports: map[string]*function{}, engine: s.Engine}
+	resolveImport, _ := ctx.Value(expctxkeys.ImportResolverKey{}).(experimental.ImportResolver)
+	in := &instantiation{c: c, s: s, sysCtx: sysCtx, root: root, resolveImport: resolveImport}
+	if err := in.instantiate(ctx); err != nil {
+		_ = root.Close(ctx)
+		return nil, err
+	}
+
+	for _, e := range c.Component.Exports {
+		switch e.Sort {
+		case SortFunc:
+			f, err := in.funcAt(e.Index)
+			if err != nil {
+				_ = root.Close(ctx)
+				return nil, fmt.Errorf("export %q: %w", e.Name, err)
+			}
+			root.addExport(e.Name, f)
+		case SortInstance:
+			inst, err := in.instanceAt(e.Index)
+			if err != nil {
+				_ = root.Close(ctx)
+				return nil, fmt.Errorf("export %q: %w", e.Name, err)
+			}
+			for _, decl := range inst.typ.Exports {
+				if decl.Sort != SortFunc {
+					continue
+				}
+				v, err := inst.export(decl.Name, SortFunc)
+				if err != nil {
+					_ = root.Close(ctx)
+					return nil, fmt.Errorf("export %q: %w", e.Name, err)
+				}
+				root.addExport(e.Name+"#"+decl.Name, v.(*function))
+			}
+		}
+	}
+
+	if root.memory == nil && len(c.Component.CoreMemories) > 0 {
+		m, name := in.coreRefAt(c.refOf(SortCoreMemory, 0))
+		root.memory = m.ExportedMemory(name)
+	}
+	return root, nil
+}
+
+func (i *ModuleInstance) addExport(name string, f *function) {
+	i.exports[name] = f
+	if i.memory == nil && f.lifted && f.memory != nil {
+		i.memory = f.memory.Memory()
+	}
+}
+
+func (in *instantiation) instantiate(ctx context.Context) (err error) {
+	c := in.c.Component
+	in.handles = &handleTable{}
+	in.root.handles = append(in.root.handles, in.handles)
+	in.coreInstances = make([]*wasm.ModuleInstance, len(c.CoreInstances))
+	in.funcs = make([]*function, len(c.Funcs))
+	in.instances = make([]*instance, len(c.Instances))
+	in.lowered = make([]*function, len(in.c.builtins))
+
+	if len(in.c.builtins) > 0 {
+		if err = in.instantiateBuiltins(ctx); err != nil {
+			return
+		}
+	}
+
+	for _, d := range c.Definitions {
+		switch d.Sort {
+		case SortCoreInstance:
+			if in.coreInstances[d.Index], err = in.instantiateCore(ctx, d.Index); err != nil {
+				return fmt.Errorf("core instance[%d]: %w", d.Index, err)
+			}
+		case SortInstance:
+			if in.instances[d.Index], err = in.instantiateComponent(ctx, d.Index); err != nil {
+				return fmt.Errorf("instance[%d]: %w", d.Index, err)
+			}
+		case SortCoreFunc:
+			if err = in.lower(d.Index); err != nil {
+				return fmt.Errorf("core func[%d]: %w", d.Index, err)
+			}
+		}
+	}
+	return nil
+}
+
+// instantiateCore instantiates a core instance, which imports the core instances given by its arguments, or the
+// definitions it is made of.
+func (in *instantiation) instantiateCore(ctx context.Context, idx Index) (*wasm.ModuleInstance, error) {
+	inst := in.c.Component.CoreInstances[idx]
+	var module *wasm.Module
+	var typeIDs []wasm.FunctionTypeID
+	var resolve experimental.ImportResolver
+	if inst.Inline {
+		sh := in.c.shims[idx]
+		module, typeIDs = sh.module, sh.typeIDs
+		resolve = func(name string) api.Module {
+			i, err := strconv.Atoi(name)
+			if err != nil || i >= len(sh.refs) {
+				return nil
+			}
+			m, _ := in.coreRefAt(sh.refs[i])
+			return m
+		}
+	} else {
+		m := in.c.Component.CoreModules[inst.Module]
+		module, typeIDs = m.Module, m.TypeIDs
+		resolve = func(name string) api.Module {
+			for _, arg := range inst.Args {
+				if arg.Name == name {
+					return in.coreInstances[arg.Instance]
+				}
+			}
+			return nil
+		}
+	}
+
+	ctx = context.WithValue(ctx, expctxkeys.ImportResolverKey{}, resolve)
+	m, err := in.s.Instantiate(ctx, module, "", in.sysCtx, typeIDs)
+	if err != nil {
+		return nil, err
+	}
+	in.root.modules = append(in.root.modules, m)
+	return m, nil
+}
+
+// instantiateComponent returns the instance of the index, instantiating a nested component with its arguments.
+func (in *instantiation) instantiateComponent(ctx context.Context, idx Index) (*instance, error) {
+	inst := in.c.Component.Instances[idx]
+	args := make(map[string]any, len(inst.Args))
+	for _, arg := range inst.Args {
+		var err error
+		switch arg.Sort {
+		case SortFunc:
+			args[arg.Name], err = in.funcAt(arg.Index)
+		case SortInstance:
+			args[arg.Name], err = in.instanceAt(arg.Index)
+		case SortType:
+			continue // Types are only checked statically.
+		default:
+			err = fmt.Errorf("%s arguments are not supported", arg.Sort)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("arg %q: %w", arg.Name, err)
+		}
+	}
+
+	nested := &instantiation{
+		c: in.c.nested[inst.Component], s: in.s, sysCtx: in.sysCtx, root: in.root, args: args,
+		resolveImport: in.resolveImport,
+	}
+	if err := nested.instantiate(ctx); err != nil {
+		return nil, err
+	}
+	ret := &instance{typ: inst.Type, exports: map[string]any{}}
+	for _, e := range nested.c.Component.Exports {
+		var err error
+		switch e.Sort {
+		case SortFunc:
+			ret.exports[e.Name], err = nested.funcAt(e.Index)
+		case SortInstance:
+			ret.exports[e.Name], err = nested.instanceAt(e.Index)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("export %q: %w", e.Name, err)
+		}
+	}
+	return ret, nil
+}
+
+// funcAt returns the function of the index.
+func (in *instantiation) funcAt(idx Index) (*function, error) {
+	if f := in.funcs[idx]; f != nil {
+		return f, nil
+	}
+	f := in.c.Component.Funcs[idx]
+	var ret *function
+	switch f.Kind {
+	case FuncKindImport:
+		v, err := in.importAt(f.Import)
+		if err != nil {
+			return nil, err
+		}
+		ret = v.(*function)
+	case FuncKindAlias:
+		inst, err := in.instanceAt(f.Instance)
+		if err != nil {
+			return nil, err
+		}
+		v, err := inst.export(f.Name, SortFunc)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if ret, ok = v.(*function); !ok {
+			return nil, fmt.Errorf("export %q is not a func", f.Name)
+		}
+	case FuncKindLift:
+		m, name := in.coreRefAt(in.c.refOf(SortCoreFunc, f.CoreFunc))
+		ret = &function{typ: f.Type, module: m, name: name, lifted: true, options: &f.Options, handles: in.handles}
+		if f.Options.Memory != nil {
+			ret.memory, _ = in.coreRefAt(in.c.refOf(SortCoreMemory, *f.Options.Memory))
+		}
+		ret.realloc, ret.postReturn = in.optionFunc(f.Options.Realloc), in.optionFunc(f.Options.PostReturn)
+	}
+	in.funcs[idx] = ret
+	return ret, nil
+}
+
+// instanceAt returns the instance of the index, which must be instantiated already if it is of a component.
+func (in *instantiation) instanceAt(idx Index) (*instance, error) {
+	if i := in.instances[idx]; i != nil {
+		return i, nil
+	}
+	inst := in.c.Component.Instances[idx]
+	var ret *instance
+	switch inst.Kind {
+	case InstanceKindImport:
+		v, err := in.importAt(inst.Import)
+		if err != nil {
+			return nil, err
+		}
+		ret = v.(*instance)
+	case InstanceKindAlias:
+		outer, err := in.instanceAt(inst.Instance)
+		if err != nil {
+			return nil, err
+		}
+		v, err := outer.export(inst.Name, SortInstance)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if ret, ok = v.(*instance); !ok {
+			return nil, fmt.Errorf("export %q is not an instance", inst.Name)
+		}
+	case InstanceKindInline:
+		ret = &instance{typ: inst.Type, exports: make(map[string]any, len(inst.Args))}
+		for _, e := range inst.Args {
+			var err error
+			switch e.Sort {
+			case SortFunc:
+				ret.exports[e.Name], err = in.funcAt(e.Index)
+			case SortInstance:
+				ret.exports[e.Name], err = in.instanceAt(e.Index)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.New("BUG: instance used before its instantiation")
+	}
+	in.instances[idx] = ret
+	return ret, nil
+}
+
+// importAt returns the definition imported, which is either a *function or an *instance.
+func (in *instantiation) importAt(idx Index) (any, error) {
+	imp := in.c.Component.Imports[idx]
+	if in.args != nil {
+		v, ok := in.args[imp.Name]
+		if !ok {
+			return nil, fmt.Errorf("import %q is not given", imp.Name)
+		}
+		return v, nil
+	}
+
+	moduleName := imp.Name
+	if imp.Sort == SortFunc {
+		moduleName = RootModuleName
+	}
+	var m *wasm.ModuleInstance
+	if in.resolveImport != nil {
+		if v := in.resolveImport(moduleName); v != nil {
+			m = v.(*wasm.ModuleInstance)
+		}
+	}
+	if m == nil {
+		if m = in.s.Module(moduleName); m == nil {
+			return nil, fmt.Errorf("import %q: module[%s] not instantiated", imp.Name, moduleName)
+		}
+	}
+	if imp.Sort == SortFunc {
+		return &function{typ: imp.Type, module: m, name: imp.Name}, nil
+	}
+	return &instance{typ: imp.Type, host: m}, nil
+}
+
+// coreRefAt returns the core instance and the name of the export of the core definition.
+func (in *instantiation) coreRefAt(ref coreRef) (*wasm.ModuleInstance, string) {
+	if ref.instance == builtinsInstance {
+		return in.builtins, ref.name
+	}
+	return in.coreInstances[ref.instance], ref.name
+}
+
+// instantiateBuiltins instantiates the host module which implements the built-in core functions of the component.
+func (in *instantiation) instantiateBuiltins(ctx context.Context) error {
+	exportNames := make([]string, len(in.c.builtins))
+	nameToHostFunc := make(map[string]*wasm.HostFunc, len(in.c.builtins))
+	for i, idx := range in.c.builtins {
+		name := strconv.Itoa(int(idx))
+		exportNames[i] = name
+		ft := in.c.builtinTypes[i]
+		nameToHostFunc[name] = &wasm.HostFunc{
+			ExportName:  name,
+			ParamTypes:  ft.Params,
+			ResultTypes: ft.Results,
+			Code:        wasm.Code{GoFunc: in.builtin(i)},
+		}
+	}
+	module, err := wasm.NewHostModule("component", exportNames, nameToHostFunc, in.s.EnabledFeatures)
+	if err != nil {
+		return err
+	} else if err = module.Validate(in.s.EnabledFeatures); err != nil {
+		return err
+	}
+	if err = in.s.Engine.CompileModule(ctx, module, nil, false); err != nil {
+		return err
+	}
+	in.root.hostModules = append(in.root.hostModules, module)
+	typeIDs, err := in.s.GetFunctionTypeIDs(module.TypeSection)
+	if err != nil {
+		return err
+	}
+	if in.builtins, err = in.s.Instantiate(ctx, module, "", nil, typeIDs); err != nil {
+		return err
+	}
+	in.root.modules = append(in.root.modules, in.builtins)
+	return nil
+}
+
+// builtin returns the implementation of the built-in core function of the index in Compiled.builtins.
+func (in *instantiation) builtin(i int) api.GoModuleFunction {
+	f := in.c.Component.CoreFuncs[in.c.builtins[i]]
+	switch f.Kind {
+	case CoreFuncKindLower:
+		return api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			in.lowered[i].call(ctx, mod, stack)
+		})
+	case CoreFuncKindResourceNew:
+		return api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = uint64(in.handles.add(&handle{typ: f.Type, rep: uint32(stack[0]), own: true}))
+		})
+	case CoreFuncKindResourceDrop:
+		return api.GoModuleFunc(func(ctx context.Context, _ api.Module, stack []uint64) {
+			in.drop(ctx, in.handles.remove(uint32(stack[0]), f.Type, false))
+		})
+	default:
+		return api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			rep, ok := in.handles.get(uint32(stack[0]), f.Type).rep.(uint32)
+			if !ok {
+				panic(fmt.Errorf("%w: %d is a resource of the host", errBadHandle, stack[0]))
+			}
+			stack[0] = uint64(rep)
+		})
+	}
+}
+
+// drop calls the destructor of the resource of the handle dropped if it's owned, which is either the destructor of
+// the resource type if it's defined by a component, or else the api.Closer of its representation, if any.
+func (in *instantiation) drop(ctx context.Context, h *handle) {
+	if !h.own {
+		return
+	}
+	if d := h.typ.Destructor; d != nil {
+		m, name := in.coreRefAt(in.c.refOf(SortCoreFunc, *d))
+		if _, err := m.ExportedFunction(name).Call(ctx, uint64(h.rep.(uint32))); err != nil {
+			panic(err)
+		}
+	} else if c, ok := h.rep.(api.Closer); ok {
+		if err := c.Close(ctx); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// lower resolves the function lowered to the core function of the index.
+func (in *instantiation) lower(idx Index) error {
+	f := in.c.Component.CoreFuncs[idx]
+	target, err := in.funcAt(f.Func)
+	if err != nil {
+		return err
+	}
+
+	var i int
+	for i = range in.c.builtins {
+		if in.c.builtins[i] == idx {
+			break
+		}
+	}
+	expected := in.c.builtinTypes[i]
+
+	var impl api.GoModuleFunction
+	if !target.lifted {
+		impl = goFuncOf(target.module, target.name)
+	}
+	host, _ := impl.(*HostFunc)
+
+	def := target.module.ExportedFunctionDefinitions()[target.name]
+	if def == nil {
+		return fmt.Errorf("%s has no function %q", target.module.ModuleName, target.name)
+	} else if host != nil {
+		if err = host.check(target.typ); err != nil {
+			return fmt.Errorf("function %q: %w", target.name, err)
+		}
+	} else if !expected.EqualsSignature(def.ParamTypes(), def.ResultTypes()) {
+		return fmt.Errorf("function %q: signature mismatch: %s != %s", target.name, expected,
+			&wasm.FunctionType{Params: def.ParamTypes(), Results: def.ResultTypes()})
+	}
+
+	if target.lifted && (target.memory != nil || f.Options.Memory != nil) {
+		// The values in memory would need to be copied from one memory to the other.
+		return fmt.Errorf("function %q: lowering a function lifted with a memory is not supported", target.name)
+	}
+	lowered := &function{
+		typ: target.typ, module: target.module, name: target.name, options: &f.Options, impl: impl, host: host,
+		handles: in.handles,
+	}
+	if f.Options.Memory != nil {
+		lowered.memory, _ = in.coreRefAt(in.c.refOf(SortCoreMemory, *f.Options.Memory))
+	}
+	lowered.realloc = in.optionFunc(f.Options.Realloc)
+	if host != nil && lowered.typ == nil {
+		lowered.typ = host.Type
+	}
+	in.lowered[i] = lowered
+	return nil
+}
+
+// optionFunc returns the core function of the index given by canonical options, or nil if it's not given.
+func (in *instantiation) optionFunc(idx *Index) api.Function {
+	if idx == nil {
+		return nil
+	}
+	m, name := in.coreRefAt(in.c.refOf(SortCoreFunc, *idx))
+	return m.ExportedFunction(name)
+}
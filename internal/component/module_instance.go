@@ -0,0 +1,98 @@
+package component
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// compile-time check to ensure ModuleInstance implements api.Module
+var _ api.Module = &ModuleInstance{}
+
+// String implements the same method as documented on api.Module.
+func (i *ModuleInstance) String() string {
+	return fmt.Sprintf("Component[%s]", i.name)
+}
+
+// Name implements the same method as documented on api.Module.
+func (i *ModuleInstance) Name() string {
+	return i.name
+}
+
+// Memory implements the same method as documented on api.Module, which returns the memory of the options of the
+// exported functions, or else the first memory aliased by the component.
+func (i *ModuleInstance) Memory() api.Memory {
+	return i.memory
+}
+
+// ExportedFunction implements the same method as documented on api.Module, which returns the core function lifted.
+func (i *ModuleInstance) ExportedFunction(name string) api.Function {
+	f, ok := i.exports[name]
+	if !ok {
+		return nil
+	}
+	return &exportedFunction{Function: f.module.ExportedFunction(f.name), f: f}
+}
+
+// ExportedFunctionDefinitions implements the same method as documented on api.Module.
+func (i *ModuleInstance) ExportedFunctionDefinitions() map[string]api.FunctionDefinition {
+	ret := make(map[string]api.FunctionDefinition, len(i.exports))
+	for name, f := range i.exports {
+		ret[name] = f.module.ExportedFunctionDefinitions()[f.name]
+	}
+	return ret
+}
+
+// ExportedMemory implements the same method as documented on api.Module, which returns nil as components don't
+// export memories.
+func (i *ModuleInstance) ExportedMemory(string) api.Memory {
+	return nil
+}
+
+// ExportedMemoryDefinitions implements the same method as documented on api.Module.
+func (i *ModuleInstance) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
+	return map[string]api.MemoryDefinition{}
+}
+
+// ExportedGlobal implements the same method as documented on api.Module, which returns nil as components don't
+// export globals.
+func (i *ModuleInstance) ExportedGlobal(string) api.Global {
+	return nil
+}
+
+// Close implements the same method as documented on api.Module.
+func (i *ModuleInstance) Close(ctx context.Context) error {
+	return i.CloseWithExitCode(ctx, 0)
+}
+
+// CloseWithExitCode implements the same method as documented on api.Module, which closes the representations of the
+// resources of the host still owned by the component, and then the core instances in the reverse order of their
+// instantiation.
+func (i *ModuleInstance) CloseWithExitCode(ctx context.Context, exitCode uint32) (err error) {
+	if !i.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	for _, h := range i.handles {
+		h.close(ctx)
+	}
+	for j := len(i.modules) - 1; j >= 0; j-- {
+		if e := i.modules[j].CloseWithExitCode(ctx, exitCode); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, m := range i.hostModules {
+		i.engine.DeleteCompiledModule(m)
+	}
+	if i.CodeCloser != nil {
+		if e := i.CodeCloser.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// IsClosed implements the same method as documented on api.Module.
+func (i *ModuleInstance) IsClosed() bool {
+	return i.closed.Load()
+}
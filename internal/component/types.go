@@ -0,0 +1,393 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// TypeKind is the kind of a Type.
+type TypeKind byte
+
+const (
+	TypeKindBool TypeKind = iota
+	TypeKindS8
+	TypeKindU8
+	TypeKindS16
+	TypeKindU16
+	TypeKindS32
+	TypeKindU32
+	TypeKindS64
+	TypeKindU64
+	TypeKindF32
+	TypeKindF64
+	TypeKindChar
+	TypeKindString
+	TypeKindErrorContext
+	TypeKindRecord
+	TypeKindVariant
+	TypeKindList
+	TypeKindTuple
+	TypeKindFlags
+	TypeKindEnum
+	TypeKindOption
+	TypeKindResult
+	TypeKindOwn
+	TypeKindBorrow
+	TypeKindStream
+	TypeKindFuture
+	TypeKindFunc
+	TypeKindComponent
+	TypeKindInstance
+	TypeKindResource
+)
+
+var typeKindNames = [...]string{
+	TypeKindBool:         "bool",
+	TypeKindS8:           "s8",
+	TypeKindU8:           "u8",
+	TypeKindS16:          "s16",
+	TypeKindU16:          "u16",
+	TypeKindS32:          "s32",
+	TypeKindU32:          "u32",
+	TypeKindS64:          "s64",
+	TypeKindU64:          "u64",
+	TypeKindF32:          "f32",
+	TypeKindF64:          "f64",
+	TypeKindChar:         "char",
+	TypeKindString:       "string",
+	TypeKindErrorContext: "error-context",
+	TypeKindRecord:       "record",
+	TypeKindVariant:      "variant",
+	TypeKindList:         "list",
+	TypeKindTuple:        "tuple",
+	TypeKindFlags:        "flags",
+	TypeKindEnum:         "enum",
+	TypeKindOption:       "option",
+	TypeKindResult:       "result",
+	TypeKindOwn:          "own",
+	TypeKindBorrow:       "borrow",
+	TypeKindStream:       "stream",
+	TypeKindFuture:       "future",
+	TypeKindFunc:         "func",
+	TypeKindComponent:    "component",
+	TypeKindInstance:     "instance",
+	TypeKindResource:     "resource",
+}
+
+// String returns the name of the kind as in the text format.
+func (k TypeKind) String() string {
+	if int(k) < len(typeKindNames) {
+		return typeKindNames[k]
+	}
+	return fmt.Sprintf("unknown(%d)", k)
+}
+
+// Type is a type of the component model, which is either a value type, a function type, the type of a component or an
+// instance, or a resource type. Types refer to each other by pointer, so the identity of resource types is the
+// identity of their Type.
+type Type struct {
+	Kind TypeKind
+
+	// Fields are the fields of a record, the cases of a variant whose Type is nil if they have no payload, the
+	// elements of a tuple whose Name is empty, or the params of a func.
+	Fields []Field
+
+	// Results are the results of a func, which is either a single result with an empty name, or named results.
+	Results []Field
+
+	// Labels are the names of the flags of flags, or of the cases of an enum.
+	Labels []string
+
+	// Elem is the element type of a list, an option, a stream or a future, the ok type of a result, or the resource
+	// type of a handle. This is nil if a result, a stream or a future has no such type.
+	Elem *Type
+
+	// Err is the error type of a result, or nil if it has none.
+	Err *Type
+
+	// Length is the length of a list of a fixed length, or zero for the other lists.
+	Length uint32
+
+	// Imports are the imports of a component type.
+	Imports []ExternDecl
+
+	// Exports are the exports of a component or an instance type.
+	Exports []ExternDecl
+
+	// Destructor is the index of the core function in the index space of the component which defines a resource
+	// type, or nil if it has none, or if the resource type is imported.
+	Destructor *Index
+
+	// Defined is true if the resource type is defined by a component, which is the one to represent its handles,
+	// as opposed to being imported.
+	Defined bool
+}
+
+// Field is a named type of a Type.
+type Field struct {
+	Name string
+	Type *Type
+}
+
+// ExternDecl is an import or an export declared by the type of a component or an instance.
+type ExternDecl struct {
+	Name string
+	Sort Sort
+	// Type is the type of the function, the component, the instance or the type declared, or nil for a core module.
+	Type *Type
+}
+
+// Export returns the declaration of the export of a component or an instance type of the given name, or nil.
+func (t *Type) Export(name string) *ExternDecl {
+	for i := range t.Exports {
+		if d := &t.Exports[i]; d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+var primitiveTypes [TypeKindErrorContext + 1]*Type
+
+func init() {
+	for k := range primitiveTypes {
+		primitiveTypes[k] = &Type{Kind: TypeKind(k)}
+	}
+}
+
+// PrimitiveType returns the type of the primitive kind, which is one of TypeKindBool to TypeKindErrorContext.
+func PrimitiveType(k TypeKind) *Type {
+	return primitiveTypes[k]
+}
+
+// IsPrimitive returns true if the type is a primitive value type.
+func (t *Type) IsPrimitive() bool {
+	return t.Kind <= TypeKindErrorContext
+}
+
+// String returns the type in a notation similar to WIT, which is used in error messages.
+func (t *Type) String() string {
+	var b strings.Builder
+	t.format(&b, 0)
+	return b.String()
+}
+
+func (t *Type) format(b *strings.Builder, depth int) {
+	if t == nil {
+		b.WriteString("_")
+		return
+	} else if depth > 8 {
+		b.WriteString("...")
+		return
+	}
+	depth++
+	switch t.Kind {
+	case TypeKindRecord, TypeKindVariant:
+		b.WriteString(t.Kind.String())
+		b.WriteString(" {")
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(f.Name)
+			if f.Type != nil {
+				b.WriteString(": ")
+				f.Type.format(b, depth)
+			}
+		}
+		b.WriteString("}")
+	case TypeKindList:
+		b.WriteString("list<")
+		t.Elem.format(b, depth)
+		if t.Length > 0 {
+			fmt.Fprintf(b, ", %d", t.Length)
+		}
+		b.WriteString(">")
+	case TypeKindTuple:
+		b.WriteString("tuple<")
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			f.Type.format(b, depth)
+		}
+		b.WriteString(">")
+	case TypeKindFlags, TypeKindEnum:
+		b.WriteString(t.Kind.String())
+		b.WriteString(" {")
+		b.WriteString(strings.Join(t.Labels, ", "))
+		b.WriteString("}")
+	case TypeKindOption, TypeKindOwn, TypeKindBorrow, TypeKindStream, TypeKindFuture:
+		b.WriteString(t.Kind.String())
+		if t.Elem != nil || t.Kind == TypeKindOption {
+			b.WriteString("<")
+			t.Elem.format(b, depth)
+			b.WriteString(">")
+		}
+	case TypeKindResult:
+		b.WriteString("result<")
+		t.Elem.format(b, depth)
+		b.WriteString(", ")
+		t.Err.format(b, depth)
+		b.WriteString(">")
+	case TypeKindFunc:
+		b.WriteString("func(")
+		formatFields(b, t.Fields, depth)
+		b.WriteString(")")
+		if len(t.Results) > 0 {
+			b.WriteString(" -> ")
+			if len(t.Results) == 1 && t.Results[0].Name == "" {
+				t.Results[0].Type.format(b, depth)
+			} else {
+				b.WriteString("(")
+				formatFields(b, t.Results, depth)
+				b.WriteString(")")
+			}
+		}
+	case TypeKindComponent, TypeKindInstance:
+		b.WriteString(t.Kind.String())
+		b.WriteString(" {")
+		for i, d := range t.Imports {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "import %q", d.Name)
+		}
+		for i, d := range t.Exports {
+			if i > 0 || len(t.Imports) > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "export %q", d.Name)
+		}
+		b.WriteString("}")
+	default:
+		b.WriteString(t.Kind.String())
+	}
+}
+
+func formatFields(b *strings.Builder, fields []Field, depth int) {
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.Name)
+		b.WriteString(": ")
+		f.Type.format(b, depth)
+	}
+}
+
+const (
+	// MaxFlatParams is the maximum number of the core params of a function, above which they are passed in memory.
+	MaxFlatParams = 16
+	// MaxFlatResults is the maximum number of the core results of a function, above which they are returned in
+	// memory.
+	MaxFlatResults = 1
+)
+
+// Flatten appends the core value types which represent a value of the type as per the canonical ABI.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md#flattening
+func (t *Type) Flatten(dst []wasm.ValueType) []wasm.ValueType {
+	switch t.Kind {
+	case TypeKindBool, TypeKindS8, TypeKindU8, TypeKindS16, TypeKindU16, TypeKindS32, TypeKindU32, TypeKindChar,
+		TypeKindEnum, TypeKindOwn, TypeKindBorrow, TypeKindErrorContext, TypeKindStream, TypeKindFuture:
+		return append(dst, wasm.ValueTypeI32)
+	case TypeKindS64, TypeKindU64:
+		return append(dst, wasm.ValueTypeI64)
+	case TypeKindF32:
+		return append(dst, wasm.ValueTypeF32)
+	case TypeKindF64:
+		return append(dst, wasm.ValueTypeF64)
+	case TypeKindString:
+		return append(dst, wasm.ValueTypeI32, wasm.ValueTypeI32)
+	case TypeKindList:
+		if t.Length > 0 {
+			for i := uint32(0); i < t.Length; i++ {
+				dst = t.Elem.Flatten(dst)
+			}
+			return dst
+		}
+		return append(dst, wasm.ValueTypeI32, wasm.ValueTypeI32)
+	case TypeKindRecord, TypeKindTuple:
+		for _, f := range t.Fields {
+			dst = f.Type.Flatten(dst)
+		}
+		return dst
+	case TypeKindFlags:
+		for i := 0; i < len(t.Labels); i += 32 {
+			dst = append(dst, wasm.ValueTypeI32)
+		}
+		return dst
+	case TypeKindVariant, TypeKindOption, TypeKindResult:
+		var flat []wasm.ValueType
+		for _, c := range t.Cases() {
+			if c == nil {
+				continue
+			}
+			for i, vt := range c.Flatten(nil) {
+				if i < len(flat) {
+					flat[i] = joinValueTypes(flat[i], vt)
+				} else {
+					flat = append(flat, vt)
+				}
+			}
+		}
+		return append(append(dst, wasm.ValueTypeI32), flat...)
+	default:
+		panic(fmt.Sprintf("BUG: %s is not a value type", t.Kind))
+	}
+}
+
+// Cases returns the payload types of the cases of a variant, an option or a result, which are nil for the cases
+// without payload.
+func (t *Type) Cases() []*Type {
+	switch t.Kind {
+	case TypeKindOption:
+		return []*Type{nil, t.Elem}
+	case TypeKindResult:
+		return []*Type{t.Elem, t.Err}
+	default:
+		cases := make([]*Type, len(t.Fields))
+		for i, f := range t.Fields {
+			cases[i] = f.Type
+		}
+		return cases
+	}
+}
+
+func joinValueTypes(a, b wasm.ValueType) wasm.ValueType {
+	if a == b {
+		return a
+	} else if (a == wasm.ValueTypeI32 && b == wasm.ValueTypeF32) || (a == wasm.ValueTypeF32 && b == wasm.ValueTypeI32) {
+		return wasm.ValueTypeI32
+	}
+	return wasm.ValueTypeI64
+}
+
+// CoreType returns the type of the core function which lifts, or is lowered from, the function type.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md#flattening
+func (t *Type) CoreType(lower bool) *wasm.FunctionType {
+	var params, results []wasm.ValueType
+	for _, f := range t.Fields {
+		params = f.Type.Flatten(params)
+	}
+	for _, f := range t.Results {
+		results = f.Type.Flatten(results)
+	}
+	if len(params) > MaxFlatParams {
+		params = []wasm.ValueType{wasm.ValueTypeI32}
+	}
+	if len(results) > MaxFlatResults {
+		if lower {
+			params, results = append(params, wasm.ValueTypeI32), nil
+		} else {
+			results = []wasm.ValueType{wasm.ValueTypeI32}
+		}
+	}
+	ft := &wasm.FunctionType{Params: params, Results: results}
+	ft.CacheNumInUint64()
+	return ft
+}
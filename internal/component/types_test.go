@@ -0,0 +1,143 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestType_Flatten(t *testing.T) {
+	i32, i64, f32, f64 := wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64
+	u8, u64, str := PrimitiveType(TypeKindU8), PrimitiveType(TypeKindU64), PrimitiveType(TypeKindString)
+
+	tests := []struct {
+		name     string
+		input    *Type
+		expected []wasm.ValueType
+	}{
+		{name: "u8", input: u8, expected: []wasm.ValueType{i32}},
+		{name: "u64", input: u64, expected: []wasm.ValueType{i64}},
+		{name: "f64", input: PrimitiveType(TypeKindF64), expected: []wasm.ValueType{f64}},
+		{name: "string", input: str, expected: []wasm.ValueType{i32, i32}},
+		{name: "list", input: &Type{Kind: TypeKindList, Elem: u64}, expected: []wasm.ValueType{i32, i32}},
+		{name: "fixed list", input: &Type{Kind: TypeKindList, Elem: u64, Length: 3}, expected: []wasm.ValueType{i64, i64, i64}},
+		{
+			name:     "record",
+			input:    &Type{Kind: TypeKindRecord, Fields: []Field{{Name: "a", Type: u8}, {Name: "b", Type: str}}},
+			expected: []wasm.ValueType{i32, i32, i32},
+		},
+		{
+			name:     "flags",
+			input:    &Type{Kind: TypeKindFlags, Labels: make([]string, 33)},
+			expected: []wasm.ValueType{i32, i32},
+		},
+		{name: "enum", input: &Type{Kind: TypeKindEnum, Labels: []string{"a", "b"}}, expected: []wasm.ValueType{i32}},
+		{name: "option", input: &Type{Kind: TypeKindOption, Elem: str}, expected: []wasm.ValueType{i32, i32, i32}},
+		{
+			name:     "result of f32 and u32",
+			input:    &Type{Kind: TypeKindResult, Elem: PrimitiveType(TypeKindF32), Err: PrimitiveType(TypeKindU32)},
+			expected: []wasm.ValueType{i32, i32},
+		},
+		{
+			name: "variant",
+			input: &Type{Kind: TypeKindVariant, Fields: []Field{
+				{Name: "a", Type: PrimitiveType(TypeKindF32)},
+				{Name: "b"},
+				{Name: "c", Type: u64},
+			}},
+			expected: []wasm.ValueType{i32, i64},
+		},
+		{
+			name:     "variant of f32",
+			input:    &Type{Kind: TypeKindVariant, Fields: []Field{{Name: "a", Type: PrimitiveType(TypeKindF32)}, {Name: "b"}}},
+			expected: []wasm.ValueType{i32, f32},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.input.Flatten(nil))
+		})
+	}
+}
+
+func TestType_CoreType(t *testing.T) {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	u32, u64, str := PrimitiveType(TypeKindU32), PrimitiveType(TypeKindU64), PrimitiveType(TypeKindString)
+
+	tests := []struct {
+		name                   string
+		input                  *Type
+		expectedLift, expected *wasm.FunctionType
+	}{
+		{
+			name:         "empty",
+			input:        &Type{Kind: TypeKindFunc},
+			expectedLift: &wasm.FunctionType{},
+			expected:     &wasm.FunctionType{},
+		},
+		{
+			name:         "flat",
+			input:        &Type{Kind: TypeKindFunc, Fields: []Field{{Name: "a", Type: u32}}, Results: []Field{{Type: u64}}},
+			expectedLift: &wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}},
+			expected:     &wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}},
+		},
+		{
+			name:         "string result",
+			input:        &Type{Kind: TypeKindFunc, Fields: []Field{{Name: "a", Type: str}}, Results: []Field{{Type: str}}},
+			expectedLift: &wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+			expected:     &wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32}},
+		},
+		{
+			name: "too many params",
+			input: &Type{Kind: TypeKindFunc, Fields: []Field{
+				{Name: "a", Type: &Type{Kind: TypeKindList, Elem: u64, Length: MaxFlatParams + 1}},
+			}},
+			expectedLift: &wasm.FunctionType{Params: []wasm.ValueType{i32}},
+			expected:     &wasm.FunctionType{Params: []wasm.ValueType{i32}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			lift := tc.input.CoreType(false)
+			require.True(t, tc.expectedLift.EqualsSignature(lift.Params, lift.Results), lift.String())
+			lower := tc.input.CoreType(true)
+			require.True(t, tc.expected.EqualsSignature(lower.Params, lower.Results), lower.String())
+		})
+	}
+}
+
+func TestType_String(t *testing.T) {
+	u32, str := PrimitiveType(TypeKindU32), PrimitiveType(TypeKindString)
+
+	tests := []struct {
+		input    *Type
+		expected string
+	}{
+		{input: u32, expected: "u32"},
+		{input: &Type{Kind: TypeKindList, Elem: str}, expected: "list<string>"},
+		{input: &Type{Kind: TypeKindOption, Elem: u32}, expected: "option<u32>"},
+		{input: &Type{Kind: TypeKindResult, Err: str}, expected: "result<_, string>"},
+		{input: &Type{Kind: TypeKindTuple, Fields: []Field{{Type: u32}, {Type: str}}}, expected: "tuple<u32, string>"},
+		{input: &Type{Kind: TypeKindEnum, Labels: []string{"a", "b"}}, expected: "enum {a, b}"},
+		{
+			input:    &Type{Kind: TypeKindFunc, Fields: []Field{{Name: "a", Type: u32}}, Results: []Field{{Type: str}}},
+			expected: "func(a: u32) -> string",
+		},
+		{
+			input:    &Type{Kind: TypeKindInstance, Exports: []ExternDecl{{Name: "f", Sort: SortFunc}}},
+			expected: `instance {export "f"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.expected, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.input.String())
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package component
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/experimental/wit"
+)
+
+// witTypes maps the types of WIT to the types of the component model, which keeps the resource types per definition.
+type witTypes struct {
+	resources map[*wit.TypeDef]*Type
+}
+
+// typeOf returns the type of the component model of the WIT type, which is nil if t is.
+func (w *witTypes) typeOf(t *wit.Type) *Type {
+	if t == nil {
+		return nil
+	} else if t.IsPrimitive() {
+		// The primitive kinds are in the same order.
+		return PrimitiveType(TypeKind(t.Kind))
+	}
+	switch t.Kind {
+	case wit.TypeKindRef:
+		return w.typeOf(t.Def.Type)
+	case wit.TypeKindResource:
+		return &Type{Kind: TypeKindResource}
+	case wit.TypeKindOwn, wit.TypeKindBorrow:
+		ret := &Type{Kind: TypeKindOwn}
+		if t.Kind == wit.TypeKindBorrow {
+			ret.Kind = TypeKindBorrow
+		}
+		if ret.Elem = w.resources[t.Def]; ret.Elem == nil {
+			ret.Elem = &Type{Kind: TypeKindResource}
+			w.resources[t.Def] = ret.Elem
+		}
+		return ret
+	case wit.TypeKindList:
+		return &Type{Kind: TypeKindList, Elem: w.typeOf(t.Elem), Length: t.Length}
+	case wit.TypeKindOption:
+		return &Type{Kind: TypeKindOption, Elem: w.typeOf(t.Elem)}
+	case wit.TypeKindResult:
+		return &Type{Kind: TypeKindResult, Elem: w.typeOf(t.Elem), Err: w.typeOf(t.Err)}
+	case wit.TypeKindEnum:
+		return &Type{Kind: TypeKindEnum, Labels: t.Labels}
+	case wit.TypeKindFlags:
+		return &Type{Kind: TypeKindFlags, Labels: t.Labels}
+	}
+	ret := &Type{Kind: TypeKindRecord}
+	switch t.Kind {
+	case wit.TypeKindVariant:
+		ret.Kind = TypeKindVariant
+	case wit.TypeKindTuple:
+		ret.Kind = TypeKindTuple
+	}
+	for _, f := range t.Fields {
+		ret.Fields = append(ret.Fields, Field{Name: f.Name, Type: w.typeOf(f.Type)})
+	}
+	return ret
+}
+
+// funcTypeOf returns the function type of the component model of the WIT function.
+func (w *witTypes) funcTypeOf(f *wit.Func) *Type {
+	ret := &Type{Kind: TypeKindFunc}
+	for _, p := range f.Params {
+		ret.Fields = append(ret.Fields, Field{Name: p.Name, Type: w.typeOf(p.Type)})
+	}
+	if f.Result != nil {
+		ret.Results = []Field{{Type: w.typeOf(f.Result)}}
+	}
+	return ret
+}
+
+// checkWorld returns an error unless the imports of the component are imported by the world, whose functions have
+// the same types, regardless of the names of their params and fields.
+func checkWorld(c *Component, world *wit.World) error {
+	w := &witTypes{resources: map[*wit.TypeDef]*Type{}}
+	for _, imp := range c.Imports {
+		item := world.Import(imp.Name)
+		switch {
+		case imp.Sort == SortType:
+			continue
+		case item == nil:
+			return fmt.Errorf("import %q is not imported by world %s", imp.Name, world.Name)
+		case imp.Sort == SortFunc:
+			if item.Func == nil {
+				return fmt.Errorf("import %q is an interface of world %s", imp.Name, world.Name)
+			} else if err := checkShape(w.funcTypeOf(item.Func), imp.Type); err != nil {
+				return fmt.Errorf("import %q: %w: %s != %s", imp.Name, err, imp.Type, w.funcTypeOf(item.Func))
+			}
+		case imp.Sort == SortInstance:
+			if item.Interface == nil {
+				return fmt.Errorf("import %q is a function of world %s", imp.Name, world.Name)
+			}
+			for _, decl := range imp.Type.Exports {
+				if decl.Sort != SortFunc {
+					continue
+				}
+				f := item.Interface.Func(decl.Name)
+				if f == nil {
+					return fmt.Errorf("import %q: function %q is not in interface %s", imp.Name, decl.Name,
+						item.Interface.QualifiedName())
+				} else if err := checkShape(w.funcTypeOf(f), decl.Type); err != nil {
+					return fmt.Errorf("import %q: function %q: %w: %s != %s", imp.Name, decl.Name, err, decl.Type,
+						w.funcTypeOf(f))
+				}
+			}
+		default:
+			return fmt.Errorf("import %q: %s imports are not in worlds", imp.Name, imp.Sort)
+		}
+	}
+	return nil
+}
@@ -34,6 +34,8 @@ type (
 		originalStackLenWithoutParamUint64 int
 		blockType                          *wasm.FunctionType
 		kind                               controlFrameKind
+		// catches holds the exception handlers of the try_table instruction which began this frame, if any.
+		catches []exceptionHandler
 	}
 	controlFrames struct{ frames []controlFrame }
 )
@@ -187,6 +189,16 @@ type compiler struct {
 	funcs []uint32
 	// globals holds the global types for all declared globals in the module where the target function exists.
 	globals []wasm.GlobalType
+	// memories holds all the memories in the module where the target function exists.
+	memories []*wasm.Memory
+	// hasMemory64 is true if any of memories is addressed with i64 as per experimental.CoreFeaturesMemory64.
+	// In that case, the integer operands of memory instructions are not checked against their signatures which
+	// assume i32 addresses, as the function has already been validated with the actual address types.
+	hasMemory64 bool
+	// tags holds the type indexes for all declared tags in the module where the target function exists.
+	tags []wasm.Index
+	// catches is reused for each try_table instruction to decode its catch clauses.
+	catches []wasm.TryTableCatch
 
 	// needSourceOffset is true if this module requires DWARF based stack trace.
 	needSourceOffset bool
@@ -271,6 +283,25 @@ type compilationResult struct {
 	HasDataInstances bool
 	// HasDataInstances is true if the module has element instances which might be used by table.init or elem.drop instructions.
 	HasElementInstances bool
+
+	// ExceptionHandlers holds the catch clauses of the try_table instructions in this function. The handlers of inner
+	// try_table instructions come before the outer ones, so the first matching handler is the one to use.
+	ExceptionHandlers []exceptionHandler
+}
+
+// exceptionHandler is a catch clause of a try_table instruction.
+type exceptionHandler struct {
+	// begin and end are the range of the operations covered by this handler. end is exclusive.
+	begin, end uint64
+	// kind is the kind of the catch clause.
+	kind wasm.CatchKind
+	// tag is the index of the tag to catch, and only valid with wasm.CatchKindCatch or wasm.CatchKindCatchRef.
+	tag wasm.Index
+	// target is the label to branch to, which is resolved to the address of the operation when lowered.
+	target uint64
+	// stackHeightInUint64 is the height of the stack, counted from the first param of the function, to which
+	// the stack is unwound before the values of the catch clause are pushed.
+	stackHeightInUint64 int
 }
 
 // newCompiler returns the new *compiler for the given parameters.
@@ -312,17 +343,23 @@ func newCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 			LabelCallers:        map[label]uint32{},
 		},
 		globals:           globals,
+		memories:          module.AllMemories(),
 		funcs:             functions,
+		tags:              module.AllTagTypes(),
 		types:             types,
 		ensureTermination: ensureTermination,
 		br:                bytes.NewReader(nil),
 		funcTypeToSigs: funcTypeToIRSignatures{
 			indirectCalls: make([]*signature, len(types)),
 			directCalls:   make([]*signature, len(types)),
+			refCalls:      make([]*signature, len(types)),
 			wasmTypes:     types,
 		},
 		needSourceOffset: module.DWARFLines != nil,
 	}
+	for _, mem := range c.memories {
+		c.hasMemory64 = c.hasMemory64 || mem.IsMemory64
+	}
 	return c, nil
 }
 
@@ -336,6 +373,7 @@ func (c *compiler) Next() (*compilationResult, error) {
 	c.result.Operations = c.result.Operations[:0]
 	c.result.IROperationSourceOffsetsInWasmBinary = c.result.IROperationSourceOffsetsInWasmBinary[:0]
 	c.result.UsesMemory = false
+	c.result.ExceptionHandlers = c.result.ExceptionHandlers[:0]
 	// Clears the existing entries in LabelCallers.
 	for frameID := uint32(0); frameID <= c.currentFrameID; frameID++ {
 		for k := labelKind(0); k < labelKindNum; k++ {
@@ -409,6 +447,8 @@ func (c *compiler) handleInstruction() error {
 			instName = wasm.AtomicInstructionName(c.body[c.pc+1])
 		} else if op == wasm.OpcodeMiscPrefix {
 			instName = wasm.MiscInstructionName(c.body[c.pc+1])
+		} else if op == wasm.OpcodeGCPrefix {
+			instName = wasm.GCInstructionName(c.body[c.pc+1])
 		} else {
 			instName = wasm.InstructionName(op)
 		}
@@ -440,7 +480,7 @@ operatorSwitch:
 		// Nop is noop!
 	case wasm.OpcodeBlock:
 		c.br.Reset(c.body[c.pc+1:])
-		bt, num, err := wasm.DecodeBlockType(c.types, c.br, c.enabledFeatures)
+		bt, num, err := wasm.DecodeBlockType(c.module, c.br, c.enabledFeatures)
 		if err != nil {
 			return fmt.Errorf("reading block type for block instruction: %w", err)
 		}
@@ -463,9 +503,68 @@ operatorSwitch:
 		}
 		c.controlFrames.push(frame)
 
+	case wasm.OpcodeExceptionHandlingTryTable:
+		c.br.Reset(c.body[c.pc+1:])
+		bt, num, err := wasm.DecodeBlockType(c.module, c.br, c.enabledFeatures)
+		if err != nil {
+			return fmt.Errorf("reading block type for try_table instruction: %w", err)
+		}
+		c.pc += num
+		c.catches, num, err = wasm.DecodeTryTableCatches(c.br, c.catches[:0])
+		if err != nil {
+			return fmt.Errorf("reading catch clauses for try_table instruction: %w", err)
+		}
+		c.pc += num
+
+		if c.unreachableState.on {
+			// If it is currently in unreachable,
+			// just remove the entire block.
+			c.unreachableState.depth++
+			break operatorSwitch
+		}
+
+		// The catch clauses branch to the frames outside this try_table,
+		// so resolve them before entering it.
+		catches := make([]exceptionHandler, len(c.catches))
+		for i := range c.catches {
+			catch := &c.catches[i]
+			targetFrame := c.controlFrames.get(int(catch.Label))
+			targetFrame.ensureContinuation()
+			target := targetFrame.asLabel()
+			c.result.LabelCallers[target]++
+			catches[i] = exceptionHandler{
+				begin:               uint64(len(c.result.Operations)),
+				kind:                catch.Kind,
+				tag:                 catch.Tag,
+				target:              uint64(target),
+				stackHeightInUint64: targetFrame.originalStackLenWithoutParamUint64,
+			}
+		}
+
+		// Create a new frame -- entering this try_table, which is otherwise the same as a block.
+		frame := controlFrame{
+			frameID:                            c.nextFrameID(),
+			originalStackLenWithoutParam:       len(c.stack) - len(bt.Params),
+			originalStackLenWithoutParamUint64: c.stackLenInUint64 - bt.ParamNumInUint64,
+			kind:                               controlFrameKindBlockWithoutContinuationLabel,
+			blockType:                          bt,
+			catches:                            catches,
+		}
+		c.controlFrames.push(frame)
+
+	case wasm.OpcodeExceptionHandlingThrow:
+		c.emit(newOperationThrow(index))
+		// Throw operation is stack-polymorphic, and mark the state as unreachable.
+		c.markUnreachable()
+
+	case wasm.OpcodeExceptionHandlingThrowRef:
+		c.emit(newOperationThrowRef())
+		// Throw operation is stack-polymorphic, and mark the state as unreachable.
+		c.markUnreachable()
+
 	case wasm.OpcodeLoop:
 		c.br.Reset(c.body[c.pc+1:])
-		bt, num, err := wasm.DecodeBlockType(c.types, c.br, c.enabledFeatures)
+		bt, num, err := wasm.DecodeBlockType(c.module, c.br, c.enabledFeatures)
 		if err != nil {
 			return fmt.Errorf("reading block type for loop instruction: %w", err)
 		}
@@ -508,7 +607,7 @@ operatorSwitch:
 		}
 	case wasm.OpcodeIf:
 		c.br.Reset(c.body[c.pc+1:])
-		bt, num, err := wasm.DecodeBlockType(c.types, c.br, c.enabledFeatures)
+		bt, num, err := wasm.DecodeBlockType(c.module, c.br, c.enabledFeatures)
 		if err != nil {
 			return fmt.Errorf("reading block type for if instruction: %w", err)
 		}
@@ -606,6 +705,7 @@ operatorSwitch:
 			c.resetUnreachable()
 
 			frame := c.controlFrames.pop()
+			c.addExceptionHandlers(frame)
 			if c.controlFrames.empty() {
 				return nil
 			}
@@ -633,6 +733,7 @@ operatorSwitch:
 		}
 
 		frame := c.controlFrames.pop()
+		c.addExceptionHandlers(frame)
 
 		// We need to reset the stack so that
 		// the values pushed inside the block.
@@ -716,18 +817,7 @@ operatorSwitch:
 			// If it is currently in unreachable, br-if is no-op.
 			break operatorSwitch
 		}
-
-		targetFrame := c.controlFrames.get(int(targetIndex))
-		targetFrame.ensureContinuation()
-		drop := c.getFrameDropRange(targetFrame, false)
-		target := targetFrame.asLabel()
-		c.result.LabelCallers[target]++
-
-		continuationLabel := newLabel(labelKindHeader, c.nextFrameID())
-		c.result.LabelCallers[continuationLabel]++
-		c.emit(newOperationBrIf(target, continuationLabel, drop))
-		// Start emitting else block operations.
-		c.emit(newOperationLabel(continuationLabel))
+		c.emitBrIf(targetIndex)
 	case wasm.OpcodeBrTable:
 		c.br.Reset(c.body[c.pc+1:])
 		r := c.br
@@ -833,8 +923,14 @@ operatorSwitch:
 			newOperationSelect(isTargetVector),
 		)
 	case wasm.OpcodeTypedSelect:
-		// Skips two bytes: vector size fixed to 1, and the value type for select.
-		c.pc += 2
+		// Skips the vector size fixed to 1, and the value type for select which can be longer than one byte
+		// as per experimental.CoreFeaturesGC.
+		c.br.Reset(c.body[c.pc+2:])
+		_, _, num, err := wasm.DecodeValueType(c.br, c.enabledFeatures)
+		if err != nil {
+			return fmt.Errorf("reading value type for typed select: %w", err)
+		}
+		c.pc += 1 + num
 		// If it is on the unreachable state, ignore the instruction.
 		if c.unreachableState.on {
 			break operatorSwitch
@@ -1053,15 +1149,21 @@ operatorSwitch:
 		)
 	case wasm.OpcodeMemorySize:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemorySizeName)
+		if err != nil {
+			return err
+		}
 		c.emit(
-			newOperationMemorySize(),
+			newOperationMemorySize(memoryIndex),
 		)
 	case wasm.OpcodeMemoryGrow:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryGrowName)
+		if err != nil {
+			return err
+		}
 		c.emit(
-			newOperationMemoryGrow(),
+			newOperationMemoryGrow(memoryIndex),
 		)
 	case wasm.OpcodeI32Const:
 		val, num, err := leb128.LoadInt32(c.body[c.pc+1:])
@@ -1616,7 +1718,13 @@ operatorSwitch:
 			newOperationRefFunc(index),
 		)
 	case wasm.OpcodeRefNull:
-		c.pc++ // Skip the type of reftype as every ref value is opaque pointer.
+		// Skip the heap type as every ref value is opaque pointer.
+		c.br.Reset(c.body[c.pc+1:])
+		_, num, err := wasm.DecodeHeapType(c.br, c.enabledFeatures)
+		if err != nil {
+			return fmt.Errorf("reading heap type for ref.null: %w", err)
+		}
+		c.pc += num
 		c.emit(
 			newOperationConstI64(0),
 		)
@@ -1625,6 +1733,20 @@ operatorSwitch:
 		c.emit(
 			newOperationEqz(unsignedInt64),
 		)
+	case wasm.OpcodeRefEq:
+		// The same references are encoded as the same uint64.
+		c.emit(
+			newOperationEq(unsignedTypeI64),
+		)
+	case wasm.OpcodeGCPrefix:
+		if err := c.handleGCInstruction(); err != nil {
+			return err
+		}
+	case wasm.OpcodeStackSwitchingContNew, wasm.OpcodeStackSwitchingContBind, wasm.OpcodeStackSwitchingSuspend,
+		wasm.OpcodeStackSwitchingResume:
+		if err := c.handleStackSwitchingInstruction(op); err != nil {
+			return err
+		}
 	case wasm.OpcodeTableGet:
 		c.pc++
 		tableIndex, num, err := leb128.LoadUint32(c.body[c.pc:])
@@ -1692,9 +1814,13 @@ operatorSwitch:
 			if err != nil {
 				return fmt.Errorf("reading i32.const value: %v", err)
 			}
-			c.pc += num + 1 // +1 to skip the memory index which is fixed to zero.
+			c.pc += num
+			memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryInitName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				newOperationMemoryInit(dataIndex),
+				newOperationMemoryInit(dataIndex, memoryIndex),
 			)
 		case wasm.OpcodeMiscDataDrop:
 			dataIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
@@ -1707,15 +1833,25 @@ operatorSwitch:
 			)
 		case wasm.OpcodeMiscMemoryCopy:
 			c.result.UsesMemory = true
-			c.pc += 2 // +2 to skip two memory indexes which are fixed to zero.
+			dstMemoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryCopyName)
+			if err != nil {
+				return err
+			}
+			srcMemoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryCopyName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				newOperationMemoryCopy(),
+				newOperationMemoryCopy(dstMemoryIndex, srcMemoryIndex),
 			)
 		case wasm.OpcodeMiscMemoryFill:
 			c.result.UsesMemory = true
-			c.pc += 1 // +1 to skip the memory index which is fixed to zero.
+			memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryFillName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				newOperationMemoryFill(),
+				newOperationMemoryFill(memoryIndex),
 			)
 		case wasm.OpcodeMiscTableInit:
 			elemIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
@@ -1787,11 +1923,27 @@ operatorSwitch:
 			c.emit(
 				newOperationTableFill(tableIndex),
 			)
+		case wasm.OpcodeMiscI64Add128:
+			c.emit(newOperationI64Add128(false))
+		case wasm.OpcodeMiscI64Sub128:
+			c.emit(newOperationI64Add128(true))
+		case wasm.OpcodeMiscI64MulWideS:
+			c.emit(newOperationI64MulWide(true))
+		case wasm.OpcodeMiscI64MulWideU:
+			c.emit(newOperationI64MulWide(false))
 		default:
 			return fmt.Errorf("unsupported misc instruction in interpreterir: 0x%x", op)
 		}
 	case wasm.OpcodeVecPrefix:
 		c.pc++
+		if wasm.IsVecRelaxed(c.body, c.pc) {
+			vecOp := c.body[c.pc]
+			c.pc++ // Skip wasm.OpcodeVecRelaxedSecondByte.
+			if err := c.emitVecRelaxed(vecOp); err != nil {
+				return err
+			}
+			break
+		}
 		switch vecOp := c.body[c.pc]; vecOp {
 		case wasm.OpcodeVecV128Const:
 			c.pc++
@@ -3463,6 +3615,68 @@ operatorSwitch:
 		// and can be safely removed.
 		c.markUnreachable()
 
+	case wasm.OpcodeFunctionReferencesCallRef:
+		c.emit(newOperationCallRef(index))
+
+	case wasm.OpcodeFunctionReferencesReturnCallRef:
+		if c.unreachableState.on {
+			break operatorSwitch
+		}
+		functionFrame := c.controlFrames.functionFrame()
+		dropRange := c.getFrameDropRange(functionFrame, false)
+		c.emit(newOperationTailCallReturnCallRef(index, dropRange, functionFrame.asLabel()))
+
+		// Return operation is stack-polymorphic, and mark the state as unreachable.
+		// That means subsequent instructions in the current control frame are "unreachable"
+		// and can be safely removed.
+		c.markUnreachable()
+
+	case wasm.OpcodeFunctionReferencesRefAsNonNull:
+		c.emit(newOperationRefAsNonNull())
+
+	case wasm.OpcodeFunctionReferencesBrOnNull:
+		if c.unreachableState.on {
+			break operatorSwitch
+		}
+		// The label doesn't receive the reference, so branch to an intermediate label which drops it first.
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationPick(0, false))
+		c.emit(newOperationEqz(unsignedInt64))
+		nullLabel := newLabel(labelKindHeader, c.nextFrameID())
+		continuationLabel := newLabel(labelKindHeader, c.nextFrameID())
+		c.result.LabelCallers[nullLabel]++
+		c.result.LabelCallers[continuationLabel]++
+		c.stackPop()
+		c.emit(newOperationBrIf(nullLabel, continuationLabel, nopinclusiveRange))
+
+		c.emit(newOperationLabel(nullLabel))
+		c.stackPop()
+		c.emit(newOperationDrop(inclusiveRange{Start: 0, End: 0}))
+		targetFrame := c.controlFrames.get(int(index))
+		targetFrame.ensureContinuation()
+		dropOp := newOperationDrop(c.getFrameDropRange(targetFrame, false))
+		targetID := targetFrame.asLabel()
+		c.result.LabelCallers[targetID]++
+		c.emit(dropOp)
+		c.emit(newOperationBr(targetID))
+
+		c.emit(newOperationLabel(continuationLabel))
+		c.stackPush(unsignedTypeI64)
+
+	case wasm.OpcodeFunctionReferencesBrOnNonNull:
+		if c.unreachableState.on {
+			break operatorSwitch
+		}
+		// The label receives the reference, so branch with it on top of the stack, and drop it otherwise.
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationPick(0, false))
+		c.emit(newOperationEqz(unsignedInt64))
+		c.emit(newOperationEqz(unsignedInt32))
+		c.stackPop()
+		c.emitBrIf(index)
+		c.stackPop()
+		c.emit(newOperationDrop(inclusiveRange{Start: 0, End: 0}))
+
 	default:
 		return fmt.Errorf("unsupported instruction in interpreterir: 0x%x", op)
 	}
@@ -3472,6 +3686,327 @@ operatorSwitch:
 	return nil
 }
 
+// emitVecRelaxed emits the operations for the relaxed vector instruction vecOp. The results are the same as the
+// deterministic ones in the relaxed SIMD proposal, so that they don't depend on the host.
+func (c *compiler) emitVecRelaxed(vecOp wasm.OpcodeVecRelaxed) error {
+	switch vecOp {
+	case wasm.OpcodeVecI8x16RelaxedSwizzle:
+		c.emit(newOperationV128Swizzle())
+	case wasm.OpcodeVecI32x4RelaxedTruncF32x4S:
+		c.emit(newOperationV128ITruncSatFromF(shapeF32x4, true))
+	case wasm.OpcodeVecI32x4RelaxedTruncF32x4U:
+		c.emit(newOperationV128ITruncSatFromF(shapeF32x4, false))
+	case wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero:
+		c.emit(newOperationV128ITruncSatFromF(shapeF64x2, true))
+	case wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+		c.emit(newOperationV128ITruncSatFromF(shapeF64x2, false))
+	case wasm.OpcodeVecF32x4RelaxedMadd:
+		c.emit(newOperationV128RelaxedMadd(shapeF32x4, false))
+	case wasm.OpcodeVecF32x4RelaxedNmadd:
+		c.emit(newOperationV128RelaxedMadd(shapeF32x4, true))
+	case wasm.OpcodeVecF64x2RelaxedMadd:
+		c.emit(newOperationV128RelaxedMadd(shapeF64x2, false))
+	case wasm.OpcodeVecF64x2RelaxedNmadd:
+		c.emit(newOperationV128RelaxedMadd(shapeF64x2, true))
+	case wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+		wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect:
+		c.emit(newOperationV128Bitselect())
+	case wasm.OpcodeVecF32x4RelaxedMin:
+		c.emit(newOperationV128Min(shapeF32x4, false))
+	case wasm.OpcodeVecF32x4RelaxedMax:
+		c.emit(newOperationV128Max(shapeF32x4, false))
+	case wasm.OpcodeVecF64x2RelaxedMin:
+		c.emit(newOperationV128Min(shapeF64x2, false))
+	case wasm.OpcodeVecF64x2RelaxedMax:
+		c.emit(newOperationV128Max(shapeF64x2, false))
+	case wasm.OpcodeVecI16x8RelaxedQ15mulrS:
+		c.emit(newOperationV128Q15mulrSatS())
+	case wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+		c.emit(newOperationV128RelaxedDot(false))
+	case wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+		c.emit(newOperationV128RelaxedDot(true))
+	default:
+		return fmt.Errorf("unsupported relaxed vector instruction in interpreterir: %s", wasm.VectorRelaxedInstructionName(vecOp))
+	}
+	return nil
+}
+
+// addExceptionHandlers adds the exception handlers of the frame, if it was
+// began by a try_table instruction, to the result.
+func (c *compiler) addExceptionHandlers(frame *controlFrame) {
+	if len(frame.catches) == 0 {
+		return
+	}
+	end := uint64(len(c.result.Operations))
+	for i := range frame.catches {
+		frame.catches[i].end = end
+	}
+	c.result.ExceptionHandlers = append(c.result.ExceptionHandlers, frame.catches...)
+}
+
+// handleGCInstruction translates the instruction of experimental.CoreFeaturesGC whose prefix is at c.pc. As the
+// signatures of these instructions depend on their immediates, the stack is manipulated here instead of applyToStack.
+func (c *compiler) handleGCInstruction() error {
+	c.br.Reset(c.body[c.pc+1:])
+	gcOp32, _, err := leb128.DecodeUint32(c.br)
+	if err != nil {
+		return fmt.Errorf("failed to read gc opcode: %v", err)
+	}
+	gcOp := byte(gcOp32)
+	readIndex := func() (uint32, error) {
+		v, _, err := leb128.DecodeUint32(c.br)
+		if err != nil {
+			return 0, fmt.Errorf("read immediate for %s: %v", wasm.GCInstructionName(gcOp), err)
+		}
+		return v, nil
+	}
+	readIndexes := func(indexes ...*uint32) (err error) {
+		for _, index := range indexes {
+			if *index, err = readIndex(); err != nil {
+				return
+			}
+		}
+		return
+	}
+	popN := func(n int) {
+		for i := 0; i < n; i++ {
+			c.stackPop()
+		}
+	}
+
+	var typeIndex, index uint32
+	var ht wasm.HeapType
+	var flags byte
+	switch gcOp {
+	case wasm.OpcodeGCArrayLen, wasm.OpcodeGCAnyConvertExtern, wasm.OpcodeGCExternConvertAny,
+		wasm.OpcodeGCRefI31, wasm.OpcodeGCI31GetS, wasm.OpcodeGCI31GetU:
+	case wasm.OpcodeGCStructGet, wasm.OpcodeGCStructGetS, wasm.OpcodeGCStructGetU, wasm.OpcodeGCStructSet,
+		wasm.OpcodeGCArrayNewFixed, wasm.OpcodeGCArrayNewData, wasm.OpcodeGCArrayNewElem, wasm.OpcodeGCArrayCopy,
+		wasm.OpcodeGCArrayInitData, wasm.OpcodeGCArrayInitElem:
+		err = readIndexes(&typeIndex, &index)
+	case wasm.OpcodeGCRefTest, wasm.OpcodeGCRefTestNull, wasm.OpcodeGCRefCast, wasm.OpcodeGCRefCastNull:
+		ht, _, err = wasm.DecodeHeapType(c.br, c.enabledFeatures)
+	case wasm.OpcodeGCBrOnCast, wasm.OpcodeGCBrOnCastFail:
+		if flags, err = c.br.ReadByte(); err == nil {
+			if index, err = readIndex(); err == nil {
+				// The source heap type is only needed for validation.
+				if _, _, err = wasm.DecodeHeapType(c.br, c.enabledFeatures); err == nil {
+					ht, _, err = wasm.DecodeHeapType(c.br, c.enabledFeatures)
+				}
+			}
+		}
+	default:
+		err = readIndexes(&typeIndex)
+	}
+	if err != nil {
+		return fmt.Errorf("reading immediates for %s: %w", wasm.GCInstructionName(gcOp), err)
+	}
+	c.pc += uint64(len(c.body[c.pc+1:]) - c.br.Len())
+
+	if c.unreachableState.on {
+		return nil
+	}
+
+	switch gcOp {
+	case wasm.OpcodeGCStructNew, wasm.OpcodeGCStructNewDefault:
+		isDefault := gcOp == wasm.OpcodeGCStructNewDefault
+		if !isDefault {
+			popN(len(c.module.SubTypes[typeIndex].Fields))
+		}
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationStructNew(typeIndex, isDefault))
+	case wasm.OpcodeGCStructGet, wasm.OpcodeGCStructGetS, wasm.OpcodeGCStructGetU:
+		popN(1)
+		field := &c.module.SubTypes[typeIndex].Fields[index]
+		c.stackPush(wasmValueTypeTounsignedType(field.ValueType()))
+		c.emit(newOperationStructGet(typeIndex, index, gcOp == wasm.OpcodeGCStructGetS))
+	case wasm.OpcodeGCStructSet:
+		popN(2)
+		c.emit(newOperationStructSet(typeIndex, index))
+	case wasm.OpcodeGCArrayNew:
+		popN(2)
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationArrayNew(typeIndex, arrayNewKindValue, 0))
+	case wasm.OpcodeGCArrayNewDefault:
+		popN(1)
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationArrayNew(typeIndex, arrayNewKindDefault, 0))
+	case wasm.OpcodeGCArrayNewFixed:
+		popN(int(index))
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationArrayNew(typeIndex, arrayNewKindFixed, index))
+	case wasm.OpcodeGCArrayNewData, wasm.OpcodeGCArrayNewElem:
+		popN(2)
+		c.stackPush(unsignedTypeI64)
+		kind := arrayNewKindData
+		if gcOp == wasm.OpcodeGCArrayNewElem {
+			kind = arrayNewKindElem
+		}
+		c.emit(newOperationArrayNew(typeIndex, kind, index))
+	case wasm.OpcodeGCArrayGet, wasm.OpcodeGCArrayGetS, wasm.OpcodeGCArrayGetU:
+		popN(2)
+		field := &c.module.SubTypes[typeIndex].Fields[0]
+		c.stackPush(wasmValueTypeTounsignedType(field.ValueType()))
+		c.emit(newOperationArrayGet(typeIndex, gcOp == wasm.OpcodeGCArrayGetS))
+	case wasm.OpcodeGCArraySet:
+		popN(3)
+		c.emit(newOperationArraySet(typeIndex))
+	case wasm.OpcodeGCArrayLen:
+		popN(1)
+		c.stackPush(unsignedTypeI32)
+		c.emit(newOperationArrayLen())
+	case wasm.OpcodeGCArrayFill:
+		popN(4)
+		c.emit(newOperationArrayFill(typeIndex))
+	case wasm.OpcodeGCArrayCopy:
+		popN(5)
+		c.emit(newOperationArrayCopy(typeIndex, index))
+	case wasm.OpcodeGCArrayInitData, wasm.OpcodeGCArrayInitElem:
+		popN(4)
+		c.emit(newOperationArrayInit(typeIndex, index, gcOp == wasm.OpcodeGCArrayInitElem))
+	case wasm.OpcodeGCRefTest, wasm.OpcodeGCRefTestNull:
+		popN(1)
+		c.stackPush(unsignedTypeI32)
+		c.emit(newOperationRefTest(ht, gcOp == wasm.OpcodeGCRefTestNull))
+	case wasm.OpcodeGCRefCast, wasm.OpcodeGCRefCastNull:
+		c.emit(newOperationRefCast(ht, gcOp == wasm.OpcodeGCRefCastNull))
+	case wasm.OpcodeGCBrOnCast, wasm.OpcodeGCBrOnCastFail:
+		// br_on_cast is translated as br_if on the result of ref.test against the duplicated operand,
+		// so the stack is the same before and after the branch.
+		c.emit(newOperationPick(0, false))
+		c.emit(newOperationRefTest(ht, flags&0b10 != 0))
+		if gcOp == wasm.OpcodeGCBrOnCastFail {
+			c.emit(newOperationEqz(unsignedInt32))
+		}
+		c.emitBrIf(index)
+	case wasm.OpcodeGCRefI31:
+		popN(1)
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationRefI31())
+	case wasm.OpcodeGCI31GetS, wasm.OpcodeGCI31GetU:
+		popN(1)
+		c.stackPush(unsignedTypeI32)
+		c.emit(newOperationI31Get(gcOp == wasm.OpcodeGCI31GetS))
+	case wasm.OpcodeGCAnyConvertExtern:
+		c.emit(newOperationAnyConvertExtern())
+	case wasm.OpcodeGCExternConvertAny:
+		c.emit(newOperationExternConvertAny())
+	default:
+		return fmt.Errorf("unsupported gc instruction in interpreterir: 0x%x", gcOp)
+	}
+	return nil
+}
+
+// handleStackSwitchingInstruction handles the stack switching instruction op, whose immediates follow c.pc.
+func (c *compiler) handleStackSwitchingInstruction(op wasm.OpcodeStackSwitching) error {
+	c.br.Reset(c.body[c.pc+1:])
+	readIndex := func() (uint32, error) {
+		v, _, err := leb128.DecodeUint32(c.br)
+		if err != nil {
+			return 0, fmt.Errorf("read immediate for %s: %v", wasm.StackSwitchingInstructionName(op), err)
+		}
+		return v, nil
+	}
+	popN := func(n int) {
+		for i := 0; i < n; i++ {
+			c.stackPop()
+		}
+	}
+	pushTypes := func(types []wasm.ValueType) {
+		for _, t := range types {
+			c.stackPush(wasmValueTypeTounsignedType(t))
+		}
+	}
+
+	var typeIndex, index uint32
+	var handlers []uint32
+	var err error
+	switch op {
+	case wasm.OpcodeStackSwitchingContBind:
+		if typeIndex, err = readIndex(); err == nil {
+			index, err = readIndex()
+		}
+	case wasm.OpcodeStackSwitchingResume:
+		if typeIndex, err = readIndex(); err != nil {
+			break
+		}
+		var count uint32
+		if count, err = readIndex(); err != nil {
+			break
+		}
+		// Each handler is (on tag label), as the others are rejected by the validation.
+		handlers = make([]uint32, 2*count)
+		for i := range handlers {
+			if i%2 == 0 {
+				if _, err = c.br.ReadByte(); err != nil {
+					break
+				}
+			}
+			if handlers[i], err = readIndex(); err != nil {
+				break
+			}
+		}
+	default:
+		typeIndex, err = readIndex()
+	}
+	if err != nil {
+		return fmt.Errorf("reading immediates for %s: %w", wasm.StackSwitchingInstructionName(op), err)
+	}
+	c.pc += uint64(len(c.body[c.pc+1:]) - c.br.Len())
+
+	if c.unreachableState.on {
+		return nil
+	}
+
+	switch op {
+	case wasm.OpcodeStackSwitchingContNew:
+		popN(1)
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationContNew(c.module.SubTypes[typeIndex].FuncType))
+	case wasm.OpcodeStackSwitchingContBind:
+		from, to := c.module.ContinuationType(typeIndex), c.module.ContinuationType(index)
+		popN(1 + len(from.Params) - len(to.Params))
+		c.stackPush(unsignedTypeI64)
+		c.emit(newOperationContBind(from.ParamNumInUint64 - to.ParamNumInUint64))
+	case wasm.OpcodeStackSwitchingSuspend:
+		tag := &c.module.TypeSection[c.tags[typeIndex]]
+		popN(len(tag.Params))
+		pushTypes(tag.Results)
+		c.emit(newOperationSuspend(typeIndex))
+	case wasm.OpcodeStackSwitchingResume:
+		// The handlers branch to the frames outside, so resolve them the same way as the catch clauses of try_table.
+		us := make([]uint64, 0, len(handlers)/2*3)
+		for i := 0; i < len(handlers); i += 2 {
+			targetFrame := c.controlFrames.get(int(handlers[i+1]))
+			targetFrame.ensureContinuation()
+			target := targetFrame.asLabel()
+			c.result.LabelCallers[target]++
+			us = append(us, uint64(handlers[i]), uint64(target), uint64(targetFrame.originalStackLenWithoutParamUint64))
+		}
+		ft := c.module.ContinuationType(typeIndex)
+		popN(1 + len(ft.Params))
+		pushTypes(ft.Results)
+		c.emit(newOperationResume(ft.ParamNumInUint64, us))
+	}
+	return nil
+}
+
+// emitBrIf emits the operations to branch to the target frame if the i32 condition, which has already been popped from
+// c.stack, is non-zero.
+func (c *compiler) emitBrIf(targetIndex uint32) {
+	targetFrame := c.controlFrames.get(int(targetIndex))
+	targetFrame.ensureContinuation()
+	drop := c.getFrameDropRange(targetFrame, false)
+	target := targetFrame.asLabel()
+	c.result.LabelCallers[target]++
+
+	continuationLabel := newLabel(labelKindHeader, c.nextFrameID())
+	c.result.LabelCallers[continuationLabel]++
+	c.emit(newOperationBrIf(target, continuationLabel, drop))
+	// Start emitting else block operations.
+	c.emit(newOperationLabel(continuationLabel))
+}
+
 func (c *compiler) nextFrameID() (id uint32) {
 	id = c.currentFrameID + 1
 	c.currentFrameID++
@@ -3492,7 +4027,14 @@ func (c *compiler) applyToStack(opcode wasm.Opcode) (index uint32, err error) {
 		wasm.OpcodeGlobalSet,
 		// tail-call proposal
 		wasm.OpcodeTailCallReturnCall,
-		wasm.OpcodeTailCallReturnCallIndirect:
+		wasm.OpcodeTailCallReturnCallIndirect,
+		// function-references proposal
+		wasm.OpcodeFunctionReferencesCallRef,
+		wasm.OpcodeFunctionReferencesReturnCallRef,
+		wasm.OpcodeFunctionReferencesBrOnNull,
+		wasm.OpcodeFunctionReferencesBrOnNonNull,
+		// exception-handling proposal
+		wasm.OpcodeExceptionHandlingThrow:
 		// Assumes that we are at the opcode now so skip it before read immediates.
 		v, num, err := leb128.LoadUint32(c.body[c.pc+1:])
 		if err != nil {
@@ -3534,7 +4076,7 @@ func (c *compiler) applyToStack(opcode wasm.Opcode) (index uint32, err error) {
 			typeParam = want
 			typeParamFound = true
 		}
-		if want != actual {
+		if want != actual && !(c.hasMemory64 && isIntegerType(want) && isIntegerType(actual)) {
 			return 0, fmt.Errorf("input signature mismatch: want %s but have %s", want, actual)
 		}
 	}
@@ -3552,6 +4094,10 @@ func (c *compiler) applyToStack(opcode wasm.Opcode) (index uint32, err error) {
 	return index, nil
 }
 
+func isIntegerType(t unsignedType) bool {
+	return t == unsignedTypeI32 || t == unsignedTypeI64
+}
+
 func (c *compiler) stackPeek() (ret unsignedType) {
 	ret = c.stack[len(c.stack)-1]
 	return
@@ -3605,7 +4151,8 @@ func (c *compiler) emitDefaultValue(t wasm.ValueType) {
 	case wasm.ValueTypeI32:
 		c.stackPush(unsignedTypeI32)
 		c.emit(newOperationConstI32(0))
-	case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+	case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeAnyref,
+		wasm.ValueTypeContref:
 		c.stackPush(unsignedTypeI64)
 		c.emit(newOperationConstI64(0))
 	case wasm.ValueTypeF32:
@@ -3666,10 +4213,41 @@ func (c *compiler) readMemoryArg(tag string) (memoryArg, error) {
 		return memoryArg{}, fmt.Errorf("reading alignment for %s: %w", tag, err)
 	}
 	c.pc += num
-	offset, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+	var memoryIndex uint32
+	if alignment&wasm.MemArgMemoryIndexFlag != 0 {
+		alignment &^= wasm.MemArgMemoryIndexFlag
+		memoryIndex, num, err = leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return memoryArg{}, fmt.Errorf("reading memory index for %s: %w", tag, err)
+		}
+		c.pc += num
+	}
+	var offset uint64
+	if c.isMemory64(memoryIndex) {
+		offset, num, err = leb128.LoadUint64(c.body[c.pc+1:])
+	} else {
+		var offset32 uint32
+		offset32, num, err = leb128.LoadUint32(c.body[c.pc+1:])
+		offset = uint64(offset32)
+	}
 	if err != nil {
 		return memoryArg{}, fmt.Errorf("reading offset for %s: %w", tag, err)
 	}
 	c.pc += num
-	return memoryArg{Offset: offset, Alignment: alignment}, nil
+	return memoryArg{Offset: offset, Alignment: alignment, MemoryIndex: memoryIndex}, nil
+}
+
+// isMemory64 returns true if the memory at the given index is addressed with i64.
+func (c *compiler) isMemory64(memoryIndex uint32) bool {
+	return int(memoryIndex) < len(c.memories) && c.memories[memoryIndex].IsMemory64
+}
+
+// readMemoryIndex reads the memory index immediate of memory.size, memory.grow and bulk memory instructions.
+func (c *compiler) readMemoryIndex(tag string) (uint32, error) {
+	memoryIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+	if err != nil {
+		return 0, fmt.Errorf("reading memory index for %s: %w", tag, err)
+	}
+	c.pc += num
+	return memoryIndex, nil
 }
@@ -157,7 +157,7 @@ func TestCompile(t *testing.T) {
 			expected: &compilationResult{
 				Operations: []unionOperation{ // begin with params: [$delta]
 					newOperationPick(0, false),                         // [$delta, $delta]
-					newOperationMemoryGrow(),                           // [$delta, $old_size]
+					newOperationMemoryGrow(0),                          // [$delta, $old_size]
 					newOperationDrop(inclusiveRange{Start: 1, End: 1}), // [$old_size]
 					newOperationBr(newLabel(labelKindReturn, 0)),       // return!
 				},
@@ -297,7 +297,7 @@ func TestCompile_BulkMemoryOperations(t *testing.T) {
 			newOperationConstI32(16),                     // [16]
 			newOperationConstI32(0),                      // [16, 0]
 			newOperationConstI32(7),                      // [16, 0, 7]
-			newOperationMemoryInit(1),                    // []
+			newOperationMemoryInit(1, 0),                 // []
 			newOperationDataDrop(1),                      // []
 			newOperationBr(newLabel(labelKindReturn, 0)), // return!
 		},
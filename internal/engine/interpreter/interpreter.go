@@ -150,10 +150,21 @@ type callEngine struct {
 
 	// stackiterator for Listeners to walk frames and stack.
 	stackIterator stackIterator
+
+	// exceptionRefs holds the exceptions referenced by exnref values created during the current call, which is
+	// shared with the call engines of its continuations.
+	exceptionRefs *wasm.ExceptionRefs
+
+	// continuations holds the continuations referenced by contref values created during the current call, which is
+	// allocated on the first cont.new and shared with the call engines of its continuations.
+	continuations *wasm.Continuations
+
+	// continuation is the continuation run by this call engine, or nil if this is the call engine of a call.
+	continuation *wasm.Continuation
 }
 
 func (e *moduleEngine) newCallEngine(compiled *function) *callEngine {
-	return &callEngine{f: compiled}
+	return &callEngine{f: compiled, exceptionRefs: &wasm.ExceptionRefs{}}
 }
 
 func (ce *callEngine) pushValue(v uint64) {
@@ -239,6 +250,8 @@ type compiledFunction struct {
 	hostFn              interface{}
 	ensureTermination   bool
 	index               wasm.Index
+	// exceptionHandlers are the catch clauses of try_table instructions with resolved target addresses.
+	exceptionHandlers []exceptionHandler
 }
 
 type function struct {
@@ -492,8 +505,20 @@ func (e *engine) lowerIR(ir *compilationResult, ret *compiledFunction) error {
 				target := op.Us[j]
 				e.setLabelAddress(&op.Us[j], label(target), labelAddressResolutions)
 			}
-		case operationKindTailCallReturnCallIndirect:
+		case operationKindTailCallReturnCallIndirect, operationKindTailCallReturnCallRef:
 			e.setLabelAddress(&op.Us[1], label(op.Us[1]), labelAddressResolutions)
+		case operationKindResume:
+			for j := 1; j < len(op.Us); j += 3 {
+				e.setLabelAddress(&op.Us[j], label(op.Us[j]), labelAddressResolutions)
+			}
+		}
+	}
+
+	if len(ir.ExceptionHandlers) > 0 {
+		ret.exceptionHandlers = slices.Clone(ir.ExceptionHandlers)
+		for i := range ret.exceptionHandlers {
+			h := &ret.exceptionHandlers[i]
+			e.setLabelAddress(&h.target, label(h.target), labelAddressResolutions)
 		}
 	}
 	return nil
@@ -515,7 +540,7 @@ func (e *moduleEngine) ResolveImportedFunction(index, descFunc, indexInImportedM
 }
 
 // ResolveImportedMemory implements wasm.ModuleEngine.
-func (e *moduleEngine) ResolveImportedMemory(wasm.ModuleEngine) {}
+func (e *moduleEngine) ResolveImportedMemory(_, _ wasm.Index, _ wasm.ModuleEngine) {}
 
 // DoneInstantiation implements wasm.ModuleEngine.
 func (e *moduleEngine) DoneInstantiation() {}
@@ -606,8 +631,18 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		if v := recover(); v != nil {
 			err = ce.recoverOnCall(ctx, m, v)
 		}
+		ce.exceptionRefs.Reset()
+		if ce.continuations != nil {
+			ce.continuations.Reset()
+		}
 	}()
 
+	if h := m.GCHeap(); h != nil {
+		// The objects are only collected when this is the only call in progress in the store.
+		h.EnterCall()
+		defer h.ExitCall()
+	}
+
 	ce.pushValues(params)
 
 	if ce.f.parent.ensureTermination {
@@ -734,6 +769,14 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 	}
 }
 
+// memoryAt returns the memory at the given index of the module, where mem is the one at index zero.
+func memoryAt(mem *wasm.MemoryInstance, m *wasm.ModuleInstance, index uint64) *wasm.MemoryInstance {
+	if index == 0 {
+		return mem
+	}
+	return m.Memories[index]
+}
+
 func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance, f *function) {
 	frame := &callFrame{f: f, base: len(ce.stack)}
 	moduleInst := f.moduleInstance
@@ -741,7 +784,6 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 	memoryInst := moduleInst.MemoryInstance
 	globals := moduleInst.Globals
 	tables := moduleInst.Tables
-	typeIDs := moduleInst.TypeIDs
 	dataInstances := moduleInst.DataInstances
 	elementInstances := moduleInst.ElementInstances
 	ce.pushFrame(frame)
@@ -779,6 +821,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.drop(op.Us[v+1])
 			frame.pc = op.Us[v]
 		case operationKindCall:
+			var caught bool
 			func() {
 				if ctx.Value(expctxkeys.EnableSnapshotterKey{}) != nil {
 					defer func() {
@@ -794,15 +837,27 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 						}
 					}()
 				}
-				ce.callFunction(ctx, f.moduleInstance, &functions[op.U1])
+				if frame.f.parent.exceptionHandlers != nil {
+					caught = ce.callFunctionInTry(ctx, f.moduleInstance, &functions[op.U1], frame)
+				} else {
+					ce.callFunction(ctx, f.moduleInstance, &functions[op.U1])
+				}
 			}()
-			frame.pc++
+			if !caught {
+				frame.pc++
+			}
 		case operationKindCallIndirect:
 			offset := ce.popValue()
 			table := tables[op.U2]
-			tf := ce.functionForOffset(table, offset, typeIDs[op.U1])
+			tf := ce.functionForOffset(moduleInst, table, offset, wasm.Index(op.U1))
 
-			ce.callFunction(ctx, f.moduleInstance, tf)
+			if frame.f.parent.exceptionHandlers != nil {
+				if ce.callFunctionInTry(ctx, f.moduleInstance, tf, frame) {
+					continue
+				}
+			} else {
+				ce.callFunction(ctx, f.moduleInstance, tf)
+			}
 			frame.pc++
 		case operationKindDrop:
 			ce.drop(op.U1)
@@ -857,16 +912,17 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			g.Val = ce.popValue()
 			frame.pc++
 		case operationKindLoad:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			offset := ce.popMemoryOffset(op)
 			switch unsignedType(op.B1) {
 			case unsignedTypeI32, unsignedTypeF32:
-				if val, ok := memoryInst.ReadUint32Le(offset); !ok {
+				if val, ok := memoryInst.ReadUint32Le64(offset); !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				} else {
 					ce.pushValue(uint64(val))
 				}
 			case unsignedTypeI64, unsignedTypeF64:
-				if val, ok := memoryInst.ReadUint64Le(offset); !ok {
+				if val, ok := memoryInst.ReadUint64Le64(offset); !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				} else {
 					ce.pushValue(val)
@@ -874,7 +930,8 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindLoad8:
-			val, ok := memoryInst.ReadByte(ce.popMemoryOffset(op))
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
+			val, ok := memoryInst.ReadByte64(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
@@ -889,8 +946,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindLoad16:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 
-			val, ok := memoryInst.ReadUint16Le(ce.popMemoryOffset(op))
+			val, ok := memoryInst.ReadUint16Le64(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
@@ -905,7 +963,8 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindLoad32:
-			val, ok := memoryInst.ReadUint32Le(ce.popMemoryOffset(op))
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
+			val, ok := memoryInst.ReadUint32Le64(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
@@ -917,49 +976,62 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindStore:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			switch unsignedType(op.B1) {
 			case unsignedTypeI32, unsignedTypeF32:
-				if !memoryInst.WriteUint32Le(offset, uint32(val)) {
+				if !memoryInst.WriteUint32Le64(offset, uint32(val)) {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 			case unsignedTypeI64, unsignedTypeF64:
-				if !memoryInst.WriteUint64Le(offset, val) {
+				if !memoryInst.WriteUint64Le64(offset, val) {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 			}
 			frame.pc++
 		case operationKindStore8:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := byte(ce.popValue())
 			offset := ce.popMemoryOffset(op)
-			if !memoryInst.WriteByte(offset, val) {
+			if !memoryInst.WriteByte64(offset, val) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case operationKindStore16:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := uint16(ce.popValue())
 			offset := ce.popMemoryOffset(op)
-			if !memoryInst.WriteUint16Le(offset, val) {
+			if !memoryInst.WriteUint16Le64(offset, val) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case operationKindStore32:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := uint32(ce.popValue())
 			offset := ce.popMemoryOffset(op)
-			if !memoryInst.WriteUint32Le(offset, val) {
+			if !memoryInst.WriteUint32Le64(offset, val) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case operationKindMemorySize:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			ce.pushValue(uint64(memoryInst.Pages()))
 			frame.pc++
 		case operationKindMemoryGrow:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			n := ce.popValue()
-			if res, ok := memoryInst.Grow(uint32(n)); !ok {
-				ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
+			if !memoryInst.Memory64 {
+				n = uint64(uint32(n))
+			}
+			if res, ok := memoryInst.Grow64(n); !ok {
+				if memoryInst.Memory64 {
+					ce.pushValue(math.MaxUint64) // = -1 in signed 64-bit integer.
+				} else {
+					ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
+				}
 			} else {
-				ce.pushValue(uint64(res))
+				ce.pushValue(res)
 			}
 			frame.pc++
 		case operationKindConstI32, operationKindConstI64,
@@ -1720,12 +1792,13 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(uint64(v))
 			frame.pc++
 		case operationKindMemoryInit:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			dataInstance := dataInstances[op.U1]
 			copySize := ce.popValue()
 			inDataOffset := ce.popValue()
 			inMemoryOffset := ce.popValue()
 			if inDataOffset+copySize > uint64(len(dataInstance)) ||
-				inMemoryOffset+copySize > uint64(len(memoryInst.Buffer)) {
+				memoryOutOfBounds(memoryInst, inMemoryOffset, copySize) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if copySize != 0 {
 				copy(memoryInst.Buffer[inMemoryOffset:inMemoryOffset+copySize], dataInstance[inDataOffset:])
@@ -1735,22 +1808,25 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			dataInstances[op.U1] = nil
 			frame.pc++
 		case operationKindMemoryCopy:
-			memLen := uint64(len(memoryInst.Buffer))
+			dstMemoryInst := memoryAt(memoryInst, moduleInst, op.U3)
+			srcMemoryInst := memoryAt(memoryInst, moduleInst, op.U1)
 			copySize := ce.popValue()
 			sourceOffset := ce.popValue()
 			destinationOffset := ce.popValue()
-			if sourceOffset+copySize > memLen || destinationOffset+copySize > memLen {
+			if memoryOutOfBounds(srcMemoryInst, sourceOffset, copySize) ||
+				memoryOutOfBounds(dstMemoryInst, destinationOffset, copySize) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if copySize != 0 {
-				copy(memoryInst.Buffer[destinationOffset:],
-					memoryInst.Buffer[sourceOffset:sourceOffset+copySize])
+				copy(dstMemoryInst.Buffer[destinationOffset:],
+					srcMemoryInst.Buffer[sourceOffset:sourceOffset+copySize])
 			}
 			frame.pc++
 		case operationKindMemoryFill:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			fillSize := ce.popValue()
 			value := byte(ce.popValue())
 			offset := ce.popValue()
-			if fillSize+offset > uint64(len(memoryInst.Buffer)) {
+			if memoryOutOfBounds(memoryInst, offset, fillSize) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if fillSize != 0 {
 				// Uses the copy trick for faster filling the buffer with the value.
@@ -1939,21 +2015,22 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindV128Load:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			offset := ce.popMemoryOffset(op)
 			switch op.B1 {
 			case v128LoadType128:
-				lo, ok := memoryInst.ReadUint64Le(offset)
+				lo, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(lo)
-				hi, ok := memoryInst.ReadUint64Le(offset + 8)
+				hi, ok := memoryInst.ReadUint64Le64(offset + 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(hi)
 			case v128LoadType8x8s:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1964,7 +2041,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					uint64(uint16(int8(data[7])))<<48 | uint64(uint16(int8(data[6])))<<32 | uint64(uint16(int8(data[5])))<<16 | uint64(uint16(int8(data[4]))),
 				)
 			case v128LoadType8x8u:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1975,7 +2052,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					uint64(data[7])<<48 | uint64(data[6])<<32 | uint64(data[5])<<16 | uint64(data[4]),
 				)
 			case v128LoadType16x4s:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1988,7 +2065,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 						uint64(uint32(int16(binary.LittleEndian.Uint16(data[4:])))),
 				)
 			case v128LoadType16x4u:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1999,21 +2076,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					uint64(binary.LittleEndian.Uint16(data[6:]))<<32 | uint64(binary.LittleEndian.Uint16(data[4:])),
 				)
 			case v128LoadType32x2s:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(uint64(int32(binary.LittleEndian.Uint32(data))))
 				ce.pushValue(uint64(int32(binary.LittleEndian.Uint32(data[4:]))))
 			case v128LoadType32x2u:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(uint64(binary.LittleEndian.Uint32(data)))
 				ce.pushValue(uint64(binary.LittleEndian.Uint32(data[4:])))
 			case v128LoadType8Splat:
-				v, ok := memoryInst.ReadByte(offset)
+				v, ok := memoryInst.ReadByte64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2022,7 +2099,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				ce.pushValue(v8)
 				ce.pushValue(v8)
 			case v128LoadType16Splat:
-				v, ok := memoryInst.ReadUint16Le(offset)
+				v, ok := memoryInst.ReadUint16Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2030,7 +2107,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				ce.pushValue(v4)
 				ce.pushValue(v4)
 			case v128LoadType32Splat:
-				v, ok := memoryInst.ReadUint32Le(offset)
+				v, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2038,21 +2115,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				ce.pushValue(vv)
 				ce.pushValue(vv)
 			case v128LoadType64Splat:
-				lo, ok := memoryInst.ReadUint64Le(offset)
+				lo, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(lo)
 				ce.pushValue(lo)
 			case v128LoadType32zero:
-				lo, ok := memoryInst.ReadUint32Le(offset)
+				lo, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(uint64(lo))
 				ce.pushValue(0)
 			case v128LoadType64zero:
-				lo, ok := memoryInst.ReadUint64Le(offset)
+				lo, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2061,11 +2138,12 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindV128LoadLane:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			switch op.B1 {
 			case 8:
-				b, ok := memoryInst.ReadByte(offset)
+				b, ok := memoryInst.ReadByte64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2077,7 +2155,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					hi = (hi & ^(0xff << s)) | uint64(b)<<s
 				}
 			case 16:
-				b, ok := memoryInst.ReadUint16Le(offset)
+				b, ok := memoryInst.ReadUint16Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2089,7 +2167,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					hi = (hi & ^(0xff_ff << s)) | uint64(b)<<s
 				}
 			case 32:
-				b, ok := memoryInst.ReadUint32Le(offset)
+				b, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2101,7 +2179,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					hi = (hi & ^(0xff_ff_ff_ff << s)) | uint64(b)<<s
 				}
 			case 64:
-				b, ok := memoryInst.ReadUint64Le(offset)
+				b, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2115,6 +2193,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(hi)
 			frame.pc++
 		case operationKindV128Store:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			// Write the upper bytes first to trigger an early error if the memory access is out of bounds.
@@ -2122,41 +2201,42 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			if uint64(offset)+8 > math.MaxUint32 {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
-			if ok := memoryInst.WriteUint64Le(offset+8, hi); !ok {
+			if ok := memoryInst.WriteUint64Le64(offset+8, hi); !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
-			if ok := memoryInst.WriteUint64Le(offset, lo); !ok {
+			if ok := memoryInst.WriteUint64Le64(offset, lo); !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case operationKindV128StoreLane:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			var ok bool
 			switch op.B1 {
 			case 8:
 				if op.B2 < 8 {
-					ok = memoryInst.WriteByte(offset, byte(lo>>(op.B2*8)))
+					ok = memoryInst.WriteByte64(offset, byte(lo>>(op.B2*8)))
 				} else {
-					ok = memoryInst.WriteByte(offset, byte(hi>>((op.B2-8)*8)))
+					ok = memoryInst.WriteByte64(offset, byte(hi>>((op.B2-8)*8)))
 				}
 			case 16:
 				if op.B2 < 4 {
-					ok = memoryInst.WriteUint16Le(offset, uint16(lo>>(op.B2*16)))
+					ok = memoryInst.WriteUint16Le64(offset, uint16(lo>>(op.B2*16)))
 				} else {
-					ok = memoryInst.WriteUint16Le(offset, uint16(hi>>((op.B2-4)*16)))
+					ok = memoryInst.WriteUint16Le64(offset, uint16(hi>>((op.B2-4)*16)))
 				}
 			case 32:
 				if op.B2 < 2 {
-					ok = memoryInst.WriteUint32Le(offset, uint32(lo>>(op.B2*32)))
+					ok = memoryInst.WriteUint32Le64(offset, uint32(lo>>(op.B2*32)))
 				} else {
-					ok = memoryInst.WriteUint32Le(offset, uint32(hi>>((op.B2-2)*32)))
+					ok = memoryInst.WriteUint32Le64(offset, uint32(hi>>((op.B2-2)*32)))
 				}
 			case 64:
 				if op.B2 == 0 {
-					ok = memoryInst.WriteUint64Le(offset, lo)
+					ok = memoryInst.WriteUint64Le64(offset, lo)
 				} else {
-					ok = memoryInst.WriteUint64Le(offset, hi)
+					ok = memoryInst.WriteUint64Le64(offset, hi)
 				}
 			}
 			if !ok {
@@ -3938,6 +4018,66 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(lo)
 			ce.pushValue(hi)
 			frame.pc++
+		case operationKindV128RelaxedMadd:
+			x3hi, x3lo := ce.popValue(), ce.popValue()
+			x2hi, x2lo := ce.popValue(), ce.popValue()
+			x1hi, x1lo := ce.popValue(), ce.popValue()
+			var retLo, retHi uint64
+			if op.B1 == shapeF32x4 {
+				retHi = maddFloat32bits(uint32(x1hi), uint32(x2hi), uint32(x3hi), op.B3) |
+					maddFloat32bits(uint32(x1hi>>32), uint32(x2hi>>32), uint32(x3hi>>32), op.B3)<<32
+				retLo = maddFloat32bits(uint32(x1lo), uint32(x2lo), uint32(x3lo), op.B3) |
+					maddFloat32bits(uint32(x1lo>>32), uint32(x2lo>>32), uint32(x3lo>>32), op.B3)<<32
+			} else {
+				retHi = maddFloat64bits(x1hi, x2hi, x3hi, op.B3)
+				retLo = maddFloat64bits(x1lo, x2lo, x3lo, op.B3)
+			}
+			ce.pushValue(retLo)
+			ce.pushValue(retHi)
+			frame.pc++
+		case operationKindV128RelaxedDot:
+			var x3Hi, x3Lo uint64
+			if op.B3 {
+				x3Hi, x3Lo = ce.popValue(), ce.popValue()
+			}
+			x2Hi, x2Lo := ce.popValue(), ce.popValue()
+			x1Hi, x1Lo := ce.popValue(), ce.popValue()
+			lo, hi := v128RelaxedDot(x1Hi, x1Lo, x2Hi, x2Lo)
+			if op.B3 {
+				lo, hi = v128RelaxedDotAdd(lo, hi, x3Lo, x3Hi)
+			}
+			ce.pushValue(lo)
+			ce.pushValue(hi)
+			frame.pc++
+		case operationKindI64Add128:
+			yHi, yLo := ce.popValue(), ce.popValue()
+			xHi, xLo := ce.popValue(), ce.popValue()
+			var lo, hi, carry uint64
+			if op.B3 {
+				lo, carry = bits.Sub64(xLo, yLo, 0)
+				hi, _ = bits.Sub64(xHi, yHi, carry)
+			} else {
+				lo, carry = bits.Add64(xLo, yLo, 0)
+				hi, _ = bits.Add64(xHi, yHi, carry)
+			}
+			ce.pushValue(lo)
+			ce.pushValue(hi)
+			frame.pc++
+		case operationKindI64MulWide:
+			y, x := ce.popValue(), ce.popValue()
+			hi, lo := bits.Mul64(x, y)
+			if op.B3 {
+				// Adjust the high half of the unsigned product for the negative operands.
+				if int64(x) < 0 {
+					hi -= y
+				}
+				if int64(y) < 0 {
+					hi -= x
+				}
+			}
+			ce.pushValue(lo)
+			ce.pushValue(hi)
+			frame.pc++
 		case operationKindV128ITruncSatFromF:
 			hi, lo := ce.popValue(), ce.popValue()
 			signed := op.B3
@@ -4010,6 +4150,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(retHi)
 			frame.pc++
 		case operationKindAtomicMemoryWait:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			timeout := int64(ce.popValue())
 			exp := ce.popValue()
 			offset := ce.popMemoryOffset(op)
@@ -4024,38 +4165,39 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				if offset%4 != 0 {
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
-				if int(offset) > len(memoryInst.Buffer)-4 {
+				if uint64(len(memoryInst.Buffer)) < 4 || offset > uint64(len(memoryInst.Buffer))-4 {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
-				ce.pushValue(memoryInst.Wait32(offset, uint32(exp), timeout, func(mem *wasm.MemoryInstance, offset uint32) uint32 {
+				ce.pushValue(memoryInst.Wait32(offset, uint32(exp), timeout, func(mem *wasm.MemoryInstance, offset uint64) uint32 {
 					mem.Mux.Lock()
 					defer mem.Mux.Unlock()
-					value, _ := mem.ReadUint32Le(offset)
+					value, _ := mem.ReadUint32Le64(offset)
 					return value
 				}))
 			case unsignedTypeI64:
 				if offset%8 != 0 {
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
-				if int(offset) > len(memoryInst.Buffer)-8 {
+				if uint64(len(memoryInst.Buffer)) < 8 || offset > uint64(len(memoryInst.Buffer))-8 {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
-				ce.pushValue(memoryInst.Wait64(offset, exp, timeout, func(mem *wasm.MemoryInstance, offset uint32) uint64 {
+				ce.pushValue(memoryInst.Wait64(offset, exp, timeout, func(mem *wasm.MemoryInstance, offset uint64) uint64 {
 					mem.Mux.Lock()
 					defer mem.Mux.Unlock()
-					value, _ := mem.ReadUint64Le(offset)
+					value, _ := mem.ReadUint64Le64(offset)
 					return value
 				}))
 			}
 			frame.pc++
 		case operationKindAtomicMemoryNotify:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			count := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			if offset%4 != 0 {
 				panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 			}
 			// Just a bounds check
-			if offset >= memoryInst.Size() {
+			if offset >= memoryInst.Size64() {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			res := memoryInst.Notify(offset, uint32(count))
@@ -4071,6 +4213,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindAtomicLoad:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			offset := ce.popMemoryOffset(op)
 			switch unsignedType(op.B1) {
 			case unsignedTypeI32:
@@ -4078,7 +4221,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				val, ok := memoryInst.ReadUint32Le(offset)
+				val, ok := memoryInst.ReadUint32Le64(offset)
 				memoryInst.Mux.Unlock()
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4089,7 +4232,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				val, ok := memoryInst.ReadUint64Le(offset)
+				val, ok := memoryInst.ReadUint64Le64(offset)
 				memoryInst.Mux.Unlock()
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4098,9 +4241,10 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindAtomicLoad8:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			offset := ce.popMemoryOffset(op)
 			memoryInst.Mux.Lock()
-			val, ok := memoryInst.ReadByte(offset)
+			val, ok := memoryInst.ReadByte64(offset)
 			memoryInst.Mux.Unlock()
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4108,12 +4252,13 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(uint64(val))
 			frame.pc++
 		case operationKindAtomicLoad16:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			offset := ce.popMemoryOffset(op)
 			if offset%2 != 0 {
 				panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 			}
 			memoryInst.Mux.Lock()
-			val, ok := memoryInst.ReadUint16Le(offset)
+			val, ok := memoryInst.ReadUint16Le64(offset)
 			memoryInst.Mux.Unlock()
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4121,6 +4266,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(uint64(val))
 			frame.pc++
 		case operationKindAtomicStore:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			switch unsignedType(op.B1) {
@@ -4129,7 +4275,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				ok := memoryInst.WriteUint32Le(offset, uint32(val))
+				ok := memoryInst.WriteUint32Le64(offset, uint32(val))
 				memoryInst.Mux.Unlock()
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4139,7 +4285,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				ok := memoryInst.WriteUint64Le(offset, val)
+				ok := memoryInst.WriteUint64Le64(offset, val)
 				memoryInst.Mux.Unlock()
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4147,29 +4293,32 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case operationKindAtomicStore8:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := byte(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			memoryInst.Mux.Lock()
-			ok := memoryInst.WriteByte(offset, val)
+			ok := memoryInst.WriteByte64(offset, val)
 			memoryInst.Mux.Unlock()
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case operationKindAtomicStore16:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := uint16(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if offset%2 != 0 {
 				panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 			}
 			memoryInst.Mux.Lock()
-			ok := memoryInst.WriteUint16Le(offset, val)
+			ok := memoryInst.WriteUint16Le64(offset, val)
 			memoryInst.Mux.Unlock()
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case operationKindAtomicRMW:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			switch unsignedType(op.B1) {
@@ -4178,7 +4327,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				old, ok := memoryInst.ReadUint32Le(offset)
+				old, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					memoryInst.Mux.Unlock()
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4198,7 +4347,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				case atomicArithmeticOpNop:
 					newVal = uint32(val)
 				}
-				memoryInst.WriteUint32Le(offset, newVal)
+				memoryInst.WriteUint32Le64(offset, newVal)
 				memoryInst.Mux.Unlock()
 				ce.pushValue(uint64(old))
 			case unsignedTypeI64:
@@ -4206,7 +4355,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				old, ok := memoryInst.ReadUint64Le(offset)
+				old, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					memoryInst.Mux.Unlock()
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4226,16 +4375,17 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				case atomicArithmeticOpNop:
 					newVal = val
 				}
-				memoryInst.WriteUint64Le(offset, newVal)
+				memoryInst.WriteUint64Le64(offset, newVal)
 				memoryInst.Mux.Unlock()
 				ce.pushValue(old)
 			}
 			frame.pc++
 		case operationKindAtomicRMW8:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			memoryInst.Mux.Lock()
-			old, ok := memoryInst.ReadByte(offset)
+			old, ok := memoryInst.ReadByte64(offset)
 			if !ok {
 				memoryInst.Mux.Unlock()
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4256,18 +4406,19 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case atomicArithmeticOpNop:
 				newVal = arg
 			}
-			memoryInst.WriteByte(offset, newVal)
+			memoryInst.WriteByte64(offset, newVal)
 			memoryInst.Mux.Unlock()
 			ce.pushValue(uint64(old))
 			frame.pc++
 		case operationKindAtomicRMW16:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			val := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			if offset%2 != 0 {
 				panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 			}
 			memoryInst.Mux.Lock()
-			old, ok := memoryInst.ReadUint16Le(offset)
+			old, ok := memoryInst.ReadUint16Le64(offset)
 			if !ok {
 				memoryInst.Mux.Unlock()
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -4288,11 +4439,12 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case atomicArithmeticOpNop:
 				newVal = arg
 			}
-			memoryInst.WriteUint16Le(offset, newVal)
+			memoryInst.WriteUint16Le64(offset, newVal)
 			memoryInst.Mux.Unlock()
 			ce.pushValue(uint64(old))
 			frame.pc++
 		case operationKindAtomicRMWCmpxchg:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			rep := ce.popValue()
 			exp := ce.popValue()
 			offset := ce.popMemoryOffset(op)
@@ -4302,13 +4454,13 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				old, ok := memoryInst.ReadUint32Le(offset)
+				old, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					memoryInst.Mux.Unlock()
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				if old == uint32(exp) {
-					memoryInst.WriteUint32Le(offset, uint32(rep))
+					memoryInst.WriteUint32Le64(offset, uint32(rep))
 				}
 				memoryInst.Mux.Unlock()
 				ce.pushValue(uint64(old))
@@ -4317,35 +4469,37 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 				}
 				memoryInst.Mux.Lock()
-				old, ok := memoryInst.ReadUint64Le(offset)
+				old, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					memoryInst.Mux.Unlock()
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				if old == exp {
-					memoryInst.WriteUint64Le(offset, rep)
+					memoryInst.WriteUint64Le64(offset, rep)
 				}
 				memoryInst.Mux.Unlock()
 				ce.pushValue(old)
 			}
 			frame.pc++
 		case operationKindAtomicRMW8Cmpxchg:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			rep := byte(ce.popValue())
 			exp := byte(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			memoryInst.Mux.Lock()
-			old, ok := memoryInst.ReadByte(offset)
+			old, ok := memoryInst.ReadByte64(offset)
 			if !ok {
 				memoryInst.Mux.Unlock()
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			if old == exp {
-				memoryInst.WriteByte(offset, rep)
+				memoryInst.WriteByte64(offset, rep)
 			}
 			memoryInst.Mux.Unlock()
 			ce.pushValue(uint64(old))
 			frame.pc++
 		case operationKindAtomicRMW16Cmpxchg:
+			memoryInst := memoryAt(memoryInst, moduleInst, op.U3)
 			rep := uint16(ce.popValue())
 			exp := uint16(ce.popValue())
 			offset := ce.popMemoryOffset(op)
@@ -4353,13 +4507,13 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				panic(wasmruntime.ErrRuntimeUnalignedAtomic)
 			}
 			memoryInst.Mux.Lock()
-			old, ok := memoryInst.ReadUint16Le(offset)
+			old, ok := memoryInst.ReadUint16Le64(offset)
 			if !ok {
 				memoryInst.Mux.Unlock()
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			if old == exp {
-				memoryInst.WriteUint16Le(offset, rep)
+				memoryInst.WriteUint16Le64(offset, rep)
 			}
 			memoryInst.Mux.Unlock()
 			ce.pushValue(uint64(old))
@@ -4372,7 +4526,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 		case operationKindTailCallReturnCallIndirect:
 			offset := ce.popValue()
 			table := tables[op.U2]
-			tf := ce.functionForOffset(table, offset, typeIDs[op.U1])
+			tf := ce.functionForOffset(moduleInst, table, offset, wasm.Index(op.U1))
 
 			// We are allowing proper tail calls only across functions that belong to the same
 			// module; for indirect calls, we have to enforce it at run-time.
@@ -4390,6 +4544,192 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.dropForTailCall(frame, tf)
 			body, bodyLen = ce.resetPc(frame, tf)
 
+		case operationKindCallRef:
+			tf := ce.functionForRef(moduleInst, ce.popValue(), wasm.Index(op.U1))
+
+			if frame.f.parent.exceptionHandlers != nil {
+				if ce.callFunctionInTry(ctx, f.moduleInstance, tf, frame) {
+					continue
+				}
+			} else {
+				ce.callFunction(ctx, f.moduleInstance, tf)
+			}
+			frame.pc++
+
+		case operationKindTailCallReturnCallRef:
+			tf := ce.functionForRef(moduleInst, ce.popValue(), wasm.Index(op.U1))
+
+			// The same as operationKindTailCallReturnCallIndirect, the function can be of another module.
+			if tf.moduleInstance != f.moduleInstance {
+				ce.callFunction(ctx, f.moduleInstance, tf)
+				ce.drop(op.Us[0])
+				frame.pc = op.Us[1]
+				continue
+			}
+
+			ce.dropForTailCall(frame, tf)
+			body, bodyLen = ce.resetPc(frame, tf)
+
+		case operationKindRefAsNonNull:
+			if ce.stack[len(ce.stack)-1] == 0 {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			}
+			frame.pc++
+
+		case operationKindThrow:
+			tag := frame.f.moduleInstance.Tags[op.U1]
+			payload := make([]uint64, tag.Type.ParamNumInUint64)
+			ce.popValues(payload)
+			ce.throw(frame, experimental.NewException(tag, payload...))
+		case operationKindThrowRef:
+			exc := ce.exceptionRefs.Get(ce.popValue())
+			if exc == nil {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			}
+			ce.throw(frame, exc)
+		case operationKindContNew:
+			tf := ce.functionForRef(moduleInst, ce.popValue(), wasm.Index(op.U1))
+			if ce.continuations == nil {
+				ce.continuations = &wasm.Continuations{}
+			}
+			ce.pushValue(ce.continuations.New(ce.continuationStart(ctx, tf)))
+			frame.pc++
+		case operationKindContBind:
+			ref := ce.popValue()
+			params := make([]uint64, op.U1)
+			ce.popValues(params)
+			ce.pushValue(ce.continuationsOf(ref).Bind(ref, params))
+			frame.pc++
+		case operationKindSuspend:
+			tag := frame.f.moduleInstance.Tags[op.U1]
+			payload := make([]uint64, tag.Type.ParamNumInUint64)
+			ce.popValues(payload)
+			ce.pushValues(ce.continuation.Suspend(tag, payload))
+			frame.pc++
+		case operationKindResume:
+			if ce.resume(frame, op) {
+				continue
+			}
+			frame.pc++
+		case operationKindStructNew:
+			ce.structNew(frame.f.moduleInstance, wasm.Index(op.U1), op.B3)
+			frame.pc++
+		case operationKindStructGet:
+			m := frame.f.moduleInstance
+			obj := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindStruct, wasm.Index(op.U1))
+			field := &obj.Type().Fields[op.U2]
+			offset := obj.Type().FieldOffset(wasm.Index(op.U2))
+			ce.pushGCValues(field, obj.Values[offset:], op.B3)
+			frame.pc++
+		case operationKindStructSet:
+			m := frame.f.moduleInstance
+			st := &m.Source.SubTypes[op.U1]
+			field := &st.Fields[op.U2]
+			values := ce.popGCValues(field)
+			obj := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindStruct, wasm.Index(op.U1))
+			copy(obj.Values[st.FieldOffset(wasm.Index(op.U2)):], values)
+			frame.pc++
+		case operationKindArrayNew:
+			ce.arrayNew(frame.f.moduleInstance, wasm.Index(op.U1), op.B1, wasm.Index(op.U2))
+			frame.pc++
+		case operationKindArrayGet:
+			m := frame.f.moduleInstance
+			index := uint64(uint32(ce.popValue()))
+			obj := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindArray, wasm.Index(op.U1))
+			if index >= uint64(obj.ArrayLen()) {
+				panic(wasmruntime.ErrRuntimeArrayOutOfBounds)
+			}
+			st := obj.Type()
+			ce.pushGCValues(&st.Fields[0], obj.Values[index*uint64(st.SizeInUint64()):], op.B3)
+			frame.pc++
+		case operationKindArraySet:
+			m := frame.f.moduleInstance
+			st := &m.Source.SubTypes[op.U1]
+			values := ce.popGCValues(&st.Fields[0])
+			index := uint64(uint32(ce.popValue()))
+			obj := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindArray, wasm.Index(op.U1))
+			if index >= uint64(obj.ArrayLen()) {
+				panic(wasmruntime.ErrRuntimeArrayOutOfBounds)
+			}
+			copy(obj.Values[index*uint64(st.SizeInUint64()):], values)
+			frame.pc++
+		case operationKindArrayLen:
+			ref := ce.popValue()
+			if ref == 0 {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			}
+			obj := frame.f.moduleInstance.GCHeap().Get(ref)
+			if obj == nil || obj.Kind != wasm.GCObjectKindArray {
+				panic(wasmruntime.ErrRuntimeCastFailure)
+			}
+			ce.pushValue(uint64(obj.ArrayLen()))
+			frame.pc++
+		case operationKindArrayFill:
+			m := frame.f.moduleInstance
+			st := &m.Source.SubTypes[op.U1]
+			n := uint64(uint32(ce.popValue()))
+			values := ce.popGCValues(&st.Fields[0])
+			offset := uint64(uint32(ce.popValue()))
+			obj := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindArray, wasm.Index(op.U1))
+			if offset+n > uint64(obj.ArrayLen()) {
+				panic(wasmruntime.ErrRuntimeArrayOutOfBounds)
+			}
+			size := uint64(st.SizeInUint64())
+			for i := offset; i < offset+n; i++ {
+				copy(obj.Values[i*size:], values)
+			}
+			frame.pc++
+		case operationKindArrayCopy:
+			m := frame.f.moduleInstance
+			n := uint64(uint32(ce.popValue()))
+			srcOffset := uint64(uint32(ce.popValue()))
+			src := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindArray, wasm.Index(op.U2))
+			dstOffset := uint64(uint32(ce.popValue()))
+			dst := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindArray, wasm.Index(op.U1))
+			if srcOffset+n > uint64(src.ArrayLen()) || dstOffset+n > uint64(dst.ArrayLen()) {
+				panic(wasmruntime.ErrRuntimeArrayOutOfBounds)
+			}
+			size := uint64(dst.Type().SizeInUint64())
+			copy(dst.Values[dstOffset*size:(dstOffset+n)*size], src.Values[srcOffset*size:])
+			frame.pc++
+		case operationKindArrayInit:
+			ce.arrayInit(frame.f.moduleInstance, wasm.Index(op.U1), wasm.Index(op.U2), op.B3)
+			frame.pc++
+		case operationKindRefTest:
+			if ce.refTest(frame.f.moduleInstance, ce.popValue(), wasm.HeapType(op.U1), op.B3) {
+				ce.pushValue(1)
+			} else {
+				ce.pushValue(0)
+			}
+			frame.pc++
+		case operationKindRefCast:
+			if !ce.refTest(frame.f.moduleInstance, ce.stack[len(ce.stack)-1], wasm.HeapType(op.U1), op.B3) {
+				panic(wasmruntime.ErrRuntimeCastFailure)
+			}
+			frame.pc++
+		case operationKindAnyConvertExtern:
+			ce.pushValue(frame.f.moduleInstance.GCHeap().AnyConvertExtern(ce.popValue(), ce.gcStackRoots))
+			frame.pc++
+		case operationKindExternConvertAny:
+			ce.pushValue(frame.f.moduleInstance.GCHeap().ExternConvertAny(ce.popValue()))
+			frame.pc++
+		case operationKindRefI31:
+			ce.pushValue(wasm.GCRefI31(uint32(ce.popValue())))
+			frame.pc++
+		case operationKindI31Get:
+			ref := ce.popValue()
+			if ref == 0 {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			} else if !wasm.GCRefIsI31(ref) {
+				panic(wasmruntime.ErrRuntimeCastFailure)
+			}
+			v := wasm.GCRefI31Value(ref)
+			if op.B3 {
+				v = uint32(int32(v<<1) >> 1)
+			}
+			ce.pushValue(uint64(v))
+			frame.pc++
+
 		default:
 			frame.pc++
 		}
@@ -4397,6 +4737,372 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 	ce.popFrame()
 }
 
+// throw transfers the control to the exception handler of the frame which catches the exception, or
+// propagates the exception to the caller if there is none.
+func (ce *callEngine) throw(frame *callFrame, exc *experimental.Exception) {
+	h := frame.exceptionHandler(exc)
+	if h == nil {
+		panic(exc)
+	}
+	ce.enterExceptionHandler(frame, h, exc)
+}
+
+// gcAlloc adds the object to the heap of the store, and returns the reference to it.
+func (ce *callEngine) gcAlloc(m *wasm.ModuleInstance, obj *wasm.GCObject) uint64 {
+	return m.GCHeap().Alloc(obj, ce.gcStackRoots)
+}
+
+// gcStackRoots enumerates the values which can be the references to the objects in wasm.GCHeap during this call.
+func (ce *callEngine) gcStackRoots(mark func(uint64)) {
+	for _, v := range ce.stack {
+		mark(v)
+	}
+	ce.exceptionRefs.ForEachPayload(mark)
+	if ce.continuations != nil {
+		ce.continuations.ForEachBound(mark)
+	}
+}
+
+// gcObject returns the object referred by ref, which must be of the kind and the type at typeIndex of m, or its subtype.
+//
+// The validation guarantees the type of the operands of Wasm code, but the references passed by host functions
+// aren't checked, so this checks the precise type at runtime.
+func (ce *callEngine) gcObject(m *wasm.ModuleInstance, ref uint64, kind wasm.GCObjectKind, typeIndex wasm.Index) *wasm.GCObject {
+	if ref == 0 {
+		panic(wasmruntime.ErrRuntimeNullReference)
+	}
+	obj := m.GCHeap().Get(ref)
+	if obj == nil || obj.Kind != kind || !obj.Module.IsSubType(obj.TypeIndex, m.Source, typeIndex) {
+		panic(wasmruntime.ErrRuntimeCastFailure)
+	}
+	return obj
+}
+
+// pushGCValues pushes the value of the field stored at the head of values, which is sign-extended if signed is true.
+func (ce *callEngine) pushGCValues(field *wasm.FieldType, values []uint64, signed bool) {
+	switch field.Type {
+	case wasm.StorageTypeI8:
+		if signed {
+			ce.pushValue(uint64(uint32(int32(int8(values[0])))))
+		} else {
+			ce.pushValue(values[0])
+		}
+	case wasm.StorageTypeI16:
+		if signed {
+			ce.pushValue(uint64(uint32(int32(int16(values[0])))))
+		} else {
+			ce.pushValue(values[0])
+		}
+	case wasm.ValueTypeV128:
+		ce.pushValues(values[:2])
+	default:
+		ce.pushValue(values[0])
+	}
+}
+
+// popGCValues pops the value of the field, and returns it as stored in wasm.GCObject. The returned slice is only valid
+// until the next push.
+func (ce *callEngine) popGCValues(field *wasm.FieldType) []uint64 {
+	n := 1
+	if field.Type == wasm.ValueTypeV128 {
+		n = 2
+	}
+	values := ce.stack[len(ce.stack)-n:]
+	ce.stack = ce.stack[:len(ce.stack)-n]
+	switch field.Type {
+	case wasm.StorageTypeI8:
+		values[0] &= 0xff
+	case wasm.StorageTypeI16:
+		values[0] &= 0xffff
+	}
+	return values
+}
+
+// structNew allocates a struct of the type at typeIndex, whose fields are popped from the stack unless isDefault is true.
+func (ce *callEngine) structNew(m *wasm.ModuleInstance, typeIndex wasm.Index, isDefault bool) {
+	st := &m.Source.SubTypes[typeIndex]
+	values := make([]uint64, st.SizeInUint64())
+	if !isDefault {
+		for i := len(st.Fields) - 1; i >= 0; i-- {
+			copy(values[st.FieldOffset(wasm.Index(i)):], ce.popGCValues(&st.Fields[i]))
+		}
+	}
+	ce.pushValue(ce.gcAlloc(m, &wasm.GCObject{Kind: wasm.GCObjectKindStruct, Module: m.Source, TypeIndex: typeIndex, Values: values}))
+}
+
+// arrayNew allocates an array of the type at typeIndex, whose elements are initialized as per kind.
+func (ce *callEngine) arrayNew(m *wasm.ModuleInstance, typeIndex wasm.Index, kind arrayNewKind, operand wasm.Index) {
+	st := &m.Source.SubTypes[typeIndex]
+	size := uint64(st.SizeInUint64())
+	var n uint64
+	if kind == arrayNewKindFixed {
+		n = uint64(operand)
+	} else {
+		n = uint64(uint32(ce.popValue()))
+	}
+	if n*size > wasm.GCObjectMaxSizeInUint64 {
+		panic(wasmruntime.ErrRuntimeAllocationTooLarge)
+	}
+
+	values := make([]uint64, n*size)
+	switch kind {
+	case arrayNewKindValue:
+		v := ce.popGCValues(&st.Fields[0])
+		for i := uint64(0); i < n; i++ {
+			copy(values[i*size:], v)
+		}
+	case arrayNewKindFixed:
+		// The elements are pushed in order, so pop them from the last one.
+		for i := n; i > 0; i-- {
+			copy(values[(i-1)*size:], ce.popGCValues(&st.Fields[0]))
+		}
+	case arrayNewKindData, arrayNewKindElem:
+		offset := uint64(uint32(ce.popValue()))
+		ce.initArray(m, st, values, operand, offset, kind == arrayNewKindElem)
+	}
+	ce.pushValue(ce.gcAlloc(m, &wasm.GCObject{Kind: wasm.GCObjectKindArray, Module: m.Source, TypeIndex: typeIndex, Values: values}))
+}
+
+// arrayInit copies the elements from the data or element segment into the array popped from the stack.
+func (ce *callEngine) arrayInit(m *wasm.ModuleInstance, typeIndex, segmentIndex wasm.Index, isElem bool) {
+	n := uint64(uint32(ce.popValue()))
+	srcOffset := uint64(uint32(ce.popValue()))
+	dstOffset := uint64(uint32(ce.popValue()))
+	obj := ce.gcObject(m, ce.popValue(), wasm.GCObjectKindArray, typeIndex)
+	if dstOffset+n > uint64(obj.ArrayLen()) {
+		panic(wasmruntime.ErrRuntimeArrayOutOfBounds)
+	}
+	st := obj.Type()
+	size := uint64(st.SizeInUint64())
+	ce.initArray(m, st, obj.Values[dstOffset*size:(dstOffset+n)*size], segmentIndex, srcOffset, isElem)
+}
+
+// initArray sets the elements in values from the data or element segment at the offset.
+func (ce *callEngine) initArray(m *wasm.ModuleInstance, st *wasm.SubType, values []uint64, segmentIndex wasm.Index, offset uint64, isElem bool) {
+	size := uint64(st.SizeInUint64())
+	n := uint64(len(values)) / size
+	if isElem {
+		elements := m.ElementInstances[segmentIndex]
+		if offset+n > uint64(len(elements)) {
+			panic(wasmruntime.ErrRuntimeInvalidTableAccess)
+		}
+		for i := uint64(0); i < n; i++ {
+			values[i] = uint64(elements[offset+i])
+		}
+		return
+	}
+
+	var width uint64
+	switch st.Fields[0].Type {
+	case wasm.StorageTypeI8:
+		width = 1
+	case wasm.StorageTypeI16:
+		width = 2
+	case wasm.ValueTypeI32, wasm.ValueTypeF32:
+		width = 4
+	case wasm.ValueTypeI64, wasm.ValueTypeF64:
+		width = 8
+	default: // wasm.ValueTypeV128
+		width = 16
+	}
+	data := m.DataInstances[segmentIndex]
+	if offset+n*width > uint64(len(data)) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	data = data[offset : offset+n*width]
+	for i := uint64(0); i < n; i++ {
+		b := data[i*width:]
+		switch width {
+		case 1:
+			values[i] = uint64(b[0])
+		case 2:
+			values[i] = uint64(binary.LittleEndian.Uint16(b))
+		case 4:
+			values[i] = uint64(binary.LittleEndian.Uint32(b))
+		case 8:
+			values[i] = binary.LittleEndian.Uint64(b)
+		default:
+			values[2*i] = binary.LittleEndian.Uint64(b)
+			values[2*i+1] = binary.LittleEndian.Uint64(b[8:])
+		}
+	}
+}
+
+// refTest returns true if ref is of the heap type ht, or null and nullable is true.
+func (ce *callEngine) refTest(m *wasm.ModuleInstance, ref uint64, ht wasm.HeapType, nullable bool) bool {
+	if ref == 0 {
+		return nullable
+	}
+	switch ht {
+	case wasm.HeapTypeAny, wasm.HeapTypeFunc, wasm.HeapTypeExtern, wasm.HeapTypeExn:
+		return true
+	case wasm.HeapTypeNone, wasm.HeapTypeNoFunc, wasm.HeapTypeNoExtern, wasm.HeapTypeNoExn:
+		return false
+	case wasm.HeapTypeI31:
+		return wasm.GCRefIsI31(ref)
+	}
+
+	if ht >= 0 {
+		if st := m.Source.SubTypes; st == nil || st[ht].Kind == wasm.CompositeTypeKindFunc {
+			return functionFromUintptr(uintptr(ref)).isOfType(m, wasm.Index(ht))
+		}
+	}
+	if wasm.GCRefIsI31(ref) {
+		return ht == wasm.HeapTypeEq
+	}
+	obj := m.GCHeap().Get(ref)
+	if obj == nil {
+		return false
+	}
+	switch ht {
+	case wasm.HeapTypeEq:
+		return obj.Kind != wasm.GCObjectKindExtern
+	case wasm.HeapTypeStruct:
+		return obj.Kind == wasm.GCObjectKindStruct
+	case wasm.HeapTypeArray:
+		return obj.Kind == wasm.GCObjectKindArray
+	default:
+		return obj.Kind != wasm.GCObjectKindExtern && obj.Module.IsSubType(obj.TypeIndex, m.Source, wasm.Index(ht))
+	}
+}
+
+// callFunctionInTry is the same as callFunction, except that if f throws an exception caught by an exception
+// handler of the frame, this unwinds the call stack, transfers the control to the handler and returns true.
+func (ce *callEngine) callFunctionInTry(ctx context.Context, m *wasm.ModuleInstance, f *function, frame *callFrame) (caught bool) {
+	frameCount := len(ce.frames)
+	defer func() {
+		if r := recover(); r != nil {
+			exc, ok := r.(*experimental.Exception)
+			if !ok {
+				panic(r)
+			}
+			h := frame.exceptionHandler(exc)
+			if h == nil {
+				// Propagate the exception with the frames intact, so that the stack trace is complete.
+				panic(r)
+			}
+
+			// Abort the listeners of the unwound frames, as recoverOnCall does.
+			for i := len(ce.frames) - 1; i >= frameCount; i-- {
+				if uf := ce.frames[i].f; uf.parent.listener != nil {
+					uf.parent.listener.Abort(ctx, m, uf.definition(), exc)
+				}
+			}
+			ce.frames = ce.frames[:frameCount]
+			ce.enterExceptionHandler(frame, h, exc)
+			caught = true
+		}
+	}()
+	ce.callFunction(ctx, m, f)
+	return
+}
+
+// continuationStart returns the wasm.ContinuationStart of the function f for cont.new, which calls it with a new
+// call engine sharing the contrefs and exnrefs with this one.
+func (ce *callEngine) continuationStart(ctx context.Context, f *function) wasm.ContinuationStart {
+	exceptionRefs, continuations := ce.exceptionRefs, ce.continuations
+	return func(k *wasm.Continuation, params []uint64) []uint64 {
+		m := f.moduleInstance
+		if h := m.GCHeap(); h != nil {
+			// The continuation is another call in progress, which keeps the objects from being collected while it
+			// is suspended, as its stack isn't scanned by the other calls.
+			h.EnterCall()
+			defer h.ExitCall()
+		}
+		kce := &callEngine{f: f, exceptionRefs: exceptionRefs, continuations: continuations, continuation: k}
+		kce.pushValues(params)
+		kce.callFunction(ctx, m, f)
+		results := make([]uint64, f.funcType.ResultNumInUint64)
+		kce.popValues(results)
+		return results
+	}
+}
+
+// continuationsOf returns the continuations of this call to look up the contref. The null contref traps when looked
+// up, even if there is no continuation yet.
+func (ce *callEngine) continuationsOf(ref uint64) *wasm.Continuations {
+	if ce.continuations == nil {
+		if ref == 0 {
+			panic(wasmruntime.ErrRuntimeNullReference)
+		}
+		panic(wasmruntime.ErrRuntimeContinuationAlreadyResumed)
+	}
+	return ce.continuations
+}
+
+// resume executes operationKindResume in the frame, and returns true if the continuation suspended with the tag of
+// one of the handlers, in which case the payload and the contref to the rest of it are passed to the label of the
+// handler the same way as to an exception handler.
+func (ce *callEngine) resume(frame *callFrame, op *unionOperation) (branched bool) {
+	m := frame.f.moduleInstance
+	ref := ce.popValue()
+	params := make([]uint64, op.U1)
+	ce.popValues(params)
+	handlers := make([]*wasm.TagInstance, len(op.Us)/3)
+	for i := range handlers {
+		handlers[i] = m.Tags[op.Us[i*3]]
+	}
+
+	if frame.f.parent.exceptionHandlers != nil {
+		// An exception thrown by the continuation is propagated to this frame, which may catch it.
+		defer func() {
+			if r := recover(); r != nil {
+				exc, ok := r.(*experimental.Exception)
+				if !ok {
+					panic(r)
+				}
+				ce.throw(frame, exc)
+				branched = true
+			}
+		}()
+	}
+	h, values := ce.continuationsOf(ref).Resume(ref, params, handlers, ce.continuation)
+	if h < 0 {
+		ce.pushValues(values)
+		return false
+	}
+	ce.stack = ce.stack[:frame.base-frame.f.funcType.ParamNumInUint64+int(op.Us[h*3+2])]
+	ce.pushValues(values)
+	frame.pc = op.Us[h*3+1]
+	return true
+}
+
+// enterExceptionHandler unwinds the stack to the height of the exception handler, pushes the values of its catch
+// clause and sets the program counter to its target.
+func (ce *callEngine) enterExceptionHandler(frame *callFrame, h *exceptionHandler, exc *experimental.Exception) {
+	ce.stack = ce.stack[:frame.base-frame.f.funcType.ParamNumInUint64+h.stackHeightInUint64]
+	switch h.kind {
+	case wasm.CatchKindCatch:
+		ce.pushValues(exc.Payload())
+	case wasm.CatchKindCatchRef:
+		ce.pushValues(exc.Payload())
+		ce.pushValue(ce.exceptionRefs.Add(exc))
+	case wasm.CatchKindCatchAllRef:
+		ce.pushValue(ce.exceptionRefs.Add(exc))
+	}
+	frame.pc = h.target
+}
+
+// exceptionHandler returns the innermost exception handler which catches the exception thrown at the current
+// program counter, or nil if there is none.
+func (frame *callFrame) exceptionHandler(exc *experimental.Exception) *exceptionHandler {
+	handlers := frame.f.parent.exceptionHandlers
+	for i := range handlers {
+		h := &handlers[i]
+		if frame.pc < h.begin || frame.pc >= h.end {
+			continue
+		}
+		switch h.kind {
+		case wasm.CatchKindCatch, wasm.CatchKindCatchRef:
+			if exc.Tag() != experimental.ExceptionTag(frame.f.moduleInstance.Tags[h.tag]) {
+				continue
+			}
+		}
+		return h
+	}
+	return nil
+}
+
 func (ce *callEngine) dropForTailCall(frame *callFrame, f *function) {
 	base := frame.base - frame.f.funcType.ParamNumInUint64
 	paramCount := f.funcType.ParamNumInUint64
@@ -4415,7 +5121,7 @@ func (ce *callEngine) resetPc(frame *callFrame, f *function) (body []unionOperat
 	return body, bodyLen
 }
 
-func (ce *callEngine) functionForOffset(table *wasm.TableInstance, offset uint64, expectedTypeID wasm.FunctionTypeID) *function {
+func (ce *callEngine) functionForOffset(m *wasm.ModuleInstance, table *wasm.TableInstance, offset uint64, typeIndex wasm.Index) *function {
 	if offset >= uint64(len(table.References)) {
 		panic(wasmruntime.ErrRuntimeInvalidTableAccess)
 	}
@@ -4425,12 +5131,35 @@ func (ce *callEngine) functionForOffset(table *wasm.TableInstance, offset uint64
 	}
 
 	tf := functionFromUintptr(rawPtr)
-	if tf.typeID != expectedTypeID {
+	if !tf.isOfType(m, typeIndex) {
 		panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
 	}
 	return tf
 }
 
+// functionForRef returns the function referenced by the funcref, which must be of the type at typeIndex of m.
+func (ce *callEngine) functionForRef(m *wasm.ModuleInstance, ref uint64, typeIndex wasm.Index) *function {
+	if ref == 0 {
+		panic(wasmruntime.ErrRuntimeNullReference)
+	}
+	tf := functionFromUintptr(uintptr(ref))
+	if !tf.isOfType(m, typeIndex) {
+		panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+	}
+	return tf
+}
+
+// isOfType returns true if the function is of the type at typeIndex of m, or its subtype. The type IDs only identify
+// the signatures, so the declared subtypes are checked as well if m defines them as per the GC proposal.
+func (f *function) isOfType(m *wasm.ModuleInstance, typeIndex wasm.Index) bool {
+	if f.typeID != m.TypeIDs[typeIndex] {
+		return false
+	} else if m.Source.SubTypes == nil {
+		return true
+	}
+	return f.parent.source.IsFunctionOfSubType(f.parent.index, m.Source, typeIndex)
+}
+
 func wasmCompatMax32bits(v1, v2 uint32) uint64 {
 	return uint64(math.Float32bits(moremath.WasmCompatMax32(
 		math.Float32frombits(v1),
@@ -4457,6 +5186,25 @@ func mulFloat32bits(v1, v2 uint32) uint64 {
 	return uint64(math.Float32bits(math.Float32frombits(v1) * math.Float32frombits(v2)))
 }
 
+// maddFloat32bits returns v1*v2+v3, or -(v1*v2)+v3 if negate is true, rounding the product before the addition.
+func maddFloat32bits(v1, v2, v3 uint32, negate bool) uint64 {
+	// The assignment forces the rounding of the product, so that it is not fused with the addition.
+	p := math.Float32frombits(v1) * math.Float32frombits(v2)
+	if negate {
+		p = -p
+	}
+	return uint64(math.Float32bits(p + math.Float32frombits(v3)))
+}
+
+// maddFloat64bits is the same as maddFloat32bits but for 64-bit floats.
+func maddFloat64bits(v1, v2, v3 uint64, negate bool) uint64 {
+	p := math.Float64frombits(v1) * math.Float64frombits(v2)
+	if negate {
+		p = -p
+	}
+	return math.Float64bits(p + math.Float64frombits(v3))
+}
+
 func divFloat32bits(v1, v2 uint32) uint64 {
 	return uint64(math.Float32bits(math.Float32frombits(v1) / math.Float32frombits(v2)))
 }
@@ -4638,14 +5386,21 @@ func (ce *callEngine) callNativeFuncWithListener(ctx context.Context, m *wasm.Mo
 	return ctx
 }
 
+// memoryOutOfBounds returns true if the range of size bytes at offset is out of bounds of the memory. The add might
+// overflow with memory64 where both come from i64 values.
+func memoryOutOfBounds(mem *wasm.MemoryInstance, offset, size uint64) bool {
+	end := offset + size
+	return end < offset || end > uint64(len(mem.Buffer))
+}
+
 // popMemoryOffset takes a memory offset off the stack for use in load and store instructions.
 // As the top of stack value is 64-bit, this ensures it is in range before returning it.
-func (ce *callEngine) popMemoryOffset(op *unionOperation) uint32 {
+func (ce *callEngine) popMemoryOffset(op *unionOperation) uint64 {
 	offset := op.U2 + ce.popValue()
-	if offset > math.MaxUint32 {
+	if offset < op.U2 { // overflow which can only happen with memory64.
 		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 	}
-	return uint32(offset)
+	return offset
 }
 
 func (ce *callEngine) callGoFuncWithStack(ctx context.Context, m *wasm.ModuleInstance, f *function) {
@@ -4674,6 +5429,35 @@ func (ce *callEngine) callGoFuncWithStack(ctx context.Context, m *wasm.ModuleIns
 // v128Dot performs a dot product of two 64-bit vectors.
 // Note: for some reason (which I suspect is due to a bug in Go compiler's regalloc),
 // inlining this function causes a bug which happens **only when** we run with -race AND arm64 AND Go 1.22.
+// v128RelaxedDot returns the i16x8 sums of the adjacent products of the signed i8x16 lanes, saturated to 16-bit.
+func v128RelaxedDot(x1Hi, x1Lo, x2Hi, x2Lo uint64) (lo, hi uint64) {
+	for i := 0; i < 4; i++ {
+		shift := i * 16
+		lo |= uint64(uint16(relaxedDotPair(uint16(x1Lo>>shift), uint16(x2Lo>>shift)))) << shift
+		hi |= uint64(uint16(relaxedDotPair(uint16(x1Hi>>shift), uint16(x2Hi>>shift)))) << shift
+	}
+	return
+}
+
+// relaxedDotPair returns the sum of the products of the two signed i8 lanes in x1 and x2, saturated to 16-bit.
+func relaxedDotPair(x1, x2 uint16) int16 {
+	sum := int32(int8(x1))*int32(int8(x2)) + int32(int8(x1>>8))*int32(int8(x2>>8))
+	if sum > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	return int16(sum) // The sum is never less than math.MinInt16.
+}
+
+// v128RelaxedDotAdd adds the adjacent i16x8 lanes of the dot product to the i32x4 lanes of the addend.
+func v128RelaxedDotAdd(dotLo, dotHi, addLo, addHi uint64) (lo, hi uint64) {
+	for i := 0; i < 2; i++ {
+		shift := i * 32
+		lo |= uint64(uint32(int32(int16(dotLo>>shift))+int32(int16(dotLo>>(shift+16)))+int32(addLo>>shift))) << shift
+		hi |= uint64(uint32(int32(int16(dotHi>>shift))+int32(int16(dotHi>>(shift+16)))+int32(addHi>>shift))) << shift
+	}
+	return
+}
+
 func v128Dot(x1Hi, x1Lo, x2Hi, x2Lo uint64) (uint64, uint64) {
 	r1 := int32(int16(x1Lo>>0)) * int32(int16(x2Lo>>0))
 	r2 := int32(int16(x1Lo>>16)) * int32(int16(x2Lo>>16))
@@ -449,6 +449,64 @@ func (o operationKind) String() (ret string) {
 		ret = "operationKindTailCallReturnCall"
 	case operationKindTailCallReturnCallIndirect:
 		ret = "operationKindTailCallReturnCallIndirect"
+	case operationKindThrow:
+		ret = "operationKindThrow"
+	case operationKindThrowRef:
+		ret = "operationKindThrowRef"
+	case operationKindStructNew:
+		ret = "operationKindStructNew"
+	case operationKindStructGet:
+		ret = "operationKindStructGet"
+	case operationKindStructSet:
+		ret = "operationKindStructSet"
+	case operationKindArrayNew:
+		ret = "operationKindArrayNew"
+	case operationKindArrayGet:
+		ret = "operationKindArrayGet"
+	case operationKindArraySet:
+		ret = "operationKindArraySet"
+	case operationKindArrayLen:
+		ret = "operationKindArrayLen"
+	case operationKindArrayFill:
+		ret = "operationKindArrayFill"
+	case operationKindArrayCopy:
+		ret = "operationKindArrayCopy"
+	case operationKindArrayInit:
+		ret = "operationKindArrayInit"
+	case operationKindRefTest:
+		ret = "operationKindRefTest"
+	case operationKindRefCast:
+		ret = "operationKindRefCast"
+	case operationKindAnyConvertExtern:
+		ret = "operationKindAnyConvertExtern"
+	case operationKindExternConvertAny:
+		ret = "operationKindExternConvertAny"
+	case operationKindRefI31:
+		ret = "operationKindRefI31"
+	case operationKindI31Get:
+		ret = "operationKindI31Get"
+	case operationKindCallRef:
+		ret = "operationKindCallRef"
+	case operationKindTailCallReturnCallRef:
+		ret = "operationKindTailCallReturnCallRef"
+	case operationKindRefAsNonNull:
+		ret = "operationKindRefAsNonNull"
+	case operationKindV128RelaxedMadd:
+		ret = "V128RelaxedMadd"
+	case operationKindV128RelaxedDot:
+		ret = "V128RelaxedDot"
+	case operationKindI64Add128:
+		ret = "I64Add128"
+	case operationKindI64MulWide:
+		ret = "I64MulWide"
+	case operationKindContNew:
+		ret = "ContNew"
+	case operationKindContBind:
+		ret = "ContBind"
+	case operationKindSuspend:
+		ret = "Suspend"
+	case operationKindResume:
+		ret = "Resume"
 	default:
 		panic(fmt.Errorf("unknown operation %d", o))
 	}
@@ -777,6 +835,78 @@ const (
 	// operationKindTailCallReturnCallIndirect is the Kind for newOperationKindTailCallReturnCallIndirect.
 	operationKindTailCallReturnCallIndirect
 
+	// operationKindThrow is the Kind for newOperationThrow.
+	operationKindThrow
+	// operationKindThrowRef is the Kind for newOperationThrowRef.
+	operationKindThrowRef
+
+	// operationKindStructNew is the Kind for newOperationStructNew.
+	operationKindStructNew
+	// operationKindStructGet is the Kind for newOperationStructGet.
+	operationKindStructGet
+	// operationKindStructSet is the Kind for newOperationStructSet.
+	operationKindStructSet
+	// operationKindArrayNew is the Kind for newOperationArrayNew.
+	operationKindArrayNew
+	// operationKindArrayGet is the Kind for newOperationArrayGet.
+	operationKindArrayGet
+	// operationKindArraySet is the Kind for newOperationArraySet.
+	operationKindArraySet
+	// operationKindArrayLen is the Kind for newOperationArrayLen.
+	operationKindArrayLen
+	// operationKindArrayFill is the Kind for newOperationArrayFill.
+	operationKindArrayFill
+	// operationKindArrayCopy is the Kind for newOperationArrayCopy.
+	operationKindArrayCopy
+	// operationKindArrayInit is the Kind for newOperationArrayInit.
+	operationKindArrayInit
+	// operationKindRefTest is the Kind for newOperationRefTest.
+	operationKindRefTest
+	// operationKindRefCast is the Kind for newOperationRefCast.
+	operationKindRefCast
+	// operationKindAnyConvertExtern is the Kind for newOperationAnyConvertExtern.
+	operationKindAnyConvertExtern
+	// operationKindExternConvertAny is the Kind for newOperationExternConvertAny.
+	operationKindExternConvertAny
+	// operationKindRefI31 is the Kind for newOperationRefI31.
+	operationKindRefI31
+	// operationKindI31Get is the Kind for newOperationI31Get.
+	operationKindI31Get
+
+	// Below are toggled with experimental.CoreFeaturesFunctionReferences
+
+	// operationKindCallRef is the Kind for newOperationCallRef.
+	operationKindCallRef
+	// operationKindTailCallReturnCallRef is the Kind for newOperationTailCallReturnCallRef.
+	operationKindTailCallReturnCallRef
+	// operationKindRefAsNonNull is the Kind for newOperationRefAsNonNull.
+	operationKindRefAsNonNull
+
+	// Below are toggled with experimental.CoreFeaturesRelaxedSIMD
+
+	// operationKindV128RelaxedMadd is the Kind for newOperationV128RelaxedMadd.
+	operationKindV128RelaxedMadd
+	// operationKindV128RelaxedDot is the Kind for newOperationV128RelaxedDot.
+	operationKindV128RelaxedDot
+
+	// Below are toggled with experimental.CoreFeaturesWideArithmetic
+
+	// operationKindI64Add128 is the Kind for newOperationI64Add128.
+	operationKindI64Add128
+	// operationKindI64MulWide is the Kind for newOperationI64MulWide.
+	operationKindI64MulWide
+
+	// Below are toggled with experimental.CoreFeaturesStackSwitching
+
+	// operationKindContNew is the Kind for newOperationContNew.
+	operationKindContNew
+	// operationKindContBind is the Kind for newOperationContBind.
+	operationKindContBind
+	// operationKindSuspend is the Kind for newOperationSuspend.
+	operationKindSuspend
+	// operationKindResume is the Kind for newOperationResume.
+	operationKindResume
+
 	// operationKindEnd is always placed at the bottom of this iota definition to be used in the test.
 	operationKindEnd
 )
@@ -1112,6 +1242,68 @@ func (o unionOperation) String() string {
 	case operationKindTailCallReturnCallIndirect:
 		return fmt.Sprintf("%s %d %d", o.Kind, o.U1, o.U2)
 
+	case operationKindThrow:
+		return fmt.Sprintf("%s %d", o.Kind, o.U1)
+
+	case operationKindThrowRef:
+		return o.Kind.String()
+
+	case operationKindStructNew,
+		operationKindArrayGet,
+		operationKindArraySet,
+		operationKindArrayFill,
+		operationKindRefTest,
+		operationKindRefCast:
+		return fmt.Sprintf("%s %d", o.Kind, int64(o.U1))
+
+	case operationKindStructGet,
+		operationKindStructSet,
+		operationKindArrayNew,
+		operationKindArrayCopy,
+		operationKindArrayInit:
+		return fmt.Sprintf("%s %d %d", o.Kind, o.U1, o.U2)
+
+	case operationKindArrayLen,
+		operationKindAnyConvertExtern,
+		operationKindExternConvertAny,
+		operationKindRefI31,
+		operationKindI31Get,
+		operationKindRefAsNonNull:
+		return o.Kind.String()
+
+	case operationKindCallRef:
+		return fmt.Sprintf("%s: type=%d", o.Kind, o.U1)
+
+	case operationKindTailCallReturnCallRef:
+		return fmt.Sprintf("%s %d", o.Kind, o.U1)
+
+	case operationKindV128RelaxedMadd:
+		return fmt.Sprintf("%s.%s", o.Kind, shapeName(o.B1))
+
+	case operationKindV128RelaxedDot:
+		if o.B3 {
+			return fmt.Sprintf("%s.Add", o.Kind)
+		}
+		return o.Kind.String()
+
+	case operationKindI64Add128:
+		if o.B3 {
+			return fmt.Sprintf("%s.Sub", o.Kind)
+		}
+		return o.Kind.String()
+
+	case operationKindI64MulWide:
+		if o.B3 {
+			return fmt.Sprintf("%s.Signed", o.Kind)
+		}
+		return o.Kind.String()
+
+	case operationKindContNew, operationKindContBind, operationKindSuspend:
+		return fmt.Sprintf("%s %d", o.Kind, o.U1)
+
+	case operationKindResume:
+		return fmt.Sprintf("%s %d %v", o.Kind, o.U1, o.Us)
+
 	default:
 		panic(fmt.Sprintf("TODO: %v", o.Kind))
 	}
@@ -1293,8 +1485,11 @@ type memoryArg struct {
 	Alignment uint32
 
 	// Offset is the address offset added to the instruction's dynamic address operand, yielding a 33-bit effective
-	// address that is the zero-based index at which the memory is accessed. Default to zero.
-	Offset uint32
+	// address (65-bit with memory64) that is the zero-based index at which the memory is accessed. Default to zero.
+	Offset uint64
+
+	// MemoryIndex is the index of the accessed memory, which can only be non-zero with multi-memory. Default to zero.
+	MemoryIndex uint32
 }
 
 // NewOperationLoad is a constructor for unionOperation with operationKindLoad.
@@ -1304,7 +1499,7 @@ type memoryArg struct {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationLoad(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationLoad8 is a constructor for unionOperation with operationKindLoad8.
@@ -1314,7 +1509,7 @@ func newOperationLoad(unsignedType unsignedType, arg memoryArg) unionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationLoad8(signedInt signedInt, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindLoad8, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindLoad8, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationLoad16 is a constructor for unionOperation with operationKindLoad16.
@@ -1324,7 +1519,7 @@ func newOperationLoad8(signedInt signedInt, arg memoryArg) unionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationLoad16(signedInt signedInt, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindLoad16, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindLoad16, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationLoad32 is a constructor for unionOperation with operationKindLoad32.
@@ -1338,7 +1533,7 @@ func newOperationLoad32(signed bool, arg memoryArg) unionOperation {
 	if signed {
 		sigB = 1
 	}
-	return unionOperation{Kind: operationKindLoad32, B1: sigB, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindLoad32, B1: sigB, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore is a constructor for unionOperation with operationKindStore.
@@ -1348,7 +1543,7 @@ func newOperationLoad32(signed bool, arg memoryArg) unionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationStore(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore8 is a constructor for unionOperation with operationKindStore8.
@@ -1358,7 +1553,7 @@ func newOperationStore(unsignedType unsignedType, arg memoryArg) unionOperation
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationStore8(arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindStore8, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindStore8, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore16 is a constructor for unionOperation with operationKindStore16.
@@ -1368,7 +1563,7 @@ func newOperationStore8(arg memoryArg) unionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationStore16(arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindStore16, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindStore16, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore32 is a constructor for unionOperation with operationKindStore32.
@@ -1378,7 +1573,7 @@ func newOperationStore16(arg memoryArg) unionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func newOperationStore32(arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindStore32, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindStore32, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationMemorySize is a constructor for unionOperation with operationKindMemorySize.
@@ -1386,8 +1581,8 @@ func newOperationStore32(arg memoryArg) unionOperation {
 // This corresponds to wasm.OpcodeMemorySize.
 //
 // The engines are expected to push the current page size of the memory onto the stack.
-func newOperationMemorySize() unionOperation {
-	return unionOperation{Kind: operationKindMemorySize}
+func newOperationMemorySize(memoryIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindMemorySize, U3: uint64(memoryIndex)}
 }
 
 // NewOperationMemoryGrow is a constructor for unionOperation with operationKindMemoryGrow.
@@ -1397,8 +1592,8 @@ func newOperationMemorySize() unionOperation {
 // The engines are expected to pop one value from the top of the stack, then
 // execute wasm.MemoryInstance Grow with the value, and push the previous
 // page size of the memory onto the stack.
-func newOperationMemoryGrow() unionOperation {
-	return unionOperation{Kind: operationKindMemoryGrow}
+func newOperationMemoryGrow(memoryIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindMemoryGrow, U3: uint64(memoryIndex)}
 }
 
 // NewOperationConstI32 is a constructor for unionOperation with OperationConstI32.
@@ -1881,8 +2076,8 @@ func newOperationSignExtend64From32() unionOperation {
 //
 // dataIndex is the index of the data instance in ModuleInstance.DataInstances
 // by which this operation instantiates a part of the memory.
-func newOperationMemoryInit(dataIndex uint32) unionOperation {
-	return unionOperation{Kind: operationKindMemoryInit, U1: uint64(dataIndex)}
+func newOperationMemoryInit(dataIndex, memoryIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindMemoryInit, U1: uint64(dataIndex), U3: uint64(memoryIndex)}
 }
 
 // NewOperationDataDrop implements Operation.
@@ -1898,13 +2093,15 @@ func newOperationDataDrop(dataIndex uint32) unionOperation {
 // NewOperationMemoryCopy is a consuctor for unionOperation with operationKindMemoryCopy.
 //
 // This corresponds to wasm.OpcodeMemoryCopyName.
-func newOperationMemoryCopy() unionOperation {
-	return unionOperation{Kind: operationKindMemoryCopy}
+//
+// dstMemoryIndex and srcMemoryIndex are the indexes of the destination and source memories.
+func newOperationMemoryCopy(dstMemoryIndex, srcMemoryIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindMemoryCopy, U1: uint64(srcMemoryIndex), U3: uint64(dstMemoryIndex)}
 }
 
 // NewOperationMemoryFill is a consuctor for unionOperation with operationKindMemoryFill.
-func newOperationMemoryFill() unionOperation {
-	return unionOperation{Kind: operationKindMemoryFill}
+func newOperationMemoryFill(memoryIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindMemoryFill, U3: uint64(memoryIndex)}
 }
 
 // NewOperationTableInit is a constructor for unionOperation with operationKindTableInit.
@@ -2075,7 +2272,7 @@ const (
 //	wasm.OpcodeVecV128Load32SplatName wasm.OpcodeVecV128Load64SplatName wasm.OpcodeVecV128Load32zeroName
 //	wasm.OpcodeVecV128Load64zeroName
 func newOperationV128Load(loadType v128LoadType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindV128Load, B1: loadType, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindV128Load, B1: loadType, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationV128LoadLane is a constructor for unionOperation with operationKindV128LoadLane.
@@ -2087,7 +2284,7 @@ func newOperationV128Load(loadType v128LoadType, arg memoryArg) unionOperation {
 // laneIndex is >=0 && <(128/LaneSize).
 // laneSize is either 8, 16, 32, or 64.
 func newOperationV128LoadLane(laneIndex, laneSize byte, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindV128LoadLane, B1: laneSize, B2: laneIndex, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindV128LoadLane, B1: laneSize, B2: laneIndex, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationV128Store is a constructor for unionOperation with operationKindV128Store.
@@ -2100,6 +2297,7 @@ func newOperationV128Store(arg memoryArg) unionOperation {
 		Kind: operationKindV128Store,
 		U1:   uint64(arg.Alignment),
 		U2:   uint64(arg.Offset),
+		U3:   uint64(arg.MemoryIndex),
 	}
 }
 
@@ -2118,6 +2316,7 @@ func newOperationV128StoreLane(laneIndex byte, laneSize byte, arg memoryArg) uni
 		B2:   laneIndex,
 		U1:   uint64(arg.Alignment),
 		U2:   uint64(arg.Offset),
+		U3:   uint64(arg.MemoryIndex),
 	}
 }
 
@@ -2685,7 +2884,7 @@ const (
 //
 //	wasm.OpcodeAtomicWait32Name wasm.OpcodeAtomicWait64Name
 func newOperationAtomicMemoryWait(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicMemoryWait, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicMemoryWait, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicMemoryNotify is a constructor for unionOperation with operationKindAtomicMemoryNotify.
@@ -2694,7 +2893,7 @@ func newOperationAtomicMemoryWait(unsignedType unsignedType, arg memoryArg) unio
 //
 //	wasm.OpcodeAtomicNotifyName
 func newOperationAtomicMemoryNotify(arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicMemoryNotify, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicMemoryNotify, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicFence is a constructor for unionOperation with operationKindAtomicFence.
@@ -2712,7 +2911,7 @@ func newOperationAtomicFence() unionOperation {
 //
 //	wasm.OpcodeAtomicI32LoadName wasm.OpcodeAtomicI64LoadName
 func newOperationAtomicLoad(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicLoad8 is a constructor for unionOperation with operationKindAtomicLoad8.
@@ -2721,7 +2920,7 @@ func newOperationAtomicLoad(unsignedType unsignedType, arg memoryArg) unionOpera
 //
 //	wasm.OpcodeAtomicI32Load8UName wasm.OpcodeAtomicI64Load8UName
 func newOperationAtomicLoad8(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicLoad8, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicLoad8, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicLoad16 is a constructor for unionOperation with operationKindAtomicLoad16.
@@ -2730,7 +2929,7 @@ func newOperationAtomicLoad8(unsignedType unsignedType, arg memoryArg) unionOper
 //
 //	wasm.OpcodeAtomicI32Load16UName wasm.OpcodeAtomicI64Load16UName
 func newOperationAtomicLoad16(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicLoad16, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicLoad16, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicStore is a constructor for unionOperation with operationKindAtomicStore.
@@ -2739,7 +2938,7 @@ func newOperationAtomicLoad16(unsignedType unsignedType, arg memoryArg) unionOpe
 //
 //	wasm.OpcodeAtomicI32StoreName wasm.OpcodeAtomicI64StoreName
 func newOperationAtomicStore(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicStore8 is a constructor for unionOperation with operationKindAtomicStore8.
@@ -2748,7 +2947,7 @@ func newOperationAtomicStore(unsignedType unsignedType, arg memoryArg) unionOper
 //
 //	wasm.OpcodeAtomicI32Store8UName wasm.OpcodeAtomicI64Store8UName
 func newOperationAtomicStore8(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicStore8, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicStore8, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicStore16 is a constructor for unionOperation with operationKindAtomicStore16.
@@ -2757,7 +2956,7 @@ func newOperationAtomicStore8(unsignedType unsignedType, arg memoryArg) unionOpe
 //
 //	wasm.OpcodeAtomicI32Store16UName wasm.OpcodeAtomicI64Store16UName
 func newOperationAtomicStore16(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicStore16, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicStore16, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicRMW is a constructor for unionOperation with operationKindAtomicRMW.
@@ -2770,7 +2969,7 @@ func newOperationAtomicStore16(unsignedType unsignedType, arg memoryArg) unionOp
 //	wasm.OpcodeAtomicI32RMWOrName wasm.OpcodeAtomicI64RmwOrName
 //	wasm.OpcodeAtomicI32RMWXorName wasm.OpcodeAtomicI64RmwXorName
 func newOperationAtomicRMW(unsignedType unsignedType, arg memoryArg, op atomicArithmeticOp) unionOperation {
-	return unionOperation{Kind: operationKindAtomicRMW, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicRMW, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicRMW8 is a constructor for unionOperation with operationKindAtomicRMW8.
@@ -2783,7 +2982,7 @@ func newOperationAtomicRMW(unsignedType unsignedType, arg memoryArg, op atomicAr
 //	wasm.OpcodeAtomicI32RMW8OrUName wasm.OpcodeAtomicI64Rmw8OrUName
 //	wasm.OpcodeAtomicI32RMW8XorUName wasm.OpcodeAtomicI64Rmw8XorUName
 func newOperationAtomicRMW8(unsignedType unsignedType, arg memoryArg, op atomicArithmeticOp) unionOperation {
-	return unionOperation{Kind: operationKindAtomicRMW8, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicRMW8, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicRMW16 is a constructor for unionOperation with operationKindAtomicRMW16.
@@ -2796,7 +2995,7 @@ func newOperationAtomicRMW8(unsignedType unsignedType, arg memoryArg, op atomicA
 //	wasm.OpcodeAtomicI32RMW16OrUName wasm.OpcodeAtomicI64Rmw16OrUName
 //	wasm.OpcodeAtomicI32RMW16XorUName wasm.OpcodeAtomicI64Rmw16XorUName
 func newOperationAtomicRMW16(unsignedType unsignedType, arg memoryArg, op atomicArithmeticOp) unionOperation {
-	return unionOperation{Kind: operationKindAtomicRMW16, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicRMW16, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicRMWCmpxchg is a constructor for unionOperation with operationKindAtomicRMWCmpxchg.
@@ -2805,7 +3004,7 @@ func newOperationAtomicRMW16(unsignedType unsignedType, arg memoryArg, op atomic
 //
 //	wasm.OpcodeAtomicI32RMWCmpxchgName wasm.OpcodeAtomicI64RmwCmpxchgName
 func newOperationAtomicRMWCmpxchg(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicRMWCmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicRMWCmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicRMW8Cmpxchg is a constructor for unionOperation with operationKindAtomicRMW8Cmpxchg.
@@ -2814,7 +3013,7 @@ func newOperationAtomicRMWCmpxchg(unsignedType unsignedType, arg memoryArg) unio
 //
 //	wasm.OpcodeAtomicI32RMW8CmpxchgUName wasm.OpcodeAtomicI64Rmw8CmpxchgUName
 func newOperationAtomicRMW8Cmpxchg(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicRMW8Cmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicRMW8Cmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationAtomicRMW16Cmpxchg is a constructor for unionOperation with operationKindAtomicRMW16Cmpxchg.
@@ -2823,7 +3022,7 @@ func newOperationAtomicRMW8Cmpxchg(unsignedType unsignedType, arg memoryArg) uni
 //
 //	wasm.OpcodeAtomicI32RMW16CmpxchgUName wasm.OpcodeAtomicI64Rmw16CmpxchgUName
 func newOperationAtomicRMW16Cmpxchg(unsignedType unsignedType, arg memoryArg) unionOperation {
-	return unionOperation{Kind: operationKindAtomicRMW16Cmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return unionOperation{Kind: operationKindAtomicRMW16Cmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // newOperationTailCallReturnCall is a constructor for unionOperation with operationKindTailCallReturnCall.
@@ -2843,3 +3042,359 @@ func newOperationTailCallReturnCall(functionIndex uint32) unionOperation {
 func newOperationTailCallReturnCallIndirect(typeIndex, tableIndex uint32, dropDepth inclusiveRange, l label) unionOperation {
 	return unionOperation{Kind: operationKindTailCallReturnCallIndirect, U1: uint64(typeIndex), U2: uint64(tableIndex), Us: []uint64{dropDepth.AsU64(), uint64(l)}}
 }
+
+// newOperationThrow is a constructor for unionOperation with operationKindThrow.
+//
+// This corresponds to
+//
+//	wasm.OpcodeExceptionHandlingThrow.
+//
+// The engines are expected to pop the payload of the tag given as the tag index from the stack,
+// and transfer the control to the innermost matching exception handler.
+func newOperationThrow(tagIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindThrow, U1: uint64(tagIndex)}
+}
+
+// newOperationThrowRef is a constructor for unionOperation with operationKindThrowRef.
+//
+// This corresponds to
+//
+//	wasm.OpcodeExceptionHandlingThrowRef.
+//
+// The engines are expected to pop an exnref from the stack, and re-throw the exception it refers to,
+// or exit the execution with wasmruntime.ErrRuntimeNullReference if it is null.
+func newOperationThrowRef() unionOperation {
+	return unionOperation{Kind: operationKindThrowRef}
+}
+
+// arrayNewKind is the variant of operationKindArrayNew.
+type arrayNewKind = byte
+
+const (
+	// arrayNewKindValue corresponds to wasm.OpcodeGCArrayNew.
+	arrayNewKindValue arrayNewKind = iota
+	// arrayNewKindDefault corresponds to wasm.OpcodeGCArrayNewDefault.
+	arrayNewKindDefault
+	// arrayNewKindFixed corresponds to wasm.OpcodeGCArrayNewFixed.
+	arrayNewKindFixed
+	// arrayNewKindData corresponds to wasm.OpcodeGCArrayNewData.
+	arrayNewKindData
+	// arrayNewKindElem corresponds to wasm.OpcodeGCArrayNewElem.
+	arrayNewKindElem
+)
+
+// newOperationStructNew is a constructor for unionOperation with operationKindStructNew.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCStructNew wasm.OpcodeGCStructNewDefault
+//
+// The engines are expected to pop the values of the fields unless isDefault is true, and push the reference to the
+// struct of the type newly allocated on the wasm.GCHeap.
+func newOperationStructNew(typeIndex uint32, isDefault bool) unionOperation {
+	return unionOperation{Kind: operationKindStructNew, U1: uint64(typeIndex), B3: isDefault}
+}
+
+// newOperationStructGet is a constructor for unionOperation with operationKindStructGet.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCStructGet wasm.OpcodeGCStructGetS wasm.OpcodeGCStructGetU
+//
+// The engines are expected to pop the reference to a struct, and push the value of the field, which is
+// sign-extended if signed is true. This exits the execution with wasmruntime.ErrRuntimeNullReference if the
+// reference is null.
+func newOperationStructGet(typeIndex, fieldIndex uint32, signed bool) unionOperation {
+	return unionOperation{Kind: operationKindStructGet, U1: uint64(typeIndex), U2: uint64(fieldIndex), B3: signed}
+}
+
+// newOperationStructSet is a constructor for unionOperation with operationKindStructSet.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCStructSet
+//
+// The engines are expected to pop the value and the reference to a struct, and set the value to the field.
+// This exits the execution with wasmruntime.ErrRuntimeNullReference if the reference is null.
+func newOperationStructSet(typeIndex, fieldIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindStructSet, U1: uint64(typeIndex), U2: uint64(fieldIndex)}
+}
+
+// newOperationArrayNew is a constructor for unionOperation with operationKindArrayNew.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArrayNew wasm.OpcodeGCArrayNewDefault wasm.OpcodeGCArrayNewFixed
+//	wasm.OpcodeGCArrayNewData wasm.OpcodeGCArrayNewElem
+//
+// The engines are expected to pop the operands as per kind, and push the reference to the array of the type newly
+// allocated on the wasm.GCHeap. operand is the number of the elements for arrayNewKindFixed, or the index of the
+// data or element segment for arrayNewKindData and arrayNewKindElem.
+func newOperationArrayNew(typeIndex uint32, kind arrayNewKind, operand uint32) unionOperation {
+	return unionOperation{Kind: operationKindArrayNew, U1: uint64(typeIndex), B1: kind, U2: uint64(operand)}
+}
+
+// newOperationArrayGet is a constructor for unionOperation with operationKindArrayGet.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArrayGet wasm.OpcodeGCArrayGetS wasm.OpcodeGCArrayGetU
+//
+// The engines are expected to pop the index and the reference to an array, and push the element, which is
+// sign-extended if signed is true. This exits the execution with wasmruntime.ErrRuntimeNullReference if the
+// reference is null, or wasmruntime.ErrRuntimeArrayOutOfBounds if the index is out of bounds.
+func newOperationArrayGet(typeIndex uint32, signed bool) unionOperation {
+	return unionOperation{Kind: operationKindArrayGet, U1: uint64(typeIndex), B3: signed}
+}
+
+// newOperationArraySet is a constructor for unionOperation with operationKindArraySet.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArraySet
+//
+// The engines are expected to pop the value, the index and the reference to an array, and set the element.
+func newOperationArraySet(typeIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindArraySet, U1: uint64(typeIndex)}
+}
+
+// newOperationArrayLen is a constructor for unionOperation with operationKindArrayLen.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArrayLen
+//
+// The engines are expected to pop the reference to an array, and push its length as i32.
+func newOperationArrayLen() unionOperation {
+	return unionOperation{Kind: operationKindArrayLen}
+}
+
+// newOperationArrayFill is a constructor for unionOperation with operationKindArrayFill.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArrayFill
+//
+// The engines are expected to pop the size, the value, the offset and the reference to an array, and fill the
+// elements in the range with the value.
+func newOperationArrayFill(typeIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindArrayFill, U1: uint64(typeIndex)}
+}
+
+// newOperationArrayCopy is a constructor for unionOperation with operationKindArrayCopy.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArrayCopy
+//
+// The engines are expected to pop the size, the source offset, the source array, the destination offset and the
+// destination array, and copy the elements in the range, which may overlap.
+func newOperationArrayCopy(dstTypeIndex, srcTypeIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindArrayCopy, U1: uint64(dstTypeIndex), U2: uint64(srcTypeIndex)}
+}
+
+// newOperationArrayInit is a constructor for unionOperation with operationKindArrayInit.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCArrayInitData wasm.OpcodeGCArrayInitElem
+//
+// The engines are expected to pop the size, the source offset, the destination offset and the reference to an
+// array, and copy the elements from the data segment, or the element segment if isElem is true.
+func newOperationArrayInit(typeIndex, segmentIndex uint32, isElem bool) unionOperation {
+	return unionOperation{Kind: operationKindArrayInit, U1: uint64(typeIndex), U2: uint64(segmentIndex), B3: isElem}
+}
+
+// newOperationRefTest is a constructor for unionOperation with operationKindRefTest.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCRefTest wasm.OpcodeGCRefTestNull
+//
+// The engines are expected to pop a reference, and push 1 as i32 if it is of the wasm.HeapType ht, or null if nullable
+// is true. Otherwise, 0 is pushed.
+func newOperationRefTest(ht int64, nullable bool) unionOperation {
+	return unionOperation{Kind: operationKindRefTest, U1: uint64(ht), B3: nullable}
+}
+
+// newOperationRefCast is a constructor for unionOperation with operationKindRefCast.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCRefCast wasm.OpcodeGCRefCastNull
+//
+// The engines are expected to exit the execution with wasmruntime.ErrRuntimeCastFailure unless the reference on
+// top of the stack is of the wasm.HeapType ht, or null if nullable is true.
+func newOperationRefCast(ht int64, nullable bool) unionOperation {
+	return unionOperation{Kind: operationKindRefCast, U1: uint64(ht), B3: nullable}
+}
+
+// newOperationAnyConvertExtern is a constructor for unionOperation with operationKindAnyConvertExtern.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCAnyConvertExtern
+//
+// The engines are expected to convert the externref on top of the stack into an anyref.
+func newOperationAnyConvertExtern() unionOperation {
+	return unionOperation{Kind: operationKindAnyConvertExtern}
+}
+
+// newOperationExternConvertAny is a constructor for unionOperation with operationKindExternConvertAny.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCExternConvertAny
+//
+// The engines are expected to convert the anyref on top of the stack into an externref.
+func newOperationExternConvertAny() unionOperation {
+	return unionOperation{Kind: operationKindExternConvertAny}
+}
+
+// newOperationRefI31 is a constructor for unionOperation with operationKindRefI31.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCRefI31
+//
+// The engines are expected to convert the i32 on top of the stack into an i31ref.
+func newOperationRefI31() unionOperation {
+	return unionOperation{Kind: operationKindRefI31}
+}
+
+// newOperationI31Get is a constructor for unionOperation with operationKindI31Get.
+//
+// This corresponds to
+//
+//	wasm.OpcodeGCI31GetS wasm.OpcodeGCI31GetU
+//
+// The engines are expected to convert the i31ref on top of the stack into an i32, which is sign-extended if signed
+// is true. This exits the execution with wasmruntime.ErrRuntimeNullReference if the reference is null.
+func newOperationI31Get(signed bool) unionOperation {
+	return unionOperation{Kind: operationKindI31Get, B3: signed}
+}
+
+// newOperationCallRef is a constructor for unionOperation with operationKindCallRef.
+//
+// This corresponds to
+//
+//	wasm.OpcodeFunctionReferencesCallRef.
+//
+// The engines are expected to call the function referenced by the funcref on top of the stack, the same way as
+// operationKindCallIndirect without the table access. This exits the execution with
+// wasmruntime.ErrRuntimeNullReference if the reference is null, or with
+// wasmruntime.ErrRuntimeIndirectCallTypeMismatch if the type of the function doesn't match typeIndex.
+func newOperationCallRef(typeIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindCallRef, U1: uint64(typeIndex)}
+}
+
+// newOperationTailCallReturnCallRef is a constructor for unionOperation with operationKindTailCallReturnCallRef.
+//
+// This corresponds to
+//
+//	wasm.OpcodeFunctionReferencesReturnCallRef.
+//
+// This is the same as operationKindTailCallReturnCallIndirect, except that the function is referenced by the
+// funcref on top of the stack as in operationKindCallRef.
+func newOperationTailCallReturnCallRef(typeIndex uint32, dropDepth inclusiveRange, l label) unionOperation {
+	return unionOperation{Kind: operationKindTailCallReturnCallRef, U1: uint64(typeIndex), Us: []uint64{dropDepth.AsU64(), uint64(l)}}
+}
+
+// newOperationRefAsNonNull is a constructor for unionOperation with operationKindRefAsNonNull.
+//
+// This corresponds to
+//
+//	wasm.OpcodeFunctionReferencesRefAsNonNull.
+//
+// The engines are expected to exit the execution with wasmruntime.ErrRuntimeNullReference if the reference on top
+// of the stack is null, and otherwise leave it as is.
+func newOperationRefAsNonNull() unionOperation {
+	return unionOperation{Kind: operationKindRefAsNonNull}
+}
+
+// newOperationV128RelaxedMadd is a constructor for unionOperation with operationKindV128RelaxedMadd.
+//
+// This corresponds to
+//
+//	wasm.OpcodeVecF32x4RelaxedMaddName wasm.OpcodeVecF32x4RelaxedNmaddName
+//	wasm.OpcodeVecF64x2RelaxedMaddName wasm.OpcodeVecF64x2RelaxedNmaddName.
+//
+// shape is either shapeF32x4 or shapeF64x2, and negate is true for the "nmadd" variants. The product is rounded
+// before the addition so that the result is the same on all platforms.
+func newOperationV128RelaxedMadd(shape shape, negate bool) unionOperation {
+	return unionOperation{Kind: operationKindV128RelaxedMadd, B1: shape, B3: negate}
+}
+
+// newOperationV128RelaxedDot is a constructor for unionOperation with operationKindV128RelaxedDot.
+//
+// This corresponds to
+//
+//	wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16SName wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName.
+//
+// Both operands are treated as signed, and the sums of the adjacent products are saturated to 16-bit. If add is
+// true, the adjacent 16-bit sums are added again to the 32-bit lanes of the third operand.
+func newOperationV128RelaxedDot(add bool) unionOperation {
+	return unionOperation{Kind: operationKindV128RelaxedDot, B3: add}
+}
+
+// newOperationI64Add128 is a constructor for unionOperation with operationKindI64Add128.
+//
+// This corresponds to wasm.OpcodeI64Add128Name wasm.OpcodeI64Sub128Name.
+//
+// The operands are two 128-bit integers, each of which is pushed as the low and then high i64 halves, and the
+// result is pushed in the same way. sub is true for i64.sub128.
+func newOperationI64Add128(sub bool) unionOperation {
+	return unionOperation{Kind: operationKindI64Add128, B3: sub}
+}
+
+// newOperationI64MulWide is a constructor for unionOperation with operationKindI64MulWide.
+//
+// This corresponds to wasm.OpcodeI64MulWideSName wasm.OpcodeI64MulWideUName.
+//
+// The 128-bit product of the two i64 operands is pushed as the low and then high i64 halves. signed is true for
+// i64.mul_wide_s.
+func newOperationI64MulWide(signed bool) unionOperation {
+	return unionOperation{Kind: operationKindI64MulWide, B3: signed}
+}
+
+// newOperationContNew is a constructor for unionOperation with operationKindContNew.
+//
+// This corresponds to wasm.OpcodeStackSwitchingContNewName.
+//
+// The engines are expected to pop the function reference, which must be of the function type given as typeIndex,
+// and push the contref to a new continuation of it.
+func newOperationContNew(typeIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindContNew, U1: uint64(typeIndex)}
+}
+
+// newOperationContBind is a constructor for unionOperation with operationKindContBind.
+//
+// This corresponds to wasm.OpcodeStackSwitchingContBindName.
+//
+// The engines are expected to pop the contref, and then the values of the bound params whose size is
+// boundNumInUint64, and push the contref to the continuation with them bound.
+func newOperationContBind(boundNumInUint64 int) unionOperation {
+	return unionOperation{Kind: operationKindContBind, U1: uint64(boundNumInUint64)}
+}
+
+// newOperationSuspend is a constructor for unionOperation with operationKindSuspend.
+//
+// This corresponds to wasm.OpcodeStackSwitchingSuspendName.
+//
+// The engines are expected to pop the payload of the tag given as the tag index, suspend the current continuation,
+// and push the results of the tag once it is resumed.
+func newOperationSuspend(tagIndex uint32) unionOperation {
+	return unionOperation{Kind: operationKindSuspend, U1: uint64(tagIndex)}
+}
+
+// newOperationResume is a constructor for unionOperation with operationKindResume.
+//
+// This corresponds to wasm.OpcodeStackSwitchingResumeName.
+//
+// The engines are expected to pop the contref, and then the params whose size is paramNumInUint64, and resume the
+// continuation with them. If it returns, its results are pushed. If it suspends with the tag of one of the handlers,
+// which are the triplets of the tag index, the label and the stack height of the label in handlers, the stack is
+// unwound to the height, and the payload and the contref to the rest of the continuation are passed to the label.
+func newOperationResume(paramNumInUint64 int, handlers []uint64) unionOperation {
+	return unionOperation{Kind: operationKindResume, U1: uint64(paramNumInUint64), Us: handlers}
+}
@@ -140,6 +140,14 @@ var (
 		in:  []unsignedType{unsignedTypeI64, unsignedTypeI64},
 		out: []unsignedType{unsignedTypeI64},
 	}
+	signature_I64I64_I64I64 = &signature{
+		in:  []unsignedType{unsignedTypeI64, unsignedTypeI64},
+		out: []unsignedType{unsignedTypeI64, unsignedTypeI64},
+	}
+	signature_I64I64I64I64_I64I64 = &signature{
+		in:  []unsignedType{unsignedTypeI64, unsignedTypeI64, unsignedTypeI64, unsignedTypeI64},
+		out: []unsignedType{unsignedTypeI64, unsignedTypeI64},
+	}
 	signature_F32F32_I32 = &signature{
 		in:  []unsignedType{unsignedTypeF32, unsignedTypeF32},
 		out: []unsignedType{unsignedTypeI32},
@@ -262,7 +270,7 @@ var (
 // The returned signature is used for stack validation when lowering Wasm's opcodes to interpreterir.
 func (c *compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature, error) {
 	switch op {
-	case wasm.OpcodeUnreachable, wasm.OpcodeNop, wasm.OpcodeBlock, wasm.OpcodeLoop:
+	case wasm.OpcodeUnreachable, wasm.OpcodeNop, wasm.OpcodeBlock, wasm.OpcodeLoop, wasm.OpcodeExceptionHandlingTryTable:
 		return signature_None_None, nil
 	case wasm.OpcodeIf:
 		return signature_I32_None, nil
@@ -276,6 +284,15 @@ func (c *compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		return c.funcTypeToSigs.get(c.funcs[index], false /* direct */), nil
 	case wasm.OpcodeCallIndirect, wasm.OpcodeTailCallReturnCallIndirect:
 		return c.funcTypeToSigs.get(index, true /* call_indirect */), nil
+	case wasm.OpcodeFunctionReferencesCallRef, wasm.OpcodeFunctionReferencesReturnCallRef:
+		return c.funcTypeToSigs.getRef(index), nil
+	case wasm.OpcodeFunctionReferencesRefAsNonNull, wasm.OpcodeFunctionReferencesBrOnNull, wasm.OpcodeFunctionReferencesBrOnNonNull:
+		// br_on_null and br_on_non_null leave the reference on the stack, which is dropped when it is not needed.
+		return signature_I64_I64, nil
+	case wasm.OpcodeExceptionHandlingThrow:
+		return c.funcTypeToSigs.get(c.tags[index], false /* direct */), nil
+	case wasm.OpcodeExceptionHandlingThrowRef:
+		return signature_I64_None, nil
 	case wasm.OpcodeDrop:
 		return signature_Unknown_None, nil
 	case wasm.OpcodeSelect, wasm.OpcodeTypedSelect:
@@ -472,6 +489,17 @@ func (c *compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 	case wasm.OpcodeRefNull:
 		// ref.null is translated as i64.const 0.
 		return signature_None_I64, nil
+	case wasm.OpcodeRefEq:
+		// ref.eq is translated as i64.eq as the same references are encoded as the same uint64.
+		return signature_I64I64_I32, nil
+	case wasm.OpcodeGCPrefix:
+		// The signatures of GC instructions depend on their immediates, so the stack is manipulated
+		// while handling each of them.
+		return signature_None_None, nil
+	case wasm.OpcodeStackSwitchingContNew, wasm.OpcodeStackSwitchingContBind, wasm.OpcodeStackSwitchingSuspend,
+		wasm.OpcodeStackSwitchingResume:
+		// The same as GC instructions, the signatures depend on the immediates.
+		return signature_None_None, nil
 	case wasm.OpcodeMiscPrefix:
 		switch miscOp := c.body[c.pc+1]; miscOp {
 		case wasm.OpcodeMiscI32TruncSatF32S, wasm.OpcodeMiscI32TruncSatF32U:
@@ -493,10 +521,29 @@ func (c *compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 			return signature_None_I32, nil
 		case wasm.OpcodeMiscTableFill:
 			return signature_I32I64I32_None, nil
+		case wasm.OpcodeMiscI64Add128, wasm.OpcodeMiscI64Sub128:
+			return signature_I64I64I64I64_I64I64, nil
+		case wasm.OpcodeMiscI64MulWideS, wasm.OpcodeMiscI64MulWideU:
+			return signature_I64I64_I64I64, nil
 		default:
 			return nil, fmt.Errorf("unsupported misc instruction in interpreterir: 0x%x", op)
 		}
 	case wasm.OpcodeVecPrefix:
+		if wasm.IsVecRelaxed(c.body, c.pc+1) {
+			switch c.body[c.pc+1] {
+			case wasm.OpcodeVecI32x4RelaxedTruncF32x4S, wasm.OpcodeVecI32x4RelaxedTruncF32x4U,
+				wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+				return signature_V128_V128, nil
+			case wasm.OpcodeVecF32x4RelaxedMadd, wasm.OpcodeVecF32x4RelaxedNmadd,
+				wasm.OpcodeVecF64x2RelaxedMadd, wasm.OpcodeVecF64x2RelaxedNmadd,
+				wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+				wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect,
+				wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+				return signature_V128V128V128_V32, nil
+			default:
+				return signature_V128V128_V128, nil
+			}
+		}
 		switch vecOp := c.body[c.pc+1]; vecOp {
 		case wasm.OpcodeVecV128Const:
 			return signature_None_V128, nil
@@ -650,6 +697,7 @@ func (c *compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 type funcTypeToIRSignatures struct {
 	directCalls   []*signature
 	indirectCalls []*signature
+	refCalls      []*signature
 	wasmTypes     []wasm.FunctionType
 }
 
@@ -694,13 +742,30 @@ func (f *funcTypeToIRSignatures) get(typeIndex wasm.Index, indirect bool) *signa
 	return sig
 }
 
+// getRef returns the *signature for call_ref against functions whose type is at `typeIndex`, where the function
+// reference is the last input.
+func (f *funcTypeToIRSignatures) getRef(typeIndex wasm.Index) *signature {
+	if sig := f.refCalls[typeIndex]; sig != nil {
+		return sig
+	}
+	direct := f.get(typeIndex, false)
+	sig := &signature{
+		in:  append(make([]unsignedType, 0, len(direct.in)+1), direct.in...),
+		out: direct.out,
+	}
+	sig.in = append(sig.in, unsignedTypeI64)
+	f.refCalls[typeIndex] = sig
+	return sig
+}
+
 func wasmValueTypeTounsignedType(vt wasm.ValueType) unsignedType {
 	switch vt {
 	case wasm.ValueTypeI32:
 		return unsignedTypeI32
 	case wasm.ValueTypeI64,
 		// From interpreterir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeAnyref,
+		wasm.ValueTypeContref:
 		return unsignedTypeI64
 	case wasm.ValueTypeF32:
 		return unsignedTypeF32
@@ -718,7 +783,8 @@ func wasmValueTypeToUnsignedOutSignature(vt wasm.ValueType) *signature {
 		return signature_None_I32
 	case wasm.ValueTypeI64,
 		// From interpreterir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeAnyref,
+		wasm.ValueTypeContref:
 		return signature_None_I64
 	case wasm.ValueTypeF32:
 		return signature_None_F32
@@ -736,7 +802,8 @@ func wasmValueTypeToUnsignedInSignature(vt wasm.ValueType) *signature {
 		return signature_I32_None
 	case wasm.ValueTypeI64,
 		// From interpreterir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeAnyref,
+		wasm.ValueTypeContref:
 		return signature_I64_None
 	case wasm.ValueTypeF32:
 		return signature_F32_None
@@ -754,7 +821,8 @@ func wasmValueTypeToUnsignedInOutSignature(vt wasm.ValueType) *signature {
 		return signature_I32_I32
 	case wasm.ValueTypeI64,
 		// At interpreterir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeAnyref,
+		wasm.ValueTypeContref:
 		return signature_I64_I64
 	case wasm.ValueTypeF32:
 		return signature_F32_F32
@@ -258,6 +258,13 @@ func (i *instruction) String() string {
 		return fmt.Sprintf("xmmcmov%s %s, %s", cond(i.u1), i.op1.format(true), i.op2.format(true))
 	case blendvpd:
 		return fmt.Sprintf("blendvpd %s, %s, %%xmm0", i.op1.format(false), i.op2.format(false))
+	case vfmadd231:
+		suffix := "ps"
+		if i.b1 {
+			suffix = "pd"
+		}
+		return fmt.Sprintf("vfmadd231%s %s, %s, %s", suffix, i.op1.format(false),
+			formatVRegSized(regalloc.VReg(i.u1), false), i.op2.format(false))
 	case mfence:
 		return "mfence"
 	case lockcmpxchg:
@@ -322,6 +329,8 @@ func (i *instruction) Defs(regs *[]regalloc.VReg) []regalloc.VReg {
 		} else {
 			*regs = append(*regs, rdxVReg)
 		}
+	case defKindRaxRdx:
+		*regs = append(*regs, raxVReg, rdxVReg)
 	default:
 		panic(fmt.Sprintf("BUG: invalid defKind \"%s\" for %s", dk, i))
 	}
@@ -393,6 +402,16 @@ func (i *instruction) Uses(regs *[]regalloc.VReg) []regalloc.VReg {
 		execCtx, divisor, tmpGp, _, _, _ := i.idivRemSequenceData()
 		// idiv uses rax and rdx as implicit operands.
 		*regs = append(*regs, raxVReg, rdxVReg, execCtx, divisor, tmpGp)
+	case useKindRaxOp1:
+		*regs = append(*regs, raxVReg)
+		switch op := i.op1; op.kind {
+		case operandKindReg:
+			*regs = append(*regs, op.reg())
+		case operandKindMem:
+			op.addressMode().uses(regs)
+		default:
+			panic(fmt.Sprintf("BUG: invalid operand: %s", i))
+		}
 	case useKindBlendvpd:
 		*regs = append(*regs, xmm0VReg)
 
@@ -410,6 +429,9 @@ func (i *instruction) Uses(regs *[]regalloc.VReg) []regalloc.VReg {
 		}
 		*regs = append(*regs, opReg.reg())
 
+	case useKindVfmadd231:
+		*regs = append(*regs, regalloc.VReg(i.u1), i.op1.reg(), i.op2.reg())
+
 	case useKindRaxOp1RegOp2:
 		opReg, opAny := &i.op1, &i.op2
 		*regs = append(*regs, raxVReg, opReg.reg())
@@ -528,6 +550,24 @@ func (i *instruction) AssignUse(index int, v regalloc.VReg) {
 		default:
 			panic("BUG")
 		}
+	case useKindRaxOp1:
+		if index == 0 {
+			if v != raxVReg {
+				panic("BUG")
+			}
+			return
+		}
+		switch op := &i.op1; op.kind {
+		case operandKindReg:
+			if index != 1 {
+				panic("BUG")
+			}
+			op.setReg(v)
+		case operandKindMem:
+			op.addressMode().assignUses(index-1, v)
+		default:
+			panic(fmt.Sprintf("BUG: invalid operand: %s", i))
+		}
 	case useKindDivRem:
 		switch index {
 		case 0:
@@ -579,6 +619,18 @@ func (i *instruction) AssignUse(index int, v regalloc.VReg) {
 			}
 		}
 
+	case useKindVfmadd231:
+		switch index {
+		case 0:
+			i.u1 = uint64(v)
+		case 1:
+			i.op1.setReg(v)
+		case 2:
+			i.op2.setReg(v)
+		default:
+			panic("BUG")
+		}
+
 	case useKindRaxOp1RegOp2:
 		switch index {
 		case 0:
@@ -854,6 +906,11 @@ const (
 	// tailCallIndirect is a meta instruction that emits a tail call with an indirect call.
 	tailCallIndirect
 
+	// vfmadd231 is https://www.felixcloutier.com/x86/vfmadd132ps:vfmadd213ps:vfmadd231ps, or its pd variant if b1
+	// is true, which computes op2 = u1 * op1 + op2 where u1 is the VReg of the multiplicand.
+	// This requires the CPU to support FMA3, and uses the VEX encoding.
+	vfmadd231
+
 	instrMax
 )
 
@@ -1099,6 +1156,8 @@ func (k instructionKind) String() string {
 		return "tailCall"
 	case tailCallIndirect:
 		return "tailCallIndirect"
+	case vfmadd231:
+		return "vfmadd231"
 	default:
 		panic("BUG")
 	}
@@ -1113,6 +1172,8 @@ const (
 	aluRmiROpcodeOr
 	aluRmiROpcodeXor
 	aluRmiROpcodeMul
+	aluRmiROpcodeAdc
+	aluRmiROpcodeSbb
 )
 
 func (a aluRmiROpcode) String() string {
@@ -1129,6 +1190,10 @@ func (a aluRmiROpcode) String() string {
 		return "xor"
 	case aluRmiROpcodeMul:
 		return "imul"
+	case aluRmiROpcodeAdc:
+		return "adc"
+	case aluRmiROpcodeSbb:
+		return "sbb"
 	default:
 		panic("BUG")
 	}
@@ -1255,6 +1320,15 @@ func (i *instruction) asBlendvpd(rm operand, rd regalloc.VReg) *instruction {
 	return i
 }
 
+func (i *instruction) asVfmadd231(x, y, rd regalloc.VReg, is64 bool) *instruction {
+	i.kind = vfmadd231
+	i.u1 = uint64(x)
+	i.op1 = newOperandReg(y)
+	i.op2 = newOperandReg(rd)
+	i.b1 = is64
+	return i
+}
+
 func (i *instruction) asXmmRmR(op sseOpcode, rm operand, rd regalloc.VReg) *instruction {
 	if rm.kind != operandKindReg && rm.kind != operandKindMem {
 		panic("BUG")
@@ -2335,6 +2409,8 @@ const (
 	defKindOp2
 	defKindCall
 	defKindDivRem
+	// defKindRaxRdx is RAX and RDX, which are implicitly defined by mulHi.
+	defKindRaxRdx
 )
 
 var defKinds = [instrMax]defKind{
@@ -2376,7 +2452,9 @@ var defKinds = [instrMax]defKind{
 	fcvtToUintSequence:     defKindNone,
 	xmmCMov:                defKindOp2,
 	idivRemSequence:        defKindDivRem,
+	mulHi:                  defKindRaxRdx,
 	blendvpd:               defKindNone,
+	vfmadd231:              defKindNone,
 	mfence:                 defKindNone,
 	xchg:                   defKindNone,
 	lockcmpxchg:            defKindNone,
@@ -2398,6 +2476,8 @@ func (d defKind) String() string {
 		return "call"
 	case defKindDivRem:
 		return "divrem"
+	case defKindRaxRdx:
+		return "raxrdx"
 	default:
 		return "invalid"
 	}
@@ -2415,7 +2495,11 @@ const (
 	// useKindRaxOp1RegOp2 is Op1 must be a register, Op2 can be any operand, and RAX is used.
 	useKindRaxOp1RegOp2
 	useKindDivRem
+	// useKindRaxOp1 is RAX and Op1, which can be a register or memory.
+	useKindRaxOp1
 	useKindBlendvpd
+	// useKindVfmadd231 is the registers of u1, op1 and op2 for vfmadd231.
+	useKindVfmadd231
 	useKindCall
 	useKindCallInd
 	useKindTailCallInd
@@ -2462,7 +2546,9 @@ var useKinds = [instrMax]useKind{
 	fcvtToUintSequence:     useKindFcvtToUintSequence,
 	xmmCMov:                useKindOp1,
 	idivRemSequence:        useKindDivRem,
+	mulHi:                  useKindRaxOp1,
 	blendvpd:               useKindBlendvpd,
+	vfmadd231:              useKindVfmadd231,
 	mfence:                 useKindNone,
 	xchg:                   useKindOp1RegOp2,
 	lockcmpxchg:            useKindRaxOp1RegOp2,
@@ -93,6 +93,10 @@ func (i *instruction) encode(c backend.Compiler) (needsLabelResolution bool) {
 				opcR, opcM, subOpcImm = 0x09, 0x0b, 0x1
 			case aluRmiROpcodeXor:
 				opcR, opcM, subOpcImm = 0x31, 0x33, 0x6
+			case aluRmiROpcodeAdc:
+				opcR, opcM, subOpcImm = 0x11, 0x13, 0x2
+			case aluRmiROpcodeSbb:
+				opcR, opcM, subOpcImm = 0x19, 0x1b, 0x3
 			default:
 				panic("BUG: invalid aluRmiROpcode")
 			}
@@ -1398,6 +1402,25 @@ func (i *instruction) encode(c backend.Compiler) (needsLabelResolution bool) {
 		}
 		i.encode(c)
 
+	case vfmadd231:
+		// VEX.128.66.0F38.W0 B8 /r for ps, and VEX.128.66.0F38.W1 B8 /r for pd.
+		// https://www.felixcloutier.com/x86/vfmadd132ps:vfmadd213ps:vfmadd231ps
+		dst := regEncodings[i.op2.reg().RealReg()]
+		src1 := regEncodings[regalloc.VReg(i.u1).RealReg()]
+		src2 := regEncodings[i.op1.reg().RealReg()]
+		// The first byte of the three-byte VEX prefix.
+		c.EmitByte(0xc4)
+		// Inverted REX.R, REX.X and REX.B followed by the opcode map 0F38.
+		c.EmitByte((dst.rexBit()^1)<<7 | 1<<6 | (src2.rexBit()^1)<<5 | 0b00010)
+		// REX.W, inverted vvvv of src1, L=0 for 128-bit, and pp=01 for the implied 0x66 prefix.
+		var w byte
+		if i.b1 {
+			w = 1
+		}
+		c.EmitByte(w<<7 | (^byte(src1)&0b1111)<<3 | 0b01)
+		c.EmitByte(0xb8)
+		c.EmitByte(encodeModRM(0b11, dst.encoding(), src2.encoding()))
+
 	case mfence:
 		// https://www.felixcloutier.com/x86/mfence
 		c.EmitByte(0x0f)
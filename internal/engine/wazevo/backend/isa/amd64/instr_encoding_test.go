@@ -2632,6 +2632,21 @@ func TestInstruction_format_encode(t *testing.T) {
 			want:       "4531df",
 			wantFormat: "xor %r11d, %r15d",
 		},
+		{
+			setup:      func(i *instruction) { i.asAluRmiR(aluRmiROpcodeAdc, newOperandReg(r11VReg), r15VReg, true) },
+			want:       "4d11df",
+			wantFormat: "adc %r11, %r15",
+		},
+		{
+			setup:      func(i *instruction) { i.asAluRmiR(aluRmiROpcodeSbb, newOperandReg(r11VReg), r15VReg, true) },
+			want:       "4d19df",
+			wantFormat: "sbb %r11, %r15",
+		},
+		{
+			setup:      func(i *instruction) { i.asAluRmiR(aluRmiROpcodeAdc, newOperandImm32(1), rdxVReg, true) },
+			want:       "4883d201",
+			wantFormat: "adc $1, %rdx",
+		},
 		{
 			setup:      func(i *instruction) { i.asLEA(newOperandMem(newAmodeImmReg(0, rdiVReg)), rdxVReg) },
 			want:       "488d17",
@@ -4115,6 +4130,16 @@ func TestInstruction_format_encode(t *testing.T) {
 			want:       "66440f3815f9",
 			wantFormat: "blendvpd %xmm1, %xmm15, %xmm0",
 		},
+		{
+			setup:      func(i *instruction) { i.asVfmadd231(xmm1VReg, xmm2VReg, xmm0VReg, false) },
+			want:       "c4e271b8c2",
+			wantFormat: "vfmadd231ps %xmm2, %xmm1, %xmm0",
+		},
+		{
+			setup:      func(i *instruction) { i.asVfmadd231(xmm1VReg, xmm10VReg, xmm15VReg, true) },
+			want:       "c442f1b8fa",
+			wantFormat: "vfmadd231pd %xmm10, %xmm1, %xmm15",
+		},
 		{
 			setup:      func(i *instruction) { i.asMFence() },
 			want:       "0faef0",
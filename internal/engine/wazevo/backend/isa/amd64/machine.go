@@ -491,6 +491,10 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		m.lowerAluRmiROp(instr, aluRmiROpcodeSub)
 	case ssa.OpcodeImul:
 		m.lowerAluRmiROp(instr, aluRmiROpcodeMul)
+	case ssa.OpcodeIadd128, ssa.OpcodeIsub128:
+		m.lowerArith128(instr, op == ssa.OpcodeIadd128)
+	case ssa.OpcodeSmulWide, ssa.OpcodeUmulWide:
+		m.lowerMulWide(instr, op == ssa.OpcodeSmulWide)
 	case ssa.OpcodeSdiv, ssa.OpcodeUdiv, ssa.OpcodeSrem, ssa.OpcodeUrem:
 		isDiv := op == ssa.OpcodeSdiv || op == ssa.OpcodeUdiv
 		isSigned := op == ssa.OpcodeSdiv || op == ssa.OpcodeSrem
@@ -886,6 +890,14 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		x, y, _ := instr.Arg2WithLane()
 		m.lowerSwizzle(x, y, instr.Return())
 
+	case ssa.OpcodeRelaxedSwizzle:
+		x, y, _ := instr.Arg2WithLane()
+		m.lowerRelaxedSwizzle(x, y, instr.Return())
+
+	case ssa.OpcodeVFma:
+		x, y, z, lane := instr.Arg3WithLane()
+		m.lowerVFma(x, y, z, instr.Return(), lane)
+
 	case ssa.OpcodeShuffle:
 		x, y, lo, hi := instr.ShuffleData()
 		m.lowerShuffle(x, y, lo, hi, instr.Return())
@@ -1717,6 +1729,55 @@ func (m *machine) lowerAluRmiROp(si *ssa.Instruction, op aluRmiROpcode) {
 	m.copyTo(tmp, rd)
 }
 
+func (m *machine) lowerArith128(si *ssa.Instruction, add bool) {
+	xLo, xHi, yLo, yHi := si.Arith128Data()
+
+	// All the operands are materialized before the flags are set, so that nothing clobbers the carry in between.
+	rnLo := m.getOperand_Reg(m.c.ValueDefinition(xLo))
+	rnHi := m.getOperand_Reg(m.c.ValueDefinition(xHi))
+	rmLo := m.getOperand_Mem_Imm32_Reg(m.c.ValueDefinition(yLo))
+	rmHi := m.getOperand_Mem_Imm32_Reg(m.c.ValueDefinition(yHi))
+	lo, rest := si.Returns()
+	rdLo, rdHi := m.c.VRegOf(lo), m.c.VRegOf(rest[0])
+
+	// rnLo and rnHi are being overwritten, so we first copy their values to temp registers.
+	tmpLo := m.copyToTmp(rnLo.reg())
+	tmpHi := m.copyToTmp(rnHi.reg())
+
+	loOp, hiOp := aluRmiROpcodeAdd, aluRmiROpcodeAdc
+	if !add {
+		loOp, hiOp = aluRmiROpcodeSub, aluRmiROpcodeSbb
+	}
+
+	alu := m.allocateInstr()
+	alu.asAluRmiR(loOp, rmLo, tmpLo, true)
+	m.insert(alu)
+
+	aluCarry := m.allocateInstr()
+	aluCarry.asAluRmiR(hiOp, rmHi, tmpHi, true)
+	m.insert(aluCarry)
+
+	m.copyTo(tmpLo, rdLo)
+	m.copyTo(tmpHi, rdHi)
+}
+
+func (m *machine) lowerMulWide(si *ssa.Instruction, signed bool) {
+	x, y := si.Arg2()
+	rn := m.getOperand_Reg(m.c.ValueDefinition(x))
+	rm := m.getOperand_Reg(m.c.ValueDefinition(y))
+	lo, rest := si.Returns()
+	rdLo, rdHi := m.c.VRegOf(lo), m.c.VRegOf(rest[0])
+
+	// mul and imul with a single operand implicitly multiply rax, and define the product in rdx:rax.
+	m.copyTo(rn.reg(), raxVReg)
+	mul := m.allocateInstr()
+	mul.asMulHi(rm, signed, true)
+	m.insert(mul)
+
+	m.copyTo(raxVReg, rdLo)
+	m.copyTo(rdxVReg, rdHi)
+}
+
 func (m *machine) lowerShiftR(si *ssa.Instruction, op shiftROp) {
 	x, amt := si.Arg2()
 	if !x.Type().IsInt() {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend/regalloc"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/platform"
 )
 
 var swizzleMask = [16]byte{
@@ -33,6 +34,45 @@ func (m *machine) lowerSwizzle(x, y ssa.Value, ret ssa.Value) {
 	m.copyTo(tmpDst, m.c.VRegOf(ret))
 }
 
+// lowerRelaxedSwizzle is the same as lowerSwizzle, except that the out-of-range indices below 0x80
+// are not saturated, in which case PSHUFB selects the lane of their lower 4 bits.
+func (m *machine) lowerRelaxedSwizzle(x, y ssa.Value, ret ssa.Value) {
+	xx := m.getOperand_Reg(m.c.ValueDefinition(x))
+	tmpDst := m.copyToTmp(xx.reg())
+	yy := m.getOperand_Reg(m.c.ValueDefinition(y))
+
+	m.insert(m.allocateInstr().asXmmRmR(sseOpcodePshufb, yy, tmpDst))
+	m.copyTo(tmpDst, m.c.VRegOf(ret))
+}
+
+func (m *machine) lowerVFma(x, y, z ssa.Value, ret ssa.Value, lane ssa.VecLane) {
+	var mulOp, addOp sseOpcode
+	switch lane {
+	case ssa.VecLaneF32x4:
+		mulOp, addOp = sseOpcodeMulps, sseOpcodeAddps
+	case ssa.VecLaneF64x2:
+		mulOp, addOp = sseOpcodeMulpd, sseOpcodeAddpd
+	default:
+		panic(fmt.Sprintf("invalid lane type: %s", lane))
+	}
+
+	xx := m.getOperand_Reg(m.c.ValueDefinition(x))
+	yy := m.getOperand_Reg(m.c.ValueDefinition(y))
+	zz := m.getOperand_Reg(m.c.ValueDefinition(z))
+
+	var tmpDst regalloc.VReg
+	if m.cpuFeatures.Has(platform.CpuFeatureAmd64FMA) {
+		tmpDst = m.copyToTmp(zz.reg())
+		m.insert(m.allocateInstr().asVfmadd231(xx.reg(), yy.reg(), tmpDst, lane == ssa.VecLaneF64x2))
+	} else {
+		// Without FMA3, the multiplication is rounded before the addition, which is allowed by the relaxed semantics.
+		tmpDst = m.copyToTmp(xx.reg())
+		m.insert(m.allocateInstr().asXmmRmR(mulOp, yy, tmpDst))
+		m.insert(m.allocateInstr().asXmmRmR(addOp, zz, tmpDst))
+	}
+	m.copyTo(tmpDst, m.c.VRegOf(ret))
+}
+
 func (m *machine) lowerInsertLane(x, y ssa.Value, index byte, ret ssa.Value, lane ssa.VecLane) {
 	// Copy x to tmp.
 	tmpDst := m.c.AllocateVReg(ssa.TypeV128)
@@ -1858,10 +1858,14 @@ func (a aluOp) String() string {
 		return "adds"
 	case aluOpSubS:
 		return "subs"
+	case aluOpAdc:
+		return "adc"
+	case aluOpSbc:
+		return "sbc"
 	case aluOpSMulH:
-		return "sMulH"
+		return "smulh"
 	case aluOpUMulH:
-		return "uMulH"
+		return "umulh"
 	case aluOpSDiv:
 		return "sdiv"
 	case aluOpUDiv:
@@ -1903,6 +1907,10 @@ const (
 	aluOpAddS
 	// 32/64-bit Subtract setting flags.
 	aluOpSubS
+	// 32/64-bit Add with carry.
+	aluOpAdc
+	// 32/64-bit Subtract with carry.
+	aluOpSbc
 	// Signed multiply, high-word result.
 	aluOpSMulH
 	// Unsigned multiply, high-word result.
@@ -1996,6 +2004,8 @@ func (b vecOp) String() string {
 		return "urhadd"
 	case vecOpFmul:
 		return "fmul"
+	case vecOpFmla:
+		return "fmla"
 	case vecOpSqrdmulh:
 		return "sqrdmulh"
 	case vecOpMul:
@@ -2110,6 +2120,7 @@ const (
 	vecOpUrhadd
 	vecOpMul
 	vecOpFmul
+	vecOpFmla
 	vecOpSqrdmulh
 	vecOpUmlal
 	vecOpFdiv
@@ -358,7 +358,7 @@ func (i *instruction) encode(m *machine) {
 			i.u2 == 1,
 		))
 	case vecRRR:
-		if op := vecOp(i.u1); op == vecOpBsl || op == vecOpBit || op == vecOpUmlal {
+		if op := vecOp(i.u1); op == vecOpBsl || op == vecOpBit || op == vecOpUmlal || op == vecOpFmla {
 			panic(fmt.Sprintf("vecOp %s must use vecRRRRewrite instead of vecRRR", op.String()))
 		}
 		fallthrough
@@ -635,6 +635,19 @@ func encodeVecRRR(op vecOp, rd, rn, rm uint32, arr vecArrangement) uint32 {
 			panic("unsupported arrangement: " + arr.String())
 		}
 		return encodeAdvancedSIMDThreeSame(rd, rn, rm, 0b11011, size, 0b1, q)
+	case vecOpFmla:
+		var size, q uint32
+		switch arr {
+		case vecArrangement4S:
+			size, q = 0b00, 0b1
+		case vecArrangement2S:
+			size, q = 0b00, 0b0
+		case vecArrangement2D:
+			size, q = 0b01, 0b1
+		default:
+			panic("unsupported arrangement: " + arr.String())
+		}
+		return encodeAdvancedSIMDThreeSame(rd, rn, rm, 0b11001, size, 0b0, q)
 	case vecOpSqrdmulh:
 		if arr < vecArrangement4H || arr > vecArrangement4S {
 			panic("unsupported arrangement: " + arr.String())
@@ -1663,6 +1676,20 @@ func encodeAluRRR(op aluOp, rd, rn, rm uint32, _64bit, isRnSp bool) uint32 {
 		}
 		// "Shifted register" with shift = 0
 		_31to21 = 0b01101011_000
+	case aluOpAdc:
+		// "Add/subtract (with carry)".
+		_31to21 = 0b00011010_000
+	case aluOpSbc:
+		// "Add/subtract (with carry)".
+		_31to21 = 0b01011010_000
+	case aluOpSMulH, aluOpUMulH:
+		// "Data-processing (3 source)" with Ra = xzr, which is only valid for 64-bit.
+		if op == aluOpSMulH {
+			_31to21 = 0b00011011_010
+		} else {
+			_31to21 = 0b00011011_110
+		}
+		_15to10 = 0b011111
 	case aluOpAnd, aluOpOrr, aluOpOrn, aluOpEor, aluOpAnds:
 		// "Logical (shifted register)".
 		var opc, n uint32
@@ -653,6 +653,15 @@ func TestInstruction_encode(t *testing.T) {
 		{want: "41dc636e", setup: func(i *instruction) {
 			i.asVecRRR(vecOpFmul, v1VReg, operandNR(v2VReg), operandNR(v3VReg), vecArrangement2D)
 		}},
+		{want: "41cc230e", setup: func(i *instruction) {
+			i.asVecRRRRewrite(vecOpFmla, v1VReg, operandNR(v2VReg), operandNR(v3VReg), vecArrangement2S)
+		}},
+		{want: "41cc234e", setup: func(i *instruction) {
+			i.asVecRRRRewrite(vecOpFmla, v1VReg, operandNR(v2VReg), operandNR(v3VReg), vecArrangement4S)
+		}},
+		{want: "41cc634e", setup: func(i *instruction) {
+			i.asVecRRRRewrite(vecOpFmla, v1VReg, operandNR(v2VReg), operandNR(v3VReg), vecArrangement2D)
+		}},
 		{want: "41b4636e", setup: func(i *instruction) {
 			i.asVecRRR(vecOpSqrdmulh, v1VReg, operandNR(v2VReg), operandNR(v3VReg), vecArrangement8H)
 		}},
@@ -1431,6 +1440,18 @@ func TestInstruction_encode(t *testing.T) {
 		{want: "4008d41a", setup: func(i *instruction) {
 			i.asALU(aluOpUDiv, x0VReg, operandNR(x2VReg), operandNR(x20VReg), false)
 		}},
+		{want: "4000149a", setup: func(i *instruction) {
+			i.asALU(aluOpAdc, x0VReg, operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "400014da", setup: func(i *instruction) {
+			i.asALU(aluOpSbc, x0VReg, operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "407c549b", setup: func(i *instruction) {
+			i.asALU(aluOpSMulH, x0VReg, operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
+		{want: "407cd49b", setup: func(i *instruction) {
+			i.asALU(aluOpUMulH, x0VReg, operandNR(x2VReg), operandNR(x20VReg), true)
+		}},
 		{want: "407c0013", setup: func(i *instruction) {
 			i.asALUShift(aluOpAsr, x0VReg, operandNR(x2VReg), operandShiftImm(0), false)
 		}},
@@ -239,6 +239,10 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		x, y := instr.Arg2()
 		result := instr.Return()
 		m.lowerImul(x, y, result)
+	case ssa.OpcodeIadd128, ssa.OpcodeIsub128:
+		m.lowerArith128(instr, op == ssa.OpcodeIadd128)
+	case ssa.OpcodeSmulWide, ssa.OpcodeUmulWide:
+		m.lowerMulWide(instr, op == ssa.OpcodeSmulWide)
 	case ssa.OpcodeUndefined:
 		undef := m.allocateInstr()
 		undef.asUDF()
@@ -712,7 +716,8 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		mov3.asFpuMov128(rd, tmpReg)
 		m.insert(mov3)
 
-	case ssa.OpcodeSwizzle:
+	case ssa.OpcodeSwizzle, ssa.OpcodeRelaxedSwizzle:
+		// TBL results in zero for the out-of-range indices, which is also valid for the relaxed swizzle.
 		x, y, lane := instr.Arg2WithLane()
 		rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
 		rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
@@ -725,6 +730,27 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		tbl1.asVecTbl(1, rd, rn, rm, arr)
 		m.insert(tbl1)
 
+	case ssa.OpcodeVFma:
+		x, y, z, lane := instr.Arg3WithLane()
+		rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+		rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+		rz := m.getOperand_NR(m.compiler.ValueDefinition(z), extModeNone)
+		tmp := m.compiler.AllocateVReg(ssa.TypeV128)
+
+		// The accumulator is overwritten by FMLA, so we need to copy z to the temporary register first.
+		mov := m.allocateInstr()
+		mov.asFpuMov128(tmp, rz.nr())
+		m.insert(mov)
+
+		fmla := m.allocateInstr()
+		fmla.asVecRRRRewrite(vecOpFmla, tmp, rn, rm, ssaLaneToArrangement(lane))
+		m.insert(fmla)
+
+		mov2 := m.allocateInstr()
+		rd := m.compiler.VRegOf(instr.Return())
+		mov2.asFpuMov128(rd, tmp)
+		m.insert(mov2)
+
 	case ssa.OpcodeShuffle:
 		x, y, lane1, lane2 := instr.ShuffleData()
 		rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
@@ -1804,6 +1830,50 @@ func (m *machine) lowerImul(x, y, result ssa.Value) {
 	m.insert(mul)
 }
 
+func (m *machine) lowerArith128(si *ssa.Instruction, add bool) {
+	xLo, xHi, yLo, yHi := si.Arith128Data()
+	// All the operands are materialized before the flags are set, so that nothing clobbers the carry in between.
+	rnLo := m.getOperand_NR(m.compiler.ValueDefinition(xLo), extModeNone)
+	rnHi := m.getOperand_NR(m.compiler.ValueDefinition(xHi), extModeNone)
+	rmLo := m.getOperand_NR(m.compiler.ValueDefinition(yLo), extModeNone)
+	rmHi := m.getOperand_NR(m.compiler.ValueDefinition(yHi), extModeNone)
+	lo, rest := si.Returns()
+	rdLo, rdHi := m.compiler.VRegOf(lo), m.compiler.VRegOf(rest[0])
+
+	loOp, hiOp := aluOpAddS, aluOpAdc
+	if !add {
+		loOp, hiOp = aluOpSubS, aluOpSbc
+	}
+
+	alu := m.allocateInstr()
+	alu.asALU(loOp, rdLo, rnLo, rmLo, true)
+	m.insert(alu)
+
+	aluCarry := m.allocateInstr()
+	aluCarry.asALU(hiOp, rdHi, rnHi, rmHi, true)
+	m.insert(aluCarry)
+}
+
+func (m *machine) lowerMulWide(si *ssa.Instruction, signed bool) {
+	x, y := si.Arg2()
+	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
+	rm := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+	lo, rest := si.Returns()
+	rdLo, rdHi := m.compiler.VRegOf(lo), m.compiler.VRegOf(rest[0])
+
+	mul := m.allocateInstr()
+	mul.asALURRRR(aluOpMAdd, rdLo, rn, rm, xzrVReg, true)
+	m.insert(mul)
+
+	hiOp := aluOpUMulH
+	if signed {
+		hiOp = aluOpSMulH
+	}
+	mulH := m.allocateInstr()
+	mulH.asALU(hiOp, rdHi, rn, rm, true)
+	m.insert(mulH)
+}
+
 func (m *machine) lowerClz(x, result ssa.Value) {
 	rd := m.compiler.VRegOf(result)
 	rn := m.getOperand_NR(m.compiler.ValueDefinition(x), extModeNone)
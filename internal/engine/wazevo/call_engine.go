@@ -3,8 +3,10 @@ package wazevo
 import (
 	"context"
 	"fmt"
+	"math"
 	"reflect"
 	"runtime"
+	"slices"
 	"sync/atomic"
 	"unsafe"
 
@@ -44,6 +46,20 @@ type (
 		execCtxPtr        uintptr
 		numberOfResults   int
 		stackIteratorImpl stackIterator
+		// exception is the exception being thrown while execCtx.exceptionPending is set.
+		exception *experimental.Exception
+		// exceptionRefs holds the exceptions referenced by exnref values created during the current call.
+		exceptionRefs *wasm.ExceptionRefs
+		// exceptionHandling is true when experimental.CoreFeaturesExceptionHandling is enabled, in which case
+		// exceptions thrown by Go functions can be caught by the guest.
+		exceptionHandling bool
+		// continuations holds the continuations created by stack switching instructions during the current call, and
+		// is shared with the call engines running them.
+		continuations *wasm.Continuations
+		// continuation is the continuation this call engine runs, or nil if this is not running a continuation.
+		continuation *wasm.Continuation
+		// continuationArgs is the buffer passing the values of stack switching instructions from and to the native code.
+		continuationArgs []uint64
 	}
 
 	// executionContext is the struct to be read/written by assembly functions.
@@ -91,6 +107,30 @@ type (
 		memoryWait64TrampolineAddress *byte
 		// memoryNotifyTrampolineAddress holds the address of the memory_notify trampoline function.
 		memoryNotifyTrampolineAddress *byte
+		// exceptionPending is non-zero while an exception is being thrown. Functions check this after each call
+		// to either transfer the control to the matching exception handler, or return to the caller immediately.
+		exceptionPending uint64
+		// exceptionPayloadPtr holds the pointer to the payload of the exception being thrown.
+		exceptionPayloadPtr *uint64
+		// exceptionRef holds the exnref of the exception being thrown.
+		exceptionRef uint64
+		// throwTrampolineAddress holds the address of the throw trampoline function.
+		throwTrampolineAddress *byte
+		// throwRefTrampolineAddress holds the address of the throw_ref trampoline function.
+		throwRefTrampolineAddress *byte
+		// exceptionTagMatchTrampolineAddress holds the address of the exception tag match trampoline function.
+		exceptionTagMatchTrampolineAddress *byte
+		// continuationArgsTrampolineAddress holds the address of the trampoline function returning the buffer of the
+		// values passed by stack switching instructions.
+		continuationArgsTrampolineAddress *byte
+		// contNewTrampolineAddress holds the address of the cont.new trampoline function.
+		contNewTrampolineAddress *byte
+		// contBindTrampolineAddress holds the address of the cont.bind trampoline function.
+		contBindTrampolineAddress *byte
+		// resumeTrampolineAddress holds the address of the resume trampoline function.
+		resumeTrampolineAddress *byte
+		// suspendTrampolineAddress holds the address of the suspend trampoline function.
+		suspendTrampolineAddress *byte
 	}
 )
 
@@ -232,6 +272,20 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			panic(s)
 		}
 
+		if c.continuation != nil {
+			// The panic of a continuation is propagated to its resumer, which builds the error with its own frames.
+			// Otherwise, this is the same as the end of the call below, except that the references created so far
+			// are still valid in the other continuations of the call.
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			if c.exception != nil || c.execCtx.exceptionPending != 0 {
+				c.clearException()
+			}
+			if r != nil {
+				panic(r)
+			}
+			return
+		}
+
 		if r != nil {
 			type listenerForAbort struct {
 				def api.FunctionDefinition
@@ -276,7 +330,8 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				lsn.lsn.Abort(ctx, m, lsn.def, err)
 			}
 		} else {
-			if err != wasmruntime.ErrRuntimeStackOverflow { // Stackoverflow case shouldn't be panic (to avoid extreme stack unwinding).
+			// Stackoverflow case shouldn't be panic (to avoid extreme stack unwinding), and neither is an uncaught exception.
+			if err != wasmruntime.ErrRuntimeStackOverflow && c.execCtx.exceptionPending == 0 {
 				err = c.parent.module.FailIfClosed()
 			}
 		}
@@ -285,6 +340,13 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			// Ensures that we can reuse this callEngine even after an error.
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 		}
+		if c.exception != nil || c.execCtx.exceptionPending != 0 {
+			c.clearException()
+		}
+		c.exceptionRefs.Reset()
+		if c.continuations != nil {
+			c.continuations.Reset()
+		}
 	}()
 
 	if ensureTermination {
@@ -299,6 +361,14 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 	for {
 		switch ec := c.execCtx.exitCode; ec & wazevoapi.ExitCodeMask {
 		case wazevoapi.ExitCodeOK:
+			if c.execCtx.exceptionPending != 0 {
+				if c.continuation != nil {
+					// The exception is thrown to the resumer of the continuation.
+					panic(c.exception)
+				}
+				// The exception was not caught by any function, which all returned to here.
+				return wasmdebug.NewErrorBuilder().FromRecovered(c.exception)
+			}
 			return nil
 		case wazevoapi.ExitCodeGrowStack:
 			oldsp := uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall))
@@ -320,13 +390,22 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, newsp, newfp)
 		case wazevoapi.ExitCodeGrowMemory:
 			mod := c.callerModuleInstance()
-			mem := mod.MemoryInstance
 			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
-			argRes := &s[0]
-			if res, ok := mem.Grow(uint32(*argRes)); !ok {
-				*argRes = uint64(0xffffffff) // = -1 in signed 32-bit integer.
+			memoryIndex, pages := uint32(s[0]), s[1]
+			mem := mod.MemoryAt(memoryIndex)
+			var res uint64
+			var ok bool
+			if mem.Memory64 {
+				res, ok = mem.Grow64(pages)
+			} else {
+				var res32 uint32
+				res32, ok = mem.Grow(uint32(pages))
+				res = uint64(res32)
+			}
+			if !ok {
+				s[0] = math.MaxUint64 // = -1 in signed integers, which is reduced to 32-bit for memory32.
 			} else {
-				*argRes = uint64(res)
+				s[0] = res
 			}
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
@@ -346,6 +425,9 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				if snapshotEnabled {
 					defer snapshotRecoverFn(c)
 				}
+				if c.exceptionHandling {
+					defer exceptionRecoverFn(c)
+				}
 				f.Call(ctx, goCallStackView(c.execCtx.stackPointerBeforeGoCall))
 			}()
 			// Back to the native code.
@@ -368,10 +450,17 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				if snapshotEnabled {
 					defer snapshotRecoverFn(c)
 				}
+				if c.exceptionHandling {
+					defer exceptionRecoverFn(c)
+				}
 				f.Call(ctx, s)
 			}()
-			// Call Listener.After.
-			listener.After(ctx, callerModule, def, s)
+			// Call Listener.After, or Listener.Abort if the Go function threw an exception.
+			if c.execCtx.exceptionPending != 0 {
+				listener.Abort(ctx, callerModule, def, c.exception)
+			} else {
+				listener.After(ctx, callerModule, def, s)
+			}
 			// Back to the native code.
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
@@ -384,6 +473,9 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				if snapshotEnabled {
 					defer snapshotRecoverFn(c)
 				}
+				if c.exceptionHandling {
+					defer exceptionRecoverFn(c)
+				}
 				f.Call(ctx, mod, goCallStackView(c.execCtx.stackPointerBeforeGoCall))
 			}()
 			// Back to the native code.
@@ -406,10 +498,17 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				if snapshotEnabled {
 					defer snapshotRecoverFn(c)
 				}
+				if c.exceptionHandling {
+					defer exceptionRecoverFn(c)
+				}
 				f.Call(ctx, callerModule, s)
 			}()
-			// Call Listener.After.
-			listener.After(ctx, callerModule, def, s)
+			// Call Listener.After, or Listener.Abort if the Go function threw an exception.
+			if c.execCtx.exceptionPending != 0 {
+				listener.Abort(ctx, callerModule, def, c.exception)
+			} else {
+				listener.After(ctx, callerModule, def, s)
+			}
 			// Back to the native code.
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
@@ -455,17 +554,17 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
 		case wazevoapi.ExitCodeMemoryWait32:
 			mod := c.callerModuleInstance()
-			mem := mod.MemoryInstance
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			mem := memoryContaining(mod, uintptr(s[2]))
 			if !mem.Shared {
 				panic(wasmruntime.ErrRuntimeExpectedSharedMemory)
 			}
 
-			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
 			timeout, exp, addr := int64(s[0]), uint32(s[1]), uintptr(s[2])
 			base := uintptr(unsafe.Pointer(&mem.Buffer[0]))
 
-			offset := uint32(addr - base)
-			res := mem.Wait32(offset, exp, timeout, func(mem *wasm.MemoryInstance, offset uint32) uint32 {
+			offset := uint64(addr - base)
+			res := mem.Wait32(offset, exp, timeout, func(mem *wasm.MemoryInstance, offset uint64) uint32 {
 				addr := unsafe.Add(unsafe.Pointer(&mem.Buffer[0]), offset)
 				return atomic.LoadUint32((*uint32)(addr))
 			})
@@ -475,17 +574,17 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
 		case wazevoapi.ExitCodeMemoryWait64:
 			mod := c.callerModuleInstance()
-			mem := mod.MemoryInstance
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			mem := memoryContaining(mod, uintptr(s[2]))
 			if !mem.Shared {
 				panic(wasmruntime.ErrRuntimeExpectedSharedMemory)
 			}
 
-			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
 			timeout, exp, addr := int64(s[0]), uint64(s[1]), uintptr(s[2])
 			base := uintptr(unsafe.Pointer(&mem.Buffer[0]))
 
-			offset := uint32(addr - base)
-			res := mem.Wait64(offset, exp, timeout, func(mem *wasm.MemoryInstance, offset uint32) uint64 {
+			offset := uint64(addr - base)
+			res := mem.Wait64(offset, exp, timeout, func(mem *wasm.MemoryInstance, offset uint64) uint64 {
 				addr := unsafe.Add(unsafe.Pointer(&mem.Buffer[0]), offset)
 				return atomic.LoadUint64((*uint64)(addr))
 			})
@@ -495,16 +594,88 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
 		case wazevoapi.ExitCodeMemoryNotify:
 			mod := c.callerModuleInstance()
-			mem := mod.MemoryInstance
-
 			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
 			count, addr := uint32(s[0]), s[1]
-			offset := uint32(uintptr(addr) - uintptr(unsafe.Pointer(&mem.Buffer[0])))
+			mem := memoryContaining(mod, uintptr(addr))
+			offset := uint64(uintptr(addr) - uintptr(unsafe.Pointer(&mem.Buffer[0])))
 			res := mem.Notify(offset, count)
 			s[0] = uint64(res)
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
 				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeThrow:
+			mod := c.callerModuleInstance()
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			tag := mod.Tags[uint32(s[0])]
+			exc := experimental.NewException(tag, make([]uint64, tag.Type.ParamNumInUint64)...)
+			c.raise(exc)
+			// Returns the pointer to the payload to be filled by the native code.
+			s[0] = uint64(uintptr(unsafe.Pointer(c.execCtx.exceptionPayloadPtr)))
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeThrowRef:
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			exc := c.exceptionRefs.Get(s[0])
+			if exc == nil {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			}
+			c.raise(exc)
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeExceptionTagMatch:
+			mod := c.callerModuleInstance()
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			if c.exception.Tag() == experimental.ExceptionTag(mod.Tags[uint32(s[0])]) {
+				s[0] = 1
+			} else {
+				s[0] = 0
+			}
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeContinuationArgs:
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			// Returns the pointer to the buffer to be filled by the native code.
+			s[0] = uint64(uintptr(unsafe.Pointer(c.continuationArgsOf(int(uint32(s[0]))))))
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeContNew:
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			s[0] = c.contNew(ctx, uintptr(s[0]), wasm.Index(s[1]))
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeContBind:
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			s[0] = c.continuationsOf(s[0]).Bind(s[0], c.continuationArgs[:uint32(s[1])])
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeResume:
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			func() {
+				if c.exceptionHandling {
+					defer exceptionRecoverFn(c)
+				}
+				s[0] = uint64(uintptr(unsafe.Pointer(c.resume(s[0], int(uint32(s[1])), int(uint32(s[2]))))))
+			}()
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
+		case wazevoapi.ExitCodeSuspend:
+			mod := c.callerModuleInstance()
+			s := goCallStackView(c.execCtx.stackPointerBeforeGoCall)
+			tag := mod.Tags[uint32(s[0])]
+			results := c.continuation.Suspend(tag, c.continuationArgs[:tag.Type.ParamNumInUint64])
+			// Returns the pointer to the results of the tag.
+			s[0] = uint64(uintptr(unsafe.Pointer(c.continuationArgsOf(len(results)))))
+			copy(c.continuationArgs, results)
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr,
+				uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)), c.execCtx.framePointerBeforeGoCall)
 		case wazevoapi.ExitCodeUnreachable:
 			panic(wasmruntime.ErrRuntimeUnreachable)
 		case wazevoapi.ExitCodeMemoryOutOfBounds:
@@ -515,6 +686,8 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			panic(wasmruntime.ErrRuntimeInvalidTableAccess)
 		case wazevoapi.ExitCodeIndirectCallTypeMismatch:
 			panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+		case wazevoapi.ExitCodeNullReference:
+			panic(wasmruntime.ErrRuntimeNullReference)
 		case wazevoapi.ExitCodeIntegerOverflow:
 			panic(wasmruntime.ErrRuntimeIntegerOverflow)
 		case wazevoapi.ExitCodeIntegerDivisionByZero:
@@ -529,10 +702,124 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 	}
 }
 
+// raise makes the exception pending, so that the native code transfers the control to the matching
+// exception handler, or returns to the caller, after returning from the Go call.
+func (c *callEngine) raise(exc *experimental.Exception) {
+	c.exception = exc
+	c.execCtx.exceptionPending = 1
+	if payload := exc.Payload(); len(payload) > 0 {
+		c.execCtx.exceptionPayloadPtr = &payload[0]
+	} else {
+		c.execCtx.exceptionPayloadPtr = nil
+	}
+	c.execCtx.exceptionRef = c.exceptionRefs.Add(exc)
+}
+
+// clearException clears the pending exception, if any.
+func (c *callEngine) clearException() {
+	c.exception = nil
+	c.execCtx.exceptionPending = 0
+	c.execCtx.exceptionPayloadPtr = nil
+	c.execCtx.exceptionRef = 0
+}
+
+// continuationArgsOf returns the pointer to the buffer passing n values of stack switching instructions, which is
+// valid until the next call.
+func (c *callEngine) continuationArgsOf(n int) *uint64 {
+	if n > len(c.continuationArgs) {
+		c.continuationArgs = make([]uint64, n)
+	} else if n == 0 && c.continuationArgs == nil {
+		// The native code always receives a valid pointer.
+		c.continuationArgs = make([]uint64, 1)
+	}
+	return &c.continuationArgs[0]
+}
+
+// continuationsOf returns the continuations of the call, which are allocated on the first use.
+func (c *callEngine) continuationsOf(ref uint64) *wasm.Continuations {
+	if c.continuations == nil {
+		if ref != 0 {
+			panic("BUG: contref without continuations")
+		}
+		c.continuations = &wasm.Continuations{}
+	}
+	return c.continuations
+}
+
+// contNew returns a new contref to the continuation of the function referenced by the funcref, whose function type is
+// the one of the continuation type at typeIndex in the module of the caller.
+func (c *callEngine) contNew(ctx context.Context, ref uintptr, typeIndex wasm.Index) uint64 {
+	if ref == 0 {
+		panic(wasmruntime.ErrRuntimeNullReference)
+	}
+	mod := c.callerModuleInstance()
+	fi := wazevoapi.PtrFromUintptr[functionInstance](ref)
+	if fi.typeID != mod.TypeIDs[mod.Source.SubTypes[typeIndex].FuncType] {
+		panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+	}
+
+	var f api.Function
+	if me := mod.Engine.(*moduleEngine); me.isImportedFunctionReference(ref) {
+		f = me.NewFunction(wasm.Index((ref - me.importedFunctionsBegin()) / wazevoapi.FunctionInstanceSize))
+	} else {
+		f = moduleInstanceFromOpaquePtr(fi.moduleContextOpaquePtr).Engine.NewFunction(fi.indexInModule)
+	}
+
+	exceptionRefs := c.exceptionRefs
+	continuations := c.continuationsOf(0)
+	return continuations.New(func(k *wasm.Continuation, params []uint64) []uint64 {
+		ce := f.(*callEngine)
+		ce.exceptionRefs, ce.continuations, ce.continuation = exceptionRefs, continuations, k
+		stack := make([]uint64, ce.sizeOfParamResultSlice)
+		copy(stack, params)
+		if err := ce.callWithStack(ctx, stack); err != nil {
+			panic(err)
+		}
+		return stack[:ce.numberOfResults]
+	})
+}
+
+// resume resumes the continuation referred by ref with the handlers and params in the buffer of continuationArgs, and
+// returns the pointer to the buffer holding the index of the handler of the suspension plus one, or zero if the
+// continuation returned, followed by the values to the target of the handler or after resume.
+func (c *callEngine) resume(ref uint64, numHandlers, numParams int) *uint64 {
+	mod := c.callerModuleInstance()
+	args := c.continuationArgs
+	handlers := make([]*wasm.TagInstance, numHandlers)
+	for i := range handlers {
+		handlers[i] = mod.Tags[uint32(args[i])]
+	}
+	params := slices.Clone(args[numHandlers : numHandlers+numParams])
+
+	h, values := c.continuationsOf(ref).Resume(ref, params, handlers, c.continuation)
+	buf := c.continuationArgs
+	if len(values)+1 > len(buf) {
+		buf = make([]uint64, len(values)+1)
+		c.continuationArgs = buf
+	}
+	buf[0] = uint64(h + 1)
+	copy(buf[1:], values)
+	return &buf[0]
+}
+
 func (c *callEngine) callerModuleInstance() *wasm.ModuleInstance {
 	return moduleInstanceFromOpaquePtr(c.execCtx.callerModuleContextPtr)
 }
 
+// memoryContaining returns the memory of the module whose buffer contains the given absolute address, which is
+// passed by atomic wait and notify instructions. This is the memory at index zero unless multi-memory is in use.
+func memoryContaining(mod *wasm.ModuleInstance, addr uintptr) *wasm.MemoryInstance {
+	for _, mem := range mod.Memories {
+		if len(mem.Buffer) == 0 {
+			continue
+		}
+		if base := uintptr(unsafe.Pointer(&mem.Buffer[0])); base <= addr && addr < base+uintptr(len(mem.Buffer)) {
+			return mem
+		}
+	}
+	return mod.MemoryInstance
+}
+
 const callStackCeiling = uintptr(50000000) // in uint64 (8 bytes) == 400000000 bytes in total == 400mb.
 
 func (c *callEngine) growStackWithGuarded() (newSP uintptr, newFP uintptr, err error) {
@@ -715,6 +1002,17 @@ func (s *snapshot) Error() string {
 		"exported function invocation than snapshot"
 }
 
+// exceptionRecoverFn raises the exception thrown by a Go function, so that it can be caught by the guest.
+func exceptionRecoverFn(c *callEngine) {
+	if r := recover(); r != nil {
+		if exc, ok := r.(*experimental.Exception); ok {
+			c.raise(exc)
+		} else {
+			panic(r)
+		}
+	}
+}
+
 func snapshotRecoverFn(c *callEngine) {
 	if r := recover(); r != nil {
 		if s, ok := r.(*snapshot); ok && s.c == c {
@@ -41,6 +41,11 @@ type (
 		sharedFunctions *sharedFunctions
 		// setFinalizer defaults to runtime.SetFinalizer, but overridable for tests.
 		setFinalizer func(obj interface{}, finalizer interface{})
+		// enabledFeatures are the features enabled for this engine.
+		enabledFeatures api.CoreFeatures
+		// exceptionHandling is true when experimental.CoreFeaturesExceptionHandling is enabled.
+		// Compiled functions check for pending exceptions after each call only when this is true.
+		exceptionHandling bool
 
 		// The followings are reused for compiling shared functions.
 		machine backend.Machine
@@ -67,6 +72,23 @@ type (
 		memoryWait64Address *byte
 		// memoryNotifyAddress is the address of memory.notify builtin function
 		memoryNotifyAddress *byte
+		// throwAddress is the address of throw builtin function.
+		throwAddress *byte
+		// throwRefAddress is the address of throw_ref builtin function.
+		throwRefAddress *byte
+		// exceptionTagMatchAddress is the address of the builtin function checking the tag of the pending exception.
+		exceptionTagMatchAddress *byte
+		// continuationArgsAddress is the address of the builtin function returning the buffer of the values passed
+		// by stack switching instructions.
+		continuationArgsAddress *byte
+		// contNewAddress is the address of cont.new builtin function.
+		contNewAddress *byte
+		// contBindAddress is the address of cont.bind builtin function.
+		contBindAddress *byte
+		// resumeAddress is the address of resume builtin function.
+		resumeAddress *byte
+		// suspendAddress is the address of suspend builtin function.
+		suspendAddress      *byte
 		listenerTrampolines listenerTrampolines
 	}
 
@@ -115,7 +137,7 @@ type sourceMap struct {
 var _ wasm.Engine = (*engine)(nil)
 
 // NewEngine returns the implementation of wasm.Engine.
-func NewEngine(ctx context.Context, _ api.CoreFeatures, fc filecache.Cache) wasm.Engine {
+func NewEngine(ctx context.Context, enabledFeatures api.CoreFeatures, fc filecache.Cache) wasm.Engine {
 	machine := newMachine()
 	be := backend.NewCompiler(ctx, machine, ssa.NewBuilder())
 	e := &engine{
@@ -125,7 +147,9 @@ func NewEngine(ctx context.Context, _ api.CoreFeatures, fc filecache.Cache) wasm
 		be:              be,
 		fileCache:       fc,
 		wazeroVersion:   version.GetWazeroVersion(),
+		enabledFeatures: enabledFeatures,
 	}
+	e.exceptionHandling = enabledFeatures.IsEnabled(experimental.CoreFeaturesExceptionHandling)
 	e.compileSharedFunctions()
 	return e
 }
@@ -137,6 +161,11 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listene
 		defer wazevoapi.PerfMap.Unlock()
 	}
 
+	if module.UsesGC {
+		return errors.New("the instructions and types of the GC proposal are not supported by the compiler: " +
+			"use the interpreter instead")
+	}
+
 	if _, ok, err := e.getCompiledModule(module, listeners, ensureTermination); ok { // cache hit!
 		return nil
 	} else if err != nil {
@@ -251,7 +280,7 @@ func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listene
 
 	if workers := experimental.GetCompilationWorkers(ctx); workers <= 1 {
 		// Compile with a single goroutine.
-		fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, ensureTermination, withListener, needSourceInfo)
+		fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, ensureTermination, withListener, needSourceInfo, e.exceptionHandling)
 
 		for i := range module.CodeSection {
 			if wazevoapi.DeterministicCompilationVerifierEnabled {
@@ -299,7 +328,7 @@ func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listene
 				machine := newMachine()
 				ssaBuilder := ssa.NewBuilder()
 				be := backend.NewCompiler(ctx, machine, ssaBuilder)
-				fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, ensureTermination, withListener, needSourceInfo)
+				fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, ensureTermination, withListener, needSourceInfo, e.exceptionHandling)
 
 				for {
 					if err := ctx.Err(); err != nil {
@@ -733,7 +762,7 @@ func (e *engine) NewModuleEngine(m *wasm.Module, mi *wasm.ModuleInstance) (wasm.
 }
 
 func (e *engine) compileSharedFunctions() {
-	var sizes [8]int
+	var sizes [16]int
 	var trampolines []byte
 
 	addTrampoline := func(i int, buf []byte) {
@@ -746,8 +775,8 @@ func (e *engine) compileSharedFunctions() {
 	e.be.Init()
 	addTrampoline(0,
 		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeGrowMemory, &ssa.Signature{
-			Params:  []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI32},
-			Results: []ssa.Type{ssa.TypeI32},
+			Params:  []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI32 /* memory index */, ssa.TypeI64 /* pages */},
+			Results: []ssa.Type{ssa.TypeI64},
 		}, false))
 
 	e.be.Init()
@@ -801,6 +830,68 @@ func (e *engine) compileSharedFunctions() {
 			Results: []ssa.Type{ssa.TypeI32},
 		}, false))
 
+	e.be.Init()
+	addTrampoline(8,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeThrow, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI32 /* tag index */},
+			// Returns the pointer to the payload of the exception.
+			Results: []ssa.Type{ssa.TypeI64},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(9,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeThrowRef, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI64 /* exnref */},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(10,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeExceptionTagMatch, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI32 /* tag index */},
+			// Returns 1 if the tag matches the pending exception.
+			Results: []ssa.Type{ssa.TypeI32},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(11,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeContinuationArgs, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI32 /* number of values */},
+			// Returns the pointer to the buffer.
+			Results: []ssa.Type{ssa.TypeI64},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(12,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeContNew, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI64 /* funcref */, ssa.TypeI32 /* type index */},
+			// Returns the contref.
+			Results: []ssa.Type{ssa.TypeI64},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(13,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeContBind, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI64 /* contref */, ssa.TypeI32 /* number of values */},
+			// Returns the new contref.
+			Results: []ssa.Type{ssa.TypeI64},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(14,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeResume, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI64 /* contref */, ssa.TypeI32 /* number of handlers */, ssa.TypeI32 /* number of values */},
+			// Returns the pointer to the buffer of the results.
+			Results: []ssa.Type{ssa.TypeI64},
+		}, false))
+
+	e.be.Init()
+	addTrampoline(15,
+		e.machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeSuspend, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI64 /* exec context */, ssa.TypeI32 /* tag index */},
+			// Returns the pointer to the buffer of the results.
+			Results: []ssa.Type{ssa.TypeI64},
+		}, false))
+
 	fns := &sharedFunctions{
 		executable:          mmapExecutable(trampolines),
 		listenerTrampolines: make(listenerTrampolines),
@@ -823,6 +914,22 @@ func (e *engine) compileSharedFunctions() {
 	fns.memoryWait64Address = &fns.executable[offset]
 	offset += sizes[6]
 	fns.memoryNotifyAddress = &fns.executable[offset]
+	offset += sizes[7]
+	fns.throwAddress = &fns.executable[offset]
+	offset += sizes[8]
+	fns.throwRefAddress = &fns.executable[offset]
+	offset += sizes[9]
+	fns.exceptionTagMatchAddress = &fns.executable[offset]
+	offset += sizes[10]
+	fns.continuationArgsAddress = &fns.executable[offset]
+	offset += sizes[11]
+	fns.contNewAddress = &fns.executable[offset]
+	offset += sizes[12]
+	fns.contBindAddress = &fns.executable[offset]
+	offset += sizes[13]
+	fns.resumeAddress = &fns.executable[offset]
+	offset += sizes[14]
+	fns.suspendAddress = &fns.executable[offset]
 
 	if wazevoapi.PerfMapEnabled {
 		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.memoryGrowAddress)), uint64(sizes[0]), "memory_grow_trampoline")
@@ -833,6 +940,14 @@ func (e *engine) compileSharedFunctions() {
 		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.memoryWait32Address)), uint64(sizes[5]), "memory_wait32_trampoline")
 		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.memoryWait64Address)), uint64(sizes[6]), "memory_wait64_trampoline")
 		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.memoryNotifyAddress)), uint64(sizes[7]), "memory_notify_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.throwAddress)), uint64(sizes[8]), "throw_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.throwRefAddress)), uint64(sizes[9]), "throw_ref_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.exceptionTagMatchAddress)), uint64(sizes[10]), "exception_tag_match_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.continuationArgsAddress)), uint64(sizes[11]), "continuation_args_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.contNewAddress)), uint64(sizes[12]), "cont_new_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.contBindAddress)), uint64(sizes[13]), "cont_bind_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.resumeAddress)), uint64(sizes[14]), "resume_trampoline")
+		wazevoapi.PerfMap.AddEntry(uintptr(unsafe.Pointer(fns.suspendAddress)), uint64(sizes[15]), "suspend_trampoline")
 	}
 
 	e.sharedFunctions = fns
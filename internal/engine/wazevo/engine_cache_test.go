@@ -7,6 +7,8 @@ import (
 	"io"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/u32"
 	"github.com/tetratelabs/wazero/internal/u64"
@@ -300,7 +302,9 @@ func Test_fileCacheKey(t *testing.T) {
 	m := &wasm.Module{}
 	s.Sum(m.ID[:0])
 	original := m.ID
-	result := fileCacheKey(m)
+	result := fileCacheKey(m, api.CoreFeaturesV2)
 	require.Equal(t, original, m.ID)
 	require.NotEqual(t, original, result)
+	// Different features must result in a different key.
+	require.NotEqual(t, result, fileCacheKey(m, api.CoreFeaturesV2|experimental.CoreFeaturesExceptionHandling))
 }
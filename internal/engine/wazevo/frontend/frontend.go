@@ -29,7 +29,20 @@ type Compiler struct {
 	tableGrowSig           ssa.Signature
 	refFuncSig             ssa.Signature
 	memmoveSig             ssa.Signature
+	throwSig               ssa.Signature
+	throwRefSig            ssa.Signature
+	exceptionTagMatchSig   ssa.Signature
+	continuationArgsSig    ssa.Signature
+	contNewSig             ssa.Signature
+	contBindSig            ssa.Signature
+	resumeSig              ssa.Signature
+	suspendSig             ssa.Signature
 	ensureTermination      bool
+	// exceptionHandling is true when the exception-handling proposal is enabled, in which case
+	// pending exceptions are checked after each function call.
+	exceptionHandling bool
+	// tagTypes holds the type index of each tag in the module, including the imported ones.
+	tagTypes []wasm.Index
 
 	// Followings are reset by per function.
 
@@ -45,11 +58,13 @@ type Compiler struct {
 	memoryBaseVariable, memoryLenVariable ssa.Variable
 	needMemory                            bool
 	memoryShared                          bool
-	globalVariables                       []ssa.Variable
-	globalVariablesTypes                  []ssa.Type
-	mutableGlobalVariablesIndexes         []wasm.Index // index to ^.
-	needListener                          bool
-	needSourceOffsetInfo                  bool
+	// memories are all the memories in the index space, which are only accessed by the index with multi-memory.
+	memories                      []*wasm.Memory
+	globalVariables               []ssa.Variable
+	globalVariablesTypes          []ssa.Type
+	mutableGlobalVariablesIndexes []wasm.Index // index to ^.
+	needListener                  bool
+	needSourceOffsetInfo          bool
 	// br is reused during lowering.
 	br            *bytes.Reader
 	loweringState loweringState
@@ -61,6 +76,9 @@ type Compiler struct {
 	varLengthKnownSafeBoundWithIDPool wazevoapi.VarLengthPool[knownSafeBoundWithID]
 
 	execCtxPtrValue, moduleCtxPtrValue ssa.Value
+	// exceptionPropagateBlk is the block returning to the caller with the pending exception
+	// which is not caught in the current function. This is lazily allocated.
+	exceptionPropagateBlk ssa.BasicBlock
 
 	// Following are reused for the known safe bounds analysis.
 
@@ -87,13 +105,16 @@ type (
 var knownSafeBoundsAtTheEndOfBlockNil = wazevoapi.NewNilVarLength[knownSafeBoundWithID]()
 
 // NewFrontendCompiler returns a frontend Compiler.
-func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoapi.ModuleContextOffsetData, ensureTermination bool, listenerOn bool, sourceInfo bool) *Compiler {
+func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoapi.ModuleContextOffsetData, ensureTermination bool, listenerOn bool, sourceInfo bool, exceptionHandling bool) *Compiler {
 	c := &Compiler{
 		m:                                 m,
 		ssaBuilder:                        ssaBuilder,
 		br:                                bytes.NewReader(nil),
 		offset:                            offset,
 		ensureTermination:                 ensureTermination,
+		exceptionHandling:                 exceptionHandling,
+		tagTypes:                          m.AllTagTypes(),
+		memories:                          m.AllMemories(),
 		needSourceOffsetInfo:              sourceInfo,
 		varLengthKnownSafeBoundWithIDPool: wazevoapi.NewVarLengthPool[knownSafeBoundWithID](),
 	}
@@ -130,10 +151,11 @@ func (c *Compiler) declareSignatures(listenerOn bool) {
 	}
 	c.memoryGrowSig = ssa.Signature{
 		ID: begin,
-		// Takes execution context and the page size to grow.
-		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32},
+		// Takes execution context, the memory index and the page size to grow.
+		// The page size is i64 to support memory64.
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32, ssa.TypeI64},
 		// Returns the previous page size.
-		Results: []ssa.Type{ssa.TypeI32},
+		Results: []ssa.Type{ssa.TypeI64},
 	}
 	c.ssaBuilder.DeclareSignature(&c.memoryGrowSig)
 
@@ -194,6 +216,81 @@ func (c *Compiler) declareSignatures(listenerOn bool) {
 		Results: []ssa.Type{ssa.TypeI32},
 	}
 	c.ssaBuilder.DeclareSignature(&c.memoryNotifySig)
+
+	if !c.exceptionHandling {
+		return
+	}
+
+	c.throwSig = ssa.Signature{
+		ID: c.memoryNotifySig.ID + 1,
+		// exec context, tag index
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32},
+		// Returns the pointer to the payload of the exception.
+		Results: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.throwSig)
+
+	c.throwRefSig = ssa.Signature{
+		ID: c.throwSig.ID + 1,
+		// exec context, exnref
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.throwRefSig)
+
+	c.exceptionTagMatchSig = ssa.Signature{
+		ID: c.throwRefSig.ID + 1,
+		// exec context, tag index
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32},
+		// Returns 1 if the tag matches the pending exception.
+		Results: []ssa.Type{ssa.TypeI32},
+	}
+	c.ssaBuilder.DeclareSignature(&c.exceptionTagMatchSig)
+
+	// The stack switching instructions require the exception-handling proposal as well.
+	c.continuationArgsSig = ssa.Signature{
+		ID: c.exceptionTagMatchSig.ID + 1,
+		// exec context, number of values
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32},
+		// Returns the pointer to the buffer of the values.
+		Results: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.continuationArgsSig)
+
+	c.contNewSig = ssa.Signature{
+		ID: c.continuationArgsSig.ID + 1,
+		// exec context, funcref, type index
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64, ssa.TypeI32},
+		// Returns the contref.
+		Results: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.contNewSig)
+
+	c.contBindSig = ssa.Signature{
+		ID: c.contNewSig.ID + 1,
+		// exec context, contref, number of values
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64, ssa.TypeI32},
+		// Returns the new contref.
+		Results: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.contBindSig)
+
+	c.resumeSig = ssa.Signature{
+		ID: c.contBindSig.ID + 1,
+		// exec context, contref, number of handlers, number of values
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64, ssa.TypeI32, ssa.TypeI32},
+		// Returns the pointer to the buffer of the results.
+		Results: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.resumeSig)
+
+	c.suspendSig = ssa.Signature{
+		ID: c.resumeSig.ID + 1,
+		// exec context, tag index
+		Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32},
+		// Returns the pointer to the buffer of the results.
+		Results: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.suspendSig)
 }
 
 // SignatureForWasmFunctionType returns the ssa.Signature for the given wasm.FunctionType.
@@ -226,6 +323,7 @@ func (c *Compiler) Init(idx, typIndex wasm.Index, typ *wasm.FunctionType, localT
 	c.wasmFunctionBody = body
 	c.wasmFunctionBodyOffsetInCodeSection = bodyOffsetInCodeSection
 	c.needListener = needListener
+	c.exceptionPropagateBlk = nil
 	c.clearSafeBounds()
 	c.varLengthKnownSafeBoundWithIDPool.Reset()
 	c.knownSafeBoundsAtTheEndOfBlocks = c.knownSafeBoundsAtTheEndOfBlocks[:0]
@@ -306,15 +404,16 @@ func (c *Compiler) declareWasmLocals() {
 }
 
 func (c *Compiler) declareNecessaryVariables() {
-	if c.needMemory = c.m.MemorySection != nil; c.needMemory {
-		c.memoryShared = c.m.MemorySection.IsShared
-	} else if c.needMemory = c.m.ImportMemoryCount > 0; c.needMemory {
+	// The variables are only for the memory at index zero, which is the imported one if any.
+	if c.needMemory = c.m.ImportMemoryCount > 0; c.needMemory {
 		for _, imp := range c.m.ImportSection {
 			if imp.Type == wasm.ExternTypeMemory {
 				c.memoryShared = imp.DescMem.IsShared
 				break
 			}
 		}
+	} else if c.needMemory = c.m.MemorySection != nil; c.needMemory {
+		c.memoryShared = c.m.MemorySection.IsShared
 	}
 
 	if c.needMemory {
@@ -346,7 +445,7 @@ func (c *Compiler) declareWasmGlobal(typ wasm.ValueType, mutable bool) {
 		st = ssa.TypeI32
 	case wasm.ValueTypeI64,
 		// Both externref and funcref are represented as I64 since we only support 64-bit platforms.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeContref:
 		st = ssa.TypeI64
 	case wasm.ValueTypeF32:
 		st = ssa.TypeF32
@@ -373,7 +472,7 @@ func WasmTypeToSSAType(vt wasm.ValueType) ssa.Type {
 		return ssa.TypeI32
 	case wasm.ValueTypeI64,
 		// Both externref and funcref are represented as I64 since we only support 64-bit platforms.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref, wasm.ValueTypeContref:
 		return ssa.TypeI64
 	case wasm.ValueTypeF32:
 		return ssa.TypeF32
@@ -1250,7 +1250,7 @@ blk0: (exec_ctx:i64, module_ctx:i64)
 			exp: `
 signatures:
 	sig0: i64i64_i32
-	sig2: i64i32_i32
+	sig2: i64i32i64_i64
 
 blk0: (exec_ctx:i64, module_ctx:i64)
 	Store module_ctx, exec_ctx, 0x8
@@ -1267,30 +1267,33 @@ blk0: (exec_ctx:i64, module_ctx:i64)
 	v12:i32 = Ushr v10, v11
 	v13:i32 = Iconst_32 0xa
 	Store module_ctx, exec_ctx, 0x8
-	v14:i64 = Load exec_ctx, 0x48
-	v15:i32 = CallIndirect v14:sig2, exec_ctx, v13
-	v16:i64 = Load module_ctx, 0x8
-	v17:i64 = Load v16, 0x0
-	v18:i64 = Load module_ctx, 0x8
-	v19:i64 = Load v18, 0x8
+	v14:i64 = UExtend v13, 32->64
+	v15:i64 = Load exec_ctx, 0x48
+	v16:i32 = Iconst_32 0x0
+	v17:i64 = CallIndirect v15:sig2, exec_ctx, v16, v14
+	v18:i32 = Ireduce v17
+	v19:i64 = Load module_ctx, 0x8
+	v20:i64 = Load v19, 0x0
+	v21:i64 = Load module_ctx, 0x8
+	v22:i64 = Load v21, 0x8
 	Store module_ctx, exec_ctx, 0x8
-	v20:i64 = Load module_ctx, 0x18
-	v21:i64 = Load module_ctx, 0x20
-	v22:i32 = CallIndirect v20:sig0, exec_ctx, v21
-	v23:i64 = Load module_ctx, 0x8
-	v24:i64 = Load v23, 0x0
-	v25:i64 = Load module_ctx, 0x8
-	v26:i64 = Load v25, 0x8
-	v27:i64 = Load module_ctx, 0x8
-	v28:i32 = Load v27, 0x8
-	v29:i32 = Iconst_32 0x10
-	v30:i32 = Ushr v28, v29
-	Jump blk_ret, v4, v12, v22, v30
+	v23:i64 = Load module_ctx, 0x18
+	v24:i64 = Load module_ctx, 0x20
+	v25:i32 = CallIndirect v23:sig0, exec_ctx, v24
+	v26:i64 = Load module_ctx, 0x8
+	v27:i64 = Load v26, 0x0
+	v28:i64 = Load module_ctx, 0x8
+	v29:i64 = Load v28, 0x8
+	v30:i64 = Load module_ctx, 0x8
+	v31:i32 = Load v30, 0x8
+	v32:i32 = Iconst_32 0x10
+	v33:i32 = Ushr v31, v32
+	Jump blk_ret, v4, v12, v25, v33
 `,
 			expAfterPasses: `
 signatures:
 	sig0: i64i64_i32
-	sig2: i64i32_i32
+	sig2: i64i32i64_i64
 
 blk0: (exec_ctx:i64, module_ctx:i64)
 	Store module_ctx, exec_ctx, 0x8
@@ -1303,17 +1306,19 @@ blk0: (exec_ctx:i64, module_ctx:i64)
 	v12:i32 = Ushr v10, v11
 	v13:i32 = Iconst_32 0xa
 	Store module_ctx, exec_ctx, 0x8
-	v14:i64 = Load exec_ctx, 0x48
-	v15:i32 = CallIndirect v14:sig2, exec_ctx, v13
+	v14:i64 = UExtend v13, 32->64
+	v15:i64 = Load exec_ctx, 0x48
+	v16:i32 = Iconst_32 0x0
+	v17:i64 = CallIndirect v15:sig2, exec_ctx, v16, v14
 	Store module_ctx, exec_ctx, 0x8
-	v20:i64 = Load module_ctx, 0x18
-	v21:i64 = Load module_ctx, 0x20
-	v22:i32 = CallIndirect v20:sig0, exec_ctx, v21
-	v27:i64 = Load module_ctx, 0x8
-	v28:i32 = Load v27, 0x8
-	v29:i32 = Iconst_32 0x10
-	v30:i32 = Ushr v28, v29
-	Jump blk_ret, v4, v12, v22, v30
+	v23:i64 = Load module_ctx, 0x18
+	v24:i64 = Load module_ctx, 0x20
+	v25:i32 = CallIndirect v23:sig0, exec_ctx, v24
+	v30:i64 = Load module_ctx, 0x8
+	v31:i32 = Load v30, 0x8
+	v32:i32 = Iconst_32 0x10
+	v33:i32 = Ushr v31, v32
+	Jump blk_ret, v4, v12, v25, v33
 `,
 		},
 		{
@@ -1321,43 +1326,55 @@ blk0: (exec_ctx:i64, module_ctx:i64)
 			m:    testcases.MemorySizeGrow.Module,
 			exp: `
 signatures:
-	sig1: i64i32_i32
+	sig1: i64i32i64_i64
 
 blk0: (exec_ctx:i64, module_ctx:i64)
 	v2:i32 = Iconst_32 0x1
 	Store module_ctx, exec_ctx, 0x8
-	v3:i64 = Load exec_ctx, 0x48
-	v4:i32 = CallIndirect v3:sig1, exec_ctx, v2
-	v5:i64 = Load module_ctx, 0x8
-	v6:i64 = Uload32 module_ctx, 0x10
-	v7:i32 = Load module_ctx, 0x10
-	v8:i32 = Iconst_32 0x10
-	v9:i32 = Ushr v7, v8
-	v10:i32 = Iconst_32 0x1
+	v3:i64 = UExtend v2, 32->64
+	v4:i64 = Load exec_ctx, 0x48
+	v5:i32 = Iconst_32 0x0
+	v6:i64 = CallIndirect v4:sig1, exec_ctx, v5, v3
+	v7:i32 = Ireduce v6
+	v8:i64 = Load module_ctx, 0x8
+	v9:i64 = Uload32 module_ctx, 0x10
+	v10:i32 = Load module_ctx, 0x10
+	v11:i32 = Iconst_32 0x10
+	v12:i32 = Ushr v10, v11
+	v13:i32 = Iconst_32 0x1
 	Store module_ctx, exec_ctx, 0x8
-	v11:i64 = Load exec_ctx, 0x48
-	v12:i32 = CallIndirect v11:sig1, exec_ctx, v10
-	v13:i64 = Load module_ctx, 0x8
-	v14:i64 = Uload32 module_ctx, 0x10
-	Jump blk_ret, v4, v9, v12
+	v14:i64 = UExtend v13, 32->64
+	v15:i64 = Load exec_ctx, 0x48
+	v16:i32 = Iconst_32 0x0
+	v17:i64 = CallIndirect v15:sig1, exec_ctx, v16, v14
+	v18:i32 = Ireduce v17
+	v19:i64 = Load module_ctx, 0x8
+	v20:i64 = Uload32 module_ctx, 0x10
+	Jump blk_ret, v7, v12, v18
 `,
 			expAfterPasses: `
 signatures:
-	sig1: i64i32_i32
+	sig1: i64i32i64_i64
 
 blk0: (exec_ctx:i64, module_ctx:i64)
 	v2:i32 = Iconst_32 0x1
 	Store module_ctx, exec_ctx, 0x8
-	v3:i64 = Load exec_ctx, 0x48
-	v4:i32 = CallIndirect v3:sig1, exec_ctx, v2
-	v7:i32 = Load module_ctx, 0x10
-	v8:i32 = Iconst_32 0x10
-	v9:i32 = Ushr v7, v8
-	v10:i32 = Iconst_32 0x1
+	v3:i64 = UExtend v2, 32->64
+	v4:i64 = Load exec_ctx, 0x48
+	v5:i32 = Iconst_32 0x0
+	v6:i64 = CallIndirect v4:sig1, exec_ctx, v5, v3
+	v7:i32 = Ireduce v6
+	v10:i32 = Load module_ctx, 0x10
+	v11:i32 = Iconst_32 0x10
+	v12:i32 = Ushr v10, v11
+	v13:i32 = Iconst_32 0x1
 	Store module_ctx, exec_ctx, 0x8
-	v11:i64 = Load exec_ctx, 0x48
-	v12:i32 = CallIndirect v11:sig1, exec_ctx, v10
-	Jump blk_ret, v4, v9, v12
+	v14:i64 = UExtend v13, 32->64
+	v15:i64 = Load exec_ctx, 0x48
+	v16:i32 = Iconst_32 0x0
+	v17:i64 = CallIndirect v15:sig1, exec_ctx, v16, v14
+	v18:i32 = Ireduce v17
+	Jump blk_ret, v7, v12, v18
 `,
 		},
 		{
@@ -3004,7 +3021,7 @@ blk9: () <-- (blk4)
 			b := ssa.NewBuilder()
 
 			offset := wazevoapi.NewModuleContextOffsetData(tc.m, tc.needListener)
-			fc := NewFrontendCompiler(tc.m, b, &offset, tc.ensureTermination, tc.needListener, false)
+			fc := NewFrontendCompiler(tc.m, b, &offset, tc.ensureTermination, tc.needListener, false, false)
 			typeIndex := tc.m.FunctionSection[tc.targetIndex]
 			code := &tc.m.CodeSection[tc.targetIndex]
 			fc.Init(tc.targetIndex, typeIndex, &tc.m.TypeSection[typeIndex], code.LocalTypes, code.Body, tc.needListener, 0)
@@ -3087,7 +3104,7 @@ func TestCompiler_declareSignatures(t *testing.T) {
 			{ID: 1, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64, ssa.TypeI64, ssa.TypeI32}},
 			{ID: 2, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64, ssa.TypeF64, ssa.TypeI32}},
 			{ID: 3, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI64}, Results: []ssa.Type{ssa.TypeI64, ssa.TypeI32}},
-			{ID: 4, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32}, Results: []ssa.Type{ssa.TypeI32}},
+			{ID: 4, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32, ssa.TypeI64}, Results: []ssa.Type{ssa.TypeI64}},
 			{ID: 5, Params: []ssa.Type{ssa.TypeI64}},
 			{ID: 6, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32, ssa.TypeI32, ssa.TypeI64}, Results: []ssa.Type{ssa.TypeI32}},
 			{ID: 7, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32}, Results: []ssa.Type{ssa.TypeI64}},
@@ -3126,7 +3143,7 @@ func TestCompiler_declareSignatures(t *testing.T) {
 			{ID: 10, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32}},
 			{ID: 11, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32, ssa.TypeI64, ssa.TypeI32}},
 			// Misc.
-			{ID: 12, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32}, Results: []ssa.Type{ssa.TypeI32}},
+			{ID: 12, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32, ssa.TypeI64}, Results: []ssa.Type{ssa.TypeI64}},
 			{ID: 13, Params: []ssa.Type{ssa.TypeI64}},
 			{ID: 14, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32, ssa.TypeI32, ssa.TypeI64}, Results: []ssa.Type{ssa.TypeI32}},
 			{ID: 15, Params: []ssa.Type{ssa.TypeI64, ssa.TypeI32}, Results: []ssa.Type{ssa.TypeI64}},
@@ -3212,7 +3229,7 @@ func TestKnownSafeBound_valid(t *testing.T) {
 }
 
 func TestCompiler_finalizeKnownSafeBoundsAtTheEndOoBlock(t *testing.T) {
-	c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false)
+	c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false, false)
 	blk := c.ssaBuilder.AllocateBasicBlock()
 	require.True(t, len(c.getKnownSafeBoundsAtTheEndOfBlocks(blk.ID()).View()) == 0)
 	c.ssaBuilder.SetCurrentBlock(blk)
@@ -3233,7 +3250,7 @@ func TestCompiler_finalizeKnownSafeBoundsAtTheEndOoBlock(t *testing.T) {
 
 func TestCompiler_initializeCurrentBlockKnownBounds(t *testing.T) {
 	t.Run("single (sealed)", func(t *testing.T) {
-		c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false)
+		c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false, false)
 		builder := c.ssaBuilder
 		child := builder.AllocateBasicBlock()
 		{
@@ -3264,7 +3281,7 @@ func TestCompiler_initializeCurrentBlockKnownBounds(t *testing.T) {
 		require.Equal(t, ssa.Value(54321), kb.absoluteAddr)
 	})
 	t.Run("single (unsealed)", func(t *testing.T) {
-		c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false)
+		c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false, false)
 		builder := c.ssaBuilder
 		child := builder.AllocateBasicBlock()
 		{
@@ -3294,7 +3311,7 @@ func TestCompiler_initializeCurrentBlockKnownBounds(t *testing.T) {
 		require.NotEqual(t, ssa.Value(54321), kb.absoluteAddr)
 	})
 	t.Run("multiple predecessors", func(t *testing.T) {
-		c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false)
+		c := NewFrontendCompiler(&wasm.Module{}, ssa.NewBuilder(), nil, false, false, false, false)
 		builder := c.ssaBuilder
 		child := builder.AllocateBasicBlock()
 		{
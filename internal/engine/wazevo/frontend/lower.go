@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/leb128"
@@ -23,6 +24,8 @@ type (
 		unreachable      bool
 		unreachableDepth int
 		tmpForBrTable    []uint32
+		tmpForTryTable   []wasm.TryTableCatch
+		tmpForResume     []uint32
 		pc               int
 	}
 	controlFrame struct {
@@ -37,6 +40,19 @@ type (
 		blockType *wasm.FunctionType
 		// clonedArgs hold the arguments to Else block.
 		clonedArgs ssa.Values
+		// catches holds the catch clauses if this is a try_table frame.
+		catches []catchClause
+		// exceptionDispatchBlk is the block which transfers the control to the matching catch clause
+		// of this try_table frame. This is lazily allocated when the exception can be thrown in the frame.
+		exceptionDispatchBlk ssa.BasicBlock
+	}
+
+	// catchClause is a catch clause of try_table whose label is resolved to the target block.
+	catchClause struct {
+		kind      wasm.CatchKind
+		tag       wasm.Index
+		targetBlk ssa.BasicBlock
+		argNum    int
 	}
 
 	controlFrameKind byte
@@ -618,26 +634,35 @@ func (c *Compiler) lowerCurrentOpcode() {
 			c.callMemmove(dstAddr, srcAddr, copySizeInBytes)
 
 		case wasm.OpcodeMiscMemoryCopy:
-			state.pc += 2 // +2 to skip two memory indexes which are fixed to zero.
+			dstMemIdx := c.readI32u()
+			srcMemIdx := c.readI32u()
 			if state.unreachable {
 				break
 			}
 
-			copySize := builder.
-				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
-			srcOffset := builder.
-				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
-			dstOffset := builder.
-				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
+			dst64, src64 := c.isMemory64(dstMemIdx), c.isMemory64(srcMemIdx)
+			copySize := c.popMemoryOperand(dst64 && src64)
+			srcOffset := c.popMemoryOperand(src64)
+			dstOffset := c.popMemoryOperand(dst64)
 
-			// Out of bounds check.
-			memLen := c.getMemoryLenValue(false)
-			c.boundsCheckInMemory(memLen, dstOffset, copySize)
-			c.boundsCheckInMemory(memLen, srcOffset, copySize)
+			var dstAddr, srcAddr ssa.Value
+			if dstMemIdx == 0 && srcMemIdx == 0 {
+				// Out of bounds check.
+				memLen := c.getMemoryLenValue(false)
+				c.boundsCheckInMemory(memLen, dstOffset, copySize, dst64)
+				c.boundsCheckInMemory(memLen, srcOffset, copySize, src64)
+
+				memBase := c.getMemoryBaseValue(false)
+				dstAddr = builder.AllocateInstruction().AsIadd(memBase, dstOffset).Insert(builder).Return()
+				srcAddr = builder.AllocateInstruction().AsIadd(memBase, srcOffset).Insert(builder).Return()
+			} else {
+				// Out of bounds check.
+				c.boundsCheckInMemory(c.getMemoryLenValueAt(dstMemIdx), dstOffset, copySize, dst64)
+				c.boundsCheckInMemory(c.getMemoryLenValueAt(srcMemIdx), srcOffset, copySize, src64)
 
-			memBase := c.getMemoryBaseValue(false)
-			dstAddr := builder.AllocateInstruction().AsIadd(memBase, dstOffset).Insert(builder).Return()
-			srcAddr := builder.AllocateInstruction().AsIadd(memBase, srcOffset).Insert(builder).Return()
+				dstAddr = builder.AllocateInstruction().AsIadd(c.getMemoryBaseValueAt(dstMemIdx), dstOffset).Insert(builder).Return()
+				srcAddr = builder.AllocateInstruction().AsIadd(c.getMemoryBaseValueAt(srcMemIdx), srcOffset).Insert(builder).Return()
+			}
 
 			c.callMemmove(dstAddr, srcAddr, copySize)
 
@@ -718,22 +743,21 @@ func (c *Compiler) lowerCurrentOpcode() {
 			builder.Seal(followingBlk)
 
 		case wasm.OpcodeMiscMemoryFill:
-			state.pc++ // Skip the memory index which is fixed to zero.
+			memIdx := c.readI32u()
 			if state.unreachable {
 				break
 			}
 
-			fillSize := builder.
-				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
+			is64 := c.isMemory64(memIdx)
+			fillSize := c.popMemoryOperand(is64)
 			value := state.pop()
-			offset := builder.
-				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
+			offset := c.popMemoryOperand(is64)
 
 			// Out of bounds check.
-			c.boundsCheckInMemory(c.getMemoryLenValue(false), offset, fillSize)
+			c.boundsCheckInMemory(c.getMemoryLenValueAt(memIdx), offset, fillSize, is64)
 
 			// Calculate the base address:
-			addr := builder.AllocateInstruction().AsIadd(c.getMemoryBaseValue(false), offset).Insert(builder).Return()
+			addr := builder.AllocateInstruction().AsIadd(c.getMemoryBaseValueAt(memIdx), offset).Insert(builder).Return()
 
 			// Uses the copy trick for faster filling buffer, with a maximum chunk size of 8KB.
 			// https://github.com/golang/go/blob/go1.24.0/src/bytes/bytes.go#L664-L673
@@ -798,7 +822,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 		case wasm.OpcodeMiscMemoryInit:
 			index := c.readI32u()
-			state.pc++ // Skip the memory index which is fixed to zero.
+			memIdx := c.readI32u()
 			if state.unreachable {
 				break
 			}
@@ -807,19 +831,19 @@ func (c *Compiler) lowerCurrentOpcode() {
 				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
 			offsetInDataInstance := builder.
 				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
-			offsetInMemory := builder.
-				AllocateInstruction().AsUExtend(state.pop(), 32, 64).Insert(builder).Return()
+			is64 := c.isMemory64(memIdx)
+			offsetInMemory := c.popMemoryOperand(is64)
 
 			dataInstPtr := c.dataOrElementInstanceAddr(index, c.offset.DataInstances1stElement)
 
 			// Bounds check.
-			c.boundsCheckInMemory(c.getMemoryLenValue(false), offsetInMemory, copySize)
+			c.boundsCheckInMemory(c.getMemoryLenValueAt(memIdx), offsetInMemory, copySize, is64)
 			c.boundsCheckInDataOrElementInstance(dataInstPtr, offsetInDataInstance, copySize, wazevoapi.ExitCodeMemoryOutOfBounds)
 
 			dataInstBaseAddr := builder.AllocateInstruction().AsLoad(dataInstPtr, 0, ssa.TypeI64).Insert(builder).Return()
 			srcAddr := builder.AllocateInstruction().AsIadd(dataInstBaseAddr, offsetInDataInstance).Insert(builder).Return()
 
-			memBase := c.getMemoryBaseValue(false)
+			memBase := c.getMemoryBaseValueAt(memIdx)
 			dstAddr := builder.AllocateInstruction().AsIadd(memBase, offsetInMemory).Insert(builder).Return()
 
 			c.callMemmove(dstAddr, srcAddr, copySize)
@@ -873,6 +897,40 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			c.dropDataOrElementInstance(index, c.offset.DataInstances1stElement)
 
+		case wasm.OpcodeMiscI64Add128, wasm.OpcodeMiscI64Sub128:
+			if state.unreachable {
+				break
+			}
+			yHi, yLo := state.pop(), state.pop()
+			xHi, xLo := state.pop(), state.pop()
+			args := c.allocateVarLengthValues(4, xLo, xHi, yLo, yHi)
+			instr := builder.AllocateInstruction()
+			if miscOp == wasm.OpcodeMiscI64Add128 {
+				instr.AsIadd128(args)
+			} else {
+				instr.AsIsub128(args)
+			}
+			builder.InsertInstruction(instr)
+			lo, rest := instr.Returns()
+			state.push(lo)
+			state.push(rest[0])
+
+		case wasm.OpcodeMiscI64MulWideS, wasm.OpcodeMiscI64MulWideU:
+			if state.unreachable {
+				break
+			}
+			y, x := state.pop(), state.pop()
+			instr := builder.AllocateInstruction()
+			if miscOp == wasm.OpcodeMiscI64MulWideS {
+				instr.AsSmulWide(x, y)
+			} else {
+				instr.AsUmulWide(x, y)
+			}
+			builder.InsertInstruction(instr)
+			lo, rest := instr.Returns()
+			state.push(lo)
+			state.push(rest[0])
+
 		default:
 			panic("Unknown MiscOp " + wasm.MiscInstructionName(miscOp))
 		}
@@ -1105,7 +1163,14 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 	case wasm.OpcodeSelect, wasm.OpcodeTypedSelect:
 		if op == wasm.OpcodeTypedSelect {
-			state.pc += 2 // ignores the type which is only needed during validation.
+			// ignores the type which is only needed during validation, and can be longer than one byte as per
+			// the function-references proposal.
+			c.br.Reset(c.wasmFunctionBody[state.pc+2:])
+			_, _, num, err := wasm.DecodeValueType(c.br, typeDecodingFeatures)
+			if err != nil {
+				panic(err) // shouldn't be reached since compilation comes after validation.
+			}
+			state.pc += 1 + int(num)
 		}
 
 		if state.unreachable {
@@ -1123,31 +1188,47 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(sl)
 
 	case wasm.OpcodeMemorySize:
-		state.pc++ // skips the memory index.
+		memIdx := c.readI32u()
 		if state.unreachable {
 			break
 		}
 
+		// The size of memory64 memories can exceed 32-bit, so it is loaded as i64.
+		sizeType := ssa.TypeI32
+		is64 := c.isMemory64(memIdx)
+		if is64 {
+			sizeType = ssa.TypeI64
+		}
+
 		var memSizeInBytes ssa.Value
-		if c.offset.LocalMemoryBegin < 0 {
+		if memIdx != 0 {
+			memSizeInBytes = builder.AllocateInstruction().
+				AsLoad(c.memoryInstanceAt(memIdx), memoryInstanceBufSizeOffset, sizeType).
+				Insert(builder).
+				Return()
+		} else if c.offset.LocalMemoryBegin < 0 {
 			memInstPtr := builder.AllocateInstruction().
 				AsLoad(c.moduleCtxPtrValue, c.offset.ImportedMemoryBegin.U32(), ssa.TypeI64).
 				Insert(builder).
 				Return()
 
 			memSizeInBytes = builder.AllocateInstruction().
-				AsLoad(memInstPtr, memoryInstanceBufSizeOffset, ssa.TypeI32).
+				AsLoad(memInstPtr, memoryInstanceBufSizeOffset, sizeType).
 				Insert(builder).
 				Return()
 		} else {
 			memSizeInBytes = builder.AllocateInstruction().
-				AsLoad(c.moduleCtxPtrValue, c.offset.LocalMemoryLen().U32(), ssa.TypeI32).
+				AsLoad(c.moduleCtxPtrValue, c.offset.LocalMemoryLen().U32(), sizeType).
 				Insert(builder).
 				Return()
 		}
 
 		amount := builder.AllocateInstruction()
-		amount.AsIconst32(uint32(wasm.MemoryPageSizeInBits))
+		if is64 {
+			amount.AsIconst64(uint64(c.memoryPageSizeInBits(memIdx)))
+		} else {
+			amount.AsIconst32(c.memoryPageSizeInBits(memIdx))
+		}
 		builder.InsertInstruction(amount)
 		memSize := builder.AllocateInstruction().
 			AsUshr(memSizeInBytes, amount.Return()).
@@ -1156,28 +1237,38 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(memSize)
 
 	case wasm.OpcodeMemoryGrow:
-		state.pc++ // skips the memory index.
+		memIdx := c.readI32u()
 		if state.unreachable {
 			break
 		}
 
 		c.storeCallerModuleContext()
 
+		// The trampoline takes and returns the pages in i64 to support memory64.
+		is64 := c.isMemory64(memIdx)
 		pages := state.pop()
+		if !is64 {
+			pages = builder.AllocateInstruction().AsUExtend(pages, 32, 64).Insert(builder).Return()
+		}
 		memoryGrowPtr := builder.AllocateInstruction().
 			AsLoad(c.execCtxPtrValue,
 				wazevoapi.ExecutionContextOffsetMemoryGrowTrampolineAddress.U32(),
 				ssa.TypeI64,
 			).Insert(builder).Return()
 
-		args := c.allocateVarLengthValues(2, c.execCtxPtrValue, pages)
+		memIdxValue := builder.AllocateInstruction().AsIconst32(memIdx).Insert(builder).Return()
+		args := c.allocateVarLengthValues(3, c.execCtxPtrValue, memIdxValue, pages)
 		callGrowRet := builder.
 			AllocateInstruction().
 			AsCallIndirect(memoryGrowPtr, &c.memoryGrowSig, args).
 			Insert(builder).Return()
+		if !is64 {
+			callGrowRet = builder.AllocateInstruction().AsIreduce(callGrowRet, ssa.TypeI32).Insert(builder).Return()
+		}
 		state.push(callGrowRet)
 
-		// After the memory grow, reload the cached memory base and len.
+		// After the memory grow, reload the cached memory base and len. This is necessary even when the grown memory
+		// isn't at index zero, as the same memory can be imported more than once.
 		c.reloadMemoryBaseLen()
 
 	case wasm.OpcodeI32Store,
@@ -1190,7 +1281,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		wasm.OpcodeI64Store16,
 		wasm.OpcodeI64Store32:
 
-		_, offset := c.readMemArg()
+		memIdx, constOffset := c.readMemArg()
 		if state.unreachable {
 			break
 		}
@@ -1218,7 +1309,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 		value := state.pop()
 		baseAddr := state.pop()
-		addr := c.memOpSetup(baseAddr, uint64(offset), opSize)
+		addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, opSize)
 		builder.AllocateInstruction().
 			AsStore(opcode, value, addr, offset).
 			Insert(builder)
@@ -1237,7 +1328,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		wasm.OpcodeI64Load16U,
 		wasm.OpcodeI64Load32S,
 		wasm.OpcodeI64Load32U:
-		_, offset := c.readMemArg()
+		memIdx, constOffset := c.readMemArg()
 		if state.unreachable {
 			break
 		}
@@ -1263,7 +1354,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		}
 
 		baseAddr := state.pop()
-		addr := c.memOpSetup(baseAddr, uint64(offset), opSize)
+		addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, opSize)
 		load := builder.AllocateInstruction()
 		switch op {
 		case wasm.OpcodeI32Load:
@@ -1452,9 +1543,16 @@ func (c *Compiler) lowerCurrentOpcode() {
 			state.unreachable = false
 		}
 
+		if ctrl.exceptionDispatchBlk != nil {
+			// All the calls and throws in the try_table are known now.
+			builder.Seal(ctrl.exceptionDispatchBlk)
+		}
+
 		switch ctrl.kind {
 		case controlFrameKindFunction:
-			break // This is the very end of function.
+			if c.exceptionPropagateBlk != nil {
+				builder.Seal(c.exceptionPropagateBlk)
+			}
 		case controlFrameKindLoop:
 			// Loop header block can be reached from any br/br_table contained in the loop,
 			// so now that we've reached End of it, we can seal it.
@@ -1490,47 +1588,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		}
 
 		v := state.pop()
-
-		targetBlk, argNum := state.brTargetArgNumFor(labelIndex)
-		args := c.nPeekDup(argNum)
-		var sealTargetBlk bool
-		if c.needListener && targetBlk.ReturnBlock() { // In this case, we have to call the listener before returning.
-			// Save the currently active block.
-			current := builder.CurrentBlock()
-
-			// Allocate the trampoline block to the return where we call the listener.
-			targetBlk = builder.AllocateBasicBlock()
-			builder.SetCurrentBlock(targetBlk)
-			sealTargetBlk = true
-
-			c.callListenerAfter()
-
-			instr := builder.AllocateInstruction()
-			instr.AsReturn(args)
-			builder.InsertInstruction(instr)
-
-			args = ssa.ValuesNil
-
-			// Revert the current block.
-			builder.SetCurrentBlock(current)
-		}
-
-		// Insert the conditional jump to the target block.
-		brnz := builder.AllocateInstruction()
-		brnz.AsBrnz(v, args, targetBlk)
-		builder.InsertInstruction(brnz)
-
-		if sealTargetBlk {
-			builder.Seal(targetBlk)
-		}
-
-		// Insert the unconditional jump to the Else block which corresponds to after br_if.
-		elseBlk := builder.AllocateBasicBlock()
-		c.insertJumpToBlock(ssa.ValuesNil, elseBlk)
-
-		// Now start translating the instructions after br_if.
-		builder.Seal(elseBlk) // Else of br_if has the current block as the only one successor.
-		builder.SetCurrentBlock(elseBlk)
+		c.lowerBrIf(labelIndex, v)
 
 	case wasm.OpcodeBrTable:
 		labels := state.tmpForBrTable[:0]
@@ -1590,6 +1648,81 @@ func (c *Compiler) lowerCurrentOpcode() {
 		}
 		c.lowerCall(fnIndex)
 
+	case wasm.OpcodeExceptionHandlingTryTable:
+		bt := c.readBlockType()
+		catches := c.readTryTableCatches()
+
+		if state.unreachable {
+			state.unreachableDepth++
+			break
+		}
+
+		// The labels of catch clauses are relative to the enclosing frames of this try_table.
+		var clauses []catchClause
+		if len(catches) > 0 {
+			clauses = make([]catchClause, len(catches))
+			for i := range catches {
+				catch := &catches[i]
+				targetBlk, argNum := state.brTargetArgNumFor(catch.Label)
+				clauses[i] = catchClause{kind: catch.Kind, tag: catch.Tag, targetBlk: targetBlk, argNum: argNum}
+			}
+		}
+
+		followingBlk := builder.AllocateBasicBlock()
+		c.addBlockParamsFromWasmTypes(bt.Results, followingBlk)
+
+		state.ctrlPush(controlFrame{
+			kind:                         controlFrameKindBlock,
+			originalStackLenWithoutParam: len(state.values) - len(bt.Params),
+			followingBlock:               followingBlk,
+			blockType:                    bt,
+			catches:                      clauses,
+		})
+
+	case wasm.OpcodeExceptionHandlingThrow:
+		tagIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		c.lowerThrow(tagIndex)
+		state.unreachable = true
+
+	case wasm.OpcodeExceptionHandlingThrowRef:
+		if state.unreachable {
+			break
+		}
+		c.lowerThrowRef()
+		state.unreachable = true
+
+	case wasm.OpcodeStackSwitchingContNew:
+		typeIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		c.lowerContNew(typeIndex)
+
+	case wasm.OpcodeStackSwitchingContBind:
+		from, to := c.readI32u(), c.readI32u()
+		if state.unreachable {
+			break
+		}
+		c.lowerContBind(from, to)
+
+	case wasm.OpcodeStackSwitchingSuspend:
+		tagIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		c.lowerSuspend(tagIndex)
+
+	case wasm.OpcodeStackSwitchingResume:
+		typeIndex := c.readI32u()
+		handlers := c.readResumeHandlers()
+		if state.unreachable {
+			break
+		}
+		c.lowerResume(typeIndex, handlers)
+
 	case wasm.OpcodeDrop:
 		if state.unreachable {
 			break
@@ -1635,6 +1768,13 @@ func (c *Compiler) lowerCurrentOpcode() {
 	case wasm.OpcodeVecPrefix:
 		state.pc++
 		vecOp := c.wasmFunctionBody[state.pc]
+		if wasm.IsVecRelaxed(c.wasmFunctionBody, uint64(state.pc)) {
+			state.pc++ // Skip wasm.OpcodeVecRelaxedSecondByte.
+			if !state.unreachable {
+				c.lowerVecRelaxed(vecOp)
+			}
+			break
+		}
 		switch vecOp {
 		case wasm.OpcodeVecV128Const:
 			state.pc++
@@ -1648,18 +1788,18 @@ func (c *Compiler) lowerCurrentOpcode() {
 			ret := builder.AllocateInstruction().AsVconst(lo, hi).Insert(builder).Return()
 			state.push(ret)
 		case wasm.OpcodeVecV128Load:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), 16)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, 16)
 			load := builder.AllocateInstruction()
 			load.AsLoad(addr, offset, ssa.TypeV128)
 			builder.InsertInstruction(load)
 			state.push(load.Return())
 		case wasm.OpcodeVecV128Load8Lane, wasm.OpcodeVecV128Load16Lane, wasm.OpcodeVecV128Load32Lane:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			state.pc++
 			if state.unreachable {
 				break
@@ -1678,7 +1818,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			laneIndex := c.wasmFunctionBody[state.pc]
 			vector := state.pop()
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), opSize)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, opSize)
 			load := builder.AllocateInstruction().
 				AsExtLoad(loadOp, addr, offset, false).
 				Insert(builder).Return()
@@ -1687,7 +1827,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 				Insert(builder).Return()
 			state.push(ret)
 		case wasm.OpcodeVecV128Load64Lane:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			state.pc++
 			if state.unreachable {
 				break
@@ -1695,7 +1835,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			laneIndex := c.wasmFunctionBody[state.pc]
 			vector := state.pop()
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), 8)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, 8)
 			load := builder.AllocateInstruction().
 				AsLoad(addr, offset, ssa.TypeI64).
 				Insert(builder).Return()
@@ -1705,7 +1845,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			state.push(ret)
 
 		case wasm.OpcodeVecV128Load32zero, wasm.OpcodeVecV128Load64zero:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -1719,7 +1859,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), uint64(scalarType.Size()))
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, uint64(scalarType.Size()))
 
 			ret := builder.AllocateInstruction().
 				AsVZeroExtLoad(addr, offset, scalarType).
@@ -1729,7 +1869,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		case wasm.OpcodeVecV128Load8x8u, wasm.OpcodeVecV128Load8x8s,
 			wasm.OpcodeVecV128Load16x4u, wasm.OpcodeVecV128Load16x4s,
 			wasm.OpcodeVecV128Load32x2u, wasm.OpcodeVecV128Load32x2s:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -1753,7 +1893,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 				lane = ssa.VecLaneI32x4
 			}
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), 8)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, 8)
 			load := builder.AllocateInstruction().
 				AsLoad(addr, offset, ssa.TypeF64).
 				Insert(builder).Return()
@@ -1763,7 +1903,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			state.push(ret)
 		case wasm.OpcodeVecV128Load8Splat, wasm.OpcodeVecV128Load16Splat,
 			wasm.OpcodeVecV128Load32Splat, wasm.OpcodeVecV128Load64Splat:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -1780,25 +1920,25 @@ func (c *Compiler) lowerCurrentOpcode() {
 				lane, opSize = ssa.VecLaneI64x2, 8
 			}
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), opSize)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, opSize)
 			ret := builder.AllocateInstruction().
 				AsLoadSplat(addr, offset, lane).
 				Insert(builder).Return()
 			state.push(ret)
 		case wasm.OpcodeVecV128Store:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
 			value := state.pop()
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), 16)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, 16)
 			builder.AllocateInstruction().
 				AsStore(ssa.OpcodeStore, value, addr, offset).
 				Insert(builder)
 		case wasm.OpcodeVecV128Store8Lane, wasm.OpcodeVecV128Store16Lane,
 			wasm.OpcodeVecV128Store32Lane, wasm.OpcodeVecV128Store64Lane:
-			_, offset := c.readMemArg()
+			memIdx, constOffset := c.readMemArg()
 			state.pc++
 			if state.unreachable {
 				break
@@ -1819,7 +1959,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			vector := state.pop()
 			baseAddr := state.pop()
-			addr := c.memOpSetup(baseAddr, uint64(offset), opSize)
+			addr, offset := c.memOpSetup(memIdx, baseAddr, constOffset, opSize)
 			value := builder.AllocateInstruction().
 				AsExtractlane(vector, laneIndex, lane, false).
 				Insert(builder).Return()
@@ -3090,7 +3230,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		atomicOp := c.wasmFunctionBody[state.pc]
 		switch atomicOp {
 		case wasm.OpcodeAtomicMemoryWait32, wasm.OpcodeAtomicMemoryWait64:
-			_, offset := c.readMemArg()
+			memIdx, offset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -3114,7 +3254,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			timeout := state.pop()
 			exp := state.pop()
 			baseAddr := state.pop()
-			addr := c.atomicMemOpSetup(baseAddr, uint64(offset), opSize)
+			addr := c.atomicMemOpSetup(memIdx, baseAddr, offset, opSize)
 
 			memoryWaitPtr := builder.AllocateInstruction().
 				AsLoad(c.execCtxPtrValue,
@@ -3128,7 +3268,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 				Insert(builder).Return()
 			state.push(memoryWaitRet)
 		case wasm.OpcodeAtomicMemoryNotify:
-			_, offset := c.readMemArg()
+			memIdx, offset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -3136,7 +3276,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			c.storeCallerModuleContext()
 			count := state.pop()
 			baseAddr := state.pop()
-			addr := c.atomicMemOpSetup(baseAddr, uint64(offset), 4)
+			addr := c.atomicMemOpSetup(memIdx, baseAddr, offset, 4)
 
 			memoryNotifyPtr := builder.AllocateInstruction().
 				AsLoad(c.execCtxPtrValue,
@@ -3149,7 +3289,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 				Insert(builder).Return()
 			state.push(memoryNotifyRet)
 		case wasm.OpcodeAtomicI32Load, wasm.OpcodeAtomicI64Load, wasm.OpcodeAtomicI32Load8U, wasm.OpcodeAtomicI32Load16U, wasm.OpcodeAtomicI64Load8U, wasm.OpcodeAtomicI64Load16U, wasm.OpcodeAtomicI64Load32U:
-			_, offset := c.readMemArg()
+			memIdx, offset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -3176,11 +3316,11 @@ func (c *Compiler) lowerCurrentOpcode() {
 				typ = ssa.TypeI32
 			}
 
-			addr := c.atomicMemOpSetup(baseAddr, uint64(offset), size)
+			addr := c.atomicMemOpSetup(memIdx, baseAddr, offset, size)
 			res := builder.AllocateInstruction().AsAtomicLoad(addr, size, typ).Insert(builder).Return()
 			state.push(res)
 		case wasm.OpcodeAtomicI32Store, wasm.OpcodeAtomicI64Store, wasm.OpcodeAtomicI32Store8, wasm.OpcodeAtomicI32Store16, wasm.OpcodeAtomicI64Store8, wasm.OpcodeAtomicI64Store16, wasm.OpcodeAtomicI64Store32:
-			_, offset := c.readMemArg()
+			memIdx, offset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -3200,7 +3340,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 				size = 1
 			}
 
-			addr := c.atomicMemOpSetup(baseAddr, uint64(offset), size)
+			addr := c.atomicMemOpSetup(memIdx, baseAddr, offset, size)
 			builder.AllocateInstruction().AsAtomicStore(addr, val, size).Insert(builder)
 		case wasm.OpcodeAtomicI32RmwAdd, wasm.OpcodeAtomicI64RmwAdd, wasm.OpcodeAtomicI32Rmw8AddU, wasm.OpcodeAtomicI32Rmw16AddU, wasm.OpcodeAtomicI64Rmw8AddU, wasm.OpcodeAtomicI64Rmw16AddU, wasm.OpcodeAtomicI64Rmw32AddU,
 			wasm.OpcodeAtomicI32RmwSub, wasm.OpcodeAtomicI64RmwSub, wasm.OpcodeAtomicI32Rmw8SubU, wasm.OpcodeAtomicI32Rmw16SubU, wasm.OpcodeAtomicI64Rmw8SubU, wasm.OpcodeAtomicI64Rmw16SubU, wasm.OpcodeAtomicI64Rmw32SubU,
@@ -3208,7 +3348,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			wasm.OpcodeAtomicI32RmwOr, wasm.OpcodeAtomicI64RmwOr, wasm.OpcodeAtomicI32Rmw8OrU, wasm.OpcodeAtomicI32Rmw16OrU, wasm.OpcodeAtomicI64Rmw8OrU, wasm.OpcodeAtomicI64Rmw16OrU, wasm.OpcodeAtomicI64Rmw32OrU,
 			wasm.OpcodeAtomicI32RmwXor, wasm.OpcodeAtomicI64RmwXor, wasm.OpcodeAtomicI32Rmw8XorU, wasm.OpcodeAtomicI32Rmw16XorU, wasm.OpcodeAtomicI64Rmw8XorU, wasm.OpcodeAtomicI64Rmw16XorU, wasm.OpcodeAtomicI64Rmw32XorU,
 			wasm.OpcodeAtomicI32RmwXchg, wasm.OpcodeAtomicI64RmwXchg, wasm.OpcodeAtomicI32Rmw8XchgU, wasm.OpcodeAtomicI32Rmw16XchgU, wasm.OpcodeAtomicI64Rmw8XchgU, wasm.OpcodeAtomicI64Rmw16XchgU, wasm.OpcodeAtomicI64Rmw32XchgU:
-			_, offset := c.readMemArg()
+			memIdx, offset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -3293,11 +3433,11 @@ func (c *Compiler) lowerCurrentOpcode() {
 				}
 			}
 
-			addr := c.atomicMemOpSetup(baseAddr, uint64(offset), size)
+			addr := c.atomicMemOpSetup(memIdx, baseAddr, offset, size)
 			res := builder.AllocateInstruction().AsAtomicRmw(rmwOp, addr, val, size).Insert(builder).Return()
 			state.push(res)
 		case wasm.OpcodeAtomicI32RmwCmpxchg, wasm.OpcodeAtomicI64RmwCmpxchg, wasm.OpcodeAtomicI32Rmw8CmpxchgU, wasm.OpcodeAtomicI32Rmw16CmpxchgU, wasm.OpcodeAtomicI64Rmw8CmpxchgU, wasm.OpcodeAtomicI64Rmw16CmpxchgU, wasm.OpcodeAtomicI64Rmw32CmpxchgU:
-			_, offset := c.readMemArg()
+			memIdx, offset := c.readMemArg()
 			if state.unreachable {
 				break
 			}
@@ -3317,7 +3457,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			case wasm.OpcodeAtomicI32Rmw8CmpxchgU, wasm.OpcodeAtomicI64Rmw8CmpxchgU:
 				size = 1
 			}
-			addr := c.atomicMemOpSetup(baseAddr, uint64(offset), size)
+			addr := c.atomicMemOpSetup(memIdx, baseAddr, offset, size)
 			res := builder.AllocateInstruction().AsAtomicCas(addr, exp, repl, size).Insert(builder).Return()
 			state.push(res)
 		case wasm.OpcodeAtomicFence:
@@ -3355,7 +3495,13 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(refFuncRet)
 
 	case wasm.OpcodeRefNull:
-		c.loweringState.pc++ // skips the reference type as we treat both of them as i64(0).
+		// skips the heap type as we treat all of them as i64(0).
+		c.br.Reset(c.wasmFunctionBody[state.pc+1:])
+		_, num, err := wasm.DecodeHeapType(c.br, typeDecodingFeatures)
+		if err != nil {
+			panic(err) // shouldn't be reached since compilation comes after validation.
+		}
+		state.pc += int(num)
 		if state.unreachable {
 			break
 		}
@@ -3411,6 +3557,65 @@ func (c *Compiler) lowerCurrentOpcode() {
 		c.lowerTailCallReturnCall(fnIndex)
 		state.unreachable = true
 
+	case wasm.OpcodeFunctionReferencesCallRef:
+		typeIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		c.lowerCallRef(typeIndex)
+
+	case wasm.OpcodeFunctionReferencesReturnCallRef:
+		typeIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		c.lowerTailCallReturnCallRef(typeIndex)
+		state.unreachable = true
+
+	case wasm.OpcodeFunctionReferencesRefAsNonNull:
+		if state.unreachable {
+			break
+		}
+		r := state.peek()
+		zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+		isNull := builder.AllocateInstruction().
+			AsIcmp(r, zero, ssa.IntegerCmpCondEqual).
+			Insert(builder).
+			Return()
+		builder.AllocateInstruction().
+			AsExitIfTrueWithCode(c.execCtxPtrValue, isNull, wazevoapi.ExitCodeNullReference).
+			Insert(builder)
+
+	case wasm.OpcodeFunctionReferencesBrOnNull:
+		labelIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		// The label doesn't receive the reference, which is pushed back if not branching.
+		r := state.pop()
+		zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+		isNull := builder.AllocateInstruction().
+			AsIcmp(r, zero, ssa.IntegerCmpCondEqual).
+			Insert(builder).
+			Return()
+		c.lowerBrIf(labelIndex, isNull)
+		state.push(r)
+
+	case wasm.OpcodeFunctionReferencesBrOnNonNull:
+		labelIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+		// The label receives the reference, which is dropped if not branching.
+		r := state.peek()
+		zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+		isNonNull := builder.AllocateInstruction().
+			AsIcmp(r, zero, ssa.IntegerCmpCondNotEqual).
+			Insert(builder).
+			Return()
+		c.lowerBrIf(labelIndex, isNonNull)
+		state.pop()
+
 	default:
 		panic("TODO: unsupported in wazevo yet: " + wasm.InstructionName(op))
 	}
@@ -3424,6 +3629,53 @@ func (c *Compiler) lowerCurrentOpcode() {
 	c.loweringState.pc++
 }
 
+// lowerBrIf inserts the conditional jump to the label if v is non-zero, and starts the block after it.
+func (c *Compiler) lowerBrIf(labelIndex uint32, v ssa.Value) {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	targetBlk, argNum := state.brTargetArgNumFor(labelIndex)
+	args := c.nPeekDup(argNum)
+	var sealTargetBlk bool
+	if c.needListener && targetBlk.ReturnBlock() { // In this case, we have to call the listener before returning.
+		// Save the currently active block.
+		current := builder.CurrentBlock()
+
+		// Allocate the trampoline block to the return where we call the listener.
+		targetBlk = builder.AllocateBasicBlock()
+		builder.SetCurrentBlock(targetBlk)
+		sealTargetBlk = true
+
+		c.callListenerAfter()
+
+		instr := builder.AllocateInstruction()
+		instr.AsReturn(args)
+		builder.InsertInstruction(instr)
+
+		args = ssa.ValuesNil
+
+		// Revert the current block.
+		builder.SetCurrentBlock(current)
+	}
+
+	// Insert the conditional jump to the target block.
+	brnz := builder.AllocateInstruction()
+	brnz.AsBrnz(v, args, targetBlk)
+	builder.InsertInstruction(brnz)
+
+	if sealTargetBlk {
+		builder.Seal(targetBlk)
+	}
+
+	// Insert the unconditional jump to the Else block which corresponds to after br_if.
+	elseBlk := builder.AllocateBasicBlock()
+	c.insertJumpToBlock(ssa.ValuesNil, elseBlk)
+
+	// Now start translating the instructions after br_if.
+	builder.Seal(elseBlk) // Else of br_if has the current block as the only one successor.
+	builder.SetCurrentBlock(elseBlk)
+}
+
 func (c *Compiler) lowerReturn(builder ssa.Builder) {
 	results := c.nPeekDup(c.results())
 	instr := builder.AllocateInstruction()
@@ -3566,6 +3818,118 @@ func (c *Compiler) lowerCall(fnIndex uint32) {
 	}
 
 	c.reloadAfterCall()
+	c.checkPendingException()
+}
+
+// lowerVecRelaxed lowers the relaxed vector instruction vecOp. Unless the module is compiled with
+// wasm.Module DeterministicRelaxedSIMD, some of them are lowered to the native instructions whose results
+// depend on the CPU, e.g. relaxed_madd to a fused multiply-add. Otherwise, they are lowered the same way as
+// their deterministic counterparts.
+func (c *Compiler) lowerVecRelaxed(vecOp wasm.OpcodeVecRelaxed) {
+	builder := c.ssaBuilder
+	state := c.state()
+	deterministic := c.m.DeterministicRelaxedSIMD
+
+	switch vecOp {
+	case wasm.OpcodeVecI8x16RelaxedSwizzle:
+		v2 := state.pop()
+		v1 := state.pop()
+		var ret ssa.Value
+		if deterministic {
+			ret = builder.AllocateInstruction().AsSwizzle(v1, v2, ssa.VecLaneI8x16).Insert(builder).Return()
+		} else {
+			ret = builder.AllocateInstruction().AsRelaxedSwizzle(v1, v2, ssa.VecLaneI8x16).Insert(builder).Return()
+		}
+		state.push(ret)
+	case wasm.OpcodeVecI32x4RelaxedTruncF32x4S, wasm.OpcodeVecI32x4RelaxedTruncF32x4U:
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().
+			AsVFcvtToIntSat(v1, ssa.VecLaneF32x4, vecOp == wasm.OpcodeVecI32x4RelaxedTruncF32x4S).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().
+			AsVFcvtToIntSat(v1, ssa.VecLaneF64x2, vecOp == wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecF32x4RelaxedMadd, wasm.OpcodeVecF32x4RelaxedNmadd,
+		wasm.OpcodeVecF64x2RelaxedMadd, wasm.OpcodeVecF64x2RelaxedNmadd:
+		lane := ssa.VecLaneF32x4
+		if vecOp == wasm.OpcodeVecF64x2RelaxedMadd || vecOp == wasm.OpcodeVecF64x2RelaxedNmadd {
+			lane = ssa.VecLaneF64x2
+		}
+		v3 := state.pop()
+		v2 := state.pop()
+		v1 := state.pop()
+		if vecOp == wasm.OpcodeVecF32x4RelaxedNmadd || vecOp == wasm.OpcodeVecF64x2RelaxedNmadd {
+			// -(v1*v2) is the same as (-v1)*v2 regardless of the rounding.
+			v1 = builder.AllocateInstruction().AsVFneg(v1, lane).Insert(builder).Return()
+		}
+		var ret ssa.Value
+		if deterministic {
+			mul := builder.AllocateInstruction().AsVFmul(v1, v2, lane).Insert(builder).Return()
+			ret = builder.AllocateInstruction().AsVFadd(mul, v3, lane).Insert(builder).Return()
+		} else {
+			ret = builder.AllocateInstruction().AsVFma(v1, v2, v3, lane).Insert(builder).Return()
+		}
+		state.push(ret)
+	case wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+		wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect:
+		mask := state.pop()
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsVbitselect(mask, v1, v2).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecF32x4RelaxedMin, wasm.OpcodeVecF64x2RelaxedMin:
+		lane := ssa.VecLaneF32x4
+		if vecOp == wasm.OpcodeVecF64x2RelaxedMin {
+			lane = ssa.VecLaneF64x2
+		}
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsVFmin(v1, v2, lane).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecF32x4RelaxedMax, wasm.OpcodeVecF64x2RelaxedMax:
+		lane := ssa.VecLaneF32x4
+		if vecOp == wasm.OpcodeVecF64x2RelaxedMax {
+			lane = ssa.VecLaneF64x2
+		}
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsVFmax(v1, v2, lane).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecI16x8RelaxedQ15mulrS:
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsSqmulRoundSat(v1, v2, ssa.VecLaneI16x8).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+		v2 := state.pop()
+		v1 := state.pop()
+		state.push(c.lowerRelaxedDot(v1, v2))
+	case wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+		v3 := state.pop()
+		v2 := state.pop()
+		v1 := state.pop()
+		dot := c.lowerRelaxedDot(v1, v2)
+		sum := builder.AllocateInstruction().AsExtIaddPairwise(dot, ssa.VecLaneI16x8, true).Insert(builder).Return()
+		ret := builder.AllocateInstruction().AsVIadd(sum, v3, ssa.VecLaneI32x4).Insert(builder).Return()
+		state.push(ret)
+	default:
+		panic("TODO: unsupported relaxed vector instruction: " + wasm.VectorRelaxedInstructionName(vecOp))
+	}
+}
+
+// lowerRelaxedDot returns the i16x8 sums of the adjacent products of the signed i8x16 lanes of x and y,
+// saturated to 16-bit.
+func (c *Compiler) lowerRelaxedDot(x, y ssa.Value) ssa.Value {
+	builder := c.ssaBuilder
+	xLow := builder.AllocateInstruction().AsWiden(x, ssa.VecLaneI8x16, true, true).Insert(builder).Return()
+	yLow := builder.AllocateInstruction().AsWiden(y, ssa.VecLaneI8x16, true, true).Insert(builder).Return()
+	xHigh := builder.AllocateInstruction().AsWiden(x, ssa.VecLaneI8x16, true, false).Insert(builder).Return()
+	yHigh := builder.AllocateInstruction().AsWiden(y, ssa.VecLaneI8x16, true, false).Insert(builder).Return()
+	low := builder.AllocateInstruction().AsWideningPairwiseDotProductS(xLow, yLow).Insert(builder).Return()
+	high := builder.AllocateInstruction().AsWideningPairwiseDotProductS(xHigh, yHigh).Insert(builder).Return()
+	return builder.AllocateInstruction().AsNarrow(low, high, ssa.VecLaneI32x4, true).Insert(builder).Return()
 }
 
 func (c *Compiler) prepareCallIndirect(typeIndex, tableIndex uint32) (ssa.Value, *wasm.FunctionType, ssa.Values) {
@@ -3578,6 +3942,14 @@ func (c *Compiler) prepareCallIndirect(typeIndex, tableIndex uint32) (ssa.Value,
 	loadFunctionInstancePtr.AsLoad(functionInstancePtrAddress, 0, ssa.TypeI64)
 	builder.InsertInstruction(loadFunctionInstancePtr)
 	functionInstancePtr := loadFunctionInstancePtr.Return()
+	return c.prepareCallFunctionInstance(typeIndex, functionInstancePtr, wazevoapi.ExitCodeIndirectCallNullPointer)
+}
+
+// prepareCallFunctionInstance is the common part of call_indirect and call_ref, which checks the function instance
+// pointer is not null, exiting with nullExitCode otherwise, and its type matches typeIndex.
+func (c *Compiler) prepareCallFunctionInstance(typeIndex uint32, functionInstancePtr ssa.Value, nullExitCode wazevoapi.ExitCode) (ssa.Value, *wasm.FunctionType, ssa.Values) {
+	builder := c.ssaBuilder
+	state := c.state()
 
 	// Check if it is not the null pointer.
 	zero := builder.AllocateInstruction()
@@ -3587,7 +3959,7 @@ func (c *Compiler) prepareCallIndirect(typeIndex, tableIndex uint32) (ssa.Value,
 	checkNull.AsIcmp(functionInstancePtr, zero.Return(), ssa.IntegerCmpCondEqual)
 	builder.InsertInstruction(checkNull)
 	exitIfNull := builder.AllocateInstruction()
-	exitIfNull.AsExitIfTrueWithCode(c.execCtxPtrValue, checkNull.Return(), wazevoapi.ExitCodeIndirectCallNullPointer)
+	exitIfNull.AsExitIfTrueWithCode(c.execCtxPtrValue, checkNull.Return(), nullExitCode)
 	builder.InsertInstruction(exitIfNull)
 
 	// We need to do the type check. First, load the target function instance's typeID.
@@ -3640,9 +4012,22 @@ func (c *Compiler) prepareCallIndirect(typeIndex, tableIndex uint32) (ssa.Value,
 }
 
 func (c *Compiler) lowerCallIndirect(typeIndex, tableIndex uint32) {
+	executablePtr, typ, args := c.prepareCallIndirect(typeIndex, tableIndex)
+	c.lowerCallExecutable(executablePtr, typ, args)
+}
+
+// lowerCallRef lowers call_ref, where the function reference on top of the stack is the pointer to the function
+// instance the same way as the elements of tables.
+func (c *Compiler) lowerCallRef(typeIndex uint32) {
+	functionInstancePtr := c.state().pop()
+	executablePtr, typ, args := c.prepareCallFunctionInstance(typeIndex, functionInstancePtr, wazevoapi.ExitCodeNullReference)
+	c.lowerCallExecutable(executablePtr, typ, args)
+}
+
+// lowerCallExecutable inserts the indirect call to the executable prepared by prepareCallFunctionInstance.
+func (c *Compiler) lowerCallExecutable(executablePtr ssa.Value, typ *wasm.FunctionType, args ssa.Values) {
 	builder := c.ssaBuilder
 	state := c.state()
-	executablePtr, typ, args := c.prepareCallIndirect(typeIndex, tableIndex)
 
 	call := builder.AllocateInstruction()
 	call.AsCallIndirect(executablePtr, c.signatures[typ], args)
@@ -3657,6 +4042,7 @@ func (c *Compiler) lowerCallIndirect(typeIndex, tableIndex uint32) {
 	}
 
 	c.reloadAfterCall()
+	c.checkPendingException()
 }
 
 func (c *Compiler) lowerTailCallReturnCall(fnIndex uint32) {
@@ -3690,9 +4076,21 @@ func (c *Compiler) lowerTailCallReturnCall(fnIndex uint32) {
 }
 
 func (c *Compiler) lowerTailCallReturnCallIndirect(typeIndex, tableIndex uint32) {
+	executablePtr, typ, args := c.prepareCallIndirect(typeIndex, tableIndex)
+	c.lowerTailCallExecutable(executablePtr, typ, args)
+}
+
+// lowerTailCallReturnCallRef lowers return_call_ref the same way as lowerCallRef.
+func (c *Compiler) lowerTailCallReturnCallRef(typeIndex uint32) {
+	functionInstancePtr := c.state().pop()
+	executablePtr, typ, args := c.prepareCallFunctionInstance(typeIndex, functionInstancePtr, wazevoapi.ExitCodeNullReference)
+	c.lowerTailCallExecutable(executablePtr, typ, args)
+}
+
+// lowerTailCallExecutable inserts the tail call to the executable prepared by prepareCallFunctionInstance.
+func (c *Compiler) lowerTailCallExecutable(executablePtr ssa.Value, typ *wasm.FunctionType, args ssa.Values) {
 	builder := c.ssaBuilder
 	state := c.state()
-	executablePtr, typ, args := c.prepareCallIndirect(typeIndex, tableIndex)
 
 	call := builder.AllocateInstruction()
 	call.AsTailCallReturnCallIndirect(executablePtr, c.signatures[typ], args)
@@ -3716,8 +4114,15 @@ func (c *Compiler) lowerTailCallReturnCallIndirect(typeIndex, tableIndex uint32)
 }
 
 // memOpSetup inserts the bounds check and calculates the address of the memory operation (loads/stores).
-func (c *Compiler) memOpSetup(baseAddr ssa.Value, constOffset, operationSizeInBytes uint64) (address ssa.Value) {
-	address = ssa.ValueInvalid
+// The returned offset is the one to be encoded in the load/store instructions on top of the address.
+func (c *Compiler) memOpSetup(memIdx wasm.Index, baseAddr ssa.Value, constOffset, operationSizeInBytes uint64) (address ssa.Value, offset uint32) {
+	if c.isMemory64(memIdx) {
+		return c.memOpSetup64(memIdx, baseAddr, constOffset, operationSizeInBytes)
+	} else if memIdx != 0 {
+		return c.memOpSetupAt(memIdx, baseAddr, constOffset, operationSizeInBytes), uint32(constOffset)
+	}
+
+	address, offset = ssa.ValueInvalid, uint32(constOffset)
 	builder := c.ssaBuilder
 
 	baseAddrID := baseAddr.ID()
@@ -3780,17 +4185,76 @@ func (c *Compiler) memOpSetup(baseAddr ssa.Value, constOffset, operationSizeInBy
 	return
 }
 
+// memOpSetupAt is like memOpSetup, but for the memories other than the one at index zero. As their base address and
+// length are not cached, the bounds check is always inserted.
+func (c *Compiler) memOpSetupAt(memIdx wasm.Index, baseAddr ssa.Value, constOffset, operationSizeInBytes uint64) ssa.Value {
+	builder := c.ssaBuilder
+
+	ceil := builder.AllocateInstruction().AsIconst64(constOffset + operationSizeInBytes).Insert(builder).Return()
+	extBaseAddr := builder.AllocateInstruction().AsUExtend(baseAddr, 32, 64).Insert(builder).Return()
+	memLen := c.getMemoryLenValueAt(memIdx)
+	baseAddrPlusCeil := builder.AllocateInstruction().AsIadd(extBaseAddr, ceil).Insert(builder).Return()
+
+	// Check for out of bounds memory access: `memLen >= baseAddrPlusCeil`.
+	cmp := builder.AllocateInstruction().
+		AsIcmp(memLen, baseAddrPlusCeil, ssa.IntegerCmpCondUnsignedLessThan).
+		Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsExitIfTrueWithCode(c.execCtxPtrValue, cmp, wazevoapi.ExitCodeMemoryOutOfBounds).
+		Insert(builder)
+
+	return builder.AllocateInstruction().
+		AsIadd(c.getMemoryBaseValueAt(memIdx), extBaseAddr).Insert(builder).Return()
+}
+
+// memOpSetup64 is like memOpSetup, but for memory64 memories whose addresses are i64. As baseAddr+ceil might overflow,
+// the bounds check is done against memLen-ceil instead, and the known safe bounds are not used. The constant offset
+// is added to the returned address if it doesn't fit in the 32-bit offset of load/store instructions.
+func (c *Compiler) memOpSetup64(memIdx wasm.Index, baseAddr ssa.Value, constOffset, operationSizeInBytes uint64) (ssa.Value, uint32) {
+	builder := c.ssaBuilder
+
+	ceil := constOffset + operationSizeInBytes
+	if ceil < constOffset {
+		ceil = math.MaxUint64 // Overflow: any access is out of bounds.
+	}
+	ceilConst := builder.AllocateInstruction().AsIconst64(ceil).Insert(builder).Return()
+	memLen := c.getMemoryLenValueAt(memIdx)
+
+	// Check for out of bounds memory access: `memLen < ceil || memLen-ceil < baseAddr`.
+	cmp := builder.AllocateInstruction().
+		AsIcmp(memLen, ceilConst, ssa.IntegerCmpCondUnsignedLessThan).
+		Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsExitIfTrueWithCode(c.execCtxPtrValue, cmp, wazevoapi.ExitCodeMemoryOutOfBounds).
+		Insert(builder)
+	limit := builder.AllocateInstruction().AsIsub(memLen, ceilConst).Insert(builder).Return()
+	cmp = builder.AllocateInstruction().
+		AsIcmp(limit, baseAddr, ssa.IntegerCmpCondUnsignedLessThan).
+		Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsExitIfTrueWithCode(c.execCtxPtrValue, cmp, wazevoapi.ExitCodeMemoryOutOfBounds).
+		Insert(builder)
+
+	address := builder.AllocateInstruction().
+		AsIadd(c.getMemoryBaseValueAt(memIdx), baseAddr).Insert(builder).Return()
+	if constOffset <= math.MaxUint32 {
+		return address, uint32(constOffset)
+	}
+	offset := builder.AllocateInstruction().AsIconst64(constOffset).Insert(builder).Return()
+	return builder.AllocateInstruction().AsIadd(address, offset).Insert(builder).Return(), 0
+}
+
 // atomicMemOpSetup inserts the bounds check and calculates the address of the memory operation (loads/stores), including
 // the constant offset and performs an alignment check on the final address.
-func (c *Compiler) atomicMemOpSetup(baseAddr ssa.Value, constOffset, operationSizeInBytes uint64) (address ssa.Value) {
+func (c *Compiler) atomicMemOpSetup(memIdx wasm.Index, baseAddr ssa.Value, constOffset, operationSizeInBytes uint64) (address ssa.Value) {
 	builder := c.ssaBuilder
 
-	addrWithoutOffset := c.memOpSetup(baseAddr, constOffset, operationSizeInBytes)
+	addrWithoutOffset, offset32 := c.memOpSetup(memIdx, baseAddr, constOffset, operationSizeInBytes)
 	var addr ssa.Value
-	if constOffset == 0 {
+	if offset32 == 0 {
 		addr = addrWithoutOffset
 	} else {
-		offset := builder.AllocateInstruction().AsIconst64(constOffset).Insert(builder).Return()
+		offset := builder.AllocateInstruction().AsIconst64(uint64(offset32)).Insert(builder).Return()
 		addr = builder.AllocateInstruction().AsIadd(addrWithoutOffset, offset).Insert(builder).Return()
 	}
 
@@ -3985,6 +4449,8 @@ func (c *Compiler) getMemoryLenValue(forceReload bool) ssa.Value {
 			lenOffset := builder.AllocateInstruction().AsIconst64(c.offset.LocalMemoryLen().U64()).Insert(builder).Return()
 			addr := builder.AllocateInstruction().AsIadd(c.moduleCtxPtrValue, lenOffset).Insert(builder).Return()
 			load.AsAtomicLoad(addr, 8, ssa.TypeI64)
+		} else if c.isMemory64(0) {
+			load.AsLoad(c.moduleCtxPtrValue, c.offset.LocalMemoryLen().U32(), ssa.TypeI64)
 		} else {
 			load.AsExtLoad(ssa.OpcodeUload32, c.moduleCtxPtrValue, c.offset.LocalMemoryLen().U32(), true)
 		}
@@ -3996,6 +4462,68 @@ func (c *Compiler) getMemoryLenValue(forceReload bool) ssa.Value {
 	return ret
 }
 
+// isMemory64 returns true if the memory at the given index is addressed with i64 as per the memory64 proposal.
+func (c *Compiler) isMemory64(memIdx wasm.Index) bool {
+	return int(memIdx) < len(c.memories) && c.memories[memIdx].IsMemory64
+}
+
+// memoryPageSizeInBits returns the log2 of the page size of the memory at memIdx, which may be a custom page size
+// as per experimental.CoreFeaturesCustomPageSizes.
+func (c *Compiler) memoryPageSizeInBits(memIdx wasm.Index) uint32 {
+	if int(memIdx) < len(c.memories) {
+		return c.memories[memIdx].PageSizeInBits()
+	}
+	return wasm.MemoryPageSizeInBits
+}
+
+// popMemoryOperand pops an address or a size operand of the bulk memory instructions, and zero extends it to i64
+// unless it is already an i64 for memory64.
+func (c *Compiler) popMemoryOperand(is64 bool) ssa.Value {
+	v := c.state().pop()
+	if is64 {
+		return v
+	}
+	builder := c.ssaBuilder
+	return builder.AllocateInstruction().AsUExtend(v, 32, 64).Insert(builder).Return()
+}
+
+// memoryInstanceAt returns the *wasm.MemoryInstance of the memory at the given non-zero index.
+func (c *Compiler) memoryInstanceAt(memIdx wasm.Index) ssa.Value {
+	builder := c.ssaBuilder
+	return builder.AllocateInstruction().
+		AsLoad(c.moduleCtxPtrValue, c.offset.MemoryInstanceOffset(memIdx).U32(), ssa.TypeI64).
+		Insert(builder).Return()
+}
+
+// getMemoryBaseValueAt is like getMemoryBaseValue, but for the memory at the given index.
+func (c *Compiler) getMemoryBaseValueAt(memIdx wasm.Index) ssa.Value {
+	if memIdx == 0 {
+		return c.getMemoryBaseValue(false)
+	}
+	builder := c.ssaBuilder
+	return builder.AllocateInstruction().
+		AsLoad(c.memoryInstanceAt(memIdx), memoryInstanceBufOffset, ssa.TypeI64).
+		Insert(builder).Return()
+}
+
+// getMemoryLenValueAt is like getMemoryLenValue, but for the memory at the given index.
+func (c *Compiler) getMemoryLenValueAt(memIdx wasm.Index) ssa.Value {
+	if memIdx == 0 {
+		return c.getMemoryLenValue(false)
+	}
+	builder := c.ssaBuilder
+	memInstPtr := c.memoryInstanceAt(memIdx)
+	load := builder.AllocateInstruction()
+	if c.memories[memIdx].IsShared {
+		sizeOffset := builder.AllocateInstruction().AsIconst64(memoryInstanceBufSizeOffset).Insert(builder).Return()
+		addr := builder.AllocateInstruction().AsIadd(memInstPtr, sizeOffset).Insert(builder).Return()
+		load.AsAtomicLoad(addr, 8, ssa.TypeI64)
+	} else {
+		load.AsLoad(memInstPtr, memoryInstanceBufSizeOffset, ssa.TypeI64)
+	}
+	return load.Insert(builder).Return()
+}
+
 func (c *Compiler) insertIcmp(cond ssa.IntegerCmpCond) {
 	state, builder := c.state(), c.ssaBuilder
 	y, x := state.pop(), state.pop()
@@ -4072,11 +4600,15 @@ func (c *Compiler) readF64() float64 {
 }
 
 // readBlockType reads the block type from the current position of the bytecode reader.
+// typeDecodingFeatures are the features to decode the types in the immediates, which have already been validated.
+const typeDecodingFeatures = api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling | experimental.CoreFeaturesFunctionReferences |
+	experimental.CoreFeaturesStackSwitching
+
 func (c *Compiler) readBlockType() *wasm.FunctionType {
 	state := c.state()
 
 	c.br.Reset(c.wasmFunctionBody[state.pc+1:])
-	bt, num, err := wasm.DecodeBlockType(c.m.TypeSection, c.br, api.CoreFeaturesV2)
+	bt, num, err := wasm.DecodeBlockType(c.m, c.br, typeDecodingFeatures)
 	if err != nil {
 		panic(err) // shouldn't be reached since compilation comes after validation.
 	}
@@ -4085,7 +4617,36 @@ func (c *Compiler) readBlockType() *wasm.FunctionType {
 	return bt
 }
 
-func (c *Compiler) readMemArg() (align, offset uint32) {
+// readTryTableCatches reads the catch clauses of try_table from the current position of the bytecode reader.
+func (c *Compiler) readTryTableCatches() []wasm.TryTableCatch {
+	state := c.state()
+
+	c.br.Reset(c.wasmFunctionBody[state.pc+1:])
+	catches, num, err := wasm.DecodeTryTableCatches(c.br, state.tmpForTryTable[:0])
+	if err != nil {
+		panic(err) // shouldn't be reached since compilation comes after validation.
+	}
+	state.pc += int(num)
+	state.tmpForTryTable = catches // reuse the temporary slice for next use.
+	return catches
+}
+
+// readResumeHandlers reads the handlers of resume, and returns the pairs of the tag and the label of each handler.
+func (c *Compiler) readResumeHandlers() []uint32 {
+	state := c.state()
+	handlers := state.tmpForResume[:0]
+	for i, n := 0, int(c.readI32u()); i < n; i++ {
+		// Each handler is (on tag label), as the others are rejected by the validation.
+		_ = c.readByte()
+		handlers = append(handlers, c.readI32u(), c.readI32u())
+	}
+	state.tmpForResume = handlers // reuse the temporary slice for next use.
+	return handlers
+}
+
+// readMemArg reads the memarg immediate, and returns the memory index and the offset as the alignment is not used.
+// The offset is encoded as u64 for memory64 memories.
+func (c *Compiler) readMemArg() (memIdx wasm.Index, offset uint64) {
 	state := c.state()
 
 	align, num, err := leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:])
@@ -4094,13 +4655,27 @@ func (c *Compiler) readMemArg() (align, offset uint32) {
 	}
 
 	state.pc += int(num)
-	offset, num, err = leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:])
+	if align&wasm.MemArgMemoryIndexFlag != 0 {
+		memIdx, num, err = leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:])
+		if err != nil {
+			panic(fmt.Errorf("read memory index: %v", err))
+		}
+		state.pc += int(num)
+	}
+
+	if c.isMemory64(memIdx) {
+		offset, num, err = leb128.LoadUint64(c.wasmFunctionBody[state.pc+1:])
+	} else {
+		var offset32 uint32
+		offset32, num, err = leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:])
+		offset = uint64(offset32)
+	}
 	if err != nil {
 		panic(fmt.Errorf("read memory offset: %v", err))
 	}
 
 	state.pc += int(num)
-	return align, offset
+	return memIdx, offset
 }
 
 // insertJumpToBlock inserts a jump instruction to the given block in the current block.
@@ -4347,7 +4922,7 @@ func (c *Compiler) loadTableBaseAddr(tableInstancePtr ssa.Value) ssa.Value {
 	return loadTableBaseAddress.Return()
 }
 
-func (c *Compiler) boundsCheckInMemory(memLen, offset, size ssa.Value) {
+func (c *Compiler) boundsCheckInMemory(memLen, offset, size ssa.Value, is64 bool) {
 	builder := c.ssaBuilder
 	ceil := builder.AllocateInstruction().AsIadd(offset, size).Insert(builder).Return()
 	cmp := builder.AllocateInstruction().
@@ -4357,4 +4932,448 @@ func (c *Compiler) boundsCheckInMemory(memLen, offset, size ssa.Value) {
 	builder.AllocateInstruction().
 		AsExitIfTrueWithCode(c.execCtxPtrValue, cmp, wazevoapi.ExitCodeMemoryOutOfBounds).
 		Insert(builder)
+	if is64 {
+		// With memory64, offset+size might overflow.
+		overflow := builder.AllocateInstruction().
+			AsIcmp(ceil, offset, ssa.IntegerCmpCondUnsignedLessThan).
+			Insert(builder).
+			Return()
+		builder.AllocateInstruction().
+			AsExitIfTrueWithCode(c.execCtxPtrValue, overflow, wazevoapi.ExitCodeMemoryOutOfBounds).
+			Insert(builder)
+	}
+}
+
+// lowerThrow lowers the throw instruction, which creates the exception with the values on the stack
+// as the payload, and transfers the control to the exception handler.
+func (c *Compiler) lowerThrow(tagIndex wasm.Index) {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	// The callee needs the current module to resolve the tag.
+	c.storeCallerModuleContext()
+
+	throwPtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetThrowTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(2, c.execCtxPtrValue,
+		builder.AllocateInstruction().AsIconst32(tagIndex).Insert(builder).Return())
+	payloadPtr := builder.AllocateInstruction().
+		AsCallIndirect(throwPtr, &c.throwSig, args).
+		Insert(builder).Return()
+
+	// Fill the payload of the created exception.
+	tagType := &c.m.TypeSection[c.tagTypes[tagIndex]]
+	tail := len(state.values) - len(tagType.Params)
+	var offset uint32
+	for _, v := range state.values[tail:] {
+		builder.AllocateInstruction().AsStore(ssa.OpcodeStore, v, payloadPtr, offset).Insert(builder)
+		offset += exceptionPayloadSize(v.Type())
+	}
+	state.values = state.values[:tail]
+
+	c.insertJumpToBlock(ssa.ValuesNil, c.exceptionDispatchBlock(len(state.controlFrames)))
+}
+
+// lowerThrowRef lowers the throw_ref instruction, which throws the exception referenced by the exnref on the stack.
+func (c *Compiler) lowerThrowRef() {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	ref := state.pop()
+	throwRefPtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetThrowRefTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(2, c.execCtxPtrValue, ref)
+	builder.AllocateInstruction().
+		AsCallIndirect(throwRefPtr, &c.throwRefSig, args).
+		Insert(builder)
+
+	c.insertJumpToBlock(ssa.ValuesNil, c.exceptionDispatchBlock(len(state.controlFrames)))
+}
+
+// checkPendingException inserts the check of the pending exception after a function call,
+// which transfers the control to the exception handler if the callee has thrown an exception.
+func (c *Compiler) checkPendingException() {
+	if !c.exceptionHandling {
+		return
+	}
+
+	builder := c.ssaBuilder
+	dispatchBlk := c.exceptionDispatchBlock(len(c.state().controlFrames))
+
+	pending := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetExceptionPending.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	brnz := builder.AllocateInstruction()
+	brnz.AsBrnz(pending, ssa.ValuesNil, dispatchBlk)
+	builder.InsertInstruction(brnz)
+
+	// Continue translating the instructions after the call in a new block.
+	continuationBlk := builder.AllocateBasicBlock()
+	c.insertJumpToBlock(ssa.ValuesNil, continuationBlk)
+	builder.Seal(continuationBlk)
+	builder.SetCurrentBlock(continuationBlk)
+}
+
+// exceptionDispatchBlock returns the block handling the exception thrown in the control frames below the given depth,
+// which is the exception dispatch block of the innermost try_table frame with catch clauses, or the block returning
+// to the caller if there's no such frame.
+func (c *Compiler) exceptionDispatchBlock(depth int) ssa.BasicBlock {
+	state := c.state()
+	for i := depth - 1; i >= 0; i-- {
+		frame := &state.controlFrames[i]
+		if len(frame.catches) == 0 {
+			continue
+		}
+		if frame.exceptionDispatchBlk == nil {
+			frame.exceptionDispatchBlk = c.ssaBuilder.AllocateBasicBlock()
+			c.lowerExceptionDispatch(frame.exceptionDispatchBlk, frame.catches, i)
+		}
+		return frame.exceptionDispatchBlk
+	}
+	return c.exceptionPropagateBlock()
+}
+
+// lowerExceptionDispatch lowers the exception dispatch block of the try_table frame at the given depth, which checks
+// the catch clauses in order, and falls back to the enclosing exception handler if none of them matches.
+func (c *Compiler) lowerExceptionDispatch(blk ssa.BasicBlock, catches []catchClause, depth int) {
+	builder := c.ssaBuilder
+	current := builder.CurrentBlock()
+	builder.SetCurrentBlock(blk)
+	defer builder.SetCurrentBlock(current)
+
+	for i := range catches {
+		catch := &catches[i]
+		if catch.kind == wasm.CatchKindCatchAll || catch.kind == wasm.CatchKindCatchAllRef {
+			// catch_all matches any exception, so the following clauses are never reached.
+			c.lowerCatch(catch)
+			return
+		}
+
+		// The callee needs the current module to resolve the tag.
+		c.storeCallerModuleContext()
+
+		tagMatchPtr := builder.AllocateInstruction().
+			AsLoad(c.execCtxPtrValue,
+				wazevoapi.ExecutionContextOffsetExceptionTagMatchTrampolineAddress.U32(),
+				ssa.TypeI64,
+			).Insert(builder).Return()
+
+		args := c.allocateVarLengthValues(2, c.execCtxPtrValue,
+			builder.AllocateInstruction().AsIconst32(catch.tag).Insert(builder).Return())
+		matched := builder.AllocateInstruction().
+			AsCallIndirect(tagMatchPtr, &c.exceptionTagMatchSig, args).
+			Insert(builder).Return()
+
+		matchedBlk, nextBlk := builder.AllocateBasicBlock(), builder.AllocateBasicBlock()
+		brz := builder.AllocateInstruction()
+		brz.AsBrz(matched, ssa.ValuesNil, nextBlk)
+		builder.InsertInstruction(brz)
+		c.insertJumpToBlock(ssa.ValuesNil, matchedBlk)
+		builder.Seal(matchedBlk)
+		builder.Seal(nextBlk)
+
+		builder.SetCurrentBlock(matchedBlk)
+		c.lowerCatch(catch)
+		builder.SetCurrentBlock(nextBlk)
+	}
+
+	// None of the clauses matched, so rethrow it to the enclosing exception handler.
+	c.insertJumpToBlock(ssa.ValuesNil, c.exceptionDispatchBlock(depth))
+}
+
+// lowerCatch clears the pending exception, and branches to the target of the catch clause
+// with the payload and/or exnref of the caught exception.
+func (c *Compiler) lowerCatch(catch *catchClause) {
+	builder := c.ssaBuilder
+	state := c.state()
+	originalLen := len(state.values)
+
+	if catch.kind == wasm.CatchKindCatch || catch.kind == wasm.CatchKindCatchRef {
+		if tagType := &c.m.TypeSection[c.tagTypes[catch.tag]]; len(tagType.Params) > 0 {
+			payloadPtr := builder.AllocateInstruction().
+				AsLoad(c.execCtxPtrValue,
+					wazevoapi.ExecutionContextOffsetExceptionPayloadPtr.U32(),
+					ssa.TypeI64,
+				).Insert(builder).Return()
+			var offset uint32
+			for _, p := range tagType.Params {
+				typ := WasmTypeToSSAType(p)
+				v := builder.AllocateInstruction().AsLoad(payloadPtr, offset, typ).Insert(builder).Return()
+				state.push(v)
+				offset += exceptionPayloadSize(typ)
+			}
+		}
+	}
+	if catch.kind == wasm.CatchKindCatchRef || catch.kind == wasm.CatchKindCatchAllRef {
+		ref := builder.AllocateInstruction().
+			AsLoad(c.execCtxPtrValue,
+				wazevoapi.ExecutionContextOffsetExceptionRef.U32(),
+				ssa.TypeI64,
+			).Insert(builder).Return()
+		state.push(ref)
+	}
+
+	zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsStore(ssa.OpcodeStore, zero, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetExceptionPending.U32()).
+		Insert(builder)
+
+	args := c.nPeekDup(catch.argNum)
+	c.insertJumpToBlock(args, catch.targetBlk)
+	state.values = state.values[:originalLen]
+}
+
+// exceptionPropagateBlock returns the block which returns to the caller with the pending exception.
+// The caller checks the pending exception after the call, so the returned values are never used.
+func (c *Compiler) exceptionPropagateBlock() ssa.BasicBlock {
+	if c.exceptionPropagateBlk != nil {
+		return c.exceptionPropagateBlk
+	}
+
+	builder := c.ssaBuilder
+	current := builder.CurrentBlock()
+	blk := builder.AllocateBasicBlock()
+	builder.SetCurrentBlock(blk)
+
+	results := c.allocateVarLengthValues(len(c.wasmFunctionTyp.Results))
+	for _, t := range c.wasmFunctionTyp.Results {
+		zero := builder.AllocateInstruction()
+		switch typ := WasmTypeToSSAType(t); typ {
+		case ssa.TypeI32:
+			zero.AsIconst32(0)
+		case ssa.TypeI64:
+			zero.AsIconst64(0)
+		case ssa.TypeF32:
+			zero.AsF32const(0)
+		case ssa.TypeF64:
+			zero.AsF64const(0)
+		case ssa.TypeV128:
+			zero.AsVconst(0, 0)
+		default:
+			panic("BUG: " + typ.String())
+		}
+		results = results.Append(builder.VarLengthPool(), zero.Insert(builder).Return())
+	}
+	ret := builder.AllocateInstruction()
+	ret.AsReturn(results)
+	builder.InsertInstruction(ret)
+
+	builder.SetCurrentBlock(current)
+	c.exceptionPropagateBlk = blk
+	return blk
+}
+
+// storeContinuationArgs stores the values into the buffer passing the values to the trampolines of the stack
+// switching instructions, in the same layout as the payload of exceptions, and returns their size in uint64.
+func (c *Compiler) storeContinuationArgs(values []ssa.Value) uint32 {
+	builder := c.ssaBuilder
+
+	var size uint32
+	for _, v := range values {
+		size += exceptionPayloadSize(v.Type())
+	}
+	continuationArgsPtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetContinuationArgsTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(2, c.execCtxPtrValue,
+		builder.AllocateInstruction().AsIconst32(size/8).Insert(builder).Return())
+	bufPtr := builder.AllocateInstruction().
+		AsCallIndirect(continuationArgsPtr, &c.continuationArgsSig, args).
+		Insert(builder).Return()
+
+	var offset uint32
+	for _, v := range values {
+		builder.AllocateInstruction().AsStore(ssa.OpcodeStore, v, bufPtr, offset).Insert(builder)
+		offset += exceptionPayloadSize(v.Type())
+	}
+	return size / 8
+}
+
+// loadContinuationResults pushes the values of the given types loaded from the buffer returned by the trampolines of
+// the stack switching instructions, beginning at the offset, and returns the offset after them.
+func (c *Compiler) loadContinuationResults(bufPtr ssa.Value, offset uint32, types []wasm.ValueType) uint32 {
+	builder := c.ssaBuilder
+	state := c.state()
+	for _, t := range types {
+		typ := WasmTypeToSSAType(t)
+		v := builder.AllocateInstruction().AsLoad(bufPtr, offset, typ).Insert(builder).Return()
+		state.push(v)
+		offset += exceptionPayloadSize(typ)
+	}
+	return offset
+}
+
+// lowerContNew lowers the cont.new instruction, which creates the continuation of the function reference on the stack.
+func (c *Compiler) lowerContNew(typeIndex wasm.Index) {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	// The callee needs the current module to resolve the type.
+	c.storeCallerModuleContext()
+
+	ref := state.pop()
+	contNewPtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetContNewTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(3, c.execCtxPtrValue, ref,
+		builder.AllocateInstruction().AsIconst32(typeIndex).Insert(builder).Return())
+	contRef := builder.AllocateInstruction().
+		AsCallIndirect(contNewPtr, &c.contNewSig, args).
+		Insert(builder).Return()
+	state.push(contRef)
+}
+
+// lowerContBind lowers the cont.bind instruction, which binds the values on the stack to the first params of the
+// continuation on the stack.
+func (c *Compiler) lowerContBind(from, to wasm.Index) {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	ref := state.pop()
+	tail := len(state.values) - (len(c.m.ContinuationType(from).Params) - len(c.m.ContinuationType(to).Params))
+	num := c.storeContinuationArgs(state.values[tail:])
+	state.values = state.values[:tail]
+
+	contBindPtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetContBindTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(3, c.execCtxPtrValue, ref,
+		builder.AllocateInstruction().AsIconst32(num).Insert(builder).Return())
+	contRef := builder.AllocateInstruction().
+		AsCallIndirect(contBindPtr, &c.contBindSig, args).
+		Insert(builder).Return()
+	state.push(contRef)
+}
+
+// lowerSuspend lowers the suspend instruction, which suspends the current continuation with the values on the stack
+// as the payload, and pushes the values it is resumed with.
+func (c *Compiler) lowerSuspend(tagIndex wasm.Index) {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	// The callee needs the current module to resolve the tag.
+	c.storeCallerModuleContext()
+
+	tagType := &c.m.TypeSection[c.tagTypes[tagIndex]]
+	tail := len(state.values) - len(tagType.Params)
+	_ = c.storeContinuationArgs(state.values[tail:])
+	state.values = state.values[:tail]
+
+	suspendPtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetSuspendTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(2, c.execCtxPtrValue,
+		builder.AllocateInstruction().AsIconst32(tagIndex).Insert(builder).Return())
+	resultsPtr := builder.AllocateInstruction().
+		AsCallIndirect(suspendPtr, &c.suspendSig, args).
+		Insert(builder).Return()
+
+	// The other continuations might have changed the memory and globals while this was suspended.
+	c.reloadAfterCall()
+	c.loadContinuationResults(resultsPtr, 0, tagType.Results)
+}
+
+// lowerResume lowers the resume instruction, which resumes the continuation on the stack with the values on the stack
+// as the params, and branches to the target of the handler matching its suspension, if any.
+//
+// handlers are the pairs of the tag and the label of each handler.
+func (c *Compiler) lowerResume(typeIndex wasm.Index, handlers []uint32) {
+	builder := c.ssaBuilder
+	state := c.state()
+
+	// The callee needs the current module to resolve the tags.
+	c.storeCallerModuleContext()
+
+	ft := c.m.ContinuationType(typeIndex)
+	ref := state.pop()
+	tail := len(state.values) - len(ft.Params)
+
+	// The tags of the handlers precede the params in the buffer.
+	values := make([]ssa.Value, 0, len(handlers)/2+len(ft.Params))
+	for i := 0; i < len(handlers); i += 2 {
+		values = append(values, builder.AllocateInstruction().AsIconst64(uint64(handlers[i])).Insert(builder).Return())
+	}
+	values = append(values, state.values[tail:]...)
+	_ = c.storeContinuationArgs(values)
+	state.values = state.values[:tail]
+
+	resumePtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetResumeTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	args := c.allocateVarLengthValues(4, c.execCtxPtrValue, ref,
+		builder.AllocateInstruction().AsIconst32(uint32(len(handlers)/2)).Insert(builder).Return(),
+		builder.AllocateInstruction().AsIconst32(uint32(ft.ParamNumInUint64)).Insert(builder).Return())
+	bufPtr := builder.AllocateInstruction().
+		AsCallIndirect(resumePtr, &c.resumeSig, args).
+		Insert(builder).Return()
+
+	c.reloadAfterCall()
+	c.checkPendingException()
+
+	// The first value in the buffer is the index of the handler plus one, or zero if the continuation returned.
+	status := builder.AllocateInstruction().AsLoad(bufPtr, 0, ssa.TypeI64).Insert(builder).Return()
+	for i := 0; i < len(handlers); i += 2 {
+		tagIndex, label := handlers[i], handlers[i+1]
+		targetBlk, argNum := state.brTargetArgNumFor(label)
+
+		index := builder.AllocateInstruction().AsIconst64(uint64(i/2 + 1)).Insert(builder).Return()
+		matched := builder.AllocateInstruction().
+			AsIcmp(status, index, ssa.IntegerCmpCondEqual).
+			Insert(builder).Return()
+
+		matchedBlk, nextBlk := builder.AllocateBasicBlock(), builder.AllocateBasicBlock()
+		brnz := builder.AllocateInstruction()
+		brnz.AsBrnz(matched, ssa.ValuesNil, matchedBlk)
+		builder.InsertInstruction(brnz)
+		c.insertJumpToBlock(ssa.ValuesNil, nextBlk)
+		builder.Seal(matchedBlk)
+		builder.Seal(nextBlk)
+
+		// The handler receives the payload of the suspension followed by the continuation of the rest.
+		builder.SetCurrentBlock(matchedBlk)
+		originalLen := len(state.values)
+		tagType := &c.m.TypeSection[c.tagTypes[tagIndex]]
+		offset := c.loadContinuationResults(bufPtr, 8, tagType.Params)
+		c.loadContinuationResults(bufPtr, offset, []wasm.ValueType{wasm.ValueTypeContref})
+		c.insertJumpToBlock(c.nPeekDup(argNum), targetBlk)
+		state.values = state.values[:originalLen]
+
+		builder.SetCurrentBlock(nextBlk)
+	}
+	c.loadContinuationResults(bufPtr, 8, ft.Results)
+}
+
+// exceptionPayloadSize returns the size of the value of the given type in the payload of an exception,
+// which is laid out as []uint64 where a v128 value takes two elements.
+func exceptionPayloadSize(typ ssa.Type) uint32 {
+	if typ == ssa.TypeV128 {
+		return 16
+	}
+	return 8
 }
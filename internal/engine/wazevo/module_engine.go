@@ -48,6 +48,7 @@ type (
 	// 	    localMemoryLength                         uint64               (optional)
 	// 	    importedMemoryInstance                    *wasm.MemoryInstance (optional)
 	// 	    importedMemoryOwnerOpaqueCtx              *byte                (optional)
+	// 	    memories                                  []*wasm.MemoryInstance (optional, except the one at index zero)
 	// 	    importedFunctions                         [# of importedFunctions]functionInstance
 	//      importedGlobals                           []ImportedGlobal       (optional)
 	//      localGlobals                              []Global               (optional)
@@ -99,7 +100,14 @@ func (m *moduleEngine) setupOpaque() {
 		m.putLocalMemory()
 	}
 
-	// Note: imported memory is resolved in ResolveImportedFunction.
+	// Note: imported memory is resolved in ResolveImportedMemory.
+
+	if memoriesOffset := offsets.MemoriesBegin; memoriesOffset >= 0 {
+		for _, mem := range inst.Memories[1:] {
+			binary.LittleEndian.PutUint64(opaque[memoriesOffset:], uint64(uintptr(unsafe.Pointer(mem))))
+			memoriesOffset += 8
+		}
+	}
 
 	// Note: imported functions are resolved in ResolveImportedFunction.
 
@@ -189,6 +197,16 @@ func (m *moduleEngine) NewFunction(index wasm.Index) api.Function {
 	ce.execCtx.memoryWait32TrampolineAddress = sharedFunctions.memoryWait32Address
 	ce.execCtx.memoryWait64TrampolineAddress = sharedFunctions.memoryWait64Address
 	ce.execCtx.memoryNotifyTrampolineAddress = sharedFunctions.memoryNotifyAddress
+	ce.execCtx.throwTrampolineAddress = sharedFunctions.throwAddress
+	ce.execCtx.throwRefTrampolineAddress = sharedFunctions.throwRefAddress
+	ce.execCtx.exceptionTagMatchTrampolineAddress = sharedFunctions.exceptionTagMatchAddress
+	ce.execCtx.continuationArgsTrampolineAddress = sharedFunctions.continuationArgsAddress
+	ce.execCtx.contNewTrampolineAddress = sharedFunctions.contNewAddress
+	ce.execCtx.contBindTrampolineAddress = sharedFunctions.contBindAddress
+	ce.execCtx.resumeTrampolineAddress = sharedFunctions.resumeAddress
+	ce.execCtx.suspendTrampolineAddress = sharedFunctions.suspendAddress
+	ce.exceptionHandling = p.parent.exceptionHandling
+	ce.exceptionRefs = &wasm.ExceptionRefs{}
 	ce.execCtx.memmoveAddress = memmovPtr
 	ce.init()
 	return ce
@@ -220,7 +238,10 @@ func (m *moduleEngine) OwnsGlobals() bool { return true }
 
 // MemoryGrown implements wasm.ModuleEngine.
 func (m *moduleEngine) MemoryGrown() {
-	m.putLocalMemory()
+	// Only the memory at index zero is cached in the opaque, which might not be the grown one with multi-memory.
+	if m.parent.offsets.LocalMemoryBegin >= 0 {
+		m.putLocalMemory()
+	}
 }
 
 // putLocalMemory writes the local memory buffer pointer and length to the opaque buffer.
@@ -261,13 +282,20 @@ func (m *moduleEngine) ResolveImportedFunction(index, descFunc, indexInImportedM
 }
 
 // ResolveImportedMemory implements wasm.ModuleEngine.
-func (m *moduleEngine) ResolveImportedMemory(importedModuleEngine wasm.ModuleEngine) {
+func (m *moduleEngine) ResolveImportedMemory(index, indexInImportedModule wasm.Index, importedModuleEngine wasm.ModuleEngine) {
+	if index != 0 {
+		// Memories other than the one at index zero are written in setupOpaque from wasm.ModuleInstance Memories.
+		return
+	}
+
 	importedME := importedModuleEngine.(*moduleEngine)
 	inst := importedME.module
 
 	var memInstPtr uint64
 	var memOwnerOpaquePtr uint64
-	if offs := importedME.parent.offsets; offs.ImportedMemoryBegin >= 0 {
+	if indexInImportedModule != 0 {
+		memInstPtr = uint64(uintptr(unsafe.Pointer(inst.MemoryAt(indexInImportedModule))))
+	} else if offs := importedME.parent.offsets; offs.ImportedMemoryBegin >= 0 {
 		offset := offs.ImportedMemoryBegin
 		memInstPtr = binary.LittleEndian.Uint64(importedME.opaque[offset:])
 		memOwnerOpaquePtr = binary.LittleEndian.Uint64(importedME.opaque[offset+8:])
@@ -325,6 +353,21 @@ func (m *moduleEngine) LookupFunction(t *wasm.TableInstance, typeId wasm.Functio
 	return moduleInstanceFromOpaquePtr(tf.moduleContextOpaquePtr), tf.indexInModule
 }
 
+// importedFunctionsBegin returns the address of the functionInstance of the first imported function in the opaque.
+func (m *moduleEngine) importedFunctionsBegin() uintptr {
+	return uintptr(unsafe.Pointer(&m.opaque[m.parent.offsets.ImportedFunctionsBegin]))
+}
+
+// isImportedFunctionReference returns true if the function reference is the one of an imported function returned by
+// FunctionInstanceReference, which doesn't hold the index of the function unlike the ones of local functions.
+func (m *moduleEngine) isImportedFunctionReference(ref uintptr) bool {
+	if len(m.importedFunctions) == 0 {
+		return false
+	}
+	begin := m.importedFunctionsBegin()
+	return begin <= ref && ref < begin+uintptr(len(m.importedFunctions))*wazevoapi.FunctionInstanceSize
+}
+
 func moduleInstanceFromOpaquePtr(ptr *byte) *wasm.ModuleInstance {
 	return *(**wasm.ModuleInstance)(unsafe.Pointer(ptr))
 }
@@ -158,6 +158,11 @@ func (i *Instruction) Arg3() (Value, Value, Value) {
 	return i.v, i.v2, i.v3
 }
 
+// Arg3WithLane returns the first three arguments to this instruction, and the lane type.
+func (i *Instruction) Arg3WithLane() (Value, Value, Value, VecLane) {
+	return i.v, i.v2, i.v3, VecLane(i.u1)
+}
+
 // Next returns the next instruction laid out next to itself.
 func (i *Instruction) Next() *Instruction {
 	return i.next
@@ -641,6 +646,31 @@ const (
 	// for tail calls. Semantically, it combines CallIndirect + Return into a single operation.
 	OpcodeTailCallReturnCallIndirect
 
+	// OpcodeVFma performs a fused multiply-add of floating point vectors: `v = VFma.lane x, y, z` which computes
+	// x*y+z with a single rounding. Backends may fall back to a multiplication and an addition if the CPU
+	// doesn't support it, so this is only used for the relaxed SIMD instructions.
+	OpcodeVFma
+
+	// OpcodeRelaxedSwizzle performs a vector swizzle operation like OpcodeSwizzle, except that the lanes
+	// selected by the out-of-range indexes are unspecified: `v = RelaxedSwizzle.lane x, y`.
+	OpcodeRelaxedSwizzle
+
+	// OpcodeIadd128 performs an addition of 128-bit integers, each of which is represented as a pair of the low and
+	// high 64-bit halves: `lo, hi = Iadd128 xLo, xHi, yLo, yHi`.
+	OpcodeIadd128
+
+	// OpcodeIsub128 performs a subtraction of 128-bit integers, each of which is represented as a pair of the low
+	// and high 64-bit halves: `lo, hi = Isub128 xLo, xHi, yLo, yHi`.
+	OpcodeIsub128
+
+	// OpcodeSmulWide performs a signed multiplication of 64-bit integers, which results in the low and high 64-bit
+	// halves of the 128-bit product: `lo, hi = SmulWide x, y`.
+	OpcodeSmulWide
+
+	// OpcodeUmulWide performs an unsigned multiplication of 64-bit integers, which results in the low and high
+	// 64-bit halves of the 128-bit product: `lo, hi = UmulWide x, y`.
+	OpcodeUmulWide
+
 	// opcodeEnd marks the end of the opcode list.
 	opcodeEnd
 )
@@ -682,6 +712,9 @@ func (op AtomicRmwOp) String() string {
 	panic(fmt.Sprintf("unknown AtomicRmwOp: %d", op))
 }
 
+// typesI64 is the types of the rest of the results of the instructions returning a pair of i64.
+var typesI64 = []Type{TypeI64}
+
 // returnTypesFn provides the info to determine the type of instruction.
 // t1 is the type of the first result, ts are the types of the remaining results.
 type returnTypesFn func(b *builder, instr *Instruction) (t1 Type, ts []Type)
@@ -693,6 +726,7 @@ var (
 	returnTypesFnF32                        = func(b *builder, instr *Instruction) (t1 Type, ts []Type) { return TypeF32, nil }
 	returnTypesFnF64                        = func(b *builder, instr *Instruction) (t1 Type, ts []Type) { return TypeF64, nil }
 	returnTypesFnV128                       = func(b *builder, instr *Instruction) (t1 Type, ts []Type) { return TypeV128, nil }
+	returnTypesFnI64I64                     = func(b *builder, instr *Instruction) (t1 Type, ts []Type) { return TypeI64, typesI64 }
 	returnTypesFnCallIndirect               = func(b *builder, instr *Instruction) (t1 Type, ts []Type) {
 		sigID := SignatureID(instr.u1)
 		sig, ok := b.signatures[sigID]
@@ -889,6 +923,12 @@ var instructionSideEffects = [opcodeEnd]sideEffect{
 	OpcodeTailCallReturnCall:          sideEffectStrict,
 	OpcodeTailCallReturnCallIndirect:  sideEffectStrict,
 	OpcodeWideningPairwiseDotProductS: sideEffectNone,
+	OpcodeVFma:                        sideEffectNone,
+	OpcodeRelaxedSwizzle:              sideEffectNone,
+	OpcodeIadd128:                     sideEffectNone,
+	OpcodeIsub128:                     sideEffectNone,
+	OpcodeSmulWide:                    sideEffectNone,
+	OpcodeUmulWide:                    sideEffectNone,
 }
 
 // sideEffect returns true if this instruction has side effects.
@@ -1047,6 +1087,12 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeTailCallReturnCallIndirect:  returnTypesFnCallIndirect,
 	OpcodeTailCallReturnCall:          returnTypesFnCall,
 	OpcodeWideningPairwiseDotProductS: returnTypesFnV128,
+	OpcodeVFma:                        returnTypesFnV128,
+	OpcodeRelaxedSwizzle:              returnTypesFnV128,
+	OpcodeIadd128:                     returnTypesFnI64I64,
+	OpcodeIsub128:                     returnTypesFnI64I64,
+	OpcodeSmulWide:                    returnTypesFnI64I64,
+	OpcodeUmulWide:                    returnTypesFnI64I64,
 }
 
 // AsLoad initializes this instruction as a store instruction with OpcodeLoad.
@@ -1425,6 +1471,17 @@ func (i *Instruction) AsVFmul(x, y Value, lane VecLane) *Instruction {
 	return i
 }
 
+// AsVFma initializes this instruction as a fused multiply-add instruction with OpcodeVFma on a vector.
+func (i *Instruction) AsVFma(x, y, z Value, lane VecLane) *Instruction {
+	i.opcode = OpcodeVFma
+	i.v = x
+	i.v2 = y
+	i.v3 = z
+	i.u1 = uint64(lane)
+	i.typ = TypeV128
+	return i
+}
+
 // AsVFdiv initializes this instruction as a floating point division instruction with OpcodeVFdiv on a vector.
 func (i *Instruction) AsVFdiv(x, y Value, lane VecLane) *Instruction {
 	i.opcode = OpcodeVFdiv
@@ -1734,6 +1791,58 @@ func (i *Instruction) AsSwizzle(x, y Value, lane VecLane) *Instruction {
 	return i
 }
 
+// AsRelaxedSwizzle initializes this instruction as a swizzle instruction with OpcodeRelaxedSwizzle on vector.
+func (i *Instruction) AsRelaxedSwizzle(x, y Value, lane VecLane) *Instruction {
+	i.opcode = OpcodeRelaxedSwizzle
+	i.v = x
+	i.v2 = y
+	i.u1 = uint64(lane)
+	i.typ = TypeV128
+	return i
+}
+
+// AsIadd128 initializes this instruction as a 128-bit integer addition instruction with OpcodeIadd128.
+// args must be the four i64 values of xLo, xHi, yLo and yHi.
+func (i *Instruction) AsIadd128(args Values) *Instruction {
+	i.opcode = OpcodeIadd128
+	i.vs = args
+	i.typ = TypeI64
+	return i
+}
+
+// AsIsub128 initializes this instruction as a 128-bit integer subtraction instruction with OpcodeIsub128.
+// args must be the four i64 values of xLo, xHi, yLo and yHi.
+func (i *Instruction) AsIsub128(args Values) *Instruction {
+	i.opcode = OpcodeIsub128
+	i.vs = args
+	i.typ = TypeI64
+	return i
+}
+
+// Arith128Data returns the operands of OpcodeIadd128 and OpcodeIsub128.
+func (i *Instruction) Arith128Data() (xLo, xHi, yLo, yHi Value) {
+	view := i.vs.View()
+	return view[0], view[1], view[2], view[3]
+}
+
+// AsSmulWide initializes this instruction as a signed widening multiplication instruction with OpcodeSmulWide.
+func (i *Instruction) AsSmulWide(x, y Value) *Instruction {
+	i.opcode = OpcodeSmulWide
+	i.v = x
+	i.v2 = y
+	i.typ = TypeI64
+	return i
+}
+
+// AsUmulWide initializes this instruction as an unsigned widening multiplication instruction with OpcodeUmulWide.
+func (i *Instruction) AsUmulWide(x, y Value) *Instruction {
+	i.opcode = OpcodeUmulWide
+	i.v = x
+	i.v2 = y
+	i.typ = TypeI64
+	return i
+}
+
 // AsSplat initializes this instruction as an insert lane instruction with OpcodeSplat on vector.
 func (i *Instruction) AsSplat(x Value, lane VecLane) *Instruction {
 	i.opcode = OpcodeSplat
@@ -2615,8 +2724,10 @@ func (i *Instruction) Format(b Builder) string {
 		OpcodeVFadd, OpcodeVFsub, OpcodeVFmul, OpcodeVFdiv,
 		OpcodeVIshl, OpcodeVSshr, OpcodeVUshr,
 		OpcodeVFmin, OpcodeVFmax, OpcodeVMinPseudo, OpcodeVMaxPseudo,
-		OpcodeSnarrow, OpcodeUnarrow, OpcodeSwizzle, OpcodeSqmulRoundSat:
+		OpcodeSnarrow, OpcodeUnarrow, OpcodeSwizzle, OpcodeRelaxedSwizzle, OpcodeSqmulRoundSat:
 		instSuffix = fmt.Sprintf(".%s %s, %s", VecLane(i.u1), i.v.Format(b), i.v2.Format(b))
+	case OpcodeVFma:
+		instSuffix = fmt.Sprintf(".%s %s, %s, %s", VecLane(i.u1), i.v.Format(b), i.v2.Format(b), i.v3.Format(b))
 	case OpcodeVIabs, OpcodeVIneg, OpcodeVIpopcnt, OpcodeVhighBits, OpcodeVallTrue, OpcodeVanyTrue,
 		OpcodeVFabs, OpcodeVFneg, OpcodeVSqrt, OpcodeVCeil, OpcodeVFloor, OpcodeVTrunc, OpcodeVNearest,
 		OpcodeVFcvtToUintSat, OpcodeVFcvtToSintSat, OpcodeVFcvtFromUint, OpcodeVFcvtFromSint,
@@ -2664,8 +2775,11 @@ func (i *Instruction) Format(b Builder) string {
 		} else {
 			instSuffix = fmt.Sprintf(" %s:%s, %s", FuncRef(i.u1), SignatureID(i.u2), strings.Join(vs, ", "))
 		}
-	case OpcodeWideningPairwiseDotProductS:
+	case OpcodeWideningPairwiseDotProductS, OpcodeSmulWide, OpcodeUmulWide:
 		instSuffix = fmt.Sprintf(" %s, %s", i.v.Format(b), i.v2.Format(b))
+	case OpcodeIadd128, OpcodeIsub128:
+		xLo, xHi, yLo, yHi := i.Arith128Data()
+		instSuffix = fmt.Sprintf(" %s, %s, %s, %s", xLo.Format(b), xHi.Format(b), yLo.Format(b), yHi.Format(b))
 	default:
 		panic(fmt.Sprintf("TODO: format for %s", i.opcode))
 	}
@@ -2927,6 +3041,18 @@ func (o Opcode) String() (ret string) {
 		return "ReturnCall"
 	case OpcodeTailCallReturnCallIndirect:
 		return "ReturnCallIndirect"
+	case OpcodeVFma:
+		return "VFma"
+	case OpcodeRelaxedSwizzle:
+		return "RelaxedSwizzle"
+	case OpcodeIadd128:
+		return "Iadd128"
+	case OpcodeIsub128:
+		return "Isub128"
+	case OpcodeSmulWide:
+		return "SmulWide"
+	case OpcodeUmulWide:
+		return "UmulWide"
 	case OpcodeVbor:
 		return "Vbor"
 	case OpcodeVbxor:
@@ -73,4 +73,15 @@ func Test_ExecutionContextOffsets(t *testing.T) {
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.memoryWait32TrampolineAddress)), wazevoapi.ExecutionContextOffsetMemoryWait32TrampolineAddress)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.memoryWait64TrampolineAddress)), wazevoapi.ExecutionContextOffsetMemoryWait64TrampolineAddress)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.memoryNotifyTrampolineAddress)), wazevoapi.ExecutionContextOffsetMemoryNotifyTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exceptionPending)), wazevoapi.ExecutionContextOffsetExceptionPending)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exceptionPayloadPtr)), wazevoapi.ExecutionContextOffsetExceptionPayloadPtr)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exceptionRef)), wazevoapi.ExecutionContextOffsetExceptionRef)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.throwTrampolineAddress)), wazevoapi.ExecutionContextOffsetThrowTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.throwRefTrampolineAddress)), wazevoapi.ExecutionContextOffsetThrowRefTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exceptionTagMatchTrampolineAddress)), wazevoapi.ExecutionContextOffsetExceptionTagMatchTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.continuationArgsTrampolineAddress)), wazevoapi.ExecutionContextOffsetContinuationArgsTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.contNewTrampolineAddress)), wazevoapi.ExecutionContextOffsetContNewTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.contBindTrampolineAddress)), wazevoapi.ExecutionContextOffsetContBindTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.resumeTrampolineAddress)), wazevoapi.ExecutionContextOffsetResumeTrampolineAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.suspendTrampolineAddress)), wazevoapi.ExecutionContextOffsetSuspendTrampolineAddress)
 }
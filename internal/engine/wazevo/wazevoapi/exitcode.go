@@ -30,6 +30,25 @@ const (
 	ExitCodeMemoryWait64
 	ExitCodeMemoryNotify
 	ExitCodeUnalignedAtomic
+	// ExitCodeThrow is an exit code for the throw instruction, to create the exception to be thrown.
+	ExitCodeThrow
+	// ExitCodeThrowRef is an exit code for the throw_ref instruction.
+	ExitCodeThrowRef
+	// ExitCodeExceptionTagMatch is an exit code to check if the tag of the pending exception matches a catch clause.
+	ExitCodeExceptionTagMatch
+	// ExitCodeNullReference is an exit code for a null function reference of call_ref, return_call_ref or
+	// ref.as_non_null.
+	ExitCodeNullReference
+	// ExitCodeContinuationArgs is an exit code to get the buffer passing the values of stack switching instructions.
+	ExitCodeContinuationArgs
+	// ExitCodeContNew is an exit code for the cont.new instruction.
+	ExitCodeContNew
+	// ExitCodeContBind is an exit code for the cont.bind instruction.
+	ExitCodeContBind
+	// ExitCodeResume is an exit code for the resume instruction.
+	ExitCodeResume
+	// ExitCodeSuspend is an exit code for the suspend instruction.
+	ExitCodeSuspend
 	exitCodeMax
 )
 
@@ -86,6 +105,24 @@ func (e ExitCode) String() string {
 		return "memory_wait64"
 	case ExitCodeMemoryNotify:
 		return "memory_notify"
+	case ExitCodeThrow:
+		return "throw"
+	case ExitCodeThrowRef:
+		return "throw_ref"
+	case ExitCodeExceptionTagMatch:
+		return "exception_tag_match"
+	case ExitCodeNullReference:
+		return "null_reference"
+	case ExitCodeContinuationArgs:
+		return "continuation_args"
+	case ExitCodeContNew:
+		return "cont_new"
+	case ExitCodeContBind:
+		return "cont_bind"
+	case ExitCodeResume:
+		return "resume"
+	case ExitCodeSuspend:
+		return "suspend"
 	}
 	panic("TODO")
 }
@@ -53,6 +53,28 @@ const (
 	ExecutionContextOffsetMemoryWait32TrampolineAddress Offset = 1160
 	ExecutionContextOffsetMemoryWait64TrampolineAddress Offset = 1168
 	ExecutionContextOffsetMemoryNotifyTrampolineAddress Offset = 1176
+	// ExecutionContextOffsetExceptionPending is an offset of `exceptionPending` field in wazevo.executionContext
+	ExecutionContextOffsetExceptionPending Offset = 1184
+	// ExecutionContextOffsetExceptionPayloadPtr is an offset of `exceptionPayloadPtr` field in wazevo.executionContext
+	ExecutionContextOffsetExceptionPayloadPtr Offset = 1192
+	// ExecutionContextOffsetExceptionRef is an offset of `exceptionRef` field in wazevo.executionContext
+	ExecutionContextOffsetExceptionRef Offset = 1200
+	// ExecutionContextOffsetThrowTrampolineAddress is an offset of `throwTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetThrowTrampolineAddress Offset = 1208
+	// ExecutionContextOffsetThrowRefTrampolineAddress is an offset of `throwRefTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetThrowRefTrampolineAddress Offset = 1216
+	// ExecutionContextOffsetExceptionTagMatchTrampolineAddress is an offset of `exceptionTagMatchTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetExceptionTagMatchTrampolineAddress Offset = 1224
+	// ExecutionContextOffsetContinuationArgsTrampolineAddress is an offset of `continuationArgsTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetContinuationArgsTrampolineAddress Offset = 1232
+	// ExecutionContextOffsetContNewTrampolineAddress is an offset of `contNewTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetContNewTrampolineAddress Offset = 1240
+	// ExecutionContextOffsetContBindTrampolineAddress is an offset of `contBindTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetContBindTrampolineAddress Offset = 1248
+	// ExecutionContextOffsetResumeTrampolineAddress is an offset of `resumeTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetResumeTrampolineAddress Offset = 1256
+	// ExecutionContextOffsetSuspendTrampolineAddress is an offset of `suspendTrampolineAddress` field in wazevo.executionContext
+	ExecutionContextOffsetSuspendTrampolineAddress Offset = 1264
 )
 
 // ModuleContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.moduleContextOpaque,
@@ -62,6 +84,7 @@ type ModuleContextOffsetData struct {
 	ModuleInstanceOffset,
 	LocalMemoryBegin,
 	ImportedMemoryBegin,
+	MemoriesBegin,
 	ImportedFunctionsBegin,
 	GlobalsBegin,
 	TypeIDs1stElement,
@@ -117,6 +140,12 @@ func (m *ModuleContextOffsetData) LocalMemoryLen() Offset {
 	return -1
 }
 
+// MemoryInstanceOffset returns an offset of the *wasm.MemoryInstance of the i-th memory, where i must not be zero
+// as the memory at index zero is either a local memory or an imported memory.
+func (m *ModuleContextOffsetData) MemoryInstanceOffset(i wasm.Index) Offset {
+	return m.MemoriesBegin + Offset(i-1)*8
+}
+
 // TableOffset returns an offset of the i-th table instance.
 func (m *ModuleContextOffsetData) TableOffset(tableIndex int) Offset {
 	return m.TablesBegin + Offset(tableIndex)*8
@@ -131,7 +160,7 @@ func NewModuleContextOffsetData(m *wasm.Module, withListener bool) ModuleContext
 	ret.ModuleInstanceOffset = 0
 	offset += 8
 
-	if m.MemorySection != nil {
+	if m.MemorySection != nil && m.ImportMemoryCount == 0 {
 		ret.LocalMemoryBegin = offset
 		// buffer base + memory size.
 		const localMemorySizeInOpaqueModuleContext = 16
@@ -152,6 +181,19 @@ func NewModuleContextOffsetData(m *wasm.Module, withListener bool) ModuleContext
 		ret.ImportedMemoryBegin = -1
 	}
 
+	memories := int(m.ImportMemoryCount) + len(m.MultiMemorySection)
+	if m.MemorySection != nil {
+		memories++
+	}
+	if memories > 1 {
+		offset = align8(offset)
+		ret.MemoriesBegin = offset
+		// Pointers to *wasm.MemoryInstance of the memories except the one at index zero.
+		offset += Offset(memories-1) * 8
+	} else {
+		ret.MemoriesBegin = -1
+	}
+
 	if m.ImportFunctionCount > 0 {
 		offset = align8(offset)
 		ret.ImportedFunctionsBegin = offset
@@ -172,7 +214,8 @@ func NewModuleContextOffsetData(m *wasm.Module, withListener bool) ModuleContext
 		ret.GlobalsBegin = -1
 	}
 
-	if tables := len(m.TableSection) + int(m.ImportTableCount); tables > 0 {
+	// call_ref and return_call_ref need TypeIDs to check the type of the referenced function even without tables.
+	if tables := len(m.TableSection) + int(m.ImportTableCount); tables > 0 || m.UsesCallRef {
 		offset = align8(offset)
 		ret.TypeIDs1stElement = offset
 		offset += 8 // First element of TypeIDs.
@@ -20,6 +20,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:                    -1,
 				ImportedMemoryBegin:                 -1,
+				MemoriesBegin:                       -1,
 				ImportedFunctionsBegin:              -1,
 				GlobalsBegin:                        -1,
 				TypeIDs1stElement:                   -1,
@@ -37,6 +38,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:                    8,
 				ImportedMemoryBegin:                 -1,
+				MemoriesBegin:                       -1,
 				ImportedFunctionsBegin:              -1,
 				GlobalsBegin:                        -1,
 				TypeIDs1stElement:                   -1,
@@ -54,6 +56,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:                    -1,
 				ImportedMemoryBegin:                 8,
+				MemoriesBegin:                       -1,
 				ImportedFunctionsBegin:              -1,
 				GlobalsBegin:                        -1,
 				TypeIDs1stElement:                   -1,
@@ -71,6 +74,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:                    -1,
 				ImportedMemoryBegin:                 -1,
+				MemoriesBegin:                       -1,
 				ImportedFunctionsBegin:              8,
 				GlobalsBegin:                        -1,
 				TypeIDs1stElement:                   -1,
@@ -88,6 +92,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:                    -1,
 				ImportedMemoryBegin:                 8,
+				MemoriesBegin:                       -1,
 				ImportedFunctionsBegin:              24,
 				GlobalsBegin:                        -1,
 				TypeIDs1stElement:                   -1,
@@ -112,6 +117,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:       8,
 				ImportedMemoryBegin:    -1,
+				MemoriesBegin:          -1,
 				ImportedFunctionsBegin: 24,
 				// Align to 16 bytes for globals.
 				GlobalsBegin:                        32 + 10*FunctionInstanceSize,
@@ -138,6 +144,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:       8,
 				ImportedMemoryBegin:    -1,
+				MemoriesBegin:          -1,
 				ImportedFunctionsBegin: 24,
 				// Align to 16 bytes for globals.
 				GlobalsBegin:                        32 + 10*FunctionInstanceSize,
@@ -150,6 +157,46 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				TotalSize:                           32 + 10*FunctionInstanceSize + 16*30 + 8 + 8*15 + 32,
 			},
 		},
+		{
+			name: "imported mem / local mems",
+			m: &wasm.Module{
+				ImportMemoryCount:  1,
+				MemorySection:      &wasm.Memory{},
+				MultiMemorySection: []*wasm.Memory{{}},
+			},
+			exp: ModuleContextOffsetData{
+				LocalMemoryBegin:                    -1,
+				ImportedMemoryBegin:                 8,
+				MemoriesBegin:                       24,
+				ImportedFunctionsBegin:              -1,
+				GlobalsBegin:                        -1,
+				TypeIDs1stElement:                   -1,
+				TablesBegin:                         -1,
+				BeforeListenerTrampolines1stElement: -1,
+				AfterListenerTrampolines1stElement:  -1,
+				DataInstances1stElement:             40,
+				ElementInstances1stElement:          48,
+				TotalSize:                           64,
+			},
+		},
+		{
+			name: "call_ref without tables",
+			m:    &wasm.Module{UsesCallRef: true},
+			exp: ModuleContextOffsetData{
+				LocalMemoryBegin:                    -1,
+				ImportedMemoryBegin:                 -1,
+				MemoriesBegin:                       -1,
+				ImportedFunctionsBegin:              -1,
+				GlobalsBegin:                        -1,
+				TypeIDs1stElement:                   8,
+				TablesBegin:                         16,
+				BeforeListenerTrampolines1stElement: -1,
+				AfterListenerTrampolines1stElement:  -1,
+				DataInstances1stElement:             16,
+				ElementInstances1stElement:          24,
+				TotalSize:                           32,
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			got := NewModuleContextOffsetData(tc.m, tc.withListener)
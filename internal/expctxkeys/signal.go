@@ -0,0 +1,5 @@
+package expctxkeys
+
+// SignalHandlerKey is a context.Context Value key. Its associated value should
+// be a SignalHandler.
+type SignalHandlerKey struct{}
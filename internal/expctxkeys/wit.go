@@ -0,0 +1,5 @@
+package expctxkeys
+
+// WITWorldKey is a context.Context Value key.
+// Its associated value should be a *wit.World, whose imports validate those of the components compiled.
+type WITWorldKey struct{}
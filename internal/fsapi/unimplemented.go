@@ -25,3 +25,12 @@ func (unimplementedFile) SetNonblock(bool) experimentalsys.Errno {
 func (unimplementedFile) Poll(Pflag, int32) (ready bool, errno experimentalsys.Errno) {
 	return false, experimentalsys.ENOSYS
 }
+
+// Lock implements experimentalsys.Locker, as the type assertion would
+// otherwise fail on the adapted file.
+func (f unimplementedFile) Lock(how experimentalsys.LockFlag) experimentalsys.Errno {
+	if l, ok := f.File.(experimentalsys.Locker); ok {
+		return l.Lock(how)
+	}
+	return experimentalsys.ENOSYS
+}
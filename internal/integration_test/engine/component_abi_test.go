@@ -0,0 +1,372 @@
+package adhoc
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	cm "github.com/tetratelabs/wazero/experimental/component"
+	"github.com/tetratelabs/wazero/experimental/wit"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var (
+	// componentABIReallocType is the type of cabi_realloc.
+	componentABIReallocType = wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}}
+	// componentABIGreetType is the type of greet lowered with a string param and a string result.
+	componentABIGreetType = wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32}}
+	// componentABIRunGreetType is the type of run_greet, which returns the pointer to the result of greet.
+	componentABIRunGreetType = wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}
+)
+
+// componentABIHeapBase is the initial pointer of the bump allocator of cabi_realloc.
+var componentABIHeapBase = wasm.Global{
+	Type: wasm.GlobalType{ValType: i32, Mutable: true},
+	Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x80, 0x08}}, // 1024
+}
+
+// componentABIRealloc is a cabi_realloc which bumps the global 0.
+var componentABIRealloc = wasm.Code{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+	// ptr = (heap + align - 1) & -align
+	wasm.OpcodeGlobalGet, 0,
+	wasm.OpcodeLocalGet, 2,
+	wasm.OpcodeI32Add,
+	wasm.OpcodeI32Const, 1,
+	wasm.OpcodeI32Sub,
+	wasm.OpcodeI32Const, 0,
+	wasm.OpcodeLocalGet, 2,
+	wasm.OpcodeI32Sub,
+	wasm.OpcodeI32And,
+	wasm.OpcodeLocalSet, 4,
+	// heap = ptr + size
+	wasm.OpcodeLocalGet, 4,
+	wasm.OpcodeLocalGet, 3,
+	wasm.OpcodeI32Add,
+	wasm.OpcodeGlobalSet, 0,
+	wasm.OpcodeLocalGet, 4,
+	wasm.OpcodeEnd,
+}}
+
+// componentABIRunGreet calls the function 0, greet, with the string of its params, and returns the pointer to its
+// result, which is 256.
+var componentABIRunGreet = wasm.Code{Body: []byte{
+	wasm.OpcodeLocalGet, 0,
+	wasm.OpcodeLocalGet, 1,
+	wasm.OpcodeI32Const, 0x80, 0x02,
+	wasm.OpcodeCall, 0,
+	wasm.OpcodeI32Const, 0x80, 0x02,
+	wasm.OpcodeEnd,
+}}
+
+// componentABICoreModule is a core module which imports greet from "test:host/api", and exports its memory and
+// cabi_realloc.
+var componentABICoreModule = &wasm.Module{
+	TypeSection:     []wasm.FunctionType{componentABIGreetType, componentABIReallocType, componentABIRunGreetType},
+	ImportSection:   []wasm.Import{{Module: "test:host/api", Name: "greet", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	FunctionSection: []wasm.Index{1, 2},
+	MemorySection:   &wasm.Memory{Min: 1},
+	GlobalSection:   []wasm.Global{componentABIHeapBase},
+	CodeSection:     []wasm.Code{componentABIRealloc, componentABIRunGreet},
+	ExportSection: []wasm.Export{
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "cabi_realloc", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "run_greet", Type: wasm.ExternTypeFunc, Index: 2},
+	},
+}
+
+// componentABIAllocModule is the core module of componentABIBinary which defines the memory and cabi_realloc.
+var componentABIAllocModule = &wasm.Module{
+	TypeSection:     []wasm.FunctionType{componentABIReallocType},
+	FunctionSection: []wasm.Index{0},
+	MemorySection:   &wasm.Memory{Min: 1},
+	GlobalSection:   []wasm.Global{componentABIHeapBase},
+	CodeSection:     []wasm.Code{componentABIRealloc},
+	ExportSection: []wasm.Export{
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "cabi_realloc", Type: wasm.ExternTypeFunc, Index: 0},
+	},
+}
+
+// componentABIGuestModule is the core module of componentABIBinary which calls the lowered functions.
+var componentABIGuestModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		componentABIGreetType,
+		{Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32}},
+		componentABIRunGreetType,
+	},
+	ImportSection: []wasm.Import{
+		{Module: "host", Name: "greet", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		{Module: "host", Name: "new-counter", Type: wasm.ExternTypeFunc, DescFunc: 1},
+		{Module: "host", Name: "inc", Type: wasm.ExternTypeFunc, DescFunc: 2},
+		{Module: "host", Name: "drop", Type: wasm.ExternTypeFunc, DescFunc: 3},
+		{Module: "alloc", Name: "memory", Type: wasm.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1}},
+	},
+	FunctionSection: []wasm.Index{4, 1},
+	CodeSection: []wasm.Code{
+		componentABIRunGreet,
+		// count increments a new counter twice, drops it, and returns its count.
+		{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+			wasm.OpcodeCall, 1,
+			wasm.OpcodeLocalSet, 0,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeCall, 2,
+			wasm.OpcodeDrop,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeCall, 2,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeCall, 3,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "run_greet", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "count", Type: wasm.ExternTypeFunc, Index: 5},
+	},
+}
+
+// componentABIBinary is a component which lowers the functions of the imported "test:host/api" instance with the
+// memory and the cabi_realloc of a core instance, and lifts the functions of its core module which call them.
+var componentABIBinary = func() []byte {
+	name := binaryencoding.ComponentName
+	vec := binaryencoding.ComponentVec
+	cat := func(bs ...[]byte) (ret []byte) {
+		for _, b := range bs {
+			ret = append(ret, b...)
+		}
+		return
+	}
+	const u32, str = 0x79, 0x73
+
+	return binaryencoding.EncodeComponent(
+		// type 0: (instance
+		//   (export "counter" (type (sub resource)))
+		//   (type (own 0)) (type (borrow 0))
+		//   (type (func (param "name" string) (result string))) (export "greet" (func (type 3)))
+		//   (type (func (result 1))) (export "new-counter" (func (type 4)))
+		//   (type (func (param "self" 2) (result u32))) (export "inc" (func (type 5))))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			cat([]byte{0x42}, vec(
+				cat([]byte{0x04, 0x00}, name("counter"), []byte{0x03, 0x01}),
+				[]byte{0x01, 0x69, 0},
+				[]byte{0x01, 0x68, 0},
+				cat([]byte{0x01, 0x40}, vec(cat(name("name"), []byte{str})), []byte{0x00, str}),
+				cat([]byte{0x04, 0x00}, name("greet"), []byte{0x01, 3}),
+				cat([]byte{0x01, 0x40}, vec(), []byte{0x00, 1}),
+				cat([]byte{0x04, 0x00}, name("new-counter"), []byte{0x01, 4}),
+				cat([]byte{0x01, 0x40}, vec(cat(name("self"), []byte{2})), []byte{0x00, u32}),
+				cat([]byte{0x04, 0x00}, name("inc"), []byte{0x01, 5}),
+			))),
+		// instance 0: (import "test:host/api" (instance (type 0)))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDImport,
+			cat([]byte{0x00}, name("test:host/api"), []byte{0x05, 0})),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			// type 1: (alias export 0 "counter")
+			cat([]byte{0x03, 0x00, 0}, name("counter")),
+			// func 0, 1 and 2: (alias export 0 "greet"), (alias export 0 "new-counter") and (alias export 0 "inc")
+			cat([]byte{0x01, 0x00, 0}, name("greet")),
+			cat([]byte{0x01, 0x00, 0}, name("new-counter")),
+			cat([]byte{0x01, 0x00, 0}, name("inc"))),
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDCoreModule,
+			binaryencoding.EncodeModule(componentABIAllocModule)),
+		// core instance 0: (instantiate 0)
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance, []byte{0x00, 0, 0}),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			// core func 0: (alias core export 0 "cabi_realloc")
+			cat([]byte{0x00, 0x00, 0x01, 0}, name("cabi_realloc")),
+			// core memory 0: (alias core export 0 "memory")
+			cat([]byte{0x00, 0x02, 0x01, 0}, name("memory"))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon,
+			// core func 1: (canon lower (func 0) (memory 0) (realloc 0))
+			cat([]byte{0x01, 0x00, 0}, vec([]byte{0x03, 0}, []byte{0x04, 0})),
+			// core func 2 and 3: (canon lower (func 1)) and (canon lower (func 2))
+			[]byte{0x01, 0x00, 1, 0},
+			[]byte{0x01, 0x00, 2, 0},
+			// core func 4: (canon resource.drop 1)
+			[]byte{0x03, 1}),
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDCoreModule,
+			binaryencoding.EncodeModule(componentABIGuestModule)),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance,
+			// core instance 1: (instance (export "greet" (func 1)) (export "new-counter" (func 2)) ...)
+			cat([]byte{0x01}, vec(
+				cat(name("greet"), []byte{0x00, 1}),
+				cat(name("new-counter"), []byte{0x00, 2}),
+				cat(name("inc"), []byte{0x00, 3}),
+				cat(name("drop"), []byte{0x00, 4}),
+			)),
+			// core instance 2: (instance (export "memory" (memory 0)))
+			cat([]byte{0x01}, vec(cat(name("memory"), []byte{0x02, 0}))),
+			// core instance 3: (instantiate 1 (with "host" (instance 1)) (with "alloc" (instance 2)))
+			cat([]byte{0x00, 1}, vec(cat(name("host"), []byte{0x12, 1}), cat(name("alloc"), []byte{0x12, 2})))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			// core func 5 and 6: (alias core export 3 "run_greet") and (alias core export 3 "count")
+			cat([]byte{0x00, 0x00, 0x01, 3}, name("run_greet")),
+			cat([]byte{0x00, 0x00, 0x01, 3}, name("count"))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			// type 2: (func (param "ptr" u32) (param "len" u32) (result u32))
+			cat([]byte{0x40}, vec(cat(name("ptr"), []byte{u32}), cat(name("len"), []byte{u32})), []byte{0x00, u32}),
+			// type 3: (func (result u32))
+			cat([]byte{0x40}, vec(), []byte{0x00, u32})),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon,
+			// func 3: (canon lift (core func 5) (type 2))
+			[]byte{0x00, 0x00, 5, 0, 2},
+			// func 4: (canon lift (core func 6) (type 3))
+			[]byte{0x00, 0x00, 6, 0, 3}),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDExport,
+			cat([]byte{0x00}, name("run-greet"), []byte{0x01, 3, 0x00}),
+			cat([]byte{0x00}, name("count"), []byte{0x01, 4, 0x00})),
+	)
+}()
+
+// componentABICounter is the representation of the counter resource of the host.
+type componentABICounter struct {
+	n      uint32
+	closed bool
+}
+
+// Close implements api.Closer.
+func (c *componentABICounter) Close(context.Context) error {
+	c.closed = true
+	return nil
+}
+
+func TestComponent_CanonicalABI(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, tc.cfg)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			var counters []*componentABICounter
+			_, err := r.NewHostModuleBuilder("test:host/api").
+				NewFunctionBuilder().WithComponentFunc(func(name string) string {
+				return "hello " + name
+			}).Export("greet").
+				NewFunctionBuilder().WithComponentFunc(func() cm.Own[*componentABICounter] {
+				c := &componentABICounter{}
+				counters = append(counters, c)
+				return cm.Own[*componentABICounter]{Rep: c}
+			}).Export("new-counter").
+				NewFunctionBuilder().WithComponentFunc(func(c cm.Borrow[*componentABICounter]) uint32 {
+				c.Rep.n++
+				return c.Rep.n
+			}).Export("inc").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			// greet writes the (ptr, len) of its result at 256, whose string is allocated with cabi_realloc.
+			runGreet := func(t *testing.T, fn api.Function, mem api.Memory, name string) string {
+				require.True(t, mem.WriteString(16, name))
+				res, err := fn.Call(ctx, 16, uint64(len(name)))
+				require.NoError(t, err)
+				ret, ok := mem.Read(uint32(res[0]), 8)
+				require.True(t, ok)
+				s, ok := mem.Read(binary.LittleEndian.Uint32(ret), binary.LittleEndian.Uint32(ret[4:]))
+				require.True(t, ok)
+				return string(s)
+			}
+
+			t.Run("core module", func(t *testing.T) {
+				mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(componentABICoreModule))
+				require.NoError(t, err)
+				defer mod.Close(ctx)
+
+				require.Equal(t, "hello wazero", runGreet(t, mod.ExportedFunction("run_greet"), mod.Memory(), "wazero"))
+			})
+
+			t.Run("component", func(t *testing.T) {
+				mod, err := r.Instantiate(ctx, componentABIBinary)
+				require.NoError(t, err)
+
+				require.Equal(t, "hello 世界", runGreet(t, mod.ExportedFunction("run-greet"), mod.Memory(), "世界"))
+
+				res, err := mod.ExportedFunction("count").Call(ctx)
+				require.NoError(t, err)
+				require.Equal(t, uint64(2), res[0])
+				require.Equal(t, 1, len(counters))
+				require.True(t, counters[0].closed)
+
+				require.NoError(t, mod.Close(ctx))
+			})
+
+			t.Run("component call", func(t *testing.T) {
+				mod, err := r.Instantiate(ctx, componentABIBinary)
+				require.NoError(t, err)
+				defer mod.Close(ctx)
+
+				var count uint32
+				require.NoError(t, cm.Call(ctx, mod.ExportedFunction("count"), &count))
+				require.Equal(t, uint32(2), count)
+
+				require.EqualError(t, cm.Call(ctx, mod.ExportedFunction("count"), &count, uint32(1)),
+					"expected 0 params, but got 1")
+				var s string
+				require.EqualError(t, cm.Call(ctx, mod.ExportedFunction("count"), &s),
+					"result: type mismatch: u32 != string")
+			})
+
+			t.Run("wit world", func(t *testing.T) {
+				for _, tc := range []struct {
+					name, api, expectedErr string
+				}{
+					{name: "valid", api: "interface api {\n" + componentABIWIT + "}\n"},
+					{
+						name:        "not imported",
+						expectedErr: `import "test:host/api" is not imported by world guest`,
+					},
+					{
+						name:        "missing function",
+						api:         "interface api {\n  resource counter;\n  greet: func(name: string) -> string;\n}\n",
+						expectedErr: `import "test:host/api": function "new-counter" is not in interface test:host/api`,
+					},
+					{
+						name: "type mismatch",
+						api: "interface api {\n" +
+							strings.Replace(componentABIWIT, "-> string", "-> u32", 1) + "}\n",
+						expectedErr: `import "test:host/api": function "greet": type mismatch: ` +
+							`func(name: string) -> string != func(name: string) -> u32`,
+					},
+				} {
+					t.Run(tc.name, func(t *testing.T) {
+						src := "package test:host;\n\n" + tc.api + "\nworld guest {\n"
+						if tc.api != "" {
+							src += "  import api;\n"
+						}
+						src += "}\n"
+						pkg, err := wit.Parse("host.wit", []byte(src))
+						require.NoError(t, err)
+
+						_, err = r.CompileModule(wit.WithWorld(ctx, pkg.World("guest")), componentABIBinary)
+						if tc.expectedErr == "" {
+							require.NoError(t, err)
+						} else {
+							require.EqualError(t, err, tc.expectedErr)
+						}
+					})
+				}
+			})
+		})
+	}
+}
+
+// componentABIWIT is the WIT of the functions of the "test:host/api" instance imported by componentABIBinary.
+const componentABIWIT = `  resource counter;
+  greet: func(name: string) -> string;
+  new-counter: func() -> counter;
+  inc: func(self: borrow<counter>) -> u32;
+`
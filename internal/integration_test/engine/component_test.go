@@ -0,0 +1,210 @@
+package adhoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/sock"
+	internalsock "github.com/tetratelabs/wazero/internal/sock"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// componentCoreModule is the core module of componentBinary, whose add logs its first parameter with the host.
+var componentCoreModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+	},
+	ImportSection:   []wasm.Import{{Module: "host", Name: "log", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	FunctionSection: []wasm.Index{1},
+	MemorySection:   &wasm.Memory{Min: 1},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{
+		{Name: "add", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+}
+
+// componentNestedCoreModule is the core module of the component nested in componentBinary.
+var componentNestedCoreModule = &wasm.Module{
+	TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "double", Type: wasm.ExternTypeFunc, Index: 0}},
+}
+
+// componentBinary is a component which lowers the "log" function of the imported "test:host/log" instance, lifts the
+// "add" function of its core module, and re-exports the "double" function of a nested component.
+var componentBinary = func() []byte {
+	name := binaryencoding.ComponentName
+	vec := binaryencoding.ComponentVec
+	cat := func(bs ...[]byte) (ret []byte) {
+		for _, b := range bs {
+			ret = append(ret, b...)
+		}
+		return
+	}
+	const u32 = 0x79
+
+	nested := binaryencoding.EncodeComponent(
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDCoreModule,
+			binaryencoding.EncodeModule(componentNestedCoreModule)),
+		// core instance 0: (instantiate 0)
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance, []byte{0x00, 0, 0}),
+		// core func 0: (alias core export 0 "double")
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			cat([]byte{0x00, 0x00, 0x01, 0}, name("double"))),
+		// type 0: (func (param "x" u32) (result u32))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			cat([]byte{0x40}, vec(cat(name("x"), []byte{u32})), []byte{0x00, u32})),
+		// func 0: (canon lift (core func 0) (type 0))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon, []byte{0x00, 0x00, 0, 0, 0}),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDExport,
+			cat([]byte{0x00}, name("double"), []byte{0x01, 0, 0x00})),
+	)[8:]
+
+	return binaryencoding.EncodeComponent(
+		// type 0: (instance (type (func (param "x" u32))) (export "log" (func (type 0))))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			cat([]byte{0x42}, vec(
+				cat([]byte{0x01, 0x40}, vec(cat(name("x"), []byte{u32})), []byte{0x01, 0x00}),
+				cat([]byte{0x04, 0x00}, name("log"), []byte{0x01, 0}),
+			))),
+		// instance 0: (import "test:host/log" (instance (type 0)))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDImport,
+			cat([]byte{0x00}, name("test:host/log"), []byte{0x05, 0})),
+		// func 0: (alias export 0 "log")
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			cat([]byte{0x01, 0x00, 0}, name("log"))),
+		// core func 0: (canon lower (func 0))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon, []byte{0x01, 0x00, 0, 0}),
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDCoreModule,
+			binaryencoding.EncodeModule(componentCoreModule)),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCoreInstance,
+			// core instance 0: (instance (export "log" (func 0)))
+			cat([]byte{0x01}, vec(cat(name("log"), []byte{0x00, 0}))),
+			// core instance 1: (instantiate 0 (with "host" (instance 0)))
+			cat([]byte{0x00, 0}, vec(cat(name("host"), []byte{0x12, 0})))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			// core func 1: (alias core export 1 "add")
+			cat([]byte{0x00, 0x00, 0x01, 1}, name("add")),
+			// core memory 0: (alias core export 1 "memory")
+			cat([]byte{0x00, 0x02, 0x01, 1}, name("memory"))),
+		// type 1: (func (param "a" u32) (param "b" u32) (result u32))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDType,
+			cat([]byte{0x40}, vec(cat(name("a"), []byte{u32}), cat(name("b"), []byte{u32})), []byte{0x00, u32})),
+		// func 1: (canon lift (core func 1) (memory 0) (type 1))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDCanon,
+			cat([]byte{0x00, 0x00, 1}, vec([]byte{0x03, 0}), []byte{1})),
+		binaryencoding.ComponentSection(binaryencoding.ComponentSectionIDComponent, nested),
+		// instance 1: (instantiate 0)
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDInstance, []byte{0x00, 0, 0}),
+		// func 2: (alias export 1 "double")
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDAlias,
+			cat([]byte{0x01, 0x00, 1}, name("double"))),
+		// instance 2: (instance (export "add" (func 1)))
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDInstance,
+			cat([]byte{0x01}, vec(cat([]byte{0x00}, name("add"), []byte{0x01, 1})))),
+		binaryencoding.ComponentVecSection(binaryencoding.ComponentSectionIDExport,
+			cat([]byte{0x00}, name("add"), []byte{0x01, 1, 0x00}),
+			cat([]byte{0x00}, name("double"), []byte{0x01, 2, 0x00}),
+			cat([]byte{0x00}, name("test:math/calc"), []byte{0x05, 2, 0x00})),
+	)
+}()
+
+func TestComponent(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, tc.cfg)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			compiled, err := r.CompileModule(ctx, componentBinary)
+			require.NoError(t, err)
+
+			defs := compiled.ExportedFunctions()
+			require.Equal(t, 3, len(defs))
+			for _, name := range []string{"add", "test:math/calc#add"} {
+				require.Equal(t, []api.ValueType{i32, i32}, defs[name].ParamTypes(), name)
+				require.Equal(t, []api.ValueType{i32}, defs[name].ResultTypes(), name)
+			}
+			require.Equal(t, []api.ValueType{i32}, defs["double"].ParamTypes())
+
+			t.Run("missing import", func(t *testing.T) {
+				_, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+				require.EqualError(t, err, `core func[0]: import "test:host/log": module[test:host/log] not instantiated`)
+			})
+
+			var logged []uint32
+			var listener bool
+			_, err = r.NewHostModuleBuilder("test:host/log").
+				NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, x uint32) {
+				require.NotNil(t, m.Memory())
+				logged = append(logged, x)
+				f, ok := m.(*wasm.ModuleInstance).Sys.FS().LookupFile(3)
+				if ok {
+					_, listener = f.File.(internalsock.TCPSock)
+				}
+			}).Export("log").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("calc"))
+			require.NoError(t, err)
+			require.Equal(t, "calc", mod.Name())
+			require.NotNil(t, mod.Memory())
+
+			for _, name := range []string{"add", "test:math/calc#add"} {
+				res, err := mod.ExportedFunction(name).Call(ctx, 2, 3)
+				require.NoError(t, err)
+				require.Equal(t, uint64(5), res[0])
+			}
+			require.Equal(t, []uint32{2, 2}, logged)
+
+			res, err := mod.ExportedFunction("double").Call(ctx, 21)
+			require.NoError(t, err)
+			require.Equal(t, uint64(42), res[0])
+			require.Nil(t, mod.ExportedFunction("log"))
+
+			require.NoError(t, mod.Close(ctx))
+			require.True(t, mod.IsClosed())
+			require.False(t, listener)
+
+			t.Run("preopened listener", func(t *testing.T) {
+				sockCtx := sock.WithConfig(ctx, sock.NewConfig().WithTCPListener("127.0.0.1", 0))
+				mod, err := r.InstantiateModule(sockCtx, compiled, wazero.NewModuleConfig().WithName("calc-sock"))
+				require.NoError(t, err)
+				defer mod.Close(ctx)
+
+				_, err = mod.ExportedFunction("add").Call(ctx, 2, 3)
+				require.NoError(t, err)
+				require.True(t, listener)
+			})
+		})
+	}
+}
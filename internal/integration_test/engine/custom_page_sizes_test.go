@@ -0,0 +1,105 @@
+package adhoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// customPageSizesModule is a module which has a memory of 1 byte pages.
+var customPageSizesModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Results: []wasm.ValueType{i32}},                                // type 0: () -> (i32)
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 1: (i32) -> (i32)
+	},
+	FunctionSection: []wasm.Index{0, 1, 1},
+	MemorySection:   &wasm.Memory{Min: 10, Cap: 10, Max: 100, IsMaxEncoded: true, IsPageSizeEncoded: true},
+	CodeSection: []wasm.Code{
+		{ // func[0] size() -> memory.size
+			Body: []byte{wasm.OpcodeMemorySize, 0, wasm.OpcodeEnd},
+		},
+		{ // func[1] grow(delta) -> memory.grow delta
+			Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd},
+		},
+		{ // func[2] load(addr) -> i32.load8_u addr
+			Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, 0, 0, wasm.OpcodeEnd},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "size", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "grow", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "load", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+}
+
+func TestCustomPageSizes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(api.CoreFeaturesV2))
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(customPageSizesModule))
+		require.Error(t, err)
+	})
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesCustomPageSizes)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			compiled, err := r.CompileModule(ctx, binaryencoding.EncodeModule(customPageSizesModule))
+			require.NoError(t, err)
+			def := compiled.ExportedMemories()["memory"]
+			require.Equal(t, uint32(1), def.PageSize())
+			require.Equal(t, uint32(10), def.Min())
+
+			mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+			require.NoError(t, err)
+			// The upper 32 bits of i32 results are undefined.
+			call := func(name string, params ...uint64) uint32 {
+				res, err := mod.ExportedFunction(name).Call(ctx, params...)
+				require.NoError(t, err)
+				return uint32(res[0])
+			}
+
+			require.Equal(t, uint32(10), call("size"))
+			require.Equal(t, uint32(10), mod.Memory().Size())
+			_, err = mod.ExportedFunction("load").Call(ctx, 10)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+			require.Equal(t, uint32(10), call("grow", 7))
+			require.Equal(t, uint32(17), call("size"))
+			require.Equal(t, uint32(17), mod.Memory().Size())
+			require.True(t, mod.Memory().WriteByte(16, 42))
+			require.Equal(t, uint32(42), call("load", 16))
+			_, err = mod.ExportedFunction("load").Call(ctx, 17)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+			// Growing over the max fails.
+			require.Equal(t, uint32(0xffffffff), call("grow", 84))
+			require.Equal(t, uint32(17), call("grow", 83))
+			require.Equal(t, uint32(100), call("size"))
+		})
+	}
+}
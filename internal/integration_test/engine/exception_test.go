@@ -0,0 +1,174 @@
+package adhoc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// exceptionModule is a module with a tag "e" of type (i32), which is thrown by the guest and the host, and
+// caught by the guest.
+var exceptionModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32}},                                 // type 0: (i32) -> ()
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 1: (i32) -> (i32)
+	},
+	ImportFunctionCount: 1,
+	ImportSection: []wasm.Import{{
+		Module:   "env",
+		Name:     "host_throw",
+		Type:     wasm.ExternTypeFunc,
+		DescFunc: 0,
+	}},
+	TagSection:      []wasm.Index{0},
+	FunctionSection: []wasm.Index{1, 1, 1, 1, 1},
+	CodeSection: []wasm.Code{
+		{ // func[1] throw(x): throw e(x)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeExceptionHandlingThrow, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] catch(x): try { throw(x) } catch e(v) { v + 1 }
+			Body: []byte{
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeExceptionHandlingTryTable, i32, 1, wasm.CatchKindCatch, 0, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeCall, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] catch_host(x): try { host_throw(x) } catch e(v) { v + 1 }
+			Body: []byte{
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeExceptionHandlingTryTable, 0x40, 1, wasm.CatchKindCatch, 0, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeCall, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] rethrow(x): try { throw(x) } catch_all_ref (r) { throw_ref r }
+			Body: []byte{
+				wasm.OpcodeBlock, wasm.ValueTypeExnref,
+				wasm.OpcodeExceptionHandlingTryTable, 0x40, 1, wasm.CatchKindCatchAllRef, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeCall, 1,
+				wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeUnreachable,
+				wasm.OpcodeEnd,
+				wasm.OpcodeExceptionHandlingThrowRef,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[5] catch_inner(x): try { try { throw(x) } catch_all { x } } catch e(v) { v + 1 }
+			Body: []byte{
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeExceptionHandlingTryTable, 0x40, 1, wasm.CatchKindCatchAll, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeCall, 1,
+				wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "e", Type: wasm.ExternTypeTag, Index: 0},
+		{Name: "throw", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "catch", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "catch_host", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "rethrow", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "catch_inner", Type: wasm.ExternTypeFunc, Index: 5},
+	},
+}
+
+func TestExceptionHandling(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(ctx context.Context, mod api.Module, v uint32) {
+					panic(experimental.NewException(experimental.ExportedTag(mod, "e"), api.EncodeU32(v*2)))
+				}).Export("host_throw").Instantiate(ctx)
+			require.NoError(t, err)
+
+			mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(exceptionModule))
+			require.NoError(t, err)
+			tag := experimental.ExportedTag(mod, "e")
+			require.NotNil(t, tag)
+			require.Equal(t, []api.ValueType{i32}, tag.ParamTypes())
+
+			t.Run("uncaught", func(t *testing.T) {
+				_, err := mod.ExportedFunction("throw").Call(ctx, 10)
+				var exc *experimental.Exception
+				require.True(t, errors.As(err, &exc))
+				require.Equal(t, tag, exc.Tag())
+				require.Equal(t, []uint64{10}, exc.Payload())
+			})
+
+			for _, tc := range []struct {
+				name     string
+				expected uint64
+			}{
+				{name: "catch", expected: 11},
+				{name: "catch_host", expected: 21},
+				{name: "catch_inner", expected: 10},
+			} {
+				t.Run(tc.name, func(t *testing.T) {
+					res, err := mod.ExportedFunction(tc.name).Call(ctx, 10)
+					require.NoError(t, err)
+					require.Equal(t, []uint64{tc.expected}, res)
+				})
+			}
+
+			t.Run("rethrow", func(t *testing.T) {
+				_, err := mod.ExportedFunction("rethrow").Call(ctx, 10)
+				var exc *experimental.Exception
+				require.True(t, errors.As(err, &exc))
+				require.Equal(t, tag, exc.Tag())
+				require.Equal(t, []uint64{10}, exc.Payload())
+			})
+		})
+	}
+}
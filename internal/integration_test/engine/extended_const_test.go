@@ -0,0 +1,153 @@
+package adhoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// extendedConstBaseModule exports the base offsets of the memory and the table, like the dynamic linker of
+// Emscripten does for position-independent modules.
+var extendedConstBaseModule = &wasm.Module{
+	GlobalSection: []wasm.Global{
+		{Type: wasm.GlobalType{ValType: i32}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x80, 0x08}}}, // 1024
+		{Type: wasm.GlobalType{ValType: i32}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{2}}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "__memory_base", Type: wasm.ExternTypeGlobal, Index: 0},
+		{Name: "__table_base", Type: wasm.ExternTypeGlobal, Index: 1},
+	},
+}
+
+// extendedConstModule is a position-independent module whose data and element segments are placed at the offsets
+// relative to the imported bases, and whose globals are computed with extended constant expressions.
+var extendedConstModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Results: []wasm.ValueType{i32}},                                // type 0: () -> (i32)
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 1: (i32) -> (i32)
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeGlobal, Module: "env", Name: "__memory_base", DescGlobal: wasm.GlobalType{ValType: i32}},
+		{Type: wasm.ExternTypeGlobal, Module: "env", Name: "__table_base", DescGlobal: wasm.GlobalType{ValType: i32}},
+	},
+	FunctionSection: []wasm.Index{0, 0, 1},
+	TableSection:    []wasm.Table{{Min: 8, Type: wasm.RefTypeFuncref}},
+	MemorySection:   &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+	GlobalSection: []wasm.Global{
+		{
+			// (i32.add (global.get $__memory_base) (i32.const 16))
+			Type: wasm.GlobalType{ValType: i32},
+			Init: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Add,
+				Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 16},
+			},
+		},
+		{
+			// (i64.mul (i64.sub (i64.const 1) (i64.const 3)) (i64.const 0x10000))
+			Type: wasm.GlobalType{ValType: i64},
+			Init: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI64Mul,
+				Data: []byte{
+					wasm.OpcodeI64Const, 1, wasm.OpcodeI64Const, 3, wasm.OpcodeI64Sub,
+					wasm.OpcodeI64Const, 0x80, 0x80, 0x04,
+				},
+			},
+		},
+	},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeI32Const, 10, wasm.OpcodeEnd}}, // func[0] ten() -> 10
+		{Body: []byte{wasm.OpcodeI32Const, 20, wasm.OpcodeEnd}}, // func[1] twenty() -> 20
+		{ // func[2] call(i) -> call_indirect (i)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeCallIndirect, 0, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ElementSection: []wasm.ElementSegment{
+		{
+			// (i32.add (global.get $__table_base) (i32.const 1))
+			OffsetExpr: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Add,
+				Data:   []byte{wasm.OpcodeGlobalGet, 1, wasm.OpcodeI32Const, 1},
+			},
+			Init: []wasm.Index{0, 1}, Type: wasm.RefTypeFuncref, Mode: wasm.ElementModeActive,
+		},
+	},
+	DataSection: []wasm.DataSegment{
+		{
+			// (i32.sub (i32.mul (global.get $__memory_base) (i32.const 2)) (i32.const 4))
+			OffsetExpression: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Sub,
+				Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Mul, wasm.OpcodeI32Const, 4},
+			},
+			Init: []byte("wazero"),
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "addr", Type: wasm.ExternTypeGlobal, Index: 2},
+		{Name: "neg", Type: wasm.ExternTypeGlobal, Index: 3},
+		{Name: "mem", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "call", Type: wasm.ExternTypeFunc, Index: 2},
+	},
+}
+
+func TestExtendedConst(t *testing.T) {
+	ctx := context.Background()
+	bin := binaryencoding.EncodeModule(extendedConstModule)
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(api.CoreFeaturesV2))
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, bin)
+		require.Error(t, err)
+	})
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			_, err := r.InstantiateWithConfig(ctx, binaryencoding.EncodeModule(extendedConstBaseModule),
+				wazero.NewModuleConfig().WithName("env"))
+			require.NoError(t, err)
+
+			mod, err := r.Instantiate(ctx, bin)
+			require.NoError(t, err)
+
+			require.Equal(t, uint64(1024+16), mod.ExportedGlobal("addr").Get())
+			require.Equal(t, uint64(0xffff_ffff_fffe_0000), mod.ExportedGlobal("neg").Get()) // -2 * 0x10000
+
+			data, ok := mod.ExportedMemory("mem").Read(1024*2-4, 6)
+			require.True(t, ok)
+			require.Equal(t, "wazero", string(data))
+
+			call := mod.ExportedFunction("call")
+			for i, exp := range []uint32{10, 20} {
+				res, err := call.Call(ctx, uint64(2+1+i))
+				require.NoError(t, err)
+				require.Equal(t, exp, uint32(res[0]))
+			}
+		})
+	}
+}
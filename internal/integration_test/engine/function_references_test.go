@@ -0,0 +1,219 @@
+package adhoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+const funcref = wasm.ValueTypeFuncref
+
+// functionReferencesModule is a module which calls the functions referenced by typed function references.
+var functionReferencesModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 0: (i32) -> (i32)
+		{ // type 1: (ref null 0, i32) -> (i32)
+			Params:    []wasm.ValueType{funcref, i32},
+			ParamRefs: []wasm.HeapRef{{HeapType: 0}, wasm.DefaultHeapRef(i32)},
+			Results:   []wasm.ValueType{i32},
+		},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{funcref}}, // type 2: (i32) -> (funcref)
+		{ // type 3: (ref null 0) -> (i32)
+			Params:    []wasm.ValueType{funcref},
+			ParamRefs: []wasm.HeapRef{{HeapType: 0}},
+			Results:   []wasm.ValueType{i32},
+		},
+	},
+	FunctionSection: []wasm.Index{0, 0, 2, 1, 1, 1, 1, 3, 0},
+	CodeSection: []wasm.Code{
+		{ // func[0] double(x) -> x + x
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] inc(x) -> x + 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] get(i) -> ref.func double, inc or get itself, which is of another type.
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Eqz,
+				wasm.OpcodeIf, funcref,
+				wasm.OpcodeRefFunc, 0,
+				wasm.OpcodeElse,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Eq,
+				wasm.OpcodeIf, funcref,
+				wasm.OpcodeRefFunc, 1,
+				wasm.OpcodeElse,
+				wasm.OpcodeRefFunc, 2,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] call(f, x) -> call_ref 0 (x, f)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeFunctionReferencesCallRef, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] tail(f, x) -> return_call_ref 0 (x, f)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeFunctionReferencesReturnCallRef, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[5] on_null(f, x) -> -1 if f is null, otherwise call_ref 0 (x, f)
+			Body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeFunctionReferencesBrOnNull, 0,
+				wasm.OpcodeFunctionReferencesCallRef, 0,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 0x7f,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[6] on_non_null(f, x) -> call_ref 0 (x, f) if f is not null, otherwise -1
+			Body: []byte{
+				wasm.OpcodeBlock, wasm.RefTypePrefixNullable, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeFunctionReferencesBrOnNonNull, 0,
+				wasm.OpcodeI32Const, 0x7f,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeFunctionReferencesCallRef, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[7] as_non_null(f) -> drop (ref.as_non_null f); 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeFunctionReferencesRefAsNonNull,
+				wasm.OpcodeDrop,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[8] countdown(n) -> 0 if n is zero, otherwise return_call_ref 0 (n - 1, ref.func countdown)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Eqz,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Sub,
+				wasm.OpcodeRefFunc, 8,
+				wasm.OpcodeFunctionReferencesReturnCallRef, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "double", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "inc", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "get", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "call", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "tail", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "on_null", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "on_non_null", Type: wasm.ExternTypeFunc, Index: 6},
+		{Name: "as_non_null", Type: wasm.ExternTypeFunc, Index: 7},
+		{Name: "countdown", Type: wasm.ExternTypeFunc, Index: 8},
+	},
+}
+
+func TestFunctionReferences(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().
+			WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesTailCall))
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(functionReferencesModule))
+		require.Error(t, err)
+	})
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesFunctionReferences |
+			experimental.CoreFeaturesTailCall)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(functionReferencesModule))
+			require.NoError(t, err)
+			call := func(name string, params ...uint64) uint64 {
+				res, err := mod.ExportedFunction(name).Call(ctx, params...)
+				require.NoError(t, err)
+				return res[0]
+			}
+			// The upper 32 bits of i32 results are undefined.
+			call32 := func(name string, params ...uint64) uint32 {
+				return uint32(call(name, params...))
+			}
+
+			double, inc, get := call("get", 0), call("get", 1), call("get", 2)
+			require.Equal(t, uint32(42), call32("call", double, 21))
+			require.Equal(t, uint32(42), call32("call", inc, 41))
+			require.Equal(t, uint32(42), call32("tail", double, 21))
+			require.Equal(t, uint32(0xffffffff), call32("on_null", 0, 5))
+			require.Equal(t, uint32(10), call32("on_null", double, 5))
+			require.Equal(t, uint32(0xffffffff), call32("on_non_null", 0, 5))
+			require.Equal(t, uint32(6), call32("on_non_null", inc, 5))
+			require.Equal(t, uint32(1), call32("as_non_null", inc))
+
+			// Proper tail calls don't grow the stack.
+			require.Equal(t, uint32(0), call32("countdown", 1_000_000))
+
+			for _, name := range []string{"call", "tail"} {
+				_, err = mod.ExportedFunction(name).Call(ctx, 0, 1)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeNullReference)
+				_, err = mod.ExportedFunction(name).Call(ctx, get, 1)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+			}
+			_, err = mod.ExportedFunction("as_non_null").Call(ctx, 0)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeNullReference)
+		})
+	}
+}
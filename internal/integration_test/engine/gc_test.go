@@ -0,0 +1,372 @@
+package adhoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/gc"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+const anyref, externref = wasm.ValueTypeAnyref, wasm.ValueTypeExternref
+
+// gcModule is a module with struct and array types, and the functions to allocate and access them.
+var gcModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{},                                  // type 0: struct {mut i32, mut i8, anyref}
+		{},                                  // type 1: array (mut i16)
+		{Results: []wasm.ValueType{anyref}}, // type 2: () -> (anyref)
+		{Params: []wasm.ValueType{anyref}, Results: []wasm.ValueType{i32}},          // type 3: (anyref) -> (i32)
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{anyref}},          // type 4: (i32) -> (anyref)
+		{Params: []wasm.ValueType{anyref, i32}, Results: []wasm.ValueType{i32}},     // type 5: (anyref, i32) -> (i32)
+		{Params: []wasm.ValueType{externref}, Results: []wasm.ValueType{externref}}, // type 6: (externref) -> (externref)
+		{}, // type 7: sub struct {i32}
+		{}, // type 8: sub final 7 struct {i32, i64}
+		{ // type 9: (eqref, eqref) -> (i32)
+			Params: []wasm.ValueType{anyref, anyref}, Results: []wasm.ValueType{i32},
+			ParamRefs: []wasm.HeapRef{{HeapType: wasm.HeapTypeEq}, {HeapType: wasm.HeapTypeEq}},
+		},
+		{ // type 10: ((ref null 0)) -> (i32)
+			Params: []wasm.ValueType{anyref}, Results: []wasm.ValueType{i32},
+			ParamRefs: []wasm.HeapRef{{HeapType: 0}},
+		},
+		{ // type 11: ((ref null 0), i32) -> (i32)
+			Params: []wasm.ValueType{anyref, i32}, Results: []wasm.ValueType{i32},
+			ParamRefs: []wasm.HeapRef{{HeapType: 0}, wasm.DefaultHeapRef(i32)},
+		},
+		{ // type 12: ((ref null 1)) -> (i32)
+			Params: []wasm.ValueType{anyref}, Results: []wasm.ValueType{i32},
+			ParamRefs: []wasm.HeapRef{{HeapType: 1}},
+		},
+		{ // type 13: ((ref null 1), i32) -> (i32)
+			Params: []wasm.ValueType{anyref, i32}, Results: []wasm.ValueType{i32},
+			ParamRefs: []wasm.HeapRef{{HeapType: 1}, wasm.DefaultHeapRef(i32)},
+		},
+	},
+	SubTypes: []wasm.SubType{
+		{Kind: wasm.CompositeTypeKindStruct, Final: true, Fields: []wasm.FieldType{
+			{Type: i32, Mutable: true}, {Type: wasm.StorageTypeI8, Mutable: true}, {Type: anyref},
+		}},
+		{Kind: wasm.CompositeTypeKindArray, Final: true, Fields: []wasm.FieldType{{Type: wasm.StorageTypeI16, Mutable: true}}},
+		{Final: true},
+		{Final: true},
+		{Final: true},
+		{Final: true},
+		{Final: true},
+		{Kind: wasm.CompositeTypeKindStruct, Fields: []wasm.FieldType{{Type: i32}}},
+		{Kind: wasm.CompositeTypeKindStruct, Final: true, HasSuperType: true, SuperType: 7, Fields: []wasm.FieldType{
+			{Type: i32}, {Type: i64},
+		}},
+		{Final: true},
+		{Final: true},
+		{Final: true},
+		{Final: true},
+		{Final: true},
+	},
+	GlobalSection: []wasm.Global{
+		{Type: wasm.GlobalType{ValType: anyref, Mutable: true}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeRefNull, Data: []byte{anyref}}},
+		{ // global 1: (ref 0) = struct.new 0 (5, 0x1ff, ref.i31 9)
+			Type: wasm.GlobalType{ValType: anyref, Ref: &wasm.HeapRef{HeapType: 0, NonNullable: true}},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeGCPrefix, Data: []byte{
+				wasm.OpcodeI32Const, 5,
+				wasm.OpcodeI32Const, 0xff, 0x03,
+				wasm.OpcodeI32Const, 9,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNew, 0,
+			}},
+		},
+		{ // global 2: (ref 1) = array.new_fixed 1 3 (1, 2, 0x10003)
+			Type: wasm.GlobalType{ValType: anyref, Ref: &wasm.HeapRef{HeapType: 1, NonNullable: true}},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeGCPrefix, Data: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32Const, 0x83, 0x80, 0x04,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCArrayNewFixed, 1, 3,
+			}},
+		},
+	},
+	FunctionSection: []wasm.Index{2, 10, 10, 10, 11, 4, 12, 13, 3, 3, 6, 3, 2, 3, 9, 4},
+	CodeSection: []wasm.Code{
+		{ // func[0] new_struct() -> struct.new 0 (42, -1, ref.i31 7)
+			Body: []byte{
+				wasm.OpcodeI32Const, 42,
+				wasm.OpcodeI32Const, 0x7f,
+				wasm.OpcodeI32Const, 7,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNew, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] struct_get_s(ref) -> struct.get_s 0 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructGetS, 0, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] struct_get_u(ref) -> struct.get_u 0 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructGetU, 0, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] struct_i31(ref) -> i31.get_s (ref.cast (ref i31) (struct.get 0 2))
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructGet, 0, 2,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCRefCast, 0x6c, // i31
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCI31GetS,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] set_and_get(ref, v) -> struct.set 0 0; struct.get 0 0
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructSet, 0, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructGet, 0, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[5] new_array(n) -> array.new 1 (0x12345, n)
+			Body: []byte{
+				wasm.OpcodeI32Const, 0xc5, 0xc6, 0x04,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCArrayNew, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[6] array_len(ref) -> array.len
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCArrayLen,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[7] array_get(ref, i) -> array.get_u 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCArrayGetU, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[8] is_struct(ref) -> ref.test (ref struct)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCRefTest, 0x6b, // struct
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[9] cast_array_len(ref) -> array.len (ref.cast (ref 1))
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCRefCast, 1,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCArrayLen,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[10] round_trip(ref) -> extern.convert_any (any.convert_extern ref)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCAnyConvertExtern,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCExternConvertAny,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[11] i31_or_minus_one(ref) -> i31.get_u if br_on_cast i31 branches, otherwise -1
+			Body: []byte{
+				wasm.OpcodeBlock, wasm.RefTypePrefixNullable, 0x6c, // (ref null i31)
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCBrOnCast, 0b11, 0, 0x6e, 0x6c, // any -> i31
+				wasm.OpcodeDrop,
+				wasm.OpcodeI32Const, 0x7f,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCI31GetU,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[12] new_sub() -> struct.new 8 (1, 2)
+			Body: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI64Const, 2,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNew, 8,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[13] is_super(ref) -> ref.test (ref 7)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCRefTest, 7,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[14] eq(a, b) -> ref.eq
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeRefEq,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[15] churn(n) -> global 0 after allocating n unreachable structs
+			Body: []byte{
+				wasm.OpcodeCall, 0,
+				wasm.OpcodeGlobalSet, 0,
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Eqz,
+				wasm.OpcodeBrIf, 1,
+				wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNewDefault, 0,
+				wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Sub,
+				wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeBr, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "new_struct", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "struct_get_s", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "struct_get_u", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "struct_i31", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "set_and_get", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "new_array", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "array_len", Type: wasm.ExternTypeFunc, Index: 6},
+		{Name: "array_get", Type: wasm.ExternTypeFunc, Index: 7},
+		{Name: "is_struct", Type: wasm.ExternTypeFunc, Index: 8},
+		{Name: "cast_array_len", Type: wasm.ExternTypeFunc, Index: 9},
+		{Name: "round_trip", Type: wasm.ExternTypeFunc, Index: 10},
+		{Name: "i31_or_minus_one", Type: wasm.ExternTypeFunc, Index: 11},
+		{Name: "new_sub", Type: wasm.ExternTypeFunc, Index: 12},
+		{Name: "is_super", Type: wasm.ExternTypeFunc, Index: 13},
+		{Name: "eq", Type: wasm.ExternTypeFunc, Index: 14},
+		{Name: "churn", Type: wasm.ExternTypeFunc, Index: 15},
+		{Name: "const_struct", Type: wasm.ExternTypeGlobal, Index: 1},
+		{Name: "const_array", Type: wasm.ExternTypeGlobal, Index: 2},
+	},
+}
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(gcModule))
+		require.Error(t, err)
+	})
+
+	if platform.CompilerSupported() {
+		t.Run("compiler", func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigCompiler().
+				WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesGC))
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(gcModule))
+			require.Error(t, err)
+		})
+	}
+
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().
+		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesGC))
+	defer func() {
+		require.NoError(t, r.Close(ctx))
+	}()
+
+	mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(gcModule))
+	require.NoError(t, err)
+	call := func(name string, params ...uint64) uint64 {
+		res, err := mod.ExportedFunction(name).Call(ctx, params...)
+		require.NoError(t, err)
+		return res[0]
+	}
+
+	s := call("new_struct")
+	require.Equal(t, gc.KindStruct, gc.KindOf(mod, s))
+	require.Equal(t, uint64(0xffffffff), call("struct_get_s", s))
+	require.Equal(t, uint64(0xff), call("struct_get_u", s))
+	require.Equal(t, uint64(7), call("struct_i31", s))
+	require.Equal(t, uint64(1234), call("set_and_get", s, 1234))
+
+	st, ok := gc.StructOf(mod, s)
+	require.True(t, ok)
+	require.Equal(t, uint32(0), st.TypeIndex())
+	require.Equal(t, 3, st.NumField())
+	require.Equal(t, uint64(1234), st.Field(0))
+	require.Equal(t, uint64(0xff), st.Field(1))
+	v, ok := gc.I31(st.Field(2))
+	require.True(t, ok)
+	require.Equal(t, int32(7), v)
+
+	a := call("new_array", 3)
+	require.Equal(t, gc.KindArray, gc.KindOf(mod, a))
+	require.Equal(t, uint64(3), call("array_len", a))
+	require.Equal(t, uint64(0x2345), call("array_get", a, 2))
+	arr, ok := gc.ArrayOf(mod, a)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), arr.Len())
+	require.Equal(t, uint64(0x2345), arr.Get(0))
+
+	require.Equal(t, uint64(1), call("is_struct", s))
+	require.Equal(t, uint64(0), call("is_struct", a))
+	require.Equal(t, uint64(0), call("is_struct", 0))
+	require.Equal(t, uint64(3), call("cast_array_len", a))
+	require.Equal(t, uint64(1), call("is_super", call("new_sub")))
+	require.Equal(t, uint64(0), call("is_super", s))
+	require.Equal(t, uint64(1), call("eq", s, s))
+	require.Equal(t, uint64(0), call("eq", s, a))
+	require.Equal(t, uint64(1), call("eq", gc.NewI31(-5), gc.NewI31(-5)))
+
+	require.Equal(t, uint64(100), call("i31_or_minus_one", gc.NewI31(100)))
+	require.Equal(t, uint64(0xffffffff), call("i31_or_minus_one", s))
+
+	// The externref from the host is converted back into the same value.
+	require.Equal(t, uint64(0xdeadbeef), call("round_trip", 0xdeadbeef))
+	require.Equal(t, uint64(0), call("round_trip", 0))
+
+	_, err = mod.ExportedFunction("cast_array_len").Call(ctx, s)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeCastFailure)
+	_, err = mod.ExportedFunction("array_get").Call(ctx, a, 3)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeArrayOutOfBounds)
+	_, err = mod.ExportedFunction("struct_get_u").Call(ctx, 0)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeNullReference)
+
+	// The objects allocated by the constant expressions are initialized as per their types.
+	cs := mod.ExportedGlobal("const_struct").Get()
+	require.Equal(t, uint64(0xffffffff), call("struct_get_s", cs))
+	require.Equal(t, uint64(9), call("struct_i31", cs))
+	ca := mod.ExportedGlobal("const_array").Get()
+	require.Equal(t, uint64(3), call("array_len", ca))
+	require.Equal(t, uint64(3), call("array_get", ca, 2))
+
+	// The unreachable objects are collected, whereas the ones in the globals survive.
+	const n = 100_000
+	g := call("churn", n)
+	require.Equal(t, uint64(0xff), call("struct_get_u", g))
+	require.Equal(t, uint64(5), call("set_and_get", cs, 5))
+	require.Equal(t, uint64(2), call("array_get", ca, 1))
+	require.True(t, mod.(*wasm.ModuleInstance).GCHeap().Live() < n)
+}
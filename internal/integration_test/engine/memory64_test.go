@@ -0,0 +1,196 @@
+package adhoc
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// memory64Module is a module with a memory64 memory "mem", which is initialized by an active data segment
+// with an i64 offset, and the functions accessing it with i64 addresses.
+var memory64Module = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i64, i64}},                            // type 0: (i64, i64) -> ()
+		{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}}, // type 1: (i64) -> (i64)
+		{Results: []wasm.ValueType{i64}},                                // type 2: () -> (i64)
+		{Params: []wasm.ValueType{i64, i32, i64}},                       // type 3: (i64, i32, i64) -> ()
+		{Params: []wasm.ValueType{i64, i64, i64}},                       // type 4: (i64, i64, i64) -> ()
+	},
+	FunctionSection: []wasm.Index{0, 1, 2, 1, 1, 3, 4},
+	MemorySection:   &wasm.Memory{Min: 1, Max: 2, IsMaxEncoded: true, IsMemory64: true},
+	CodeSection: []wasm.Code{
+		{ // func[0] store(addr, v): mem[addr] = v
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI64Store, 3, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] load(addr) -> mem[addr]
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 3, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] size() -> memory.size
+			Body: []byte{
+				wasm.OpcodeMemorySize, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] grow(pages) -> memory.grow
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeMemoryGrow, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] load_offset_4gib(addr) -> mem[addr+4GiB]
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI64Load, 3, 0x80, 0x80, 0x80, 0x80, 0x10,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[5] fill(addr, v, n): memory.fill
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[6] copy(dst, src, n): memory.copy
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	DataSection: []wasm.DataSegment{
+		{
+			OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(16)},
+			Init:             []byte("hello"),
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "mem", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "store", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "load", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "size", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "grow", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "load_offset_4gib", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "fill", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "copy", Type: wasm.ExternTypeFunc, Index: 6},
+	},
+}
+
+func TestMemory64(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("disabled", func(t *testing.T) {
+				r := wazero.NewRuntimeWithConfig(ctx, tc.cfg.WithCoreFeatures(api.CoreFeaturesV2))
+				defer func() {
+					require.NoError(t, r.Close(ctx))
+				}()
+
+				_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(memory64Module))
+				require.Error(t, err)
+			})
+
+			config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64)
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(memory64Module))
+			require.NoError(t, err)
+
+			mem, ok := mod.ExportedMemory("mem").(api.Memory64)
+			require.True(t, ok)
+
+			buf, ok := mem.Read64(16, 5)
+			require.True(t, ok)
+			require.Equal(t, "hello", string(buf))
+
+			_, err = mod.ExportedFunction("store").Call(ctx, 8, 0xdeadbeefcafe)
+			require.NoError(t, err)
+			v, ok := mem.ReadUint64Le64(8)
+			require.True(t, ok)
+			require.Equal(t, uint64(0xdeadbeefcafe), v)
+
+			require.True(t, mem.WriteUint64Le64(32, 1234))
+			res, err := mod.ExportedFunction("load").Call(ctx, 32)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1234}, res)
+
+			// Addresses over 32-bit and those overflowing with the access size must trap.
+			for _, addr := range []uint64{1 << 40, math.MaxUint64 - 2, uint64(wasm.MemoryPageSize) - 7} {
+				_, err = mod.ExportedFunction("load").Call(ctx, addr)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+			}
+			_, err = mod.ExportedFunction("load_offset_4gib").Call(ctx, 0)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+			_, err = mod.ExportedFunction("fill").Call(ctx, 100, 0xaa, 8)
+			require.NoError(t, err)
+			v, ok = mem.ReadUint64Le64(100)
+			require.True(t, ok)
+			require.Equal(t, uint64(0xaaaaaaaaaaaaaaaa), v)
+			_, err = mod.ExportedFunction("fill").Call(ctx, math.MaxUint64, 0xaa, 2)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+			_, err = mod.ExportedFunction("copy").Call(ctx, 200, 16, 5)
+			require.NoError(t, err)
+			buf, ok = mem.Read64(200, 5)
+			require.True(t, ok)
+			require.Equal(t, "hello", string(buf))
+			_, err = mod.ExportedFunction("copy").Call(ctx, 200, 1<<33, 5)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+			res, err = mod.ExportedFunction("size").Call(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1}, res)
+			res, err = mod.ExportedFunction("grow").Call(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1}, res)
+			res, err = mod.ExportedFunction("grow").Call(ctx, 1<<32)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{math.MaxUint64}, res)
+			res, err = mod.ExportedFunction("size").Call(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{2}, res)
+			require.Equal(t, uint64(2*wasm.MemoryPageSize), mem.Size64())
+
+			_, err = mod.ExportedFunction("store").Call(ctx, uint64(wasm.MemoryPageSize), 5678)
+			require.NoError(t, err)
+			v, ok = mem.ReadUint64Le64(uint64(wasm.MemoryPageSize))
+			require.True(t, ok)
+			require.Equal(t, uint64(5678), v)
+		})
+	}
+}
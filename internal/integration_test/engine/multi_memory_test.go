@@ -0,0 +1,238 @@
+package adhoc
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// multiMemoryModule is a module with two memories "mem0" and "mem1", where "mem1" is initialized by an active
+// data segment and all the functions except load0 access "mem1".
+var multiMemoryModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32, i32}},                            // type 0: (i32, i32) -> ()
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 1: (i32) -> (i32)
+		{Results: []wasm.ValueType{i32}},                                // type 2: () -> (i32)
+		{Params: []wasm.ValueType{i32, i32, i32}},                       // type 3: (i32, i32, i32) -> ()
+	},
+	FunctionSection:    []wasm.Index{0, 1, 1, 2, 1, 3, 3},
+	MemorySection:      &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+	MultiMemorySection: []*wasm.Memory{{Min: 1, Max: 2, IsMaxEncoded: true}},
+	CodeSection: []wasm.Code{
+		{ // func[0] store1(addr, v): mem1[addr] = v
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI32Store, 2 | wasm.MemArgMemoryIndexFlag, 1, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] load0(addr) -> mem0[addr]
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 2, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] load1(addr) -> mem1[addr]
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 2 | wasm.MemArgMemoryIndexFlag, 1, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] size1() -> memory.size 1
+			Body: []byte{
+				wasm.OpcodeMemorySize, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] grow1(pages) -> memory.grow 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeMemoryGrow, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[5] copy1to0(dst, src, n): memory.copy 0 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[6] fill1(addr, v, n): memory.fill 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 1,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	DataSection: []wasm.DataSegment{
+		{
+			OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(16)},
+			Init:             []byte("hello"),
+			MemoryIndex:      1,
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "mem0", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "mem1", Type: wasm.ExternTypeMemory, Index: 1},
+		{Name: "store1", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "load0", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "load1", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "size1", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "grow1", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "copy1to0", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "fill1", Type: wasm.ExternTypeFunc, Index: 6},
+	},
+}
+
+// multiMemoryImporter imports "mem1" and "mem0" of multiMemoryModule in the reverse order, so that its
+// memory at index zero is the one at index one in the imported module.
+var multiMemoryImporter = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 0: (i32) -> (i32)
+	},
+	ImportSection: []wasm.Import{
+		{Module: "multi", Name: "mem1", Type: wasm.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1}},
+		{Module: "multi", Name: "mem0", Type: wasm.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1}},
+	},
+	FunctionSection: []wasm.Index{0, 0},
+	CodeSection: []wasm.Code{
+		{ // func[0] load_imported0(addr) -> mem1[addr]
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 2, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] load_imported1(addr) -> mem0[addr]
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 2 | wasm.MemArgMemoryIndexFlag, 1, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "load_imported0", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "load_imported1", Type: wasm.ExternTypeFunc, Index: 1},
+	},
+}
+
+func TestMultiMemory(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("disabled", func(t *testing.T) {
+				r := wazero.NewRuntimeWithConfig(ctx, tc.cfg.WithCoreFeatures(api.CoreFeaturesV2))
+				defer func() {
+					require.NoError(t, r.Close(ctx))
+				}()
+
+				_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(multiMemoryModule))
+				require.Error(t, err)
+			})
+
+			config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory)
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			mod, err := r.InstantiateWithConfig(ctx, binaryencoding.EncodeModule(multiMemoryModule),
+				wazero.NewModuleConfig().WithName("multi"))
+			require.NoError(t, err)
+
+			mem0, mem1 := mod.ExportedMemory("mem0"), mod.ExportedMemory("mem1")
+			require.NotNil(t, mem0)
+			require.NotNil(t, mem1)
+			require.Equal(t, mem0, mod.Memory())
+			require.Equal(t, 2, len(mod.ExportedMemoryDefinitions()))
+
+			buf, ok := mem1.Read(16, 5)
+			require.True(t, ok)
+			require.Equal(t, "hello", string(buf))
+
+			_, err = mod.ExportedFunction("store1").Call(ctx, 0, 0xdeadbeef)
+			require.NoError(t, err)
+			v, ok := mem1.ReadUint32Le(0)
+			require.True(t, ok)
+			require.Equal(t, uint32(0xdeadbeef), v)
+			v, ok = mem0.ReadUint32Le(0)
+			require.True(t, ok)
+			require.Equal(t, uint32(0), v)
+
+			res, err := mod.ExportedFunction("load1").Call(ctx, 0)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{0xdeadbeef}, res)
+			res, err = mod.ExportedFunction("load0").Call(ctx, 0)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{0}, res)
+
+			_, err = mod.ExportedFunction("copy1to0").Call(ctx, 100, 16, 5)
+			require.NoError(t, err)
+			buf, ok = mem0.Read(100, 5)
+			require.True(t, ok)
+			require.Equal(t, "hello", string(buf))
+
+			_, err = mod.ExportedFunction("fill1").Call(ctx, 200, 0xaa, 4)
+			require.NoError(t, err)
+			v, ok = mem1.ReadUint32Le(200)
+			require.True(t, ok)
+			require.Equal(t, uint32(0xaaaaaaaa), v)
+
+			_, err = mod.ExportedFunction("load1").Call(ctx, uint64(wasm.MemoryPageSize))
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+			res, err = mod.ExportedFunction("size1").Call(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1}, res)
+			res, err = mod.ExportedFunction("grow1").Call(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1}, res)
+			res, err = mod.ExportedFunction("size1").Call(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{2}, res)
+			require.Equal(t, uint32(2*wasm.MemoryPageSize), mem1.Size())
+			require.Equal(t, uint32(wasm.MemoryPageSize), mem0.Size())
+
+			_, err = mod.ExportedFunction("store1").Call(ctx, uint64(wasm.MemoryPageSize), 1234)
+			require.NoError(t, err)
+			res, err = mod.ExportedFunction("load1").Call(ctx, uint64(wasm.MemoryPageSize))
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1234}, res)
+
+			importer, err := r.Instantiate(ctx, binaryencoding.EncodeModule(multiMemoryImporter))
+			require.NoError(t, err)
+			res, err = importer.ExportedFunction("load_imported0").Call(ctx, uint64(wasm.MemoryPageSize))
+			require.NoError(t, err)
+			require.Equal(t, []uint64{1234}, res)
+			res, err = importer.ExportedFunction("load_imported1").Call(ctx, 100)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{uint64(binary.LittleEndian.Uint32([]byte("hell")))}, res)
+		})
+	}
+}
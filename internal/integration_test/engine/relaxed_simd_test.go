@@ -0,0 +1,306 @@
+package adhoc
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// relaxedSIMDInstructions are the instructions exported by relaxedSIMDModule.
+var relaxedSIMDInstructions = []struct {
+	vecOp    wasm.OpcodeVecRelaxed
+	operands int
+}{
+	{vecOp: wasm.OpcodeVecI8x16RelaxedSwizzle, operands: 2},
+	{vecOp: wasm.OpcodeVecI32x4RelaxedTruncF32x4S, operands: 1},
+	{vecOp: wasm.OpcodeVecI32x4RelaxedTruncF32x4U, operands: 1},
+	{vecOp: wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, operands: 1},
+	{vecOp: wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero, operands: 1},
+	{vecOp: wasm.OpcodeVecF32x4RelaxedMadd, operands: 3},
+	{vecOp: wasm.OpcodeVecF32x4RelaxedNmadd, operands: 3},
+	{vecOp: wasm.OpcodeVecF64x2RelaxedMadd, operands: 3},
+	{vecOp: wasm.OpcodeVecF64x2RelaxedNmadd, operands: 3},
+	{vecOp: wasm.OpcodeVecI8x16RelaxedLaneselect, operands: 3},
+	{vecOp: wasm.OpcodeVecI16x8RelaxedLaneselect, operands: 3},
+	{vecOp: wasm.OpcodeVecI32x4RelaxedLaneselect, operands: 3},
+	{vecOp: wasm.OpcodeVecI64x2RelaxedLaneselect, operands: 3},
+	{vecOp: wasm.OpcodeVecF32x4RelaxedMin, operands: 2},
+	{vecOp: wasm.OpcodeVecF32x4RelaxedMax, operands: 2},
+	{vecOp: wasm.OpcodeVecF64x2RelaxedMin, operands: 2},
+	{vecOp: wasm.OpcodeVecF64x2RelaxedMax, operands: 2},
+	{vecOp: wasm.OpcodeVecI16x8RelaxedQ15mulrS, operands: 2},
+	{vecOp: wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S, operands: 2},
+	{vecOp: wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS, operands: 3},
+}
+
+// relaxedSIMDModule exports a function of (v128, v128, v128) -> (v128) per relaxed SIMD instruction, which is named
+// after it, and passes the first N parameters as the operands.
+var relaxedSIMDModule = func() *wasm.Module {
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{{
+			Params:            []wasm.ValueType{v128, v128, v128},
+			Results:           []wasm.ValueType{v128},
+			ParamNumInUint64:  6,
+			ResultNumInUint64: 2,
+		}},
+	}
+	for i, inst := range relaxedSIMDInstructions {
+		var body []byte
+		for j := 0; j < inst.operands; j++ {
+			body = append(body, wasm.OpcodeLocalGet, byte(j))
+		}
+		body = append(body, wasm.OpcodeVecPrefix, inst.vecOp, wasm.OpcodeVecRelaxedSecondByte, wasm.OpcodeEnd)
+		m.FunctionSection = append(m.FunctionSection, 0)
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, wasm.Export{
+			Name: wasm.VectorRelaxedInstructionName(inst.vecOp), Type: wasm.ExternTypeFunc, Index: wasm.Index(i),
+		})
+	}
+	return m
+}()
+
+func TestRelaxedSIMD(t *testing.T) {
+	ctx := context.Background()
+	bin := binaryencoding.EncodeModule(relaxedSIMDModule)
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(api.CoreFeaturesV2))
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, bin)
+		require.Error(t, err)
+	})
+
+	f32x4 := func(a, b, c, d float32) []uint64 {
+		return []uint64{
+			uint64(math.Float32bits(a)) | uint64(math.Float32bits(b))<<32,
+			uint64(math.Float32bits(c)) | uint64(math.Float32bits(d))<<32,
+		}
+	}
+	f64x2 := func(a, b float64) []uint64 {
+		return []uint64{math.Float64bits(a), math.Float64bits(b)}
+	}
+	params := func(vs ...[]uint64) (ret []uint64) {
+		for _, v := range vs {
+			ret = append(ret, v...)
+		}
+		// Pad the unused operands.
+		for len(ret) < 6 {
+			ret = append(ret, 0)
+		}
+		return
+	}
+
+	tests := []struct {
+		name   string
+		params []uint64
+		exp    []uint64
+		// deterministicOnly is true if the result of the input may differ between platforms, so it is only expected
+		// with the deterministic lowering.
+		deterministicOnly bool
+	}{
+		{
+			name:   "i8x16.relaxed_swizzle",
+			params: params([]uint64{0x0706050403020100, 0x0f0e0d0c0b0a0908}, []uint64{0x0001020304050607, 0x08090a0b0c0d0e0f}),
+			exp:    []uint64{0x0001020304050607, 0x08090a0b0c0d0e0f},
+		},
+		{
+			name:              "i8x16.relaxed_swizzle",
+			params:            params([]uint64{0x0706050403020100, 0x0f0e0d0c0b0a0908}, []uint64{0x8010_0f00_0102_0304, 0xff20_0000_0000_0000}),
+			exp:               []uint64{0x0000_0f00_0102_0304, 0},
+			deterministicOnly: true,
+		},
+		{
+			name:   "i32x4.relaxed_trunc_f32x4_s",
+			params: params(f32x4(-1.5, 2.5, 100, -7)),
+			exp:    []uint64{0x00000002_ffffffff, 0xfffffff9_00000064},
+		},
+		{
+			name:              "i32x4.relaxed_trunc_f32x4_s",
+			params:            params(f32x4(-1.5, float32(math.NaN()), 1e10, 42.9)),
+			exp:               []uint64{0x00000000_ffffffff, 0x0000002a_7fffffff},
+			deterministicOnly: true,
+		},
+		{
+			name:   "i32x4.relaxed_trunc_f32x4_u",
+			params: params(f32x4(1.5, 2.5, 100, 7)),
+			exp:    []uint64{0x00000002_00000001, 0x00000007_00000064},
+		},
+		{
+			name:              "i32x4.relaxed_trunc_f32x4_u",
+			params:            params(f32x4(-1.5, float32(math.NaN()), 1e10, 42.9)),
+			exp:               []uint64{0, 0x0000002a_ffffffff},
+			deterministicOnly: true,
+		},
+		{
+			name:   "i32x4.relaxed_trunc_f64x2_s_zero",
+			params: params(f64x2(-3.9, 7.2)),
+			exp:    []uint64{0x00000007_fffffffd, 0},
+		},
+		{
+			name:   "i32x4.relaxed_trunc_f64x2_u_zero",
+			params: params(f64x2(3.9, 7.2)),
+			exp:    []uint64{0x00000007_00000003, 0},
+		},
+		{
+			name:   "f32x4.relaxed_madd",
+			params: params(f32x4(2, -1, 0.5, 3), f32x4(3, 4, 8, 1), f32x4(1, 0, -4, 0.5)),
+			exp:    f32x4(7, -4, 0, 3.5),
+		},
+		{
+			name:   "f32x4.relaxed_nmadd",
+			params: params(f32x4(2, -1, 0.5, 3), f32x4(3, 4, 8, 1), f32x4(1, 0, -4, 0.5)),
+			exp:    f32x4(-5, 4, -8, -2.5),
+		},
+		{
+			// The product of 1+2^-23 and 1-2^-23 is 1-2^-46, which is rounded to one unless fused with the addition.
+			name:              "f32x4.relaxed_madd",
+			params:            params(f32x4(1+0x1p-23, 2, 0, 0), f32x4(1-0x1p-23, 3, 0, 0), f32x4(-1, 1, 0, 0)),
+			exp:               f32x4(0, 7, 0, 0),
+			deterministicOnly: true,
+		},
+		{
+			name:   "f64x2.relaxed_madd",
+			params: params(f64x2(2, 0.5), f64x2(3, 8), f64x2(1, -4)),
+			exp:    f64x2(7, 0),
+		},
+		{
+			name:   "f64x2.relaxed_nmadd",
+			params: params(f64x2(2, 0.5), f64x2(3, 8), f64x2(1, -4)),
+			exp:    f64x2(-5, -8),
+		},
+		{
+			// The product of 1+2^-52 and 1-2^-52 is 1-2^-104, which is rounded to one unless fused with the addition.
+			name:              "f64x2.relaxed_madd",
+			params:            params(f64x2(1+0x1p-52, 2), f64x2(1-0x1p-52, 3), f64x2(-1, 1)),
+			exp:               f64x2(0, 7),
+			deterministicOnly: true,
+		},
+		{
+			name:              "f64x2.relaxed_nmadd",
+			params:            params(f64x2(1+0x1p-52, 2), f64x2(1-0x1p-52, 3), f64x2(-1, 1)),
+			exp:               f64x2(-2, -5),
+			deterministicOnly: true,
+		},
+		{
+			name: "i8x16.relaxed_laneselect",
+			params: params([]uint64{0xaaaaaaaaaaaaaaaa, 0xaaaaaaaaaaaaaaaa}, []uint64{0x5555555555555555, 0x5555555555555555},
+				[]uint64{0xff00ff00ff00ff00, 0x00ff00ff00ff00ff}),
+			exp: []uint64{0xaa55aa55aa55aa55, 0x55aa55aa55aa55aa},
+		},
+		{
+			name: "i16x8.relaxed_laneselect",
+			params: params([]uint64{0xaaaaaaaaaaaaaaaa, 0xaaaaaaaaaaaaaaaa}, []uint64{0x5555555555555555, 0x5555555555555555},
+				[]uint64{0xffff0000ffff0000, 0}),
+			exp: []uint64{0xaaaa5555aaaa5555, 0x5555555555555555},
+		},
+		{
+			name: "i32x4.relaxed_laneselect",
+			params: params([]uint64{0xaaaaaaaaaaaaaaaa, 0xaaaaaaaaaaaaaaaa}, []uint64{0x5555555555555555, 0x5555555555555555},
+				[]uint64{0xffffffff00000000, 0xffffffffffffffff}),
+			exp: []uint64{0xaaaaaaaa55555555, 0xaaaaaaaaaaaaaaaa},
+		},
+		{
+			name: "i64x2.relaxed_laneselect",
+			params: params([]uint64{0xaaaaaaaaaaaaaaaa, 0xaaaaaaaaaaaaaaaa}, []uint64{0x5555555555555555, 0x5555555555555555},
+				[]uint64{0xffffffffffffffff, 0}),
+			exp: []uint64{0xaaaaaaaaaaaaaaaa, 0x5555555555555555},
+		},
+		{
+			name:   "f32x4.relaxed_min",
+			params: params(f32x4(1, -2, 3, -4), f32x4(2, -3, 1, 5)),
+			exp:    f32x4(1, -3, 1, -4),
+		},
+		{
+			name:   "f32x4.relaxed_max",
+			params: params(f32x4(1, -2, 3, -4), f32x4(2, -3, 1, 5)),
+			exp:    f32x4(2, -2, 3, 5),
+		},
+		{
+			name:   "f64x2.relaxed_min",
+			params: params(f64x2(1, -2), f64x2(-1, 5)),
+			exp:    f64x2(-1, -2),
+		},
+		{
+			name:   "f64x2.relaxed_max",
+			params: params(f64x2(1, -2), f64x2(-1, 5)),
+			exp:    f64x2(1, 5),
+		},
+		{
+			name:   "i16x8.relaxed_q15mulr_s",
+			params: params([]uint64{0x0000_0000_8000_4000, 0}, []uint64{0x0000_0000_7fff_4000, 0}),
+			exp:    []uint64{0x0000_0000_8001_2000, 0},
+		},
+		{
+			name:              "i16x8.relaxed_q15mulr_s",
+			params:            params([]uint64{0x8000_8000_4000_8000, 0}, []uint64{0x8000_7fff_4000_8000, 0}),
+			exp:               []uint64{0x7fff_8001_2000_7fff, 0},
+			deterministicOnly: true,
+		},
+		{
+			name:   "i16x8.relaxed_dot_i8x16_i7x16_s",
+			params: params([]uint64{0x0302_0180, 0}, []uint64{0x0101_0102, 0}),
+			exp:    []uint64{0x0005_ff01, 0},
+		},
+		{
+			// The second operand is out of the range of i7, so the sums are saturated.
+			name:              "i16x8.relaxed_dot_i8x16_i7x16_s",
+			params:            params([]uint64{0x8080_8080_0302_0180, 0}, []uint64{0x8080_8080_0101_0102, 0}),
+			exp:               []uint64{0x7fff_7fff_0005_ff01, 0},
+			deterministicOnly: true,
+		},
+		{
+			name:   "i32x4.relaxed_dot_i8x16_i7x16_add_s",
+			params: params([]uint64{0x0302_0180, 0}, []uint64{0x0101_0102, 0}, []uint64{0x00000001_00000010, 0}),
+			exp:    []uint64{0x00000001_ffffff16, 0},
+		},
+		{
+			name:              "i32x4.relaxed_dot_i8x16_i7x16_add_s",
+			params:            params([]uint64{0x8080_8080_0302_0180, 0}, []uint64{0x8080_8080_0101_0102, 0}, []uint64{0x00000001_00000010, 0}),
+			exp:               []uint64{0x0000ffff_ffffff16, 0},
+			deterministicOnly: true,
+		},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		cfg           wazero.RuntimeConfig
+		deterministic bool
+	}{
+		// The interpreter always uses the deterministic lowering.
+		{"interpreter", wazero.NewRuntimeConfigInterpreter(), true},
+		{"interpreter with deterministic", wazero.NewRuntimeConfigInterpreter().WithDeterministicRelaxedSIMD(true), true},
+		{"default", wazero.NewRuntimeConfig(), false},
+		{"default with deterministic", wazero.NewRuntimeConfig().WithDeterministicRelaxedSIMD(true), true},
+	} {
+		config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			mod, err := r.Instantiate(ctx, bin)
+			require.NoError(t, err)
+
+			for _, c := range tests {
+				if c.deterministicOnly && !tc.deterministic {
+					continue
+				}
+				res, err := mod.ExportedFunction(c.name).Call(ctx, c.params...)
+				require.NoError(t, err)
+				require.Equal(t, c.exp, res, c.name)
+			}
+		})
+	}
+}
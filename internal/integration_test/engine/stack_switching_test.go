@@ -0,0 +1,288 @@
+package adhoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+const contref = wasm.ValueTypeContref
+
+// stackSwitchingModule is a module whose continuations are used as a generator, and to ask the values of their
+// resumers with a tag having results.
+var stackSwitchingModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{},                              // type 0: () -> ()
+		{},                              // type 1: cont 0
+		{Params: []wasm.ValueType{i32}}, // type 2: (i32) -> ()
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, // type 3: (i32) -> (i32)
+		{}, // type 4: cont 2
+		{}, // type 5: cont 3
+		{Results: []wasm.ValueType{i32, contref}},                                // type 6: () -> (i32, contref)
+		{Params: []wasm.ValueType{i32, funcref}, Results: []wasm.ValueType{i32}}, // type 7: (i32, funcref) -> (i32)
+	},
+	SubTypes: []wasm.SubType{
+		{Final: true, RecGroupStart: 0, RecGroupSize: 1},
+		{Kind: wasm.CompositeTypeKindCont, FuncType: 0, Final: true, RecGroupStart: 1, RecGroupSize: 1},
+		{Final: true, RecGroupStart: 2, RecGroupSize: 1},
+		{Final: true, RecGroupStart: 3, RecGroupSize: 1},
+		{Kind: wasm.CompositeTypeKindCont, FuncType: 2, Final: true, RecGroupStart: 4, RecGroupSize: 1},
+		{Kind: wasm.CompositeTypeKindCont, FuncType: 3, Final: true, RecGroupStart: 5, RecGroupSize: 1},
+		{Final: true, RecGroupStart: 6, RecGroupSize: 1},
+		{Final: true, RecGroupStart: 7, RecGroupSize: 1},
+	},
+	// tag 0 yield: (i32) -> (), tag 1 ask: (i32) -> (i32), tag 2 e: (i32) -> ()
+	TagSection:      []wasm.Index{2, 3, 2},
+	FunctionSection: []wasm.Index{2, 3, 3, 7, 3, 3, 3, 2, 2, 2, 2, 2, 3},
+	CodeSection: []wasm.Code{
+		{ // func[0] count_from(n): loop { suspend yield(n); n++ }
+			Body: []byte{
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeStackSwitchingSuspend, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeBr, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] sum(k) -> the sum of the first k values yielded by (cont.bind count_from 1)
+			LocalTypes: []wasm.ValueType{contref, i32},
+			Body: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeRefFunc, 0,
+				wasm.OpcodeStackSwitchingContNew, 4,
+				wasm.OpcodeStackSwitchingContBind, 4, 1,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Eqz,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeBlock, 6,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeStackSwitchingResume, 1, 1, wasm.ResumeHandlerKindOnLabel, 0, 0,
+				wasm.OpcodeUnreachable,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeLocalSet, 2,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Sub,
+				wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeBr, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeUnreachable,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] ask_plus_one(x) -> (suspend ask(x)) + 1
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeStackSwitchingSuspend, 1,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] run(x, f) -> resumes (cont.new f) with x, answering ask(v) with v * 10.
+			LocalTypes: []wasm.ValueType{contref},
+			Body: []byte{
+				wasm.OpcodeBlock, 6,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeStackSwitchingContNew, 5,
+				wasm.OpcodeStackSwitchingResume, 5, 1, wasm.ResumeHandlerKindOnLabel, 1, 0,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalSet, 2,
+				wasm.OpcodeI32Const, 10,
+				wasm.OpcodeI32Mul,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeStackSwitchingResume, 5, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] answer(x) -> run(x, ask_plus_one)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeRefFunc, 2,
+				wasm.OpcodeCall, 3,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[5] middle(x) -> (resume (cont.new ask_plus_one) x) + 100, which doesn't handle ask.
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeRefFunc, 2,
+				wasm.OpcodeStackSwitchingContNew, 5,
+				wasm.OpcodeStackSwitchingResume, 5, 0,
+				wasm.OpcodeI32Const, 0xe4, 0x00, // 100
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[6] forward(x) -> run(x, middle)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeRefFunc, 5,
+				wasm.OpcodeCall, 3,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[7] unhandled(x): suspend yield(x)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeStackSwitchingSuspend, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[8] noop(x)
+			Body: []byte{wasm.OpcodeEnd},
+		},
+		{ // func[9] twice(x): resumes (cont.new noop) twice.
+			LocalTypes: []wasm.ValueType{contref},
+			Body: []byte{
+				wasm.OpcodeRefFunc, 8,
+				wasm.OpcodeStackSwitchingContNew, 4,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeStackSwitchingResume, 4, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeStackSwitchingResume, 4, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[10] resume_null(x): resumes the null contref.
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeRefNull, contref,
+				wasm.OpcodeStackSwitchingResume, 4, 0,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[11] throw_e(x): throw e(x)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeExceptionHandlingThrow, 2,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[12] catch_from_cont(x): try { resume (cont.new throw_e) x } catch e(v) { v + 1 }
+			Body: []byte{
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeExceptionHandlingTryTable, 0x40, 1, wasm.CatchKindCatch, 2, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeRefFunc, 11,
+				wasm.OpcodeStackSwitchingContNew, 4,
+				wasm.OpcodeStackSwitchingResume, 4, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 0x7f,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "count_from", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "sum", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "ask_plus_one", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "answer", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "middle", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "forward", Type: wasm.ExternTypeFunc, Index: 6},
+		{Name: "unhandled", Type: wasm.ExternTypeFunc, Index: 7},
+		{Name: "noop", Type: wasm.ExternTypeFunc, Index: 8},
+		{Name: "twice", Type: wasm.ExternTypeFunc, Index: 9},
+		{Name: "resume_null", Type: wasm.ExternTypeFunc, Index: 10},
+		{Name: "throw_e", Type: wasm.ExternTypeFunc, Index: 11},
+		{Name: "catch_from_cont", Type: wasm.ExternTypeFunc, Index: 12},
+	},
+}
+
+func TestStackSwitching(t *testing.T) {
+	ctx := context.Background()
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling |
+		experimental.CoreFeaturesFunctionReferences
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(features))
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(stackSwitchingModule))
+		require.Error(t, err)
+	})
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		config := tc.cfg.WithCoreFeatures(features | experimental.CoreFeaturesStackSwitching)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(stackSwitchingModule))
+			require.NoError(t, err)
+			// The upper 32 bits of i32 results are undefined.
+			call32 := func(name string, params ...uint64) uint32 {
+				res, err := mod.ExportedFunction(name).Call(ctx, params...)
+				require.NoError(t, err)
+				return uint32(res[0])
+			}
+
+			// 1 + 2 + ... + 10
+			require.Equal(t, uint32(55), call32("sum", 10))
+			// The generators suspended forever are discarded at the end of each call.
+			for i := 0; i < 100; i++ {
+				require.Equal(t, uint32(6), call32("sum", 3))
+			}
+			// ask(5) is answered with 50.
+			require.Equal(t, uint32(51), call32("answer", 5))
+			// ask(5) is forwarded by middle, which doesn't handle it.
+			require.Equal(t, uint32(151), call32("forward", 5))
+			// The exception thrown in the continuation is caught by its resumer.
+			require.Equal(t, uint32(6), call32("catch_from_cont", 5))
+
+			for _, tt := range []struct {
+				name        string
+				expectedErr error
+			}{
+				{"unhandled", wasmruntime.ErrRuntimeUnhandledSuspension},
+				{"ask_plus_one", wasmruntime.ErrRuntimeUnhandledSuspension},
+				{"middle", wasmruntime.ErrRuntimeUnhandledSuspension},
+				{"twice", wasmruntime.ErrRuntimeContinuationAlreadyResumed},
+				{"resume_null", wasmruntime.ErrRuntimeNullReference},
+			} {
+				_, err = mod.ExportedFunction(tt.name).Call(ctx, 1)
+				require.ErrorIs(t, err, tt.expectedErr, tt.name)
+			}
+		})
+	}
+}
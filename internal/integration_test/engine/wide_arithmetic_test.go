@@ -0,0 +1,141 @@
+package adhoc
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// wideArithmeticModule is a module which exports the instructions of the wide arithmetic proposal.
+var wideArithmeticModule = &wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i64, i64, i64, i64}, Results: []wasm.ValueType{i64, i64}}, // type 0
+		{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64, i64}},           // type 1
+	},
+	FunctionSection: []wasm.Index{0, 0, 1, 1, 1},
+	CodeSection: []wasm.Code{
+		{ // func[0] add128(xLo, xHi, yLo, yHi)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64Add128,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[1] sub128(xLo, xHi, yLo, yHi)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64Sub128,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[2] mul_wide_s(x, y)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64MulWideS,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[3] mul_wide_u(x, y)
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64MulWideU,
+				wasm.OpcodeEnd,
+			},
+		},
+		{ // func[4] inc128(lo, hi) -> add128(lo, hi, 1, 0), which has constant operands.
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Const, 0,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64Add128,
+				wasm.OpcodeEnd,
+			},
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "add128", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "sub128", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "mul_wide_s", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "mul_wide_u", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "inc128", Type: wasm.ExternTypeFunc, Index: 4},
+	},
+}
+
+func TestWideArithmetic(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(api.CoreFeaturesV2))
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		_, err := r.CompileModule(ctx, binaryencoding.EncodeModule(wideArithmeticModule))
+		require.Error(t, err)
+	})
+
+	values := []uint64{0, 1, 2, 0x7fffffffffffffff, 0x8000000000000000, 0xfffffffffffffffe, math.MaxUint64, 0x123456789abcdef0}
+
+	for _, tc := range []struct {
+		name string
+		cfg  wazero.RuntimeConfig
+	}{
+		{"interpreter", wazero.NewRuntimeConfigInterpreter()},
+		{"default", wazero.NewRuntimeConfig()},
+	} {
+		config := tc.cfg.WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesWideArithmetic)
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(wideArithmeticModule))
+			require.NoError(t, err)
+			call := func(name string, params ...uint64) (lo, hi uint64) {
+				res, err := mod.ExportedFunction(name).Call(ctx, params...)
+				require.NoError(t, err)
+				return res[0], res[1]
+			}
+
+			for _, x := range values {
+				for _, y := range values {
+					lo, carry := bits.Add64(x, y, 0)
+					hi, _ := bits.Add64(y, x, carry)
+					actualLo, actualHi := call("add128", x, y, y, x)
+					require.Equal(t, [2]uint64{lo, hi}, [2]uint64{actualLo, actualHi}, "add128(%#x, %#x)", x, y)
+
+					lo, borrow := bits.Sub64(x, y, 0)
+					hi, _ = bits.Sub64(y, x, borrow)
+					actualLo, actualHi = call("sub128", x, y, y, x)
+					require.Equal(t, [2]uint64{lo, hi}, [2]uint64{actualLo, actualHi}, "sub128(%#x, %#x)", x, y)
+
+					hi, lo = bits.Mul64(x, y)
+					actualLo, actualHi = call("mul_wide_u", x, y)
+					require.Equal(t, [2]uint64{lo, hi}, [2]uint64{actualLo, actualHi}, "mul_wide_u(%#x, %#x)", x, y)
+
+					if int64(x) < 0 {
+						hi -= y
+					}
+					if int64(y) < 0 {
+						hi -= x
+					}
+					actualLo, actualHi = call("mul_wide_s", x, y)
+					require.Equal(t, [2]uint64{lo, hi}, [2]uint64{actualLo, actualHi}, "mul_wide_s(%#x, %#x)", x, y)
+				}
+			}
+
+			lo, hi := call("inc128", math.MaxUint64, 1)
+			require.Equal(t, [2]uint64{0, 2}, [2]uint64{lo, hi})
+			lo, hi = call("mul_wide_s", math.MaxUint64, 3)
+			require.Equal(t, [2]uint64{math.MaxUint64 - 2, math.MaxUint64}, [2]uint64{lo, hi})
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package spectest
+
+import (
+	"context"
+	"embed"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/integration_test/spectest"
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+//go:embed testdata/*.wasm
+//go:embed testdata/*.json
+var testcases embed.FS
+
+const enabledFeatures = api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling | experimental.CoreFeaturesTailCall | experimental.CoreFeaturesFunctionReferences
+
+// skips are the commands which need other proposals than enabledFeatures.
+var skips = spectest.Skips{
+	"tag.wast:30": "recursive types need the gc proposal",
+	"tag.wast:40": "recursive types need the gc proposal",
+	"tag.wast:49": "recursive types need the gc proposal",
+	"tag.wast:60": "imports the module of tag.wast:30",
+}
+
+func TestCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	spectest.RunWithSkips(t, testcases, context.Background(), wazero.NewRuntimeConfigCompiler().WithCoreFeatures(enabledFeatures), skips)
+}
+
+func TestInterpreter(t *testing.T) {
+	spectest.RunWithSkips(t, testcases, context.Background(), wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(enabledFeatures), skips)
+}
@@ -0,0 +1 @@
+(module (func (catch_all))) 
\ No newline at end of file
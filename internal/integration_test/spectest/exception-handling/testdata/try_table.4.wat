@@ -0,0 +1 @@
+(module (tag $e) (func (catch $e))) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func (result i32) (param i32) (i32.const 0)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(type $sig (func (param i32) (result i32))) (func (type $sig) (result i32) (i32.const 0)) 
\ No newline at end of file
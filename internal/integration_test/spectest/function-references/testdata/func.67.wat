@@ -0,0 +1 @@
+(func (nop) (local i32)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func (nop) (param i32)) 
\ No newline at end of file
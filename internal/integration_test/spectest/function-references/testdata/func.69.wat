@@ -0,0 +1 @@
+(func (nop) (result i32)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func (local i32) (param i32)) 
\ No newline at end of file
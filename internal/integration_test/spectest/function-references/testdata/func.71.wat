@@ -0,0 +1 @@
+(func (local i32) (result i32) (local.get 0)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func $foo) (func $foo) 
\ No newline at end of file
@@ -0,0 +1 @@
+(import "" "" (func $foo)) (func $foo) 
\ No newline at end of file
@@ -0,0 +1 @@
+(import "" "" (func $foo)) (import "" "" (func $foo)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func (param $foo i32) (param $foo i32)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func (param $foo i32) (local $foo i32)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(func (local $foo i32) (local $foo i32)) 
\ No newline at end of file
@@ -0,0 +1 @@
+(table 0 funcref) (func (return_call_indirect (param $x i32) (i32.const 0) (i32.const 0))) 
\ No newline at end of file
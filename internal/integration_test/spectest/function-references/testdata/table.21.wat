@@ -0,0 +1 @@
+(table 0 0x1_0000_0000 funcref) 
\ No newline at end of file
@@ -0,0 +1 @@
+(table $foo 1 funcref) (table $foo 1 funcref) 
\ No newline at end of file
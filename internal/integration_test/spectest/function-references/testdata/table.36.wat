@@ -0,0 +1 @@
+(import "" "" (table $foo 1 funcref)) (import "" "" (table $foo 1 funcref)) 
\ No newline at end of file
@@ -0,0 +1,28 @@
+package spectest
+
+import (
+	"context"
+	"embed"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/integration_test/spectest"
+)
+
+//go:embed testdata/*.wasm
+//go:embed testdata/*.json
+var testcases embed.FS
+
+const enabledFeatures = api.CoreFeaturesV2 | experimental.CoreFeaturesGC | experimental.CoreFeaturesTailCall
+
+// skips are the parts of the proposal which aren't implemented. The files in testdata all pass, but the
+// type-canon.wast file of the proposal is not in testdata yet.
+var skips = spectest.Skips{}
+
+// The compiler doesn't support experimental.CoreFeaturesGC, so only the interpreter runs the tests.
+
+func TestInterpreter(t *testing.T) {
+	spectest.RunWithSkips(t, testcases, context.Background(), wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(enabledFeatures), skips)
+}
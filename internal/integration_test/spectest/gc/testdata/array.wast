@@ -0,0 +1,282 @@
+;; Type syntax
+
+(module
+  (type (array i8))
+  (type (array i16))
+  (type (array i32))
+  (type (array i64))
+  (type (array f32))
+  (type (array f64))
+  (type (array anyref))
+  (type (array (ref struct)))
+  (type (array (ref 0)))
+  (type (array (ref null 1)))
+  (type (array (mut i8)))
+  (type (array (mut i16)))
+  (type (array (mut i32)))
+  (type (array (mut i64)))
+  (type (array (mut f32)))
+  (type (array (mut f64)))
+  (type (array (mut anyref)))
+  (type (array (mut (ref struct))))
+  (type (array (mut (ref 0))))
+  (type (array (mut (ref null i31))))
+)
+
+
+(assert_invalid
+  (module
+    (type (array (mut (ref null 10))))
+  )
+  "unknown type"
+)
+
+
+;; Binding structure
+
+(module
+  (rec
+    (type $s0 (array (ref $s1)))
+    (type $s1 (array (ref $s0)))
+  )
+
+  (func (param (ref $s0)))
+
+  (type $s (array i32))
+  (type $t (array i32))
+
+  (func (param (ref $s)) (result (ref $t)) (local.get 0))
+)
+
+(assert_invalid
+  (module (type (array (ref 1))))
+  "unknown type"
+)
+(assert_invalid
+  (module (type (array (mut (ref 1)))))
+  "unknown type"
+)
+
+
+;; Basic instructions
+
+(module
+  (type $vec (array f32))
+  (type $mvec (array (mut f32)))
+
+  (global (ref $vec) (array.new $vec (f32.const 1) (i32.const 3)))
+  (global (ref $vec) (array.new_default $vec (i32.const 3)))
+
+  (func $new (export "new") (result (ref $vec))
+    (array.new_default $vec (i32.const 3))
+  )
+
+  (func $get (param $i i32) (param $v (ref $vec)) (result f32)
+    (array.get $vec (local.get $v) (local.get $i))
+  )
+  (func (export "get") (param $i i32) (result f32)
+    (call $get (local.get $i) (call $new))
+  )
+
+  (func $set_get (param $i i32) (param $v (ref $mvec)) (param $y f32) (result f32)
+    (array.set $mvec (local.get $v) (local.get $i) (local.get $y))
+    (array.get $mvec (local.get $v) (local.get $i))
+  )
+  (func (export "set_get") (param $i i32) (param $y f32) (result f32)
+    (call $set_get (local.get $i)
+      (array.new_default $mvec (i32.const 3))
+      (local.get $y)
+    )
+  )
+
+  (func $len (param $v (ref array)) (result i32)
+    (array.len (local.get $v))
+  )
+  (func (export "len") (result i32)
+    (call $len (call $new))
+  )
+
+  (func (export "get_global_1") (param $i i32) (result f32)
+    (array.get $vec (global.get 0) (local.get $i))
+  )
+  (func (export "get_global_2") (param $i i32) (result f32)
+    (array.get $vec (global.get 1) (local.get $i))
+  )
+)
+
+(assert_return (invoke "new") (ref.array))
+(assert_return (invoke "get" (i32.const 0)) (f32.const 0))
+(assert_return (invoke "set_get" (i32.const 1) (f32.const 7)) (f32.const 7))
+(assert_return (invoke "len") (i32.const 3))
+(assert_return (invoke "get_global_1" (i32.const 2)) (f32.const 1))
+(assert_return (invoke "get_global_2" (i32.const 2)) (f32.const 0))
+
+(assert_trap (invoke "get" (i32.const 10)) "out of bounds array access")
+(assert_trap (invoke "set_get" (i32.const 10) (f32.const 7)) "out of bounds array access")
+(assert_trap (invoke "get_global_1" (i32.const 3)) "out of bounds array access")
+
+(module
+  (type $vec (array f32))
+  (type $mvec (array (mut f32)))
+
+  (global (ref $vec) (array.new_fixed $vec 2 (f32.const 1) (f32.const 2)))
+
+  (func $new (export "new") (result (ref $vec))
+    (array.new_fixed $vec 2 (f32.const 1) (f32.const 2))
+  )
+
+  (func $get (param $i i32) (param $v (ref $vec)) (result f32)
+    (array.get $vec (local.get $v) (local.get $i))
+  )
+  (func (export "get") (param $i i32) (result f32)
+    (call $get (local.get $i) (call $new))
+  )
+
+  (func $set_get (param $i i32) (param $v (ref $mvec)) (param $y f32) (result f32)
+    (array.set $mvec (local.get $v) (local.get $i) (local.get $y))
+    (array.get $mvec (local.get $v) (local.get $i))
+  )
+  (func (export "set_get") (param $i i32) (param $y f32) (result f32)
+    (call $set_get (local.get $i)
+      (array.new_fixed $mvec 3 (f32.const 1) (f32.const 2) (f32.const 3))
+      (local.get $y)
+    )
+  )
+
+  (func $len (param $v (ref array)) (result i32)
+    (array.len (local.get $v))
+  )
+  (func (export "len") (result i32)
+    (call $len (call $new))
+  )
+
+  (func (export "get_global") (param $i i32) (result f32)
+    (array.get $vec (global.get 0) (local.get $i))
+  )
+)
+
+(assert_return (invoke "new") (ref.array))
+(assert_return (invoke "get" (i32.const 0)) (f32.const 1))
+(assert_return (invoke "set_get" (i32.const 1) (f32.const 7)) (f32.const 7))
+(assert_return (invoke "len") (i32.const 2))
+(assert_return (invoke "get_global" (i32.const 1)) (f32.const 2))
+
+(assert_trap (invoke "get" (i32.const 10)) "out of bounds array access")
+(assert_trap (invoke "set_get" (i32.const 10) (f32.const 7)) "out of bounds array access")
+
+(assert_invalid
+  (module
+    (type $a (array i64))
+    (func (export "array.set-immutable") (param $a (ref $a))
+      (array.set $a (local.get $a) (i32.const 0) (i64.const 1))
+    )
+  )
+  "array is immutable"
+)
+
+(assert_invalid
+  (module
+    (type $a (array (ref any)))
+    (func (result anyref) (array.new_default $a (i32.const 1)))
+  )
+  "non-defaultable"
+)
+
+(assert_invalid
+  (module
+    (type $a (array i32))
+    (func (result anyref) (array.new_fixed $a 2 (i32.const 1)))
+  )
+  "type mismatch"
+)
+
+(assert_invalid
+  (module
+    (type $a (array i32))
+    (global (ref $a) (array.new_fixed $a 1 (i64.const 1)))
+  )
+  "type mismatch"
+)
+
+
+;; Packed elements
+
+(module
+  (type $a8 (array (mut i8)))
+  (type $a16 (array (mut i16)))
+
+  (global $g8 (ref $a8) (array.new_fixed $a8 2 (i32.const 0x1ff) (i32.const 0x7f)))
+  (global $g16 (ref $a16) (array.new $a16 (i32.const 0x18000) (i32.const 2)))
+
+  (func (export "get8_s") (param i32) (result i32)
+    (array.get_s $a8 (global.get $g8) (local.get 0))
+  )
+  (func (export "get8_u") (param i32) (result i32)
+    (array.get_u $a8 (global.get $g8) (local.get 0))
+  )
+  (func (export "get16_s") (param i32) (result i32)
+    (array.get_s $a16 (global.get $g16) (local.get 0))
+  )
+  (func (export "get16_u") (param i32) (result i32)
+    (array.get_u $a16 (global.get $g16) (local.get 0))
+  )
+  (func (export "set_get8_s") (param i32 i32) (result i32)
+    (array.set $a8 (global.get $g8) (local.get 0) (local.get 1))
+    (array.get_s $a8 (global.get $g8) (local.get 0))
+  )
+)
+
+(assert_return (invoke "get8_s" (i32.const 0)) (i32.const -1))
+(assert_return (invoke "get8_u" (i32.const 0)) (i32.const 255))
+(assert_return (invoke "get8_s" (i32.const 1)) (i32.const 127))
+(assert_return (invoke "get16_s" (i32.const 1)) (i32.const -32768))
+(assert_return (invoke "get16_u" (i32.const 1)) (i32.const 32768))
+(assert_return (invoke "set_get8_s" (i32.const 1) (i32.const 0x180)) (i32.const -128))
+
+(assert_invalid
+  (module
+    (type $a (array i8))
+    (func (param (ref $a)) (result i32) (array.get $a (local.get 0) (i32.const 0)))
+  )
+  "array is packed"
+)
+
+
+;; Null dereference
+
+(module
+  (type $t (array (mut i32)))
+  (func (export "array.get-null")
+    (local (ref null $t)) (drop (array.get $t (local.get 0) (i32.const 0)))
+  )
+  (func (export "array.set-null")
+    (local (ref null $t)) (array.set $t (local.get 0) (i32.const 0) (i32.const 0))
+  )
+  (func (export "array.len-null") (result i32)
+    (local (ref null $t)) (array.len (local.get 0))
+  )
+)
+
+(assert_trap (invoke "array.get-null") "null array reference")
+(assert_trap (invoke "array.set-null") "null array reference")
+(assert_trap (invoke "array.len-null") "null array reference")
+
+
+;; Allocation limits
+
+(module
+  (type $a (array i64))
+  (func (export "new-huge") (result i32)
+    (array.len (array.new_default $a (i32.const -1)))
+  )
+)
+
+(assert_trap (invoke "new-huge") "allocation too large")
+
+(assert_trap
+  (module
+    (type $a (array i64))
+    (global (ref $a) (array.new_default $a (i32.const -1)))
+  )
+  "allocation too large"
+)
@@ -0,0 +1,57 @@
+(module
+  (type $arr (array (mut i8)))
+  (type $arr16 (array (mut i16)))
+
+  (data $d "abcd")
+  (data $d16 "\01\00\02\00\ff\ff")
+
+  (func (export "array-new-data") (param $offset i32) (param $len i32) (result (ref $arr))
+    (array.new_data $arr $d (local.get $offset) (local.get $len))
+  )
+
+  (func (export "array-new-data-contents") (result i32 i32)
+    (local (ref $arr))
+    (local.set 0 (array.new_data $arr $d (i32.const 1) (i32.const 2)))
+    (array.get_u $arr (local.get 0) (i32.const 0))
+    (array.get_u $arr (local.get 0) (i32.const 1))
+  )
+
+  (func (export "array-new-data-i16") (result i32 i32 i32)
+    (local (ref $arr16))
+    (local.set 0 (array.new_data $arr16 $d16 (i32.const 0) (i32.const 3)))
+    (array.get_u $arr16 (local.get 0) (i32.const 0))
+    (array.get_u $arr16 (local.get 0) (i32.const 1))
+    (array.get_s $arr16 (local.get 0) (i32.const 2))
+  )
+
+  (func (export "drop-and-new") (param $len i32) (result (ref $arr))
+    (data.drop $d)
+    (array.new_data $arr $d (i32.const 0) (local.get $len))
+  )
+)
+
+(assert_return (invoke "array-new-data" (i32.const 0) (i32.const 0)) (ref.array))
+(assert_return (invoke "array-new-data" (i32.const 0) (i32.const 4)) (ref.array))
+(assert_return (invoke "array-new-data" (i32.const 1) (i32.const 2)) (ref.array))
+(assert_return (invoke "array-new-data" (i32.const 4) (i32.const 0)) (ref.array))
+
+(assert_trap (invoke "array-new-data" (i32.const 0) (i32.const 5)) "out of bounds memory access")
+(assert_trap (invoke "array-new-data" (i32.const 5) (i32.const 0)) "out of bounds memory access")
+(assert_trap (invoke "array-new-data" (i32.const 1) (i32.const 4)) "out of bounds memory access")
+
+(assert_return (invoke "array-new-data-contents") (i32.const 0x62) (i32.const 0x63))
+(assert_return (invoke "array-new-data-i16") (i32.const 1) (i32.const 2) (i32.const -1))
+
+(assert_return (invoke "drop-and-new" (i32.const 0)) (ref.array))
+(assert_trap (invoke "drop-and-new" (i32.const 1)) "out of bounds memory access")
+
+(assert_invalid
+  (module
+    (type $arr (array (mut funcref)))
+    (data $d "abcd")
+    (func (result (ref $arr))
+      (array.new_data $arr $d (i32.const 0) (i32.const 0))
+    )
+  )
+  "array type is not numeric or vector"
+)
@@ -0,0 +1,64 @@
+(module
+  (type $vec (array i31ref))
+  (type $mvec (array (mut i31ref)))
+  (type $fvec (array (ref null func)))
+  (type $ft (func (result i32)))
+
+  (elem $e i31ref
+    (ref.i31 (i32.const 0xaa))
+    (ref.i31 (i32.const 0xbb))
+    (ref.i31 (i32.const 0xcc))
+    (ref.i31 (i32.const 0xdd)))
+
+  (elem $f func $f0 $f1)
+  (func $f0 (type $ft) (i32.const 10))
+  (func $f1 (type $ft) (i32.const 11))
+
+  (func (export "array-new-elem") (param $offset i32) (param $len i32) (result (ref $vec))
+    (array.new_elem $vec $e (local.get $offset) (local.get $len))
+  )
+
+  (func (export "array-new-elem-contents") (result i32 i32)
+    (local (ref $vec))
+    (local.set 0 (array.new_elem $vec $e (i32.const 1) (i32.const 2)))
+    (i31.get_u (array.get $vec (local.get 0) (i32.const 0)))
+    (i31.get_u (array.get $vec (local.get 0) (i32.const 1)))
+  )
+
+  (func (export "array-new-elem-funcs") (result i32)
+    (local (ref $fvec))
+    (local.set 0 (array.new_elem $fvec $f (i32.const 0) (i32.const 2)))
+    (call_ref $ft (ref.cast (ref $ft) (array.get $fvec (local.get 0) (i32.const 1))))
+  )
+
+  (func (export "drop-and-new") (param $len i32) (result (ref $vec))
+    (elem.drop $e)
+    (array.new_elem $vec $e (i32.const 0) (local.get $len))
+  )
+)
+
+(assert_return (invoke "array-new-elem" (i32.const 0) (i32.const 0)) (ref.array))
+(assert_return (invoke "array-new-elem" (i32.const 0) (i32.const 4)) (ref.array))
+(assert_return (invoke "array-new-elem" (i32.const 1) (i32.const 2)) (ref.array))
+(assert_return (invoke "array-new-elem" (i32.const 4) (i32.const 0)) (ref.array))
+
+(assert_trap (invoke "array-new-elem" (i32.const 0) (i32.const 5)) "out of bounds table access")
+(assert_trap (invoke "array-new-elem" (i32.const 5) (i32.const 0)) "out of bounds table access")
+(assert_trap (invoke "array-new-elem" (i32.const 1) (i32.const 4)) "out of bounds table access")
+
+(assert_return (invoke "array-new-elem-contents") (i32.const 0xbb) (i32.const 0xcc))
+(assert_return (invoke "array-new-elem-funcs") (i32.const 11))
+
+(assert_return (invoke "drop-and-new" (i32.const 0)) (ref.array))
+(assert_trap (invoke "drop-and-new" (i32.const 1)) "out of bounds table access")
+
+(assert_invalid
+  (module
+    (type $vec (array i31ref))
+    (elem $e funcref)
+    (func (result (ref $vec))
+      (array.new_elem $vec $e (i32.const 0) (i32.const 0))
+    )
+  )
+  "type mismatch"
+)
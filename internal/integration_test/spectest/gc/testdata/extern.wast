@@ -0,0 +1,119 @@
+(module
+  (type $ft (func))
+  (type $st (struct))
+  (type $at (array i8))
+
+  (table 10 anyref)
+
+  (elem declare func $f)
+  (func $f)
+
+  (func (export "init") (param $x externref)
+    (table.set (i32.const 0) (ref.null any))
+    (table.set (i32.const 1) (ref.i31 (i32.const 7)))
+    (table.set (i32.const 2) (struct.new_default $st))
+    (table.set (i32.const 3) (array.new_default $at (i32.const 0)))
+    (table.set (i32.const 4) (any.convert_extern (local.get $x)))
+    (table.set (i32.const 5) (ref.null i31))
+    (table.set (i32.const 6) (ref.null struct))
+    (table.set (i32.const 7) (ref.null none))
+  )
+
+  (func (export "internalize") (param externref) (result anyref)
+    (any.convert_extern (local.get 0))
+  )
+  (func (export "externalize") (param anyref) (result externref)
+    (extern.convert_any (local.get 0))
+  )
+
+  (func (export "externalize-i") (param i32) (result externref)
+    (extern.convert_any (table.get (local.get 0)))
+  )
+  (func (export "externalize-ii") (param i32) (result anyref)
+    (any.convert_extern (extern.convert_any (table.get (local.get 0))))
+  )
+
+  (func (export "is-i31") (param i32) (result i32)
+    (ref.test (ref i31) (any.convert_extern (extern.convert_any (table.get (local.get 0)))))
+  )
+  (func (export "is-struct") (param i32) (result i32)
+    (ref.test (ref $st) (any.convert_extern (extern.convert_any (table.get (local.get 0)))))
+  )
+  (func (export "roundtrip-eq") (param i32) (result i32)
+    (ref.eq
+      (ref.cast eqref (table.get (local.get 0)))
+      (ref.cast eqref (any.convert_extern (extern.convert_any (table.get (local.get 0)))))
+    )
+  )
+  (func (export "host-roundtrip") (param externref) (result externref)
+    (extern.convert_any (any.convert_extern (local.get 0)))
+  )
+  (func (export "host-is-null") (param externref) (result i32)
+    (ref.is_null (any.convert_extern (local.get 0)))
+  )
+)
+
+(invoke "init" (ref.extern 0))
+
+(assert_return (invoke "internalize" (ref.extern 1)) (ref.any))
+(assert_return (invoke "internalize" (ref.null extern)) (ref.null any))
+
+(assert_return (invoke "externalize" (ref.null any)) (ref.null extern))
+
+(assert_return (invoke "externalize-i" (i32.const 0)) (ref.null extern))
+(assert_return (invoke "externalize-i" (i32.const 1)) (ref.extern))
+(assert_return (invoke "externalize-i" (i32.const 2)) (ref.extern))
+(assert_return (invoke "externalize-i" (i32.const 3)) (ref.extern))
+(assert_return (invoke "externalize-i" (i32.const 4)) (ref.extern))
+(assert_return (invoke "externalize-i" (i32.const 5)) (ref.null extern))
+
+(assert_return (invoke "externalize-ii" (i32.const 0)) (ref.null any))
+(assert_return (invoke "externalize-ii" (i32.const 1)) (ref.i31))
+(assert_return (invoke "externalize-ii" (i32.const 2)) (ref.struct))
+(assert_return (invoke "externalize-ii" (i32.const 3)) (ref.array))
+(assert_return (invoke "externalize-ii" (i32.const 4)) (ref.any))
+(assert_return (invoke "externalize-ii" (i32.const 5)) (ref.null any))
+
+(assert_return (invoke "is-i31" (i32.const 1)) (i32.const 1))
+(assert_return (invoke "is-i31" (i32.const 2)) (i32.const 0))
+(assert_return (invoke "is-struct" (i32.const 1)) (i32.const 0))
+(assert_return (invoke "is-struct" (i32.const 2)) (i32.const 1))
+(assert_return (invoke "is-struct" (i32.const 4)) (i32.const 0))
+
+(assert_return (invoke "roundtrip-eq" (i32.const 1)) (i32.const 1))
+(assert_return (invoke "roundtrip-eq" (i32.const 2)) (i32.const 1))
+(assert_return (invoke "roundtrip-eq" (i32.const 3)) (i32.const 1))
+
+(assert_return (invoke "host-roundtrip" (ref.extern 1)) (ref.extern 1))
+(assert_return (invoke "host-roundtrip" (ref.null extern)) (ref.null extern))
+(assert_return (invoke "host-is-null" (ref.extern 1)) (i32.const 0))
+(assert_return (invoke "host-is-null" (ref.null extern)) (i32.const 1))
+
+(module
+  (global $g externref (extern.convert_any (ref.i31 (i32.const 5))))
+  (global $h anyref (any.convert_extern (global.get $g)))
+  (func (export "get") (result i32)
+    (i31.get_u (ref.cast i31ref (global.get $h)))
+  )
+)
+
+(assert_return (invoke "get") (i32.const 5))
+
+(assert_invalid
+  (module
+    (func (param anyref) (result anyref) (any.convert_extern (local.get 0)))
+  )
+  "type mismatch"
+)
+(assert_invalid
+  (module
+    (func (param externref) (result externref) (extern.convert_any (local.get 0)))
+  )
+  "type mismatch"
+)
+(assert_invalid
+  (module
+    (func (param (ref null extern)) (result (ref any)) (any.convert_extern (local.get 0)))
+  )
+  "type mismatch"
+)
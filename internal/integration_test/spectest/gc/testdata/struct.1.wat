@@ -0,0 +1 @@
+(type (struct (field $x i32) (field $x i32)))
\ No newline at end of file
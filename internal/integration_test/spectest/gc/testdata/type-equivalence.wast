@@ -0,0 +1,100 @@
+;; Syntactic types (validation time)
+
+;; Simple types.
+
+(module
+  (type $t1 (func (param f32 (ref any))))
+  (type $t2 (func (param f32 (ref any))))
+  (func $f1 (param $r (ref $t1)) (call $f2 (local.get $r)))
+  (func $f2 (param $r (ref $t2)) (call $f1 (local.get $r)))
+)
+
+(assert_invalid
+  (module
+    (type $t1 (func (param i32 (ref any))))
+    (type $t2 (func (param f32 (ref any))))
+    (func $f1 (param $r (ref $t1)) (call $f2 (local.get $r)))
+    (func $f2 (param $r (ref $t2)) (call $f1 (local.get $r)))
+  )
+  "type mismatch"
+)
+
+;; Indirect types.
+
+(module
+  (type $s0 (func (param i32) (result f32)))
+  (type $s1 (func (param i32 (ref $s0)) (result (ref $s0))))
+  (type $s2 (func (param i32 (ref $s0)) (result (ref $s0))))
+  (type $t1 (func (param (ref $s1)) (result (ref $s2))))
+  (type $t2 (func (param (ref $s2)) (result (ref $s1))))
+  (func $f1 (param $r (ref $t1)) (call $f2 (local.get $r)))
+  (func $f2 (param $r (ref $t2)) (call $f1 (local.get $r)))
+)
+
+;; Recursive types.
+
+(module
+  (rec (type $t1 (func (param i32 (ref $t1)))))
+  (rec (type $t2 (func (param i32 (ref $t2)))))
+  (func $f1 (param $r (ref $t1)) (call $f2 (local.get $r)))
+  (func $f2 (param $r (ref $t2)) (call $f1 (local.get $r)))
+)
+
+(assert_invalid
+  (module
+    (rec (type $t1 (func (param i32 (ref $t1)))))
+    (rec (type $t2 (func (param i32 (ref $t2)))) (type (struct)))
+    (func $f1 (param $r (ref $t1)) (call $f2 (local.get $r)))
+    (func $f2 (param $r (ref $t2)) (call $f1 (local.get $r)))
+  )
+  "type mismatch"
+)
+
+
+;; Semantic types (run time)
+
+(module
+  (rec (type $s1 (struct (field (ref null $s1)))))
+  (rec (type $s2 (struct (field (ref null $s2)))))
+
+  (func (export "run") (result i32)
+    (ref.test (ref $s2) (struct.new_default $s1))
+  )
+)
+(assert_return (invoke "run") (i32.const 1))
+
+(module
+  (rec (type $s1 (struct (field (ref null $s1)))) (type (struct)))
+  (rec (type $s2 (struct (field (ref null $s2)))))
+
+  (func (export "run") (result i32)
+    (ref.test (ref $s2) (struct.new_default $s1))
+  )
+)
+(assert_return (invoke "run") (i32.const 0))
+
+
+;; Semantic types (link time)
+
+(module
+  (rec (type $t0 (func (param i32 (ref null $t0)))))
+  (type $t1 (func (param (ref $t0))))
+  (func (export "f") (param (ref $t0)))
+  (func (export "g") (param (ref $t1)))
+)
+(register "M")
+
+(module
+  (rec (type $u0 (func (param i32 (ref null $u0)))))
+  (type $u1 (func (param (ref $u0))))
+  (func (import "M" "f") (param (ref $u0)))
+  (func (import "M" "g") (param (ref $u1)))
+)
+
+(assert_unlinkable
+  (module
+    (rec (type $u0 (func (param i32 (ref null $u0)))) (type (struct)))
+    (func (import "M" "f") (param (ref $u0)))
+  )
+  "incompatible import type"
+)
@@ -0,0 +1 @@
+(memory i64 1) (func (drop (i32.load offset=0x1_0000_0000_0000_0000 (i64.const 0))))
\ No newline at end of file
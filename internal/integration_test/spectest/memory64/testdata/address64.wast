@@ -0,0 +1,166 @@
+;; Load i32 data with different offset/align arguments
+
+(module
+  (memory i64 1)
+  (data (i64.const 0) "abcdefghijklmnopqrstuvwxyz")
+
+  (func (export "8u_good1") (param $i i64) (result i32)
+    (i32.load8_u offset=0 (local.get $i))                   ;; 97 'a'
+  )
+  (func (export "8u_good2") (param $i i64) (result i32)
+    (i32.load8_u align=1 (local.get $i))                    ;; 97 'a'
+  )
+  (func (export "8u_good3") (param $i i64) (result i32)
+    (i32.load8_u offset=1 align=1 (local.get $i))           ;; 98 'b'
+  )
+  (func (export "8u_good4") (param $i i64) (result i32)
+    (i32.load8_u offset=2 align=1 (local.get $i))           ;; 99 'c'
+  )
+  (func (export "8u_good5") (param $i i64) (result i32)
+    (i32.load8_u offset=25 align=1 (local.get $i))          ;; 122 'z'
+  )
+
+  (func (export "16s_good1") (param $i i64) (result i32)
+    (i32.load16_s offset=0 (local.get $i))                  ;; 25185 'ab'
+  )
+  (func (export "16s_good5") (param $i i64) (result i32)
+    (i32.load16_s offset=25 align=2 (local.get $i))         ;; 122 'z\0'
+  )
+
+  (func (export "32_good1") (param $i i64) (result i32)
+    (i32.load offset=0 (local.get $i))                      ;; 1684234849 'abcd'
+  )
+  (func (export "32_good2") (param $i i64) (result i32)
+    (i32.load align=1 (local.get $i))                       ;; 1684234849 'abcd'
+  )
+  (func (export "32_good3") (param $i i64) (result i32)
+    (i32.load offset=1 align=1 (local.get $i))              ;; 1701077858 'bcde'
+  )
+  (func (export "32_good4") (param $i i64) (result i32)
+    (i32.load offset=2 align=2 (local.get $i))              ;; 1717920867 'cdef'
+  )
+  (func (export "32_good5") (param $i i64) (result i32)
+    (i32.load offset=25 align=4 (local.get $i))             ;; 122 'z\0\0\0'
+  )
+
+  (func (export "8u_bad") (param $i i64)
+    (drop (i32.load8_u offset=4294967295 (local.get $i)))
+  )
+  (func (export "16s_bad") (param $i i64)
+    (drop (i32.load16_s offset=4294967295 (local.get $i)))
+  )
+  (func (export "32_bad") (param $i i64)
+    (drop (i32.load offset=4294967295 (local.get $i)))
+  )
+  (func (export "64_offset_bad") (param $i i64)
+    (drop (i32.load offset=0xffff_ffff_ffff_ffff (local.get $i)))
+  )
+)
+
+(assert_return (invoke "8u_good1" (i64.const 0)) (i32.const 97))
+(assert_return (invoke "8u_good2" (i64.const 0)) (i32.const 97))
+(assert_return (invoke "8u_good3" (i64.const 0)) (i32.const 98))
+(assert_return (invoke "8u_good4" (i64.const 0)) (i32.const 99))
+(assert_return (invoke "8u_good5" (i64.const 0)) (i32.const 122))
+(assert_return (invoke "16s_good1" (i64.const 0)) (i32.const 25185))
+(assert_return (invoke "16s_good5" (i64.const 0)) (i32.const 122))
+(assert_return (invoke "32_good1" (i64.const 0)) (i32.const 1684234849))
+(assert_return (invoke "32_good2" (i64.const 0)) (i32.const 1684234849))
+(assert_return (invoke "32_good3" (i64.const 0)) (i32.const 1701077858))
+(assert_return (invoke "32_good4" (i64.const 0)) (i32.const 1717920867))
+(assert_return (invoke "32_good5" (i64.const 0)) (i32.const 122))
+
+(assert_return (invoke "8u_good1" (i64.const 65503)) (i32.const 0))
+(assert_return (invoke "8u_good5" (i64.const 65503)) (i32.const 0))
+(assert_return (invoke "32_good1" (i64.const 65503)) (i32.const 0))
+(assert_return (invoke "32_good5" (i64.const 65503)) (i32.const 0))
+
+(assert_return (invoke "8u_good1" (i64.const 65535)) (i32.const 0))
+(assert_trap (invoke "8u_good5" (i64.const 65535)) "out of bounds memory access")
+(assert_trap (invoke "16s_good1" (i64.const 65535)) "out of bounds memory access")
+(assert_trap (invoke "32_good1" (i64.const 65533)) "out of bounds memory access")
+(assert_trap (invoke "32_good5" (i64.const 65508)) "out of bounds memory access")
+
+(assert_trap (invoke "8u_good1" (i64.const -1)) "out of bounds memory access")
+(assert_trap (invoke "32_good1" (i64.const -1)) "out of bounds memory access")
+(assert_trap (invoke "32_good1" (i64.const 0x1_0000_0000)) "out of bounds memory access")
+(assert_trap (invoke "8u_good5" (i64.const 0xffff_ffff_ffff_fff0)) "out of bounds memory access")
+
+(assert_trap (invoke "8u_bad" (i64.const 0)) "out of bounds memory access")
+(assert_trap (invoke "16s_bad" (i64.const 0)) "out of bounds memory access")
+(assert_trap (invoke "32_bad" (i64.const 0)) "out of bounds memory access")
+(assert_trap (invoke "8u_bad" (i64.const 1)) "out of bounds memory access")
+(assert_trap (invoke "64_offset_bad" (i64.const 0)) "out of bounds memory access")
+(assert_trap (invoke "64_offset_bad" (i64.const 1)) "out of bounds memory access")
+
+;; Load i64 data with different offset/align arguments
+
+(module
+  (memory i64 1)
+  (data (i64.const 0) "abcdefghijklmnopqrstuvwxyz")
+
+  (func (export "64_good1") (param $i i64) (result i64)
+    (i64.load offset=0 (local.get $i))                     ;; 0x6867666564636261 'abcdefgh'
+  )
+  (func (export "64_good4") (param $i i64) (result i64)
+    (i64.load offset=2 align=2 (local.get $i))             ;; 0x6a69686766656463 'cdefghij'
+  )
+  (func (export "64_good5") (param $i i64) (result i64)
+    (i64.load offset=25 align=8 (local.get $i))            ;; 122 'z\0\0\0\0\0\0\0'
+  )
+  (func (export "32u_good3") (param $i i64) (result i64)
+    (i64.load32_u offset=1 align=1 (local.get $i))         ;; 1701077858 'bcde'
+  )
+  (func (export "64_bad") (param $i i64)
+    (drop (i64.load offset=4294967295 (local.get $i)))
+  )
+)
+
+(assert_return (invoke "64_good1" (i64.const 0)) (i64.const 0x6867666564636261))
+(assert_return (invoke "64_good4" (i64.const 0)) (i64.const 0x6a69686766656463))
+(assert_return (invoke "64_good5" (i64.const 0)) (i64.const 122))
+(assert_return (invoke "32u_good3" (i64.const 0)) (i64.const 1701077858))
+(assert_return (invoke "64_good1" (i64.const 65528)) (i64.const 0))
+(assert_trap (invoke "64_good1" (i64.const 65529)) "out of bounds memory access")
+(assert_trap (invoke "64_good5" (i64.const 65504)) "out of bounds memory access")
+(assert_trap (invoke "64_bad" (i64.const 0)) "out of bounds memory access")
+
+;; Store with different offset/align arguments
+
+(module
+  (memory i64 1)
+
+  (func (export "store") (param $i i64) (param $v i64)
+    (i64.store offset=4 align=4 (local.get $i) (local.get $v))
+  )
+  (func (export "load") (param $i i64) (result i64)
+    (i64.load offset=4 (local.get $i))
+  )
+  (func (export "store_big_offset") (param $i i64) (param $v i32)
+    (i32.store offset=0x1_0000_0000 (local.get $i) (local.get $v))
+  )
+)
+
+(invoke "store" (i64.const 0) (i64.const 0x0102030405060708))
+(assert_return (invoke "load" (i64.const 0)) (i64.const 0x0102030405060708))
+(invoke "store" (i64.const 65524) (i64.const 42))
+(assert_return (invoke "load" (i64.const 65524)) (i64.const 42))
+(assert_trap (invoke "store" (i64.const 65525) (i64.const 0)) "out of bounds memory access")
+(assert_trap (invoke "store" (i64.const -4) (i64.const 0)) "out of bounds memory access")
+(assert_trap (invoke "store_big_offset" (i64.const 0) (i32.const 0)) "out of bounds memory access")
+(assert_trap (invoke "store_big_offset" (i64.const -0x1_0000_0000) (i32.const 0)) "out of bounds memory access")
+
+(assert_malformed
+  (module quote
+    "(memory i64 1)"
+    "(func (drop (i32.load offset=0x1_0000_0000_0000_0000 (i64.const 0))))"
+  )
+  "i64 constant"
+)
+(assert_invalid
+  (module
+    (memory 1)
+    (func (drop (i32.load offset=0x1_0000_0000 (i32.const 0))))
+  )
+  "offset out of range"
+)
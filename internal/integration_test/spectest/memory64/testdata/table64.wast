@@ -0,0 +1,32 @@
+;; Test table section structure
+
+(module (table i64 0 funcref))
+(module (table i64 1 funcref))
+(module (table i64 0 0 funcref))
+(module (table i64 0 1 funcref))
+(module (table i64 1 256 funcref))
+(module (table i64 0 65536 funcref))
+(module (table i64 0 0xffff_ffff funcref))
+(module (table i64 0 0x1_0000_0000 funcref))
+
+(module (table i64 0 funcref) (table i64 0 funcref))
+(module (table (import "spectest" "table") 0 funcref) (table i64 0 funcref))
+
+(assert_invalid (module (elem (i32.const 0))) "unknown table")
+(assert_invalid (module (elem (i32.const 0) $f) (func $f)) "unknown table")
+
+(assert_invalid
+  (module (table i64 1 0 funcref))
+  "size minimum must not be greater than maximum"
+)
+
+(module
+  (table $t64 i64 3 funcref)
+  (func (export "size") (result i64) (table.size $t64))
+  (func (export "grow") (param i64) (result i64)
+    (table.grow $t64 (ref.null func) (local.get 0)))
+)
+
+(assert_return (invoke "size") (i64.const 3))
+(assert_return (invoke "grow" (i64.const 2)) (i64.const 3))
+(assert_return (invoke "size") (i64.const 5))
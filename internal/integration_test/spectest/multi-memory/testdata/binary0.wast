@@ -0,0 +1,72 @@
+;; Unsigned LEB128 can have non-minimal length
+(module binary
+  "\00asm" "\01\00\00\00"
+  "\05\07\02"                          ;; Memory section with 2 entries
+  "\00\82\00"                          ;; no max, minimum 2
+  "\00\82\00"                          ;; no max, minimum 2
+)
+(module binary
+  "\00asm" "\01\00\00\00"
+  "\05\13\03"                          ;; Memory section with 3 entries
+  "\00\83\80\80\80\00"                 ;; no max, minimum 3
+  "\00\84\80\80\80\00"                 ;; no max, minimum 4
+  "\00\85\80\80\80\00"                 ;; no max, minimum 5
+)
+
+;; A memory index is encoded in the memarg when bit 6 of the alignment is set
+(module binary
+  "\00asm" "\01\00\00\00"
+  "\01\05\01"                          ;; Type section with 1 entry
+  "\60\00\01\7f"                       ;; [] -> [i32]
+  "\03\02\01\00"                       ;; Function section with 1 entry
+  "\05\05\02"                          ;; Memory section with 2 entries
+  "\00\00"                             ;; no max, minimum 0
+  "\00\01"                             ;; no max, minimum 1
+  "\07\05\01"                          ;; Export section with 1 entry
+  "\01f\00\00"                         ;; "f" function 0
+  "\0a\0a\01"                          ;; Code section with 1 entry
+  "\08\00"                             ;; Function 0, no locals
+  "\41\00"                             ;; i32.const 0
+  "\28\42\01\00"                       ;; i32.load align=4 memory 1 offset=0
+  "\0b"                                ;; end
+  "\0b\08\01"                          ;; Data section with 1 entry
+  "\02\01\41\00\0b\01\2a"              ;; memory 1, i32.const 0, "\2a"
+)
+
+(assert_return (invoke "f") (i32.const 42))
+
+;; A memory index with more than one byte
+(module binary
+  "\00asm" "\01\00\00\00"
+  "\01\05\01"                          ;; Type section with 1 entry
+  "\60\00\01\7f"                       ;; [] -> [i32]
+  "\03\02\01\00"                       ;; Function section with 1 entry
+  "\05\05\02"                          ;; Memory section with 2 entries
+  "\00\00"                             ;; no max, minimum 0
+  "\00\01"                             ;; no max, minimum 1
+  "\07\05\01"                          ;; Export section with 1 entry
+  "\01f\00\00"                         ;; "f" function 0
+  "\0a\0d\01"                          ;; Code section with 1 entry
+  "\0b\00"                             ;; Function 0, no locals
+  "\41\00"                             ;; i32.const 0
+  "\28\42\81\80\80\00\00"              ;; i32.load align=4 memory 1 (padded) offset=0
+  "\0b"                                ;; end
+)
+
+(assert_return (invoke "f") (i32.const 0))
+
+(assert_invalid
+  (module binary
+    "\00asm" "\01\00\00\00"
+    "\01\04\01\60\00\00"               ;; Type section: [] -> []
+    "\03\02\01\00"                     ;; Function section
+    "\05\03\01\00\01"                  ;; Memory section with 1 entry
+    "\0a\0b\01"                        ;; Code section
+    "\09\00"                           ;; Function 0, no locals
+    "\41\00"                           ;; i32.const 0
+    "\28\42\01\00"                     ;; i32.load align=4 memory 1 offset=0
+    "\1a"                              ;; drop
+    "\0b"                              ;; end
+  )
+  "unknown memory 1"
+)
@@ -0,0 +1,116 @@
+;; Test the data section
+
+;; Syntax
+
+(module
+  (memory $mem0 1)
+  (memory $mem1 1)
+  (memory $mem2 1)
+
+  (data (i32.const 0))
+  (data (i32.const 1) "a" "" "bcd")
+  (data (offset (i32.const 0)))
+  (data (offset (i32.const 0)) "" "a" "bc" "")
+  (data (memory 0) (i32.const 0))
+  (data (memory 0x0) (i32.const 1) "a" "" "bcd")
+  (data (memory 0x000) (offset (i32.const 0)))
+  (data (memory 0) (offset (i32.const 0)) "" "a" "bc" "")
+  (data (memory $mem0) (i32.const 0))
+  (data (memory $mem1) (i32.const 1) "a" "" "bcd")
+  (data (memory $mem2) (offset (i32.const 0)))
+  (data (memory $mem0) (offset (i32.const 0)) "" "a" "bc" "")
+  (data $d1 (i32.const 0))
+  (data $d2 (i32.const 1) "a" "" "bcd")
+  (data $d3 (offset (i32.const 0)))
+  (data $d4 (offset (i32.const 0)) "" "a" "bc" "")
+  (data $d5 (memory 0) (i32.const 0))
+  (data $d6 (memory 0x0) (i32.const 1) "a" "" "bcd")
+  (data $d7 (memory 0x000) (offset (i32.const 0)))
+  (data $d8 (memory 0) (offset (i32.const 0)) "" "a" "bc" "")
+  (data $d9 (memory $mem0) (i32.const 0))
+  (data $d10 (memory $mem1) (i32.const 1) "a" "" "bcd")
+  (data $d11 (memory $mem2) (offset (i32.const 0)))
+  (data $d12 (memory $mem0) (offset (i32.const 0)) "" "a" "bc" "")
+)
+
+;; Active segments of each memory
+
+(module
+  (memory $m0 1)
+  (memory $m1 1)
+  (memory $m2 2)
+  (data (memory $m0) (i32.const 0) "\00")
+  (data (memory $m1) (i32.const 0xffff) "\01")
+  (data (memory $m2) (i32.const 0x1ffff) "\02")
+
+  (func (export "load0") (param i32) (result i32) (i32.load8_u $m0 (local.get 0)))
+  (func (export "load1") (param i32) (result i32) (i32.load8_u $m1 (local.get 0)))
+  (func (export "load2") (param i32) (result i32) (i32.load8_u $m2 (local.get 0)))
+)
+
+(assert_return (invoke "load0" (i32.const 0)) (i32.const 0))
+(assert_return (invoke "load1" (i32.const 0xffff)) (i32.const 1))
+(assert_return (invoke "load2" (i32.const 0x1ffff)) (i32.const 2))
+(assert_return (invoke "load2" (i32.const 0xffff)) (i32.const 0))
+
+;; Imported memories
+
+(module $M
+  (memory (export "mem0") 1)
+  (memory (export "mem1") 1)
+
+  (func (export "load1") (param i32) (result i32) (i32.load8_u 1 (local.get 0)))
+)
+(register "M")
+
+(module
+  (memory $m0 (import "M" "mem0") 1)
+  (memory $m1 (import "M" "mem1") 1)
+  (memory $m2 1)
+  (data (memory $m1) (i32.const 42) "\2a")
+  (data (memory $m2) (i32.const 42) "\2b")
+
+  (func (export "load2") (param i32) (result i32) (i32.load8_u $m2 (local.get 0)))
+)
+
+(assert_return (invoke $M "load1" (i32.const 42)) (i32.const 42))
+(assert_return (invoke "load2" (i32.const 42)) (i32.const 43))
+
+;; Out of bounds segments fail to instantiate
+
+(assert_trap
+  (module
+    (memory 1)
+    (memory 0)
+    (data (memory 1) (i32.const 0) "a")
+  )
+  "out of bounds memory access"
+)
+
+(assert_trap
+  (module
+    (memory 0)
+    (memory 1)
+    (data (memory 1) (i32.const 0x10000) "a")
+  )
+  "out of bounds memory access"
+)
+
+;; Invalid memory index
+
+(assert_invalid
+  (module
+    (memory 1)
+    (data (memory 1) (i32.const 0) "")
+  )
+  "unknown memory 1"
+)
+
+(assert_invalid
+  (module
+    (memory 1)
+    (memory 1)
+    (data (memory 2) (i32.const 0) "")
+  )
+  "unknown memory 2"
+)
@@ -0,0 +1,38 @@
+;; Memories
+
+(module (memory 0) (memory 0) (export "a" (memory 0)) (export "b" (memory 1)))
+(module (memory $m0 0) (memory $m1 0) (export "a" (memory $m0)) (export "b" (memory $m1)))
+(module (memory 0) (memory 0) (export "a" (memory 0)) (export "b" (memory 0)))
+(module (memory $m0 0) (memory $m1 0) (export "a" (memory $m1)) (export "b" (memory $m1)))
+(module (memory (export "a") 0) (memory (export "b") 1))
+
+(assert_invalid
+  (module (memory 0) (export "a" (memory 1)))
+  "unknown memory"
+)
+(assert_invalid
+  (module (memory 0) (memory 0) (export "a" (memory 0)) (export "a" (memory 1)))
+  "duplicate export name"
+)
+
+(module $M
+  (memory (export "a") 1)
+  (memory (export "b") 2)
+  (memory (export "c") 3)
+  (func (export "size-a") (result i32) (memory.size 0))
+  (func (export "size-c") (result i32) (memory.size 2))
+)
+(register "M")
+
+(module
+  (memory $a (import "M" "a") 1)
+  (memory $c (import "M" "c") 3)
+  (memory $b (import "M" "b") 2)
+  (func (export "grow-c") (param i32) (result i32) (memory.grow $c (local.get 0)))
+  (func (export "size-b") (result i32) (memory.size $b))
+)
+
+(assert_return (invoke "grow-c" (i32.const 1)) (i32.const 3))
+(assert_return (invoke "size-b") (i32.const 2))
+(assert_return (invoke $M "size-c") (i32.const 4))
+(assert_return (invoke $M "size-a") (i32.const 1))
@@ -0,0 +1,55 @@
+(module
+  (memory (export "memory-2-inf") 2)
+  (memory (export "memory-2-4") 2 4)
+)
+(register "test")
+
+(module
+  (import "test" "memory-2-inf" (memory 2))
+  (import "test" "memory-2-4" (memory 2 4))
+)
+
+(module
+  (import "test" "memory-2-4" (memory $m1 1))
+  (import "test" "memory-2-inf" (memory $m2 1))
+  (memory $m3 1)
+  (data (memory $m1) (i32.const 10) "\10")
+  (data (memory $m2) (i32.const 10) "\20")
+  (data (memory $m3) (i32.const 10) "\30")
+
+  (func (export "load1") (param i32) (result i32) (i32.load8_u $m1 (local.get 0)))
+  (func (export "load2") (param i32) (result i32) (i32.load8_u $m2 (local.get 0)))
+  (func (export "load3") (param i32) (result i32) (i32.load8_u $m3 (local.get 0)))
+  (func (export "grow1") (param i32) (result i32) (memory.grow $m1 (local.get 0)))
+  (func (export "grow2") (param i32) (result i32) (memory.grow $m2 (local.get 0)))
+  (func (export "size1") (result i32) (memory.size $m1))
+  (func (export "size2") (result i32) (memory.size $m2))
+)
+
+(assert_return (invoke "load1" (i32.const 10)) (i32.const 16))
+(assert_return (invoke "load2" (i32.const 10)) (i32.const 32))
+(assert_return (invoke "load3" (i32.const 10)) (i32.const 48))
+(assert_return (invoke "size1") (i32.const 2))
+(assert_return (invoke "size2") (i32.const 2))
+(assert_return (invoke "grow1" (i32.const 2)) (i32.const 2))
+(assert_return (invoke "grow1" (i32.const 1)) (i32.const -1))
+(assert_return (invoke "grow2" (i32.const 3)) (i32.const 2))
+(assert_return (invoke "size1") (i32.const 4))
+(assert_return (invoke "size2") (i32.const 5))
+
+(assert_unlinkable
+  (module (import "test" "memory-2-4" (memory 1)) (import "test" "unknown" (memory 1)))
+  "unknown import"
+)
+(assert_unlinkable
+  (module (import "test" "memory-2-inf" (memory 1)) (import "test" "memory-2-4" (memory 5)))
+  "incompatible import type"
+)
+(assert_unlinkable
+  (module (import "test" "memory-2-4" (memory 1)) (import "test" "memory-2-inf" (memory 1 4)))
+  "incompatible import type"
+)
+(assert_unlinkable
+  (module (import "test" "memory-2-inf" (memory 1)) (import "test" "memory-2-4" (memory 2 3)))
+  "incompatible import type"
+)
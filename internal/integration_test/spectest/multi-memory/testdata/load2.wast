@@ -0,0 +1,77 @@
+;; Loads of every width from the non-zero memories
+
+(module
+  (memory 0)
+  (memory 0)
+  (memory 0)
+  (memory $m 1)
+
+  (data (memory $m) (i32.const 0) "\80\81\82\83\84\85\86\87\88\89\8a\8b\8c\8d\8e\8f")
+
+  (func (export "i32_load8_s") (param $i i32) (result i32)
+    (i32.load8_s $m (local.get $i))
+  )
+  (func (export "i32_load8_u") (param $i i32) (result i32)
+    (i32.load8_u $m (local.get $i))
+  )
+  (func (export "i32_load16_s") (param $i i32) (result i32)
+    (i32.load16_s $m (local.get $i))
+  )
+  (func (export "i32_load16_u") (param $i i32) (result i32)
+    (i32.load16_u $m (local.get $i))
+  )
+  (func (export "i32_load") (param $i i32) (result i32)
+    (i32.load $m (local.get $i))
+  )
+  (func (export "i64_load8_s") (param $i i32) (result i64)
+    (i64.load8_s $m (local.get $i))
+  )
+  (func (export "i64_load8_u") (param $i i32) (result i64)
+    (i64.load8_u $m (local.get $i))
+  )
+  (func (export "i64_load16_s") (param $i i32) (result i64)
+    (i64.load16_s $m (local.get $i))
+  )
+  (func (export "i64_load16_u") (param $i i32) (result i64)
+    (i64.load16_u $m (local.get $i))
+  )
+  (func (export "i64_load32_s") (param $i i32) (result i64)
+    (i64.load32_s $m (local.get $i))
+  )
+  (func (export "i64_load32_u") (param $i i32) (result i64)
+    (i64.load32_u $m (local.get $i))
+  )
+  (func (export "i64_load") (param $i i32) (result i64)
+    (i64.load $m (local.get $i))
+  )
+  (func (export "f32_load") (param $i i32) (result f32)
+    (f32.load $m (local.get $i))
+  )
+  (func (export "f64_load") (param $i i32) (result f64)
+    (f64.load $m (local.get $i))
+  )
+  (func (export "i32_load_offset") (param $i i32) (result i32)
+    (i32.load $m offset=4 align=2 (local.get $i))
+  )
+)
+
+(assert_return (invoke "i32_load8_s" (i32.const 0)) (i32.const -128))
+(assert_return (invoke "i32_load8_u" (i32.const 0)) (i32.const 128))
+(assert_return (invoke "i32_load16_s" (i32.const 0)) (i32.const -32384))
+(assert_return (invoke "i32_load16_u" (i32.const 0)) (i32.const 33152))
+(assert_return (invoke "i32_load" (i32.const 0)) (i32.const 0x83828180))
+(assert_return (invoke "i64_load8_s" (i32.const 1)) (i64.const -127))
+(assert_return (invoke "i64_load8_u" (i32.const 1)) (i64.const 129))
+(assert_return (invoke "i64_load16_s" (i32.const 2)) (i64.const -31870))
+(assert_return (invoke "i64_load16_u" (i32.const 2)) (i64.const 33666))
+(assert_return (invoke "i64_load32_s" (i32.const 4)) (i64.const -2021227132))
+(assert_return (invoke "i64_load32_u" (i32.const 4)) (i64.const 0x87868584))
+(assert_return (invoke "i64_load" (i32.const 8)) (i64.const 0x8f8e8d8c8b8a8988))
+(assert_return (invoke "f32_load" (i32.const 0)) (f32.const -0x1.0503p-120))
+(assert_return (invoke "f64_load" (i32.const 8)) (f64.const -0x1.e8d8c8b8a8988p-775))
+(assert_return (invoke "i32_load_offset" (i32.const 0)) (i32.const 0x87868584))
+
+(assert_trap (invoke "i32_load" (i32.const 65533)) "out of bounds memory access")
+(assert_trap (invoke "i64_load" (i32.const 65529)) "out of bounds memory access")
+(assert_trap (invoke "i32_load_offset" (i32.const 65529)) "out of bounds memory access")
+(assert_trap (invoke "i32_load_offset" (i32.const -1)) "out of bounds memory access")
@@ -0,0 +1,107 @@
+;; memory.copy within and between memories
+
+(module
+  (memory $mem0 1)
+  (memory $mem1 1)
+  (memory $mem2 1)
+
+  (data (memory $mem2) (i32.const 2) "\03\01\04\01")
+  (data (memory $mem2) (i32.const 12) "\07\05\02\03\06")
+
+  (func (export "copy-2-0") (param i32 i32 i32)
+    (memory.copy $mem0 $mem2 (local.get 0) (local.get 1) (local.get 2))
+  )
+  (func (export "copy-0-1") (param i32 i32 i32)
+    (memory.copy $mem1 $mem0 (local.get 0) (local.get 1) (local.get 2))
+  )
+  (func (export "copy-2-2") (param i32 i32 i32)
+    (memory.copy $mem2 $mem2 (local.get 0) (local.get 1) (local.get 2))
+  )
+  (func (export "load0") (param i32) (result i32) (i32.load8_u $mem0 (local.get 0)))
+  (func (export "load1") (param i32) (result i32) (i32.load8_u $mem1 (local.get 0)))
+  (func (export "load2") (param i32) (result i32) (i32.load8_u $mem2 (local.get 0)))
+)
+
+(invoke "copy-2-0" (i32.const 10) (i32.const 2) (i32.const 4))
+(assert_return (invoke "load0" (i32.const 9)) (i32.const 0))
+(assert_return (invoke "load0" (i32.const 10)) (i32.const 3))
+(assert_return (invoke "load0" (i32.const 11)) (i32.const 1))
+(assert_return (invoke "load0" (i32.const 12)) (i32.const 4))
+(assert_return (invoke "load0" (i32.const 13)) (i32.const 1))
+(assert_return (invoke "load0" (i32.const 14)) (i32.const 0))
+(assert_return (invoke "load1" (i32.const 10)) (i32.const 0))
+(assert_return (invoke "load2" (i32.const 10)) (i32.const 0))
+
+(invoke "copy-0-1" (i32.const 0xfffc) (i32.const 10) (i32.const 4))
+(assert_return (invoke "load1" (i32.const 0xfffb)) (i32.const 0))
+(assert_return (invoke "load1" (i32.const 0xfffc)) (i32.const 3))
+(assert_return (invoke "load1" (i32.const 0xfffd)) (i32.const 1))
+(assert_return (invoke "load1" (i32.const 0xfffe)) (i32.const 4))
+(assert_return (invoke "load1" (i32.const 0xffff)) (i32.const 1))
+
+;; Overlapping copies within the same memory.
+(invoke "copy-2-2" (i32.const 13) (i32.const 12) (i32.const 5))
+(assert_return (invoke "load2" (i32.const 12)) (i32.const 7))
+(assert_return (invoke "load2" (i32.const 13)) (i32.const 7))
+(assert_return (invoke "load2" (i32.const 14)) (i32.const 5))
+(assert_return (invoke "load2" (i32.const 15)) (i32.const 2))
+(assert_return (invoke "load2" (i32.const 16)) (i32.const 3))
+(assert_return (invoke "load2" (i32.const 17)) (i32.const 6))
+(invoke "copy-2-2" (i32.const 12) (i32.const 13) (i32.const 5))
+(assert_return (invoke "load2" (i32.const 12)) (i32.const 7))
+(assert_return (invoke "load2" (i32.const 13)) (i32.const 5))
+(assert_return (invoke "load2" (i32.const 16)) (i32.const 6))
+(assert_return (invoke "load2" (i32.const 17)) (i32.const 6))
+
+;; Out of bounds of either memory, with nothing copied.
+(assert_trap (invoke "copy-2-0" (i32.const 0xfffe) (i32.const 2) (i32.const 4))
+  "out of bounds memory access")
+(assert_trap (invoke "copy-2-0" (i32.const 0) (i32.const 0xfffe) (i32.const 4))
+  "out of bounds memory access")
+(assert_return (invoke "load0" (i32.const 0xfffe)) (i32.const 0))
+(assert_return (invoke "load0" (i32.const 0)) (i32.const 0))
+
+;; Zero-length copies at the end of the memories.
+(invoke "copy-2-0" (i32.const 0x10000) (i32.const 0x10000) (i32.const 0))
+(assert_trap (invoke "copy-2-0" (i32.const 0x10001) (i32.const 0) (i32.const 0))
+  "out of bounds memory access")
+(assert_trap (invoke "copy-2-0" (i32.const 0) (i32.const 0x10001) (i32.const 0))
+  "out of bounds memory access")
+
+(module
+  (memory $small 1)
+  (memory $big 2)
+
+  (func (export "copy-big-small") (param i32 i32 i32)
+    (memory.copy $small $big (local.get 0) (local.get 1) (local.get 2))
+  )
+  (func (export "copy-small-big") (param i32 i32 i32)
+    (memory.copy $big $small (local.get 0) (local.get 1) (local.get 2))
+  )
+  (func (export "store-big") (param i32 i32) (i32.store8 $big (local.get 0) (local.get 1)))
+  (func (export "load-small") (param i32) (result i32) (i32.load8_u $small (local.get 0)))
+  (func (export "load-big") (param i32) (result i32) (i32.load8_u $big (local.get 0)))
+)
+
+(invoke "store-big" (i32.const 0x1ffff) (i32.const 7))
+(invoke "copy-big-small" (i32.const 0xffff) (i32.const 0x1ffff) (i32.const 1))
+(assert_return (invoke "load-small" (i32.const 0xffff)) (i32.const 7))
+(assert_trap (invoke "copy-big-small" (i32.const 0x10000) (i32.const 0x1ffff) (i32.const 1))
+  "out of bounds memory access")
+(invoke "copy-small-big" (i32.const 0x1fffe) (i32.const 0xfffe) (i32.const 2))
+(assert_return (invoke "load-big" (i32.const 0x1ffff)) (i32.const 7))
+
+(assert_invalid
+  (module
+    (memory 1)
+    (func (memory.copy 0 1 (i32.const 0) (i32.const 0) (i32.const 0)))
+  )
+  "unknown memory 1"
+)
+(assert_invalid
+  (module
+    (memory 1)
+    (func (memory.copy 1 0 (i32.const 0) (i32.const 0) (i32.const 0)))
+  )
+  "unknown memory 1"
+)
@@ -0,0 +1,42 @@
+;; Grow each of several memories independently
+
+(module
+  (memory $mem1 0)
+  (memory $mem2 1 3)
+  (memory $mem3 0 0)
+
+  (func (export "grow1") (param i32) (result i32) (memory.grow $mem1 (local.get 0)))
+  (func (export "grow2") (param i32) (result i32) (memory.grow $mem2 (local.get 0)))
+  (func (export "grow3") (param i32) (result i32) (memory.grow $mem3 (local.get 0)))
+  (func (export "size1") (result i32) (memory.size $mem1))
+  (func (export "size2") (result i32) (memory.size $mem2))
+  (func (export "size3") (result i32) (memory.size $mem3))
+
+  (func (export "load1") (param i32) (result i32) (i32.load8_u $mem1 (local.get 0)))
+  (func (export "load2") (param i32) (result i32) (i32.load8_u $mem2 (local.get 0)))
+  (func (export "store2") (param i32 i32) (i32.store8 $mem2 (local.get 0) (local.get 1)))
+)
+
+(assert_return (invoke "size1") (i32.const 0))
+(assert_return (invoke "size2") (i32.const 1))
+(assert_return (invoke "size3") (i32.const 0))
+(assert_trap (invoke "load1" (i32.const 0)) "out of bounds memory access")
+(assert_return (invoke "grow1" (i32.const 1)) (i32.const 0))
+(assert_return (invoke "load1" (i32.const 0)) (i32.const 0))
+(assert_return (invoke "load1" (i32.const 0xffff)) (i32.const 0))
+(assert_trap (invoke "load1" (i32.const 0x10000)) "out of bounds memory access")
+(assert_return (invoke "size1") (i32.const 1))
+(assert_return (invoke "size2") (i32.const 1))
+
+(invoke "store2" (i32.const 0xffff) (i32.const 42))
+(assert_trap (invoke "load2" (i32.const 0x10000)) "out of bounds memory access")
+(assert_return (invoke "grow2" (i32.const 2)) (i32.const 1))
+(assert_return (invoke "load2" (i32.const 0xffff)) (i32.const 42))
+(assert_return (invoke "load2" (i32.const 0x10000)) (i32.const 0))
+(assert_return (invoke "grow2" (i32.const 1)) (i32.const -1))
+(assert_return (invoke "size2") (i32.const 3))
+(assert_return (invoke "size1") (i32.const 1))
+
+(assert_return (invoke "grow3" (i32.const 0)) (i32.const 0))
+(assert_return (invoke "grow3" (i32.const 1)) (i32.const -1))
+(assert_return (invoke "size3") (i32.const 0))
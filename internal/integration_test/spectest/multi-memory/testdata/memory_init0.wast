@@ -0,0 +1,57 @@
+(module
+  (memory $mem0 0)
+  (memory $mem1 0)
+  (memory $mem2 1)
+  (memory $mem3 0)
+  (data $d (memory $mem2) (i32.const 0) "\aa\bb\cc\dd")
+  (data $p "\01\02\03\04")
+
+  (func (export "init") (param i32 i32 i32)
+    (memory.init $mem2 $p (local.get 0) (local.get 1) (local.get 2))
+  )
+  (func (export "drop")
+    (data.drop $p)
+  )
+  (func (export "load8_u") (param i32) (result i32)
+    (i32.load8_u $mem2 (local.get 0))
+  )
+)
+
+(assert_return (invoke "load8_u" (i32.const 0)) (i32.const 0xaa))
+(assert_return (invoke "load8_u" (i32.const 3)) (i32.const 0xdd))
+(invoke "init" (i32.const 2) (i32.const 0) (i32.const 4))
+(assert_return (invoke "load8_u" (i32.const 1)) (i32.const 0xbb))
+(assert_return (invoke "load8_u" (i32.const 2)) (i32.const 1))
+(assert_return (invoke "load8_u" (i32.const 5)) (i32.const 4))
+(assert_return (invoke "load8_u" (i32.const 6)) (i32.const 0))
+
+;; Init ending at memory limit and segment limit with offset.
+(invoke "init" (i32.const 0xfffc) (i32.const 1) (i32.const 3))
+(assert_return (invoke "load8_u" (i32.const 0xfffc)) (i32.const 2))
+(assert_return (invoke "load8_u" (i32.const 0xfffe)) (i32.const 4))
+(assert_return (invoke "load8_u" (i32.const 0xffff)) (i32.const 0))
+
+;; Out of bounds of the memory or of the segment.
+(assert_trap (invoke "init" (i32.const 0xfffe) (i32.const 0) (i32.const 3))
+    "out of bounds memory access")
+(assert_trap (invoke "init" (i32.const 0) (i32.const 2) (i32.const 3))
+    "out of bounds memory access")
+
+;; Succeed when writing 0 bytes at the end of either region.
+(invoke "init" (i32.const 0x10000) (i32.const 0) (i32.const 0))
+(invoke "init" (i32.const 0) (i32.const 4) (i32.const 0))
+
+;; A dropped segment has length zero.
+(invoke "drop")
+(invoke "init" (i32.const 0) (i32.const 0) (i32.const 0))
+(assert_trap (invoke "init" (i32.const 0) (i32.const 0) (i32.const 1))
+    "out of bounds memory access")
+
+(assert_invalid
+  (module
+    (memory 1)
+    (data "\01")
+    (func (memory.init 1 0 (i32.const 0) (i32.const 0) (i32.const 0)))
+  )
+  "unknown memory 1"
+)
@@ -0,0 +1,72 @@
+(module
+  (memory 0)
+  (memory 0)
+  (memory $m 0)
+  (memory 0)
+
+  (func (export "size") (result i32) (memory.size $m))
+  (func (export "grow") (param $sz i32) (drop (memory.grow $m (local.get $sz))))
+)
+
+(assert_return (invoke "size") (i32.const 0))
+(assert_return (invoke "grow" (i32.const 1)))
+(assert_return (invoke "size") (i32.const 1))
+(assert_return (invoke "grow" (i32.const 4)))
+(assert_return (invoke "size") (i32.const 5))
+(assert_return (invoke "grow" (i32.const 0)))
+(assert_return (invoke "size") (i32.const 5))
+
+(module
+  (memory 0)
+  (memory $m 1)
+  (memory 0)
+
+  (func (export "size") (result i32) (memory.size $m))
+  (func (export "size0") (result i32) (memory.size 0))
+  (func (export "size2") (result i32) (memory.size 2))
+  (func (export "grow") (param $sz i32) (drop (memory.grow $m (local.get $sz))))
+)
+
+(assert_return (invoke "size") (i32.const 1))
+(assert_return (invoke "grow" (i32.const 1)))
+(assert_return (invoke "size") (i32.const 2))
+(assert_return (invoke "size0") (i32.const 0))
+(assert_return (invoke "size2") (i32.const 0))
+
+(module
+  (memory 0)
+  (memory 0)
+  (memory $m 0 2)
+
+  (func (export "size") (result i32) (memory.size $m))
+  (func (export "grow") (param $sz i32) (drop (memory.grow $m (local.get $sz))))
+)
+
+(assert_return (invoke "size") (i32.const 0))
+(assert_return (invoke "grow" (i32.const 3)))
+(assert_return (invoke "size") (i32.const 0))
+(assert_return (invoke "grow" (i32.const 1)))
+(assert_return (invoke "size") (i32.const 1))
+(assert_return (invoke "grow" (i32.const 0)))
+(assert_return (invoke "size") (i32.const 1))
+(assert_return (invoke "grow" (i32.const 4)))
+(assert_return (invoke "size") (i32.const 1))
+(assert_return (invoke "grow" (i32.const 1)))
+(assert_return (invoke "size") (i32.const 2))
+
+(assert_invalid
+  (module
+    (memory 1)
+    (func $type-result-i32-vs-empty
+      (memory.size 0)
+    )
+  )
+  "type mismatch"
+)
+(assert_invalid
+  (module
+    (memory 1)
+    (func (result i32) (memory.size 1))
+  )
+  "unknown memory"
+)
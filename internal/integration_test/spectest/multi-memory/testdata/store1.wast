@@ -0,0 +1,41 @@
+;; Stores of every width to the non-zero memories
+
+(module
+  (memory $m0 1)
+  (memory $m1 1)
+
+  (func (export "i32_store8") (param i32 i32) (i32.store8 $m1 (local.get 0) (local.get 1)))
+  (func (export "i32_store16") (param i32 i32) (i32.store16 $m1 (local.get 0) (local.get 1)))
+  (func (export "i32_store") (param i32 i32) (i32.store $m1 (local.get 0) (local.get 1)))
+  (func (export "i64_store8") (param i32 i64) (i64.store8 $m1 (local.get 0) (local.get 1)))
+  (func (export "i64_store16") (param i32 i64) (i64.store16 $m1 (local.get 0) (local.get 1)))
+  (func (export "i64_store32") (param i32 i64) (i64.store32 $m1 (local.get 0) (local.get 1)))
+  (func (export "f32_store") (param i32 f32) (f32.store $m1 (local.get 0) (local.get 1)))
+  (func (export "f64_store") (param i32 f64) (f64.store $m1 offset=8 (local.get 0) (local.get 1)))
+
+  (func (export "load0") (param i32) (result i64) (i64.load $m0 (local.get 0)))
+  (func (export "load1") (param i32) (result i64) (i64.load $m1 (local.get 0)))
+)
+
+(invoke "i32_store8" (i32.const 0) (i32.const 0x1ff))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0xff))
+(invoke "i32_store16" (i32.const 0) (i32.const 0x1abcd))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0xabcd))
+(invoke "i32_store" (i32.const 0) (i32.const 0x12345678))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0x12345678))
+(invoke "i64_store8" (i32.const 4) (i64.const 0x1ff))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0xff12345678))
+(invoke "i64_store16" (i32.const 4) (i64.const 0x1abcd))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0xabcd12345678))
+(invoke "i64_store32" (i32.const 4) (i64.const 0x1deadbeef))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0xdeadbeef12345678))
+(invoke "f32_store" (i32.const 0) (f32.const 1.0))
+(assert_return (invoke "load1" (i32.const 0)) (i64.const 0xdeadbeef3f800000))
+(invoke "f64_store" (i32.const 0) (f64.const -2.0))
+(assert_return (invoke "load1" (i32.const 8)) (i64.const 0xc000000000000000))
+
+(assert_return (invoke "load0" (i32.const 0)) (i64.const 0))
+(assert_return (invoke "load0" (i32.const 8)) (i64.const 0))
+
+(assert_trap (invoke "i32_store" (i32.const 65533) (i32.const 0)) "out of bounds memory access")
+(assert_trap (invoke "f64_store" (i32.const 65528) (f64.const 0)) "out of bounds memory access")
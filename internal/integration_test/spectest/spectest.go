@@ -4,6 +4,7 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/moremath"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -36,7 +38,7 @@ type (
 		// Set when type == "register"
 		As string `json:"as,omitempty"`
 
-		// Set when type == "assert_return" || "action"
+		// Set when type == "assert_return" || "action" || "assert_exception"
 		Action commandAction      `json:"action,omitempty"`
 		Exps   []commandActionVal `json:"expected"`
 
@@ -114,6 +116,14 @@ func (c commandActionVal) String() string {
 	case "funcref":
 		// All the in and out funcref params are null in spectest (cannot represent non-null as it depends on runtime impl).
 		v = "null"
+	case "exnref":
+		v = "null"
+	case "anyref":
+		// Only the null anyref can be represented, and the others match any reference.
+		v = "null"
+		if c.Value == nil {
+			v = "*"
+		}
 	case "v128":
 		simdValues, ok := c.Value.([]interface{})
 		if !ok {
@@ -151,6 +161,8 @@ func (c command) String() string {
 		// TODO:
 	case "assert_trap":
 		msg += fmt.Sprintf(", args: %v, error text:  %s", c.Action.Args, c.Text)
+	case "assert_exception":
+		msg += fmt.Sprintf(", field: %s, args: %v", c.Action.Field, c.Action.Args)
 	case "assert_invalid":
 		// TODO:
 	case "assert_exhaustion":
@@ -260,6 +272,9 @@ func getNaNBits(strValue string, is32bit bool) (ret uint64) {
 }
 
 func (c commandActionVal) toUint64() (ret uint64) {
+	if c.Value == nil || c.Value == "null" {
+		return 0 // a null reference, or any reference when nil.
+	}
 	strValue := c.Value.(string)
 	if strings.Contains(strValue, "nan") {
 		ret = getNaNBits(strValue, c.ValType == "f32")
@@ -272,6 +287,13 @@ func (c commandActionVal) toUint64() (ret uint64) {
 			// So in order to treat "externref 0" in spectest non nullref, we increment the value.
 			ret = original + 1
 		}
+	} else if strings.HasPrefix(strValue, "-") {
+		// wasm-tools writes integers as signed decimals, unlike wast2json.
+		v, _ := strconv.ParseInt(strValue, 10, 64)
+		ret = uint64(v)
+		if strings.Contains(c.ValType, "32") {
+			ret = uint64(uint32(v))
+		}
 	} else if strings.Contains(c.ValType, "32") {
 		ret, _ = strconv.ParseUint(strValue, 10, 32)
 	} else {
@@ -305,6 +327,15 @@ func (c command) expectedError() (err error) {
 		err = wasmruntime.ErrRuntimeUnalignedAtomic
 	case "unreachable":
 		err = wasmruntime.ErrRuntimeUnreachable
+	case "null exception reference", "null function reference", "null reference",
+		"null structure reference", "null array reference", "null i31 reference":
+		err = wasmruntime.ErrRuntimeNullReference
+	case "cast failure":
+		err = wasmruntime.ErrRuntimeCastFailure
+	case "out of bounds array access":
+		err = wasmruntime.ErrRuntimeArrayOutOfBounds
+	case "allocation too large":
+		err = wasmruntime.ErrRuntimeAllocationTooLarge
 	default:
 		if strings.HasPrefix(c.Text, "uninitialized") {
 			err = wasmruntime.ErrRuntimeInvalidTableAccess
@@ -324,9 +355,27 @@ func (c command) expectedError() (err error) {
 //go:embed testdata/spectest.wasm
 var spectestWasm []byte
 
+// Skips are the spectest commands known to fail, mapped to the reason why. The key is either the name of a wast file,
+// e.g. "table64.wast", to skip all of its commands, or the name and the line of a command, e.g. "tag.wast:42".
+type Skips map[string]string
+
+// reason returns the reason to skip the command at the line of the wast file, or false if it is run.
+func (s Skips) reason(wastName string, line int) (string, bool) {
+	if reason, ok := s[wastName]; ok {
+		return reason, true
+	}
+	reason, ok := s[fmt.Sprintf("%s:%d", wastName, line)]
+	return reason, ok
+}
+
 // Run runs all the test inside the testDataFS file system where all the cases are described
 // via JSON files created from wast2json.
 func Run(t *testing.T, testDataFS embed.FS, ctx context.Context, config wazero.RuntimeConfig) {
+	RunWithSkips(t, testDataFS, ctx, config, nil)
+}
+
+// RunWithSkips is like Run, except the commands in skips are skipped.
+func RunWithSkips(t *testing.T, testDataFS embed.FS, ctx context.Context, config wazero.RuntimeConfig, skips Skips) {
 	files, err := testDataFS.ReadDir("testdata")
 	require.NoError(t, err)
 
@@ -343,7 +392,7 @@ func Run(t *testing.T, testDataFS embed.FS, ctx context.Context, config wazero.R
 	require.True(t, len(caseNames) > 0, "len(caseNames)=%d (not greater than zero)", len(caseNames))
 
 	for _, f := range caseNames {
-		RunCase(t, testDataFS, f, ctx, config, -1, 0, math.MaxInt)
+		runCase(t, testDataFS, f, ctx, config, -1, 0, math.MaxInt, skips)
 	}
 }
 
@@ -358,6 +407,10 @@ func Run(t *testing.T, testDataFS embed.FS, ctx context.Context, config wazero.R
 // we only want to run specific command while running "module" command to instantiate a module. If you don't need it,
 // just pass -1.
 func RunCase(t *testing.T, testDataFS embed.FS, f string, ctx context.Context, config wazero.RuntimeConfig, mandatoryLine, lineBegin, lineEnd int) {
+	runCase(t, testDataFS, f, ctx, config, mandatoryLine, lineBegin, lineEnd, nil)
+}
+
+func runCase(t *testing.T, testDataFS embed.FS, f string, ctx context.Context, config wazero.RuntimeConfig, mandatoryLine, lineBegin, lineEnd int, skips Skips) {
 	raw, err := testDataFS.ReadFile(testdataPath(f + ".json"))
 	require.NoError(t, err)
 
@@ -385,6 +438,12 @@ func RunCase(t *testing.T, testDataFS embed.FS, f string, ctx context.Context, c
 				continue
 			}
 			t.Run(fmt.Sprintf("%s/line:%d", c.CommandType, c.Line), func(t *testing.T) {
+				if reason, ok := skips.reason(wastName, c.Line); ok {
+					if c.CommandType == "module" && i+1 < len(base.Commands) && base.Commands[i+1].CommandType == "register" {
+						i++ // Skip the "register" command of the skipped module.
+					}
+					t.Skip(reason)
+				}
 				msg := fmt.Sprintf("%s:%d %s", wastName, c.Line, c.CommandType)
 				switch c.CommandType {
 				case "module":
@@ -419,12 +478,15 @@ func RunCase(t *testing.T, testDataFS embed.FS, f string, ctx context.Context, c
 						require.NoError(t, err, msg)
 						require.Equal(t, len(exps), len(results), msg)
 						laneTypes := map[int]string{}
+						anyRefs := map[int]bool{}
 						for i, expV := range c.Exps {
 							if expV.ValType == "v128" {
 								laneTypes[i] = expV.LaneType
+							} else if expV.Value == nil {
+								anyRefs[i] = true // e.g. (ref.func) without an index matches any non-null reference.
 							}
 						}
-						matched, valuesMsg := valuesEq(results, exps, fn.Definition().ResultTypes(), laneTypes)
+						matched, valuesMsg := valuesEq(results, exps, fn.Definition().ResultTypes(), laneTypes, anyRefs)
 						require.True(t, matched, msg+"\n"+valuesMsg)
 					case "get":
 						_, exps := c.getAssertReturnArgsExps()
@@ -464,6 +526,24 @@ func RunCase(t *testing.T, testDataFS embed.FS, f string, ctx context.Context, c
 					default:
 						t.Fatalf("unsupported action type type: %v", c)
 					}
+				case "assert_exception":
+					m := lastInstantiatedModule
+					if c.Action.Module != "" {
+						m = modules[c.Action.Module]
+					}
+					switch c.Action.ActionType {
+					case "invoke":
+						args := c.getAssertReturnArgs()
+						msg = fmt.Sprintf("%s invoke %s (%s)", msg, c.Action.Field, c.Action.Args)
+						if c.Action.Module != "" {
+							msg += " in module " + c.Action.Module
+						}
+						_, err := m.ExportedFunction(c.Action.Field).Call(ctx, args...)
+						var exc *experimental.Exception
+						require.True(t, errors.As(err, &exc), "%s: expected an exception, but got %v", msg, err)
+					default:
+						t.Fatalf("unsupported action type type: %v", c)
+					}
 				case "assert_invalid":
 					if c.ModuleType == "text" {
 						// We don't support direct loading of wast yet.
@@ -537,21 +617,28 @@ func testdataPath(filename string) string {
 //     we have actual/exp = [(lower-64bit of the first V128), (higher-64bit of the first V128), I32].
 //   - valTypes holds the wasm.ValueType(s) of the original values in Wasm.
 //   - laneTypes maps the index of valueTypes to laneType if valueTypes[i] == wasm.ValueTypeV128.
+//   - anyRefs holds the index of valueTypes whose expected reference is any, so it isn't compared.
 //
 // Also, if matched == false this returns non-empty valuesMsg which can be used to augment the test failure message.
-func valuesEq(actual, exps []uint64, valTypes []wasm.ValueType, laneTypes map[int]laneType) (matched bool, valuesMsg string) {
+func valuesEq(actual, exps []uint64, valTypes []wasm.ValueType, laneTypes map[int]laneType, anyRefs map[int]bool) (matched bool, valuesMsg string) {
 	matched = true
 
 	var msgExpValuesStrs, msgActualValuesStrs []string
 	var uint64RepPos int // the index to actual and exps slice.
 	for i, tp := range valTypes {
+		if anyRefs[i] {
+			msgExpValuesStrs = append(msgExpValuesStrs, "*")
+			msgActualValuesStrs = append(msgActualValuesStrs, fmt.Sprintf("%d", actual[uint64RepPos]))
+			uint64RepPos++
+			continue
+		}
 		switch tp {
 		case wasm.ValueTypeI32:
 			msgExpValuesStrs = append(msgExpValuesStrs, fmt.Sprintf("%d", uint32(exps[uint64RepPos])))
 			msgActualValuesStrs = append(msgActualValuesStrs, fmt.Sprintf("%d", uint32(actual[uint64RepPos])))
 			matched = matched && uint32(exps[uint64RepPos]) == uint32(actual[uint64RepPos])
 			uint64RepPos++
-		case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeAnyref:
 			msgExpValuesStrs = append(msgExpValuesStrs, fmt.Sprintf("%d", exps[uint64RepPos]))
 			msgActualValuesStrs = append(msgActualValuesStrs, fmt.Sprintf("%d", actual[uint64RepPos]))
 			matched = matched && exps[uint64RepPos] == actual[uint64RepPos]
@@ -456,7 +456,7 @@ func Test_valuesEq(t *testing.T) {
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			actualMatched, actualValuesMsg := valuesEq(tc.actual, tc.exps, tc.valueTypes, tc.laneTypes)
+			actualMatched, actualValuesMsg := valuesEq(tc.actual, tc.exps, tc.valueTypes, tc.laneTypes, nil)
 			require.Equal(t, tc.expMatched, actualMatched)
 			require.Equal(t, tc.expValuesMsg, actualValuesMsg)
 		})
@@ -626,3 +626,38 @@ func TestCommand_getAssertReturnArgsExps(t *testing.T) {
 		})
 	}
 }
+
+func TestSkips_reason(t *testing.T) {
+	skips := Skips{
+		"table64.wast": "table64 is not supported",
+		"tag.wast:26":  "recursive types need the gc proposal",
+	}
+
+	tests := []struct {
+		name     string
+		wastName string
+		line     int
+		reason   string
+		skipped  bool
+	}{
+		{name: "file", wastName: "table64.wast", line: 3, reason: "table64 is not supported", skipped: true},
+		{name: "line", wastName: "tag.wast", line: 26, reason: "recursive types need the gc proposal", skipped: true},
+		{name: "other line", wastName: "tag.wast", line: 3},
+		{name: "other file", wastName: "throw.wast", line: 26},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			reason, skipped := skips.reason(tc.wastName, tc.line)
+			require.Equal(t, tc.reason, reason)
+			require.Equal(t, tc.skipped, skipped)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var none Skips
+		_, skipped := none.reason("tag.wast", 26)
+		require.False(t, skipped)
+	})
+}
@@ -10,6 +10,8 @@ const (
 	CpuFeatureAmd64BMI1
 	// CpuExtraFeatureABM is the flag to query CpuFeatureFlags.Has for Advanced Bit Manipulation capabilities (e.g. LZCNT) on amd64
 	CpuFeatureAmd64ABM
+	// CpuFeatureAmd64FMA is the flag to query CpuFeatureFlags.Has for the fused multiply-add instructions (e.g. VFMADD231PS) on amd64
+	CpuFeatureAmd64FMA
 )
 
 const (
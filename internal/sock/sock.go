@@ -1,8 +1,11 @@
 package sock
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
+	"net/netip"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
 )
@@ -26,6 +29,41 @@ type TCPConn interface {
 	Shutdown(how int) sys.Errno
 }
 
+// UDPConn is a pseudo-file representing a UDP socket, which is bound.
+type UDPConn interface {
+	sys.File
+
+	// Connect sets the address datagrams are sent to by Write, as the socket
+	// is otherwise unconnected.
+	Connect(addr netip.AddrPort) sys.Errno
+
+	// RecvFrom receives a datagram, and returns the address it was sent from.
+	RecvFrom(p []byte) (n int, addr netip.AddrPort, errno sys.Errno)
+
+	// SendTo sends a datagram to the address.
+	SendTo(p []byte, addr netip.AddrPort) (n int, errno sys.Errno)
+}
+
+// Socket is a pseudo-file representing a socket opened by the guest, which
+// isn't connected yet, as the net package only creates it once it is.
+type Socket interface {
+	sys.File
+
+	// Network returns the network of the socket: "tcp4", "tcp6", "udp4" or
+	// "udp6".
+	Network() string
+
+	// Bind binds the socket to the local address. A UDP socket is bound
+	// immediately, and the result replaces it. Otherwise, the result is nil,
+	// and the address is the local one of the connection.
+	Bind(addr netip.AddrPort) (UDPConn, sys.Errno)
+
+	// Connect connects the socket to the address, and returns the TCPConn or
+	// UDPConn which replaces it. Connecting a TCP socket blocks until it is
+	// connected or the context is done.
+	Connect(ctx context.Context, addr netip.AddrPort) (sys.File, sys.Errno)
+}
+
 // ConfigKey is a context.Context Value key. Its associated value should be a Config.
 type ConfigKey struct{}
 
@@ -34,6 +72,59 @@ type ConfigKey struct{}
 type Config struct {
 	// TCPAddresses is a slice of the configured host:port pairs.
 	TCPAddresses []TCPAddress
+	// UDPAddresses is a slice of the configured host:port pairs of UDP
+	// sockets.
+	UDPAddresses []TCPAddress
+	// UnixPaths is a slice of the configured paths of Unix domain sockets.
+	UnixPaths []string
+	// Connections is a slice of the configured addresses to connect to.
+	Connections []Connection
+
+	// BindAddresses are the addresses guests are allowed to bind sockets to.
+	BindAddresses []AllowedAddress
+	// ConnectAddresses are the addresses guests are allowed to connect
+	// sockets, or send datagrams, to.
+	ConnectAddresses []AllowedAddress
+	// LookupNames are the names guests are allowed to look up, where the
+	// empty string allows any.
+	LookupNames []string
+}
+
+// AllowedAddress is an address allowed to guests, which is any when a field
+// is the zero value.
+type AllowedAddress struct {
+	// Network is "tcp" or "udp".
+	Network string
+	// Host is an IP address, e.g. "127.0.0.1", or prefix, e.g. "10.0.0.0/8".
+	Host string
+	// Port is the port number.
+	Port int
+}
+
+// allows returns true if the address on the network, e.g. "tcp4", is allowed.
+func (a AllowedAddress) allows(network string, addr netip.AddrPort) bool {
+	if a.Network != "" && a.Network != network[:3] {
+		return false
+	} else if a.Port != 0 && a.Port != int(addr.Port()) {
+		return false
+	} else if a.Host == "" {
+		return true
+	}
+	ip := addr.Addr().Unmap()
+	if prefix, err := netip.ParsePrefix(a.Host); err == nil {
+		return prefix.Contains(ip)
+	} else if host, err := netip.ParseAddr(a.Host); err == nil {
+		return host.Unmap() == ip
+	}
+	return false // An invalid host allows nothing.
+}
+
+// Connection is an address to connect to, and pre-open the connection.
+type Connection struct {
+	// Network is "tcp", "udp" or "unix".
+	Network string
+	// Address is the address to connect to, as documented on net.Dial.
+	Address string
 }
 
 // TCPAddress is a host:port pair to pre-open.
@@ -54,32 +145,164 @@ func (c *Config) WithTCPListener(host string, port int) *Config {
 	return &ret
 }
 
+// WithUDPListener implements the method of the same name in experimental/sock/Config.
+func (c *Config) WithUDPListener(host string, port int) *Config {
+	ret := c.clone()
+	ret.UDPAddresses = append(ret.UDPAddresses, TCPAddress{host, port})
+	return &ret
+}
+
+// WithUnixListener implements the method of the same name in experimental/sock/Config.
+func (c *Config) WithUnixListener(path string) *Config {
+	ret := c.clone()
+	ret.UnixPaths = append(ret.UnixPaths, path)
+	return &ret
+}
+
+// WithConnection implements the method of the same name in experimental/sock/Config.
+func (c *Config) WithConnection(network, address string) *Config {
+	ret := c.clone()
+	ret.Connections = append(ret.Connections, Connection{network, address})
+	return &ret
+}
+
+// WithAllowedBind implements the method of the same name in experimental/sock/Config.
+func (c *Config) WithAllowedBind(network, host string, port int) *Config {
+	ret := c.clone()
+	ret.BindAddresses = append(ret.BindAddresses, AllowedAddress{network, host, port})
+	return &ret
+}
+
+// WithAllowedConnect implements the method of the same name in experimental/sock/Config.
+func (c *Config) WithAllowedConnect(network, host string, port int) *Config {
+	ret := c.clone()
+	ret.ConnectAddresses = append(ret.ConnectAddresses, AllowedAddress{network, host, port})
+	return &ret
+}
+
+// WithAllowedLookup implements the method of the same name in experimental/sock/Config.
+func (c *Config) WithAllowedLookup(name string) *Config {
+	ret := c.clone()
+	ret.LookupNames = append(ret.LookupNames, name)
+	return &ret
+}
+
+// IsZero returns true if the config doesn't pre-open sockets, nor allow
+// guests to open them.
+func (c *Config) IsZero() bool {
+	return len(c.TCPAddresses) == 0 && len(c.UDPAddresses) == 0 &&
+		len(c.UnixPaths) == 0 && len(c.Connections) == 0 &&
+		len(c.BindAddresses) == 0 && len(c.ConnectAddresses) == 0 &&
+		len(c.LookupNames) == 0
+}
+
+// AllowBind returns true if guests are allowed to bind a socket to the
+// address on the network, e.g. "tcp4". A nil config allows nothing.
+func (c *Config) AllowBind(network string, addr netip.AddrPort) bool {
+	return c != nil && allows(c.BindAddresses, network, addr)
+}
+
+// AllowConnect returns true if guests are allowed to connect a socket, or
+// send a datagram, to the address on the network, e.g. "udp6". A nil config
+// allows nothing.
+func (c *Config) AllowConnect(network string, addr netip.AddrPort) bool {
+	return c != nil && allows(c.ConnectAddresses, network, addr)
+}
+
+// AllowLookup returns true if guests are allowed to look up the name. A nil
+// config allows nothing.
+func (c *Config) AllowLookup(name string) bool {
+	if c == nil {
+		return false
+	}
+	for _, n := range c.LookupNames {
+		if n == "" || n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func allows(addresses []AllowedAddress, network string, addr netip.AddrPort) bool {
+	for _, a := range addresses {
+		if a.allows(network, addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // Makes a deep copy of this sockConfig.
 func (c *Config) clone() Config {
 	ret := *c
 	ret.TCPAddresses = make([]TCPAddress, 0, len(c.TCPAddresses))
 	ret.TCPAddresses = append(ret.TCPAddresses, c.TCPAddresses...)
+	ret.UDPAddresses = append([]TCPAddress(nil), c.UDPAddresses...)
+	ret.UnixPaths = append([]string(nil), c.UnixPaths...)
+	ret.Connections = append([]Connection(nil), c.Connections...)
+	ret.BindAddresses = append([]AllowedAddress(nil), c.BindAddresses...)
+	ret.ConnectAddresses = append([]AllowedAddress(nil), c.ConnectAddresses...)
+	ret.LookupNames = append([]string(nil), c.LookupNames...)
 	return ret
 }
 
-// BuildTCPListeners build listeners from the current configuration.
-func (c *Config) BuildTCPListeners() (tcpListeners []*net.TCPListener, err error) {
+// BuildSockets builds the sockets to pre-open from the current
+// configuration: the listeners of TCPAddresses, the UDP sockets of
+// UDPAddresses, the listeners of UnixPaths and the connections of
+// Connections, in this order.
+//
+// The sockets are *net.TCPListener, *net.UDPConn, *net.UnixListener,
+// *net.TCPConn or *net.UnixConn. Connections of other networks are an error,
+// and on error, the sockets built so far are closed.
+//
+// Connections are dialed with ctx, so that a peer which doesn't answer
+// doesn't block instantiation past its deadline or cancellation.
+func (c *Config) BuildSockets(ctx context.Context) (sockets []io.Closer, err error) {
+	defer func() {
+		if err != nil {
+			// An error occurred, cleanup.
+			for _, s := range sockets {
+				_ = s.Close() // Ignore errors, we are already cleaning.
+			}
+			sockets = nil
+		}
+	}()
+
 	for _, tcpAddr := range c.TCPAddresses {
 		var ln net.Listener
-		ln, err = net.Listen("tcp", tcpAddr.String())
-		if err != nil {
-			break
+		if ln, err = net.Listen("tcp", tcpAddr.String()); err != nil {
+			return
 		}
-		if tcpln, ok := ln.(*net.TCPListener); ok {
-			tcpListeners = append(tcpListeners, tcpln)
+		sockets = append(sockets, ln)
+	}
+	for _, udpAddr := range c.UDPAddresses {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket("udp", udpAddr.String()); err != nil {
+			return
 		}
+		sockets = append(sockets, conn)
 	}
-	if err != nil {
-		// An error occurred, cleanup.
-		for _, l := range tcpListeners {
-			_ = l.Close() // Ignore errors, we are already cleaning.
+	for _, path := range c.UnixPaths {
+		var ln net.Listener
+		if ln, err = net.Listen("unix", path); err != nil {
+			return
+		}
+		sockets = append(sockets, ln)
+	}
+	var dialer net.Dialer
+	for _, conn := range c.Connections {
+		var nc net.Conn
+		if nc, err = dialer.DialContext(ctx, conn.Network, conn.Address); err != nil {
+			return
+		}
+		switch nc.(type) {
+		case *net.TCPConn, *net.UDPConn, *net.UnixConn:
+		default: // e.g. *net.IPConn
+			_ = nc.Close()
+			err = fmt.Errorf("unsupported network: %s", conn.Network)
+			return
 		}
-		tcpListeners = nil
+		sockets = append(sockets, nc)
 	}
 	return
 }
@@ -1,6 +1,7 @@
 package sys
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"net"
@@ -53,10 +54,31 @@ type FileEntry struct {
 	// File is always non-nil.
 	File fsapi.File
 
+	// Rights are the WASI rights of this file descriptor, or nil if it isn't
+	// restricted. See HasRights
+	Rights *Rights
+
 	// direntCache is nil until DirentCache was called.
 	direntCache *DirentCache
 }
 
+// Rights are the WASI rights of a file descriptor, each a bit set of
+// wasip1.RIGHT_XXX flags.
+type Rights struct {
+	// Base are the rights of operations on the file descriptor.
+	Base uint64
+
+	// Inheriting are the most rights that file descriptors opened relative to
+	// this one, via path_open, can have.
+	Inheriting uint64
+}
+
+// HasRights returns true if this file descriptor has all the given base
+// rights, which is always the case when it isn't restricted.
+func (f *FileEntry) HasRights(base uint64) bool {
+	return f.Rights == nil || f.Rights.Base&base == base
+}
+
 // DirentCache gets or creates a DirentCache for this file or returns an error.
 //
 // # Errors
@@ -345,6 +367,17 @@ func (c *FSContext) SockAccept(sockFD int32, nonblock bool) (int32, sys.Errno) {
 	}
 }
 
+// SockOpen opens a socketapi.Socket of the network into the file table and
+// returns its file descriptor. See sysfs.NewSocketFile
+func (c *FSContext) SockOpen(network string) (int32, sys.Errno) {
+	fe := &FileEntry{File: fsapi.Adapt(sysfs.NewSocketFile(network))}
+	if newFD, ok := c.openedFiles.Insert(fe); !ok {
+		return 0, sys.EBADF
+	} else {
+		return newFD, 0
+	}
+}
+
 // CloseFile returns any error closing the existing file.
 func (c *FSContext) CloseFile(fd int32) (errno sys.Errno) {
 	f, ok := c.openedFiles.Lookup(fd)
@@ -373,13 +406,23 @@ func (c *FSContext) Close() (err error) {
 }
 
 // InitFSContext initializes a FSContext with stdio streams and optional
-// pre-opened filesystems and TCP listeners.
+// pre-opened filesystems and sockets, as built by socketapi.Config
+// BuildSockets. rights are index-correlated with fs, where a nil slice or
+// element leaves the pre-opened directory unrestricted.
+//
+// The sockets are closed on error, as they aren't all owned by the FSContext.
 func (c *Context) InitFSContext(
 	stdin io.Reader,
 	stdout, stderr io.Writer,
-	fs []sys.FS, guestPaths []string,
-	tcpListeners []*net.TCPListener,
+	fs []sys.FS, guestPaths []string, rights []*Rights,
+	sockets []io.Closer,
 ) (err error) {
+	defer func() {
+		if err != nil {
+			closeSockets(sockets)
+		}
+	}()
+
 	inFile, err := stdinFileEntry(stdin)
 	if err != nil {
 		return err
@@ -403,20 +446,47 @@ func (c *Context) InitFSContext(
 			// Default to bind to '/' when guestPath is effectively empty.
 			guestPath = "/"
 		}
-		c.fsc.openedFiles.Insert(&FileEntry{
+		fe := &FileEntry{
 			FS:        f,
 			Name:      guestPath,
 			IsPreopen: true,
 			File:      &lazyDir{fs: f},
-		})
+		}
+		if rights != nil {
+			fe.Rights = rights[i]
+		}
+		c.fsc.openedFiles.Insert(fe)
 	}
 
-	for _, tl := range tcpListeners {
-		c.fsc.openedFiles.Insert(&FileEntry{IsPreopen: true, File: fsapi.Adapt(sysfs.NewTCPListenerFile(tl))})
+	for _, s := range sockets {
+		var f sys.File
+		switch s := s.(type) {
+		case *net.TCPListener:
+			f = sysfs.NewTCPListenerFile(s)
+		case *net.UDPConn:
+			f = sysfs.NewUDPConnFile(s)
+		case *net.UnixListener:
+			f = sysfs.NewUnixListenerFile(s)
+		case *net.TCPConn:
+			f = sysfs.NewTCPConnFile(s)
+		case *net.UnixConn:
+			f = sysfs.NewUnixConnFile(s)
+		default:
+			return fmt.Errorf("unsupported socket: %T", s)
+		}
+		c.fsc.openedFiles.Insert(&FileEntry{IsPreopen: true, File: fsapi.Adapt(f)})
 	}
 	return nil
 }
 
+// closeSockets closes the sockets built for InitFSContext, ignoring errors as
+// it is called on another error.
+func closeSockets(sockets []io.Closer) {
+	for _, s := range sockets {
+		_ = s.Close()
+	}
+}
+
 // StripPrefixesAndTrailingSlash skips any leading "./" or "/" such that the
 // result index begins with another string. A result of "." coerces to the
 // empty string "" because the current directory is handled by the guest.
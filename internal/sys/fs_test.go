@@ -4,7 +4,9 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"net"
 	"os"
 	"path"
 	"testing"
@@ -57,7 +59,7 @@ func TestNewFSContext(t *testing.T) {
 			for _, root := range []string{"/", ""} {
 				t.Run(fmt.Sprintf("root = '%s'", root), func(t *testing.T) {
 					c := Context{}
-					err := c.InitFSContext(nil, nil, nil, []sys.FS{tc.fs}, []string{root}, nil)
+					err := c.InitFSContext(nil, nil, nil, []sys.FS{tc.fs}, []string{root}, nil, nil)
 					require.NoError(t, err)
 					fsc := c.fsc
 					defer fsc.Close()
@@ -99,7 +101,7 @@ func TestFSContext_CloseFile(t *testing.T) {
 	testFS := &sysfs.AdaptFS{FS: embedFS}
 
 	c := Context{}
-	err = c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil)
+	err = c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil, nil)
 	require.NoError(t, err)
 	fsc := c.fsc
 	defer fsc.Close()
@@ -131,7 +133,7 @@ func TestFSContext_CloseFile(t *testing.T) {
 
 func TestFSContext_noPreopens(t *testing.T) {
 	c := Context{}
-	err := c.InitFSContext(nil, nil, nil, nil, nil, nil)
+	err := c.InitFSContext(nil, nil, nil, nil, nil, nil, nil)
 	require.NoError(t, err)
 	testFS := &c.fsc
 	require.NoError(t, err)
@@ -153,11 +155,25 @@ func TestFSContext_noPreopens(t *testing.T) {
 	})
 }
 
+func TestFSContext_unsupportedSocket(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	c := Context{}
+	err = c.InitFSContext(nil, nil, nil, nil, nil, nil, []io.Closer{ln, io.NopCloser(nil)})
+	require.EqualError(t, err, "unsupported socket: io.nopCloser")
+
+	// The listener pre-opened before the unsupported socket was closed.
+	_, err = ln.Accept()
+	require.ErrorIs(t, err, net.ErrClosed)
+}
+
 func TestContext_Close(t *testing.T) {
 	testFS := &sysfs.AdaptFS{FS: testfs.FS{"foo": &testfs.File{}}}
 
 	c := Context{}
-	err := c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil)
+	err := c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil, nil)
 	require.NoError(t, err)
 	fsc := c.fsc
 
@@ -184,7 +200,7 @@ func TestContext_Close_Error(t *testing.T) {
 	testFS := &sysfs.AdaptFS{FS: testfs.FS{"foo": file}}
 
 	c := Context{}
-	err := c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil)
+	err := c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, nil, nil)
 	require.NoError(t, err)
 	fsc := c.fsc
 
@@ -208,7 +224,7 @@ func TestFSContext_Renumber(t *testing.T) {
 	require.EqualErrno(t, 0, errno)
 
 	c := Context{}
-	err := c.InitFSContext(nil, nil, nil, []sys.FS{dirFS}, []string{"/"}, nil)
+	err := c.InitFSContext(nil, nil, nil, []sys.FS{dirFS}, []string{"/"}, nil, nil)
 	require.NoError(t, err)
 	fsc := c.fsc
 
@@ -256,7 +272,7 @@ func TestDirentCache_ReadNewFile(t *testing.T) {
 
 	c := Context{}
 	root := sysfs.DirFS(tmpDir)
-	err := c.InitFSContext(nil, nil, nil, []sys.FS{root}, []string{"/"}, nil)
+	err := c.InitFSContext(nil, nil, nil, []sys.FS{root}, []string{"/"}, nil, nil)
 	require.NoError(t, err)
 	fsc := c.fsc
 	defer fsc.Close()
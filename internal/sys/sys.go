@@ -4,11 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"time"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/platform"
+	socketapi "github.com/tetratelabs/wazero/internal/sock"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -26,6 +26,7 @@ type Context struct {
 	osyield            sys.Osyield
 	randSource         io.Reader
 	fsc                FSContext
+	sockConfig         *socketapi.Config
 }
 
 // Args is like os.Args and defaults to nil.
@@ -103,6 +104,13 @@ func (c *Context) FS() *FSContext {
 	return &c.fsc
 }
 
+// SockConfig returns the possibly nil configuration of the sockets guests
+// are allowed to open.
+// see experimental/sock.WithConfig
+func (c *Context) SockConfig() *socketapi.Config {
+	return c.sockConfig
+}
+
 // RandSource is a source of random bytes and defaults to a deterministic source.
 // see wazero.ModuleConfig WithRandSource
 func (c *Context) RandSource() io.Reader {
@@ -114,7 +122,7 @@ func (c *Context) RandSource() io.Reader {
 //
 // Note: This is only used for testing.
 func DefaultContext(fs experimentalsys.FS) *Context {
-	if sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, nil, nil, 0, nil, 0, nil, nil, []experimentalsys.FS{fs}, []string{""}, nil); err != nil {
+	if sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, nil, nil, 0, nil, 0, nil, nil, []experimentalsys.FS{fs}, []string{""}, nil, nil, nil); err != nil {
 		panic(fmt.Errorf("BUG: DefaultContext should never error: %w", err))
 	} else {
 		return sysCtx
@@ -135,10 +143,17 @@ func NewContext(
 	nanotimeResolution sys.ClockResolution,
 	nanosleep sys.Nanosleep,
 	osyield sys.Osyield,
-	fs []experimentalsys.FS, guestPaths []string,
-	tcpListeners []*net.TCPListener,
+	fs []experimentalsys.FS, guestPaths []string, rights []*Rights,
+	sockets []io.Closer,
+	sockConfig *socketapi.Config,
 ) (sysCtx *Context, err error) {
-	sysCtx = &Context{args: args, environ: environ}
+	defer func() {
+		if err != nil { // Otherwise, the sockets are owned by the FSContext.
+			closeSockets(sockets)
+		}
+	}()
+
+	sysCtx = &Context{args: args, environ: environ, sockConfig: sockConfig}
 
 	if sysCtx.argsSize, err = nullTerminatedByteCount(max, args); err != nil {
 		return nil, fmt.Errorf("args invalid: %w", err)
@@ -188,7 +203,7 @@ func NewContext(
 		sysCtx.osyield = platform.FakeOsyield
 	}
 
-	err = sysCtx.InitFSContext(stdin, stdout, stderr, fs, guestPaths, tcpListeners)
+	err = sysCtx.InitFSContext(stdin, stdout, stderr, fs, guestPaths, rights, sockets)
 
 	return
 }
@@ -67,6 +67,14 @@ type stdioFile struct {
 	st sys.Stat_t
 }
 
+// pollFd implements the same method as documented on pollFdFile
+func (f *stdioFile) pollFd() (uintptr, bool) {
+	if ff, ok := f.File.(pollFdFile); ok {
+		return ff.pollFd()
+	}
+	return 0, false
+}
+
 // SetAppend implements File.SetAppend
 func (f *stdioFile) SetAppend(bool) experimentalsys.Errno {
 	// Ignore for stdio.
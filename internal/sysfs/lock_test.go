@@ -0,0 +1,84 @@
+//go:build unix && !(aix || solaris)
+
+package sysfs
+
+import (
+	"path"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFileLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := path.Join(tmpDir, "file")
+
+	// Locks belong to the open file, so they conflict even in the same process.
+	f0 := requireOpenFile(t, path, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	defer f0.Close()
+	f1 := requireOpenFile(t, path, experimentalsys.O_RDWR, 0o600)
+	defer f1.Close()
+	l0, l1 := f0.(experimentalsys.Locker), f1.(experimentalsys.Locker)
+
+	// Shared locks don't conflict with each other.
+	require.EqualErrno(t, 0, l0.Lock(experimentalsys.LOCK_SH|experimentalsys.LOCK_NB))
+	require.EqualErrno(t, 0, l1.Lock(experimentalsys.LOCK_SH|experimentalsys.LOCK_NB))
+	require.EqualErrno(t, experimentalsys.EAGAIN, l0.Lock(experimentalsys.LOCK_EX|experimentalsys.LOCK_NB))
+
+	// Exclusive locks conflict with any other lock.
+	require.EqualErrno(t, 0, l1.Lock(experimentalsys.LOCK_UN))
+	require.EqualErrno(t, 0, l0.Lock(experimentalsys.LOCK_EX|experimentalsys.LOCK_NB))
+	require.EqualErrno(t, experimentalsys.EAGAIN, l1.Lock(experimentalsys.LOCK_SH|experimentalsys.LOCK_NB))
+
+	// A blocked lock is placed once the conflicting one is removed.
+	done := make(chan experimentalsys.Errno)
+	go func() { done <- l1.Lock(experimentalsys.LOCK_EX) }()
+	require.EqualErrno(t, 0, l0.Lock(experimentalsys.LOCK_UN))
+	require.EqualErrno(t, 0, <-done)
+
+	// Closing the file removes its lock.
+	require.EqualErrno(t, 0, f1.Close())
+	require.EqualErrno(t, 0, l0.Lock(experimentalsys.LOCK_EX|experimentalsys.LOCK_NB))
+}
+
+func TestFileLock_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := path.Join(tmpDir, "file")
+
+	f := requireOpenFile(t, path, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	l := f.(experimentalsys.Locker)
+
+	for _, how := range []experimentalsys.LockFlag{
+		0,
+		experimentalsys.LOCK_NB,
+		experimentalsys.LOCK_SH | experimentalsys.LOCK_EX,
+		experimentalsys.LOCK_EX | experimentalsys.LOCK_UN,
+	} {
+		require.EqualErrno(t, experimentalsys.EINVAL, l.Lock(how))
+	}
+
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, experimentalsys.EBADF, l.Lock(experimentalsys.LOCK_SH))
+}
+
+func TestReadFS_Lock(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeable := DirFS(tmpDir)
+	f, errno := writeable.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	readFS := &ReadFS{FS: writeable}
+	rf, errno := readFS.OpenFile("file", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer rf.Close()
+
+	// Read-only files can still be locked, e.g. for a consistent read.
+	require.EqualErrno(t, 0, rf.(experimentalsys.Locker).Lock(experimentalsys.LOCK_SH|experimentalsys.LOCK_NB))
+	require.EqualErrno(t, experimentalsys.EAGAIN, f.(experimentalsys.Locker).Lock(experimentalsys.LOCK_EX|experimentalsys.LOCK_NB))
+
+	// Exclusive locks need the file to be open for writing.
+	require.EqualErrno(t, experimentalsys.EBADF, rf.(experimentalsys.Locker).Lock(experimentalsys.LOCK_EX))
+	require.EqualErrno(t, experimentalsys.EBADF, rf.(experimentalsys.Locker).Lock(experimentalsys.LOCK_EX|experimentalsys.LOCK_NB))
+}
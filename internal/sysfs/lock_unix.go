@@ -0,0 +1,19 @@
+//go:build unix && !(aix || solaris)
+
+package sysfs
+
+import (
+	"syscall"
+
+	"github.com/tetratelabs/wazero/experimental/sys"
+)
+
+func flock(fd uintptr, how sys.LockFlag) sys.Errno {
+	for {
+		// LockFlag values are the same as syscall.LOCK_XX on all platforms.
+		err := syscall.Flock(int(fd), int(how))
+		if err != syscall.EINTR {
+			return sys.UnwrapOSError(err)
+		}
+	}
+}
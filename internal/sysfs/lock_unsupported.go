@@ -0,0 +1,9 @@
+//go:build !unix || aix || solaris
+
+package sysfs
+
+import "github.com/tetratelabs/wazero/experimental/sys"
+
+func flock(uintptr, sys.LockFlag) sys.Errno {
+	return sys.ENOSYS
+}
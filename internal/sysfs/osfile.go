@@ -24,6 +24,8 @@ func newOsFile(path string, flag experimentalsys.Oflag, perm fs.FileMode, f *os.
 	return &osFile{path: path, flag: flag, perm: perm, reopenDir: true, file: f, fd: f.Fd()}
 }
 
+var _ experimentalsys.Locker = (*osFile)(nil)
+
 // osFile is a file opened with this package, and uses os.File or syscalls to
 // implement api.File.
 type osFile struct {
@@ -234,6 +236,11 @@ func (f *osFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno
 	return poll(f.fd, flag, timeoutMillis)
 }
 
+// pollFd implements the same method as documented on pollFdFile
+func (f *osFile) pollFd() (uintptr, bool) {
+	return f.fd, !f.closed
+}
+
 // Readdir implements File.Readdir. Notably, this uses "Readdir", not
 // "ReadDir", from os.File.
 func (f *osFile) Readdir(n int) (dirents []experimentalsys.Dirent, errno experimentalsys.Errno) {
@@ -305,6 +312,22 @@ func (f *osFile) Utimens(atim, mtim int64) experimentalsys.Errno {
 	return experimentalsys.UnwrapOSError(err)
 }
 
+// Lock implements the same method as documented on sys.Locker
+//
+// Note: The lock is removed if SetAppend re-opens the file.
+func (f *osFile) Lock(how experimentalsys.LockFlag) experimentalsys.Errno {
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+
+	switch how &^ experimentalsys.LOCK_NB {
+	case experimentalsys.LOCK_SH, experimentalsys.LOCK_EX, experimentalsys.LOCK_UN:
+	default:
+		return experimentalsys.EINVAL
+	}
+	return flock(f.fd, how)
+}
+
 // Close implements the same method as documented on sys.File
 func (f *osFile) Close() experimentalsys.Errno {
 	if f.closed {
@@ -9,10 +9,11 @@ import (
 
 // poll implements `Poll` as documented on sys.File via a file descriptor.
 func poll(fd uintptr, flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno sys.Errno) {
-	if flag != fsapi.POLLIN {
-		return false, sys.ENOTSUP
+	events, errno := pollEvents(flag)
+	if errno != 0 {
+		return false, errno
 	}
-	fds := []pollFd{newPollFd(fd, _POLLIN, 0)}
+	fds := []pollFd{newPollFd(fd, events, 0)}
 	count, errno := _poll(fds, timeoutMillis)
 	return count > 0, errno
 }
@@ -0,0 +1,141 @@
+package sysfs
+
+import (
+	"context"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+)
+
+// PollFile is a file polled by PollFiles.
+type PollFile struct {
+	// File is polled for the event of Flag.
+	File fsapi.File
+	Flag fsapi.Pflag
+
+	// Ready is true once the file is ready, or failed to poll with Errno.
+	Ready bool
+	Errno experimentalsys.Errno
+
+	// Hangup is true when the file is ready as its peer hung up, which is
+	// only known for the files polled together as file descriptors.
+	Hangup bool
+}
+
+// pollFdFile is implemented by the files whose readiness is the one of their
+// file descriptor, so that PollFiles polls them together.
+type pollFdFile interface {
+	// pollFd returns the file descriptor, or false if there's none, such as
+	// when the file is closed.
+	pollFd() (fd uintptr, ok bool)
+}
+
+// pollFilesInterval is the interval between the polls of the files which
+// can't be polled together, as they aren't file descriptors.
+const pollFilesInterval = 10 * time.Millisecond
+
+// pollContextInterval is the interval PollFiles checks whether the context is
+// done at, when it can be.
+const pollContextInterval = 100 * time.Millisecond
+
+// PollFiles polls the files until at least one of them is ready, or the
+// timeout expires, and returns the count of files ready.
+//
+// The timeoutMillis is the same as documented on fsapi.File Poll. The files
+// which are file descriptors are polled together on platforms which support
+// it. Otherwise, the files are polled each with fsapi.File Poll: the only one
+// with the timeout, or else at an interval until it expires.
+//
+// # Errors
+//
+// A zero sys.Errno is success. The below are expected otherwise:
+//   - sys.EINTR: the context was done before a file was ready, e.g. when it
+//     was canceled.
+//
+// # Notes
+//
+//   - A file which doesn't support Poll, with sys.ENOSYS or sys.ENOTSUP, is
+//     ready, like regular files are in POSIX.
+//   - When the context can be done, the files are polled in rounds of at most
+//     pollContextInterval, so that an unlimited timeout can be interrupted.
+//   - This is like `poll` in POSIX, for several files.
+//     See https://pubs.opengroup.org/onlinepubs/9699919799/functions/poll.html
+func PollFiles(ctx context.Context, files []PollFile, timeoutMillis int32) (n int, errno experimentalsys.Errno) {
+	done := ctx.Done() // nil if the context can't be done.
+
+	var fds fdPoller
+	var others []*PollFile
+	for i := range files {
+		if f := &files[i]; !fds.add(f) {
+			others = append(others, f)
+		}
+	}
+
+	// Poll a single file which isn't a file descriptor with the timeout, as
+	// it may block until ready.
+	if fds.len() == 0 && len(others) == 1 && done == nil {
+		return pollFile(others[0], timeoutMillis), 0
+	}
+
+	var deadline time.Time
+	if timeoutMillis > 0 {
+		deadline = time.Now().Add(time.Duration(timeoutMillis) * time.Millisecond)
+	}
+	for {
+		n = 0
+		for _, f := range others {
+			n += pollFile(f, 0)
+		}
+
+		// Wait for the file descriptors unless a file is already ready, at
+		// most until the next poll of the other files.
+		wait := timeoutMillis
+		if n > 0 {
+			wait = 0
+		} else if timeoutMillis > 0 {
+			wait = int32(time.Until(deadline).Milliseconds())
+			if wait < 0 {
+				wait = 0
+			}
+		}
+		if len(others) > 0 && (wait < 0 || wait > int32(pollFilesInterval.Milliseconds())) {
+			wait = int32(pollFilesInterval.Milliseconds())
+		} else if done != nil && (wait < 0 || wait > int32(pollContextInterval.Milliseconds())) {
+			wait = int32(pollContextInterval.Milliseconds())
+		}
+
+		if fds.len() > 0 {
+			ready, errno := fds.poll(wait)
+			if errno != 0 && errno != experimentalsys.EINTR {
+				return 0, errno
+			}
+			n += ready
+		} else if wait > 0 {
+			time.Sleep(time.Duration(wait) * time.Millisecond)
+		}
+
+		if n > 0 || timeoutMillis == 0 || (timeoutMillis > 0 && !time.Now().Before(deadline)) {
+			return n, 0
+		} else if ctx.Err() != nil {
+			return 0, experimentalsys.EINTR
+		}
+	}
+}
+
+// pollFile polls the file with fsapi.File Poll, and returns one if it's ready.
+func pollFile(f *PollFile, timeoutMillis int32) int {
+	ready, errno := f.File.Poll(f.Flag, timeoutMillis)
+	switch errno {
+	case 0:
+		f.Ready = ready
+	case experimentalsys.ENOSYS, experimentalsys.ENOTSUP:
+		f.Ready = true
+	default:
+		f.Ready, f.Errno = true, errno
+	}
+	if f.Ready {
+		return 1
+	}
+	return 0
+}
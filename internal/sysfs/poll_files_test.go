@@ -0,0 +1,191 @@
+package sysfs
+
+import (
+	"context"
+	"net"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// readyFile is a file which isn't a file descriptor, and is ready to read
+// once ready is set.
+type readyFile struct {
+	fsapi.File
+	ready atomic.Bool
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (f *readyFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (bool, experimentalsys.Errno) {
+	if flag != fsapi.POLLIN {
+		return false, experimentalsys.ENOTSUP
+	}
+	return f.ready.Load(), 0
+}
+
+// newPipe returns the files of the ends of a new pipe.
+func newPipe(t *testing.T) (r, w fsapi.File, pw *os.File) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = pr.Close()
+		_ = pw.Close()
+	})
+	r, err = NewStdioFile(true, pr)
+	require.NoError(t, err)
+	w, err = NewStdioFile(false, pw)
+	require.NoError(t, err)
+	return
+}
+
+func TestPollFiles(t *testing.T) {
+	r1, _, _ := newPipe(t)
+	r2, _, w2 := newPipe(t)
+
+	t.Run("timeout", func(t *testing.T) {
+		files := []PollFile{{File: r1, Flag: fsapi.POLLIN}, {File: r2, Flag: fsapi.POLLIN}}
+		start := time.Now()
+		n, errno := PollFiles(context.Background(), files, 50)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 0, n)
+		require.True(t, time.Since(start) >= 40*time.Millisecond)
+		require.False(t, files[0].Ready)
+		require.False(t, files[1].Ready)
+	})
+
+	t.Run("one ready", func(t *testing.T) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_, _ = w2.Write([]byte("wazero"))
+		}()
+		files := []PollFile{{File: r1, Flag: fsapi.POLLIN}, {File: r2, Flag: fsapi.POLLIN}}
+		n, errno := PollFiles(context.Background(), files, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, n)
+		require.False(t, files[0].Ready)
+		require.True(t, files[1].Ready)
+	})
+
+	t.Run("not a file descriptor", func(t *testing.T) {
+		other := &readyFile{File: fsapi.Adapt(experimentalsys.UnimplementedFile{})}
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			other.ready.Store(true)
+		}()
+		files := []PollFile{{File: r1, Flag: fsapi.POLLIN}, {File: other, Flag: fsapi.POLLIN}}
+		n, errno := PollFiles(context.Background(), files, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, n)
+		require.False(t, files[0].Ready)
+		require.True(t, files[1].Ready)
+	})
+
+	t.Run("poll unsupported", func(t *testing.T) {
+		// Files which can't be polled are ready, like regular files.
+		files := []PollFile{{File: r1, Flag: fsapi.POLLIN}, {File: fsapi.Adapt(experimentalsys.UnimplementedFile{}), Flag: fsapi.POLLIN}}
+		n, errno := PollFiles(context.Background(), files, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, n)
+		require.False(t, files[0].Ready)
+		require.True(t, files[1].Ready)
+		require.EqualErrno(t, 0, files[1].Errno)
+	})
+}
+
+func TestPollFiles_POLLOUT(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POLLOUT isn't supported on windows")
+	}
+	r, w, _ := newPipe(t)
+
+	files := []PollFile{{File: r, Flag: fsapi.POLLIN}, {File: w, Flag: fsapi.POLLOUT}}
+	n, errno := PollFiles(context.Background(), files, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.False(t, files[0].Ready)
+	require.True(t, files[1].Ready)
+}
+
+func TestPollFiles_Hangup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file descriptors are polled each on windows")
+	}
+	r, _, w := newPipe(t)
+
+	// The read end is ready once the write end is closed.
+	require.NoError(t, w.Close())
+	files := []PollFile{{File: r, Flag: fsapi.POLLIN}}
+	n, errno := PollFiles(context.Background(), files, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.True(t, files[0].Ready)
+	require.True(t, files[0].Hangup)
+}
+
+func TestPollFiles_TCP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sockets are polled at an interval on windows")
+	}
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listen.Close()
+	listener := newTCPListenerFile(listen.(*net.TCPListener))
+
+	conn, err := net.Dial("tcp", listen.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The listener is ready once a connection is pending.
+	files := []PollFile{{File: fsapi.Adapt(listener), Flag: fsapi.POLLIN}}
+	n, errno := PollFiles(context.Background(), files, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+
+	accepted, errno := listener.Accept()
+	require.EqualErrno(t, 0, errno)
+	defer accepted.Close()
+
+	// The connection is ready to read once the peer writes.
+	files = []PollFile{{File: fsapi.Adapt(accepted), Flag: fsapi.POLLIN}}
+	n, errno = PollFiles(context.Background(), files, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 0, n)
+
+	_, err = conn.Write([]byte("wazero"))
+	require.NoError(t, err)
+	n, errno = PollFiles(context.Background(), files, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.True(t, files[0].Ready)
+}
+
+func TestPollFiles_Context(t *testing.T) {
+	r, _, _ := newPipe(t)
+	other := &readyFile{}
+
+	tests := []struct {
+		name  string
+		files []PollFile
+	}{
+		{name: "file descriptor", files: []PollFile{{File: r, Flag: fsapi.POLLIN}}},
+		{name: "other file", files: []PollFile{{File: other, Flag: fsapi.POLLIN}}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			// An unlimited timeout is interrupted once the context is done.
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			n, errno := PollFiles(ctx, tc.files, -1)
+			require.EqualErrno(t, experimentalsys.EINTR, errno)
+			require.Equal(t, 0, n)
+		})
+	}
+}
@@ -23,8 +23,14 @@ func newPollFd(fd uintptr, events, revents int16) pollFd {
 	return pollFd{fd: int32(fd), events: events, revents: revents}
 }
 
-// _POLLIN subscribes a notification when any readable data is available.
-const _POLLIN = 0x0001
+const (
+	// _POLLIN subscribes a notification when any readable data is available.
+	_POLLIN = 0x0001
+	// _POLLOUT subscribes a notification when data can be written.
+	_POLLOUT = 0x0004
+	// _POLLHUP is returned when the peer hung up, without subscribing.
+	_POLLHUP = 0x0010
+)
 
 // _poll implements poll on Linux via ppoll.
 func _poll(fds []pollFd, timeoutMillis int32) (n int, errno sys.Errno) {
@@ -0,0 +1,74 @@
+//go:build linux || darwin
+
+package sysfs
+
+import (
+	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+)
+
+// pollEvents returns the poll events of the flag.
+func pollEvents(flag fsapi.Pflag) (events int16, errno sys.Errno) {
+	if flag&^(fsapi.POLLIN|fsapi.POLLOUT) != 0 {
+		return 0, sys.ENOTSUP
+	}
+	if flag&fsapi.POLLIN != 0 {
+		events |= _POLLIN
+	}
+	if flag&fsapi.POLLOUT != 0 {
+		events |= _POLLOUT
+	}
+	if events == 0 {
+		return 0, sys.ENOTSUP
+	}
+	return
+}
+
+// fdPoller polls the files which are file descriptors together, with a
+// single call to poll.
+type fdPoller struct {
+	fds   []pollFd
+	files []*PollFile
+}
+
+// add adds the file to the poller, unless it isn't a file descriptor.
+func (p *fdPoller) add(f *PollFile) bool {
+	ff, ok := f.File.(pollFdFile)
+	if !ok {
+		return false
+	}
+	fd, ok := ff.pollFd()
+	if !ok {
+		return false
+	}
+	events, errno := pollEvents(f.Flag)
+	if errno != 0 {
+		return false
+	}
+	p.fds = append(p.fds, newPollFd(fd, events, 0))
+	p.files = append(p.files, f)
+	return true
+}
+
+// len returns the count of files added.
+func (p *fdPoller) len() int {
+	return len(p.fds)
+}
+
+// poll sets the files which are ready, and returns their count.
+func (p *fdPoller) poll(timeoutMillis int32) (n int, errno sys.Errno) {
+	if n, errno = _poll(p.fds, timeoutMillis); errno != 0 || n == 0 {
+		return 0, errno
+	}
+	n = 0
+	for i := range p.fds {
+		// Errors, such as a hang up, are ready too, as reading or writing
+		// doesn't block then.
+		if revents := p.fds[i].revents; revents != 0 {
+			p.files[i].Ready = true
+			p.files[i].Hangup = revents&_POLLHUP != 0
+			n++
+		}
+	}
+	return
+}
@@ -11,3 +11,12 @@ import (
 func poll(uintptr, fsapi.Pflag, int32) (bool, sys.Errno) {
 	return false, sys.ENOSYS
 }
+
+// fdPoller is unsupported, so all files are polled with fsapi.File Poll.
+type fdPoller struct{}
+
+func (*fdPoller) add(*PollFile) bool { return false }
+
+func (*fdPoller) len() int { return 0 }
+
+func (*fdPoller) poll(int32) (int, sys.Errno) { return 0, sys.ENOSYS }
@@ -6,6 +6,7 @@ import (
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
 )
 
 var (
@@ -22,6 +23,24 @@ const (
 	_POLLIN = (_POLLRDNORM | _POLLRDBAND)
 )
 
+// pollEvents returns the poll events of the flag, which is only POLLIN.
+func pollEvents(flag fsapi.Pflag) (int16, sys.Errno) {
+	if flag != fsapi.POLLIN {
+		return 0, sys.ENOTSUP
+	}
+	return _POLLIN, 0
+}
+
+// fdPoller isn't supported, as _poll doesn't report which files are ready,
+// so all files are polled with fsapi.File Poll.
+type fdPoller struct{}
+
+func (*fdPoller) add(*PollFile) bool { return false }
+
+func (*fdPoller) len() int { return 0 }
+
+func (*fdPoller) poll(int32) (int, sys.Errno) { return 0, sys.ENOSYS }
+
 // pollFd is the struct to query for file descriptor events using poll.
 type pollFd struct {
 	// fd is the file descriptor.
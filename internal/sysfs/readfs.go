@@ -71,7 +71,10 @@ func (r *ReadFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
 }
 
 // compile-time check to ensure readFile implements api.File.
-var _ experimentalsys.File = (*readFile)(nil)
+var (
+	_ experimentalsys.File   = (*readFile)(nil)
+	_ experimentalsys.Locker = (*readFile)(nil)
+)
 
 type readFile struct {
 	experimentalsys.File
@@ -107,6 +110,19 @@ func (r *readFile) Utimens(int64, int64) experimentalsys.Errno {
 	return experimentalsys.EBADF
 }
 
+// Lock implements the same method as documented on sys.Locker, as shared
+// locks are also needed to read files consistently. Exclusive locks return
+// EBADF, as the file isn't open for writing.
+func (r *readFile) Lock(how experimentalsys.LockFlag) experimentalsys.Errno {
+	if how&^experimentalsys.LOCK_NB == experimentalsys.LOCK_EX {
+		return experimentalsys.EBADF
+	}
+	if l, ok := r.File.(experimentalsys.Locker); ok {
+		return l.Lock(how)
+	}
+	return experimentalsys.ENOSYS
+}
+
 func (r *readFile) writeErr() experimentalsys.Errno {
 	if isDir, errno := r.IsDir(); errno != 0 {
 		return errno
@@ -3,6 +3,7 @@ package sysfs
 import (
 	"net"
 	"os"
+	"syscall"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
@@ -15,6 +16,38 @@ func NewTCPListenerFile(tl *net.TCPListener) socketapi.TCPSock {
 	return newTCPListenerFile(tl)
 }
 
+// NewUnixListenerFile creates a socketapi.TCPSock for a given
+// *net.UnixListener, whose connections are socketapi.TCPConn, as they are
+// streams like TCP connections.
+func NewUnixListenerFile(ul *net.UnixListener) socketapi.TCPSock {
+	return newTCPListenerFile(ul)
+}
+
+// NewTCPConnFile creates a socketapi.TCPConn for a given *net.TCPConn.
+func NewTCPConnFile(tc *net.TCPConn) socketapi.TCPConn {
+	return newTcpConn(tc)
+}
+
+// NewUnixConnFile creates a socketapi.TCPConn for a given *net.UnixConn.
+func NewUnixConnFile(uc *net.UnixConn) socketapi.TCPConn {
+	return newTcpConn(uc)
+}
+
+// streamListener is a listener of stream sockets: *net.TCPListener or
+// *net.UnixListener.
+type streamListener interface {
+	net.Listener
+	syscall.Conn
+}
+
+// streamConn is a connected stream socket: *net.TCPConn or *net.UnixConn.
+type streamConn interface {
+	net.Conn
+	syscall.Conn
+	CloseRead() error
+	CloseWrite() error
+}
+
 // baseSockFile implements base behavior for all TCPSock, TCPConn files,
 // regardless the platform.
 type baseSockFile struct {
@@ -42,7 +75,7 @@ var _ socketapi.TCPSock = (*tcpListenerFile)(nil)
 type tcpListenerFile struct {
 	baseSockFile
 
-	tl       *net.TCPListener
+	tl       streamListener
 	closed   bool
 	nonblock bool
 }
@@ -54,7 +87,7 @@ type tcpListenerFile struct {
 // this internal calls RawConn.Control(func(fd)), making sure
 // that the underlying file descriptor is valid throughout
 // the duration of the syscall.
-func newDefaultTCPListenerFile(tl *net.TCPListener) socketapi.TCPSock {
+func newDefaultTCPListenerFile(tl streamListener) socketapi.TCPSock {
 	return &tcpListenerFile{tl: tl}
 }
 
@@ -78,7 +111,15 @@ func (f *tcpListenerFile) IsNonblock() bool {
 
 // Poll implements the same method as documented on fsapi.File
 func (f *tcpListenerFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
-	return false, experimentalsys.ENOSYS
+	return _pollSock(f.tl, flag, timeoutMillis)
+}
+
+// pollFd implements the same method as documented on pollFdFile
+func (f *tcpListenerFile) pollFd() (uintptr, bool) {
+	if f.closed {
+		return 0, false
+	}
+	return sockFd(f.tl)
 }
 
 var _ socketapi.TCPConn = (*tcpConnFile)(nil)
@@ -86,7 +127,7 @@ var _ socketapi.TCPConn = (*tcpConnFile)(nil)
 type tcpConnFile struct {
 	baseSockFile
 
-	tc *net.TCPConn
+	tc streamConn
 
 	// nonblock is true when the underlying connection is flagged as non-blocking.
 	// This ensures that reads and writes return experimentalsys.EAGAIN without blocking the caller.
@@ -95,7 +136,7 @@ type tcpConnFile struct {
 	closed bool
 }
 
-func newTcpConn(tc *net.TCPConn) socketapi.TCPConn {
+func newTcpConn(tc streamConn) socketapi.TCPConn {
 	return &tcpConnFile{tc: tc}
 }
 
@@ -164,7 +205,7 @@ func (f *tcpConnFile) close() experimentalsys.Errno {
 		return 0
 	}
 	f.closed = true
-	return f.Shutdown(socketapi.SHUT_RDWR)
+	return experimentalsys.UnwrapOSError(f.tc.Close())
 }
 
 // SetNonblock implements the same method as documented on fsapi.File
@@ -183,5 +224,23 @@ func (f *tcpConnFile) IsNonblock() bool {
 
 // Poll implements the same method as documented on fsapi.File
 func (f *tcpConnFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
-	return false, experimentalsys.ENOSYS
+	return _pollSock(f.tc, flag, timeoutMillis)
+}
+
+// pollFd implements the same method as documented on pollFdFile
+func (f *tcpConnFile) pollFd() (uintptr, bool) {
+	if f.closed {
+		return 0, false
+	}
+	return sockFd(f.tc)
+}
+
+// sockFd returns the file descriptor of the socket, which is only valid until
+// it's closed.
+func sockFd(conn syscall.Conn) (fd uintptr, ok bool) {
+	_, errno := syscallConnControl(conn, func(sfd uintptr) (int, experimentalsys.Errno) {
+		fd = sfd
+		return 0, 0
+	})
+	return fd, errno == 0
 }
@@ -0,0 +1,263 @@
+package sysfs
+
+import (
+	"context"
+	"net"
+	"net/netip"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	socketapi "github.com/tetratelabs/wazero/internal/sock"
+)
+
+// NewSocketFile creates a socketapi.Socket opened by the guest, whose network
+// is "tcp4", "tcp6", "udp4" or "udp6".
+func NewSocketFile(network string) socketapi.Socket {
+	return &socketFile{network: network}
+}
+
+var (
+	_ socketapi.Socket = (*socketFile)(nil)
+	_ fsapi.File       = (*socketFile)(nil)
+)
+
+// socketFile is a socket which isn't connected yet. The net package creates
+// the socket once it is, or bound for a UDP socket, so this only keeps the
+// state to create it with.
+type socketFile struct {
+	baseSockFile
+
+	network string
+	// local is the address a TCP socket is bound to, if valid.
+	local    netip.AddrPort
+	nonblock bool
+}
+
+// Network implements the same method as documented on socketapi.Socket
+func (f *socketFile) Network() string {
+	return f.network
+}
+
+// Bind implements the same method as documented on socketapi.Socket
+func (f *socketFile) Bind(addr netip.AddrPort) (socketapi.UDPConn, experimentalsys.Errno) {
+	if f.local.IsValid() {
+		return nil, experimentalsys.EINVAL // Already bound.
+	}
+	if !f.isUDP() {
+		f.local = addr
+		return nil, 0
+	}
+	uc, err := net.ListenUDP(f.network, net.UDPAddrFromAddrPort(addr))
+	if err != nil {
+		return nil, experimentalsys.UnwrapOSError(err)
+	}
+	return &udpConnFile{uc: uc, nonblock: f.nonblock}, 0
+}
+
+// Connect implements the same method as documented on socketapi.Socket
+func (f *socketFile) Connect(ctx context.Context, addr netip.AddrPort) (experimentalsys.File, experimentalsys.Errno) {
+	if f.isUDP() {
+		conn, errno := f.Bind(netip.AddrPortFrom(unspecified(addr), 0))
+		if errno != 0 {
+			return nil, errno
+		}
+		_ = conn.Connect(addr)
+		return conn, 0
+	}
+
+	var dialer net.Dialer
+	if f.local.IsValid() {
+		dialer.LocalAddr = net.TCPAddrFromAddrPort(f.local)
+	}
+	c, err := dialer.DialContext(ctx, f.network, addr.String())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, experimentalsys.EINTR // Interrupted, e.g. by a timeout.
+		}
+		return nil, experimentalsys.UnwrapOSError(err)
+	}
+	conn := newTcpConn(c.(*net.TCPConn))
+	if f.nonblock {
+		if errno := conn.(fsapi.File).SetNonblock(true); errno != 0 {
+			_ = conn.Close()
+			return nil, errno
+		}
+	}
+	return conn, 0
+}
+
+func (f *socketFile) isUDP() bool {
+	return f.network == "udp4" || f.network == "udp6"
+}
+
+// unspecified returns the unspecified address of the family of the address.
+func unspecified(addr netip.AddrPort) netip.Addr {
+	if addr.Addr().Is4() {
+		return netip.IPv4Unspecified()
+	}
+	return netip.IPv6Unspecified()
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (f *socketFile) IsNonblock() bool {
+	return f.nonblock
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (f *socketFile) SetNonblock(enabled bool) experimentalsys.Errno {
+	f.nonblock = enabled
+	return 0
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (f *socketFile) Poll(fsapi.Pflag, int32) (ready bool, errno experimentalsys.Errno) {
+	return false, experimentalsys.ENOTSUP
+}
+
+// Close implements the same method as documented on experimentalsys.File
+func (f *socketFile) Close() experimentalsys.Errno {
+	return 0 // There's no socket yet.
+}
+
+// NewUDPConnFile creates a socketapi.UDPConn for a given *net.UDPConn, which
+// is pre-opened.
+//
+// If the socket is connected, e.g. by net.DialUDP, it only sends to the
+// address it's connected to. Otherwise, Write sends to the address of the last
+// datagram received, so that guests without a function like sendto can reply.
+func NewUDPConnFile(uc *net.UDPConn) socketapi.UDPConn {
+	f := &udpConnFile{uc: uc, reply: true}
+	if remote, ok := uc.RemoteAddr().(*net.UDPAddr); ok {
+		f.remote, f.connected, f.reply = remote.AddrPort(), true, false
+	}
+	return f
+}
+
+var (
+	_ socketapi.UDPConn = (*udpConnFile)(nil)
+	_ fsapi.File        = (*udpConnFile)(nil)
+)
+
+// udpConnFile is a UDP socket, which is bound.
+//
+// The socket isn't connected by the net package, unless pre-opened so, so that
+// datagrams can also be sent to other addresses: Connect only sets the address
+// Write sends to, and Read receives datagrams from any address.
+type udpConnFile struct {
+	baseSockFile
+
+	uc *net.UDPConn
+	// remote is the address Write sends to, if valid.
+	remote netip.AddrPort
+	// connected is true when the net package connected uc to remote, so it
+	// can't send to other addresses.
+	connected bool
+	// reply is true when remote is the address of the last datagram received.
+	reply bool
+
+	// nonblock is true when reads return experimentalsys.EAGAIN instead of
+	// blocking, as checked by polling first.
+	nonblock bool
+	// closed is true when closed was called. This ensures proper experimentalsys.EBADF
+	closed bool
+}
+
+// Connect implements the same method as documented on socketapi.UDPConn
+func (f *udpConnFile) Connect(addr netip.AddrPort) experimentalsys.Errno {
+	if f.connected {
+		return experimentalsys.EINVAL // Like EISCONN.
+	}
+	f.remote, f.reply = addr, false
+	return 0
+}
+
+// Read implements the same method as documented on experimentalsys.File
+func (f *udpConnFile) Read(buf []byte) (n int, errno experimentalsys.Errno) {
+	n, _, errno = f.RecvFrom(buf)
+	return
+}
+
+// RecvFrom implements the same method as documented on socketapi.UDPConn
+func (f *udpConnFile) RecvFrom(p []byte) (n int, addr netip.AddrPort, errno experimentalsys.Errno) {
+	if f.closed {
+		return 0, addr, experimentalsys.EBADF
+	}
+	if f.nonblock {
+		if ready, errno := _pollSock(f.uc, fsapi.POLLIN, 0); errno != 0 {
+			return 0, addr, errno
+		} else if !ready {
+			return 0, addr, experimentalsys.EAGAIN
+		}
+	}
+	n, addr, err := f.uc.ReadFromUDPAddrPort(p)
+	if err != nil {
+		return n, addr, experimentalsys.UnwrapOSError(err)
+	}
+	if f.reply {
+		f.remote = addr
+	}
+	return n, addr, 0
+}
+
+// Write implements the same method as documented on experimentalsys.File
+func (f *udpConnFile) Write(buf []byte) (int, experimentalsys.Errno) {
+	if !f.remote.IsValid() {
+		return 0, experimentalsys.EINVAL // Like EDESTADDRREQ, as not connected.
+	} else if f.connected {
+		if f.closed {
+			return 0, experimentalsys.EBADF
+		}
+		n, err := f.uc.Write(buf)
+		return n, experimentalsys.UnwrapOSError(err)
+	}
+	return f.SendTo(buf, f.remote)
+}
+
+// SendTo implements the same method as documented on socketapi.UDPConn
+func (f *udpConnFile) SendTo(p []byte, addr netip.AddrPort) (int, experimentalsys.Errno) {
+	if f.closed {
+		return 0, experimentalsys.EBADF
+	} else if f.connected {
+		return 0, experimentalsys.EINVAL // Like EISCONN.
+	}
+	n, err := f.uc.WriteToUDPAddrPort(p, addr)
+	return n, experimentalsys.UnwrapOSError(err)
+}
+
+// Addr is exposed for testing.
+func (f *udpConnFile) Addr() *net.UDPAddr {
+	return f.uc.LocalAddr().(*net.UDPAddr)
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (f *udpConnFile) IsNonblock() bool {
+	return f.nonblock
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (f *udpConnFile) SetNonblock(enabled bool) experimentalsys.Errno {
+	f.nonblock = enabled
+	return 0
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (f *udpConnFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	return _pollSock(f.uc, flag, timeoutMillis)
+}
+
+// pollFd implements the same method as documented on pollFdFile
+func (f *udpConnFile) pollFd() (uintptr, bool) {
+	if f.closed {
+		return 0, false
+	}
+	return sockFd(f.uc)
+}
+
+// Close implements the same method as documented on experimentalsys.File
+func (f *udpConnFile) Close() experimentalsys.Errno {
+	if f.closed {
+		return 0
+	}
+	f.closed = true
+	return experimentalsys.UnwrapOSError(f.uc.Close())
+}
@@ -3,8 +3,8 @@
 package sysfs
 
 import (
-	"net"
 	"syscall"
+	"time"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
@@ -26,7 +26,7 @@ func (f *tcpListenerFile) Accept() (socketapi.TCPConn, experimentalsys.Errno) {
 	if conn, err := f.tl.Accept(); err != nil {
 		return nil, experimentalsys.UnwrapOSError(err)
 	} else {
-		return newTcpConn(conn.(*net.TCPConn)), 0
+		return newTcpConn(conn.(streamConn)), 0
 	}
 }
 
@@ -49,7 +49,9 @@ func (f *tcpConnFile) Shutdown(how int) experimentalsys.Errno {
 	case socketapi.SHUT_WR:
 		err = f.tc.CloseWrite()
 	case socketapi.SHUT_RDWR:
-		return f.close()
+		if err = f.tc.CloseRead(); err == nil {
+			err = f.tc.CloseWrite()
+		}
 	default:
 		return experimentalsys.EINVAL
 	}
@@ -75,3 +77,36 @@ func syscallConnControl(conn syscall.Conn, fn func(fd uintptr) (int, experimenta
 	}
 	return
 }
+
+// sockPollInterval is the maximum time pollSockFd polls the file descriptor
+// of a socket at once. The socket can't be closed meanwhile, so this bounds
+// how long Close blocks.
+const sockPollInterval = 100 * time.Millisecond
+
+// pollSockFd polls the file descriptor of the socket with pollFd, until it's
+// ready or the timeout expires, in rounds of at most sockPollInterval. The
+// timeoutMillis is the same as documented on fsapi.File Poll.
+func pollSockFd(conn syscall.Conn, timeoutMillis int32, pollFd func(fd uintptr, timeoutMillis int32) (bool, experimentalsys.Errno)) (ready bool, errno experimentalsys.Errno) {
+	var deadline time.Time
+	if timeoutMillis > 0 {
+		deadline = time.Now().Add(time.Duration(timeoutMillis) * time.Millisecond)
+	}
+	for {
+		wait := timeoutMillis
+		if timeoutMillis > 0 {
+			wait = int32(max(time.Until(deadline).Milliseconds(), 0))
+		}
+		if wait < 0 || wait > int32(sockPollInterval.Milliseconds()) {
+			wait = int32(sockPollInterval.Milliseconds())
+		}
+
+		_, errno = syscallConnControl(conn, func(fd uintptr) (int, experimentalsys.Errno) {
+			var errno experimentalsys.Errno
+			ready, errno = pollFd(fd, wait)
+			return 0, errno
+		})
+		if ready || errno != 0 || timeoutMillis == 0 || (timeoutMillis > 0 && !time.Now().Before(deadline)) {
+			return
+		}
+	}
+}
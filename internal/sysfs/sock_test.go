@@ -1,12 +1,18 @@
 package sysfs
 
 import (
+	"context"
+	"io"
 	"net"
+	"net/netip"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
+	socketapi "github.com/tetratelabs/wazero/internal/sock"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -179,3 +185,137 @@ func TestTcpConnFile_SetNonblock(t *testing.T) {
 	require.EqualErrno(t, 0, errno)
 	require.True(t, file.IsNonblock())
 }
+
+func TestUnixListenerFile_Accept(t *testing.T) {
+	listen, err := net.Listen("unix", filepath.Join(t.TempDir(), "sock"))
+	require.NoError(t, err)
+	file := NewUnixListenerFile(listen.(*net.UnixListener))
+	defer file.Close()
+
+	conn, err := net.Dial("unix", listen.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The listener is ready once a connection is pending.
+	ready, errno := file.(fsapi.File).Poll(fsapi.POLLIN, -1)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	accepted, errno := file.Accept()
+	require.EqualErrno(t, 0, errno)
+	defer accepted.Close()
+
+	_, err = conn.Write([]byte("wazero"))
+	require.NoError(t, err)
+	buf := make([]byte, 6)
+	n, errno := accepted.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+
+	require.EqualErrno(t, 0, accepted.Shutdown(socketapi.SHUT_WR))
+	n, err = conn.Read(buf)
+	require.Equal(t, 0, n)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestUDPConnFile_Reply(t *testing.T) {
+	uc, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	file := NewUDPConnFile(uc)
+	defer file.Close()
+
+	// There's no address to reply to until a datagram is received.
+	_, errno := file.Write([]byte("wazero"))
+	require.EqualErrno(t, sys.EINVAL, errno)
+
+	client, err := net.DialUDP("udp", nil, uc.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 6)
+	n, errno := file.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "ping", string(buf[:n]))
+
+	_, errno = file.Write([]byte("pong"))
+	require.EqualErrno(t, 0, errno)
+	n, err = client.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(buf[:n]))
+}
+
+func TestUDPConnFile_Connected(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+
+	uc, err := net.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	file := NewUDPConnFile(uc)
+	defer file.Close()
+
+	_, errno := file.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 6)
+	n, client, err := server.ReadFromUDP(buf)
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(buf[:n]))
+
+	// A connected socket can't send to other addresses.
+	other := netip.MustParseAddrPort("127.0.0.1:9")
+	_, errno = file.SendTo([]byte("wazero"), other)
+	require.EqualErrno(t, sys.EINVAL, errno)
+	require.EqualErrno(t, sys.EINVAL, file.Connect(other))
+
+	_, err = server.WriteToUDP([]byte("pong"), client)
+	require.NoError(t, err)
+	n, errno = file.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "pong", string(buf[:n]))
+}
+
+func TestSocketFile_Connect(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listen.Close()
+	addr := listen.Addr().(*net.TCPAddr).AddrPort()
+
+	conn, errno := NewSocketFile("tcp4").Connect(context.Background(), addr)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, conn.Close())
+
+	// Connecting is interrupted when the context is done, e.g. when the
+	// module is closed by wazero.RuntimeConfig WithCloseOnContextDone.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errno = NewSocketFile("tcp4").Connect(ctx, addr)
+	require.EqualErrno(t, sys.EINTR, errno)
+}
+
+func TestTcpConnFile_Poll_Close(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("sockets can't be polled on " + runtime.GOOS)
+	}
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listen.Close()
+
+	conn, err := net.Dial("tcp", listen.Addr().String())
+	require.NoError(t, err)
+	file := newTcpConn(conn.(*net.TCPConn))
+
+	// Closing the socket doesn't wait for the timeout of a pending poll.
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		_, _ = file.(fsapi.File).Poll(fsapi.POLLIN, 10000)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	require.EqualErrno(t, 0, file.Close())
+	<-polled
+	require.True(t, time.Since(start) < 5*time.Second)
+}
@@ -3,7 +3,6 @@
 package sysfs
 
 import (
-	"net"
 	"syscall"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
@@ -14,19 +13,14 @@ import (
 // MSG_PEEK is the constant syscall.MSG_PEEK
 const MSG_PEEK = syscall.MSG_PEEK
 
-func newTCPListenerFile(tl *net.TCPListener) socketapi.TCPSock {
+func newTCPListenerFile(tl streamListener) socketapi.TCPSock {
 	return newDefaultTCPListenerFile(tl)
 }
 
 func _pollSock(conn syscall.Conn, flag fsapi.Pflag, timeoutMillis int32) (bool, sys.Errno) {
-	n, errno := syscallConnControl(conn, func(fd uintptr) (int, sys.Errno) {
-		if ready, errno := poll(fd, fsapi.POLLIN, 0); !ready || errno != 0 {
-			return -1, errno
-		} else {
-			return 0, errno
-		}
+	return pollSockFd(conn, timeoutMillis, func(fd uintptr, timeoutMillis int32) (bool, sys.Errno) {
+		return poll(fd, flag, timeoutMillis)
 	})
-	return n >= 0, errno
 }
 
 func setNonblockSocket(fd uintptr, enabled bool) sys.Errno {
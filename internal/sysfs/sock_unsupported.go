@@ -3,7 +3,6 @@
 package sysfs
 
 import (
-	"net"
 	"syscall"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
@@ -15,7 +14,7 @@ import (
 // MSG_PEEK is a filler value.
 const MSG_PEEK = 0x2
 
-func newTCPListenerFile(tl *net.TCPListener) socketapi.TCPSock {
+func newTCPListenerFile(tl streamListener) socketapi.TCPSock {
 	return &unsupportedSockFile{}
 }
 
@@ -3,7 +3,6 @@
 package sysfs
 
 import (
-	"net"
 	"syscall"
 	"unsafe"
 
@@ -30,7 +29,7 @@ var (
 	procioctlsocket = modws2_32.NewProc("ioctlsocket")
 )
 
-func newTCPListenerFile(tl *net.TCPListener) socketapi.TCPSock {
+func newTCPListenerFile(tl streamListener) socketapi.TCPSock {
 	return newDefaultTCPListenerFile(tl)
 }
 
@@ -74,8 +73,8 @@ func _pollSock(conn syscall.Conn, flag fsapi.Pflag, timeoutMillis int32) (bool,
 	if flag != fsapi.POLLIN {
 		return false, sys.ENOTSUP
 	}
-	n, errno := syscallConnControl(conn, func(fd uintptr) (int, sys.Errno) {
-		return _poll([]pollFd{newPollFd(fd, _POLLIN, 0)}, timeoutMillis)
+	return pollSockFd(conn, timeoutMillis, func(fd uintptr, timeoutMillis int32) (bool, sys.Errno) {
+		n, errno := _poll([]pollFd{newPollFd(fd, _POLLIN, 0)}, timeoutMillis)
+		return n > 0, errno
 	})
-	return n > 0, errno
 }
@@ -0,0 +1,57 @@
+package binaryencoding
+
+import (
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// Section IDs of the binary format of components.
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Binary.md#component-definitions
+const (
+	ComponentSectionIDCoreModule   byte = 1
+	ComponentSectionIDCoreInstance byte = 2
+	ComponentSectionIDCoreType     byte = 3
+	ComponentSectionIDComponent    byte = 4
+	ComponentSectionIDInstance     byte = 5
+	ComponentSectionIDAlias        byte = 6
+	ComponentSectionIDType         byte = 7
+	ComponentSectionIDCanon        byte = 8
+	ComponentSectionIDImport       byte = 10
+	ComponentSectionIDExport       byte = 11
+)
+
+// componentPreamble is the version and the layer of components, which follow Magic.
+var componentPreamble = []byte{0x0d, 0x00, 0x01, 0x00}
+
+// EncodeComponent encodes the sections, given by ComponentSection and ComponentVecSection, as a component.
+func EncodeComponent(sections ...[]byte) []byte {
+	ret := append(append([]byte{}, Magic...), componentPreamble...)
+	for _, s := range sections {
+		ret = append(ret, s...)
+	}
+	return ret
+}
+
+// ComponentSection encodes a section of a component whose contents aren't a vector, such as a core module or a
+// nested component.
+func ComponentSection(id byte, contents []byte) []byte {
+	return append([]byte{id}, encodeSizePrefixed(contents)...)
+}
+
+// ComponentVecSection encodes a section of a component whose contents are a vector of the entries.
+func ComponentVecSection(id byte, entries ...[]byte) []byte {
+	return ComponentSection(id, ComponentVec(entries...))
+}
+
+// ComponentName encodes a name of a component, which is size prefixed.
+func ComponentName(name string) []byte {
+	return append(leb128.EncodeUint32(uint32(len(name))), name...)
+}
+
+// ComponentVec concatenates the entries prefixed by their count.
+func ComponentVec(entries ...[]byte) []byte {
+	ret := leb128.EncodeUint32(uint32(len(entries)))
+	for _, e := range entries {
+		ret = append(ret, e...)
+	}
+	return ret
+}
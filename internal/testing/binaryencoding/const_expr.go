@@ -5,7 +5,19 @@ import (
 )
 
 func encodeConstantExpression(expr wasm.ConstantExpression) (ret []byte) {
-	if expr.Opcode == wasm.OpcodeVecV128Const {
+	switch expr.Opcode {
+	case wasm.OpcodeI32Add, wasm.OpcodeI32Sub, wasm.OpcodeI32Mul,
+		wasm.OpcodeI64Add, wasm.OpcodeI64Sub, wasm.OpcodeI64Mul:
+		// The extended constant expression has the preceding instructions in Data.
+		ret = append(ret, expr.Data...)
+		ret = append(ret, expr.Opcode, wasm.OpcodeEnd)
+		return
+	case wasm.OpcodeGCPrefix:
+		// The constant expression allocating objects has all the instructions in Data.
+		ret = append(ret, expr.Data...)
+		ret = append(ret, wasm.OpcodeEnd)
+		return
+	case wasm.OpcodeVecV128Const:
 		ret = append(ret, wasm.OpcodeVecPrefix)
 	}
 	ret = append(ret, expr.Opcode)
@@ -13,7 +13,7 @@ var sizePrefixedName = []byte{4, 'n', 'a', 'm', 'e'}
 func EncodeModule(m *wasm.Module) (bytes []byte) {
 	bytes = append(Magic, version...)
 	if m.SectionElementCount(wasm.SectionIDType) > 0 {
-		bytes = append(bytes, encodeTypeSection(m.TypeSection)...)
+		bytes = append(bytes, encodeTypeSection(m.TypeSection, m.SubTypes)...)
 	}
 	if m.SectionElementCount(wasm.SectionIDImport) > 0 {
 		bytes = append(bytes, encodeImportSection(m.ImportSection)...)
@@ -25,9 +25,11 @@ func EncodeModule(m *wasm.Module) (bytes []byte) {
 		bytes = append(bytes, encodeTableSection(m.TableSection)...)
 	}
 	if m.SectionElementCount(wasm.SectionIDMemory) > 0 {
-		bytes = append(bytes, encodeMemorySection(m.MemorySection)...)
+		bytes = append(bytes, encodeMemorySection(m.MemorySection, m.MultiMemorySection)...)
+	}
+	if m.SectionElementCount(wasm.SectionIDTag) > 0 {
+		bytes = append(bytes, encodeTagSection(m.TagSection)...)
 	}
-	// wasm.SectionIDTag (used for Exceptions) would be here.
 	if m.SectionElementCount(wasm.SectionIDGlobal) > 0 {
 		bytes = append(bytes, encodeGlobalSection(m.GlobalSection)...)
 	}
@@ -10,6 +10,6 @@ import (
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#function-types%E2%91%A4
 func EncodeFunctionType(t *wasm.FunctionType) []byte {
 	// Only reached when "multi-value" is enabled because WebAssembly 1.0 (20191205) supports at most 1 result.
-	data := append([]byte{0x60}, EncodeValTypes(t.Params)...)
-	return append(data, EncodeValTypes(t.Results)...)
+	data := append([]byte{0x60}, encodeValTypesWithRefs(t.Params, t.ParamRefs)...)
+	return append(data, encodeValTypesWithRefs(t.Results, t.ResultRefs)...)
 }
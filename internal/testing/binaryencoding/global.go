@@ -12,7 +12,11 @@ func encodeGlobal(g wasm.Global) (data []byte) {
 	if g.Type.Mutable {
 		mutable = 1
 	}
-	data = []byte{g.Type.ValType, mutable}
+	data = []byte{g.Type.ValType}
+	if g.Type.Ref != nil {
+		data = encodeValTypeWithRef(g.Type.ValType, *g.Type.Ref)
+	}
+	data = append(data, mutable)
 	data = append(data, encodeConstantExpression(g.Init)...)
 	return
 }
@@ -1,6 +1,7 @@
 package binaryencoding
 
 import (
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -12,5 +13,14 @@ func EncodeMemory(i *wasm.Memory) []byte {
 	if !i.IsMaxEncoded {
 		maxPtr = nil
 	}
-	return EncodeLimitsType(i.Min, maxPtr, i.IsShared)
+	ret := EncodeLimitsType(i.Min, maxPtr, i.IsShared)
+	if i.IsMemory64 {
+		// The u64 limits of memory64 are encoded the same as u32 as long as they fit in 32-bit.
+		ret[0] |= 0x04
+	}
+	if i.IsPageSizeEncoded {
+		ret[0] |= 0x08
+		ret = append(ret, leb128.EncodeUint32(i.PageSizeLog2)...)
+	}
+	return ret
 }
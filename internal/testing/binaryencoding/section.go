@@ -16,7 +16,10 @@ func encodeSection(sectionID wasm.SectionID, contents []byte) []byte {
 //
 // See EncodeFunctionType
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#type-section%E2%91%A0
-func encodeTypeSection(types []wasm.FunctionType) []byte {
+func encodeTypeSection(types []wasm.FunctionType, subTypes []wasm.SubType) []byte {
+	if subTypes != nil {
+		return encodeSection(wasm.SectionIDType, encodeRecGroups(types, subTypes))
+	}
 	contents := leb128.EncodeUint32(uint32(len(types)))
 	for i := range types {
 		t := &types[i]
@@ -25,6 +28,68 @@ func encodeTypeSection(types []wasm.FunctionType) []byte {
 	return encodeSection(wasm.SectionIDType, contents)
 }
 
+// encodeRecGroups encodes the types as the recursion groups of experimental.CoreFeaturesGC, where a group of a single
+// type is encoded without the prefix.
+//
+// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md#type-definitions-1
+func encodeRecGroups(types []wasm.FunctionType, subTypes []wasm.SubType) []byte {
+	var groups uint32
+	var contents []byte
+	for i := 0; i < len(subTypes); {
+		size := max(int(subTypes[i].RecGroupSize), 1)
+		if size > 1 {
+			contents = append(contents, wasm.TypeRecGroupPrefix)
+			contents = append(contents, leb128.EncodeUint32(uint32(size))...)
+		}
+		for j := i; j < i+size; j++ {
+			contents = append(contents, encodeSubType(&types[j], &subTypes[j])...)
+		}
+		groups++
+		i += size
+	}
+	return append(leb128.EncodeUint32(groups), contents...)
+}
+
+func encodeSubType(ft *wasm.FunctionType, st *wasm.SubType) (ret []byte) {
+	if !st.Final || st.HasSuperType {
+		if st.Final {
+			ret = append(ret, wasm.TypeSubFinalPrefix)
+		} else {
+			ret = append(ret, wasm.TypeSubPrefix)
+		}
+		if st.HasSuperType {
+			ret = append(ret, 1)
+			ret = append(ret, leb128.EncodeUint32(st.SuperType)...)
+		} else {
+			ret = append(ret, 0)
+		}
+	}
+	switch st.Kind {
+	case wasm.CompositeTypeKindStruct:
+		ret = append(ret, wasm.TypeStructPrefix)
+		ret = append(ret, leb128.EncodeUint32(uint32(len(st.Fields)))...)
+		for _, f := range st.Fields {
+			ret = append(ret, encodeFieldType(f)...)
+		}
+	case wasm.CompositeTypeKindArray:
+		ret = append(ret, wasm.TypeArrayPrefix)
+		ret = append(ret, encodeFieldType(st.Fields[0])...)
+	case wasm.CompositeTypeKindCont:
+		ret = append(ret, wasm.TypeContPrefix)
+		ret = append(ret, leb128.EncodeUint32(st.FuncType)...)
+	default:
+		ret = append(ret, EncodeFunctionType(ft)...)
+	}
+	return
+}
+
+func encodeFieldType(f wasm.FieldType) []byte {
+	if f.Mutable {
+		return []byte{f.Type, 1}
+	}
+	return []byte{f.Type, 0}
+}
+
 // encodeImportSection encodes a wasm.SectionIDImport for the given imports in WebAssembly 1.0 (20191205) Binary
 // Format.
 //
@@ -84,11 +149,27 @@ func encodeTableSection(tables []wasm.Table) []byte {
 //
 // See EncodeMemory
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#memory-section%E2%91%A0
-func encodeMemorySection(memory *wasm.Memory) []byte {
-	contents := append([]byte{1}, EncodeMemory(memory)...)
+func encodeMemorySection(memory *wasm.Memory, rest []*wasm.Memory) []byte {
+	contents := leb128.EncodeUint32(uint32(1 + len(rest)))
+	contents = append(contents, EncodeMemory(memory)...)
+	for _, mem := range rest {
+		contents = append(contents, EncodeMemory(mem)...)
+	}
 	return encodeSection(wasm.SectionIDMemory, contents)
 }
 
+// encodeTagSection encodes a wasm.SectionIDTag for the type indexes in tags.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#tag-section
+func encodeTagSection(tags []wasm.Index) []byte {
+	contents := leb128.EncodeUint32(uint32(len(tags)))
+	for _, typeIndex := range tags {
+		contents = append(contents, 0) // attribute: exception
+		contents = append(contents, leb128.EncodeUint32(typeIndex)...)
+	}
+	return encodeSection(wasm.SectionIDTag, contents)
+}
+
 // encodeGlobalSection encodes a wasm.SectionIDGlobal for the given globals in WebAssembly 1.0 (20191205) Binary
 // Format.
 //
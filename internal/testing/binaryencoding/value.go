@@ -39,3 +39,28 @@ func EncodeValTypes(vt []wasm.ValueType) []byte {
 	count := leb128.EncodeUint32(uint32(len(vt)))
 	return append(count, vt...)
 }
+
+// encodeValTypesWithRefs is like EncodeValTypes, but encodes the reference types precisely when refs is not nil.
+func encodeValTypesWithRefs(vt []wasm.ValueType, refs []wasm.HeapRef) []byte {
+	if refs == nil {
+		return EncodeValTypes(vt)
+	}
+	data := leb128.EncodeUint32(uint32(len(vt)))
+	for i, t := range vt {
+		data = append(data, encodeValTypeWithRef(t, refs[i])...)
+	}
+	return data
+}
+
+// encodeValTypeWithRef encodes the value type, which is a reference type of the heap type ref unless it is the
+// wasm.DefaultHeapRef.
+func encodeValTypeWithRef(vt wasm.ValueType, ref wasm.HeapRef) []byte {
+	if ref == wasm.DefaultHeapRef(vt) {
+		return []byte{vt}
+	}
+	prefix := wasm.RefTypePrefixNullable
+	if ref.NonNullable {
+		prefix = wasm.RefTypePrefixNonNullable
+	}
+	return append([]byte{prefix}, leb128.EncodeInt64(ref.HeapType)...)
+}
@@ -10,6 +10,14 @@ const (
 	EventTypeFdWrite
 )
 
+// Event RW Flags are the flags of an fd_read or fd_write event.
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-eventrwflags-flagsu16
+const (
+	// EVENTRWFLAGS_FD_READWRITE_HANGUP is set when the peer of the socket
+	// closed or disconnected.
+	EVENTRWFLAGS_FD_READWRITE_HANGUP uint16 = 1 << iota //nolint
+)
+
 const (
 	PollOneoffName = "poll_oneoff"
 )
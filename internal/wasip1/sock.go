@@ -9,6 +9,44 @@ const (
 	SockShutdownName = "sock_shutdown"
 )
 
+// The functions below aren't in WASI, but are the socket extensions of
+// WasmEdge, also used by WASIX.
+// See https://github.com/second-state/wasmedge_wasi_socket
+const (
+	SockOpenName        = "sock_open"
+	SockBindName        = "sock_bind"
+	SockConnectName     = "sock_connect"
+	SockGetaddrinfoName = "sock_getaddrinfo"
+	SockSendToName      = "sock_send_to"
+	SockRecvFromName    = "sock_recv_from"
+)
+
+// Address families of the socket extensions, and of the addresses they read
+// or write.
+const (
+	AF_UNSPEC uint8 = iota //nolint
+	AF_INET4
+	AF_INET6
+)
+
+// Socket types of the socket extensions.
+const (
+	SOCK_ANY uint8 = iota //nolint
+	SOCK_DGRAM
+	SOCK_STREAM
+)
+
+// Protocols of the addresses written by sock_getaddrinfo.
+const (
+	IPPROTO_IP uint8 = iota //nolint
+	IPPROTO_TCP
+	IPPROTO_UDP
+)
+
+// AI_PASSIVE is the flag of the hints of sock_getaddrinfo to return the
+// unspecified address when the name is empty, for binding.
+const AI_PASSIVE uint16 = 1 //nolint
+
 // SD Flags indicate which channels on a socket to shut down.
 // https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-sdflags-flagsu8
 const (
@@ -6,11 +6,12 @@ import (
 	"io"
 	"math"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err error) {
+func decodeCode(r *bytes.Reader, codeSectionStart uint64, enabledFeatures api.CoreFeatures, m *wasm.Module, ret *wasm.Code) (err error) {
 	ss, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return fmt.Errorf("get the size of code: %w", err)
@@ -47,9 +48,15 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 		bytesRead += n + 1
 		switch vt := b; vt {
 		case wasm.ValueTypeI32, wasm.ValueTypeF32, wasm.ValueTypeI64, wasm.ValueTypeF64,
-			wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeV128:
+			wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeV128, wasm.ValueTypeExnref:
 		default:
-			return fmt.Errorf("invalid local type: 0x%x", vt)
+			// The reference types of the GC proposal can be longer than a byte.
+			_ = r.UnreadByte()
+			_, _, typeLen, err := wasm.DecodeValueType(r, enabledFeatures)
+			if err != nil {
+				return fmt.Errorf("invalid local type: 0x%x", vt)
+			}
+			bytesRead += typeLen - 1
 		}
 	}
 
@@ -64,22 +71,33 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 	}
 
 	localTypes := make([]wasm.ValueType, 0, sum)
+	var localRefs []wasm.HeapRef
 	for i := uint32(0); i < ls; i++ {
 		num, bytesRead, err := leb128.DecodeUint32(r)
-		remaining -= int64(bytesRead) + 1 // +1 for the subsequent ReadByte
 		if err != nil {
 			return fmt.Errorf("read n of locals: %v", err)
-		} else if remaining < 0 {
-			return io.EOF
 		}
 
-		b, err := r.ReadByte()
+		b, ref, typeLen, err := m.DecodeResolvedValueTypeRef(r, enabledFeatures)
 		if err != nil {
 			return fmt.Errorf("read type of local: %v", err)
 		}
+		remaining -= int64(bytesRead + typeLen)
+		if remaining < 0 {
+			return io.EOF
+		}
 
+		if localRefs == nil && ref != wasm.DefaultHeapRef(b) {
+			localRefs = make([]wasm.HeapRef, len(localTypes), sum)
+			for j, vt := range localTypes {
+				localRefs[j] = wasm.DefaultHeapRef(vt)
+			}
+		}
 		for j := uint32(0); j < num; j++ {
 			localTypes = append(localTypes, b)
+			if localRefs != nil {
+				localRefs = append(localRefs, ref)
+			}
 		}
 	}
 
@@ -95,6 +113,7 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 
 	ret.BodyOffsetInCodeSection = bodyOffsetInCodeSection
 	ret.LocalTypes = localTypes
+	ret.LocalRefs = localRefs
 	ret.Body = body
 	return nil
 }
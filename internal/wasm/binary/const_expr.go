@@ -6,12 +6,19 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/ieee754"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.ConstantExpression) error {
+func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module, ret *wasm.ConstantExpression) error {
+	if enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) {
+		if ok, err := decodeGCConstantExpression(r, enabledFeatures, ret); ok || err != nil {
+			return err
+		}
+	}
+
 	b, err := r.ReadByte()
 	if err != nil {
 		return fmt.Errorf("read opcode: %v", err)
@@ -49,6 +56,33 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 		reftype, err := r.ReadByte()
 		if err != nil {
 			return fmt.Errorf("read reference type for ref.null: %w", err)
+		} else if reftype != wasm.RefTypeFuncref && reftype != wasm.RefTypeExternref &&
+			(enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) ||
+				reftype != wasm.RefTypeExnref && wasm.RequireTypedReferences(enabledFeatures) == nil) {
+			// The heap types of the GC proposal, and the concrete function types of the function-references
+			// proposal, are normalized to the top type of their hierarchy, so that the expression is evaluated
+			// the same way as funcref and externref ones.
+			_ = r.UnreadByte()
+			ht, _, err := wasm.DecodeHeapType(r, enabledFeatures)
+			if err != nil {
+				return fmt.Errorf("read reference type for ref.null: %w", err)
+			}
+			vt, err := m.HeapTypeValueType(ht)
+			if err != nil {
+				return fmt.Errorf("read reference type for ref.null: %w", err)
+			}
+			if b, err = r.ReadByte(); err != nil {
+				return fmt.Errorf("look for end opcode: %v", err)
+			} else if b != wasm.OpcodeEnd {
+				return fmt.Errorf("constant expression has been not terminated")
+			}
+			// The heap type follows the normalized type, which is only used by the validation.
+			ret.Opcode, ret.Data = opcode, append([]byte{vt}, leb128.EncodeInt64(ht)...)
+			return nil
+		} else if reftype == wasm.RefTypeExnref {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("ref.null exn is not supported as %w", err)
+			}
 		} else if reftype != wasm.RefTypeFuncref && reftype != wasm.RefTypeExternref {
 			return fmt.Errorf("invalid type for ref.null: 0x%x", reftype)
 		}
@@ -93,6 +127,12 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 	}
 
 	if b != wasm.OpcodeEnd {
+		if (opcode == wasm.OpcodeI32Const || opcode == wasm.OpcodeI64Const || opcode == wasm.OpcodeGlobalGet) &&
+			enabledFeatures.IsEnabled(experimental.CoreFeaturesExtendedConst) {
+			// The first instruction starts right before its immediate.
+			_ = r.UnreadByte()
+			return decodeExtendedConstantExpression(r, offsetAtData-1, ret)
+		}
 		return fmt.Errorf("constant expression has been not terminated")
 	}
 
@@ -103,3 +143,146 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 	ret.Opcode = opcode
 	return nil
 }
+
+// decodeExtendedConstantExpression decodes the rest of the constant expression which starts at the offset start and
+// has more than one instruction, as allowed by experimental.CoreFeaturesExtendedConst.
+//
+// The last instruction must be one of the arithmetic ones, which is set to wasm.ConstantExpression Opcode, and the
+// preceding instructions are set to its Data as is.
+func decodeExtendedConstantExpression(r *bytes.Reader, start int64, ret *wasm.ConstantExpression) error {
+	var last wasm.Opcode
+	lastOffset := start
+	for {
+		offset := r.Size() - int64(r.Len())
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("look for end opcode: %v", err)
+		}
+
+		switch b {
+		case wasm.OpcodeI32Const:
+			_, _, err = leb128.DecodeInt32(r)
+		case wasm.OpcodeI64Const:
+			_, _, err = leb128.DecodeInt64(r)
+		case wasm.OpcodeGlobalGet:
+			_, _, err = leb128.DecodeUint32(r)
+		case wasm.OpcodeI32Add, wasm.OpcodeI32Sub, wasm.OpcodeI32Mul,
+			wasm.OpcodeI64Add, wasm.OpcodeI64Sub, wasm.OpcodeI64Mul:
+		case wasm.OpcodeEnd:
+			switch last {
+			case wasm.OpcodeI32Add, wasm.OpcodeI32Sub, wasm.OpcodeI32Mul,
+				wasm.OpcodeI64Add, wasm.OpcodeI64Sub, wasm.OpcodeI64Mul:
+			default:
+				// Otherwise, more than one value would be left on the stack.
+				return fmt.Errorf("constant expression has been not terminated")
+			}
+			ret.Data = make([]byte, lastOffset-start)
+			if _, err = r.ReadAt(ret.Data, start); err != nil {
+				return fmt.Errorf("error re-buffering ConstantExpression.Data")
+			}
+			ret.Opcode = last
+			return nil
+		default:
+			return fmt.Errorf("%v for const expression opt code: %#x", ErrInvalidByte, b)
+		}
+		if err != nil {
+			return fmt.Errorf("read value: %v", err)
+		}
+		last, lastOffset = b, offset
+	}
+}
+
+// decodeGCConstantExpression decodes the constant expression which has one of the instructions of
+// experimental.CoreFeaturesGC, such as struct.new, which is set to wasm.ConstantExpression Data as is with
+// wasm.OpcodeGCPrefix as its Opcode.
+//
+// This returns false without consuming r if the expression has no such instruction, or an error is found before
+// the first one, in which case decodeConstantExpression decodes it and reports the error if any.
+func decodeGCConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.ConstantExpression) (bool, error) {
+	start := r.Size() - int64(r.Len())
+	hasGC := false
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if hasGC {
+				return false, fmt.Errorf("look for end opcode: %v", err)
+			}
+			break
+		}
+
+		switch b {
+		case wasm.OpcodeI32Const:
+			_, _, err = leb128.DecodeInt32(r)
+		case wasm.OpcodeI64Const:
+			_, _, err = leb128.DecodeInt64(r)
+		case wasm.OpcodeF32Const:
+			_, err = r.Seek(4, io.SeekCurrent)
+		case wasm.OpcodeF64Const:
+			_, err = r.Seek(8, io.SeekCurrent)
+		case wasm.OpcodeGlobalGet, wasm.OpcodeRefFunc:
+			_, _, err = leb128.DecodeUint32(r)
+		case wasm.OpcodeRefNull:
+			_, _, err = wasm.DecodeHeapType(r, enabledFeatures)
+		case wasm.OpcodeI32Add, wasm.OpcodeI32Sub, wasm.OpcodeI32Mul,
+			wasm.OpcodeI64Add, wasm.OpcodeI64Sub, wasm.OpcodeI64Mul:
+		case wasm.OpcodeVecPrefix:
+			var op byte
+			if op, err = r.ReadByte(); err == nil {
+				if op != wasm.OpcodeVecV128Const {
+					err = fmt.Errorf("invalid vector opcode for const expression: %#x", op)
+				} else {
+					_, err = r.Seek(16, io.SeekCurrent)
+				}
+			}
+		case wasm.OpcodeGCPrefix:
+			hasGC = true
+			err = decodeGCConstantInstruction(r)
+		case wasm.OpcodeEnd:
+			if !hasGC {
+				_, _ = r.Seek(start, io.SeekStart)
+				return false, nil
+			}
+			end := r.Size() - int64(r.Len()) - 1
+			ret.Opcode, ret.Data = wasm.OpcodeGCPrefix, make([]byte, end-start)
+			_, _ = r.ReadAt(ret.Data, start)
+			return true, nil
+		default:
+			err = fmt.Errorf("%v for const expression opt code: %#x", ErrInvalidByte, b)
+		}
+
+		if err != nil {
+			if hasGC {
+				return false, err
+			}
+			break
+		}
+	}
+	_, _ = r.Seek(start, io.SeekStart)
+	return false, nil
+}
+
+// decodeGCConstantInstruction decodes the immediates of the instruction following wasm.OpcodeGCPrefix, which must be
+// one of the constant ones.
+func decodeGCConstantInstruction(r *bytes.Reader) (err error) {
+	op32, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return fmt.Errorf("read GC opcode: %v", err)
+	} else if uint32(byte(op32)) != op32 {
+		return fmt.Errorf("invalid GC opcode for const expression: %#x", op32)
+	}
+	switch op := byte(op32); op {
+	case wasm.OpcodeGCStructNew, wasm.OpcodeGCStructNewDefault, wasm.OpcodeGCArrayNew, wasm.OpcodeGCArrayNewDefault:
+		_, _, err = leb128.DecodeUint32(r)
+	case wasm.OpcodeGCArrayNewFixed:
+		if _, _, err = leb128.DecodeUint32(r); err == nil {
+			_, _, err = leb128.DecodeUint32(r)
+		}
+	case wasm.OpcodeGCRefI31, wasm.OpcodeGCAnyConvertExtern, wasm.OpcodeGCExternConvertAny:
+	default:
+		return fmt.Errorf("invalid GC opcode for const expression: %#x", op)
+	}
+	if err != nil {
+		return fmt.Errorf("read value: %v", err)
+	}
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -86,7 +87,7 @@ func TestDecodeConstantExpression(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			var actual wasm.ConstantExpression
 			err := decodeConstantExpression(bytes.NewReader(tc.in),
-				api.CoreFeatureBulkMemoryOperations|api.CoreFeatureSIMD, &actual)
+				api.CoreFeatureBulkMemoryOperations|api.CoreFeatureSIMD, nil, &actual)
 			require.NoError(t, err)
 			require.Equal(t, tc.exp, actual)
 		})
@@ -184,7 +185,132 @@ func TestDecodeConstantExpression_errors(t *testing.T) {
 		tc := tt
 		t.Run(tc.expectedErr, func(t *testing.T) {
 			var actual wasm.ConstantExpression
-			err := decodeConstantExpression(bytes.NewReader(tc.in), tc.features, &actual)
+			err := decodeConstantExpression(bytes.NewReader(tc.in), tc.features, nil, &actual)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDecodeConstantExpression_ExtendedConst(t *testing.T) {
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst
+
+	t.Run("ok", func(t *testing.T) {
+		// (i64.mul (i64.add (global.get 1) (i64.const 0x80)) (i64.const 2))
+		in := []byte{
+			wasm.OpcodeGlobalGet, 1,
+			wasm.OpcodeI64Const, 0x80, 0x01,
+			wasm.OpcodeI64Add,
+			wasm.OpcodeI64Const, 2,
+			wasm.OpcodeI64Mul,
+			wasm.OpcodeEnd,
+		}
+		var actual wasm.ConstantExpression
+		err := decodeConstantExpression(bytes.NewReader(in), features, nil, &actual)
+		require.NoError(t, err)
+		require.Equal(t, wasm.ConstantExpression{Opcode: wasm.OpcodeI64Mul, Data: in[:len(in)-2]}, actual)
+	})
+
+	tests := []struct {
+		name        string
+		in          []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:        "disabled",
+			in:          []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Add, wasm.OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "constant expression has been not terminated",
+		},
+		{
+			name:        "not ending with arithmetic",
+			in:          []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeEnd},
+			features:    features,
+			expectedErr: "constant expression has been not terminated",
+		},
+		{
+			name:        "not constant",
+			in:          []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeI32DivS, wasm.OpcodeEnd},
+			features:    features,
+			expectedErr: "invalid byte for const expression opt code: 0x6d",
+		},
+		{
+			name:        "missing end",
+			in:          []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Add},
+			features:    features,
+			expectedErr: "look for end opcode: EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var actual wasm.ConstantExpression
+			err := decodeConstantExpression(bytes.NewReader(tc.in), tc.features, nil, &actual)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDecodeConstantExpression_GC(t *testing.T) {
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesGC
+
+	t.Run("allocation", func(t *testing.T) {
+		// (struct.new 0 (i32.const 1) (array.new_fixed 1 2 (ref.i31 (i32.const 2)) (ref.null none)))
+		in := []byte{
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Const, 2,
+			wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31,
+			wasm.OpcodeRefNull, 0x71, // none
+			wasm.OpcodeGCPrefix, wasm.OpcodeGCArrayNewFixed, 1, 2,
+			wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNew, 0,
+			wasm.OpcodeEnd,
+		}
+		var actual wasm.ConstantExpression
+		err := decodeConstantExpression(bytes.NewReader(in), features, nil, &actual)
+		require.NoError(t, err)
+		require.Equal(t, wasm.ConstantExpression{Opcode: wasm.OpcodeGCPrefix, Data: in[:len(in)-1]}, actual)
+	})
+
+	t.Run("without allocation", func(t *testing.T) {
+		in := []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}
+		var actual wasm.ConstantExpression
+		err := decodeConstantExpression(bytes.NewReader(in), features, nil, &actual)
+		require.NoError(t, err)
+		require.Equal(t, wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}}, actual)
+	})
+
+	tests := []struct {
+		name        string
+		in          []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:        "disabled",
+			in:          []byte{wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNewDefault, 0, wasm.OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "invalid byte for const expression opt code: 0xfb",
+		},
+		{
+			name:        "not constant",
+			in:          []byte{wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNewDefault, 0, wasm.OpcodeGCPrefix, wasm.OpcodeGCStructGet, 0, 0, wasm.OpcodeEnd},
+			features:    features,
+			expectedErr: "invalid GC opcode for const expression: 0x2",
+		},
+		{
+			name:        "missing end",
+			in:          []byte{wasm.OpcodeGCPrefix, wasm.OpcodeGCStructNewDefault, 0},
+			features:    features,
+			expectedErr: "look for end opcode: EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var actual wasm.ConstantExpression
+			err := decodeConstantExpression(bytes.NewReader(tc.in), tc.features, nil, &actual)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -20,7 +21,8 @@ const (
 	dataSegmentPrefixActive dataSegmentPrefix = 0x0
 	// dataSegmentPrefixPassive prefixes the "passive" data segment as in version 2.0 specification.
 	dataSegmentPrefixPassive dataSegmentPrefix = 0x1
-	// dataSegmentPrefixActiveWithMemoryIndex is the active prefix with memory index encoded which is defined for futur use as of 2.0.
+	// dataSegmentPrefixActiveWithMemoryIndex is the active prefix with memory index encoded which is defined for futur use as of 2.0,
+	// and the memory index can be non-zero with experimental.CoreFeaturesMultiMemory.
 	dataSegmentPrefixActiveWithMemoryIndex dataSegmentPrefix = 0x2
 )
 
@@ -47,12 +49,13 @@ func decodeDataSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *w
 			d, _, err := leb128.DecodeUint32(r)
 			if err != nil {
 				return fmt.Errorf("read memory index: %v", err)
-			} else if d != 0 {
+			} else if d != 0 && !enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
 				return fmt.Errorf("memory index must be zero but was %d", d)
 			}
+			ret.MemoryIndex = d
 		}
 
-		err = decodeConstantExpression(r, enabledFeatures, &ret.OffsetExpression)
+		err = decodeConstantExpression(r, enabledFeatures, nil, &ret.OffsetExpression)
 		if err != nil {
 			return fmt.Errorf("read offset expression: %v", err)
 		}
@@ -8,6 +8,7 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
@@ -31,7 +32,11 @@ func DecodeModule(
 	}
 
 	// Version.
-	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, version) {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, ErrInvalidVersion
+	} else if bytes.Equal(buf, componentVersion) {
+		return nil, ErrComponent
+	} else if !bytes.Equal(buf, version) {
 		return nil, ErrInvalidVersion
 	}
 
@@ -111,20 +116,20 @@ func DecodeModule(
 				m.NameSection, err = decodeNameSection(r, uint64(limit))
 			}
 		case wasm.SectionIDType:
-			m.TypeSection, err = decodeTypeSection(enabledFeatures, r)
+			err = decodeTypeSection(enabledFeatures, r, m)
 		case wasm.SectionIDImport:
-			m.ImportSection, m.ImportPerModule, m.ImportFunctionCount, m.ImportGlobalCount, m.ImportMemoryCount, m.ImportTableCount, err = decodeImportSection(r, memSizer, memoryLimitPages, enabledFeatures)
+			m.ImportSection, m.ImportPerModule, m.ImportFunctionCount, m.ImportGlobalCount, m.ImportMemoryCount, m.ImportTableCount, m.ImportTagCount, err = decodeImportSection(r, memSizer, memoryLimitPages, enabledFeatures, m)
 			if err != nil {
 				return nil, err // avoid re-wrapping the error.
 			}
 		case wasm.SectionIDFunction:
 			m.FunctionSection, err = decodeFunctionSection(r)
 		case wasm.SectionIDTable:
-			m.TableSection, err = decodeTableSection(r, enabledFeatures)
+			m.TableSection, err = decodeTableSection(r, enabledFeatures, m)
 		case wasm.SectionIDMemory:
-			m.MemorySection, err = decodeMemorySection(r, enabledFeatures, memSizer, memoryLimitPages)
+			m.MemorySection, m.MultiMemorySection, err = decodeMemorySection(r, enabledFeatures, memSizer, memoryLimitPages)
 		case wasm.SectionIDGlobal:
-			if m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures); err != nil {
+			if m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures, m); err != nil {
 				return nil, err // avoid re-wrapping the error.
 			}
 		case wasm.SectionIDExport:
@@ -132,9 +137,9 @@ func DecodeModule(
 		case wasm.SectionIDStart:
 			m.StartSection, err = decodeStartSection(r)
 		case wasm.SectionIDElement:
-			m.ElementSection, err = decodeElementSection(r, enabledFeatures)
+			m.ElementSection, err = decodeElementSection(r, enabledFeatures, m)
 		case wasm.SectionIDCode:
-			m.CodeSection, err = decodeCodeSection(r)
+			m.CodeSection, err = decodeCodeSection(r, enabledFeatures, m)
 		case wasm.SectionIDData:
 			m.DataSection, err = decodeDataSection(r, enabledFeatures)
 		case wasm.SectionIDDataCount:
@@ -142,6 +147,11 @@ func DecodeModule(
 				return nil, fmt.Errorf("data count section not supported as %v", err)
 			}
 			m.DataCountSection, err = decodeDataCountSection(r)
+		case wasm.SectionIDTag:
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return nil, fmt.Errorf("tag section not supported as %v", err)
+			}
+			m.TagSection, err = decodeTagSection(r)
 		default:
 			err = ErrInvalidSectionID
 		}
@@ -176,50 +186,76 @@ func checkSectionOrder(current, previous wasm.SectionID) (byte, bool) {
 		return previous, true
 	}
 
-	// DataCount was introduced in Wasm 2.0,
+	// Tag was introduced by the exception-handling proposal,
 	// and it's the maximum we support so far.
-	// It must come after Element and before Code.
-	if current > wasm.SectionIDDataCount {
+	if current > wasm.SectionIDTag {
 		return current, false
 	}
-	if current == wasm.SectionIDDataCount {
-		return current, previous <= wasm.SectionIDElement
-	}
-	if previous == wasm.SectionIDDataCount {
-		return current, current >= wasm.SectionIDCode
-	}
-
-	// Tag will be introduced in Wasm 3.0.
-	// It must come after Memory and before Global.
 
 	// Otherwise, strictly increasing order.
-	return current, current > previous
+	return current, sectionOrder[current] > sectionOrder[previous]
 }
 
-// memorySizer derives min, capacity and max pages from decoded wasm.
-type memorySizer func(minPages uint32, maxPages *uint32) (min uint32, capacity uint32, max uint32)
+// sectionOrder is the position of each section in a module, indexed by wasm.SectionID.
+// This is needed as sections introduced after WebAssembly 1.0 are not in ID order:
+//   - DataCount (Wasm 2.0) must come after Element and before Code.
+//   - Tag (exception-handling) must come after Memory and before Global.
+var sectionOrder = [...]byte{
+	wasm.SectionIDCustom:    0,
+	wasm.SectionIDType:      1,
+	wasm.SectionIDImport:    2,
+	wasm.SectionIDFunction:  3,
+	wasm.SectionIDTable:     4,
+	wasm.SectionIDMemory:    5,
+	wasm.SectionIDTag:       6,
+	wasm.SectionIDGlobal:    7,
+	wasm.SectionIDExport:    8,
+	wasm.SectionIDStart:     9,
+	wasm.SectionIDElement:   10,
+	wasm.SectionIDDataCount: 11,
+	wasm.SectionIDCode:      12,
+	wasm.SectionIDData:      13,
+}
+
+// memorySizer derives min, capacity and max pages from decoded wasm, where pages are 1<<pageSizeInBits bytes.
+type memorySizer func(minPages uint32, maxPages *uint32, memory64 bool, pageSizeInBits uint32) (min uint32, capacity uint32, max uint32)
 
 // newMemorySizer sets capacity to minPages unless max is defined and
 // memoryCapacityFromMax is true.
 func newMemorySizer(memoryLimitPages uint32, memoryCapacityFromMax bool) memorySizer {
-	return func(minPages uint32, maxPages *uint32) (min, capacity, max uint32) {
+	return func(minPages uint32, maxPages *uint32, memory64 bool, pageSizeInBits uint32) (min, capacity, max uint32) {
+		limit := memoryLimit(memoryLimitPages, memory64, pageSizeInBits)
+		addressLimit := wasm.MemoryLimitPagesInPageSize(wasm.MemoryLimitPages, pageSizeInBits)
+		if memory64 {
+			addressLimit = wasm.Memory64LimitPages
+		}
 		if maxPages != nil {
 			if memoryCapacityFromMax {
 				return minPages, *maxPages, *maxPages
 			}
 			// This is an invalid value: let it propagate, we will fail later.
-			if *maxPages > wasm.MemoryLimitPages {
+			if *maxPages > addressLimit {
 				return minPages, minPages, *maxPages
 			}
 			// This is a valid value, but it goes over the run-time limit: return the limit.
-			if *maxPages > memoryLimitPages {
-				return minPages, minPages, memoryLimitPages
+			if *maxPages > limit {
+				return minPages, minPages, limit
 			}
 			return minPages, minPages, *maxPages
 		}
 		if memoryCapacityFromMax {
-			return minPages, memoryLimitPages, memoryLimitPages
+			return minPages, limit, limit
 		}
-		return minPages, minPages, memoryLimitPages
+		return minPages, minPages, limit
+	}
+}
+
+// memoryLimit returns the run-time limit of pages for a memory. Only a memory64 memory can exceed
+// wasm.MemoryLimitPages, which is the maximum addressable with i32. memoryLimitPages is always in the unit of
+// wasm.MemoryPageSize, but the result is in the unit of 1<<pageSizeInBits bytes.
+func memoryLimit(memoryLimitPages uint32, memory64 bool, pageSizeInBits uint32) uint32 {
+	if !memory64 && memoryLimitPages > wasm.MemoryLimitPages {
+		memoryLimitPages = wasm.MemoryLimitPages
 	}
+	return wasm.MemoryLimitPagesInPageSize(memoryLimitPages, pageSizeInBits)
 }
@@ -241,6 +241,11 @@ func TestDecodeModule_Errors(t *testing.T) {
 			input:       []byte("\x00asm\x01\x00\x00\x01"),
 			expectedErr: "invalid version header",
 		},
+		{
+			name:        "component",
+			input:       []byte("\x00asm\x0d\x00\x01\x00"),
+			expectedErr: "binary is a component, not a module",
+		},
 		{
 			name: "invalid section order",
 			input: append(append(Magic, version...),
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -42,31 +43,39 @@ func decodeElementInitValueVector(r *bytes.Reader) ([]wasm.Index, error) {
 	return vec, nil
 }
 
-func decodeElementConstExprVector(r *bytes.Reader, elemType wasm.RefType, enabledFeatures api.CoreFeatures) ([]wasm.Index, error) {
+// funcIndexVectorRef returns the precise type of the element segments encoded as the vector of function indexes,
+// which is "ref func" as the indexes cannot be null.
+func funcIndexVectorRef() *wasm.HeapRef {
+	return &wasm.HeapRef{HeapType: wasm.HeapTypeFunc, NonNullable: true}
+}
+
+// decodeElementConstExprVector decodes the init vector of the element segment of elemType, and returns the expressions
+// of its items allocating objects as per experimental.CoreFeaturesGC as well.
+func decodeElementConstExprVector(r *bytes.Reader, elemType wasm.RefType, enabledFeatures api.CoreFeatures, m *wasm.Module) (vec []wasm.Index, exprs []wasm.ConstantExpression, err error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get the size of constexpr vector: %w", err)
+		return nil, nil, fmt.Errorf("failed to get the size of constexpr vector: %w", err)
 	}
-	vec := make([]wasm.Index, vs)
+	vec = make([]wasm.Index, vs)
 	for i := range vec {
 		var expr wasm.ConstantExpression
-		err := decodeConstantExpression(r, enabledFeatures, &expr)
+		err := decodeConstantExpression(r, enabledFeatures, m, &expr)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		switch expr.Opcode {
 		case wasm.OpcodeRefFunc:
 			if elemType != wasm.RefTypeFuncref {
-				return nil, fmt.Errorf("element type mismatch: want %s, but constexpr has funcref", wasm.RefTypeName(elemType))
+				return nil, nil, fmt.Errorf("element type mismatch: want %s, but constexpr has funcref", wasm.RefTypeName(elemType))
 			}
 			v, _, _ := leb128.LoadUint32(expr.Data)
 			if v >= wasm.MaximumFunctionIndex {
-				return nil, fmt.Errorf("too large function index in Element init: %d", v)
+				return nil, nil, fmt.Errorf("too large function index in Element init: %d", v)
 			}
 			vec[i] = v
 		case wasm.OpcodeRefNull:
 			if elemType != expr.Data[0] {
-				return nil, fmt.Errorf("element type mismatch: want %s, but constexpr has %s",
+				return nil, nil, fmt.Errorf("element type mismatch: want %s, but constexpr has %s",
 					wasm.RefTypeName(elemType), wasm.RefTypeName(expr.Data[0]))
 			}
 			vec[i] = wasm.ElementInitNullReference
@@ -75,21 +84,37 @@ func decodeElementConstExprVector(r *bytes.Reader, elemType wasm.RefType, enable
 			// Resolving the reference type from globals is done at instantiation phase. See the comment on
 			// wasm.elementInitImportedGlobalReferenceType.
 			vec[i] = wasm.WrapGlobalIndexAsElementInit(wasm.Index(i32))
+		case wasm.OpcodeGCPrefix:
+			// The type of the expression is validated with the globals by wasm.Module.
+			vec[i] = wasm.WrapConstExpressionAsElementInit(wasm.Index(len(exprs)))
+			exprs = append(exprs, expr)
 		default:
-			return nil, fmt.Errorf("const expr must be either ref.null or ref.func but was %s", wasm.InstructionName(expr.Opcode))
+			return nil, nil, fmt.Errorf("const expr must be either ref.null or ref.func but was %s", wasm.InstructionName(expr.Opcode))
 		}
 	}
-	return vec, nil
+	return vec, exprs, nil
 }
 
-func decodeElementRefType(r *bytes.Reader) (ret wasm.RefType, err error) {
+func decodeElementRefType(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module) (ret wasm.RefType, ref *wasm.HeapRef, err error) {
 	ret, err = r.ReadByte()
 	if err != nil {
 		err = fmt.Errorf("read element ref type: %w", err)
 		return
 	}
-	if ret != wasm.RefTypeFuncref && ret != wasm.RefTypeExternref {
-		return 0, errors.New("ref type must be funcref or externref for element as of WebAssembly 2.0")
+	if ret == wasm.RefTypePrefixNullable || ret == wasm.RefTypePrefixNonNullable ||
+		ret != wasm.RefTypeFuncref && ret != wasm.RefTypeExternref && enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) {
+		// The references to function types as per the function-references proposal are normalized to funcref, and the
+		// types of the any hierarchy as per the GC proposal are normalized to anyref.
+		_ = r.UnreadByte()
+		var hr wasm.HeapRef
+		if ret, hr, _, err = m.DecodeResolvedValueTypeRef(r, enabledFeatures); err != nil {
+			err = fmt.Errorf("read element ref type: %w", err)
+			return
+		}
+		ref = wasm.OptionalHeapRef(ret, hr)
+	}
+	if ret != wasm.RefTypeFuncref && ret != wasm.RefTypeExternref && ret != wasm.ValueTypeAnyref {
+		return 0, nil, errors.New("ref type must be funcref or externref for element as of WebAssembly 2.0")
 	}
 	return
 }
@@ -115,7 +140,7 @@ const (
 	elementSegmentPrefixDeclarativeConstExprVector
 )
 
-func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.ElementSegment) error {
+func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module, ret *wasm.ElementSegment) error {
 	prefix, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return fmt.Errorf("read element prefix: %w", err)
@@ -131,7 +156,7 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 	switch prefix {
 	case elementSegmentPrefixLegacy:
 		// Legacy prefix which is WebAssembly 1.0 compatible.
-		err = decodeConstantExpression(r, enabledFeatures, &ret.OffsetExpr)
+		err = decodeConstantExpression(r, enabledFeatures, m, &ret.OffsetExpr)
 		if err != nil {
 			return fmt.Errorf("read expr for offset: %w", err)
 		}
@@ -142,7 +167,7 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 		}
 
 		ret.Mode = wasm.ElementModeActive
-		ret.Type = wasm.RefTypeFuncref
+		ret.Type, ret.Ref = wasm.RefTypeFuncref, funcIndexVectorRef()
 		return nil
 	case elementSegmentPrefixPassiveFuncrefValueVector:
 		// Prefix 1 requires funcref.
@@ -155,7 +180,7 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 			return err
 		}
 		ret.Mode = wasm.ElementModePassive
-		ret.Type = wasm.RefTypeFuncref
+		ret.Type, ret.Ref = wasm.RefTypeFuncref, funcIndexVectorRef()
 		return nil
 	case elementSegmentPrefixActiveFuncrefValueVectorWithTableIndex:
 		ret.TableIndex, _, err = leb128.DecodeUint32(r)
@@ -169,7 +194,7 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 			}
 		}
 
-		err := decodeConstantExpression(r, enabledFeatures, &ret.OffsetExpr)
+		err := decodeConstantExpression(r, enabledFeatures, m, &ret.OffsetExpr)
 		if err != nil {
 			return fmt.Errorf("read expr for offset: %w", err)
 		}
@@ -185,7 +210,7 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 		}
 
 		ret.Mode = wasm.ElementModeActive
-		ret.Type = wasm.RefTypeFuncref
+		ret.Type, ret.Ref = wasm.RefTypeFuncref, funcIndexVectorRef()
 		return nil
 	case elementSegmentPrefixDeclarativeFuncrefValueVector:
 		// Prefix 3 requires funcref.
@@ -196,16 +221,16 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 		if err != nil {
 			return err
 		}
-		ret.Type = wasm.RefTypeFuncref
+		ret.Type, ret.Ref = wasm.RefTypeFuncref, funcIndexVectorRef()
 		ret.Mode = wasm.ElementModeDeclarative
 		return nil
 	case elementSegmentPrefixActiveFuncrefConstExprVector:
-		err := decodeConstantExpression(r, enabledFeatures, &ret.OffsetExpr)
+		err := decodeConstantExpression(r, enabledFeatures, m, &ret.OffsetExpr)
 		if err != nil {
 			return fmt.Errorf("read expr for offset: %w", err)
 		}
 
-		ret.Init, err = decodeElementConstExprVector(r, wasm.RefTypeFuncref, enabledFeatures)
+		ret.Init, ret.InitExpressions, err = decodeElementConstExprVector(r, wasm.RefTypeFuncref, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
@@ -213,11 +238,11 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 		ret.Type = wasm.RefTypeFuncref
 		return nil
 	case elementSegmentPrefixPassiveConstExprVector:
-		ret.Type, err = decodeElementRefType(r)
+		ret.Type, ret.Ref, err = decodeElementRefType(r, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
-		ret.Init, err = decodeElementConstExprVector(r, ret.Type, enabledFeatures)
+		ret.Init, ret.InitExpressions, err = decodeElementConstExprVector(r, ret.Type, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
@@ -234,17 +259,17 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 				return fmt.Errorf("table index must be zero but was %d: %w", ret.TableIndex, err)
 			}
 		}
-		err := decodeConstantExpression(r, enabledFeatures, &ret.OffsetExpr)
+		err := decodeConstantExpression(r, enabledFeatures, m, &ret.OffsetExpr)
 		if err != nil {
 			return fmt.Errorf("read expr for offset: %w", err)
 		}
 
-		ret.Type, err = decodeElementRefType(r)
+		ret.Type, ret.Ref, err = decodeElementRefType(r, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
 
-		ret.Init, err = decodeElementConstExprVector(r, ret.Type, enabledFeatures)
+		ret.Init, ret.InitExpressions, err = decodeElementConstExprVector(r, ret.Type, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
@@ -252,11 +277,11 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret
 		ret.Mode = wasm.ElementModeActive
 		return nil
 	case elementSegmentPrefixDeclarativeConstExprVector:
-		ret.Type, err = decodeElementRefType(r)
+		ret.Type, ret.Ref, err = decodeElementRefType(r, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
-		ret.Init, err = decodeElementConstExprVector(r, ret.Type, enabledFeatures)
+		ret.Init, ret.InitExpressions, err = decodeElementConstExprVector(r, ret.Type, enabledFeatures, m)
 		if err != nil {
 			return err
 		}
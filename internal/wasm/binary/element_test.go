@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -57,6 +58,7 @@ func Test_decodeElementConstExprVector(t *testing.T) {
 		in       []byte
 		refType  wasm.RefType
 		exp      []wasm.Index
+		expExprs []wasm.ConstantExpression
 		features api.CoreFeatures
 	}{
 		{
@@ -109,14 +111,34 @@ func Test_decodeElementConstExprVector(t *testing.T) {
 			refType:  wasm.RefTypeExternref,
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
+		{
+			in: []byte{
+				3, // Three indexes.
+				wasm.OpcodeI32Const, 7, wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31, wasm.OpcodeEnd,
+				wasm.OpcodeRefNull, wasm.ValueTypeAnyref, wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 8, wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31, wasm.OpcodeEnd,
+			},
+			exp: []wasm.Index{
+				wasm.WrapConstExpressionAsElementInit(0),
+				wasm.ElementInitNullReference,
+				wasm.WrapConstExpressionAsElementInit(1),
+			},
+			expExprs: []wasm.ConstantExpression{
+				{Opcode: wasm.OpcodeGCPrefix, Data: []byte{wasm.OpcodeI32Const, 7, wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31}},
+				{Opcode: wasm.OpcodeGCPrefix, Data: []byte{wasm.OpcodeI32Const, 8, wasm.OpcodeGCPrefix, wasm.OpcodeGCRefI31}},
+			},
+			refType:  wasm.ValueTypeAnyref,
+			features: api.CoreFeaturesV2 | experimental.CoreFeaturesGC,
+		},
 	}
 
 	for i, tt := range tests {
 		tc := tt
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			actual, err := decodeElementConstExprVector(bytes.NewReader(tc.in), tc.refType, tc.features)
+			actual, exprs, err := decodeElementConstExprVector(bytes.NewReader(tc.in), tc.refType, tc.features, nil)
 			require.NoError(t, err)
 			require.Equal(t, tc.exp, actual)
+			require.Equal(t, tc.expExprs, exprs)
 		})
 	}
 }
@@ -178,7 +200,7 @@ func Test_decodeElementConstExprVector_errors(t *testing.T) {
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeElementConstExprVector(bytes.NewReader(tc.in), tc.refType, tc.features)
+			_, _, err := decodeElementConstExprVector(bytes.NewReader(tc.in), tc.refType, tc.features, nil)
 			require.EqualError(t, err, tc.expErr)
 		})
 	}
@@ -206,6 +228,7 @@ func TestDecodeElementSegment(t *testing.T) {
 				Init:       []wasm.Index{1, 2, 3, 4, 5},
 				Mode:       wasm.ElementModeActive,
 				Type:       wasm.RefTypeFuncref,
+				Ref:        funcIndexVectorRef(),
 			},
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
@@ -223,6 +246,7 @@ func TestDecodeElementSegment(t *testing.T) {
 				Init:       []wasm.Index{1, 2, 3, 4, 5},
 				Mode:       wasm.ElementModeActive,
 				Type:       wasm.RefTypeFuncref,
+				Ref:        funcIndexVectorRef(),
 			},
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
@@ -238,6 +262,7 @@ func TestDecodeElementSegment(t *testing.T) {
 				Init: []wasm.Index{1, 2, 3, 4, 5},
 				Mode: wasm.ElementModePassive,
 				Type: wasm.RefTypeFuncref,
+				Ref:  funcIndexVectorRef(),
 			},
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
@@ -257,6 +282,7 @@ func TestDecodeElementSegment(t *testing.T) {
 				Init:       []wasm.Index{1, 2, 3, 4, 5},
 				Mode:       wasm.ElementModeActive,
 				Type:       wasm.RefTypeFuncref,
+				Ref:        funcIndexVectorRef(),
 			},
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
@@ -276,6 +302,7 @@ func TestDecodeElementSegment(t *testing.T) {
 				Init:       []wasm.Index{1, 2, 3, 4, 5},
 				Mode:       wasm.ElementModeActive,
 				Type:       wasm.RefTypeFuncref,
+				Ref:        funcIndexVectorRef(),
 				TableIndex: 10,
 			},
 			features: api.CoreFeatureBulkMemoryOperations | api.CoreFeatureReferenceTypes,
@@ -306,6 +333,7 @@ func TestDecodeElementSegment(t *testing.T) {
 				Init: []wasm.Index{1, 2, 3, 4, 5},
 				Mode: wasm.ElementModeDeclarative,
 				Type: wasm.RefTypeFuncref,
+				Ref:  funcIndexVectorRef(),
 			},
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
@@ -453,7 +481,7 @@ func TestDecodeElementSegment(t *testing.T) {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			var actual wasm.ElementSegment
-			err := decodeElementSegment(bytes.NewReader(tc.in), tc.features, &actual)
+			err := decodeElementSegment(bytes.NewReader(tc.in), tc.features, nil, &actual)
 			if tc.expErr != "" {
 				require.EqualError(t, err, tc.expErr)
 			} else {
@@ -466,6 +494,6 @@ func TestDecodeElementSegment(t *testing.T) {
 
 func TestDecodeElementSegment_errors(t *testing.T) {
 	var actual wasm.ElementSegment
-	err := decodeElementSegment(bytes.NewReader([]byte{1}), api.CoreFeatureMultiValue, &actual)
+	err := decodeElementSegment(bytes.NewReader([]byte{1}), api.CoreFeatureMultiValue, nil, &actual)
 	require.EqualError(t, err, `non-zero prefix for element segment is invalid as feature "bulk-memory-operations" is disabled`)
 }
@@ -8,4 +8,5 @@ var (
 	ErrInvalidVersion        = errors.New("invalid version header")
 	ErrInvalidSectionID      = errors.New("invalid section id")
 	ErrCustomSectionNotFound = errors.New("custom section not found")
+	ErrComponent             = errors.New("binary is a component, not a module")
 )
@@ -9,7 +9,9 @@ import (
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeFunctionType(enabledFeatures api.CoreFeatures, r *bytes.Reader, ret *wasm.FunctionType) (err error) {
+// decodeFunctionType decodes a function type in the type section, where references to concrete types are added to
+// fixups to be resolved later.
+func decodeFunctionType(enabledFeatures api.CoreFeatures, r *bytes.Reader, ret *wasm.FunctionType, fixups *[]refTypeFixup) (err error) {
 	b, err := r.ReadByte()
 	if err != nil {
 		return fmt.Errorf("read leading byte: %w", err)
@@ -24,7 +26,7 @@ func decodeFunctionType(enabledFeatures api.CoreFeatures, r *bytes.Reader, ret *
 		return fmt.Errorf("could not read parameter count: %w", err)
 	}
 
-	paramTypes, err := decodeValueTypes(r, paramCount)
+	paramTypes, paramRefs, err := decodeValueTypes(r, paramCount, enabledFeatures, nil, fixups)
 	if err != nil {
 		return fmt.Errorf("could not read parameter types: %w", err)
 	}
@@ -41,16 +43,19 @@ func decodeFunctionType(enabledFeatures api.CoreFeatures, r *bytes.Reader, ret *
 		}
 	}
 
-	resultTypes, err := decodeValueTypes(r, resultCount)
+	resultTypes, resultRefs, err := decodeValueTypes(r, resultCount, enabledFeatures, nil, fixups)
 	if err != nil {
 		return fmt.Errorf("could not read result types: %w", err)
 	}
 
 	ret.Params = paramTypes
 	ret.Results = resultTypes
+	ret.ParamRefs = paramRefs
+	ret.ResultRefs = resultRefs
 
-	// cache the key for the function type
-	_ = ret.String()
-
+	// cache the key for the function type, unless the references are resolved later.
+	if fixups == nil {
+		_ = ret.String()
+	}
 	return nil
 }
@@ -85,7 +85,7 @@ func TestFunctionType(t *testing.T) {
 
 		t.Run(fmt.Sprintf("decode - %s", tc.name), func(t *testing.T) {
 			var actual wasm.FunctionType
-			err := decodeFunctionType(api.CoreFeaturesV2, bytes.NewReader(b), &actual)
+			err := decodeFunctionType(api.CoreFeaturesV2, bytes.NewReader(b), &actual, nil)
 			require.NoError(t, err)
 			// Set the FunctionType key on the input.
 			_ = tc.input.String()
@@ -104,18 +104,28 @@ func TestDecodeFunctionType_Errors(t *testing.T) {
 	}{
 		{
 			name:        "undefined param no result",
-			input:       []byte{0x60, 1, 0x6e, 0},
-			expectedErr: "could not read parameter types: invalid value type: 110",
+			input:       []byte{0x60, 1, 0x7a, 0},
+			expectedErr: "could not read parameter types: invalid value type: 122",
 		},
 		{
 			name:        "no param undefined result",
-			input:       []byte{0x60, 0, 1, 0x6e},
-			expectedErr: "could not read result types: invalid value type: 110",
+			input:       []byte{0x60, 0, 1, 0x7a},
+			expectedErr: "could not read result types: invalid value type: 122",
 		},
 		{
 			name:        "undefined param undefined result",
-			input:       []byte{0x60, 1, 0x6e, 1, 0x6e},
-			expectedErr: "could not read parameter types: invalid value type: 110",
+			input:       []byte{0x60, 1, 0x7a, 1, 0x7a},
+			expectedErr: "could not read parameter types: invalid value type: 122",
+		},
+		{
+			name:        "anyref param - gc not enabled",
+			input:       []byte{0x60, 1, wasm.ValueTypeAnyref, 0},
+			expectedErr: "could not read parameter types: value type anyref invalid as feature \"\" is disabled",
+		},
+		{
+			name:        "concrete reference result - gc not enabled",
+			input:       []byte{0x60, 0, 1, wasm.RefTypePrefixNullable, 0},
+			expectedErr: "could not read result types: reference type 0x63 invalid as feature \"\" is disabled",
 		},
 		{
 			name:        "no param two results - multi-value not enabled",
@@ -139,7 +149,7 @@ func TestDecodeFunctionType_Errors(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			var actual wasm.FunctionType
-			err := decodeFunctionType(api.CoreFeaturesV1, bytes.NewReader(tc.input), &actual)
+			err := decodeFunctionType(api.CoreFeaturesV1, bytes.NewReader(tc.input), &actual, nil)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
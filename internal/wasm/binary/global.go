@@ -11,27 +11,28 @@ import (
 // decodeGlobal returns the api.Global decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-global
-func decodeGlobal(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Global) (err error) {
-	ret.Type, err = decodeGlobalType(r)
+func decodeGlobal(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module, ret *wasm.Global) (err error) {
+	ret.Type, err = decodeGlobalType(r, enabledFeatures, m)
 	if err != nil {
 		return err
 	}
 
-	err = decodeConstantExpression(r, enabledFeatures, &ret.Init)
+	err = decodeConstantExpression(r, enabledFeatures, m, &ret.Init)
 	return
 }
 
 // decodeGlobalType returns the wasm.GlobalType decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-globaltype
-func decodeGlobalType(r *bytes.Reader) (wasm.GlobalType, error) {
-	vt, err := decodeValueTypes(r, 1)
+func decodeGlobalType(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module) (wasm.GlobalType, error) {
+	vt, ref, err := decodeValueType(r, enabledFeatures, m, nil, nil)
 	if err != nil {
 		return wasm.GlobalType{}, fmt.Errorf("read value type: %w", err)
 	}
 
 	ret := wasm.GlobalType{
-		ValType: vt[0],
+		ValType: vt,
+		Ref:     wasm.OptionalHeapRef(vt, ref),
 	}
 
 	b, err := r.ReadByte()
@@ -7,3 +7,7 @@ var Magic = []byte{0x00, 0x61, 0x73, 0x6D}
 // version is format version and doesn't change between known specification versions
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-version
 var version = []byte{0x01, 0x00, 0x00, 0x00}
+
+// componentVersion is the version and the layer of components, which are decoded by the component package.
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Binary.md#component-definitions
+var componentVersion = []byte{0x0d, 0x00, 0x01, 0x00}
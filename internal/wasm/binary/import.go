@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -15,6 +16,7 @@ func decodeImport(
 	memorySizer memorySizer,
 	memoryLimitPages uint32,
 	enabledFeatures api.CoreFeatures,
+	m *wasm.Module,
 	ret *wasm.Import,
 ) (err error) {
 	if ret.Module, _, err = decodeUTF8(r, "import module"); err != nil {
@@ -37,11 +39,17 @@ func decodeImport(
 	case wasm.ExternTypeFunc:
 		ret.DescFunc, _, err = leb128.DecodeUint32(r)
 	case wasm.ExternTypeTable:
-		err = decodeTable(r, enabledFeatures, &ret.DescTable)
+		if err = decodeTable(r, enabledFeatures, m, &ret.DescTable); err == nil && ret.DescTable.Init != nil {
+			err = fmt.Errorf("imported table cannot have an initializer")
+		}
 	case wasm.ExternTypeMemory:
 		ret.DescMem, err = decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages)
 	case wasm.ExternTypeGlobal:
-		ret.DescGlobal, err = decodeGlobalType(r)
+		ret.DescGlobal, err = decodeGlobalType(r, enabledFeatures, m)
+	case wasm.ExternTypeTag:
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err == nil {
+			ret.DescTag, err = decodeTag(r)
+		}
 	default:
 		err = fmt.Errorf("%w: invalid byte for importdesc: %#x", ErrInvalidByte, b)
 	}
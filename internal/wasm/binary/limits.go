@@ -3,23 +3,33 @@ package binary
 import (
 	"bytes"
 	"fmt"
+	"math"
 
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
+// memory64MaxPages is the maximum number of pages allowed by the memory64 proposal (2^48).
+const memory64MaxPages = uint64(1) << 48
+
 // decodeLimitsType returns the `limitsType` (min, max) decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A6
 //
 // Extended in threads proposal: https://webassembly.github.io/threads/core/binary/types.html#limits
-func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, shared bool, err error) {
+//
+// Extended in memory64 proposal, where is64 is true if the limits are encoded as u64:
+// https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md#binary-format
+//
+// Extended in custom-page-sizes proposal, where pageSizeLog2 is non-nil if the flag 0x08 is set:
+// https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md#binary-encoding
+func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, shared, is64 bool, pageSizeLog2 *uint32, err error) {
 	var flag byte
 	if flag, err = r.ReadByte(); err != nil {
 		err = fmt.Errorf("read leading byte: %v", err)
 		return
 	}
 
-	switch flag {
+	switch flag &^ 0x08 {
 	case 0x00, 0x02:
 		min, _, err = leb128.DecodeUint32(r)
 		if err != nil {
@@ -37,11 +47,49 @@ func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, shared bool, er
 		} else {
 			max = &m
 		}
+	case 0x04, 0x06:
+		min, err = decodeLimit64(r, "min")
+	case 0x05, 0x07:
+		if min, err = decodeLimit64(r, "min"); err != nil {
+			return
+		}
+		var m uint32
+		if m, err = decodeLimit64(r, "max"); err == nil {
+			max = &m
+		}
 	default:
-		err = fmt.Errorf("%v for limits: %#x not in (0x00, 0x01, 0x02, 0x03)", ErrInvalidByte, flag)
+		err = fmt.Errorf("%v for limits: %#x not in (0x00, 0x01, ..., 0x0f)", ErrInvalidByte, flag)
+	}
+	if err != nil {
+		return
 	}
 
-	shared = flag == 0x02 || flag == 0x03
+	if flag&0x08 != 0 {
+		var p uint32
+		if p, _, err = leb128.DecodeUint32(r); err != nil {
+			err = fmt.Errorf("read page size of limit: %v", err)
+			return
+		}
+		pageSizeLog2 = &p
+	}
 
+	shared = flag&0x02 != 0
+	is64 = flag&0x04 != 0
 	return
 }
+
+// decodeLimit64 decodes a u64 limit of memory64 in pages. As pages are represented as uint32, the value over that
+// is clamped, which is fine as such a memory can never be allocated anyway.
+func decodeLimit64(r *bytes.Reader, name string) (uint32, error) {
+	v, _, err := leb128.DecodeUint64(r)
+	if err != nil {
+		return 0, fmt.Errorf("read %s of limit: %v", name, err)
+	}
+	if v > memory64MaxPages {
+		return 0, fmt.Errorf("%s %d pages over limit of %d pages", name, v, memory64MaxPages)
+	}
+	if v > math.MaxUint32 {
+		return math.MaxUint32, nil
+	}
+	return uint32(v), nil
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"testing"
 
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -87,11 +88,133 @@ func TestLimitsType(t *testing.T) {
 		})
 
 		t.Run(fmt.Sprintf("decode - %s", tc.name), func(t *testing.T) {
-			min, max, shared, err := decodeLimitsType(bytes.NewReader(b))
+			min, max, shared, is64, pageSizeLog2, err := decodeLimitsType(bytes.NewReader(b))
 			require.NoError(t, err)
 			require.Equal(t, min, tc.min)
 			require.Equal(t, max, tc.max)
 			require.Equal(t, shared, tc.shared)
+			require.False(t, is64)
+			require.Nil(t, pageSizeLog2)
+		})
+	}
+}
+
+func TestLimitsType_64(t *testing.T) {
+	one, largest := uint32(1), uint32(math.MaxUint32)
+
+	tests := []struct {
+		name        string
+		input       []byte
+		min         uint32
+		max         *uint32
+		shared      bool
+		expectedErr string
+	}{
+		{
+			name:  "min 1",
+			input: []byte{0x4, 1},
+			min:   1,
+		},
+		{
+			name:  "min 1, max 1",
+			input: []byte{0x5, 1, 1},
+			min:   1,
+			max:   &one,
+		},
+		{
+			name:   "min 1, max 1, shared",
+			input:  []byte{0x7, 1, 1},
+			min:    1,
+			max:    &one,
+			shared: true,
+		},
+		{
+			name:  "max over uint32 is clamped",
+			input: append([]byte{0x5, 0}, leb128.EncodeUint64(1<<40)...),
+			max:   &largest,
+		},
+		{
+			name:        "max over 2^48",
+			input:       append([]byte{0x5, 0}, leb128.EncodeUint64(1<<48+1)...),
+			expectedErr: "max 281474976710657 pages over limit of 281474976710656 pages",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			min, max, shared, is64, _, err := decodeLimitsType(bytes.NewReader(tc.input))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.min, min)
+			require.Equal(t, tc.max, max)
+			require.Equal(t, tc.shared, shared)
+			require.True(t, is64)
+		})
+	}
+}
+
+func TestLimitsType_PageSize(t *testing.T) {
+	zero, one, sixteen := uint32(0), uint32(1), uint32(16)
+
+	tests := []struct {
+		name         string
+		input        []byte
+		min          uint32
+		max          *uint32
+		is64         bool
+		pageSizeLog2 *uint32
+		expectedErr  string
+	}{
+		{
+			name:         "min 1, page size 1",
+			input:        []byte{0x8, 1, 0},
+			min:          1,
+			pageSizeLog2: &zero,
+		},
+		{
+			name:         "min 1, max 1, page size 64KiB",
+			input:        []byte{0x9, 1, 1, 16},
+			min:          1,
+			max:          &one,
+			pageSizeLog2: &sixteen,
+		},
+		{
+			name:         "min 1, max 1, memory64, page size 1",
+			input:        []byte{0xd, 1, 1, 0},
+			min:          1,
+			max:          &one,
+			is64:         true,
+			pageSizeLog2: &zero,
+		},
+		{
+			name:        "page size missing",
+			input:       []byte{0x8, 1},
+			expectedErr: "read page size of limit: EOF",
+		},
+		{
+			name:        "invalid flag",
+			input:       []byte{0x10, 1},
+			expectedErr: "invalid byte for limits: 0x10 not in (0x00, 0x01, ..., 0x0f)",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			min, max, _, is64, pageSizeLog2, err := decodeLimitsType(bytes.NewReader(tc.input))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.min, min)
+			require.Equal(t, tc.max, max)
+			require.Equal(t, tc.is64, is64)
+			require.Equal(t, tc.pageSizeLog2, pageSizeLog2)
 		})
 	}
 }
@@ -15,14 +15,32 @@ import (
 func decodeMemory(
 	r *bytes.Reader,
 	enabledFeatures api.CoreFeatures,
-	memorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32),
+	memorySizer memorySizer,
 	memoryLimitPages uint32,
 ) (*wasm.Memory, error) {
-	min, maxP, shared, err := decodeLimitsType(r)
+	min, maxP, shared, memory64, pageSizeLog2P, err := decodeLimitsType(r)
 	if err != nil {
 		return nil, err
 	}
 
+	pageSizeInBits := uint32(wasm.MemoryPageSizeInBits)
+	if pageSizeLog2P != nil {
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesCustomPageSizes); err != nil {
+			return nil, fmt.Errorf("custom page size invalid as %w", err)
+		}
+		// Only the page sizes of 1 byte and 64KiB are allowed for now.
+		// https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md#validation
+		if pageSizeInBits = *pageSizeLog2P; pageSizeInBits != 0 && pageSizeInBits != wasm.MemoryPageSizeInBits {
+			return nil, fmt.Errorf("invalid custom page size 2^%d", pageSizeInBits)
+		}
+	}
+
+	if memory64 {
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesMemory64); err != nil {
+			return nil, fmt.Errorf("memory64 invalid as %w", err)
+		}
+	}
+
 	if shared {
 		if !enabledFeatures.IsEnabled(experimental.CoreFeaturesThreads) {
 			return nil, fmt.Errorf("shared memory requested but threads feature not enabled")
@@ -35,8 +53,11 @@ func decodeMemory(
 		}
 	}
 
-	min, capacity, max := memorySizer(min, maxP)
-	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil, IsShared: shared}
+	min, capacity, max := memorySizer(min, maxP, memory64, pageSizeInBits)
+	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil, IsShared: shared, IsMemory64: memory64}
+	if pageSizeLog2P != nil {
+		mem.IsPageSizeEncoded, mem.PageSizeLog2 = true, pageSizeInBits
+	}
 
-	return mem, mem.Validate(memoryLimitPages)
+	return mem, mem.Validate(memoryLimit(memoryLimitPages, memory64, pageSizeInBits))
 }
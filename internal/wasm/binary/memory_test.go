@@ -3,6 +3,7 @@ package binary
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
@@ -111,7 +112,7 @@ func Test_newMemorySizer(t *testing.T) {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			sizer := newMemorySizer(tc.limit, tc.memoryCapacityFromMax)
-			min, capacity, max := sizer(tc.min, tc.max)
+			min, capacity, max := sizer(tc.min, tc.max, false, wasm.MemoryPageSizeInBits)
 			require.Equal(t, tc.expectedMin, min)
 			require.Equal(t, tc.expectedCapacity, capacity)
 			require.Equal(t, tc.expectedMax, max)
@@ -170,6 +171,31 @@ func TestMemoryType(t *testing.T) {
 			input:    &wasm.Memory{Max: 1, IsMaxEncoded: true, IsShared: true},
 			expected: []byte{0x3, 0, 1},
 		},
+		{
+			name:     "min 0, max 1, memory64",
+			input:    &wasm.Memory{Max: 1, IsMaxEncoded: true, IsMemory64: true},
+			expected: []byte{0x5, 0, 1},
+		},
+		{
+			name:     "min 1, default max, memory64",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: max, IsMemory64: true},
+			expected: []byte{0x4, 1},
+		},
+		{
+			name:     "min 1, max 100, page size 1",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: 100, IsMaxEncoded: true, IsPageSizeEncoded: true},
+			expected: []byte{0x9, 1, 100, 0},
+		},
+		{
+			name:     "min 1, default max, page size 1",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: math.MaxUint32, IsPageSizeEncoded: true},
+			expected: []byte{0x8, 1, 0},
+		},
+		{
+			name:     "min 1, default max, page size 64KiB",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: max, IsPageSizeEncoded: true, PageSizeLog2: 16},
+			expected: []byte{0x8, 1, 16},
+		},
 	}
 
 	for _, tt := range tests {
@@ -193,6 +219,12 @@ func TestMemoryType(t *testing.T) {
 			if tc.input.IsShared {
 				features = features.SetEnabled(experimental.CoreFeaturesThreads, true)
 			}
+			if tc.input.IsMemory64 {
+				features = features.SetEnabled(experimental.CoreFeaturesMemory64, true)
+			}
+			if tc.input.IsPageSizeEncoded {
+				features = features.SetEnabled(experimental.CoreFeaturesCustomPageSizes, true)
+			}
 			binary, err := decodeMemory(bytes.NewReader(b), features, newMemorySizer(tmax, false), tmax)
 			require.NoError(t, err)
 			require.Equal(t, binary, expectedDecoded)
@@ -204,10 +236,11 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 	max := wasm.MemoryLimitPages
 
 	tests := []struct {
-		name           string
-		input          []byte
-		threadsEnabled bool
-		expectedErr    string
+		name                   string
+		input                  []byte
+		threadsEnabled         bool
+		customPageSizesEnabled bool
+		expectedErr            string
 	}{
 		{
 			name:        "max < min",
@@ -217,12 +250,12 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 		{
 			name:        "min > limit",
 			input:       []byte{0x0, 0xff, 0xff, 0xff, 0xff, 0xf},
-			expectedErr: "min 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "min 4294967295 pages (255 Ti) over limit of 65536 pages (4 Gi)",
 		},
 		{
 			name:        "max > limit",
 			input:       []byte{0x1, 0, 0xff, 0xff, 0xff, 0xff, 0xf},
-			expectedErr: "max 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "max 4294967295 pages (255 Ti) over limit of 65536 pages (4 Gi)",
 		},
 		{
 			name:        "shared but no threads",
@@ -235,6 +268,28 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 			threadsEnabled: true,
 			expectedErr:    "shared memory requires a maximum size to be specified",
 		},
+		{
+			name:        "memory64 disabled",
+			input:       []byte{0x4, 0},
+			expectedErr: `memory64 invalid as feature "" is disabled`,
+		},
+		{
+			name:        "custom page size disabled",
+			input:       []byte{0x8, 0, 0},
+			expectedErr: `custom page size invalid as feature "" is disabled`,
+		},
+		{
+			name:                   "invalid custom page size",
+			input:                  []byte{0x8, 0, 12},
+			customPageSizesEnabled: true,
+			expectedErr:            "invalid custom page size 2^12",
+		},
+		{
+			name:                   "max < min, page size 1",
+			input:                  []byte{0x9, 2, 1, 0},
+			customPageSizesEnabled: true,
+			expectedErr:            "min 2 pages (2 B) > max 1 pages (1 B)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -248,6 +303,9 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 				// Allow test to work if threads is ever added to default features by explicitly removing threads features
 				features = features.SetEnabled(experimental.CoreFeaturesThreads, false)
 			}
+			if tc.customPageSizesEnabled {
+				features = features.SetEnabled(experimental.CoreFeaturesCustomPageSizes, true)
+			}
 			_, err := decodeMemory(bytes.NewReader(tc.input), features, newMemorySizer(max, false), max)
 			require.EqualError(t, err, tc.expectedErr)
 		})
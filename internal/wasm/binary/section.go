@@ -6,23 +6,80 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeTypeSection(enabledFeatures api.CoreFeatures, r *bytes.Reader) ([]wasm.FunctionType, error) {
+// decodeTypeSection decodes the type section into m.TypeSection, and m.SubTypes if it uses the encoding of the GC
+// proposal, where each entry of the vector is a recursion group which can define multiple types.
+func decodeTypeSection(enabledFeatures api.CoreFeatures, r *bytes.Reader, m *wasm.Module) error {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
-		return nil, fmt.Errorf("get size of vector: %w", err)
+		return fmt.Errorf("get size of vector: %w", err)
 	}
 
-	result := make([]wasm.FunctionType, vs)
+	types := make([]wasm.FunctionType, 0, vs)
+	subTypes := make([]wasm.SubType, 0, vs)
+	var usesGC bool
+	var fixups []refTypeFixup
 	for i := uint32(0); i < vs; i++ {
-		if err = decodeFunctionType(enabledFeatures, r, &result[i]); err != nil {
-			return nil, fmt.Errorf("read %d-th type: %v", i, err)
+		groupSize := uint32(1)
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("read %d-th type: %v", i, err)
+		}
+		if b == wasm.TypeRecGroupPrefix {
+			if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+				return fmt.Errorf("read %d-th type: recursion group invalid as %v", i, err)
+			}
+			if groupSize, _, err = leb128.DecodeUint32(r); err != nil {
+				return fmt.Errorf("read %d-th type: get size of recursion group: %v", i, err)
+			}
+			usesGC = true
+		} else {
+			_ = r.UnreadByte()
+		}
+
+		groupStart := uint32(len(types))
+		for j := uint32(0); j < groupSize; j++ {
+			types = append(types, wasm.FunctionType{})
+			subTypes = append(subTypes, wasm.SubType{RecGroupStart: groupStart, RecGroupSize: groupSize})
+			gc, err := decodeSubType(enabledFeatures, r, &types[len(types)-1], &subTypes[len(subTypes)-1], &fixups)
+			if err != nil {
+				return fmt.Errorf("read %d-th type: %v", i, err)
+			}
+			usesGC = usesGC || gc
+		}
+
+		// The types in the group can refer to each other, so resolve the references after decoding all of them.
+		for _, f := range fixups {
+			if f.ht >= int64(len(types)) ||
+				// Without the recursion groups of the GC proposal, a type can only refer to the types before it.
+				(f.ht >= int64(groupStart) && !enabledFeatures.IsEnabled(experimental.CoreFeaturesGC)) {
+				return fmt.Errorf("read %d-th type: unknown type: %d", i, f.ht)
+			}
+			switch subTypes[f.ht].Kind {
+			case wasm.CompositeTypeKindFunc:
+				*f.vt = wasm.ValueTypeFuncref
+			case wasm.CompositeTypeKindCont:
+				*f.vt = wasm.ValueTypeContref
+			default:
+				*f.vt = wasm.ValueTypeAnyref
+			}
+		}
+		fixups = fixups[:0]
+		for j := groupStart; j < uint32(len(types)); j++ {
+			// cache the key for the function type
+			_ = types[j].String()
 		}
 	}
-	return result, nil
+
+	m.TypeSection = types
+	if usesGC {
+		m.SubTypes = subTypes
+	}
+	return nil
 }
 
 // decodeImportSection decodes the decoded import segments plus the count per wasm.ExternType.
@@ -31,9 +88,10 @@ func decodeImportSection(
 	memorySizer memorySizer,
 	memoryLimitPages uint32,
 	enabledFeatures api.CoreFeatures,
+	m *wasm.Module,
 ) (result []wasm.Import,
 	perModule map[string][]*wasm.Import,
-	funcCount, globalCount, memoryCount, tableCount wasm.Index, err error,
+	funcCount, globalCount, memoryCount, tableCount, tagCount wasm.Index, err error,
 ) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
@@ -45,7 +103,7 @@ func decodeImportSection(
 	result = make([]wasm.Import, vs)
 	for i := uint32(0); i < vs; i++ {
 		imp := &result[i]
-		if err = decodeImport(r, i, memorySizer, memoryLimitPages, enabledFeatures, imp); err != nil {
+		if err = decodeImport(r, i, memorySizer, memoryLimitPages, enabledFeatures, m, imp); err != nil {
 			return
 		}
 		switch imp.Type {
@@ -61,6 +119,9 @@ func decodeImportSection(
 		case wasm.ExternTypeTable:
 			imp.IndexPerType = tableCount
 			tableCount++
+		case wasm.ExternTypeTag:
+			imp.IndexPerType = tagCount
+			tagCount++
 		}
 		perModule[imp.Module] = append(perModule[imp.Module], imp)
 	}
@@ -82,7 +143,22 @@ func decodeFunctionSection(r *bytes.Reader) ([]uint32, error) {
 	return result, err
 }
 
-func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.Table, error) {
+func decodeTagSection(r *bytes.Reader) ([]wasm.Index, error) {
+	vs, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return nil, fmt.Errorf("get size of vector: %w", err)
+	}
+
+	result := make([]wasm.Index, vs)
+	for i := uint32(0); i < vs; i++ {
+		if result[i], err = decodeTag(r); err != nil {
+			return nil, fmt.Errorf("tag[%d]: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
+func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module) ([]wasm.Table, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("error reading size")
@@ -95,7 +171,7 @@ func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wa
 
 	ret := make([]wasm.Table, vs)
 	for i := range ret {
-		err = decodeTable(r, enabledFeatures, &ret[i])
+		err = decodeTable(r, enabledFeatures, m, &ret[i])
 		if err != nil {
 			return nil, err
 		}
@@ -108,22 +184,33 @@ func decodeMemorySection(
 	enabledFeatures api.CoreFeatures,
 	memorySizer memorySizer,
 	memoryLimitPages uint32,
-) (*wasm.Memory, error) {
+) (*wasm.Memory, []*wasm.Memory, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
-		return nil, fmt.Errorf("error reading size")
+		return nil, nil, fmt.Errorf("error reading size")
 	}
-	if vs > 1 {
-		return nil, fmt.Errorf("at most one memory allowed in module, but read %d", vs)
+	if vs > 1 && !enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+		return nil, nil, fmt.Errorf("at most one memory allowed in module, but read %d", vs)
 	} else if vs == 0 {
 		// memory count can be zero.
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	return decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages)
+	mem, err := decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages)
+	if err != nil || vs == 1 {
+		return mem, nil, err
+	}
+
+	rest := make([]*wasm.Memory, vs-1)
+	for i := range rest {
+		if rest[i], err = decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages); err != nil {
+			return nil, nil, err
+		}
+	}
+	return mem, rest, nil
 }
 
-func decodeGlobalSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.Global, error) {
+func decodeGlobalSection(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module) ([]wasm.Global, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
@@ -131,7 +218,7 @@ func decodeGlobalSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]w
 
 	result := make([]wasm.Global, vs)
 	for i := uint32(0); i < vs; i++ {
-		if err = decodeGlobal(r, enabledFeatures, &result[i]); err != nil {
+		if err = decodeGlobal(r, enabledFeatures, m, &result[i]); err != nil {
 			return nil, fmt.Errorf("global[%d]: %w", i, err)
 		}
 	}
@@ -169,7 +256,7 @@ func decodeStartSection(r *bytes.Reader) (*wasm.Index, error) {
 	return &vs, nil
 }
 
-func decodeElementSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.ElementSegment, error) {
+func decodeElementSection(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module) ([]wasm.ElementSegment, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
@@ -177,14 +264,14 @@ func decodeElementSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]
 
 	result := make([]wasm.ElementSegment, vs)
 	for i := uint32(0); i < vs; i++ {
-		if err = decodeElementSegment(r, enabledFeatures, &result[i]); err != nil {
+		if err = decodeElementSegment(r, enabledFeatures, m, &result[i]); err != nil {
 			return nil, fmt.Errorf("read element: %w", err)
 		}
 	}
 	return result, nil
 }
 
-func decodeCodeSection(r *bytes.Reader) ([]wasm.Code, error) {
+func decodeCodeSection(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module) ([]wasm.Code, error) {
 	codeSectionStart := uint64(r.Len())
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
@@ -193,7 +280,7 @@ func decodeCodeSection(r *bytes.Reader) ([]wasm.Code, error) {
 
 	result := make([]wasm.Code, vs)
 	for i := uint32(0); i < vs; i++ {
-		err = decodeCode(r, codeSectionStart, &result[i])
+		err = decodeCode(r, codeSectionStart, enabledFeatures, m, &result[i])
 		if err != nil {
 			return nil, fmt.Errorf("read %d-th code segment: %v", i, err)
 		}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -45,7 +46,7 @@ func TestTableSection(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			tables, err := decodeTableSection(bytes.NewReader(tc.input), api.CoreFeatureReferenceTypes)
+			tables, err := decodeTableSection(bytes.NewReader(tc.input), api.CoreFeatureReferenceTypes, nil)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, tables)
 		})
@@ -75,7 +76,7 @@ func TestTableSection_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeTableSection(bytes.NewReader(tc.input), tc.features)
+			_, err := decodeTableSection(bytes.NewReader(tc.input), tc.features, nil)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -86,9 +87,11 @@ func TestMemorySection(t *testing.T) {
 
 	three := uint32(3)
 	tests := []struct {
-		name     string
-		input    []byte
-		expected *wasm.Memory
+		name         string
+		input        []byte
+		features     api.CoreFeatures
+		expected     *wasm.Memory
+		expectedRest []*wasm.Memory
 	}{
 		{
 			name: "min and min with max",
@@ -96,17 +99,30 @@ func TestMemorySection(t *testing.T) {
 				0x01,             // 1 memory
 				0x01, 0x02, 0x03, // (memory 2 3)
 			},
+			features: api.CoreFeaturesV2,
 			expected: &wasm.Memory{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true},
 		},
+		{
+			name: "multiple memories",
+			input: []byte{
+				0x02,       // 2 memories
+				0x00, 0x01, //  (memory 1)
+				0x01, 0x02, 0x03, // (memory 2 3)
+			},
+			features:     api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory,
+			expected:     &wasm.Memory{Min: 1, Cap: 1, Max: max},
+			expectedRest: []*wasm.Memory{{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true}},
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			memories, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max)
+			memory, rest, err := decodeMemorySection(bytes.NewReader(tc.input), tc.features, newMemorySizer(max, false), max)
 			require.NoError(t, err)
-			require.Equal(t, tc.expected, memories)
+			require.Equal(t, tc.expected, memory)
+			require.Equal(t, tc.expectedRest, rest)
 		})
 	}
 }
@@ -134,7 +150,7 @@ func TestMemorySection_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max)
+			_, _, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
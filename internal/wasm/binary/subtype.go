@@ -0,0 +1,124 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// decodeSubType decodes a type in a recursion group of the type section, which is a function type unless
+// experimental.CoreFeaturesGC or experimental.CoreFeaturesStackSwitching is enabled. usesGC is true if the type uses
+// the encoding of the GC proposal or is a continuation type, which are defined in wasm.Module SubTypes.
+//
+// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md#type-definitions-1
+func decodeSubType(enabledFeatures api.CoreFeatures, r *bytes.Reader, ft *wasm.FunctionType, st *wasm.SubType, fixups *[]refTypeFixup) (usesGC bool, err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return false, fmt.Errorf("read leading byte: %w", err)
+	}
+
+	st.Final = true
+	if b == wasm.TypeSubPrefix || b == wasm.TypeSubFinalPrefix {
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+			return false, fmt.Errorf("subtype invalid as %v", err)
+		}
+		usesGC = true
+		st.Final = b == wasm.TypeSubFinalPrefix
+
+		superCount, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return false, fmt.Errorf("get size of supertypes: %w", err)
+		}
+		switch superCount {
+		case 0:
+		case 1:
+			st.HasSuperType = true
+			if st.SuperType, _, err = leb128.DecodeUint32(r); err != nil {
+				return false, fmt.Errorf("read supertype: %w", err)
+			}
+		default:
+			return false, fmt.Errorf("at most one supertype is allowed, but got %d", superCount)
+		}
+
+		if b, err = r.ReadByte(); err != nil {
+			return false, fmt.Errorf("read leading byte of composite type: %w", err)
+		}
+	}
+
+	switch b {
+	case wasm.TypeStructPrefix:
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+			return false, fmt.Errorf("struct type invalid as %v", err)
+		}
+		st.Kind = wasm.CompositeTypeKindStruct
+		fieldCount, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return false, fmt.Errorf("get size of fields: %w", err)
+		}
+		st.Fields = make([]wasm.FieldType, fieldCount)
+		for i := range st.Fields {
+			if err = decodeFieldType(enabledFeatures, r, &st.Fields[i], fixups); err != nil {
+				return false, fmt.Errorf("read field[%d]: %w", i, err)
+			}
+		}
+		return true, nil
+	case wasm.TypeArrayPrefix:
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+			return false, fmt.Errorf("array type invalid as %v", err)
+		}
+		st.Kind = wasm.CompositeTypeKindArray
+		st.Fields = make([]wasm.FieldType, 1)
+		if err = decodeFieldType(enabledFeatures, r, &st.Fields[0], fixups); err != nil {
+			return false, fmt.Errorf("read element type: %w", err)
+		}
+		return true, nil
+	case wasm.TypeContPrefix:
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesStackSwitching); err != nil {
+			return false, fmt.Errorf("continuation type invalid as %v", err)
+		}
+		st.Kind = wasm.CompositeTypeKindCont
+		if st.FuncType, _, err = leb128.DecodeUint32(r); err != nil {
+			return false, fmt.Errorf("read function type of continuation type: %w", err)
+		}
+		return true, nil
+	default:
+		_ = r.UnreadByte()
+		st.Kind = wasm.CompositeTypeKindFunc
+		return usesGC, decodeFunctionType(enabledFeatures, r, ft, fixups)
+	}
+}
+
+// decodeFieldType decodes the storage type and mutability of a struct field or array elements.
+func decodeFieldType(enabledFeatures api.CoreFeatures, r *bytes.Reader, ret *wasm.FieldType, fixups *[]refTypeFixup) (err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read storage type: %w", err)
+	}
+	if b == wasm.StorageTypeI8 || b == wasm.StorageTypeI16 {
+		ret.Type = b
+	} else {
+		_ = r.UnreadByte()
+		var ref wasm.HeapRef
+		if ret.Type, ref, err = decodeValueType(r, enabledFeatures, nil, fixups, &ret.Type); err != nil {
+			return err
+		}
+		ret.Ref = wasm.OptionalHeapRef(ret.Type, ref)
+	}
+
+	b, err = r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read mutability: %w", err)
+	}
+	switch b {
+	case 0x00: // not mutable
+	case 0x01: // mutable
+		ret.Mutable = true
+	default:
+		return fmt.Errorf("%w for mutability: %#x != 0x00 or 0x01", ErrInvalidByte, b)
+	}
+	return nil
+}
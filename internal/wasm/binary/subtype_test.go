@@ -0,0 +1,128 @@
+package binary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestDecodeTypeSection_GC(t *testing.T) {
+	input := []byte{
+		0x02,       // 2 recursion groups
+		0x4e, 0x02, // (rec
+		0x50, 0x00, 0x5f, 0x01, 0x63, 0x01, 0x01, //   (type (sub (struct (field (mut (ref null 1)))))
+		0x5e, 0x78, 0x00, //   (type (array i8)))
+		0x4f, 0x01, 0x00, 0x5f, 0x02, 0x63, 0x01, 0x01, 0x7f, 0x00, // (type (sub final 0 (struct (field (mut (ref null 1)) i32))))
+	}
+
+	m := &wasm.Module{}
+	err := decodeTypeSection(api.CoreFeaturesV2|experimental.CoreFeaturesGC, bytes.NewReader(input), m)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(m.TypeSection))
+	require.Equal(t, []wasm.SubType{
+		{
+			Kind:         wasm.CompositeTypeKindStruct,
+			Fields:       []wasm.FieldType{{Type: wasm.ValueTypeAnyref, Ref: &wasm.HeapRef{HeapType: 1}, Mutable: true}},
+			RecGroupSize: 2,
+		},
+		{
+			Kind:          wasm.CompositeTypeKindArray,
+			Fields:        []wasm.FieldType{{Type: wasm.StorageTypeI8}},
+			Final:         true,
+			RecGroupStart: 0,
+			RecGroupSize:  2,
+		},
+		{
+			Kind:          wasm.CompositeTypeKindStruct,
+			Fields:        []wasm.FieldType{{Type: wasm.ValueTypeAnyref, Ref: &wasm.HeapRef{HeapType: 1}, Mutable: true}, {Type: wasm.ValueTypeI32}},
+			Final:         true,
+			HasSuperType:  true,
+			SuperType:     0,
+			RecGroupStart: 2,
+			RecGroupSize:  1,
+		},
+	}, m.SubTypes)
+}
+
+func TestDecodeTypeSection_GC_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:        "recursion group - gc not enabled",
+			input:       []byte{0x01, 0x4e, 0x01, 0x5f, 0x00},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "read 0-th type: recursion group invalid as feature \"\" is disabled",
+		},
+		{
+			name:        "struct - gc not enabled",
+			input:       []byte{0x01, 0x5f, 0x00},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "read 0-th type: struct type invalid as feature \"\" is disabled",
+		},
+		{
+			name:        "too many supertypes",
+			input:       []byte{0x01, 0x50, 0x02, 0x00, 0x00, 0x5f, 0x00},
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesGC,
+			expectedErr: "read 0-th type: at most one supertype is allowed, but got 2",
+		},
+		{
+			name:        "unknown type",
+			input:       []byte{0x01, 0x5e, 0x63, 0x05, 0x00},
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesGC,
+			expectedErr: "read 0-th type: unknown type: 5",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := decodeTypeSection(tc.features, bytes.NewReader(tc.input), &wasm.Module{})
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDecodeTypeSection_FunctionReferences(t *testing.T) {
+	input := []byte{
+		0x02,             // 2 types
+		0x60, 0x00, 0x00, // (type (func))
+		0x60, 0x02, 0x63, 0x00, 0x64, 0x00, 0x01, 0x64, 0x00, // (type (func (param (ref null 0) (ref 0)) (result (ref 0))))
+	}
+
+	m := &wasm.Module{}
+	err := decodeTypeSection(api.CoreFeaturesV2|experimental.CoreFeaturesFunctionReferences, bytes.NewReader(input), m)
+	require.NoError(t, err)
+	require.Equal(t, []wasm.ValueType{wasm.ValueTypeFuncref, wasm.ValueTypeFuncref}, m.TypeSection[1].Params)
+	require.Equal(t, []wasm.ValueType{wasm.ValueTypeFuncref}, m.TypeSection[1].Results)
+	// The typed references to function types don't use the encoding of the GC proposal.
+	require.Nil(t, m.SubTypes)
+
+	err = decodeTypeSection(api.CoreFeaturesV2, bytes.NewReader(input), &wasm.Module{})
+	require.EqualError(t, err, "read 1-th type: could not read parameter types: reference type 0x63 invalid as feature \"\" is disabled")
+}
+
+func TestDecodeTypeSection_StackSwitching(t *testing.T) {
+	input := []byte{
+		0x02,                   // 2 types
+		0x60, 0x01, 0x7f, 0x00, // (type (func (param i32)))
+		0x5d, 0x00, // (type (cont 0))
+	}
+
+	m := &wasm.Module{}
+	err := decodeTypeSection(api.CoreFeaturesV2|experimental.CoreFeaturesStackSwitching, bytes.NewReader(input), m)
+	require.NoError(t, err)
+	require.Equal(t, wasm.CompositeTypeKindCont, m.SubTypes[1].Kind)
+	require.Equal(t, wasm.Index(0), m.SubTypes[1].FuncType)
+	require.Equal(t, &m.TypeSection[0], m.ContinuationType(1))
+
+	err = decodeTypeSection(api.CoreFeaturesV2, bytes.NewReader(input), &wasm.Module{})
+	require.EqualError(t, err, "read 1-th type: continuation type invalid as feature \"\" is disabled")
+}
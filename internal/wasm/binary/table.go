@@ -5,17 +5,52 @@ import (
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
+// tableWithInitPrefix is the prefix of a table in the table section which has the initializer expression.
+const tableWithInitPrefix = 0x40
+
 // decodeTable returns the wasm.Table decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-table
-func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Table) (err error) {
-	ret.Type, err = r.ReadByte()
+func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module, ret *wasm.Table) (err error) {
+	b, err := r.ReadByte()
 	if err != nil {
 		return fmt.Errorf("read leading byte: %v", err)
 	}
+	if b == tableWithInitPrefix {
+		// The table type followed by the initial value of its elements as per the function-references proposal.
+		if err = wasm.RequireTypedReferences(enabledFeatures); err != nil {
+			return fmt.Errorf("table initializer invalid as %v", err)
+		}
+		if b, err = r.ReadByte(); err != nil {
+			return fmt.Errorf("read reserved byte: %v", err)
+		} else if b != 0 {
+			return fmt.Errorf("%w: table initializer reserved byte must be zero but was 0x%x", ErrInvalidByte, b)
+		}
+		if err = decodeTable(r, enabledFeatures, m, ret); err != nil {
+			return err
+		}
+		ret.Init = &wasm.ConstantExpression{}
+		if err = decodeConstantExpression(r, enabledFeatures, m, ret.Init); err != nil {
+			return fmt.Errorf("read table initializer: %v", err)
+		}
+		return nil
+	}
+	ret.Type = b
+	if b != wasm.RefTypeFuncref && b != wasm.RefTypeExternref && (enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) ||
+		(b == wasm.RefTypePrefixNullable || b == wasm.RefTypePrefixNonNullable) && wasm.RequireTypedReferences(enabledFeatures) == nil) {
+		// The reference types of the GC and function-references proposals, which are normalized to the top type of
+		// their hierarchy.
+		_ = r.UnreadByte()
+		var ref wasm.HeapRef
+		if ret.Type, ref, _, err = m.DecodeResolvedValueTypeRef(r, enabledFeatures); err != nil {
+			return fmt.Errorf("read table type: %v", err)
+		}
+		ret.Ref = wasm.OptionalHeapRef(ret.Type, ref)
+	}
 
 	if ret.Type != wasm.RefTypeFuncref {
 		if err = enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
@@ -23,11 +58,18 @@ func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Ta
 		}
 	}
 
-	var shared bool
-	ret.Min, ret.Max, shared, err = decodeLimitsType(r)
+	var shared, is64 bool
+	var pageSizeLog2 *uint32
+	ret.Min, ret.Max, shared, is64, pageSizeLog2, err = decodeLimitsType(r)
 	if err != nil {
 		return fmt.Errorf("read limits: %v", err)
 	}
+	if is64 {
+		return fmt.Errorf("64-bit tables are not supported")
+	}
+	if pageSizeLog2 != nil {
+		return fmt.Errorf("tables cannot have a custom page size")
+	}
 	if ret.Min > wasm.MaximumFunctionIndex {
 		return fmt.Errorf("table min must be at most %d", wasm.MaximumFunctionIndex)
 	}
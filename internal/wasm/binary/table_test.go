@@ -63,7 +63,7 @@ func TestTableType(t *testing.T) {
 
 		t.Run(fmt.Sprintf("decode - %s", tc.name), func(t *testing.T) {
 			var decoded wasm.Table
-			err := decodeTable(bytes.NewReader(b), api.CoreFeatureReferenceTypes, &decoded)
+			err := decodeTable(bytes.NewReader(b), api.CoreFeatureReferenceTypes, nil, &decoded)
 			require.NoError(t, err)
 			require.Equal(t, decoded, tc.input)
 		})
@@ -107,7 +107,7 @@ func TestDecodeTableType_Errors(t *testing.T) {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			var decoded wasm.Table
-			err := decodeTable(bytes.NewReader(tc.input), tc.features, &decoded)
+			err := decodeTable(bytes.NewReader(tc.input), tc.features, nil, &decoded)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -0,0 +1,26 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// decodeTag returns the type index of a tag, which is only valid if the attribute is zero (exception).
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#tag-section
+func decodeTag(r *bytes.Reader) (uint32, error) {
+	attribute, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("read attribute: %w", err)
+	}
+	if attribute != 0 {
+		return 0, fmt.Errorf("%w: invalid tag attribute: %#x", ErrInvalidByte, attribute)
+	}
+	typeIndex, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return 0, fmt.Errorf("read type index: %w", err)
+	}
+	return typeIndex, nil
+}
@@ -7,30 +7,50 @@ import (
 	"unicode/utf8"
 	"unsafe"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeValueTypes(r *bytes.Reader, num uint32) ([]wasm.ValueType, error) {
+// decodeValueTypes decodes num value types with decodeValueType, and returns their precise types as well, which are
+// nil if all of them are the wasm.DefaultHeapRef.
+func decodeValueTypes(r *bytes.Reader, num uint32, enabledFeatures api.CoreFeatures, m *wasm.Module, fixups *[]refTypeFixup) ([]wasm.ValueType, []wasm.HeapRef, error) {
 	if num == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	ret := make([]wasm.ValueType, num)
-	_, err := io.ReadFull(r, ret)
-	if err != nil {
-		return nil, err
+	refs := make([]wasm.HeapRef, num)
+	for i := range ret {
+		vt, ref, err := decodeValueType(r, enabledFeatures, m, fixups, &ret[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		ret[i], refs[i] = vt, ref
 	}
+	return ret, wasm.OptionalHeapRefs(ret, refs), nil
+}
 
-	for _, v := range ret {
-		switch v {
-		case wasm.ValueTypeI32, wasm.ValueTypeF32, wasm.ValueTypeI64, wasm.ValueTypeF64,
-			wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeV128:
-		default:
-			return nil, fmt.Errorf("invalid value type: %d", v)
-		}
+// refTypeFixup is a reference to a concrete type in the type section, which is resolved after decoding the whole
+// recursion group, as it can refer to the types defined after it in the same group.
+type refTypeFixup struct {
+	vt *wasm.ValueType
+	ht wasm.HeapType
+}
+
+// decodeValueType decodes a value type and its precise type, resolving a reference to a concrete type with the type
+// section of m. If fixups is non-nil, the resolution is deferred by adding the fixup for dst instead.
+func decodeValueType(r *bytes.Reader, enabledFeatures api.CoreFeatures, m *wasm.Module, fixups *[]refTypeFixup, dst *wasm.ValueType) (wasm.ValueType, wasm.HeapRef, error) {
+	vt, ref, _, err := wasm.DecodeValueTypeRef(r, enabledFeatures)
+	if err != nil || vt != 0 {
+		return vt, ref, err
+	}
+	if fixups != nil {
+		*fixups = append(*fixups, refTypeFixup{vt: dst, ht: ref.HeapType})
+		return 0, ref, nil
 	}
-	return ret, nil
+	vt, err = m.HeapTypeValueType(ref.HeapType)
+	return vt, ref, err
 }
 
 // decodeUTF8 decodes a size prefixed string from the reader, returning it and the count of bytes read.
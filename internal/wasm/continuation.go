@@ -0,0 +1,196 @@
+package wasm
+
+import (
+	"slices"
+
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// ContinuationStart calls the function of a continuation with the params on the current goroutine, and returns its
+// results. This is implemented by engines, which pass k to Continuation.Suspend when the function executes suspend.
+type ContinuationStart func(k *Continuation, params []uint64) []uint64
+
+// Continuation is a function of experimental.CoreFeaturesStackSwitching which can be suspended and resumed.
+//
+// The function runs on a goroutine of its own once resumed, so that its stack is kept while it is suspended. Only one
+// of the goroutines of a call runs at a time, and the others are blocked on the channels below, which is what
+// serializes the accesses to Continuations.
+//
+// This is an interim step: the engines are to swap stack segments of the continuations themselves, which removes the
+// goroutine handoff of each resume and suspend, and is needed for resume_throw and switch.
+type Continuation struct {
+	start ContinuationStart
+	// bound are the params given by cont.bind, which precede the ones given by resume.
+	bound []uint64
+	// started is true once the goroutine of the function is started.
+	started bool
+	// resumeCh sends the values to the function suspended in Suspend.
+	resumeCh chan continuationResume
+	// eventCh receives the suspensions of the function, and then its return.
+	eventCh chan continuationEvent
+}
+
+// continuationResume is sent to a suspended continuation.
+type continuationResume struct {
+	// values are the results of the tag of the suspension.
+	values []uint64
+	// abort is true if the continuation is discarded, and its stack is to be unwound.
+	abort bool
+}
+
+// continuationEvent is sent by a continuation when it suspends or returns.
+type continuationEvent struct {
+	// tag is the tag of the suspension, or nil if the function returned.
+	tag *TagInstance
+	// values are the payload of the suspension, or the results of the function.
+	values []uint64
+	// recovered is the value recovered from the panic of the function, such as a trap or an exception, which is
+	// panicked again by the resumer.
+	recovered any
+}
+
+// continuationAborted is panicked in a suspended continuation to unwind its stack when it is discarded.
+type continuationAborted struct{}
+
+// run calls the function of the continuation, and sends its return as the last event.
+func (k *Continuation) run(params []uint64) {
+	var ev continuationEvent
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(continuationAborted); !ok {
+				ev.recovered = r
+			}
+		}
+		k.eventCh <- ev
+	}()
+	ev.values = k.start(k, params)
+}
+
+// Suspend suspends the continuation k with the tag and its payload, and returns the results of the tag which k is
+// resumed with. k is nil if suspend is executed outside any continuation, which traps as there is no handler.
+func (k *Continuation) Suspend(tag *TagInstance, payload []uint64) []uint64 {
+	if k == nil {
+		panic(wasmruntime.ErrRuntimeUnhandledSuspension)
+	}
+	k.eventCh <- continuationEvent{tag: tag, values: slices.Clone(payload)}
+	r := <-k.resumeCh
+	if r.abort {
+		panic(continuationAborted{})
+	}
+	return r.values
+}
+
+// Continuations associates contref values with the continuations they refer to, and is shared by the call engines
+// of a call and of the continuations created during it.
+//
+// A contref is encoded the same way as an exnref of ExceptionRefs, and the zero value is the null contref.
+// Continuations are one-shot: resuming or binding one invalidates its contref, and the rest of the continuation
+// gets a new one.
+type Continuations struct {
+	refs       []*Continuation
+	generation uint64
+	// live are the continuations whose goroutines are started and haven't returned yet.
+	live []*Continuation
+}
+
+// New returns a new contref to the continuation of the function called by start.
+func (c *Continuations) New(start ContinuationStart) uint64 {
+	return c.add(&Continuation{start: start})
+}
+
+func (c *Continuations) add(k *Continuation) uint64 {
+	c.refs = append(c.refs, k)
+	return c.generation<<32 | uint64(len(c.refs))
+}
+
+// take returns the continuation referred by the contref and invalidates it, or traps if the contref is null, or was
+// already taken or created before the last Reset.
+func (c *Continuations) take(ref uint64) *Continuation {
+	if ref == 0 {
+		panic(wasmruntime.ErrRuntimeNullReference)
+	}
+	if ref>>32 == c.generation&0xffffffff {
+		if i := uint32(ref); i > 0 && int(i) <= len(c.refs) {
+			if k := c.refs[i-1]; k != nil {
+				c.refs[i-1] = nil
+				return k
+			}
+		}
+	}
+	panic(wasmruntime.ErrRuntimeContinuationAlreadyResumed)
+}
+
+// Bind returns a new contref to the continuation referred by ref, whose first params are bound to params.
+func (c *Continuations) Bind(ref uint64, params []uint64) uint64 {
+	k := c.take(ref)
+	k.bound = append(k.bound, params...)
+	return c.add(k)
+}
+
+// ForEachBound calls fn for each value bound to the continuations with Bind, which is used to find the references
+// to the objects in GCHeap.
+func (c *Continuations) ForEachBound(fn func(uint64)) {
+	for _, k := range c.refs {
+		if k != nil {
+			for _, v := range k.bound {
+				fn(v)
+			}
+		}
+	}
+}
+
+// Resume resumes the continuation referred by ref with the params until it returns or suspends. current is the
+// continuation executing resume, or nil if this is the call itself, and handlers are the tags of the handlers of
+// resume.
+//
+// This returns the index of the handler of the suspension with its payload followed by a new contref to the rest of
+// the continuation, or -1 with the results of the function if it returned. A suspension with a tag which isn't
+// handled is forwarded to the resumer of current, and traps if there is none. A panic of the function is propagated.
+func (c *Continuations) Resume(ref uint64, params []uint64, handlers []*TagInstance, current *Continuation) (handler int, values []uint64) {
+	k := c.take(ref)
+	values = append(k.bound, params...)
+	k.bound = nil
+	if !k.started {
+		k.started = true
+		k.resumeCh = make(chan continuationResume)
+		k.eventCh = make(chan continuationEvent)
+		c.live = append(c.live, k)
+		go k.run(values)
+	} else {
+		k.resumeCh <- continuationResume{values: values}
+	}
+
+	for {
+		ev := <-k.eventCh
+		if ev.tag == nil {
+			c.live = slices.DeleteFunc(c.live, func(l *Continuation) bool { return l == k })
+			if ev.recovered != nil {
+				panic(ev.recovered)
+			}
+			return -1, ev.values
+		}
+		for i, h := range handlers {
+			if h == ev.tag {
+				return i, append(ev.values, c.add(k))
+			}
+		}
+		// k stays live while suspended, so it is discarded by Reset if this traps.
+		k.resumeCh <- continuationResume{values: current.Suspend(ev.tag, ev.values)}
+	}
+}
+
+// Reset invalidates all the contrefs created so far, and discards the continuations which are still suspended by
+// unwinding their stacks.
+func (c *Continuations) Reset() {
+	for _, k := range c.live {
+		k.resumeCh <- continuationResume{abort: true}
+		<-k.eventCh
+	}
+	clear(c.live)
+	c.live = c.live[:0]
+	if len(c.refs) > 0 {
+		clear(c.refs)
+		c.refs = c.refs[:0]
+		c.generation++
+	}
+}
@@ -27,7 +27,7 @@ func (m *Module) SectionElementCount(sectionID SectionID) uint32 { // element as
 		return uint32(len(m.TableSection))
 	case SectionIDMemory:
 		if m.MemorySection != nil {
-			return 1
+			return 1 + uint32(len(m.MultiMemorySection))
 		}
 		return 0
 	case SectionIDGlobal:
@@ -45,6 +45,8 @@ func (m *Module) SectionElementCount(sectionID SectionID) uint32 { // element as
 		return uint32(len(m.CodeSection))
 	case SectionIDData:
 		return uint32(len(m.DataSection))
+	case SectionIDTag:
+		return uint32(len(m.TagSection))
 	default:
 		panic(fmt.Errorf("BUG: unknown section: %d", sectionID))
 	}
@@ -34,27 +34,88 @@ func (m *Module) validateFunction(sts *stacks, enabledFeatures api.CoreFeatures,
 	return m.validateFunctionWithMaxStackValues(sts, enabledFeatures, idx, functions, globals, memory, tables, maximumValuesOnStack, declaredFunctionIndexes, br)
 }
 
-func readMemArg(pc uint64, body []byte) (align, offset uint32, read uint64, err error) {
+// MemArgMemoryIndexFlag is the bit in the alignment of memarg, which indicates the memory index is encoded.
+// This is only valid when experimental.CoreFeaturesMultiMemory is enabled.
+const MemArgMemoryIndexFlag = 1 << 6
+
+// readMemArg reads the memarg immediate of load and store instructions. When experimental.CoreFeaturesMultiMemory
+// is enabled, the bit 6 of the alignment indicates that the memory index is encoded between the alignment and offset.
+// The returned addressType is the type of the address operand, and memory is the one at index zero.
+func (m *Module) readMemArg(pc uint64, body []byte, enabledFeatures api.CoreFeatures, memory *Memory) (addressType ValueType, align uint32, offset uint64, read uint64, err error) {
+	var memIdx Index
 	align, num, err := leb128.LoadUint32(body[pc:])
 	if err != nil {
 		err = fmt.Errorf("read memory align: %v", err)
 		return
 	}
+	read += num
+
+	if align&MemArgMemoryIndexFlag != 0 && enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+		align &^= MemArgMemoryIndexFlag
+		memIdx, num, err = leb128.LoadUint32(body[pc+read:])
+		if err != nil {
+			err = fmt.Errorf("read memory index: %v", err)
+			return
+		}
+		if memIdx > 0 && memIdx >= m.memoryCount() {
+			err = fmt.Errorf("unknown memory %d", memIdx)
+			return
+		}
+		read += num
+	}
+
 	if align >= 32 {
 		// Prevent 1<<align uint32 overflow.
 		err = fmt.Errorf("invalid memory alignment")
 		return
 	}
-	read += num
 
-	offset, num, err = leb128.LoadUint32(body[pc+num:])
+	addressType = m.memoryAddressType(memIdx, memory)
+	if addressType == ValueTypeI64 {
+		offset, num, err = leb128.LoadUint64(body[pc+read:])
+	} else {
+		var offset32 uint32
+		offset32, num, err = leb128.LoadUint32(body[pc+read:])
+		offset = uint64(offset32)
+	}
 	if err != nil {
 		err = fmt.Errorf("read memory offset: %v", err)
 		return
 	}
 
 	read += num
-	return align, offset, read, nil
+	return addressType, align, offset, read, nil
+}
+
+// memoryAddressType returns the type of addresses into the memory at memIdx, which is ValueTypeI64 for memory64.
+// memory is the one at index zero.
+func (m *Module) memoryAddressType(memIdx Index, memory *Memory) ValueType {
+	if memIdx != 0 {
+		memory = m.memoryAt(memIdx)
+	}
+	if memory != nil && memory.IsMemory64 {
+		return ValueTypeI64
+	}
+	return ValueTypeI32
+}
+
+// readMemoryIndex reads the memory index immediate of memory.size, memory.grow and bulk memory instructions, which
+// must be zero encoded in one byte unless experimental.CoreFeaturesMultiMemory is enabled.
+func (m *Module) readMemoryIndex(pc uint64, body []byte, enabledFeatures api.CoreFeatures) (memIdx Index, read uint64, ok bool, err error) {
+	memIdx, read, err = leb128.LoadUint32(body[pc:])
+	if err != nil {
+		return
+	}
+	if enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+		if memIdx > 0 && memIdx >= m.memoryCount() {
+			err = fmt.Errorf("unknown memory %d", memIdx)
+			return
+		}
+		ok = true
+	} else {
+		ok = memIdx == 0 && read == 1
+	}
+	return
 }
 
 // validateFunctionWithMaxStackValues is like validateFunction, but allows overriding maxStackValues for testing.
@@ -77,8 +138,10 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	code := &m.CodeSection[idx]
 	body := code.Body
 	localTypes := code.LocalTypes
+	// tags are the type indexes of the tags, which is lazily initialized as only needed for exception handling.
+	var tags []Index
 
-	sts.reset(functionType)
+	sts.reset(m, functionType)
 	valueTypeStack := &sts.vs
 	// We start with the outermost control block which is for function return if the code branches into it.
 	controlBlockStack := &sts.cs
@@ -110,7 +173,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
 			}
 			pc++
-			align, _, read, err := readMemArg(pc, body)
+			addressType, align, _, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 			if err != nil {
 				return err
 			}
@@ -120,7 +183,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -128,7 +191,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeF32)
@@ -139,7 +202,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeF32Store:
@@ -149,14 +212,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeF32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeI64Load:
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -164,7 +227,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeF64)
@@ -175,7 +238,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeF64Store:
@@ -185,14 +248,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeF64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeI32Load8S:
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -200,7 +263,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -208,7 +271,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -219,7 +282,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeI64Store8:
@@ -229,14 +292,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeI32Load16S, OpcodeI32Load16U:
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -244,7 +307,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -255,7 +318,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeI64Store16:
@@ -265,14 +328,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeI64Load32S, OpcodeI64Load32U:
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -283,7 +346,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			}
@@ -292,21 +355,22 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
 			}
 			pc++
-			val, num, err := leb128.LoadUint32(body[pc:])
+			memIdx, num, ok, err := m.readMemoryIndex(pc, body, enabledFeatures)
 			if err != nil {
 				return fmt.Errorf("read immediate: %v", err)
 			}
-			if val != 0 || num != 1 {
+			if !ok {
 				return fmt.Errorf("memory instruction reserved bytes not zero with 1 byte")
 			}
+			addressType := m.memoryAddressType(memIdx, memory)
 			switch Opcode(op) {
 			case OpcodeMemoryGrow:
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
-				valueTypeStack.push(ValueTypeI32)
+				valueTypeStack.push(addressType)
 			case OpcodeMemorySize:
-				valueTypeStack.push(ValueTypeI32)
+				valueTypeStack.push(addressType)
 			}
 			pc += num - 1
 		} else if OpcodeI32Const <= op && op <= OpcodeF64Const {
@@ -348,9 +412,13 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						OpcodeLocalGetName, index, l)
 				}
 				if index < inputLen {
-					valueTypeStack.push(functionType.Params[index])
+					valueTypeStack.pushRef(functionType.Params[index], heapRefAt(functionType.Params, functionType.ParamRefs, int(index)))
 				} else {
-					valueTypeStack.push(localTypes[index-inputLen])
+					ref := heapRefAt(localTypes, code.LocalRefs, int(index-inputLen))
+					if ref.NonNullable && !controlBlockStack.isLocalInitialized(index) {
+						return fmt.Errorf("uninitialized local %d for %s", index, OpcodeLocalGetName)
+					}
+					valueTypeStack.pushRef(localTypes[index-inputLen], ref)
 				}
 			case OpcodeLocalSet:
 				inputLen := uint32(len(functionType.Params))
@@ -359,12 +427,16 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						OpcodeLocalSetName, index, l)
 				}
 				var expType ValueType
+				var expRef HeapRef
 				if index < inputLen {
-					expType = functionType.Params[index]
+					expType, expRef = functionType.Params[index], heapRefAt(functionType.Params, functionType.ParamRefs, int(index))
 				} else {
-					expType = localTypes[index-inputLen]
+					expType, expRef = localTypes[index-inputLen], heapRefAt(localTypes, code.LocalRefs, int(index-inputLen))
+					if expRef.NonNullable {
+						controlBlockStack.initializeLocal(index)
+					}
 				}
-				if err := valueTypeStack.popAndVerifyType(expType); err != nil {
+				if err := valueTypeStack.popAndVerifyRef(expType, expRef); err != nil {
 					return err
 				}
 			case OpcodeLocalTee:
@@ -374,27 +446,31 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						OpcodeLocalTeeName, index, l)
 				}
 				var expType ValueType
+				var expRef HeapRef
 				if index < inputLen {
-					expType = functionType.Params[index]
+					expType, expRef = functionType.Params[index], heapRefAt(functionType.Params, functionType.ParamRefs, int(index))
 				} else {
-					expType = localTypes[index-inputLen]
+					expType, expRef = localTypes[index-inputLen], heapRefAt(localTypes, code.LocalRefs, int(index-inputLen))
+					if expRef.NonNullable {
+						controlBlockStack.initializeLocal(index)
+					}
 				}
-				if err := valueTypeStack.popAndVerifyType(expType); err != nil {
+				if err := valueTypeStack.popAndVerifyRef(expType, expRef); err != nil {
 					return err
 				}
-				valueTypeStack.push(expType)
+				valueTypeStack.pushRef(expType, expRef)
 			case OpcodeGlobalGet:
 				if index >= uint32(len(globals)) {
 					return fmt.Errorf("invalid index for %s", OpcodeGlobalGetName)
 				}
-				valueTypeStack.push(globals[index].ValType)
+				valueTypeStack.pushRef(globals[index].ValType, heapRefOf(globals[index].ValType, globals[index].Ref))
 			case OpcodeGlobalSet:
 				if index >= uint32(len(globals)) {
 					return fmt.Errorf("invalid global index")
 				} else if !globals[index].Mutable {
 					return fmt.Errorf("%s when not mutable", OpcodeGlobalSetName)
-				} else if err := valueTypeStack.popAndVerifyType(
-					globals[index].ValType); err != nil {
+				} else if err := valueTypeStack.popAndVerifyRef(
+					globals[index].ValType, heapRefOf(globals[index].ValType, globals[index].Ref)); err != nil {
 					return err
 				}
 			}
@@ -409,13 +485,8 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			pc += num - 1
 			// Check type soundness.
 			target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(index)-1]
-			var targetResultType []ValueType
-			if target.op == OpcodeLoop {
-				targetResultType = target.blockType.Params
-			} else {
-				targetResultType = target.blockType.Results
-			}
-			if err = valueTypeStack.popResults(op, targetResultType, false); err != nil {
+			targetResultType, targetResultRefs := target.labelTypes()
+			if err = valueTypeStack.popResults(op, targetResultType, targetResultRefs, false); err != nil {
 				return err
 			}
 			// br instruction is stack-polymorphic.
@@ -436,19 +507,12 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			}
 			// Check type soundness.
 			target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(index)-1]
-			var targetResultType []ValueType
-			if target.op == OpcodeLoop {
-				targetResultType = target.blockType.Params
-			} else {
-				targetResultType = target.blockType.Results
-			}
-			if err := valueTypeStack.popResults(op, targetResultType, false); err != nil {
+			targetResultType, targetResultRefs := target.labelTypes()
+			if err := valueTypeStack.popResults(op, targetResultType, targetResultRefs, false); err != nil {
 				return err
 			}
 			// Push back the result
-			for _, t := range targetResultType {
-				valueTypeStack.push(t)
-			}
+			valueTypeStack.pushTypes(targetResultType, targetResultRefs)
 		} else if op == OpcodeBrTable {
 			pc++
 			br.Reset(body[pc:])
@@ -480,57 +544,39 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				return fmt.Errorf("cannot pop the required operand for %s", OpcodeBrTableName)
 			}
 			lnLabel := &controlBlockStack.stack[len(controlBlockStack.stack)-1-int(ln)]
-			var defaultLabelType []ValueType
-			// Below, we might modify the slice in case of unreachable. Therefore,
-			// we have to copy the content of block result types, otherwise the original
-			// function type might result in invalid value types if the block is the outermost label
-			// which equals the function's type.
-			if lnLabel.op != OpcodeLoop { // Loop operation doesn't require results since the continuation is the beginning of the loop.
-				defaultLabelType = slices.Clone(lnLabel.blockType.Results)
-			} else {
-				defaultLabelType = slices.Clone(lnLabel.blockType.Params)
+			defaultLabelType, _ := lnLabel.labelTypes()
+			for _, l := range sts.ls {
+				if int(l) >= len(controlBlockStack.stack) {
+					return fmt.Errorf("invalid l param given for %s", OpcodeBrTableName)
+				}
+				label := &controlBlockStack.stack[len(controlBlockStack.stack)-1-int(l)]
+				tableLabelType, _ := label.labelTypes()
+				if len(defaultLabelType) != len(tableLabelType) {
+					return fmt.Errorf("inconsistent block type length for %s at %d; %v (ln=%d) != %v (l=%d)", OpcodeBrTableName, l, defaultLabelType, ln, tableLabelType, l)
+				}
 			}
 
 			if enabledFeatures.IsEnabled(api.CoreFeatureReferenceTypes) {
 				// As of reference-types proposal, br_table on unreachable state
-				// can choose unknown types for expected parameter types for each label.
+				// can choose unknown types for expected parameter types for each label,
+				// so the values are checked against the types of each label.
 				// https://github.com/WebAssembly/reference-types/pull/116
-				for i := range defaultLabelType {
-					index := len(defaultLabelType) - 1 - i
-					exp := defaultLabelType[index]
-					actual, err := valueTypeStack.pop()
-					if err != nil {
+				sts.ls = append(sts.ls, ln)
+				for _, l := range sts.ls {
+					label := &controlBlockStack.stack[len(controlBlockStack.stack)-1-int(l)]
+					tableLabelType, tableLabelRefs := label.labelTypes()
+					if err = valueTypeStack.peekResults(op, tableLabelType, tableLabelRefs); err != nil {
 						return err
 					}
-					if actual == valueTypeUnknown {
-						// Re-assign the expected type to unknown.
-						defaultLabelType[index] = valueTypeUnknown
-					} else if actual != exp {
-						return typeMismatchError(true, OpcodeBrTableName, actual, exp, i)
-					}
 				}
 			} else {
-				if err = valueTypeStack.popResults(op, defaultLabelType, false); err != nil {
+				if err = valueTypeStack.popResults(op, defaultLabelType, nil, false); err != nil {
 					return err
 				}
-			}
-
-			for _, l := range sts.ls {
-				if int(l) >= len(controlBlockStack.stack) {
-					return fmt.Errorf("invalid l param given for %s", OpcodeBrTableName)
-				}
-				label := &controlBlockStack.stack[len(controlBlockStack.stack)-1-int(l)]
-				var tableLabelType []ValueType
-				if label.op != OpcodeLoop {
-					tableLabelType = label.blockType.Results
-				} else {
-					tableLabelType = label.blockType.Params
-				}
-				if len(defaultLabelType) != len(tableLabelType) {
-					return fmt.Errorf("inconsistent block type length for %s at %d; %v (ln=%d) != %v (l=%d)", OpcodeBrTableName, l, defaultLabelType, ln, tableLabelType, l)
-				}
-				for i := range defaultLabelType {
-					if defaultLabelType[i] != valueTypeUnknown && defaultLabelType[i] != tableLabelType[i] {
+				for _, l := range sts.ls {
+					label := &controlBlockStack.stack[len(controlBlockStack.stack)-1-int(l)]
+					tableLabelType, _ := label.labelTypes()
+					if !bytes.Equal(defaultLabelType, tableLabelType) {
 						return fmt.Errorf("incosistent block type for %s at %d", OpcodeBrTableName, l)
 					}
 				}
@@ -557,24 +603,23 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			}
 
 			funcType := &m.TypeSection[functions[index]]
-			for i := 0; i < len(funcType.Params); i++ {
-				if err := valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
+			for i := len(funcType.Params) - 1; i >= 0; i-- {
+				if err := valueTypeStack.popAndVerifyRef(funcType.Params[i], heapRefAt(funcType.Params, funcType.ParamRefs, i)); err != nil {
 					return fmt.Errorf("type mismatch on %s operation param type: %v", opcodeName, err)
 				}
 			}
-			for _, exp := range funcType.Results {
-				valueTypeStack.push(exp)
-			}
 			if op == OpcodeTailCallReturnCall {
 				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesTailCall); err != nil {
 					return fmt.Errorf("%s invalid as %v", OpcodeTailCallReturnCallName, err)
 				}
-				// Same formatting as OpcodeEnd on the outer-most block
-				if err := valueTypeStack.requireStackValues(false, "", functionType.Results, false); err != nil {
+				// The results of the callee are returned from this function as they are.
+				if err := m.requireResults("", funcType, functionType); err != nil {
 					return err
 				}
 				// behaves as a jump.
 				valueTypeStack.unreachable()
+			} else {
+				valueTypeStack.pushTypes(funcType.Results, funcType.ResultRefs)
 			}
 		} else if op == OpcodeCallIndirect || op == OpcodeTailCallReturnCallIndirect {
 			pc++
@@ -619,25 +664,24 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				return fmt.Errorf("cannot pop the offset in table for %s", opcodeName)
 			}
 			funcType := &m.TypeSection[typeIndex]
-			for i := 0; i < len(funcType.Params); i++ {
-				if err = valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
+			for i := len(funcType.Params) - 1; i >= 0; i-- {
+				if err = valueTypeStack.popAndVerifyRef(funcType.Params[i], heapRefAt(funcType.Params, funcType.ParamRefs, i)); err != nil {
 					return fmt.Errorf("type mismatch on %s operation input type", opcodeName)
 				}
 			}
-			for _, exp := range funcType.Results {
-				valueTypeStack.push(exp)
-			}
 
 			if op == OpcodeTailCallReturnCallIndirect {
 				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesTailCall); err != nil {
 					return fmt.Errorf("%s invalid as %v", OpcodeTailCallReturnCallIndirectName, err)
 				}
-				// Same formatting as OpcodeEnd on the outer-most block
-				if err := valueTypeStack.requireStackValues(false, "", functionType.Results, false); err != nil {
+				// The results of the callee are returned from this function as they are.
+				if err := m.requireResults("", funcType, functionType); err != nil {
 					return err
 				}
 				// behaves as a jump.
 				valueTypeStack.unreachable()
+			} else {
+				valueTypeStack.pushTypes(funcType.Results, funcType.ResultRefs)
 			}
 		} else if OpcodeI32Eqz <= op && op <= OpcodeI64Extend32S {
 			switch op {
@@ -861,11 +905,29 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				pc++
 				switch reftype := body[pc]; reftype {
 				case ValueTypeExternref:
-					valueTypeStack.push(ValueTypeExternref)
+					valueTypeStack.pushRef(ValueTypeExternref, HeapRef{HeapType: HeapTypeExtern})
 				case ValueTypeFuncref:
-					valueTypeStack.push(ValueTypeFuncref)
+					valueTypeStack.pushRef(ValueTypeFuncref, HeapRef{HeapType: HeapTypeFunc})
+				case ValueTypeExnref:
+					if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+						return fmt.Errorf("ref.null exn invalid as %v", err)
+					}
+					valueTypeStack.pushRef(ValueTypeExnref, HeapRef{HeapType: HeapTypeExn})
 				default:
-					return fmt.Errorf("unknown type for ref.null: 0x%x", reftype)
+					if RequireTypedReferences(enabledFeatures) != nil {
+						return fmt.Errorf("unknown type for ref.null: 0x%x", reftype)
+					}
+					br.Reset(body[pc:])
+					ht, num, err := DecodeHeapType(br, enabledFeatures)
+					if err != nil {
+						return fmt.Errorf("unknown type for ref.null: %v", err)
+					}
+					vt, err := m.HeapTypeValueType(ht)
+					if err != nil {
+						return fmt.Errorf("unknown type for ref.null: %v", err)
+					}
+					pc += num - 1
+					valueTypeStack.pushRef(vt, HeapRef{HeapType: ht})
 				}
 			case OpcodeRefIsNull:
 				tp, err := valueTypeStack.pop()
@@ -885,8 +947,33 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					return fmt.Errorf("undeclared function index %d for ref.func", index)
 				}
 				pc += num - 1
-				valueTypeStack.push(ValueTypeFuncref)
+				ref := HeapRef{HeapType: HeapTypeFunc, NonNullable: true}
+				if index < uint32(len(functions)) {
+					ref.HeapType = HeapType(functions[index])
+				}
+				valueTypeStack.pushRef(ValueTypeFuncref, ref)
+			}
+		} else if op == OpcodeRefEq {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeRefEqName, err)
+			}
+			for i := 0; i < 2; i++ {
+				if err := valueTypeStack.popAndVerifyRef(ValueTypeAnyref, HeapRef{HeapType: HeapTypeEq}); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeRefEqName, err)
+				}
+			}
+			valueTypeStack.push(ValueTypeI32)
+		} else if op == OpcodeGCPrefix {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeGCPrefixName, err)
 			}
+			pc++
+			read, err := m.validateGCInstruction(pc, body, br, valueTypeStack, controlBlockStack, enabledFeatures)
+			if err != nil {
+				return err
+			}
+			pc += read - 1
+			valueTypeStack.usesGC = true
 		} else if op == OpcodeTableGet || op == OpcodeTableSet {
 			if err := enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
 				return fmt.Errorf("%s is invalid as %v", InstructionName(op), err)
@@ -900,14 +987,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				return fmt.Errorf("table of index %d not found", tableIndex)
 			}
 
-			refType := tables[tableIndex].Type
+			refType, ref := tables[tableIndex].Type, heapRefOf(tables[tableIndex].Type, tables[tableIndex].Ref)
 			if op == OpcodeTableGet {
 				if err := valueTypeStack.popAndVerifyType(api.ValueTypeI32); err != nil {
 					return fmt.Errorf("cannot pop the operand for table.get: %v", err)
 				}
-				valueTypeStack.push(refType)
+				valueTypeStack.pushRef(refType, ref)
 			} else {
-				if err := valueTypeStack.popAndVerifyType(refType); err != nil {
+				if err := valueTypeStack.popAndVerifyRef(refType, ref); err != nil {
 					return fmt.Errorf("cannot pop the operand for table.set: %v", err)
 				}
 				if err := valueTypeStack.popAndVerifyType(api.ValueTypeI32); err != nil {
@@ -995,24 +1082,38 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					}
 
 					pc++
-					val, num, err := leb128.LoadUint32(body[pc:])
+					memIdx, num, ok, err := m.readMemoryIndex(pc, body, enabledFeatures)
 					if err != nil {
 						return fmt.Errorf("failed to read memory index for %s: %v", MiscInstructionName(miscOpcode), err)
 					}
-					if val != 0 || num != 1 {
+					if !ok {
 						return fmt.Errorf("%s reserved byte must be zero encoded with 1 byte", MiscInstructionName(miscOpcode))
 					}
-					if miscOpcode == OpcodeMiscMemoryCopy {
-						pc++
-						// memory.copy needs two memory index which are reserved as zero.
-						val, num, err := leb128.LoadUint32(body[pc:])
+					addressType := m.memoryAddressType(memIdx, memory)
+					switch miscOpcode {
+					case OpcodeMiscMemoryInit:
+						params[2] = addressType
+					case OpcodeMiscMemoryFill:
+						params[0], params[2] = addressType, addressType
+					case OpcodeMiscMemoryCopy:
+						pc += num
+						// memory.copy needs two memory indexes: the destination and the source.
+						var srcIdx Index
+						srcIdx, num, ok, err = m.readMemoryIndex(pc, body, enabledFeatures)
 						if err != nil {
 							return fmt.Errorf("failed to read memory index for %s: %v", MiscInstructionName(miscOpcode), err)
 						}
-						if val != 0 || num != 1 {
+						if !ok {
 							return fmt.Errorf("%s reserved byte must be zero encoded with 1 byte", MiscInstructionName(miscOpcode))
 						}
+						srcAddressType := m.memoryAddressType(srcIdx, memory)
+						params[0], params[1] = addressType, srcAddressType
+						// The size is an i64 only if both memories are memory64.
+						if addressType == ValueTypeI64 && srcAddressType == ValueTypeI64 {
+							params[2] = ValueTypeI64
+						}
 					}
+					pc += num - 1
 
 				case OpcodeMiscTableInit:
 					params = []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI32}
@@ -1039,7 +1140,8 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						return fmt.Errorf("table of index %d not found", tableIndex)
 					}
 
-					if m.ElementSection[elementIndex].Type != tables[tableIndex].Type {
+					elem, table := &m.ElementSection[elementIndex], &tables[tableIndex]
+					if elem.Type != table.Type || !m.isHeapRefSubType(heapRefOf(elem.Type, elem.Ref), m, heapRefOf(table.Type, table.Ref)) {
 						return fmt.Errorf("type mismatch for table.init: element type %s does not match table type %s",
 							RefTypeName(m.ElementSection[elementIndex].Type),
 							RefTypeName(tables[tableIndex].Type),
@@ -1086,7 +1188,8 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						return fmt.Errorf("table of index %d not found", srcTableIndex)
 					}
 
-					if tables[srcTableIndex].Type != tables[dstTableIndex].Type {
+					src, dst := &tables[srcTableIndex], &tables[dstTableIndex]
+					if src.Type != dst.Type || !m.isHeapRefSubType(heapRefOf(src.Type, src.Ref), m, heapRefOf(dst.Type, dst.Ref)) {
 						return fmt.Errorf("table type mismatch for table.copy: %s (src) != %s (dst)",
 							RefTypeName(tables[srcTableIndex].Type), RefTypeName(tables[dstTableIndex].Type))
 					}
@@ -1114,7 +1217,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				pc += num - 1
 
 				var params, results []ValueType
-				reftype := tables[tableIndex].Type
+				reftype, ref := tables[tableIndex].Type, heapRefOf(tables[tableIndex].Type, tables[tableIndex].Ref)
 				if miscOpcode == OpcodeMiscTableGrow {
 					params = []ValueType{ValueTypeI32, reftype}
 					results = []ValueType{ValueTypeI32}
@@ -1125,16 +1228,70 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				}
 
 				for _, p := range params {
-					if err := valueTypeStack.popAndVerifyType(p); err != nil {
+					want := unknownHeapRef
+					if p == reftype {
+						want = ref
+					}
+					if err := valueTypeStack.popAndVerifyRef(p, want); err != nil {
 						return fmt.Errorf("cannot pop the operand for %s: %v", miscInstructionNames[miscOpcode], err)
 					}
 				}
 				for _, r := range results {
 					valueTypeStack.push(r)
 				}
+			} else if miscOpcode >= OpcodeMiscI64Add128 && miscOpcode <= OpcodeMiscI64MulWideU {
+				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesWideArithmetic); err != nil {
+					return fmt.Errorf("%s invalid as %v", miscInstructionNames[miscOpcode], err)
+				}
+				// i64.add128 and i64.sub128 take two 128-bit operands of (low, high) pairs, and
+				// i64.mul_wide_s and i64.mul_wide_u take two i64 operands. All of them result in a (low, high) pair.
+				operands := 2
+				if miscOpcode == OpcodeMiscI64Add128 || miscOpcode == OpcodeMiscI64Sub128 {
+					operands = 4
+				}
+				for i := 0; i < operands; i++ {
+					if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
+						return fmt.Errorf("cannot pop the operand for %s: %v", miscInstructionNames[miscOpcode], err)
+					}
+				}
+				valueTypeStack.push(ValueTypeI64)
+				valueTypeStack.push(ValueTypeI64)
 			} else {
 				return fmt.Errorf("unknown misc opcode %#x", miscOpcode)
 			}
+		} else if op == OpcodeVecPrefix && IsVecRelaxed(body, pc+1) {
+			pc++
+			vecOpcode := body[pc]
+			pc++ // Skip OpcodeVecRelaxedSecondByte.
+			opcodeName := VectorRelaxedInstructionName(vecOpcode)
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureSIMD); err != nil {
+				return fmt.Errorf("%s invalid as %v", opcodeName, err)
+			} else if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesRelaxedSIMD); err != nil {
+				return fmt.Errorf("%s invalid as %v", opcodeName, err)
+			}
+
+			var operands int
+			switch vecOpcode {
+			case OpcodeVecI32x4RelaxedTruncF32x4S, OpcodeVecI32x4RelaxedTruncF32x4U,
+				OpcodeVecI32x4RelaxedTruncF64x2SZero, OpcodeVecI32x4RelaxedTruncF64x2UZero:
+				operands = 1
+			case OpcodeVecI8x16RelaxedSwizzle,
+				OpcodeVecF32x4RelaxedMin, OpcodeVecF32x4RelaxedMax, OpcodeVecF64x2RelaxedMin, OpcodeVecF64x2RelaxedMax,
+				OpcodeVecI16x8RelaxedQ15mulrS, OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+				operands = 2
+			case OpcodeVecF32x4RelaxedMadd, OpcodeVecF32x4RelaxedNmadd, OpcodeVecF64x2RelaxedMadd, OpcodeVecF64x2RelaxedNmadd,
+				OpcodeVecI8x16RelaxedLaneselect, OpcodeVecI16x8RelaxedLaneselect, OpcodeVecI32x4RelaxedLaneselect, OpcodeVecI64x2RelaxedLaneselect,
+				OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+				operands = 3
+			default:
+				return fmt.Errorf("unknown relaxed SIMD instruction 0x%x", 0x100|uint32(vecOpcode&0x7f))
+			}
+			for i := 0; i < operands; i++ {
+				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", opcodeName, err)
+				}
+			}
+			valueTypeStack.push(ValueTypeV128)
 		} else if op == OpcodeVecPrefix {
 			pc++
 			// Vector instructions come with two bytes where the first byte is always OpcodeVecPrefix,
@@ -1166,7 +1323,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				addressType, align, _, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1195,7 +1352,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > maxAlign {
 					return fmt.Errorf("invalid memory alignment %d for %s", align, VectorInstructionName(vecOpcode))
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", VectorInstructionName(vecOpcode), err)
 				}
 				valueTypeStack.push(ValueTypeV128)
@@ -1204,7 +1361,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				addressType, align, _, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1215,7 +1372,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeVecV128StoreName, err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeVecV128StoreName, err)
 				}
 			case OpcodeVecV128Load8Lane, OpcodeVecV128Load16Lane, OpcodeVecV128Load32Lane, OpcodeVecV128Load64Lane:
@@ -1224,7 +1381,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				}
 				attr := vecLoadLanes[vecOpcode]
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				addressType, align, _, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1242,7 +1399,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
 				valueTypeStack.push(ValueTypeV128)
@@ -1252,7 +1409,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				}
 				attr := vecStoreLanes[vecOpcode]
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				addressType, align, _, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1270,7 +1427,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
 			case OpcodeVecI8x16ExtractLaneS,
@@ -1441,18 +1598,16 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			}
 		} else if op == OpcodeBlock {
 			br.Reset(body[pc+1:])
-			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
+			bt, num, err := DecodeBlockType(m, br, enabledFeatures)
 			if err != nil {
 				return fmt.Errorf("read block: %w", err)
 			}
 			controlBlockStack.push(pc, 0, 0, bt, num, 0)
-			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
+			if err = valueTypeStack.popParams(op, bt.Params, bt.ParamRefs, false); err != nil {
 				return err
 			}
 			// Plus we have to push any block params again.
-			for _, p := range bt.Params {
-				valueTypeStack.push(p)
-			}
+			valueTypeStack.pushTypes(bt.Params, bt.ParamRefs)
 			valueTypeStack.pushStackLimit(len(bt.Params))
 			pc += num
 		} else if op == OpcodeAtomicPrefix {
@@ -1478,7 +1633,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if memory == nil {
 				return fmt.Errorf("memory must exist for %s", AtomicInstructionName(atomicOpcode))
 			}
-			align, _, read, err := readMemArg(pc, body)
+			addressType, align, _, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 			if err != nil {
 				return err
 			}
@@ -1491,7 +1646,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1505,7 +1660,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1519,7 +1674,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1527,7 +1682,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1535,7 +1690,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1543,7 +1698,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align != 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1551,7 +1706,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align != 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1559,7 +1714,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align != 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1567,7 +1722,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1575,7 +1730,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1586,7 +1741,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI64Store:
@@ -1596,7 +1751,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI32Store8:
@@ -1606,7 +1761,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI32Store16:
@@ -1616,7 +1771,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI64Store8:
@@ -1626,7 +1781,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI64Store16:
@@ -1636,7 +1791,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI64Store32:
@@ -1646,7 +1801,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 			case OpcodeAtomicI32RmwAdd, OpcodeAtomicI32RmwSub, OpcodeAtomicI32RmwAnd, OpcodeAtomicI32RmwOr, OpcodeAtomicI32RmwXor, OpcodeAtomicI32RmwXchg:
@@ -1656,7 +1811,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1667,7 +1822,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1678,7 +1833,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1689,7 +1844,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1700,7 +1855,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1711,7 +1866,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1722,7 +1877,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1736,7 +1891,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1750,7 +1905,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1764,7 +1919,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -1778,7 +1933,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1792,7 +1947,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1806,7 +1961,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1820,7 +1975,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addressType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -1829,23 +1984,21 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			}
 		} else if op == OpcodeLoop {
 			br.Reset(body[pc+1:])
-			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
+			bt, num, err := DecodeBlockType(m, br, enabledFeatures)
 			if err != nil {
 				return fmt.Errorf("read block: %w", err)
 			}
 			controlBlockStack.push(pc, 0, 0, bt, num, op)
-			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
+			if err = valueTypeStack.popParams(op, bt.Params, bt.ParamRefs, false); err != nil {
 				return err
 			}
 			// Plus we have to push any block params again.
-			for _, p := range bt.Params {
-				valueTypeStack.push(p)
-			}
+			valueTypeStack.pushTypes(bt.Params, bt.ParamRefs)
 			valueTypeStack.pushStackLimit(len(bt.Params))
 			pc += num
 		} else if op == OpcodeIf {
 			br.Reset(body[pc+1:])
-			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
+			bt, num, err := DecodeBlockType(m, br, enabledFeatures)
 			if err != nil {
 				return fmt.Errorf("read block: %w", err)
 			}
@@ -1853,13 +2006,11 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err = valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 				return fmt.Errorf("cannot pop the operand for 'if': %v", err)
 			}
-			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
+			if err = valueTypeStack.popParams(op, bt.Params, bt.ParamRefs, false); err != nil {
 				return err
 			}
 			// Plus we have to push any block params again.
-			for _, p := range bt.Params {
-				valueTypeStack.push(p)
-			}
+			valueTypeStack.pushTypes(bt.Params, bt.ParamRefs)
 			valueTypeStack.pushStackLimit(len(bt.Params))
 			pc += num
 		} else if op == OpcodeElse {
@@ -1870,15 +2021,15 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			bl.op = OpcodeElse
 			bl.elseAt = pc
 			// Check the type soundness of the instructions *before* entering this else Op.
-			if err := valueTypeStack.popResults(OpcodeIf, bl.blockType.Results, true); err != nil {
+			if err := valueTypeStack.popResults(OpcodeIf, bl.blockType.Results, bl.blockType.ResultRefs, true); err != nil {
 				return err
 			}
 			// Before entering instructions inside else, we pop all the values pushed by then block.
 			valueTypeStack.resetAtStackLimit()
+			// The locals set in then block are not initialized in else block.
+			controlBlockStack.initializedLocals = controlBlockStack.initializedLocals[:bl.initializedLocals]
 			// Plus we have to push any block params again.
-			for _, p := range bl.blockType.Params {
-				valueTypeStack.push(p)
-			}
+			valueTypeStack.pushTypes(bl.blockType.Params, bl.blockType.ParamRefs)
 		} else if op == OpcodeEnd {
 			bl := controlBlockStack.pop()
 			bl.endAt = pc
@@ -1900,27 +2051,27 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			ctx := "" // the outer-most block: the function return
 			if bl.op == OpcodeIf && !ifMissingElse && bl.elseAt > 0 {
 				ctx = OpcodeElseName
+			} else if bl.op == OpcodeExceptionHandlingTryTable {
+				ctx = OpcodeExceptionHandlingTryTableName
 			} else if bl.op != 0 {
 				ctx = InstructionName(bl.op)
 			}
 
 			// Check return types match
-			if err := valueTypeStack.requireStackValues(false, ctx, bl.blockType.Results, true); err != nil {
+			if err := valueTypeStack.requireStackValues(false, ctx, bl.blockType.Results, bl.blockType.ResultRefs, true); err != nil {
 				return err
 			}
 
 			// Put the result types at the end after resetting at the stack limit
 			// since we might have Any type between the limit and the current top.
 			valueTypeStack.resetAtStackLimit()
-			for _, exp := range bl.blockType.Results {
-				valueTypeStack.push(exp)
-			}
+			valueTypeStack.pushTypes(bl.blockType.Results, bl.blockType.ResultRefs)
 			// We exit if/loop/block, so reset the constraints on the stack manipulation
 			// on values previously pushed by outer blocks.
 			valueTypeStack.popStackLimit()
 		} else if op == OpcodeReturn {
 			// Same formatting as OpcodeEnd on the outer-most block
-			if err := valueTypeStack.requireStackValues(false, "", functionType.Results, false); err != nil {
+			if err := valueTypeStack.requireStackValues(false, "", functionType.Results, functionType.ResultRefs, false); err != nil {
 				return err
 			}
 			// return instruction is stack-polymorphic.
@@ -1934,11 +2085,11 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 				return fmt.Errorf("type mismatch on 3rd select operand: %v", err)
 			}
-			v1, err := valueTypeStack.pop()
+			v1, ref1, err := valueTypeStack.popRef()
 			if err != nil {
 				return fmt.Errorf("invalid select: %v", err)
 			}
-			v2, err := valueTypeStack.pop()
+			v2, ref2, err := valueTypeStack.popRef()
 			if err != nil {
 				return fmt.Errorf("invalid select: %v", err)
 			}
@@ -1953,22 +2104,215 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				}
 				pc++
 				tp := body[pc]
-				if tp != ValueTypeI32 && tp != ValueTypeI64 && tp != ValueTypeF32 && tp != ValueTypeF64 &&
-					tp != api.ValueTypeExternref && tp != ValueTypeFuncref && tp != ValueTypeV128 {
+				ref := DefaultHeapRef(tp)
+				if tp == RefTypePrefixNullable || tp == RefTypePrefixNonNullable || enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) {
+					br.Reset(body[pc:])
+					vt, vtRef, num, err := m.DecodeResolvedValueTypeRef(br, enabledFeatures)
+					if err != nil {
+						return fmt.Errorf("invalid type for %s: %v", OpcodeTypedSelectName, err)
+					}
+					tp, ref = vt, vtRef
+					pc += num - 1
+				}
+				if tp != ValueTypeAnyref && tp != ValueTypeI32 && tp != ValueTypeI64 && tp != ValueTypeF32 && tp != ValueTypeF64 &&
+					tp != api.ValueTypeExternref && tp != ValueTypeFuncref && tp != ValueTypeV128 && tp != ValueTypeExnref &&
+					tp != ValueTypeContref {
 					return fmt.Errorf("invalid type %s for %s", ValueTypeName(tp), OpcodeTypedSelectName)
 				}
+				if (v1 != tp && v1 != valueTypeUnknown) || (v2 != tp && v2 != valueTypeUnknown) ||
+					!m.isHeapRefSubType(ref1, m, ref) || !m.isHeapRefSubType(ref2, m, ref) {
+					return fmt.Errorf("type mismatch on 1st and 2nd select operands")
+				}
+				valueTypeStack.pushRef(tp, ref)
 			} else if isReferenceValueType(v1) || isReferenceValueType(v2) {
 				return fmt.Errorf("reference types cannot be used for non typed select instruction")
-			}
-
-			if v1 != v2 && v1 != valueTypeUnknown && v2 != valueTypeUnknown {
+			} else if v1 != v2 && v1 != valueTypeUnknown && v2 != valueTypeUnknown {
 				return fmt.Errorf("type mismatch on 1st and 2nd select operands")
-			}
-			if v1 == valueTypeUnknown {
+			} else if v1 == valueTypeUnknown {
 				valueTypeStack.push(v2)
 			} else {
 				valueTypeStack.push(v1)
 			}
+		} else if op == OpcodeExceptionHandlingTryTable {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeExceptionHandlingTryTableName, err)
+			}
+			br.Reset(body[pc+1:])
+			bt, num, err := DecodeBlockType(m, br, enabledFeatures)
+			if err != nil {
+				return fmt.Errorf("read block: %w", err)
+			}
+			var catchesNum uint64
+			sts.catches, catchesNum, err = DecodeTryTableCatches(br, sts.catches[:0])
+			if err != nil {
+				return fmt.Errorf("read %s: %w", OpcodeExceptionHandlingTryTableName, err)
+			}
+			if tags == nil {
+				tags = m.AllTagTypes()
+			}
+			// Catch clauses branch to labels outside the try_table, so check them before entering it.
+			for i := range sts.catches {
+				if err = m.validateCatch(&sts.catches[i], controlBlockStack, tags); err != nil {
+					return fmt.Errorf("invalid catch[%d] for %s: %w", i, OpcodeExceptionHandlingTryTableName, err)
+				}
+			}
+			controlBlockStack.push(pc, 0, 0, bt, num+catchesNum, op)
+			if err = valueTypeStack.popParams(op, bt.Params, bt.ParamRefs, false); err != nil {
+				return err
+			}
+			// Plus we have to push any block params again.
+			valueTypeStack.pushTypes(bt.Params, bt.ParamRefs)
+			valueTypeStack.pushStackLimit(len(bt.Params))
+			pc += num + catchesNum
+		} else if op == OpcodeExceptionHandlingThrow {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeExceptionHandlingThrowName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			pc += num - 1
+			if tags == nil {
+				tags = m.AllTagTypes()
+			}
+			if int(index) >= len(tags) {
+				return fmt.Errorf("unknown tag %d for %s", index, OpcodeExceptionHandlingThrowName)
+			}
+			if len(m.TypeSection[tags[index]].Results) > 0 {
+				return fmt.Errorf("tag %d with results invalid for %s", index, OpcodeExceptionHandlingThrowName)
+			}
+			tagType := &m.TypeSection[tags[index]]
+			for i := len(tagType.Params) - 1; i >= 0; i-- {
+				if err := valueTypeStack.popAndVerifyRef(tagType.Params[i], heapRefAt(tagType.Params, tagType.ParamRefs, i)); err != nil {
+					return fmt.Errorf("type mismatch on %s operation param type: %v", OpcodeExceptionHandlingThrowName, err)
+				}
+			}
+			// throw instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
+		} else if op == OpcodeExceptionHandlingThrowRef {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeExceptionHandlingThrowRefName, err)
+			}
+			if err := valueTypeStack.popAndVerifyType(ValueTypeExnref); err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeExceptionHandlingThrowRefName, err)
+			}
+			// throw_ref instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
+		} else if op == OpcodeFunctionReferencesCallRef || op == OpcodeFunctionReferencesReturnCallRef {
+			opcodeName := FunctionReferencesInstructionName(op)
+			if err := RequireTypedReferences(enabledFeatures); err != nil {
+				return fmt.Errorf("%s invalid as %v", opcodeName, err)
+			}
+			pc++
+			typeIndex, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			pc += num - 1
+			if int(typeIndex) >= len(m.TypeSection) || !m.isFunctionType(typeIndex) {
+				return fmt.Errorf("invalid type index at %s: %d", opcodeName, typeIndex)
+			}
+			m.UsesCallRef = true
+			// The reference is validated with its function type, which is also checked at runtime the same way as
+			// call_indirect unless the reference is of a subtype.
+			if err = valueTypeStack.popAndVerifyRef(ValueTypeFuncref, HeapRef{HeapType: HeapType(typeIndex)}); err != nil {
+				return fmt.Errorf("cannot pop the function reference for %s: %v", opcodeName, err)
+			}
+			funcType := &m.TypeSection[typeIndex]
+			for i := len(funcType.Params) - 1; i >= 0; i-- {
+				if err = valueTypeStack.popAndVerifyRef(funcType.Params[i], heapRefAt(funcType.Params, funcType.ParamRefs, i)); err != nil {
+					return fmt.Errorf("type mismatch on %s operation input type", opcodeName)
+				}
+			}
+
+			if op == OpcodeFunctionReferencesReturnCallRef {
+				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesTailCall); err != nil {
+					return fmt.Errorf("%s invalid as %v", opcodeName, err)
+				}
+				// The results of the callee are returned from this function as they are.
+				if err := m.requireResults("", funcType, functionType); err != nil {
+					return err
+				}
+				// behaves as a jump.
+				valueTypeStack.unreachable()
+			} else {
+				valueTypeStack.pushTypes(funcType.Results, funcType.ResultRefs)
+			}
+		} else if op == OpcodeFunctionReferencesRefAsNonNull {
+			if err := RequireTypedReferences(enabledFeatures); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeFunctionReferencesRefAsNonNullName, err)
+			}
+			tp, ref, err := valueTypeStack.popRef()
+			if err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeFunctionReferencesRefAsNonNullName, err)
+			} else if !isReferenceValueType(tp) && tp != valueTypeUnknown {
+				return fmt.Errorf("type mismatch: expected reference type but was %s", ValueTypeName(tp))
+			}
+			valueTypeStack.pushRef(tp, ref.asNonNullable())
+		} else if op == OpcodeFunctionReferencesBrOnNull || op == OpcodeFunctionReferencesBrOnNonNull {
+			opcodeName := FunctionReferencesInstructionName(op)
+			if err := RequireTypedReferences(enabledFeatures); err != nil {
+				return fmt.Errorf("%s invalid as %v", opcodeName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			} else if int(index) >= len(controlBlockStack.stack) {
+				return fmt.Errorf("invalid label %d for %s", index, opcodeName)
+			}
+			pc += num - 1
+			tp, ref, err := valueTypeStack.popRef()
+			if err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", opcodeName, err)
+			} else if !isReferenceValueType(tp) && tp != valueTypeUnknown {
+				return fmt.Errorf("type mismatch: expected reference type but was %s", ValueTypeName(tp))
+			}
+			target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(index)-1]
+			targetResultType, targetResultRefs := target.labelTypes()
+			if op == OpcodeFunctionReferencesBrOnNull {
+				// The label receives the values below the operand, which is pushed back as non-null if not branching.
+				if err = valueTypeStack.requireStackValues(false, opcodeName, targetResultType, targetResultRefs, false); err != nil {
+					return err
+				}
+				valueTypeStack.pushTypes(targetResultType, targetResultRefs)
+				valueTypeStack.pushRef(tp, ref.asNonNullable())
+			} else {
+				// The label receives the operand as non-null, which is dropped if not branching.
+				n := len(targetResultType)
+				if n == 0 || !isReferenceValueType(targetResultType[n-1]) ||
+					(tp != valueTypeUnknown && targetResultType[n-1] != tp) ||
+					!m.isHeapRefSubType(ref.asNonNullable(), m, heapRefAt(targetResultType, targetResultRefs, n-1)) {
+					return fmt.Errorf("type mismatch for %s: label %d must receive %s", opcodeName, index, ValueTypeName(tp))
+				}
+				rest, restRefs := targetResultType[:n-1], targetResultRefs
+				if restRefs != nil {
+					restRefs = restRefs[:n-1]
+				}
+				if err = valueTypeStack.requireStackValues(false, opcodeName, rest, restRefs, false); err != nil {
+					return err
+				}
+				valueTypeStack.pushTypes(rest, restRefs)
+			}
+		} else if op >= OpcodeStackSwitchingContNew && op <= OpcodeStackSwitchingSwitch {
+			opcodeName := StackSwitchingInstructionName(op)
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesStackSwitching); err != nil {
+				return fmt.Errorf("%s invalid as %v", opcodeName, err)
+			}
+			// The tags of the handlers and suspensions are those of the exception-handling proposal.
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", opcodeName, err)
+			}
+			if tags == nil {
+				tags = m.AllTagTypes()
+			}
+			read, err := m.validateStackSwitchingInstruction(op, body[pc+1:], valueTypeStack, controlBlockStack, tags)
+			if err != nil {
+				return fmt.Errorf("%s: %v", opcodeName, err)
+			}
+			pc += read
 		} else if op == OpcodeUnreachable {
 			// unreachable instruction is stack-polymorphic.
 			valueTypeStack.unreachable()
@@ -1981,6 +2325,9 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	if len(controlBlockStack.stack) > 0 {
 		return fmt.Errorf("ill-nested block exists")
 	}
+	if valueTypeStack.usesGC {
+		m.UsesGC = true
+	}
 	if valueTypeStack.maximumStackPointer > maxStackValues {
 		return fmt.Errorf("function may have %d stack values, which exceeds limit %d", valueTypeStack.maximumStackPointer, maxStackValues)
 	}
@@ -2047,52 +2394,89 @@ type stacks struct {
 	cs controlBlockStack
 	// ls is the label slice that is reused for each br_table instruction.
 	ls []uint32
+	// catches is the catch clause slice that is reused for each try_table instruction.
+	catches []TryTableCatch
 }
 
-func (sts *stacks) reset(functionType *FunctionType) {
+func (sts *stacks) reset(m *Module, functionType *FunctionType) {
 	// Reset valueStack for reuse.
 	sts.vs.stack = sts.vs.stack[:0]
+	sts.vs.refs = sts.vs.refs[:0]
 	sts.vs.stackLimits = sts.vs.stackLimits[:0]
 	sts.vs.maximumStackPointer = 0
+	sts.vs.usesGC = false
+	sts.vs.m = m
 	sts.cs.stack = sts.cs.stack[:0]
 	sts.cs.stack = append(sts.cs.stack, controlBlock{blockType: functionType})
+	sts.cs.initializedLocals = sts.cs.initializedLocals[:0]
 	sts.ls = sts.ls[:0]
 }
 
 type controlBlockStack struct {
 	stack []controlBlock
+	// initializedLocals are the indexes of the non-nullable locals which are set in the current control block or
+	// its enclosing ones, as they must be set before local.get per the function-references proposal.
+	initializedLocals []Index
 }
 
 func (s *controlBlockStack) pop() *controlBlock {
 	tail := len(s.stack) - 1
 	ret := &s.stack[tail]
 	s.stack = s.stack[:tail]
+	s.initializedLocals = s.initializedLocals[:ret.initializedLocals]
 	return ret
 }
 
 func (s *controlBlockStack) push(startAt, elseAt, endAt uint64, blockType *FunctionType, blockTypeBytes uint64, op Opcode) {
 	s.stack = append(s.stack, controlBlock{
-		startAt:        startAt,
-		elseAt:         elseAt,
-		endAt:          endAt,
-		blockType:      blockType,
-		blockTypeBytes: blockTypeBytes,
-		op:             op,
+		startAt:           startAt,
+		elseAt:            elseAt,
+		endAt:             endAt,
+		blockType:         blockType,
+		blockTypeBytes:    blockTypeBytes,
+		op:                op,
+		initializedLocals: len(s.initializedLocals),
 	})
 }
 
+// isLocalInitialized returns true if the non-nullable local at the index is set in the current control block.
+func (s *controlBlockStack) isLocalInitialized(index Index) bool {
+	return slices.Contains(s.initializedLocals, index)
+}
+
+// initializeLocal marks the non-nullable local at the index as set until the end of the current control block.
+func (s *controlBlockStack) initializeLocal(index Index) {
+	if !s.isLocalInitialized(index) {
+		s.initializedLocals = append(s.initializedLocals, index)
+	}
+}
+
 type valueTypeStack struct {
-	stack               []ValueType
+	stack []ValueType
+	// refs are the precise types of the values in stack, which are unknownHeapRef unless tracked.
+	refs                []HeapRef
 	stackLimits         []int
 	maximumStackPointer int
 	// requireStackValuesTmp is used in requireStackValues function to reduce the allocation.
 	requireStackValuesTmp []ValueType
+	// requireStackValuesRefsTmp is the precise types of requireStackValuesTmp.
+	requireStackValuesRefsTmp []HeapRef
+	// usesGC is set if the function uses ValueTypeAnyref or any GC instruction.
+	usesGC bool
+	// m is the module of the function, which defines the concrete types in refs.
+	m *Module
 }
 
 // Only used in the analyzeFunction below.
 const valueTypeUnknown = ValueType(0xFF)
 
 func (s *valueTypeStack) tryPop() (vt ValueType, limit int, ok bool) {
+	vt, _, limit, ok = s.tryPopRef()
+	return
+}
+
+// tryPopRef is the same as tryPop, except that it also returns the precise type of the value.
+func (s *valueTypeStack) tryPopRef() (vt ValueType, ref HeapRef, limit int, ok bool) {
 	if len(s.stackLimits) > 0 {
 		limit = s.stackLimits[len(s.stackLimits)-1]
 	}
@@ -2101,11 +2485,14 @@ func (s *valueTypeStack) tryPop() (vt ValueType, limit int, ok bool) {
 		return
 	} else if stackLen == limit+1 && s.stack[limit] == valueTypeUnknown {
 		vt = valueTypeUnknown
+		ref = unknownHeapRef
 		ok = true
 		return
 	} else {
 		vt = s.stack[stackLen-1]
+		ref = s.refs[stackLen-1]
 		s.stack = s.stack[:stackLen-1]
+		s.refs = s.refs[:stackLen-1]
 		ok = true
 		return
 	}
@@ -2131,16 +2518,58 @@ func (s *valueTypeStack) popAndVerifyType(expected ValueType) error {
 	return nil
 }
 
+// popAndVerifyRef is the same as popAndVerifyType, except that it also verifies that the precise type of the
+// value is a subtype of want.
+func (s *valueTypeStack) popAndVerifyRef(expected ValueType, want HeapRef) error {
+	have, ref, _, ok := s.tryPopRef()
+	if !ok {
+		return fmt.Errorf("%s missing", ValueTypeName(expected))
+	}
+	if have != expected && have != valueTypeUnknown && expected != valueTypeUnknown {
+		return fmt.Errorf("type mismatch: expected %s, but was %s", ValueTypeName(expected), ValueTypeName(have))
+	}
+	if !s.m.isHeapRefSubType(ref, s.m, want) {
+		return fmt.Errorf("type mismatch: expected %s, but was %s", want, ref)
+	}
+	return nil
+}
+
+// popRef pops a reference value, and returns its precise type.
+func (s *valueTypeStack) popRef() (ValueType, HeapRef, error) {
+	if vt, ref, limit, ok := s.tryPopRef(); ok {
+		return vt, ref, nil
+	} else {
+		return 0, ref, fmt.Errorf("invalid operation: trying to pop at %d with limit %d", len(s.stack), limit)
+	}
+}
+
 func (s *valueTypeStack) push(v ValueType) {
+	s.pushRef(v, unknownHeapRef)
+}
+
+// pushRef is the same as push, except that the precise type of the value is known.
+func (s *valueTypeStack) pushRef(v ValueType, ref HeapRef) {
+	if v == ValueTypeAnyref {
+		s.usesGC = true
+	}
 	s.stack = append(s.stack, v)
+	s.refs = append(s.refs, ref)
 	if sp := len(s.stack); sp > s.maximumStackPointer {
 		s.maximumStackPointer = sp
 	}
 }
 
+// pushTypes pushes the values of types, where refs are their optional precise types.
+func (s *valueTypeStack) pushTypes(types []ValueType, refs []HeapRef) {
+	for i, vt := range types {
+		s.pushRef(vt, heapRefAt(types, refs, i))
+	}
+}
+
 func (s *valueTypeStack) unreachable() {
 	s.resetAtStackLimit()
 	s.stack = append(s.stack, valueTypeUnknown)
+	s.refs = append(s.refs, unknownHeapRef)
 }
 
 func (s *valueTypeStack) resetAtStackLimit() {
@@ -2149,6 +2578,7 @@ func (s *valueTypeStack) resetAtStackLimit() {
 	} else {
 		s.stack = s.stack[:0]
 	}
+	s.refs = s.refs[:len(s.stack)]
 }
 
 func (s *valueTypeStack) popStackLimit() {
@@ -2163,18 +2593,28 @@ func (s *valueTypeStack) pushStackLimit(params int) {
 	s.stackLimits = append(s.stackLimits, limit)
 }
 
-func (s *valueTypeStack) popParams(oc Opcode, want []ValueType, checkAboveLimit bool) error {
-	return s.requireStackValues(true, InstructionName(oc), want, checkAboveLimit)
+// peekResults is the same as popResults, except that the values are kept on the stack.
+func (s *valueTypeStack) peekResults(oc Opcode, want []ValueType, wantRefs []HeapRef) error {
+	stackLen := len(s.stack)
+	err := s.popResults(oc, want, wantRefs, false)
+	s.stack, s.refs = s.stack[:stackLen], s.refs[:stackLen]
+	return err
+}
+
+func (s *valueTypeStack) popParams(oc Opcode, want []ValueType, wantRefs []HeapRef, checkAboveLimit bool) error {
+	return s.requireStackValues(true, InstructionName(oc), want, wantRefs, checkAboveLimit)
 }
 
-func (s *valueTypeStack) popResults(oc Opcode, want []ValueType, checkAboveLimit bool) error {
-	return s.requireStackValues(false, InstructionName(oc), want, checkAboveLimit)
+func (s *valueTypeStack) popResults(oc Opcode, want []ValueType, wantRefs []HeapRef, checkAboveLimit bool) error {
+	return s.requireStackValues(false, InstructionName(oc), want, wantRefs, checkAboveLimit)
 }
 
+// requireStackValues pops the values of want, where wantRefs are their optional precise types.
 func (s *valueTypeStack) requireStackValues(
 	isParam bool,
 	context string,
 	want []ValueType,
+	wantRefs []HeapRef,
 	checkAboveLimit bool,
 ) error {
 	limit := 0
@@ -2186,8 +2626,9 @@ func (s *valueTypeStack) requireStackValues(
 
 	// First, check if there are enough values on the stack.
 	s.requireStackValuesTmp = s.requireStackValuesTmp[:0]
+	s.requireStackValuesRefsTmp = s.requireStackValuesRefsTmp[:0]
 	for i := countWanted - 1; i >= 0; i-- {
-		popped, _, ok := s.tryPop()
+		popped, ref, _, ok := s.tryPopRef()
 		if !ok {
 			if len(s.requireStackValuesTmp) > len(want) {
 				return typeCountError(isParam, context, s.requireStackValuesTmp, want)
@@ -2195,6 +2636,7 @@ func (s *valueTypeStack) requireStackValues(
 			return typeCountError(isParam, context, s.requireStackValuesTmp, want)
 		}
 		s.requireStackValuesTmp = append(s.requireStackValuesTmp, popped)
+		s.requireStackValuesRefsTmp = append(s.requireStackValuesRefsTmp, ref)
 	}
 
 	// Now, check if there are too many values.
@@ -2210,10 +2652,56 @@ func (s *valueTypeStack) requireStackValues(
 		if v != nextWant && v != valueTypeUnknown && nextWant != valueTypeUnknown {
 			return typeMismatchError(isParam, context, v, nextWant, i)
 		}
+		if wantRefs != nil {
+			have, want := s.requireStackValuesRefsTmp[i], wantRefs[countWanted-i-1]
+			if !s.m.isHeapRefSubType(have, s.m, want) {
+				return refTypeMismatchError(isParam, context, have, want, i)
+			}
+		}
+	}
+	return nil
+}
+
+// requireResults returns an error unless the results of the function type ft are the same as, or the subtypes of
+// those of the function type want, e.g. for a tail call.
+func (m *Module) requireResults(context string, ft, want *FunctionType) error {
+	if len(ft.Results) != len(want.Results) {
+		return typeCountError(false, context, ft.Results, want.Results)
+	}
+	for i, vt := range ft.Results {
+		if vt != want.Results[i] {
+			return typeMismatchError(false, context, vt, want.Results[i], i)
+		}
+		have, wantRef := heapRefAt(ft.Results, ft.ResultRefs, i), heapRefAt(want.Results, want.ResultRefs, i)
+		if !m.isHeapRefSubType(have, m, wantRef) {
+			return refTypeMismatchError(false, context, have, wantRef, i)
+		}
 	}
 	return nil
 }
 
+// refTypeMismatchError is the same as typeMismatchError, except that it is for the precise types of references.
+func refTypeMismatchError(isParam bool, context string, have, want HeapRef, i int) error {
+	var ret strings.Builder
+	ret.WriteString("cannot use ")
+	ret.WriteString(have.String())
+	if context != "" {
+		ret.WriteString(" in ")
+		ret.WriteString(context)
+		ret.WriteString(" block")
+	}
+	if isParam {
+		ret.WriteString(" as param")
+	} else {
+		ret.WriteString(" as result")
+	}
+	ret.WriteString("[")
+	ret.WriteString(strconv.Itoa(i))
+	ret.WriteString("] type ")
+	ret.WriteString(want.String())
+	return errors.New(ret.String())
+}
+
 // typeMismatchError returns an error similar to go compiler's error on type mismatch.
 func typeMismatchError(isParam bool, context string, have ValueType, want ValueType, i int) error {
 	var ret strings.Builder
@@ -2298,21 +2786,35 @@ func (s *valueTypeStack) String() string {
 		strings.Join(typeStrs, ", "), strings.Join(limits, ","))
 }
 
+// labelTypes returns the types of the values passed by the branch to the block, and their optional precise types.
+func (b *controlBlock) labelTypes() ([]ValueType, []HeapRef) {
+	if b.op == OpcodeLoop {
+		return b.blockType.Params, b.blockType.ParamRefs
+	}
+	return b.blockType.Results, b.blockType.ResultRefs
+}
+
 type controlBlock struct {
 	startAt, elseAt, endAt uint64
 	blockType              *FunctionType
 	blockTypeBytes         uint64
 	// op is zero when the outermost block
 	op Opcode
+	// initializedLocals is the length of controlBlockStack.initializedLocals at the start of the block.
+	initializedLocals int
 }
 
 // DecodeBlockType decodes the type index from a positive 33-bit signed integer. Negative numbers indicate up to one
 // WebAssembly 1.0 (20191205) compatible result type. Positive numbers are decoded when `enabledFeatures` include
 // CoreFeatureMultiValue and include an index in the Module.TypeSection.
 //
+// When experimental.CoreFeaturesGC is enabled, the single result can also be any reference type of the GC proposal,
+// which is resolved with the type section of m. References to function types are also allowed by
+// experimental.CoreFeaturesFunctionReferences.
+//
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-blocktype
 // See https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/multi-value/Overview.md
-func DecodeBlockType(types []FunctionType, r *bytes.Reader, enabledFeatures api.CoreFeatures) (*FunctionType, uint64, error) {
+func DecodeBlockType(m *Module, r *bytes.Reader, enabledFeatures api.CoreFeatures) (*FunctionType, uint64, error) {
 	raw, num, err := leb128.DecodeInt33AsInt64(r)
 	if err != nil {
 		return nil, 0, fmt.Errorf("decode int33: %w", err)
@@ -2336,18 +2838,70 @@ func DecodeBlockType(types []FunctionType, r *bytes.Reader, enabledFeatures api.
 		ret = blockType_v_funcref
 	case -17: // 0x6f in original byte = externref
 		ret = blockType_v_externref
+	case -23: // 0x69 in original byte = exnref
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+			return nil, num, fmt.Errorf("block with exnref return invalid as %v", err)
+		}
+		ret = blockType_v_exnref
+	case -24: // 0x68 in original byte = contref
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesStackSwitching); err != nil {
+			return nil, num, fmt.Errorf("block with contref return invalid as %v", err)
+		}
+		ret = blockType_v_contref
+	case int64(RefTypePrefixNullable) - 0x80, int64(RefTypePrefixNonNullable) - 0x80:
+		if err = RequireTypedReferences(enabledFeatures); err != nil {
+			return nil, num, fmt.Errorf("block with reference type return invalid as %v", err)
+		}
+		ht, n, err := DecodeHeapType(r, enabledFeatures)
+		if err != nil {
+			return nil, num, err
+		}
+		num += n
+		vt, err := m.HeapTypeValueType(ht)
+		if err != nil {
+			return nil, num, err
+		}
+		ret = blockTypeReference(vt, HeapRef{HeapType: ht, NonNullable: raw == int64(RefTypePrefixNonNullable)-0x80})
 	default:
+		if vt, ok := abstractHeapTypeValueType(raw); ok {
+			// The other abstract heap types of the GC proposal abbreviated as a single byte.
+			if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+				return nil, num, fmt.Errorf("block with %sref return invalid as %v", heapTypeName(raw), err)
+			}
+			ret = blockTypeReference(vt, HeapRef{HeapType: raw})
+			break
+		}
 		if err = enabledFeatures.RequireEnabled(api.CoreFeatureMultiValue); err != nil {
 			return nil, num, fmt.Errorf("block with function type return invalid as %v", err)
 		}
-		if raw < 0 || (raw >= int64(len(types))) {
+		if raw < 0 || (raw >= int64(len(m.TypeSection))) {
 			return nil, 0, fmt.Errorf("type index out of range: %d", raw)
 		}
-		ret = &types[raw]
+		ret = &m.TypeSection[raw]
 	}
 	return ret, num, err
 }
 
+// blockTypeReference returns the block type with the single result of the reference type, which is only allocated
+// if the precise type ref is not the DefaultHeapRef.
+func blockTypeReference(vt ValueType, ref HeapRef) *FunctionType {
+	if ref != DefaultHeapRef(vt) {
+		return &FunctionType{Results: []ValueType{vt}, ResultRefs: []HeapRef{ref}, ResultNumInUint64: 1}
+	}
+	switch vt {
+	case ValueTypeFuncref:
+		return blockType_v_funcref
+	case ValueTypeExternref:
+		return blockType_v_externref
+	case ValueTypeExnref:
+		return blockType_v_exnref
+	case ValueTypeContref:
+		return blockType_v_contref
+	default:
+		return blockType_v_anyref
+	}
+}
+
 // These block types are defined as globals in order to avoid allocations in DecodeBlockType.
 var (
 	blockType_v_v         = &FunctionType{}
@@ -2358,8 +2912,656 @@ var (
 	blockType_v_v128      = &FunctionType{Results: []ValueType{ValueTypeV128}, ResultNumInUint64: 2}
 	blockType_v_funcref   = &FunctionType{Results: []ValueType{ValueTypeFuncref}, ResultNumInUint64: 1}
 	blockType_v_externref = &FunctionType{Results: []ValueType{ValueTypeExternref}, ResultNumInUint64: 1}
+	blockType_v_exnref    = &FunctionType{Results: []ValueType{ValueTypeExnref}, ResultNumInUint64: 1}
+	blockType_v_anyref    = &FunctionType{Results: []ValueType{ValueTypeAnyref}, ResultNumInUint64: 1}
+	blockType_v_contref   = &FunctionType{Results: []ValueType{ValueTypeContref}, ResultNumInUint64: 1}
 )
 
+// validateCatch checks that the values passed by the catch clause match the types of its target label.
+func (m *Module) validateCatch(c *TryTableCatch, controlBlockStack *controlBlockStack, tags []Index) error {
+	if int(c.Label) >= len(controlBlockStack.stack) {
+		return fmt.Errorf("label index out of range: %d", c.Label)
+	}
+	target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(c.Label)-1]
+	targetTypes, targetRefs := target.labelTypes()
+
+	var types []ValueType
+	var refs []HeapRef
+	switch c.Kind {
+	case CatchKindCatch, CatchKindCatchRef:
+		if int(c.Tag) >= len(tags) {
+			return fmt.Errorf("unknown tag %d", c.Tag)
+		} else if len(m.TypeSection[tags[c.Tag]].Results) > 0 {
+			return fmt.Errorf("tag %d with results cannot be caught", c.Tag)
+		}
+		tagType := &m.TypeSection[tags[c.Tag]]
+		types = tagType.Params
+		for i := range types {
+			refs = append(refs, heapRefAt(types, tagType.ParamRefs, i))
+		}
+		if c.Kind == CatchKindCatchRef {
+			// The caught exception is passed as a non-null reference.
+			types = append(slices.Clip(types), ValueTypeExnref)
+			refs = append(refs, HeapRef{HeapType: HeapTypeExn, NonNullable: true})
+		}
+	case CatchKindCatchAllRef:
+		types = []ValueType{ValueTypeExnref}
+		refs = []HeapRef{{HeapType: HeapTypeExn, NonNullable: true}}
+	}
+	if !bytes.Equal(types, targetTypes) {
+		return fmt.Errorf("type mismatch: %s != %s", valueTypesString(types), valueTypesString(targetTypes))
+	}
+	for i := range types {
+		if want := heapRefAt(targetTypes, targetRefs, i); !m.isHeapRefSubType(refs[i], m, want) {
+			return fmt.Errorf("type mismatch: %s != %s", refs[i], want)
+		}
+	}
+	return nil
+}
+
+// validateStackSwitchingInstruction validates the stack switching instruction op whose immediates start at
+// immediates, and returns the number of bytes read for them.
+//
+// Continuations are only distinguished as ValueTypeContref the same way as the other references, so the types of
+// the continuations are checked at runtime instead.
+//
+// See https://github.com/WebAssembly/stack-switching/blob/main/proposals/stack-switching/Explainer.md#instructions
+func (m *Module) validateStackSwitchingInstruction(op OpcodeStackSwitching, immediates []byte,
+	valueTypeStack *valueTypeStack, controlBlockStack *controlBlockStack, tags []Index,
+) (read uint64, err error) {
+	readIndex := func() (Index, error) {
+		v, num, err := leb128.LoadUint32(immediates[read:])
+		if err != nil {
+			return 0, fmt.Errorf("read immediate: %v", err)
+		}
+		read += num
+		return v, nil
+	}
+	readContType := func() (*FunctionType, error) {
+		typeIndex, err := readIndex()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = m.compositeType(typeIndex, CompositeTypeKindCont); err != nil {
+			return nil, err
+		}
+		return m.ContinuationType(typeIndex), nil
+	}
+	readTag := func() (*FunctionType, error) {
+		tagIndex, err := readIndex()
+		if err != nil {
+			return nil, err
+		}
+		if int(tagIndex) >= len(tags) {
+			return nil, fmt.Errorf("unknown tag %d", tagIndex)
+		}
+		return &m.TypeSection[tags[tagIndex]], nil
+	}
+	popParams := func(params []ValueType) error {
+		for i := range params {
+			if err := valueTypeStack.popAndVerifyType(params[len(params)-1-i]); err != nil {
+				return fmt.Errorf("type mismatch on param type: %v", err)
+			}
+		}
+		return nil
+	}
+
+	switch op {
+	case OpcodeStackSwitchingContNew:
+		if _, err = readContType(); err != nil {
+			return
+		}
+		if err = valueTypeStack.popAndVerifyType(ValueTypeFuncref); err != nil {
+			return 0, fmt.Errorf("cannot pop the function reference: %v", err)
+		}
+		valueTypeStack.push(ValueTypeContref)
+	case OpcodeStackSwitchingContBind:
+		var from, to *FunctionType
+		if from, err = readContType(); err != nil {
+			return
+		}
+		if to, err = readContType(); err != nil {
+			return
+		}
+		bound := len(from.Params) - len(to.Params)
+		if bound < 0 || !to.EqualsSignature(from.Params[bound:], from.Results) {
+			return 0, fmt.Errorf("type mismatch: cannot bind %s to %s", from, to)
+		}
+		if err = valueTypeStack.popAndVerifyType(ValueTypeContref); err != nil {
+			return 0, fmt.Errorf("cannot pop the continuation: %v", err)
+		}
+		if err = popParams(from.Params[:bound]); err != nil {
+			return
+		}
+		valueTypeStack.push(ValueTypeContref)
+	case OpcodeStackSwitchingSuspend:
+		var tag *FunctionType
+		if tag, err = readTag(); err != nil {
+			return
+		}
+		if err = popParams(tag.Params); err != nil {
+			return
+		}
+		for _, t := range tag.Results {
+			valueTypeStack.push(t)
+		}
+	case OpcodeStackSwitchingResume:
+		var ft *FunctionType
+		if ft, err = readContType(); err != nil {
+			return
+		}
+		var handlerCount uint32
+		if handlerCount, err = readIndex(); err != nil {
+			return
+		}
+		for i := uint32(0); i < handlerCount; i++ {
+			if int(read) >= len(immediates) {
+				return 0, fmt.Errorf("read handler[%d]: unexpected end of body", i)
+			}
+			kind := immediates[read]
+			read++
+			if kind != ResumeHandlerKindOnLabel {
+				return 0, fmt.Errorf("unsupported handler[%d] kind: 0x%x", i, kind)
+			}
+			var tag *FunctionType
+			if tag, err = readTag(); err != nil {
+				return 0, fmt.Errorf("handler[%d]: %v", i, err)
+			}
+			var label Index
+			if label, err = readIndex(); err != nil {
+				return 0, fmt.Errorf("handler[%d]: %v", i, err)
+			}
+			if int(label) >= len(controlBlockStack.stack) {
+				return 0, fmt.Errorf("handler[%d]: label index out of range: %d", i, label)
+			}
+			target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(label)-1]
+			targetTypes := target.blockType.Results
+			if target.op == OpcodeLoop {
+				targetTypes = target.blockType.Params
+			}
+			if types := append(slices.Clip(tag.Params), ValueTypeContref); !bytes.Equal(types, targetTypes) {
+				return 0, fmt.Errorf("handler[%d]: type mismatch: %s != %s", i, valueTypesString(types), valueTypesString(targetTypes))
+			}
+		}
+		if err = valueTypeStack.popAndVerifyType(ValueTypeContref); err != nil {
+			return 0, fmt.Errorf("cannot pop the continuation: %v", err)
+		}
+		if err = popParams(ft.Params); err != nil {
+			return
+		}
+		for _, t := range ft.Results {
+			valueTypeStack.push(t)
+		}
+	default:
+		return 0, fmt.Errorf("not supported yet")
+	}
+	return
+}
+
+// validateGCInstruction validates the GC instruction whose opcode starts at body[pc] after OpcodeGCPrefix, and
+// returns the number of bytes read for the opcode and its immediates.
+//
+// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md#instructions
+func (m *Module) validateGCInstruction(pc uint64, body []byte, br *bytes.Reader, valueTypeStack *valueTypeStack,
+	controlBlockStack *controlBlockStack, enabledFeatures api.CoreFeatures,
+) (read uint64, err error) {
+	br.Reset(body[pc:])
+	gcOp32, _, err := leb128.DecodeUint32(br)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read gc opcode: %v", err)
+	}
+	if uint32(byte(gcOp32)) != gcOp32 {
+		return 0, fmt.Errorf("invalid gc opcode: %#x", gcOp32)
+	}
+	gcOp := byte(gcOp32)
+	name := GCInstructionName(gcOp)
+
+	readIndex := func() (Index, error) {
+		v, _, err := leb128.DecodeUint32(br)
+		if err != nil {
+			return 0, fmt.Errorf("read immediate for %s: %v", name, err)
+		}
+		return v, nil
+	}
+	pop := func(vt ValueType) error {
+		if err := valueTypeStack.popAndVerifyType(vt); err != nil {
+			return fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+		}
+		return nil
+	}
+	popN := func(vts ...ValueType) error {
+		for _, vt := range vts {
+			if err := pop(vt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	popRef := func(vt ValueType, ref HeapRef) error {
+		if err := valueTypeStack.popAndVerifyRef(vt, ref); err != nil {
+			return fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+		}
+		return nil
+	}
+	popField := func(field *FieldType) error {
+		return popRef(field.ValueType(), field.heapRef())
+	}
+	pushField := func(field *FieldType) {
+		valueTypeStack.pushRef(field.ValueType(), field.heapRef())
+	}
+	// popObject pops the reference to the struct or array of the type at typeIndex, or its subtype.
+	popObject := func(typeIndex Index) error {
+		return popRef(ValueTypeAnyref, HeapRef{HeapType: HeapType(typeIndex)})
+	}
+	// pushObject pushes the reference to the newly allocated object of the type at typeIndex.
+	pushObject := func(typeIndex Index) {
+		valueTypeStack.pushRef(ValueTypeAnyref, HeapRef{HeapType: HeapType(typeIndex), NonNullable: true})
+	}
+	readType := func(kind CompositeTypeKind) (Index, *SubType, error) {
+		typeIndex, err := readIndex()
+		if err != nil {
+			return 0, nil, err
+		}
+		st, err := m.compositeType(typeIndex, kind)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid type for %s: %v", name, err)
+		}
+		return typeIndex, st, nil
+	}
+	readMutableArray := func() (Index, *SubType, error) {
+		typeIndex, st, err := readType(CompositeTypeKindArray)
+		if err == nil && !st.Fields[0].Mutable {
+			err = fmt.Errorf("%s on immutable array", name)
+		}
+		return typeIndex, st, err
+	}
+	readHeapType := func() (ValueType, HeapType, error) {
+		ht, _, err := DecodeHeapType(br, enabledFeatures)
+		if err != nil {
+			return 0, 0, fmt.Errorf("read heap type for %s: %v", name, err)
+		}
+		vt, err := m.HeapTypeValueType(ht)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid heap type for %s: %v", name, err)
+		}
+		return vt, ht, nil
+	}
+
+	switch gcOp {
+	case OpcodeGCStructNew, OpcodeGCStructNewDefault:
+		typeIndex, st, err := readType(CompositeTypeKindStruct)
+		if err != nil {
+			return 0, err
+		}
+		if gcOp == OpcodeGCStructNew {
+			for i := len(st.Fields) - 1; i >= 0; i-- {
+				if err = popField(&st.Fields[i]); err != nil {
+					return 0, err
+				}
+			}
+		} else if err = st.requireDefaultable(name); err != nil {
+			return 0, err
+		}
+		pushObject(typeIndex)
+	case OpcodeGCStructGet, OpcodeGCStructGetS, OpcodeGCStructGetU, OpcodeGCStructSet:
+		typeIndex, st, err := readType(CompositeTypeKindStruct)
+		if err != nil {
+			return 0, err
+		}
+		fieldIndex, err := readIndex()
+		if err != nil {
+			return 0, err
+		}
+		if fieldIndex >= Index(len(st.Fields)) {
+			return 0, fmt.Errorf("unknown field %d for %s", fieldIndex, name)
+		}
+		field := &st.Fields[fieldIndex]
+		switch gcOp {
+		case OpcodeGCStructGet, OpcodeGCStructGetS, OpcodeGCStructGetU:
+			if field.IsPacked() != (gcOp != OpcodeGCStructGet) {
+				return 0, fmt.Errorf("%s on the field of type %s", name, storageTypeName(field.Type))
+			}
+			if err = popObject(typeIndex); err != nil {
+				return 0, err
+			}
+			pushField(field)
+		default:
+			if !field.Mutable {
+				return 0, fmt.Errorf("%s on immutable field %d", name, fieldIndex)
+			}
+			if err = popField(field); err != nil {
+				return 0, err
+			}
+			if err = popObject(typeIndex); err != nil {
+				return 0, err
+			}
+		}
+	case OpcodeGCArrayNew, OpcodeGCArrayNewDefault:
+		typeIndex, st, err := readType(CompositeTypeKindArray)
+		if err != nil {
+			return 0, err
+		}
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if gcOp == OpcodeGCArrayNew {
+			if err = popField(&st.Fields[0]); err != nil {
+				return 0, err
+			}
+		} else if err = st.requireDefaultable(name); err != nil {
+			return 0, err
+		}
+		pushObject(typeIndex)
+	case OpcodeGCArrayNewFixed:
+		typeIndex, st, err := readType(CompositeTypeKindArray)
+		if err != nil {
+			return 0, err
+		}
+		n, err := readIndex()
+		if err != nil {
+			return 0, err
+		}
+		for i := uint32(0); i < n; i++ {
+			if err = popField(&st.Fields[0]); err != nil {
+				return 0, err
+			}
+		}
+		pushObject(typeIndex)
+	case OpcodeGCArrayNewData, OpcodeGCArrayInitData:
+		var typeIndex Index
+		var st *SubType
+		if gcOp == OpcodeGCArrayNewData {
+			typeIndex, st, err = readType(CompositeTypeKindArray)
+		} else {
+			typeIndex, st, err = readMutableArray()
+		}
+		if err != nil {
+			return 0, err
+		}
+		if isReferenceValueType(st.Fields[0].Type) {
+			return 0, fmt.Errorf("%s on array of %s", name, ValueTypeName(st.Fields[0].Type))
+		}
+		dataIndex, err := readIndex()
+		if err != nil {
+			return 0, err
+		}
+		if m.DataCountSection == nil {
+			return 0, fmt.Errorf("%s requires data count section", name)
+		} else if dataIndex >= *m.DataCountSection {
+			return 0, fmt.Errorf("index %d out of range of data section(len=%d)", dataIndex, *m.DataCountSection)
+		}
+		if gcOp == OpcodeGCArrayNewData {
+			if err = popN(ValueTypeI32, ValueTypeI32); err != nil {
+				return 0, err
+			}
+			pushObject(typeIndex)
+		} else {
+			if err = popN(ValueTypeI32, ValueTypeI32, ValueTypeI32); err != nil {
+				return 0, err
+			}
+			if err = popObject(typeIndex); err != nil {
+				return 0, err
+			}
+		}
+	case OpcodeGCArrayNewElem, OpcodeGCArrayInitElem:
+		var typeIndex Index
+		var st *SubType
+		if gcOp == OpcodeGCArrayNewElem {
+			typeIndex, st, err = readType(CompositeTypeKindArray)
+		} else {
+			typeIndex, st, err = readMutableArray()
+		}
+		if err != nil {
+			return 0, err
+		}
+		elemIndex, err := readIndex()
+		if err != nil {
+			return 0, err
+		}
+		if elemIndex >= Index(len(m.ElementSection)) {
+			return 0, fmt.Errorf("index %d out of range of element section(len=%d)", elemIndex, len(m.ElementSection))
+		}
+		elem := &m.ElementSection[elemIndex]
+		if field := &st.Fields[0]; elem.Type != field.Type || !m.isHeapRefSubType(heapRefOf(elem.Type, elem.Ref), m, field.heapRef()) {
+			return 0, fmt.Errorf("type mismatch for %s: element segment of %s != array of %s",
+				name, heapRefOf(elem.Type, elem.Ref), field.heapRef())
+		}
+		if gcOp == OpcodeGCArrayNewElem {
+			if err = popN(ValueTypeI32, ValueTypeI32); err != nil {
+				return 0, err
+			}
+			pushObject(typeIndex)
+		} else {
+			if err = popN(ValueTypeI32, ValueTypeI32, ValueTypeI32); err != nil {
+				return 0, err
+			}
+			if err = popObject(typeIndex); err != nil {
+				return 0, err
+			}
+		}
+	case OpcodeGCArrayGet, OpcodeGCArrayGetS, OpcodeGCArrayGetU:
+		typeIndex, st, err := readType(CompositeTypeKindArray)
+		if err != nil {
+			return 0, err
+		}
+		field := &st.Fields[0]
+		if field.IsPacked() != (gcOp != OpcodeGCArrayGet) {
+			return 0, fmt.Errorf("%s on array of %s", name, storageTypeName(field.Type))
+		}
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if err = popObject(typeIndex); err != nil {
+			return 0, err
+		}
+		pushField(field)
+	case OpcodeGCArraySet:
+		typeIndex, st, err := readMutableArray()
+		if err != nil {
+			return 0, err
+		}
+		if err = popField(&st.Fields[0]); err != nil {
+			return 0, err
+		}
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if err = popObject(typeIndex); err != nil {
+			return 0, err
+		}
+	case OpcodeGCArrayLen:
+		if err = popRef(ValueTypeAnyref, HeapRef{HeapType: HeapTypeArray}); err != nil {
+			return 0, err
+		}
+		valueTypeStack.push(ValueTypeI32)
+	case OpcodeGCArrayFill:
+		typeIndex, st, err := readMutableArray()
+		if err != nil {
+			return 0, err
+		}
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if err = popField(&st.Fields[0]); err != nil {
+			return 0, err
+		}
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if err = popObject(typeIndex); err != nil {
+			return 0, err
+		}
+	case OpcodeGCArrayCopy:
+		dstIndex, dst, err := readMutableArray()
+		if err != nil {
+			return 0, err
+		}
+		srcIndex, src, err := readType(CompositeTypeKindArray)
+		if err != nil {
+			return 0, err
+		}
+		// The elements of the source must be assignable to those of the destination.
+		if df, sf := &dst.Fields[0], &src.Fields[0]; df.Type != sf.Type || !m.isHeapRefSubType(sf.heapRef(), m, df.heapRef()) {
+			return 0, fmt.Errorf("type mismatch for %s: %s != %s", name, fieldTypeName(df), fieldTypeName(sf))
+		}
+		if err = popN(ValueTypeI32, ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if err = popObject(srcIndex); err != nil {
+			return 0, err
+		}
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		if err = popObject(dstIndex); err != nil {
+			return 0, err
+		}
+	case OpcodeGCRefTest, OpcodeGCRefTestNull, OpcodeGCRefCast, OpcodeGCRefCastNull:
+		vt, ht, err := readHeapType()
+		if err != nil {
+			return 0, err
+		}
+		// The operand can be of any type in the same hierarchy, as the type to test is its subtype.
+		if err = popRef(vt, DefaultHeapRef(vt)); err != nil {
+			return 0, err
+		}
+		switch gcOp {
+		case OpcodeGCRefTest, OpcodeGCRefTestNull:
+			valueTypeStack.push(ValueTypeI32)
+		default:
+			valueTypeStack.pushRef(vt, HeapRef{HeapType: ht, NonNullable: gcOp == OpcodeGCRefCast})
+		}
+	case OpcodeGCBrOnCast, OpcodeGCBrOnCastFail:
+		flags, err := br.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("read flags for %s: %v", name, err)
+		} else if flags > 3 {
+			return 0, fmt.Errorf("invalid flags for %s: %#x", name, flags)
+		}
+		label, err := readIndex()
+		if err != nil {
+			return 0, err
+		} else if int(label) >= len(controlBlockStack.stack) {
+			return 0, fmt.Errorf("invalid label %d for %s", label, name)
+		}
+		vt1, ht1, err := readHeapType()
+		if err != nil {
+			return 0, err
+		}
+		vt2, ht2, err := readHeapType()
+		if err != nil {
+			return 0, err
+		}
+		rt1 := HeapRef{HeapType: ht1, NonNullable: flags&1 == 0}
+		rt2 := HeapRef{HeapType: ht2, NonNullable: flags&2 == 0}
+		if vt1 != vt2 || !m.isHeapRefSubType(rt2, m, rt1) {
+			return 0, fmt.Errorf("type mismatch for %s: %s is not a subtype of %s", name, rt2, rt1)
+		}
+		if err = popRef(vt1, rt1); err != nil {
+			return 0, err
+		}
+		// The operand which fails the cast is non-null if null passes it.
+		diff := HeapRef{HeapType: ht1, NonNullable: rt1.NonNullable || !rt2.NonNullable}
+		sent, kept := rt2, diff
+		if gcOp == OpcodeGCBrOnCastFail {
+			sent, kept = diff, rt2
+		}
+		target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(label)-1]
+		targetResultType, targetResultRefs := target.labelTypes()
+		// The label receives the operand, which is pushed back to the stack if not branching.
+		n := len(targetResultType)
+		if n == 0 || targetResultType[n-1] != vt1 ||
+			!m.isHeapRefSubType(sent, m, heapRefAt(targetResultType, targetResultRefs, n-1)) {
+			return 0, fmt.Errorf("type mismatch for %s: label %d must receive %s", name, label, sent)
+		}
+		rest, restRefs := targetResultType[:n-1], targetResultRefs
+		if restRefs != nil {
+			restRefs = restRefs[:n-1]
+		}
+		if err = valueTypeStack.requireStackValues(false, name, rest, restRefs, false); err != nil {
+			return 0, err
+		}
+		valueTypeStack.pushTypes(rest, restRefs)
+		valueTypeStack.pushRef(vt1, kept)
+	case OpcodeGCAnyConvertExtern, OpcodeGCExternConvertAny:
+		from, to := ValueTypeExternref, ValueTypeAnyref
+		if gcOp == OpcodeGCExternConvertAny {
+			from, to = to, from
+		}
+		vt, ref, err := valueTypeStack.popRef()
+		if err != nil {
+			return 0, fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+		} else if vt != from && vt != valueTypeUnknown {
+			return 0, fmt.Errorf("cannot pop the operand for %s: type mismatch: expected %s, but was %s",
+				name, ValueTypeName(from), ValueTypeName(vt))
+		}
+		// The conversion preserves the nullability.
+		valueTypeStack.pushRef(to, HeapRef{HeapType: DefaultHeapRef(to).HeapType, NonNullable: ref.NonNullable})
+	case OpcodeGCRefI31:
+		if err = pop(ValueTypeI32); err != nil {
+			return 0, err
+		}
+		valueTypeStack.pushRef(ValueTypeAnyref, HeapRef{HeapType: HeapTypeI31, NonNullable: true})
+	case OpcodeGCI31GetS, OpcodeGCI31GetU:
+		if err = popRef(ValueTypeAnyref, HeapRef{HeapType: HeapTypeI31}); err != nil {
+			return 0, err
+		}
+		valueTypeStack.push(ValueTypeI32)
+	default:
+		return 0, fmt.Errorf("invalid gc opcode: %#x", gcOp)
+	}
+	return uint64(len(body[pc:]) - br.Len()), nil
+}
+
+func valueTypesString(types []ValueType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = ValueTypeName(t)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// TryTableCatch is a catch clause of OpcodeExceptionHandlingTryTable.
+type TryTableCatch struct {
+	Kind CatchKind
+	// Tag is the index of the tag to catch, and only valid with CatchKindCatch or CatchKindCatchRef.
+	Tag Index
+	// Label is the relative depth of the branch target, counted from outside the try_table.
+	Label Index
+}
+
+// DecodeTryTableCatches decodes the catch clauses of OpcodeExceptionHandlingTryTable, which follow its block type.
+// The decoded clauses are appended to ret, and the number of bytes read is returned.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#control-flow-instructions
+func DecodeTryTableCatches(r *bytes.Reader, ret []TryTableCatch) ([]TryTableCatch, uint64, error) {
+	size := r.Len()
+	n, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read the number of catch clauses: %w", err)
+	}
+	for i := uint32(0); i < n; i++ {
+		var c TryTableCatch
+		if c.Kind, err = r.ReadByte(); err != nil {
+			return nil, 0, fmt.Errorf("read catch kind: %w", err)
+		}
+		switch c.Kind {
+		case CatchKindCatch, CatchKindCatchRef:
+			if c.Tag, _, err = leb128.DecodeUint32(r); err != nil {
+				return nil, 0, fmt.Errorf("read catch tag: %w", err)
+			}
+		case CatchKindCatchAll, CatchKindCatchAllRef:
+		default:
+			return nil, 0, fmt.Errorf("invalid catch kind: %#x", c.Kind)
+		}
+		if c.Label, _, err = leb128.DecodeUint32(r); err != nil {
+			return nil, 0, fmt.Errorf("read catch label: %w", err)
+		}
+		ret = append(ret, c)
+	}
+	return ret, uint64(size - r.Len()), nil
+}
+
 // SplitCallStack returns the input stack resliced to the count of params and
 // results, or errors if it isn't long enough for either.
 func SplitCallStack(ft *FunctionType, stack []uint64) (params []uint64, results []uint64, err error) {
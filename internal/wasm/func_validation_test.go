@@ -3349,7 +3349,7 @@ func TestDecodeBlockType(t *testing.T) {
 		} {
 			tc := tc
 			t.Run(tc.name, func(t *testing.T) {
-				actual, read, err := DecodeBlockType(nil, bytes.NewReader([]byte{tc.in}), api.CoreFeaturesV2)
+				actual, read, err := DecodeBlockType(&Module{}, bytes.NewReader([]byte{tc.in}), api.CoreFeaturesV2)
 				require.NoError(t, err)
 				require.Equal(t, uint64(1), read)
 				require.Equal(t, 0, len(actual.Params))
@@ -3374,7 +3374,7 @@ func TestDecodeBlockType(t *testing.T) {
 		}
 		for index := range types {
 			expected := &types[index]
-			actual, read, err := DecodeBlockType(types, bytes.NewReader([]byte{byte(index)}), api.CoreFeatureMultiValue)
+			actual, read, err := DecodeBlockType(&Module{TypeSection: types}, bytes.NewReader([]byte{byte(index)}), api.CoreFeatureMultiValue)
 			require.NoError(t, err)
 			require.Equal(t, uint64(1), read)
 			require.Equal(t, expected, actual)
@@ -4870,3 +4870,639 @@ func TestModule_funcValidation_Atomic(t *testing.T) {
 		}
 	})
 }
+
+func TestModule_funcValidation_Memory64(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{
+			name: "i32.load",
+			body: []byte{OpcodeI64Const, 0x0, OpcodeI32Load, 0x2, 0x0, OpcodeDrop},
+		},
+		{
+			name: "i64.store with offset over 32-bit",
+			body: []byte{
+				OpcodeI64Const, 0x0, OpcodeI64Const, 0x1,
+				OpcodeI64Store, 0x3, 0x80, 0x80, 0x80, 0x80, 0x10, // offset=2^32
+			},
+		},
+		{
+			name: "memory.size",
+			body: []byte{OpcodeMemorySize, 0x0, OpcodeI64Const, 0x0, OpcodeI64Add, OpcodeDrop},
+		},
+		{
+			name: "memory.grow",
+			body: []byte{OpcodeI64Const, 0x1, OpcodeMemoryGrow, 0x0, OpcodeI64Const, 0x0, OpcodeI64Add, OpcodeDrop},
+		},
+		{
+			name: "memory.fill",
+			body: []byte{
+				OpcodeI64Const, 0x0, OpcodeI32Const, 0x1, OpcodeI64Const, 0x2,
+				OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0x0,
+			},
+		},
+		{
+			name: "memory.copy",
+			body: []byte{
+				OpcodeI64Const, 0x0, OpcodeI64Const, 0x1, OpcodeI64Const, 0x2,
+				OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0x0, 0x0,
+			},
+		},
+		{
+			name: "memory.init",
+			body: []byte{
+				OpcodeI64Const, 0x0, OpcodeI32Const, 0x1, OpcodeI32Const, 0x2,
+				OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0x0, 0x0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			dataCount := uint32(1)
+			m := &Module{
+				TypeSection:      []FunctionType{v_v},
+				FunctionSection:  []Index{0},
+				CodeSection:      []Code{{Body: append(tc.body, OpcodeEnd)}},
+				DataSection:      []DataSegment{{Passive: true}},
+				DataCountSection: &dataCount,
+			}
+			features := api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64
+			err := m.validateFunction(&stacks{}, features,
+				0, []Index{0}, nil, &Memory{IsMemory64: true}, []Table{}, nil, bytes.NewReader(nil))
+			require.NoError(t, err)
+
+			// The same instructions are invalid on a memory indexed by i32.
+			err = m.validateFunction(&stacks{}, features,
+				0, []Index{0}, nil, &Memory{}, []Table{}, nil, bytes.NewReader(nil))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestModule_funcValidation_GC(t *testing.T) {
+	// type 0: () -> (), type 1: struct {mut i32, i8}, type 2: array (mut i16)
+	types := []FunctionType{v_v, {}, {}}
+	subTypes := []SubType{
+		{Final: true},
+		{Kind: CompositeTypeKindStruct, Final: true, Fields: []FieldType{{Type: ValueTypeI32, Mutable: true}, {Type: StorageTypeI8}}},
+		{Kind: CompositeTypeKindArray, Final: true, Fields: []FieldType{{Type: StorageTypeI16, Mutable: true}}},
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name: "struct.new and struct.get_s",
+			body: []byte{
+				OpcodeI32Const, 1, OpcodeI32Const, 2,
+				OpcodeGCPrefix, OpcodeGCStructNew, 1,
+				OpcodeGCPrefix, OpcodeGCStructGetS, 1, 1,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "array.new_default and array.set",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeGCPrefix, OpcodeGCArrayNewDefault, 2,
+				OpcodeI32Const, 0, OpcodeI32Const, 5,
+				OpcodeGCPrefix, OpcodeGCArraySet, 2,
+			},
+		},
+		{
+			name: "ref.test and ref.eq",
+			body: []byte{
+				OpcodeI32Const, 1, OpcodeGCPrefix, OpcodeGCRefI31,
+				OpcodeRefNull, 0x6d, // eq
+				OpcodeRefEq,
+				OpcodeRefNull, 0x6e, // any
+				OpcodeGCPrefix, OpcodeGCRefTest, 0x01,
+				OpcodeI32Add, OpcodeDrop,
+			},
+		},
+		{
+			name: "struct.get on packed field",
+			body: []byte{
+				OpcodeGCPrefix, OpcodeGCStructNewDefault, 1,
+				OpcodeGCPrefix, OpcodeGCStructGet, 1, 1,
+				OpcodeDrop,
+			},
+			expectedErr: "struct.get on the field of type i8",
+		},
+		{
+			name: "struct.set on immutable field",
+			body: []byte{
+				OpcodeGCPrefix, OpcodeGCStructNewDefault, 1,
+				OpcodeI32Const, 0,
+				OpcodeGCPrefix, OpcodeGCStructSet, 1, 1,
+			},
+			expectedErr: "struct.set on immutable field 1",
+		},
+		{
+			name: "struct.new with array type",
+			body: []byte{
+				OpcodeGCPrefix, OpcodeGCStructNewDefault, 2,
+				OpcodeDrop,
+			},
+			expectedErr: "invalid type for struct.new_default: type 2 is not a struct type",
+		},
+		{
+			name: "array.len on i32",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeGCPrefix, OpcodeGCArrayLen,
+				OpcodeDrop,
+			},
+			expectedErr: "cannot pop the operand for array.len: type mismatch: expected anyref, but was i32",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     types,
+				SubTypes:        subTypes,
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: append(tc.body, OpcodeEnd)}},
+			}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|experimental.CoreFeaturesGC,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.True(t, m.UsesGC)
+			}
+
+			// GC instructions are invalid unless the feature is enabled.
+			err = m.validateFunction(&stacks{}, api.CoreFeaturesV2,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestModule_funcValidation_FunctionReferences(t *testing.T) {
+	// type 0: () -> (), type 1: (i32) -> (i32)
+	types := []FunctionType{v_v, i32_i32}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name: "call_ref",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeRefNull, 1,
+				OpcodeFunctionReferencesCallRef, 1,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "return_call_ref",
+			body: []byte{
+				OpcodeRefNull, 0,
+				OpcodeFunctionReferencesReturnCallRef, 0,
+			},
+			features: experimental.CoreFeaturesTailCall,
+		},
+		{
+			name: "ref.as_non_null",
+			body: []byte{
+				OpcodeRefNull, RefTypeFuncref,
+				OpcodeFunctionReferencesRefAsNonNull,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "br_on_null",
+			body: []byte{
+				OpcodeBlock, 0x40,
+				OpcodeRefNull, 1,
+				OpcodeFunctionReferencesBrOnNull, 0,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+		},
+		{
+			name: "br_on_non_null",
+			body: []byte{
+				OpcodeBlock, RefTypeFuncref,
+				OpcodeRefNull, 1,
+				OpcodeFunctionReferencesBrOnNonNull, 0,
+				OpcodeRefNull, RefTypeFuncref,
+				OpcodeEnd,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "call_ref on i32",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeI32Const, 1,
+				OpcodeFunctionReferencesCallRef, 1,
+				OpcodeDrop,
+			},
+			expectedErr: "cannot pop the function reference for call_ref: type mismatch: expected funcref, but was i32",
+		},
+		{
+			name: "call_ref with unknown type",
+			body: []byte{
+				OpcodeRefNull, RefTypeFuncref,
+				OpcodeFunctionReferencesCallRef, 2,
+			},
+			expectedErr: "invalid type index at call_ref: 2",
+		},
+		{
+			name: "return_call_ref without tail-call",
+			body: []byte{
+				OpcodeRefNull, 0,
+				OpcodeFunctionReferencesReturnCallRef, 0,
+			},
+			expectedErr: "return_call_ref invalid as feature \"\" is disabled",
+		},
+		{
+			name: "br_on_non_null without reference in label",
+			body: []byte{
+				OpcodeBlock, 0x40,
+				OpcodeRefNull, 1,
+				OpcodeFunctionReferencesBrOnNonNull, 0,
+				OpcodeEnd,
+			},
+			expectedErr: "type mismatch for br_on_non_null: label 0 must receive funcref",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     types,
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: append(tc.body, OpcodeEnd)}},
+			}
+			for _, f := range []api.CoreFeatures{experimental.CoreFeaturesFunctionReferences, experimental.CoreFeaturesGC} {
+				err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|tc.features|f,
+					0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+				if tc.expectedErr != "" {
+					require.EqualError(t, err, tc.expectedErr)
+				} else {
+					require.NoError(t, err)
+					require.False(t, m.UsesGC)
+				}
+			}
+
+			// The instructions are invalid unless the feature is enabled.
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|tc.features,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestModule_funcValidation_NonNullableLocals(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name: "set before get",
+			body: []byte{
+				OpcodeRefFunc, 0,
+				OpcodeLocalSet, 0,
+				OpcodeLocalGet, 0,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "tee before get",
+			body: []byte{
+				OpcodeRefFunc, 0,
+				OpcodeLocalTee, 0,
+				OpcodeDrop,
+				OpcodeLocalGet, 0,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "get before set",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeDrop,
+			},
+			expectedErr: "uninitialized local 0 for local.get",
+		},
+		{
+			name: "set in block",
+			body: []byte{
+				OpcodeBlock, 0x40,
+				OpcodeRefFunc, 0,
+				OpcodeLocalSet, 0,
+				OpcodeEnd,
+				OpcodeLocalGet, 0,
+				OpcodeDrop,
+			},
+			expectedErr: "uninitialized local 0 for local.get",
+		},
+		{
+			name: "set null",
+			body: []byte{
+				OpcodeRefNull, 0,
+				OpcodeLocalSet, 0,
+			},
+			expectedErr: "type mismatch: expected (ref 0), but was (ref null 0)",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection: []Code{{
+					LocalTypes: []ValueType{ValueTypeFuncref},
+					LocalRefs:  []HeapRef{{HeapType: 0, NonNullable: true}},
+					Body:       append(tc.body, OpcodeEnd),
+				}},
+			}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|experimental.CoreFeaturesFunctionReferences,
+				0, []Index{0}, nil, nil, nil, map[Index]struct{}{0: {}}, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_RelaxedSIMD(t *testing.T) {
+	v128Const := []byte{OpcodeVecPrefix, OpcodeVecV128Const, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	relaxed := func(vecOp OpcodeVecRelaxed, operands int) (body []byte) {
+		for i := 0; i < operands; i++ {
+			body = append(body, v128Const...)
+		}
+		return append(body, OpcodeVecPrefix, vecOp, OpcodeVecRelaxedSecondByte, OpcodeDrop)
+	}
+
+	tests := []struct {
+		vecOp    OpcodeVecRelaxed
+		operands int
+	}{
+		{vecOp: OpcodeVecI8x16RelaxedSwizzle, operands: 2},
+		{vecOp: OpcodeVecI32x4RelaxedTruncF32x4S, operands: 1},
+		{vecOp: OpcodeVecI32x4RelaxedTruncF32x4U, operands: 1},
+		{vecOp: OpcodeVecI32x4RelaxedTruncF64x2SZero, operands: 1},
+		{vecOp: OpcodeVecI32x4RelaxedTruncF64x2UZero, operands: 1},
+		{vecOp: OpcodeVecF32x4RelaxedMadd, operands: 3},
+		{vecOp: OpcodeVecF32x4RelaxedNmadd, operands: 3},
+		{vecOp: OpcodeVecF64x2RelaxedMadd, operands: 3},
+		{vecOp: OpcodeVecF64x2RelaxedNmadd, operands: 3},
+		{vecOp: OpcodeVecI8x16RelaxedLaneselect, operands: 3},
+		{vecOp: OpcodeVecI16x8RelaxedLaneselect, operands: 3},
+		{vecOp: OpcodeVecI32x4RelaxedLaneselect, operands: 3},
+		{vecOp: OpcodeVecI64x2RelaxedLaneselect, operands: 3},
+		{vecOp: OpcodeVecF32x4RelaxedMin, operands: 2},
+		{vecOp: OpcodeVecF32x4RelaxedMax, operands: 2},
+		{vecOp: OpcodeVecF64x2RelaxedMin, operands: 2},
+		{vecOp: OpcodeVecF64x2RelaxedMax, operands: 2},
+		{vecOp: OpcodeVecI16x8RelaxedQ15mulrS, operands: 2},
+		{vecOp: OpcodeVecI16x8RelaxedDotI8x16I7x16S, operands: 2},
+		{vecOp: OpcodeVecI32x4RelaxedDotI8x16I7x16AddS, operands: 3},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		name := VectorRelaxedInstructionName(tc.vecOp)
+		t.Run(name, func(t *testing.T) {
+			validate := func(body []byte, features api.CoreFeatures) error {
+				m := &Module{
+					TypeSection:     []FunctionType{v_v},
+					FunctionSection: []Index{0},
+					CodeSection:     []Code{{Body: append(body, OpcodeEnd)}},
+				}
+				return m.validateFunction(&stacks{}, features, 0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			}
+
+			err := validate(relaxed(tc.vecOp, tc.operands), api.CoreFeaturesV2|experimental.CoreFeaturesRelaxedSIMD)
+			require.NoError(t, err)
+
+			err = validate(relaxed(tc.vecOp, tc.operands), api.CoreFeaturesV2)
+			require.EqualError(t, err, name+" invalid as feature \"\" is disabled")
+
+			err = validate(relaxed(tc.vecOp, tc.operands-1), api.CoreFeaturesV2|experimental.CoreFeaturesRelaxedSIMD)
+			require.Contains(t, err.Error(), "cannot pop the operand for "+name)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		m := &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{{Body: []byte{OpcodeVecPrefix, 0xff, OpcodeVecRelaxedSecondByte, OpcodeEnd}}},
+		}
+		err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|experimental.CoreFeaturesRelaxedSIMD,
+			0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+		require.EqualError(t, err, "unknown relaxed SIMD instruction 0x17f")
+	})
+}
+
+func TestModule_funcValidation_WideArithmetic(t *testing.T) {
+	wide := func(miscOp OpcodeMisc, operands int) (body []byte) {
+		for i := 0; i < operands; i++ {
+			body = append(body, OpcodeI64Const, 1)
+		}
+		return append(body, OpcodeMiscPrefix, miscOp, OpcodeDrop, OpcodeDrop)
+	}
+
+	tests := []struct {
+		miscOp   OpcodeMisc
+		operands int
+	}{
+		{miscOp: OpcodeMiscI64Add128, operands: 4},
+		{miscOp: OpcodeMiscI64Sub128, operands: 4},
+		{miscOp: OpcodeMiscI64MulWideS, operands: 2},
+		{miscOp: OpcodeMiscI64MulWideU, operands: 2},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		name := MiscInstructionName(tc.miscOp)
+		t.Run(name, func(t *testing.T) {
+			validate := func(body []byte, features api.CoreFeatures) error {
+				m := &Module{
+					TypeSection:     []FunctionType{v_v},
+					FunctionSection: []Index{0},
+					CodeSection:     []Code{{Body: append(body, OpcodeEnd)}},
+				}
+				return m.validateFunction(&stacks{}, features, 0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			}
+
+			err := validate(wide(tc.miscOp, tc.operands), api.CoreFeaturesV2|experimental.CoreFeaturesWideArithmetic)
+			require.NoError(t, err)
+
+			err = validate(wide(tc.miscOp, tc.operands), api.CoreFeaturesV2)
+			require.EqualError(t, err, name+" invalid as feature \"\" is disabled")
+
+			err = validate(wide(tc.miscOp, tc.operands-1), api.CoreFeaturesV2|experimental.CoreFeaturesWideArithmetic)
+			require.Contains(t, err.Error(), "cannot pop the operand for "+name)
+		})
+	}
+}
+
+func TestModule_funcValidation_StackSwitching(t *testing.T) {
+	contref := ValueTypeContref
+	m := &Module{
+		TypeSection: []FunctionType{
+			i32_i32,                              // type 0: (i32) -> (i32), which is the type of the tag 0 as well.
+			{},                                   // type 1: (cont 0)
+			{Results: []ValueType{i32, contref}}, // type 2: () -> (i32, contref)
+			v_v,                                  // type 3: () -> ()
+			{Results: []ValueType{i32}},          // type 4: () -> (i32)
+			{},                                   // type 5: (cont 4)
+		},
+		SubTypes: []SubType{
+			{Kind: CompositeTypeKindFunc, Final: true, RecGroupStart: 0, RecGroupSize: 1},
+			{Kind: CompositeTypeKindCont, FuncType: 0, Final: true, RecGroupStart: 1, RecGroupSize: 1},
+			{Kind: CompositeTypeKindFunc, Final: true, RecGroupStart: 2, RecGroupSize: 1},
+			{Kind: CompositeTypeKindFunc, Final: true, RecGroupStart: 3, RecGroupSize: 1},
+			{Kind: CompositeTypeKindFunc, Final: true, RecGroupStart: 4, RecGroupSize: 1},
+			{Kind: CompositeTypeKindCont, FuncType: 4, Final: true, RecGroupStart: 5, RecGroupSize: 1},
+		},
+		FunctionSection: []Index{3},
+		TagSection:      []Index{0},
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name: "cont.new",
+			body: []byte{
+				OpcodeRefNull, 0,
+				OpcodeStackSwitchingContNew, 1,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "cont.bind",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeRefNull, 1,
+				OpcodeStackSwitchingContBind, 1, 5,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "suspend",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeStackSwitchingSuspend, 0,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "resume",
+			body: []byte{
+				OpcodeBlock, 2,
+				OpcodeI32Const, 1,
+				OpcodeRefNull, 1,
+				OpcodeStackSwitchingResume, 1, 1, ResumeHandlerKindOnLabel, 0, 0,
+				OpcodeDrop,
+				OpcodeUnreachable,
+				OpcodeEnd,
+				OpcodeDrop,
+				OpcodeDrop,
+			},
+		},
+		{
+			name: "cont.new on function type",
+			body: []byte{
+				OpcodeRefNull, 0,
+				OpcodeStackSwitchingContNew, 0,
+				OpcodeDrop,
+			},
+			expectedErr: "cont.new: type 0 is not a continuation type",
+		},
+		{
+			name: "cont.bind with unbindable types",
+			body: []byte{
+				OpcodeRefNull, 5,
+				OpcodeStackSwitchingContBind, 5, 1,
+				OpcodeDrop,
+			},
+			expectedErr: "cont.bind: type mismatch: cannot bind v_i32 to i32_i32",
+		},
+		{
+			name: "suspend with unknown tag",
+			body: []byte{
+				OpcodeStackSwitchingSuspend, 1,
+			},
+			expectedErr: "suspend: unknown tag 1",
+		},
+		{
+			name: "resume with handler type mismatch",
+			body: []byte{
+				OpcodeBlock, 0x40,
+				OpcodeI32Const, 1,
+				OpcodeRefNull, 1,
+				OpcodeStackSwitchingResume, 1, 1, ResumeHandlerKindOnLabel, 0, 0,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: "resume: handler[0]: type mismatch: [i32, contref] != []",
+		},
+		{
+			name: "resume with switch handler",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeRefNull, 1,
+				OpcodeStackSwitchingResume, 1, 1, ResumeHandlerKindOnSwitch, 0,
+				OpcodeDrop,
+			},
+			expectedErr: "resume: unsupported handler[0] kind: 0x1",
+		},
+		{
+			name: "resume_throw",
+			body: []byte{
+				OpcodeRefNull, 1,
+				OpcodeStackSwitchingResumeThrow, 1, 0, 0,
+			},
+			expectedErr: "resume_throw: not supported yet",
+		},
+	}
+
+	const features = api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling |
+		experimental.CoreFeaturesFunctionReferences | experimental.CoreFeaturesStackSwitching
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m.CodeSection = []Code{{Body: append(tc.body, OpcodeEnd)}}
+			err := m.validateFunction(&stacks{}, features, 0, []Index{3}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// The instructions are invalid unless both the stack switching and exception handling are enabled.
+			for _, disabled := range []api.CoreFeatures{experimental.CoreFeaturesStackSwitching, experimental.CoreFeaturesExceptionHandling} {
+				err = m.validateFunction(&stacks{}, features&^disabled, 0, []Index{3}, nil, nil, nil, nil, bytes.NewReader(nil))
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,1234 @@
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// HeapType is the heap type of a reference type as per the GC proposal. A non-negative value is the index of a
+// concrete type in Module.TypeSection, and otherwise it is one of the abstract heap types below, which are encoded
+// as negative s33 values.
+//
+// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md#heap-types
+type HeapType = int64
+
+const (
+	HeapTypeNoCont   HeapType = -0x0b // 0x75
+	HeapTypeNoExn    HeapType = -0x0c // 0x74
+	HeapTypeNoFunc   HeapType = -0x0d // 0x73
+	HeapTypeNoExtern HeapType = -0x0e // 0x72
+	HeapTypeNone     HeapType = -0x0f // 0x71
+	HeapTypeFunc     HeapType = -0x10 // 0x70
+	HeapTypeExtern   HeapType = -0x11 // 0x6f
+	HeapTypeAny      HeapType = -0x12 // 0x6e
+	HeapTypeEq       HeapType = -0x13 // 0x6d
+	HeapTypeI31      HeapType = -0x14 // 0x6c
+	HeapTypeStruct   HeapType = -0x15 // 0x6b
+	HeapTypeArray    HeapType = -0x16 // 0x6a
+	HeapTypeExn      HeapType = -0x17 // 0x69
+	HeapTypeCont     HeapType = -0x18 // 0x68
+)
+
+const (
+	// TypeRecGroupPrefix is the prefix of a recursion group of types in the type section.
+	TypeRecGroupPrefix byte = 0x4e
+	// TypeSubPrefix is the prefix of a subtype which can be further subtyped.
+	TypeSubPrefix byte = 0x50
+	// TypeSubFinalPrefix is the prefix of a final subtype.
+	TypeSubFinalPrefix byte = 0x4f
+	// TypeStructPrefix is the prefix of a struct type.
+	TypeStructPrefix byte = 0x5f
+	// TypeArrayPrefix is the prefix of an array type.
+	TypeArrayPrefix byte = 0x5e
+	// TypeContPrefix is the prefix of a continuation type of experimental.CoreFeaturesStackSwitching.
+	TypeContPrefix byte = 0x5d
+
+	// RefTypePrefixNullable is the prefix of a nullable reference type "ref null ht".
+	RefTypePrefixNullable byte = 0x63
+	// RefTypePrefixNonNullable is the prefix of a non-nullable reference type "ref ht".
+	RefTypePrefixNonNullable byte = 0x64
+)
+
+// heapTypeName returns the text format name of the heap type.
+func heapTypeName(ht HeapType) string {
+	switch ht {
+	case HeapTypeNoCont:
+		return "nocont"
+	case HeapTypeNoExn:
+		return "noexn"
+	case HeapTypeNoFunc:
+		return "nofunc"
+	case HeapTypeNoExtern:
+		return "noextern"
+	case HeapTypeNone:
+		return "none"
+	case HeapTypeFunc:
+		return "func"
+	case HeapTypeExtern:
+		return "extern"
+	case HeapTypeAny:
+		return "any"
+	case HeapTypeEq:
+		return "eq"
+	case HeapTypeI31:
+		return "i31"
+	case HeapTypeStruct:
+		return "struct"
+	case HeapTypeArray:
+		return "array"
+	case HeapTypeExn:
+		return "exn"
+	case HeapTypeCont:
+		return "cont"
+	}
+	return fmt.Sprintf("%d", ht)
+}
+
+// abstractHeapTypeValueType returns the ValueType of the references to the abstract heap type, which is the top type
+// of its hierarchy. This returns false if ht is not an abstract heap type.
+func abstractHeapTypeValueType(ht HeapType) (ValueType, bool) {
+	switch ht {
+	case HeapTypeFunc, HeapTypeNoFunc:
+		return ValueTypeFuncref, true
+	case HeapTypeExtern, HeapTypeNoExtern:
+		return ValueTypeExternref, true
+	case HeapTypeExn, HeapTypeNoExn:
+		return ValueTypeExnref, true
+	case HeapTypeCont, HeapTypeNoCont:
+		return ValueTypeContref, true
+	case HeapTypeAny, HeapTypeEq, HeapTypeI31, HeapTypeStruct, HeapTypeArray, HeapTypeNone:
+		return ValueTypeAnyref, true
+	}
+	return 0, false
+}
+
+// HeapTypeValueType returns the ValueType of the references to the heap type.
+//
+// Reference types are only distinguished by the hierarchy they belong to: ValueTypeFuncref, ValueTypeExternref,
+// ValueTypeExnref, ValueTypeContref or ValueTypeAnyref, regardless of their nullability. The precise heap type and
+// nullability are carried alongside as a HeapRef, which the validation checks on top of the ValueType.
+func (m *Module) HeapTypeValueType(ht HeapType) (ValueType, error) {
+	if ht < 0 {
+		if vt, ok := abstractHeapTypeValueType(ht); ok {
+			return vt, nil
+		}
+		return 0, fmt.Errorf("invalid heap type: %d", ht)
+	}
+	if ht >= int64(len(m.TypeSection)) {
+		return 0, fmt.Errorf("unknown type: %d", ht)
+	}
+	if m.isFunctionType(Index(ht)) {
+		return ValueTypeFuncref, nil
+	} else if m.SubTypes[ht].Kind == CompositeTypeKindCont {
+		return ValueTypeContref, nil
+	}
+	return ValueTypeAnyref, nil
+}
+
+// RequireTypedReferences returns an error unless references to concrete types are enabled, which are introduced by
+// experimental.CoreFeaturesFunctionReferences and are also part of experimental.CoreFeaturesGC.
+func RequireTypedReferences(enabledFeatures api.CoreFeatures) error {
+	if enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) {
+		return nil
+	}
+	return enabledFeatures.RequireEnabled(experimental.CoreFeaturesFunctionReferences)
+}
+
+// DecodeHeapType decodes a heap type encoded as s33, and requires experimental.CoreFeaturesGC unless it is one of
+// the heap types of the reference-types and exception-handling proposals, or a concrete type allowed by
+// RequireTypedReferences. The continuation heap types require experimental.CoreFeaturesStackSwitching instead.
+func DecodeHeapType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (HeapType, uint64, error) {
+	ht, num, err := leb128.DecodeInt33AsInt64(r)
+	if err != nil {
+		return 0, 0, fmt.Errorf("read heap type: %w", err)
+	}
+	if ht < 0 {
+		if _, ok := abstractHeapTypeValueType(ht); !ok {
+			return 0, 0, fmt.Errorf("invalid heap type: %d", ht)
+		}
+	}
+	switch {
+	case ht == HeapTypeFunc, ht == HeapTypeExtern, ht == HeapTypeExn:
+	case ht >= 0:
+		if err = RequireTypedReferences(enabledFeatures); err != nil {
+			return 0, 0, fmt.Errorf("heap type %s invalid as %v", heapTypeName(ht), err)
+		}
+	case ht == HeapTypeCont, ht == HeapTypeNoCont:
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesStackSwitching); err != nil {
+			return 0, 0, fmt.Errorf("heap type %s invalid as %v", heapTypeName(ht), err)
+		}
+	default:
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+			return 0, 0, fmt.Errorf("heap type %s invalid as %v", heapTypeName(ht), err)
+		}
+	}
+	return ht, num, nil
+}
+
+// DecodeValueType decodes a value type including the reference types of the GC proposal, which are either
+// abbreviated as a single byte, or encoded as RefTypePrefixNullable or RefTypePrefixNonNullable followed by a heap
+// type.
+//
+// If the value type is a reference to a concrete type, vt is zero and ht is the index of the type, which is to be
+// resolved with Module.HeapTypeValueType. Otherwise, ht is meaningless.
+func DecodeValueType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (vt ValueType, ht HeapType, num uint64, err error) {
+	vt, ref, num, err := DecodeValueTypeRef(r, enabledFeatures)
+	return vt, ref.HeapType, num, err
+}
+
+// DecodeValueTypeRef is the same as DecodeValueType, except that it also returns the HeapRef of a reference type,
+// which is unknownHeapRef for the other value types.
+func DecodeValueTypeRef(r *bytes.Reader, enabledFeatures api.CoreFeatures) (vt ValueType, ref HeapRef, num uint64, err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, ref, 0, fmt.Errorf("read value type: %w", err)
+	}
+	switch b {
+	case ValueTypeI32, ValueTypeF32, ValueTypeI64, ValueTypeF64, ValueTypeV128:
+		return b, unknownHeapRef, 1, nil
+	case ValueTypeExternref, ValueTypeFuncref, ValueTypeExnref:
+		return b, DefaultHeapRef(b), 1, nil
+	case RefTypePrefixNullable, RefTypePrefixNonNullable:
+		if err = RequireTypedReferences(enabledFeatures); err != nil {
+			return 0, ref, 0, fmt.Errorf("reference type 0x%x invalid as %v", b, err)
+		}
+		ht, num, err := DecodeHeapType(r, enabledFeatures)
+		if err != nil {
+			return 0, ref, 0, err
+		}
+		ref = HeapRef{HeapType: ht, NonNullable: b == RefTypePrefixNonNullable}
+		num++
+		if ht >= 0 {
+			return 0, ref, num, nil
+		}
+		vt, _ = abstractHeapTypeValueType(ht)
+		return vt, ref, num, nil
+	default:
+		// The other abstract heap types can be abbreviated the same way as funcref and externref.
+		ht := int64(b) - 0x80
+		if vt, ok := abstractHeapTypeValueType(ht); ok {
+			feature := experimental.CoreFeaturesGC
+			if vt == ValueTypeContref {
+				feature = experimental.CoreFeaturesStackSwitching
+			}
+			if err = enabledFeatures.RequireEnabled(feature); err != nil {
+				return 0, ref, 0, fmt.Errorf("value type %sref invalid as %v", heapTypeName(ht), err)
+			}
+			return vt, HeapRef{HeapType: ht}, 1, nil
+		}
+		return 0, ref, 0, fmt.Errorf("invalid value type: %d", b)
+	}
+}
+
+// DecodeResolvedValueType is the same as DecodeValueType, except that references to concrete types are resolved
+// with the type section of this module.
+func (m *Module) DecodeResolvedValueType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (ValueType, uint64, error) {
+	vt, _, num, err := m.DecodeResolvedValueTypeRef(r, enabledFeatures)
+	return vt, num, err
+}
+
+// DecodeResolvedValueTypeRef is the same as DecodeResolvedValueType, except that it also returns the HeapRef of a
+// reference type.
+func (m *Module) DecodeResolvedValueTypeRef(r *bytes.Reader, enabledFeatures api.CoreFeatures) (ValueType, HeapRef, uint64, error) {
+	vt, ref, num, err := DecodeValueTypeRef(r, enabledFeatures)
+	if err == nil && vt == 0 {
+		vt, err = m.HeapTypeValueType(ref.HeapType)
+	}
+	return vt, ref, num, err
+}
+
+// HeapRef is the heap type and the nullability of a reference type, which ValueType doesn't distinguish. These are
+// used by the validation to check the subtyping of references as per the function-references and GC proposals.
+//
+// The precise types are optional where they are declared, for example, FunctionType.ParamRefs is nil unless any of
+// the params is a reference type other than the one of DefaultHeapRef.
+type HeapRef struct {
+	// HeapType is the heap type of the reference.
+	HeapType HeapType
+	// NonNullable is true for the reference type "ref ht", and false for "ref null ht".
+	NonNullable bool
+}
+
+// heapTypeUnknown is the heap type of the values whose precise type isn't tracked by the validation, which matches
+// any reference type of the same hierarchy.
+const heapTypeUnknown HeapType = -0x80
+
+// unknownHeapRef is the HeapRef of the values whose precise type isn't tracked, and of the non-reference types.
+var unknownHeapRef = HeapRef{HeapType: heapTypeUnknown}
+
+// DefaultHeapRef returns the HeapRef of the reference type vt when no precise type is declared, which is the
+// nullable reference to the top type of its hierarchy, for example, "ref null func" for ValueTypeFuncref.
+func DefaultHeapRef(vt ValueType) HeapRef {
+	switch vt {
+	case ValueTypeFuncref:
+		return HeapRef{HeapType: HeapTypeFunc}
+	case ValueTypeExternref:
+		return HeapRef{HeapType: HeapTypeExtern}
+	case ValueTypeExnref:
+		return HeapRef{HeapType: HeapTypeExn}
+	case ValueTypeContref:
+		return HeapRef{HeapType: HeapTypeCont}
+	case ValueTypeAnyref:
+		return HeapRef{HeapType: HeapTypeAny}
+	}
+	return unknownHeapRef
+}
+
+// heapRefAt returns the HeapRef of the i-th type of types, where refs are their optional precise types.
+func heapRefAt(types []ValueType, refs []HeapRef, i int) HeapRef {
+	if refs == nil {
+		return DefaultHeapRef(types[i])
+	}
+	return refs[i]
+}
+
+// heapRefOf returns ref, or the DefaultHeapRef of vt if ref is nil.
+func heapRefOf(vt ValueType, ref *HeapRef) HeapRef {
+	if ref == nil {
+		return DefaultHeapRef(vt)
+	}
+	return *ref
+}
+
+// OptionalHeapRefs returns refs, or nil if all of them are the DefaultHeapRef of types, which is how the precise
+// types are stored when they are optional.
+func OptionalHeapRefs(types []ValueType, refs []HeapRef) []HeapRef {
+	for i := range refs {
+		if refs[i] != DefaultHeapRef(types[i]) {
+			return refs
+		}
+	}
+	return nil
+}
+
+// OptionalHeapRef returns &ref, or nil if it is the DefaultHeapRef of vt.
+func OptionalHeapRef(vt ValueType, ref HeapRef) *HeapRef {
+	if ref == DefaultHeapRef(vt) {
+		return nil
+	}
+	return &ref
+}
+
+// asNonNullable returns the non-nullable reference type of the same heap type, e.g. for ref.as_non_null.
+func (r HeapRef) asNonNullable() HeapRef {
+	if r.HeapType != heapTypeUnknown {
+		r.NonNullable = true
+	}
+	return r
+}
+
+// String implements fmt.Stringer.
+func (r HeapRef) String() string {
+	switch {
+	case r.HeapType == heapTypeUnknown:
+		return "ref"
+	case r.NonNullable:
+		return "(ref " + heapTypeName(r.HeapType) + ")"
+	default:
+		return "(ref null " + heapTypeName(r.HeapType) + ")"
+	}
+}
+
+// isHeapRefSubType returns true if the reference type have of this module is a subtype of want of the module other.
+func (m *Module) isHeapRefSubType(have HeapRef, other *Module, want HeapRef) bool {
+	if have.HeapType == heapTypeUnknown || want.HeapType == heapTypeUnknown {
+		return true
+	} else if !have.NonNullable && want.NonNullable {
+		return false
+	}
+	h, w := have.HeapType, want.HeapType
+	switch {
+	case h >= 0 && w >= 0:
+		return m.IsSubType(Index(h), other, Index(w))
+	case h >= 0:
+		return isAbstractHeapSubType(m.abstractHeapType(Index(h)), w)
+	case w >= 0:
+		return h == bottomHeapType(other.abstractHeapType(Index(w)))
+	default:
+		return isAbstractHeapSubType(h, w)
+	}
+}
+
+// isHeapRefEquivalent returns true if the reference types are the subtypes of each other.
+func (m *Module) isHeapRefEquivalent(have HeapRef, other *Module, want HeapRef) bool {
+	return m.isHeapRefSubType(have, other, want) && other.isHeapRefSubType(want, m, have)
+}
+
+// isAbstractHeapSubType returns true if the abstract heap type h is a subtype of w.
+func isAbstractHeapSubType(h, w HeapType) bool {
+	if h == w {
+		return true
+	}
+	switch w {
+	case HeapTypeAny:
+		return h == HeapTypeEq || h == HeapTypeI31 || h == HeapTypeStruct || h == HeapTypeArray || h == HeapTypeNone
+	case HeapTypeEq:
+		return h == HeapTypeI31 || h == HeapTypeStruct || h == HeapTypeArray || h == HeapTypeNone
+	case HeapTypeI31, HeapTypeStruct, HeapTypeArray:
+		return h == HeapTypeNone
+	case HeapTypeFunc, HeapTypeExtern, HeapTypeExn, HeapTypeCont:
+		return h == bottomHeapType(w)
+	}
+	return false
+}
+
+// bottomHeapType returns the bottom type of the hierarchy of the abstract heap type, for example, HeapTypeNoFunc
+// for HeapTypeFunc.
+func bottomHeapType(ht HeapType) HeapType {
+	switch ht {
+	case HeapTypeFunc, HeapTypeNoFunc:
+		return HeapTypeNoFunc
+	case HeapTypeExtern, HeapTypeNoExtern:
+		return HeapTypeNoExtern
+	case HeapTypeExn, HeapTypeNoExn:
+		return HeapTypeNoExn
+	case HeapTypeCont, HeapTypeNoCont:
+		return HeapTypeNoCont
+	}
+	return HeapTypeNone
+}
+
+// abstractHeapType returns the abstract heap type which the concrete type at the index is a subtype of, for
+// example, HeapTypeFunc for a function type.
+func (m *Module) abstractHeapType(typeIndex Index) HeapType {
+	switch m.subType(typeIndex).Kind {
+	case CompositeTypeKindStruct:
+		return HeapTypeStruct
+	case CompositeTypeKindArray:
+		return HeapTypeArray
+	case CompositeTypeKindCont:
+		return HeapTypeCont
+	}
+	return HeapTypeFunc
+}
+
+// isFunctionSubType returns true if the function type ft of this module is a subtype of want of the module other,
+// whose params are contravariant, and results are covariant.
+func (m *Module) isFunctionSubType(ft *FunctionType, other *Module, want *FunctionType) bool {
+	if !ft.EqualsSignature(want.Params, want.Results) {
+		return false
+	}
+	for i := range ft.Params {
+		if !other.isHeapRefSubType(heapRefAt(want.Params, want.ParamRefs, i), m, heapRefAt(ft.Params, ft.ParamRefs, i)) {
+			return false
+		}
+	}
+	for i := range ft.Results {
+		if !m.isHeapRefSubType(heapRefAt(ft.Results, ft.ResultRefs, i), other, heapRefAt(want.Results, want.ResultRefs, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompositeTypeKind is the kind of a type defined in the type section.
+type CompositeTypeKind byte
+
+const (
+	// CompositeTypeKindFunc is a function type, which is the only kind before the GC proposal.
+	CompositeTypeKindFunc CompositeTypeKind = iota
+	// CompositeTypeKindStruct is a struct type.
+	CompositeTypeKindStruct
+	// CompositeTypeKindArray is an array type.
+	CompositeTypeKindArray
+	// CompositeTypeKindCont is a continuation type of experimental.CoreFeaturesStackSwitching.
+	CompositeTypeKindCont
+)
+
+// StorageType is the type of struct fields and array elements, which is either a ValueType, or one of the packed
+// types below.
+type StorageType = byte
+
+const (
+	// StorageTypeI8 is a packed 8-bit integer, which is read as ValueTypeI32.
+	StorageTypeI8 StorageType = 0x78
+	// StorageTypeI16 is a packed 16-bit integer, which is read as ValueTypeI32.
+	StorageTypeI16 StorageType = 0x77
+)
+
+// FieldType is the type of a struct field or the elements of an array.
+type FieldType struct {
+	Type StorageType
+	// Ref is the precise type of a reference Type, or nil if it is the DefaultHeapRef.
+	Ref     *HeapRef
+	Mutable bool
+}
+
+// heapRef returns the precise type of the values read from or written to the field.
+func (f *FieldType) heapRef() HeapRef {
+	return heapRefOf(f.Type, f.Ref)
+}
+
+// ValueType returns the type of the values read from or written to the field.
+func (f *FieldType) ValueType() ValueType {
+	switch f.Type {
+	case StorageTypeI8, StorageTypeI16:
+		return ValueTypeI32
+	default:
+		return f.Type
+	}
+}
+
+// storageTypeName returns the text format name of the storage type.
+func storageTypeName(t StorageType) string {
+	switch t {
+	case StorageTypeI8:
+		return "i8"
+	case StorageTypeI16:
+		return "i16"
+	default:
+		return ValueTypeName(t)
+	}
+}
+
+// IsPacked returns true if the field is a packed integer.
+func (f *FieldType) IsPacked() bool {
+	return f.Type == StorageTypeI8 || f.Type == StorageTypeI16
+}
+
+// fieldTypeName returns the text format name of the type of the field.
+func fieldTypeName(f *FieldType) string {
+	name := storageTypeName(f.Type)
+	if isReferenceValueType(f.Type) {
+		name = f.heapRef().String()
+	}
+	if f.Mutable {
+		return "(mut " + name + ")"
+	}
+	return name
+}
+
+// SubType is a type defined in the type section as per the GC proposal. Each type in Module.TypeSection has the
+// SubType at the same index in Module.SubTypes, which are only set when the module uses the GC proposal.
+//
+// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md#type-definitions
+type SubType struct {
+	// Kind is the kind of this type. The params and results of a function type are at the same index in
+	// Module.TypeSection, whereas that entry is empty for the other kinds.
+	Kind CompositeTypeKind
+	// Fields are the fields of a struct type, or the element type of an array type as the only field.
+	Fields []FieldType
+	// FuncType is the index of the function type of a continuation type.
+	FuncType Index
+	// Final is true if this type cannot have subtypes.
+	Final bool
+	// HasSuperType is true if this type declares SuperType as its supertype.
+	HasSuperType bool
+	// SuperType is the index of the supertype, which is defined before this type.
+	SuperType Index
+	// RecGroupStart is the index of the first type of the recursion group which defines this type, and RecGroupSize
+	// is the number of types in it.
+	RecGroupStart, RecGroupSize Index
+
+	// fieldOffsets are the positions of Fields in the uint64 values of a struct, where v128 fields take two of them.
+	fieldOffsets []int
+	// sizeInUint64 is the number of uint64 values of a struct, or an element of an array.
+	sizeInUint64 int
+}
+
+// requireDefaultable returns an error unless all the fields have the default values, which the references of
+// non-nullable types don't have. name is the instruction which requires them.
+func (t *SubType) requireDefaultable(name string) error {
+	for i := range t.Fields {
+		if ref := t.Fields[i].Ref; ref != nil && ref.NonNullable {
+			return fmt.Errorf("%s on the type with non-defaultable field %d", name, i)
+		}
+	}
+	return nil
+}
+
+// FieldOffset returns the position of the field in the uint64 values of a struct.
+func (t *SubType) FieldOffset(field Index) int {
+	return t.fieldOffsets[field]
+}
+
+// SizeInUint64 returns the number of uint64 values of a struct, or an element of an array.
+func (t *SubType) SizeInUint64() int {
+	return t.sizeInUint64
+}
+
+func (t *SubType) cacheFieldOffsets() {
+	if t.fieldOffsets != nil || t.Kind == CompositeTypeKindFunc || t.Kind == CompositeTypeKindCont {
+		return
+	}
+	t.fieldOffsets = make([]int, len(t.Fields))
+	t.sizeInUint64 = 0
+	for i := range t.Fields {
+		t.fieldOffsets[i] = t.sizeInUint64
+		t.sizeInUint64++
+		if t.Fields[i].Type == ValueTypeV128 {
+			t.sizeInUint64++
+		}
+	}
+}
+
+// isFunctionType returns true if the type at the index is a function type.
+func (m *Module) isFunctionType(typeIndex Index) bool {
+	return m.SubTypes == nil || m.SubTypes[typeIndex].Kind == CompositeTypeKindFunc
+}
+
+// compositeType returns the type at the index, or an error if it is not of the given kind.
+func (m *Module) compositeType(typeIndex Index, kind CompositeTypeKind) (*SubType, error) {
+	if typeIndex >= uint32(len(m.TypeSection)) {
+		return nil, fmt.Errorf("unknown type %d", typeIndex)
+	}
+	if m.SubTypes == nil || m.SubTypes[typeIndex].Kind != kind {
+		switch kind {
+		case CompositeTypeKindStruct:
+			return nil, fmt.Errorf("type %d is not a struct type", typeIndex)
+		case CompositeTypeKindArray:
+			return nil, fmt.Errorf("type %d is not an array type", typeIndex)
+		case CompositeTypeKindCont:
+			return nil, fmt.Errorf("type %d is not a continuation type", typeIndex)
+		}
+		return nil, fmt.Errorf("type %d is not a function type", typeIndex)
+	}
+	return &m.SubTypes[typeIndex], nil
+}
+
+// usesAnyref returns true if any of the imports, globals, tables, element segments or locals is of ValueTypeAnyref.
+func (m *Module) usesAnyref() bool {
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		if (imp.Type == ExternTypeGlobal && imp.DescGlobal.ValType == ValueTypeAnyref) ||
+			(imp.Type == ExternTypeTable && imp.DescTable.Type == ValueTypeAnyref) {
+			return true
+		}
+	}
+	for i := range m.GlobalSection {
+		if m.GlobalSection[i].Type.ValType == ValueTypeAnyref {
+			return true
+		}
+	}
+	for i := range m.TableSection {
+		if m.TableSection[i].Type == ValueTypeAnyref {
+			return true
+		}
+	}
+	for i := range m.ElementSection {
+		if m.ElementSection[i].Type == ValueTypeAnyref {
+			return true
+		}
+	}
+	for i := range m.CodeSection {
+		if bytes.IndexByte(m.CodeSection[i].LocalTypes, ValueTypeAnyref) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ContinuationType returns the function type of the continuation type at the index, which must be valid.
+func (m *Module) ContinuationType(typeIndex Index) *FunctionType {
+	return &m.TypeSection[m.SubTypes[typeIndex].FuncType]
+}
+
+// usesGCTypes returns true if the type section uses the types of experimental.CoreFeaturesGC, as opposed to only
+// function and continuation types.
+func (m *Module) usesGCTypes() bool {
+	for i := range m.SubTypes {
+		t := &m.SubTypes[i]
+		if t.Kind == CompositeTypeKindStruct || t.Kind == CompositeTypeKindArray ||
+			!t.Final || t.HasSuperType || t.RecGroupSize != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSubTypes validates the supertypes declared in the type section, and the function types of the
+// continuation types.
+func (m *Module) validateSubTypes(enabledFeatures api.CoreFeatures) error {
+	if m.SubTypes == nil {
+		return nil
+	}
+	if m.usesGCTypes() {
+		if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+			return fmt.Errorf("type section invalid as %v", err)
+		}
+	}
+	if len(m.SubTypes) != len(m.TypeSection) {
+		return fmt.Errorf("subtypes (%d) != types (%d)", len(m.SubTypes), len(m.TypeSection))
+	}
+	for i := range m.SubTypes {
+		t := &m.SubTypes[i]
+		t.cacheFieldOffsets()
+		if t.Kind == CompositeTypeKindCont {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesStackSwitching); err != nil {
+				return fmt.Errorf("invalid type[%d]: continuation type invalid as %v", i, err)
+			}
+			if t.FuncType >= Index(len(m.TypeSection)) || !m.isFunctionType(t.FuncType) {
+				return fmt.Errorf("invalid type[%d]: type %d is not a function type", i, t.FuncType)
+			}
+		}
+		if !t.HasSuperType {
+			continue
+		}
+		if t.SuperType >= Index(i) {
+			return fmt.Errorf("invalid type[%d]: supertype %d must be defined before", i, t.SuperType)
+		}
+		super := &m.SubTypes[t.SuperType]
+		if super.Final {
+			return fmt.Errorf("invalid type[%d]: supertype %d is final", i, t.SuperType)
+		}
+		if !m.matchesSuperType(Index(i), t.SuperType) {
+			return fmt.Errorf("invalid type[%d]: type mismatch with supertype %d", i, t.SuperType)
+		}
+	}
+	return nil
+}
+
+// matchesSuperType returns true if the type at the index can be a subtype of super, which requires that the
+// fields of super are the prefix of those of the type, and each of them is a subtype of the one in super.
+func (m *Module) matchesSuperType(typeIndex, super Index) bool {
+	t, s := &m.SubTypes[typeIndex], &m.SubTypes[super]
+	if t.Kind != s.Kind {
+		return false
+	}
+	switch t.Kind {
+	case CompositeTypeKindFunc:
+		return m.isFunctionSubType(&m.TypeSection[typeIndex], m, &m.TypeSection[super])
+	case CompositeTypeKindArray:
+		return m.isFieldSubType(&t.Fields[0], &s.Fields[0])
+	case CompositeTypeKindCont:
+		return m.IsSubType(t.FuncType, m, s.FuncType)
+	default:
+		if len(t.Fields) < len(s.Fields) {
+			return false
+		}
+		for i := range s.Fields {
+			if !m.isFieldSubType(&t.Fields[i], &s.Fields[i]) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// isFieldSubType returns true if the field f can be a subtype of the field s of a supertype. Immutable fields are
+// covariant, whereas mutable fields must be of the equivalent types.
+func (m *Module) isFieldSubType(f, s *FieldType) bool {
+	if f.Mutable != s.Mutable || f.Type != s.Type {
+		return false
+	} else if f.Mutable {
+		return m.isHeapRefEquivalent(f.heapRef(), m, s.heapRef())
+	}
+	return m.isHeapRefSubType(f.heapRef(), m, s.heapRef())
+}
+
+// IsSubType returns true if the type at typeIndex of this module is the same as, or a declared subtype of the type
+// at target in the module other. The types in different modules are the same if their recursion groups are
+// structurally equivalent as per the iso-recursive type system of the GC proposal.
+func (m *Module) IsSubType(typeIndex Index, other *Module, target Index) bool {
+	for {
+		if m.typesEquivalent(typeIndex, other, target) {
+			return true
+		}
+		t := m.subType(typeIndex)
+		if !t.HasSuperType {
+			return false
+		}
+		typeIndex = t.SuperType
+	}
+}
+
+// IsFunctionOfSubType returns true if the type of the function at funcIdx of this module is a subtype of the type at
+// target of other, which call_indirect, call_ref and casts check at runtime.
+func (m *Module) IsFunctionOfSubType(funcIdx Index, other *Module, target Index) bool {
+	typeIndex, ok := m.typeIndexOfFunction(funcIdx)
+	return ok && m.IsSubType(typeIndex, other, target)
+}
+
+// typesEquivalent returns true if the type at typeIndex of this module is equivalent to the one at target of other.
+func (m *Module) typesEquivalent(typeIndex Index, other *Module, target Index) bool {
+	if m == other && typeIndex == target {
+		return true
+	}
+	t, o := m.subType(typeIndex), other.subType(target)
+	if t.RecGroupSize != o.RecGroupSize || typeIndex-t.RecGroupStart != target-o.RecGroupStart {
+		return false
+	}
+	for i := Index(0); i < t.RecGroupSize; i++ {
+		if !m.subTypeEquivalent(t.RecGroupStart+i, t.RecGroupStart, other, o.RecGroupStart+i, o.RecGroupStart) {
+			return false
+		}
+	}
+	return true
+}
+
+// subTypeEquivalent compares the types in the recursion groups starting at groupStart and otherGroupStart.
+func (m *Module) subTypeEquivalent(typeIndex, groupStart Index, other *Module, target, otherGroupStart Index) bool {
+	t, o := m.subType(typeIndex), other.subType(target)
+	if t.Kind != o.Kind || t.Final != o.Final || t.HasSuperType != o.HasSuperType || len(t.Fields) != len(o.Fields) {
+		return false
+	}
+	for i := range t.Fields {
+		f, of := &t.Fields[i], &o.Fields[i]
+		if f.Type != of.Type || f.Mutable != of.Mutable ||
+			!m.heapRefEquivalent(f.heapRef(), groupStart, other, of.heapRef(), otherGroupStart) {
+			return false
+		}
+	}
+	if t.Kind == CompositeTypeKindFunc {
+		if !m.functionTypeEquivalent(&m.TypeSection[typeIndex], groupStart, other, &other.TypeSection[target], otherGroupStart) {
+			return false
+		}
+	} else if t.Kind == CompositeTypeKindCont {
+		if !m.heapRefEquivalent(HeapRef{HeapType: HeapType(t.FuncType)}, groupStart, other,
+			HeapRef{HeapType: HeapType(o.FuncType)}, otherGroupStart) {
+			return false
+		}
+	}
+	if !t.HasSuperType {
+		return true
+	}
+	// Supertypes in the same group are compared by their relative positions, and the others by their equivalence.
+	tInGroup, oInGroup := t.SuperType >= groupStart, o.SuperType >= otherGroupStart
+	if tInGroup || oInGroup {
+		return tInGroup && oInGroup && t.SuperType-groupStart == o.SuperType-otherGroupStart
+	}
+	return m.typesEquivalent(t.SuperType, other, o.SuperType)
+}
+
+// functionTypeEquivalent compares the function types in the recursion groups starting at groupStart and
+// otherGroupStart, including the precise types of their references.
+func (m *Module) functionTypeEquivalent(ft *FunctionType, groupStart Index, other *Module, ot *FunctionType, otherGroupStart Index) bool {
+	if !ft.EqualsSignature(ot.Params, ot.Results) {
+		return false
+	}
+	for i := range ft.Params {
+		if !m.heapRefEquivalent(heapRefAt(ft.Params, ft.ParamRefs, i), groupStart, other,
+			heapRefAt(ot.Params, ot.ParamRefs, i), otherGroupStart) {
+			return false
+		}
+	}
+	for i := range ft.Results {
+		if !m.heapRefEquivalent(heapRefAt(ft.Results, ft.ResultRefs, i), groupStart, other,
+			heapRefAt(ot.Results, ot.ResultRefs, i), otherGroupStart) {
+			return false
+		}
+	}
+	return true
+}
+
+// heapRefEquivalent compares the reference types used in the recursion groups starting at groupStart and
+// otherGroupStart, where the concrete types in the groups are compared by their relative positions.
+func (m *Module) heapRefEquivalent(r HeapRef, groupStart Index, other *Module, o HeapRef, otherGroupStart Index) bool {
+	if r.NonNullable != o.NonNullable {
+		return false
+	} else if r.HeapType < 0 || o.HeapType < 0 {
+		return r.HeapType == o.HeapType
+	}
+	h, w := Index(r.HeapType), Index(o.HeapType)
+	hInGroup, wInGroup := h >= groupStart, w >= otherGroupStart
+	if hInGroup || wInGroup {
+		return hInGroup && wInGroup && h-groupStart == w-otherGroupStart
+	}
+	return m.typesEquivalent(h, other, w)
+}
+
+// subType returns the definition of the type at the index, which is a final function type in its own recursion
+// group if the module doesn't use the encoding of the GC proposal.
+func (m *Module) subType(typeIndex Index) *SubType {
+	if m.SubTypes != nil {
+		return &m.SubTypes[typeIndex]
+	}
+	return &SubType{RecGroupStart: typeIndex, RecGroupSize: 1, Final: true}
+}
+
+// constExpressionHeapRef returns the precise type of the result of the constant expression, where globals are the
+// types of the globals it can refer to.
+func (m *Module) constExpressionHeapRef(globals []GlobalType, expr *ConstantExpression) HeapRef {
+	switch expr.Opcode {
+	case OpcodeGlobalGet:
+		if id, _, err := leb128.LoadUint32(expr.Data); err == nil && id < uint32(len(globals)) {
+			return heapRefOf(globals[id].ValType, globals[id].Ref)
+		}
+	case OpcodeRefNull:
+		if len(expr.Data) > 1 {
+			// The heap type follows the normalized type, see decodeConstantExpression.
+			if ht, _, err := leb128.LoadInt64(expr.Data[1:]); err == nil {
+				return HeapRef{HeapType: ht}
+			}
+		} else if len(expr.Data) == 1 {
+			return DefaultHeapRef(expr.Data[0])
+		}
+	case OpcodeRefFunc:
+		if index, _, err := leb128.LoadUint32(expr.Data); err == nil {
+			return m.funcRefHeapRef(index)
+		}
+	}
+	return unknownHeapRef
+}
+
+// funcRefHeapRef returns the precise type of the reference to the function at the index, which is the one to its
+// type unless unknown.
+func (m *Module) funcRefHeapRef(index Index) HeapRef {
+	if typeIndex, ok := m.typeIndexOfFunction(index); ok {
+		return HeapRef{HeapType: HeapType(typeIndex), NonNullable: true}
+	}
+	return HeapRef{HeapType: HeapTypeFunc, NonNullable: true}
+}
+
+// validateConstExpressionRef returns an error unless the precise type of the result of the constant expression is
+// a subtype of want. The other types of the expression are validated by validateConstExpression.
+func (m *Module) validateConstExpressionRef(globals []GlobalType, expr *ConstantExpression, want HeapRef) error {
+	if have := m.constExpressionHeapRef(globals, expr); !m.isHeapRefSubType(have, m, want) {
+		return fmt.Errorf("const expression type mismatch expected %s but got %s", want, have)
+	}
+	return nil
+}
+
+// gcConstInstruction is an instruction of the constant expression whose Opcode is OpcodeGCPrefix.
+type gcConstInstruction struct {
+	// op is the opcode, which is OpcodeVecPrefix for v128.const.
+	op Opcode
+	// gcOp is the opcode following OpcodeGCPrefix.
+	gcOp OpcodeGC
+	// imm is the signed value of i32.const and i64.const, the heap type of ref.null, the index of global.get and
+	// ref.func, or the type index of the GC instructions.
+	imm int64
+	// n is the number of the elements of array.new_fixed.
+	n uint32
+	// data is the immediate of f32.const, f64.const and v128.const.
+	data []byte
+}
+
+// gcInstructions returns the instructions of the constant expression whose Opcode is OpcodeGCPrefix, which Data
+// holds as encoded in the binary format.
+func (e *ConstantExpression) gcInstructions() ([]gcConstInstruction, error) {
+	var ret []gcConstInstruction
+	r := bytes.NewReader(e.Data)
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+		in := gcConstInstruction{op: op}
+		var err error
+		switch op {
+		case OpcodeI32Const:
+			var v int32
+			v, _, err = leb128.DecodeInt32(r)
+			in.imm = int64(v)
+		case OpcodeI64Const:
+			in.imm, _, err = leb128.DecodeInt64(r)
+		case OpcodeF32Const, OpcodeF64Const, OpcodeVecPrefix:
+			size := 4
+			if op == OpcodeF64Const {
+				size = 8
+			} else if op == OpcodeVecPrefix {
+				// Skip OpcodeVecV128Const, which is the only vector instruction of the constant expressions.
+				_, _ = r.ReadByte()
+				size = 16
+			}
+			in.data = make([]byte, size)
+			_, err = io.ReadFull(r, in.data)
+		case OpcodeGlobalGet, OpcodeRefFunc:
+			var v uint32
+			v, _, err = leb128.DecodeUint32(r)
+			in.imm = int64(v)
+		case OpcodeRefNull:
+			in.imm, _, err = leb128.DecodeInt33AsInt64(r)
+		case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		case OpcodeGCPrefix:
+			var gcOp, v uint32
+			if gcOp, _, err = leb128.DecodeUint32(r); err != nil {
+				break
+			}
+			in.gcOp = OpcodeGC(gcOp)
+			switch in.gcOp {
+			case OpcodeGCStructNew, OpcodeGCStructNewDefault, OpcodeGCArrayNew, OpcodeGCArrayNewDefault:
+				v, _, err = leb128.DecodeUint32(r)
+				in.imm = int64(v)
+			case OpcodeGCArrayNewFixed:
+				if v, _, err = leb128.DecodeUint32(r); err == nil {
+					in.imm = int64(v)
+					in.n, _, err = leb128.DecodeUint32(r)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid opcode for const expression: 0x%x", op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read immediate for const expression: %w", err)
+		}
+		ret = append(ret, in)
+	}
+	return ret, nil
+}
+
+// declareFunctionIndexes adds the indexes of the functions referred by ref.func in the constant expression whose
+// Opcode is OpcodeGCPrefix to declared.
+func (e *ConstantExpression) declareFunctionIndexes(declared map[Index]struct{}) error {
+	instructions, err := e.gcInstructions()
+	if err != nil {
+		return err
+	}
+	for i := range instructions {
+		if in := &instructions[i]; in.op == OpcodeRefFunc {
+			declared[Index(in.imm)] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// validateGCConstExpression returns the type of the constant expression whose Opcode is OpcodeGCPrefix, where
+// globals are the types of the globals it can refer to.
+func (m *Module) validateGCConstExpression(globals []GlobalType, numFuncs uint32, expr *ConstantExpression) (ValueType, HeapRef, error) {
+	instructions, err := expr.gcInstructions()
+	if err != nil {
+		return 0, HeapRef{}, err
+	}
+
+	s := &valueTypeStack{m: m}
+	popN := func(name string, n uint32, vt ValueType, ref HeapRef) error {
+		for i := uint32(0); i < n; i++ {
+			if err := s.popAndVerifyRef(vt, ref); err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+			}
+		}
+		return nil
+	}
+	for i := range instructions {
+		in := &instructions[i]
+		switch in.op {
+		case OpcodeI32Const:
+			s.push(ValueTypeI32)
+		case OpcodeI64Const:
+			s.push(ValueTypeI64)
+		case OpcodeF32Const:
+			s.push(ValueTypeF32)
+		case OpcodeF64Const:
+			s.push(ValueTypeF64)
+		case OpcodeVecPrefix:
+			s.push(ValueTypeV128)
+		case OpcodeGlobalGet:
+			if uint64(len(globals)) <= uint64(in.imm) {
+				return 0, HeapRef{}, fmt.Errorf("global index out of range")
+			}
+			g := &globals[in.imm]
+			if g.Mutable {
+				return 0, HeapRef{}, fmt.Errorf("constant expression required: global %d is mutable", in.imm)
+			}
+			s.pushRef(g.ValType, heapRefOf(g.ValType, g.Ref))
+		case OpcodeRefNull:
+			vt, err := m.HeapTypeValueType(in.imm)
+			if err != nil {
+				return 0, HeapRef{}, fmt.Errorf("invalid type for ref.null: %w", err)
+			}
+			s.pushRef(vt, HeapRef{HeapType: in.imm})
+		case OpcodeRefFunc:
+			if uint64(in.imm) >= uint64(numFuncs) {
+				return 0, HeapRef{}, fmt.Errorf("ref.func index out of range [%d] with length %d", in.imm, numFuncs-1)
+			}
+			s.pushRef(ValueTypeFuncref, m.funcRefHeapRef(Index(in.imm)))
+		case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+			vt := ValueTypeI64
+			if in.op == OpcodeI32Add || in.op == OpcodeI32Sub || in.op == OpcodeI32Mul {
+				vt = ValueTypeI32
+			}
+			if err := popN(InstructionName(in.op), 2, vt, unknownHeapRef); err != nil {
+				return 0, HeapRef{}, err
+			}
+			s.push(vt)
+		case OpcodeGCPrefix:
+			name := GCInstructionName(in.gcOp)
+			switch in.gcOp {
+			case OpcodeGCStructNew, OpcodeGCStructNewDefault:
+				st, err := m.compositeType(Index(in.imm), CompositeTypeKindStruct)
+				if err != nil {
+					return 0, HeapRef{}, fmt.Errorf("invalid type for %s: %v", name, err)
+				}
+				if in.gcOp == OpcodeGCStructNewDefault {
+					if err := st.requireDefaultable(name); err != nil {
+						return 0, HeapRef{}, err
+					}
+				} else {
+					for j := len(st.Fields) - 1; j >= 0; j-- {
+						if err := popN(name, 1, st.Fields[j].ValueType(), st.Fields[j].heapRef()); err != nil {
+							return 0, HeapRef{}, err
+						}
+					}
+				}
+			case OpcodeGCArrayNew, OpcodeGCArrayNewDefault, OpcodeGCArrayNewFixed:
+				st, err := m.compositeType(Index(in.imm), CompositeTypeKindArray)
+				if err != nil {
+					return 0, HeapRef{}, fmt.Errorf("invalid type for %s: %v", name, err)
+				}
+				field := &st.Fields[0]
+				switch in.gcOp {
+				case OpcodeGCArrayNew:
+					err = popN(name, 1, ValueTypeI32, unknownHeapRef)
+					if err == nil {
+						err = popN(name, 1, field.ValueType(), field.heapRef())
+					}
+				case OpcodeGCArrayNewDefault:
+					if err = st.requireDefaultable(name); err == nil {
+						err = popN(name, 1, ValueTypeI32, unknownHeapRef)
+					}
+				default:
+					err = popN(name, in.n, field.ValueType(), field.heapRef())
+				}
+				if err != nil {
+					return 0, HeapRef{}, err
+				}
+			case OpcodeGCRefI31:
+				if err := popN(name, 1, ValueTypeI32, unknownHeapRef); err != nil {
+					return 0, HeapRef{}, err
+				}
+				s.pushRef(ValueTypeAnyref, HeapRef{HeapType: HeapTypeI31, NonNullable: true})
+				continue
+			case OpcodeGCAnyConvertExtern, OpcodeGCExternConvertAny:
+				from, to := ValueTypeExternref, ValueTypeAnyref
+				if in.gcOp == OpcodeGCExternConvertAny {
+					from, to = to, from
+				}
+				vt, ref, err := s.popRef()
+				if err == nil && vt != from {
+					err = fmt.Errorf("type mismatch: expected %s, but was %s", ValueTypeName(from), ValueTypeName(vt))
+				}
+				if err != nil {
+					return 0, HeapRef{}, fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+				}
+				converted := DefaultHeapRef(to)
+				converted.NonNullable = ref.NonNullable
+				s.pushRef(to, converted)
+				continue
+			default:
+				return 0, HeapRef{}, fmt.Errorf("invalid opcode for const expression: %s", name)
+			}
+			s.pushRef(ValueTypeAnyref, HeapRef{HeapType: in.imm, NonNullable: true})
+		}
+	}
+	if len(s.stack) != 1 {
+		return 0, HeapRef{}, fmt.Errorf("const expression must produce exactly one value but produced %d", len(s.stack))
+	}
+	return s.stack[0], s.refs[0], nil
+}
+
+// validateTypedConstExpression validates the constant expression whose result must be of the type vt, and a subtype
+// of want, where globals are the types of the globals it can refer to.
+func (m *Module) validateTypedConstExpression(globals []GlobalType, numFuncs uint32, expr *ConstantExpression, vt ValueType, want HeapRef) error {
+	if expr.Opcode != OpcodeGCPrefix {
+		if err := validateConstExpression(globals, numFuncs, expr, vt); err != nil {
+			return err
+		}
+		return m.validateConstExpressionRef(globals, expr, want)
+	}
+
+	haveType, have, err := m.validateGCConstExpression(globals, numFuncs, expr)
+	if err != nil {
+		return err
+	} else if haveType != vt {
+		return fmt.Errorf("const expression type mismatch expected %s but got %s", ValueTypeName(vt), ValueTypeName(haveType))
+	} else if !m.isHeapRefSubType(have, m, want) {
+		return fmt.Errorf("const expression type mismatch expected %s but got %s", want, have)
+	}
+	return nil
+}
+
+// validateTableInits validates the initializers of the tables in the table section, which are required for the
+// tables of non-nullable reference types.
+func (m *Module) validateTableInits(globals []GlobalType, numFuncs uint32) error {
+	importedGlobals := globals[:m.ImportGlobalCount]
+	for i := range m.TableSection {
+		t := &m.TableSection[i]
+		ref := heapRefOf(t.Type, t.Ref)
+		if t.Init == nil {
+			if ref.NonNullable {
+				return fmt.Errorf("%s[%d]: table of non-nullable type %s must have an initializer",
+					SectionIDName(SectionIDTable), i, ref)
+			}
+			continue
+		}
+		if err := m.validateTypedConstExpression(importedGlobals, numFuncs, t.Init, t.Type, ref); err != nil {
+			return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDTable), i, err)
+		}
+	}
+	return nil
+}
+
+// validateElementInits validates the types of the items of the element segments referring to globals, and the
+// constant expressions allocating objects, where globals are the types of all the globals.
+func (m *Module) validateElementInits(globals []GlobalType, numFuncs uint32) error {
+	for i := range m.ElementSection {
+		elem := &m.ElementSection[i]
+		ref := heapRefOf(elem.Type, elem.Ref)
+		for ei, init := range elem.Init {
+			index, ok := unwrapElementInitGlobalReference(init)
+			if !ok || init == ElementInitNullReference || index >= Index(len(globals)) {
+				// The out of range indexes are reported by validateTable.
+				continue
+			}
+			if g := &globals[index]; g.ValType != elem.Type || !m.isHeapRefSubType(heapRefOf(g.ValType, g.Ref), m, ref) {
+				return fmt.Errorf("%s[%d].init[%d] global index %d type mismatch: want %s", SectionIDName(SectionIDElement), i, ei, index, ref)
+			}
+		}
+		for ei := range elem.InitExpressions {
+			if err := m.validateTypedConstExpression(globals, numFuncs, &elem.InitExpressions[ei], elem.Type, ref); err != nil {
+				return fmt.Errorf("%s[%d].init: %w", SectionIDName(SectionIDElement), i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// globalTypeAt returns the type of the global at the given index of the global index space, or nil if it doesn't
+// exist.
+func (m *Module) globalTypeAt(idx Index) *GlobalType {
+	if idx < m.ImportGlobalCount {
+		for i := range m.ImportSection {
+			if imp := &m.ImportSection[i]; imp.Type == ExternTypeGlobal {
+				if idx == 0 {
+					return &imp.DescGlobal
+				}
+				idx--
+			}
+		}
+		return nil
+	}
+	if idx -= m.ImportGlobalCount; idx < Index(len(m.GlobalSection)) {
+		return &m.GlobalSection[idx].Type
+	}
+	return nil
+}
+
+// tableAt returns the table at the given index of the table index space, or nil if it doesn't exist.
+func (m *Module) tableAt(idx Index) *Table {
+	if idx < m.ImportTableCount {
+		for i := range m.ImportSection {
+			if imp := &m.ImportSection[i]; imp.Type == ExternTypeTable {
+				if idx == 0 {
+					return &imp.DescTable
+				}
+				idx--
+			}
+		}
+		return nil
+	}
+	if idx -= m.ImportTableCount; idx < Index(len(m.TableSection)) {
+		return &m.TableSection[idx]
+	}
+	return nil
+}
+
+// sourceGlobalTypeAt returns the type of the global at the index declared in the Source of the module instance, or
+// nil if the instance has no Source.
+func (m *ModuleInstance) sourceGlobalTypeAt(idx Index) *GlobalType {
+	if m.Source == nil {
+		return nil
+	}
+	return m.Source.globalTypeAt(idx)
+}
+
+// sourceTableAt returns the table at the index declared in the Source of the module instance, or nil if the
+// instance has no Source.
+func (m *ModuleInstance) sourceTableAt(idx Index) *Table {
+	if m.Source == nil {
+		return nil
+	}
+	return m.Source.tableAt(idx)
+}
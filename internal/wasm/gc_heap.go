@@ -0,0 +1,269 @@
+package wasm
+
+import (
+	"sync"
+)
+
+// GCObjectKind is the kind of a GCObject.
+type GCObjectKind byte
+
+const (
+	// GCObjectKindStruct is a struct whose type is defined by GCObject.Module.
+	GCObjectKindStruct GCObjectKind = iota
+	// GCObjectKindArray is an array whose type is defined by GCObject.Module.
+	GCObjectKindArray
+	// GCObjectKindExtern is an externref from the host converted with any.convert_extern.
+	GCObjectKindExtern
+)
+
+// GCObject is an object allocated by the instructions of experimental.CoreFeaturesGC, which lives on the Go heap.
+type GCObject struct {
+	Kind GCObjectKind
+	// Module defines the type of this object at TypeIndex, which is nil for GCObjectKindExtern.
+	Module    *Module
+	TypeIndex Index
+	// Values are the fields of a struct, or the elements of an array, laid out as per SubType.FieldOffset and
+	// SubType.SizeInUint64. Packed fields hold the zero-extended integer. For GCObjectKindExtern, this holds the
+	// externref as the only value.
+	Values []uint64
+
+	// marked is used during GCHeap.collect.
+	marked bool
+}
+
+// Type returns the type of this object.
+func (o *GCObject) Type() *SubType {
+	return &o.Module.SubTypes[o.TypeIndex]
+}
+
+// ArrayLen returns the number of the elements of an array.
+func (o *GCObject) ArrayLen() uint32 {
+	return uint32(len(o.Values) / o.Type().sizeInUint64)
+}
+
+// The references to the GC objects are encoded as uint64 as follows:
+//   - Zero is the null reference.
+//   - An i31ref has the lowest bit set, and holds the value in the bits 1 to 31.
+//   - Otherwise, it is a handle to the object in GCHeap, which is even.
+//
+// When converted with extern.convert_any, a non-null reference has the highest bit set, so that it can be
+// distinguished from the externref values passed from the host, which are pointers in practice.
+const (
+	gcRefI31Flag      uint64 = 1
+	GCRefExternalized uint64 = 1 << 63
+)
+
+// GCRefI31 returns the i31ref of the lower 31 bits of v.
+func GCRefI31(v uint32) uint64 {
+	return uint64(v&0x7fffffff)<<1 | gcRefI31Flag
+}
+
+// GCRefIsI31 returns true if the reference is an i31ref.
+func GCRefIsI31(ref uint64) bool {
+	return ref&gcRefI31Flag != 0
+}
+
+// GCRefI31Value returns the zero-extended value of the i31ref.
+func GCRefI31Value(ref uint64) uint32 {
+	return uint32(ref>>1) & 0x7fffffff
+}
+
+// GCHeap holds the objects allocated by all the modules in a Store, which is only created when
+// experimental.CoreFeaturesGC is enabled.
+//
+// The objects are collected by a conservative mark and sweep, which treats any value on the stack of the call as a
+// potential reference. As this is only triggered on allocation when no other call is in progress in the Store, the
+// roots are the stack of the current call plus the globals, tables and element segments of all the modules.
+// Therefore, references held by the host are only valid while they are reachable from one of these roots.
+type GCHeap struct {
+	s *Store
+
+	mux     sync.Mutex
+	objects []*GCObject
+	// free holds the indexes of the slots in objects which can be reused.
+	free []uint32
+	// live is the number of the objects in the heap, and threshold is the one to trigger the next collection.
+	live, threshold int
+	// activeCalls is the number of calls in progress in the store, which is tracked by engines.
+	activeCalls int
+}
+
+// GCObjectMaxSizeInUint64 is the maximum number of uint64 values of an object, which limits the length of arrays.
+const GCObjectMaxSizeInUint64 = 1 << 27
+
+// gcHeapMinThreshold is the minimum number of objects to trigger a collection.
+const gcHeapMinThreshold = 1 << 14
+
+func newGCHeap(s *Store) *GCHeap {
+	return &GCHeap{s: s, threshold: gcHeapMinThreshold}
+}
+
+// GCHeap returns the heap of the Store on which this module is instantiated, or nil if
+// experimental.CoreFeaturesGC is not enabled.
+func (m *ModuleInstance) GCHeap() *GCHeap {
+	if m.s == nil {
+		return nil
+	}
+	return m.s.gcHeap
+}
+
+// EnterCall must be called by engines when a call starts, and paired with ExitCall.
+func (h *GCHeap) EnterCall() {
+	h.mux.Lock()
+	h.activeCalls++
+	h.mux.Unlock()
+}
+
+// ExitCall must be called by engines when a call started with EnterCall ends.
+func (h *GCHeap) ExitCall() {
+	h.mux.Lock()
+	h.activeCalls--
+	h.mux.Unlock()
+}
+
+// Alloc adds the object to the heap and returns the reference to it.
+//
+// If the heap has grown enough and the caller is the only call in progress, the unreachable objects are collected
+// beforehand. stackRoots is called to enumerate the values on the stack of the caller in that case, and the
+// references held by obj are also kept alive.
+func (h *GCHeap) Alloc(obj *GCObject, stackRoots func(mark func(uint64))) uint64 {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.live >= h.threshold && h.activeCalls <= 1 {
+		h.collect(obj, stackRoots)
+		h.threshold = max(gcHeapMinThreshold, 2*h.live)
+	}
+
+	h.live++
+	var index uint32
+	if n := len(h.free); n > 0 {
+		index = h.free[n-1]
+		h.free = h.free[:n-1]
+		h.objects[index] = obj
+	} else {
+		index = uint32(len(h.objects))
+		h.objects = append(h.objects, obj)
+	}
+	return uint64(index+1) << 1
+}
+
+// AnyConvertExtern converts the externref into an anyref as any.convert_extern. The externref converted from an
+// anyref is converted back into the original value, and the others from the host are wrapped into
+// GCObjectKindExtern objects, which are allocated as per Alloc.
+func (h *GCHeap) AnyConvertExtern(ref uint64, stackRoots func(mark func(uint64))) uint64 {
+	if ref == 0 {
+		return 0
+	} else if ref&GCRefExternalized != 0 {
+		return ref &^ GCRefExternalized
+	}
+	return h.Alloc(&GCObject{Kind: GCObjectKindExtern, Values: []uint64{ref}}, stackRoots)
+}
+
+// ExternConvertAny converts the anyref into an externref as extern.convert_any, which is the inverse of
+// AnyConvertExtern.
+func (h *GCHeap) ExternConvertAny(ref uint64) uint64 {
+	if ref == 0 {
+		return 0
+	} else if obj := h.Get(ref); obj != nil && obj.Kind == GCObjectKindExtern {
+		// The externref from the host is converted back into the original value.
+		return obj.Values[0]
+	}
+	return ref | GCRefExternalized
+}
+
+// Get returns the object referred by ref, or nil if ref is null, an i31ref or invalid.
+func (h *GCHeap) Get(ref uint64) *GCObject {
+	ref &^= GCRefExternalized
+	if ref == 0 || GCRefIsI31(ref) {
+		return nil
+	}
+	index := ref>>1 - 1
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if index >= uint64(len(h.objects)) {
+		return nil
+	}
+	return h.objects[index]
+}
+
+// Live returns the number of the objects in the heap.
+func (h *GCHeap) Live() int {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.live
+}
+
+// collect frees the objects unreachable from the roots, which include the references held by obj being allocated.
+func (h *GCHeap) collect(obj *GCObject, stackRoots func(mark func(uint64))) {
+	worklist := []*GCObject{obj}
+	mark := func(ref uint64) {
+		ref &^= GCRefExternalized
+		if ref == 0 || GCRefIsI31(ref) {
+			return
+		}
+		if index := ref>>1 - 1; index < uint64(len(h.objects)) {
+			if obj := h.objects[index]; obj != nil && !obj.marked {
+				obj.marked = true
+				worklist = append(worklist, obj)
+			}
+		}
+	}
+
+	if stackRoots != nil {
+		stackRoots(mark)
+	}
+	h.s.mux.RLock()
+	for m := h.s.moduleList; m != nil; m = m.next {
+		for _, g := range m.Globals {
+			if isReferenceValueType(g.Type.ValType) {
+				mark(g.Val)
+			}
+		}
+		for _, t := range m.Tables {
+			for _, r := range t.References {
+				mark(uint64(r))
+			}
+		}
+		for _, e := range m.ElementInstances {
+			for _, r := range e {
+				mark(uint64(r))
+			}
+		}
+	}
+	h.s.mux.RUnlock()
+
+	for len(worklist) > 0 {
+		obj := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		if obj.Kind == GCObjectKindExtern {
+			continue
+		}
+		t := obj.Type()
+		if obj.Kind == GCObjectKindArray {
+			if isReferenceValueType(t.Fields[0].Type) {
+				for _, v := range obj.Values {
+					mark(v)
+				}
+			}
+			continue
+		}
+		for i := range t.Fields {
+			if isReferenceValueType(t.Fields[i].Type) {
+				mark(obj.Values[t.fieldOffsets[i]])
+			}
+		}
+	}
+
+	for i, obj := range h.objects {
+		if obj == nil {
+			continue
+		}
+		if obj.marked {
+			obj.marked = false
+			continue
+		}
+		h.objects[i] = nil
+		h.free = append(h.free, uint32(i))
+		h.live--
+	}
+}
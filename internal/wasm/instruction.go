@@ -249,6 +249,9 @@ const (
 	//
 	// Currently, this is only supported in the constant expression in element segments.
 	OpcodeRefFunc = 0xd2
+	// OpcodeRefEq pops two eqref values, and pushes 1 if they refer to the same object or i31, 0 otherwise.
+	// This is toggled with CoreFeaturesGC.
+	OpcodeRefEq = 0xd3
 
 	// Below are toggled with CoreFeatureSignExtensionOps
 
@@ -272,6 +275,9 @@ const (
 	// Note: This is dependent on the flag CoreFeatureSignExtensionOps
 	OpcodeI64Extend32S Opcode = 0xc4
 
+	// OpcodeGCPrefix is the prefix of all GC instructions toggled with CoreFeaturesGC.
+	OpcodeGCPrefix Opcode = 0xfb
+
 	// OpcodeMiscPrefix is the prefix of various multi-byte opcodes.
 	// Introduced in CoreFeatureNonTrappingFloatToIntConversion, but used in other
 	// features, such as CoreFeatureBulkMemoryOperations.
@@ -321,6 +327,13 @@ const (
 	OpcodeMiscTableGrow OpcodeMisc = 0x0f
 	OpcodeMiscTableSize OpcodeMisc = 0x10
 	OpcodeMiscTableFill OpcodeMisc = 0x11
+
+	// Below are toggled with experimental.CoreFeaturesWideArithmetic
+
+	OpcodeMiscI64Add128   OpcodeMisc = 0x13
+	OpcodeMiscI64Sub128   OpcodeMisc = 0x14
+	OpcodeMiscI64MulWideS OpcodeMisc = 0x15
+	OpcodeMiscI64MulWideU OpcodeMisc = 0x16
 )
 
 // OpcodeVec represents an opcode of a vector instructions which has
@@ -626,6 +639,50 @@ const (
 	OpcodeVecF64x2PromoteLowF32x4Zero OpcodeVec = 0x5f
 )
 
+// OpcodeVecRelaxed represents an opcode of the relaxed vector instructions which
+// is prefixed by OpcodeVecPrefix the same way as OpcodeVec.
+//
+// The opcodes are 0x100 and above, so they are encoded with two bytes in
+// LEB128: the first one is the value of OpcodeVecRelaxed which has the
+// continuation bit set, followed by OpcodeVecRelaxedSecondByte. Since the
+// first byte collides with OpcodeVec, use IsVecRelaxed to distinguish them.
+//
+// These opcodes are toggled with experimental.CoreFeaturesRelaxedSIMD.
+type OpcodeVecRelaxed = byte
+
+// OpcodeVecRelaxedSecondByte is the second byte of the LEB128 encoding of
+// OpcodeVecRelaxed.
+const OpcodeVecRelaxedSecondByte byte = 0x02
+
+const (
+	OpcodeVecI8x16RelaxedSwizzle           OpcodeVecRelaxed = 0x80 // 0x100
+	OpcodeVecI32x4RelaxedTruncF32x4S       OpcodeVecRelaxed = 0x81 // 0x101
+	OpcodeVecI32x4RelaxedTruncF32x4U       OpcodeVecRelaxed = 0x82 // 0x102
+	OpcodeVecI32x4RelaxedTruncF64x2SZero   OpcodeVecRelaxed = 0x83 // 0x103
+	OpcodeVecI32x4RelaxedTruncF64x2UZero   OpcodeVecRelaxed = 0x84 // 0x104
+	OpcodeVecF32x4RelaxedMadd              OpcodeVecRelaxed = 0x85 // 0x105
+	OpcodeVecF32x4RelaxedNmadd             OpcodeVecRelaxed = 0x86 // 0x106
+	OpcodeVecF64x2RelaxedMadd              OpcodeVecRelaxed = 0x87 // 0x107
+	OpcodeVecF64x2RelaxedNmadd             OpcodeVecRelaxed = 0x88 // 0x108
+	OpcodeVecI8x16RelaxedLaneselect        OpcodeVecRelaxed = 0x89 // 0x109
+	OpcodeVecI16x8RelaxedLaneselect        OpcodeVecRelaxed = 0x8a // 0x10a
+	OpcodeVecI32x4RelaxedLaneselect        OpcodeVecRelaxed = 0x8b // 0x10b
+	OpcodeVecI64x2RelaxedLaneselect        OpcodeVecRelaxed = 0x8c // 0x10c
+	OpcodeVecF32x4RelaxedMin               OpcodeVecRelaxed = 0x8d // 0x10d
+	OpcodeVecF32x4RelaxedMax               OpcodeVecRelaxed = 0x8e // 0x10e
+	OpcodeVecF64x2RelaxedMin               OpcodeVecRelaxed = 0x8f // 0x10f
+	OpcodeVecF64x2RelaxedMax               OpcodeVecRelaxed = 0x90 // 0x110
+	OpcodeVecI16x8RelaxedQ15mulrS          OpcodeVecRelaxed = 0x91 // 0x111
+	OpcodeVecI16x8RelaxedDotI8x16I7x16S    OpcodeVecRelaxed = 0x92 // 0x112
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddS OpcodeVecRelaxed = 0x93 // 0x113
+)
+
+// IsVecRelaxed returns true if the vector instruction in body at pc, right
+// after OpcodeVecPrefix, is OpcodeVecRelaxed.
+func IsVecRelaxed(body []byte, pc uint64) bool {
+	return body[pc] >= 0x80 && pc+1 < uint64(len(body)) && body[pc+1] == OpcodeVecRelaxedSecondByte
+}
+
 // OpcodeAtomic represents an opcode of atomic instructions which has
 // multi-byte encoding and is prefixed by OpcodeAtomicPrefix.
 //
@@ -965,6 +1022,7 @@ const (
 	OpcodeRefNullName   = "ref.null"
 	OpcodeRefIsNullName = "ref.is_null"
 	OpcodeRefFuncName   = "ref.func"
+	OpcodeRefEqName     = "ref.eq"
 
 	OpcodeTableGetName = "table.get"
 	OpcodeTableSetName = "table.set"
@@ -977,6 +1035,7 @@ const (
 	OpcodeI64Extend16SName = "i64.extend16_s"
 	OpcodeI64Extend32SName = "i64.extend32_s"
 
+	OpcodeGCPrefixName     = "gc_prefix"
 	OpcodeMiscPrefixName   = "misc_prefix"
 	OpcodeVecPrefixName    = "vector_prefix"
 	OpcodeAtomicPrefixName = "atomic_prefix"
@@ -1160,6 +1219,7 @@ var instructionNames = [256]string{
 	OpcodeRefNull:   OpcodeRefNullName,
 	OpcodeRefIsNull: OpcodeRefIsNullName,
 	OpcodeRefFunc:   OpcodeRefFuncName,
+	OpcodeRefEq:     OpcodeRefEqName,
 
 	OpcodeTableGet: OpcodeTableGetName,
 	OpcodeTableSet: OpcodeTableSetName,
@@ -1172,6 +1232,7 @@ var instructionNames = [256]string{
 	OpcodeI64Extend16S: OpcodeI64Extend16SName,
 	OpcodeI64Extend32S: OpcodeI64Extend32SName,
 
+	OpcodeGCPrefix:   OpcodeGCPrefixName,
 	OpcodeMiscPrefix: OpcodeMiscPrefixName,
 	OpcodeVecPrefix:  OpcodeVecPrefixName,
 }
@@ -1202,6 +1263,11 @@ const (
 	OpcodeTableGrowName  = "table.grow"
 	OpcodeTableSizeName  = "table.size"
 	OpcodeTableFillName  = "table.fill"
+
+	OpcodeI64Add128Name   = "i64.add128"
+	OpcodeI64Sub128Name   = "i64.sub128"
+	OpcodeI64MulWideSName = "i64.mul_wide_s"
+	OpcodeI64MulWideUName = "i64.mul_wide_u"
 )
 
 var miscInstructionNames = [256]string{
@@ -1224,6 +1290,11 @@ var miscInstructionNames = [256]string{
 	OpcodeMiscTableGrow:  OpcodeTableGrowName,
 	OpcodeMiscTableSize:  OpcodeTableSizeName,
 	OpcodeMiscTableFill:  OpcodeTableFillName,
+
+	OpcodeMiscI64Add128:   OpcodeI64Add128Name,
+	OpcodeMiscI64Sub128:   OpcodeI64Sub128Name,
+	OpcodeMiscI64MulWideS: OpcodeI64MulWideSName,
+	OpcodeMiscI64MulWideU: OpcodeI64MulWideUName,
 }
 
 // MiscInstructionName returns the instruction corresponding to this miscellaneous Opcode.
@@ -1470,6 +1541,29 @@ const (
 	OpcodeVecF64x2PromoteLowF32x4ZeroName  = "f64x2.promote_low_f32x4"
 )
 
+const (
+	OpcodeVecI8x16RelaxedSwizzleName           = "i8x16.relaxed_swizzle"
+	OpcodeVecI32x4RelaxedTruncF32x4SName       = "i32x4.relaxed_trunc_f32x4_s"
+	OpcodeVecI32x4RelaxedTruncF32x4UName       = "i32x4.relaxed_trunc_f32x4_u"
+	OpcodeVecI32x4RelaxedTruncF64x2SZeroName   = "i32x4.relaxed_trunc_f64x2_s_zero"
+	OpcodeVecI32x4RelaxedTruncF64x2UZeroName   = "i32x4.relaxed_trunc_f64x2_u_zero"
+	OpcodeVecF32x4RelaxedMaddName              = "f32x4.relaxed_madd"
+	OpcodeVecF32x4RelaxedNmaddName             = "f32x4.relaxed_nmadd"
+	OpcodeVecF64x2RelaxedMaddName              = "f64x2.relaxed_madd"
+	OpcodeVecF64x2RelaxedNmaddName             = "f64x2.relaxed_nmadd"
+	OpcodeVecI8x16RelaxedLaneselectName        = "i8x16.relaxed_laneselect"
+	OpcodeVecI16x8RelaxedLaneselectName        = "i16x8.relaxed_laneselect"
+	OpcodeVecI32x4RelaxedLaneselectName        = "i32x4.relaxed_laneselect"
+	OpcodeVecI64x2RelaxedLaneselectName        = "i64x2.relaxed_laneselect"
+	OpcodeVecF32x4RelaxedMinName               = "f32x4.relaxed_min"
+	OpcodeVecF32x4RelaxedMaxName               = "f32x4.relaxed_max"
+	OpcodeVecF64x2RelaxedMinName               = "f64x2.relaxed_min"
+	OpcodeVecF64x2RelaxedMaxName               = "f64x2.relaxed_max"
+	OpcodeVecI16x8RelaxedQ15mulrSName          = "i16x8.relaxed_q15mulr_s"
+	OpcodeVecI16x8RelaxedDotI8x16I7x16SName    = "i16x8.relaxed_dot_i8x16_i7x16_s"
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName = "i32x4.relaxed_dot_i8x16_i7x16_add_s"
+)
+
 var vectorInstructionName = map[OpcodeVec]string{
 	OpcodeVecV128Load:                  OpcodeVecV128LoadName,
 	OpcodeVecV128Load8x8s:              OpcodeVecV128Load8x8SName,
@@ -1714,6 +1808,34 @@ func VectorInstructionName(oc OpcodeVec) (ret string) {
 	return vectorInstructionName[oc]
 }
 
+var vectorRelaxedInstructionName = map[OpcodeVecRelaxed]string{
+	OpcodeVecI8x16RelaxedSwizzle:           OpcodeVecI8x16RelaxedSwizzleName,
+	OpcodeVecI32x4RelaxedTruncF32x4S:       OpcodeVecI32x4RelaxedTruncF32x4SName,
+	OpcodeVecI32x4RelaxedTruncF32x4U:       OpcodeVecI32x4RelaxedTruncF32x4UName,
+	OpcodeVecI32x4RelaxedTruncF64x2SZero:   OpcodeVecI32x4RelaxedTruncF64x2SZeroName,
+	OpcodeVecI32x4RelaxedTruncF64x2UZero:   OpcodeVecI32x4RelaxedTruncF64x2UZeroName,
+	OpcodeVecF32x4RelaxedMadd:              OpcodeVecF32x4RelaxedMaddName,
+	OpcodeVecF32x4RelaxedNmadd:             OpcodeVecF32x4RelaxedNmaddName,
+	OpcodeVecF64x2RelaxedMadd:              OpcodeVecF64x2RelaxedMaddName,
+	OpcodeVecF64x2RelaxedNmadd:             OpcodeVecF64x2RelaxedNmaddName,
+	OpcodeVecI8x16RelaxedLaneselect:        OpcodeVecI8x16RelaxedLaneselectName,
+	OpcodeVecI16x8RelaxedLaneselect:        OpcodeVecI16x8RelaxedLaneselectName,
+	OpcodeVecI32x4RelaxedLaneselect:        OpcodeVecI32x4RelaxedLaneselectName,
+	OpcodeVecI64x2RelaxedLaneselect:        OpcodeVecI64x2RelaxedLaneselectName,
+	OpcodeVecF32x4RelaxedMin:               OpcodeVecF32x4RelaxedMinName,
+	OpcodeVecF32x4RelaxedMax:               OpcodeVecF32x4RelaxedMaxName,
+	OpcodeVecF64x2RelaxedMin:               OpcodeVecF64x2RelaxedMinName,
+	OpcodeVecF64x2RelaxedMax:               OpcodeVecF64x2RelaxedMaxName,
+	OpcodeVecI16x8RelaxedQ15mulrS:          OpcodeVecI16x8RelaxedQ15mulrSName,
+	OpcodeVecI16x8RelaxedDotI8x16I7x16S:    OpcodeVecI16x8RelaxedDotI8x16I7x16SName,
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddS: OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName,
+}
+
+// VectorRelaxedInstructionName returns the instruction name corresponding to the relaxed vector Opcode.
+func VectorRelaxedInstructionName(oc OpcodeVecRelaxed) (ret string) {
+	return vectorRelaxedInstructionName[oc]
+}
+
 const (
 	OpcodeAtomicMemoryNotifyName = "memory.atomic.notify"
 	OpcodeAtomicMemoryWait32Name = "memory.atomic.wait32"
@@ -1889,3 +2011,301 @@ var tailCallInstructionName = map[OpcodeTailCall]string{
 func TailCallInstructionName(oc OpcodeTailCall) (ret string) {
 	return tailCallInstructionName[oc]
 }
+
+// OpcodeFunctionReferences represents an opcode of a typed function references instruction.
+//
+// These opcodes are toggled with CoreFeaturesFunctionReferences, or CoreFeaturesGC.
+type OpcodeFunctionReferences = byte
+
+const (
+	// OpcodeFunctionReferencesCallRef calls the function referenced by the popped value, which must be of the
+	// function type given by the immediate.
+	OpcodeFunctionReferencesCallRef OpcodeFunctionReferences = 0x14
+	// OpcodeFunctionReferencesReturnCallRef is the tail call version of OpcodeFunctionReferencesCallRef, and also
+	// requires CoreFeaturesTailCall.
+	OpcodeFunctionReferencesReturnCallRef OpcodeFunctionReferences = 0x15
+	// OpcodeFunctionReferencesRefAsNonNull traps if the reference on the top of the stack is null.
+	OpcodeFunctionReferencesRefAsNonNull OpcodeFunctionReferences = 0xd4
+	// OpcodeFunctionReferencesBrOnNull branches to the label given by the immediate if the popped reference is null,
+	// and otherwise pushes it back.
+	OpcodeFunctionReferencesBrOnNull OpcodeFunctionReferences = 0xd5
+	// OpcodeFunctionReferencesBrOnNonNull branches to the label given by the immediate with the reference on the
+	// top of the stack if it is not null, and otherwise drops it.
+	OpcodeFunctionReferencesBrOnNonNull OpcodeFunctionReferences = 0xd6
+)
+
+const (
+	OpcodeFunctionReferencesCallRefName       = "call_ref"
+	OpcodeFunctionReferencesReturnCallRefName = "return_call_ref"
+	OpcodeFunctionReferencesRefAsNonNullName  = "ref.as_non_null"
+	OpcodeFunctionReferencesBrOnNullName      = "br_on_null"
+	OpcodeFunctionReferencesBrOnNonNullName   = "br_on_non_null"
+)
+
+var functionReferencesInstructionName = map[OpcodeFunctionReferences]string{
+	OpcodeFunctionReferencesCallRef:       OpcodeFunctionReferencesCallRefName,
+	OpcodeFunctionReferencesReturnCallRef: OpcodeFunctionReferencesReturnCallRefName,
+	OpcodeFunctionReferencesRefAsNonNull:  OpcodeFunctionReferencesRefAsNonNullName,
+	OpcodeFunctionReferencesBrOnNull:      OpcodeFunctionReferencesBrOnNullName,
+	OpcodeFunctionReferencesBrOnNonNull:   OpcodeFunctionReferencesBrOnNonNullName,
+}
+
+// FunctionReferencesInstructionName returns the instruction name corresponding to the typed function references
+// Opcode.
+func FunctionReferencesInstructionName(oc OpcodeFunctionReferences) (ret string) {
+	return functionReferencesInstructionName[oc]
+}
+
+// OpcodeExceptionHandling represents an opcode of an exception handling instruction.
+//
+// These opcodes are toggled with CoreFeaturesExceptionHandling.
+type OpcodeExceptionHandling = byte
+
+const (
+	// OpcodeExceptionHandlingThrow throws an exception with the tag given by the immediate, and the tag's params
+	// popped from the stack as the payload.
+	OpcodeExceptionHandlingThrow OpcodeExceptionHandling = 0x08
+	// OpcodeExceptionHandlingThrowRef re-throws the exception referenced by the exnref popped from the stack.
+	OpcodeExceptionHandlingThrowRef OpcodeExceptionHandling = 0x0a
+	// OpcodeExceptionHandlingTryTable brackets a sequence of instructions like OpcodeBlock, and if an exception is
+	// thrown in it, branches to the label of the first matching catch clause given as immediates.
+	OpcodeExceptionHandlingTryTable OpcodeExceptionHandling = 0x1f
+)
+
+// CatchKind is the kind of a catch clause of OpcodeExceptionHandlingTryTable.
+type CatchKind = byte
+
+const (
+	// CatchKindCatch catches an exception with the given tag, and branches with its payload.
+	CatchKindCatch CatchKind = iota
+	// CatchKindCatchRef catches an exception with the given tag, and branches with its payload and exnref.
+	CatchKindCatchRef
+	// CatchKindCatchAll catches any exception, and branches with no values.
+	CatchKindCatchAll
+	// CatchKindCatchAllRef catches any exception, and branches with its exnref.
+	CatchKindCatchAllRef
+)
+
+const (
+	OpcodeExceptionHandlingThrowName    = "throw"
+	OpcodeExceptionHandlingThrowRefName = "throw_ref"
+	OpcodeExceptionHandlingTryTableName = "try_table"
+)
+
+var exceptionHandlingInstructionName = map[OpcodeExceptionHandling]string{
+	OpcodeExceptionHandlingThrow:    OpcodeExceptionHandlingThrowName,
+	OpcodeExceptionHandlingThrowRef: OpcodeExceptionHandlingThrowRefName,
+	OpcodeExceptionHandlingTryTable: OpcodeExceptionHandlingTryTableName,
+}
+
+// ExceptionHandlingInstructionName returns the instruction name corresponding to the exception handling Opcode.
+func ExceptionHandlingInstructionName(oc OpcodeExceptionHandling) (ret string) {
+	return exceptionHandlingInstructionName[oc]
+}
+
+// OpcodeStackSwitching represents an opcode of a stack switching instruction.
+//
+// These opcodes are toggled with CoreFeaturesStackSwitching.
+type OpcodeStackSwitching = byte
+
+const (
+	// OpcodeStackSwitchingContNew creates a continuation of the continuation type given by the immediate from the
+	// popped function reference.
+	OpcodeStackSwitchingContNew OpcodeStackSwitching = 0xe0
+	// OpcodeStackSwitchingContBind creates a continuation of the second continuation type given by the immediates
+	// from the popped one of the first type, by binding some of its params to the popped values.
+	OpcodeStackSwitchingContBind OpcodeStackSwitching = 0xe1
+	// OpcodeStackSwitchingSuspend suspends the current continuation with the tag given by the immediate, and the
+	// tag's params popped from the stack as the payload. The tag's results are pushed when it is resumed.
+	OpcodeStackSwitchingSuspend OpcodeStackSwitching = 0xe2
+	// OpcodeStackSwitchingResume resumes the popped continuation with its params popped from the stack. If it
+	// suspends with one of the tags of the handlers given as immediates, this branches to the handler's label with
+	// the payload and the continuation of the rest.
+	OpcodeStackSwitchingResume OpcodeStackSwitching = 0xe3
+	// OpcodeStackSwitchingResumeThrow resumes the popped continuation by throwing an exception in it.
+	//
+	// Note: This is not supported yet, and rejected by the validation.
+	OpcodeStackSwitchingResumeThrow OpcodeStackSwitching = 0xe4
+	// OpcodeStackSwitchingSwitch switches to the popped continuation, passing the current one to it.
+	//
+	// Note: This is not supported yet, and rejected by the validation.
+	OpcodeStackSwitchingSwitch OpcodeStackSwitching = 0xe5
+)
+
+// ResumeHandlerKind is the kind of a handler of OpcodeStackSwitchingResume.
+type ResumeHandlerKind = byte
+
+const (
+	// ResumeHandlerKindOnLabel handles a suspension with the given tag by branching to the given label.
+	ResumeHandlerKindOnLabel ResumeHandlerKind = 0x00
+	// ResumeHandlerKindOnSwitch handles a switch with the given tag.
+	//
+	// Note: This is not supported yet, and rejected by the validation.
+	ResumeHandlerKindOnSwitch ResumeHandlerKind = 0x01
+)
+
+const (
+	OpcodeStackSwitchingContNewName     = "cont.new"
+	OpcodeStackSwitchingContBindName    = "cont.bind"
+	OpcodeStackSwitchingSuspendName     = "suspend"
+	OpcodeStackSwitchingResumeName      = "resume"
+	OpcodeStackSwitchingResumeThrowName = "resume_throw"
+	OpcodeStackSwitchingSwitchName      = "switch"
+)
+
+var stackSwitchingInstructionName = map[OpcodeStackSwitching]string{
+	OpcodeStackSwitchingContNew:     OpcodeStackSwitchingContNewName,
+	OpcodeStackSwitchingContBind:    OpcodeStackSwitchingContBindName,
+	OpcodeStackSwitchingSuspend:     OpcodeStackSwitchingSuspendName,
+	OpcodeStackSwitchingResume:      OpcodeStackSwitchingResumeName,
+	OpcodeStackSwitchingResumeThrow: OpcodeStackSwitchingResumeThrowName,
+	OpcodeStackSwitchingSwitch:      OpcodeStackSwitchingSwitchName,
+}
+
+// StackSwitchingInstructionName returns the instruction name corresponding to the stack switching Opcode.
+func StackSwitchingInstructionName(oc OpcodeStackSwitching) (ret string) {
+	return stackSwitchingInstructionName[oc]
+}
+
+// OpcodeGC represents an opcode of a GC instruction, which is prefixed by OpcodeGCPrefix.
+//
+// These opcodes are toggled with CoreFeaturesGC.
+type OpcodeGC = byte
+
+const (
+	// OpcodeGCStructNew allocates a struct of the type given by the immediate, initialized with the popped values.
+	OpcodeGCStructNew OpcodeGC = 0x00
+	// OpcodeGCStructNewDefault allocates a struct of the type given by the immediate, initialized with zeros.
+	OpcodeGCStructNewDefault OpcodeGC = 0x01
+	// OpcodeGCStructGet pushes the field of a struct.
+	OpcodeGCStructGet OpcodeGC = 0x02
+	// OpcodeGCStructGetS pushes the packed field of a struct with sign extension.
+	OpcodeGCStructGetS OpcodeGC = 0x03
+	// OpcodeGCStructGetU pushes the packed field of a struct with zero extension.
+	OpcodeGCStructGetU OpcodeGC = 0x04
+	// OpcodeGCStructSet sets the mutable field of a struct.
+	OpcodeGCStructSet OpcodeGC = 0x05
+	// OpcodeGCArrayNew allocates an array of the given length whose elements are the popped value.
+	OpcodeGCArrayNew OpcodeGC = 0x06
+	// OpcodeGCArrayNewDefault allocates an array of the given length whose elements are zeros.
+	OpcodeGCArrayNewDefault OpcodeGC = 0x07
+	// OpcodeGCArrayNewFixed allocates an array whose elements are the popped values of the number given by the immediate.
+	OpcodeGCArrayNewFixed OpcodeGC = 0x08
+	// OpcodeGCArrayNewData allocates an array whose elements are read from a data segment.
+	OpcodeGCArrayNewData OpcodeGC = 0x09
+	// OpcodeGCArrayNewElem allocates an array whose elements are read from an element segment.
+	OpcodeGCArrayNewElem OpcodeGC = 0x0a
+	// OpcodeGCArrayGet pushes the element of an array.
+	OpcodeGCArrayGet OpcodeGC = 0x0b
+	// OpcodeGCArrayGetS pushes the packed element of an array with sign extension.
+	OpcodeGCArrayGetS OpcodeGC = 0x0c
+	// OpcodeGCArrayGetU pushes the packed element of an array with zero extension.
+	OpcodeGCArrayGetU OpcodeGC = 0x0d
+	// OpcodeGCArraySet sets the element of a mutable array.
+	OpcodeGCArraySet OpcodeGC = 0x0e
+	// OpcodeGCArrayLen pushes the length of an array.
+	OpcodeGCArrayLen OpcodeGC = 0x0f
+	// OpcodeGCArrayFill sets the elements in the range of a mutable array to the popped value.
+	OpcodeGCArrayFill OpcodeGC = 0x10
+	// OpcodeGCArrayCopy copies the elements between arrays.
+	OpcodeGCArrayCopy OpcodeGC = 0x11
+	// OpcodeGCArrayInitData sets the elements in the range of a mutable array to the ones read from a data segment.
+	OpcodeGCArrayInitData OpcodeGC = 0x12
+	// OpcodeGCArrayInitElem sets the elements in the range of a mutable array to the ones read from an element segment.
+	OpcodeGCArrayInitElem OpcodeGC = 0x13
+	// OpcodeGCRefTest pushes 1 if the popped non-null reference is of the heap type given by the immediate.
+	OpcodeGCRefTest OpcodeGC = 0x14
+	// OpcodeGCRefTestNull is the same as OpcodeGCRefTest, except that null references pass the test.
+	OpcodeGCRefTestNull OpcodeGC = 0x15
+	// OpcodeGCRefCast traps unless the popped non-null reference is of the heap type given by the immediate.
+	OpcodeGCRefCast OpcodeGC = 0x16
+	// OpcodeGCRefCastNull is the same as OpcodeGCRefCast, except that null references pass the cast.
+	OpcodeGCRefCastNull OpcodeGC = 0x17
+	// OpcodeGCBrOnCast branches if the reference on the top of the stack is of the target heap type.
+	OpcodeGCBrOnCast OpcodeGC = 0x18
+	// OpcodeGCBrOnCastFail branches unless the reference on the top of the stack is of the target heap type.
+	OpcodeGCBrOnCastFail OpcodeGC = 0x19
+	// OpcodeGCAnyConvertExtern converts an externref into an anyref.
+	OpcodeGCAnyConvertExtern OpcodeGC = 0x1a
+	// OpcodeGCExternConvertAny converts an anyref into an externref.
+	OpcodeGCExternConvertAny OpcodeGC = 0x1b
+	// OpcodeGCRefI31 converts the lower 31 bits of an i32 into an i31ref.
+	OpcodeGCRefI31 OpcodeGC = 0x1c
+	// OpcodeGCI31GetS converts an i31ref into an i32 with sign extension.
+	OpcodeGCI31GetS OpcodeGC = 0x1d
+	// OpcodeGCI31GetU converts an i31ref into an i32 with zero extension.
+	OpcodeGCI31GetU OpcodeGC = 0x1e
+)
+
+const (
+	OpcodeGCStructNewName        = "struct.new"
+	OpcodeGCStructNewDefaultName = "struct.new_default"
+	OpcodeGCStructGetName        = "struct.get"
+	OpcodeGCStructGetSName       = "struct.get_s"
+	OpcodeGCStructGetUName       = "struct.get_u"
+	OpcodeGCStructSetName        = "struct.set"
+	OpcodeGCArrayNewName         = "array.new"
+	OpcodeGCArrayNewDefaultName  = "array.new_default"
+	OpcodeGCArrayNewFixedName    = "array.new_fixed"
+	OpcodeGCArrayNewDataName     = "array.new_data"
+	OpcodeGCArrayNewElemName     = "array.new_elem"
+	OpcodeGCArrayGetName         = "array.get"
+	OpcodeGCArrayGetSName        = "array.get_s"
+	OpcodeGCArrayGetUName        = "array.get_u"
+	OpcodeGCArraySetName         = "array.set"
+	OpcodeGCArrayLenName         = "array.len"
+	OpcodeGCArrayFillName        = "array.fill"
+	OpcodeGCArrayCopyName        = "array.copy"
+	OpcodeGCArrayInitDataName    = "array.init_data"
+	OpcodeGCArrayInitElemName    = "array.init_elem"
+	OpcodeGCRefTestName          = "ref.test"
+	OpcodeGCRefTestNullName      = "ref.test null"
+	OpcodeGCRefCastName          = "ref.cast"
+	OpcodeGCRefCastNullName      = "ref.cast null"
+	OpcodeGCBrOnCastName         = "br_on_cast"
+	OpcodeGCBrOnCastFailName     = "br_on_cast_fail"
+	OpcodeGCAnyConvertExternName = "any.convert_extern"
+	OpcodeGCExternConvertAnyName = "extern.convert_any"
+	OpcodeGCRefI31Name           = "ref.i31"
+	OpcodeGCI31GetSName          = "i31.get_s"
+	OpcodeGCI31GetUName          = "i31.get_u"
+)
+
+var gcInstructionName = map[OpcodeGC]string{
+	OpcodeGCStructNew:        OpcodeGCStructNewName,
+	OpcodeGCStructNewDefault: OpcodeGCStructNewDefaultName,
+	OpcodeGCStructGet:        OpcodeGCStructGetName,
+	OpcodeGCStructGetS:       OpcodeGCStructGetSName,
+	OpcodeGCStructGetU:       OpcodeGCStructGetUName,
+	OpcodeGCStructSet:        OpcodeGCStructSetName,
+	OpcodeGCArrayNew:         OpcodeGCArrayNewName,
+	OpcodeGCArrayNewDefault:  OpcodeGCArrayNewDefaultName,
+	OpcodeGCArrayNewFixed:    OpcodeGCArrayNewFixedName,
+	OpcodeGCArrayNewData:     OpcodeGCArrayNewDataName,
+	OpcodeGCArrayNewElem:     OpcodeGCArrayNewElemName,
+	OpcodeGCArrayGet:         OpcodeGCArrayGetName,
+	OpcodeGCArrayGetS:        OpcodeGCArrayGetSName,
+	OpcodeGCArrayGetU:        OpcodeGCArrayGetUName,
+	OpcodeGCArraySet:         OpcodeGCArraySetName,
+	OpcodeGCArrayLen:         OpcodeGCArrayLenName,
+	OpcodeGCArrayFill:        OpcodeGCArrayFillName,
+	OpcodeGCArrayCopy:        OpcodeGCArrayCopyName,
+	OpcodeGCArrayInitData:    OpcodeGCArrayInitDataName,
+	OpcodeGCArrayInitElem:    OpcodeGCArrayInitElemName,
+	OpcodeGCRefTest:          OpcodeGCRefTestName,
+	OpcodeGCRefTestNull:      OpcodeGCRefTestNullName,
+	OpcodeGCRefCast:          OpcodeGCRefCastName,
+	OpcodeGCRefCastNull:      OpcodeGCRefCastNullName,
+	OpcodeGCBrOnCast:         OpcodeGCBrOnCastName,
+	OpcodeGCBrOnCastFail:     OpcodeGCBrOnCastFailName,
+	OpcodeGCAnyConvertExtern: OpcodeGCAnyConvertExternName,
+	OpcodeGCExternConvertAny: OpcodeGCExternConvertAnyName,
+	OpcodeGCRefI31:           OpcodeGCRefI31Name,
+	OpcodeGCI31GetS:          OpcodeGCI31GetSName,
+	OpcodeGCI31GetU:          OpcodeGCI31GetUName,
+}
+
+// GCInstructionName returns the instruction name corresponding to the GC Opcode.
+func GCInstructionName(oc OpcodeGC) (ret string) {
+	return gcInstructionName[oc]
+}
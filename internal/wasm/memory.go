@@ -25,12 +25,26 @@ const (
 	// MemoryLimitPages is maximum number of pages defined (2^16).
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
 	MemoryLimitPages = uint32(65536)
+	// Memory64LimitPages is maximum number of pages of a memory64 memory supported by wazero. The memory64 proposal
+	// allows up to 2^48 pages, but pages are represented as uint32 which is already large enough (256Ti).
+	// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+	Memory64LimitPages = uint32(math.MaxUint32)
 	// MemoryPageSizeInBits satisfies the relation: "1 << MemoryPageSizeInBits == MemoryPageSize".
 	MemoryPageSizeInBits = 16
 )
 
-// compile-time check to ensure MemoryInstance implements api.Memory
-var _ api.Memory = &MemoryInstance{}
+// MemoryLimitPagesInPageSize converts memoryLimitPages of MemoryPageSize into the number of pages of
+// 1<<pageSizeInBits bytes as per experimental.CoreFeaturesCustomPageSizes, which is clamped to math.MaxUint32.
+func MemoryLimitPagesInPageSize(memoryLimitPages uint32, pageSizeInBits uint32) uint32 {
+	pages := MemoryPagesToBytesNum(memoryLimitPages) >> pageSizeInBits
+	if pages > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(pages)
+}
+
+// compile-time check to ensure MemoryInstance implements api.Memory and api.Memory64
+var _ api.Memory64 = &MemoryInstance{}
 
 type waiters struct {
 	mux sync.Mutex
@@ -48,6 +62,12 @@ type MemoryInstance struct {
 	Buffer        []byte
 	Min, Cap, Max uint32
 	Shared        bool
+	// Memory64 is true if this memory is addressed with i64 as per the memory64 proposal.
+	Memory64 bool
+	// customPageSize is true if a custom page size of 1<<customPageSizeInBits bytes is specified as per
+	// experimental.CoreFeaturesCustomPageSizes. In that case, Min, Cap and Max are in the unit of that page size.
+	customPageSize       bool
+	customPageSizeInBits uint32
 	// definition is known at compile time.
 	definition api.MemoryDefinition
 
@@ -68,9 +88,10 @@ type MemoryInstance struct {
 
 // NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
 func NewMemoryInstance(memSec *Memory, allocator experimental.MemoryAllocator, moduleEngine ModuleEngine) *MemoryInstance {
-	minBytes := MemoryPagesToBytesNum(memSec.Min)
-	capBytes := MemoryPagesToBytesNum(memSec.Cap)
-	maxBytes := MemoryPagesToBytesNum(memSec.Max)
+	pageSizeInBits := memSec.PageSizeInBits()
+	minBytes := uint64(memSec.Min) << pageSizeInBits
+	capBytes := uint64(memSec.Cap) << pageSizeInBits
+	maxBytes := uint64(memSec.Max) << pageSizeInBits
 
 	var buffer []byte
 	var expBuffer experimental.LinearMemory
@@ -93,13 +114,16 @@ func NewMemoryInstance(memSec *Memory, allocator experimental.MemoryAllocator, m
 		buffer = make([]byte, minBytes, capBytes)
 	}
 	return &MemoryInstance{
-		Buffer:            buffer,
-		Min:               memSec.Min,
-		Cap:               memoryBytesNumToPages(uint64(cap(buffer))),
-		Max:               memSec.Max,
-		Shared:            memSec.IsShared,
-		expBuffer:         expBuffer,
-		ownerModuleEngine: moduleEngine,
+		Buffer:               buffer,
+		Min:                  memSec.Min,
+		Cap:                  uint32(uint64(cap(buffer)) >> pageSizeInBits),
+		Max:                  memSec.Max,
+		Shared:               memSec.IsShared,
+		Memory64:             memSec.IsMemory64,
+		customPageSize:       memSec.IsPageSizeEncoded,
+		customPageSizeInBits: memSec.PageSizeLog2,
+		expBuffer:            expBuffer,
+		ownerModuleEngine:    moduleEngine,
 	}
 }
 
@@ -113,8 +137,18 @@ func (m *MemoryInstance) Size() uint32 {
 	return uint32(len(m.Buffer))
 }
 
+// Size64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) Size64() uint64 {
+	return uint64(len(m.Buffer))
+}
+
 // ReadByte implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadByte(offset uint32) (byte, bool) {
+	return m.ReadByte64(uint64(offset))
+}
+
+// ReadByte64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) ReadByte64(offset uint64) (byte, bool) {
 	if !m.hasSize(offset, 1) {
 		return 0, false
 	}
@@ -123,6 +157,11 @@ func (m *MemoryInstance) ReadByte(offset uint32) (byte, bool) {
 
 // ReadUint16Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadUint16Le(offset uint32) (uint16, bool) {
+	return m.ReadUint16Le64(uint64(offset))
+}
+
+// ReadUint16Le64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) ReadUint16Le64(offset uint64) (uint16, bool) {
 	if !m.hasSize(offset, 2) {
 		return 0, false
 	}
@@ -131,12 +170,17 @@ func (m *MemoryInstance) ReadUint16Le(offset uint32) (uint16, bool) {
 
 // ReadUint32Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadUint32Le(offset uint32) (uint32, bool) {
+	return m.readUint32Le(uint64(offset))
+}
+
+// ReadUint32Le64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) ReadUint32Le64(offset uint64) (uint32, bool) {
 	return m.readUint32Le(offset)
 }
 
 // ReadFloat32Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadFloat32Le(offset uint32) (float32, bool) {
-	v, ok := m.readUint32Le(offset)
+	v, ok := m.readUint32Le(uint64(offset))
 	if !ok {
 		return 0, false
 	}
@@ -145,12 +189,17 @@ func (m *MemoryInstance) ReadFloat32Le(offset uint32) (float32, bool) {
 
 // ReadUint64Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadUint64Le(offset uint32) (uint64, bool) {
+	return m.readUint64Le(uint64(offset))
+}
+
+// ReadUint64Le64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) ReadUint64Le64(offset uint64) (uint64, bool) {
 	return m.readUint64Le(offset)
 }
 
 // ReadFloat64Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadFloat64Le(offset uint32) (float64, bool) {
-	v, ok := m.readUint64Le(offset)
+	v, ok := m.readUint64Le(uint64(offset))
 	if !ok {
 		return 0, false
 	}
@@ -159,7 +208,12 @@ func (m *MemoryInstance) ReadFloat64Le(offset uint32) (float64, bool) {
 
 // Read implements the same method as documented on api.Memory.
 func (m *MemoryInstance) Read(offset, byteCount uint32) ([]byte, bool) {
-	if !m.hasSize(offset, uint64(byteCount)) {
+	return m.Read64(uint64(offset), uint64(byteCount))
+}
+
+// Read64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) Read64(offset, byteCount uint64) ([]byte, bool) {
+	if !m.hasSize(offset, byteCount) {
 		return nil, false
 	}
 	return m.Buffer[offset : offset+byteCount : offset+byteCount], true
@@ -167,6 +221,11 @@ func (m *MemoryInstance) Read(offset, byteCount uint32) ([]byte, bool) {
 
 // WriteByte implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteByte(offset uint32, v byte) bool {
+	return m.WriteByte64(uint64(offset), v)
+}
+
+// WriteByte64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) WriteByte64(offset uint64, v byte) bool {
 	if !m.hasSize(offset, 1) {
 		return false
 	}
@@ -176,6 +235,11 @@ func (m *MemoryInstance) WriteByte(offset uint32, v byte) bool {
 
 // WriteUint16Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteUint16Le(offset uint32, v uint16) bool {
+	return m.WriteUint16Le64(uint64(offset), v)
+}
+
+// WriteUint16Le64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) WriteUint16Le64(offset uint64, v uint16) bool {
 	if !m.hasSize(offset, 2) {
 		return false
 	}
@@ -185,26 +249,41 @@ func (m *MemoryInstance) WriteUint16Le(offset uint32, v uint16) bool {
 
 // WriteUint32Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteUint32Le(offset, v uint32) bool {
+	return m.writeUint32Le(uint64(offset), v)
+}
+
+// WriteUint32Le64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) WriteUint32Le64(offset uint64, v uint32) bool {
 	return m.writeUint32Le(offset, v)
 }
 
 // WriteFloat32Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteFloat32Le(offset uint32, v float32) bool {
-	return m.writeUint32Le(offset, math.Float32bits(v))
+	return m.writeUint32Le(uint64(offset), math.Float32bits(v))
 }
 
 // WriteUint64Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteUint64Le(offset uint32, v uint64) bool {
+	return m.writeUint64Le(uint64(offset), v)
+}
+
+// WriteUint64Le64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) WriteUint64Le64(offset uint64, v uint64) bool {
 	return m.writeUint64Le(offset, v)
 }
 
 // WriteFloat64Le implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteFloat64Le(offset uint32, v float64) bool {
-	return m.writeUint64Le(offset, math.Float64bits(v))
+	return m.writeUint64Le(uint64(offset), math.Float64bits(v))
 }
 
 // Write implements the same method as documented on api.Memory.
 func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
+	return m.Write64(uint64(offset), val)
+}
+
+// Write64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) Write64(offset uint64, val []byte) bool {
 	if !m.hasSize(offset, uint64(len(val))) {
 		return false
 	}
@@ -214,6 +293,11 @@ func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
 
 // WriteString implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteString(offset uint32, val string) bool {
+	return m.WriteString64(uint64(offset), val)
+}
+
+// WriteString64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) WriteString64(offset uint64, val string) bool {
 	if !m.hasSize(offset, uint64(len(val))) {
 		return false
 	}
@@ -228,6 +312,15 @@ func MemoryPagesToBytesNum(pages uint32) (bytesNum uint64) {
 
 // Grow implements the same method as documented on api.Memory.
 func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
+	if int32(delta) < 0 {
+		return 0, false
+	}
+	res, ok := m.Grow64(uint64(delta))
+	return uint32(res), ok
+}
+
+// Grow64 implements the same method as documented on api.Memory64.
+func (m *MemoryInstance) Grow64(delta uint64) (result uint64, ok bool) {
 	if m.Shared {
 		m.Mux.Lock()
 		defer m.Mux.Unlock()
@@ -235,14 +328,15 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 
 	currentPages := m.Pages()
 	if delta == 0 {
-		return currentPages, true
+		return uint64(currentPages), true
 	}
 
-	newPages := currentPages + delta
-	if newPages > m.Max || int32(delta) < 0 {
+	if delta > uint64(m.Max-currentPages) {
 		return 0, false
-	} else if m.expBuffer != nil {
-		buffer := m.expBuffer.Reallocate(MemoryPagesToBytesNum(newPages))
+	}
+	newPages := currentPages + uint32(delta)
+	if m.expBuffer != nil {
+		buffer := m.expBuffer.Reallocate(m.pagesToBytesNum(newPages))
 		if buffer == nil {
 			// Allocator failed to grow.
 			return 0, false
@@ -255,7 +349,7 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 			// But the memory length is accessed elsewhere,
 			// so use atomic to make the new length visible across threads.
 			atomicStoreLengthAndCap(&m.Buffer, uintptr(len(buffer)), uintptr(cap(buffer)))
-			m.Cap = memoryBytesNumToPages(uint64(cap(buffer)))
+			m.Cap = m.bytesNumToPages(uint64(cap(buffer)))
 		} else {
 			m.Buffer = buffer
 			m.Cap = newPages
@@ -264,32 +358,60 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 		if m.Shared {
 			panic("shared memory cannot be grown, this is a bug in wazero")
 		}
-		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
+		m.Buffer = append(m.Buffer, make([]byte, m.pagesToBytesNum(uint32(delta)))...)
 		m.Cap = newPages
 	} else { // We already have the capacity we need.
 		if m.Shared {
 			// We assume grow is called under a guest lock.
 			// But the memory length is accessed elsewhere,
 			// so use atomic to make the new length visible across threads.
-			atomicStoreLength(&m.Buffer, uintptr(MemoryPagesToBytesNum(newPages)))
+			atomicStoreLength(&m.Buffer, uintptr(m.pagesToBytesNum(newPages)))
 		} else {
-			m.Buffer = m.Buffer[:MemoryPagesToBytesNum(newPages)]
+			m.Buffer = m.Buffer[:m.pagesToBytesNum(newPages)]
 		}
 	}
 	m.ownerModuleEngine.MemoryGrown()
-	return currentPages, true
+	return uint64(currentPages), true
 }
 
 // Pages implements the same method as documented on api.Memory.
 func (m *MemoryInstance) Pages() (result uint32) {
-	return memoryBytesNumToPages(uint64(len(m.Buffer)))
+	return m.bytesNumToPages(uint64(len(m.Buffer)))
+}
+
+// PageSizeInBits returns the log2 of the page size of this memory, which is MemoryPageSizeInBits unless a custom
+// page size is specified.
+func (m *MemoryInstance) PageSizeInBits() uint32 {
+	if m.customPageSize {
+		return m.customPageSizeInBits
+	}
+	return MemoryPageSizeInBits
+}
+
+// pagesToBytesNum converts the given pages of this memory into the number of bytes contained in these pages.
+func (m *MemoryInstance) pagesToBytesNum(pages uint32) uint64 {
+	return uint64(pages) << m.PageSizeInBits()
+}
+
+// bytesNumToPages converts the given number of bytes into the number of pages of this memory.
+func (m *MemoryInstance) bytesNumToPages(bytesNum uint64) uint32 {
+	return uint32(bytesNum >> m.PageSizeInBits())
 }
 
 // PagesToUnitOfBytes converts the pages to a human-readable form similar to what's specified. e.g. 1 -> "64Ki"
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#memory-instances%E2%91%A0
 func PagesToUnitOfBytes(pages uint32) string {
-	k := pages * 64
+	return bytesToUnitOfBytes(MemoryPagesToBytesNum(pages))
+}
+
+// bytesToUnitOfBytes is the same as PagesToUnitOfBytes, but takes the number of bytes, which may not be a multiple
+// of MemoryPageSize with experimental.CoreFeaturesCustomPageSizes.
+func bytesToUnitOfBytes(bytesNum uint64) string {
+	if bytesNum%1024 != 0 {
+		return fmt.Sprintf("%d B", bytesNum)
+	}
+	k := bytesNum / 1024
 	if k < 1024 {
 		return fmt.Sprintf("%d Ki", k)
 	}
@@ -324,21 +446,17 @@ func atomicStoreLength(slice *[]byte, length uintptr) {
 	atomic.StoreUintptr(lenPtr, length)
 }
 
-// memoryBytesNumToPages converts the given number of bytes into the number of pages.
-func memoryBytesNumToPages(bytesNum uint64) (pages uint32) {
-	return uint32(bytesNum >> MemoryPageSizeInBits)
-}
-
 // hasSize returns true if Len is sufficient for byteCount at the given offset.
 //
 // Note: This is always fine, because memory can grow, but never shrink.
-func (m *MemoryInstance) hasSize(offset uint32, byteCount uint64) bool {
-	return uint64(offset)+byteCount <= uint64(len(m.Buffer)) // uint64 prevents overflow on add
+func (m *MemoryInstance) hasSize(offset uint64, byteCount uint64) bool {
+	end := offset + byteCount
+	return end >= offset && end <= uint64(len(m.Buffer)) // end < offset means overflow on add
 }
 
 // readUint32Le implements ReadUint32Le without using a context. This is extracted as both ints and floats are stored in
 // memory as uint32le.
-func (m *MemoryInstance) readUint32Le(offset uint32) (uint32, bool) {
+func (m *MemoryInstance) readUint32Le(offset uint64) (uint32, bool) {
 	if !m.hasSize(offset, 4) {
 		return 0, false
 	}
@@ -347,7 +465,7 @@ func (m *MemoryInstance) readUint32Le(offset uint32) (uint32, bool) {
 
 // readUint64Le implements ReadUint64Le without using a context. This is extracted as both ints and floats are stored in
 // memory as uint64le.
-func (m *MemoryInstance) readUint64Le(offset uint32) (uint64, bool) {
+func (m *MemoryInstance) readUint64Le(offset uint64) (uint64, bool) {
 	if !m.hasSize(offset, 8) {
 		return 0, false
 	}
@@ -356,7 +474,7 @@ func (m *MemoryInstance) readUint64Le(offset uint32) (uint64, bool) {
 
 // writeUint32Le implements WriteUint32Le without using a context. This is extracted as both ints and floats are stored
 // in memory as uint32le.
-func (m *MemoryInstance) writeUint32Le(offset uint32, v uint32) bool {
+func (m *MemoryInstance) writeUint32Le(offset uint64, v uint32) bool {
 	if !m.hasSize(offset, 4) {
 		return false
 	}
@@ -366,7 +484,7 @@ func (m *MemoryInstance) writeUint32Le(offset uint32, v uint32) bool {
 
 // writeUint64Le implements WriteUint64Le without using a context. This is extracted as both ints and floats are stored
 // in memory as uint64le.
-func (m *MemoryInstance) writeUint64Le(offset uint32, v uint64) bool {
+func (m *MemoryInstance) writeUint64Le(offset uint64, v uint64) bool {
 	if !m.hasSize(offset, 8) {
 		return false
 	}
@@ -375,7 +493,7 @@ func (m *MemoryInstance) writeUint64Le(offset uint32, v uint64) bool {
 }
 
 // Wait32 suspends the caller until the offset is notified by a different agent.
-func (m *MemoryInstance) Wait32(offset uint32, exp uint32, timeout int64, reader func(mem *MemoryInstance, offset uint32) uint32) uint64 {
+func (m *MemoryInstance) Wait32(offset uint64, exp uint32, timeout int64, reader func(mem *MemoryInstance, offset uint64) uint32) uint64 {
 	w := m.getWaiters(offset)
 	w.mux.Lock()
 
@@ -389,7 +507,7 @@ func (m *MemoryInstance) Wait32(offset uint32, exp uint32, timeout int64, reader
 }
 
 // Wait64 suspends the caller until the offset is notified by a different agent.
-func (m *MemoryInstance) Wait64(offset uint32, exp uint64, timeout int64, reader func(mem *MemoryInstance, offset uint32) uint64) uint64 {
+func (m *MemoryInstance) Wait64(offset uint64, exp uint64, timeout int64, reader func(mem *MemoryInstance, offset uint64) uint64) uint64 {
 	w := m.getWaiters(offset)
 	w.mux.Lock()
 
@@ -438,7 +556,7 @@ func (m *MemoryInstance) wait(w *waiters, timeout int64) uint64 {
 	}
 }
 
-func (m *MemoryInstance) getWaiters(offset uint32) *waiters {
+func (m *MemoryInstance) getWaiters(offset uint64) *waiters {
 	wAny, ok := m.waiters.Load(offset)
 	if !ok {
 		// The first time an address is waited on, simultaneous waits will cause extra allocations.
@@ -451,7 +569,7 @@ func (m *MemoryInstance) getWaiters(offset uint32) *waiters {
 }
 
 // Notify wakes up at most count waiters at the given offset.
-func (m *MemoryInstance) Notify(offset uint32, count uint32) uint32 {
+func (m *MemoryInstance) Notify(offset uint64, count uint32) uint32 {
 	wAny, ok := m.waiters.Load(offset)
 	if !ok {
 		return 0
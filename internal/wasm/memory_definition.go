@@ -38,11 +38,7 @@ func (m *Module) BuildMemoryDefinitions() {
 		moduleName = m.NameSection.ModuleName
 	}
 
-	memoryCount := m.ImportMemoryCount
-	if m.MemorySection != nil {
-		memoryCount++
-	}
-
+	memoryCount := m.memoryCount()
 	if memoryCount == 0 {
 		return
 	}
@@ -68,6 +64,12 @@ func (m *Module) BuildMemoryDefinitions() {
 			index:  importMemIdx,
 			memory: m.MemorySection,
 		})
+		for i, mem := range m.MultiMemorySection {
+			m.MemoryDefinitionSection = append(m.MemoryDefinitionSection, MemoryDefinition{
+				index:  importMemIdx + 1 + Index(i),
+				memory: mem,
+			})
+		}
 	}
 
 	for i := range m.MemoryDefinitionSection {
@@ -126,3 +128,8 @@ func (f *MemoryDefinition) Max() (max uint32, encoded bool) {
 	encoded = f.memory.IsMaxEncoded
 	return
 }
+
+// PageSize implements the same method as documented on api.MemoryDefinition.
+func (f *MemoryDefinition) PageSize() uint32 {
+	return 1 << f.memory.PageSizeInBits()
+}
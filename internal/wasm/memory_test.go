@@ -25,10 +25,23 @@ func TestMemoryPagesToBytesNum(t *testing.T) {
 	}
 }
 
-func TestMemoryBytesNumToPages(t *testing.T) {
+func TestMemoryInstance_bytesNumToPages(t *testing.T) {
+	m := &MemoryInstance{}
 	for _, numbytes := range []uint32{0, MemoryPageSize * 1, MemoryPageSize * 10} {
-		require.Equal(t, numbytes/MemoryPageSize, memoryBytesNumToPages(uint64(numbytes)))
+		require.Equal(t, numbytes/MemoryPageSize, m.bytesNumToPages(uint64(numbytes)))
 	}
+
+	m = &MemoryInstance{customPageSize: true, customPageSizeInBits: 0}
+	for _, numbytes := range []uint32{0, 1, 10, MemoryPageSize + 1} {
+		require.Equal(t, numbytes, m.bytesNumToPages(uint64(numbytes)))
+	}
+}
+
+func TestMemoryLimitPagesInPageSize(t *testing.T) {
+	require.Equal(t, uint32(2), MemoryLimitPagesInPageSize(2, MemoryPageSizeInBits))
+	require.Equal(t, uint32(2*MemoryPageSize), MemoryLimitPagesInPageSize(2, 0))
+	// 4GiB of 1 byte pages doesn't fit in uint32.
+	require.Equal(t, uint32(math.MaxUint32), MemoryLimitPagesInPageSize(MemoryLimitPages, 0))
 }
 
 func TestMemoryInstance_Grow_Size(t *testing.T) {
@@ -124,6 +137,22 @@ func TestMemoryInstance_NegativeDelta(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestMemoryInstance_Grow_CustomPageSize(t *testing.T) {
+	m := NewMemoryInstance(&Memory{Min: 3, Cap: 3, Max: 10, IsPageSizeEncoded: true}, nil, &mockModuleEngine{})
+	require.Equal(t, uint32(3), m.Pages())
+	require.Equal(t, uint32(3), m.Size())
+
+	res, ok := m.Grow(5)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), res)
+	require.Equal(t, uint32(8), m.Pages())
+	require.Equal(t, 8, len(m.Buffer))
+
+	_, ok = m.Grow(3)
+	require.False(t, ok)
+	require.Equal(t, uint32(8), m.Pages())
+}
+
 func TestMemoryInstance_ReadByte(t *testing.T) {
 	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 0, 0, 0, 16}, Min: 1}
 	v, ok := mem.ReadByte(7)
@@ -166,7 +195,7 @@ func TestPagesToUnitOfBytes(t *testing.T) {
 		{
 			name:     "max uint32",
 			pages:    math.MaxUint32,
-			expected: "3 Ti",
+			expected: "255 Ti",
 		},
 	}
 
@@ -184,7 +213,7 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 
 	tests := []struct {
 		name        string
-		offset      uint32
+		offset      uint64
 		sizeInBytes uint64
 		expected    bool
 	}{
@@ -196,7 +225,7 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 		},
 		{
 			name:        "maximum valid sizeInBytes",
-			offset:      memory.Size() - 8,
+			offset:      memory.Size64() - 8,
 			sizeInBytes: 8,
 			expected:    true,
 		},
@@ -208,7 +237,7 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 		},
 		{
 			name:        "offset exceeds the memory size",
-			offset:      memory.Size(),
+			offset:      memory.Size64(),
 			sizeInBytes: 1, // arbitrary size
 			expected:    false,
 		},
@@ -224,6 +253,12 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 			sizeInBytes: 1,
 			expected:    false,
 		},
+		{
+			name:        "offset + sizeInBytes overflows in uint64",
+			offset:      math.MaxUint64 - 1,
+			sizeInBytes: 4,
+			expected:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -877,8 +912,8 @@ func TestNewMemoryInstance_Shared(t *testing.T) {
 }
 
 func TestMemoryInstance_WaitNotifyOnce(t *testing.T) {
-	reader := func(mem *MemoryInstance, offset uint32) uint32 {
-		val, _ := mem.ReadUint32Le(offset)
+	reader := func(mem *MemoryInstance, offset uint64) uint32 {
+		val, _ := mem.ReadUint32Le64(offset)
 		return val
 	}
 	t.Run("no waiters", func(t *testing.T) {
@@ -989,7 +1024,7 @@ func notifyWaiters(t *testing.T, mem *MemoryInstance, offset, count, exp int) {
 		if tries > 100 {
 			t.Fatal("too many tries waiting for wait and notify to converge")
 		}
-		n := mem.Notify(uint32(offset), uint32(count))
+		n := mem.Notify(uint64(offset), uint32(count))
 		cur += int(n)
 		time.Sleep(1 * time.Millisecond)
 		tries++
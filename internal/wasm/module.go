@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -36,6 +35,12 @@ type Module struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#types%E2%91%A0%E2%91%A0
 	TypeSection []FunctionType
 
+	// SubTypes are the definitions of the types in TypeSection as per experimental.CoreFeaturesGC, which are
+	// index-correlated with TypeSection. This is nil unless the type section uses the encoding of the GC proposal
+	// or defines continuation types, in which case the entry of TypeSection for a struct, array or continuation
+	// type is empty.
+	SubTypes []SubType
+
 	// ImportSection contains imported functions, tables, memories or globals required for instantiation
 	// (Store.Instantiate).
 	//
@@ -45,12 +50,13 @@ type Module struct {
 	//
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#import-section%E2%91%A0
 	ImportSection []Import
-	// ImportFunctionCount ImportGlobalCount ImportMemoryCount, and ImportTableCount are
+	// ImportFunctionCount ImportGlobalCount ImportMemoryCount, ImportTableCount and ImportTagCount are
 	// the cached import count per ExternType set during decoding.
 	ImportFunctionCount,
 	ImportGlobalCount,
 	ImportMemoryCount,
-	ImportTableCount Index
+	ImportTableCount,
+	ImportTagCount Index
 	// ImportPerModule maps a module name to the list of Import to be imported from the module.
 	// This is used to do fast import resolution during instantiation.
 	ImportPerModule map[string][]*Import
@@ -97,6 +103,14 @@ type Module struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#memory-section%E2%91%A0
 	MemorySection *Memory
 
+	// MultiMemorySection contains the memories defined in this module after MemorySection, which is only possible
+	// when experimental.CoreFeaturesMultiMemory is enabled.
+	//
+	// Note: The memory Index space begins with imported memories, followed by MemorySection and then these.
+	//
+	// See https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+	MultiMemorySection []*Memory
+
 	// GlobalSection contains each global defined in this module.
 	//
 	// Global indexes are offset by any imported globals because the global index begins with imports, followed by
@@ -108,6 +122,16 @@ type Module struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#global-section%E2%91%A0
 	GlobalSection []Global
 
+	// TagSection contains the index in TypeSection of each exception tag defined in this module.
+	//
+	// Note: The tag Index space begins with imported tags and ends with those defined in this module.
+	//
+	// Note: In the Binary Format, this is SectionIDTag, and only exists with
+	// experimental.CoreFeaturesExceptionHandling enabled.
+	//
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#tag-section
+	TagSection []Index
+
 	// ExportSection contains each export defined in this module.
 	//
 	// Note: In the Binary Format, this is SectionIDExport.
@@ -172,6 +196,18 @@ type Module struct {
 	// IsHostModule true if this is the host module, false otherwise.
 	IsHostModule bool
 
+	// UsesGC is set during Validate if the module uses the types or instructions of experimental.CoreFeaturesGC,
+	// so that the engines without its support can reject the module.
+	UsesGC bool
+
+	// UsesCallRef is set during Validate if the module uses call_ref or return_call_ref, which check the type of
+	// the referenced function at runtime even if the module has no table.
+	UsesCallRef bool
+
+	// DeterministicRelaxedSIMD is set before AssignModuleID if the relaxed vector instructions of
+	// experimental.CoreFeaturesRelaxedSIMD must be compiled to produce the same results on all platforms.
+	DeterministicRelaxedSIMD bool
+
 	// functionDefinitionSectionInitOnce guards FunctionDefinitionSection so that it is initialized exactly once.
 	functionDefinitionSectionInitOnce sync.Once
 
@@ -214,6 +250,11 @@ func (m *Module) AssignModuleID(wasm []byte, listeners []experimental.FunctionLi
 	// Write the flag of ensureTermination to the checksum.
 	m.ID[0] = boolToByte(withEnsureTermination)
 	h.Write(m.ID[:1])
+	// Write the flag of DeterministicRelaxedSIMD only if set, so that the checksum of the other modules is unchanged.
+	if m.DeterministicRelaxedSIMD {
+		m.ID[0] = 1
+		h.Write(m.ID[:1])
+	}
 	// Get checksum by passing the slice underlying m.ID.
 	h.Sum(m.ID[:0])
 }
@@ -227,6 +268,15 @@ func boolToByte(b bool) (ret byte) {
 
 // typeOfFunction returns the wasm.FunctionType for the given function space index or nil.
 func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
+	if typeIdx, ok := m.typeIndexOfFunction(funcIdx); ok {
+		return &m.TypeSection[typeIdx]
+	}
+	return nil
+}
+
+// typeIndexOfFunction returns the index in TypeSection of the type for the given function space index, or false if
+// either of them is out of range.
+func (m *Module) typeIndexOfFunction(funcIdx Index) (Index, bool) {
 	typeSectionLength, importedFunctionCount := uint32(len(m.TypeSection)), m.ImportFunctionCount
 	if funcIdx < importedFunctionCount {
 		// Imports are not exclusively functions. This is the current function index in the loop.
@@ -237,10 +287,7 @@ func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
 				continue
 			}
 			if funcIdx == cur {
-				if imp.DescFunc >= typeSectionLength {
-					return nil
-				}
-				return &m.TypeSection[imp.DescFunc]
+				return imp.DescFunc, imp.DescFunc < typeSectionLength
 			}
 			cur++
 		}
@@ -248,19 +295,34 @@ func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
 
 	funcSectionIdx := funcIdx - m.ImportFunctionCount
 	if funcSectionIdx >= uint32(len(m.FunctionSection)) {
-		return nil
+		return 0, false
 	}
 	typeIdx := m.FunctionSection[funcSectionIdx]
-	if typeIdx >= typeSectionLength {
-		return nil
-	}
-	return &m.TypeSection[typeIdx]
+	return typeIdx, typeIdx < typeSectionLength
 }
 
 func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 	for i := range m.TypeSection {
 		tp := &m.TypeSection[i]
 		tp.CacheNumInUint64()
+		if !enabledFeatures.IsEnabled(experimental.CoreFeaturesExceptionHandling) &&
+			(bytes.IndexByte(tp.Params, ValueTypeExnref) >= 0 || bytes.IndexByte(tp.Results, ValueTypeExnref) >= 0) {
+			return fmt.Errorf("invalid type[%d]: exnref invalid as %v", i,
+				enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling))
+		}
+		if bytes.IndexByte(tp.Params, ValueTypeAnyref) >= 0 || bytes.IndexByte(tp.Results, ValueTypeAnyref) >= 0 {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesGC); err != nil {
+				return fmt.Errorf("invalid type[%d]: anyref invalid as %v", i, err)
+			}
+			m.UsesGC = true
+		}
+	}
+
+	if err := m.validateSubTypes(enabledFeatures); err != nil {
+		return err
+	}
+	if m.usesGCTypes() || m.usesAnyref() {
+		m.UsesGC = true
 	}
 
 	if err := m.validateStartSection(); err != nil {
@@ -276,7 +338,7 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 		return err
 	}
 
-	if err = m.validateGlobals(globals, uint32(len(functions)), MaximumGlobals); err != nil {
+	if err = m.validateGlobals(enabledFeatures, globals, uint32(len(functions)), MaximumGlobals); err != nil {
 		return err
 	}
 
@@ -284,7 +346,12 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 		return err
 	}
 
-	if err = m.validateExports(enabledFeatures, functions, globals, memory, tables); err != nil {
+	tags := m.AllTagTypes()
+	if err = m.validateTags(tags, enabledFeatures); err != nil {
+		return err
+	}
+
+	if err = m.validateExports(enabledFeatures, functions, globals, memory, tables, tags); err != nil {
 		return err
 	}
 
@@ -298,6 +365,14 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 		return err
 	}
 
+	if err = m.validateTableInits(globals, uint32(len(functions))); err != nil {
+		return err
+	}
+
+	if err = m.validateElementInits(globals, uint32(len(functions))); err != nil {
+		return err
+	}
+
 	if err = m.validateDataCountSection(); err != nil {
 		return err
 	}
@@ -320,17 +395,23 @@ func (m *Module) validateStartSection() error {
 	return nil
 }
 
-func (m *Module) validateGlobals(globals []GlobalType, numFuncts, maxGlobals uint32) error {
+func (m *Module) validateGlobals(enabledFeatures api.CoreFeatures, globals []GlobalType, numFuncts, maxGlobals uint32) error {
 	if uint32(len(globals)) > maxGlobals {
 		return fmt.Errorf("too many globals in a module")
 	}
 
 	// Global initialization constant expression can only reference the imported globals.
 	// See the note on https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#constant-expressions%E2%91%A0
-	importedGlobals := globals[:m.ImportGlobalCount]
+	//
+	// As of experimental.CoreFeaturesGC, it can also reference the globals defined before it.
+	// See https://github.com/WebAssembly/gc/blob/main/proposals/gc/MVP.md#constant-expressions
+	visibleGlobals := globals[:m.ImportGlobalCount]
 	for i := range m.GlobalSection {
+		if enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) {
+			visibleGlobals = globals[:m.ImportGlobalCount+Index(i)]
+		}
 		g := &m.GlobalSection[i]
-		if err := validateConstExpression(importedGlobals, numFuncts, &g.Init, g.Type.ValType); err != nil {
+		if err := m.validateTypedConstExpression(visibleGlobals, numFuncts, &g.Init, g.Type.ValType, heapRefOf(g.Type.ValType, g.Type.Ref)); err != nil {
 			return err
 		}
 	}
@@ -417,16 +498,41 @@ func (m *Module) declaredFunctionIndexes() (ret map[Index]struct{}, err error) {
 				return
 			}
 			ret[index] = struct{}{}
+		} else if g.Init.Opcode == OpcodeGCPrefix {
+			if err = g.Init.declareFunctionIndexes(ret); err != nil {
+				err = fmt.Errorf("%s[%d] failed to initialize: %w", SectionIDName(SectionIDGlobal), i, err)
+				return
+			}
+		}
+	}
+
+	for i := range m.TableSection {
+		if init := m.TableSection[i].Init; init != nil && init.Opcode == OpcodeRefFunc {
+			index, _, _ := leb128.LoadUint32(init.Data)
+			ret[index] = struct{}{}
+		} else if init != nil && init.Opcode == OpcodeGCPrefix {
+			if err = init.declareFunctionIndexes(ret); err != nil {
+				err = fmt.Errorf("%s[%d] failed to initialize: %w", SectionIDName(SectionIDTable), i, err)
+				return
+			}
 		}
 	}
 
 	for i := range m.ElementSection {
 		elem := &m.ElementSection[i]
 		for _, index := range elem.Init {
-			if index != ElementInitNullReference {
+			if _, ok := unwrapElementInitConstExpression(index); ok {
+				continue
+			} else if index != ElementInitNullReference {
 				ret[index] = struct{}{}
 			}
 		}
+		for ei := range elem.InitExpressions {
+			if err = elem.InitExpressions[ei].declareFunctionIndexes(ret); err != nil {
+				err = fmt.Errorf("%s[%d] failed to initialize: %w", SectionIDName(SectionIDElement), i, err)
+				return
+			}
+		}
 	}
 	return
 }
@@ -449,12 +555,22 @@ func (m *Module) funcDesc(sectionID SectionID, sectionIndex Index) string {
 	return fmt.Sprintf("%s[%d] export[%s]", sectionIDName, sectionIndex, strings.Join(exportNames, ","))
 }
 
-func (m *Module) validateMemory(memory *Memory, globals []GlobalType, _ api.CoreFeatures) error {
+func (m *Module) validateMemory(memory *Memory, globals []GlobalType, enabledFeatures api.CoreFeatures) error {
+	memoryCount := m.memoryCount()
+	if memoryCount > 1 {
+		if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesMultiMemory); err != nil {
+			return fmt.Errorf("multiple memories invalid as %w", err)
+		}
+	}
+
 	var activeElementCount int
 	for i := range m.DataSection {
 		d := &m.DataSection[i]
 		if !d.IsPassive() {
 			activeElementCount++
+			if d.MemoryIndex > 0 && d.MemoryIndex >= memoryCount {
+				return fmt.Errorf("unknown memory %d for %s[%d]", d.MemoryIndex, SectionIDName(SectionIDData), i)
+			}
 		}
 	}
 	if activeElementCount > 0 && memory == nil {
@@ -467,7 +583,11 @@ func (m *Module) validateMemory(memory *Memory, globals []GlobalType, _ api.Core
 	for i := range m.DataSection {
 		d := &m.DataSection[i]
 		if !d.IsPassive() {
-			if err := validateConstExpression(importedGlobals, 0, &d.OffsetExpression, ValueTypeI32); err != nil {
+			offsetType := ValueTypeI32
+			if mem := m.memoryAt(d.MemoryIndex); mem != nil && mem.IsMemory64 {
+				offsetType = ValueTypeI64
+			}
+			if err := validateConstExpression(importedGlobals, 0, &d.OffsetExpression, offsetType); err != nil {
 				return fmt.Errorf("calculate offset: %w", err)
 			}
 		}
@@ -486,6 +606,10 @@ func (m *Module) validateImports(enabledFeatures api.CoreFeatures) error {
 			if int(imp.DescFunc) >= len(m.TypeSection) {
 				return fmt.Errorf("invalid import[%q.%q] function: type index out of range", imp.Module, imp.Name)
 			}
+		case ExternTypeTag:
+			if int(imp.DescTag) >= len(m.TypeSection) {
+				return fmt.Errorf("invalid import[%q.%q] tag: type index out of range", imp.Module, imp.Name)
+			}
 		case ExternTypeGlobal:
 			if !imp.DescGlobal.Mutable {
 				continue
@@ -498,7 +622,7 @@ func (m *Module) validateImports(enabledFeatures api.CoreFeatures) error {
 	return nil
 }
 
-func (m *Module) validateExports(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table) error {
+func (m *Module) validateExports(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, tags []Index) error {
 	for i := range m.ExportSection {
 		exp := &m.ExportSection[i]
 		index := exp.Index
@@ -518,13 +642,17 @@ func (m *Module) validateExports(enabledFeatures api.CoreFeatures, functions []I
 				return fmt.Errorf("invalid export[%q] global[%d]: %w", exp.Name, index, err)
 			}
 		case ExternTypeMemory:
-			if index > 0 || memory == nil {
+			if memory == nil || (index > 0 && index >= m.memoryCount()) {
 				return fmt.Errorf("memory for export[%q] out of range", exp.Name)
 			}
 		case ExternTypeTable:
 			if index >= uint32(len(tables)) {
 				return fmt.Errorf("table for export[%q] out of range", exp.Name)
 			}
+		case ExternTypeTag:
+			if index >= uint32(len(tags)) {
+				return fmt.Errorf("tag for export[%q] out of range", exp.Name)
+			}
 		}
 	}
 	return nil
@@ -573,7 +701,7 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 			return fmt.Errorf("read reference type for ref.null: %w", io.ErrShortBuffer)
 		}
 		reftype := expr.Data[0]
-		if reftype != RefTypeFuncref && reftype != RefTypeExternref {
+		if reftype != RefTypeFuncref && reftype != RefTypeExternref && reftype != RefTypeExnref && reftype != ValueTypeAnyref {
 			return fmt.Errorf("invalid type for ref.null: 0x%x", reftype)
 		}
 		actualType = reftype
@@ -590,6 +718,16 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 			return fmt.Errorf("%s needs 16 bytes but was %d bytes", OpcodeVecV128ConstName, len(expr.Data))
 		}
 		actualType = ValueTypeV128
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		actualType, err = validateExtendedConstExpression(expr, func(id Index) (ValueType, error) {
+			if uint32(len(globals)) <= id {
+				return 0, fmt.Errorf("global index out of range")
+			}
+			return globals[id].ValType, nil
+		})
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("invalid opcode for const expression: 0x%x", expr.Opcode)
 	}
@@ -601,6 +739,81 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 	return nil
 }
 
+// validateExtendedConstExpression returns the type of expr, which has more than one instruction as allowed by
+// experimental.CoreFeaturesExtendedConst. globalType returns the type of the global at the index for global.get.
+func validateExtendedConstExpression(expr *ConstantExpression, globalType func(Index) (ValueType, error)) (ValueType, error) {
+	var stack []ValueType
+	err := expr.forEachExtendedInstruction(func(op Opcode, imm int64) error {
+		switch op {
+		case OpcodeI32Const:
+			stack = append(stack, ValueTypeI32)
+		case OpcodeI64Const:
+			stack = append(stack, ValueTypeI64)
+		case OpcodeGlobalGet:
+			vt, err := globalType(Index(imm))
+			if err != nil {
+				return err
+			}
+			stack = append(stack, vt)
+		default:
+			vt := ValueTypeI64
+			if op == OpcodeI32Add || op == OpcodeI32Sub || op == OpcodeI32Mul {
+				vt = ValueTypeI32
+			}
+			if n := len(stack); n < 2 || stack[n-1] != vt || stack[n-2] != vt {
+				return fmt.Errorf("type mismatch: %s requires two %s operands", InstructionName(op), ValueTypeName(vt))
+			}
+			stack = stack[:len(stack)-1]
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	} else if len(stack) != 1 {
+		return 0, fmt.Errorf("const expression must produce exactly one value but produced %d", len(stack))
+	}
+	return stack[0], nil
+}
+
+// forEachExtendedInstruction calls fn with each instruction of the extended constant expression in order, where the
+// last one is ConstantExpression.Opcode. imm is the signed value of i32.const and i64.const, or the index of
+// global.get.
+func (e *ConstantExpression) forEachExtendedInstruction(fn func(op Opcode, imm int64) error) error {
+	data := e.Data
+	for pc := uint64(0); pc < uint64(len(data)); {
+		op := data[pc]
+		pc++
+
+		var imm int64
+		var n uint64
+		var err error
+		switch op {
+		case OpcodeI32Const:
+			var v int32
+			v, n, err = leb128.LoadInt32(data[pc:])
+			imm = int64(v)
+		case OpcodeI64Const:
+			imm, n, err = leb128.LoadInt64(data[pc:])
+		case OpcodeGlobalGet:
+			var v uint32
+			v, n, err = leb128.LoadUint32(data[pc:])
+			imm = int64(v)
+		case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		default:
+			return fmt.Errorf("invalid opcode for const expression: 0x%x", op)
+		}
+		if err != nil {
+			return fmt.Errorf("read immediate of %s: %w", InstructionName(op), err)
+		}
+		pc += n
+
+		if err = fn(op, imm); err != nil {
+			return err
+		}
+	}
+	return fn(e.Opcode, 0)
+}
+
 func (m *Module) validateDataCountSection() (err error) {
 	if m.DataCountSection != nil && int(*m.DataCountSection) != len(m.DataSection) {
 		err = fmt.Errorf("data count section (%d) doesn't match the length of data section (%d)",
@@ -609,9 +822,7 @@ func (m *Module) validateDataCountSection() (err error) {
 	return
 }
 
-func (m *ModuleInstance) buildGlobals(module *Module, funcRefResolver func(funcIndex Index) Reference) {
-	importedGlobals := m.Globals[:module.ImportGlobalCount]
-
+func (m *ModuleInstance) buildGlobals(module *Module, funcRefResolver func(funcIndex Index) Reference) error {
 	me := m.Engine
 	engineOwnGlobal := me.OwnsGlobals()
 	for i := Index(0); i < Index(len(module.GlobalSection)); i++ {
@@ -621,10 +832,15 @@ func (m *ModuleInstance) buildGlobals(module *Module, funcRefResolver func(funcI
 			g.Me = me
 			g.Index = i + module.ImportGlobalCount
 		}
-		m.Globals[i+module.ImportGlobalCount] = g
 		g.Type = gs.Type
-		g.initialize(importedGlobals, &gs.Init, funcRefResolver)
+		// As of experimental.CoreFeaturesGC, the initializer can refer to the globals defined before it, otherwise
+		// the validation ensures that it only refers to the imported ones.
+		if err := m.initializeGlobal(g, m.Globals[:i+module.ImportGlobalCount], &gs.Init, funcRefResolver); err != nil {
+			return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDGlobal), i, err)
+		}
+		m.Globals[i+module.ImportGlobalCount] = g
 	}
+	return nil
 }
 
 func paramNames(localNames IndirectNameMap, funcIdx uint32, paramLen int) []string {
@@ -649,9 +865,25 @@ func paramNames(localNames IndirectNameMap, funcIdx uint32, paramLen int) []stri
 
 func (m *ModuleInstance) buildMemory(module *Module, allocator experimental.MemoryAllocator) {
 	memSec := module.MemorySection
-	if memSec != nil {
-		m.MemoryInstance = NewMemoryInstance(memSec, allocator, m.Engine)
-		m.MemoryInstance.definition = &module.MemoryDefinitionSection[0]
+	if memSec == nil {
+		return
+	}
+
+	// Defined memories follow the imported ones in the memory index space.
+	idx := module.ImportMemoryCount
+	mem := NewMemoryInstance(memSec, allocator, m.Engine)
+	mem.definition = &module.MemoryDefinitionSection[idx]
+	if idx == 0 {
+		m.MemoryInstance = mem
+	}
+	if m.Memories != nil {
+		m.Memories[idx] = mem
+	}
+	for _, memSec = range module.MultiMemorySection {
+		idx++
+		mem = NewMemoryInstance(memSec, allocator, m.Engine)
+		mem.definition = &module.MemoryDefinitionSection[idx]
+		m.Memories[idx] = mem
 	}
 }
 
@@ -677,6 +909,10 @@ type FunctionType struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#result-types%E2%91%A0
 	Results []ValueType
 
+	// ParamRefs and ResultRefs are the precise types of Params and Results, or nil if all of them are the
+	// DefaultHeapRef. See OptionalHeapRefs.
+	ParamRefs, ResultRefs []HeapRef
+
 	// string is cached as it is used both for String and key
 	string string
 
@@ -757,6 +993,8 @@ type Import struct {
 	DescMem *Memory
 	// DescGlobal is the inlined GlobalType when Type equals ExternTypeGlobal
 	DescGlobal GlobalType
+	// DescTag is the index in Module.TypeSection when Type equals ExternTypeTag
+	DescTag Index
 	// IndexPerType has the index of this import per ExternType.
 	IndexPerType Index
 }
@@ -768,27 +1006,48 @@ type Memory struct {
 	IsMaxEncoded bool
 	// IsShared true if the memory is shared for access from multiple agents.
 	IsShared bool
+	// IsMemory64 true if the memory is addressed with i64 as per experimental.CoreFeaturesMemory64.
+	IsMemory64 bool
+	// IsPageSizeEncoded true if the page size is encoded in the original binary as per
+	// experimental.CoreFeaturesCustomPageSizes. In that case, Min, Cap and Max are in the unit of 1<<PageSizeLog2
+	// bytes instead of MemoryPageSize.
+	IsPageSizeEncoded bool
+	// PageSizeLog2 is the log2 of the page size, which is only valid if IsPageSizeEncoded.
+	PageSizeLog2 uint32
 }
 
-// Validate ensures values assigned to Min, Cap and Max are within valid thresholds.
+// PageSizeInBits returns the log2 of the page size of this memory, which is MemoryPageSizeInBits unless
+// IsPageSizeEncoded.
+func (m *Memory) PageSizeInBits() uint32 {
+	if m.IsPageSizeEncoded {
+		return m.PageSizeLog2
+	}
+	return MemoryPageSizeInBits
+}
+
+// Validate ensures values assigned to Min, Cap and Max are within valid thresholds, where memoryLimitPages is in the
+// unit of the page size of this memory.
 func (m *Memory) Validate(memoryLimitPages uint32) error {
 	min, capacity, max := m.Min, m.Cap, m.Max
+	unit := func(pages uint32) string {
+		return bytesToUnitOfBytes(uint64(pages) << m.PageSizeInBits())
+	}
 
 	if max > memoryLimitPages {
 		return fmt.Errorf("max %d pages (%s) over limit of %d pages (%s)",
-			max, PagesToUnitOfBytes(max), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+			max, unit(max), memoryLimitPages, unit(memoryLimitPages))
 	} else if min > memoryLimitPages {
 		return fmt.Errorf("min %d pages (%s) over limit of %d pages (%s)",
-			min, PagesToUnitOfBytes(min), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+			min, unit(min), memoryLimitPages, unit(memoryLimitPages))
 	} else if min > max {
 		return fmt.Errorf("min %d pages (%s) > max %d pages (%s)",
-			min, PagesToUnitOfBytes(min), max, PagesToUnitOfBytes(max))
+			min, unit(min), max, unit(max))
 	} else if capacity < min {
 		return fmt.Errorf("capacity %d pages (%s) less than minimum %d pages (%s)",
-			capacity, PagesToUnitOfBytes(capacity), min, PagesToUnitOfBytes(min))
+			capacity, unit(capacity), min, unit(min))
 	} else if capacity > memoryLimitPages {
 		return fmt.Errorf("capacity %d pages (%s) over limit of %d pages (%s)",
-			capacity, PagesToUnitOfBytes(capacity), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+			capacity, unit(capacity), memoryLimitPages, unit(memoryLimitPages))
 	}
 	return nil
 }
@@ -796,6 +1055,8 @@ func (m *Memory) Validate(memoryLimitPages uint32) error {
 type GlobalType struct {
 	ValType ValueType
 	Mutable bool
+	// Ref is the precise type of ValType, or nil if it is the DefaultHeapRef.
+	Ref *HeapRef
 }
 
 type Global struct {
@@ -803,6 +1064,15 @@ type Global struct {
 	Init ConstantExpression
 }
 
+// ConstantExpression is a constant expression, which is a single instruction with its immediates in Data, e.g. the
+// LEB128 encoded value of OpcodeI32Const.
+//
+// When experimental.CoreFeaturesExtendedConst is enabled, the expression can have more than one instruction. In that
+// case, Opcode is the last instruction, which is one of the arithmetic ones such as OpcodeI32Add, and Data is the
+// encoded instructions before it.
+//
+// When experimental.CoreFeaturesGC is enabled, the expression can allocate objects, e.g. with struct.new. In that
+// case, Opcode is OpcodeGCPrefix, and Data is all the encoded instructions except for the last OpcodeEnd.
 type ConstantExpression struct {
 	Opcode Opcode
 	Data   []byte
@@ -828,6 +1098,9 @@ type Code struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-local
 	LocalTypes []ValueType
 
+	// LocalRefs are the precise types of LocalTypes, or nil if all of them are the DefaultHeapRef.
+	LocalRefs []HeapRef
+
 	// Body is a sequence of expressions ending in OpcodeEnd
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-expr
 	Body []byte
@@ -849,6 +1122,9 @@ type DataSegment struct {
 	OffsetExpression ConstantExpression
 	Init             []byte
 	Passive          bool
+	// MemoryIndex is the memory an active segment is applied to, which can only be non-zero when
+	// experimental.CoreFeaturesMultiMemory is enabled.
+	MemoryIndex Index
 }
 
 // IsPassive returns true if this data segment is "passive" in the sense that memory offset and
@@ -952,11 +1228,8 @@ func (m *Module) AllDeclarations() (functions []Index, globals []GlobalType, mem
 		g := &m.GlobalSection[i]
 		globals = append(globals, g.Type)
 	}
-	if m.MemorySection != nil {
-		if memory != nil { // shouldn't be possible due to Validate
-			err = errors.New("at most one table allowed in module")
-			return
-		}
+	if memory == nil {
+		// memory is the one at index zero, which can only be defined when no memory is imported.
 		memory = m.MemorySection
 	}
 	if m.TableSection != nil {
@@ -965,6 +1238,54 @@ func (m *Module) AllDeclarations() (functions []Index, globals []GlobalType, mem
 	return
 }
 
+// AllMemories returns all memories in the memory index space, beginning with imported ones.
+func (m *Module) AllMemories() (memories []*Memory) {
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeMemory {
+			memories = append(memories, imp.DescMem)
+		}
+	}
+	if m.MemorySection != nil {
+		memories = append(memories, m.MemorySection)
+	}
+	return append(memories, m.MultiMemorySection...)
+}
+
+// memoryAt returns the memory at the given index of the memory index space, or nil if it doesn't exist.
+func (m *Module) memoryAt(idx Index) *Memory {
+	if idx < m.ImportMemoryCount {
+		for i := range m.ImportSection {
+			if imp := &m.ImportSection[i]; imp.Type == ExternTypeMemory {
+				if idx == 0 {
+					return imp.DescMem
+				}
+				idx--
+			}
+		}
+		return nil
+	}
+	idx -= m.ImportMemoryCount
+	if m.MemorySection != nil {
+		if idx == 0 {
+			return m.MemorySection
+		}
+		idx--
+	}
+	if idx < Index(len(m.MultiMemorySection)) {
+		return m.MultiMemorySection[idx]
+	}
+	return nil
+}
+
+// memoryCount returns the number of memories in the memory index space.
+func (m *Module) memoryCount() Index {
+	count := m.ImportMemoryCount + Index(len(m.MultiMemorySection))
+	if m.MemorySection != nil {
+		count++
+	}
+	return count
+}
+
 // SectionID identifies the sections of a Module in the WebAssembly 1.0 (20191205) Binary Format.
 //
 // Note: these are defined in the wasm package, instead of the binary package, as a key per section is needed regardless
@@ -993,6 +1314,11 @@ const (
 	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#data-count-section
 	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/appendix/changes.html#bulk-memory-and-table-instructions
 	SectionIDDataCount
+
+	// SectionIDTag may exist with experimental.CoreFeaturesExceptionHandling enabled.
+	//
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#tag-section
+	SectionIDTag
 )
 
 // SectionIDName returns the canonical name of a module section.
@@ -1025,6 +1351,8 @@ func SectionIDName(sectionID SectionID) string {
 		return "data"
 	case SectionIDDataCount:
 		return "data_count"
+	case SectionIDTag:
+		return "tag"
 	}
 	return "unknown"
 }
@@ -1042,6 +1370,17 @@ const (
 	// TODO: ValueTypeFuncref is not exposed in the api pkg yet.
 	ValueTypeFuncref   ValueType = 0x70
 	ValueTypeExternref           = api.ValueTypeExternref
+	// ValueTypeExnref is a reference to an exception, and only valid with
+	// experimental.CoreFeaturesExceptionHandling.
+	ValueTypeExnref ValueType = 0x69
+	// ValueTypeAnyref is a reference to an object of experimental.CoreFeaturesGC, which is the type of all the
+	// reference types in the hierarchy of the "any" heap type.
+	//
+	// See HeapTypeValueType
+	ValueTypeAnyref ValueType = 0x6e
+	// ValueTypeContref is a reference to a continuation, and only valid with
+	// experimental.CoreFeaturesStackSwitching.
+	ValueTypeContref ValueType = 0x68
 )
 
 // ValueTypeName is an alias of api.ValueTypeName defined to simplify imports.
@@ -1050,12 +1389,19 @@ func ValueTypeName(t ValueType) string {
 		return "funcref"
 	} else if t == ValueTypeV128 {
 		return "v128"
+	} else if t == ValueTypeExnref {
+		return "exnref"
+	} else if t == ValueTypeAnyref {
+		return "anyref"
+	} else if t == ValueTypeContref {
+		return "contref"
 	}
 	return api.ValueTypeName(t)
 }
 
 func isReferenceValueType(vt ValueType) bool {
-	return vt == ValueTypeExternref || vt == ValueTypeFuncref
+	return vt == ValueTypeExternref || vt == ValueTypeFuncref || vt == ValueTypeExnref || vt == ValueTypeAnyref ||
+		vt == ValueTypeContref
 }
 
 // ExternType is an alias of api.ExternType defined to simplify imports.
@@ -1070,6 +1416,8 @@ const (
 	ExternTypeMemoryName = api.ExternTypeMemoryName
 	ExternTypeGlobal     = api.ExternTypeGlobal
 	ExternTypeGlobalName = api.ExternTypeGlobalName
+	ExternTypeTag        = api.ExternTypeTag
+	ExternTypeTagName    = api.ExternTypeTagName
 )
 
 // ExternTypeName is an alias of api.ExternTypeName defined to simplify imports.
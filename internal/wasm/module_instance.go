@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -19,6 +20,9 @@ func (m *ModuleInstance) FailIfClosed() (err error) {
 			// and the closure of resources have been deferred here.
 			_ = m.ensureResourcesClosed(context.Background())
 		}
+		if signal := uint8(closed >> exitCodeSignalShift); signal != 0 {
+			return sys.NewSignalExitError(signal)
+		}
 		return sys.NewExitError(uint32(closed >> 32)) // Unpack the high order bits as the exit code.
 	}
 	return nil
@@ -105,6 +109,31 @@ func (m *ModuleInstance) CloseWithExitCode(ctx context.Context, exitCode uint32)
 	return m.ensureResourcesClosed(ctx)
 }
 
+// CloseWithSignal is like CloseWithExitCode, except the module is terminated
+// by the signal, so that the sys.ExitError is the one of
+// sys.NewSignalExitError.
+func (m *ModuleInstance) CloseWithSignal(ctx context.Context, signal uint8) (err error) {
+	exitCode := sys.NewSignalExitError(signal).ExitCode()
+	if !m.setExitCode(exitCode, exitCodeFlagResourceClosed|exitCodeFlag(signal)<<exitCodeSignalShift) {
+		return nil // not an error to have already closed
+	}
+	_ = m.s.deleteModule(m)
+	return m.ensureResourcesClosed(ctx)
+}
+
+// AddPendingSignal adds the signal to the set of pending signals, which the
+// guest takes with TakePendingSignals. Like in POSIX, a signal caught again
+// before it's taken is only pending once.
+func (m *ModuleInstance) AddPendingSignal(signal uint8) {
+	m.pendingSignals.Or(1 << signal)
+}
+
+// TakePendingSignals returns the set of pending signals, where bit N is the
+// signal N, and clears it.
+func (m *ModuleInstance) TakePendingSignals() uint32 {
+	return m.pendingSignals.Swap(0)
+}
+
 // IsClosed implements the same method as documented on api.Module.
 func (m *ModuleInstance) IsClosed() bool {
 	return m.Closed.Load() != 0
@@ -130,6 +159,10 @@ type exitCodeFlag = uint64
 
 const exitCodeFlagMask = 0xff
 
+// exitCodeSignalShift is the shift of the signal which terminated the module,
+// if any, stored in the bits between the flags and the exit code.
+const exitCodeSignalShift = 8
+
 const (
 	// exitCodeFlagResourceClosed indicates that the module was closed and resources were already closed.
 	exitCodeFlagResourceClosed = 1 << iota
@@ -142,26 +175,62 @@ func (m *ModuleInstance) setExitCode(exitCode uint32, flag exitCodeFlag) bool {
 	return m.Closed.CompareAndSwap(0, closed)
 }
 
+// AddCloseNotifier adds the notifier to call when the module is closed, after
+// any CloseNotifier already set. This is safe to call concurrently with
+// closing the module, and returns false if the module is already closed, in
+// which case the notifier is never called.
+func (m *ModuleInstance) AddCloseNotifier(notifier experimental.CloseNotifier) bool {
+	m.closeNotifierMu.Lock()
+	defer m.closeNotifierMu.Unlock()
+	if m.IsClosed() {
+		return false
+	}
+	if prev := m.CloseNotifier; prev != nil {
+		m.CloseNotifier = experimental.CloseNotifyFunc(func(ctx context.Context, exitCode uint32) {
+			prev.CloseNotify(ctx, exitCode)
+			notifier.CloseNotify(ctx, exitCode)
+		})
+	} else {
+		m.CloseNotifier = notifier
+	}
+	return true
+}
+
 // ensureResourcesClosed ensures that resources assigned to ModuleInstance is released.
 // Only one call will happen per module, due to external atomic guards on Closed.
 func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error) {
-	if closeNotifier := m.CloseNotifier; closeNotifier != nil { // experimental
+	m.closeNotifierMu.Lock()
+	closeNotifier := m.CloseNotifier
+	m.CloseNotifier = nil
+	m.closeNotifierMu.Unlock()
+	if closeNotifier != nil { // experimental
 		closeNotifier.CloseNotify(ctx, uint32(m.Closed.Load()>>32))
-		m.CloseNotifier = nil
 	}
 
 	if sysCtx := m.Sys; sysCtx != nil { // nil if from HostModuleBuilder
-		err = sysCtx.FS().Close()
+		if !m.Thread { // The module which spawned the thread closes it.
+			err = sysCtx.FS().Close()
+		}
 		m.Sys = nil
 	}
 
-	if mem := m.MemoryInstance; mem != nil {
+	if mem := m.MemoryInstance; mem != nil && !(m.Thread && m.Source.ImportMemoryCount > 0) {
 		if mem.expBuffer != nil {
 			mem.expBuffer.Free()
 			mem.expBuffer = nil
 		}
 	}
 
+	// Free any other memory defined by this module, where index zero was handled above.
+	if m.Memories != nil {
+		for i := max(m.Source.ImportMemoryCount, 1); i < Index(len(m.Memories)); i++ {
+			if mem := m.Memories[i]; mem.expBuffer != nil {
+				mem.expBuffer.Free()
+				mem.expBuffer = nil
+			}
+		}
+	}
+
 	if m.CodeCloser != nil {
 		if e := m.CodeCloser.Close(ctx); err == nil {
 			err = e
@@ -176,29 +245,33 @@ func (m *ModuleInstance) Memory() api.Memory {
 	return m.MemoryInstance
 }
 
+// MemoryAt returns the memory at the given index in the memory index space.
+func (m *ModuleInstance) MemoryAt(index Index) *MemoryInstance {
+	if index == 0 {
+		return m.MemoryInstance
+	}
+	return m.Memories[index]
+}
+
 // ExportedMemory implements the same method as documented on api.Module.
 func (m *ModuleInstance) ExportedMemory(name string) api.Memory {
-	_, err := m.getExport(name, ExternTypeMemory)
+	exp, err := m.getExport(name, ExternTypeMemory)
 	if err != nil {
 		return nil
 	}
-	// We Assume that we have at most one memory.
-	return m.MemoryInstance
+	return m.MemoryAt(exp.Index)
 }
 
 // ExportedMemoryDefinitions implements the same method as documented on
 // api.Module.
 func (m *ModuleInstance) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
-	// Special case as we currently only support one memory.
-	if mem := m.MemoryInstance; mem != nil {
-		// Now, find out if it is exported
-		for name, exp := range m.Exports {
-			if exp.Type == ExternTypeMemory {
-				return map[string]api.MemoryDefinition{name: mem.definition}
-			}
+	ret := map[string]api.MemoryDefinition{}
+	for name, exp := range m.Exports {
+		if exp.Type == ExternTypeMemory {
+			ret[name] = m.MemoryAt(exp.Index).definition
 		}
 	}
-	return map[string]api.MemoryDefinition{}
+	return ret
 }
 
 // ExportedFunction implements the same method as documented on api.Module.
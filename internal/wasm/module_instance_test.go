@@ -14,6 +14,7 @@ import (
 	testfs "github.com/tetratelabs/wazero/internal/testing/fs"
 	"github.com/tetratelabs/wazero/internal/testing/hammer"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	sysapi "github.com/tetratelabs/wazero/sys"
 )
 
 func TestModuleInstance_String(t *testing.T) {
@@ -79,6 +80,13 @@ func TestModuleInstance_Close(t *testing.T) {
 			},
 			expectedClosed: uint64(255)<<32 + 1,
 		},
+		{
+			name: "CloseWithSignal(2)",
+			closer: func(ctx context.Context, m *ModuleInstance) error {
+				return m.CloseWithSignal(ctx, 2)
+			},
+			expectedClosed: uint64(130)<<32 + 2<<8 + 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -108,6 +116,15 @@ func TestModuleInstance_Close(t *testing.T) {
 		})
 	}
 
+	t.Run("FailIfClosed after CloseWithSignal", func(t *testing.T) {
+		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, m.CloseWithSignal(testCtx, 15))
+
+		err = m.FailIfClosed()
+		require.Equal(t, sysapi.NewSignalExitError(15), err)
+	})
+
 	t.Run("calls Context.Close()", func(t *testing.T) {
 		testFS := &sysfs.AdaptFS{FS: testfs.FS{"foo": &testfs.File{}}}
 		sysCtx := internalsys.DefaultContext(testFS)
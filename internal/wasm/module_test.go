@@ -88,7 +88,7 @@ func TestMemory_Validate(t *testing.T) {
 		{
 			name:        "cap > maxLimit",
 			mem:         &Memory{Min: 2, Cap: math.MaxUint32, Max: 2},
-			expectedErr: "capacity 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "capacity 4294967295 pages (255 Ti) over limit of 65536 pages (4 Gi)",
 		},
 		{
 			name:        "max < min",
@@ -98,12 +98,12 @@ func TestMemory_Validate(t *testing.T) {
 		{
 			name:        "min > limit",
 			mem:         &Memory{Min: math.MaxUint32},
-			expectedErr: "min 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "min 4294967295 pages (255 Ti) over limit of 65536 pages (4 Gi)",
 		},
 		{
 			name:        "max > limit",
 			mem:         &Memory{Max: math.MaxUint32, IsMaxEncoded: true},
-			expectedErr: "max 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "max 4294967295 pages (255 Ti) over limit of 65536 pages (4 Gi)",
 		},
 	}
 
@@ -323,6 +323,87 @@ func TestValidateConstExpression(t *testing.T) {
 			}
 		})
 	})
+	t.Run("extended", func(t *testing.T) {
+		globals := []GlobalType{{ValType: ValueTypeI32}, {ValType: ValueTypeI64}}
+		tests := []struct {
+			name        string
+			expr        *ConstantExpression
+			vt          ValueType
+			expectedErr string
+		}{
+			{
+				name: "i32",
+				// (i32.sub (i32.mul (global.get 0) (i32.const 4)) (i32.const 1))
+				expr: &ConstantExpression{
+					Opcode: OpcodeI32Sub,
+					Data:   []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 4, OpcodeI32Mul, OpcodeI32Const, 1},
+				},
+				vt: ValueTypeI32,
+			},
+			{
+				name: "i64",
+				expr: &ConstantExpression{Opcode: OpcodeI64Add, Data: []byte{OpcodeI64Const, 1, OpcodeGlobalGet, 1}},
+				vt:   ValueTypeI64,
+			},
+			{
+				name:        "result type mismatch",
+				expr:        &ConstantExpression{Opcode: OpcodeI64Add, Data: []byte{OpcodeI64Const, 1, OpcodeGlobalGet, 1}},
+				vt:          ValueTypeI32,
+				expectedErr: "const expression type mismatch expected i32 but got i64",
+			},
+			{
+				name:        "operand type mismatch",
+				expr:        &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeGlobalGet, 1}},
+				vt:          ValueTypeI32,
+				expectedErr: "type mismatch: i32.add requires two i32 operands",
+			},
+			{
+				name:        "missing operand",
+				expr:        &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1}},
+				vt:          ValueTypeI32,
+				expectedErr: "type mismatch: i32.add requires two i32 operands",
+			},
+			{
+				name: "too many values",
+				expr: &ConstantExpression{
+					Opcode: OpcodeI32Add,
+					Data:   []byte{OpcodeI32Const, 1, OpcodeI32Const, 1, OpcodeI32Const, 1},
+				},
+				vt:          ValueTypeI32,
+				expectedErr: "const expression must produce exactly one value but produced 2",
+			},
+			{
+				name:        "global index out of range",
+				expr:        &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeGlobalGet, 2}},
+				vt:          ValueTypeI32,
+				expectedErr: "global index out of range",
+			},
+			{
+				name:        "invalid opcode",
+				expr:        &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeNop}},
+				vt:          ValueTypeI32,
+				expectedErr: "invalid opcode for const expression: 0x1",
+			},
+			{
+				name:        "invalid immediate",
+				expr:        &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeI32Const}},
+				vt:          ValueTypeI32,
+				expectedErr: "read immediate of i32.const: readByte failed: EOF",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				err := validateConstExpression(globals, 0, tc.expr, tc.vt)
+				if tc.expectedErr != "" {
+					require.EqualError(t, err, tc.expectedErr)
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	})
 }
 
 func TestModule_Validate_Errors(t *testing.T) {
@@ -396,7 +477,7 @@ func TestModule_validateStartSection(t *testing.T) {
 func TestModule_validateGlobals(t *testing.T) {
 	t.Run("too many globals", func(t *testing.T) {
 		m := Module{}
-		err := m.validateGlobals(make([]GlobalType, 10), 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, make([]GlobalType, 10), 0, 9)
 		require.Error(t, err)
 		require.EqualError(t, err, "too many globals in a module")
 	})
@@ -408,7 +489,7 @@ func TestModule_validateGlobals(t *testing.T) {
 				Init: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{1}},
 			},
 		}}
-		err := m.validateGlobals(nil, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, nil, 0, 9)
 		require.Error(t, err)
 		require.EqualError(t, err, "global index out of range")
 	})
@@ -419,7 +500,7 @@ func TestModule_validateGlobals(t *testing.T) {
 				Init: ConstantExpression{Opcode: OpcodeUnreachable},
 			},
 		}}
-		err := m.validateGlobals(nil, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, nil, 0, 9)
 		require.Error(t, err)
 		require.EqualError(t, err, "invalid opcode for const expression: 0x0")
 	})
@@ -430,7 +511,7 @@ func TestModule_validateGlobals(t *testing.T) {
 				Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const0},
 			},
 		}}
-		err := m.validateGlobals(nil, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, nil, 0, 9)
 		require.NoError(t, err)
 	})
 	t.Run("ok with imported global", func(t *testing.T) {
@@ -449,9 +530,74 @@ func TestModule_validateGlobals(t *testing.T) {
 			{ValType: ValueTypeI32}, // Imported one.
 			{},                      // the local one trying to validate.
 		}
-		err := m.validateGlobals(globalDeclarations, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, globalDeclarations, 0, 9)
 		require.NoError(t, err)
 	})
+	t.Run("gc", func(t *testing.T) {
+		structRef := &HeapRef{HeapType: 0, NonNullable: true}
+		m := Module{
+			TypeSection: []FunctionType{{}},
+			SubTypes:    []SubType{{Kind: CompositeTypeKindStruct, Final: true, Fields: []FieldType{{Type: ValueTypeI32}}}},
+			GlobalSection: []Global{
+				{
+					Type: GlobalType{ValType: ValueTypeAnyref, Ref: structRef},
+					// (struct.new 0 (i32.const 1))
+					Init: ConstantExpression{Opcode: OpcodeGCPrefix, Data: []byte{
+						OpcodeI32Const, 1, OpcodeGCPrefix, OpcodeGCStructNew, 0,
+					}},
+				},
+				{
+					Type: GlobalType{ValType: ValueTypeAnyref},
+					// Referring to the preceding global, which is not imported.
+					Init: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{0}},
+				},
+			},
+		}
+		globalDeclarations := []GlobalType{m.GlobalSection[0].Type, m.GlobalSection[1].Type}
+
+		err := m.validateGlobals(api.CoreFeaturesV2|experimental.CoreFeaturesGC, globalDeclarations, 0, 9)
+		require.NoError(t, err)
+
+		err = m.validateGlobals(api.CoreFeaturesV2, globalDeclarations, 0, 9)
+		require.EqualError(t, err, "global index out of range")
+
+		m.GlobalSection[0].Init.Data[0] = OpcodeI64Const
+		err = m.validateGlobals(api.CoreFeaturesV2|experimental.CoreFeaturesGC, globalDeclarations, 0, 9)
+		require.EqualError(t, err, "cannot pop the operand for struct.new: type mismatch: expected i32, but was i64")
+	})
+}
+
+func TestModule_validateElementInits(t *testing.T) {
+	i31Ref := &HeapRef{HeapType: HeapTypeI31, NonNullable: true}
+	newModule := func() *Module {
+		return &Module{
+			ElementSection: []ElementSegment{{
+				Type: ValueTypeAnyref,
+				Ref:  i31Ref,
+				Init: []Index{WrapConstExpressionAsElementInit(0), WrapGlobalIndexAsElementInit(0)},
+				// (ref.i31 (i32.const 1))
+				InitExpressions: []ConstantExpression{{Opcode: OpcodeGCPrefix, Data: []byte{
+					OpcodeI32Const, 1, OpcodeGCPrefix, OpcodeGCRefI31,
+				}}},
+				Mode: ElementModePassive,
+			}},
+		}
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		err := newModule().validateElementInits([]GlobalType{{ValType: ValueTypeAnyref, Ref: i31Ref}}, 0)
+		require.NoError(t, err)
+	})
+	t.Run("global type mismatch", func(t *testing.T) {
+		err := newModule().validateElementInits([]GlobalType{{ValType: ValueTypeAnyref}}, 0)
+		require.EqualError(t, err, "element[0].init[1] global index 0 type mismatch: want (ref i31)")
+	})
+	t.Run("expression type mismatch", func(t *testing.T) {
+		m := newModule()
+		m.ElementSection[0].InitExpressions[0].Data[0] = OpcodeI64Const
+		err := m.validateElementInits([]GlobalType{{ValType: ValueTypeAnyref, Ref: i31Ref}}, 0)
+		require.EqualError(t, err, "element[0].init: cannot pop the operand for ref.i31: type mismatch: expected i32, but was i64")
+	})
 }
 
 func TestModule_validateFunctions(t *testing.T) {
@@ -567,6 +713,24 @@ func TestModule_validateMemory(t *testing.T) {
 		err := m.validateMemory(&Memory{}, nil, api.CoreFeaturesV1)
 		require.NoError(t, err)
 	})
+	t.Run("multiple memories disabled", func(t *testing.T) {
+		m := Module{MemorySection: &Memory{}, MultiMemorySection: []*Memory{{}}}
+		err := m.validateMemory(m.MemorySection, nil, api.CoreFeaturesV2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "multiple memories invalid as")
+	})
+	t.Run("unknown memory for data segment", func(t *testing.T) {
+		m := Module{
+			MemorySection:      &Memory{},
+			MultiMemorySection: []*Memory{{}},
+			DataSection: []DataSegment{{
+				OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+				MemoryIndex:      2,
+			}},
+		}
+		err := m.validateMemory(m.MemorySection, nil, api.CoreFeaturesV2|experimental.CoreFeaturesMultiMemory)
+		require.EqualError(t, err, "unknown memory 2 for data[0]")
+	})
 }
 
 func TestModule_validateImports(t *testing.T) {
@@ -733,7 +897,7 @@ func TestModule_validateExports(t *testing.T) {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			m := Module{ExportSection: tc.exportSection}
-			err := m.validateExports(tc.enabledFeatures, tc.functions, tc.globals, tc.memory, tc.tables)
+			err := m.validateExports(tc.enabledFeatures, tc.functions, tc.globals, tc.memory, tc.tables, nil)
 			if tc.expectedErr != "" {
 				require.EqualError(t, err, tc.expectedErr)
 			} else {
@@ -816,10 +980,11 @@ func TestModule_buildGlobals(t *testing.T) {
 
 	mi.Globals[0], mi.Globals[1] = imported[0], imported[1]
 
-	mi.buildGlobals(m, func(funcIndex Index) Reference {
+	err := mi.buildGlobals(m, func(funcIndex Index) Reference {
 		require.Equal(t, localFuncRefInstructionIndex, funcIndex)
 		return 0x99999
 	})
+	require.NoError(t, err)
 	expectedGlobals := []*GlobalInstance{
 		imported[0], imported[1],
 		{Type: GlobalType{ValType: ValueTypeF64, Mutable: true}, Val: api.EncodeF64(math.MaxFloat64)},
@@ -14,6 +14,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -62,6 +63,9 @@ type (
 
 		// mux is used to guard the fields from concurrent access.
 		mux sync.RWMutex
+
+		// gcHeap holds the objects of experimental.CoreFeaturesGC, and is nil unless it is enabled.
+		gcHeap *GCHeap
 	}
 
 	// ModuleInstance represents instantiated wasm module.
@@ -78,7 +82,14 @@ type (
 		Exports        map[string]*Export
 		Globals        []*GlobalInstance
 		MemoryInstance *MemoryInstance
-		Tables         []*TableInstance
+		// Memories are only non-nil when experimental.CoreFeaturesMultiMemory is enabled, and the module
+		// imports or defines more than one memory. This is the entire memory index space, so Memories[0]
+		// is MemoryInstance.
+		Memories []*MemoryInstance
+		Tables   []*TableInstance
+		// Tags are only non-nil when experimental.CoreFeaturesExceptionHandling is enabled,
+		// and the module imports or defines tags.
+		Tags []*TagInstance
 
 		// Engine implements function calls for this module.
 		Engine ModuleEngine
@@ -126,7 +137,19 @@ type (
 		Source *Module
 
 		// CloseNotifier is an experimental hook called once on close.
+		//
+		// Note: Once the module is published, this is only accessed with closeNotifierMu held. See AddCloseNotifier.
 		CloseNotifier experimental.CloseNotifier
+		// closeNotifierMu guards CloseNotifier against concurrent closes.
+		closeNotifierMu sync.Mutex
+
+		// Thread is true when this module is instantiated by InstantiateThread. It shares Sys and the imported
+		// memories with the module which spawned it, so closing it releases neither.
+		Thread bool
+
+		// pendingSignals is the set of signals caught for the guest, which it hasn't taken yet, where bit N is the
+		// signal N of wasi_snapshot_preview1. See AddPendingSignal.
+		pendingSignals atomic.Uint32
 	}
 
 	// DataInstance holds bytes corresponding to the data segment in a module.
@@ -171,30 +194,40 @@ func (m *ModuleInstance) GetFunctionTypeID(t *FunctionType) FunctionTypeID {
 	return id
 }
 
-func (m *ModuleInstance) buildElementInstances(elements []ElementSegment) {
+func (m *ModuleInstance) buildElementInstances(elements []ElementSegment) (err error) {
 	m.ElementInstances = make([][]Reference, len(elements))
-	for i, elm := range elements {
-		if elm.Type == RefTypeFuncref && elm.Mode == ElementModePassive {
+	for i := range elements {
+		elm := &elements[i]
+		if (elm.Type == RefTypeFuncref || elm.Type == ValueTypeAnyref) && elm.Mode == ElementModePassive {
 			// Only passive elements can be access as element instances.
 			// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/syntax/modules.html#element-segments
 			inits := elm.Init
 			inst := make([]Reference, len(inits))
 			m.ElementInstances[i] = inst
 			for j, idx := range inits {
-				if index, ok := unwrapElementInitGlobalReference(idx); ok {
-					global := m.Globals[index]
-					inst[j] = Reference(global.Val)
-				} else {
-					if idx != ElementInitNullReference {
-						inst[j] = m.Engine.FunctionInstanceReference(idx)
-					}
+				if inst[j], err = m.elementInitReference(elm, idx); err != nil {
+					return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDElement), i, err)
 				}
 			}
 		}
 	}
+	return
 }
 
-func (m *ModuleInstance) applyElements(elems []ElementSegment) {
+// elementInitReference returns the reference of the item of ElementSegment.Init.
+func (m *ModuleInstance) elementInitReference(elem *ElementSegment, init Index) (Reference, error) {
+	if init == ElementInitNullReference {
+		return 0, nil
+	} else if index, ok := unwrapElementInitGlobalReference(init); ok {
+		return Reference(m.Globals[index].Val), nil
+	} else if index, ok = unwrapElementInitConstExpression(init); ok {
+		ref, _, err := m.executeGCConstExpression(m.Globals, &elem.InitExpressions[index], m.Engine.FunctionInstanceReference)
+		return Reference(ref), err
+	}
+	return m.Engine.FunctionInstanceReference(init), nil
+}
+
+func (m *ModuleInstance) applyElements(elems []ElementSegment) error {
 	for elemI := range elems {
 		elem := &elems[elemI]
 		if !elem.IsActive() ||
@@ -202,17 +235,7 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 			len(elem.Init) == 0 {
 			continue
 		}
-		var offset uint32
-		if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-			// Ignore error as it's already validated.
-			globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-			global := m.Globals[globalIdx]
-			offset = uint32(global.Val)
-		} else {
-			// Ignore error as it's already validated.
-			o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-			offset = uint32(o)
-		}
+		offset := uint32(executeConstExpressionI32(m.Globals, &elem.OffsetExpr))
 
 		table := m.Tables[elem.TableIndex]
 		references := table.References
@@ -224,7 +247,7 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 			//
 			// In wazero, we ignore it since in any way, the instantiated module and engines are fine and can be used
 			// for function invocations.
-			return
+			return nil
 		}
 
 		if table.Type == RefTypeExternref {
@@ -236,18 +259,15 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 				if init == ElementInitNullReference {
 					continue
 				}
-
-				var ref Reference
-				if index, ok := unwrapElementInitGlobalReference(init); ok {
-					global := m.Globals[index]
-					ref = Reference(global.Val)
-				} else {
-					ref = m.Engine.FunctionInstanceReference(index)
+				ref, err := m.elementInitReference(elem, init)
+				if err != nil {
+					return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDElement), elemI, err)
 				}
 				references[offset+uint32(i)] = ref
 			}
 		}
 	}
+	return nil
 }
 
 // validateData ensures that data segments are valid in terms of memory boundary.
@@ -256,9 +276,7 @@ func (m *ModuleInstance) validateData(data []DataSegment) (err error) {
 	for i := range data {
 		d := &data[i]
 		if !d.IsPassive() {
-			offset := int(executeConstExpressionI32(m.Globals, &d.OffsetExpression))
-			ceil := offset + len(d.Init)
-			if offset < 0 || ceil > len(m.MemoryInstance.Buffer) {
+			if _, _, ok := m.dataOffset(d); !ok {
 				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
 			}
 		}
@@ -275,16 +293,34 @@ func (m *ModuleInstance) applyData(data []DataSegment) error {
 		d := &data[i]
 		m.DataInstances[i] = d.Init
 		if !d.IsPassive() {
-			offset := executeConstExpressionI32(m.Globals, &d.OffsetExpression)
-			if offset < 0 || int(offset)+len(d.Init) > len(m.MemoryInstance.Buffer) {
+			mem, offset, ok := m.dataOffset(d)
+			if !ok {
 				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
 			}
-			copy(m.MemoryInstance.Buffer[offset:], d.Init)
+			copy(mem.Buffer[offset:], d.Init)
 		}
 	}
 	return nil
 }
 
+// dataOffset returns the target memory and the offset of the active data segment d, where the offset is an i64 if the
+// memory is memory64. ok is false if the segment doesn't fit in the memory.
+func (m *ModuleInstance) dataOffset(d *DataSegment) (mem *MemoryInstance, offset uint64, ok bool) {
+	mem = m.MemoryAt(d.MemoryIndex)
+	if mem.Memory64 {
+		offset = uint64(executeConstExpressionI64(m.Globals, &d.OffsetExpression))
+	} else {
+		v := executeConstExpressionI32(m.Globals, &d.OffsetExpression)
+		if v < 0 {
+			return
+		}
+		offset = uint64(v)
+	}
+	ceil := offset + uint64(len(d.Init))
+	ok = ceil >= offset && ceil <= uint64(len(mem.Buffer))
+	return
+}
+
 // GetExport returns an export of the given name and type or errs if not exported or the wrong type.
 func (m *ModuleInstance) getExport(name string, et ExternType) (*Export, error) {
 	exp, ok := m.Exports[name]
@@ -298,7 +334,7 @@ func (m *ModuleInstance) getExport(name string, et ExternType) (*Export, error)
 }
 
 func NewStore(enabledFeatures api.CoreFeatures, engine Engine) *Store {
-	return &Store{
+	s := &Store{
 		nameToModule:     map[string]*ModuleInstance{},
 		nameToModuleCap:  nameToModuleShrinkThreshold,
 		EnabledFeatures:  enabledFeatures,
@@ -306,6 +342,10 @@ func NewStore(enabledFeatures api.CoreFeatures, engine Engine) *Store {
 		typeIDs:          map[string]FunctionTypeID{},
 		functionMaxTypes: maximumFunctionTypes,
 	}
+	if enabledFeatures.IsEnabled(experimental.CoreFeaturesGC) {
+		s.gcHeap = newGCHeap(s)
+	}
+	return s
 }
 
 // Instantiate uses name instead of the Module.NameSection ModuleName as it allows instantiating the same module under
@@ -337,6 +377,23 @@ func (s *Store) Instantiate(
 	return m, nil
 }
 
+// InstantiateThread instantiates an anonymous module from the same source as m, for a thread of m. The result
+// shares the system context of m, and resolves the same imports, such as a shared memory.
+func (m *ModuleInstance) InstantiateThread(ctx context.Context) (*ModuleInstance, error) {
+	s := m.s
+	t, err := s.instantiate(ctx, m.Source, "", m.Sys, m.TypeIDs)
+	if err != nil {
+		return nil, err
+	}
+	t.Thread = true
+
+	if err = s.registerModule(t); err != nil {
+		_ = t.Close(ctx)
+		return nil, err
+	}
+	return t, nil
+}
+
 func (s *Store) instantiate(
 	ctx context.Context,
 	module *Module,
@@ -348,6 +405,10 @@ func (s *Store) instantiate(
 
 	m.Tables = make([]*TableInstance, int(module.ImportTableCount)+len(module.TableSection))
 	m.Globals = make([]*GlobalInstance, int(module.ImportGlobalCount)+len(module.GlobalSection))
+	if n := module.memoryCount(); n > 1 {
+		m.Memories = make([]*MemoryInstance, n)
+	}
+	m.buildTags(module)
 	m.Engine, err = s.Engine.NewModuleEngine(module, m)
 	if err != nil {
 		return nil, err
@@ -366,7 +427,9 @@ func (s *Store) instantiate(
 
 	allocator, _ := ctx.Value(expctxkeys.MemoryAllocatorKey{}).(experimental.MemoryAllocator)
 
-	m.buildGlobals(module, m.Engine.FunctionInstanceReference)
+	if err = m.buildGlobals(module, m.Engine.FunctionInstanceReference); err != nil {
+		return nil, err
+	}
 	m.buildMemory(module, allocator)
 	m.Exports = module.Exports
 	for _, exp := range m.Exports {
@@ -386,14 +449,18 @@ func (s *Store) instantiate(
 	}
 
 	// After engine creation, we can create the funcref element instances and initialize funcref type globals.
-	m.buildElementInstances(module.ElementSection)
+	if err = m.buildElementInstances(module.ElementSection); err != nil {
+		return nil, err
+	}
 
 	// Now all the validation passes, we are safe to mutate memory instances (possibly imported ones).
 	if err = m.applyData(module.DataSection); err != nil {
 		return nil, err
 	}
 
-	m.applyElements(module.ElementSection)
+	if err = m.applyElements(module.ElementSection); err != nil {
+		return nil, err
+	}
 
 	m.Engine.DoneInstantiation()
 
@@ -445,6 +512,11 @@ func (m *ModuleInstance) resolveImports(ctx context.Context, module *Module) (er
 					err = errorInvalidImport(i, fmt.Errorf("signature mismatch: %s != %s", expectedType, actual))
 					return
 				}
+				// The type must also be a subtype including the precise types of the references.
+				if actualIndex, ok := src.typeIndexOfFunction(imported.Index); ok && !src.IsSubType(actualIndex, module, i.DescFunc) {
+					err = errorInvalidImport(i, fmt.Errorf("type mismatch: %s != %s", expectedType, actual))
+					return
+				}
 
 				m.Engine.ResolveImportedFunction(i.IndexPerType, i.DescFunc, imported.Index, importedModule.Engine)
 			case ExternTypeTable:
@@ -455,6 +527,14 @@ func (m *ModuleInstance) resolveImports(ctx context.Context, module *Module) (er
 						RefTypeName(expected.Type), RefTypeName(importedTable.Type)))
 					return
 				}
+				// The element types must be equivalent as the table is both read and written.
+				if actual := importedModule.sourceTableAt(imported.Index); actual != nil {
+					expectedRef, actualRef := heapRefOf(expected.Type, expected.Ref), heapRefOf(actual.Type, actual.Ref)
+					if !importedModule.Source.isHeapRefEquivalent(actualRef, module, expectedRef) {
+						err = errorInvalidImport(i, fmt.Errorf("table type mismatch: %s != %s", expectedRef, actualRef))
+						return
+					}
+				}
 
 				if uint64(expected.Min) > uint64(len(importedTable.References)) {
 					err = errorMinSizeMismatch(i, expected.Min, importedTable.Min)
@@ -480,9 +560,21 @@ func (m *ModuleInstance) resolveImports(ctx context.Context, module *Module) (er
 				importedTable.involvingModuleInstancesMutex.Unlock()
 			case ExternTypeMemory:
 				expected := i.DescMem
-				importedMemory := importedModule.MemoryInstance
+				importedMemory := importedModule.MemoryAt(imported.Index)
 
-				if expected.Min > memoryBytesNumToPages(uint64(len(importedMemory.Buffer))) {
+				if expected.IsMemory64 != importedMemory.Memory64 {
+					err = errorInvalidImport(i, fmt.Errorf("memory64 mismatch: %t != %t",
+						expected.IsMemory64, importedMemory.Memory64))
+					return
+				}
+
+				if expected.PageSizeInBits() != importedMemory.PageSizeInBits() {
+					err = errorInvalidImport(i, fmt.Errorf("page size mismatch: %d != %d",
+						uint64(1)<<expected.PageSizeInBits(), uint64(1)<<importedMemory.PageSizeInBits()))
+					return
+				}
+
+				if expected.Min > importedMemory.Pages() {
 					err = errorMinSizeMismatch(i, expected.Min, importedMemory.Min)
 					return
 				}
@@ -491,8 +583,13 @@ func (m *ModuleInstance) resolveImports(ctx context.Context, module *Module) (er
 					err = errorMaxSizeMismatch(i, expected.Max, importedMemory.Max)
 					return
 				}
-				m.MemoryInstance = importedMemory
-				m.Engine.ResolveImportedMemory(importedModule.Engine)
+				if i.IndexPerType == 0 {
+					m.MemoryInstance = importedMemory
+				}
+				if m.Memories != nil {
+					m.Memories[i.IndexPerType] = importedMemory
+				}
+				m.Engine.ResolveImportedMemory(i.IndexPerType, imported.Index, importedModule.Engine)
 			case ExternTypeGlobal:
 				expected := i.DescGlobal
 				importedGlobal := importedModule.Globals[imported.Index]
@@ -508,7 +605,25 @@ func (m *ModuleInstance) resolveImports(ctx context.Context, module *Module) (er
 						ValueTypeName(expected.ValType), ValueTypeName(importedGlobal.Type.ValType)))
 					return
 				}
+				// The type of an immutable global can be a subtype, but the one of a mutable global must be equivalent.
+				if actual := importedModule.sourceGlobalTypeAt(imported.Index); actual != nil {
+					src := importedModule.Source
+					expectedRef, actualRef := heapRefOf(expected.ValType, expected.Ref), heapRefOf(actual.ValType, actual.Ref)
+					if !src.isHeapRefSubType(actualRef, module, expectedRef) ||
+						(expected.Mutable && !src.isHeapRefEquivalent(actualRef, module, expectedRef)) {
+						err = errorInvalidImport(i, fmt.Errorf("value type mismatch: %s != %s", expectedRef, actualRef))
+						return
+					}
+				}
 				m.Globals[i.IndexPerType] = importedGlobal
+			case ExternTypeTag:
+				expected := &module.TypeSection[i.DescTag]
+				importedTag := importedModule.Tags[imported.Index]
+				if !importedTag.Type.EqualsSignature(expected.Params, expected.Results) {
+					err = errorInvalidImport(i, fmt.Errorf("signature mismatch: %s != %s", expected, importedTag.Type))
+					return
+				}
+				m.Tags[i.IndexPerType] = importedTag
 			}
 		}
 	}
@@ -542,10 +657,231 @@ func executeConstExpressionI32(importedGlobals []*GlobalInstance, expr *Constant
 		id, _, _ := leb128.LoadUint32(expr.Data)
 		g := importedGlobals[id]
 		ret = int32(g.Val)
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul:
+		ret = int32(executeExtendedConstExpression(importedGlobals, expr))
+	}
+	return
+}
+
+func executeConstExpressionI64(importedGlobals []*GlobalInstance, expr *ConstantExpression) (ret int64) {
+	switch expr.Opcode {
+	case OpcodeI64Const:
+		ret, _, _ = leb128.LoadInt64(expr.Data)
+	case OpcodeGlobalGet:
+		id, _, _ := leb128.LoadUint32(expr.Data)
+		g := importedGlobals[id]
+		ret = int64(g.Val)
+	case OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		ret = int64(executeExtendedConstExpression(importedGlobals, expr))
+	}
+	return
+}
+
+// executeExtendedConstExpression executes the ConstantExpression which has more than one instruction, as allowed by
+// experimental.CoreFeaturesExtendedConst. The result of an i32 expression is zero-extended.
+func executeExtendedConstExpression(importedGlobals []*GlobalInstance, expr *ConstantExpression) uint64 {
+	var stack []uint64
+	// Ignore error as it's already validated.
+	_ = expr.forEachExtendedInstruction(func(op Opcode, imm int64) error {
+		switch op {
+		case OpcodeI32Const:
+			stack = append(stack, uint64(uint32(imm)))
+		case OpcodeI64Const:
+			stack = append(stack, uint64(imm))
+		case OpcodeGlobalGet:
+			stack = append(stack, importedGlobals[imm].Val)
+		default:
+			x1, x2 := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var v uint64
+			switch op {
+			case OpcodeI32Add:
+				v = uint64(uint32(x1) + uint32(x2))
+			case OpcodeI32Sub:
+				v = uint64(uint32(x1) - uint32(x2))
+			case OpcodeI32Mul:
+				v = uint64(uint32(x1) * uint32(x2))
+			case OpcodeI64Add:
+				v = x1 + x2
+			case OpcodeI64Sub:
+				v = x1 - x2
+			case OpcodeI64Mul:
+				v = x1 * x2
+			}
+			stack[len(stack)-1] = v
+		}
+		return nil
+	})
+	return stack[0]
+}
+
+// initializeGlobal initializes the value of g given the const expr, where globals are the instances of the globals
+// it can refer to. This is the same as GlobalInstance.initialize, except that the expression can allocate objects as
+// per experimental.CoreFeaturesGC.
+func (m *ModuleInstance) initializeGlobal(g *GlobalInstance, globals []*GlobalInstance, expr *ConstantExpression, funcRefResolver func(funcIndex Index) Reference) (err error) {
+	if expr.Opcode != OpcodeGCPrefix {
+		g.initialize(globals, expr, funcRefResolver)
+		return nil
 	}
+	g.Val, g.ValHi, err = m.executeGCConstExpression(globals, expr, funcRefResolver)
 	return
 }
 
+// executeGCConstExpression executes the ConstantExpression whose Opcode is OpcodeGCPrefix, and returns the lower and
+// higher 64 bits of the result. The validity of the expression is ensured as executeConstExpressionI32.
+//
+// The objects are allocated in GCHeap, and kept alive by the globals, tables and element instances of this module
+// being instantiated, as it is not one of the roots of the heap yet.
+func (m *ModuleInstance) executeGCConstExpression(globals []*GlobalInstance, expr *ConstantExpression, funcRefResolver func(funcIndex Index) Reference) (lo, hi uint64, err error) {
+	// Ignore error as it's already validated.
+	instructions, _ := expr.gcInstructions()
+
+	var stack []uint64
+	pop := func() uint64 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	popField := func(field *FieldType) []uint64 {
+		n := 1
+		if field.Type == ValueTypeV128 {
+			n = 2
+		}
+		values := stack[len(stack)-n:]
+		stack = stack[:len(stack)-n]
+		switch field.Type {
+		case StorageTypeI8:
+			values[0] &= 0xff
+		case StorageTypeI16:
+			values[0] &= 0xffff
+		}
+		return values
+	}
+
+	h := m.GCHeap()
+	// Count the instantiation as a call in progress, so that the objects are only collected if no other call is.
+	h.EnterCall()
+	defer h.ExitCall()
+	stackRoots := func(mark func(uint64)) {
+		for _, v := range stack {
+			mark(v)
+		}
+		for _, g := range m.Globals {
+			if g != nil && isReferenceValueType(g.Type.ValType) {
+				mark(g.Val)
+			}
+		}
+		for _, t := range m.Tables {
+			if t != nil {
+				for _, r := range t.References {
+					mark(uint64(r))
+				}
+			}
+		}
+		for _, e := range m.ElementInstances {
+			for _, r := range e {
+				mark(uint64(r))
+			}
+		}
+	}
+
+	for i := range instructions {
+		in := &instructions[i]
+		switch in.op {
+		case OpcodeI32Const:
+			stack = append(stack, uint64(uint32(in.imm)))
+		case OpcodeI64Const:
+			stack = append(stack, uint64(in.imm))
+		case OpcodeF32Const:
+			stack = append(stack, uint64(binary.LittleEndian.Uint32(in.data)))
+		case OpcodeF64Const:
+			stack = append(stack, binary.LittleEndian.Uint64(in.data))
+		case OpcodeVecPrefix:
+			stack = append(stack, binary.LittleEndian.Uint64(in.data[0:8]), binary.LittleEndian.Uint64(in.data[8:16]))
+		case OpcodeGlobalGet:
+			g := globals[in.imm]
+			stack = append(stack, g.Val)
+			if g.Type.ValType == ValueTypeV128 {
+				stack = append(stack, g.ValHi)
+			}
+		case OpcodeRefNull:
+			stack = append(stack, 0)
+		case OpcodeRefFunc:
+			stack = append(stack, uint64(funcRefResolver(Index(in.imm))))
+		case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+			x2 := pop()
+			x1 := pop()
+			var v uint64
+			switch in.op {
+			case OpcodeI32Add:
+				v = uint64(uint32(x1) + uint32(x2))
+			case OpcodeI32Sub:
+				v = uint64(uint32(x1) - uint32(x2))
+			case OpcodeI32Mul:
+				v = uint64(uint32(x1) * uint32(x2))
+			case OpcodeI64Add:
+				v = x1 + x2
+			case OpcodeI64Sub:
+				v = x1 - x2
+			case OpcodeI64Mul:
+				v = x1 * x2
+			}
+			stack = append(stack, v)
+		case OpcodeGCPrefix:
+			switch in.gcOp {
+			case OpcodeGCStructNew, OpcodeGCStructNewDefault:
+				typeIndex := Index(in.imm)
+				st := &m.Source.SubTypes[typeIndex]
+				values := make([]uint64, st.SizeInUint64())
+				if in.gcOp == OpcodeGCStructNew {
+					for j := len(st.Fields) - 1; j >= 0; j-- {
+						copy(values[st.FieldOffset(Index(j)):], popField(&st.Fields[j]))
+					}
+				}
+				obj := &GCObject{Kind: GCObjectKindStruct, Module: m.Source, TypeIndex: typeIndex, Values: values}
+				stack = append(stack, h.Alloc(obj, stackRoots))
+			case OpcodeGCArrayNew, OpcodeGCArrayNewDefault, OpcodeGCArrayNewFixed:
+				typeIndex := Index(in.imm)
+				st := &m.Source.SubTypes[typeIndex]
+				size := uint64(st.SizeInUint64())
+				n := uint64(in.n)
+				if in.gcOp != OpcodeGCArrayNewFixed {
+					n = uint64(uint32(pop()))
+				}
+				if n*size > GCObjectMaxSizeInUint64 {
+					return 0, 0, wasmruntime.ErrRuntimeAllocationTooLarge
+				}
+				values := make([]uint64, n*size)
+				switch in.gcOp {
+				case OpcodeGCArrayNew:
+					v := popField(&st.Fields[0])
+					for j := uint64(0); j < n; j++ {
+						copy(values[j*size:], v)
+					}
+				case OpcodeGCArrayNewFixed:
+					// The elements are pushed in order, so pop them from the last one.
+					for j := n; j > 0; j-- {
+						copy(values[(j-1)*size:], popField(&st.Fields[0]))
+					}
+				}
+				obj := &GCObject{Kind: GCObjectKindArray, Module: m.Source, TypeIndex: typeIndex, Values: values}
+				stack = append(stack, h.Alloc(obj, stackRoots))
+			case OpcodeGCRefI31:
+				stack = append(stack, GCRefI31(uint32(pop())))
+			case OpcodeGCAnyConvertExtern:
+				stack = append(stack, h.AnyConvertExtern(pop(), stackRoots))
+			case OpcodeGCExternConvertAny:
+				stack = append(stack, h.ExternConvertAny(pop()))
+			}
+		}
+	}
+	if len(stack) == 2 {
+		// v128 takes two values.
+		return stack[0], stack[1], nil
+	}
+	return stack[0], 0, nil
+}
+
 // initialize initializes the value of this global instance given the const expr and imported globals.
 // funcRefResolver is called to get the actual funcref (engine specific) from the OpcodeRefFunc const expr.
 //
@@ -579,12 +915,12 @@ func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *Con
 			g.Val = importedG.Val
 		case ValueTypeV128:
 			g.Val, g.ValHi = importedG.Val, importedG.ValHi
-		case ValueTypeFuncref, ValueTypeExternref:
+		case ValueTypeFuncref, ValueTypeExternref, ValueTypeExnref, ValueTypeAnyref, ValueTypeContref:
 			g.Val = importedG.Val
 		}
 	case OpcodeRefNull:
 		switch expr.Data[0] {
-		case ValueTypeExternref, ValueTypeFuncref:
+		case ValueTypeExternref, ValueTypeFuncref, ValueTypeExnref, ValueTypeAnyref, ValueTypeContref:
 			g.Val = 0 // Reference types are opaque 64bit pointer at runtime.
 		}
 	case OpcodeRefFunc:
@@ -592,6 +928,8 @@ func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *Con
 		g.Val = uint64(funcRefResolver(v))
 	case OpcodeVecV128Const:
 		g.Val, g.ValHi = binary.LittleEndian.Uint64(expr.Data[0:8]), binary.LittleEndian.Uint64(expr.Data[8:16])
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		g.Val = executeExtendedConstExpression(importedGlobals, expr)
 	}
 }
 
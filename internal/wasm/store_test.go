@@ -117,6 +117,37 @@ func TestStore_Instantiate(t *testing.T) {
 	})
 }
 
+func TestModuleInstance_InstantiateThread(t *testing.T) {
+	s := newStore()
+	_, err := s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1, IsShared: true},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+		Exports:                 map[string]*Export{"memory": {Type: ExternTypeMemory, Name: "memory"}},
+	}, "env", nil, nil)
+	require.NoError(t, err)
+
+	sysCtx := sys.DefaultContext(nil)
+	mod, err := s.Instantiate(testCtx, &Module{
+		ImportMemoryCount: 1,
+		ImportSection:     []Import{{Type: ExternTypeMemory, Module: "env", Name: "memory", DescMem: &Memory{Min: 1, Max: 1, IsShared: true}}},
+		ImportPerModule:   map[string][]*Import{"env": {{Type: ExternTypeMemory, Module: "env", Name: "memory", DescMem: &Memory{Min: 1, Max: 1, IsShared: true}}}},
+	}, "main", sysCtx, nil)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	thread, err := mod.InstantiateThread(testCtx)
+	require.NoError(t, err)
+	require.True(t, thread.Thread)
+	require.Equal(t, "", thread.Name())
+	require.Equal(t, mod.MemoryInstance, thread.MemoryInstance)
+	require.Equal(t, sysCtx, thread.Sys)
+
+	// Closing the thread doesn't close the files of the module which spawned it.
+	require.NoError(t, thread.Close(testCtx))
+	_, ok := sysCtx.FS().LookupFile(sys.FdStdout)
+	require.True(t, ok)
+}
+
 func TestStore_CloseWithExitCode(t *testing.T) {
 	const importedModuleName = "imported"
 	const importingModuleName = "test"
@@ -490,7 +521,7 @@ func (e *mockModuleEngine) ResolveImportedFunction(index, _, importedIndex Index
 }
 
 // ResolveImportedMemory implements the same method as documented on wasm.ModuleEngine.
-func (e *mockModuleEngine) ResolveImportedMemory(imp ModuleEngine) {
+func (e *mockModuleEngine) ResolveImportedMemory(_, _ Index, imp ModuleEngine) {
 	e.importedMemModEngine = imp
 }
 
@@ -629,6 +660,51 @@ func TestGlobalInstance_initialize(t *testing.T) {
 			})
 		}
 	})
+	t.Run("extended", func(t *testing.T) {
+		globals := []*GlobalInstance{
+			{Val: 0xffff_fff0, Type: GlobalType{ValType: ValueTypeI32}},
+			{Val: 1 << 40, Type: GlobalType{ValType: ValueTypeI64}},
+		}
+		tests := []struct {
+			name string
+			expr *ConstantExpression
+			exp  uint64
+		}{
+			{
+				name: "i32 wraps and is zero-extended",
+				// (i32.add (global.get 0) (i32.const 0x20))
+				expr: &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 0x20}},
+				exp:  0x10,
+			},
+			{
+				name: "i32 nested",
+				// (i32.sub (i32.mul (i32.const 3) (i32.const 4)) (i32.const 13))
+				expr: &ConstantExpression{
+					Opcode: OpcodeI32Sub,
+					Data:   []byte{OpcodeI32Const, 3, OpcodeI32Const, 4, OpcodeI32Mul, OpcodeI32Const, 13},
+				},
+				exp: 0xffff_ffff,
+			},
+			{
+				name: "i64",
+				// (i64.mul (global.get 1) (i64.sub (i64.const 1) (i64.const 3)))
+				expr: &ConstantExpression{
+					Opcode: OpcodeI64Mul,
+					Data:   []byte{OpcodeGlobalGet, 1, OpcodeI64Const, 1, OpcodeI64Const, 3, OpcodeI64Sub},
+				},
+				exp: 0xffff_fe00_0000_0000, // -2 << 40
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				g := &GlobalInstance{}
+				g.initialize(globals, tc.expr, nil)
+				require.Equal(t, tc.exp, g.Val)
+			})
+		}
+	})
 	t.Run("ref.func", func(t *testing.T) {
 		g := GlobalInstance{Type: GlobalType{ValType: RefTypeFuncref}}
 		g.initialize(nil,
@@ -896,6 +972,23 @@ func Test_resolveImports(t *testing.T) {
 			})
 			require.EqualError(t, err, "import memory[test.target]: maximum size mismatch: 10 < 65536")
 		})
+		t.Run("page size mismatch", func(t *testing.T) {
+			s := newStore()
+			s.nameToModule[moduleName] = &ModuleInstance{
+				MemoryInstance: &MemoryInstance{Max: MemoryLimitPages},
+				Exports: map[string]*Export{name: {
+					Type: ExternTypeMemory,
+				}},
+				ModuleName: moduleName,
+			}
+
+			importMemoryType := &Memory{Max: MemoryLimitPages, IsPageSizeEncoded: true}
+			m := &ModuleInstance{s: s}
+			err := m.resolveImports(context.Background(), &Module{
+				ImportPerModule: map[string][]*Import{moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: importMemoryType}}},
+			})
+			require.EqualError(t, err, "import memory[test.target]: page size mismatch: 1 != 65536")
+		})
 	})
 }
 
@@ -983,17 +1076,17 @@ func TestModuleInstance_applyElements(t *testing.T) {
 		}
 
 		// This shouldn't panic.
-		m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128_100}}})
-		m.applyElements([]ElementSegment{
+		require.NoError(t, m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128_100}}}))
+		require.NoError(t, m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{0}}, Init: make([]Index, 3)},
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128_100}, Init: make([]Index, 5)}, // Iteration stops at this point, so the offset:5 below shouldn't be applied.
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{5}}, Init: make([]Index, 5)},
-		})
+		}))
 		require.Equal(t, []Reference{0, 0, 0, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff},
 			m.Tables[0].References)
-		m.applyElements([]ElementSegment{
+		require.NoError(t, m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{5}}, Init: make([]Index, 5)},
-		})
+		}))
 		require.Equal(t, []Reference{0, 0, 0, 0xffff, 0xffff, 0, 0, 0, 0, 0}, m.Tables[0].References)
 	})
 	t.Run("funcref", func(t *testing.T) {
@@ -1009,18 +1102,18 @@ func TestModuleInstance_applyElements(t *testing.T) {
 		}
 
 		// This shouldn't panic.
-		m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128_100}, Init: []Index{1, 2, 3}}})
-		m.applyElements([]ElementSegment{
+		require.NoError(t, m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128_100}, Init: []Index{1, 2, 3}}}))
+		require.NoError(t, m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{0}}, Init: []Index{0, 1, 2}},
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{9}}, Init: []Index{1 | elementInitImportedGlobalReferenceType}},
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128_100}, Init: make([]Index, 5)}, // Iteration stops at this point, so the offset:5 below shouldn't be applied.
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{5}}, Init: make([]Index, 5)},
-		})
+		}))
 		require.Equal(t, []Reference{0xa, 0xaa, 0xaaa, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xabcde},
 			m.Tables[0].References)
-		m.applyElements([]ElementSegment{
+		require.NoError(t, m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{5}}, Init: []Index{0, ElementInitNullReference, 2}},
-		})
+		}))
 		require.Equal(t, []Reference{0xa, 0xaa, 0xaaa, 0xffff, 0xffff, 0xa, 0xffff, 0xaaa, 0xffff, 0xabcde},
 			m.Tables[0].References)
 	})
@@ -14,6 +14,11 @@ type Table struct {
 	Min  uint32
 	Max  *uint32
 	Type RefType
+	// Ref is the precise type of Type, or nil if it is the DefaultHeapRef.
+	Ref *HeapRef
+	// Init is the initial value of the elements as per experimental.CoreFeaturesFunctionReferences, or nil if they
+	// are initialized with null.
+	Init *ConstantExpression
 }
 
 // RefType is either RefTypeFuncref or RefTypeExternref as of WebAssembly core 2.0.
@@ -24,6 +29,9 @@ const (
 	RefTypeFuncref = ValueTypeFuncref
 	// RefTypeExternref represents a reference to a host object, which is not currently supported in wazero.
 	RefTypeExternref = ValueTypeExternref
+	// RefTypeExnref represents a reference to an exception, only valid with
+	// experimental.CoreFeaturesExceptionHandling.
+	RefTypeExnref = ValueTypeExnref
 )
 
 func RefTypeName(t RefType) (ret string) {
@@ -32,6 +40,10 @@ func RefTypeName(t RefType) (ret string) {
 		ret = "funcref"
 	case RefTypeExternref:
 		ret = "externref"
+	case RefTypeExnref:
+		ret = "exnref"
+	case ValueTypeAnyref:
+		ret = "anyref"
 	default:
 		ret = fmt.Sprintf("unknown(0x%x)", t)
 	}
@@ -69,9 +81,16 @@ type ElementSegment struct {
 	// Init indices are (nullable) table elements where each index is the function index by which the module initialize the table.
 	Init []Index
 
+	// InitExpressions are the constant expressions of Init items which allocate objects as per
+	// experimental.CoreFeaturesGC. See WrapConstExpressionAsElementInit.
+	InitExpressions []ConstantExpression
+
 	// Type holds the type of this element segment, which is the RefType in WebAssembly 2.0.
 	Type RefType
 
+	// Ref is the precise type of Type, or nil if it is the DefaultHeapRef.
+	Ref *HeapRef
+
 	// Mode is the mode of this element segment.
 	Mode ElementMode
 }
@@ -91,6 +110,10 @@ const (
 	// but in practice, that is not allowed in wazero thanks to our limit MaximumFunctionIndex. Thus, it is safe to set this flag
 	// in init element to indicate as such.
 	elementInitImportedGlobalReferenceType Index = 1 << 30
+	// elementInitConstExpression represents an init item which is resolved by executing the constant expression of
+	// ElementSegment.InitExpressions at the index. This doesn't collide with the function indexes as
+	// elementInitImportedGlobalReferenceType.
+	elementInitConstExpression Index = 1 << 29
 )
 
 // unwrapElementInitGlobalReference takes an item of the init vector of an ElementSegment,
@@ -109,6 +132,22 @@ func WrapGlobalIndexAsElementInit(init Index) Index {
 	return init | elementInitImportedGlobalReferenceType
 }
 
+// unwrapElementInitConstExpression takes an item of the init vector of an ElementSegment, and returns the index of
+// ElementSegment.InitExpressions if it is supposed to get generated from a constant expression.
+// ok is true if the given init item is as such.
+func unwrapElementInitConstExpression(init Index) (_ Index, ok bool) {
+	if init&elementInitConstExpression == elementInitConstExpression {
+		return init &^ elementInitConstExpression, true
+	}
+	return init, false
+}
+
+// WrapConstExpressionAsElementInit wraps the given index of ElementSegment.InitExpressions as an init item.
+// See the comments on elementInitConstExpression for more details.
+func WrapConstExpressionAsElementInit(index Index) Index {
+	return index | elementInitConstExpression
+}
+
 // IsActive returns true if the element segment is "active" mode which requires the runtime to initialize table
 // with the contents in .Init field.
 func (e *ElementSegment) IsActive() bool {
@@ -172,9 +211,17 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 		idx := Index(i)
 		initCount := uint32(len(elem.Init))
 
+		elemRef := heapRefOf(elem.Type, elem.Ref)
 		// Any offset applied is to the element, not the function index: validate here if the funcidx is sound.
 		for ei, init := range elem.Init {
 			if init == ElementInitNullReference {
+				if elemRef.NonNullable {
+					return fmt.Errorf("%s[%d].init[%d] must not be null for %s", SectionIDName(SectionIDElement), idx, ei, elemRef)
+				}
+				continue
+			}
+			if _, ok := unwrapElementInitConstExpression(init); ok {
+				// Validated with the types of the globals by validateElementInits.
 				continue
 			}
 			index, ok := unwrapElementInitGlobalReference(init)
@@ -183,12 +230,16 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 					return fmt.Errorf("%s[%d].init[%d] global index %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
 				}
 			} else {
-				if elem.Type == RefTypeExternref {
+				if elem.Type == RefTypeExternref || elem.Type == ValueTypeAnyref {
 					return fmt.Errorf("%s[%d].init[%d] must be ref.null but was %d", SectionIDName(SectionIDElement), idx, ei, init)
 				}
 				if index >= funcCount {
 					return fmt.Errorf("%s[%d].init[%d] func index %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
 				}
+				if typeIndex, ok := m.typeIndexOfFunction(index); ok &&
+					!m.isHeapRefSubType(HeapRef{HeapType: HeapType(typeIndex), NonNullable: true}, m, elemRef) {
+					return fmt.Errorf("%s[%d].init[%d] func index %d type mismatch: want %s", SectionIDName(SectionIDElement), idx, ei, index, elemRef)
+				}
 			}
 		}
 
@@ -198,7 +249,7 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 			}
 
 			t := tables[elem.TableIndex]
-			if t.Type != elem.Type {
+			if t.Type != elem.Type || !m.isHeapRefSubType(elemRef, m, heapRefOf(t.Type, t.Ref)) {
 				return fmt.Errorf("element type mismatch: table has %s but element has %s",
 					RefTypeName(t.Type), RefTypeName(elem.Type),
 				)
@@ -228,6 +279,18 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 						return err
 					}
 				}
+			} else if oc == OpcodeI32Add || oc == OpcodeI32Sub || oc == OpcodeI32Mul {
+				// The extended constant expression can only reference the imported i32 globals as well.
+				var globalErr error
+				_, err := validateExtendedConstExpression(&elem.OffsetExpr, func(globalIdx Index) (ValueType, error) {
+					globalErr = m.verifyImportGlobalI32(SectionIDElement, idx, globalIdx)
+					return ValueTypeI32, globalErr
+				})
+				if globalErr != nil {
+					return globalErr
+				} else if err != nil {
+					return fmt.Errorf("%s[%d] has an invalid const expression: %w", SectionIDName(SectionIDElement), idx, err)
+				}
 			} else {
 				return fmt.Errorf("%s[%d] has an invalid const expression: %s", SectionIDName(SectionIDElement), idx, InstructionName(oc))
 			}
@@ -252,6 +315,17 @@ func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err e
 			References: make([]Reference, tsec.Min), Min: tsec.Min, Max: tsec.Max,
 			Type: tsec.Type,
 		}
+		if tsec.Init != nil {
+			// The initializer can only refer to the imported globals, so it can be evaluated before the others.
+			init := &GlobalInstance{Type: GlobalType{ValType: tsec.Type}}
+			err = m.initializeGlobal(init, m.Globals[:module.ImportGlobalCount], tsec.Init, m.Engine.FunctionInstanceReference)
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDTable), i, err)
+			}
+			for i := range m.Tables[idx].References {
+				m.Tables[idx].References[i] = Reference(init.Val)
+			}
+		}
 		idx++
 	}
 
@@ -259,17 +333,7 @@ func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err e
 		for elemI := range module.ElementSection { // Do not loop over the value since elementSegments is a slice of value.
 			elem := &module.ElementSection[elemI]
 			table := m.Tables[elem.TableIndex]
-			var offset uint32
-			if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-				// Ignore error as it's already validated.
-				globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-				global := m.Globals[globalIdx]
-				offset = uint32(global.Val)
-			} else { // i32.const
-				// Ignore error as it's already validated.
-				o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-				offset = uint32(o)
-			}
+			offset := uint32(executeConstExpressionI32(m.Globals, &elem.OffsetExpr))
 
 			// Check to see if we are out-of-bounds
 			initCount := uint64(len(elem.Init))
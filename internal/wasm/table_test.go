@@ -231,6 +231,28 @@ func TestModule_validateTable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "extended const derived element offset and one index",
+			input: &Module{
+				TypeSection: []FunctionType{{}},
+				ImportSection: []Import{
+					{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeI32}},
+				},
+				TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{
+							Opcode: OpcodeI32Add,
+							Data:   []byte{OpcodeGlobalGet, 0x0, OpcodeI32Const, 0x1},
+						},
+						Init: []Index{0},
+						Type: RefTypeFuncref,
+					},
+				},
+			},
+		},
 		{
 			name: "imported global derived element offset and one index - imported table",
 			input: &Module{
@@ -644,6 +666,29 @@ func TestModule_validateTable_Errors(t *testing.T) {
 			},
 			expectedErr: "element[0] (global.get 0): import[0].global.ValType != i32",
 		},
+		{
+			name: "extended const derived element offset - wrong ValType",
+			input: &Module{
+				TypeSection: []FunctionType{{}},
+				ImportSection: []Import{
+					{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeI64}},
+				},
+				TableSection:    []Table{{Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{
+							Opcode: OpcodeI32Add,
+							Data:   []byte{OpcodeGlobalGet, 0x0, OpcodeI32Const, 0x1},
+						},
+						Init: []Index{0},
+						Type: RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0] (global.get 0): import[0].global.ValType != i32",
+		},
 		{
 			name: "imported global derived element offset - decode error",
 			input: &Module{
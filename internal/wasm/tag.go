@@ -0,0 +1,130 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+)
+
+// TagInstance is an exception tag defined or imported by a module. Tags are
+// compared by identity when an exception is caught.
+//
+// This implements experimental.ExceptionTag.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#tags
+type TagInstance struct {
+	internalapi.WazeroOnlyType
+
+	// Type is the type of the tag, whose Params are the payload of exceptions
+	// or suspensions. Results are empty unless the tag is only used by the
+	// suspend instruction, which pushes them when resumed.
+	Type *FunctionType
+}
+
+// ParamTypes implements experimental.ExceptionTag.
+func (t *TagInstance) ParamTypes() []api.ValueType {
+	return t.Type.Params
+}
+
+// ExportedTag implements the interface asserted by experimental.ExportedTag.
+func (m *ModuleInstance) ExportedTag(name string) experimental.ExceptionTag {
+	exp, err := m.getExport(name, ExternTypeTag)
+	if err != nil {
+		return nil
+	}
+	return m.Tags[exp.Index]
+}
+
+// buildTags allocates Tags and instantiates the ones defined in the module.
+// Imported tags are set by resolveImports.
+func (m *ModuleInstance) buildTags(module *Module) {
+	if n := int(module.ImportTagCount) + len(module.TagSection); n > 0 {
+		m.Tags = make([]*TagInstance, n)
+	}
+	for i, typeIndex := range module.TagSection {
+		m.Tags[int(module.ImportTagCount)+i] = &TagInstance{Type: &module.TypeSection[typeIndex]}
+	}
+}
+
+// AllTagTypes returns the type index of each tag in the module, beginning
+// with the imported ones.
+func (m *Module) AllTagTypes() []Index {
+	if m.ImportTagCount == 0 {
+		return m.TagSection
+	}
+	ret := make([]Index, 0, int(m.ImportTagCount)+len(m.TagSection))
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeTag {
+			ret = append(ret, imp.DescTag)
+		}
+	}
+	return append(ret, m.TagSection...)
+}
+
+// validateTags validates the types of the tags, which can only have results with
+// experimental.CoreFeaturesStackSwitching, as the values passed back by resume to suspend.
+func (m *Module) validateTags(tags []Index, enabledFeatures api.CoreFeatures) error {
+	resultsAllowed := enabledFeatures.IsEnabled(experimental.CoreFeaturesStackSwitching)
+	for i, typeIndex := range tags {
+		if int(typeIndex) >= len(m.TypeSection) {
+			return fmt.Errorf("invalid tag[%d]: type index out of range", i)
+		} else if !m.isFunctionType(typeIndex) {
+			return fmt.Errorf("invalid tag[%d]: type %d is not a function type", i, typeIndex)
+		}
+		if tp := &m.TypeSection[typeIndex]; len(tp.Results) > 0 && !resultsAllowed {
+			return fmt.Errorf("invalid tag[%d]: type %s must not have results", i, tp)
+		}
+	}
+	return nil
+}
+
+// ExceptionRefs associates exnref values with the exceptions they refer to,
+// and is used by engines for the duration of a call.
+//
+// An exnref is the one-based position of the exception in this table, combined
+// with a generation which is incremented on Reset, so that stale references
+// from a previous call are detected instead of aliasing a different exception.
+// The zero value is the null exnref.
+type ExceptionRefs struct {
+	exceptions []*experimental.Exception
+	generation uint64
+}
+
+// Add returns a new exnref for the given exception.
+func (e *ExceptionRefs) Add(exc *experimental.Exception) uint64 {
+	e.exceptions = append(e.exceptions, exc)
+	return e.generation<<32 | uint64(len(e.exceptions))
+}
+
+// Get returns the exception referred by the exnref, or nil if it is null or
+// was created before the last Reset.
+func (e *ExceptionRefs) Get(ref uint64) *experimental.Exception {
+	if ref>>32 != e.generation&0xffffffff {
+		return nil
+	}
+	if i := uint32(ref); i > 0 && int(i) <= len(e.exceptions) {
+		return e.exceptions[i-1]
+	}
+	return nil
+}
+
+// ForEachPayload calls fn for each value in the payloads of the exceptions, which is used to find
+// the references to the objects in GCHeap.
+func (e *ExceptionRefs) ForEachPayload(fn func(uint64)) {
+	for _, exc := range e.exceptions {
+		for _, v := range exc.Payload() {
+			fn(v)
+		}
+	}
+}
+
+// Reset invalidates all the exnrefs created so far.
+func (e *ExceptionRefs) Reset() {
+	if len(e.exceptions) > 0 {
+		clear(e.exceptions)
+		e.exceptions = e.exceptions[:0]
+		e.generation++
+	}
+}
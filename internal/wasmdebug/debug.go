@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
@@ -135,6 +136,11 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", wasmErr, stack)
 	}
 
+	// An exception thrown by the guest, but not caught, is not a bug either.
+	if exc, ok := recovered.(*experimental.Exception); ok {
+		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", exc, stack)
+	}
+
 	// If we have a runtime.Error, something severe happened which should include the stack trace. This could be
 	// a nil pointer from wazero or a user-defined function from HostModuleBuilder.
 	if runtimeErr, ok := recovered.(runtime.Error); ok {
@@ -33,6 +33,20 @@ var (
 	ErrRuntimeExpectedSharedMemory = New("expected shared memory")
 	// ErrRuntimeTooManyWaiters indicates that atomic.wait was called with too many waiters.
 	ErrRuntimeTooManyWaiters = New("too many waiters")
+	// ErrRuntimeNullReference indicates that a null reference was dereferenced, for example by throw_ref.
+	ErrRuntimeNullReference = New("null reference")
+	// ErrRuntimeCastFailure indicates that a reference was not of the type required by ref.cast, or by the
+	// instructions accessing the fields of a struct or the elements of an array.
+	ErrRuntimeCastFailure = New("cast failure")
+	// ErrRuntimeArrayOutOfBounds indicates that the program tried to access the elements beyond the length of an array.
+	ErrRuntimeArrayOutOfBounds = New("out of bounds array access")
+	// ErrRuntimeAllocationTooLarge indicates that the program tried to allocate an array larger than the limit.
+	ErrRuntimeAllocationTooLarge = New("allocation too large")
+	// ErrRuntimeContinuationAlreadyResumed indicates that a continuation was resumed or bound more than once, or
+	// after the call which created it returned.
+	ErrRuntimeContinuationAlreadyResumed = New("continuation already resumed")
+	// ErrRuntimeUnhandledSuspension indicates that suspend was executed without any enclosing resume handling its tag.
+	ErrRuntimeUnhandledSuspension = New("unhandled suspension")
 )
 
 // Error is returned by a wasm.Engine during the execution of Wasm functions, and they indicate that the Wasm runtime
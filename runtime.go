@@ -7,6 +7,7 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	experimentalapi "github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/component"
 	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo"
 	"github.com/tetratelabs/wazero/internal/expctxkeys"
@@ -98,8 +99,14 @@ type Runtime interface {
 	//
 	//   - The resulting module name defaults to what was binary from the custom name section.
 	//   - Any pre-compilation done after decoding the source is dependent on RuntimeConfig.
+	//   - The binary can also be a component of the component model, such as
+	//     the output of `wasm-tools component new`. Its instances export the
+	//     core functions it lifts, and the functions of its exported instances
+	//     are named "<instance>#<function>". Its imported instances are
+	//     resolved as modules of the same name. This is experimental.
 	//
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#name-section%E2%91%A0
+	// See https://github.com/WebAssembly/component-model
 	CompileModule(ctx context.Context, binary []byte) (CompiledModule, error)
 
 	// InstantiateModule instantiates the module or errs for reasons including
@@ -179,14 +186,15 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 	}
 	store := wasm.NewStore(config.enabledFeatures, engine)
 	return &runtime{
-		cache:                 cacheImpl,
-		store:                 store,
-		enabledFeatures:       config.enabledFeatures,
-		memoryLimitPages:      config.memoryLimitPages,
-		memoryCapacityFromMax: config.memoryCapacityFromMax,
-		dwarfDisabled:         config.dwarfDisabled,
-		storeCustomSections:   config.storeCustomSections,
-		ensureTermination:     config.ensureTermination,
+		cache:                    cacheImpl,
+		store:                    store,
+		enabledFeatures:          config.enabledFeatures,
+		memoryLimitPages:         config.memoryLimitPages,
+		memoryCapacityFromMax:    config.memoryCapacityFromMax,
+		dwarfDisabled:            config.dwarfDisabled,
+		storeCustomSections:      config.storeCustomSections,
+		ensureTermination:        config.ensureTermination,
+		deterministicRelaxedSIMD: config.deterministicRelaxedSIMD,
 	}
 }
 
@@ -208,7 +216,8 @@ type runtime struct {
 	// See /RATIONALE.md
 	closed atomic.Uint64
 
-	ensureTermination bool
+	ensureTermination        bool
+	deterministicRelaxedSIMD bool
 }
 
 // Module implements Runtime.Module.
@@ -231,6 +240,13 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 		return nil, err
 	}
 
+	if component.IsComponent(binary) {
+		return r.compileComponent(ctx, binary)
+	}
+	return r.compileModule(ctx, binary)
+}
+
+func (r *runtime) compileModule(ctx context.Context, binary []byte) (*compiledModule, error) {
 	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures,
 		r.memoryLimitPages, r.memoryCapacityFromMax, !r.dwarfDisabled, r.storeCustomSections)
 	if err != nil {
@@ -258,6 +274,7 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	if err != nil {
 		return nil, err
 	}
+	internal.DeterministicRelaxedSIMD = r.deterministicRelaxedSIMD
 	internal.AssignModuleID(binary, listeners, r.ensureTermination)
 	if err = r.store.Engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
 		return nil, err
@@ -265,6 +282,27 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	return c, nil
 }
 
+// compileComponent compiles a component, whose core modules are compiled the same way as the other modules.
+func (r *runtime) compileComponent(ctx context.Context, binary []byte) (*compiledModule, error) {
+	decoded, err := component.DecodeComponent(binary)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := component.Compile(ctx, decoded, r.store,
+		func(ctx context.Context, binary []byte) (*wasm.Module, []wasm.FunctionTypeID, error) {
+			c, err := r.compileModule(ctx, binary)
+			if err != nil {
+				return nil, nil, err
+			}
+			return c.module, c.typeIDs, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	// The module is empty, as the definitions of the component are given by compiledModule.component.
+	return &compiledModule{module: &wasm.Module{}, compiledEngine: r.store.Engine, component: compiled}, nil
+}
+
 func buildFunctionListeners(ctx context.Context, internal *wasm.Module) ([]experimentalapi.FunctionListener, error) {
 	// Test to see if internal code are using an experimental feature.
 	fnlf := ctx.Value(expctxkeys.FunctionListenerFactoryKey{})
@@ -316,15 +354,20 @@ func (r *runtime) InstantiateModule(
 	code := compiled.(*compiledModule)
 	config := mConfig.(*moduleConfig)
 
-	// Only add guest module configuration to guests.
-	if !code.module.IsHostModule {
+	// Only add guest module configuration to guests, which includes the core
+	// instances of a component.
+	if code.component != nil || !code.module.IsHostModule {
 		if sockConfig, ok := ctx.Value(internalsock.ConfigKey{}).(*internalsock.Config); ok {
 			config.sockConfig = sockConfig
 		}
 	}
 
+	if code.component != nil {
+		return r.instantiateComponent(ctx, code, config)
+	}
+
 	var sysCtx *internalsys.Context
-	if sysCtx, err = config.toSysContext(); err != nil {
+	if sysCtx, err = config.toSysContext(ctx); err != nil {
 		return nil, err
 	}
 
@@ -375,6 +418,45 @@ func (r *runtime) InstantiateModule(
 	return
 }
 
+// instantiateComponent instantiates a component, whose core instances share the system context of the config.
+func (r *runtime) instantiateComponent(ctx context.Context, code *compiledModule, config *moduleConfig) (mod api.Module, err error) {
+	var sysCtx *internalsys.Context
+	if sysCtx, err = config.toSysContext(ctx); err != nil {
+		return nil, err
+	}
+
+	inst, err := component.Instantiate(ctx, r.store, code.component, config.name, sysCtx)
+	if err != nil {
+		if code.closeWithModule {
+			_ = code.Close(ctx) // don't overwrite the error
+		}
+		return nil, err
+	}
+	if code.closeWithModule {
+		inst.CodeCloser = code
+	}
+
+	for _, fn := range config.startFunctions {
+		start := inst.ExportedFunction(fn)
+		if start == nil {
+			continue
+		}
+		if _, err = start.Call(ctx); err != nil {
+			_ = inst.Close(ctx) // Don't leak the instance on error.
+
+			if se, ok := err.(*sys.ExitError); ok {
+				if se.ExitCode() == 0 { // Don't err on success.
+					err = nil
+				}
+				return // Don't wrap an exit error
+			}
+			err = fmt.Errorf("component[%s] function[%s] failed: %w", config.name, fn, err)
+			return
+		}
+	}
+	return inst, nil
+}
+
 // Close implements api.Closer embedded in Runtime.
 func (r *runtime) Close(ctx context.Context) error {
 	return r.CloseWithExitCode(ctx, 0)
@@ -40,6 +40,7 @@ type ExitError struct {
 	// Note: this is a struct not a uint32 type as it was originally one and
 	// we don't want to break call-sites that cast into it.
 	exitCode uint32
+	signal   uint8
 }
 
 var exitZero = &ExitError{}
@@ -51,13 +52,29 @@ func NewExitError(exitCode uint32) *ExitError {
 	return &ExitError{exitCode: exitCode}
 }
 
+// NewSignalExitError returns an ExitError of a module terminated by a signal,
+// numbered as in "wasi_snapshot_preview1", e.g. 2 for SIGINT. Its exit code
+// is 128 plus the signal, like POSIX shells report it.
+func NewSignalExitError(signal uint8) *ExitError {
+	return &ExitError{exitCode: 128 + uint32(signal), signal: signal}
+}
+
 // ExitCode returns zero on success, and an arbitrary value otherwise.
 func (e *ExitError) ExitCode() uint32 {
 	return e.exitCode
 }
 
+// Signal returns the signal which terminated the module, or zero if it
+// wasn't terminated by a signal. See NewSignalExitError.
+func (e *ExitError) Signal() uint8 {
+	return e.signal
+}
+
 // Error implements the error interface.
 func (e *ExitError) Error() string {
+	if e.signal != 0 {
+		return fmt.Sprintf("module closed with signal(%d)", e.signal)
+	}
 	switch e.exitCode {
 	case ExitCodeContextCanceled:
 		return fmt.Sprintf("module closed with %s", context.Canceled)
@@ -71,7 +88,7 @@ func (e *ExitError) Error() string {
 // Is allows use via errors.Is
 func (e *ExitError) Is(err error) bool {
 	if target, ok := err.(*ExitError); ok {
-		return e.exitCode == target.exitCode
+		return e.exitCode == target.exitCode && e.signal == target.signal
 	}
 	if e.exitCode == ExitCodeContextCanceled && err == context.Canceled {
 		return true
@@ -34,6 +34,11 @@ func TestIs(t *testing.T) {
 			target:  sys.NewExitError(1),
 			matches: false,
 		},
+		{
+			name:    "signal",
+			target:  sys.NewSignalExitError(2),
+			matches: false,
+		},
 		{
 			name: "different type",
 			target: &notExitError{
@@ -70,4 +75,12 @@ func TestExitError_Error(t *testing.T) {
 		require.Equal(t, uint32(123), err.ExitCode())
 		require.EqualError(t, err, "module closed with exit_code(123)")
 	})
+	t.Run("signal", func(t *testing.T) {
+		err := sys.NewSignalExitError(2)
+		require.Equal(t, uint32(130), err.ExitCode())
+		require.Equal(t, uint8(2), err.Signal())
+		require.EqualError(t, err, "module closed with signal(2)")
+		require.ErrorIs(t, err, sys.NewSignalExitError(2))
+		require.False(t, errors.Is(err, sys.NewExitError(130)))
+	})
 }